@@ -64,6 +64,12 @@ func newCompiledGraphDefinition(wf *AgentWorkflow) *graph.GraphDefinition {
 			"compiled_at":           time.Now().UTC().Format(time.RFC3339),
 			"compiler_version":      compilerVersion,
 		},
+		Masking:     wf.Masking,
+		Vars:        wf.Vars,
+		RunDefaults: wf.RunDefaults,
+		Presets:     wf.Presets,
+		SLA:         wf.SLA,
+		Requires:    wf.Requires,
 	}
 }
 
@@ -82,6 +88,8 @@ func compileTaskNodes(
 			return fmt.Errorf("task %q references undefined agent %q", taskID, task.Agent)
 		}
 		compileTaskNode(gd, reg, taskID, task, ag, taskStartNodeIDs, taskNodeIDs)
+		compileTaskHandoffs(gd, wf, reg, taskID, task, ag, taskNodeIDs)
+		appendTaskHumanReviewNode(gd, taskID, task, taskNodeIDs[taskID], taskNodeIDs)
 	}
 
 	return nil
@@ -116,12 +124,17 @@ func compileTaskNode(
 		})
 	}
 
+	config := buildTaskLLMConfig(task, ag, taskNodeIDs, fcTools)
+	if len(task.Handoffs) > 0 && task.OutputKey == "" {
+		// The task's result may come from a handoff agent, so every agent
+		// writes to the output of the node that ends the task.
+		config["output_key"] = handoffDoneNodeID(nodeID) + "_output"
+	}
 	gd.Nodes = append(gd.Nodes, graph.NodeDef{
 		ID:     nodeID,
 		Type:   "llm_prompt",
-		Config: buildTaskLLMConfig(task, ag, taskNodeIDs, fcTools),
+		Config: config,
 	})
-	appendTaskHumanReviewNode(gd, taskID, task, nodeID, taskNodeIDs)
 }
 
 func compileTaskTools(
@@ -137,14 +150,8 @@ func compileTaskTools(
 
 	for _, toolID := range ag.Tools {
 		toolRef := strings.TrimSpace(toolID)
-		mode := reg.ToolMode(toolRef)
-		if mode == "" {
-			if def, ok := reg.Get(toolRef); ok && def.IsTool {
-				mode = inferredToolMode(def)
-			}
-		}
 
-		switch mode {
+		switch resolveToolMode(reg, toolRef) {
 		case "function_call":
 			fcTools = append(fcTools, toolRef)
 		case "standalone":
@@ -174,6 +181,16 @@ func compileTaskTools(
 	return fcTools, prevStandaloneNodeID
 }
 
+func resolveToolMode(reg *registry.Registry, toolRef string) string {
+	mode := reg.ToolMode(toolRef)
+	if mode == "" {
+		if def, ok := reg.Get(toolRef); ok && def.IsTool {
+			mode = inferredToolMode(def)
+		}
+	}
+	return mode
+}
+
 func buildStandaloneToolNodeConfig(reg *registry.Registry, toolRef string, ag Agent) map[string]any {
 	toolNodeConfig := map[string]any{}
 
@@ -259,6 +276,119 @@ func appendTaskHumanReviewNode(
 	taskNodeIDs[taskID] = hitlID
 }
 
+func handoffDoneNodeID(nodeID string) string {
+	return nodeID + "__handoff_done"
+}
+
+// compileTaskHandoffs compiles a task's handoffs into an llm_router that
+// lets the working agent either finish the task or pass it on, one
+// llm_prompt node per receiving agent, and a noop node that ends the task
+// on every path:
+//
+//	task -> router -> done
+//	             \-> task__handoff__<agent> -> done
+//
+// The router records an agent.handoff event when it picks a handoff.
+func compileTaskHandoffs(
+	gd *graph.GraphDefinition,
+	wf *AgentWorkflow,
+	reg *registry.Registry,
+	taskID string,
+	task Task,
+	ag Agent,
+	taskNodeIDs map[string]string,
+) {
+	if len(task.Handoffs) == 0 {
+		return
+	}
+
+	nodeID := taskNodeIDs[taskID]
+	routerID := nodeID + "__handoff"
+	doneID := handoffDoneNodeID(nodeID)
+	outputKey := task.OutputKey
+	if outputKey == "" {
+		outputKey = doneID + "_output"
+	}
+
+	var options strings.Builder
+	options.WriteString("complete: the work fully meets the expected output")
+	allowedTargets := map[string]any{"complete": doneID}
+	handoffTargets := map[string]any{}
+
+	for _, h := range task.Handoffs {
+		target, ok := wf.Agents[h.Agent]
+		if !ok {
+			continue // validation reports unknown handoff agents
+		}
+		targetID := nodeID + "__handoff__" + h.Agent
+		allowedTargets[h.Agent] = targetID
+		handoffTargets[targetID] = h.Agent
+		fmt.Fprintf(&options, "\n%s: hand off to the %s when %s", h.Agent, target.Role, h.When)
+
+		var fcTools []string
+		for _, toolRef := range target.Tools {
+			if resolveToolMode(reg, strings.TrimSpace(toolRef)) == "function_call" {
+				fcTools = append(fcTools, strings.TrimSpace(toolRef))
+			}
+		}
+		config := buildTaskLLMConfig(task, target, taskNodeIDs, fcTools)
+		config["prompt_template"] = buildHandoffPrompt(ag, task, h, routerID, outputKey, taskNodeIDs)
+		config["output_key"] = outputKey
+
+		gd.Nodes = append(gd.Nodes, graph.NodeDef{ID: targetID, Type: "llm_prompt", Config: config})
+		gd.Edges = append(gd.Edges,
+			graph.EdgeDef{Source: routerID, SourceHandle: "output", Target: targetID, TargetHandle: "input"},
+			graph.EdgeDef{Source: targetID, SourceHandle: "output", Target: doneID, TargetHandle: "input"},
+		)
+	}
+
+	gd.Nodes = append(gd.Nodes,
+		graph.NodeDef{
+			ID:   routerID,
+			Type: "llm_router",
+			Config: map[string]any{
+				"system_prompt": fmt.Sprintf(
+					"You are a %s working on this task: %s\n\nReview your work so far and decide whether it is complete or another agent should take over.\n\nOptions:\n%s",
+					ag.Role, task.Description, options.String()),
+				"provider":        ag.Provider,
+				"model":           ag.Model,
+				"input_vars":      []any{outputKey},
+				"allowed_targets": allowedTargets,
+				"handoff": map[string]any{
+					"task":       taskID,
+					"from_agent": task.Agent,
+					"targets":    handoffTargets,
+				},
+			},
+		},
+		graph.NodeDef{ID: doneID, Type: "noop"},
+	)
+	gd.Edges = append(gd.Edges,
+		graph.EdgeDef{Source: nodeID, SourceHandle: "output", Target: routerID, TargetHandle: "input"},
+		graph.EdgeDef{Source: routerID, SourceHandle: "output", Target: doneID, TargetHandle: "input"},
+	)
+
+	taskNodeIDs[taskID] = doneID
+}
+
+// buildHandoffPrompt builds the receiving agent's prompt: the handoff
+// reason chosen by the router, the task, the work so far, and the
+// handoff's context subset.
+func buildHandoffPrompt(from Agent, task Task, h Handoff, routerID, outputKey string, taskNodeIDs map[string]string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("You are taking over this task from the %s.", from.Role))
+	sb.WriteString(fmt.Sprintf("\nHandoff reason: {{.%s_decision.Reason}}", routerID))
+	sb.WriteString("\n\nTask: " + rewriteTemplate(task.Description, taskNodeIDs))
+	sb.WriteString(fmt.Sprintf("\n\nWork so far:\n{{.%s}}", outputKey))
+	if len(h.Context) > 0 {
+		sb.WriteString("\n\nContext:")
+		for _, label := range sortedKeys(h.Context) {
+			sb.WriteString(fmt.Sprintf("\n%s: %s", label, rewriteTemplate(h.Context[label], taskNodeIDs)))
+		}
+	}
+	return sb.String()
+}
+
 func wireTaskReferenceEdges(
 	gd *graph.GraphDefinition,
 	wf *AgentWorkflow,
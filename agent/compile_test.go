@@ -1143,3 +1143,85 @@ func TestCompile_SortedKeys(t *testing.T) {
 		}
 	}
 }
+
+func TestCompile_Handoff(t *testing.T) {
+	wf := minimalWorkflow()
+	wf.Agents["analyst"] = Agent{
+		Role:     "Data Analyst",
+		Goal:     "Crunch numbers",
+		Provider: "openai",
+		Model:    "gpt-4o",
+	}
+	wf.Tasks["research"] = Task{
+		Description:    "Research {{input.topic}}",
+		Agent:          "researcher",
+		ExpectedOutput: "Findings",
+		Review:         "human",
+		Handoffs: []Handoff{{
+			Agent:   "analyst",
+			When:    "the findings need statistical analysis",
+			Context: map[string]string{"region": "{{input.region}}"},
+		}},
+	}
+
+	gd, err := Compile(wf)
+	if err != nil {
+		t.Fatalf("Compile() error = %v", err)
+	}
+
+	nodes := make(map[string]graph.NodeDef)
+	for _, n := range gd.Nodes {
+		nodes[n.ID] = n
+	}
+	const (
+		taskNode = "research__researcher"
+		router   = "research__researcher__handoff"
+		analyst  = "research__researcher__handoff__analyst"
+		done     = "research__researcher__handoff_done"
+		hitl     = "research__researcher__handoff_done__hitl"
+	)
+	for id, typ := range map[string]string{taskNode: "llm_prompt", router: "llm_router", analyst: "llm_prompt", done: "noop", hitl: "human"} {
+		if nodes[id].Type != typ {
+			t.Errorf("node %q type = %q, want %q", id, nodes[id].Type, typ)
+		}
+	}
+
+	outputKey := done + "_output"
+	if nodes[taskNode].Config["output_key"] != outputKey || nodes[analyst].Config["output_key"] != outputKey {
+		t.Errorf("task and handoff nodes should share output_key %q", outputKey)
+	}
+	if nodes[analyst].Config["provider"] != "openai" || nodes[analyst].Config["model"] != "gpt-4o" {
+		t.Errorf("handoff node should run as the receiving agent: %v", nodes[analyst].Config)
+	}
+	prompt, _ := nodes[analyst].Config["prompt_template"].(string)
+	for _, want := range []string{"{{." + router + "_decision.Reason}}", "Task: Research {{.topic}}", "{{." + outputKey + "}}", "region: {{.region}}"} {
+		if !strings.Contains(prompt, want) {
+			t.Errorf("handoff prompt missing %q:\n%s", want, prompt)
+		}
+	}
+
+	targets, _ := nodes[router].Config["allowed_targets"].(map[string]any)
+	if targets["complete"] != done || targets["analyst"] != analyst {
+		t.Errorf("allowed_targets = %v", targets)
+	}
+	handoff, _ := nodes[router].Config["handoff"].(map[string]any)
+	if handoff["from_agent"] != "researcher" || handoff["task"] != "research" {
+		t.Errorf("handoff = %v", handoff)
+	}
+
+	wantEdges := [][2]string{{taskNode, router}, {router, done}, {router, analyst}, {analyst, done}, {done, hitl}}
+	for _, we := range wantEdges {
+		found := false
+		for _, e := range gd.Edges {
+			if e.Source == we[0] && e.Target == we[1] {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("missing edge %s -> %s", we[0], we[1])
+		}
+	}
+	if gd.Entry != taskNode {
+		t.Errorf("Entry = %q, want %q", gd.Entry, taskNode)
+	}
+}
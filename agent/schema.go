@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/mask"
 )
 
 // AgentWorkflow is the top-level Agent/Task schema. It defines agents, tasks,
@@ -17,6 +20,17 @@ type AgentWorkflow struct {
 	Agents        map[string]Agent `json:"agents"`
 	Tasks         map[string]Task  `json:"tasks"`
 	Execution     ExecutionConfig  `json:"execution"`
+	Masking       *mask.Policy     `json:"masking,omitempty"`
+	// Vars declares lifetimes for envelope variables, keyed by name.
+	Vars map[string]graph.VarLifetime `json:"vars,omitempty"`
+	// RunDefaults are run options applied to every run of the workflow.
+	RunDefaults *graph.RunDefaults `json:"run_defaults,omitempty"`
+	// Presets are named run inputs, keyed by name.
+	Presets map[string]graph.InputPreset `json:"presets,omitempty"`
+	// SLA declares service levels tracked for every run.
+	SLA *graph.SLA `json:"sla,omitempty"`
+	// Requires are the engine and node type versions the workflow needs.
+	Requires *graph.Requirements `json:"requires,omitempty"`
 }
 
 // Agent describes an AI agent with its role, provider, model, and optional tools.
@@ -40,6 +54,21 @@ type Task struct {
 	Inputs         map[string]string `json:"inputs,omitempty"`
 	Review         string            `json:"review,omitempty"`
 	Context        []string          `json:"context,omitempty"`
+	Handoffs       []Handoff         `json:"handoffs,omitempty"`
+}
+
+// Handoff lets the agent working on a task transfer it mid-task to another
+// agent. After its first attempt the working agent decides whether the task
+// is complete or one of its handoffs applies; the receiving agent gets the
+// handoff reason, the work so far and only the selected context.
+type Handoff struct {
+	// Agent is the agent that takes over.
+	Agent string `json:"agent"`
+	// When describes the situations that call for this handoff.
+	When string `json:"when"`
+	// Context maps labels to templates ({{input.X}}, {{tasks.T.output}})
+	// passed to the receiving agent.
+	Context map[string]string `json:"context,omitempty"`
 }
 
 // ExecutionConfig controls how tasks are executed: sequentially, in parallel,
@@ -274,6 +274,7 @@ func validateTasks(wf *AgentWorkflow) []graph.Diagnostic {
 		diags = append(diags, validateTaskAgentReference(wf, id, path, task)...)
 		diags = append(diags, validateTaskInputReferences(wf, id, path, task)...)
 		diags = append(diags, validateTaskContextReferences(wf, id, path, task)...)
+		diags = append(diags, validateTaskHandoffs(wf, id, path, task)...)
 	}
 
 	return diags
@@ -347,6 +348,54 @@ func validateTaskContextReferences(wf *AgentWorkflow, id string, path string, ta
 	return diags
 }
 
+func validateTaskHandoffs(wf *AgentWorkflow, id string, path string, task Task) []graph.Diagnostic {
+	diags := make([]graph.Diagnostic, 0)
+	seen := make(map[string]bool, len(task.Handoffs))
+
+	// AT-015: Validate handoff targets.
+	for i, h := range task.Handoffs {
+		hPath := fmt.Sprintf("%s.handoffs[%d]", path, i)
+		_, defined := wf.Agents[h.Agent]
+		switch {
+		case h.Agent == "":
+			diags = append(diags, errDiag("AT-010", "MISSING_REQUIRED",
+				fmt.Sprintf("Task %q handoff is missing required field \"agent\"", id), hPath+".agent"))
+		case !defined:
+			diags = append(diags, errDiag("AT-001", "UNDEFINED_AGENT",
+				fmt.Sprintf("Task %q hands off to undefined agent %q", id, h.Agent), hPath+".agent"))
+		case h.Agent == task.Agent:
+			diags = append(diags, errDiag("AT-015", "INVALID_HANDOFF",
+				fmt.Sprintf("Task %q cannot hand off to its own agent %q", id, h.Agent), hPath+".agent"))
+		case h.Agent == "complete":
+			diags = append(diags, errDiag("AT-015", "INVALID_HANDOFF",
+				fmt.Sprintf("Task %q handoff agent ID %q is reserved", id, h.Agent), hPath+".agent"))
+		case seen[h.Agent]:
+			diags = append(diags, errDiag("AT-015", "INVALID_HANDOFF",
+				fmt.Sprintf("Task %q hands off to agent %q more than once", id, h.Agent), hPath+".agent"))
+		}
+		seen[h.Agent] = true
+
+		if strings.TrimSpace(h.When) == "" {
+			diags = append(diags, errDiag("AT-010", "MISSING_REQUIRED",
+				fmt.Sprintf("Task %q handoff to %q is missing required field \"when\"", id, h.Agent), hPath+".when"))
+		}
+
+		// AT-008: Validate context template references.
+		for label, tmpl := range h.Context {
+			for _, ref := range extractTaskRefs(tmpl) {
+				if _, ok := wf.Tasks[ref]; ok {
+					continue
+				}
+				diags = append(diags, errDiag("AT-008", "UNRESOLVED_REF",
+					fmt.Sprintf("Unresolved reference %q in task %q handoff context", tmpl, id),
+					fmt.Sprintf("%s.context.%s", hPath, label)))
+			}
+		}
+	}
+
+	return diags
+}
+
 func validateExecutionStrategy(wf *AgentWorkflow) (string, []graph.Diagnostic) {
 	diags := make([]graph.Diagnostic, 0)
 	strategy := wf.Execution.Strategy
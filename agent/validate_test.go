@@ -778,6 +778,55 @@ func TestIsValidID(t *testing.T) {
 	}
 }
 
+// --- AT-015: INVALID_HANDOFF ---
+
+func TestValidate_AT015_Handoffs(t *testing.T) {
+	wf := validWorkflow()
+	wf.Agents["editor"] = Agent{Role: "Editor", Goal: "Polish", Provider: "openai", Model: "gpt-4"}
+	wf.Tasks["research"] = Task{
+		Description:    "Research",
+		Agent:          "researcher",
+		ExpectedOutput: "Results",
+		Handoffs: []Handoff{
+			{Agent: "editor", When: "the draft needs polish", Context: map[string]string{"notes": "{{tasks.missing.output}}"}},
+			{Agent: "editor", When: "again"},
+			{Agent: "researcher", When: "never"},
+			{Agent: "ghost", When: "sometimes"},
+			{Agent: "editor"},
+		},
+	}
+
+	diags := Validate(wf)
+	wantPaths := map[string]string{
+		"tasks.research.handoffs[0].context.notes": "AT-008",
+		"tasks.research.handoffs[1].agent":         "AT-015",
+		"tasks.research.handoffs[2].agent":         "AT-015",
+		"tasks.research.handoffs[3].agent":         "AT-001",
+		"tasks.research.handoffs[4].when":          "AT-010",
+	}
+	for path, code := range wantPaths {
+		found := false
+		for _, d := range diags {
+			if d.Path == path && d.Code == code {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected %s at %s, got %v", code, path, diags)
+		}
+	}
+
+	wf.Tasks["research"] = Task{
+		Description:    "Research",
+		Agent:          "researcher",
+		ExpectedOutput: "Results",
+		Handoffs:       []Handoff{{Agent: "editor", When: "the draft needs polish"}},
+	}
+	if diags := Validate(wf); len(diags) != 0 {
+		t.Errorf("valid handoff produced diagnostics: %v", diags)
+	}
+}
+
 // --- helpers ---
 
 func findDiagCode(diags []graph.Diagnostic, code string) *graph.Diagnostic {
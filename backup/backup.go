@@ -0,0 +1,590 @@
+// Package backup snapshots and restores the daemon's SQLite state:
+// workflows, schedules, tool registrations, uploads and, optionally, run
+// events.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Format identifies the snapshot encoding written by this package.
+const Format = "petalflow.backup/v1"
+
+// table describes a daemon table covered by snapshots.
+type table struct {
+	name string
+	// key identifies a row: a column, or an expression joining the columns
+	// of a composite key. Tables without one are append-only, so
+	// incremental snapshots never delete from them.
+	key string
+	// changed is the timestamp column compared against Options.Since.
+	changed string
+	events  bool
+	// serial marks a table whose rows may never be deleted or rewritten.
+	// It names the column the database assigns; restores leave it out and
+	// insert only rows no existing row matches on every other column, and
+	// full restores do not clear the table.
+	serial string
+}
+
+// tables lists the snapshot tables in restore order.
+var tables = []table{
+	{name: "workflows", key: "id", changed: "updated_at"},
+	{name: "workflow_schedules", key: "id", changed: "updated_at"},
+	{name: "tool_registrations", key: "name", changed: "updated_at"},
+	{name: "uploads", key: "id", changed: "created_at"},
+	{name: "conditions", key: "name", changed: "updated_at"},
+	{name: "eval_datasets", key: "name", changed: "updated_at"},
+	{name: "workflow_templates", key: "id", changed: "updated_at"},
+	{name: "workflow_rollouts", key: "workflow_id", changed: "updated_at"},
+	{name: "workflow_lifecycles", key: "workflow_id", changed: "updated_at"},
+	{name: "workflow_presets", key: "id", changed: "updated_at"},
+	{name: "llm_output_history", key: "seq", changed: "created_at"},
+	{name: "model_arm_stats", key: "stats_key || char(31) || arm", changed: "updated_at"},
+	// Rewards update a selection's payload but not created_at, so every
+	// snapshot copies all selections.
+	{name: "model_selections", key: "selection_id"},
+	{name: "workflow_state", key: "namespace || char(31) || state_key", changed: "updated_at"},
+	{name: "outbox_deliveries", key: "id", changed: "updated_at"},
+	{name: "audit_log", changed: "at", serial: "id"},
+	{name: "events", changed: "time", events: true},
+}
+
+// incrementalOverlap widens an incremental snapshot's window. Stores stamp
+// rows before they commit, so a row stamped just before a base snapshot can
+// still be invisible to it; re-copying recent rows is harmless because
+// restores upsert.
+const incrementalOverlap = time.Minute
+
+// Options controls what Create includes.
+type Options struct {
+	// IncludeEvents adds run history (the events table).
+	IncludeEvents bool
+	// Since makes the snapshot incremental: only rows changed after Since
+	// are included, along with the keys of every current row so restores
+	// can replay deletions.
+	Since time.Time
+}
+
+// Snapshot is a consistent copy of the daemon tables.
+type Snapshot struct {
+	Format    string    `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	// Since is set on incremental snapshots.
+	Since  *time.Time `json:"since,omitempty"`
+	Tables []Table    `json:"tables"`
+}
+
+// Incremental reports whether s only holds changes since an earlier
+// snapshot.
+func (s *Snapshot) Incremental() bool {
+	return s.Since != nil
+}
+
+// Table holds the rows of one table.
+type Table struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Rows    [][]any  `json:"rows"`
+	// Keys lists every row key present when an incremental snapshot was
+	// taken. Rows missing from it are deleted on restore; an empty list
+	// empties the table, so it is encoded even then.
+	Keys []string `json:"keys"`
+}
+
+// RestoreResult reports what Restore changed per table.
+type RestoreResult struct {
+	Tables []TableResult `json:"tables"`
+}
+
+// TableResult counts the rows written and deleted in one table.
+type TableResult struct {
+	Name     string `json:"name"`
+	Restored int    `json:"restored"`
+	Deleted  int    `json:"deleted"`
+}
+
+// Manager creates and restores snapshots of one SQLite database.
+type Manager struct {
+	db *sql.DB
+}
+
+// Open opens the SQLite database at dsn for backup and restore.
+func Open(dsn string) (*Manager, error) {
+	if strings.TrimSpace(dsn) == "" {
+		return nil, errors.New("backup sqlite dsn is required")
+	}
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("backup sqlite open: %w", err)
+	}
+	if _, err := db.Exec("PRAGMA journal_mode=WAL"); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("backup sqlite set WAL mode: %w", err)
+	}
+	return &Manager{db: db}, nil
+}
+
+// Close closes the database.
+func (m *Manager) Close() error {
+	return m.db.Close()
+}
+
+// Create reads every snapshot table inside one read transaction, so the
+// snapshot is consistent even while the daemon keeps writing. Tables that
+// do not exist yet are skipped.
+func (m *Manager) Create(ctx context.Context, opts Options) (*Snapshot, error) {
+	tx, err := m.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("backup begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	snap := &Snapshot{Format: Format, CreatedAt: time.Now().UTC()}
+	if !opts.Since.IsZero() {
+		since := opts.Since.UTC()
+		snap.Since = &since
+	}
+	for _, t := range tables {
+		if t.events && !opts.IncludeEvents {
+			continue
+		}
+		columns, err := tableColumns(ctx, tx, t.name)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		data, err := readTable(ctx, tx, t, columns, snap.Since)
+		if err != nil {
+			return nil, err
+		}
+		snap.Tables = append(snap.Tables, data)
+	}
+	return snap, nil
+}
+
+func readTable(ctx context.Context, tx *sql.Tx, t table, columns []string, since *time.Time) (Table, error) {
+	out := Table{Name: t.name, Columns: columns, Rows: [][]any{}}
+	changedIdx := slices.Index(columns, t.changed)
+
+	var cutoff time.Time
+	if since != nil {
+		cutoff = since.Add(-incrementalOverlap)
+	}
+	query := "SELECT " + quoteColumns(columns) + " FROM " + t.name
+	var args []any
+	if since != nil && changedIdx >= 0 {
+		// Timestamps are RFC3339Nano text, which does not sort exactly
+		// at sub-second precision. Narrow by second in SQL and compare
+		// precisely below.
+		query += " WHERE " + t.changed + " >= ?"
+		args = append(args, cutoff.Format("2006-01-02T15:04:05"))
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return out, fmt.Errorf("backup read %s: %w", t.name, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		values := make([]any, len(columns))
+		ptrs := make([]any, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return out, fmt.Errorf("backup read %s: %w", t.name, err)
+		}
+		if since != nil && changedIdx >= 0 && !changedAfter(values[changedIdx], cutoff) {
+			continue
+		}
+		out.Rows = append(out.Rows, values)
+	}
+	if err := rows.Err(); err != nil {
+		return out, fmt.Errorf("backup read %s: %w", t.name, err)
+	}
+
+	if since != nil && t.key != "" {
+		keys, err := readKeys(ctx, tx, t)
+		if err != nil {
+			return out, err
+		}
+		out.Keys = keys
+	}
+	return out, nil
+}
+
+// changedAfter reports whether a stored timestamp is after since. Values
+// that do not parse are treated as changed so they are never lost.
+func changedAfter(v any, since time.Time) bool {
+	var text string
+	switch tv := v.(type) {
+	case string:
+		text = tv
+	case []byte:
+		text = string(tv)
+	default:
+		return true
+	}
+	ts, err := time.Parse(time.RFC3339Nano, text)
+	if err != nil {
+		return true
+	}
+	return ts.After(since)
+}
+
+func readKeys(ctx context.Context, tx *sql.Tx, t table) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT "+t.key+" FROM "+t.name)
+	if err != nil {
+		return nil, fmt.Errorf("backup read %s keys: %w", t.name, err)
+	}
+	defer rows.Close()
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("backup read %s keys: %w", t.name, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Restore applies snapshots in order inside one transaction. A full
+// snapshot replaces the contents of each table it holds; an incremental
+// snapshot upserts its rows and deletes rows whose keys it does not list.
+// Tables absent from a snapshot, or from the database, are left alone, as
+// are columns the database does not have.
+func (m *Manager) Restore(ctx context.Context, snaps ...*Snapshot) (*RestoreResult, error) {
+	if len(snaps) == 0 {
+		return nil, errors.New("no snapshots to restore")
+	}
+	for i, snap := range snaps {
+		if snap == nil || snap.Format != Format {
+			return nil, fmt.Errorf("snapshot %d: unsupported format", i+1)
+		}
+		if i > 0 && !snap.Incremental() {
+			return nil, fmt.Errorf("snapshot %d: only the first snapshot may be a full backup", i+1)
+		}
+	}
+
+	// Foreign keys are checked per statement, which would make the order
+	// of a full replace matter. The snapshot itself is consistent, so turn
+	// them off for the restore connection.
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("restore connect: %w", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys=OFF"); err != nil {
+		return nil, fmt.Errorf("restore disable foreign keys: %w", err)
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("restore begin: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	totals := make(map[string]*TableResult)
+	for _, snap := range snaps {
+		byName := make(map[string]Table, len(snap.Tables))
+		for _, data := range snap.Tables {
+			byName[data.Name] = data
+		}
+		for _, t := range tables {
+			data, ok := byName[t.name]
+			if !ok {
+				continue
+			}
+			columns, err := tableColumns(ctx, tx, t.name)
+			if err != nil {
+				return nil, err
+			}
+			if len(columns) == 0 {
+				continue
+			}
+			res := totals[t.name]
+			if res == nil {
+				res = &TableResult{Name: t.name}
+				totals[t.name] = res
+			}
+			if err := restoreTable(ctx, tx, t, columns, data, snap.Incremental(), res); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("restore commit: %w", err)
+	}
+
+	result := &RestoreResult{Tables: []TableResult{}}
+	for _, t := range tables {
+		if res, ok := totals[t.name]; ok {
+			result.Tables = append(result.Tables, *res)
+		}
+	}
+	return result, nil
+}
+
+func restoreTable(ctx context.Context, tx *sql.Tx, t table, existing []string, data Table, incremental bool, res *TableResult) error {
+	if t.serial != "" {
+		return restoreAppendOnly(ctx, tx, t, existing, data, res)
+	}
+	if !incremental {
+		deleted, err := tx.ExecContext(ctx, "DELETE FROM "+t.name)
+		if err != nil {
+			return fmt.Errorf("restore clear %s: %w", t.name, err)
+		}
+		n, _ := deleted.RowsAffected()
+		res.Deleted += int(n)
+	}
+
+	// Only write columns both sides know about, by index into the row.
+	var columns []string
+	var indexes []int
+	for i, c := range data.Columns {
+		if slices.Contains(existing, c) {
+			columns = append(columns, c)
+			indexes = append(indexes, i)
+		}
+	}
+	if len(columns) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+		stmt, err := tx.PrepareContext(ctx, "INSERT OR REPLACE INTO "+t.name+" ("+quoteColumns(columns)+") VALUES ("+placeholders+")")
+		if err != nil {
+			return fmt.Errorf("restore prepare %s: %w", t.name, err)
+		}
+		defer stmt.Close()
+		for n, row := range data.Rows {
+			if len(row) != len(data.Columns) {
+				return fmt.Errorf("restore %s: row %d has %d values for %d columns", t.name, n+1, len(row), len(data.Columns))
+			}
+			args := make([]any, len(indexes))
+			for i, idx := range indexes {
+				args[i] = row[idx]
+			}
+			if _, err := stmt.ExecContext(ctx, args...); err != nil {
+				return fmt.Errorf("restore %s: %w", t.name, err)
+			}
+			res.Restored++
+		}
+	}
+
+	if !incremental || t.key == "" || data.Keys == nil {
+		return nil
+	}
+	current, err := readKeys(ctx, tx, t)
+	if err != nil {
+		return err
+	}
+	keep := make(map[string]bool, len(data.Keys))
+	for _, k := range data.Keys {
+		keep[k] = true
+	}
+	for _, k := range current {
+		if keep[k] {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+t.name+" WHERE ("+t.key+") = ?", k); err != nil {
+			return fmt.Errorf("restore delete from %s: %w", t.name, err)
+		}
+		res.Deleted++
+	}
+	return nil
+}
+
+// restoreAppendOnly adds the snapshot's rows of a serial table that the
+// database does not hold yet, comparing every column but the serial one.
+// Existing rows are kept, so restoring twice or from overlapping
+// snapshots adds nothing.
+func restoreAppendOnly(ctx context.Context, tx *sql.Tx, t table, existing []string, data Table, res *TableResult) error {
+	var columns []string
+	var indexes []int
+	for i, c := range data.Columns {
+		if c != t.serial && slices.Contains(existing, c) {
+			columns = append(columns, c)
+			indexes = append(indexes, i)
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	match := make([]string, len(columns))
+	for i, c := range columns {
+		match[i] = quoteColumns([]string{c}) + " IS ?"
+	}
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO "+t.name+" ("+quoteColumns(columns)+") SELECT "+placeholders+
+		" WHERE NOT EXISTS (SELECT 1 FROM "+t.name+" WHERE "+strings.Join(match, " AND ")+")")
+	if err != nil {
+		return fmt.Errorf("restore prepare %s: %w", t.name, err)
+	}
+	defer stmt.Close()
+	for n, row := range data.Rows {
+		if len(row) != len(data.Columns) {
+			return fmt.Errorf("restore %s: row %d has %d values for %d columns", t.name, n+1, len(row), len(data.Columns))
+		}
+		args := make([]any, 0, 2*len(indexes))
+		for _, idx := range indexes {
+			args = append(args, row[idx])
+		}
+		args = append(args, args...)
+		result, err := stmt.ExecContext(ctx, args...)
+		if err != nil {
+			return fmt.Errorf("restore %s: %w", t.name, err)
+		}
+		if added, _ := result.RowsAffected(); added > 0 {
+			res.Restored++
+		}
+	}
+	return nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// tableColumns returns the columns of name, or nil when the table does not
+// exist. name always comes from the tables list, never from a snapshot.
+func tableColumns(ctx context.Context, q queryer, name string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `PRAGMA table_info(`+name+`)`)
+	if err != nil {
+		return nil, fmt.Errorf("backup inspect %s: %w", name, err)
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var (
+			cid       int
+			column    string
+			colType   string
+			notNull   int
+			dfltValue sql.NullString
+			pk        int
+		)
+		if err := rows.Scan(&cid, &column, &colType, &notNull, &dfltValue, &pk); err != nil {
+			return nil, fmt.Errorf("backup inspect %s: %w", name, err)
+		}
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+func quoteColumns(columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = `"` + strings.ReplaceAll(c, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Write encodes snap as gzip-compressed JSON.
+func Write(w io.Writer, snap *Snapshot) error {
+	zw := gzip.NewWriter(w)
+	if err := json.NewEncoder(zw).Encode(encodeSnapshot(snap)); err != nil {
+		_ = zw.Close()
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+	return zw.Close()
+}
+
+// Read decodes a snapshot written by Write. Uncompressed JSON is accepted
+// too.
+func Read(r io.Reader) (*Snapshot, error) {
+	br := bufio.NewReader(r)
+	var src io.Reader = br
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("reading snapshot: %w", err)
+		}
+		defer zr.Close()
+		src = zr
+	}
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+	var snap Snapshot
+	if err := dec.Decode(&snap); err != nil {
+		return nil, fmt.Errorf("decoding snapshot: %w", err)
+	}
+	if snap.Format != Format {
+		return nil, fmt.Errorf("unsupported snapshot format %q", snap.Format)
+	}
+	for ti := range snap.Tables {
+		for _, row := range snap.Tables[ti].Rows {
+			for i, v := range row {
+				value, err := decodeValue(v)
+				if err != nil {
+					return nil, fmt.Errorf("decoding snapshot table %s: %w", snap.Tables[ti].Name, err)
+				}
+				row[i] = value
+			}
+		}
+	}
+	return &snap, nil
+}
+
+// blobValue carries BLOB columns through JSON, which would otherwise turn
+// them into indistinguishable base64 strings.
+type blobValue struct {
+	Bytes string `json:"$bytes"`
+}
+
+func encodeSnapshot(snap *Snapshot) *Snapshot {
+	out := *snap
+	out.Tables = make([]Table, len(snap.Tables))
+	for ti, t := range snap.Tables {
+		rows := make([][]any, len(t.Rows))
+		for ri, row := range t.Rows {
+			encoded := make([]any, len(row))
+			for i, v := range row {
+				if b, ok := v.([]byte); ok {
+					encoded[i] = blobValue{Bytes: base64.StdEncoding.EncodeToString(b)}
+					continue
+				}
+				encoded[i] = v
+			}
+			rows[ri] = encoded
+		}
+		t.Rows = rows
+		out.Tables[ti] = t
+	}
+	return &out
+}
+
+func decodeValue(v any) (any, error) {
+	switch tv := v.(type) {
+	case json.Number:
+		if n, err := tv.Int64(); err == nil {
+			return n, nil
+		}
+		return tv.Float64()
+	case map[string]any:
+		encoded, ok := tv["$bytes"].(string)
+		if !ok || len(tv) != 1 {
+			return nil, errors.New("unexpected object value")
+		}
+		return base64.StdEncoding.DecodeString(encoded)
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,373 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSchema = `
+CREATE TABLE workflows (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	id TEXT NOT NULL UNIQUE,
+	source BLOB NOT NULL,
+	updated_at TEXT NOT NULL
+);
+CREATE TABLE tool_registrations (
+	name TEXT PRIMARY KEY,
+	payload BLOB NOT NULL,
+	updated_at TEXT NOT NULL
+);
+CREATE TABLE events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	run_id TEXT NOT NULL,
+	seq INTEGER NOT NULL,
+	time TEXT NOT NULL,
+	UNIQUE(run_id, seq)
+);`
+
+func openTestDB(t *testing.T) (*Manager, *sql.DB) {
+	t.Helper()
+	dsn := filepath.Join(t.TempDir(), "petalflow.db")
+	m, err := Open(dsn)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { _ = m.Close() })
+	if _, err := m.db.Exec(testSchema); err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	return m, m.db
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string, args ...any) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("exec %q: %v", query, err)
+	}
+}
+
+func stamp(ts time.Time) string {
+	return ts.UTC().Format(time.RFC3339Nano)
+}
+
+func roundTrip(t *testing.T, snap *Snapshot) *Snapshot {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Write(&buf, snap); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	out, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return out
+}
+
+func TestCreateRestore_FullSnapshot(t *testing.T) {
+	ctx := context.Background()
+	src, srcDB := openTestDB(t)
+	now := time.Now()
+	mustExec(t, srcDB, "INSERT INTO workflows (id, source, updated_at) VALUES (?, ?, ?)", "wf1", []byte(`{"id":"wf1"}`), stamp(now))
+	mustExec(t, srcDB, "INSERT INTO tool_registrations (name, payload, updated_at) VALUES (?, ?, ?)", "search", []byte("enc:v1:secret"), stamp(now))
+	mustExec(t, srcDB, "INSERT INTO events (run_id, seq, time) VALUES (?, ?, ?)", "run-1", 1, stamp(now))
+
+	snap, err := src.Create(ctx, Options{})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	for _, table := range snap.Tables {
+		if table.Name == "events" {
+			t.Fatal("events included without IncludeEvents")
+		}
+	}
+
+	dst, dstDB := openTestDB(t)
+	mustExec(t, dstDB, "INSERT INTO workflows (id, source, updated_at) VALUES (?, ?, ?)", "stale", []byte(`{}`), stamp(now))
+	res, err := dst.Restore(ctx, roundTrip(t, snap))
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if len(res.Tables) != 2 || res.Tables[0].Name != "workflows" || res.Tables[0].Restored != 1 || res.Tables[0].Deleted != 1 {
+		t.Fatalf("result = %+v", res.Tables)
+	}
+
+	var source, payload []byte
+	if err := dstDB.QueryRow("SELECT source FROM workflows WHERE id = 'wf1'").Scan(&source); err != nil {
+		t.Fatalf("restored workflow: %v", err)
+	}
+	if string(source) != `{"id":"wf1"}` {
+		t.Errorf("source = %q", source)
+	}
+	if err := dstDB.QueryRow("SELECT payload FROM tool_registrations WHERE name = 'search'").Scan(&payload); err != nil {
+		t.Fatalf("restored tool: %v", err)
+	}
+	if string(payload) != "enc:v1:secret" {
+		t.Errorf("payload = %q", payload)
+	}
+	var stale int
+	_ = dstDB.QueryRow("SELECT COUNT(*) FROM workflows WHERE id = 'stale'").Scan(&stale)
+	if stale != 0 {
+		t.Error("full restore kept a row missing from the snapshot")
+	}
+}
+
+func TestCreateRestore_Incremental(t *testing.T) {
+	ctx := context.Background()
+	src, srcDB := openTestDB(t)
+	old := time.Now().Add(-time.Hour)
+	mustExec(t, srcDB, "INSERT INTO workflows (id, source, updated_at) VALUES (?, ?, ?)", "keep", []byte("v1"), stamp(old))
+	mustExec(t, srcDB, "INSERT INTO workflows (id, source, updated_at) VALUES (?, ?, ?)", "gone", []byte("v1"), stamp(old))
+	mustExec(t, srcDB, "INSERT INTO events (run_id, seq, time) VALUES (?, ?, ?)", "run-1", 1, stamp(old))
+
+	base, err := src.Create(ctx, Options{IncludeEvents: true})
+	if err != nil {
+		t.Fatalf("Create base: %v", err)
+	}
+
+	later := base.CreatedAt.Add(time.Second)
+	mustExec(t, srcDB, "UPDATE workflows SET source = ?, updated_at = ? WHERE id = 'keep'", []byte("v2"), stamp(later))
+	mustExec(t, srcDB, "DELETE FROM workflows WHERE id = 'gone'")
+	mustExec(t, srcDB, "INSERT INTO workflows (id, source, updated_at) VALUES (?, ?, ?)", "new", []byte("v1"), stamp(later))
+	mustExec(t, srcDB, "INSERT INTO events (run_id, seq, time) VALUES (?, ?, ?)", "run-1", 2, stamp(later))
+
+	inc, err := src.Create(ctx, Options{IncludeEvents: true, Since: base.CreatedAt})
+	if err != nil {
+		t.Fatalf("Create incremental: %v", err)
+	}
+	if !inc.Incremental() {
+		t.Fatal("snapshot not marked incremental")
+	}
+	for _, table := range inc.Tables {
+		if table.Name == "workflows" && len(table.Rows) != 2 {
+			t.Errorf("incremental workflows rows = %d, want 2", len(table.Rows))
+		}
+		if table.Name == "events" && len(table.Rows) != 1 {
+			t.Errorf("incremental events rows = %d, want 1", len(table.Rows))
+		}
+	}
+
+	dst, dstDB := openTestDB(t)
+	if _, err := dst.Restore(ctx, roundTrip(t, base), roundTrip(t, inc)); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	rows, err := dstDB.Query("SELECT id, source FROM workflows ORDER BY id")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+	got := map[string]string{}
+	for rows.Next() {
+		var id string
+		var source []byte
+		if err := rows.Scan(&id, &source); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		got[id] = string(source)
+	}
+	if len(got) != 2 || got["keep"] != "v2" || got["new"] != "v1" {
+		t.Errorf("workflows after restore = %v", got)
+	}
+	var events int
+	_ = dstDB.QueryRow("SELECT COUNT(*) FROM events").Scan(&events)
+	if events != 2 {
+		t.Errorf("events after restore = %d, want 2", events)
+	}
+}
+
+func TestCreateRestore_IncrementalEmptiedTable(t *testing.T) {
+	ctx := context.Background()
+	src, srcDB := openTestDB(t)
+	mustExec(t, srcDB, "INSERT INTO workflows (id, source, updated_at) VALUES (?, ?, ?)", "last", []byte("v1"), stamp(time.Now().Add(-time.Hour)))
+	base, err := src.Create(ctx, Options{})
+	if err != nil {
+		t.Fatalf("Create base: %v", err)
+	}
+
+	mustExec(t, srcDB, "DELETE FROM workflows")
+	inc, err := src.Create(ctx, Options{Since: base.CreatedAt})
+	if err != nil {
+		t.Fatalf("Create incremental: %v", err)
+	}
+
+	dst, dstDB := openTestDB(t)
+	if _, err := dst.Restore(ctx, roundTrip(t, base), roundTrip(t, inc)); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	var workflows int
+	_ = dstDB.QueryRow("SELECT COUNT(*) FROM workflows").Scan(&workflows)
+	if workflows != 0 {
+		t.Errorf("workflows after restore = %d, want the last one deleted", workflows)
+	}
+}
+
+const stateSchema = `
+CREATE TABLE workflow_state (
+	namespace TEXT NOT NULL,
+	state_key TEXT NOT NULL,
+	value TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	PRIMARY KEY (namespace, state_key)
+);`
+
+func TestCreateRestore_IncrementalCompositeKey(t *testing.T) {
+	ctx := context.Background()
+	src, srcDB := openTestDB(t)
+	mustExec(t, srcDB, stateSchema)
+	old := time.Now().Add(-time.Hour)
+	for _, key := range []string{"count", "cursor"} {
+		mustExec(t, srcDB, "INSERT INTO workflow_state (namespace, state_key, value, updated_at) VALUES (?, ?, ?, ?)", "wf", key, "1", stamp(old))
+	}
+	base, err := src.Create(ctx, Options{})
+	if err != nil {
+		t.Fatalf("Create base: %v", err)
+	}
+
+	mustExec(t, srcDB, "DELETE FROM workflow_state WHERE state_key = 'cursor'")
+	mustExec(t, srcDB, "UPDATE workflow_state SET value = '2', updated_at = ? WHERE state_key = 'count'", stamp(base.CreatedAt.Add(time.Second)))
+	inc, err := src.Create(ctx, Options{Since: base.CreatedAt})
+	if err != nil {
+		t.Fatalf("Create incremental: %v", err)
+	}
+
+	dst, dstDB := openTestDB(t)
+	mustExec(t, dstDB, stateSchema)
+	if _, err := dst.Restore(ctx, roundTrip(t, base), roundTrip(t, inc)); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	var keys int
+	var value string
+	_ = dstDB.QueryRow("SELECT COUNT(*) FROM workflow_state").Scan(&keys)
+	_ = dstDB.QueryRow("SELECT value FROM workflow_state WHERE namespace = 'wf' AND state_key = 'count'").Scan(&value)
+	if keys != 1 || value != "2" {
+		t.Errorf("workflow_state after restore: %d rows, count = %q", keys, value)
+	}
+}
+
+const outboxSchema = `
+CREATE TABLE outbox_deliveries (
+	id TEXT PRIMARY KEY,
+	status TEXT NOT NULL,
+	body BLOB NOT NULL,
+	next_attempt_at INTEGER NOT NULL,
+	updated_at TEXT NOT NULL
+);`
+
+func TestCreateRestore_OutboxDeliveries(t *testing.T) {
+	ctx := context.Background()
+	src, srcDB := openTestDB(t)
+	mustExec(t, srcDB, outboxSchema)
+	now := time.Now()
+	mustExec(t, srcDB, "INSERT INTO outbox_deliveries (id, status, body, next_attempt_at, updated_at) VALUES (?, ?, ?, ?, ?)", "d1", "pending", []byte(`{"a":1}`), now.Unix(), stamp(now.Add(-time.Hour)))
+	base, err := src.Create(ctx, Options{})
+	if err != nil {
+		t.Fatalf("Create base: %v", err)
+	}
+	mustExec(t, srcDB, "INSERT INTO outbox_deliveries (id, status, body, next_attempt_at, updated_at) VALUES (?, ?, ?, ?, ?)", "d2", "pending", []byte(`{"b":2}`), now.Unix(), stamp(base.CreatedAt.Add(time.Second)))
+	inc, err := src.Create(ctx, Options{Since: base.CreatedAt})
+	if err != nil {
+		t.Fatalf("Create incremental: %v", err)
+	}
+
+	dst, dstDB := openTestDB(t)
+	mustExec(t, dstDB, outboxSchema)
+	if _, err := dst.Restore(ctx, roundTrip(t, base), roundTrip(t, inc)); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	var pending int
+	var body []byte
+	_ = dstDB.QueryRow("SELECT COUNT(*) FROM outbox_deliveries WHERE status = 'pending'").Scan(&pending)
+	_ = dstDB.QueryRow("SELECT body FROM outbox_deliveries WHERE id = 'd2'").Scan(&body)
+	if pending != 2 || string(body) != `{"b":2}` {
+		t.Errorf("outbox_deliveries after restore: %d pending, d2 body %q", pending, body)
+	}
+}
+
+const auditSchema = `
+CREATE TABLE audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	at TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	payload BLOB NOT NULL
+);
+CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit log is append-only');
+END;
+CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit log is append-only');
+END;`
+
+func TestCreateRestore_AuditLogIsAppendOnly(t *testing.T) {
+	ctx := context.Background()
+	src, srcDB := openTestDB(t)
+	mustExec(t, srcDB, auditSchema)
+	old := time.Now().Add(-time.Hour)
+	mustExec(t, srcDB, "INSERT INTO audit_log (at, actor, payload) VALUES (?, ?, ?)", stamp(old), "alice", []byte(`{"op":"create"}`))
+	base, err := src.Create(ctx, Options{})
+	if err != nil {
+		t.Fatalf("Create base: %v", err)
+	}
+	mustExec(t, srcDB, "INSERT INTO audit_log (at, actor, payload) VALUES (?, ?, ?)", stamp(base.CreatedAt.Add(time.Second)), "bob", []byte(`{"op":"delete"}`))
+	inc, err := src.Create(ctx, Options{Since: base.CreatedAt})
+	if err != nil {
+		t.Fatalf("Create incremental: %v", err)
+	}
+
+	// The new host already audited a request of its own.
+	dst, dstDB := openTestDB(t)
+	mustExec(t, dstDB, auditSchema)
+	mustExec(t, dstDB, "INSERT INTO audit_log (at, actor, payload) VALUES (?, ?, ?)", stamp(time.Now()), "carol", []byte(`{"op":"restore"}`))
+	for range 2 {
+		if _, err := dst.Restore(ctx, roundTrip(t, base), roundTrip(t, inc)); err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+	}
+	rows, err := dstDB.Query("SELECT actor FROM audit_log ORDER BY actor")
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	defer rows.Close()
+	var actors []string
+	for rows.Next() {
+		var actor string
+		if err := rows.Scan(&actor); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		actors = append(actors, actor)
+	}
+	if strings.Join(actors, ",") != "alice,bob,carol" {
+		t.Errorf("audit_log actors after restoring twice = %v, want alice,bob,carol", actors)
+	}
+}
+
+func TestRestore_RejectsFullSnapshotAfterFirst(t *testing.T) {
+	m, _ := openTestDB(t)
+	full := &Snapshot{Format: Format}
+	if _, err := m.Restore(context.Background(), full, full); err == nil {
+		t.Fatal("expected error for a second full snapshot")
+	}
+	if _, err := m.Restore(context.Background(), &Snapshot{Format: "other"}); err == nil {
+		t.Fatal("expected error for an unknown format")
+	}
+}
+
+func TestRead_AcceptsPlainJSON(t *testing.T) {
+	snap, err := Read(bytes.NewBufferString(`{"format":"petalflow.backup/v1","created_at":"2026-01-02T03:04:05Z","tables":[{"name":"workflows","columns":["seq","source"],"rows":[[7,{"$bytes":"aGk="}]]}]}`))
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	row := snap.Tables[0].Rows[0]
+	if row[0] != int64(7) {
+		t.Errorf("seq = %#v, want int64 7", row[0])
+	}
+	if b, ok := row[1].([]byte); !ok || string(b) != "hi" {
+		t.Errorf("source = %#v, want []byte(\"hi\")", row[1])
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
@@ -172,6 +175,205 @@ func (s *SQLiteEventStore) RunIDs(ctx context.Context) ([]string, error) {
 	return ids, rows.Err()
 }
 
+// WorkflowEvents returns events of the given kinds (all kinds when none are
+// given) for runs of workflowID whose run.started event was recorded at or
+// after since. Runs are matched on the run.started "workflow_id" payload.
+// Events are ordered by run and sequence.
+func (s *SQLiteEventStore) WorkflowEvents(ctx context.Context, workflowID string, since time.Time, kinds ...runtime.EventKind) ([]runtime.Event, error) {
+	return s.runEvents(ctx, " AND json_extract(payload, '$.workflow_id') = ?", []any{workflowID}, since, kinds)
+}
+
+// EventsSince returns events of the given kinds (all kinds when none are
+// given) for every run whose run.started event was recorded at or after
+// since, ordered by run and sequence.
+func (s *SQLiteEventStore) EventsSince(ctx context.Context, since time.Time, kinds ...runtime.EventKind) ([]runtime.Event, error) {
+	return s.runEvents(ctx, "", nil, since, kinds)
+}
+
+// runEvents selects the events of the runs whose run.started event was
+// recorded at or after since and matches startFilter.
+func (s *SQLiteEventStore) runEvents(ctx context.Context, startFilter string, filterArgs []any, since time.Time, kinds []runtime.EventKind) ([]runtime.Event, error) {
+	query := `SELECT run_id, seq, kind, node_id, node_kind, time, attempt, elapsed, payload, trace_id, span_id
+	           FROM events WHERE run_id IN (
+	               SELECT run_id FROM events
+	               WHERE kind = ? AND time >= ?` + startFilter + `
+	           )`
+	args := append([]any{string(runtime.EventRunStarted), since.Format(time.RFC3339Nano)}, filterArgs...)
+
+	if len(kinds) > 0 {
+		query += " AND kind IN (?" + strings.Repeat(", ?", len(kinds)-1) + ")"
+		for _, kind := range kinds {
+			args = append(args, string(kind))
+		}
+	}
+	query += " ORDER BY run_id, seq ASC"
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlitestore: run events: %w", err)
+	}
+	defer rows.Close()
+
+	return scanEvents(rows)
+}
+
+// RunQuery selects runs by their run.started and run.finished events.
+type RunQuery struct {
+	// WorkflowID matches the run.started "workflow_id" payload.
+	WorkflowID string
+	// Status is "completed", "failed" or "running" (no run.finished event
+	// yet). Empty matches any status.
+	Status string
+	// Since and Until bound the run.started time: at or after Since and
+	// before Until. Zero values leave the bound open.
+	Since, Until time.Time
+	// After is the Cursor of the last run of the previous page.
+	After string
+	// Limit caps the number of runs returned (0 means no limit).
+	Limit int
+}
+
+// RunInfo summarizes a run from its run.started and run.finished events.
+type RunInfo struct {
+	RunID      string
+	WorkflowID string
+	Trigger    string
+	// Status is "completed", "failed" or "running".
+	Status     string
+	Error      string
+	StartedAt  time.Time
+	FinishedAt *time.Time
+	// SLABreaches names the workflow SLAs a finished run breached. It is
+	// nil when the run tracked no SLA and empty when it met them all.
+	SLABreaches []string
+	// Priority and Deadline are the caller's hints. Deadline is nil for
+	// runs without one.
+	Priority int
+	Deadline *time.Time
+	// DeadlineMet reports whether a finished run with a deadline completed
+	// by it. It is nil otherwise.
+	DeadlineMet *bool
+	// Cursor resumes a RunQuery after this run.
+	Cursor string
+}
+
+// ErrInvalidCursor is returned for a RunQuery.After the store did not
+// produce.
+var ErrInvalidCursor = errors.New("sqlitestore: invalid run cursor")
+
+// Runs returns the runs matching q ordered by start time and run ID.
+func (s *SQLiteEventStore) Runs(ctx context.Context, q RunQuery) ([]RunInfo, error) {
+	query := `SELECT st.run_id, st.time, st.payload, fin.time, fin.payload
+	           FROM events st
+	           LEFT JOIN events fin ON fin.run_id = st.run_id AND fin.kind = ?
+	           WHERE st.kind = ?`
+	args := []any{string(runtime.EventRunFinished), string(runtime.EventRunStarted)}
+
+	if q.WorkflowID != "" {
+		query += " AND json_extract(st.payload, '$.workflow_id') = ?"
+		args = append(args, q.WorkflowID)
+	}
+	switch q.Status {
+	case "":
+	case "running":
+		query += " AND fin.id IS NULL"
+	default:
+		query += " AND json_extract(fin.payload, '$.status') = ?"
+		args = append(args, q.Status)
+	}
+	if !q.Since.IsZero() {
+		query += " AND st.time >= ?"
+		args = append(args, q.Since.Format(time.RFC3339Nano))
+	}
+	if !q.Until.IsZero() {
+		query += " AND st.time < ?"
+		args = append(args, q.Until.Format(time.RFC3339Nano))
+	}
+	if q.After != "" {
+		afterTime, afterRunID, err := decodeRunCursor(q.After)
+		if err != nil {
+			return nil, err
+		}
+		query += " AND (st.time > ? OR (st.time = ? AND st.run_id > ?))"
+		args = append(args, afterTime, afterTime, afterRunID)
+	}
+	query += " ORDER BY st.time, st.run_id"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlitestore: runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []RunInfo
+	for rows.Next() {
+		var (
+			run                     RunInfo
+			startedAt, startPayload string
+			finishedAt, finPayload  sql.NullString
+		)
+		if err := rows.Scan(&run.RunID, &startedAt, &startPayload, &finishedAt, &finPayload); err != nil {
+			return nil, fmt.Errorf("sqlitestore: scan run: %w", err)
+		}
+		if run.StartedAt, err = time.Parse(time.RFC3339Nano, startedAt); err != nil {
+			return nil, fmt.Errorf("sqlitestore: parse time %q: %w", startedAt, err)
+		}
+		var started struct {
+			WorkflowID string     `json:"workflow_id"`
+			Trigger    string     `json:"trigger"`
+			Priority   int        `json:"priority"`
+			Deadline   *time.Time `json:"deadline"`
+		}
+		_ = json.Unmarshal([]byte(startPayload), &started)
+		run.WorkflowID, run.Trigger = started.WorkflowID, started.Trigger
+		run.Priority, run.Deadline = started.Priority, started.Deadline
+
+		run.Status = "running"
+		if finishedAt.Valid {
+			t, err := time.Parse(time.RFC3339Nano, finishedAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("sqlitestore: parse time %q: %w", finishedAt.String, err)
+			}
+			run.FinishedAt = &t
+			var finished struct {
+				Status      string   `json:"status"`
+				Error       string   `json:"error"`
+				SLABreaches []string `json:"sla_breaches"`
+				DeadlineMet *bool    `json:"deadline_met"`
+			}
+			_ = json.Unmarshal([]byte(finPayload.String), &finished)
+			run.Status, run.Error = finished.Status, finished.Error
+			run.SLABreaches = finished.SLABreaches
+			run.DeadlineMet = finished.DeadlineMet
+		}
+		run.Cursor = encodeRunCursor(startedAt, run.RunID)
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+// encodeRunCursor keeps the stored start time verbatim, so the next page
+// compares against exactly the value the rows are ordered by.
+func encodeRunCursor(startedAt, runID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(startedAt + "\x00" + runID))
+}
+
+func decodeRunCursor(cursor string) (startedAt, runID string, err error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", "", ErrInvalidCursor
+	}
+	startedAt, runID, ok := strings.Cut(string(raw), "\x00")
+	if !ok {
+		return "", "", ErrInvalidCursor
+	}
+	return startedAt, runID, nil
+}
+
 // Close stops the background pruner and closes the database connection.
 func (s *SQLiteEventStore) Close() error {
 	select {
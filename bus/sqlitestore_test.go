@@ -2,7 +2,9 @@ package bus
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -254,8 +256,152 @@ func TestSQLiteEventStore_RunIsolation(t *testing.T) {
 	}
 }
 
+func TestSQLiteEventStore_WorkflowEvents(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	started := func(runID, workflowID string, at time.Time) runtime.Event {
+		e := makeEvent(runID, 1, runtime.EventRunStarted)
+		e.Time = at
+		e.Payload = map[string]any{"workflow_id": workflowID}
+		return e
+	}
+	store.Append(ctx, started("run-1", "wf", now))
+	store.Append(ctx, makeEvent("run-1", 2, runtime.EventNodeStarted))
+	store.Append(ctx, makeEvent("run-1", 3, runtime.EventRunFinished))
+	store.Append(ctx, started("run-2", "other", now))
+	store.Append(ctx, makeEvent("run-2", 2, runtime.EventRunFinished))
+	store.Append(ctx, started("run-3", "wf", now.Add(-48*time.Hour)))
+	store.Append(ctx, makeEvent("run-3", 2, runtime.EventRunFinished))
+
+	events, err := store.WorkflowEvents(ctx, "wf", now.Add(-time.Hour), runtime.EventRunStarted, runtime.EventRunFinished)
+	if err != nil {
+		t.Fatalf("WorkflowEvents: %v", err)
+	}
+	if len(events) != 2 || events[0].Kind != runtime.EventRunStarted || events[1].Kind != runtime.EventRunFinished {
+		t.Fatalf("events = %+v, want run-1 started and finished", events)
+	}
+	for _, e := range events {
+		if e.RunID != "run-1" {
+			t.Errorf("unexpected run %q", e.RunID)
+		}
+	}
+
+	events, _ = store.WorkflowEvents(ctx, "wf", now.Add(-72*time.Hour))
+	if len(events) != 5 {
+		t.Errorf("all kinds over 72h = %d events, want 5", len(events))
+	}
+}
+
+func TestSQLiteEventStore_Runs(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	add := func(runID, workflowID string, at time.Time, status string) {
+		started := makeEvent(runID, 1, runtime.EventRunStarted)
+		started.Time = at
+		started.Payload = map[string]any{"workflow_id": workflowID, "trigger": "webhook"}
+		store.Append(ctx, started)
+		if status == "" {
+			return
+		}
+		finished := makeEvent(runID, 2, runtime.EventRunFinished)
+		finished.Time = at.Add(time.Second)
+		finished.Payload = map[string]any{"status": status}
+		store.Append(ctx, finished)
+	}
+	add("run-c", "wf", base, "completed")
+	add("run-a", "wf", base.Add(time.Minute), "failed")
+	add("run-b", "wf", base.Add(time.Minute), "completed")
+	add("run-d", "other", base.Add(2*time.Minute), "")
+	add("run-e", "wf", base.Add(-time.Hour), "completed")
+
+	ids := func(runs []RunInfo) string {
+		var out []string
+		for _, r := range runs {
+			out = append(out, r.RunID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	runs, err := store.Runs(ctx, RunQuery{Since: base})
+	if err != nil {
+		t.Fatalf("Runs: %v", err)
+	}
+	if got := ids(runs); got != "run-c,run-a,run-b,run-d" {
+		t.Fatalf("runs = %s", got)
+	}
+	if r := runs[0]; r.WorkflowID != "wf" || r.Trigger != "webhook" || r.Status != "completed" ||
+		!r.StartedAt.Equal(base) || r.FinishedAt == nil || !r.FinishedAt.Equal(base.Add(time.Second)) {
+		t.Errorf("run-c = %+v", r)
+	}
+	if r := runs[3]; r.Status != "running" || r.FinishedAt != nil {
+		t.Errorf("run-d = %+v, want running", r)
+	}
+	if runs[0].SLABreaches != nil {
+		t.Errorf("run-c SLA breaches = %v, want nil without an SLA", runs[0].SLABreaches)
+	}
+
+	for name, tc := range map[string]struct {
+		q    RunQuery
+		want string
+	}{
+		"workflow": {RunQuery{WorkflowID: "other"}, "run-d"},
+		"status":   {RunQuery{Status: "completed"}, "run-e,run-c,run-b"},
+		"running":  {RunQuery{Status: "running"}, "run-d"},
+		"until":    {RunQuery{Until: base.Add(time.Minute)}, "run-e,run-c"},
+	} {
+		runs, err := store.Runs(ctx, tc.q)
+		if err != nil || ids(runs) != tc.want {
+			t.Errorf("%s: runs = %s, %v; want %s", name, ids(runs), err, tc.want)
+		}
+	}
+
+	// Paging with the cursor visits every run once, ties broken by run ID.
+	var paged []string
+	q := RunQuery{Limit: 2}
+	for {
+		page, err := store.Runs(ctx, q)
+		if err != nil {
+			t.Fatalf("Runs page: %v", err)
+		}
+		paged = append(paged, ids(page))
+		if len(page) < q.Limit {
+			break
+		}
+		q.After = page[len(page)-1].Cursor
+	}
+	if got := strings.Join(paged, "|"); got != "run-e,run-c|run-a,run-b|run-d" {
+		t.Errorf("pages = %s", got)
+	}
+
+	if _, err := store.Runs(ctx, RunQuery{After: "!!"}); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("bad cursor err = %v, want ErrInvalidCursor", err)
+	}
+}
+
 // --- Retention pruning: age-based ---
 
+func TestSQLiteEventStore_Runs_SLABreaches(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	store.Append(ctx, makeEvent("run-sla", 1, runtime.EventRunStarted))
+	finished := makeEvent("run-sla", 2, runtime.EventRunFinished)
+	finished.Payload = map[string]any{"status": "completed", "sla_breaches": []string{"max_duration"}}
+	store.Append(ctx, finished)
+
+	runs, err := store.Runs(ctx, RunQuery{})
+	if err != nil {
+		t.Fatalf("Runs: %v", err)
+	}
+	if len(runs) != 1 || len(runs[0].SLABreaches) != 1 || runs[0].SLABreaches[0] != "max_duration" {
+		t.Errorf("runs = %+v", runs)
+	}
+}
+
 func TestSQLiteEventStore_PruneByAge(t *testing.T) {
 	dsn := testDSN(t)
 	store, err := NewSQLiteEventStore(SQLiteStoreConfig{
@@ -0,0 +1,394 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petal-labs/petalflow/backup"
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/server"
+	"github.com/petal-labs/petalflow/tool"
+)
+
+// NewAdminCmd creates the "admin" command group.
+func NewAdminCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "admin",
+		Short: "Back up and restore daemon state",
+	}
+	cmd.PersistentFlags().String("sqlite-path", "", "Path to SQLite database (default: $PETALFLOW_SQLITE_PATH or ~/.petalflow/petalflow.db)")
+
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Write a snapshot of workflows, schedules, tools and uploads",
+		Long: `Write a consistent snapshot of the daemon database. Tool secrets stay
+encrypted; restoring on another host needs the same PETALFLOW_SECRET_KEY.
+
+With --base, only rows changed since that earlier snapshot are written,
+along with enough to replay deletions. Restore incremental snapshots after
+their base, in order.`,
+		Args: cobra.NoArgs,
+		RunE: runAdminBackup,
+	}
+	backupCmd.Flags().StringP("output", "o", "", "Snapshot file to write (default: petalflow-<timestamp>.backup)")
+	backupCmd.Flags().Bool("events", false, "Include run history (events)")
+	backupCmd.Flags().String("base", "", "Earlier snapshot to write an incremental backup against")
+	backupCmd.Flags().String("since", "", "Write an incremental backup of changes after this RFC3339 time")
+	cmd.AddCommand(backupCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "restore <snapshot>...",
+		Short: "Restore daemon state from snapshots",
+		Long: `Restore one full snapshot, optionally followed by incremental snapshots
+in the order they were taken. Tables in the snapshot replace the current
+contents; tables it does not hold are left alone.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: runAdminRestore,
+	})
+	cmd.AddCommand(newAdminMaintenanceCmd())
+	cmd.AddCommand(newAdminLoggingCmd())
+	return cmd
+}
+
+func newAdminMaintenanceCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "maintenance",
+		Short: "Show or switch a running daemon's read-only mode and banner",
+		Long: `Read-only mode rejects workflow, schedule and other changes and refuses
+new runs, while workflows, run history and event streams stay readable.
+The switch lives in the daemon's memory; a restart returns to the
+configured state.`,
+	}
+	addDaemonFlag(cmd)
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show the maintenance state",
+		Args:  cobra.NoArgs,
+		RunE:  runAdminMaintenanceStatus,
+	})
+	for _, readOnly := range []bool{true, false} {
+		use, short := "on", "Switch the daemon to read-only mode"
+		if !readOnly {
+			use, short = "off", "Leave read-only mode"
+		}
+		sub := &cobra.Command{
+			Use:   use,
+			Short: short,
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, _ []string) error {
+				return runAdminMaintenanceSet(cmd, readOnly)
+			},
+		}
+		sub.Flags().String("message", "", "Message returned with rejected requests")
+		sub.Flags().String("banner", "", "Announcement banner to show in UIs")
+		sub.Flags().String("banner-level", "", "Banner level: info, warning or critical")
+		sub.Flags().Bool("clear-banner", false, "Remove the current banner")
+		cmd.AddCommand(sub)
+	}
+	return cmd
+}
+
+func runAdminMaintenanceStatus(cmd *cobra.Command, _ []string) error {
+	var state server.MaintenanceState
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), "/api/maintenance", &state); err != nil {
+		return exitError(exitRuntime, "reading maintenance state: %v", err)
+	}
+	printMaintenanceState(cmd, state)
+	return nil
+}
+
+// runAdminMaintenanceSet changes the read-only switch. The current banner
+// and message are kept unless flags replace them.
+func runAdminMaintenanceSet(cmd *cobra.Command, readOnly bool) error {
+	client := resolveDaemonClient(cmd)
+	var state server.MaintenanceState
+	if err := client.getJSON(cmd.Context(), "/api/maintenance", &state); err != nil {
+		return exitError(exitRuntime, "reading maintenance state: %v", err)
+	}
+	state.ReadOnly = readOnly
+	if cmd.Flags().Changed("message") {
+		state.Message, _ = cmd.Flags().GetString("message")
+	}
+	if clear, _ := cmd.Flags().GetBool("clear-banner"); clear {
+		state.Banner = nil
+	}
+	if cmd.Flags().Changed("banner") || cmd.Flags().Changed("banner-level") {
+		banner := server.Banner{}
+		if state.Banner != nil {
+			banner = *state.Banner
+		}
+		if cmd.Flags().Changed("banner") {
+			banner.Message, _ = cmd.Flags().GetString("banner")
+		}
+		if cmd.Flags().Changed("banner-level") {
+			banner.Level, _ = cmd.Flags().GetString("banner-level")
+		}
+		state.Banner = &banner
+	}
+	if err := state.Banner.Validate(); err != nil {
+		return exitError(exitInputParse, "%v", err)
+	}
+
+	var updated server.MaintenanceState
+	if err := client.doJSON(cmd.Context(), http.MethodPut, server.AdminMaintenancePath, state, &updated); err != nil {
+		return exitError(exitRuntime, "updating maintenance state: %v", err)
+	}
+	printMaintenanceState(cmd, updated)
+	return nil
+}
+
+func printMaintenanceState(cmd *cobra.Command, state server.MaintenanceState) {
+	out := cmd.OutOrStdout()
+	mode := "read-write"
+	if state.ReadOnly {
+		mode = "read-only"
+	}
+	fmt.Fprintf(out, "Mode:    %s\n", mode)
+	if state.ReadOnly && state.Message != "" {
+		fmt.Fprintf(out, "Message: %s\n", state.Message)
+	}
+	if state.Banner != nil {
+		fmt.Fprintf(out, "Banner:  [%s] %s\n", state.Banner.Level, state.Banner.Message)
+	}
+}
+
+func newAdminLoggingCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "logging",
+		Short: "Show or change a running daemon's log level and debug logging",
+		Long: `Change the log level, log node input and output snapshots of a
+workflow's runs, or log LLM requests and responses, without restarting the
+daemon. Debug logging turns itself off after the given duration, at most
+24h; a duration of 0 turns it off now. The settings live in the daemon's
+memory; a restart turns debug logging off.`,
+	}
+	addDaemonFlag(cmd)
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show the log level and debug logging",
+		Args:  cobra.NoArgs,
+		RunE:  runAdminLoggingStatus,
+	})
+	set := &cobra.Command{
+		Use:   "set",
+		Short: "Change the log level or debug logging",
+		Example: `  petalflow admin logging set --level debug
+  petalflow admin logging set --debug-workflow support=15m --llm-traffic 10m
+  petalflow admin logging set --debug-workflow support=0 --llm-traffic 0`,
+		Args: cobra.NoArgs,
+		RunE: runAdminLoggingSet,
+	}
+	set.Flags().String("level", "", "Log level: debug, info, warn or error")
+	set.Flags().StringArray("debug-workflow", nil, "Log node snapshots of a workflow's runs for a while, as id=duration (repeatable)")
+	set.Flags().String("llm-traffic", "", "Log LLM requests and responses for this long")
+	cmd.AddCommand(set)
+	return cmd
+}
+
+func runAdminLoggingStatus(cmd *cobra.Command, _ []string) error {
+	var state server.LoggingState
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), server.AdminLoggingPath, &state); err != nil {
+		return exitError(exitRuntime, "reading logging state: %v", err)
+	}
+	printLoggingState(cmd, state)
+	return nil
+}
+
+func runAdminLoggingSet(cmd *cobra.Command, _ []string) error {
+	var update server.LoggingUpdate
+	update.Level, _ = cmd.Flags().GetString("level")
+	update.LLMTraffic, _ = cmd.Flags().GetString("llm-traffic")
+	debug, _ := cmd.Flags().GetStringArray("debug-workflow")
+	for _, entry := range debug {
+		id, window, ok := strings.Cut(entry, "=")
+		if !ok || id == "" || window == "" {
+			return exitError(exitInputParse, "--debug-workflow must be id=duration, got %q", entry)
+		}
+		if update.DebugWorkflows == nil {
+			update.DebugWorkflows = make(map[string]string)
+		}
+		update.DebugWorkflows[id] = window
+	}
+	if update.Level == "" && update.LLMTraffic == "" && len(update.DebugWorkflows) == 0 {
+		return exitError(exitInputParse, "nothing to change: pass --level, --debug-workflow or --llm-traffic")
+	}
+
+	var state server.LoggingState
+	if err := resolveDaemonClient(cmd).doJSON(cmd.Context(), http.MethodPut, server.AdminLoggingPath, update, &state); err != nil {
+		return exitError(exitRuntime, "updating logging state: %v", err)
+	}
+	printLoggingState(cmd, state)
+	return nil
+}
+
+func printLoggingState(cmd *cobra.Command, state server.LoggingState) {
+	out := cmd.OutOrStdout()
+	level := state.Level
+	if level == "" {
+		level = "fixed"
+	}
+	fmt.Fprintf(out, "Level:       %s\n", level)
+	if state.LLMTrafficUntil != nil {
+		fmt.Fprintf(out, "LLM traffic: on until %s\n", state.LLMTrafficUntil.Local().Format(time.RFC3339))
+	} else {
+		fmt.Fprintf(out, "LLM traffic: off\n")
+	}
+	for _, wf := range state.DebugWorkflows {
+		fmt.Fprintf(out, "Debug:       %s until %s\n", wf.WorkflowID, wf.Until.Local().Format(time.RFC3339))
+	}
+}
+
+func adminSQLiteDSN(cmd *cobra.Command) (string, error) {
+	sqlitePath, _ := cmd.Flags().GetString("sqlite-path")
+	if sqlitePath == "" {
+		sqlitePath = os.Getenv("PETALFLOW_SQLITE_PATH")
+	}
+	dsn, _, err := resolveServeSQLiteDSN(sqlitePath)
+	return dsn, err
+}
+
+func runAdminBackup(cmd *cobra.Command, _ []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	events, _ := cmd.Flags().GetBool("events")
+	basePath, _ := cmd.Flags().GetString("base")
+	sinceRaw, _ := cmd.Flags().GetString("since")
+	if basePath != "" && sinceRaw != "" {
+		return exitError(exitInputParse, "--base and --since are mutually exclusive")
+	}
+
+	opts := backup.Options{IncludeEvents: events}
+	switch {
+	case basePath != "":
+		base, err := readSnapshotFile(basePath)
+		if err != nil {
+			return err
+		}
+		opts.Since = base.CreatedAt
+	case sinceRaw != "":
+		since, err := time.Parse(time.RFC3339Nano, sinceRaw)
+		if err != nil {
+			return exitError(exitInputParse, "--since must be an RFC3339 timestamp: %v", err)
+		}
+		opts.Since = since
+	}
+
+	dsn, err := adminSQLiteDSN(cmd)
+	if err != nil {
+		return err
+	}
+	m, err := backup.Open(dsn)
+	if err != nil {
+		return exitError(exitRuntime, "opening database: %v", err)
+	}
+	defer func() {
+		_ = m.Close()
+	}()
+
+	snap, err := m.Create(cmd.Context(), opts)
+	if err != nil {
+		return exitError(exitRuntime, "creating backup: %v", err)
+	}
+	if output == "" {
+		output = "petalflow-" + snap.CreatedAt.Format("20060102T150405Z") + ".backup"
+	}
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return exitError(exitRuntime, "creating %s: %v", output, err)
+	}
+	if err := backup.Write(f, snap); err != nil {
+		_ = f.Close()
+		return exitError(exitRuntime, "writing %s: %v", output, err)
+	}
+	if err := f.Close(); err != nil {
+		return exitError(exitRuntime, "writing %s: %v", output, err)
+	}
+
+	rows := 0
+	for _, t := range snap.Tables {
+		rows += len(t.Rows)
+	}
+	kind := "full"
+	if snap.Incremental() {
+		kind = "incremental"
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Wrote %s backup to %s (%d row(s) in %d table(s))\n", kind, output, rows, len(snap.Tables))
+	return nil
+}
+
+func runAdminRestore(cmd *cobra.Command, args []string) error {
+	snaps := make([]*backup.Snapshot, 0, len(args))
+	for _, path := range args {
+		snap, err := readSnapshotFile(path)
+		if err != nil {
+			return err
+		}
+		snaps = append(snaps, snap)
+	}
+
+	dsn, err := adminSQLiteDSN(cmd)
+	if err != nil {
+		return err
+	}
+	if err := ensureDaemonSchema(dsn); err != nil {
+		return exitError(exitRuntime, "preparing database: %v", err)
+	}
+	m, err := backup.Open(dsn)
+	if err != nil {
+		return exitError(exitRuntime, "opening database: %v", err)
+	}
+	defer func() {
+		_ = m.Close()
+	}()
+
+	result, err := m.Restore(cmd.Context(), snaps...)
+	if err != nil {
+		return exitError(exitRuntime, "restoring: %v", err)
+	}
+	out := cmd.OutOrStdout()
+	for _, t := range result.Tables {
+		fmt.Fprintf(out, "%s: %d restored, %d deleted\n", t.Name, t.Restored, t.Deleted)
+	}
+	fmt.Fprintf(out, "Restored %d snapshot(s)\n", len(snaps))
+	return nil
+}
+
+func readSnapshotFile(path string) (*backup.Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, exitError(exitFileNotFound, "snapshot not found: %s", path)
+		}
+		return nil, exitError(exitRuntime, "opening %s: %v", path, err)
+	}
+	defer f.Close()
+	snap, err := backup.Read(f)
+	if err != nil {
+		return nil, exitError(exitInputParse, "%s: %v", path, err)
+	}
+	return snap, nil
+}
+
+// ensureDaemonSchema creates the daemon tables so a restore into a fresh
+// database has somewhere to write.
+func ensureDaemonSchema(dsn string) error {
+	workflows, err := server.NewSQLiteStore(server.SQLiteStoreConfig{DSN: dsn})
+	if err != nil {
+		return err
+	}
+	_ = workflows.Close()
+	tools, err := tool.NewSQLiteStore(tool.SQLiteStoreConfig{DSN: dsn})
+	if err != nil {
+		return err
+	}
+	_ = tools.Close()
+	events, err := bus.NewSQLiteEventStore(bus.SQLiteStoreConfig{DSN: dsn})
+	if err != nil {
+		return err
+	}
+	return events.Close()
+}
@@ -0,0 +1,175 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/loader"
+	"github.com/petal-labs/petalflow/server"
+)
+
+func TestAdminBackupRestore(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "src.db")
+	dstPath := filepath.Join(dir, "dst.db")
+	snapPath := filepath.Join(dir, "full.backup")
+	incPath := filepath.Join(dir, "inc.backup")
+
+	store, err := server.NewSQLiteStore(server.SQLiteStoreConfig{DSN: srcPath})
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	defer store.Close()
+	createWorkflow := func(id string) {
+		t.Helper()
+		gd := &graph.GraphDefinition{ID: id, Version: "1.0", Nodes: []graph.NodeDef{{ID: "a", Type: "noop"}}, Entry: "a"}
+		source, _ := json.Marshal(gd)
+		now := time.Now().UTC()
+		if err := store.Create(context.Background(), server.WorkflowRecord{
+			ID: id, SchemaKind: loader.SchemaKindGraph, Source: source, Compiled: gd, CreatedAt: now, UpdatedAt: now,
+		}); err != nil {
+			t.Fatalf("Create %s: %v", id, err)
+		}
+	}
+	createWorkflow("first")
+
+	admin := func(args ...string) string {
+		t.Helper()
+		root := &cobra.Command{Use: "petalflow", SilenceUsage: true}
+		root.AddCommand(NewAdminCmd())
+		stdout, _, err := executeCommand(root, append([]string{"admin"}, args...)...)
+		if err != nil {
+			t.Fatalf("admin %v: %v", args, err)
+		}
+		return stdout
+	}
+
+	if out := admin("backup", "--sqlite-path", srcPath, "-o", snapPath); !strings.Contains(out, "Wrote full backup") {
+		t.Fatalf("backup output = %q", out)
+	}
+	createWorkflow("second")
+	if out := admin("backup", "--sqlite-path", srcPath, "-o", incPath, "--base", snapPath); !strings.Contains(out, "Wrote incremental backup") {
+		t.Fatalf("incremental backup output = %q", out)
+	}
+	if out := admin("restore", "--sqlite-path", dstPath, snapPath, incPath); !strings.Contains(out, "Restored 2 snapshot(s)") {
+		t.Fatalf("restore output = %q", out)
+	}
+
+	restored, err := server.NewSQLiteStore(server.SQLiteStoreConfig{DSN: dstPath})
+	if err != nil {
+		t.Fatalf("open restored store: %v", err)
+	}
+	defer restored.Close()
+	records, err := restored.List(context.Background())
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("restored %d workflows, want 2", len(records))
+	}
+	for _, rec := range records {
+		if rec.Compiled == nil || rec.Compiled.Entry != "a" {
+			t.Errorf("workflow %s not restored intact: %+v", rec.ID, rec.Compiled)
+		}
+	}
+}
+
+func TestAdminRestore_MissingSnapshot(t *testing.T) {
+	root := &cobra.Command{Use: "petalflow", SilenceUsage: true}
+	root.AddCommand(NewAdminCmd())
+	_, _, err := executeCommand(root, "admin", "restore", "--sqlite-path", filepath.Join(t.TempDir(), "db"), "missing.backup")
+	if err == nil || !strings.Contains(err.Error(), "snapshot not found") {
+		t.Fatalf("err = %v, want snapshot not found", err)
+	}
+}
+
+func TestAdminMaintenance(t *testing.T) {
+	srv := server.NewServer(server.ServerConfig{AllowAdmin: true})
+	daemon := httptest.NewServer(srv.Handler())
+	t.Cleanup(daemon.Close)
+
+	maintenance := func(args ...string) string {
+		t.Helper()
+		root := newTestRoot()
+		root.AddCommand(NewAdminCmd())
+		stdout, _, err := executeCommand(root, append([]string{"admin", "maintenance"}, append(args, "--daemon", daemon.URL)...)...)
+		if err != nil {
+			t.Fatalf("admin maintenance %v: %v", args, err)
+		}
+		return stdout
+	}
+
+	out := maintenance("on", "--message", "migrating", "--banner", "Back at 10:00", "--banner-level", "warning")
+	if !strings.Contains(out, "Mode:    read-only") || !strings.Contains(out, "[warning] Back at 10:00") {
+		t.Fatalf("on output = %q", out)
+	}
+	if state := srv.Maintenance(); !state.ReadOnly || state.Message != "migrating" {
+		t.Fatalf("state = %+v", state)
+	}
+
+	out = maintenance("off")
+	if !strings.Contains(out, "Mode:    read-write") || !strings.Contains(out, "Back at 10:00") {
+		t.Fatalf("off output = %q, want banner kept", out)
+	}
+
+	out = maintenance("off", "--clear-banner")
+	if strings.Contains(out, "Banner") {
+		t.Fatalf("banner not cleared: %q", out)
+	}
+	if out := maintenance("status"); !strings.Contains(out, "read-write") {
+		t.Fatalf("status output = %q", out)
+	}
+}
+
+func TestAdminLogging(t *testing.T) {
+	level := new(slog.LevelVar)
+	srv := server.NewServer(server.ServerConfig{LogLevel: level, AllowAdmin: true})
+	daemon := httptest.NewServer(srv.Handler())
+	t.Cleanup(daemon.Close)
+
+	logging := func(args ...string) (string, error) {
+		t.Helper()
+		root := newTestRoot()
+		root.AddCommand(NewAdminCmd())
+		stdout, _, err := executeCommand(root, append([]string{"admin", "logging"}, append(args, "--daemon", daemon.URL)...)...)
+		return stdout, err
+	}
+
+	out, err := logging("set", "--level", "debug", "--debug-workflow", "support=15m", "--llm-traffic", "10m")
+	if err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if !strings.Contains(out, "Level:       debug") || !strings.Contains(out, "Debug:       support until") || !strings.Contains(out, "LLM traffic: on until") {
+		t.Fatalf("set output = %q", out)
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Errorf("level = %v, want debug", level.Level())
+	}
+
+	if _, err := logging("set", "--debug-workflow", "support"); err == nil {
+		t.Error("set accepted --debug-workflow without a duration")
+	}
+	if _, err := logging("set"); err == nil {
+		t.Error("set accepted no changes")
+	}
+
+	if _, err := logging("set", "--debug-workflow", "support=0", "--llm-traffic", "0"); err != nil {
+		t.Fatalf("set off: %v", err)
+	}
+	out, err = logging("status")
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if !strings.Contains(out, "LLM traffic: off") || strings.Contains(out, "Debug:") {
+		t.Fatalf("status output = %q", out)
+	}
+}
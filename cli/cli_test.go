@@ -3,11 +3,13 @@ package cli
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/petal-labs/petalflow/graph"
 	"github.com/petal-labs/petalflow/nodes"
 	"github.com/spf13/cobra"
 )
@@ -22,6 +24,7 @@ func newTestRoot() *cobra.Command {
 	root.AddCommand(NewRunCmd())
 	root.AddCommand(NewCompileCmd())
 	root.AddCommand(NewValidateCmd())
+	root.AddCommand(NewNewCmd())
 	root.AddCommand(NewToolsCmd())
 	return root
 }
@@ -157,6 +160,26 @@ func TestValidate_ValidGraphJSON(t *testing.T) {
 	}
 }
 
+func TestValidate_PolicyViolations(t *testing.T) {
+	workflow := writeTestFile(t, "workflow.json", `{
+  "id": "notifier",
+  "version": "1.0",
+  "nodes": [{"id": "notify", "type": "webhook_call", "config": {"url": "https://hooks.evil.example/x"}}],
+  "edges": [],
+  "entry": "notify"
+}`)
+	policyPath := writeTestFile(t, "policy.yaml", "allowed_hosts: [\"*.corp.example.com\"]\n")
+
+	root := newTestRoot()
+	stdout, _, err := executeCommand(root, "validate", workflow, "--policy", policyPath)
+	if err == nil {
+		t.Fatal("expected the policy violation to fail validation")
+	}
+	if !strings.Contains(stdout, "POL-001") {
+		t.Errorf("expected POL-001 in output, got: %q", stdout)
+	}
+}
+
 func TestValidate_ValidAgentYAML(t *testing.T) {
 	yaml := `version: "1.0"
 kind: agent_workflow
@@ -199,6 +222,26 @@ func TestValidate_InvalidFile_ShowsDiagnostics(t *testing.T) {
 	}
 }
 
+func TestValidate_ShowsNodeDocs(t *testing.T) {
+	path := writeTestFile(t, "docs.json", `{
+  "id": "docs",
+  "version": "1.0",
+  "nodes": [
+    {"id": "a", "type": "noop"},
+    {"id": "notify", "type": "noop", "description": "Pages the on-call", "doc_url": "wiki/notify"}
+  ],
+  "edges": [{"source": "a", "sourceHandle": "output", "target": "notify", "targetHandle": "input"}],
+  "entry": "a"
+}`)
+	root := newTestRoot()
+	stdout, _, _ := executeCommand(root, "validate", path)
+	for _, want := range []string{"GR-015", "notify: Pages the on-call", "docs: wiki/notify"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q: %q", want, stdout)
+		}
+	}
+}
+
 func TestValidate_JSONFormat(t *testing.T) {
 	path := writeTestFile(t, "workflow.json", validGraphJSON)
 	root := newTestRoot()
@@ -280,6 +323,79 @@ func TestCompile_WrongSchemaKind(t *testing.T) {
 	}
 }
 
+const multiDocAgentYAML = `kind: agents
+id: shared
+agents:
+  researcher:
+    role: Researcher
+    goal: Find information
+    provider: anthropic
+    model: claude-sonnet-4-6
+---
+version: "1.0"
+kind: agent_workflow
+id: first
+name: First
+agents_ref: shared
+tasks:
+  research:
+    description: Research the topic
+    agent: researcher
+    expected_output: Summary of findings
+execution:
+  strategy: sequential
+  task_order: [research]
+---
+version: "1.0"
+kind: agent_workflow
+id: second
+name: Second
+agents_ref: shared
+tasks:
+  recap:
+    description: Recap the topic
+    agent: researcher
+    expected_output: Recap
+execution:
+  strategy: sequential
+  task_order: [recap]
+`
+
+func TestCompile_MultiDocument(t *testing.T) {
+	path := writeTestFile(t, "team.yaml", multiDocAgentYAML)
+
+	stdout, _, err := executeCommand(newTestRoot(), "compile", path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var gds []graph.GraphDefinition
+	if err := json.Unmarshal([]byte(stdout), &gds); err != nil || len(gds) != 2 || gds[1].ID != "second" {
+		t.Fatalf("expected a JSON array of both workflows, got: %q (%v)", stdout, err)
+	}
+
+	stdout, _, err = executeCommand(newTestRoot(), "compile", path, "--workflow", "second", "--format", "yaml")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !strings.Contains(stdout, "id: second") || strings.Contains(stdout, "id: first") {
+		t.Errorf("expected only the second workflow, got: %q", stdout)
+	}
+
+	stdout, _, err = executeCommand(newTestRoot(), "validate", path)
+	if err != nil || !strings.Contains(stdout, "Valid") {
+		t.Errorf("validate: %q, %v", stdout, err)
+	}
+
+	_, _, err = executeCommand(newTestRoot(), "run", path, "--dry-run")
+	if err == nil || !strings.Contains(err.Error(), "first, second") {
+		t.Errorf("run without --workflow: %v, want the workflow ids", err)
+	}
+	stdout, _, err = executeCommand(newTestRoot(), "run", path, "--dry-run", "--workflow", "first")
+	if err != nil || !strings.Contains(stdout, "successful") {
+		t.Errorf("run --workflow first: %q, %v", stdout, err)
+	}
+}
+
 func TestCompile_FileNotFound(t *testing.T) {
 	root := newTestRoot()
 	_, _, err := executeCommand(root, "compile", "/nonexistent/path.json")
@@ -320,6 +436,83 @@ func TestCompile_InvalidSchemaVersion(t *testing.T) {
 	}
 }
 
+func TestCompile_YAMLFormat(t *testing.T) {
+	path := writeTestFile(t, "workflow.json", validAgentJSON)
+	root := newTestRoot()
+	stdout, _, err := executeCommand(root, "compile", path, "--format", "yaml")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !strings.Contains(stdout, "\nnodes:\n") || strings.Contains(stdout, `"nodes"`) {
+		t.Errorf("expected compiled YAML, got: %q", stdout)
+	}
+
+	root = newTestRoot()
+	if _, _, err := executeCommand(root, "compile", path, "--format", "toml"); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
+
+func TestCompile_TargetGo(t *testing.T) {
+	path := writeTestFile(t, "workflow.json", validGraphJSON)
+	outDir := filepath.Join(t.TempDir(), "bin")
+
+	root := newTestRoot()
+	stdout, _, err := executeCommand(root, "compile", path, "--target", "go", "-o", outDir, "--module", "example.com/testgraph")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !strings.Contains(stdout, "Generated Go program") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+	for _, name := range []string{"main.go", "bindings.go", "workflow.json", "go.mod"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+
+	// Regenerating keeps an edited bindings.go.
+	bindingsPath := filepath.Join(outDir, "bindings.go")
+	if err := os.WriteFile(bindingsPath, []byte("package main // edited\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	root = newTestRoot()
+	if _, _, err := executeCommand(root, "compile", path, "--target", "go", "-o", outDir); err != nil {
+		t.Fatalf("regenerate: %v", err)
+	}
+	data, err := os.ReadFile(bindingsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "edited") {
+		t.Error("bindings.go should not be overwritten")
+	}
+
+	root = newTestRoot()
+	if _, _, err := executeCommand(root, "compile", path, "--target", "rust"); err == nil {
+		t.Fatal("expected error for unsupported target")
+	}
+}
+
+func TestCompile_TargetTemporal(t *testing.T) {
+	path := writeTestFile(t, "workflow.json", validGraphJSON)
+	outDir := filepath.Join(t.TempDir(), "worker")
+
+	root := newTestRoot()
+	stdout, _, err := executeCommand(root, "compile", path, "--target", "temporal", "-o", outDir)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !strings.Contains(stdout, "Generated Temporal worker") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+	for _, name := range []string{"main.go", "workflow.go", "activities.go", "bindings.go", "workflow.json"} {
+		if _, err := os.Stat(filepath.Join(outDir, name)); err != nil {
+			t.Errorf("expected %s: %v", name, err)
+		}
+	}
+}
+
 // --- Run command tests ---
 
 func TestRun_DryRun(t *testing.T) {
@@ -334,6 +527,27 @@ func TestRun_DryRun(t *testing.T) {
 	}
 }
 
+func TestRun_DryRunListsLLMSettings(t *testing.T) {
+	path := writeTestFile(t, "workflow.json", `{
+		"id": "support", "version": "1.0", "kind": "graph", "schema_version": "1.0.0", "entry": "answer",
+		"llm_defaults": {"provider": "anthropic", "model": "claude-haiku-4-5", "temperature": 0.2},
+		"nodes": [{"id": "answer", "type": "llm_prompt", "config": {"prompt_template": "hi", "max_tokens": 512}}],
+		"edges": []
+	}`)
+	stdout, _, err := executeCommand(newTestRoot(), "run", path, "--dry-run")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for _, want := range []string{
+		"answer (llm_prompt): anthropic/claude-haiku-4-5, temperature 0.2, max_tokens 512",
+		"from llm_defaults: provider, model, temperature",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, stdout)
+		}
+	}
+}
+
 func TestRun_FileNotFound(t *testing.T) {
 	root := newTestRoot()
 	_, _, err := executeCommand(root, "run", "/nonexistent/path.json")
@@ -443,6 +657,47 @@ func TestRun_StreamFlagAccepted(t *testing.T) {
 	}
 }
 
+const simulatedGraphJSON = `{
+  "id": "simulated_graph",
+  "version": "1.0",
+  "nodes": [
+    {"id": "answer", "type": "llm_prompt", "config": {
+      "provider": "anthropic",
+      "prompt_template": "Question: {{.question}}",
+      "output_key": "output"
+    }}
+  ],
+  "edges": [],
+  "entry": "answer",
+  "simulate": {
+    "nodes": {"answer": {"template": "simulated reply to {{.prompt}}"}}
+  }
+}`
+
+func TestRun_Simulate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path := writeTestFile(t, "simulated.json", simulatedGraphJSON)
+
+	root := newTestRoot()
+	stdout, _, err := executeCommand(root, "run", path, "--simulate", "--format", "text", "--input", `{"question":"why?"}`)
+	if err != nil {
+		t.Fatalf("simulated run failed: %v", err)
+	}
+	if strings.TrimSpace(stdout) != "simulated reply to Question: why?" {
+		t.Errorf("stdout = %q", stdout)
+	}
+
+	override := writeTestFile(t, "override.json", `{"nodes": {"answer": {"response": "canned"}}}`)
+	root = newTestRoot()
+	stdout, _, err = executeCommand(root, "run", path, "--simulate-file", override, "--format", "text")
+	if err != nil {
+		t.Fatalf("simulated run with override failed: %v", err)
+	}
+	if strings.TrimSpace(stdout) != "canned" {
+		t.Errorf("stdout with override = %q", stdout)
+	}
+}
+
 // --- CLI human handler tests ---
 
 func TestCLIHumanHandler_AutoApproval(t *testing.T) {
@@ -4,7 +4,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
@@ -12,29 +16,49 @@ import (
 	"github.com/petal-labs/petalflow/graph"
 	"github.com/petal-labs/petalflow/loader"
 	"github.com/petal-labs/petalflow/registry"
+	"github.com/petal-labs/petalflow/standalone"
+	"github.com/petal-labs/petalflow/temporalexport"
 )
 
 // NewCompileCmd creates the "compile" subcommand.
 func NewCompileCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "compile <file>",
-		Short: "Compile agent workflow to graph IR",
-		Args:  cobra.ExactArgs(1),
-		RunE:  runCompile,
+		Short: "Compile agent workflow to graph IR, or any workflow to a Go program",
+		Long: `Compile an agent workflow to graph IR.
+
+With --target go, compile an agent or graph workflow into a Go main package
+in the --output directory (default: the workflow ID). The package embeds the
+compiled workflow and builds into a single binary that runs it without the
+daemon:
+
+  petalflow compile support.yaml --target go -o support --module example.com/support
+  cd support && go mod tidy && go build
+
+With --target temporal, the package is a Temporal worker instead: a Temporal
+workflow that runs each node as an activity, with activity stubs that run
+the nodes' PetalFlow implementations.`,
+		Args: cobra.ExactArgs(1),
+		RunE: runCompile,
 	}
 
-	cmd.Flags().StringP("output", "o", "", "Output file path (default: stdout)")
+	cmd.Flags().StringP("output", "o", "", "Output file path (default: stdout), or the output directory with --target go or temporal")
 	cmd.Flags().Bool("pretty", true, "Pretty-print JSON output")
 	cmd.Flags().Bool("validate-only", false, "Only run AgentTask validation, don't compile")
+	cmd.Flags().String("format", "json", "Output format: json | yaml")
+	cmd.Flags().String("target", "graph", "Compile target: graph | go | temporal")
+	cmd.Flags().String("module", "", "With --target go or temporal, also write a go.mod declaring this module path")
+	cmd.Flags().String("workflow", "", "Compile only the workflow with this id from a multi-document file")
 
 	return cmd
 }
 
 // runCompile implements the compile pipeline:
 //
-//	read file → detectSchema → must be agent workflow → parse → validate
-//	→ (if --validate-only: print "Valid" and exit 0)
-//	→ compile → graph validate → serialize JSON → write output
+//	read file → split documents → for each: detectSchema → must be agent
+//	workflow → parse → validate → (if --validate-only: print "Valid" and
+//	exit 0) → compile → graph validate → serialize JSON (or YAML) → write
+//	output
 func runCompile(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
 	stderr := cmd.ErrOrStderr()
@@ -43,6 +67,18 @@ func runCompile(cmd *cobra.Command, args []string) error {
 	pretty, _ := cmd.Flags().GetBool("pretty")
 	validateOnly, _ := cmd.Flags().GetBool("validate-only")
 	outputPath, _ := cmd.Flags().GetString("output")
+	formatName, _ := cmd.Flags().GetString("format")
+	format, err := loader.ParseSourceFormat(formatName)
+	if err != nil {
+		return exitError(exitInputParse, "%v", err)
+	}
+	switch target, _ := cmd.Flags().GetString("target"); target {
+	case "graph":
+	case "go", "temporal":
+		return runCompileGo(cmd, filePath, target)
+	default:
+		return exitError(exitInputParse, "unknown target %q (use graph, go or temporal)", target)
+	}
 
 	// Step 1: Read file
 	data, err := os.ReadFile(filePath) // #nosec G304 -- path from user CLI arg
@@ -53,76 +89,200 @@ func runCompile(cmd *cobra.Command, args []string) error {
 		return exitError(exitFileNotFound, "reading file: %s", err)
 	}
 
-	// Step 2: Detect schema — must be agent workflow
-	kind, err := loader.DetectSchema(data, filePath)
+	// Step 2: Split the file into its workflows and pick --workflow
+	docs, err := loader.SplitDocuments(data, loader.FormatForPath(data, filePath))
 	if err != nil {
 		return exitError(exitValidation, "schema detection failed: %s", err)
 	}
-	if kind != loader.SchemaKindAgent {
-		return exitError(exitWrongSchema, "compile only accepts agent workflow files")
+	if id, _ := cmd.Flags().GetString("workflow"); id != "" {
+		doc, err := loader.SelectDocument(docs, id)
+		if err != nil {
+			return exitError(exitInputParse, "%v", err)
+		}
+		docs = [][]byte{doc}
+	}
+
+	// Steps 3-7 for each workflow
+	gds := make([]*graph.GraphDefinition, 0, len(docs))
+	for _, doc := range docs {
+		gd, err := compileAgentDocument(stderr, doc, validateOnly)
+		if err != nil {
+			return err
+		}
+		gds = append(gds, gd)
 	}
 
-	// Step 3: Convert YAML to JSON if needed, then parse into AgentWorkflow
-	jsonData, err := yamlToJSONIfNeeded(data, filePath)
+	// Step 5: If --validate-only, print "Valid" and exit 0
+	if validateOnly {
+		fmt.Fprintln(stdout, "Valid")
+		return nil
+	}
+
+	// Step 8: Serialize the GraphDefinitions, a multi-workflow file to a
+	// JSON array or a YAML stream
+	jsonOut, err := marshalGraphDefinitions(gds, pretty, format)
 	if err != nil {
-		return exitError(exitValidation, "parsing file: %s", err)
+		return exitError(exitValidation, "serializing graph definition: %s", err)
 	}
 
-	wf, err := agent.LoadFromBytes(jsonData)
+	// Step 9: Write to --output or stdout
+	if outputPath != "" {
+		if err := os.WriteFile(outputPath, jsonOut, 0600); err != nil {
+			return fmt.Errorf("writing output file: %w", err)
+		}
+	} else {
+		if _, err := stdout.Write(jsonOut); err != nil {
+			return fmt.Errorf("writing to stdout: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// compileAgentDocument validates one agent workflow document and, unless
+// validateOnly, compiles it and validates the compiled graph.
+func compileAgentDocument(stderr io.Writer, doc []byte, validateOnly bool) (*graph.GraphDefinition, error) {
+	// Step 3: Must be an agent workflow; parse into AgentWorkflow
+	kind, err := loader.DetectSchema(doc, "")
 	if err != nil {
-		return exitError(exitValidation, "parsing agent workflow: %s", err)
+		return nil, exitError(exitValidation, "schema detection failed: %s", err)
+	}
+	if kind != loader.SchemaKindAgent {
+		return nil, exitError(exitWrongSchema, "compile only accepts agent workflow files")
+	}
+
+	wf, err := agent.LoadFromBytes(doc)
+	if err != nil {
+		return nil, exitError(exitValidation, "parsing agent workflow: %s", err)
 	}
 
 	// Step 4: AgentTask validation
 	diags := agent.Validate(wf)
 	if graph.HasErrors(diags) {
 		printDiagnosticsText(stderr, graph.Errors(diags))
-		return exitError(exitValidation, "agent workflow validation failed with %d error(s)", len(graph.Errors(diags)))
+		return nil, exitError(exitValidation, "agent workflow validation failed with %d error(s)", len(graph.Errors(diags)))
 	}
-
-	// Step 5: If --validate-only, print "Valid" and exit 0
 	if validateOnly {
-		fmt.Fprintln(stdout, "Valid")
-		return nil
+		return nil, nil
 	}
 
 	// Step 6: Compile to GraphDefinition
 	gd, err := agent.Compile(wf)
 	if err != nil {
-		return exitError(exitValidation, "compilation failed: %s", err)
+		return nil, exitError(exitValidation, "compilation failed: %s", err)
 	}
 
 	// Step 7: Graph validation on compiled output
 	graphDiags := gd.ValidateWithRegistry(registry.Global())
 	if graph.HasErrors(graphDiags) {
 		printDiagnosticsText(stderr, graph.Errors(graphDiags))
-		return exitError(exitValidation, "compiled graph validation failed with %d error(s)", len(graph.Errors(graphDiags)))
+		return nil, exitError(exitValidation, "compiled graph validation failed with %d error(s)", len(graph.Errors(graphDiags)))
+	}
+	return gd, nil
+}
+
+// marshalGraphDefinitions serializes compiled workflows with a trailing
+// newline. One workflow is a single document; several are a JSON array or
+// a YAML stream of documents.
+func marshalGraphDefinitions(gds []*graph.GraphDefinition, pretty bool, format loader.SourceFormat) ([]byte, error) {
+	var v any = gds
+	if len(gds) == 1 {
+		v = gds[0]
+	}
+	if format == loader.FormatYAML && len(gds) > 1 {
+		var out []byte
+		for _, gd := range gds {
+			doc, err := marshalGraphDefinitions([]*graph.GraphDefinition{gd}, pretty, format)
+			if err != nil {
+				return nil, err
+			}
+			out = append(append(out, "---\n"...), doc...)
+		}
+		return out, nil
 	}
 
-	// Step 8: Serialize GraphDefinition to JSON
-	var jsonOut []byte
+	var out []byte
+	var err error
 	if pretty {
-		jsonOut, err = json.MarshalIndent(gd, "", "  ")
+		out, err = json.MarshalIndent(v, "", "  ")
 	} else {
-		jsonOut, err = json.Marshal(gd)
+		out, err = json.Marshal(v)
 	}
 	if err != nil {
-		return exitError(exitValidation, "serializing graph definition: %s", err)
+		return nil, err
+	}
+	out = append(out, '\n')
+	if format == loader.FormatYAML {
+		return loader.ToYAML(out)
 	}
+	return out, nil
+}
 
-	// Append trailing newline for clean output
-	jsonOut = append(jsonOut, '\n')
+// runCompileGo writes a Go main package that runs the workflow at filePath,
+// as a standalone binary for the go target or a Temporal worker for the
+// temporal target. An existing bindings.go is kept so that edits to it
+// survive recompiling.
+func runCompileGo(cmd *cobra.Command, filePath, target string) error {
+	workflowID, _ := cmd.Flags().GetString("workflow")
+	gd, err := loadWorkflowForRun(cmd, filePath, workflowID)
+	if err != nil {
+		return err
+	}
+	if validateOnly, _ := cmd.Flags().GetBool("validate-only"); validateOnly {
+		fmt.Fprintln(cmd.OutOrStdout(), "Valid")
+		return nil
+	}
 
-	// Step 9: Write to --output or stdout
-	if outputPath != "" {
-		if err := os.WriteFile(outputPath, jsonOut, 0600); err != nil {
-			return fmt.Errorf("writing output file: %w", err)
-		}
+	module, _ := cmd.Flags().GetString("module")
+	var files map[string][]byte
+	what := "Go program"
+	if target == "temporal" {
+		what = "Temporal worker"
+		files, err = temporalexport.Generate(gd, temporalexport.GenerateOptions{
+			Module:           module,
+			PetalflowVersion: cmd.Root().Version,
+		})
 	} else {
-		if _, err := stdout.Write(jsonOut); err != nil {
-			return fmt.Errorf("writing to stdout: %w", err)
+		files, err = standalone.Generate(gd, standalone.GenerateOptions{
+			Module:           module,
+			PetalflowVersion: cmd.Root().Version,
+		})
+	}
+	if err != nil {
+		return exitError(exitValidation, "generating %s: %v", what, err)
+	}
+
+	dir, _ := cmd.Flags().GetString("output")
+	if dir == "" {
+		dir = gd.ID
+	}
+	if dir == "" {
+		return exitError(exitInputParse, "--output is required for workflows without an id")
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if name == standalone.BindingsFile || name == standalone.GoModFile {
+			if _, err := os.Stat(path); err == nil {
+				continue
+			}
+		}
+		if err := os.WriteFile(path, files[name], 0600); err != nil {
+			return fmt.Errorf("writing %s: %w", path, err)
 		}
 	}
 
+	fmt.Fprintf(cmd.OutOrStdout(), "Generated %s for %q in %s\n", what, gd.ID, dir)
+	if tools := standalone.WorkflowTools(gd); len(tools) > 0 {
+		fmt.Fprintf(cmd.OutOrStdout(), "Register these tools in %s: %s\n", standalone.BindingsFile, strings.Join(tools, ", "))
+	}
 	return nil
 }
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+// completionTimeout bounds daemon lookups so a slow or absent daemon never
+// stalls the user's shell.
+const completionTimeout = 2 * time.Second
+
+// CompletionFunc matches cobra's ValidArgsFunction signature.
+type CompletionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// completionClient returns a daemon client only when an address is
+// explicitly configured; completions never guess at a default daemon.
+func completionClient(cmd *cobra.Command) (*daemonClient, context.Context, context.CancelFunc, bool) {
+	addr := configuredDaemonAddr(cmd)
+	if addr == "" {
+		return nil, nil, nil, false
+	}
+	parent := cmd.Context()
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(parent, completionTimeout)
+	return newDaemonClient(addr, configuredDaemonToken(cmd), completionTimeout), ctx, cancel, true
+}
+
+// CompleteWorkflowIDs completes workflow IDs from the configured daemon.
+func CompleteWorkflowIDs(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	client, ctx, cancel, ok := completionClient(cmd)
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer cancel()
+
+	var workflows []workflowSummary
+	if err := client.getJSON(ctx, "/api/workflows", &workflows); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var out []string
+	for _, wf := range workflows {
+		if strings.HasPrefix(wf.ID, toComplete) {
+			out = append(out, withDescription(wf.ID, wf.Name))
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteRunIDs completes run IDs from the configured daemon.
+func CompleteRunIDs(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	client, ctx, cancel, ok := completionClient(cmd)
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer cancel()
+
+	var runs []runSummary
+	if err := client.getJSON(ctx, "/api/runs", &runs); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var out []string
+	for _, run := range runs {
+		if strings.HasPrefix(run.RunID, toComplete) {
+			out = append(out, run.RunID)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteScheduleIDs completes schedule IDs for the workflow named by the
+// first positional argument.
+func CompleteScheduleIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	client, ctx, cancel, ok := completionClient(cmd)
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	defer cancel()
+
+	var schedules []scheduleSummary
+	path := "/api/workflows/" + url.PathEscape(args[0]) + "/schedules"
+	if err := client.getJSON(ctx, path, &schedules); err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	var out []string
+	for _, sched := range schedules {
+		if strings.HasPrefix(sched.ID, toComplete) {
+			out = append(out, withDescription(sched.ID, sched.Cron))
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteProviderNames completes provider names from the configured daemon,
+// falling back to providers resolved from the local config and environment.
+func CompleteProviderNames(cmd *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names []string
+	if client, ctx, cancel, ok := completionClient(cmd); ok {
+		defer cancel()
+		var providers []struct {
+			Name string `json:"name"`
+		}
+		if err := client.getJSON(ctx, "/api/providers", &providers); err == nil {
+			for _, p := range providers {
+				names = append(names, p.Name)
+			}
+		}
+	}
+	if len(names) == 0 {
+		if local, err := hydrate.ResolveProviders(nil); err == nil {
+			for name := range local {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+		}
+	}
+
+	var out []string
+	for _, name := range names {
+		if strings.HasPrefix(name, toComplete) {
+			out = append(out, name)
+		}
+	}
+	return out, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeProviderKeyFlag completes the name half of --provider-key name=key.
+func completeProviderKeyFlag(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if strings.Contains(toComplete, "=") {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, _ := CompleteProviderNames(cmd, args, toComplete)
+	for i, name := range names {
+		names[i] = name + "="
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveNoSpace
+}
+
+// completeFirstArg restricts fn to the first positional argument.
+func completeFirstArg(fn CompletionFunc) CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return fn(cmd, args, toComplete)
+	}
+}
+
+func completeWorkflowThenSchedule(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	switch len(args) {
+	case 0:
+		return CompleteWorkflowIDs(cmd, args, toComplete)
+	case 1:
+		return CompleteScheduleIDs(cmd, args, toComplete)
+	default:
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// withDescription formats a completion candidate with cobra's tab-separated
+// description, shown by shells that support it.
+func withDescription(value, description string) string {
+	if description == "" {
+		return value
+	}
+	return value + "\t" + description
+}
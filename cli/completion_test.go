@@ -0,0 +1,206 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func newFakeDaemon(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	writeJSON := func(w http.ResponseWriter, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(v)
+	}
+	mux.HandleFunc("GET /api/workflows", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, []map[string]any{
+			{"id": "support_triage", "kind": "graph", "name": "Support triage", "lifecycle": map[string]any{"state": "deprecated"}},
+			{"id": "summarize", "kind": "agent_workflow"},
+		})
+	})
+	mux.HandleFunc("GET /api/runs", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, []map[string]any{{"run_id": "run-abc"}, {"run_id": "run-def"}})
+	})
+	mux.HandleFunc("GET /api/workflows/{id}/schedules", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []map[string]any{{"id": "nightly", "workflow_id": r.PathValue("id"), "cron": "0 2 * * *"}})
+	})
+	mux.HandleFunc("GET /api/providers", func(w http.ResponseWriter, _ *http.Request) {
+		writeJSON(w, []map[string]any{{"name": "anthropic"}, {"name": "openai"}})
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func completionOutput(t *testing.T, args ...string) []string {
+	t.Helper()
+	root := newTestRoot()
+	root.AddCommand(NewWorkflowsCmd(), NewRunsCmd(), NewSchedulesCmd())
+	stdout, _, err := executeCommand(root, append([]string{cobra.ShellCompRequestCmd}, args...)...)
+	if err != nil {
+		t.Fatalf("completion failed: %v", err)
+	}
+	var out []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		if line == "" || strings.HasPrefix(line, ":") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+func TestCompletion_DaemonBackedIDs(t *testing.T) {
+	daemon := newFakeDaemon(t)
+
+	got := completionOutput(t, "workflows", "get", "--daemon", daemon.URL, "sup")
+	if len(got) != 1 || got[0] != "support_triage\tSupport triage" {
+		t.Fatalf("workflow completions = %q", got)
+	}
+
+	got = completionOutput(t, "runs", "events", "--daemon", daemon.URL, "")
+	if len(got) != 2 || got[0] != "run-abc" {
+		t.Fatalf("run completions = %q", got)
+	}
+
+	got = completionOutput(t, "schedules", "get", "--daemon", daemon.URL, "summarize", "")
+	if len(got) != 1 || got[0] != "nightly\t0 2 * * *" {
+		t.Fatalf("schedule completions = %q", got)
+	}
+}
+
+func TestCompletion_ProviderKeyFlag(t *testing.T) {
+	daemon := newFakeDaemon(t)
+	t.Setenv("PETALFLOW_DAEMON_ADDR", daemon.URL)
+
+	got := completionOutput(t, "run", "wf.json", "--provider-key", "op")
+	if len(got) != 1 || got[0] != "openai=" {
+		t.Fatalf("provider completions = %q", got)
+	}
+}
+
+func TestCompletion_NoDaemonConfigured(t *testing.T) {
+	t.Setenv("PETALFLOW_DAEMON_ADDR", "")
+
+	if got := completionOutput(t, "workflows", "get", ""); len(got) != 0 {
+		t.Fatalf("expected no completions without a daemon, got %q", got)
+	}
+}
+
+func TestWorkflowsList_FromDaemon(t *testing.T) {
+	daemon := newFakeDaemon(t)
+	root := newTestRoot()
+	root.AddCommand(NewWorkflowsCmd())
+
+	stdout, _, err := executeCommand(root, "workflows", "list", "--daemon", daemon.URL)
+	if err != nil {
+		t.Fatalf("workflows list: %v", err)
+	}
+	if !strings.Contains(stdout, "support_triage") || !strings.Contains(stdout, "summarize") || !strings.Contains(stdout, "deprecated") {
+		t.Fatalf("unexpected output:\n%s", stdout)
+	}
+}
+
+func TestWorkflowsLifecycle_FromDaemon(t *testing.T) {
+	t.Setenv("PETALFLOW_TOKEN", "env-token")
+	var gotBody map[string]any
+	var gotAuth string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/workflows/{id}/lifecycle", func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"workflow_id": "summarize", "state": "deprecated", "reason": "use v2",
+			"sunset_at": "2026-12-31T00:00:00Z", "replacement": "summarize_v2",
+			"history": [{"from": "active", "to": "deprecated", "reason": "use v2", "actor": "ops", "at": "2026-10-01T09:00:00Z"}]}`))
+	})
+	daemon := httptest.NewServer(mux)
+	t.Cleanup(daemon.Close)
+
+	root := newTestRoot()
+	root.AddCommand(NewWorkflowsCmd())
+	stdout, _, err := executeCommand(root, "workflows", "lifecycle", "summarize", "--set", "deprecated", "--reason", "use v2",
+		"--replacement", "summarize_v2", "--sunset", "2026-12-31", "--daemon", daemon.URL)
+	if err != nil {
+		t.Fatalf("workflows lifecycle: %v", err)
+	}
+	if gotBody["state"] != "deprecated" || gotBody["replacement"] != "summarize_v2" || gotBody["sunset_at"] != "2026-12-31T00:00:00Z" {
+		t.Errorf("request body = %v", gotBody)
+	}
+	if gotAuth != "Bearer env-token" {
+		t.Errorf("Authorization = %q, want the PETALFLOW_TOKEN bearer token", gotAuth)
+	}
+	if !strings.Contains(stdout, "State: deprecated") || !strings.Contains(stdout, "Replacement: summarize_v2") || !strings.Contains(stdout, "ops") {
+		t.Fatalf("unexpected output:\n%s", stdout)
+	}
+}
+
+func TestWorkflowsExport_FromDaemon(t *testing.T) {
+	var gotQuery, gotAuth string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/workflows/{id}/export", func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write([]byte("id: " + r.PathValue("id") + "\n"))
+	})
+	daemon := httptest.NewServer(mux)
+	t.Cleanup(daemon.Close)
+
+	outPath := filepath.Join(t.TempDir(), "wf.yaml")
+	root := newTestRoot()
+	root.AddCommand(NewWorkflowsCmd())
+	if _, _, err := executeCommand(root, "workflows", "export", "summarize", "--format", "yaml", "-o", outPath, "--daemon", daemon.URL, "--token", "flag-token"); err != nil {
+		t.Fatalf("workflows export: %v", err)
+	}
+	data, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	if string(data) != "id: summarize\n" || gotQuery != "format=yaml" || gotAuth != "Bearer flag-token" {
+		t.Fatalf("export = %q, query = %q, Authorization = %q", data, gotQuery, gotAuth)
+	}
+}
+
+func TestWorkflowsGrep_FromDaemon(t *testing.T) {
+	var gotQuery string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/workflows/search", func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.RawQuery
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"results": [{"id": "refunds", "kind": "graph", "matches": [
+			{"node_id": "notify", "node_type": "webhook_call", "field": "config.url", "text": "https://hooks.example.com/refunds"}
+		]}]}`))
+	})
+	daemon := httptest.NewServer(mux)
+	t.Cleanup(daemon.Close)
+
+	grep := func(args ...string) string {
+		t.Helper()
+		root := newTestRoot()
+		root.AddCommand(NewWorkflowsCmd())
+		stdout, _, err := executeCommand(root, append(append([]string{"workflows", "grep"}, args...), "--daemon", daemon.URL)...)
+		if err != nil {
+			t.Fatalf("workflows grep %v: %v", args, err)
+		}
+		return stdout
+	}
+
+	out := grep("--node-type", "webhook_call", "--config-contains", "refunds")
+	if gotQuery != "config_contains=refunds&node_type=webhook_call" {
+		t.Errorf("query = %q", gotQuery)
+	}
+	if !strings.Contains(out, "refunds") || !strings.Contains(out, "notify") || !strings.Contains(out, "config.url") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+	if out := grep("refund", "-l"); out != "refunds\n" || gotQuery != "q=refund" {
+		t.Fatalf("-l output = %q, query = %q", out, gotQuery)
+	}
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/petal-labs/petalflow/nodes/conditional"
+)
+
+// NewConditionsCmd creates the "conditions" command group, which manages the
+// named condition library of a running daemon.
+func NewConditionsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "conditions",
+		Short: "Manage the shared condition library in a running daemon",
+		Long: `Manage named, parameterized conditions that conditional and rule_router
+nodes reference as ref: business_hours(tz: "UTC").`,
+	}
+	addDaemonFlag(cmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List conditions",
+		Args:  cobra.NoArgs,
+		RunE:  runConditionsList,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get <name>",
+		Short: "Show a condition",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runConditionsGet,
+	})
+	applyCmd := &cobra.Command{
+		Use:   "apply",
+		Short: "Create or update conditions from a YAML or JSON file",
+		Long: `Create or update conditions from a file holding one definition or a
+list of them:
+
+  name: business_hours
+  expression: hour >= start && hour < end
+  params:
+    - {name: start, type: number, default: 9}
+    - {name: end, type: number, default: 17}`,
+		Args: cobra.NoArgs,
+		RunE: runConditionsApply,
+	}
+	applyCmd.Flags().StringP("file", "f", "", "Condition definition file")
+	_ = applyCmd.MarkFlagRequired("file")
+	cmd.AddCommand(applyCmd)
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a condition",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runConditionsDelete,
+	})
+	return cmd
+}
+
+func runConditionsList(cmd *cobra.Command, _ []string) error {
+	var defs []conditional.Definition
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), "/api/conditions", &defs); err != nil {
+		return exitError(exitRuntime, "listing conditions: %v", err)
+	}
+
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 2, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tPARAMS\tEXPRESSION")
+	for _, def := range defs {
+		params := make([]string, 0, len(def.Params))
+		for _, p := range def.Params {
+			params = append(params, p.Name)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", def.Name, dashIfEmpty(strings.Join(params, ",")), def.Expression)
+	}
+	return writer.Flush()
+}
+
+func runConditionsGet(cmd *cobra.Command, args []string) error {
+	var def json.RawMessage
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), "/api/conditions/"+url.PathEscape(args[0]), &def); err != nil {
+		return exitError(exitRuntime, "getting condition: %v", err)
+	}
+	return writeIndentedJSON(cmd, def)
+}
+
+func runConditionsApply(cmd *cobra.Command, _ []string) error {
+	path, _ := cmd.Flags().GetString("file")
+	defs, err := readConditionFile(path)
+	if err != nil {
+		return err
+	}
+
+	client := resolveDaemonClient(cmd)
+	for _, def := range defs {
+		if err := def.Validate(); err != nil {
+			return exitError(exitValidation, "%s: %v", path, err)
+		}
+		action := "Updated"
+		err := client.doJSON(cmd.Context(), http.MethodPut, "/api/conditions/"+url.PathEscape(def.Name), def, nil)
+		var statusErr *daemonStatusError
+		if errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound {
+			action = "Created"
+			err = client.doJSON(cmd.Context(), http.MethodPost, "/api/conditions", def, nil)
+		}
+		if err != nil {
+			return exitError(exitRuntime, "applying condition %q: %v", def.Name, err)
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "%s condition %s\n", action, def.Name)
+	}
+	return nil
+}
+
+func runConditionsDelete(cmd *cobra.Command, args []string) error {
+	if err := resolveDaemonClient(cmd).doJSON(cmd.Context(), http.MethodDelete, "/api/conditions/"+url.PathEscape(args[0]), nil, nil); err != nil {
+		return exitError(exitRuntime, "deleting condition: %v", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Deleted condition %s\n", args[0])
+	return nil
+}
+
+// conditionFileEntry is a condition definition as written in a file.
+type conditionFileEntry struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Expression  string              `json:"expression"`
+	Params      []conditional.Param `json:"params,omitempty"`
+}
+
+// readConditionFile reads one condition definition or a list of them from
+// YAML or JSON.
+func readConditionFile(path string) ([]conditionFileEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, exitError(exitFileNotFound, "file not found: %s", path)
+		}
+		return nil, exitError(exitRuntime, "reading %s: %v", path, err)
+	}
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, exitError(exitInputParse, "parsing %s: %v", path, err)
+	}
+	if _, ok := raw.([]any); !ok {
+		raw = []any{raw}
+	}
+	// Round-trip through JSON so the json tags on conditional.Param apply.
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, exitError(exitInputParse, "parsing %s: %v", path, err)
+	}
+	var entries []conditionFileEntry
+	if err := json.Unmarshal(encoded, &entries); err != nil {
+		return nil, exitError(exitInputParse, "parsing %s: %v", path, err)
+	}
+	return entries, nil
+}
+
+// Validate checks the entry the way the daemon will.
+func (e conditionFileEntry) Validate() error {
+	return conditional.Definition{Name: e.Name, Expression: e.Expression, Params: e.Params}.Validate()
+}
@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestConditionsApply(t *testing.T) {
+	var mu sync.Mutex
+	stored := map[string]json.RawMessage{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /api/conditions/{name}", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if _, ok := stored[r.PathValue("name")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"NOT_FOUND","message":"condition not found"}}`))
+			return
+		}
+		var body json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		stored[r.PathValue("name")] = body
+		_, _ = w.Write(body)
+	})
+	mux.HandleFunc("POST /api/conditions", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		var body struct {
+			Name string `json:"name"`
+		}
+		var raw json.RawMessage
+		_ = json.NewDecoder(r.Body).Decode(&raw)
+		_ = json.Unmarshal(raw, &body)
+		stored[body.Name] = raw
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write(raw)
+	})
+	daemon := httptest.NewServer(mux)
+	t.Cleanup(daemon.Close)
+
+	path := writeTestFile(t, "conditions.yaml", `
+- name: business_hours
+  expression: hour >= start && hour < end
+  params:
+    - {name: start, type: number, default: 9}
+    - {name: end, type: number, default: 17}
+- name: vip
+  expression: input.tier == "gold"
+`)
+	root := newTestRoot()
+	root.AddCommand(NewConditionsCmd())
+	stdout, _, err := executeCommand(root, "conditions", "apply", "-f", path, "--daemon", daemon.URL)
+	if err != nil {
+		t.Fatalf("conditions apply: %v", err)
+	}
+	if !strings.Contains(stdout, "Created condition business_hours") || !strings.Contains(stdout, "Created condition vip") {
+		t.Fatalf("unexpected output:\n%s", stdout)
+	}
+	if !strings.Contains(string(stored["business_hours"]), `"default":9`) {
+		t.Fatalf("stored business_hours = %s", stored["business_hours"])
+	}
+
+	root = newTestRoot()
+	root.AddCommand(NewConditionsCmd())
+	stdout, _, err = executeCommand(root, "conditions", "apply", "-f", path, "--daemon", daemon.URL)
+	if err != nil {
+		t.Fatalf("second apply: %v", err)
+	}
+	if !strings.Contains(stdout, "Updated condition vip") {
+		t.Fatalf("unexpected output:\n%s", stdout)
+	}
+
+	bad := writeTestFile(t, "bad.yaml", "name: broken\nexpression: 'a &&'\n")
+	root = newTestRoot()
+	root.AddCommand(NewConditionsCmd())
+	if _, _, err := executeCommand(root, "conditions", "apply", "-f", bad, "--daemon", daemon.URL); err == nil {
+		t.Fatal("expected validation error for an invalid expression")
+	}
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultDaemonAddr = "http://localhost:8080"
+
+// daemonClient is a minimal JSON client for the daemon HTTP API.
+type daemonClient struct {
+	baseURL string
+	// token, when set, is sent as a bearer token.
+	token      string
+	httpClient *http.Client
+}
+
+// daemonAPIError mirrors the daemon's error envelope.
+type daemonAPIError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// daemonStatusError is returned for non-2xx daemon responses.
+type daemonStatusError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *daemonStatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("unexpected status %d", e.Status)
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// addDaemonFlag registers the --daemon and --token flags on a command
+// group.
+func addDaemonFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().String("daemon", "", "Daemon address (default: $PETALFLOW_DAEMON_ADDR or "+defaultDaemonAddr+")")
+	cmd.PersistentFlags().String("token", "", "Daemon API token (default: $PETALFLOW_TOKEN)")
+}
+
+// configuredDaemonAddr returns the daemon address from --daemon or
+// PETALFLOW_DAEMON_ADDR, or "" when neither is set.
+func configuredDaemonAddr(cmd *cobra.Command) string {
+	if flag := cmd.Flags().Lookup("daemon"); flag != nil {
+		if addr := strings.TrimSpace(flag.Value.String()); addr != "" {
+			return addr
+		}
+	}
+	return strings.TrimSpace(os.Getenv("PETALFLOW_DAEMON_ADDR"))
+}
+
+// configuredDaemonToken returns the API token from --token or
+// PETALFLOW_TOKEN, or "" when neither is set.
+func configuredDaemonToken(cmd *cobra.Command) string {
+	if flag := cmd.Flags().Lookup("token"); flag != nil {
+		if token := strings.TrimSpace(flag.Value.String()); token != "" {
+			return token
+		}
+	}
+	return strings.TrimSpace(os.Getenv("PETALFLOW_TOKEN"))
+}
+
+func newDaemonClient(addr, token string, timeout time.Duration) *daemonClient {
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	return &daemonClient{
+		baseURL:    strings.TrimRight(addr, "/"),
+		token:      token,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// resolveDaemonClient builds a client for commands that talk to the daemon,
+// falling back to the default local address.
+func resolveDaemonClient(cmd *cobra.Command) *daemonClient {
+	addr := configuredDaemonAddr(cmd)
+	if addr == "" {
+		addr = defaultDaemonAddr
+	}
+	return newDaemonClient(addr, configuredDaemonToken(cmd), 30*time.Second)
+}
+
+func (c *daemonClient) getJSON(ctx context.Context, path string, out any) error {
+	return c.doJSON(ctx, http.MethodGet, path, nil, out)
+}
+
+// doJSON sends body, when non-nil, as JSON and decodes the response into
+// out, when non-nil.
+func (c *daemonClient) doJSON(ctx context.Context, method, path string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return newDaemonStatusError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// getRaw fetches path and returns the response body as-is, for endpoints
+// that do not answer with JSON.
+func (c *daemonClient) getRaw(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newDaemonStatusError(resp)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// do sends req with the client's bearer token, if any.
+func (c *daemonClient) do(req *http.Request) (*http.Response, error) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return c.httpClient.Do(req)
+}
+
+func newDaemonStatusError(resp *http.Response) *daemonStatusError {
+	statusErr := &daemonStatusError{Status: resp.StatusCode}
+	var apiErr daemonAPIError
+	if json.NewDecoder(resp.Body).Decode(&apiErr) == nil && apiErr.Error.Message != "" {
+		statusErr.Code = apiErr.Error.Code
+		statusErr.Message = apiErr.Error.Message
+	}
+	return statusErr
+}
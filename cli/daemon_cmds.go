@@ -0,0 +1,397 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petal-labs/petalflow/server"
+)
+
+// workflowSummary is the subset of a daemon workflow record the CLI lists.
+type workflowSummary struct {
+	ID        string                  `json:"id"`
+	Kind      string                  `json:"kind"`
+	Name      string                  `json:"name,omitempty"`
+	UpdatedAt time.Time               `json:"updated_at"`
+	Lifecycle *server.LifecycleStatus `json:"lifecycle,omitempty"`
+}
+
+// scheduleSummary is the subset of a daemon schedule the CLI lists.
+type scheduleSummary struct {
+	ID         string    `json:"id"`
+	WorkflowID string    `json:"workflow_id"`
+	Cron       string    `json:"cron"`
+	Timezone   string    `json:"timezone,omitempty"`
+	Enabled    bool      `json:"enabled"`
+	NextRunAt  time.Time `json:"next_run_at"`
+	LastStatus string    `json:"last_status,omitempty"`
+}
+
+type runSummary struct {
+	RunID string `json:"run_id"`
+}
+
+// NewWorkflowsCmd creates the "workflows" command group for a running daemon.
+func NewWorkflowsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workflows",
+		Short: "Inspect workflows stored in a running daemon",
+	}
+	addDaemonFlag(cmd)
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List workflows",
+		Args:  cobra.NoArgs,
+		RunE:  runWorkflowsList,
+	}
+	listCmd.Flags().String("lifecycle", "", "Only workflows in this state: draft | active | deprecated | archived")
+	cmd.AddCommand(listCmd)
+	cmd.AddCommand(&cobra.Command{
+		Use:               "get <workflow_id>",
+		Short:             "Show a workflow record",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeFirstArg(CompleteWorkflowIDs),
+		RunE:              runWorkflowsGet,
+	})
+	exportCmd := &cobra.Command{
+		Use:               "export <workflow_id>",
+		Short:             "Print a workflow definition in the format it was submitted in",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeFirstArg(CompleteWorkflowIDs),
+		RunE:              runWorkflowsExport,
+	}
+	exportCmd.Flags().String("format", "", "Convert to this format: json | yaml (default: as submitted)")
+	exportCmd.Flags().StringP("output", "o", "", "Output file path (default: stdout)")
+	cmd.AddCommand(exportCmd)
+
+	grepCmd := &cobra.Command{
+		Use:   "grep [text]",
+		Short: "Search workflow definitions",
+		Long: `Search the definitions of stored workflows: node types, node IDs and
+descriptions, config values including templates, and metadata. Text
+matches case-insensitively; every word must appear in the workflow.`,
+		Example: `  petalflow workflows grep --node-type webhook_call --config-contains refunds
+  petalflow workflows grep "refund policy" --tag team=payments`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runWorkflowsGrep,
+	}
+	grepCmd.Flags().String("node-type", "", "Only workflows with a node of this type")
+	grepCmd.Flags().String("config-contains", "", "Only workflows with a node config value containing this text")
+	grepCmd.Flags().String("tag", "", "Only workflows with this metadata key, key=value or tags entry")
+	grepCmd.Flags().BoolP("files-with-matches", "l", false, "Print only the IDs of matching workflows")
+	cmd.AddCommand(grepCmd)
+
+	lifecycleCmd := &cobra.Command{
+		Use:   "lifecycle <workflow_id>",
+		Short: "Show or change a workflow's lifecycle state",
+		Long: `Show a workflow's lifecycle state and the transitions that led to it,
+or move it to another state with --set. Deprecated workflows still run
+but warn; archived workflows reject new runs and keep their history.`,
+		Example: `  petalflow workflows lifecycle support_triage
+  petalflow workflows lifecycle support_triage --set deprecated --reason "use v2" --replacement support_triage_v2 --sunset 2026-12-31
+  petalflow workflows lifecycle support_triage --set archived`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeFirstArg(CompleteWorkflowIDs),
+		RunE:              runWorkflowsLifecycle,
+	}
+	lifecycleCmd.Flags().String("set", "", "Move the workflow to this state: draft | active | deprecated | archived")
+	lifecycleCmd.Flags().String("reason", "", "Why the state changes, recorded in the history")
+	lifecycleCmd.Flags().String("sunset", "", "When a deprecated workflow is planned to be archived (RFC 3339 or YYYY-MM-DD)")
+	lifecycleCmd.Flags().String("replacement", "", "ID of the workflow that replaces this one")
+	cmd.AddCommand(lifecycleCmd)
+	return cmd
+}
+
+// NewRunsCmd creates the "runs" command group for a running daemon.
+func NewRunsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "runs",
+		Short: "Inspect runs recorded by a running daemon",
+	}
+	addDaemonFlag(cmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List runs with persisted events",
+		Args:  cobra.NoArgs,
+		RunE:  runRunsList,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:               "events <run_id>",
+		Short:             "Print persisted events for a run",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeFirstArg(CompleteRunIDs),
+		RunE:              runRunsEvents,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:               "output <run_id>",
+		Short:             "Print the output a run published so far",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeFirstArg(CompleteRunIDs),
+		RunE:              runRunsOutput,
+	})
+	cmd.AddCommand(newRunsExportCmd())
+	return cmd
+}
+
+// NewSchedulesCmd creates the "schedules" command group for a running daemon.
+func NewSchedulesCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schedules",
+		Short: "Inspect workflow schedules in a running daemon",
+	}
+	addDaemonFlag(cmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:               "list <workflow_id>",
+		Short:             "List schedules for a workflow",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: completeFirstArg(CompleteWorkflowIDs),
+		RunE:              runSchedulesList,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:               "get <workflow_id> <schedule_id>",
+		Short:             "Show a schedule",
+		Args:              cobra.ExactArgs(2),
+		ValidArgsFunction: completeWorkflowThenSchedule,
+		RunE:              runSchedulesGet,
+	})
+	return cmd
+}
+
+func runWorkflowsList(cmd *cobra.Command, _ []string) error {
+	path := "/api/workflows"
+	if state, _ := cmd.Flags().GetString("lifecycle"); state != "" {
+		path += "?" + url.Values{"lifecycle": {state}}.Encode()
+	}
+	var workflows []workflowSummary
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), path, &workflows); err != nil {
+		return exitError(exitRuntime, "listing workflows: %v", err)
+	}
+
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 2, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tKIND\tNAME\tSTATE\tUPDATED")
+	for _, wf := range workflows {
+		state := server.LifecycleActive
+		if wf.Lifecycle != nil {
+			state = wf.Lifecycle.State
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", wf.ID, wf.Kind, dashIfEmpty(wf.Name), state, wf.UpdatedAt.Format(time.RFC3339))
+	}
+	return writer.Flush()
+}
+
+func runWorkflowsGet(cmd *cobra.Command, args []string) error {
+	var record json.RawMessage
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), "/api/workflows/"+url.PathEscape(args[0]), &record); err != nil {
+		return exitError(exitRuntime, "getting workflow: %v", err)
+	}
+	return writeIndentedJSON(cmd, record)
+}
+
+func runWorkflowsExport(cmd *cobra.Command, args []string) error {
+	path := "/api/workflows/" + url.PathEscape(args[0]) + "/export"
+	if format, _ := cmd.Flags().GetString("format"); format != "" {
+		path += "?format=" + url.QueryEscape(format)
+	}
+	data, err := resolveDaemonClient(cmd).getRaw(cmd.Context(), path)
+	if err != nil {
+		return exitError(exitRuntime, "exporting workflow: %v", err)
+	}
+	if outputPath, _ := cmd.Flags().GetString("output"); outputPath != "" {
+		if err := os.WriteFile(outputPath, data, 0o600); err != nil {
+			return exitError(exitRuntime, "writing %s: %v", outputPath, err)
+		}
+		return nil
+	}
+	_, err = cmd.OutOrStdout().Write(data)
+	return err
+}
+
+func runWorkflowsGrep(cmd *cobra.Command, args []string) error {
+	query := url.Values{}
+	if len(args) == 1 {
+		query.Set("q", args[0])
+	}
+	for flag, param := range map[string]string{"node-type": "node_type", "config-contains": "config_contains", "tag": "tag"} {
+		if value, _ := cmd.Flags().GetString(flag); value != "" {
+			query.Set(param, value)
+		}
+	}
+	if len(query) == 0 {
+		return exitError(exitInputParse, "give search text, --node-type, --config-contains or --tag")
+	}
+
+	var resp server.WorkflowSearchResponse
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), "/api/workflows/search?"+query.Encode(), &resp); err != nil {
+		return exitError(exitRuntime, "searching workflows: %v", err)
+	}
+	if idsOnly, _ := cmd.Flags().GetBool("files-with-matches"); idsOnly {
+		for _, result := range resp.Results {
+			fmt.Fprintln(cmd.OutOrStdout(), result.ID)
+		}
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 2, 2, ' ', 0)
+	fmt.Fprintln(writer, "WORKFLOW\tNODE\tFIELD\tTEXT")
+	for _, result := range resp.Results {
+		for _, match := range result.Matches {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", result.ID, dashIfEmpty(match.NodeID), match.Field, strings.ReplaceAll(match.Text, "\n", " "))
+		}
+	}
+	return writer.Flush()
+}
+
+func runWorkflowsLifecycle(cmd *cobra.Command, args []string) error {
+	client := resolveDaemonClient(cmd)
+	path := "/api/workflows/" + url.PathEscape(args[0]) + "/lifecycle"
+
+	var lifecycle server.WorkflowLifecycle
+	if state, _ := cmd.Flags().GetString("set"); state != "" {
+		body := map[string]any{"state": state}
+		for _, flag := range []string{"reason", "replacement"} {
+			if value, _ := cmd.Flags().GetString(flag); value != "" {
+				body[flag] = value
+			}
+		}
+		if raw, _ := cmd.Flags().GetString("sunset"); raw != "" {
+			sunset, err := parseSunset(raw)
+			if err != nil {
+				return exitError(exitInputParse, "invalid --sunset: %v", err)
+			}
+			body["sunset_at"] = sunset
+		}
+		if err := client.doJSON(cmd.Context(), http.MethodPost, path, body, &lifecycle); err != nil {
+			return exitError(exitRuntime, "changing workflow lifecycle: %v", err)
+		}
+	} else if err := client.getJSON(cmd.Context(), path, &lifecycle); err != nil {
+		return exitError(exitRuntime, "getting workflow lifecycle: %v", err)
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "State: %s\n", lifecycle.State)
+	if lifecycle.Reason != "" {
+		fmt.Fprintf(out, "Reason: %s\n", lifecycle.Reason)
+	}
+	if lifecycle.SunsetAt != nil {
+		fmt.Fprintf(out, "Sunset: %s\n", lifecycle.SunsetAt.Format(time.RFC3339))
+	}
+	if lifecycle.Replacement != "" {
+		fmt.Fprintf(out, "Replacement: %s\n", lifecycle.Replacement)
+	}
+	if len(lifecycle.History) == 0 {
+		return nil
+	}
+	fmt.Fprintln(out)
+	writer := tabwriter.NewWriter(out, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(writer, "AT\tFROM\tTO\tACTOR\tREASON")
+	for _, t := range lifecycle.History {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", t.At.Format(time.RFC3339), t.From, t.To, dashIfEmpty(t.Actor), dashIfEmpty(t.Reason))
+	}
+	return writer.Flush()
+}
+
+// parseSunset reads an RFC 3339 time or a date, taken as midnight UTC.
+func parseSunset(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
+
+func runRunsList(cmd *cobra.Command, _ []string) error {
+	var runs []runSummary
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), "/api/runs", &runs); err != nil {
+		return exitError(exitRuntime, "listing runs: %v", err)
+	}
+	for _, run := range runs {
+		fmt.Fprintln(cmd.OutOrStdout(), run.RunID)
+	}
+	return nil
+}
+
+func runRunsEvents(cmd *cobra.Command, args []string) error {
+	var events json.RawMessage
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), "/api/runs/"+url.PathEscape(args[0])+"/events", &events); err != nil {
+		return exitError(exitRuntime, "getting run events: %v", err)
+	}
+	return writeIndentedJSON(cmd, events)
+}
+
+func runRunsOutput(cmd *cobra.Command, args []string) error {
+	var output json.RawMessage
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), "/api/runs/"+url.PathEscape(args[0])+"/output", &output); err != nil {
+		return exitError(exitRuntime, "getting run output: %v", err)
+	}
+	return writeIndentedJSON(cmd, output)
+}
+
+func runSchedulesList(cmd *cobra.Command, args []string) error {
+	var schedules []scheduleSummary
+	path := "/api/workflows/" + url.PathEscape(args[0]) + "/schedules"
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), path, &schedules); err != nil {
+		return exitError(exitRuntime, "listing schedules: %v", err)
+	}
+
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 2, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tCRON\tTIMEZONE\tENABLED\tNEXT RUN\tLAST STATUS")
+	for _, sched := range schedules {
+		fmt.Fprintf(
+			writer,
+			"%s\t%s\t%s\t%t\t%s\t%s\n",
+			sched.ID,
+			sched.Cron,
+			scheduleTimezone(sched.Timezone),
+			sched.Enabled,
+			sched.NextRunAt.Format(time.RFC3339),
+			dashIfEmpty(sched.LastStatus),
+		)
+	}
+	return writer.Flush()
+}
+
+func scheduleTimezone(timezone string) string {
+	if timezone == "" {
+		return "UTC"
+	}
+	return timezone
+}
+
+func runSchedulesGet(cmd *cobra.Command, args []string) error {
+	var schedule json.RawMessage
+	path := "/api/workflows/" + url.PathEscape(args[0]) + "/schedules/" + url.PathEscape(args[1])
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), path, &schedule); err != nil {
+		return exitError(exitRuntime, "getting schedule: %v", err)
+	}
+	return writeIndentedJSON(cmd, schedule)
+}
+
+func writeIndentedJSON(cmd *cobra.Command, raw json.RawMessage) error {
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return exitError(exitRuntime, "decoding response: %v", err)
+	}
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return exitError(exitRuntime, "encoding response: %v", err)
+	}
+	_, _ = cmd.OutOrStdout().Write(append(data, '\n'))
+	return nil
+}
+
+func dashIfEmpty(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
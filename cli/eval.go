@@ -0,0 +1,359 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/evals"
+	"github.com/petal-labs/petalflow/llmprovider"
+	"github.com/petal-labs/petalflow/runtime"
+	"github.com/petal-labs/petalflow/server"
+)
+
+// NewEvalCmd creates the "eval" command, which scores a workflow against a
+// dataset, and its "datasets" subcommands for datasets stored in a daemon.
+func NewEvalCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "eval --workflow <file> --dataset <file|name>",
+		Short: "Score a workflow against an evaluation dataset",
+		Long: `Run a workflow once per dataset example, score each output with the
+dataset's scorers and print a report. The command exits with status 7 when
+the share of passing examples is below the dataset's pass_threshold, so it
+can gate prompt changes in CI.
+
+--dataset is a YAML or JSON file, or the name of a dataset stored in the
+daemon. Use --simulate to run against the workflow's canned responses
+instead of live providers; judge scorers always call their provider.`,
+		Args: cobra.NoArgs,
+		RunE: runEval,
+	}
+	addDaemonFlag(cmd)
+	cmd.Flags().String("workflow", "", "Workflow file to evaluate")
+	cmd.Flags().String("dataset", "", "Dataset file, or the name of a dataset stored in the daemon")
+	_ = cmd.MarkFlagRequired("workflow")
+	_ = cmd.MarkFlagRequired("dataset")
+	cmd.Flags().String("format", "text", "Report format: text | json")
+	cmd.Flags().String("report", "", "Also write the JSON report to this file")
+	cmd.Flags().Float64("pass-threshold", -1, "Override the dataset's pass_threshold (0-1)")
+	cmd.Flags().Int("concurrency", 1, "Examples to run at once")
+	cmd.Flags().Duration("timeout", 5*time.Minute, "Timeout per example")
+	cmd.Flags().StringArray("env", nil, "Set environment variable (repeatable)")
+	cmd.Flags().StringArray("provider-key", nil, "Set provider API key (repeatable, e.g. --provider-key anthropic=sk-...)")
+	_ = cmd.RegisterFlagCompletionFunc("provider-key", completeProviderKeyFlag)
+	cmd.Flags().String("store-path", "", "Path to SQLite store for tool registry (default: ~/.petalflow/petalflow.db)")
+	cmd.Flags().Bool("simulate", false, "Use canned LLM and tool responses from the workflow's simulate section")
+	cmd.Flags().String("simulate-file", "", "JSON file of simulated responses layered over the workflow's (implies --simulate)")
+
+	cmd.AddCommand(newEvalDatasetsCmd())
+	return cmd
+}
+
+func runEval(cmd *cobra.Command, _ []string) error {
+	workflowPath, _ := cmd.Flags().GetString("workflow")
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		return exitError(exitInputParse, "unknown format %q (use text or json)", format)
+	}
+
+	ds, err := loadEvalDataset(cmd)
+	if err != nil {
+		return err
+	}
+	if threshold, _ := cmd.Flags().GetFloat64("pass-threshold"); threshold >= 0 {
+		ds.PassThreshold = &threshold
+	}
+	ds.Normalize()
+	if err := ds.Validate(); err != nil {
+		return exitError(exitValidation, "%v", err)
+	}
+
+	explicitStore := hasRunExplicitStore(cmd)
+	store, err := resolveToolStore(cmd)
+	if err != nil {
+		if explicitStore {
+			return exitError(exitRuntime, "loading tool store: %v", err)
+		}
+		store = runNoopToolStore{}
+	}
+	defer closeToolStore(store)
+	if err := syncRunToolNodeTypes(cmd.Context(), store); err != nil {
+		return exitError(exitRuntime, "syncing tool node types: %v", err)
+	}
+
+	gd, err := loadWorkflowForRun(cmd, workflowPath, "")
+	if err != nil {
+		return err
+	}
+	providers, err := resolveRunProviders(cmd)
+	if err != nil {
+		return err
+	}
+	toolRegistry, err := buildRunToolRegistry(cmd, store)
+	if err != nil {
+		return err
+	}
+	simulation, err := resolveRunSimulation(cmd, gd)
+	if err != nil {
+		return err
+	}
+	// Hydrate once up front so configuration errors fail the command
+	// instead of every example.
+	if _, err := hydrateRunGraph(cmd, gd, providers, toolRegistry, simulation); err != nil {
+		return err
+	}
+	applyRunEnvVars(cmd)
+
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	runner := evals.Runner{
+		Concurrency: concurrency,
+		Target: func(ctx context.Context, input map[string]any) (*core.Envelope, error) {
+			// Each example gets freshly hydrated nodes so state such as
+			// caches does not leak between examples.
+			execGraph, err := hydrateRunGraph(cmd, gd, providers, toolRegistry, simulation)
+			if err != nil {
+				return nil, err
+			}
+			runCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			return runtime.NewRuntime().Run(runCtx, execGraph, server.EnvelopeFromJSON(input), runtime.DefaultRunOptions())
+		},
+		Judges: func(provider string) (core.LLMClient, error) {
+			cfg, ok := providers[provider]
+			if !ok {
+				return nil, fmt.Errorf("provider %q not configured", provider)
+			}
+			return llmprovider.NewClient(provider, cfg)
+		},
+	}
+
+	report, err := runner.Run(cmd.Context(), ds)
+	if err != nil {
+		return exitError(exitRuntime, "running eval: %v", err)
+	}
+	report.Workflow = gd.ID
+
+	if reportPath, _ := cmd.Flags().GetString("report"); reportPath != "" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return exitError(exitRuntime, "encoding report: %v", err)
+		}
+		if err := os.WriteFile(reportPath, append(data, '\n'), 0o600); err != nil {
+			return exitError(exitRuntime, "writing report: %v", err)
+		}
+	}
+	if format == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return exitError(exitRuntime, "encoding report: %v", err)
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), string(data))
+	} else {
+		writeEvalReport(cmd.OutOrStdout(), report)
+	}
+
+	if !report.Passed {
+		return exitError(exitEvalFailed, "eval failed: %.1f%% of examples passed, %.1f%% required", report.PassRate*100, report.PassThreshold*100)
+	}
+	return nil
+}
+
+// loadEvalDataset reads --dataset from disk, or from the daemon when no
+// such file exists.
+func loadEvalDataset(cmd *cobra.Command) (*evals.Dataset, error) {
+	ref, _ := cmd.Flags().GetString("dataset")
+	data, err := os.ReadFile(ref) // #nosec G304 -- path from user CLI flag
+	if err == nil {
+		ds, err := evals.ParseDataset(data)
+		if err != nil {
+			return nil, exitError(exitInputParse, "parsing %s: %v", ref, err)
+		}
+		if ds.Name == "" {
+			ds.Name = strings.TrimSuffix(filepath.Base(ref), filepath.Ext(ref))
+		}
+		return ds, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, exitError(exitRuntime, "reading %s: %v", ref, err)
+	}
+
+	var ds evals.Dataset
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), "/api/evals/datasets/"+url.PathEscape(ref), &ds); err != nil {
+		var statusErr *daemonStatusError
+		if errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound {
+			return nil, exitError(exitFileNotFound, "dataset %q is neither a file nor stored in the daemon", ref)
+		}
+		return nil, exitError(exitRuntime, "fetching dataset %q: %v", ref, err)
+	}
+	return &ds, nil
+}
+
+func writeEvalReport(out io.Writer, report *evals.Report) {
+	passed := 0
+	for _, ex := range report.Examples {
+		if ex.Passed {
+			passed++
+		}
+	}
+	fmt.Fprintf(out, "Dataset %s: %d/%d examples passed (%.1f%%), score %.2f\n\n",
+		report.Dataset, passed, len(report.Examples), report.PassRate*100, report.Score)
+
+	writer := tabwriter.NewWriter(out, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(writer, "RESULT\tEXAMPLE\tSCORE\tDETAILS")
+	for _, ex := range report.Examples {
+		result := "PASS"
+		if !ex.Passed {
+			result = "FAIL"
+		}
+		var details []string
+		if ex.Error != "" {
+			details = append(details, "error: "+ex.Error)
+		}
+		for _, s := range ex.Scores {
+			if s.Passed {
+				continue
+			}
+			detail := fmt.Sprintf("%s=%.2f", s.Scorer, s.Value)
+			if s.Reason != "" {
+				detail += " (" + s.Reason + ")"
+			}
+			details = append(details, detail)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%.2f\t%s\n", result, ex.ID, ex.Score, dashIfEmpty(strings.Join(details, "; ")))
+	}
+	_ = writer.Flush()
+
+	fmt.Fprintln(out)
+	writer = tabwriter.NewWriter(out, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(writer, "SCORER\tTYPE\tMEAN\tPASS RATE\tTHRESHOLD")
+	for _, sc := range report.Scorers {
+		fmt.Fprintf(writer, "%s\t%s\t%.2f\t%.1f%%\t%.2f\n", sc.Name, sc.Type, sc.Mean, sc.PassRate*100, sc.Threshold)
+	}
+	_ = writer.Flush()
+
+	verdict := "PASS"
+	if !report.Passed {
+		verdict = "FAIL"
+	}
+	fmt.Fprintf(out, "\n%s (pass threshold %.1f%%)\n", verdict, report.PassThreshold*100)
+}
+
+func newEvalDatasetsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "datasets",
+		Short: "Manage evaluation datasets stored in a running daemon",
+	}
+	cmd.AddCommand(&cobra.Command{
+		Use:   "list",
+		Short: "List datasets",
+		Args:  cobra.NoArgs,
+		RunE:  runEvalDatasetsList,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get <name>",
+		Short: "Show a dataset",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runEvalDatasetsGet,
+	})
+	pushCmd := &cobra.Command{
+		Use:   "push <file>",
+		Short: "Create or replace a dataset from a YAML or JSON file",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runEvalDatasetsPush,
+	}
+	pushCmd.Flags().String("name", "", "Dataset name (default: the file's name field, else its base name)")
+	cmd.AddCommand(pushCmd)
+	cmd.AddCommand(&cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a dataset",
+		Args:  cobra.ExactArgs(1),
+		RunE:  runEvalDatasetsDelete,
+	})
+	return cmd
+}
+
+func runEvalDatasetsList(cmd *cobra.Command, _ []string) error {
+	var datasets []evals.Dataset
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), "/api/evals/datasets", &datasets); err != nil {
+		return exitError(exitRuntime, "listing datasets: %v", err)
+	}
+
+	writer := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 2, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tEXAMPLES\tSCORERS\tUPDATED")
+	for _, ds := range datasets {
+		scorers := make([]string, 0, len(ds.Scorers))
+		for _, sc := range ds.Scorers {
+			scorers = append(scorers, sc.Name)
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%s\t%s\n", ds.Name, len(ds.Examples), dashIfEmpty(strings.Join(scorers, ",")), ds.UpdatedAt.Format(time.RFC3339))
+	}
+	return writer.Flush()
+}
+
+func runEvalDatasetsGet(cmd *cobra.Command, args []string) error {
+	var ds json.RawMessage
+	if err := resolveDaemonClient(cmd).getJSON(cmd.Context(), "/api/evals/datasets/"+url.PathEscape(args[0]), &ds); err != nil {
+		return exitError(exitRuntime, "getting dataset: %v", err)
+	}
+	return writeIndentedJSON(cmd, ds)
+}
+
+func runEvalDatasetsPush(cmd *cobra.Command, args []string) error {
+	path := args[0]
+	data, err := os.ReadFile(path) // #nosec G304 -- path from user CLI argument
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return exitError(exitFileNotFound, "file not found: %s", path)
+		}
+		return exitError(exitRuntime, "reading %s: %v", path, err)
+	}
+	ds, err := evals.ParseDataset(data)
+	if err != nil {
+		return exitError(exitInputParse, "parsing %s: %v", path, err)
+	}
+	if name, _ := cmd.Flags().GetString("name"); name != "" {
+		ds.Name = name
+	}
+	if ds.Name == "" {
+		ds.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	ds.Normalize()
+	if err := ds.Validate(); err != nil {
+		return exitError(exitValidation, "%s: %v", path, err)
+	}
+
+	client := resolveDaemonClient(cmd)
+	action := "Updated"
+	err = client.doJSON(cmd.Context(), http.MethodPut, "/api/evals/datasets/"+url.PathEscape(ds.Name), ds, nil)
+	var statusErr *daemonStatusError
+	if errors.As(err, &statusErr) && statusErr.Status == http.StatusNotFound {
+		action = "Created"
+		err = client.doJSON(cmd.Context(), http.MethodPost, "/api/evals/datasets", ds, nil)
+	}
+	if err != nil {
+		return exitError(exitRuntime, "pushing dataset %q: %v", ds.Name, err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s dataset %s (%d examples)\n", action, ds.Name, len(ds.Examples))
+	return nil
+}
+
+func runEvalDatasetsDelete(cmd *cobra.Command, args []string) error {
+	if err := resolveDaemonClient(cmd).doJSON(cmd.Context(), http.MethodDelete, "/api/evals/datasets/"+url.PathEscape(args[0]), nil, nil); err != nil {
+		return exitError(exitRuntime, "deleting dataset: %v", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Deleted dataset %s\n", args[0])
+	return nil
+}
@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/evals"
+)
+
+const evalDatasetYAML = `
+name: answers
+scorers:
+  - type: exact
+  - name: mentions_question
+    type: contains
+    field: output
+    ignore_case: true
+examples:
+  - id: why
+    input: {question: "why?"}
+    expected: "simulated reply to Question: why?"
+  - id: how
+    input: {question: "how?"}
+    expected: "something else"
+`
+
+func TestEval_File(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	workflow := writeTestFile(t, "simulated.json", simulatedGraphJSON)
+	dataset := writeTestFile(t, "answers.yaml", evalDatasetYAML)
+	reportPath := t.TempDir() + "/report.json"
+
+	root := newTestRoot()
+	root.AddCommand(NewEvalCmd())
+	stdout, _, err := executeCommand(root, "eval", "--workflow", workflow, "--dataset", dataset, "--simulate", "--report", reportPath)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != exitEvalFailed {
+		t.Fatalf("err = %v, want exit code %d", err, exitEvalFailed)
+	}
+	for _, want := range []string{"1/2 examples passed", "PASS", "FAIL", "how", "mentions_question"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("report missing %q:\n%s", want, stdout)
+		}
+	}
+
+	data, err := os.ReadFile(reportPath)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var report evals.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if report.Workflow != "simulated_graph" || report.PassRate != 0.5 || len(report.Scorers) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	root = newTestRoot()
+	root.AddCommand(NewEvalCmd())
+	stdout, _, err = executeCommand(root, "eval", "--workflow", workflow, "--dataset", dataset, "--simulate", "--pass-threshold", "0.5", "--format", "json")
+	if err != nil {
+		t.Fatalf("eval with lowered threshold: %v", err)
+	}
+	if err := json.Unmarshal([]byte(stdout), &report); err != nil || !report.Passed {
+		t.Fatalf("json report = %s (err %v)", stdout, err)
+	}
+}
+
+func TestEval_DaemonDataset(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	ds, err := evals.ParseDataset([]byte(evalDatasetYAML))
+	if err != nil {
+		t.Fatalf("parse dataset: %v", err)
+	}
+	ds.Examples = ds.Examples[:1]
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/evals/datasets/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("name") != "answers" {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"NOT_FOUND","message":"dataset not found"}}`))
+			return
+		}
+		_ = json.NewEncoder(w).Encode(ds)
+	})
+	daemon := httptest.NewServer(mux)
+	t.Cleanup(daemon.Close)
+	workflow := writeTestFile(t, "simulated.json", simulatedGraphJSON)
+
+	root := newTestRoot()
+	root.AddCommand(NewEvalCmd())
+	stdout, _, err := executeCommand(root, "eval", "--workflow", workflow, "--dataset", "answers", "--simulate", "--daemon", daemon.URL)
+	if err != nil {
+		t.Fatalf("eval: %v\n%s", err, stdout)
+	}
+	if !strings.Contains(stdout, "1/1 examples passed") {
+		t.Fatalf("unexpected output:\n%s", stdout)
+	}
+
+	root = newTestRoot()
+	root.AddCommand(NewEvalCmd())
+	_, _, err = executeCommand(root, "eval", "--workflow", workflow, "--dataset", "missing", "--simulate", "--daemon", daemon.URL)
+	var exitErr *ExitError
+	if !errors.As(err, &exitErr) || exitErr.Code != exitFileNotFound {
+		t.Fatalf("missing dataset err = %v", err)
+	}
+}
+
+func TestEvalDatasetsPush(t *testing.T) {
+	var created, updated int
+	mux := http.NewServeMux()
+	mux.HandleFunc("PUT /api/evals/datasets/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if created == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error":{"code":"NOT_FOUND","message":"dataset not found"}}`))
+			return
+		}
+		updated++
+		_, _ = w.Write([]byte(`{}`))
+	})
+	mux.HandleFunc("POST /api/evals/datasets", func(w http.ResponseWriter, r *http.Request) {
+		var ds evals.Dataset
+		_ = json.NewDecoder(r.Body).Decode(&ds)
+		if ds.Name != "answers" || len(ds.Examples) != 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		created++
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{}`))
+	})
+	daemon := httptest.NewServer(mux)
+	t.Cleanup(daemon.Close)
+	path := writeTestFile(t, "answers.yaml", evalDatasetYAML)
+
+	for _, want := range []string{"Created dataset answers (2 examples)", "Updated dataset answers"} {
+		root := newTestRoot()
+		root.AddCommand(NewEvalCmd())
+		stdout, _, err := executeCommand(root, "eval", "datasets", "push", path, "--daemon", daemon.URL)
+		if err != nil {
+			t.Fatalf("push: %v", err)
+		}
+		if !strings.Contains(stdout, want) {
+			t.Fatalf("stdout = %q, want %q", stdout, want)
+		}
+	}
+	if created != 1 || updated != 1 {
+		t.Fatalf("created=%d updated=%d", created, updated)
+	}
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/loader"
+	"github.com/petal-labs/petalflow/registry"
+	"github.com/petal-labs/petalflow/server"
+)
+
+// NewMigrateCmd creates the "migrate" subcommand.
+func NewMigrateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Rewrite stored workflows off deprecated node types",
+		Long: `Rewrite workflows stored in the daemon database so nodes using deprecated
+node type aliases use the current type, with their config remapped.`,
+		Args: cobra.NoArgs,
+		RunE: runMigrate,
+	}
+
+	cmd.Flags().String("config", "", "Path to petalflow.yaml (server.stores.sqlite_path)")
+	cmd.Flags().String("sqlite-path", "", "Path to SQLite database (default: $PETALFLOW_SQLITE_PATH, petalflow.yaml or ~/.petalflow/petalflow.db)")
+	cmd.Flags().Bool("dry-run", false, "Report the migrations without writing them")
+
+	return cmd
+}
+
+func runMigrate(cmd *cobra.Command, _ []string) error {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	// Resolve the database the way serve does, so both open the same one.
+	cfg, _, err := resolveServeConfig(cmd)
+	if err != nil {
+		return err
+	}
+	dsn, _, err := resolveServeSQLiteDSN(cfg.Stores.SQLitePath)
+	if err != nil {
+		return err
+	}
+
+	store, err := server.NewSQLiteStore(server.SQLiteStoreConfig{DSN: dsn})
+	if err != nil {
+		return exitError(exitRuntime, "opening sqlite workflow store: %v", err)
+	}
+	defer func() {
+		_ = store.Close()
+	}()
+
+	records, err := store.List(cmd.Context())
+	if err != nil {
+		return exitError(exitRuntime, "listing workflows: %v", err)
+	}
+
+	out := cmd.OutOrStdout()
+	verb := "migrated"
+	if dryRun {
+		verb = "would migrate"
+	}
+	nodeCount, workflowCount := 0, 0
+	for _, rec := range records {
+		migrated, changed, err := migrateWorkflowRecord(rec, registry.Global())
+		if err != nil {
+			return exitError(exitValidation, "workflow %q: %v", rec.ID, err)
+		}
+		if len(changed) == 0 {
+			continue
+		}
+		for _, m := range changed {
+			fmt.Fprintf(out, "%s: %s node %q %s -> %s\n", rec.ID, verb, m.NodeID, m.From, m.To)
+		}
+		nodeCount += len(changed)
+		workflowCount++
+		if dryRun {
+			continue
+		}
+		if err := store.Update(cmd.Context(), migrated); err != nil {
+			return exitError(exitRuntime, "saving workflow %q: %v", rec.ID, err)
+		}
+	}
+
+	if workflowCount == 0 {
+		fmt.Fprintln(out, "No workflows use deprecated node types")
+		return nil
+	}
+	fmt.Fprintf(out, "%d node(s) in %d workflow(s) %s\n", nodeCount, workflowCount, verb)
+	return nil
+}
+
+// migrateWorkflowRecord moves a stored workflow's compiled graph off
+// deprecated node types. Graph workflows also have their source rewritten;
+// agent workflow sources do not name node types and are kept as written.
+func migrateWorkflowRecord(rec server.WorkflowRecord, reg *registry.Registry) (server.WorkflowRecord, []graph.NodeTypeMigration, error) {
+	if rec.Compiled == nil {
+		return rec, nil, nil
+	}
+	compiled, changed, err := rec.Compiled.MigrateNodeTypes(reg)
+	if err != nil || len(changed) == 0 {
+		return rec, nil, err
+	}
+
+	rec.Compiled = compiled
+	if rec.SchemaKind == loader.SchemaKindGraph && len(rec.Source) > 0 {
+		source, err := migrateGraphSource(rec.Source, compiled, changed)
+		if err != nil {
+			return rec, nil, err
+		}
+		rec.Source = source
+		if rec.SourceFormat == loader.FormatYAML {
+			// The verbatim text no longer matches; re-render it from the
+			// migrated source.
+			text, err := loader.ToYAML(source)
+			if err != nil {
+				return rec, nil, err
+			}
+			rec.SourceText = string(text)
+		}
+	}
+	rec.UpdatedAt = time.Now().UTC()
+	return rec, changed, nil
+}
+
+// migrateGraphSource updates the migrated nodes in a graph source document
+// while leaving every other field as stored.
+func migrateGraphSource(source json.RawMessage, compiled *graph.GraphDefinition, changed []graph.NodeTypeMigration) (json.RawMessage, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(source, &doc); err != nil {
+		return nil, fmt.Errorf("parsing source: %w", err)
+	}
+	nodes, _ := doc["nodes"].([]any)
+
+	migratedByID := make(map[string]graph.NodeDef, len(changed))
+	for _, node := range compiled.Nodes {
+		migratedByID[node.ID] = node
+	}
+	for _, m := range changed {
+		for _, raw := range nodes {
+			node, ok := raw.(map[string]any)
+			if !ok || node["id"] != m.NodeID {
+				continue
+			}
+			current := migratedByID[m.NodeID]
+			node["type"] = current.Type
+			if current.Config != nil {
+				node["config"] = current.Config
+			}
+		}
+	}
+
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("encoding source: %w", err)
+	}
+	return data, nil
+}
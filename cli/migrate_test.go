@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/loader"
+	"github.com/petal-labs/petalflow/server"
+)
+
+func TestMigrate_RewritesDeprecatedNodeTypes(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "petalflow.db")
+	store, err := server.NewSQLiteStore(server.SQLiteStoreConfig{DSN: dbPath})
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+
+	gd := &graph.GraphDefinition{
+		ID:      "legacy",
+		Version: "1.0",
+		Nodes: []graph.NodeDef{
+			{ID: "notify", Type: "webhook_send", Config: map[string]any{"endpoint": "https://example.com"}},
+		},
+		Entry: "notify",
+	}
+	source, _ := json.Marshal(gd)
+	now := time.Now().UTC()
+	if err := store.Create(context.Background(), server.WorkflowRecord{
+		ID:         "legacy",
+		SchemaKind: loader.SchemaKindGraph,
+		Source:     source,
+		Compiled:   gd,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	_ = store.Close()
+
+	configPath := filepath.Join(t.TempDir(), "petalflow.yaml")
+	if err := os.WriteFile(configPath, []byte("server:\n  stores:\n    sqlite_path: "+dbPath+"\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	run := func(args ...string) string {
+		t.Helper()
+		root := &cobra.Command{Use: "petalflow", SilenceUsage: true}
+		root.AddCommand(NewMigrateCmd())
+		stdout, _, err := executeCommand(root, append([]string{"migrate"}, args...)...)
+		if err != nil {
+			t.Fatalf("migrate %v: %v", args, err)
+		}
+		return stdout
+	}
+	migrate := func(args ...string) string {
+		t.Helper()
+		return run(append([]string{"--sqlite-path", dbPath}, args...)...)
+	}
+
+	// The database configured in petalflow.yaml, as serve uses it.
+	if out := run("--config", configPath, "--dry-run"); !strings.Contains(out, `would migrate node "notify" webhook_send -> webhook_call`) {
+		t.Fatalf("dry run from config output = %q", out)
+	}
+	if out := migrate("--dry-run"); !strings.Contains(out, `would migrate node "notify" webhook_send -> webhook_call`) {
+		t.Fatalf("dry run output = %q", out)
+	}
+	if out := migrate(); !strings.Contains(out, "1 node(s) in 1 workflow(s) migrated") {
+		t.Fatalf("migrate output = %q", out)
+	}
+	if out := migrate(); !strings.Contains(out, "No workflows use deprecated node types") {
+		t.Fatalf("second migrate output = %q", out)
+	}
+
+	store, err = server.NewSQLiteStore(server.SQLiteStoreConfig{DSN: dbPath})
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	defer store.Close()
+	rec, _, err := store.Get(context.Background(), "legacy")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if node := rec.Compiled.Nodes[0]; node.Type != "webhook_call" || node.Config["url"] != "https://example.com" {
+		t.Errorf("compiled node = %+v", node)
+	}
+	if !strings.Contains(string(rec.Source), `"webhook_call"`) || strings.Contains(string(rec.Source), "endpoint") {
+		t.Errorf("source not migrated: %s", rec.Source)
+	}
+}
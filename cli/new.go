@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petal-labs/petalflow/templates"
+)
+
+// NewNewCmd creates the "new" subcommand.
+func NewNewCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "new",
+		Short: "Create a workflow file from a template",
+		Long: `Create a workflow definition from one of the built-in templates, filling in
+its parameters, for example:
+
+  petalflow new --template rag-qa --param retriever_url=https://search.internal/query
+
+The file is written to --output, or <workflow id>.json in the current
+directory. Use --list to see the templates and their parameters.`,
+		Args: cobra.NoArgs,
+		RunE: runNew,
+	}
+
+	cmd.Flags().String("template", "", "Template ID")
+	cmd.Flags().StringArray("param", nil, "Template parameter as name=value (repeatable)")
+	cmd.Flags().String("id", "", "Workflow ID (default: the template's)")
+	cmd.Flags().StringP("output", "o", "", "Output file path, or - for stdout (default: <workflow id>.json)")
+	cmd.Flags().Bool("force", false, "Overwrite an existing output file")
+	cmd.Flags().Bool("list", false, "List the available templates")
+
+	return cmd
+}
+
+func runNew(cmd *cobra.Command, _ []string) error {
+	if list, _ := cmd.Flags().GetBool("list"); list {
+		return listTemplates(cmd)
+	}
+
+	id, _ := cmd.Flags().GetString("template")
+	if id == "" {
+		return exitError(exitInputParse, "--template is required (see --list)")
+	}
+	tmpl, ok := templates.Builtin(id)
+	if !ok {
+		return exitError(exitInputParse, "unknown template %q (see --list)", id)
+	}
+
+	paramFlags, _ := cmd.Flags().GetStringArray("param")
+	values := make(map[string]string, len(paramFlags))
+	for _, p := range paramFlags {
+		name, value, ok := strings.Cut(p, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return exitError(exitInputParse, "invalid --param %q: want name=value", p)
+		}
+		values[strings.TrimSpace(name)] = value
+	}
+
+	workflowID, _ := cmd.Flags().GetString("id")
+	data, err := tmpl.Instantiate(values, workflowID)
+	if err != nil {
+		return exitError(exitValidation, "%v", err)
+	}
+	data = append(data, '\n')
+
+	outputPath, _ := cmd.Flags().GetString("output")
+	if outputPath == "-" {
+		_, err := cmd.OutOrStdout().Write(data)
+		return err
+	}
+	if outputPath == "" {
+		if workflowID == "" {
+			workflowID = tmpl.ID
+		}
+		outputPath = workflowID + ".json"
+	}
+	if force, _ := cmd.Flags().GetBool("force"); !force {
+		if _, err := os.Stat(outputPath); err == nil {
+			return exitError(exitValidation, "%s already exists (use --force to overwrite)", outputPath)
+		}
+	}
+	if err := os.WriteFile(outputPath, data, 0600); err != nil {
+		return fmt.Errorf("writing output file: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Created %s from template %s\n", outputPath, tmpl.ID)
+	return nil
+}
+
+func listTemplates(cmd *cobra.Command) error {
+	tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tPARAMETERS\tDESCRIPTION")
+	for _, t := range templates.Builtins() {
+		params := make([]string, 0, len(t.Params))
+		for _, p := range t.Params {
+			switch {
+			case p.Required:
+				params = append(params, p.Name+" (required)")
+			case p.Default != "":
+				params = append(params, p.Name+"="+p.Default)
+			default:
+				params = append(params, p.Name)
+			}
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", t.ID, strings.Join(params, ", "), t.Description)
+	}
+	return tw.Flush()
+}
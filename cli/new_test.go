@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNew_FromTemplate(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "qa.json")
+	stdout, _, err := executeCommand(newTestRoot(), "new", "--template", "rag-qa", "--id", "support-qa",
+		"--param", "retriever_url=https://search.internal/query", "--param", "model=gpt-4o", "-o", out)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	if !strings.Contains(stdout, "Created "+out) {
+		t.Errorf("stdout = %q", stdout)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read output: %v", err)
+	}
+	var def map[string]any
+	if err := json.Unmarshal(data, &def); err != nil {
+		t.Fatalf("decode output: %v", err)
+	}
+	if def["id"] != "support-qa" || !strings.Contains(string(data), "https://search.internal/query") || !strings.Contains(string(data), `"gpt-4o"`) {
+		t.Errorf("output = %s", data)
+	}
+
+	// The file is validated like any hand-written workflow.
+	if _, _, err := executeCommand(newTestRoot(), "validate", out); err != nil {
+		t.Errorf("validate generated workflow: %v", err)
+	}
+
+	if _, _, err := executeCommand(newTestRoot(), "new", "--template", "rag-qa", "--param", "retriever_url=https://x", "-o", out); err == nil {
+		t.Error("expected an error overwriting without --force")
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"missing template", []string{"new"}, "--template is required"},
+		{"unknown template", []string{"new", "--template", "nope"}, "unknown template"},
+		{"missing param", []string{"new", "--template", "rag-qa", "-o", "-"}, "requires retriever_url"},
+		{"bad param", []string{"new", "--template", "chat-assistant", "--param", "model"}, "want name=value"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := executeCommand(newTestRoot(), tt.args...)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew_List(t *testing.T) {
+	stdout, _, err := executeCommand(newTestRoot(), "new", "--list")
+	if err != nil {
+		t.Fatalf("new --list: %v", err)
+	}
+	for _, want := range []string{"rag-qa", "retriever_url (required)", "model=gpt-4o-mini"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("list missing %q:\n%s", want, stdout)
+		}
+	}
+}
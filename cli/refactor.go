@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/loader"
+	"github.com/petal-labs/petalflow/registry"
+)
+
+// NewRefactorCmd creates the "refactor" subcommand.
+func NewRefactorCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "refactor",
+		Short: "Restructure graph workflow files",
+	}
+	cmd.AddCommand(newRefactorExtractCmd())
+	return cmd
+}
+
+func newRefactorExtractCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "extract",
+		Short: "Move nodes into a subworkflow run by a subgraph node",
+		Long: `Move the selected nodes of a graph workflow into a new workflow file and
+replace them with a subgraph node that runs it.
+
+The edges into the selection are moved to the subgraph node, as are the
+edges out of it, with their handles. The selection needs one entry node
+and one last node. Before writing, the rewritten workflow is inlined again
+and compared with the original; both files are only written when they
+match.
+
+  petalflow refactor extract --workflow support.json --nodes fetch,parse,score --name enrich
+
+The subgraph node refers to the new file by workflow_ref, relative to the
+workflow. Use --inline to embed the graph instead, for example for
+workflows stored in the daemon.`,
+		Args: cobra.NoArgs,
+		RunE: runRefactorExtract,
+	}
+
+	cmd.Flags().String("workflow", "", "Graph workflow file to refactor (required)")
+	cmd.Flags().StringSlice("nodes", nil, "IDs of the nodes to extract (required)")
+	cmd.Flags().String("name", "", "ID of the subworkflow and of the subgraph node replacing the nodes (required)")
+	cmd.Flags().String("out", "", "Subworkflow file (default: <name> next to the workflow, in its format)")
+	cmd.Flags().Bool("inline", false, "Embed the subworkflow in the subgraph node instead of writing a file")
+	cmd.Flags().Bool("force", false, "Overwrite an existing subworkflow file")
+	cmd.Flags().Bool("dry-run", false, "Check the extraction and print the results without writing files")
+	_ = cmd.MarkFlagRequired("workflow")
+	_ = cmd.MarkFlagRequired("nodes")
+	_ = cmd.MarkFlagRequired("name")
+
+	return cmd
+}
+
+func runRefactorExtract(cmd *cobra.Command, _ []string) error {
+	path, _ := cmd.Flags().GetString("workflow")
+	nodeIDs, _ := cmd.Flags().GetStringSlice("nodes")
+	name, _ := cmd.Flags().GetString("name")
+	outPath, _ := cmd.Flags().GetString("out")
+	inline, _ := cmd.Flags().GetBool("inline")
+	force, _ := cmd.Flags().GetBool("force")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	stdout := cmd.OutOrStdout()
+
+	data, err := os.ReadFile(path) // #nosec G304 -- path from user CLI arg
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return exitError(exitFileNotFound, "file not found: %s", path)
+		}
+		return exitError(exitFileNotFound, "reading file: %s", err)
+	}
+	format := loader.FormatForPath(data, path)
+	docs, err := loader.SplitDocuments(data, format)
+	if err != nil {
+		return exitError(exitValidation, "%v", err)
+	}
+	if len(docs) != 1 {
+		return exitError(exitInputParse, "%s defines %d workflows (%s); refactor works on single-workflow files", path, len(docs), strings.Join(loader.DocumentIDs(docs), ", "))
+	}
+	original, kind, err := loader.LoadDocument(docs[0])
+	if err != nil {
+		return exitError(exitValidation, "%v", err)
+	}
+	if kind != loader.SchemaKindGraph {
+		return exitError(exitWrongSchema, "refactor only accepts graph workflow files")
+	}
+	reg := registry.Global()
+	if diags := original.ValidateWithRegistry(reg); graph.HasErrors(diags) {
+		printDiagnosticsText(cmd.ErrOrStderr(), graph.Errors(diags))
+		return exitError(exitValidation, "fix the workflow's %d validation error(s) before refactoring", len(graph.Errors(diags)))
+	}
+
+	parent, sub, err := original.ExtractSubgraph(nodeIDs, name)
+	if err != nil {
+		return exitError(exitValidation, "cannot extract: %v", err)
+	}
+	if diags := parent.ValidateWithRegistry(reg); graph.HasErrors(diags) {
+		printDiagnosticsText(cmd.ErrOrStderr(), graph.Errors(diags))
+		return exitError(exitValidation, "refactored workflow fails validation with %d error(s)", len(graph.Errors(diags)))
+	}
+	inlined, err := parent.InlineSubgraphs()
+	if err == nil {
+		err = original.EquivalentTo(inlined)
+	}
+	if err != nil {
+		return exitError(exitValidation, "equivalence check failed: %v", err)
+	}
+
+	if outPath == "" {
+		ext := filepath.Ext(path)
+		if ext == "" {
+			ext = ".json"
+		}
+		outPath = filepath.Join(filepath.Dir(path), name+ext)
+	}
+	if !inline {
+		ref, err := filepath.Rel(filepath.Dir(path), outPath)
+		if err != nil {
+			ref = outPath
+		}
+		setSubgraphRef(parent, name, filepath.ToSlash(ref))
+	}
+
+	parentOut, err := marshalGraphDefinitions([]*graph.GraphDefinition{parent}, true, format)
+	if err != nil {
+		return err
+	}
+	subOut, err := marshalGraphDefinitions([]*graph.GraphDefinition{sub}, true, loader.FormatForPath(nil, outPath))
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Fprintf(stdout, "# %s\n%s", path, parentOut)
+		if !inline {
+			fmt.Fprintf(stdout, "# %s\n%s", outPath, subOut)
+		}
+		fmt.Fprintln(stdout, "Equivalence check passed; no files written (dry run)")
+		return nil
+	}
+	if !inline {
+		if _, err := os.Stat(outPath); err == nil && !force {
+			return exitError(exitInputParse, "%s already exists; use --force to overwrite it or --out to pick another file", outPath)
+		}
+		if err := os.WriteFile(outPath, subOut, 0o600); err != nil {
+			return fmt.Errorf("writing subworkflow: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, parentOut, 0o600); err != nil {
+		return fmt.Errorf("writing workflow: %w", err)
+	}
+
+	target := "inline"
+	if !inline {
+		target = outPath
+	}
+	fmt.Fprintf(stdout, "Extracted %d node(s) into subgraph node %q (%s)\n", len(sub.Nodes), name, target)
+	fmt.Fprintln(stdout, "Equivalence check passed: inlining the subgraph reproduces the original graph")
+	return nil
+}
+
+// setSubgraphRef replaces the embedded graph of the subgraph node id with a
+// reference to the file defining it.
+func setSubgraphRef(gd *graph.GraphDefinition, id, ref string) {
+	for i, node := range gd.Nodes {
+		if node.ID == id {
+			gd.Nodes[i].Config = map[string]any{"workflow_ref": ref}
+		}
+	}
+}
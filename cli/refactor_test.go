@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/loader"
+)
+
+const threeStepGraphJSON = `{
+  "id": "pipeline",
+  "version": "1.0",
+  "nodes": [
+    {"id": "a", "type": "noop"},
+    {"id": "b", "type": "noop"},
+    {"id": "c", "type": "noop"}
+  ],
+  "edges": [
+    {"source": "a", "sourceHandle": "output", "target": "b", "targetHandle": "input"},
+    {"source": "b", "sourceHandle": "output", "target": "c", "targetHandle": "input"}
+  ],
+  "entry": "a"
+}`
+
+func TestRefactorExtract(t *testing.T) {
+	path := writeTestFile(t, "pipeline.json", threeStepGraphJSON)
+	root := newTestRoot()
+	root.AddCommand(NewRefactorCmd())
+	stdout, _, err := executeCommand(root, "refactor", "extract", "--workflow", path, "--nodes", "b,c", "--name", "enrich")
+	if err != nil {
+		t.Fatalf("refactor extract: %v", err)
+	}
+	for _, want := range []string{`Extracted 2 node(s) into subgraph node "enrich"`, "Equivalence check passed"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q: %q", want, stdout)
+		}
+	}
+
+	subPath := filepath.Join(filepath.Dir(path), "enrich.json")
+	if _, err := os.Stat(subPath); err != nil {
+		t.Fatalf("subworkflow not written: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"workflow_ref": "enrich.json"`) {
+		t.Errorf("workflow does not refer to the subworkflow:\n%s", data)
+	}
+
+	gd, _, err := loader.LoadWorkflow(path)
+	if err != nil {
+		t.Fatalf("loading refactored workflow: %v", err)
+	}
+	if len(gd.Nodes) != 2 || gd.Nodes[1].Config["graph"] == nil {
+		t.Errorf("refactored nodes = %+v", gd.Nodes)
+	}
+	if _, _, err := executeCommand(root, "validate", path); err != nil {
+		t.Errorf("validate refactored workflow: %v", err)
+	}
+
+	// A second extraction would overwrite enrich.json.
+	if err := os.WriteFile(path, []byte(threeStepGraphJSON), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	root = newTestRoot()
+	root.AddCommand(NewRefactorCmd())
+	_, _, err = executeCommand(root, "refactor", "extract", "--workflow", path, "--nodes", "b,c", "--name", "enrich")
+	if err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("err = %v, want an existing-file error", err)
+	}
+}
+
+func TestRefactorExtract_InlineDryRun(t *testing.T) {
+	path := writeTestFile(t, "pipeline.json", threeStepGraphJSON)
+	root := newTestRoot()
+	root.AddCommand(NewRefactorCmd())
+	stdout, _, err := executeCommand(root, "refactor", "extract", "--workflow", path, "--nodes", "a,b", "--name", "head", "--inline", "--dry-run")
+	if err != nil {
+		t.Fatalf("refactor extract: %v", err)
+	}
+	if !strings.Contains(stdout, `"graph"`) || !strings.Contains(stdout, "no files written") {
+		t.Errorf("unexpected output: %q", stdout)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != threeStepGraphJSON {
+		t.Error("dry run rewrote the workflow")
+	}
+}
+
+func TestRefactorExtract_InvalidSelection(t *testing.T) {
+	path := writeTestFile(t, "pipeline.json", threeStepGraphJSON)
+	root := newTestRoot()
+	root.AddCommand(NewRefactorCmd())
+	_, _, err := executeCommand(root, "refactor", "extract", "--workflow", path, "--nodes", "a,c", "--name", "x")
+	if err == nil || !strings.Contains(err.Error(), "cannot extract") {
+		t.Errorf("err = %v, want an extraction error", err)
+	}
+}
@@ -0,0 +1,380 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+const replHelp = `Commands:
+  :vars              Show the sample variables
+  :set name = value  Set a variable (value is JSON, or a plain string)
+  :unset name        Remove a variable
+  :help              Show this help
+  :quit              Exit (as does Ctrl-D)`
+
+// NewExprCmd creates the "expr" command, which evaluates condition
+// expressions against a sample envelope.
+func NewExprCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "expr [expression]",
+		Short: "Evaluate condition expressions against sample variables",
+		Long: `Evaluate the expressions conditional and rule_router nodes use against a
+sample envelope. Variables are visible at the top level and under input, as
+they are to a conditional node.
+
+With an expression argument, prints its value and exits. Without one, starts
+an interactive session that evaluates each line as it is entered:
+
+  petalflow expr --vars sample.json
+  expr> tier == "pro" && 3 in items
+  => true`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runExpr,
+	}
+	addSampleFlags(cmd)
+	return cmd
+}
+
+// NewTemplateCmd creates the "template" command, which renders node
+// templates against a sample envelope.
+func NewTemplateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "template [template]",
+		Short: "Render node templates against sample variables",
+		Long: `Render a Go template the way an llm_prompt, human or transform node would,
+against a sample envelope.
+
+With a template argument, prints the rendered text and exits. Without one,
+starts an interactive session that renders each entry. End a line with a
+backslash to continue the template on the next line:
+
+  petalflow template --run run-123.json --kind transform
+  template> Hello {{.customer}}, \
+  ... you have {{len .items}} items.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: runTemplate,
+	}
+	addSampleFlags(cmd)
+	cmd.Flags().String("kind", string(nodes.TemplateKindLLM), "Node whose template data to use: llm | human | transform")
+	cmd.Flags().Bool("sandbox", false, "Render with the default template sandbox limits")
+	return cmd
+}
+
+func addSampleFlags(cmd *cobra.Command) {
+	cmd.Flags().String("vars", "", "JSON or YAML file of sample variables")
+	cmd.Flags().String("run", "", "Run export file (from petalflow runs export) to take the sample envelope from")
+	cmd.Flags().String("node", "", "With --run, use the variables this node finished with (default: the last node)")
+}
+
+func runExpr(cmd *cobra.Command, args []string) error {
+	env, err := loadSampleEnvelope(cmd)
+	if err != nil {
+		return err
+	}
+	eval := func(line string) (string, error) {
+		return evalSampleExpr(line, env)
+	}
+	if len(args) == 1 {
+		out, err := eval(args[0])
+		if err != nil {
+			printReplError(cmd.ErrOrStderr(), args[0], err)
+			return exitError(exitValidation, "expression failed")
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), out)
+		return nil
+	}
+	return runREPL(cmd, "expr> ", env, eval)
+}
+
+func runTemplate(cmd *cobra.Command, args []string) error {
+	env, err := loadSampleEnvelope(cmd)
+	if err != nil {
+		return err
+	}
+	kind, _ := cmd.Flags().GetString("kind")
+	switch nodes.TemplateKind(kind) {
+	case nodes.TemplateKindLLM, nodes.TemplateKindHuman, nodes.TemplateKindTransform:
+	default:
+		return exitError(exitInputParse, "unknown --kind %q (use llm, human or transform)", kind)
+	}
+	var sandbox *nodes.TemplateSandbox
+	if useSandbox, _ := cmd.Flags().GetBool("sandbox"); useSandbox {
+		sandbox = nodes.DefaultTemplateSandbox()
+	}
+	render := func(text string) (string, error) {
+		return nodes.PreviewTemplate(nodes.TemplateKind(kind), text, env, sandbox)
+	}
+	if len(args) == 1 {
+		out, err := render(args[0])
+		if err != nil {
+			printReplError(cmd.ErrOrStderr(), args[0], err)
+			return exitError(exitValidation, "template failed")
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), out)
+		return nil
+	}
+	return runREPL(cmd, "template> ", env, render)
+}
+
+// evalSampleExpr evaluates line with the variables a conditional node
+// would see and formats the value as JSON.
+func evalSampleExpr(line string, env *core.Envelope) (string, error) {
+	parsed, err := expr.Parse(line)
+	if err != nil {
+		return "", err
+	}
+	vars := make(map[string]any, len(env.Vars)+1)
+	for k, v := range env.Vars {
+		vars[k] = v
+	}
+	if _, ok := vars["input"]; !ok {
+		vars["input"] = env.Vars
+	}
+	value, err := expr.Eval(parsed, vars)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value), nil
+	}
+	return string(data), nil
+}
+
+// runREPL reads entries from stdin until :quit or end of input, passing
+// each one to eval and printing the result or the error's location.
+func runREPL(cmd *cobra.Command, prompt string, env *core.Envelope, eval func(string) (string, error)) error {
+	out, errOut := cmd.OutOrStdout(), cmd.ErrOrStderr()
+	scanner := bufio.NewScanner(cmd.InOrStdin())
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+
+	fmt.Fprintf(out, "%d sample variables loaded. Type :help for commands.\n", len(env.Vars))
+	var pending []string
+	for {
+		if len(pending) == 0 {
+			fmt.Fprint(out, prompt)
+		} else {
+			fmt.Fprint(out, "... ")
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		line := scanner.Text()
+		if cont, ok := strings.CutSuffix(line, `\`); ok {
+			pending = append(pending, cont)
+			continue
+		}
+		entry := strings.Join(append(pending, line), "\n")
+		pending = nil
+
+		trimmed := strings.TrimSpace(entry)
+		switch {
+		case trimmed == "":
+			continue
+		case trimmed == ":quit" || trimmed == ":q":
+			return nil
+		case strings.HasPrefix(trimmed, ":"):
+			if err := replCommand(out, trimmed, env); err != nil {
+				fmt.Fprintf(errOut, "error: %v\n", err)
+			}
+			continue
+		}
+
+		result, err := eval(entry)
+		if err != nil {
+			printReplError(errOut, entry, err)
+			continue
+		}
+		fmt.Fprintf(out, "=> %s\n", result)
+	}
+}
+
+// replCommand runs a ":" command.
+func replCommand(out io.Writer, line string, env *core.Envelope) error {
+	name, rest, _ := strings.Cut(line, " ")
+	rest = strings.TrimSpace(rest)
+	switch name {
+	case ":help":
+		fmt.Fprintln(out, replHelp)
+	case ":vars":
+		keys := make([]string, 0, len(env.Vars))
+		for k := range env.Vars {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			data, _ := json.Marshal(env.Vars[k])
+			fmt.Fprintf(out, "%s = %s\n", k, data)
+		}
+	case ":set":
+		key, raw, ok := strings.Cut(rest, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return errors.New("usage: :set name = value")
+		}
+		raw = strings.TrimSpace(raw)
+		var value any
+		if err := json.Unmarshal([]byte(raw), &value); err != nil {
+			value = raw
+		}
+		env.SetVar(key, value)
+	case ":unset":
+		if rest == "" {
+			return errors.New("usage: :unset name")
+		}
+		delete(env.Vars, rest)
+	default:
+		return fmt.Errorf("unknown command %s (type :help)", name)
+	}
+	return nil
+}
+
+// templateErrorLocation matches the "template: name:line:col:" prefix of
+// text/template errors; parse errors carry only the line.
+var templateErrorLocation = regexp.MustCompile(`^template: [^:]+:(\d+)(?::(\d+))?: `)
+
+// printReplError prints err, preceded by the offending line of entry with
+// a caret under the error's position when the error carries one.
+func printReplError(w io.Writer, entry string, err error) {
+	line, col := -1, -1
+	var syntaxErr *expr.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		line, col = 0, syntaxErr.Pos
+		for i, text := range strings.Split(entry, "\n") {
+			if col <= len(text) {
+				line = i
+				break
+			}
+			col -= len(text) + 1
+		}
+	} else if m := templateErrorLocation.FindStringSubmatch(err.Error()); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		line = n - 1
+		if m[2] != "" {
+			col, _ = strconv.Atoi(m[2])
+		}
+	}
+
+	lines := strings.Split(entry, "\n")
+	if line >= 0 && line < len(lines) {
+		if len(lines) > 1 {
+			fmt.Fprintf(w, "line %d:\n", line+1)
+		}
+		fmt.Fprintf(w, "  %s\n", lines[line])
+		if col >= 0 && col <= len(lines[line]) {
+			fmt.Fprintf(w, "  %s^\n", strings.Repeat(" ", col))
+		}
+	}
+	fmt.Fprintf(w, "error: %v\n", err)
+}
+
+// loadSampleEnvelope builds the sample envelope from --vars or --run.
+func loadSampleEnvelope(cmd *cobra.Command) (*core.Envelope, error) {
+	varsPath, _ := cmd.Flags().GetString("vars")
+	runPath, _ := cmd.Flags().GetString("run")
+	nodeID, _ := cmd.Flags().GetString("node")
+	if varsPath != "" && runPath != "" {
+		return nil, exitError(exitInputParse, "cannot specify both --vars and --run")
+	}
+	if nodeID != "" && runPath == "" {
+		return nil, exitError(exitInputParse, "--node requires --run")
+	}
+
+	env := core.NewEnvelope()
+	switch {
+	case varsPath != "":
+		var vars map[string]any
+		if err := readSampleFile(varsPath, &vars); err != nil {
+			return nil, err
+		}
+		for k, v := range vars {
+			env.SetVar(k, v)
+		}
+	case runPath != "":
+		var export runExport
+		if err := readSampleFile(runPath, &export); err != nil {
+			return nil, err
+		}
+		vars, err := runExportVars(export, nodeID)
+		if err != nil {
+			return nil, exitError(exitInputParse, "%s: %v", runPath, err)
+		}
+		for k, v := range vars {
+			env.SetVar(k, v)
+		}
+	}
+	return env, nil
+}
+
+func readSampleFile(path string, dst any) error {
+	data, err := os.ReadFile(path) // #nosec G304 -- path from user CLI flag
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return exitError(exitFileNotFound, "file not found: %s", path)
+		}
+		return exitError(exitRuntime, "reading %s: %v", path, err)
+	}
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return exitError(exitInputParse, "parsing %s: %v", path, err)
+	}
+	// Round-trip through JSON so json tags apply and numbers decode the
+	// way they do in a run.
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return exitError(exitInputParse, "parsing %s: %v", path, err)
+	}
+	if err := json.Unmarshal(encoded, dst); err != nil {
+		return exitError(exitInputParse, "parsing %s: %v", path, err)
+	}
+	return nil
+}
+
+// runExportVars returns the variables nodeID finished with in an exported
+// run, or those of the run's last finished node when nodeID is empty. Runs
+// recorded without node outputs fall back to the run's inputs.
+func runExportVars(export runExport, nodeID string) (map[string]any, error) {
+	var output any
+	if nodeID != "" {
+		var ok bool
+		if output, ok = export.Outputs[nodeID]; !ok {
+			return nil, fmt.Errorf("run has no recorded output for node %q", nodeID)
+		}
+	} else {
+		for _, e := range export.Events {
+			if e.Kind == runtime.EventNodeFinished && e.Payload["output"] != nil {
+				output = e.Payload["output"]
+			}
+		}
+	}
+	if recorded, ok := output.(map[string]any); ok {
+		vars, _ := recorded["vars"].(map[string]any)
+		return vars, nil
+	}
+	for _, e := range export.Events {
+		if e.Kind != runtime.EventRunStarted {
+			continue
+		}
+		if inputs, ok := e.Payload["inputs"].(map[string]any); ok {
+			return inputs, nil
+		}
+	}
+	return nil, errors.New("run has no recorded node outputs or inputs")
+}
@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func newReplRoot(stdin string) *cobra.Command {
+	root := &cobra.Command{Use: "petalflow", SilenceUsage: true}
+	root.AddCommand(NewExprCmd())
+	root.AddCommand(NewTemplateCmd())
+	root.SetIn(strings.NewReader(stdin))
+	return root
+}
+
+const sampleVarsYAML = `tier: pro
+items: [1, 2, 3]
+customer: Acme
+`
+
+func TestExprCmd_OneShot(t *testing.T) {
+	vars := writeTestFile(t, "vars.yaml", sampleVarsYAML)
+
+	stdout, _, err := executeCommand(newReplRoot(""), "expr", "--vars", vars, `tier == "pro" && 3 in items`)
+	if err != nil || strings.TrimSpace(stdout) != "true" {
+		t.Fatalf("stdout = %q, err = %v", stdout, err)
+	}
+
+	_, stderr, err := executeCommand(newReplRoot(""), "expr", "--vars", vars, "tier == pro)")
+	if exitErr, ok := err.(*ExitError); !ok || exitErr.Code != exitValidation {
+		t.Fatalf("err = %v, want a validation exit", err)
+	}
+	want := "  tier == pro)\n             ^\nerror: unexpected token ) at position 11\n"
+	if !strings.HasPrefix(stderr, want) {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+}
+
+func TestExprCmd_REPL(t *testing.T) {
+	vars := writeTestFile(t, "vars.json", `{"tier": "pro"}`)
+	stdin := "tier\n:set tier = \"free\"\ninput.tier == \"free\"\nnope(1)\n:unset tier\n:vars\n:quit\ntier\n"
+
+	stdout, stderr, err := executeCommand(newReplRoot(stdin), "expr", "--vars", vars)
+	if err != nil {
+		t.Fatalf("expr: %v", err)
+	}
+	for _, want := range []string{`=> "pro"`, "=> true"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("stdout missing %q:\n%s", want, stdout)
+		}
+	}
+	if strings.Count(stdout, "=>") != 2 {
+		t.Errorf("entries after :quit were evaluated:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "  ^\nerror: unknown function \"nope\" at position 0") {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
+func TestTemplateCmd_RunExport(t *testing.T) {
+	export := writeTestFile(t, "run.json", `{
+  "run": {"run_id": "run-1", "status": "completed"},
+  "events": [
+    {"kind": "run.started", "payload": {"inputs": {"customer": "Acme"}}},
+    {"kind": "node.finished", "node_id": "fetch", "payload": {"output": {"vars": {"customer": "Acme", "items": [1, 2]}}}},
+    {"kind": "node.finished", "node_id": "draft", "payload": {"output": {"vars": {"customer": "Acme", "items": [1, 2], "draft": "Hi"}}}}
+  ],
+  "outputs": {
+    "fetch": {"vars": {"customer": "Acme", "items": [1, 2]}},
+    "draft": {"vars": {"customer": "Acme", "items": [1, 2], "draft": "Hi"}}
+  }
+}`)
+
+	stdout, _, err := executeCommand(newReplRoot(""), "template", "--run", export, "{{.draft}} {{.customer}}")
+	if err != nil || strings.TrimSpace(stdout) != "Hi Acme" {
+		t.Fatalf("last node: stdout = %q, err = %v", stdout, err)
+	}
+
+	stdout, _, err = executeCommand(newReplRoot(""), "template", "--run", export, "--node", "fetch", "--kind", "transform", "{{len .items}} {{._input}}")
+	if err != nil || strings.TrimSpace(stdout) != "2 <no value>" {
+		t.Fatalf("--node fetch: stdout = %q, err = %v", stdout, err)
+	}
+
+	_, _, err = executeCommand(newReplRoot(""), "template", "--run", export, "--node", "missing", "x")
+	if err == nil || !strings.Contains(err.Error(), `no recorded output for node "missing"`) {
+		t.Errorf("missing node: err = %v", err)
+	}
+}
+
+func TestTemplateCmd_REPLMultilineError(t *testing.T) {
+	vars := writeTestFile(t, "vars.json", `{"items": [1]}`)
+	stdin := "first \\\n{{index .items 7}}\n"
+
+	_, stderr, err := executeCommand(newReplRoot(stdin), "template", "--vars", vars)
+	if err != nil {
+		t.Fatalf("template: %v", err)
+	}
+	if !strings.Contains(stderr, "line 2:\n  {{index .items 7}}\n    ^\nerror: template: llm:2:2:") {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
@@ -16,6 +16,7 @@ import (
 	"github.com/petal-labs/petalflow/hydrate"
 	"github.com/petal-labs/petalflow/llmprovider"
 	"github.com/petal-labs/petalflow/loader"
+	"github.com/petal-labs/petalflow/nodes"
 	"github.com/petal-labs/petalflow/runtime"
 	"github.com/petal-labs/petalflow/server"
 	"github.com/petal-labs/petalflow/tool"
@@ -30,9 +31,14 @@ const (
 	exitInputParse   = 4
 	exitProvider     = 5
 	exitWrongSchema  = 6
+	exitEvalFailed   = 7
 	exitTimeout      = 10
 )
 
+// defaultRunTimeout bounds runs when neither --timeout nor the workflow's
+// run_defaults set a timeout.
+const defaultRunTimeout = 5 * time.Minute
+
 // NewRunCmd creates the "run" subcommand.
 func NewRunCmd() *cobra.Command {
 	cmd := &cobra.Command{
@@ -44,14 +50,24 @@ func NewRunCmd() *cobra.Command {
 
 	cmd.Flags().StringP("input", "i", "", "Input data as inline JSON string")
 	cmd.Flags().StringP("input-file", "f", "", "Input data from a JSON or YAML file")
+	cmd.Flags().StringArray("preset", nil, "Start from a named input preset of the workflow (repeatable, later presets win; --input overrides)")
 	cmd.Flags().StringP("output", "o", "", "Write output envelope to file (default: stdout)")
 	cmd.Flags().String("format", "pretty", "Output format: json | text | pretty")
-	cmd.Flags().Duration("timeout", 5*time.Minute, "Execution timeout")
+	cmd.Flags().Duration("timeout", defaultRunTimeout, "Execution timeout (default: the workflow's run_defaults, else 5m)")
 	cmd.Flags().Bool("dry-run", false, "Compile and validate only, do not execute")
+	cmd.Flags().String("workflow", "", "Run the workflow with this id from a multi-document file")
 	cmd.Flags().StringArray("env", nil, "Set environment variable (repeatable)")
 	cmd.Flags().StringArray("provider-key", nil, "Set provider API key (repeatable, e.g. --provider-key anthropic=sk-...)")
+	_ = cmd.RegisterFlagCompletionFunc("provider-key", completeProviderKeyFlag)
 	cmd.Flags().String("store-path", "", "Path to SQLite store for tool registry (default: ~/.petalflow/petalflow.db)")
 	cmd.Flags().Bool("stream", false, "Enable streaming output via SSE to stdout")
+	cmd.Flags().Int("max-node-executions", 0, "Fail the run after this many node executions (0 = unlimited)")
+	cmd.Flags().Int("max-hops", 0, "Maximum edges the run may follow (default: the workflow's run_defaults, else 100)")
+	cmd.Flags().Int("concurrency", 0, "Nodes that may execute at once (default: the workflow's run_defaults, else 1)")
+	cmd.Flags().Bool("continue-on-error", false, "Keep running after a node fails (default: the workflow's run_defaults)")
+	cmd.Flags().Bool("simulate", false, "Use canned LLM and tool responses from the workflow's simulate section")
+	cmd.Flags().String("simulate-file", "", "JSON file of simulated responses layered over the workflow's (implies --simulate)")
+	cmd.Flags().String("chaos-file", "", "JSON file of seeded faults to inject (latency, provider errors, dropped tool responses)")
 
 	return cmd
 }
@@ -73,7 +89,8 @@ func runRun(cmd *cobra.Command, args []string) error {
 		return exitError(exitRuntime, "syncing tool node types: %v", err)
 	}
 
-	gd, err := loadWorkflowForRun(cmd, filePath)
+	workflowID, _ := cmd.Flags().GetString("workflow")
+	gd, err := loadWorkflowForRun(cmd, filePath, workflowID)
 	if err != nil {
 		return err
 	}
@@ -81,6 +98,7 @@ func runRun(cmd *cobra.Command, args []string) error {
 	// Dry run: just validate and compile, don't execute.
 	if isRunDry(cmd) {
 		fmt.Fprintln(cmd.OutOrStdout(), "Validation and compilation successful.")
+		printLLMSettings(cmd.OutOrStdout(), gd.LLMSettings())
 		return nil
 	}
 
@@ -91,7 +109,7 @@ func runRun(cmd *cobra.Command, args []string) error {
 
 	// Build input envelope before store hydration so input validation errors are
 	// deterministic and not masked by external store state.
-	env, err := buildInputEnvelope(cmd)
+	env, err := buildInputEnvelope(cmd, gd)
 	if err != nil {
 		return err
 	}
@@ -101,19 +119,38 @@ func runRun(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	execGraph, err := hydrateRunGraph(cmd, gd, providers, toolRegistry)
+	simulation, err := resolveRunSimulation(cmd, gd)
+	if err != nil {
+		return err
+	}
+
+	execGraph, err := hydrateRunGraph(cmd, gd, providers, toolRegistry, simulation)
+	if err != nil {
+		return err
+	}
+
+	chaos, err := resolveRunChaos(cmd)
+	if err != nil {
+		return err
+	}
+
+	settings, err := resolveRunSettings(cmd, gd)
 	if err != nil {
 		return err
 	}
 
 	applyRunEnvVars(cmd)
-	ctx, cancel, timeout := runContext(cmd)
+	ctx, cancel := context.WithTimeout(cmd.Context(), settings.Timeout)
 	defer cancel()
 
 	opts, streaming := buildRunOptions(cmd)
+	applyRunSettings(&opts, settings)
+	opts.Chaos = chaos
+	opts.VarLifetimes = gd.VarLifetimes()
+	opts.SLA = gd.SLATargets()
 	result, err := runtime.NewRuntime().Run(ctx, execGraph, env, opts)
 	if err != nil {
-		return runRuntimeError(ctx, timeout, err)
+		return runRuntimeError(ctx, settings.Timeout, err)
 	}
 
 	// Skip writeOutput when streaming — output was already printed incrementally.
@@ -125,9 +162,10 @@ func runRun(cmd *cobra.Command, args []string) error {
 	return writeOutput(cmd, result)
 }
 
-func loadWorkflowForRun(cmd *cobra.Command, filePath string) (*graph.GraphDefinition, error) {
-	// Load and compile the workflow (handles both agent and graph schemas).
-	gd, _, err := loader.LoadWorkflow(filePath)
+// loadWorkflowForRun loads and compiles the workflow (handles both agent
+// and graph schemas). id picks the workflow from a multi-document file.
+func loadWorkflowForRun(cmd *cobra.Command, filePath, id string) (*graph.GraphDefinition, error) {
+	gd, _, err := loader.LoadWorkflowByID(filePath, id)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil, exitError(exitFileNotFound, "file not found: %s", filePath)
@@ -142,6 +180,37 @@ func loadWorkflowForRun(cmd *cobra.Command, filePath string) (*graph.GraphDefini
 	return gd, nil
 }
 
+// printLLMSettings lists the provider, model and sampling settings each LLM
+// node will run with, marking those inherited from llm_defaults.
+func printLLMSettings(w io.Writer, settings []graph.LLMSettings) {
+	if len(settings) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nLLM nodes:")
+	for _, s := range settings {
+		var parts []string
+		switch {
+		case s.Provider != "" || s.Model != "":
+			parts = append(parts, s.Provider+"/"+s.Model)
+		case s.NodeType == "model_select":
+			parts = append(parts, "models from arms")
+		}
+		if s.Temperature != nil {
+			parts = append(parts, fmt.Sprintf("temperature %g", *s.Temperature))
+		}
+		if s.MaxTokens > 0 {
+			parts = append(parts, fmt.Sprintf("max_tokens %d", s.MaxTokens))
+		}
+		if s.SystemPrompt != "" {
+			parts = append(parts, fmt.Sprintf("system_prompt (%d chars)", len(s.SystemPrompt)))
+		}
+		fmt.Fprintf(w, "  %s (%s): %s\n", s.NodeID, s.NodeType, strings.Join(parts, ", "))
+		if len(s.Inherited) > 0 {
+			fmt.Fprintf(w, "    from llm_defaults: %s\n", strings.Join(s.Inherited, ", "))
+		}
+	}
+}
+
 func isRunDry(cmd *cobra.Command) bool {
 	dryRun, _ := cmd.Flags().GetBool("dry-run")
 	return dryRun
@@ -168,19 +237,38 @@ func buildRunToolRegistry(cmd *cobra.Command, store tool.Store) (*core.ToolRegis
 	return toolRegistry, nil
 }
 
+// runOutputHistory holds drift guard history for the life of the process,
+// so repeated runs in one command (such as eval) share a baseline.
+var runOutputHistory = nodes.NewMemoryOutputHistory()
+
+// runArmStats holds model_select statistics for the life of the process.
+var runArmStats = nodes.NewMemoryArmStats()
+
+// runState holds state node values for the life of the process.
+var runState = nodes.NewMemoryStateStore()
+
 func hydrateRunGraph(
 	cmd *cobra.Command,
 	gd *graph.GraphDefinition,
 	providers hydrate.ProviderMap,
 	toolRegistry *core.ToolRegistry,
+	simulation *graph.SimulationDef,
 ) (*graph.BasicGraph, error) {
+	factoryOpts := []hydrate.LiveNodeOption{
+		hydrate.WithToolRegistry(toolRegistry),
+		hydrate.WithHumanHandler(&cliHumanHandler{w: cmd.ErrOrStderr()}),
+		hydrate.WithOutputHistory(runOutputHistory, gd.ID),
+		hydrate.WithArmStats(runArmStats, gd.ID),
+		hydrate.WithStateStore(runState, gd.ID),
+	}
+	if simulation != nil {
+		factoryOpts = append(factoryOpts, hydrate.WithSimulation(simulation))
+	}
+
 	// Build executable graph from definition.
 	factory := hydrate.NewLiveNodeFactory(providers, func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
 		return llmprovider.NewClient(name, cfg)
-	},
-		hydrate.WithToolRegistry(toolRegistry),
-		hydrate.WithHumanHandler(&cliHumanHandler{w: cmd.ErrOrStderr()}),
-	)
+	}, factoryOpts...)
 	execGraph, err := hydrate.HydrateGraph(gd, providers, factory)
 	if err != nil {
 		return nil, exitError(exitProvider, "hydrating graph: %v", err)
@@ -188,6 +276,55 @@ func hydrateRunGraph(
 	return execGraph, nil
 }
 
+// resolveRunSimulation returns the simulated responses for this run, or nil
+// when neither --simulate nor --simulate-file was given.
+func resolveRunSimulation(cmd *cobra.Command, gd *graph.GraphDefinition) (*graph.SimulationDef, error) {
+	simulate, _ := cmd.Flags().GetBool("simulate")
+	simulateFile, _ := cmd.Flags().GetString("simulate-file")
+	if !simulate && simulateFile == "" {
+		return nil, nil
+	}
+
+	var override *graph.SimulationDef
+	if simulateFile != "" {
+		data, err := os.ReadFile(simulateFile) // #nosec G304 -- path from user CLI flag
+		if err != nil {
+			return nil, exitError(exitFileNotFound, "reading simulate file: %v", err)
+		}
+		override = &graph.SimulationDef{}
+		if err := json.Unmarshal(data, override); err != nil {
+			return nil, exitError(exitInputParse, "parsing simulate file: %v", err)
+		}
+	}
+
+	simulation := graph.MergeSimulation(gd.Simulate, override)
+	if err := simulation.Validate(); err != nil {
+		return nil, exitError(exitValidation, "invalid simulation: %v", err)
+	}
+	return simulation, nil
+}
+
+// resolveRunChaos reads the --chaos-file fault injection config, or returns
+// nil when the flag is not set.
+func resolveRunChaos(cmd *cobra.Command) (*runtime.ChaosConfig, error) {
+	chaosFile, _ := cmd.Flags().GetString("chaos-file")
+	if chaosFile == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(chaosFile) // #nosec G304 -- path from user CLI flag
+	if err != nil {
+		return nil, exitError(exitFileNotFound, "reading chaos file: %v", err)
+	}
+	chaos := &runtime.ChaosConfig{}
+	if err := json.Unmarshal(data, chaos); err != nil {
+		return nil, exitError(exitInputParse, "parsing chaos file: %v", err)
+	}
+	if err := chaos.Validate(); err != nil {
+		return nil, exitError(exitValidation, "invalid chaos config: %v", err)
+	}
+	return chaos, nil
+}
+
 func applyRunEnvVars(cmd *cobra.Command) {
 	envVars, _ := cmd.Flags().GetStringArray("env")
 	for _, kv := range envVars {
@@ -218,10 +355,49 @@ func closeToolStore(store tool.Store) {
 	}
 }
 
-func runContext(cmd *cobra.Command) (context.Context, context.CancelFunc, time.Duration) {
-	timeout, _ := cmd.Flags().GetDuration("timeout")
-	ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
-	return ctx, cancel, timeout
+// resolveRunSettings layers the run flags the user set over the workflow's
+// run_defaults and the runtime's defaults.
+func resolveRunSettings(cmd *cobra.Command, gd *graph.GraphDefinition) (graph.RunSettings, error) {
+	var overrides graph.RunOverrides
+	flags := cmd.Flags()
+	if flags.Changed("timeout") {
+		overrides.Timeout, _ = flags.GetDuration("timeout")
+	}
+	if flags.Changed("max-hops") {
+		overrides.MaxHops, _ = flags.GetInt("max-hops")
+	}
+	if flags.Changed("concurrency") {
+		overrides.Concurrency, _ = flags.GetInt("concurrency")
+	}
+	if flags.Changed("continue-on-error") {
+		continueOnError, _ := flags.GetBool("continue-on-error")
+		overrides.ContinueOnError = &continueOnError
+	}
+	overrides.MaxNodeExecutions, _ = flags.GetInt("max-node-executions")
+	if overrides.Timeout < 0 || overrides.MaxHops < 0 || overrides.Concurrency < 0 || overrides.MaxNodeExecutions < 0 {
+		return graph.RunSettings{}, exitError(exitValidation, "--timeout, --max-hops, --concurrency and --max-node-executions must not be negative")
+	}
+
+	defaults := runtime.DefaultRunOptions()
+	settings, err := gd.ResolveRunSettings(graph.RunSettings{
+		MaxHops:         defaults.MaxHops,
+		Concurrency:     defaults.Concurrency,
+		ContinueOnError: defaults.ContinueOnError,
+		Timeout:         defaultRunTimeout,
+	}, overrides)
+	if err != nil {
+		return graph.RunSettings{}, exitError(exitValidation, "%v", err)
+	}
+	return settings, nil
+}
+
+// applyRunSettings copies resolved run settings onto runtime options.
+func applyRunSettings(opts *runtime.RunOptions, settings graph.RunSettings) {
+	opts.MaxHops = settings.MaxHops
+	opts.Concurrency = settings.Concurrency
+	opts.ContinueOnError = settings.ContinueOnError
+	opts.MaxNodeExecutions = settings.MaxNodeExecutions
+	opts.NodeVisitLimits = settings.NodeVisitLimits
 }
 
 func buildRunOptions(cmd *cobra.Command) (runtime.RunOptions, bool) {
@@ -250,20 +426,37 @@ func runRuntimeError(ctx context.Context, timeout time.Duration, err error) erro
 	if ctx.Err() == context.DeadlineExceeded {
 		return exitError(exitTimeout, "execution timed out after %s", timeout)
 	}
+	if pe, ok := core.AsProviderError(err); ok {
+		return exitError(exitRuntime, "execution failed: %v\n%s: %s", err, pe.Code, pe.Remediation())
+	}
 	return exitError(exitRuntime, "execution failed: %v", err)
 }
 
-// buildInputEnvelope creates an Envelope from --input or --input-file flags.
-func buildInputEnvelope(cmd *cobra.Command) (*core.Envelope, error) {
+// buildInputEnvelope creates an Envelope from the workflow's --preset
+// inputs overlaid with --input or --input-file.
+func buildInputEnvelope(cmd *cobra.Command, gd *graph.GraphDefinition) (*core.Envelope, error) {
 	inputStr, _ := cmd.Flags().GetString("input")
 	inputFile, _ := cmd.Flags().GetString("input-file")
+	presetNames, _ := cmd.Flags().GetStringArray("preset")
 
 	if inputStr != "" && inputFile != "" {
 		return nil, exitError(exitInputParse, "cannot specify both --input and --input-file")
 	}
 
+	inputs := make([]map[string]any, 0, len(presetNames)+1)
+	for _, name := range presetNames {
+		preset, ok := gd.Presets[name]
+		if !ok {
+			return nil, exitError(exitInputParse, "workflow has no input preset %q", name)
+		}
+		inputs = append(inputs, preset.Input)
+	}
+
 	if inputStr == "" && inputFile == "" {
-		return core.NewEnvelope(), nil
+		if len(inputs) == 0 {
+			return core.NewEnvelope(), nil
+		}
+		return server.EnvelopeFromJSON(graph.MergePresetInputs(inputs...)), nil
 	}
 
 	var data []byte
@@ -282,7 +475,7 @@ func buildInputEnvelope(cmd *cobra.Command) (*core.Envelope, error) {
 		return nil, exitError(exitInputParse, "parsing input JSON: %v", err)
 	}
 
-	return server.EnvelopeFromJSON(vars), nil
+	return server.EnvelopeFromJSON(graph.MergePresetInputs(append(inputs, vars)...)), nil
 }
 
 // writeOutput formats and writes the result envelope.
@@ -11,6 +11,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
 	"github.com/petal-labs/petalflow/registry"
 	"github.com/petal-labs/petalflow/runtime"
 	"github.com/petal-labs/petalflow/tool"
@@ -54,6 +56,69 @@ func TestBuildRunOptions_StreamingHandler(t *testing.T) {
 	}
 }
 
+func TestResolveRunSettings_FlagsOverrideWorkflowDefaults(t *testing.T) {
+	yes := true
+	gd := &graph.GraphDefinition{RunDefaults: &graph.RunDefaults{
+		Concurrency:     3,
+		ContinueOnError: &yes,
+		Timeout:         "45s",
+		Limits:          &graph.RunLimits{Concurrency: 4},
+	}}
+
+	cmd := NewRunCmd()
+	settings, err := resolveRunSettings(cmd, gd)
+	if err != nil {
+		t.Fatalf("resolveRunSettings: %v", err)
+	}
+	if settings.Timeout != 45*time.Second || settings.Concurrency != 3 || !settings.ContinueOnError || settings.MaxHops != 100 {
+		t.Fatalf("settings = %+v, want workflow defaults over runtime defaults", settings)
+	}
+
+	for flag, value := range map[string]string{"timeout": "2m", "concurrency": "4", "continue-on-error": "false"} {
+		if err := cmd.Flags().Set(flag, value); err != nil {
+			t.Fatalf("setting %s: %v", flag, err)
+		}
+	}
+	settings, err = resolveRunSettings(cmd, gd)
+	if err != nil {
+		t.Fatalf("resolveRunSettings with flags: %v", err)
+	}
+	if settings.Timeout != 2*time.Minute || settings.Concurrency != 4 || settings.ContinueOnError {
+		t.Fatalf("settings = %+v, want flags to override", settings)
+	}
+
+	_ = cmd.Flags().Set("concurrency", "5")
+	if _, err := resolveRunSettings(cmd, gd); err == nil {
+		t.Fatal("expected --concurrency above the workflow's limit to fail")
+	}
+}
+
+func TestBuildInputEnvelope_LayersPresetsUnderInput(t *testing.T) {
+	gd := &graph.GraphDefinition{Presets: map[string]graph.InputPreset{
+		"demo customer":      {Input: map[string]any{"customer": "acme", "tier": "free"}},
+		"regression case 17": {Input: map[string]any{"tier": "enterprise"}},
+	}}
+
+	cmd := NewRunCmd()
+	for _, name := range []string{"demo customer", "regression case 17"} {
+		_ = cmd.Flags().Set("preset", name)
+	}
+	_ = cmd.Flags().Set("input", `{"customer": "globex"}`)
+	env, err := buildInputEnvelope(cmd, gd)
+	if err != nil {
+		t.Fatalf("buildInputEnvelope: %v", err)
+	}
+	if env.Vars["customer"] != "globex" || env.Vars["tier"] != "enterprise" {
+		t.Fatalf("vars = %v, want later presets and --input to win", env.Vars)
+	}
+
+	cmd = NewRunCmd()
+	_ = cmd.Flags().Set("preset", "ghost")
+	if _, err := buildInputEnvelope(cmd, gd); err == nil {
+		t.Fatal("expected an unknown preset to fail")
+	}
+}
+
 func TestApplyRunEnvVars(t *testing.T) {
 	cmd := NewRunCmd()
 	key := "PETALFLOW_RUN_ENV_TEST"
@@ -118,6 +183,16 @@ func TestRunRuntimeError(t *testing.T) {
 	if exitRuntimeErr.Code != exitRuntime {
 		t.Fatalf("runtime exit code = %d, want %d", exitRuntimeErr.Code, exitRuntime)
 	}
+
+	providerErr := fmt.Errorf("node answer: %w", &core.ProviderError{
+		Provider: "openai",
+		Code:     core.ProviderErrContextLength,
+		Err:      errors.New("maximum context length is 8192 tokens"),
+	})
+	err := runRuntimeError(context.Background(), 2*time.Second, providerErr)
+	if msg := err.Error(); !strings.Contains(msg, "context_length_exceeded: Shorten the prompt") {
+		t.Fatalf("provider failure message = %q, want the remediation hint", msg)
+	}
 }
 
 func TestRunDryRunFallsBackWhenImplicitStoreUnavailable(t *testing.T) {
@@ -0,0 +1,281 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petal-labs/petalflow/runtime"
+	"github.com/petal-labs/petalflow/server"
+)
+
+// runExport is one exported run: its history entry, persisted events and
+// the output each node finished with.
+type runExport struct {
+	Run     server.RunHistoryEntry `json:"run"`
+	Events  []runtime.Event        `json:"events"`
+	Outputs map[string]any         `json:"outputs"`
+}
+
+// runExportFilter is the run history query of an export.
+type runExportFilter struct {
+	WorkflowID string `json:"workflow_id,omitempty"`
+	Status     string `json:"status,omitempty"`
+	Since      string `json:"since,omitempty"`
+	Until      string `json:"until,omitempty"`
+}
+
+// runExportState records export progress after every run, so an
+// interrupted export resumes after the last run it wrote. Since and Until are kept as resolved times,
+// so a relative --since keeps pointing at the same range on resume.
+type runExportState struct {
+	Flags    runExportFilter `json:"flags"`
+	Query    runExportFilter `json:"query"`
+	Cursor   string          `json:"cursor,omitempty"`
+	Exported int             `json:"exported"`
+}
+
+func newRunsExportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export run history with events and outputs to files",
+		Long: `Export runs recorded by the daemon, oldest first, with their events and
+node outputs. Each run is written to <dir>/<run_id>.json, or appended as one
+line to a JSONL file with --jsonl.
+
+Progress is saved next to the output after every run. Running the same
+command again resumes where it stopped, and picks up runs started since the
+last export. Use --restart to start over.`,
+		Args: cobra.NoArgs,
+		RunE: runRunsExport,
+	}
+	cmd.Flags().String("since", "", "Export runs started at or after this time (RFC 3339 or a window such as 24h or 7d)")
+	cmd.Flags().String("until", "", "Export runs started before this time (RFC 3339 or a window such as 24h or 7d)")
+	cmd.Flags().String("workflow", "", "Only export runs of this workflow")
+	cmd.Flags().String("status", "", "Only export runs with this status: completed | failed | running")
+	cmd.Flags().StringP("output", "o", "", "Directory to write one JSON file per run to")
+	cmd.Flags().String("jsonl", "", "JSONL file to append one run per line to")
+	cmd.Flags().Int("page-size", server.DefaultRunHistoryLimit, "Runs requested per page")
+	cmd.Flags().Bool("restart", false, "Ignore saved progress and export from the start")
+	_ = cmd.RegisterFlagCompletionFunc("workflow", CompleteWorkflowIDs)
+	return cmd
+}
+
+func runRunsExport(cmd *cobra.Command, _ []string) error {
+	dir, _ := cmd.Flags().GetString("output")
+	jsonlPath, _ := cmd.Flags().GetString("jsonl")
+	if (dir == "") == (jsonlPath == "") {
+		return exitError(exitValidation, "set exactly one of --output and --jsonl")
+	}
+	pageSize, _ := cmd.Flags().GetInt("page-size")
+	if pageSize <= 0 || pageSize > server.MaxRunHistoryLimit {
+		return exitError(exitValidation, "--page-size must be between 1 and %d", server.MaxRunHistoryLimit)
+	}
+
+	var flags runExportFilter
+	flags.WorkflowID, _ = cmd.Flags().GetString("workflow")
+	flags.Status, _ = cmd.Flags().GetString("status")
+	flags.Since, _ = cmd.Flags().GetString("since")
+	flags.Until, _ = cmd.Flags().GetString("until")
+
+	statePath := jsonlPath + ".state"
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return exitError(exitRuntime, "creating %s: %v", dir, err)
+		}
+		statePath = filepath.Join(dir, ".export-state.json")
+	}
+
+	restart, _ := cmd.Flags().GetBool("restart")
+	state, err := loadRunExportState(statePath, flags, restart)
+	if err != nil {
+		return err
+	}
+	if restart && jsonlPath != "" {
+		if err := os.Remove(jsonlPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return exitError(exitRuntime, "removing %s: %v", jsonlPath, err)
+		}
+	}
+
+	var jsonl *os.File
+	if jsonlPath != "" {
+		jsonl, err = os.OpenFile(jsonlPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return exitError(exitRuntime, "opening %s: %v", jsonlPath, err)
+		}
+		defer jsonl.Close()
+	}
+
+	client := resolveDaemonClient(cmd)
+	exported := 0
+	for {
+		var page server.RunHistoryPage
+		if err := client.getJSON(cmd.Context(), runHistoryPath(state, pageSize), &page); err != nil {
+			return exitError(exitRuntime, "listing runs: %v", err)
+		}
+
+		for _, run := range page.Runs {
+			export, err := fetchRunExport(cmd, client, run)
+			if err != nil {
+				return exitError(exitRuntime, "exporting run %s: %v", run.RunID, err)
+			}
+			if jsonl != nil {
+				err = writeRunExportLine(jsonl, export)
+			} else {
+				err = writeRunExportFile(dir, export)
+			}
+			if err != nil {
+				return exitError(exitRuntime, "writing run %s: %v", run.RunID, err)
+			}
+			exported++
+			state.Exported++
+			state.Cursor = run.Cursor
+			if err := saveRunExportState(statePath, state); err != nil {
+				return exitError(exitRuntime, "saving export progress: %v", err)
+			}
+		}
+
+		if page.NextCursor == "" {
+			break
+		}
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "Exported %d runs (%d in total)\n", exported, state.Exported)
+	return nil
+}
+
+// loadRunExportState resumes saved progress for the same flags, or starts
+// a new export with the time range resolved to absolute times.
+func loadRunExportState(path string, flags runExportFilter, restart bool) (*runExportState, error) {
+	if !restart {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			var state runExportState
+			if err := json.Unmarshal(data, &state); err != nil {
+				return nil, exitError(exitRuntime, "reading export progress %s: %v", path, err)
+			}
+			if state.Flags != flags {
+				return nil, exitError(exitValidation, "%s was written for different filters; use --restart to start over", path)
+			}
+			return &state, nil
+		case !errors.Is(err, os.ErrNotExist):
+			return nil, exitError(exitRuntime, "reading export progress %s: %v", path, err)
+		}
+	}
+
+	query := runExportFilter{WorkflowID: flags.WorkflowID, Status: flags.Status}
+	now := time.Now().UTC()
+	for _, bound := range []struct {
+		raw string
+		out *string
+	}{{flags.Since, &query.Since}, {flags.Until, &query.Until}} {
+		if bound.raw == "" {
+			continue
+		}
+		t, err := parseExportTime(bound.raw, now)
+		if err != nil {
+			return nil, exitError(exitValidation, "%v", err)
+		}
+		*bound.out = t.Format(time.RFC3339Nano)
+	}
+	return &runExportState{Flags: flags, Query: query}, nil
+}
+
+func saveRunExportState(path string, state *runExportState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// parseExportTime accepts an RFC 3339 time, a Go duration or a whole-day
+// "d" window, both counted back from now.
+func parseExportTime(raw string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	var d time.Duration
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q", raw)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time %q: use RFC 3339 or a window such as 24h or 7d", raw)
+		}
+		d = parsed
+	}
+	if d <= 0 {
+		return time.Time{}, fmt.Errorf("window %q must be positive", raw)
+	}
+	return now.Add(-d), nil
+}
+
+func runHistoryPath(state *runExportState, limit int) string {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(limit))
+	for key, value := range map[string]string{
+		"workflow_id": state.Query.WorkflowID,
+		"status":      state.Query.Status,
+		"since":       state.Query.Since,
+		"until":       state.Query.Until,
+		"cursor":      state.Cursor,
+	} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+	return "/api/runs/history?" + params.Encode()
+}
+
+func fetchRunExport(cmd *cobra.Command, client *daemonClient, run server.RunHistoryEntry) (runExport, error) {
+	export := runExport{Run: run, Outputs: map[string]any{}}
+	if err := client.getJSON(cmd.Context(), "/api/runs/"+url.PathEscape(run.RunID)+"/events", &export.Events); err != nil {
+		return runExport{}, err
+	}
+	for _, e := range export.Events {
+		if e.Kind != runtime.EventNodeFinished || e.NodeID == "" {
+			continue
+		}
+		if output, ok := e.Payload["output"]; ok {
+			export.Outputs[e.NodeID] = output
+		}
+	}
+	return export, nil
+}
+
+func writeRunExportFile(dir string, export runExport) error {
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := filepath.Join(dir, url.PathEscape(export.Run.RunID)+".json")
+	return os.WriteFile(name, append(data, '\n'), 0o600)
+}
+
+func writeRunExportLine(w io.Writer, export runExport) error {
+	data, err := json.Marshal(export)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,114 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/runtime"
+	"github.com/petal-labs/petalflow/server"
+)
+
+func TestRunsExport(t *testing.T) {
+	store, err := bus.NewSQLiteEventStore(bus.SQLiteStoreConfig{DSN: filepath.Join(t.TempDir(), "events.sqlite")})
+	if err != nil {
+		t.Fatalf("NewSQLiteEventStore: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	daemon := httptest.NewServer(server.NewServer(server.ServerConfig{EventStore: store}).Handler())
+	t.Cleanup(daemon.Close)
+
+	start := time.Now().Add(-time.Hour).UTC()
+	addRun := func(runID, workflowID, status string, at time.Time) {
+		t.Helper()
+		events := []runtime.Event{
+			runtime.NewEvent(runtime.EventRunStarted, runID).WithPayload("workflow_id", workflowID),
+			runtime.NewEvent(runtime.EventNodeFinished, runID).WithNode("answer", "llm").
+				WithPayload("output", map[string]any{"vars": map[string]any{"answer": runID}}),
+			runtime.NewEvent(runtime.EventRunFinished, runID).WithPayload("status", status),
+		}
+		for i, e := range events {
+			e.Seq = uint64(i + 1)
+			e.Time = at.Add(time.Duration(i) * time.Second)
+			if err := store.Append(context.Background(), e); err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+		}
+	}
+	addRun("run-1", "wf", "completed", start)
+	addRun("run-2", "other", "completed", start.Add(time.Minute))
+	addRun("run-3", "wf", "failed", start.Add(2*time.Minute))
+	addRun("run-old", "wf", "completed", start.Add(-48*time.Hour))
+
+	export := func(args ...string) string {
+		t.Helper()
+		root := newTestRoot()
+		root.AddCommand(NewRunsCmd())
+		stdout, _, err := executeCommand(root, append([]string{"runs", "export", "--daemon", daemon.URL, "--page-size", "1"}, args...)...)
+		if err != nil {
+			t.Fatalf("runs export %v: %v", args, err)
+		}
+		return stdout
+	}
+
+	dir := filepath.Join(t.TempDir(), "runs")
+	if out := export("--since", "24h", "--workflow", "wf", "-o", dir); !strings.Contains(out, "Exported 2 runs") {
+		t.Fatalf("output = %q", out)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "run-3.json"))
+	if err != nil {
+		t.Fatalf("read export: %v", err)
+	}
+	var run runExport
+	if err := json.Unmarshal(data, &run); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if run.Run.Status != "failed" || len(run.Events) != 3 || run.Outputs["answer"] == nil {
+		t.Errorf("export = %+v", run)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "run-old.json")); !os.IsNotExist(err) {
+		t.Errorf("run outside --since was exported")
+	}
+
+	// Running again resumes after the last exported run.
+	addRun("run-4", "wf", "completed", start.Add(3*time.Minute))
+	if out := export("--since", "24h", "--workflow", "wf", "-o", dir); !strings.Contains(out, "Exported 1 runs (3 in total)") {
+		t.Fatalf("resumed output = %q", out)
+	}
+
+	root := newTestRoot()
+	root.AddCommand(NewRunsCmd())
+	if _, _, err := executeCommand(root, "runs", "export", "--daemon", daemon.URL, "--status", "failed", "-o", dir); err == nil ||
+		!strings.Contains(err.Error(), "--restart") {
+		t.Errorf("changed filters err = %v, want --restart hint", err)
+	}
+
+	jsonl := filepath.Join(t.TempDir(), "runs.jsonl")
+	export("--status", "completed", "--jsonl", jsonl)
+	export("--status", "completed", "--jsonl", jsonl, "--restart")
+	f, err := os.Open(jsonl)
+	if err != nil {
+		t.Fatalf("open jsonl: %v", err)
+	}
+	defer f.Close()
+	var ids []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var line runExport
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			t.Fatalf("decode line: %v", err)
+		}
+		ids = append(ids, line.Run.RunID)
+	}
+	if got := strings.Join(ids, ","); got != "run-old,run-1,run-2,run-4" {
+		t.Errorf("jsonl runs = %s", got)
+	}
+}
@@ -11,18 +11,22 @@ import (
 	"os/signal"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	otelapi "go.opentelemetry.io/otel"
 
+	"github.com/petal-labs/petalflow/backup"
 	"github.com/petal-labs/petalflow/bus"
 	"github.com/petal-labs/petalflow/core"
 	"github.com/petal-labs/petalflow/daemon"
 	"github.com/petal-labs/petalflow/hydrate"
 	"github.com/petal-labs/petalflow/llmprovider"
+	"github.com/petal-labs/petalflow/nodes"
 	petalotel "github.com/petal-labs/petalflow/otel"
+	"github.com/petal-labs/petalflow/propagate"
 	"github.com/petal-labs/petalflow/server"
 	"github.com/petal-labs/petalflow/tool"
 )
@@ -32,43 +36,56 @@ func NewServeCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "serve",
 		Short: "Start the daemon HTTP server",
-		RunE:  runServe,
+		Long: `Start the daemon HTTP server.
+
+Settings come from the "server" section of petalflow.yaml, then PETALFLOW_*
+environment variables, then flags set on the command line.`,
+		RunE: runServe,
 	}
 
 	cmd.Flags().IntP("port", "p", 8080, "Listen port")
 	cmd.Flags().String("host", "0.0.0.0", "Listen host")
 	cmd.Flags().String("cors-origin", "*", "Allowed CORS origin")
 	cmd.Flags().String("sqlite-path", "", "Path to SQLite database (default: ~/.petalflow/petalflow.db)")
-	cmd.Flags().String("config", "", "Path to petalflow.yaml tool config")
+	cmd.Flags().String("config", "", "Path to petalflow.yaml (tools and server settings)")
+	cmd.Flags().Bool("check-config", false, "Validate the configuration, print the effective settings and exit")
+	cmd.Flags().Bool("migrate-only", false, "Check the data directory, migrate the SQLite stores and exit")
 	cmd.Flags().StringArray("provider-key", nil, "Set provider API key (repeatable)")
+	_ = cmd.RegisterFlagCompletionFunc("provider-key", completeProviderKeyFlag)
+	cmd.Flags().Bool("verify-credentials", false, "Check provider keys at startup and when workflows using them are saved")
 	cmd.Flags().String("tls-cert", "", "TLS certificate file")
 	cmd.Flags().String("tls-key", "", "TLS key file")
 	cmd.Flags().Duration("read-timeout", 30*time.Second, "HTTP read timeout")
 	cmd.Flags().Duration("write-timeout", 60*time.Second, "HTTP write timeout")
 	cmd.Flags().Int64("max-body", 1<<20, "Max request body size in bytes")
+	cmd.Flags().Int64("max-upload", 32<<20, "Max upload size in bytes for POST /api/uploads")
+	cmd.Flags().Int64("upload-quota", 256<<20, "Total upload bytes stored per workspace (negative disables the quota)")
 	cmd.Flags().Duration("workflow-schedule-poll", 5*time.Second, "Workflow schedule poll interval")
+	cmd.Flags().Duration("workflow-schedule-clock-skew", 30*time.Second, "How late past the poll interval a scheduled run still counts as on time")
+	cmd.Flags().Bool("read-only", false, "Start in read-only maintenance mode: reads work, changes and new runs are rejected")
+	cmd.Flags().String("banner", "", "Announcement banner surfaced through /health and /api/maintenance")
 
 	return cmd
 }
 
 func runServe(cmd *cobra.Command, _ []string) error {
-	host, _ := cmd.Flags().GetString("host")
-	port, _ := cmd.Flags().GetInt("port")
-	corsOrigin, _ := cmd.Flags().GetString("cors-origin")
-	readTimeout, _ := cmd.Flags().GetDuration("read-timeout")
-	writeTimeout, _ := cmd.Flags().GetDuration("write-timeout")
-	maxBody, _ := cmd.Flags().GetInt64("max-body")
-	workflowSchedulePoll, _ := cmd.Flags().GetDuration("workflow-schedule-poll")
-	tlsCert, _ := cmd.Flags().GetString("tls-cert")
-	tlsKey, _ := cmd.Flags().GetString("tls-key")
-	explicitConfigPath, _ := cmd.Flags().GetString("config")
-
-	sqliteDSN, sqliteScope, err := resolveServeSQLiteDSN(cmd)
+	cfg, configPath, err := resolveServeConfig(cmd)
 	if err != nil {
 		return err
 	}
+	if checkOnly, _ := cmd.Flags().GetBool("check-config"); checkOnly {
+		return printServeConfig(cmd, cfg, configPath)
+	}
 
-	// --- Daemon tool server (Phase 3) ---
+	sqliteDSN, sqliteScope, err := resolveServeSQLiteDSN(cfg.Stores.SQLitePath)
+	if err != nil {
+		return err
+	}
+	if err := server.CheckDataDir(sqliteDSN); err != nil {
+		return exitError(exitRuntime, "%v", err)
+	}
+
+	// Opening the stores creates and migrates their tables.
 	toolStore, err := tool.NewSQLiteStore(tool.SQLiteStoreConfig{
 		DSN:   sqliteDSN,
 		Scope: sqliteScope,
@@ -79,6 +96,34 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	defer func() {
 		_ = toolStore.Close()
 	}()
+	es, err := bus.NewSQLiteEventStore(bus.SQLiteStoreConfig{
+		DSN:            sqliteDSN,
+		RetentionAge:   cfg.Stores.Events.RetentionAge,
+		RetentionCount: cfg.Stores.Events.RetentionCount,
+	})
+	if err != nil {
+		return fmt.Errorf("opening sqlite event store: %w", err)
+	}
+	defer func() {
+		_ = es.Close()
+	}()
+	workflowStore, err := server.NewSQLiteStore(server.SQLiteStoreConfig{DSN: sqliteDSN})
+	if err != nil {
+		return fmt.Errorf("opening sqlite workflow store: %w", err)
+	}
+	defer func() {
+		_ = workflowStore.Close()
+	}()
+	if migrateOnly, _ := cmd.Flags().GetBool("migrate-only"); migrateOnly {
+		fmt.Fprintf(cmd.OutOrStdout(), "Migrated %s\n", sqliteDSN)
+		return nil
+	}
+	startupChecks := []server.StartupCheck{
+		{Name: "data_dir", Status: "ok", Detail: sqliteDSN},
+		{Name: "migrations", Status: "ok"},
+	}
+
+	// --- Daemon tool server (Phase 3) ---
 
 	daemonServer, err := daemon.NewServer(daemon.ServerConfig{
 		Store: toolStore,
@@ -97,11 +142,7 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	tool.SetObserver(toolObserver)
 	defer tool.SetObserver(nil)
 
-	configPath, found, err := daemon.DiscoverToolConfigPath(explicitConfigPath)
-	if err != nil {
-		return err
-	}
-	if found {
+	if configPath != "" {
 		registered, err := daemon.RegisterToolsFromConfig(cmd.Context(), daemonServer.Service(), configPath)
 		if err != nil {
 			return fmt.Errorf("loading startup tool declarations: %w", err)
@@ -126,33 +167,46 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	}()
 
 	// --- Workflow API server ---
-	providerFlags, _ := cmd.Flags().GetStringArray("provider-key")
-	flagMap, err := hydrate.ParseProviderFlags(providerFlags)
+	providers := make(hydrate.ProviderMap, len(cfg.Providers))
+	for name, p := range cfg.Providers {
+		providers[name] = p.ProviderConfig()
+	}
+
+	eb := bus.NewMemBus(bus.MemBusConfig{SubscriberBufferSize: cfg.Bus.SubscriberBuffer})
+	logger := slog.Default()
+
+	var backups *backup.Manager
+	if cfg.AllowAdmin {
+		backups, err = backup.Open(sqliteDSN)
+		if err != nil {
+			return fmt.Errorf("opening sqlite backup manager: %w", err)
+		}
+		defer func() {
+			_ = backups.Close()
+		}()
+		if len(cfg.Auth.Tokens) == 0 && len(cfg.Auth.Principals) == 0 {
+			logger.Warn("admin API enabled without auth tokens: anyone who can reach the daemon can restore its state")
+		}
+	}
+
+	runQueue, err := serveRunQueue(cfg)
 	if err != nil {
-		return exitError(exitProvider, "invalid provider flag: %v", err)
+		return err
 	}
-	providers, err := hydrate.ResolveProviders(flagMap)
+	sandbox, err := serveTemplateSandbox(cfg)
 	if err != nil {
-		return exitError(exitProvider, "resolving providers: %v", err)
+		return err
 	}
-
-	eb := bus.NewMemBus(bus.MemBusConfig{})
-	es, err := bus.NewSQLiteEventStore(bus.SQLiteStoreConfig{DSN: sqliteDSN})
+	authorizer, err := serveAuthorizer(cfg)
 	if err != nil {
-		return fmt.Errorf("opening sqlite event store: %w", err)
+		return err
 	}
-	defer func() {
-		_ = es.Close()
-	}()
-
-	workflowStore, err := server.NewSQLiteStore(server.SQLiteStoreConfig{DSN: sqliteDSN})
+	// Workflow secrets share the tool store's key, so both follow
+	// PETALFLOW_SECRET_KEY or the database they are stored in.
+	secrets, err := tool.NewSecretCodec(sqliteScope)
 	if err != nil {
-		return fmt.Errorf("opening sqlite workflow store: %w", err)
+		return fmt.Errorf("initializing workflow secrets: %w", err)
 	}
-	defer func() {
-		_ = workflowStore.Close()
-	}()
-	logger := slog.Default()
 
 	workflowServer := server.NewServer(server.ServerConfig{
 		Store:         workflowStore,
@@ -162,28 +216,115 @@ func runServe(cmd *cobra.Command, _ []string) error {
 		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
 			return llmprovider.NewClient(name, cfg)
 		},
-		Bus:        eb,
-		EventStore: es,
-		CORSOrigin: corsOrigin,
-		MaxBody:    maxBody,
-		Logger:     logger,
+		VerifyCredentials:   cfg.VerifyCredentials.Enabled,
+		CredentialTTL:       cfg.VerifyCredentials.TTL,
+		RegionProbeInterval: cfg.RegionProbeInterval,
+		Bus:                 eb,
+		EventStore:          es,
+		RecordNodeOutputs:   cfg.Stores.Events.RecordOutputs,
+		UploadStore:         workflowStore,
+		ConditionStore:      workflowStore,
+		EvalDatasets:        workflowStore,
+		TemplateStore:       workflowStore,
+		RolloutStore:        workflowStore,
+		PresetStore:         workflowStore,
+		LifecycleStore:      workflowStore,
+		AuditStore:          workflowStore,
+		OutputHistory:       workflowStore,
+		ArmStats:            workflowStore,
+		State:               workflowStore,
+		WebhookDedupe:       workflowStore,
+		Outbox:              serveOutbox(cfg, workflowStore),
+		OutboundHeaders:     propagate.Config{Enabled: cfg.OutboundHeaders.Enabled, Extra: cfg.OutboundHeaders.Extra},
+		Secrets:             secrets,
+		TemplateSandbox:     sandbox,
+		Policy:              cfg.Policy,
+		Holidays:            cfg.Holidays,
+		CORS:                serveCORSConfig(cfg),
+		SecurityHeaders:     serveSecurityHeaders(cfg),
+		MaxBody:             cfg.Limits.MaxBody,
+		MaxUploadBytes:      cfg.Limits.MaxUpload,
+		UploadQuotaBytes:    cfg.Limits.UploadQuota,
+		RunMemorySoftLimit:  cfg.Limits.RunMemorySoft,
+		RunMemoryHardLimit:  cfg.Limits.RunMemoryHard,
+		UploadScanner:       serveUploadScanner(cfg),
+		QuarantineAction:    cfg.UploadScan.OnQuarantine,
+		AllowChaos:          cfg.AllowChaos,
+		ValidateEvents:      cfg.ValidateEvents,
+		Backups:             backups,
+		AllowAdmin:          cfg.AllowAdmin,
+		LeaseStore:          serveLeaseStore(cfg, workflowStore),
+		LeaseTTL:            cfg.Leases.TTL,
+		RunQueue:            runQueue,
+		Maintenance:         serveMaintenance(cfg),
+		Authorizer:          authorizer,
+		Logger:              logger,
+		LogLevel:            &defaultLogLevel{level: slog.LevelInfo},
 	})
+	if cfg.AllowChaos {
+		logger.Warn("fault injection enabled: run requests may set options.chaos")
+	}
+	go workflowServer.VerifyProviders(cmd.Context())
 
-	workflowScheduler, err := server.NewWorkflowScheduler(server.WorkflowSchedulerConfig{
-		Runner:       workflowServer,
-		Store:        workflowStore,
-		PollInterval: workflowSchedulePoll,
-		Logger:       logger,
-	})
-	if err != nil {
-		return fmt.Errorf("creating workflow scheduler: %w", err)
+	if cfg.Schedules.Enabled {
+		workflowScheduler, err := server.NewWorkflowScheduler(server.WorkflowSchedulerConfig{
+			Runner:       workflowServer,
+			Store:        workflowStore,
+			PollInterval: cfg.Schedules.PollInterval,
+			ClockSkew:    cfg.Schedules.ClockSkew,
+			Logger:       logger,
+		})
+		if err != nil {
+			return fmt.Errorf("creating workflow scheduler: %w", err)
+		}
+		if err := workflowScheduler.Start(cmd.Context()); err != nil {
+			return fmt.Errorf("starting workflow scheduler: %w", err)
+		}
+		defer func() {
+			_ = workflowScheduler.Stop(context.Background())
+		}()
 	}
-	if err := workflowScheduler.Start(cmd.Context()); err != nil {
-		return fmt.Errorf("starting workflow scheduler: %w", err)
+
+	if cfg.Leases.Enabled {
+		runJanitor, err := server.NewRunJanitor(server.RunJanitorConfig{
+			Runner:   workflowServer,
+			Store:    workflowStore,
+			Interval: cfg.Leases.JanitorInterval,
+			Requeue:  cfg.Leases.Requeue,
+			Logger:   logger,
+		})
+		if err != nil {
+			return fmt.Errorf("creating run janitor: %w", err)
+		}
+		if err := runJanitor.Start(cmd.Context()); err != nil {
+			return fmt.Errorf("starting run janitor: %w", err)
+		}
+		defer func() {
+			_ = runJanitor.Stop(context.Background())
+		}()
+	}
+
+	if cfg.Outbox.Enabled {
+		outboxWorker, err := server.NewOutboxWorker(server.OutboxWorkerConfig{
+			Runner:         workflowServer,
+			Store:          workflowStore,
+			PollInterval:   cfg.Outbox.PollInterval,
+			RequestTimeout: cfg.Outbox.RequestTimeout,
+			MaxAttempts:    cfg.Outbox.MaxAttempts,
+			Backoff:        cfg.Outbox.Backoff,
+			MaxBackoff:     cfg.Outbox.MaxBackoff,
+			Logger:         logger,
+		})
+		if err != nil {
+			return fmt.Errorf("creating outbox worker: %w", err)
+		}
+		if err := outboxWorker.Start(cmd.Context()); err != nil {
+			return fmt.Errorf("starting outbox worker: %w", err)
+		}
+		defer func() {
+			_ = outboxWorker.Stop(context.Background())
+		}()
 	}
-	defer func() {
-		_ = workflowScheduler.Stop(context.Background())
-	}()
 
 	// Compose both handlers on one mux.
 	// Workflow routes: /health, /api/workflows/*, /api/runs/*, /api/node-types
@@ -194,26 +335,31 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	mux.Handle("/api/tools/", daemonHandler)
 	mux.Handle("/api/tools", daemonHandler)
 
-	handler := withCORS(mux, corsOrigin)
-	handler = maxBodyMiddleware(handler, maxBody)
+	handler := workflowServer.AuthorizationMiddleware(workflowServer.ReadOnlyMiddleware(mux))
+	handler = workflowServer.AuditMiddleware(handler)
+	handler = withAuth(handler, cfg.Auth)
+	handler = server.CORSMiddleware(serveCORSConfig(cfg))(handler)
+	handler = server.SecurityHeadersMiddleware(*serveSecurityHeaders(cfg))(handler)
+	handler = maxBodyMiddleware(handler, cfg.Limits.MaxBody)
 
-	addr := net.JoinHostPort(host, fmt.Sprintf("%d", port))
+	addr := net.JoinHostPort(cfg.Host, fmt.Sprintf("%d", cfg.Port))
 	httpServer := &http.Server{
 		Addr:         addr,
 		Handler:      handler,
-		ReadTimeout:  readTimeout,
-		WriteTimeout: writeTimeout,
+		ReadTimeout:  cfg.Limits.ReadTimeout,
+		WriteTimeout: cfg.Limits.WriteTimeout,
 	}
 
 	// Signal handling
 	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	workflowServer.MarkStarted(startupChecks)
 	errCh := make(chan error, 1)
 	go func() {
 		fmt.Fprintf(cmd.OutOrStdout(), "PetalFlow daemon listening on %s\n", addr)
-		if tlsCert != "" && tlsKey != "" {
-			errCh <- httpServer.ListenAndServeTLS(tlsCert, tlsKey)
+		if cfg.TLS.Cert != "" && cfg.TLS.Key != "" {
+			errCh <- httpServer.ListenAndServeTLS(cfg.TLS.Cert, cfg.TLS.Key)
 		} else {
 			errCh <- httpServer.ListenAndServe()
 		}
@@ -238,16 +384,8 @@ func runServe(cmd *cobra.Command, _ []string) error {
 	}
 }
 
-func resolveServeSQLiteDSN(cmd *cobra.Command) (string, string, error) {
-	sqlitePath, _ := cmd.Flags().GetString("sqlite-path")
+func resolveServeSQLiteDSN(sqlitePath string) (string, string, error) {
 	dsn := strings.TrimSpace(sqlitePath)
-	if dsn == "" {
-		dsn = strings.TrimSpace(os.Getenv("PETALFLOW_SQLITE_PATH"))
-	}
-	if dsn == "" {
-		// Backward-compatible fallback for existing automation/env usage.
-		dsn = strings.TrimSpace(os.Getenv("PETALFLOW_TOOLS_STORE_PATH"))
-	}
 	if dsn == "" {
 		defaultPath, err := tool.DefaultSQLitePath()
 		if err != nil {
@@ -265,22 +403,95 @@ func resolveServeSQLiteDSN(cmd *cobra.Command) (string, string, error) {
 	return dsn, scope, nil
 }
 
-func withCORS(next http.Handler, allowedOrigin string) http.Handler {
-	origin := strings.TrimSpace(allowedOrigin)
-	if origin == "" {
-		origin = "*"
+// serveMaintenance returns the maintenance state the daemon starts in.
+func serveMaintenance(cfg daemon.ServeConfig) server.MaintenanceState {
+	state := server.MaintenanceState{
+		ReadOnly: cfg.Maintenance.ReadOnly,
+		Message:  cfg.Maintenance.Message,
+	}
+	if strings.TrimSpace(cfg.Maintenance.Banner) != "" {
+		state.Banner = &server.Banner{Message: cfg.Maintenance.Banner, Level: cfg.Maintenance.BannerLevel}
 	}
+	return state
+}
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", origin)
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
-			return
+// defaultLogLevel is the level of slog's default handler, which the
+// daemon logs through.
+type defaultLogLevel struct {
+	mu    sync.Mutex
+	level slog.Level
+}
+
+func (l *defaultLogLevel) Level() slog.Level {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.level
+}
+
+func (l *defaultLogLevel) Set(level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	slog.SetLogLoggerLevel(level)
+	l.level = level
+}
+
+// serveTemplateSandbox returns the sandbox for stored workflow templates.
+func serveTemplateSandbox(cfg daemon.ServeConfig) (*nodes.TemplateSandbox, error) {
+	sb := cfg.TemplateSandbox
+	sandbox := &nodes.TemplateSandbox{
+		AllowedFuncs:       sb.AllowedFuncs,
+		Timeout:            sb.Timeout,
+		MaxOutputBytes:     sb.MaxOutputBytes,
+		MaxRangeIterations: sb.MaxRangeIterations,
+		MaxDepth:           sb.MaxDepth,
+	}
+	if err := sandbox.Validate(); err != nil {
+		return nil, fmt.Errorf("server.template_sandbox: %w", err)
+	}
+	return sandbox, nil
+}
+
+// serveRunQueue builds the run queue when run_queue.max_concurrent is set.
+func serveRunQueue(cfg daemon.ServeConfig) (*server.RunQueue, error) {
+	if cfg.RunQueue.MaxConcurrent <= 0 {
+		return nil, nil
+	}
+	weights := make(map[server.RunClass]int, len(cfg.RunQueue.Weights))
+	for name, weight := range cfg.RunQueue.Weights {
+		class, err := server.ParseRunClass(name)
+		if err != nil {
+			return nil, fmt.Errorf("server.run_queue.weights: %w", err)
 		}
-		next.ServeHTTP(w, r)
+		weights[class] = weight
+	}
+	queue, err := server.NewRunQueue(server.RunQueueConfig{
+		MaxConcurrent: cfg.RunQueue.MaxConcurrent,
+		Weights:       weights,
+		MaxWait:       cfg.RunQueue.MaxWait,
 	})
+	if err != nil {
+		return nil, fmt.Errorf("creating run queue: %w", err)
+	}
+	return queue, nil
+}
+
+// serveLeaseStore returns the lease store when run leases are enabled. A
+// typed nil would look configured to the server, so nil is returned
+// explicitly.
+func serveLeaseStore(cfg daemon.ServeConfig, store *server.SQLiteStore) server.RunLeaseStore {
+	if !cfg.Leases.Enabled {
+		return nil
+	}
+	return store
+}
+
+// serveOutbox returns the outbox store, or nil when outbox delivery is
+// disabled and webhook_call nodes send directly.
+func serveOutbox(cfg daemon.ServeConfig, store *server.SQLiteStore) server.OutboxStore {
+	if !cfg.Outbox.Enabled {
+		return nil
+	}
+	return store
 }
 
 func maxBodyMiddleware(next http.Handler, maxBody int64) http.Handler {
@@ -288,6 +499,10 @@ func maxBodyMiddleware(next http.Handler, maxBody int64) http.Handler {
 		maxBody = 1 << 20
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if server.IsUploadRequest(r) || server.IsRestoreRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
 		r.Body = http.MaxBytesReader(w, r.Body, maxBody)
 		next.ServeHTTP(w, r)
 	})
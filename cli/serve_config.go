@@ -0,0 +1,279 @@
+package cli
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/petal-labs/petalflow/daemon"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/scan"
+	"github.com/petal-labs/petalflow/server"
+)
+
+// serveFlagOverrides copies explicitly set serve flags onto the config.
+// Flags left at their defaults do not override the file or environment.
+var serveFlagOverrides = map[string]func(cmd *cobra.Command, cfg *daemon.ServeConfig){
+	"host": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Host, _ = c.Flags().GetString("host")
+	},
+	"port": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Port, _ = c.Flags().GetInt("port")
+	},
+	"cors-origin": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.CORSOrigin, _ = c.Flags().GetString("cors-origin")
+		cfg.CORS.AllowedOrigins = nil
+	},
+	"sqlite-path": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Stores.SQLitePath, _ = c.Flags().GetString("sqlite-path")
+	},
+	"tls-cert": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.TLS.Cert, _ = c.Flags().GetString("tls-cert")
+	},
+	"tls-key": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.TLS.Key, _ = c.Flags().GetString("tls-key")
+	},
+	"read-timeout": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Limits.ReadTimeout, _ = c.Flags().GetDuration("read-timeout")
+	},
+	"write-timeout": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Limits.WriteTimeout, _ = c.Flags().GetDuration("write-timeout")
+	},
+	"max-body": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Limits.MaxBody, _ = c.Flags().GetInt64("max-body")
+	},
+	"max-upload": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Limits.MaxUpload, _ = c.Flags().GetInt64("max-upload")
+	},
+	"upload-quota": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Limits.UploadQuota, _ = c.Flags().GetInt64("upload-quota")
+	},
+	"workflow-schedule-poll": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Schedules.PollInterval, _ = c.Flags().GetDuration("workflow-schedule-poll")
+	},
+	"workflow-schedule-clock-skew": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Schedules.ClockSkew, _ = c.Flags().GetDuration("workflow-schedule-clock-skew")
+	},
+	"read-only": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Maintenance.ReadOnly, _ = c.Flags().GetBool("read-only")
+	},
+	"banner": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.Maintenance.Banner, _ = c.Flags().GetString("banner")
+	},
+	"verify-credentials": func(c *cobra.Command, cfg *daemon.ServeConfig) {
+		cfg.VerifyCredentials.Enabled, _ = c.Flags().GetBool("verify-credentials")
+	},
+}
+
+// resolveServeConfig builds the effective serve configuration from
+// defaults, the discovered petalflow.yaml, the environment and flags, and
+// validates it. It also returns the config file path ("" when none exists).
+func resolveServeConfig(cmd *cobra.Command) (daemon.ServeConfig, string, error) {
+	cfg := daemon.DefaultServeConfig()
+
+	explicitConfigPath, _ := cmd.Flags().GetString("config")
+	configPath, found, err := daemon.DiscoverToolConfigPath(explicitConfigPath)
+	if err != nil {
+		return cfg, "", err
+	}
+	if !found {
+		configPath = ""
+	}
+	if configPath != "" {
+		if err := daemon.LoadServeConfig(configPath, &cfg); err != nil {
+			return cfg, configPath, exitError(exitValidation, "%v", err)
+		}
+	}
+
+	if err := cfg.ApplyEnv(os.LookupEnv); err != nil {
+		return cfg, configPath, exitError(exitValidation, "invalid environment override: %v", err)
+	}
+	for name, apply := range serveFlagOverrides {
+		if cmd.Flags().Changed(name) {
+			apply(cmd, &cfg)
+		}
+	}
+
+	providerFlags, _ := cmd.Flags().GetStringArray("provider-key")
+	flagMap, err := hydrate.ParseProviderFlags(providerFlags)
+	if err != nil {
+		return cfg, configPath, exitError(exitProvider, "invalid provider flag: %v", err)
+	}
+	resolved, err := hydrate.ResolveProviders(flagMap)
+	if err != nil {
+		return cfg, configPath, exitError(exitProvider, "resolving providers: %v", err)
+	}
+	cfg.Providers = mergeServeProviders(cfg.Providers, resolved)
+
+	if err := cfg.Validate(); err != nil {
+		return cfg, configPath, exitError(exitValidation, "invalid server configuration:\n%v", err)
+	}
+	return cfg, configPath, nil
+}
+
+// mergeServeProviders layers providers from config.json, environment and
+// flags over the petalflow.yaml entries, field by field.
+func mergeServeProviders(file map[string]daemon.ServeProviderConfig, resolved hydrate.ProviderMap) map[string]daemon.ServeProviderConfig {
+	merged := make(map[string]daemon.ServeProviderConfig, len(file)+len(resolved))
+	for name, p := range file {
+		merged[name] = p
+	}
+	for name, p := range resolved {
+		entry := merged[name]
+		if p.APIKey != "" {
+			entry.APIKey = p.APIKey
+		}
+		if p.BaseURL != "" {
+			entry.BaseURL = p.BaseURL
+		}
+		if len(p.Regions) > 0 {
+			entry.Regions = make([]daemon.ServeProviderRegionConfig, len(p.Regions))
+			for i, r := range p.Regions {
+				entry.Regions[i] = daemon.ServeProviderRegionConfig{Name: r.Name, BaseURL: r.BaseURL, APIKey: r.APIKey}
+			}
+		}
+		if p.Routing != "" {
+			entry.Routing = p.Routing
+		}
+		merged[name] = entry
+	}
+	return merged
+}
+
+// serveCORSConfig converts the cors settings for the server middleware.
+func serveCORSConfig(cfg daemon.ServeConfig) server.CORSConfig {
+	return server.CORSConfig{
+		AllowedOrigins:   cfg.CORSOrigins(),
+		AllowedMethods:   cfg.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.CORS.AllowedHeaders,
+		ExposedHeaders:   cfg.CORS.ExposedHeaders,
+		RouteMethods:     cfg.CORS.RouteMethods,
+		AllowCredentials: cfg.CORS.AllowCredentials,
+		MaxAge:           cfg.CORS.MaxAge,
+	}
+}
+
+// serveSecurityHeaders converts the security_headers settings for the
+// server middleware.
+func serveSecurityHeaders(cfg daemon.ServeConfig) *server.SecurityHeadersConfig {
+	h := cfg.SecurityHeaders
+	return &server.SecurityHeadersConfig{
+		HSTSMaxAge:            h.HSTSMaxAge,
+		HSTSIncludeSubdomains: h.HSTSIncludeSubdomains,
+		ContentTypeNosniff:    h.ContentTypeNosniff,
+		FrameOptions:          strings.ToUpper(h.FrameOptions),
+		ReferrerPolicy:        h.ReferrerPolicy,
+		ContentSecurityPolicy: h.ContentSecurityPolicy,
+	}
+}
+
+// printServeConfig writes the effective configuration, with secrets
+// redacted, in the same shape as the petalflow.yaml "server" section.
+func printServeConfig(cmd *cobra.Command, cfg daemon.ServeConfig, configPath string) error {
+	out := cmd.OutOrStdout()
+	if configPath != "" {
+		fmt.Fprintf(out, "# config file: %s\n", configPath)
+	} else {
+		fmt.Fprintln(out, "# config file: none (defaults, environment and flags)")
+	}
+	data, err := yaml.Marshal(map[string]daemon.ServeConfig{"server": cfg.Redacted()})
+	if err != nil {
+		return fmt.Errorf("encoding configuration: %w", err)
+	}
+	_, err = out.Write(data)
+	return err
+}
+
+// withAuth requires one of the configured tokens as a bearer token on API
+// requests and records the caller for authorization: the principal's name
+// for named tokens, anonymous for plain ones. Health checks, webhook
+// deliveries and tool callbacks, which authenticate themselves, are exempt. No tokens
+// disables the check.
+func withAuth(next http.Handler, auth daemon.ServeAuthConfig) http.Handler {
+	if len(auth.Tokens) == 0 && len(auth.Principals) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || server.IsWebhookRequest(r) || server.IsToolCallbackRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		principal, err := checkBearerToken(r, auth)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="petalflow"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprintf(w, `{"error":{"code":"UNAUTHORIZED","message":%q}}`, err.Error())
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(server.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+func checkBearerToken(r *http.Request, auth daemon.ServeAuthConfig) (server.Principal, error) {
+	presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || presented == "" {
+		return server.Principal{}, errors.New("missing bearer token")
+	}
+	for _, p := range auth.Principals {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(p.Token)) == 1 {
+			return server.Principal{ID: p.Name}, nil
+		}
+	}
+	for _, token := range auth.Tokens {
+		if subtle.ConstantTimeCompare([]byte(presented), []byte(token)) == 1 {
+			return server.Principal{}, nil
+		}
+	}
+	return server.Principal{}, errors.New("invalid bearer token")
+}
+
+// serveUploadScanner builds the upload scanner: the policy checks, then
+// ClamAV. It returns nil when upload_scan configures neither.
+func serveUploadScanner(cfg daemon.ServeConfig) scan.Scanner {
+	sc := cfg.UploadScan
+	if !sc.Enabled() {
+		return nil
+	}
+	var scanners []scan.Scanner
+	if sc.MaxBytes > 0 || len(sc.BlockedTypes) > 0 || len(sc.BlockedExtensions) > 0 || sc.BlockExecutables {
+		scanners = append(scanners, &scan.Policy{
+			MaxBytes:          sc.MaxBytes,
+			BlockedTypes:      sc.BlockedTypes,
+			BlockedExtensions: sc.BlockedExtensions,
+			BlockExecutables:  sc.BlockExecutables,
+		})
+	}
+	if sc.ClamAV != "" {
+		scanners = append(scanners, scan.NewClamAV(sc.ClamAV, sc.Timeout))
+	}
+	return scan.Chain(scanners...)
+}
+
+// serveAuthorizer builds the authorizer for auth.authorization.mode. It
+// returns nil when authorization is off.
+func serveAuthorizer(cfg daemon.ServeConfig) (server.Authorizer, error) {
+	authz := cfg.Auth.Authorization
+	switch authz.Mode {
+	case daemon.AuthorizationRBAC:
+		bindings := make([]server.RoleBinding, len(authz.Bindings))
+		for i, b := range authz.Bindings {
+			bindings[i] = server.RoleBinding{Principal: b.Principal, Role: b.Role, Workspace: b.Workspace, Workflow: b.Workflow}
+		}
+		rbac, err := server.NewRBACAuthorizer(bindings)
+		if err != nil {
+			return nil, fmt.Errorf("server.auth.authorization: %w", err)
+		}
+		return rbac, nil
+	case daemon.AuthorizationWebhook:
+		return server.NewWebhookAuthorizer(authz.Webhook.URL, authz.Webhook.Token, authz.Webhook.Timeout), nil
+	default:
+		return nil, nil
+	}
+}
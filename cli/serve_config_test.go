@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/petal-labs/petalflow/daemon"
+	"github.com/petal-labs/petalflow/server"
+)
+
+func TestServe_CheckConfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("PETALFLOW_MAX_BODY", "4096")
+	path := writeTestFile(t, "petalflow.yaml", `
+server:
+  port: 9000
+  limits:
+    max_body: 2048
+  auth:
+    tokens: [top-secret]
+`)
+
+	root := &cobra.Command{Use: "petalflow", SilenceUsage: true}
+	root.AddCommand(NewServeCmd())
+	stdout, _, err := executeCommand(root, "serve", "--config", path, "--check-config", "--port", "9100")
+	if err != nil {
+		t.Fatalf("check-config: %v", err)
+	}
+	for _, want := range []string{"port: 9100", "max_body: 4096", "<redacted>", "read_timeout: 30s"} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("output missing %q:\n%s", want, stdout)
+		}
+	}
+	if strings.Contains(stdout, "top-secret") {
+		t.Error("auth token leaked in output")
+	}
+
+	bad := writeTestFile(t, "petalflow.yaml", "server:\n  port: 70000\n")
+	root = &cobra.Command{Use: "petalflow", SilenceUsage: true}
+	root.AddCommand(NewServeCmd())
+	if _, _, err := executeCommand(root, "serve", "--config", bad, "--check-config"); err == nil || !strings.Contains(err.Error(), "server.port") {
+		t.Fatalf("expected port validation error, got %v", err)
+	}
+}
+
+func TestServe_MigrateOnly(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	dbPath := filepath.Join(t.TempDir(), "data", "petalflow.db")
+
+	root := &cobra.Command{Use: "petalflow", SilenceUsage: true}
+	root.AddCommand(NewServeCmd())
+	stdout, _, err := executeCommand(root, "serve", "--sqlite-path", dbPath, "--migrate-only")
+	if err != nil {
+		t.Fatalf("migrate-only: %v", err)
+	}
+	if !strings.Contains(stdout, "Migrated "+dbPath) {
+		t.Errorf("output = %q", stdout)
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		t.Fatalf("database not created: %v", err)
+	}
+}
+
+func TestWithAuth(t *testing.T) {
+	handler := withAuth(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), daemon.ServeAuthConfig{Tokens: []string{"token-1"}})
+
+	tests := []struct {
+		path, auth string
+		want       int
+	}{
+		{"/api/workflows", "", http.StatusUnauthorized},
+		{"/api/workflows", "Bearer wrong", http.StatusUnauthorized},
+		{"/api/workflows", "Bearer token-1", http.StatusOK},
+		{"/health", "", http.StatusOK},
+		{"/api/workflows/wf/webhooks/incoming", "", http.StatusOK},
+		{"/api/workflows/webhooks/run", "", http.StatusUnauthorized},
+		{"/api/workflows/webhooks/export", "", http.StatusUnauthorized},
+		{"/api/workflows/webhooks/presets/nightly", "", http.StatusUnauthorized},
+		{"/api/workflows/wf/webhooks/incoming/extra", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.auth != "" {
+			r.Header.Set("Authorization", tt.auth)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s with %q: got %d, want %d", tt.path, tt.auth, w.Code, tt.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/tool-callbacks/3f2a9c0d8e7b6a5f4e3d2c1b0a998877", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("tool callback without a token: got %d, want 200", w.Code)
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/tool-callbacks/job-8841", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("callback to a token the daemon cannot have issued: got %d, want 401", w.Code)
+	}
+}
+
+func TestWithAuth_Principals(t *testing.T) {
+	var got server.Principal
+	handler := withAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = server.PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}), daemon.ServeAuthConfig{
+		Tokens:     []string{"shared"},
+		Principals: []daemon.ServeAuthPrincipal{{Name: "ci-bot", Token: "bot-token"}},
+	})
+
+	for token, want := range map[string]string{"bot-token": "ci-bot", "shared": ""} {
+		got = server.Principal{ID: "unset"}
+		r := httptest.NewRequest(http.MethodGet, "/api/workflows", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK || got.ID != want {
+			t.Errorf("token %q: status %d, principal %q, want %q", token, w.Code, got.ID, want)
+		}
+	}
+}
+
+func TestServeAuthorizer(t *testing.T) {
+	cfg := daemon.DefaultServeConfig()
+	if authz, err := serveAuthorizer(cfg); err != nil || authz != nil {
+		t.Fatalf("no mode: %v, %v", authz, err)
+	}
+
+	cfg.Auth.Authorization = daemon.ServeAuthorizationConfig{
+		Mode:     daemon.AuthorizationRBAC,
+		Bindings: []daemon.ServeRoleBinding{{Principal: "ci-bot", Role: "runner", Workflow: "nightly"}},
+	}
+	authz, err := serveAuthorizer(cfg)
+	if err != nil {
+		t.Fatalf("rbac: %v", err)
+	}
+	resource := server.Resource{Kind: "workflows", ID: "nightly", Workflow: "nightly"}
+	if err := authz.Authorize(context.Background(), server.Principal{ID: "ci-bot"}, server.ActionRun, resource); err != nil {
+		t.Errorf("run nightly: %v", err)
+	}
+	if err := authz.Authorize(context.Background(), server.Principal{ID: "ci-bot"}, server.ActionEdit, resource); !errors.Is(err, server.ErrForbidden) {
+		t.Errorf("edit nightly: got %v, want forbidden", err)
+	}
+
+	cfg.Auth.Authorization = daemon.ServeAuthorizationConfig{
+		Mode:    daemon.AuthorizationWebhook,
+		Webhook: daemon.ServeAuthorizationWebhookConfig{URL: "https://policy.internal/decide"},
+	}
+	authz, err = serveAuthorizer(cfg)
+	if err != nil {
+		t.Fatalf("webhook: %v", err)
+	}
+	if wh, ok := authz.(*server.WebhookAuthorizer); !ok || wh.URL != "https://policy.internal/decide" {
+		t.Errorf("webhook authorizer = %#v", authz)
+	}
+}
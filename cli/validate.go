@@ -6,15 +6,14 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
 
 	"github.com/petal-labs/petalflow/agent"
 	"github.com/petal-labs/petalflow/graph"
 	"github.com/petal-labs/petalflow/loader"
+	"github.com/petal-labs/petalflow/policy"
 	"github.com/petal-labs/petalflow/registry"
 )
 
@@ -29,6 +28,7 @@ func NewValidateCmd() *cobra.Command {
 
 	cmd.Flags().String("format", "text", "Output format: text | json")
 	cmd.Flags().Bool("strict", false, "Treat warnings as errors")
+	cmd.Flags().String("policy", "", "Also check the workflow against an organization policy file (YAML or JSON)")
 
 	return cmd
 }
@@ -37,8 +37,17 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	filePath := args[0]
 	format, _ := cmd.Flags().GetString("format")
 	strict, _ := cmd.Flags().GetBool("strict")
+	policyPath, _ := cmd.Flags().GetString("policy")
 	out := cmd.OutOrStdout()
 
+	var pol *policy.Policy
+	if policyPath != "" {
+		var err error
+		if pol, err = policy.Load(policyPath); err != nil {
+			return exitError(exitInputParse, "%v", err)
+		}
+	}
+
 	// Read the file.
 	data, err := os.ReadFile(filePath) // #nosec G304 -- path from user CLI arg
 	if err != nil {
@@ -48,21 +57,22 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("reading file: %w", err)
 	}
 
-	// Detect schema kind.
-	kind, err := loader.DetectSchema(data, filePath)
+	docs, err := loader.SplitDocuments(data, loader.FormatForPath(data, filePath))
 	if err != nil {
 		return fmt.Errorf("detecting schema: %w", err)
 	}
 
 	var diags []graph.Diagnostic
-
-	switch kind {
-	case loader.SchemaKindAgent:
-		diags = validateAgentWorkflow(data, filePath)
-	case loader.SchemaKindGraph:
-		diags = validateGraphIR(data, filePath)
-	default:
-		return fmt.Errorf("unknown schema kind %q", kind)
+	ids := loader.DocumentIDs(docs)
+	for i, doc := range docs {
+		docDiags, err := validateDocument(doc, pol)
+		if err != nil {
+			return err
+		}
+		if len(docs) > 1 {
+			labelDiagnostics(docDiags, ids[i])
+		}
+		diags = append(diags, docDiags...)
 	}
 
 	// Print diagnostics in the requested format.
@@ -79,18 +89,52 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-// validateAgentWorkflow runs the agent-task validator and, if no errors,
-// compiles to a GraphDefinition and runs graph validation as a second pass.
-func validateAgentWorkflow(data []byte, filePath string) []graph.Diagnostic {
-	jsonData, err := yamlToJSONIfNeeded(data, filePath)
+// validateDocument validates one workflow of a file and, when pol is set and
+// the workflow is otherwise valid, checks it against the policy.
+func validateDocument(doc []byte, pol *policy.Policy) ([]graph.Diagnostic, error) {
+	kind, err := loader.DetectSchema(doc, "")
 	if err != nil {
-		return []graph.Diagnostic{{
-			Code:     "AT-000",
-			Severity: graph.SeverityError,
-			Message:  fmt.Sprintf("Failed to parse file: %v", err),
-		}}
+		return nil, fmt.Errorf("detecting schema: %w", err)
 	}
 
+	var diags []graph.Diagnostic
+	switch kind {
+	case loader.SchemaKindAgent:
+		diags = validateAgentWorkflow(doc)
+	case loader.SchemaKindGraph:
+		diags = validateGraphIR(doc)
+	default:
+		return nil, fmt.Errorf("unknown schema kind %q", kind)
+	}
+
+	// Policy checks need the compiled graph, so they run only on workflows
+	// that are otherwise valid.
+	if pol != nil && !graph.HasErrors(diags) {
+		gd, _, err := loader.LoadDocument(doc)
+		if err != nil {
+			return nil, exitError(exitValidation, "%v", err)
+		}
+		diags = append(diags, pol.Check(gd)...)
+	}
+	return diags, nil
+}
+
+// labelDiagnostics prefixes the paths of a workflow's diagnostics with its
+// id, telling apart the workflows of a multi-document file.
+func labelDiagnostics(diags []graph.Diagnostic, id string) {
+	for i := range diags {
+		if diags[i].Path == "" {
+			diags[i].Path = id
+		} else {
+			diags[i].Path = id + ":" + diags[i].Path
+		}
+	}
+}
+
+// validateAgentWorkflow runs the agent-task validator on a JSON document
+// and, if no errors, compiles to a GraphDefinition and runs graph
+// validation as a second pass.
+func validateAgentWorkflow(jsonData []byte) []graph.Diagnostic {
 	wf, err := agent.LoadFromBytes(jsonData)
 	if err != nil {
 		return []graph.Diagnostic{{
@@ -121,17 +165,8 @@ func validateAgentWorkflow(data []byte, filePath string) []graph.Diagnostic {
 	return diags
 }
 
-// validateGraphIR parses a Graph IR file and runs graph validation.
-func validateGraphIR(data []byte, filePath string) []graph.Diagnostic {
-	jsonData, err := yamlToJSONIfNeeded(data, filePath)
-	if err != nil {
-		return []graph.Diagnostic{{
-			Code:     "GR-000",
-			Severity: graph.SeverityError,
-			Message:  fmt.Sprintf("Failed to parse file: %v", err),
-		}}
-	}
-
+// validateGraphIR parses a Graph IR document and runs graph validation.
+func validateGraphIR(jsonData []byte) []graph.Diagnostic {
 	var gd graph.GraphDefinition
 	if err := json.Unmarshal(jsonData, &gd); err != nil {
 		return []graph.Diagnostic{{
@@ -164,6 +199,12 @@ func printDiagnosticsText(w io.Writer, diags []graph.Diagnostic) {
 		} else {
 			fmt.Fprintf(w, "%s [%s]: %s\n", sev, d.Code, d.Message)
 		}
+		if d.NodeDescription != "" {
+			fmt.Fprintf(w, "    %s: %s\n", d.Node, d.NodeDescription)
+		}
+		if d.DocURL != "" {
+			fmt.Fprintf(w, "    docs: %s\n", d.DocURL)
+		}
 	}
 
 	errs := graph.Errors(diags)
@@ -191,20 +232,6 @@ func printDiagnosticsJSON(w io.Writer, diags []graph.Diagnostic) {
 	_ = enc.Encode(diags)
 }
 
-// yamlToJSONIfNeeded converts YAML data to JSON if the file path indicates a
-// YAML file. JSON files are returned as-is.
-func yamlToJSONIfNeeded(data []byte, path string) ([]byte, error) {
-	ext := strings.ToLower(filepath.Ext(path))
-	if ext == ".yaml" || ext == ".yml" {
-		var raw any
-		if err := yaml.Unmarshal(data, &raw); err != nil {
-			return nil, err
-		}
-		return json.Marshal(raw)
-	}
-	return data, nil
-}
-
 // pluralize returns the singular or plural form of a word based on count.
 func pluralize(word string, count int) string {
 	if count == 1 {
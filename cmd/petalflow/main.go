@@ -8,6 +8,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/petal-labs/petalflow/cli"
+	"github.com/petal-labs/petalflow/graph"
 )
 
 // Set via ldflags at build time.
@@ -37,11 +38,22 @@ func init() {
 	rootCmd.PersistentFlags().Bool("no-color", false, "Disable colored output")
 
 	rootCmd.Version = version
-	rootCmd.SetVersionTemplate(fmt.Sprintf("petalflow version %s\n", version))
+	rootCmd.SetVersionTemplate(fmt.Sprintf("petalflow version %s (workflow engine %s)\n", version, graph.EngineVersion))
 
 	rootCmd.AddCommand(cli.NewRunCmd())
 	rootCmd.AddCommand(cli.NewCompileCmd())
+	rootCmd.AddCommand(cli.NewRefactorCmd())
 	rootCmd.AddCommand(cli.NewValidateCmd())
+	rootCmd.AddCommand(cli.NewNewCmd())
 	rootCmd.AddCommand(cli.NewServeCmd())
+	rootCmd.AddCommand(cli.NewMigrateCmd())
+	rootCmd.AddCommand(cli.NewAdminCmd())
 	rootCmd.AddCommand(cli.NewToolsCmd())
+	rootCmd.AddCommand(cli.NewWorkflowsCmd())
+	rootCmd.AddCommand(cli.NewRunsCmd())
+	rootCmd.AddCommand(cli.NewSchedulesCmd())
+	rootCmd.AddCommand(cli.NewConditionsCmd())
+	rootCmd.AddCommand(cli.NewEvalCmd())
+	rootCmd.AddCommand(cli.NewExprCmd())
+	rootCmd.AddCommand(cli.NewTemplateCmd())
 }
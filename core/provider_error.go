@@ -0,0 +1,108 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ProviderErrorCode is the normalized cause of a failed LLM provider call.
+type ProviderErrorCode string
+
+const (
+	// ProviderErrInvalidAPIKey means the provider rejected the credentials.
+	ProviderErrInvalidAPIKey ProviderErrorCode = "invalid_api_key"
+	// ProviderErrModelNotFound means the model does not exist or the key
+	// has no access to it.
+	ProviderErrModelNotFound ProviderErrorCode = "model_not_found"
+	// ProviderErrContextLength means the request exceeded the model's
+	// context window.
+	ProviderErrContextLength ProviderErrorCode = "context_length_exceeded"
+	// ProviderErrContentFiltered means the provider's safety system
+	// blocked the prompt or the completion.
+	ProviderErrContentFiltered ProviderErrorCode = "content_filtered"
+	// ProviderErrRateLimited means too many requests or tokens were sent
+	// in a short time.
+	ProviderErrRateLimited ProviderErrorCode = "rate_limited"
+	// ProviderErrQuotaExceeded means the account is out of credit or over
+	// its spending limit.
+	ProviderErrQuotaExceeded ProviderErrorCode = "quota_exceeded"
+	// ProviderErrUnavailable means the provider could not be reached or
+	// failed on its side.
+	ProviderErrUnavailable ProviderErrorCode = "provider_unavailable"
+)
+
+// providerRemediations are the user-facing hints for each code.
+var providerRemediations = map[ProviderErrorCode]string{
+	ProviderErrInvalidAPIKey:   "Check the provider's API key: it may be missing, mistyped, revoked, or lack permission for this request.",
+	ProviderErrModelNotFound:   "Check the model name for typos and that the account has access to it; list the provider's models to confirm.",
+	ProviderErrContextLength:   "Shorten the prompt or input, add a compact_messages node, lower max_tokens, or switch to a model with a larger context window.",
+	ProviderErrContentFiltered: "The provider's safety filter blocked the request or the response; review the input for policy-sensitive content or rephrase the prompt.",
+	ProviderErrRateLimited:     "Slow down: add a retry policy with backoff, lower map concurrency, or request a higher rate limit from the provider.",
+	ProviderErrQuotaExceeded:   "The account is out of credit or over its spending limit; add billing credit or raise the limit in the provider console.",
+	ProviderErrUnavailable:     "The provider is unreachable or failing; retry later, add a retry policy, or check the provider's status page and base_url.",
+}
+
+// Remediation returns a user-facing hint for resolving errors with code c,
+// or "" for an unknown code.
+func (c ProviderErrorCode) Remediation() string {
+	return providerRemediations[c]
+}
+
+// Retryable reports whether a call that failed with code c may succeed
+// when retried unchanged.
+func (c ProviderErrorCode) Retryable() bool {
+	return c == ProviderErrRateLimited || c == ProviderErrUnavailable
+}
+
+// ProviderError is a failed LLM provider call classified into a
+// ProviderErrorCode. LLM clients return it so failures read the same
+// whatever the provider's own error prose.
+type ProviderError struct {
+	Provider string
+	Code     ProviderErrorCode
+	// Status is the provider's HTTP status, or 0 when there was none.
+	Status int
+	Err    error
+}
+
+// Error returns the provider's original message; the code and hint are
+// in ErrorDetails.
+func (e *ProviderError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s: %s", e.Provider, e.Code)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the provider's original error.
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// Remediation returns the hint for e's code.
+func (e *ProviderError) Remediation() string {
+	return e.Code.Remediation()
+}
+
+// ErrorDetails implements DetailedError, so node failures carry the code
+// and hint.
+func (e *ProviderError) ErrorDetails() map[string]any {
+	details := map[string]any{
+		"provider":    e.Provider,
+		"error_code":  string(e.Code),
+		"remediation": e.Remediation(),
+	}
+	if e.Status != 0 {
+		details["status"] = e.Status
+	}
+	return details
+}
+
+// AsProviderError returns the first ProviderError in err's chain.
+func AsProviderError(err error) (*ProviderError, bool) {
+	var pe *ProviderError
+	if errors.As(err, &pe) {
+		return pe, true
+	}
+	return nil, false
+}
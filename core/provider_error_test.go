@@ -0,0 +1,49 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestProviderError(t *testing.T) {
+	cause := errors.New("openai: Incorrect API key provided (status=401, code=invalid_api_key)")
+	err := fmt.Errorf("node answer: %w", &ProviderError{
+		Provider: "openai",
+		Code:     ProviderErrInvalidAPIKey,
+		Status:   401,
+		Err:      cause,
+	})
+
+	pe, ok := AsProviderError(err)
+	if !ok {
+		t.Fatal("AsProviderError did not find the provider error")
+	}
+	if pe.Error() != cause.Error() || !errors.Is(err, cause) {
+		t.Fatalf("error = %q, want the provider's message", pe.Error())
+	}
+	details := ErrorDetails(err)
+	if details["error_code"] != "invalid_api_key" || details["provider"] != "openai" || details["status"] != 401 ||
+		details["remediation"] != ProviderErrInvalidAPIKey.Remediation() {
+		t.Fatalf("details = %v", details)
+	}
+
+	if _, ok := AsProviderError(errors.New("plain")); ok {
+		t.Fatal("plain errors are not provider errors")
+	}
+}
+
+func TestProviderErrorCode_Remediation(t *testing.T) {
+	codes := []ProviderErrorCode{
+		ProviderErrInvalidAPIKey, ProviderErrModelNotFound, ProviderErrContextLength, ProviderErrContentFiltered,
+		ProviderErrRateLimited, ProviderErrQuotaExceeded, ProviderErrUnavailable,
+	}
+	for _, code := range codes {
+		if code.Remediation() == "" {
+			t.Errorf("%s has no remediation", code)
+		}
+	}
+	if ProviderErrorCode("other").Remediation() != "" {
+		t.Error("unknown codes have no remediation")
+	}
+}
@@ -8,6 +8,7 @@ package core
 
 import (
 	"context"
+	"errors"
 	"time"
 )
 
@@ -31,6 +32,7 @@ const (
 	NodeKindWebhookTrigger NodeKind = "webhook_trigger"
 	NodeKindHuman          NodeKind = "human"
 	NodeKindConditional    NodeKind = "conditional"
+	NodeKindSubgraph       NodeKind = "subgraph"
 )
 
 // String returns the string representation of the NodeKind.
@@ -90,6 +92,24 @@ func (e NodeError) Unwrap() error {
 	return e.Cause
 }
 
+// DetailedError is implemented by errors that carry structured context.
+// The runtime copies the details into NodeError.Details and node.failed
+// event payloads.
+type DetailedError interface {
+	error
+	ErrorDetails() map[string]any
+}
+
+// ErrorDetails returns the details of the first DetailedError in err's
+// chain, or nil.
+func ErrorDetails(err error) map[string]any {
+	var detailed DetailedError
+	if errors.As(err, &detailed) {
+		return detailed.ErrorDetails()
+	}
+	return nil
+}
+
 // RouteDecision is produced by RouterNode to indicate which targets to activate.
 type RouteDecision struct {
 	Targets    []string       // node IDs to route to
@@ -126,6 +146,9 @@ type TokenUsage struct {
 	OutputTokens int     // tokens generated in output
 	TotalTokens  int     // total tokens (input + output)
 	CostUSD      float64 // cost in USD (if computable)
+	// Provider prompt caching (zero when unsupported or unused)
+	CacheReadTokens  int // input tokens served from the provider's prompt cache
+	CacheWriteTokens int // input tokens written to the provider's prompt cache
 }
 
 // Add combines two TokenUsage values.
@@ -135,6 +158,9 @@ func (u TokenUsage) Add(other TokenUsage) TokenUsage {
 		OutputTokens: u.OutputTokens + other.OutputTokens,
 		TotalTokens:  u.TotalTokens + other.TotalTokens,
 		CostUSD:      u.CostUSD + other.CostUSD,
+
+		CacheReadTokens:  u.CacheReadTokens + other.CacheReadTokens,
+		CacheWriteTokens: u.CacheWriteTokens + other.CacheWriteTokens,
 	}
 }
 
@@ -176,6 +202,7 @@ type StreamChunk struct {
 	Done        bool           // final chunk indicator
 	Accumulated string         // full text so far (optional)
 	Usage       *LLMTokenUsage // populated on final chunk
+	Region      string         // provider region that served the stream, on the final chunk (optional)
 	Error       error          // streaming error
 }
 
@@ -191,6 +218,17 @@ type LLMRequest struct {
 	Temperature  *float64       // optional: sampling temperature
 	MaxTokens    *int           // optional: maximum output tokens
 	Meta         map[string]any // trace/cost controls
+	Cache        *LLMCacheHints // optional: provider prompt caching hints
+}
+
+// LLMCacheHints mark the stable prefix of an LLMRequest as cacheable by the
+// provider. Clients apply them where the provider supports prompt caching and
+// ignore them otherwise.
+type LLMCacheHints struct {
+	System   bool          // cache the system prompt
+	Messages int           // cache the first Messages entries of LLMRequest.Messages
+	TTL      time.Duration // requested cache lifetime (0 = provider default)
+	Key      string        // optional: groups requests sharing a prefix
 }
 
 // LLMMessage is a chat message in PetalFlow format.
@@ -211,6 +249,7 @@ type LLMResponse struct {
 	Usage     LLMTokenUsage       // token consumption
 	Provider  string              // provider ID that handled the request
 	Model     string              // model that generated the response
+	Region    string              // provider region that served the request (optional)
 	ToolCalls []LLMToolCall       // tool calls requested by the model
 	Reasoning *LLMReasoningOutput // reasoning output from the model (optional)
 	Status    string              // response status (optional)
@@ -223,6 +262,9 @@ type LLMTokenUsage struct {
 	OutputTokens int
 	TotalTokens  int
 	CostUSD      float64 // optional: computed cost
+	// Provider prompt caching (zero when unsupported or unused)
+	CacheReadTokens  int // input tokens served from the provider's prompt cache
+	CacheWriteTokens int // input tokens written to the provider's prompt cache
 }
 
 // LLMToolCall represents a tool invocation requested by the model.
@@ -258,6 +300,94 @@ type PetalTool interface {
 	Invoke(ctx context.Context, args map[string]any) (map[string]any, error)
 }
 
+// ToolArtifactsKey is the tool output key reserved for files a tool
+// produced. ToolNode turns each entry into an envelope artifact and keeps
+// only a summary of it in the stored output. Entries are ToolArtifact
+// values or, from tools that answer in JSON, objects with the fields
+// filename, mime_type, type, data (base64), text, uri and meta.
+const ToolArtifactsKey = "_artifacts"
+
+// ToolArtifact is a file returned by a tool under ToolArtifactsKey. Set
+// one of Data, Text or URI.
+type ToolArtifact struct {
+	Filename string         // optional: name of the file, also used in the artifact ID
+	MimeType string         // optional: detected from Filename or Data when empty
+	Type     string         // artifact type; defaults to "file"
+	Data     []byte         // binary content
+	Text     string         // textual content
+	URI      string         // pointer to external storage
+	Meta     map[string]any // extra metadata copied onto the artifact
+}
+
+// ToolPendingKey is the tool output key reserved for asynchronous
+// invocations. A tool that accepted a job it cannot finish in time returns
+// {"_pending": {"token": "..."}} and ToolNode parks the run until the
+// result arrives through a callback to its ToolCallbackToken or, for a
+// ToolPoller, a poll for token.
+const ToolPendingKey = "_pending"
+
+// ToolPendingToken returns the correlation token of an output that marks
+// an asynchronous invocation. ok is false for a finished invocation.
+func ToolPendingToken(output map[string]any) (token string, ok bool) {
+	pending, ok := output[ToolPendingKey].(map[string]any)
+	if !ok {
+		return "", false
+	}
+	token, _ = pending["token"].(string)
+	return token, true
+}
+
+type toolCallbackKey struct{}
+
+// WithToolCallbackToken returns a context carrying the callback token the
+// daemon issued for a tool invocation. The token is a random secret: an
+// asynchronous tool hands it to whatever posts the job's result to
+// POST /api/tool-callbacks/{token}, while its own ToolPendingKey token only
+// correlates the job.
+func WithToolCallbackToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, toolCallbackKey{}, token)
+}
+
+// ToolCallbackToken returns the callback token issued for ctx's tool
+// invocation, or "" when results cannot be delivered by callback.
+func ToolCallbackToken(ctx context.Context) string {
+	token, _ := ctx.Value(toolCallbackKey{}).(string)
+	return token
+}
+
+// ToolPoller is implemented by asynchronous tools that can be asked for
+// the result of a pending invocation. Poll returns an output still
+// carrying ToolPendingKey while the job runs.
+type ToolPoller interface {
+	PetalTool
+	Poll(ctx context.Context, token string) (map[string]any, error)
+}
+
+// ArgsSchemaTool is implemented by tools that publish a JSON Schema for
+// their arguments. ToolNode validates rendered arguments against it before
+// invoking the tool. A nil schema disables validation.
+type ArgsSchemaTool interface {
+	PetalTool
+	ArgsSchema() map[string]any
+}
+
+// ToolCachePolicy says how long a tool's responses may be reused and which
+// arguments they depend on.
+type ToolCachePolicy struct {
+	TTL time.Duration
+	// VaryOn lists the arguments that make up the cache key. Empty means
+	// all of them.
+	VaryOn []string
+}
+
+// CacheableTool is implemented by tools whose responses may be cached.
+// ToolNode answers repeat invocations from its cache store while they are
+// fresh. A nil policy disables caching.
+type CacheableTool interface {
+	PetalTool
+	CachePolicy() *ToolCachePolicy
+}
+
 // FuncTool is a simple function-backed tool for PetalFlow.
 // Useful for creating tools inline without implementing a full interface.
 type FuncTool struct {
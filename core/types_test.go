@@ -2,6 +2,7 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -57,6 +58,24 @@ func TestNodeError_Unwrap(t *testing.T) {
 	}
 }
 
+type detailedError struct{ rule string }
+
+func (e detailedError) Error() string { return "violation" }
+
+func (e detailedError) ErrorDetails() map[string]any {
+	return map[string]any{"rule": e.rule}
+}
+
+func TestErrorDetails(t *testing.T) {
+	err := fmt.Errorf("node failed: %w", detailedError{rule: "size"})
+	if got := ErrorDetails(err); got["rule"] != "size" {
+		t.Errorf("ErrorDetails() = %v, want rule=size", got)
+	}
+	if got := ErrorDetails(errors.New("plain")); got != nil {
+		t.Errorf("ErrorDetails(plain) = %v, want nil", got)
+	}
+}
+
 func TestNodeError_Unwrap_Nil(t *testing.T) {
 	err := NodeError{
 		NodeID:  "test-node",
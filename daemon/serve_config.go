@@ -0,0 +1,950 @@
+package daemon
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+	"github.com/petal-labs/petalflow/policy"
+	"github.com/petal-labs/petalflow/propagate"
+)
+
+// ServeConfig is the "server" section of petalflow.yaml. It configures
+// `petalflow serve`. Values resolve in order: defaults, the config file,
+// PETALFLOW_* environment variables, then explicitly set flags.
+type ServeConfig struct {
+	Host string `yaml:"host"`
+	Port int    `yaml:"port"`
+	// CORSOrigin is the single allowed origin used when cors.allowed_origins
+	// is empty.
+	CORSOrigin      string                         `yaml:"cors_origin"`
+	CORS            ServeCORSConfig                `yaml:"cors"`
+	SecurityHeaders ServeSecurityHeadersConfig     `yaml:"security_headers"`
+	TLS             ServeTLSConfig                 `yaml:"tls"`
+	Stores          ServeStoresConfig              `yaml:"stores"`
+	Bus             ServeBusConfig                 `yaml:"bus"`
+	Limits          ServeLimitsConfig              `yaml:"limits"`
+	Providers       map[string]ServeProviderConfig `yaml:"providers,omitempty"`
+	// VerifyCredentials checks provider keys when the daemon starts and
+	// when workflows using them are saved.
+	VerifyCredentials ServeVerifyCredentialsConfig `yaml:"verify_credentials"`
+	Auth              ServeAuthConfig              `yaml:"auth"`
+	Schedules         ServeSchedulesConfig         `yaml:"schedules"`
+	Leases            ServeLeasesConfig            `yaml:"leases"`
+	Outbox            ServeOutboxConfig            `yaml:"outbox"`
+	OutboundHeaders   ServeOutboundHeadersConfig   `yaml:"outbound_headers"`
+	RunQueue          ServeRunQueueConfig          `yaml:"run_queue"`
+	Maintenance       ServeMaintenanceConfig       `yaml:"maintenance"`
+	TemplateSandbox   ServeTemplateSandboxConfig   `yaml:"template_sandbox"`
+	UploadScan        ServeUploadScanConfig        `yaml:"upload_scan"`
+	// Policy is the organization policy every saved workflow is checked
+	// against. Nil allows every workflow.
+	Policy *policy.Policy `yaml:"policy,omitempty"`
+	// Holidays are holiday calendars by name, for isHoliday in condition
+	// expressions. Dates are YYYY-MM-DD, or MM-DD for every year.
+	Holidays expr.Holidays `yaml:"holidays,omitempty"`
+	// RegionProbeInterval is how long the health and latency probe of a
+	// provider region is reused. 0 uses one minute.
+	RegionProbeInterval time.Duration `yaml:"region_probe_interval"`
+	// AllowChaos accepts fault-injection options on run requests. Only
+	// enable it on test deployments.
+	AllowChaos bool `yaml:"allow_chaos"`
+	// AllowAdmin enables the admin endpoints: backup and restore, and the
+	// runtime maintenance and logging switches. Pair it with auth tokens: a restore
+	// replaces the daemon's state.
+	AllowAdmin bool `yaml:"allow_admin"`
+	// ValidateEvents checks every run event against its payload schema
+	// and logs mismatches. Meant for development.
+	ValidateEvents bool `yaml:"validate_events"`
+}
+
+// ServeCORSConfig configures cross-origin access for browser clients such
+// as the daemon UI. Empty lists keep the server defaults.
+type ServeCORSConfig struct {
+	AllowedOrigins []string `yaml:"allowed_origins,omitempty"`
+	AllowedMethods []string `yaml:"allowed_methods,omitempty"`
+	AllowedHeaders []string `yaml:"allowed_headers,omitempty"`
+	ExposedHeaders []string `yaml:"exposed_headers,omitempty"`
+	// RouteMethods narrows the allowed methods under a path prefix.
+	RouteMethods     map[string][]string `yaml:"route_methods,omitempty"`
+	AllowCredentials bool                `yaml:"allow_credentials"`
+	MaxAge           time.Duration       `yaml:"max_age"`
+}
+
+// ServeSecurityHeadersConfig sets hardening headers on every response.
+// HSTS is only sent on HTTPS requests.
+type ServeSecurityHeadersConfig struct {
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age"`
+	HSTSIncludeSubdomains bool          `yaml:"hsts_include_subdomains"`
+	ContentTypeNosniff    bool          `yaml:"content_type_nosniff"`
+	FrameOptions          string        `yaml:"frame_options,omitempty"`
+	ReferrerPolicy        string        `yaml:"referrer_policy,omitempty"`
+	ContentSecurityPolicy string        `yaml:"content_security_policy,omitempty"`
+}
+
+// ServeTLSConfig enables HTTPS when both files are set.
+type ServeTLSConfig struct {
+	Cert string `yaml:"cert,omitempty"`
+	Key  string `yaml:"key,omitempty"`
+}
+
+// ServeStoresConfig configures the SQLite database shared by the workflow,
+// tool and event stores.
+type ServeStoresConfig struct {
+	// SQLitePath is a file path or "file:" DSN. Empty uses
+	// ~/.petalflow/petalflow.db.
+	SQLitePath string            `yaml:"sqlite_path,omitempty"`
+	Events     ServeEventsConfig `yaml:"events"`
+}
+
+// ServeEventsConfig configures event store retention and what events
+// record.
+type ServeEventsConfig struct {
+	RetentionAge   time.Duration `yaml:"retention_age"`
+	RetentionCount int           `yaml:"retention_count"`
+	// RecordOutputs adds each node's output to its node.finished event,
+	// so resumed and requeued runs restore completed nodes.
+	RecordOutputs bool `yaml:"record_outputs"`
+}
+
+// ServeBusConfig configures the event bus. Only the in-memory bus exists.
+type ServeBusConfig struct {
+	Type             string `yaml:"type"`
+	SubscriberBuffer int    `yaml:"subscriber_buffer"`
+}
+
+// ServeLimitsConfig holds request size, timeout and run memory limits.
+type ServeLimitsConfig struct {
+	ReadTimeout  time.Duration `yaml:"read_timeout"`
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	MaxBody      int64         `yaml:"max_body"`
+	MaxUpload    int64         `yaml:"max_upload"`
+	UploadQuota  int64         `yaml:"upload_quota"`
+	// RunMemorySoft and RunMemoryHard bound a run's approximate memory in
+	// bytes: past the soft limit the run emits a memory.warning event, past
+	// the hard limit it fails. 0 disables a limit.
+	RunMemorySoft int64 `yaml:"run_memory_soft"`
+	RunMemoryHard int64 `yaml:"run_memory_hard"`
+}
+
+// ServeProviderConfig is an LLM provider entry. Values may reference
+// environment variables as ${NAME}.
+type ServeProviderConfig struct {
+	APIKey  string `yaml:"api_key,omitempty"`
+	BaseURL string `yaml:"base_url,omitempty"`
+	// Regions are regional endpoints LLM requests are routed across by
+	// Routing: failover (the default) or lowest_latency.
+	Regions []ServeProviderRegionConfig `yaml:"regions,omitempty"`
+	Routing string                      `yaml:"routing,omitempty"`
+}
+
+// ServeProviderRegionConfig is a regional endpoint of a provider. APIKey
+// overrides the provider's key.
+type ServeProviderRegionConfig struct {
+	Name    string `yaml:"name"`
+	BaseURL string `yaml:"base_url"`
+	APIKey  string `yaml:"api_key,omitempty"`
+}
+
+// ProviderConfig converts the entry for hydration.
+func (p ServeProviderConfig) ProviderConfig() hydrate.ProviderConfig {
+	cfg := hydrate.ProviderConfig{APIKey: p.APIKey, BaseURL: p.BaseURL, Routing: p.Routing}
+	for _, r := range p.Regions {
+		cfg.Regions = append(cfg.Regions, hydrate.RegionConfig{Name: r.Name, BaseURL: r.BaseURL, APIKey: r.APIKey})
+	}
+	return cfg
+}
+
+// ServeVerifyCredentialsConfig configures provider credential checks: a
+// one-token completion per provider, cached for TTL.
+type ServeVerifyCredentialsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// TTL is how long a result is reused. 0 uses 15 minutes.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+// ServeAuthConfig protects the API with static bearer tokens. Health checks
+// and webhook routes, which carry their own auth, stay open.
+type ServeAuthConfig struct {
+	// Tokens authenticate anonymous callers.
+	Tokens []string `yaml:"tokens,omitempty"`
+	// Principals are named tokens. The name identifies the caller to the
+	// authorizer.
+	Principals    []ServeAuthPrincipal     `yaml:"principals,omitempty"`
+	Authorization ServeAuthorizationConfig `yaml:"authorization"`
+}
+
+// ServeAuthPrincipal is a named bearer token.
+type ServeAuthPrincipal struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+}
+
+// ServeAuthorizationConfig decides what authenticated callers may do.
+type ServeAuthorizationConfig struct {
+	// Mode is "" (no checks), rbac or webhook.
+	Mode string `yaml:"mode,omitempty"`
+	// Bindings grant roles in rbac mode.
+	Bindings []ServeRoleBinding              `yaml:"bindings,omitempty"`
+	Webhook  ServeAuthorizationWebhookConfig `yaml:"webhook"`
+}
+
+// ServeRoleBinding grants viewer, runner, editor or admin to a principal
+// name, or "*" for every caller, optionally scoped to a workspace or a
+// workflow.
+type ServeRoleBinding struct {
+	Principal string `yaml:"principal"`
+	Role      string `yaml:"role"`
+	Workspace string `yaml:"workspace,omitempty"`
+	Workflow  string `yaml:"workflow,omitempty"`
+}
+
+// ServeAuthorizationWebhookConfig points webhook mode at a central policy
+// service.
+type ServeAuthorizationWebhookConfig struct {
+	URL string `yaml:"url,omitempty"`
+	// Token is sent to the policy service as a bearer token.
+	Token string `yaml:"token,omitempty"`
+	// Timeout bounds a decision. 0 uses 5s.
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Authorization modes accepted in auth.authorization.mode.
+const (
+	AuthorizationRBAC    = "rbac"
+	AuthorizationWebhook = "webhook"
+)
+
+// AuthorizationRoles are the roles accepted in role bindings.
+var AuthorizationRoles = []string{"viewer", "runner", "editor", "admin"}
+
+// ServeSchedulesConfig configures the workflow schedule poller.
+type ServeSchedulesConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// ClockSkew is how late past the poll interval a scheduled run may
+	// start and still count as on time. Later runs were missed and follow
+	// their schedule's missed-fire policy.
+	ClockSkew time.Duration `yaml:"clock_skew"`
+}
+
+// ServeLeasesConfig configures run leases. Every run holds a lease renewed
+// by heartbeats; a janitor marks runs whose lease expired as interrupted.
+type ServeLeasesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// TTL is how long a lease outlives its last heartbeat.
+	TTL             time.Duration `yaml:"ttl"`
+	JanitorInterval time.Duration `yaml:"janitor_interval"`
+	// Requeue resumes interrupted runs from their recorded node outputs.
+	// It requires Stores.Events.RecordOutputs.
+	Requeue bool `yaml:"requeue"`
+}
+
+// ServeOutboxConfig configures outbox delivery. webhook_call nodes with
+// delivery: outbox record their requests in the store, and a worker sends
+// them with retries.
+type ServeOutboxConfig struct {
+	Enabled      bool          `yaml:"enabled"`
+	PollInterval time.Duration `yaml:"poll_interval"`
+	// RequestTimeout bounds each delivery attempt.
+	RequestTimeout time.Duration `yaml:"request_timeout"`
+	// MaxAttempts is how many attempts a delivery gets before it fails.
+	MaxAttempts int `yaml:"max_attempts"`
+	// Backoff is the wait after the first failed attempt. It doubles with
+	// each further attempt up to MaxBackoff.
+	Backoff    time.Duration `yaml:"backoff"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+}
+
+// ServeOutboundHeadersConfig adds run correlation headers (traceparent,
+// X-PetalFlow-Run-ID and X-PetalFlow-Node-ID) to the outbound HTTP requests
+// of webhook_call nodes and tools. Nodes override it with their
+// propagate_headers config.
+type ServeOutboundHeadersConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Extra headers are added to every request, such as a deployment
+	// name.
+	Extra map[string]string `yaml:"extra,omitempty"`
+}
+
+// ServeRunQueueConfig caps concurrent runs. Waiting runs queue in priority
+// lanes by trigger: interactive (manual API runs), webhook and scheduled
+// (schedules and requeued runs).
+type ServeRunQueueConfig struct {
+	// MaxConcurrent is the number of runs executing at once. 0 disables
+	// the queue.
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// Weights sets each lane's share of freed slots while several lanes
+	// are waiting. Unset lanes keep the defaults 6, 3 and 1.
+	Weights map[string]int `yaml:"weights,omitempty"`
+	// MaxWait admits a run that has waited this long ahead of
+	// higher-weight lanes. 0 uses one minute; negative disables it.
+	MaxWait time.Duration `yaml:"max_wait"`
+}
+
+// ServeMaintenanceConfig sets the state the daemon starts in. Operators
+// can change it at runtime through the admin maintenance endpoint.
+type ServeMaintenanceConfig struct {
+	// ReadOnly rejects changes and new runs while reads keep working.
+	ReadOnly bool `yaml:"read_only"`
+	// Message is returned with rejected requests.
+	Message string `yaml:"message,omitempty"`
+	// Banner is an announcement surfaced through /health and
+	// /api/maintenance.
+	Banner      string `yaml:"banner,omitempty"`
+	BannerLevel string `yaml:"banner_level,omitempty"`
+}
+
+// ServeTemplateSandboxConfig limits the Go templates of stored workflows:
+// llm_prompt prompts, transform templates, webhook_call bodies and cache
+// keys. Zero values keep the defaults.
+type ServeTemplateSandboxConfig struct {
+	// AllowedFuncs replaces the default function allowlist (the
+	// text/template builtins except call, plus the node helpers).
+	AllowedFuncs []string `yaml:"allowed_funcs,omitempty"`
+	// Timeout bounds a single render. Defaults to 1s.
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxOutputBytes caps the rendered size. Defaults to 1 MB.
+	MaxOutputBytes int `yaml:"max_output_bytes"`
+	// MaxRangeIterations caps range iterations per render. Defaults to
+	// 10000.
+	MaxRangeIterations int `yaml:"max_range_iterations"`
+	// MaxDepth caps nested {{template}} calls. Defaults to 10.
+	MaxDepth int `yaml:"max_depth"`
+}
+
+// ServeUploadScanConfig scans uploads before any node reads them. Uploads
+// that fail a check are stored quarantined. Scanning is off when neither
+// ClamAV nor a policy check is set.
+type ServeUploadScanConfig struct {
+	// ClamAV is the clamd address: host:port or unix:/path/to/clamd.sock.
+	ClamAV string `yaml:"clamav,omitempty"`
+	// Timeout bounds a ClamAV scan. 0 uses 30s.
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxBytes quarantines larger uploads. 0 disables the check.
+	MaxBytes int64 `yaml:"max_bytes"`
+	// BlockedTypes are media types to quarantine, declared or sniffed.
+	// Entries may end in "/*".
+	BlockedTypes []string `yaml:"blocked_types,omitempty"`
+	// BlockedExtensions are file name extensions to quarantine, such as
+	// ".exe".
+	BlockedExtensions []string `yaml:"blocked_extensions,omitempty"`
+	// BlockExecutables quarantines native executables and #! scripts.
+	BlockExecutables bool `yaml:"block_executables"`
+	// OnQuarantine is what runs referencing a quarantined upload do:
+	// fail (the default) or strip, which runs without the upload.
+	OnQuarantine string `yaml:"on_quarantine,omitempty"`
+}
+
+// Enabled reports whether any scanner is configured.
+func (c ServeUploadScanConfig) Enabled() bool {
+	return c.ClamAV != "" || c.MaxBytes > 0 || len(c.BlockedTypes) > 0 || len(c.BlockedExtensions) > 0 || c.BlockExecutables
+}
+
+// RunQueueClasses are the lane names accepted in run_queue.weights.
+var RunQueueClasses = []string{"interactive", "webhook", "scheduled"}
+
+// BusTypeMemory is the in-process event bus.
+const BusTypeMemory = "memory"
+
+// DefaultServeConfig returns the configuration `petalflow serve` uses
+// without a config file, environment overrides or flags.
+func DefaultServeConfig() ServeConfig {
+	return ServeConfig{
+		Host:       "0.0.0.0",
+		Port:       8080,
+		CORSOrigin: "*",
+		SecurityHeaders: ServeSecurityHeadersConfig{
+			ContentTypeNosniff: true,
+			FrameOptions:       "DENY",
+			ReferrerPolicy:     "no-referrer",
+		},
+		Bus: ServeBusConfig{Type: BusTypeMemory, SubscriberBuffer: 256},
+		Limits: ServeLimitsConfig{
+			ReadTimeout:  30 * time.Second,
+			WriteTimeout: 60 * time.Second,
+			MaxBody:      1 << 20,
+			MaxUpload:    32 << 20,
+			UploadQuota:  256 << 20,
+
+			RunMemorySoft: 128 << 20,
+			RunMemoryHard: 512 << 20,
+		},
+		Schedules: ServeSchedulesConfig{Enabled: true, PollInterval: 5 * time.Second, ClockSkew: 30 * time.Second},
+		Leases:    ServeLeasesConfig{Enabled: true, TTL: 30 * time.Second, JanitorInterval: 15 * time.Second},
+		Outbox: ServeOutboxConfig{
+			Enabled:        true,
+			PollInterval:   2 * time.Second,
+			RequestTimeout: 30 * time.Second,
+			MaxAttempts:    8,
+			Backoff:        5 * time.Second,
+			MaxBackoff:     10 * time.Minute,
+		},
+	}
+}
+
+// LoadServeConfig reads the "server" section of the config file at path
+// over cfg. Unknown keys are rejected so typos do not silently fall back
+// to defaults. A missing section leaves cfg unchanged.
+func LoadServeConfig(path string, cfg *ServeConfig) error {
+	// #nosec G304 -- path resolved from explicit local config discovery.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading server config %q: %w", path, err)
+	}
+
+	var file struct {
+		Server yaml.Node `yaml:"server"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parsing server config %q: %w", path, err)
+	}
+	if file.Server.Kind == 0 {
+		return nil
+	}
+
+	section, err := yaml.Marshal(&file.Server)
+	if err != nil {
+		return fmt.Errorf("parsing server config %q: %w", path, err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(section))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil {
+		return fmt.Errorf("parsing server config %q: server: %w", path, err)
+	}
+
+	for name, p := range cfg.Providers {
+		p.APIKey = expandEnvValue(p.APIKey)
+		p.BaseURL = expandEnvValue(p.BaseURL)
+		for i, r := range p.Regions {
+			r.APIKey = expandEnvValue(r.APIKey)
+			r.BaseURL = expandEnvValue(r.BaseURL)
+			p.Regions[i] = r
+		}
+		cfg.Providers[name] = p
+	}
+	for i, token := range cfg.Auth.Tokens {
+		cfg.Auth.Tokens[i] = expandEnvValue(token)
+	}
+	for i, p := range cfg.Auth.Principals {
+		cfg.Auth.Principals[i].Token = expandEnvValue(p.Token)
+	}
+	cfg.Auth.Authorization.Webhook.Token = expandEnvValue(cfg.Auth.Authorization.Webhook.Token)
+	return nil
+}
+
+// serveEnvOverrides maps environment variables to the fields they set.
+var serveEnvOverrides = []struct {
+	name  string
+	apply func(cfg *ServeConfig, value string) error
+}{
+	{"PETALFLOW_HOST", func(c *ServeConfig, v string) error { c.Host = v; return nil }},
+	{"PETALFLOW_PORT", func(c *ServeConfig, v string) error { return setInt(&c.Port, v) }},
+	{"PETALFLOW_CORS_ORIGIN", func(c *ServeConfig, v string) error { c.CORSOrigin = v; return nil }},
+	{"PETALFLOW_CORS_ORIGINS", func(c *ServeConfig, v string) error { c.CORS.AllowedOrigins = splitList(v); return nil }},
+	{"PETALFLOW_CORS_ALLOW_CREDENTIALS", func(c *ServeConfig, v string) error { return setBool(&c.CORS.AllowCredentials, v) }},
+	{"PETALFLOW_CORS_MAX_AGE", func(c *ServeConfig, v string) error { return setDuration(&c.CORS.MaxAge, v) }},
+	{"PETALFLOW_HSTS_MAX_AGE", func(c *ServeConfig, v string) error { return setDuration(&c.SecurityHeaders.HSTSMaxAge, v) }},
+	{"PETALFLOW_TLS_CERT", func(c *ServeConfig, v string) error { c.TLS.Cert = v; return nil }},
+	{"PETALFLOW_TLS_KEY", func(c *ServeConfig, v string) error { c.TLS.Key = v; return nil }},
+	// PETALFLOW_TOOLS_STORE_PATH is the legacy name; PETALFLOW_SQLITE_PATH wins.
+	{"PETALFLOW_TOOLS_STORE_PATH", func(c *ServeConfig, v string) error { c.Stores.SQLitePath = v; return nil }},
+	{"PETALFLOW_SQLITE_PATH", func(c *ServeConfig, v string) error { c.Stores.SQLitePath = v; return nil }},
+	{"PETALFLOW_EVENT_RETENTION_AGE", func(c *ServeConfig, v string) error { return setDuration(&c.Stores.Events.RetentionAge, v) }},
+	{"PETALFLOW_EVENT_RETENTION_COUNT", func(c *ServeConfig, v string) error { return setInt(&c.Stores.Events.RetentionCount, v) }},
+	{"PETALFLOW_RECORD_NODE_OUTPUTS", func(c *ServeConfig, v string) error { return setBool(&c.Stores.Events.RecordOutputs, v) }},
+	{"PETALFLOW_BUS_TYPE", func(c *ServeConfig, v string) error { c.Bus.Type = v; return nil }},
+	{"PETALFLOW_READ_TIMEOUT", func(c *ServeConfig, v string) error { return setDuration(&c.Limits.ReadTimeout, v) }},
+	{"PETALFLOW_WRITE_TIMEOUT", func(c *ServeConfig, v string) error { return setDuration(&c.Limits.WriteTimeout, v) }},
+	{"PETALFLOW_MAX_BODY", func(c *ServeConfig, v string) error { return setInt64(&c.Limits.MaxBody, v) }},
+	{"PETALFLOW_MAX_UPLOAD", func(c *ServeConfig, v string) error { return setInt64(&c.Limits.MaxUpload, v) }},
+	{"PETALFLOW_UPLOAD_QUOTA", func(c *ServeConfig, v string) error { return setInt64(&c.Limits.UploadQuota, v) }},
+	{"PETALFLOW_RUN_MEMORY_SOFT", func(c *ServeConfig, v string) error { return setInt64(&c.Limits.RunMemorySoft, v) }},
+	{"PETALFLOW_RUN_MEMORY_HARD", func(c *ServeConfig, v string) error { return setInt64(&c.Limits.RunMemoryHard, v) }},
+	{"PETALFLOW_VERIFY_CREDENTIALS", func(c *ServeConfig, v string) error { return setBool(&c.VerifyCredentials.Enabled, v) }},
+	{"PETALFLOW_CREDENTIAL_TTL", func(c *ServeConfig, v string) error { return setDuration(&c.VerifyCredentials.TTL, v) }},
+	{"PETALFLOW_API_TOKENS", func(c *ServeConfig, v string) error { c.Auth.Tokens = splitList(v); return nil }},
+	{"PETALFLOW_SCHEDULES_ENABLED", func(c *ServeConfig, v string) error { return setBool(&c.Schedules.Enabled, v) }},
+	{"PETALFLOW_ALLOW_CHAOS", func(c *ServeConfig, v string) error { return setBool(&c.AllowChaos, v) }},
+	{"PETALFLOW_ALLOW_ADMIN", func(c *ServeConfig, v string) error { return setBool(&c.AllowAdmin, v) }},
+	{"PETALFLOW_VALIDATE_EVENTS", func(c *ServeConfig, v string) error { return setBool(&c.ValidateEvents, v) }},
+	{"PETALFLOW_SCHEDULE_POLL", func(c *ServeConfig, v string) error { return setDuration(&c.Schedules.PollInterval, v) }},
+	{"PETALFLOW_SCHEDULE_CLOCK_SKEW", func(c *ServeConfig, v string) error { return setDuration(&c.Schedules.ClockSkew, v) }},
+	{"PETALFLOW_LEASES_ENABLED", func(c *ServeConfig, v string) error { return setBool(&c.Leases.Enabled, v) }},
+	{"PETALFLOW_LEASE_TTL", func(c *ServeConfig, v string) error { return setDuration(&c.Leases.TTL, v) }},
+	{"PETALFLOW_REQUEUE_INTERRUPTED", func(c *ServeConfig, v string) error { return setBool(&c.Leases.Requeue, v) }},
+	{"PETALFLOW_OUTBOX_ENABLED", func(c *ServeConfig, v string) error { return setBool(&c.Outbox.Enabled, v) }},
+	{"PETALFLOW_OUTBOX_MAX_ATTEMPTS", func(c *ServeConfig, v string) error { return setInt(&c.Outbox.MaxAttempts, v) }},
+	{"PETALFLOW_READ_ONLY", func(c *ServeConfig, v string) error { return setBool(&c.Maintenance.ReadOnly, v) }},
+	{"PETALFLOW_BANNER", func(c *ServeConfig, v string) error { c.Maintenance.Banner = v; return nil }},
+	{"PETALFLOW_MAX_CONCURRENT_RUNS", func(c *ServeConfig, v string) error { return setInt(&c.RunQueue.MaxConcurrent, v) }},
+	{"PETALFLOW_RUN_QUEUE_MAX_WAIT", func(c *ServeConfig, v string) error { return setDuration(&c.RunQueue.MaxWait, v) }},
+	{"PETALFLOW_CLAMAV_ADDRESS", func(c *ServeConfig, v string) error { c.UploadScan.ClamAV = v; return nil }},
+	{"PETALFLOW_UPLOAD_QUARANTINE", func(c *ServeConfig, v string) error { c.UploadScan.OnQuarantine = v; return nil }},
+}
+
+// serveEnvField is a setting reachable through the environment variable
+// derived from its path.
+type serveEnvField struct {
+	name  string
+	index []int
+}
+
+// serveEnvFields names an environment variable for every string, number,
+// boolean, duration and string list setting: PETALFLOW_ plus its path in
+// the config file, upper-cased with dots as underscores, such as
+// PETALFLOW_OUTBOX_POLL_INTERVAL for outbox.poll_interval. Maps and lists
+// of objects can only be set in the file.
+var serveEnvFields = collectServeEnvFields(reflect.TypeOf(ServeConfig{}), "PETALFLOW", nil)
+
+func collectServeEnvFields(t reflect.Type, prefix string, index []int) []serveEnvField {
+	var out []serveEnvField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		tag, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(tag)
+		idx := append(slices.Clone(index), i)
+		if f.Type.Kind() == reflect.Struct && f.Type != reflect.TypeOf(time.Duration(0)) {
+			out = append(out, collectServeEnvFields(f.Type, name, idx)...)
+			continue
+		}
+		if setServeEnvField(reflect.New(f.Type).Elem(), "") != errUnsupportedEnvField {
+			out = append(out, serveEnvField{name: name, index: idx})
+		}
+	}
+	return out
+}
+
+var errUnsupportedEnvField = errors.New("not settable from the environment")
+
+// setServeEnvField parses value into a setting. An empty value only checks
+// that the setting's type is supported.
+func setServeEnvField(v reflect.Value, value string) error {
+	switch dst := v.Addr().Interface().(type) {
+	case *string:
+		*dst = value
+	case *bool:
+		if value != "" {
+			return setBool(dst, value)
+		}
+	case *int:
+		if value != "" {
+			return setInt(dst, value)
+		}
+	case *int64:
+		if value != "" {
+			return setInt64(dst, value)
+		}
+	case *time.Duration:
+		if value != "" {
+			return setDuration(dst, value)
+		}
+	case *[]string:
+		*dst = splitList(value)
+	default:
+		return errUnsupportedEnvField
+	}
+	return nil
+}
+
+// ApplyEnv overrides cfg with the PETALFLOW_* variables that lookup finds:
+// the variable derived from each setting's path, then the shorter names in
+// serveEnvOverrides, which win. Provider keys keep their
+// PETALFLOW_PROVIDER_{NAME}_API_KEY handling in hydrate.ResolveProviders.
+func (c *ServeConfig) ApplyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	explicit := make(map[string]bool, len(serveEnvOverrides))
+	for _, o := range serveEnvOverrides {
+		explicit[o.name] = true
+	}
+	cv := reflect.ValueOf(c).Elem()
+	for _, f := range serveEnvFields {
+		value, ok := lookup(f.name)
+		if !ok || strings.TrimSpace(value) == "" || explicit[f.name] {
+			continue
+		}
+		if err := setServeEnvField(cv.FieldByIndex(f.index), strings.TrimSpace(value)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.name, err))
+		}
+	}
+	for _, o := range serveEnvOverrides {
+		value, ok := lookup(o.name)
+		if !ok || strings.TrimSpace(value) == "" {
+			continue
+		}
+		if err := o.apply(c, strings.TrimSpace(value)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", o.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid setting, each prefixed with its path in
+// the config file.
+func (c *ServeConfig) Validate() error {
+	var errs []error
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("server.%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if strings.TrimSpace(c.Host) == "" {
+		fail("host", "must not be empty")
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		fail("port", "must be between 1 and 65535, got %d", c.Port)
+	}
+	c.validateCORS(fail)
+	if c.SecurityHeaders.HSTSMaxAge < 0 {
+		fail("security_headers.hsts_max_age", "must not be negative")
+	}
+	switch strings.ToUpper(c.SecurityHeaders.FrameOptions) {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		fail("security_headers.frame_options", "must be DENY or SAMEORIGIN, got %q", c.SecurityHeaders.FrameOptions)
+	}
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		fail("tls", "cert and key must be set together")
+	}
+	if c.Stores.Events.RetentionAge < 0 {
+		fail("stores.events.retention_age", "must not be negative")
+	}
+	if c.Stores.Events.RetentionCount < 0 {
+		fail("stores.events.retention_count", "must not be negative")
+	}
+	if c.Bus.Type != BusTypeMemory {
+		fail("bus.type", "unsupported bus %q (supported: %s)", c.Bus.Type, BusTypeMemory)
+	}
+	if c.Bus.SubscriberBuffer < 0 {
+		fail("bus.subscriber_buffer", "must not be negative")
+	}
+	if c.Limits.ReadTimeout < 0 {
+		fail("limits.read_timeout", "must not be negative")
+	}
+	if c.Limits.WriteTimeout < 0 {
+		fail("limits.write_timeout", "must not be negative")
+	}
+	if c.Limits.MaxBody <= 0 {
+		fail("limits.max_body", "must be positive")
+	}
+	if c.Limits.MaxUpload <= 0 {
+		fail("limits.max_upload", "must be positive")
+	}
+	if c.Limits.RunMemorySoft < 0 {
+		fail("limits.run_memory_soft", "must not be negative")
+	}
+	if c.Limits.RunMemoryHard < 0 {
+		fail("limits.run_memory_hard", "must not be negative")
+	} else if c.Limits.RunMemoryHard > 0 && c.Limits.RunMemorySoft > c.Limits.RunMemoryHard {
+		fail("limits.run_memory_soft", "must not exceed run_memory_hard")
+	}
+	for name, p := range c.Providers {
+		if strings.TrimSpace(name) == "" {
+			fail("providers", "provider names must not be empty")
+		}
+		if p.APIKey == "" && p.BaseURL == "" && len(p.Regions) == 0 {
+			fail("providers."+name, "needs api_key, base_url or regions")
+		}
+		if err := p.ProviderConfig().ValidateRegions(); err != nil {
+			fail("providers."+name, "%v", err)
+		}
+	}
+	if c.RegionProbeInterval < 0 {
+		fail("region_probe_interval", "must not be negative")
+	}
+	if c.VerifyCredentials.TTL < 0 {
+		fail("verify_credentials.ttl", "must not be negative")
+	}
+	for i, token := range c.Auth.Tokens {
+		if strings.TrimSpace(token) == "" {
+			fail(fmt.Sprintf("auth.tokens[%d]", i), "must not be empty")
+		}
+	}
+	c.validateAuthorization(fail)
+	if c.Schedules.Enabled && c.Schedules.PollInterval <= 0 {
+		fail("schedules.poll_interval", "must be positive when schedules are enabled")
+	}
+	if c.Schedules.ClockSkew < 0 {
+		fail("schedules.clock_skew", "must not be negative")
+	}
+	if c.Leases.Enabled {
+		if c.Leases.TTL < time.Second {
+			fail("leases.ttl", "must be at least 1s, got %s", c.Leases.TTL)
+		}
+		if c.Leases.JanitorInterval <= 0 {
+			fail("leases.janitor_interval", "must be positive when leases are enabled")
+		}
+	}
+	if c.Leases.Requeue && !c.Stores.Events.RecordOutputs {
+		fail("leases.requeue", "requires stores.events.record_outputs, or requeued runs could not restore their completed nodes")
+	}
+	if err := (propagate.Config{Extra: c.OutboundHeaders.Extra}).Validate(); err != nil {
+		fail("outbound_headers", "%v", err)
+	}
+	if c.Outbox.Enabled {
+		if c.Outbox.PollInterval <= 0 {
+			fail("outbox.poll_interval", "must be positive when the outbox is enabled")
+		}
+		if c.Outbox.RequestTimeout <= 0 {
+			fail("outbox.request_timeout", "must be positive when the outbox is enabled")
+		}
+		if c.Outbox.MaxAttempts < 1 {
+			fail("outbox.max_attempts", "must be at least 1, got %d", c.Outbox.MaxAttempts)
+		}
+		if c.Outbox.Backoff <= 0 || c.Outbox.MaxBackoff < c.Outbox.Backoff {
+			fail("outbox.backoff", "must be positive and at most max_backoff")
+		}
+	}
+	switch c.Maintenance.BannerLevel {
+	case "", "info", "warning", "critical":
+	default:
+		fail("maintenance.banner_level", "must be info, warning or critical, got %q", c.Maintenance.BannerLevel)
+	}
+	if c.RunQueue.MaxConcurrent < 0 {
+		fail("run_queue.max_concurrent", "must not be negative")
+	}
+	if c.TemplateSandbox.Timeout < 0 {
+		fail("template_sandbox.timeout", "must not be negative")
+	}
+	if c.TemplateSandbox.MaxOutputBytes < 0 {
+		fail("template_sandbox.max_output_bytes", "must not be negative")
+	}
+	if c.TemplateSandbox.MaxRangeIterations < 0 {
+		fail("template_sandbox.max_range_iterations", "must not be negative")
+	}
+	if c.TemplateSandbox.MaxDepth < 0 {
+		fail("template_sandbox.max_depth", "must not be negative")
+	}
+	if c.UploadScan.Timeout < 0 {
+		fail("upload_scan.timeout", "must not be negative")
+	}
+	if c.UploadScan.MaxBytes < 0 {
+		fail("upload_scan.max_bytes", "must not be negative")
+	}
+	switch c.UploadScan.OnQuarantine {
+	case "", "fail", "strip":
+	default:
+		fail("upload_scan.on_quarantine", "must be fail or strip, got %q", c.UploadScan.OnQuarantine)
+	}
+	if c.Policy != nil {
+		if err := c.Policy.Validate(); err != nil {
+			fail("policy", "%v", err)
+		}
+	}
+	if err := c.Holidays.Validate(); err != nil {
+		fail("holidays", "%v", err)
+	}
+	for name, weight := range c.RunQueue.Weights {
+		if !slices.Contains(RunQueueClasses, name) {
+			fail("run_queue.weights."+name, "unknown lane (use %s)", strings.Join(RunQueueClasses, ", "))
+		} else if weight <= 0 {
+			fail("run_queue.weights."+name, "must be positive")
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *ServeConfig) validateAuthorization(fail func(path, format string, args ...any)) {
+	names := make(map[string]bool, len(c.Auth.Principals))
+	for i, p := range c.Auth.Principals {
+		path := fmt.Sprintf("auth.principals[%d]", i)
+		switch {
+		case strings.TrimSpace(p.Name) == "" || p.Name == "*":
+			fail(path+".name", "must be a non-empty name other than \"*\"")
+		case names[p.Name]:
+			fail(path+".name", "duplicate principal %q", p.Name)
+		}
+		names[p.Name] = true
+		if strings.TrimSpace(p.Token) == "" {
+			fail(path+".token", "must not be empty")
+		}
+	}
+
+	authz := c.Auth.Authorization
+	switch authz.Mode {
+	case "":
+	case AuthorizationRBAC:
+		if len(authz.Bindings) == 0 {
+			fail("auth.authorization.bindings", "must grant at least one role in rbac mode")
+		}
+	case AuthorizationWebhook:
+		if !strings.HasPrefix(authz.Webhook.URL, "http://") && !strings.HasPrefix(authz.Webhook.URL, "https://") {
+			fail("auth.authorization.webhook.url", "must be an http or https URL in webhook mode")
+		}
+	default:
+		fail("auth.authorization.mode", "must be rbac or webhook, got %q", authz.Mode)
+	}
+	for i, b := range authz.Bindings {
+		path := fmt.Sprintf("auth.authorization.bindings[%d]", i)
+		if strings.TrimSpace(b.Principal) == "" {
+			fail(path+".principal", "must not be empty")
+		}
+		if !slices.Contains(AuthorizationRoles, b.Role) {
+			fail(path+".role", "must be %s, got %q", strings.Join(AuthorizationRoles, ", "), b.Role)
+		}
+	}
+	if authz.Webhook.Timeout < 0 {
+		fail("auth.authorization.webhook.timeout", "must not be negative")
+	}
+	if authz.Mode != "" && len(c.Auth.Tokens) == 0 && len(c.Auth.Principals) == 0 {
+		fail("auth.authorization.mode", "needs auth tokens or principals to identify callers")
+	}
+}
+
+// CORSOrigins returns the allowed origins: cors.allowed_origins when set,
+// otherwise cors_origin.
+func (c *ServeConfig) CORSOrigins() []string {
+	if len(c.CORS.AllowedOrigins) > 0 {
+		return c.CORS.AllowedOrigins
+	}
+	if strings.TrimSpace(c.CORSOrigin) == "" {
+		return nil
+	}
+	return []string{strings.TrimSpace(c.CORSOrigin)}
+}
+
+func (c *ServeConfig) validateCORS(fail func(path, format string, args ...any)) {
+	origins := c.CORSOrigins()
+	for i, origin := range c.CORS.AllowedOrigins {
+		if origin != "*" && !strings.Contains(origin, "://") {
+			fail(fmt.Sprintf("cors.allowed_origins[%d]", i), "must be \"*\" or scheme://host, got %q", origin)
+		}
+	}
+	if c.CORS.AllowCredentials {
+		for _, origin := range origins {
+			if origin == "*" {
+				fail("cors.allow_credentials", "cannot be combined with a wildcard origin; list the allowed origins")
+				break
+			}
+		}
+	}
+	if c.CORS.MaxAge < 0 {
+		fail("cors.max_age", "must not be negative")
+	}
+	for i, method := range c.CORS.AllowedMethods {
+		if !validHTTPMethod(method) {
+			fail(fmt.Sprintf("cors.allowed_methods[%d]", i), "invalid method %q", method)
+		}
+	}
+	for prefix, methods := range c.CORS.RouteMethods {
+		if !strings.HasPrefix(prefix, "/") {
+			fail("cors.route_methods."+prefix, "path prefix must start with /")
+		}
+		if len(methods) == 0 {
+			fail("cors.route_methods."+prefix, "must list at least one method")
+		}
+		for _, method := range methods {
+			if !validHTTPMethod(method) {
+				fail("cors.route_methods."+prefix, "invalid method %q", method)
+			}
+		}
+	}
+}
+
+func validHTTPMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// Redacted returns a copy safe to print: provider keys and auth tokens are
+// replaced with a placeholder.
+func (c ServeConfig) Redacted() ServeConfig {
+	const hidden = "<redacted>"
+	out := c
+	if len(c.Providers) > 0 {
+		out.Providers = make(map[string]ServeProviderConfig, len(c.Providers))
+		for name, p := range c.Providers {
+			if p.APIKey != "" {
+				p.APIKey = hidden
+			}
+			if len(p.Regions) > 0 {
+				regions := make([]ServeProviderRegionConfig, len(p.Regions))
+				for i, r := range p.Regions {
+					if r.APIKey != "" {
+						r.APIKey = hidden
+					}
+					regions[i] = r
+				}
+				p.Regions = regions
+			}
+			out.Providers[name] = p
+		}
+	}
+	if len(c.Auth.Tokens) > 0 {
+		out.Auth.Tokens = make([]string, len(c.Auth.Tokens))
+		for i := range out.Auth.Tokens {
+			out.Auth.Tokens[i] = hidden
+		}
+	}
+	if len(c.Auth.Principals) > 0 {
+		out.Auth.Principals = make([]ServeAuthPrincipal, len(c.Auth.Principals))
+		for i, p := range c.Auth.Principals {
+			out.Auth.Principals[i] = ServeAuthPrincipal{Name: p.Name, Token: hidden}
+		}
+	}
+	if c.Auth.Authorization.Webhook.Token != "" {
+		out.Auth.Authorization.Webhook.Token = hidden
+	}
+	return out
+}
+
+func setInt(dst *int, value string) error {
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", value)
+	}
+	*dst = n
+	return nil
+}
+
+func setInt64(dst *int64, value string) error {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid integer %q", value)
+	}
+	*dst = n
+	return nil
+}
+
+func setBool(dst *bool, value string) error {
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %q", value)
+	}
+	*dst = b
+	return nil
+}
+
+func setDuration(dst *time.Duration, value string) error {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", value)
+	}
+	*dst = d
+	return nil
+}
+
+func splitList(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
@@ -0,0 +1,317 @@
+package daemon
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/policy"
+)
+
+func writeServeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "petalflow.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadServeConfig(t *testing.T) {
+	t.Setenv("TEST_ANTHROPIC_KEY", "sk-test")
+	path := writeServeConfig(t, `
+tools: {}
+server:
+  port: 9090
+  stores:
+    sqlite_path: /var/lib/petalflow.db
+    events:
+      retention_age: 72h
+  limits:
+    read_timeout: 10s
+    max_body: 2048
+  providers:
+    anthropic:
+      api_key: ${TEST_ANTHROPIC_KEY}
+  auth:
+    tokens: [secret-token]
+`)
+
+	cfg := DefaultServeConfig()
+	if err := LoadServeConfig(path, &cfg); err != nil {
+		t.Fatalf("LoadServeConfig: %v", err)
+	}
+	if cfg.Port != 9090 || cfg.Host != "0.0.0.0" {
+		t.Errorf("host/port = %s:%d, want file port over default host", cfg.Host, cfg.Port)
+	}
+	if cfg.Stores.SQLitePath != "/var/lib/petalflow.db" || cfg.Stores.Events.RetentionAge != 72*time.Hour {
+		t.Errorf("stores = %+v", cfg.Stores)
+	}
+	if cfg.Limits.ReadTimeout != 10*time.Second || cfg.Limits.MaxBody != 2048 || cfg.Limits.WriteTimeout != 60*time.Second {
+		t.Errorf("limits = %+v", cfg.Limits)
+	}
+	if cfg.Providers["anthropic"].APIKey != "sk-test" {
+		t.Errorf("provider key not expanded: %+v", cfg.Providers)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+
+	redacted := cfg.Redacted()
+	if redacted.Providers["anthropic"].APIKey == "sk-test" || redacted.Auth.Tokens[0] == "secret-token" {
+		t.Errorf("secrets not redacted: %+v", redacted)
+	}
+	if cfg.Auth.Tokens[0] != "secret-token" {
+		t.Error("Redacted must not modify the original")
+	}
+}
+
+func TestLoadServeConfig_RejectsUnknownKeys(t *testing.T) {
+	path := writeServeConfig(t, "server:\n  prot: 9090\n")
+	cfg := DefaultServeConfig()
+	err := LoadServeConfig(path, &cfg)
+	if err == nil || !strings.Contains(err.Error(), "prot") {
+		t.Fatalf("expected unknown field error, got %v", err)
+	}
+}
+
+func TestLoadServeConfig_ProviderRegions(t *testing.T) {
+	t.Setenv("TEST_EU_KEY", "sk-eu")
+	path := writeServeConfig(t, `
+server:
+  providers:
+    openai:
+      api_key: sk-test
+      routing: lowest_latency
+      regions:
+        - name: us
+          base_url: https://us.example.com/v1
+        - name: eu
+          base_url: https://eu.example.com/v1
+          api_key: ${TEST_EU_KEY}
+`)
+	cfg := DefaultServeConfig()
+	if err := LoadServeConfig(path, &cfg); err != nil {
+		t.Fatalf("LoadServeConfig: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	provider := cfg.Providers["openai"].ProviderConfig()
+	if provider.Routing != "lowest_latency" || len(provider.Regions) != 2 || provider.Regions[1].APIKey != "sk-eu" {
+		t.Errorf("provider = %+v", provider)
+	}
+	if redacted := cfg.Redacted(); redacted.Providers["openai"].Regions[1].APIKey == "sk-eu" {
+		t.Error("region key not redacted")
+	}
+	if cfg.Providers["openai"].Regions[1].APIKey != "sk-eu" {
+		t.Error("Redacted must not modify the original")
+	}
+
+	p := cfg.Providers["openai"]
+	p.Routing = "random"
+	cfg.Providers["openai"] = p
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "server.providers.openai") {
+		t.Errorf("Validate = %v, want a routing error", err)
+	}
+}
+
+func TestServeConfig_ApplyEnv(t *testing.T) {
+	env := map[string]string{
+		"PETALFLOW_PORT":                "7000",
+		"PETALFLOW_TOOLS_STORE_PATH":    "/legacy.db",
+		"PETALFLOW_SQLITE_PATH":         "/current.db",
+		"PETALFLOW_API_TOKENS":          "a, b",
+		"PETALFLOW_SCHEDULE_POLL":       "1m",
+		"PETALFLOW_MAX_CONCURRENT_RUNS": "8",
+		"PETALFLOW_READ_ONLY":           "true",
+		"PETALFLOW_VERIFY_CREDENTIALS":  "true",
+		"PETALFLOW_CREDENTIAL_TTL":      "5m",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	cfg := DefaultServeConfig()
+	if err := cfg.ApplyEnv(lookup); err != nil {
+		t.Fatalf("ApplyEnv: %v", err)
+	}
+	if cfg.Port != 7000 || cfg.Stores.SQLitePath != "/current.db" || cfg.Schedules.PollInterval != time.Minute {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if len(cfg.Auth.Tokens) != 2 || cfg.Auth.Tokens[1] != "b" {
+		t.Errorf("tokens = %v", cfg.Auth.Tokens)
+	}
+	if !cfg.Maintenance.ReadOnly {
+		t.Error("maintenance.read_only not set from PETALFLOW_READ_ONLY")
+	}
+	if cfg.RunQueue.MaxConcurrent != 8 {
+		t.Errorf("run_queue.max_concurrent = %d, want 8", cfg.RunQueue.MaxConcurrent)
+	}
+	if !cfg.VerifyCredentials.Enabled || cfg.VerifyCredentials.TTL != 5*time.Minute {
+		t.Errorf("verify_credentials = %+v, want enabled with 5m ttl", cfg.VerifyCredentials)
+	}
+
+	env = map[string]string{"PETALFLOW_PORT": "http", "PETALFLOW_READ_TIMEOUT": "soon"}
+	err := cfg.ApplyEnv(lookup)
+	if err == nil || !strings.Contains(err.Error(), "PETALFLOW_PORT") || !strings.Contains(err.Error(), "PETALFLOW_READ_TIMEOUT") {
+		t.Fatalf("expected both env errors, got %v", err)
+	}
+}
+
+func TestServeConfig_ApplyEnvDerivedNames(t *testing.T) {
+	env := map[string]string{
+		"PETALFLOW_OUTBOX_POLL_INTERVAL":              "3s",
+		"PETALFLOW_SECURITY_HEADERS_FRAME_OPTIONS":    "SAMEORIGIN",
+		"PETALFLOW_UPLOAD_SCAN_BLOCKED_EXTENSIONS":    ".exe, .js",
+		"PETALFLOW_AUTH_AUTHORIZATION_WEBHOOK_URL":    "https://authz.internal",
+		"PETALFLOW_TEMPLATE_SANDBOX_MAX_OUTPUT_BYTES": "2048",
+		"PETALFLOW_BUS_SUBSCRIBER_BUFFER":             "64",
+		"PETALFLOW_MAINTENANCE_READ_ONLY":             "true",
+		"PETALFLOW_READ_ONLY":                         "false",
+	}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	cfg := DefaultServeConfig()
+	if err := cfg.ApplyEnv(lookup); err != nil {
+		t.Fatalf("ApplyEnv: %v", err)
+	}
+	if cfg.Outbox.PollInterval != 3*time.Second || cfg.SecurityHeaders.FrameOptions != "SAMEORIGIN" {
+		t.Errorf("outbox = %+v, security_headers = %+v", cfg.Outbox, cfg.SecurityHeaders)
+	}
+	if len(cfg.UploadScan.BlockedExtensions) != 2 || cfg.UploadScan.BlockedExtensions[1] != ".js" {
+		t.Errorf("blocked_extensions = %v", cfg.UploadScan.BlockedExtensions)
+	}
+	if cfg.Auth.Authorization.Webhook.URL != "https://authz.internal" || cfg.TemplateSandbox.MaxOutputBytes != 2048 || cfg.Bus.SubscriberBuffer != 64 {
+		t.Errorf("cfg = %+v", cfg)
+	}
+	if cfg.Maintenance.ReadOnly {
+		t.Error("PETALFLOW_READ_ONLY should win over PETALFLOW_MAINTENANCE_READ_ONLY")
+	}
+
+	env = map[string]string{"PETALFLOW_LEASES_JANITOR_INTERVAL": "often"}
+	if err := cfg.ApplyEnv(lookup); err == nil || !strings.Contains(err.Error(), "PETALFLOW_LEASES_JANITOR_INTERVAL") {
+		t.Fatalf("expected derived env error, got %v", err)
+	}
+}
+
+func TestServeConfig_Validate(t *testing.T) {
+	cfg := DefaultServeConfig()
+	cfg.Port = 0
+	cfg.TLS.Cert = "cert.pem"
+	cfg.Bus.Type = "kafka"
+	cfg.Limits.MaxBody = 0
+	cfg.Providers = map[string]ServeProviderConfig{"openai": {}}
+	cfg.Leases.TTL = 100 * time.Millisecond
+	cfg.RunQueue.Weights = map[string]int{"batch": 1, "webhook": 0}
+	cfg.Maintenance.BannerLevel = "loud"
+	cfg.TemplateSandbox.MaxOutputBytes = -1
+	cfg.UploadScan.OnQuarantine = "delete"
+	cfg.Policy = &policy.Policy{Severity: map[string]string{policy.RuleEgress: "loud"}}
+	cfg.Holidays = map[string][]string{"default": {"25 Dec"}}
+	cfg.Outbox.MaxAttempts = 0
+	cfg.Limits.RunMemoryHard = 1 << 20
+	cfg.Schedules.ClockSkew = -time.Second
+	cfg.OutboundHeaders.Extra = map[string]string{"X Team": "geo"}
+	cfg.Leases.Requeue = true
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, path := range []string{"server.port", "server.tls", "server.bus.type", "server.limits.max_body", "server.providers.openai", "server.leases.ttl", "server.run_queue.weights.batch", "server.run_queue.weights.webhook", "server.maintenance.banner_level", "server.template_sandbox.max_output_bytes", "server.upload_scan.on_quarantine", "server.policy", "server.holidays", "server.outbox.max_attempts", "server.limits.run_memory_soft", "server.schedules.clock_skew", "server.outbound_headers", "server.leases.requeue"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("missing %s in %v", path, err)
+		}
+	}
+}
+
+func TestServeConfig_ValidateCORS(t *testing.T) {
+	cfg := DefaultServeConfig()
+	cfg.CORS.AllowCredentials = true
+	cfg.CORS.AllowedMethods = []string{"FETCH"}
+	cfg.CORS.RouteMethods = map[string][]string{"api/runs": {"GET"}}
+	cfg.SecurityHeaders.FrameOptions = "ALLOW-FROM x"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, path := range []string{"server.cors.allow_credentials", "server.cors.allowed_methods[0]", "server.cors.route_methods.api/runs", "server.security_headers.frame_options"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("missing %s in %v", path, err)
+		}
+	}
+
+	cfg = DefaultServeConfig()
+	cfg.CORS.AllowedOrigins = []string{"https://ui.example.com"}
+	cfg.CORS.AllowCredentials = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if got := cfg.CORSOrigins(); len(got) != 1 || got[0] != "https://ui.example.com" {
+		t.Errorf("CORSOrigins = %v", got)
+	}
+}
+
+func TestServeConfig_ValidateAuthorization(t *testing.T) {
+	t.Setenv("TEST_BOT_TOKEN", "bot-secret")
+	path := writeServeConfig(t, `
+server:
+  auth:
+    principals:
+      - name: ci-bot
+        token: ${TEST_BOT_TOKEN}
+    authorization:
+      mode: rbac
+      bindings:
+        - principal: ci-bot
+          role: runner
+          workflow: nightly
+        - principal: "*"
+          role: viewer
+`)
+	cfg := DefaultServeConfig()
+	if err := LoadServeConfig(path, &cfg); err != nil {
+		t.Fatalf("LoadServeConfig: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if cfg.Auth.Principals[0].Token != "bot-secret" {
+		t.Errorf("principal token not expanded: %+v", cfg.Auth.Principals)
+	}
+	if redacted := cfg.Redacted(); redacted.Auth.Principals[0].Token == "bot-secret" || redacted.Auth.Principals[0].Name != "ci-bot" {
+		t.Errorf("principals not redacted: %+v", redacted.Auth.Principals)
+	}
+
+	cfg = DefaultServeConfig()
+	cfg.Auth.Principals = []ServeAuthPrincipal{{Name: "*", Token: "x"}}
+	cfg.Auth.Authorization = ServeAuthorizationConfig{
+		Mode:     AuthorizationWebhook,
+		Bindings: []ServeRoleBinding{{Principal: "ci-bot", Role: "owner"}},
+		Webhook:  ServeAuthorizationWebhookConfig{URL: "policy.internal"},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, path := range []string{"server.auth.principals[0].name", "server.auth.authorization.webhook.url", "server.auth.authorization.bindings[0].role"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("missing %s in %v", path, err)
+		}
+	}
+
+	cfg = DefaultServeConfig()
+	cfg.Auth.Authorization.Mode = AuthorizationRBAC
+	cfg.Auth.Authorization.Bindings = []ServeRoleBinding{{Principal: "*", Role: "viewer"}}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "needs auth tokens") {
+		t.Errorf("expected missing tokens error, got %v", err)
+	}
+}
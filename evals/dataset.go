@@ -0,0 +1,119 @@
+// Package evals runs workflows against datasets of input/expected examples
+// and scores the outputs, producing a report that can gate prompt changes
+// in CI.
+package evals
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultOutput is the envelope variable scored when neither the dataset
+// nor a scorer names one.
+const DefaultOutput = "output"
+
+var datasetNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Dataset is a set of examples together with the scorers that grade a
+// workflow's output for each of them.
+type Dataset struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Output is the envelope variable path scored by default.
+	Output  string         `json:"output,omitempty"`
+	Scorers []ScorerConfig `json:"scorers,omitempty"`
+	// PassThreshold is the fraction of examples that must pass for the
+	// report to pass. Defaults to 1.
+	PassThreshold *float64  `json:"pass_threshold,omitempty"`
+	Examples      []Example `json:"examples"`
+	CreatedAt     time.Time `json:"created_at,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at,omitempty"`
+}
+
+// Example is one input and the output expected for it.
+type Example struct {
+	ID       string         `json:"id,omitempty"`
+	Input    map[string]any `json:"input"`
+	Expected any            `json:"expected,omitempty"`
+}
+
+// ParseDataset decodes a dataset from YAML or JSON.
+func ParseDataset(data []byte) (*Dataset, error) {
+	var raw any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	// Round-trip through JSON so json tags apply and numbers decode the
+	// same way they do in run inputs.
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	var ds Dataset
+	if err := json.Unmarshal(encoded, &ds); err != nil {
+		return nil, err
+	}
+	return &ds, nil
+}
+
+// Normalize numbers unnamed examples and adds the exact scorer when the
+// dataset declares none.
+func (d *Dataset) Normalize() {
+	for i := range d.Examples {
+		if d.Examples[i].ID == "" {
+			d.Examples[i].ID = strconv.Itoa(i + 1)
+		}
+	}
+	if len(d.Scorers) == 0 {
+		d.Scorers = []ScorerConfig{{Type: ScorerExact}}
+	}
+	for i := range d.Scorers {
+		if d.Scorers[i].Name == "" {
+			d.Scorers[i].Name = d.Scorers[i].Type
+		}
+	}
+}
+
+// Validate checks a normalized dataset.
+func (d *Dataset) Validate() error {
+	if !datasetNamePattern.MatchString(d.Name) {
+		return fmt.Errorf("dataset name %q must start with a letter or digit and contain only letters, digits, '_', '.' and '-'", d.Name)
+	}
+	if len(d.Examples) == 0 {
+		return fmt.Errorf("dataset %q has no examples", d.Name)
+	}
+	if d.PassThreshold != nil && (*d.PassThreshold < 0 || *d.PassThreshold > 1) {
+		return fmt.Errorf("dataset %q: pass_threshold must be between 0 and 1", d.Name)
+	}
+	ids := make(map[string]bool, len(d.Examples))
+	for _, ex := range d.Examples {
+		if ids[ex.ID] {
+			return fmt.Errorf("dataset %q: duplicate example id %q", d.Name, ex.ID)
+		}
+		ids[ex.ID] = true
+	}
+	names := make(map[string]bool, len(d.Scorers))
+	for _, sc := range d.Scorers {
+		if names[sc.Name] {
+			return fmt.Errorf("dataset %q: duplicate scorer %q", d.Name, sc.Name)
+		}
+		names[sc.Name] = true
+		if err := sc.Validate(); err != nil {
+			return fmt.Errorf("dataset %q: %w", d.Name, err)
+		}
+	}
+	return nil
+}
+
+// passThreshold returns the effective example pass fraction.
+func (d *Dataset) passThreshold() float64 {
+	if d.PassThreshold == nil {
+		return 1
+	}
+	return *d.PassThreshold
+}
@@ -0,0 +1,62 @@
+package evals
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDataset_YAML(t *testing.T) {
+	ds, err := ParseDataset([]byte(`
+name: triage
+output: category
+pass_threshold: 0.5
+scorers:
+  - type: contains
+    ignore_case: true
+examples:
+  - input: {ticket: "refund please", priority: 2}
+    expected: billing
+  - id: outage
+    input: {ticket: "site down"}
+    expected: [incident, urgent]
+`))
+	if err != nil {
+		t.Fatalf("ParseDataset: %v", err)
+	}
+	ds.Normalize()
+	if err := ds.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if ds.Examples[0].ID != "1" || ds.Examples[1].ID != "outage" {
+		t.Fatalf("example ids = %q, %q", ds.Examples[0].ID, ds.Examples[1].ID)
+	}
+	if ds.Examples[0].Input["priority"] != float64(2) {
+		t.Fatalf("priority = %#v, want float64 2", ds.Examples[0].Input["priority"])
+	}
+	if ds.Scorers[0].Name != ScorerContains || ds.passThreshold() != 0.5 {
+		t.Fatalf("scorers = %+v, threshold = %v", ds.Scorers, ds.passThreshold())
+	}
+}
+
+func TestDatasetValidate(t *testing.T) {
+	tooHigh := 1.5
+	tests := map[string]struct {
+		ds      Dataset
+		wantErr string
+	}{
+		"name":       {Dataset{Name: "bad name", Examples: []Example{{}}}, "dataset name"},
+		"empty":      {Dataset{Name: "ds"}, "no examples"},
+		"duplicate":  {Dataset{Name: "ds", Examples: []Example{{ID: "a"}, {ID: "a"}}}, "duplicate example id"},
+		"threshold":  {Dataset{Name: "ds", PassThreshold: &tooHigh, Examples: []Example{{}}}, "pass_threshold"},
+		"scorer":     {Dataset{Name: "ds", Scorers: []ScorerConfig{{Type: "fuzzy"}}, Examples: []Example{{}}}, "unknown type"},
+		"expression": {Dataset{Name: "ds", Scorers: []ScorerConfig{{Type: ScorerExpression, Expression: "output =="}}, Examples: []Example{{}}}, "scorer"},
+		"judge":      {Dataset{Name: "ds", Scorers: []ScorerConfig{{Type: ScorerJudge}}, Examples: []Example{{}}}, "provider and model"},
+	}
+	for name, tt := range tests {
+		tt.ds.Normalize()
+		err := tt.ds.Validate()
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want %q", name, err, tt.wantErr)
+		}
+	}
+}
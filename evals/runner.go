@@ -0,0 +1,204 @@
+package evals
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// Target runs the workflow under evaluation for one example input and
+// returns its final envelope.
+type Target func(ctx context.Context, input map[string]any) (*core.Envelope, error)
+
+// Runner evaluates a Target against datasets.
+type Runner struct {
+	Target Target
+	// Judges supplies model clients for judge scorers.
+	Judges JudgeClients
+	// Concurrency is how many examples run at once. Defaults to 1.
+	Concurrency int
+	// Now is used for report timestamps. Defaults to time.Now.
+	Now func() time.Time
+}
+
+// Report is the scored result of evaluating a dataset.
+type Report struct {
+	Dataset    string          `json:"dataset"`
+	Workflow   string          `json:"workflow,omitempty"`
+	StartedAt  time.Time       `json:"started_at"`
+	DurationMs int64           `json:"duration_ms"`
+	Examples   []ExampleResult `json:"examples"`
+	Scorers    []ScorerSummary `json:"scorers"`
+	// Score is the mean weighted score over all examples.
+	Score float64 `json:"score"`
+	// PassRate is the fraction of examples that passed every scorer.
+	PassRate      float64 `json:"pass_rate"`
+	PassThreshold float64 `json:"pass_threshold"`
+	Passed        bool    `json:"passed"`
+}
+
+// ExampleResult is the outcome of one example.
+type ExampleResult struct {
+	ID         string  `json:"id"`
+	Passed     bool    `json:"passed"`
+	Score      float64 `json:"score"`
+	Scores     []Score `json:"scores,omitempty"`
+	Output     any     `json:"output,omitempty"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs int64   `json:"duration_ms"`
+}
+
+// ScorerSummary aggregates one scorer over all examples.
+type ScorerSummary struct {
+	Name      string  `json:"name"`
+	Type      string  `json:"type"`
+	Mean      float64 `json:"mean"`
+	PassRate  float64 `json:"pass_rate"`
+	Threshold float64 `json:"threshold"`
+}
+
+// Run evaluates every example of ds. The dataset is normalized and
+// validated first. A failing example or scorer is recorded in the report;
+// Run only returns an error for an invalid dataset or a cancelled context.
+func (r Runner) Run(ctx context.Context, ds *Dataset) (*Report, error) {
+	if r.Target == nil {
+		return nil, errors.New("eval runner has no target")
+	}
+	ds.Normalize()
+	if err := ds.Validate(); err != nil {
+		return nil, err
+	}
+	now := r.Now
+	if now == nil {
+		now = time.Now
+	}
+	concurrency := r.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	started := now()
+	results := make([]ExampleResult, len(ds.Examples))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range ds.Examples {
+		if ctx.Err() != nil {
+			break
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = r.runExample(ctx, ds, ds.Examples[i], now)
+		}(i)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		Dataset:       ds.Name,
+		StartedAt:     started.UTC(),
+		DurationMs:    now().Sub(started).Milliseconds(),
+		Examples:      results,
+		PassThreshold: ds.passThreshold(),
+	}
+	summarize(report, ds)
+	return report, nil
+}
+
+func (r Runner) runExample(ctx context.Context, ds *Dataset, ex Example, now func() time.Time) ExampleResult {
+	started := now()
+	result := ExampleResult{ID: ex.ID}
+	defer func() {
+		result.DurationMs = now().Sub(started).Milliseconds()
+	}()
+
+	input := ex.Input
+	if input == nil {
+		input = map[string]any{}
+	}
+	env, err := r.Target(ctx, input)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	vars := env.Vars
+	if vars == nil {
+		vars = map[string]any{}
+	}
+	if out, ok := env.GetVarNested(datasetOutput(ds)); ok {
+		result.Output = out
+	}
+
+	var weighted, totalWeight float64
+	result.Passed = true
+	for _, sc := range ds.Scorers {
+		output := result.Output
+		if sc.Field != "" {
+			output, _ = env.GetVarNested(sc.Field)
+		}
+		value, reason, err := score(ctx, sc, sample{Input: input, Expected: ex.Expected, Output: output, Vars: vars}, r.Judges)
+		s := Score{Scorer: sc.Name, Value: value, Reason: reason}
+		if err != nil {
+			s.Value = 0
+			s.Reason = err.Error()
+		}
+		s.Passed = err == nil && s.Value >= sc.threshold()
+		if !s.Passed {
+			result.Passed = false
+		}
+		result.Scores = append(result.Scores, s)
+		weighted += s.Value * sc.weight()
+		totalWeight += sc.weight()
+	}
+	if totalWeight > 0 {
+		result.Score = weighted / totalWeight
+	}
+	return result
+}
+
+func datasetOutput(ds *Dataset) string {
+	if ds.Output != "" {
+		return ds.Output
+	}
+	return DefaultOutput
+}
+
+func summarize(report *Report, ds *Dataset) {
+	n := float64(len(report.Examples))
+	passed := 0
+	var total float64
+	for _, ex := range report.Examples {
+		if ex.Passed {
+			passed++
+		}
+		total += ex.Score
+	}
+	report.Score = total / n
+	report.PassRate = float64(passed) / n
+	report.Passed = report.PassRate >= report.PassThreshold
+
+	for i, sc := range ds.Scorers {
+		summary := ScorerSummary{Name: sc.Name, Type: sc.Type, Threshold: sc.threshold()}
+		var sum float64
+		scorerPassed := 0
+		for _, ex := range report.Examples {
+			// Examples whose run failed have no scores and count as zero.
+			if i < len(ex.Scores) {
+				sum += ex.Scores[i].Value
+				if ex.Scores[i].Passed {
+					scorerPassed++
+				}
+			}
+		}
+		summary.Mean = sum / n
+		summary.PassRate = float64(scorerPassed) / n
+		report.Scorers = append(report.Scorers, summary)
+	}
+}
@@ -0,0 +1,68 @@
+package evals
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func TestRunnerRun(t *testing.T) {
+	threshold := 0.6
+	ds := &Dataset{
+		Name:          "triage",
+		PassThreshold: &threshold,
+		Scorers: []ScorerConfig{
+			{Type: ScorerExact, Field: "result.label"},
+			{Name: "fast", Type: ScorerExpression, Expression: "vars.result.steps <= 2", Weight: 3},
+		},
+		Examples: []Example{
+			{ID: "billing", Input: map[string]any{"ticket": "refund"}, Expected: "billing"},
+			{ID: "wrong", Input: map[string]any{"ticket": "hello"}, Expected: "support"},
+			{ID: "broken", Input: map[string]any{"ticket": "boom"}, Expected: "incident"},
+		},
+	}
+	target := func(_ context.Context, input map[string]any) (*core.Envelope, error) {
+		switch input["ticket"] {
+		case "boom":
+			return nil, errors.New("provider unavailable")
+		case "refund":
+			return core.NewEnvelope().WithVar("result", map[string]any{"label": "billing", "steps": 1}), nil
+		default:
+			return core.NewEnvelope().WithVar("result", map[string]any{"label": "spam", "steps": 2}), nil
+		}
+	}
+
+	report, err := Runner{Target: target, Concurrency: 2}.Run(context.Background(), ds)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(report.Examples) != 3 {
+		t.Fatalf("examples = %d", len(report.Examples))
+	}
+	billing, wrong, broken := report.Examples[0], report.Examples[1], report.Examples[2]
+	if !billing.Passed || billing.Score != 1 {
+		t.Errorf("billing = %+v", billing)
+	}
+	if wrong.Passed || wrong.Score != 0.75 {
+		t.Errorf("wrong = %+v, want score 0.75", wrong)
+	}
+	if broken.Passed || !strings.Contains(broken.Error, "provider unavailable") {
+		t.Errorf("broken = %+v", broken)
+	}
+	if report.PassRate != 1.0/3 || report.Passed {
+		t.Errorf("pass rate = %v passed = %v, want 1/3 and failing", report.PassRate, report.Passed)
+	}
+	if len(report.Scorers) != 2 || report.Scorers[0].Name != ScorerExact || report.Scorers[1].Mean != 2.0/3 {
+		t.Errorf("scorers = %+v", report.Scorers)
+	}
+}
+
+func TestRunnerRun_InvalidDataset(t *testing.T) {
+	target := func(context.Context, map[string]any) (*core.Envelope, error) { return core.NewEnvelope(), nil }
+	if _, err := (Runner{Target: target}).Run(context.Background(), &Dataset{Name: "empty"}); err == nil {
+		t.Fatal("expected an error for a dataset without examples")
+	}
+}
@@ -0,0 +1,335 @@
+package evals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+)
+
+// Scorer types.
+const (
+	// ScorerExact scores 1 when the output equals the expected value.
+	ScorerExact = "exact"
+	// ScorerContains scores the fraction of expected strings found in
+	// the output.
+	ScorerContains = "contains"
+	// ScorerExpression evaluates a conditional expression over input,
+	// expected, output and vars. Booleans score 0 or 1; numbers are
+	// clamped to [0, 1].
+	ScorerExpression = "expression"
+	// ScorerJudge asks a model to grade the output from 0 to 1.
+	ScorerJudge = "judge"
+)
+
+// defaultJudgeThreshold is the passing score for judge scorers; the other
+// scorers must score 1 by default.
+const defaultJudgeThreshold = 0.7
+
+// ScorerConfig declares how example outputs are scored.
+type ScorerConfig struct {
+	// Name identifies the scorer in reports. Defaults to Type.
+	Name string `json:"name,omitempty"`
+	Type string `json:"type"`
+	// Field is the envelope variable path to score. Defaults to the
+	// dataset's output.
+	Field string `json:"field,omitempty"`
+	// IgnoreCase compares strings case-insensitively (exact, contains).
+	IgnoreCase bool `json:"ignore_case,omitempty"`
+	// Expression is the expression for the expression scorer.
+	Expression string `json:"expression,omitempty"`
+	// Provider and Model select the judge model; Rubric tells it what a
+	// good answer looks like.
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Rubric   string `json:"rubric,omitempty"`
+	// Threshold is the score an example needs to pass this scorer.
+	// Defaults to 1, or 0.7 for judge scorers.
+	Threshold *float64 `json:"threshold,omitempty"`
+	// Weight sets the scorer's share of an example's overall score.
+	// Defaults to 1.
+	Weight float64 `json:"weight,omitempty"`
+}
+
+// Validate checks the scorer's type and type-specific fields.
+func (c ScorerConfig) Validate() error {
+	switch c.Type {
+	case ScorerExact, ScorerContains:
+	case ScorerExpression:
+		if strings.TrimSpace(c.Expression) == "" {
+			return fmt.Errorf("scorer %q: expression is required", c.Name)
+		}
+		if err := expr.ValidateSyntax(c.Expression); err != nil {
+			return fmt.Errorf("scorer %q: %w", c.Name, err)
+		}
+	case ScorerJudge:
+		if c.Provider == "" || c.Model == "" {
+			return fmt.Errorf("scorer %q: judge scorers need provider and model", c.Name)
+		}
+	default:
+		return fmt.Errorf("scorer %q: unknown type %q (use exact, contains, expression or judge)", c.Name, c.Type)
+	}
+	if c.Threshold != nil && (*c.Threshold < 0 || *c.Threshold > 1) {
+		return fmt.Errorf("scorer %q: threshold must be between 0 and 1", c.Name)
+	}
+	if c.Weight < 0 {
+		return fmt.Errorf("scorer %q: weight must be >= 0", c.Name)
+	}
+	return nil
+}
+
+func (c ScorerConfig) threshold() float64 {
+	if c.Threshold != nil {
+		return *c.Threshold
+	}
+	if c.Type == ScorerJudge {
+		return defaultJudgeThreshold
+	}
+	return 1
+}
+
+func (c ScorerConfig) weight() float64 {
+	if c.Weight == 0 {
+		return 1
+	}
+	return c.Weight
+}
+
+// Score is one scorer's grade for an example.
+type Score struct {
+	Scorer string  `json:"scorer"`
+	Value  float64 `json:"value"`
+	Passed bool    `json:"passed"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// sample is what a scorer grades.
+type sample struct {
+	Input    map[string]any
+	Expected any
+	Output   any
+	Vars     map[string]any
+}
+
+// JudgeClients returns the model client for a judge scorer's provider.
+type JudgeClients func(provider string) (core.LLMClient, error)
+
+func score(ctx context.Context, c ScorerConfig, s sample, judges JudgeClients) (float64, string, error) {
+	switch c.Type {
+	case ScorerExact:
+		return scoreExact(c, s), "", nil
+	case ScorerContains:
+		return scoreContains(c, s)
+	case ScorerExpression:
+		return scoreExpression(c, s)
+	case ScorerJudge:
+		return scoreJudge(ctx, c, s, judges)
+	default:
+		return 0, "", fmt.Errorf("unknown scorer type %q", c.Type)
+	}
+}
+
+func scoreExact(c ScorerConfig, s sample) float64 {
+	got, want := normalizeJSON(s.Output), normalizeJSON(s.Expected)
+	if gs, ok := got.(string); ok {
+		if ws, ok := want.(string); ok {
+			gs, ws = strings.TrimSpace(gs), strings.TrimSpace(ws)
+			if c.IgnoreCase {
+				return boolScore(strings.EqualFold(gs, ws))
+			}
+			return boolScore(gs == ws)
+		}
+	}
+	return boolScore(reflect.DeepEqual(got, want))
+}
+
+func scoreContains(c ScorerConfig, s sample) (float64, string, error) {
+	var needles []string
+	switch v := s.Expected.(type) {
+	case string:
+		needles = []string{v}
+	case []any:
+		for _, item := range v {
+			needles = append(needles, fmt.Sprint(item))
+		}
+	default:
+		return 0, "", fmt.Errorf("contains scorer needs expected to be a string or a list of strings, got %T", s.Expected)
+	}
+	if len(needles) == 0 {
+		return 1, "", nil
+	}
+
+	haystack := stringify(s.Output)
+	if c.IgnoreCase {
+		haystack = strings.ToLower(haystack)
+	}
+	var missing []string
+	for _, needle := range needles {
+		n := needle
+		if c.IgnoreCase {
+			n = strings.ToLower(n)
+		}
+		if !strings.Contains(haystack, n) {
+			missing = append(missing, needle)
+		}
+	}
+	value := float64(len(needles)-len(missing)) / float64(len(needles))
+	if len(missing) > 0 {
+		return value, "missing " + strings.Join(quoteAll(missing), ", "), nil
+	}
+	return value, "", nil
+}
+
+func scoreExpression(c ScorerConfig, s sample) (float64, string, error) {
+	parsed, err := expr.Parse(c.Expression)
+	if err != nil {
+		return 0, "", err
+	}
+	result, err := expr.Eval(parsed, map[string]any{
+		"input":    s.Input,
+		"expected": normalizeJSON(s.Expected),
+		"output":   normalizeJSON(s.Output),
+		"vars":     s.Vars,
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	if n, ok := result.(float64); ok {
+		return clamp01(n), "", nil
+	}
+	return boolScore(expr.IsTruthy(result)), "", nil
+}
+
+const judgeSystemPrompt = `You grade the output of an AI workflow. Compare the output with the
+expected answer and the rubric, then reply with a JSON object holding a
+"score" between 0 (wrong) and 1 (fully correct) and a one-sentence "reason".`
+
+var judgePromptTemplate = template.Must(template.New("judge").Parse(`{{if .Rubric}}Rubric:
+{{.Rubric}}
+
+{{end}}Input:
+{{.Input}}
+
+Expected:
+{{.Expected}}
+
+Output:
+{{.Output}}`))
+
+func scoreJudge(ctx context.Context, c ScorerConfig, s sample, judges JudgeClients) (float64, string, error) {
+	if judges == nil {
+		return 0, "", fmt.Errorf("no judge model client is configured")
+	}
+	client, err := judges(c.Provider)
+	if err != nil {
+		return 0, "", fmt.Errorf("judge provider %q: %w", c.Provider, err)
+	}
+
+	var prompt strings.Builder
+	if err := judgePromptTemplate.Execute(&prompt, map[string]string{
+		"Rubric":   c.Rubric,
+		"Input":    stringify(s.Input),
+		"Expected": stringify(s.Expected),
+		"Output":   stringify(s.Output),
+	}); err != nil {
+		return 0, "", err
+	}
+	temperature := 0.0
+	resp, err := client.Complete(ctx, core.LLMRequest{
+		Model:       c.Model,
+		System:      judgeSystemPrompt,
+		InputText:   prompt.String(),
+		Temperature: &temperature,
+		JSONSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"score":  map[string]any{"type": "number"},
+				"reason": map[string]any{"type": "string"},
+			},
+			"required": []any{"score", "reason"},
+		},
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("judge: %w", err)
+	}
+
+	verdict := resp.JSON
+	if verdict == nil {
+		if err := json.Unmarshal([]byte(extractJSONObject(resp.Text)), &verdict); err != nil {
+			return 0, "", fmt.Errorf("judge returned no JSON verdict: %q", resp.Text)
+		}
+	}
+	value, ok := verdict["score"].(float64)
+	if !ok {
+		return 0, "", fmt.Errorf("judge verdict has no numeric score: %v", verdict)
+	}
+	reason, _ := verdict["reason"].(string)
+	return clamp01(value), reason, nil
+}
+
+// extractJSONObject returns the outermost {...} in s, tolerating code
+// fences and prose around a model's JSON reply.
+func extractJSONObject(s string) string {
+	start := strings.Index(s, "{")
+	end := strings.LastIndex(s, "}")
+	if start < 0 || end < start {
+		return s
+	}
+	return s[start : end+1]
+}
+
+// normalizeJSON gives values the shape they have after a JSON round trip,
+// so 3 and 3.0 or typed and untyped maps compare equal.
+func normalizeJSON(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
+
+func stringify(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func quoteAll(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = fmt.Sprintf("%q", v)
+	}
+	return out
+}
+
+func boolScore(ok bool) float64 {
+	if ok {
+		return 1
+	}
+	return 0
+}
+
+func clamp01(v float64) float64 {
+	switch {
+	case v < 0:
+		return 0
+	case v > 1:
+		return 1
+	default:
+		return v
+	}
+}
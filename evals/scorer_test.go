@@ -0,0 +1,83 @@
+package evals
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+type fakeJudge struct {
+	resp core.LLMResponse
+	err  error
+	req  core.LLMRequest
+}
+
+func (f *fakeJudge) Complete(_ context.Context, req core.LLMRequest) (core.LLMResponse, error) {
+	f.req = req
+	return f.resp, f.err
+}
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ScorerConfig
+		sample sample
+		want   float64
+	}{
+		{"exact string", ScorerConfig{Type: ScorerExact}, sample{Output: " billing\n", Expected: "billing"}, 1},
+		{"exact case", ScorerConfig{Type: ScorerExact}, sample{Output: "Billing", Expected: "billing"}, 0},
+		{"exact ignore case", ScorerConfig{Type: ScorerExact, IgnoreCase: true}, sample{Output: "Billing", Expected: "billing"}, 1},
+		{"exact number", ScorerConfig{Type: ScorerExact}, sample{Output: 3, Expected: float64(3)}, 1},
+		{"exact object", ScorerConfig{Type: ScorerExact}, sample{Output: map[string]any{"a": []string{"x"}}, Expected: map[string]any{"a": []any{"x"}}}, 1},
+		{"contains partial", ScorerConfig{Type: ScorerContains}, sample{Output: "refund issued", Expected: []any{"refund", "apology"}}, 0.5},
+		{"contains object", ScorerConfig{Type: ScorerContains}, sample{Output: map[string]any{"label": "spam"}, Expected: "spam"}, 1},
+		{"expression bool", ScorerConfig{Type: ScorerExpression, Expression: `output.score >= input.min`}, sample{Input: map[string]any{"min": float64(0.5)}, Output: map[string]any{"score": 0.9}}, 1},
+		{"expression number", ScorerConfig{Type: ScorerExpression, Expression: `output.confidence`}, sample{Output: map[string]any{"confidence": 1.7}}, 1},
+	}
+	for _, tt := range tests {
+		got, _, err := score(context.Background(), tt.config, tt.sample, nil)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: score = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, _, err := score(context.Background(), ScorerConfig{Type: ScorerContains}, sample{Output: "x", Expected: 5}, nil); err == nil {
+		t.Error("contains with a numeric expected value should fail")
+	}
+}
+
+func TestScoreJudge(t *testing.T) {
+	judge := &fakeJudge{resp: core.LLMResponse{Text: "```json\n{\"score\": 0.8, \"reason\": \"mostly right\"}\n```"}}
+	judges := func(provider string) (core.LLMClient, error) {
+		if provider != "openai" {
+			return nil, errors.New("unexpected provider")
+		}
+		return judge, nil
+	}
+	cfg := ScorerConfig{Name: "quality", Type: ScorerJudge, Provider: "openai", Model: "gpt-4o-mini", Rubric: "Must apologize."}
+	value, reason, err := score(context.Background(), cfg, sample{Input: map[string]any{"q": "hi"}, Expected: "sorry", Output: "so sorry"}, judges)
+	if err != nil {
+		t.Fatalf("score: %v", err)
+	}
+	if value != 0.8 || reason != "mostly right" {
+		t.Fatalf("judge = %v %q", value, reason)
+	}
+	if judge.req.Model != "gpt-4o-mini" || !strings.Contains(judge.req.InputText, "Must apologize.") || !strings.Contains(judge.req.InputText, "so sorry") {
+		t.Fatalf("judge request = %+v", judge.req)
+	}
+
+	judge.resp = core.LLMResponse{Text: "looks good"}
+	if _, _, err := score(context.Background(), cfg, sample{}, judges); err == nil {
+		t.Fatal("expected an error for a reply without a verdict")
+	}
+	if _, _, err := score(context.Background(), cfg, sample{}, nil); err == nil {
+		t.Fatal("expected an error without judge clients")
+	}
+}
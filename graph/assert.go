@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// AssertErrorPort is the output port a node with route assertions sends
+// failing output to. Edges leave it with sourceHandle "error".
+const AssertErrorPort = "error"
+
+// Assertion actions.
+const (
+	// AssertFail fails the node. It is the default.
+	AssertFail = "fail"
+	// AssertWarn emits a node.assertion_failed event and continues.
+	AssertWarn = "warn"
+	// AssertRoute sends the output down the node's error port only.
+	AssertRoute = "route"
+)
+
+// Assertion is one entry of a node's "assert" config: an expression over
+// the node's outputs that must be truthy once the node has run. Entries
+// may also be written as a bare expression string.
+type Assertion struct {
+	Expr string `json:"expr"`
+	// Action is fail, warn or route.
+	Action string `json:"action,omitempty"`
+	// Message replaces the expression in errors and events.
+	Message string `json:"message,omitempty"`
+}
+
+// Assertions decodes the node's "assert" config. A node without one has
+// none.
+func (nd NodeDef) Assertions() ([]Assertion, error) {
+	raw, ok := nd.Config["assert"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("assert must be an array")
+	}
+	out := make([]Assertion, 0, len(items))
+	for i, item := range items {
+		var a Assertion
+		switch v := item.(type) {
+		case string:
+			a.Expr = v
+		case map[string]any:
+			data, _ := json.Marshal(v)
+			dec := json.NewDecoder(bytes.NewReader(data))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&a); err != nil {
+				return nil, fmt.Errorf("assert[%d]: %w", i, err)
+			}
+		default:
+			return nil, fmt.Errorf("assert[%d] must be an expression or an object", i)
+		}
+		if a.Expr == "" {
+			return nil, fmt.Errorf("assert[%d]: expr is required", i)
+		}
+		switch a.Action {
+		case "":
+			a.Action = AssertFail
+		case AssertFail, AssertWarn, AssertRoute:
+		default:
+			return nil, fmt.Errorf("assert[%d]: action %q must be one of: fail, warn, route", i, a.Action)
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+// ErrorPortTarget returns the node wired to nodeID's error port, or "" if
+// there is none.
+func (gd *GraphDefinition) ErrorPortTarget(nodeID string) string {
+	for _, edge := range gd.Edges {
+		if edge.Source == nodeID && edge.SourceHandle == AssertErrorPort {
+			return edge.Target
+		}
+	}
+	return ""
+}
+
+// validateAssertions checks GR-018: assert blocks decode, their
+// expressions parse, and nodes that route failures have an error edge.
+func (gd *GraphDefinition) validateAssertions() []Diagnostic {
+	var diags []Diagnostic
+	for i, node := range gd.Nodes {
+		assertions, err := node.Assertions()
+		if err != nil {
+			diags = append(diags, Diagnostic{
+				Code:     "GR-018",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Node %q: %v", node.ID, err),
+				Path:     fmt.Sprintf("nodes[%d].config.assert", i),
+			})
+			continue
+		}
+		for j, a := range assertions {
+			path := fmt.Sprintf("nodes[%d].config.assert[%d]", i, j)
+			if registeredExprValidator != nil {
+				if err := registeredExprValidator(a.Expr); err != nil {
+					diags = append(diags, Diagnostic{
+						Code:     "GR-018",
+						Severity: SeverityError,
+						Message:  fmt.Sprintf("Node %q: assertion %q has invalid expression: %v", node.ID, a.Expr, err),
+						Path:     path,
+					})
+				}
+			}
+			if a.Action == AssertRoute && gd.ErrorPortTarget(node.ID) == "" {
+				diags = append(diags, Diagnostic{
+					Code:     "GR-018",
+					Severity: SeverityError,
+					Message:  fmt.Sprintf("Node %q: assertion %q routes to the error port, but no edge leaves it", node.ID, a.Expr),
+					Path:     path + ".action",
+				})
+			}
+		}
+	}
+	return diags
+}
@@ -0,0 +1,80 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/registry"
+)
+
+func TestNodeDef_Assertions(t *testing.T) {
+	nd := NodeDef{ID: "retrieve", Config: map[string]any{"assert": []any{
+		"output.documents.length > 0",
+		map[string]any{"expr": "output.documents[0].score > 0.5", "action": "warn", "message": "weak match"},
+	}}}
+	got, err := nd.Assertions()
+	if err != nil {
+		t.Fatalf("Assertions: %v", err)
+	}
+	want := []Assertion{
+		{Expr: "output.documents.length > 0", Action: AssertFail},
+		{Expr: "output.documents[0].score > 0.5", Action: AssertWarn, Message: "weak match"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Assertions() = %+v, want %+v", got, want)
+	}
+
+	for _, tt := range []struct {
+		raw  any
+		want string
+	}{
+		{"output.x", "must be an array"},
+		{[]any{42}, "must be an expression or an object"},
+		{[]any{map[string]any{"action": "warn"}}, "expr is required"},
+		{[]any{map[string]any{"expr": "true", "action": "ignore"}}, "action"},
+		{[]any{map[string]any{"expr": "true", "on_fail": "warn"}}, "unknown field"},
+	} {
+		_, err := NodeDef{Config: map[string]any{"assert": tt.raw}}.Assertions()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: err = %v, want %q", tt.raw, err, tt.want)
+		}
+	}
+}
+
+func TestValidate_GR018_Assertions(t *testing.T) {
+	gd := GraphDefinition{
+		ID:      "rag",
+		Version: "1.0",
+		Nodes: []NodeDef{
+			{ID: "retrieve", Type: "noop", Config: map[string]any{"assert": []any{
+				map[string]any{"expr": "output.documents.length > 0", "action": "route"},
+			}}},
+			{ID: "answer", Type: "noop", Config: map[string]any{"assert": []any{
+				map[string]any{"expr": "output.text", "action": "route"},
+			}}},
+			{ID: "no_results", Type: "noop"},
+		},
+		Edges: []EdgeDef{
+			{Source: "retrieve", Target: "answer"},
+			{Source: "retrieve", SourceHandle: AssertErrorPort, Target: "no_results"},
+		},
+		Entry: "retrieve",
+	}
+
+	diags := gd.ValidateWithRegistry(registry.Global())
+	var gr018 []Diagnostic
+	for _, d := range diags {
+		if d.Code == "GR-018" {
+			gr018 = append(gr018, d)
+		}
+		if d.Code == "GR-006" {
+			t.Errorf("unexpected GR-006 for the error port: %+v", d)
+		}
+	}
+	if len(gr018) != 1 || gr018[0].Path != "nodes[1].config.assert[0].action" {
+		t.Errorf("GR-018 diagnostics = %+v, want one for answer's route without an error edge", gr018)
+	}
+	if got := gd.ErrorPortTarget("retrieve"); got != "no_results" {
+		t.Errorf("ErrorPortTarget = %q, want no_results", got)
+	}
+}
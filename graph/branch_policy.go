@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Branch timeout policies.
+const (
+	// BranchOnTimeoutFail fails the run when the branch times out.
+	BranchOnTimeoutFail = "fail"
+	// BranchOnTimeoutPartial merges the branches that finished and
+	// reports the missing one.
+	BranchOnTimeoutPartial = "partial"
+	// BranchOnTimeoutDefault merges the envelope the branch started with,
+	// plus Defaults, in place of the branch's result.
+	BranchOnTimeoutDefault = "default"
+)
+
+var branchTimeoutPolicies = []string{BranchOnTimeoutFail, BranchOnTimeoutPartial, BranchOnTimeoutDefault}
+
+// MissingBranchesVar is the variable that lists the branches a merge went
+// without. Each entry is an object with the source and target of the
+// edge that started the branch, the node that was running, the timeout
+// and the policy.
+const MissingBranchesVar = "missing_branches"
+
+// BranchPolicy bounds the branch a fan-out edge starts. The branch runs
+// from the edge's target to the next merge node, and its nodes see the
+// deadline through their context.
+type BranchPolicy struct {
+	// Timeout is a Go duration string, measured from when the branch's
+	// first node starts.
+	Timeout string `json:"timeout"`
+	// OnTimeout is fail (default), partial or default.
+	OnTimeout string `json:"on_timeout,omitempty"`
+	// Defaults are the variables the default policy merges in place of
+	// the branch's outputs.
+	Defaults map[string]any `json:"defaults,omitempty"`
+}
+
+// TimeoutDuration returns the parsed timeout, or 0 when it is not a
+// positive duration.
+func (p BranchPolicy) TimeoutDuration() time.Duration {
+	d, ok := parseRunDuration(p.Timeout)
+	if !ok {
+		return 0
+	}
+	return d
+}
+
+// Policy returns OnTimeout, defaulting to fail.
+func (p BranchPolicy) Policy() string {
+	if p.OnTimeout == "" {
+		return BranchOnTimeoutFail
+	}
+	return p.OnTimeout
+}
+
+// BranchPolicyGraph is implemented by graphs whose edges carry branch
+// policies. BasicGraph implements it.
+type BranchPolicyGraph interface {
+	// EdgeBranch returns the policy of the edge from one node to the next,
+	// or nil.
+	EdgeBranch(from, to string) *BranchPolicy
+}
+
+// validateBranchPolicies checks GR-023: branch policies have a positive
+// timeout and a known policy, and only the default policy has defaults.
+func (gd *GraphDefinition) validateBranchPolicies() []Diagnostic {
+	var diags []Diagnostic
+	for i, edge := range gd.Edges {
+		p := edge.Branch
+		if p == nil {
+			continue
+		}
+		fail := func(msg string) {
+			diags = append(diags, Diagnostic{
+				Code:     "GR-023",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Edge %s -> %s: %s", edge.Source, edge.Target, msg),
+				Path:     fmt.Sprintf("edges[%d].branch", i),
+			})
+		}
+		if p.TimeoutDuration() == 0 {
+			fail(fmt.Sprintf("branch timeout %q must be a positive duration", p.Timeout))
+		}
+		if !slices.Contains(branchTimeoutPolicies, p.Policy()) {
+			fail(fmt.Sprintf("unknown on_timeout %q; use one of: %s", p.OnTimeout, strings.Join(branchTimeoutPolicies, ", ")))
+		} else if len(p.Defaults) > 0 && p.Policy() != BranchOnTimeoutDefault {
+			fail(fmt.Sprintf("defaults need on_timeout %q", BranchOnTimeoutDefault))
+		}
+	}
+	return diags
+}
@@ -0,0 +1,55 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func TestValidate_GR023_BranchPolicies(t *testing.T) {
+	var edge EdgeDef
+	data := `{"source": "a", "sourceHandle": "output", "target": "b", "targetHandle": "input",
+		"branch": {"timeout": "2s", "on_timeout": "default", "defaults": {"forecast": "unknown"}}}`
+	if err := json.Unmarshal([]byte(data), &edge); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	gd := GraphDefinition{
+		ID:      "branches",
+		Version: "1.0",
+		Nodes:   []NodeDef{{ID: "a", Type: "noop"}, {ID: "b", Type: "noop"}},
+		Edges:   []EdgeDef{edge},
+		Entry:   "a",
+	}
+	if found := findDiag(gd.Validate(), "GR-023"); found != nil {
+		t.Fatalf("unexpected GR-023: %+v", found)
+	}
+
+	g, err := gd.ToGraph(WithNodeFactory(func(nd NodeDef) (core.Node, error) {
+		return core.NewNoopNode(nd.ID), nil
+	}))
+	if err != nil {
+		t.Fatalf("ToGraph: %v", err)
+	}
+	if p := g.EdgeBranch("a", "b"); p == nil || p.TimeoutDuration() != 2*time.Second || p.Defaults["forecast"] != "unknown" {
+		t.Errorf("EdgeBranch = %+v", p)
+	}
+
+	for _, bad := range []BranchPolicy{
+		{},
+		{Timeout: "-1s"},
+		{Timeout: "1s", OnTimeout: "retry"},
+		{Timeout: "1s", OnTimeout: BranchOnTimeoutPartial, Defaults: map[string]any{"x": 1}},
+	} {
+		gd.Edges[0].Branch = &bad
+		found := findDiag(gd.Validate(), "GR-023")
+		if found == nil {
+			t.Errorf("%+v: expected GR-023", bad)
+			continue
+		}
+		if found.Path != "edges[0].branch" {
+			t.Errorf("Path = %q", found.Path)
+		}
+	}
+}
@@ -2,8 +2,13 @@ package graph
 
 import (
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/mask"
 	"github.com/petal-labs/petalflow/registry"
 	"github.com/petal-labs/petalflow/schemafmt"
 )
@@ -16,6 +21,12 @@ type Diagnostic struct {
 	Message  string `json:"message"`        // human-readable description
 	Path     string `json:"path,omitempty"` // JSON path to offending field
 	Line     int    `json:"line,omitempty"` // source line number (0 if unavailable)
+
+	// Node, NodeDescription and DocURL identify the node the diagnostic is
+	// about and repeat its author's documentation, when it has any.
+	Node            string `json:"node,omitempty"`
+	NodeDescription string `json:"node_description,omitempty"`
+	DocURL          string `json:"doc_url,omitempty"`
 }
 
 const (
@@ -67,21 +78,74 @@ type GraphDefinition struct {
 	Nodes         []NodeDef         `json:"nodes"`
 	Edges         []EdgeDef         `json:"edges"`
 	Entry         string            `json:"entry,omitempty"`
+	Masking       *mask.Policy      `json:"masking,omitempty"`
+	Simulate      *SimulationDef    `json:"simulate,omitempty"`
+	// Vars declares lifetimes for envelope variables, keyed by name.
+	Vars map[string]VarLifetime `json:"vars,omitempty"`
+	// RunDefaults are run options applied to every run of the workflow.
+	RunDefaults *RunDefaults `json:"run_defaults,omitempty"`
+	// Presets are named run inputs, keyed by name.
+	Presets map[string]InputPreset `json:"presets,omitempty"`
+	// SLA declares service levels tracked for every run.
+	SLA *SLA `json:"sla,omitempty"`
+	// LLMDefaults are settings LLM nodes inherit unless they set their own.
+	LLMDefaults *LLMDefaults `json:"llm_defaults,omitempty"`
+	// Requires are the engine and node type versions the workflow needs.
+	Requires *Requirements `json:"requires,omitempty"`
 }
 
 // NodeDef is a serializable node within a GraphDefinition.
 type NodeDef struct {
-	ID     string         `json:"id"`
-	Type   string         `json:"type"`
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	// Description states what the node is for. It is shown in run events,
+	// diagrams and validation messages.
+	Description string `json:"description,omitempty"`
+	// DocURL links to the node's runbook or design notes.
+	DocURL string         `json:"doc_url,omitempty"`
 	Config map[string]any `json:"config,omitempty"`
 }
 
+// NodeDoc is the documentation an author attached to a node.
+type NodeDoc struct {
+	Description string `json:"description,omitempty"`
+	DocURL      string `json:"doc_url,omitempty"`
+}
+
+// Variable scopes.
+const (
+	// VarScopeRun keeps a variable for the whole run. It is the default.
+	VarScopeRun = "run"
+	// VarScopeBranch drops a variable when parallel branches merge and
+	// from the run's result.
+	VarScopeBranch = "branch"
+	// VarScopeNode drops a variable after the node that runs next on its
+	// envelope, so only the nodes directly after the one that set it see
+	// it.
+	VarScopeNode = "node"
+)
+
+// VarLifetime limits how long an envelope variable lives and whether it
+// leaves the process.
+type VarLifetime struct {
+	// Scope is run (default), branch or node.
+	Scope string `json:"scope,omitempty"`
+	// DropBeforePersist keeps the variable out of recorded node outputs
+	// and the run.started inputs snapshot, so it is never written to an
+	// event store.
+	DropBeforePersist bool `json:"drop_before_persist,omitempty"`
+}
+
 // EdgeDef is a serializable edge within a GraphDefinition.
 type EdgeDef struct {
 	Source       string `json:"source"`
 	SourceHandle string `json:"sourceHandle"`
 	Target       string `json:"target"`
 	TargetHandle string `json:"targetHandle"`
+	// Vars are applied when the run moves along the edge.
+	Vars []VarMapping `json:"vars,omitempty"`
+	// Branch bounds the branch the edge starts when it fans out.
+	Branch *BranchPolicy `json:"branch,omitempty"`
 }
 
 // Validate checks structural integrity of the GraphDefinition.
@@ -92,8 +156,23 @@ type EdgeDef struct {
 //   - GR-004: topological sort (cycle detection)
 //   - GR-005: duplicate node IDs
 //   - GR-007: entry references existing node
+//   - GR-011: masking policy rules are well formed
+//   - GR-012: simulated responses are well formed and reference existing nodes
+//   - GR-013: node "idempotent" declarations are booleans
+//   - GR-015: node doc_url values are absolute http(s) URLs (warning)
+//   - GR-016: variable lifetimes use a known scope
+//   - GR-017: run defaults are well formed and within their limits
+//   - GR-018: node assert blocks are well formed
+//   - GR-019: input preset names are well formed
+//   - GR-022: edge variable mappings are well formed
+//   - GR-023: edge branch policies are well formed
+//   - GR-024: LLM defaults are well formed
+//   - GR-025: requirement constraints are well formed and the engine meets
+//     requires.engine
 //
-// Registry-dependent rules (GR-003, GR-006, GR-008) require a registry
+// Diagnostics about a node carry its description and doc URL.
+//
+// Registry-dependent rules (GR-003, GR-006, GR-008, GR-021, GR-026) require a registry
 // and are checked via ValidateWithRegistry.
 func (gd *GraphDefinition) Validate() []Diagnostic {
 	var diags []Diagnostic
@@ -177,12 +256,190 @@ func (gd *GraphDefinition) Validate() []Diagnostic {
 		}
 	}
 
+	// GR-011: masking policy must be well formed
+	if err := gd.Masking.Validate(); err != nil {
+		diags = append(diags, Diagnostic{
+			Code:     "GR-011",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Invalid masking policy: %v", err),
+			Path:     "masking",
+		})
+	}
+
+	// GR-012: simulated responses must be well formed and target real nodes
+	diags = append(diags, gd.validateSimulation(nodeIDs)...)
+
+	// GR-013: idempotent declarations must be booleans
+	for i, node := range gd.Nodes {
+		if v, ok := node.Config["idempotent"]; ok {
+			if _, isBool := v.(bool); !isBool {
+				diags = append(diags, Diagnostic{
+					Code:     "GR-013",
+					Severity: SeverityError,
+					Message:  fmt.Sprintf("Node %q: idempotent must be a boolean", node.ID),
+					Path:     fmt.Sprintf("nodes[%d].config.idempotent", i),
+				})
+			}
+		}
+	}
+
+	// GR-015: doc links must be usable from events and diagrams
+	for i, node := range gd.Nodes {
+		if node.DocURL != "" && !isHTTPURL(node.DocURL) {
+			diags = append(diags, Diagnostic{
+				Code:     "GR-015",
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("Node %q: doc_url %q is not an absolute http(s) URL", node.ID, node.DocURL),
+				Path:     fmt.Sprintf("nodes[%d].doc_url", i),
+			})
+		}
+	}
+
+	// GR-016: variable lifetimes must use a known scope
+	names := make([]string, 0, len(gd.Vars))
+	for name := range gd.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		switch scope := gd.Vars[name].Scope; scope {
+		case "", VarScopeRun, VarScopeBranch, VarScopeNode:
+		default:
+			diags = append(diags, Diagnostic{
+				Code:     "GR-016",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Variable %q: scope %q must be one of: run, branch, node", name, scope),
+				Path:     fmt.Sprintf("vars.%s.scope", name),
+			})
+		}
+	}
+
+	// GR-017: run defaults must be well formed
+	diags = append(diags, gd.validateRunDefaults(nodeIDs)...)
+
+	// GR-018: node assertions must be well formed
+	diags = append(diags, gd.validateAssertions()...)
+
+	// GR-019: input preset names must be well formed
+	diags = append(diags, gd.validatePresets()...)
+
+	// GR-020: SLA durations must be positive
+	diags = append(diags, gd.validateSLA()...)
+
+	// GR-022: edge variable mappings must be well formed
+	diags = append(diags, gd.validateEdgeVars()...)
+
+	// GR-023: edge branch policies must be well formed
+	diags = append(diags, gd.validateBranchPolicies()...)
+
+	// GR-024: LLM defaults must be well formed
+	diags = append(diags, gd.validateLLMDefaults()...)
+
+	// GR-025: requirements must be well formed and met by the engine
+	diags = append(diags, gd.validateRequires()...)
+
 	// CN-*: conditional node validation
 	diags = append(diags, gd.validateConditionalNodes(nodeIDs)...)
 
+	return gd.annotateNodeDocs(diags)
+}
+
+// NodeDocs returns the documentation of the nodes that have any, keyed by
+// node ID.
+func (gd *GraphDefinition) NodeDocs() map[string]NodeDoc {
+	docs := make(map[string]NodeDoc)
+	for _, node := range gd.Nodes {
+		if node.Description != "" || node.DocURL != "" {
+			docs[node.ID] = NodeDoc{Description: node.Description, DocURL: node.DocURL}
+		}
+	}
+	return docs
+}
+
+// VarLifetimes returns the declared variable lifetimes that differ from the
+// default of a persisted, run-scoped variable.
+func (gd *GraphDefinition) VarLifetimes() map[string]VarLifetime {
+	lifetimes := make(map[string]VarLifetime)
+	for name, lt := range gd.Vars {
+		if (lt.Scope != "" && lt.Scope != VarScopeRun) || lt.DropBeforePersist {
+			lifetimes[name] = lt
+		}
+	}
+	return lifetimes
+}
+
+func isHTTPURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// annotateNodeDocs fills in the node and its documentation on diagnostics
+// whose path points at a node or an edge endpoint.
+func (gd *GraphDefinition) annotateNodeDocs(diags []Diagnostic) []Diagnostic {
+	byID := make(map[string]int, len(gd.Nodes))
+	for i, node := range gd.Nodes {
+		byID[node.ID] = i
+	}
+	for i := range diags {
+		d := &diags[i]
+		if d.Node != "" {
+			continue
+		}
+		idx := -1
+		if n, _, ok := scanIndexedPath(d.Path, "nodes"); ok {
+			idx = n
+		} else if n, field, ok := scanIndexedPath(d.Path, "edges"); ok && n < len(gd.Edges) {
+			endpoint := gd.Edges[n].Source
+			if field == "target" || field == "targetHandle" {
+				endpoint = gd.Edges[n].Target
+			}
+			if j, found := byID[endpoint]; found {
+				idx = j
+			}
+		}
+		if idx < 0 || idx >= len(gd.Nodes) {
+			continue
+		}
+		node := gd.Nodes[idx]
+		d.Node = node.ID
+		d.NodeDescription = node.Description
+		d.DocURL = node.DocURL
+	}
 	return diags
 }
 
+// scanIndexedPath splits a path such as "edges[2].source" that starts with
+// prefix into its index and the field after it.
+func scanIndexedPath(path, prefix string) (int, string, bool) {
+	rest, ok := strings.CutPrefix(path, prefix+"[")
+	if !ok {
+		return 0, "", false
+	}
+	index, field, ok := strings.Cut(rest, "]")
+	if !ok {
+		return 0, "", false
+	}
+	n, err := strconv.Atoi(index)
+	if err != nil {
+		return 0, "", false
+	}
+	return n, strings.TrimPrefix(field, "."), true
+}
+
+// IdempotentNodes returns the IDs of nodes whose config declares
+// "idempotent": true. Such nodes re-execute when a run is resumed or
+// retried; every other node that already completed is restored from its
+// recorded output instead.
+func (gd *GraphDefinition) IdempotentNodes() map[string]bool {
+	ids := make(map[string]bool)
+	for _, node := range gd.Nodes {
+		if v, _ := node.Config["idempotent"].(bool); v {
+			ids[node.ID] = true
+		}
+	}
+	return ids
+}
+
 func (gd *GraphDefinition) validateSchemaHeader() []Diagnostic {
 	var diags []Diagnostic
 
@@ -223,7 +480,23 @@ func (gd *GraphDefinition) validateSchemaHeader() []Diagnostic {
 //   - GR-003: node type must exist in the registry
 //   - GR-006: source handle should map to a declared output port when static
 //   - GR-008: function_call tools cannot be used as standalone graph nodes
+//   - GR-013: webhook_call and tool nodes should declare idempotent (warning)
+//   - GR-014: node types registered as deprecated aliases (warning)
+//   - GR-026: the registry provides the node type versions in requires.node_types
+//
+// Nodes using a deprecated alias are validated as their replacement type.
 func (gd *GraphDefinition) ValidateWithRegistry(reg *registry.Registry) []Diagnostic {
+	if reg != nil {
+		migrated, migrations, err := gd.MigrateNodeTypes(reg)
+		if err != nil || len(migrations) > 0 {
+			diags := gd.deprecatedTypeDiagnostics(migrations, err)
+			if err != nil {
+				return gd.annotateNodeDocs(append(gd.Validate(), diags...))
+			}
+			return gd.annotateNodeDocs(append(diags, migrated.ValidateWithRegistry(reg)...))
+		}
+	}
+
 	diags := gd.Validate()
 	if reg == nil {
 		return diags
@@ -232,8 +505,10 @@ func (gd *GraphDefinition) ValidateWithRegistry(reg *registry.Registry) []Diagno
 	// Collect node definitions for edge validation.
 	nodesByID := make(map[string]NodeDef, len(gd.Nodes))
 	defsByNodeID := make(map[string]registry.NodeTypeDef, len(gd.Nodes))
+	// Subgraph nodes keep the source handles of the nodes they replaced.
 	dynamicOutputs := map[string]bool{
-		"conditional": true,
+		"conditional":    true,
+		SubgraphNodeType: true,
 	}
 
 	for i, node := range gd.Nodes {
@@ -275,6 +550,9 @@ func (gd *GraphDefinition) ValidateWithRegistry(reg *registry.Registry) []Diagno
 		if dynamicOutputs[srcNode.Type] {
 			continue
 		}
+		if _, asserts := srcNode.Config["assert"]; asserts && edge.SourceHandle == AssertErrorPort {
+			continue
+		}
 
 		srcDef, ok := defsByNodeID[edge.Source]
 		if !ok {
@@ -291,6 +569,23 @@ func (gd *GraphDefinition) ValidateWithRegistry(reg *registry.Registry) []Diagno
 		}
 	}
 
+	// GR-013: nodes with external side effects should say whether they are
+	// safe to re-execute on resume or retry.
+	for i, node := range gd.Nodes {
+		def, ok := defsByNodeID[node.ID]
+		if !ok || (node.Type != "webhook_call" && (!def.IsTool || def.ToolMode == "function_call")) {
+			continue
+		}
+		if _, declared := node.Config["idempotent"]; !declared {
+			diags = append(diags, Diagnostic{
+				Code:     "GR-013",
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("Node %q (%s) has side effects but does not declare idempotent; it will not re-execute on resume or retry", node.ID, node.Type),
+				Path:     fmt.Sprintf("nodes[%d].config.idempotent", i),
+			})
+		}
+	}
+
 	// GR-009: webhook_trigger nodes must not have inbound edges.
 	inboundCount := make(map[string]int, len(gd.Nodes))
 	for _, edge := range gd.Edges {
@@ -310,7 +605,17 @@ func (gd *GraphDefinition) ValidateWithRegistry(reg *registry.Registry) []Diagno
 		}
 	}
 
-	return diags
+	// GR-021: subgraph nodes carry a valid graph.
+	for i, node := range gd.Nodes {
+		if node.Type == SubgraphNodeType {
+			diags = append(diags, subgraphDiagnostics(i, node, reg)...)
+		}
+	}
+
+	// GR-026: required node type versions are available.
+	diags = append(diags, gd.validateRequiredNodeTypes(reg)...)
+
+	return gd.annotateNodeDocs(diags)
 }
 
 func hasPortName(ports []registry.PortDef, name string) bool {
@@ -556,28 +861,21 @@ func (gd *GraphDefinition) ToGraph(opts ...BuildOption) (*BasicGraph, error) {
 		if err := g.AddEdge(ed.Source, ed.Target); err != nil {
 			return nil, fmt.Errorf("adding edge %s -> %s: %w", ed.Source, ed.Target, err)
 		}
-	}
-
-	// Resolve entry node
-	entry := gd.Entry
-	if entry == "" && len(gd.Nodes) > 0 {
-		// Default: first node with no inbound edges
-		hasInbound := make(map[string]bool)
-		for _, ed := range gd.Edges {
-			hasInbound[ed.Target] = true
-		}
-		for _, nd := range gd.Nodes {
-			if !hasInbound[nd.ID] {
-				entry = nd.ID
-				break
+		if len(ed.Vars) > 0 {
+			vars := append(g.EdgeVars(ed.Source, ed.Target), ed.Vars...)
+			if err := g.SetEdgeVars(ed.Source, ed.Target, vars); err != nil {
+				return nil, err
 			}
 		}
-		// Fallback: first node
-		if entry == "" {
-			entry = gd.Nodes[0].ID
+		if ed.Branch != nil {
+			if err := g.SetEdgeBranch(ed.Source, ed.Target, ed.Branch); err != nil {
+				return nil, err
+			}
 		}
 	}
-	if entry != "" {
+
+	// Resolve entry node
+	if entry := gd.EntryNode(); entry != "" {
 		if err := g.SetEntry(entry); err != nil {
 			return nil, fmt.Errorf("setting entry node %q: %w", entry, err)
 		}
@@ -585,3 +883,21 @@ func (gd *GraphDefinition) ToGraph(opts ...BuildOption) (*BasicGraph, error) {
 
 	return g, nil
 }
+
+// EntryNode returns the node a run starts at: Entry, or else the first
+// node without inbound edges, or else the first node.
+func (gd *GraphDefinition) EntryNode() string {
+	if gd.Entry != "" || len(gd.Nodes) == 0 {
+		return gd.Entry
+	}
+	hasInbound := make(map[string]bool)
+	for _, ed := range gd.Edges {
+		hasInbound[ed.Target] = true
+	}
+	for _, nd := range gd.Nodes {
+		if !hasInbound[nd.ID] {
+			return nd.ID
+		}
+	}
+	return gd.Nodes[0].ID
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/mask"
 	"github.com/petal-labs/petalflow/registry"
 )
 
@@ -403,6 +404,60 @@ func TestValidate_GR007_EmptyEntryIsOK(t *testing.T) {
 	}
 }
 
+func TestValidate_GR011_InvalidMaskingPolicy(t *testing.T) {
+	gd := GraphDefinition{
+		ID:      "bad_masking",
+		Version: "1.0",
+		Nodes:   []NodeDef{{ID: "a", Type: "noop"}},
+		Edges:   []EdgeDef{},
+		Masking: &mask.Policy{Rules: []mask.Rule{{Field: "email", Strategy: "redact"}}},
+	}
+
+	diags := gd.Validate()
+	found := findDiag(diags, "GR-011")
+	if found == nil {
+		t.Fatal("expected GR-011 diagnostic for invalid masking strategy")
+	}
+	if found.Path != "masking" {
+		t.Errorf("GR-011 path = %q, want %q", found.Path, "masking")
+	}
+
+	gd.Masking.Rules[0].Strategy = mask.StrategyHash
+	if findDiag(gd.Validate(), "GR-011") != nil {
+		t.Error("valid masking policy should not trigger GR-011")
+	}
+}
+
+func TestValidate_GR012_Simulation(t *testing.T) {
+	gd := GraphDefinition{
+		ID:      "simulated",
+		Version: "1.0",
+		Nodes:   []NodeDef{{ID: "a", Type: "llm_prompt"}},
+		Edges:   []EdgeDef{},
+		Simulate: &SimulationDef{Nodes: map[string]SimulatedResponse{
+			"a": {Response: "hi", Template: "{{.prompt}}"},
+		}},
+	}
+
+	found := findDiag(gd.Validate(), "GR-012")
+	if found == nil {
+		t.Fatal("expected GR-012 diagnostic for ambiguous simulated response")
+	}
+	if found.Path != "simulate.nodes.a" {
+		t.Errorf("GR-012 path = %q, want %q", found.Path, "simulate.nodes.a")
+	}
+
+	gd.Simulate.Nodes = map[string]SimulatedResponse{"missing": {Response: "hi"}}
+	if findDiag(gd.Validate(), "GR-012") == nil {
+		t.Error("expected GR-012 diagnostic for unknown node")
+	}
+
+	gd.Simulate.Nodes = map[string]SimulatedResponse{"a": {Choices: []any{"x", "y"}}}
+	if findDiag(gd.Validate(), "GR-012") != nil {
+		t.Error("valid simulation should not trigger GR-012")
+	}
+}
+
 func TestValidate_MultipleErrors(t *testing.T) {
 	gd := GraphDefinition{
 		ID:      "many_errors",
@@ -485,6 +540,42 @@ func TestValidateWithRegistry_GR008_FunctionCallToolAsNode(t *testing.T) {
 	}
 }
 
+func TestValidateWithRegistry_GR013_UndeclaredIdempotency(t *testing.T) {
+	reg := registry.Global()
+
+	gd := GraphDefinition{
+		ID:      "side_effects",
+		Version: "1.0",
+		Nodes: []NodeDef{
+			{ID: "notify", Type: "webhook_call", Config: map[string]any{"url": "https://example.com"}},
+			{ID: "ping", Type: "webhook_call", Config: map[string]any{"url": "https://example.com", "idempotent": true}},
+			{ID: "done", Type: "noop"},
+		},
+		Edges: []EdgeDef{{Source: "notify", Target: "ping"}, {Source: "ping", Target: "done"}},
+		Entry: "notify",
+	}
+
+	var gr013 []Diagnostic
+	for _, d := range gd.ValidateWithRegistry(reg) {
+		if d.Code == "GR-013" {
+			gr013 = append(gr013, d)
+		}
+	}
+	if len(gr013) != 1 || gr013[0].Severity != SeverityWarning || gr013[0].Path != "nodes[0].config.idempotent" {
+		t.Fatalf("GR-013 diagnostics = %+v, want one warning for notify", gr013)
+	}
+
+	if got := gd.IdempotentNodes(); len(got) != 1 || !got["ping"] {
+		t.Errorf("IdempotentNodes() = %v, want only ping", got)
+	}
+
+	gd.Nodes[1].Config["idempotent"] = "yes"
+	found := findDiag(gd.Validate(), "GR-013")
+	if found == nil || found.Severity != SeverityError {
+		t.Fatalf("expected GR-013 error for non-boolean idempotent, got %+v", found)
+	}
+}
+
 func TestValidateWithRegistry_GR009_WebhookTriggerHasInboundEdge(t *testing.T) {
 	reg := registry.Global()
 
@@ -759,3 +850,154 @@ func findDiag(diags []Diagnostic, code string) *Diagnostic {
 	}
 	return nil
 }
+
+func TestValidateWithRegistry_GR014_DeprecatedNodeType(t *testing.T) {
+	reg := registry.Global()
+
+	gd := GraphDefinition{
+		ID:      "legacy",
+		Version: "1.0",
+		Nodes: []NodeDef{
+			{ID: "start", Type: "noop"},
+			{ID: "notify", Type: "webhook_send", Config: map[string]any{"endpoint": "https://example.com", "idempotent": true}},
+		},
+		Edges: []EdgeDef{{Source: "start", Target: "notify"}},
+		Entry: "start",
+	}
+
+	diags := gd.ValidateWithRegistry(reg)
+	found := findDiag(diags, "GR-014")
+	if found == nil || found.Severity != SeverityWarning || found.Path != "nodes[1].type" {
+		t.Fatalf("expected GR-014 warning for notify, got %+v", diags)
+	}
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			t.Errorf("deprecated node should validate as webhook_call, got error %+v", d)
+		}
+	}
+
+	migrated, migrations, err := gd.MigrateNodeTypes(reg)
+	if err != nil {
+		t.Fatalf("MigrateNodeTypes: %v", err)
+	}
+	if len(migrations) != 1 || migrations[0].From != "webhook_send" || migrations[0].To != "webhook_call" {
+		t.Fatalf("migrations = %+v", migrations)
+	}
+	if node := migrated.Nodes[1]; node.Type != "webhook_call" || node.Config["url"] != "https://example.com" {
+		t.Errorf("migrated node = %+v", node)
+	}
+	if gd.Nodes[1].Type != "webhook_send" {
+		t.Error("MigrateNodeTypes must not modify the receiver")
+	}
+}
+
+func TestValidate_NodeDocs(t *testing.T) {
+	gd := GraphDefinition{
+		ID:      "docs",
+		Version: "1.0",
+		Nodes: []NodeDef{
+			{ID: "start", Type: "noop"},
+			{ID: "enrich", Type: "noop", Description: "Adds CRM fields to the customer", DocURL: "https://runbooks.example.com/enrich"},
+			{ID: "notify", Type: "noop", DocURL: "runbooks/notify.md"},
+		},
+		Edges: []EdgeDef{{Source: "start", Target: "enrich"}, {Source: "enrich", Target: "missing"}},
+		Entry: "start",
+	}
+
+	diags := gd.Validate()
+	gr015 := findDiag(diags, "GR-015")
+	if gr015 == nil || gr015.Severity != SeverityWarning || gr015.Node != "notify" || gr015.DocURL != "runbooks/notify.md" {
+		t.Fatalf("expected GR-015 warning for notify, got %+v", diags)
+	}
+	gr001 := findDiag(diags, "GR-001")
+	if gr001 == nil {
+		t.Fatalf("expected GR-001, got %+v", diags)
+	}
+	if gr001.Node != "" {
+		t.Errorf("unknown edge target annotated with node %q", gr001.Node)
+	}
+	for _, d := range diags {
+		if d.Code == "GR-002" && d.Node == "enrich" {
+			t.Errorf("enrich is connected, got %+v", d)
+		}
+	}
+
+	docs := gd.NodeDocs()
+	if len(docs) != 2 || docs["enrich"].Description != "Adds CRM fields to the customer" {
+		t.Fatalf("NodeDocs() = %+v", docs)
+	}
+	if _, ok := docs["start"]; ok {
+		t.Error("undocumented node in NodeDocs()")
+	}
+}
+
+func TestAnnotateNodeDocs_EdgeEndpoints(t *testing.T) {
+	gd := GraphDefinition{
+		Nodes: []NodeDef{
+			{ID: "a", Type: "noop", Description: "first"},
+			{ID: "b", Type: "noop", Description: "second", DocURL: "https://example.com/b"},
+		},
+		Edges: []EdgeDef{{Source: "a", SourceHandle: "nope", Target: "b"}},
+	}
+	diags := gd.annotateNodeDocs([]Diagnostic{
+		{Code: "X", Path: "edges[0].sourceHandle"},
+		{Code: "Y", Path: "edges[0].target"},
+		{Code: "Z", Path: "nodes[1].config.idempotent"},
+		{Code: "W", Path: "masking"},
+	})
+	if diags[0].Node != "a" || diags[0].NodeDescription != "first" {
+		t.Errorf("source handle diag = %+v", diags[0])
+	}
+	if diags[1].Node != "b" || diags[1].DocURL != "https://example.com/b" {
+		t.Errorf("target diag = %+v", diags[1])
+	}
+	if diags[2].Node != "b" {
+		t.Errorf("node diag = %+v", diags[2])
+	}
+	if diags[3].Node != "" {
+		t.Errorf("graph-level diag annotated: %+v", diags[3])
+	}
+}
+
+func TestMigrateNodeTypes_KeepsNodeDocs(t *testing.T) {
+	gd := GraphDefinition{
+		ID: "legacy",
+		Nodes: []NodeDef{
+			{ID: "notify", Type: "webhook_send", Description: "Tells the CRM", DocURL: "https://example.com/notify",
+				Config: map[string]any{"endpoint": "https://example.com"}},
+		},
+	}
+	migrated, _, err := gd.MigrateNodeTypes(registry.Global())
+	if err != nil {
+		t.Fatalf("MigrateNodeTypes: %v", err)
+	}
+	if node := migrated.Nodes[0]; node.Description != "Tells the CRM" || node.DocURL != "https://example.com/notify" {
+		t.Errorf("migrated node = %+v", node)
+	}
+}
+
+func TestValidate_VarLifetimes(t *testing.T) {
+	gd := GraphDefinition{
+		ID:      "vars",
+		Version: "1.0",
+		Nodes:   []NodeDef{{ID: "start", Type: "noop"}},
+		Entry:   "start",
+		Vars: map[string]VarLifetime{
+			"raw_body": {Scope: VarScopeNode, DropBeforePersist: true},
+			"draft":    {Scope: VarScopeBranch},
+			"summary":  {Scope: VarScopeRun},
+			"blob":     {Scope: "step"},
+		},
+	}
+
+	diags := gd.Validate()
+	gr016 := findDiag(diags, "GR-016")
+	if gr016 == nil || gr016.Severity != SeverityError || gr016.Path != "vars.blob.scope" {
+		t.Fatalf("expected GR-016 for blob, got %+v", diags)
+	}
+
+	lifetimes := gd.VarLifetimes()
+	if _, ok := lifetimes["summary"]; ok || len(lifetimes) != 3 {
+		t.Errorf("VarLifetimes() = %+v, want run-scoped persisted vars left out", lifetimes)
+	}
+}
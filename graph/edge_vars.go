@@ -0,0 +1,216 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// Variable mapping transforms.
+const (
+	// VarTransformString formats the value as a string; objects and lists
+	// become JSON.
+	VarTransformString = "string"
+	// VarTransformNumber parses a string as a number.
+	VarTransformNumber = "number"
+	// VarTransformBool parses a string as a boolean.
+	VarTransformBool = "bool"
+	// VarTransformJSON parses a string as JSON.
+	VarTransformJSON = "json"
+	// VarTransformLower lowercases a string.
+	VarTransformLower = "lower"
+	// VarTransformUpper uppercases a string.
+	VarTransformUpper = "upper"
+	// VarTransformTrim trims surrounding whitespace from a string.
+	VarTransformTrim = "trim"
+)
+
+var varTransforms = []string{
+	VarTransformString, VarTransformNumber, VarTransformBool, VarTransformJSON,
+	VarTransformLower, VarTransformUpper, VarTransformTrim,
+}
+
+// VarMapping copies a variable when the run traverses an edge, so the
+// target node reads it under its own name whatever the source calls it.
+// It may be written as the string "from -> to".
+type VarMapping struct {
+	// From is the variable to read, as a dotted path into the envelope
+	// vars.
+	From string `json:"from"`
+	// To is the top-level variable the target node reads.
+	To string `json:"to"`
+	// Transform converts the value on the way; see the VarTransform
+	// constants.
+	Transform string `json:"transform,omitempty"`
+	// Default is used when From is missing. Without one, a missing From
+	// fails the run.
+	Default any `json:"default,omitempty"`
+}
+
+// UnmarshalJSON accepts the object form and the "from -> to" shorthand.
+func (m *VarMapping) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		from, to, ok := strings.Cut(s, "->")
+		if !ok {
+			return fmt.Errorf("variable mapping %q must have the form \"from -> to\"", s)
+		}
+		*m = VarMapping{From: strings.TrimSpace(from), To: strings.TrimSpace(to)}
+		return nil
+	}
+	type plain VarMapping
+	return json.Unmarshal(data, (*plain)(m))
+}
+
+// String returns the mapping in its shorthand form.
+func (m VarMapping) String() string {
+	return m.From + " -> " + m.To
+}
+
+// EdgeVarsGraph is implemented by graphs whose edges carry variable
+// mappings. BasicGraph implements it.
+type EdgeVarsGraph interface {
+	// EdgeVars returns the mappings applied when the run moves from one
+	// node to the next.
+	EdgeVars(from, to string) []VarMapping
+}
+
+// MissingEdgeVarError reports a mapped variable the envelope lacked when
+// an edge was traversed.
+type MissingEdgeVarError struct {
+	From, To string
+	Var      string
+}
+
+func (e *MissingEdgeVarError) Error() string {
+	return fmt.Sprintf("edge %s -> %s maps variable %q, which is not set", e.From, e.To, e.Var)
+}
+
+// ApplyEdgeVars applies the mappings of the edge from -> to to env. All
+// values are read before any is written, so mappings may swap variables.
+func ApplyEdgeVars(env *core.Envelope, from, to string, mappings []VarMapping) error {
+	if len(mappings) == 0 {
+		return nil
+	}
+	values := make([]any, len(mappings))
+	for i, m := range mappings {
+		value, ok := env.GetVarNested(m.From)
+		if !ok || value == nil {
+			if m.Default == nil {
+				return &MissingEdgeVarError{From: from, To: to, Var: m.From}
+			}
+			value = m.Default
+		}
+		value, err := transformVar(value, m.Transform)
+		if err != nil {
+			return fmt.Errorf("edge %s -> %s: %s: %w", from, to, m, err)
+		}
+		values[i] = value
+	}
+	for i, m := range mappings {
+		env.SetVar(m.To, values[i])
+	}
+	return nil
+}
+
+func transformVar(value any, transform string) (any, error) {
+	switch transform {
+	case "":
+		return value, nil
+	case VarTransformString:
+		if s, ok := value.(string); ok {
+			return s, nil
+		}
+		switch value.(type) {
+		case map[string]any, []any:
+			data, err := json.Marshal(value)
+			return string(data), err
+		}
+		return fmt.Sprint(value), nil
+	case VarTransformJSON:
+		s, ok := value.(string)
+		if !ok {
+			return value, nil
+		}
+		var out any
+		if err := json.Unmarshal([]byte(s), &out); err != nil {
+			return nil, fmt.Errorf("value is not JSON: %w", err)
+		}
+		return out, nil
+	}
+
+	s, ok := value.(string)
+	if !ok {
+		switch transform {
+		case VarTransformNumber:
+			switch value.(type) {
+			case int, int64, float64:
+				return value, nil
+			}
+		case VarTransformBool:
+			if _, ok := value.(bool); ok {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("transform %s needs a string, got %T", transform, value)
+	}
+	switch transform {
+	case VarTransformNumber:
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", s)
+		}
+		return f, nil
+	case VarTransformBool:
+		b, err := strconv.ParseBool(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", s)
+		}
+		return b, nil
+	case VarTransformLower:
+		return strings.ToLower(s), nil
+	case VarTransformUpper:
+		return strings.ToUpper(s), nil
+	case VarTransformTrim:
+		return strings.TrimSpace(s), nil
+	default:
+		return nil, fmt.Errorf("unknown transform %q", transform)
+	}
+}
+
+// validateEdgeVars checks GR-022: edge variable mappings name a source
+// and a top-level target variable, use a known transform, and write each
+// target once.
+func (gd *GraphDefinition) validateEdgeVars() []Diagnostic {
+	var diags []Diagnostic
+	for i, edge := range gd.Edges {
+		written := make(map[string]bool, len(edge.Vars))
+		for j, m := range edge.Vars {
+			path := fmt.Sprintf("edges[%d].vars[%d]", i, j)
+			fail := func(msg string) {
+				diags = append(diags, Diagnostic{
+					Code:     "GR-022",
+					Severity: SeverityError,
+					Message:  fmt.Sprintf("Edge %s -> %s: %s", edge.Source, edge.Target, msg),
+					Path:     path,
+				})
+			}
+			switch {
+			case m.From == "" || m.To == "":
+				fail("variable mappings need a from and a to variable")
+			case strings.Contains(m.To, "."):
+				fail(fmt.Sprintf("mapping target %q must be a top-level variable name", m.To))
+			case written[m.To]:
+				fail(fmt.Sprintf("variable %q is mapped more than once", m.To))
+			case m.Transform != "" && !slices.Contains(varTransforms, m.Transform):
+				fail(fmt.Sprintf("unknown transform %q; use one of: %s", m.Transform, strings.Join(varTransforms, ", ")))
+			}
+			written[m.To] = true
+		}
+	}
+	return diags
+}
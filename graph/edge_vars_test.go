@@ -0,0 +1,119 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func TestVarMapping_UnmarshalJSON(t *testing.T) {
+	var edge EdgeDef
+	data := `{"source": "a", "sourceHandle": "output", "target": "b", "targetHandle": "input",
+		"vars": ["ticket.body -> text", {"from": "score", "to": "threshold", "transform": "number", "default": "0.5"}]}`
+	if err := json.Unmarshal([]byte(data), &edge); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	want := []VarMapping{
+		{From: "ticket.body", To: "text"},
+		{From: "score", To: "threshold", Transform: VarTransformNumber, Default: "0.5"},
+	}
+	if len(edge.Vars) != 2 || edge.Vars[0] != want[0] || edge.Vars[1] != want[1] {
+		t.Errorf("Vars = %+v, want %+v", edge.Vars, want)
+	}
+
+	var m VarMapping
+	if err := json.Unmarshal([]byte(`"no arrow"`), &m); err == nil {
+		t.Error("expected an error for a shorthand without ->")
+	}
+}
+
+func TestApplyEdgeVars(t *testing.T) {
+	env := core.NewEnvelope()
+	env.SetVar("a", "first")
+	env.SetVar("b", "second")
+	env.SetVar("doc", map[string]any{"count": "3", "meta": map[string]any{"ok": true}})
+
+	err := ApplyEdgeVars(env, "x", "y", []VarMapping{
+		{From: "a", To: "b"},
+		{From: "b", To: "a"},
+		{From: "doc.count", To: "count", Transform: VarTransformNumber},
+		{From: "doc.meta", To: "meta", Transform: VarTransformString},
+		{From: "missing", To: "fallback", Default: "none"},
+	})
+	if err != nil {
+		t.Fatalf("ApplyEdgeVars: %v", err)
+	}
+	want := map[string]any{"a": "second", "b": "first", "count": 3.0, "meta": `{"ok":true}`, "fallback": "none"}
+	for name, value := range want {
+		if got, _ := env.GetVar(name); got != value {
+			t.Errorf("%s = %#v, want %#v", name, got, value)
+		}
+	}
+
+	if err := ApplyEdgeVars(env, "x", "y", []VarMapping{{From: "a", To: "n", Transform: VarTransformNumber}}); err == nil {
+		t.Error("expected an error for a non-numeric string")
+	}
+}
+
+func TestValidate_GR022_EdgeVars(t *testing.T) {
+	gd := GraphDefinition{
+		ID:      "edge_vars",
+		Version: "1.0",
+		Nodes:   []NodeDef{{ID: "a", Type: "noop"}, {ID: "b", Type: "noop"}},
+		Edges: []EdgeDef{{
+			Source: "a", SourceHandle: "output", Target: "b", TargetHandle: "input",
+			Vars: []VarMapping{{From: "x", To: "y"}},
+		}},
+		Entry: "a",
+	}
+	if found := findDiag(gd.Validate(), "GR-022"); found != nil {
+		t.Fatalf("unexpected GR-022: %+v", found)
+	}
+
+	for _, bad := range []VarMapping{
+		{From: "", To: "y"},
+		{From: "x", To: "out.y"},
+		{From: "x", To: "y", Transform: "reverse"},
+	} {
+		gd.Edges[0].Vars = []VarMapping{bad}
+		found := findDiag(gd.Validate(), "GR-022")
+		if found == nil {
+			t.Errorf("%+v: expected GR-022", bad)
+			continue
+		}
+		if found.Path != "edges[0].vars[0]" {
+			t.Errorf("Path = %q", found.Path)
+		}
+	}
+
+	gd.Edges[0].Vars = []VarMapping{{From: "x", To: "y"}, {From: "z", To: "y"}}
+	if findDiag(gd.Validate(), "GR-022") == nil {
+		t.Error("expected GR-022 for a variable mapped twice")
+	}
+}
+
+func TestToGraph_EdgeVars(t *testing.T) {
+	gd := GraphDefinition{
+		ID:      "edge_vars",
+		Version: "1.0",
+		Nodes:   []NodeDef{{ID: "a", Type: "noop"}, {ID: "b", Type: "noop"}},
+		Edges: []EdgeDef{{
+			Source: "a", SourceHandle: "output", Target: "b", TargetHandle: "input",
+			Vars: []VarMapping{{From: "x", To: "y"}},
+		}},
+		Entry: "a",
+	}
+	g, err := gd.ToGraph(WithNodeFactory(func(nd NodeDef) (core.Node, error) {
+		return core.NewNoopNode(nd.ID), nil
+	}))
+	if err != nil {
+		t.Fatalf("ToGraph: %v", err)
+	}
+	if vars := g.EdgeVars("a", "b"); len(vars) != 1 || vars[0].To != "y" {
+		t.Errorf("EdgeVars = %+v", vars)
+	}
+	if err := g.SetEdgeVars("b", "a", nil); err == nil {
+		t.Error("expected an error for an edge that does not exist")
+	}
+}
@@ -4,6 +4,7 @@ package graph
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/petal-labs/petalflow/core"
 )
@@ -59,6 +60,8 @@ type BasicGraph struct {
 	edges        []Edge
 	successors   map[string][]string // node ID -> successor IDs
 	predecessors map[string][]string // node ID -> predecessor IDs
+	edgeVars     map[Edge][]VarMapping
+	edgeBranches map[Edge]*BranchPolicy
 	entry        string
 }
 
@@ -164,6 +167,46 @@ func (g *BasicGraph) AddEdge(from, to string) error {
 	return nil
 }
 
+// SetEdgeVars sets the variable mappings applied when the run moves from
+// one node to the other. The edge must already exist.
+func (g *BasicGraph) SetEdgeVars(from, to string, vars []VarMapping) error {
+	edge := Edge{From: from, To: to}
+	if !slices.Contains(g.edges, edge) {
+		return fmt.Errorf("%w: no edge %s -> %s", ErrInvalidEdge, from, to)
+	}
+	if g.edgeVars == nil {
+		g.edgeVars = make(map[Edge][]VarMapping)
+	}
+	g.edgeVars[edge] = vars
+	return nil
+}
+
+// EdgeVars returns the variable mappings of the edge from one node to the
+// other.
+func (g *BasicGraph) EdgeVars(from, to string) []VarMapping {
+	return g.edgeVars[Edge{From: from, To: to}]
+}
+
+// SetEdgeBranch sets the policy of the branch the edge from one node to
+// the other starts. The edge must already exist.
+func (g *BasicGraph) SetEdgeBranch(from, to string, policy *BranchPolicy) error {
+	edge := Edge{From: from, To: to}
+	if !slices.Contains(g.edges, edge) {
+		return fmt.Errorf("%w: no edge %s -> %s", ErrInvalidEdge, from, to)
+	}
+	if g.edgeBranches == nil {
+		g.edgeBranches = make(map[Edge]*BranchPolicy)
+	}
+	g.edgeBranches[edge] = policy
+	return nil
+}
+
+// EdgeBranch returns the branch policy of the edge from one node to the
+// other, or nil.
+func (g *BasicGraph) EdgeBranch(from, to string) *BranchPolicy {
+	return g.edgeBranches[Edge{From: from, To: to}]
+}
+
 // SetEntry sets the entry node for execution.
 // The node must already exist in the graph.
 func (g *BasicGraph) SetEntry(nodeID string) error {
@@ -0,0 +1,185 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"slices"
+)
+
+// LLMDefaults are LLM settings a workflow declares once for all of its LLM
+// nodes. A node inherits each setting it does not configure itself.
+type LLMDefaults struct {
+	Provider string `json:"provider,omitempty"`
+	// Model is only inherited by nodes that use Provider, whether they
+	// inherit it or name it themselves.
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   int      `json:"max_tokens,omitempty"`
+	// SystemPrompt is the system preamble of nodes without a
+	// system_prompt of their own.
+	SystemPrompt string `json:"system_prompt,omitempty"`
+}
+
+// llmSettingKeys lists, by node type, the config keys an LLM node inherits
+// from LLMDefaults. model_select nodes take provider and model from their
+// arms, and a compact_messages max_tokens is a history budget, not an
+// output limit.
+var llmSettingKeys = map[string][]string{
+	"llm_prompt":       {"provider", "model", "temperature", "max_tokens", "system_prompt"},
+	"llm_router":       {"provider", "model", "temperature", "system_prompt"},
+	"model_select":     {"temperature", "max_tokens", "system_prompt"},
+	"compact_messages": {"provider", "model"},
+	"translate":        {"provider", "model"},
+	"summarize":        {"provider", "model"},
+	"classify":         {"provider", "model"},
+	"extract_entities": {"provider", "model"},
+}
+
+// IsLLMNodeType reports whether nodes of type nodeType call an LLM
+// provider.
+func IsLLMNodeType(nodeType string) bool {
+	_, ok := llmSettingKeys[nodeType]
+	return ok
+}
+
+// LLMSettings are the effective LLM settings of a node, after LLMDefaults.
+type LLMSettings struct {
+	NodeID       string   `json:"node_id"`
+	NodeType     string   `json:"node_type"`
+	Provider     string   `json:"provider,omitempty"`
+	Model        string   `json:"model,omitempty"`
+	Temperature  *float64 `json:"temperature,omitempty"`
+	MaxTokens    int      `json:"max_tokens,omitempty"`
+	SystemPrompt string   `json:"system_prompt,omitempty"`
+	// Inherited lists the config keys taken from llm_defaults.
+	Inherited []string `json:"inherited,omitempty"`
+}
+
+// ApplyLLMDefaults returns a copy of gd whose LLM nodes carry the settings
+// they inherit from LLMDefaults in their config. gd itself is returned when
+// it declares no defaults.
+func (gd *GraphDefinition) ApplyLLMDefaults() *GraphDefinition {
+	if gd == nil || gd.LLMDefaults == nil {
+		return gd
+	}
+	out := *gd
+	out.Nodes = make([]NodeDef, len(gd.Nodes))
+	for i, nd := range gd.Nodes {
+		inherited := gd.inheritedLLMSettings(nd)
+		if len(inherited) > 0 {
+			config := make(map[string]any, len(nd.Config)+len(inherited))
+			for k, v := range nd.Config {
+				config[k] = v
+			}
+			for k, v := range inherited {
+				config[k] = v
+			}
+			nd.Config = config
+		}
+		out.Nodes[i] = nd
+	}
+	return &out
+}
+
+// LLMSettings reports the effective settings of gd's LLM nodes, in node
+// order.
+func (gd *GraphDefinition) LLMSettings() []LLMSettings {
+	var out []LLMSettings
+	for _, nd := range gd.Nodes {
+		if _, ok := llmSettingKeys[nd.Type]; !ok {
+			continue
+		}
+		inherited := gd.inheritedLLMSettings(nd)
+		value := func(key string) any {
+			if v, ok := inherited[key]; ok {
+				return v
+			}
+			return nd.Config[key]
+		}
+		s := LLMSettings{NodeID: nd.ID, NodeType: nd.Type}
+		s.Provider, _ = value("provider").(string)
+		s.Model, _ = value("model").(string)
+		s.SystemPrompt, _ = value("system_prompt").(string)
+		if v, ok := value("temperature").(float64); ok {
+			s.Temperature = &v
+		}
+		if v, ok := value("max_tokens").(float64); ok {
+			s.MaxTokens = int(v)
+		}
+		for _, key := range llmSettingKeys[nd.Type] {
+			if _, ok := inherited[key]; ok {
+				s.Inherited = append(s.Inherited, key)
+			}
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// inheritedLLMSettings returns the config values nd takes from
+// LLMDefaults, keyed by config key.
+func (gd *GraphDefinition) inheritedLLMSettings(nd NodeDef) map[string]any {
+	d := gd.LLMDefaults
+	keys, ok := llmSettingKeys[nd.Type]
+	if d == nil || !ok {
+		return nil
+	}
+	inherited := make(map[string]any)
+	set := func(key string, value any) {
+		if !slices.Contains(keys, key) {
+			return
+		}
+		if current, ok := nd.Config[key]; ok && current != nil && current != "" {
+			return
+		}
+		inherited[key] = value
+	}
+	if d.Provider != "" {
+		set("provider", d.Provider)
+	}
+	if d.Model != "" {
+		provider, _ := nd.Config["provider"].(string)
+		if provider == "" || provider == d.Provider {
+			set("model", d.Model)
+		}
+	}
+	if d.Temperature != nil {
+		set("temperature", *d.Temperature)
+	}
+	if d.MaxTokens > 0 {
+		// Config numbers are float64, as decoded from JSON.
+		set("max_tokens", float64(d.MaxTokens))
+	}
+	if d.SystemPrompt != "" {
+		set("system_prompt", d.SystemPrompt)
+	}
+	return inherited
+}
+
+// validateLLMDefaults checks GR-024: LLM defaults use non-negative sampling
+// settings and name a provider along with a model.
+func (gd *GraphDefinition) validateLLMDefaults() []Diagnostic {
+	d := gd.LLMDefaults
+	if d == nil {
+		return nil
+	}
+	var diags []Diagnostic
+	fail := func(path, format string, args ...any) {
+		diags = append(diags, Diagnostic{
+			Code:     "GR-024",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("LLM defaults: "+format, args...),
+			Path:     "llm_defaults." + path,
+		})
+	}
+	if d.Model != "" && d.Provider == "" {
+		fail("model", "model %q needs a provider", d.Model)
+	}
+	if t := d.Temperature; t != nil && (math.IsNaN(*t) || *t < 0) {
+		fail("temperature", "temperature must be >= 0")
+	}
+	if d.MaxTokens < 0 {
+		fail("max_tokens", "max_tokens must be >= 0")
+	}
+	return diags
+}
@@ -0,0 +1,102 @@
+package graph
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+func TestApplyLLMDefaults(t *testing.T) {
+	var gd GraphDefinition
+	data := `{
+		"id": "support", "version": "1.0", "entry": "answer",
+		"llm_defaults": {"provider": "anthropic", "model": "claude-haiku-4-5", "temperature": 0.2, "max_tokens": 1024, "system_prompt": "Be brief."},
+		"nodes": [
+			{"id": "answer", "type": "llm_prompt", "config": {"prompt_template": "{{.question}}"}},
+			{"id": "escalate", "type": "llm_prompt", "config": {"provider": "openai", "temperature": 0.7}},
+			{"id": "pick", "type": "model_select", "config": {"arms": [{"provider": "openai", "model": "gpt-4o-mini"}]}},
+			{"id": "compact", "type": "compact_messages", "config": {"max_tokens": 4000}},
+			{"id": "fetch", "type": "webhook_call", "config": {"url": "https://example.com"}}
+		],
+		"edges": []
+	}`
+	if err := json.Unmarshal([]byte(data), &gd); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if found := findDiag(gd.Validate(), "GR-024"); found != nil {
+		t.Fatalf("unexpected GR-024: %+v", found)
+	}
+
+	applied := gd.ApplyLLMDefaults()
+	answer := applied.Nodes[0].Config
+	if answer["provider"] != "anthropic" || answer["model"] != "claude-haiku-4-5" || answer["temperature"] != 0.2 ||
+		answer["max_tokens"] != float64(1024) || answer["system_prompt"] != "Be brief." {
+		t.Errorf("answer config = %v", answer)
+	}
+	if _, ok := gd.Nodes[0].Config["provider"]; ok {
+		t.Error("ApplyLLMDefaults modified the original definition")
+	}
+	escalate := applied.Nodes[1].Config
+	if escalate["provider"] != "openai" || escalate["temperature"] != 0.7 {
+		t.Errorf("escalate overrides lost: %v", escalate)
+	}
+	if _, ok := escalate["model"]; ok {
+		t.Errorf("escalate inherited the default model of another provider: %v", escalate)
+	}
+	if pick := applied.Nodes[2].Config; pick["provider"] != nil || pick["temperature"] != 0.2 {
+		t.Errorf("pick config = %v", pick)
+	}
+	if compact := applied.Nodes[3].Config; compact["max_tokens"] != float64(4000) || compact["provider"] != "anthropic" {
+		t.Errorf("compact config = %v", compact)
+	}
+	if _, ok := applied.Nodes[4].Config["provider"]; ok {
+		t.Error("webhook_call inherited LLM settings")
+	}
+
+	settings := gd.LLMSettings()
+	if len(settings) != 4 {
+		t.Fatalf("LLMSettings = %+v", settings)
+	}
+	if s := settings[0]; s.Provider != "anthropic" || s.MaxTokens != 1024 || s.Temperature == nil || *s.Temperature != 0.2 ||
+		!slices.Equal(s.Inherited, []string{"provider", "model", "temperature", "max_tokens", "system_prompt"}) {
+		t.Errorf("answer settings = %+v", s)
+	}
+	if s := settings[1]; s.Provider != "openai" || s.Model != "" || !slices.Equal(s.Inherited, []string{"max_tokens", "system_prompt"}) {
+		t.Errorf("escalate settings = %+v", s)
+	}
+}
+
+func TestValidate_GR024_LLMDefaults(t *testing.T) {
+	temperature := -0.5
+	gd := GraphDefinition{
+		ID:          "support",
+		Version:     "1.0",
+		Nodes:       []NodeDef{{ID: "answer", Type: "llm_prompt"}},
+		Edges:       []EdgeDef{},
+		Entry:       "answer",
+		LLMDefaults: &LLMDefaults{Model: "gpt-4o", Temperature: &temperature, MaxTokens: -1},
+	}
+	var paths []string
+	for _, d := range gd.Validate() {
+		if d.Code == "GR-024" {
+			paths = append(paths, d.Path)
+		}
+	}
+	want := []string{"llm_defaults.model", "llm_defaults.temperature", "llm_defaults.max_tokens"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("GR-024 paths = %v, want %v", paths, want)
+	}
+}
+
+func TestIsLLMNodeType(t *testing.T) {
+	for _, nodeType := range []string{"llm_prompt", "llm_router", "model_select", "compact_messages", "translate", "summarize", "classify", "extract_entities"} {
+		if !IsLLMNodeType(nodeType) {
+			t.Errorf("IsLLMNodeType(%q) = false", nodeType)
+		}
+	}
+	for _, nodeType := range []string{"tool", "rule_router", "transform", ""} {
+		if IsLLMNodeType(nodeType) {
+			t.Errorf("IsLLMNodeType(%q) = true", nodeType)
+		}
+	}
+}
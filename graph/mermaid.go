@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Mermaid renders the definition as a Mermaid flowchart. Node labels show
+// the node ID, its type and its description; nodes with a doc URL link to
+// it.
+func (gd *GraphDefinition) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+
+	ids := make(map[string]string, len(gd.Nodes))
+	for i, node := range gd.Nodes {
+		ids[node.ID] = fmt.Sprintf("n%d", i)
+	}
+	for _, node := range gd.Nodes {
+		label := mermaidText(node.ID) + "<br/><i>" + mermaidText(node.Type) + "</i>"
+		if node.Description != "" {
+			label += "<br/>" + mermaidText(node.Description)
+		}
+		fmt.Fprintf(&b, "    %s[\"%s\"]\n", ids[node.ID], label)
+		if mermaidLinkable(node.DocURL) {
+			fmt.Fprintf(&b, "    click %s href \"%s\" \"Open docs\" _blank\n", ids[node.ID], node.DocURL)
+		}
+	}
+	for _, edge := range gd.Edges {
+		from, ok := ids[edge.Source]
+		if !ok {
+			continue
+		}
+		to, ok := ids[edge.Target]
+		if !ok {
+			continue
+		}
+		if edge.SourceHandle != "" {
+			fmt.Fprintf(&b, "    %s -->|\"%s\"| %s\n", from, mermaidText(edge.SourceHandle), to)
+			continue
+		}
+		fmt.Fprintf(&b, "    %s --> %s\n", from, to)
+	}
+	return b.String()
+}
+
+var mermaidEscaper = strings.NewReplacer(
+	`"`, "#quot;",
+	"<", "#lt;",
+	">", "#gt;",
+	"\n", " ",
+)
+
+// mermaidLinkable reports whether raw can be the target of a click line: an
+// absolute http(s) URL made only of characters that need no escaping in a
+// quoted Mermaid string. Anything else is left unlinked rather than escaped.
+func mermaidLinkable(raw string) bool {
+	if !isHTTPURL(raw) {
+		return false
+	}
+	for _, r := range raw {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-._~:/?#[]@!$&'()*+,;=%", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// mermaidText escapes text for a quoted Mermaid label.
+func mermaidText(s string) string {
+	return mermaidEscaper.Replace(s)
+}
@@ -0,0 +1,42 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGraphDefinition_Mermaid(t *testing.T) {
+	gd := GraphDefinition{
+		ID: "support",
+		Nodes: []NodeDef{
+			{ID: "triage", Type: "llm_router", Description: `Routes "billing" questions`},
+			{ID: "billing", Type: "noop", DocURL: "https://runbooks.example.com/billing"},
+			{ID: "other", Type: "noop", DocURL: "not a url"},
+			{ID: "script", Type: "noop", DocURL: "javascript:alert(1)//https://example.com"},
+			{ID: "quoted", Type: "noop", DocURL: `https://example.com/?q=" onclick="x`},
+		},
+		Edges: []EdgeDef{
+			{Source: "triage", SourceHandle: "billing", Target: "billing"},
+			{Source: "triage", Target: "other"},
+			{Source: "triage", Target: "ghost"},
+		},
+	}
+
+	got := gd.Mermaid()
+	want := `flowchart TD
+    n0["triage<br/><i>llm_router</i><br/>Routes #quot;billing#quot; questions"]
+    n1["billing<br/><i>noop</i>"]
+    click n1 href "https://runbooks.example.com/billing" "Open docs" _blank
+    n2["other<br/><i>noop</i>"]
+    n3["script<br/><i>noop</i>"]
+    n4["quoted<br/><i>noop</i>"]
+    n0 -->|"billing"| n1
+    n0 --> n2
+`
+	if got != want {
+		t.Fatalf("Mermaid() =\n%s\nwant\n%s", got, want)
+	}
+	if strings.Contains(got, "ghost") {
+		t.Error("edge to unknown node rendered")
+	}
+}
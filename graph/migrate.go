@@ -0,0 +1,92 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/petal-labs/petalflow/registry"
+)
+
+// NodeTypeMigration records a node moved off a deprecated type.
+type NodeTypeMigration struct {
+	NodeID  string `json:"node_id"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Message string `json:"message,omitempty"`
+}
+
+// MigrateNodeTypes returns a copy of gd in which every node using a
+// deprecated type alias has its current type and a remapped config. The
+// receiver is not modified. When nothing needs migrating the original
+// definition is returned with no migrations.
+func (gd *GraphDefinition) MigrateNodeTypes(reg *registry.Registry) (*GraphDefinition, []NodeTypeMigration, error) {
+	if reg == nil {
+		return gd, nil, nil
+	}
+
+	var (
+		migrations []NodeTypeMigration
+		nodes      []NodeDef
+	)
+	for i, node := range gd.Nodes {
+		resolved, err := reg.ResolveType(node.Type, node.Config)
+		if err != nil {
+			return gd, nil, fmt.Errorf("node %q: %w", node.ID, err)
+		}
+		if len(resolved.Via) == 0 {
+			continue
+		}
+		if nodes == nil {
+			nodes = append([]NodeDef(nil), gd.Nodes...)
+		}
+		migrated := node
+		migrated.Type, migrated.Config = resolved.Type, resolved.Config
+		nodes[i] = migrated
+
+		migration := NodeTypeMigration{NodeID: node.ID, From: node.Type, To: resolved.Type}
+		for _, alias := range resolved.Via {
+			if alias.Message != "" {
+				migration.Message = alias.Message
+			}
+		}
+		migrations = append(migrations, migration)
+	}
+	if len(migrations) == 0 {
+		return gd, nil, nil
+	}
+
+	migrated := *gd
+	migrated.Nodes = nodes
+	return &migrated, migrations, nil
+}
+
+// deprecatedTypeDiagnostics reports GR-014 for every node still using a
+// deprecated type. A failed migration is an error.
+func (gd *GraphDefinition) deprecatedTypeDiagnostics(migrations []NodeTypeMigration, err error) []Diagnostic {
+	if err != nil {
+		return []Diagnostic{{
+			Code:     "GR-014",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Cannot migrate deprecated node type: %v", err),
+			Path:     "nodes",
+		}}
+	}
+
+	indexByID := make(map[string]int, len(gd.Nodes))
+	for i, node := range gd.Nodes {
+		indexByID[node.ID] = i
+	}
+	diags := make([]Diagnostic, 0, len(migrations))
+	for _, m := range migrations {
+		msg := fmt.Sprintf("Node %q uses deprecated type %q; use %q instead (petalflow migrate rewrites stored workflows)", m.NodeID, m.From, m.To)
+		if m.Message != "" {
+			msg += ": " + m.Message
+		}
+		diags = append(diags, Diagnostic{
+			Code:     "GR-014",
+			Severity: SeverityWarning,
+			Message:  msg,
+			Path:     fmt.Sprintf("nodes[%d].type", indexByID[m.NodeID]),
+		})
+	}
+	return diags
+}
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// InputPreset is a named run input saved with a workflow, such as a demo
+// customer or the input of a reported bug. Runs select presets by name.
+type InputPreset struct {
+	// Description says what the preset is for.
+	Description string `json:"description,omitempty"`
+	// Input holds envelope variables, like a run request's input.
+	Input map[string]any `json:"input"`
+}
+
+// MergePresetInputs layers run inputs in order: a variable set by a later
+// input replaces the same variable from an earlier one. Presets come first
+// and the run's own input last, so explicit values win.
+func MergePresetInputs(inputs ...map[string]any) map[string]any {
+	merged := make(map[string]any)
+	for _, input := range inputs {
+		for key, value := range input {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// ValidatePresetName checks that a preset name is not blank and carries no
+// surrounding whitespace.
+func ValidatePresetName(name string) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("preset name is required")
+	}
+	if strings.TrimSpace(name) != name {
+		return fmt.Errorf("preset name %q must not start or end with whitespace", name)
+	}
+	return nil
+}
+
+// validatePresets checks GR-019: preset names are well formed.
+func (gd *GraphDefinition) validatePresets() []Diagnostic {
+	names := make([]string, 0, len(gd.Presets))
+	for name := range gd.Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var diags []Diagnostic
+	for _, name := range names {
+		if err := ValidatePresetName(name); err != nil {
+			diags = append(diags, Diagnostic{
+				Code:     "GR-019",
+				Severity: SeverityError,
+				Message:  "Presets: " + err.Error(),
+				Path:     fmt.Sprintf("presets.%s", name),
+			})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,38 @@
+package graph
+
+import "testing"
+
+func TestMergePresetInputs_LaterInputsWin(t *testing.T) {
+	got := MergePresetInputs(
+		map[string]any{"customer": "acme", "tier": "free"},
+		nil,
+		map[string]any{"tier": "pro"},
+	)
+	if len(got) != 2 || got["customer"] != "acme" || got["tier"] != "pro" {
+		t.Errorf("MergePresetInputs() = %v", got)
+	}
+}
+
+func TestValidate_PresetNames(t *testing.T) {
+	gd := &GraphDefinition{
+		ID:      "presets",
+		Version: "1.0",
+		Nodes:   []NodeDef{{ID: "a", Type: "noop"}},
+		Entry:   "a",
+		Presets: map[string]InputPreset{
+			"demo customer": {Input: map[string]any{"customer": "acme"}},
+			" padded":       {},
+			"":              {},
+		},
+	}
+	var paths []string
+	for _, d := range gd.Validate() {
+		if d.Code == "GR-019" {
+			paths = append(paths, d.Path)
+		}
+	}
+	want := []string{"presets.", "presets. padded"}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("GR-019 paths = %q, want %q", paths, want)
+	}
+}
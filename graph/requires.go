@@ -0,0 +1,259 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/petal-labs/petalflow/registry"
+)
+
+// EngineVersion is the version of the workflow engine that workflow
+// requires.engine constraints are checked against. It moves with the
+// release version when the engine gains or changes a capability.
+const EngineVersion = "0.3.0"
+
+// Requirements are the engine and node type versions a workflow needs.
+// A daemon or CLI that does not meet them refuses to save or run the
+// workflow instead of running it with missing behavior.
+type Requirements struct {
+	// Engine is a version constraint on EngineVersion, such as ">=0.3".
+	Engine string `json:"engine,omitempty"`
+	// NodeTypes are version constraints on node types, keyed by type.
+	NodeTypes map[string]string `json:"node_types,omitempty"`
+}
+
+// CheckRequires reports the requirements the engine and reg do not meet,
+// for definitions validated before they were loaded, such as workflows a
+// newer daemon stored.
+func (gd *GraphDefinition) CheckRequires(reg *registry.Registry) []Diagnostic {
+	diags := gd.validateRequires()
+	if reg != nil {
+		diags = append(diags, gd.validateRequiredNodeTypes(reg)...)
+	}
+	return diags
+}
+
+// validateRequires checks that requirement constraints are well formed and
+// that the engine meets requires.engine (GR-025).
+func (gd *GraphDefinition) validateRequires() []Diagnostic {
+	if gd.Requires == nil {
+		return nil
+	}
+	var diags []Diagnostic
+	fail := func(path, format string, args ...any) {
+		diags = append(diags, Diagnostic{
+			Code:     "GR-025",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf(format, args...),
+			Path:     path,
+		})
+	}
+
+	if engine := gd.Requires.Engine; engine != "" {
+		constraint, err := ParseVersionConstraint(engine)
+		switch {
+		case err != nil:
+			fail("requires.engine", "Invalid requires.engine: %v", err)
+		case !constraint.Allows(mustParseVersion(EngineVersion)):
+			fail("requires.engine", "Workflow requires engine %s, but this engine is version %s", engine, EngineVersion)
+		}
+	}
+
+	for _, nodeType := range sortedKeys(gd.Requires.NodeTypes) {
+		if _, err := ParseVersionConstraint(gd.Requires.NodeTypes[nodeType]); err != nil {
+			fail("requires.node_types."+nodeType, "Invalid requires.node_types[%q]: %v", nodeType, err)
+		}
+	}
+	return diags
+}
+
+// validateRequiredNodeTypes checks requires.node_types against the node
+// types reg provides (GR-026). Malformed constraints are reported by
+// validateRequires.
+func (gd *GraphDefinition) validateRequiredNodeTypes(reg *registry.Registry) []Diagnostic {
+	if gd.Requires == nil {
+		return nil
+	}
+	var diags []Diagnostic
+	for _, nodeType := range sortedKeys(gd.Requires.NodeTypes) {
+		raw := gd.Requires.NodeTypes[nodeType]
+		constraint, err := ParseVersionConstraint(raw)
+		if err != nil {
+			continue
+		}
+		path := "requires.node_types." + nodeType
+		def, ok := reg.Get(nodeType)
+		if !ok {
+			diags = append(diags, Diagnostic{
+				Code:     "GR-026",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Workflow requires node type %q %s, but this engine does not provide it", nodeType, raw),
+				Path:     path,
+			})
+			continue
+		}
+		version, err := ParseVersion(def.Version)
+		if err != nil || !constraint.Allows(version) {
+			diags = append(diags, Diagnostic{
+				Code:     "GR-026",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Workflow requires node type %q %s, but this engine provides version %s", nodeType, raw, def.Version),
+				Path:     path,
+			})
+		}
+	}
+	return diags
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Version is a MAJOR.MINOR.PATCH version.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 as v is older than, the same as or newer
+// than o.
+func (v Version) Compare(o Version) int {
+	for _, d := range [3]int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d < 0 {
+			return -1
+		}
+		if d > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// ParseVersion parses a version such as "1.4.2", "v2" or "0.9". Missing
+// minor and patch numbers are zero.
+func ParseVersion(s string) (Version, error) {
+	v, _, err := parsePartialVersion(s)
+	return v, err
+}
+
+func mustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// parsePartialVersion parses a version and reports how many of its
+// numbers were given.
+func parsePartialVersion(s string) (Version, int, error) {
+	clean := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	parts := strings.Split(clean, ".")
+	if clean == "" || len(parts) > 3 {
+		return Version{}, 0, fmt.Errorf("version %q must be MAJOR[.MINOR[.PATCH]]", s)
+	}
+	var nums [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part != strconv.Itoa(n) {
+			return Version{}, 0, fmt.Errorf("version %q must be MAJOR[.MINOR[.PATCH]]", s)
+		}
+		nums[i] = n
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, len(parts), nil
+}
+
+// VersionConstraint is a set of version comparisons that must all hold,
+// such as ">=1.2, <2".
+type VersionConstraint []versionComparison
+
+type versionComparison struct {
+	op      string
+	version Version
+}
+
+// ParseVersionConstraint parses comparisons separated by commas or spaces.
+// Each is an operator (=, !=, >, >=, <, <=, ^ or ~) and a version; a
+// version alone means =. ^1.2 allows 1.2 up to 2, and ~1.2 allows 1.2 up
+// to 1.3.
+func ParseVersionConstraint(s string) (VersionConstraint, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("version constraint is empty")
+	}
+	var constraint VersionConstraint
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		op := strings.TrimRight(field, "0123456789.v")
+		if field == op && i+1 < len(fields) {
+			// An operator written apart from its version: ">= 1.2".
+			i++
+			field += fields[i]
+		}
+		version, given, err := parsePartialVersion(strings.TrimPrefix(field, op))
+		if err != nil {
+			return nil, fmt.Errorf("constraint %q: %w", s, err)
+		}
+		switch op {
+		case "", "==":
+			op = "="
+		case "=", "!=", ">", ">=", "<", "<=":
+		case "^":
+			constraint = append(constraint, versionComparison{op: ">=", version: version})
+			upper := Version{Major: version.Major + 1}
+			if version.Major == 0 && given > 1 {
+				upper = Version{Minor: version.Minor + 1}
+			}
+			constraint = append(constraint, versionComparison{op: "<", version: upper})
+			continue
+		case "~":
+			constraint = append(constraint, versionComparison{op: ">=", version: version})
+			upper := Version{Major: version.Major, Minor: version.Minor + 1}
+			if given == 1 {
+				upper = Version{Major: version.Major + 1}
+			}
+			constraint = append(constraint, versionComparison{op: "<", version: upper})
+			continue
+		default:
+			return nil, fmt.Errorf("constraint %q: unknown operator %q", s, op)
+		}
+		constraint = append(constraint, versionComparison{op: op, version: version})
+	}
+	return constraint, nil
+}
+
+// Allows reports whether v meets every comparison in c.
+func (c VersionConstraint) Allows(v Version) bool {
+	for _, cmp := range c {
+		d := v.Compare(cmp.version)
+		var ok bool
+		switch cmp.op {
+		case "=":
+			ok = d == 0
+		case "!=":
+			ok = d != 0
+		case ">":
+			ok = d > 0
+		case ">=":
+			ok = d >= 0
+		case "<":
+			ok = d < 0
+		case "<=":
+			ok = d <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/registry"
+)
+
+func TestParseVersionConstraint(t *testing.T) {
+	cases := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=0.9", "0.9.0", true},
+		{">=0.9", "0.8.7", false},
+		{">= 2", "2.1.0", true},
+		{">1.2, <2", "1.9.9", true},
+		{">1.2, <2", "2.0.0", false},
+		{"1.4", "1.4.0", true},
+		{"!=1.4.1", "1.4.1", false},
+		{"^1.2", "1.9.0", true},
+		{"^1.2", "2.0.0", false},
+		{"^0.3", "0.3.5", true},
+		{"^0.3", "0.4.0", false},
+		{"~1.2", "1.2.9", true},
+		{"~1.2", "1.3.0", false},
+		{"~1", "1.9.0", true},
+		{"<=v1.0", "1.0.0", true},
+	}
+	for _, tc := range cases {
+		constraint, err := ParseVersionConstraint(tc.constraint)
+		if err != nil {
+			t.Fatalf("ParseVersionConstraint(%q): %v", tc.constraint, err)
+		}
+		version, err := ParseVersion(tc.version)
+		if err != nil {
+			t.Fatalf("ParseVersion(%q): %v", tc.version, err)
+		}
+		if got := constraint.Allows(version); got != tc.want {
+			t.Errorf("%q allows %s = %v, want %v", tc.constraint, tc.version, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"", ">=", "=>1", ">=1.x", "1.2.3.4", ">=-1"} {
+		if _, err := ParseVersionConstraint(bad); err == nil {
+			t.Errorf("ParseVersionConstraint(%q) expected error", bad)
+		}
+	}
+}
+
+func TestValidate_GR025_Requires(t *testing.T) {
+	gd := &GraphDefinition{
+		ID:    "wf",
+		Nodes: []NodeDef{{ID: "a", Type: "noop"}},
+		Requires: &Requirements{
+			Engine:    ">=99",
+			NodeTypes: map[string]string{"webhook_call": "at least 2"},
+		},
+	}
+	diags := gd.Validate()
+	var messages []string
+	for _, d := range diags {
+		if d.Code == "GR-025" {
+			messages = append(messages, d.Message)
+		}
+	}
+	if len(messages) != 2 {
+		t.Fatalf("GR-025 diagnostics = %v, want 2", messages)
+	}
+	if !strings.Contains(messages[0], "requires engine >=99, but this engine is version "+EngineVersion) {
+		t.Errorf("engine message = %q", messages[0])
+	}
+	if !strings.Contains(messages[1], `requires.node_types["webhook_call"]`) {
+		t.Errorf("node type message = %q", messages[1])
+	}
+
+	gd.Requires = &Requirements{Engine: ">=" + EngineVersion}
+	if found := findDiag(gd.Validate(), "GR-025"); found != nil {
+		t.Errorf("unexpected GR-025: %+v", found)
+	}
+}
+
+func TestValidateWithRegistry_GR026_RequiredNodeTypes(t *testing.T) {
+	gd := &GraphDefinition{
+		ID:    "wf",
+		Nodes: []NodeDef{{ID: "a", Type: "noop"}},
+		Requires: &Requirements{NodeTypes: map[string]string{
+			"noop":         ">=1",
+			"webhook_call": ">=2",
+			"teleport":     ">=1",
+		}},
+	}
+	var messages []string
+	for _, d := range gd.ValidateWithRegistry(registry.Global()) {
+		if d.Code == "GR-026" {
+			messages = append(messages, d.Message)
+		}
+	}
+	want := []string{
+		`Workflow requires node type "teleport" >=1, but this engine does not provide it`,
+		`Workflow requires node type "webhook_call" >=2, but this engine provides version ` + registry.DefaultNodeTypeVersion,
+	}
+	if strings.Join(messages, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("GR-026 diagnostics = %q, want %q", messages, want)
+	}
+}
@@ -0,0 +1,211 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RunDefaults are run options a workflow declares for every run of it,
+// whether started from the CLI, the API, a schedule or a webhook. A run
+// request may override each of them within Limits.
+type RunDefaults struct {
+	// MaxHops caps how many times a run may follow an edge.
+	MaxHops int `json:"max_hops,omitempty"`
+	// Concurrency is how many nodes may execute at once.
+	Concurrency int `json:"concurrency,omitempty"`
+	// ContinueOnError keeps the run going after a node fails.
+	ContinueOnError *bool `json:"continue_on_error,omitempty"`
+	// Timeout is a Go duration string such as "90s".
+	Timeout string `json:"timeout,omitempty"`
+	// MaxNodeExecutions caps total node executions (0 = unlimited).
+	MaxNodeExecutions int `json:"max_node_executions,omitempty"`
+	// NodeVisitLimits caps executions of individual nodes by ID.
+	NodeVisitLimits map[string]int `json:"node_visit_limits,omitempty"`
+
+	// Limits bounds what a run request may override the defaults with.
+	Limits *RunLimits `json:"limits,omitempty"`
+}
+
+// RunLimits are the largest values a run request may set. Zero fields do
+// not limit.
+type RunLimits struct {
+	MaxHops     int `json:"max_hops,omitempty"`
+	Concurrency int `json:"concurrency,omitempty"`
+	// Timeout is a Go duration string.
+	Timeout string `json:"timeout,omitempty"`
+	// MaxNodeExecutions also applies to runs that would otherwise have no
+	// budget.
+	MaxNodeExecutions int `json:"max_node_executions,omitempty"`
+}
+
+// RunSettings are run options resolved from a request, a workflow's
+// RunDefaults and the caller's own defaults.
+type RunSettings struct {
+	MaxHops           int
+	Concurrency       int
+	ContinueOnError   bool
+	Timeout           time.Duration
+	MaxNodeExecutions int
+	NodeVisitLimits   map[string]int
+}
+
+// RunOverrides are the run options a request sets. Zero fields and a nil
+// ContinueOnError are unset.
+type RunOverrides struct {
+	MaxHops           int
+	Concurrency       int
+	ContinueOnError   *bool
+	Timeout           time.Duration
+	MaxNodeExecutions int
+	NodeVisitLimits   map[string]int
+}
+
+// ResolveRunSettings layers req over the definition's run defaults and
+// those over base, the caller's defaults. Node visit limits are merged by
+// node ID. Unset values taken from base are lowered to the definition's
+// limits; a request value above them is an error. The definition is
+// assumed to have passed Validate.
+func (gd *GraphDefinition) ResolveRunSettings(base RunSettings, req RunOverrides) (RunSettings, error) {
+	defaults := gd.RunDefaults
+	if defaults == nil {
+		defaults = &RunDefaults{}
+	}
+	limits := defaults.Limits
+	if limits == nil {
+		limits = &RunLimits{}
+	}
+	maxTimeout, _ := time.ParseDuration(limits.Timeout)
+	defaultTimeout, _ := time.ParseDuration(defaults.Timeout)
+
+	out := base
+	var err error
+	if out.MaxHops, err = resolveRunLimit("max_hops", req.MaxHops, defaults.MaxHops, base.MaxHops, limits.MaxHops); err != nil {
+		return RunSettings{}, err
+	}
+	if out.Concurrency, err = resolveRunLimit("concurrency", req.Concurrency, defaults.Concurrency, base.Concurrency, limits.Concurrency); err != nil {
+		return RunSettings{}, err
+	}
+	if out.MaxNodeExecutions, err = resolveRunLimit("max_node_executions", req.MaxNodeExecutions, defaults.MaxNodeExecutions, base.MaxNodeExecutions, limits.MaxNodeExecutions); err != nil {
+		return RunSettings{}, err
+	}
+	timeout, err := resolveRunLimit("timeout", int64(req.Timeout), int64(defaultTimeout), int64(base.Timeout), int64(maxTimeout))
+	if err != nil {
+		return RunSettings{}, fmt.Errorf("timeout %s exceeds the workflow's limit of %s", req.Timeout, maxTimeout)
+	}
+	out.Timeout = time.Duration(timeout)
+
+	switch {
+	case req.ContinueOnError != nil:
+		out.ContinueOnError = *req.ContinueOnError
+	case defaults.ContinueOnError != nil:
+		out.ContinueOnError = *defaults.ContinueOnError
+	}
+
+	if len(defaults.NodeVisitLimits) > 0 || len(req.NodeVisitLimits) > 0 {
+		merged := make(map[string]int, len(base.NodeVisitLimits)+len(defaults.NodeVisitLimits)+len(req.NodeVisitLimits))
+		for _, layer := range []map[string]int{base.NodeVisitLimits, defaults.NodeVisitLimits, req.NodeVisitLimits} {
+			for id, limit := range layer {
+				merged[id] = limit
+			}
+		}
+		out.NodeVisitLimits = merged
+	}
+	return out, nil
+}
+
+// resolveRunLimit picks the request value, else the workflow default, else
+// the base value, bounded by limit when it is set.
+func resolveRunLimit[T int | int64](name string, req, def, base, limit T) (T, error) {
+	switch {
+	case req > 0:
+		if limit > 0 && req > limit {
+			return 0, fmt.Errorf("%s %d exceeds the workflow's limit of %d", name, req, limit)
+		}
+		return req, nil
+	case def > 0:
+		return def, nil
+	case limit > 0 && (base <= 0 || base > limit):
+		return limit, nil
+	default:
+		return base, nil
+	}
+}
+
+// validateRunDefaults checks GR-017: run defaults use valid durations and
+// non-negative values within their own limits, and visit limits name
+// existing nodes.
+func (gd *GraphDefinition) validateRunDefaults(nodeIDs map[string]bool) []Diagnostic {
+	d := gd.RunDefaults
+	if d == nil {
+		return nil
+	}
+	var diags []Diagnostic
+	fail := func(path, format string, args ...any) {
+		diags = append(diags, Diagnostic{
+			Code:     "GR-017",
+			Severity: SeverityError,
+			Message:  fmt.Sprintf("Run defaults: "+format, args...),
+			Path:     "run_defaults." + path,
+		})
+	}
+
+	limits := d.Limits
+	if limits == nil {
+		limits = &RunLimits{}
+	}
+	ints := []struct {
+		name       string
+		value, max int
+	}{
+		{"max_hops", d.MaxHops, limits.MaxHops},
+		{"concurrency", d.Concurrency, limits.Concurrency},
+		{"max_node_executions", d.MaxNodeExecutions, limits.MaxNodeExecutions},
+	}
+	for _, f := range ints {
+		switch {
+		case f.value < 0:
+			fail(f.name, "%s must be >= 0", f.name)
+		case f.max < 0:
+			fail("limits."+f.name, "limits.%s must be >= 0", f.name)
+		case f.max > 0 && f.value > f.max:
+			fail(f.name, "%s %d exceeds limits.%s %d", f.name, f.value, f.name, f.max)
+		}
+	}
+
+	timeout, timeoutOK := parseRunDuration(d.Timeout)
+	if !timeoutOK {
+		fail("timeout", "timeout %q must be a positive duration", d.Timeout)
+	}
+	maxTimeout, maxOK := parseRunDuration(limits.Timeout)
+	if !maxOK {
+		fail("limits.timeout", "limits.timeout %q must be a positive duration", limits.Timeout)
+	}
+	if timeoutOK && maxOK && maxTimeout > 0 && timeout > maxTimeout {
+		fail("timeout", "timeout %s exceeds limits.timeout %s", timeout, maxTimeout)
+	}
+
+	ids := make([]string, 0, len(d.NodeVisitLimits))
+	for id := range d.NodeVisitLimits {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		path := fmt.Sprintf("node_visit_limits.%s", id)
+		if !nodeIDs[id] {
+			fail(path, "node_visit_limits references unknown node %q", id)
+		} else if d.NodeVisitLimits[id] < 0 {
+			fail(path, "node_visit_limits[%q] must be >= 0", id)
+		}
+	}
+	return diags
+}
+
+// parseRunDuration parses an optional positive duration; "" is 0.
+func parseRunDuration(s string) (time.Duration, bool) {
+	if s == "" {
+		return 0, true
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil && d > 0
+}
@@ -0,0 +1,120 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolveRunSettings(t *testing.T) {
+	yes, no := true, false
+	base := RunSettings{MaxHops: 100, Concurrency: 1, Timeout: 5 * time.Minute}
+	gd := &GraphDefinition{
+		RunDefaults: &RunDefaults{
+			Concurrency:     4,
+			ContinueOnError: &yes,
+			Timeout:         "90s",
+			NodeVisitLimits: map[string]int{"loop": 3, "retry": 2},
+			Limits: &RunLimits{
+				MaxHops:           50,
+				Concurrency:       8,
+				Timeout:           "10m",
+				MaxNodeExecutions: 200,
+			},
+		},
+	}
+
+	got, err := gd.ResolveRunSettings(base, RunOverrides{})
+	if err != nil {
+		t.Fatalf("ResolveRunSettings: %v", err)
+	}
+	if got.Concurrency != 4 || !got.ContinueOnError || got.Timeout != 90*time.Second {
+		t.Errorf("defaults not applied: %+v", got)
+	}
+	if got.MaxHops != 50 || got.MaxNodeExecutions != 200 {
+		t.Errorf("unset values = hops %d, executions %d, want lowered to limits 50, 200", got.MaxHops, got.MaxNodeExecutions)
+	}
+
+	got, err = gd.ResolveRunSettings(base, RunOverrides{
+		Concurrency:     8,
+		ContinueOnError: &no,
+		Timeout:         10 * time.Minute,
+		NodeVisitLimits: map[string]int{"loop": 5},
+	})
+	if err != nil {
+		t.Fatalf("ResolveRunSettings with overrides: %v", err)
+	}
+	if got.Concurrency != 8 || got.ContinueOnError || got.Timeout != 10*time.Minute {
+		t.Errorf("overrides not applied: %+v", got)
+	}
+	if got.NodeVisitLimits["loop"] != 5 || got.NodeVisitLimits["retry"] != 2 {
+		t.Errorf("NodeVisitLimits = %v, want request merged over defaults", got.NodeVisitLimits)
+	}
+	if gd.RunDefaults.NodeVisitLimits["loop"] != 3 {
+		t.Error("resolving must not modify the definition's visit limits")
+	}
+
+	for name, req := range map[string]RunOverrides{
+		"concurrency": {Concurrency: 9},
+		"max_hops":    {MaxHops: 51},
+		"timeout":     {Timeout: 11 * time.Minute},
+		"executions":  {MaxNodeExecutions: 201},
+	} {
+		if _, err := gd.ResolveRunSettings(base, req); err == nil || !strings.Contains(err.Error(), "limit") {
+			t.Errorf("%s above limit: err = %v, want limit error", name, err)
+		}
+	}
+}
+
+func TestResolveRunSettings_NoDefaults(t *testing.T) {
+	base := RunSettings{MaxHops: 100, Concurrency: 1, Timeout: 5 * time.Minute}
+	got, err := (&GraphDefinition{}).ResolveRunSettings(base, RunOverrides{MaxHops: 500, MaxNodeExecutions: 7})
+	if err != nil {
+		t.Fatalf("ResolveRunSettings: %v", err)
+	}
+	if got.MaxHops != 500 || got.Concurrency != 1 || got.Timeout != 5*time.Minute || got.MaxNodeExecutions != 7 {
+		t.Errorf("settings = %+v, want base with request overrides", got)
+	}
+	if got.NodeVisitLimits != nil {
+		t.Errorf("NodeVisitLimits = %v, want nil", got.NodeVisitLimits)
+	}
+}
+
+func TestValidate_RunDefaults(t *testing.T) {
+	gd := GraphDefinition{
+		ID:      "defaults",
+		Version: "1.0",
+		Nodes:   []NodeDef{{ID: "start", Type: "noop"}},
+		Entry:   "start",
+		RunDefaults: &RunDefaults{
+			Concurrency:     16,
+			Timeout:         "soon",
+			NodeVisitLimits: map[string]int{"start": 2, "ghost": 1},
+			Limits:          &RunLimits{Concurrency: 8},
+		},
+	}
+
+	paths := map[string]bool{}
+	for _, d := range gd.Validate() {
+		if d.Code == "GR-017" {
+			paths[d.Path] = true
+		}
+	}
+	for _, want := range []string{
+		"run_defaults.concurrency",
+		"run_defaults.timeout",
+		"run_defaults.node_visit_limits.ghost",
+	} {
+		if !paths[want] {
+			t.Errorf("missing GR-017 at %s, got %v", want, paths)
+		}
+	}
+	if len(paths) != 3 {
+		t.Errorf("GR-017 paths = %v, want 3", paths)
+	}
+
+	gd.RunDefaults = &RunDefaults{Timeout: "30s", Limits: &RunLimits{Timeout: "1m"}}
+	if d := findDiag(gd.Validate(), "GR-017"); d != nil {
+		t.Errorf("unexpected GR-017: %+v", d)
+	}
+}
@@ -0,0 +1,121 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SimulationDef supplies canned responses for LLM and tool nodes so a
+// workflow can run end-to-end without provider credentials. It is ignored
+// unless simulation is explicitly enabled for a run.
+type SimulationDef struct {
+	// Seed makes random-choice responses reproducible. Zero seeds from the
+	// clock.
+	Seed int64 `json:"seed,omitempty"`
+	// Nodes maps node IDs to their simulated responses.
+	Nodes map[string]SimulatedResponse `json:"nodes"`
+}
+
+// SimulatedResponse describes what a simulated node returns. Exactly one of
+// Response, Template or Choices must be set.
+type SimulatedResponse struct {
+	// Response is returned verbatim. Strings become LLM text; objects become
+	// structured output (and tool results).
+	Response any `json:"response,omitempty"`
+	// Template is a Go text/template rendered against the request. LLM
+	// nodes expose .prompt, .system, .model and .node; tool nodes expose
+	// .args and .node.
+	Template string `json:"template,omitempty"`
+	// Choices returns one of its entries at random on every call.
+	Choices []any `json:"choices,omitempty"`
+}
+
+// Validate reports the first malformed entry, in node ID order. A nil
+// definition is valid.
+func (s *SimulationDef) Validate() error {
+	if s == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(s.Nodes))
+	for id := range s.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		if err := s.Nodes[id].validate(); err != nil {
+			return fmt.Errorf("node %q: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (r SimulatedResponse) validate() error {
+	set := 0
+	if r.Response != nil {
+		set++
+	}
+	if r.Template != "" {
+		set++
+	}
+	if len(r.Choices) > 0 {
+		set++
+	}
+	if set != 1 {
+		return fmt.Errorf("exactly one of response, template or choices is required")
+	}
+	return nil
+}
+
+// validateSimulation implements GR-012.
+func (gd *GraphDefinition) validateSimulation(nodeIDs map[string]bool) []Diagnostic {
+	if gd.Simulate == nil {
+		return nil
+	}
+	ids := make([]string, 0, len(gd.Simulate.Nodes))
+	for id := range gd.Simulate.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var diags []Diagnostic
+	for _, id := range ids {
+		path := "simulate.nodes." + id
+		if !nodeIDs[id] {
+			diags = append(diags, Diagnostic{
+				Code:     "GR-012",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Simulated response references unknown node %q", id),
+				Path:     path,
+			})
+			continue
+		}
+		if err := gd.Simulate.Nodes[id].validate(); err != nil {
+			diags = append(diags, Diagnostic{
+				Code:     "GR-012",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("Invalid simulated response for node %q: %v", id, err),
+				Path:     path,
+			})
+		}
+	}
+	return diags
+}
+
+// MergeSimulation overlays override on base: override's seed (when set) and
+// per-node responses win. Either argument may be nil; the result is never
+// nil and never aliases base's node map.
+func MergeSimulation(base, override *SimulationDef) *SimulationDef {
+	merged := &SimulationDef{Nodes: make(map[string]SimulatedResponse)}
+	for _, s := range []*SimulationDef{base, override} {
+		if s == nil {
+			continue
+		}
+		if s.Seed != 0 {
+			merged.Seed = s.Seed
+		}
+		for id, resp := range s.Nodes {
+			merged.Nodes[id] = resp
+		}
+	}
+	return merged
+}
@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"fmt"
+	"time"
+)
+
+// SLA names, as reported in sla.breached events and run history.
+const (
+	SLAMaxDuration         = "max_duration"
+	SLAMaxFirstLLMResponse = "max_first_llm_response"
+	SLAMaxQueueWait        = "max_queue_wait"
+)
+
+// SLA declares service levels every run of a workflow is expected to meet.
+// Durations are Go duration strings such as "30s"; empty fields are not
+// tracked.
+type SLA struct {
+	// MaxDuration bounds the run from start to finish.
+	MaxDuration string `json:"max_duration,omitempty"`
+	// MaxFirstLLMResponse bounds the time from the run's start to its
+	// first LLM response.
+	MaxFirstLLMResponse string `json:"max_first_llm_response,omitempty"`
+	// MaxQueueWait bounds how long the run waits for a slot in the run
+	// queue before it starts.
+	MaxQueueWait string `json:"max_queue_wait,omitempty"`
+}
+
+// SLATargets are the parsed durations of an SLA. Zero fields are not
+// tracked.
+type SLATargets struct {
+	MaxDuration         time.Duration
+	MaxFirstLLMResponse time.Duration
+	MaxQueueWait        time.Duration
+}
+
+// IsZero reports whether no target is set.
+func (t SLATargets) IsZero() bool {
+	return t == SLATargets{}
+}
+
+// SLATargets returns the definition's SLA durations. The definition is
+// assumed to have passed Validate.
+func (gd *GraphDefinition) SLATargets() SLATargets {
+	if gd.SLA == nil {
+		return SLATargets{}
+	}
+	var t SLATargets
+	t.MaxDuration, _ = parseRunDuration(gd.SLA.MaxDuration)
+	t.MaxFirstLLMResponse, _ = parseRunDuration(gd.SLA.MaxFirstLLMResponse)
+	t.MaxQueueWait, _ = parseRunDuration(gd.SLA.MaxQueueWait)
+	return t
+}
+
+// validateSLA checks GR-020: SLA durations are positive.
+func (gd *GraphDefinition) validateSLA() []Diagnostic {
+	if gd.SLA == nil {
+		return nil
+	}
+	var diags []Diagnostic
+	for _, f := range []struct{ name, value string }{
+		{SLAMaxDuration, gd.SLA.MaxDuration},
+		{SLAMaxFirstLLMResponse, gd.SLA.MaxFirstLLMResponse},
+		{SLAMaxQueueWait, gd.SLA.MaxQueueWait},
+	} {
+		if _, ok := parseRunDuration(f.value); !ok {
+			diags = append(diags, Diagnostic{
+				Code:     "GR-020",
+				Severity: SeverityError,
+				Message:  fmt.Sprintf("SLA: %s %q must be a positive duration", f.name, f.value),
+				Path:     "sla." + f.name,
+			})
+		}
+	}
+	return diags
+}
@@ -0,0 +1,37 @@
+package graph
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGraphDefinition_SLATargets(t *testing.T) {
+	gd := &GraphDefinition{SLA: &SLA{MaxDuration: "30s", MaxQueueWait: "500ms"}}
+	want := SLATargets{MaxDuration: 30 * time.Second, MaxQueueWait: 500 * time.Millisecond}
+	if got := gd.SLATargets(); got != want {
+		t.Errorf("SLATargets() = %+v, want %+v", got, want)
+	}
+	if !(&GraphDefinition{}).SLATargets().IsZero() {
+		t.Error("SLATargets() without an SLA should be zero")
+	}
+}
+
+func TestValidate_SLADurations(t *testing.T) {
+	gd := &GraphDefinition{
+		ID:      "sla",
+		Version: "1.0",
+		Nodes:   []NodeDef{{ID: "a", Type: "noop"}},
+		Entry:   "a",
+		SLA:     &SLA{MaxDuration: "soon", MaxFirstLLMResponse: "-1s", MaxQueueWait: "2s"},
+	}
+	var paths []string
+	for _, d := range gd.Validate() {
+		if d.Code == "GR-020" {
+			paths = append(paths, d.Path)
+		}
+	}
+	want := []string{"sla.max_duration", "sla.max_first_llm_response"}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Errorf("GR-020 paths = %q, want %q", paths, want)
+	}
+}
@@ -0,0 +1,489 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/petal-labs/petalflow/registry"
+)
+
+// SubgraphNodeType is the node type that runs a nested graph. Its config
+// holds the graph under "graph", or names the file defining it under
+// "workflow_ref" until a loader resolves it.
+const SubgraphNodeType = "subgraph"
+
+// routingNodeTypes pick their successors by node ID, so they cannot be the
+// last node of an extracted subgraph.
+var routingNodeTypes = map[string]bool{
+	"llm_router":  true,
+	"rule_router": true,
+	"conditional": true,
+}
+
+// SubgraphDefinition returns the nested graph of a subgraph node, or nil
+// when its config has none.
+func SubgraphDefinition(node NodeDef) (*GraphDefinition, error) {
+	raw, ok := node.Config["graph"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("node %q: encoding graph: %w", node.ID, err)
+	}
+	var sub GraphDefinition
+	if err := json.Unmarshal(data, &sub); err != nil {
+		return nil, fmt.Errorf("node %q: graph is not a graph definition: %w", node.ID, err)
+	}
+	return &sub, nil
+}
+
+// ExtractSubgraph moves the nodes with the given IDs into a new graph
+// named name and returns the rewritten graph, in which a subgraph node
+// called name stands in for them, along with the extracted graph.
+//
+// The subgraph node keeps the handles of the edges it replaced: edges into
+// the selection now end at it, and edges out of it leave from it. For the
+// rewrite to run the same way, the selection must have one entry node,
+// which every edge from outside targets, and one last node, which every
+// other selected node leads to and every edge to the outside leaves from.
+// Routers outside the selection that named the entry node in their config
+// are updated to name the subgraph node.
+func (gd *GraphDefinition) ExtractSubgraph(nodeIDs []string, name string) (*GraphDefinition, *GraphDefinition, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, nil, fmt.Errorf("subgraph name is required")
+	}
+	if len(nodeIDs) == 0 {
+		return nil, nil, fmt.Errorf("no nodes selected")
+	}
+	byID := make(map[string]NodeDef, len(gd.Nodes))
+	for _, node := range gd.Nodes {
+		byID[node.ID] = node
+	}
+	if _, taken := byID[name]; taken {
+		return nil, nil, fmt.Errorf("node %q already exists; choose another subgraph name", name)
+	}
+	selected := make(map[string]bool, len(nodeIDs))
+	for _, id := range nodeIDs {
+		node, ok := byID[id]
+		switch {
+		case !ok:
+			return nil, nil, fmt.Errorf("node %q not found", id)
+		case selected[id]:
+			return nil, nil, fmt.Errorf("node %q selected twice", id)
+		case node.Type == "webhook_trigger":
+			return nil, nil, fmt.Errorf("node %q: webhook_trigger nodes must stay in the workflow they trigger", id)
+		}
+		if gd.Simulate != nil {
+			if _, ok := gd.Simulate.Nodes[id]; ok {
+				return nil, nil, fmt.Errorf("node %q has a simulated response; remove it before extracting", id)
+			}
+		}
+		selected[id] = true
+	}
+
+	var internal, incoming, outgoing []EdgeDef
+	for _, edge := range gd.Edges {
+		switch {
+		case selected[edge.Source] && selected[edge.Target]:
+			internal = append(internal, edge)
+		case selected[edge.Target]:
+			incoming = append(incoming, edge)
+		case selected[edge.Source]:
+			outgoing = append(outgoing, edge)
+		}
+	}
+
+	entry, err := subgraphEntry(gd, selected, internal, incoming)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := subgraphExit(byID, selected, internal, outgoing); err != nil {
+		return nil, nil, err
+	}
+
+	sub := &GraphDefinition{
+		ID:            name,
+		Version:       gd.Version,
+		SchemaVersion: gd.SchemaVersion,
+		Kind:          gd.Kind,
+		Edges:         append([]EdgeDef{}, internal...),
+		Entry:         entry,
+	}
+	parent := *gd
+	parent.Nodes = nil
+	parent.Edges = nil
+	for _, node := range gd.Nodes {
+		if !selected[node.ID] {
+			parent.Nodes = append(parent.Nodes, node)
+			continue
+		}
+		sub.Nodes = append(sub.Nodes, node)
+		if len(sub.Nodes) == 1 {
+			parent.Nodes = append(parent.Nodes, NodeDef{ID: name, Type: SubgraphNodeType})
+		}
+	}
+	for _, edge := range gd.Edges {
+		switch {
+		case selected[edge.Source] && selected[edge.Target]:
+			continue
+		case selected[edge.Target]:
+			edge.Target = name
+		case selected[edge.Source]:
+			edge.Source = name
+		}
+		parent.Edges = append(parent.Edges, edge)
+	}
+	// The default entry depends on node order, so pin it.
+	parent.Entry = gd.EntryNode()
+	if selected[parent.Entry] {
+		parent.Entry = name
+	}
+
+	subConfig, err := subgraphConfigValue(sub)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i, node := range parent.Nodes {
+		switch {
+		case node.ID == name:
+			parent.Nodes[i].Config = map[string]any{"graph": subConfig}
+		case routesTo(node.ID, entry, incoming):
+			parent.Nodes[i].Config = renameNodeRefs(node.Config, entry, name).(map[string]any)
+		}
+	}
+	if cycle := parent.detectCycle(); cycle != "" {
+		return nil, nil, fmt.Errorf("extracting these nodes creates a cycle (%s): a path leaves the selection and comes back; select the nodes on it too", cycle)
+	}
+	return &parent, sub, nil
+}
+
+// subgraphEntry finds the one selected node that edges from outside, or
+// the graph's entry, lead to.
+func subgraphEntry(gd *GraphDefinition, selected map[string]bool, internal, incoming []EdgeDef) (string, error) {
+	entries := map[string]bool{}
+	for _, edge := range incoming {
+		entries[edge.Target] = true
+	}
+	if id := gd.EntryNode(); selected[id] {
+		entries[id] = true
+	}
+	if len(entries) == 0 {
+		// Nothing leads into the selection: its entry is its one root.
+		hasInbound := map[string]bool{}
+		for _, edge := range internal {
+			hasInbound[edge.Target] = true
+		}
+		for id := range selected {
+			if !hasInbound[id] {
+				entries[id] = true
+			}
+		}
+	}
+	if len(entries) != 1 {
+		return "", fmt.Errorf("the selection is entered at %s; a subgraph has one entry node", joinSorted(entries))
+	}
+	var entry string
+	for id := range entries {
+		entry = id
+	}
+
+	reached := map[string]bool{entry: true}
+	queue := []string{entry}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, edge := range internal {
+			if edge.Source == current && !reached[edge.Target] {
+				reached[edge.Target] = true
+				queue = append(queue, edge.Target)
+			}
+		}
+	}
+	for id := range selected {
+		if !reached[id] {
+			return "", fmt.Errorf("node %q cannot be reached from the subgraph's entry %q within the selection", id, entry)
+		}
+	}
+	return entry, nil
+}
+
+// subgraphExit finds the one selected node with no selected successors and
+// checks that every edge to the outside leaves from it.
+func subgraphExit(byID map[string]NodeDef, selected map[string]bool, internal, outgoing []EdgeDef) error {
+	hasSuccessor := map[string]bool{}
+	for _, edge := range internal {
+		hasSuccessor[edge.Source] = true
+	}
+	sinks := map[string]bool{}
+	for id := range selected {
+		if !hasSuccessor[id] {
+			sinks[id] = true
+		}
+	}
+	if len(sinks) != 1 {
+		return fmt.Errorf("the selection ends at %s; a subgraph has one last node", joinSorted(sinks))
+	}
+	var exit string
+	for id := range sinks {
+		exit = id
+	}
+	for _, edge := range outgoing {
+		if edge.Source != exit {
+			return fmt.Errorf("node %q has an edge to %q outside the selection; only the last node %q may", edge.Source, edge.Target, exit)
+		}
+	}
+	if len(outgoing) > 0 && routingNodeTypes[byID[exit].Type] {
+		return fmt.Errorf("node %q (%s) routes to nodes outside the selection; select its targets too", exit, byID[exit].Type)
+	}
+	return nil
+}
+
+// subgraphDiagnostics checks GR-021: a subgraph node has a graph, or a
+// workflow_ref for a loader to resolve, and the graph has one last node and
+// passes validation itself. Its diagnostics are reported under the node.
+func subgraphDiagnostics(i int, node NodeDef, reg *registry.Registry) []Diagnostic {
+	path := fmt.Sprintf("nodes[%d].config.graph", i)
+	fail := func(msg string) []Diagnostic {
+		return []Diagnostic{{Code: "GR-021", Severity: SeverityError, Message: msg, Path: path}}
+	}
+	sub, err := SubgraphDefinition(node)
+	switch {
+	case err != nil:
+		return fail(err.Error())
+	case sub == nil:
+		if ref, _ := node.Config["workflow_ref"].(string); ref != "" {
+			return nil
+		}
+		return fail(fmt.Sprintf("Subgraph node %q needs a graph or a workflow_ref", node.ID))
+	}
+	if _, _, err := sub.entryAndExit(); err != nil {
+		return fail(fmt.Sprintf("Subgraph node %q: %v", node.ID, err))
+	}
+	var diags []Diagnostic
+	for _, d := range sub.ValidateWithRegistry(reg) {
+		d.Message = fmt.Sprintf("Subgraph %q: %s", node.ID, d.Message)
+		if d.Path != "" {
+			d.Path = path + "." + d.Path
+		} else {
+			d.Path = path
+		}
+		diags = append(diags, d)
+	}
+	return diags
+}
+
+// InlineSubgraphs replaces every subgraph node that carries its graph with
+// the graph's nodes, reconnecting the subgraph node's edges to the nested
+// entry and last nodes. Nested subgraphs are inlined too.
+func (gd *GraphDefinition) InlineSubgraphs() (*GraphDefinition, error) {
+	out := *gd
+	out.Nodes = nil
+	out.Edges = append([]EdgeDef{}, gd.Edges...)
+	for _, node := range gd.Nodes {
+		sub, err := SubgraphDefinition(node)
+		if node.Type != SubgraphNodeType || sub == nil || err != nil {
+			if err != nil {
+				return nil, err
+			}
+			out.Nodes = append(out.Nodes, node)
+			continue
+		}
+		if sub, err = sub.InlineSubgraphs(); err != nil {
+			return nil, err
+		}
+		entry, exit, err := sub.entryAndExit()
+		if err != nil {
+			return nil, fmt.Errorf("node %q: %w", node.ID, err)
+		}
+		out.Nodes = append(out.Nodes, sub.Nodes...)
+		for i, edge := range out.Edges {
+			if edge.Target == node.ID {
+				out.Edges[i].Target = entry
+			}
+			if edge.Source == node.ID {
+				out.Edges[i].Source = exit
+			}
+		}
+		out.Edges = append(out.Edges, sub.Edges...)
+		if out.Entry == node.ID {
+			out.Entry = entry
+		}
+		for i, n := range out.Nodes {
+			if routesTo(n.ID, entry, out.Edges) {
+				out.Nodes[i].Config = renameNodeRefs(n.Config, node.ID, entry).(map[string]any)
+			}
+		}
+	}
+	return &out, nil
+}
+
+// entryAndExit returns a nested graph's entry node and its one node
+// without successors.
+func (gd *GraphDefinition) entryAndExit() (string, string, error) {
+	if len(gd.Nodes) == 0 {
+		return "", "", fmt.Errorf("subgraph has no nodes")
+	}
+	hasSuccessor := map[string]bool{}
+	for _, edge := range gd.Edges {
+		hasSuccessor[edge.Source] = true
+	}
+	var sinks []string
+	for _, node := range gd.Nodes {
+		if !hasSuccessor[node.ID] {
+			sinks = append(sinks, node.ID)
+		}
+	}
+	if len(sinks) != 1 {
+		return "", "", fmt.Errorf("subgraph ends at %d nodes; it needs exactly one last node", len(sinks))
+	}
+	return gd.EntryNode(), sinks[0], nil
+}
+
+// EquivalentTo reports how gd differs from other as a runnable graph: the
+// same nodes with the same types and config, the same edges with the same
+// handles, and the same entry. Metadata and the order of nodes and edges
+// are ignored. Nil means they run the same way.
+func (gd *GraphDefinition) EquivalentTo(other *GraphDefinition) error {
+	var problems []string
+	mine := make(map[string]NodeDef, len(gd.Nodes))
+	for _, node := range gd.Nodes {
+		mine[node.ID] = node
+	}
+	theirs := make(map[string]NodeDef, len(other.Nodes))
+	for _, node := range other.Nodes {
+		theirs[node.ID] = node
+		want, ok := mine[node.ID]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("node %q was added", node.ID))
+		case want.Type != node.Type:
+			problems = append(problems, fmt.Sprintf("node %q changed type from %s to %s", node.ID, want.Type, node.Type))
+		case !sameConfig(want.Config, node.Config):
+			problems = append(problems, fmt.Sprintf("node %q changed config", node.ID))
+		}
+	}
+	for id := range mine {
+		if _, ok := theirs[id]; !ok {
+			problems = append(problems, fmt.Sprintf("node %q was removed", id))
+		}
+	}
+	if !reflect.DeepEqual(sortedEdges(gd.Edges), sortedEdges(other.Edges)) {
+		problems = append(problems, "edges differ")
+	}
+	if gd.EntryNode() != other.EntryNode() {
+		problems = append(problems, fmt.Sprintf("entry changed from %q to %q", gd.EntryNode(), other.EntryNode()))
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("graphs differ: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// subgraphConfigValue encodes a graph the way it appears in node config
+// decoded from JSON.
+func subgraphConfigValue(sub *GraphDefinition) (map[string]any, error) {
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return nil, err
+	}
+	var value map[string]any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// routesTo reports whether an edge leads from source to target.
+func routesTo(source, target string, edges []EdgeDef) bool {
+	for _, edge := range edges {
+		if edge.Source == source && edge.Target == target {
+			return true
+		}
+	}
+	return false
+}
+
+// renameNodeRefs copies a config, replacing string values and map keys
+// equal to from with to. Routers name their targets this way.
+func renameNodeRefs(value any, from, to string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		if v == nil {
+			return v
+		}
+		out := make(map[string]any, len(v))
+		for key, item := range v {
+			if key == from {
+				key = to
+			}
+			out[key] = renameNodeRefs(item, from, to)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, item := range v {
+			out[i] = renameNodeRefs(item, from, to)
+		}
+		return out
+	case []string:
+		out := make([]string, len(v))
+		for i, item := range v {
+			out[i] = renameNodeRefs(item, from, to).(string)
+		}
+		return out
+	case string:
+		if v == from {
+			return to
+		}
+		return v
+	default:
+		return value
+	}
+}
+
+func sameConfig(a, b map[string]any) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return reflect.DeepEqual(a, b)
+	}
+	return string(ja) == string(jb)
+}
+
+func sortedEdges(edges []EdgeDef) []EdgeDef {
+	out := append([]EdgeDef{}, edges...)
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		if a.Target != b.Target {
+			return a.Target < b.Target
+		}
+		if a.SourceHandle != b.SourceHandle {
+			return a.SourceHandle < b.SourceHandle
+		}
+		return a.TargetHandle < b.TargetHandle
+	})
+	return out
+}
+
+func joinSorted(set map[string]bool) string {
+	if len(set) == 0 {
+		return "no node"
+	}
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, fmt.Sprintf("%q", id))
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ", ")
+}
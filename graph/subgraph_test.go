@@ -0,0 +1,180 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/registry"
+)
+
+// pipelineDef is start -> fetch -> parse -> score -> report.
+func pipelineDef() *GraphDefinition {
+	return &GraphDefinition{
+		ID:      "pipeline",
+		Version: "1.0",
+		Nodes: []NodeDef{
+			{ID: "start", Type: "noop"},
+			{ID: "fetch", Type: "noop"},
+			{ID: "parse", Type: "transform", Config: map[string]any{"transform": "pick", "fields": []any{"a"}}},
+			{ID: "score", Type: "noop"},
+			{ID: "report", Type: "noop"},
+		},
+		Edges: []EdgeDef{
+			{Source: "start", SourceHandle: "output", Target: "fetch", TargetHandle: "input"},
+			{Source: "fetch", SourceHandle: "output", Target: "parse", TargetHandle: "input"},
+			{Source: "parse", SourceHandle: "output", Target: "score", TargetHandle: "input"},
+			{Source: "score", SourceHandle: "output", Target: "report", TargetHandle: "input"},
+		},
+		Entry: "start",
+	}
+}
+
+func TestExtractSubgraph_RoundTrip(t *testing.T) {
+	original := pipelineDef()
+	parent, sub, err := original.ExtractSubgraph([]string{"fetch", "parse", "score"}, "enrich")
+	if err != nil {
+		t.Fatalf("ExtractSubgraph: %v", err)
+	}
+
+	if len(parent.Nodes) != 3 || parent.Nodes[1].ID != "enrich" || parent.Nodes[1].Type != SubgraphNodeType {
+		t.Fatalf("parent nodes = %+v, want start, enrich, report", parent.Nodes)
+	}
+	if len(parent.Edges) != 2 || parent.Edges[0].Target != "enrich" || parent.Edges[1].Source != "enrich" {
+		t.Errorf("parent edges = %+v", parent.Edges)
+	}
+	if sub.ID != "enrich" || sub.Entry != "fetch" || len(sub.Nodes) != 3 || len(sub.Edges) != 2 {
+		t.Errorf("sub = %+v", sub)
+	}
+
+	nested, err := SubgraphDefinition(parent.Nodes[1])
+	if err != nil || nested == nil || len(nested.Nodes) != 3 {
+		t.Fatalf("SubgraphDefinition = %+v, %v", nested, err)
+	}
+	if diags := parent.ValidateWithRegistry(registry.Global()); HasErrors(diags) {
+		t.Fatalf("parent has errors: %v", Errors(diags))
+	}
+
+	inlined, err := parent.InlineSubgraphs()
+	if err != nil {
+		t.Fatalf("InlineSubgraphs: %v", err)
+	}
+	if err := original.EquivalentTo(inlined); err != nil {
+		t.Errorf("inlined graph differs: %v", err)
+	}
+}
+
+func TestExtractSubgraph_EntryNode(t *testing.T) {
+	parent, _, err := pipelineDef().ExtractSubgraph([]string{"start", "fetch"}, "head")
+	if err != nil {
+		t.Fatalf("ExtractSubgraph: %v", err)
+	}
+	if parent.Entry != "head" {
+		t.Errorf("Entry = %q, want head", parent.Entry)
+	}
+}
+
+func TestExtractSubgraph_RenamesRouterTargets(t *testing.T) {
+	gd := &GraphDefinition{
+		ID:      "routed",
+		Version: "1.0",
+		Nodes: []NodeDef{
+			{ID: "route", Type: "rule_router", Config: map[string]any{
+				"rules":   []any{map[string]any{"target": "a"}},
+				"default": "other",
+			}},
+			{ID: "a", Type: "noop"},
+			{ID: "b", Type: "noop"},
+			{ID: "other", Type: "noop"},
+		},
+		Edges: []EdgeDef{
+			{Source: "route", SourceHandle: "a", Target: "a", TargetHandle: "input"},
+			{Source: "route", SourceHandle: "other", Target: "other", TargetHandle: "input"},
+			{Source: "a", SourceHandle: "output", Target: "b", TargetHandle: "input"},
+		},
+		Entry: "route",
+	}
+	parent, _, err := gd.ExtractSubgraph([]string{"a", "b"}, "branch")
+	if err != nil {
+		t.Fatalf("ExtractSubgraph: %v", err)
+	}
+	rules := parent.Nodes[0].Config["rules"].([]any)
+	if target := rules[0].(map[string]any)["target"]; target != "branch" {
+		t.Errorf("rule target = %v, want branch", target)
+	}
+	if gd.Nodes[0].Config["rules"].([]any)[0].(map[string]any)["target"] != "a" {
+		t.Error("original config was modified")
+	}
+
+	inlined, err := parent.InlineSubgraphs()
+	if err != nil {
+		t.Fatalf("InlineSubgraphs: %v", err)
+	}
+	if err := gd.EquivalentTo(inlined); err != nil {
+		t.Errorf("inlined graph differs: %v", err)
+	}
+}
+
+func TestExtractSubgraph_Errors(t *testing.T) {
+	twoEntries := pipelineDef()
+	twoEntries.Edges = append(twoEntries.Edges,
+		EdgeDef{Source: "start", SourceHandle: "output", Target: "parse", TargetHandle: "input"})
+	sideExit := pipelineDef()
+	sideExit.Nodes = append(sideExit.Nodes, NodeDef{ID: "side", Type: "noop"})
+	sideExit.Edges = append(sideExit.Edges,
+		EdgeDef{Source: "fetch", SourceHandle: "output", Target: "side", TargetHandle: "input"})
+
+	tests := []struct {
+		name  string
+		gd    *GraphDefinition
+		nodes []string
+		sub   string
+		want  string
+	}{
+		{"no name", pipelineDef(), []string{"fetch"}, "", "name is required"},
+		{"taken name", pipelineDef(), []string{"fetch"}, "report", "already exists"},
+		{"unknown node", pipelineDef(), []string{"missing"}, "x", `node "missing" not found`},
+		{"two entries", twoEntries, []string{"fetch", "parse"}, "x", "one entry node"},
+		{"leaves early", sideExit, []string{"fetch", "parse", "score"}, "x", "outside the selection"},
+		{"gap", pipelineDef(), []string{"fetch", "score"}, "x", "one entry node"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.gd.ExtractSubgraph(tt.nodes, tt.sub)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateWithRegistry_GR021_Subgraph(t *testing.T) {
+	reg := registry.Global()
+	gd := &GraphDefinition{
+		ID:      "outer",
+		Version: "1.0",
+		Nodes:   []NodeDef{{ID: "sub", Type: SubgraphNodeType}},
+		Entry:   "sub",
+	}
+	if found := findDiag(gd.ValidateWithRegistry(reg), "GR-021"); found == nil {
+		t.Fatal("expected GR-021 for a subgraph node without a graph")
+	}
+
+	gd.Nodes[0].Config = map[string]any{"workflow_ref": "sub.json"}
+	if found := findDiag(gd.ValidateWithRegistry(reg), "GR-021"); found != nil {
+		t.Errorf("unexpected GR-021 for workflow_ref: %+v", found)
+	}
+
+	gd.Nodes[0].Config = map[string]any{"graph": map[string]any{
+		"id":      "inner",
+		"version": "1.0",
+		"nodes":   []any{map[string]any{"id": "x", "type": "definitely_not_registered"}},
+		"entry":   "x",
+	}}
+	found := findDiag(gd.ValidateWithRegistry(reg), "GR-003")
+	if found == nil {
+		t.Fatal("expected the nested graph's GR-003")
+	}
+	if !strings.HasPrefix(found.Path, "nodes[0].config.graph.") {
+		t.Errorf("Path = %q, want it under nodes[0].config.graph", found.Path)
+	}
+}
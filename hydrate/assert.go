@@ -0,0 +1,43 @@
+package hydrate
+
+import (
+	"fmt"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/nodes"
+)
+
+// withAssertions wraps factory so nodes with an "assert" config are built
+// as an AssertNode around the node factory builds. Route assertions go to
+// the node wired to its error port.
+func withAssertions(def *graph.GraphDefinition, factory NodeFactory) NodeFactory {
+	return func(nd graph.NodeDef) (core.Node, error) {
+		node, err := factory(nd)
+		if err != nil {
+			return nil, err
+		}
+		assertions, err := nd.Assertions()
+		if err != nil || len(assertions) == 0 {
+			return node, err
+		}
+		if _, merges := node.(core.MergeCapable); merges {
+			return nil, fmt.Errorf("assert is not supported on merge nodes")
+		}
+
+		cfg := nodes.AssertNodeConfig{Node: node, ErrorTarget: def.ErrorPortTarget(nd.ID)}
+		for _, edge := range def.Edges {
+			if edge.Source == nd.ID && edge.SourceHandle != graph.AssertErrorPort {
+				cfg.Targets = append(cfg.Targets, edge.Target)
+			}
+		}
+		for _, a := range assertions {
+			cfg.Assertions = append(cfg.Assertions, nodes.Assertion{
+				Expr:    a.Expr,
+				Action:  nodes.AssertAction(a.Action),
+				Message: a.Message,
+			})
+		}
+		return nodes.NewAssertNode(cfg)
+	}
+}
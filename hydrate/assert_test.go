@@ -0,0 +1,101 @@
+package hydrate
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func TestHydrateGraph_AssertRoutesToErrorPort(t *testing.T) {
+	factory, _ := newMockClientFactory()
+	build := func(template string) *graph.BasicGraph {
+		t.Helper()
+		gd := &graph.GraphDefinition{
+			ID:      "digest",
+			Version: "1.0",
+			Nodes: []graph.NodeDef{
+				{ID: "summarize", Type: "transform", Config: map[string]any{
+					"transform":  "template",
+					"template":   template,
+					"output_var": "summary",
+					"assert": []any{
+						`output.summary != ""`,
+						map[string]any{"expr": "output.summary.length > 10", "action": "route", "message": "summary too short"},
+					},
+				}},
+				{ID: "publish", Type: "transform", Config: map[string]any{"transform": "template", "template": "published", "output_var": "path"}},
+				{ID: "fallback", Type: "transform", Config: map[string]any{"transform": "template", "template": "fallback", "output_var": "path"}},
+			},
+			Edges: []graph.EdgeDef{
+				{Source: "summarize", Target: "publish"},
+				{Source: "summarize", SourceHandle: "error", Target: "fallback"},
+			},
+			Entry: "summarize",
+		}
+		g, err := HydrateGraph(gd, ProviderMap{}, NewLiveNodeFactory(ProviderMap{}, factory))
+		if err != nil {
+			t.Fatalf("HydrateGraph: %v", err)
+		}
+		return g
+	}
+	run := func(g *graph.BasicGraph) (*core.Envelope, []runtime.Event, error) {
+		var events []runtime.Event
+		opts := runtime.DefaultRunOptions()
+		opts.EventHandler = func(e runtime.Event) { events = append(events, e) }
+		result, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+		return result, events, err
+	}
+
+	result, _, err := run(build("a long enough summary"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if path, _ := result.GetVar("path"); path != "published" {
+		t.Errorf("path = %v, want published", path)
+	}
+
+	result, events, err := run(build("short"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if path, _ := result.GetVar("path"); path != "fallback" {
+		t.Errorf("path = %v, want fallback", path)
+	}
+	var failed int
+	for _, e := range events {
+		if e.Kind == runtime.EventNodeAssertionFailed && e.NodeID == "summarize" && e.Payload["message"] == "summary too short" {
+			failed++
+		}
+	}
+	if failed != 1 {
+		t.Errorf("assertion_failed events = %d, want 1", failed)
+	}
+
+	_, _, err = run(build("{{if false}}x{{end}}"))
+	if err == nil || !strings.Contains(err.Error(), "assertion failed: output.summary != \"\"") {
+		t.Errorf("expected the fail assertion to fail the run, got %v", err)
+	}
+}
+
+func TestHydrateGraph_AssertRouteNeedsErrorEdge(t *testing.T) {
+	gd := &graph.GraphDefinition{
+		ID:      "digest",
+		Version: "1.0",
+		Nodes: []graph.NodeDef{
+			{ID: "summarize", Type: "noop", Config: map[string]any{
+				"assert": []any{map[string]any{"expr": "output.summary", "action": "route"}},
+			}},
+		},
+		Entry: "summarize",
+	}
+	factory := func(nd graph.NodeDef) (core.Node, error) {
+		return core.NewNoopNode(nd.ID), nil
+	}
+	if _, err := HydrateGraph(gd, nil, factory); err == nil || !strings.Contains(err.Error(), "error port") {
+		t.Errorf("expected a missing error edge error, got %v", err)
+	}
+}
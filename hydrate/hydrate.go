@@ -3,18 +3,26 @@ package hydrate
 import (
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/petal-labs/petalflow/core"
 	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/registry"
 )
 
 // ProviderConfig holds configuration for a single LLM provider.
 type ProviderConfig struct {
 	APIKey  string `json:"api_key"`
 	BaseURL string `json:"base_url,omitempty"`
+	// Regions lists the provider's regional endpoints. When set, LLM nodes
+	// send each request to the region Routing picks and fail over to the
+	// others; BaseURL is not used.
+	Regions []RegionConfig `json:"regions,omitempty"`
+	// Routing is RoutingFailover (the default) or RoutingLowestLatency.
+	Routing string `json:"routing,omitempty"`
 }
 
 // ProviderMap maps provider names to their configurations.
@@ -116,17 +124,40 @@ type NodeFactory func(graph.NodeDef) (core.Node, error)
 //
 // The nodeFactory parameter creates live Node instances from NodeDef descriptors.
 // If nil, a default factory is used that creates FuncNode placeholders.
+//
+// LLM nodes inherit the definition's llm_defaults (see
+// graph.GraphDefinition.ApplyLLMDefaults) before they are built.
+//
+// Nodes with an "assert" config are wrapped in a nodes.AssertNode.
+//
+// Nodes using a deprecated type alias are built as their replacement type
+// and a deprecation warning is logged for each.
 func HydrateGraph(def *graph.GraphDefinition, providers ProviderMap, nodeFactory NodeFactory) (*graph.BasicGraph, error) {
 	if def == nil {
 		return nil, fmt.Errorf("graph definition is nil")
 	}
 
+	def, migrations, err := def.MigrateNodeTypes(registry.Global())
+	if err != nil {
+		return nil, err
+	}
+	// Secret values are plaintext here unless the caller left them
+	// encrypted, which fails: only the daemon holds the key.
+	def, err = UnwrapSecrets(def, nil)
+	if err != nil {
+		return nil, err
+	}
+	def = def.ApplyLLMDefaults()
+	for _, m := range migrations {
+		slog.Warn("deprecated node type", "graph", def.ID, "node", m.NodeID, "type", m.From, "replacement", m.To)
+	}
+
 	factory := nodeFactory
 	if factory == nil {
 		factory = defaultNodeFactory(providers)
 	}
 
-	return def.ToGraph(graph.WithNodeFactory(factory))
+	return def.ToGraph(graph.WithNodeFactory(withAssertions(def, factory)))
 }
 
 // defaultNodeFactory creates a basic NodeFactory that produces FuncNode
@@ -136,7 +167,7 @@ func HydrateGraph(def *graph.GraphDefinition, providers ProviderMap, nodeFactory
 func defaultNodeFactory(providers ProviderMap) NodeFactory {
 	return func(nd graph.NodeDef) (core.Node, error) {
 		// For LLM nodes, verify the provider exists
-		if nd.Type == "llm_prompt" || nd.Type == "llm_router" {
+		if graph.IsLLMNodeType(nd.Type) {
 			providerName, _ := nd.Config["provider"].(string)
 			if providerName != "" {
 				if _, ok := providers[providerName]; !ok {
@@ -144,6 +175,17 @@ func defaultNodeFactory(providers ProviderMap) NodeFactory {
 				}
 			}
 		}
+		if nd.Type == "model_select" {
+			arms, err := parseModelArms(nd)
+			if err != nil {
+				return nil, err
+			}
+			for _, arm := range arms {
+				if _, ok := providers[arm.Provider]; !ok {
+					return nil, fmt.Errorf("provider %q not configured (needed by node %q)", arm.Provider, nd.ID)
+				}
+			}
+		}
 		// Create a placeholder FuncNode
 		return core.NewFuncNode(nd.ID, nil), nil
 	}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/petal-labs/petalflow/core"
@@ -222,6 +223,58 @@ func TestHydrateGraph_NilDefinition(t *testing.T) {
 	}
 }
 
+func TestHydrateGraph_MigratesDeprecatedNodeTypes(t *testing.T) {
+	def := &graph.GraphDefinition{
+		ID:      "legacy",
+		Version: "1.0",
+		Nodes: []graph.NodeDef{
+			{ID: "notify", Type: "webhook_send", Config: map[string]any{"endpoint": "https://example.com/hook"}},
+		},
+		Entry: "notify",
+	}
+
+	var built graph.NodeDef
+	_, err := HydrateGraph(def, ProviderMap{}, func(nd graph.NodeDef) (core.Node, error) {
+		built = nd
+		return core.NewNoopNode(nd.ID), nil
+	})
+	if err != nil {
+		t.Fatalf("HydrateGraph: %v", err)
+	}
+	if built.Type != "webhook_call" || built.Config["url"] != "https://example.com/hook" {
+		t.Errorf("factory received %+v, want the migrated webhook_call node", built)
+	}
+	if def.Nodes[0].Type != "webhook_send" {
+		t.Error("HydrateGraph must not modify the definition")
+	}
+}
+
+func TestHydrateGraph_AppliesLLMDefaults(t *testing.T) {
+	def := &graph.GraphDefinition{
+		ID:          "support",
+		Version:     "1.0",
+		Nodes:       []graph.NodeDef{{ID: "answer", Type: "llm_prompt", Config: map[string]any{"model": "gpt-4o"}}},
+		Entry:       "answer",
+		LLMDefaults: &graph.LLMDefaults{Provider: "openai", Model: "gpt-4o-mini", SystemPrompt: "Be brief."},
+	}
+
+	var built graph.NodeDef
+	_, err := HydrateGraph(def, ProviderMap{"openai": {APIKey: "sk-test"}}, func(nd graph.NodeDef) (core.Node, error) {
+		built = nd
+		return core.NewNoopNode(nd.ID), nil
+	})
+	if err != nil {
+		t.Fatalf("HydrateGraph: %v", err)
+	}
+	if built.Config["provider"] != "openai" || built.Config["model"] != "gpt-4o" || built.Config["system_prompt"] != "Be brief." {
+		t.Errorf("factory received config %v", built.Config)
+	}
+
+	if _, err := HydrateGraph(def, ProviderMap{}, nil); err == nil || !strings.Contains(err.Error(), `provider "openai" not configured`) {
+		t.Errorf("HydrateGraph without the inherited provider: %v", err)
+	}
+}
+
 func TestHydrateGraph_DefaultFactory_MissingProvider(t *testing.T) {
 	def := &graph.GraphDefinition{
 		ID:      "llm-graph",
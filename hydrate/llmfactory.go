@@ -1,8 +1,11 @@
 package hydrate
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"math"
+	"slices"
 	"strings"
 	"time"
 
@@ -11,6 +14,7 @@ import (
 	"github.com/petal-labs/petalflow/nodes"
 	"github.com/petal-labs/petalflow/nodes/conditional"
 	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+	"github.com/petal-labs/petalflow/propagate"
 )
 
 func init() {
@@ -27,6 +31,21 @@ type ClientFactory func(providerName string, cfg ProviderConfig) (core.LLMClient
 type liveFactoryOptions struct {
 	toolRegistry *core.ToolRegistry
 	humanHandler nodes.HumanHandler
+	simulation   *simulator
+	conditions   *conditional.Library
+	history      nodes.OutputHistoryStore
+	historyScope string
+	armStats     nodes.ArmStatsStore
+	armScope     string
+	state        nodes.StateStore
+	stateScope   string
+	sandbox      *nodes.TemplateSandbox
+	credentials  *CredentialVerifier
+	regions      *RegionRouter
+	holidays     expr.Holidays
+	outbox       nodes.Outbox
+	callbacks    *nodes.ToolCallbacks
+	toolCache    nodes.CacheStore
 }
 
 type liveFactoryRuntime struct {
@@ -49,13 +68,97 @@ func WithHumanHandler(h nodes.HumanHandler) LiveNodeOption {
 	return func(o *liveFactoryOptions) { o.humanHandler = h }
 }
 
+// WithConditionLibrary resolves `ref` entries in conditional and
+// rule_router conditions against lib. Without it, any ref fails hydration.
+func WithConditionLibrary(lib *conditional.Library) LiveNodeOption {
+	return func(o *liveFactoryOptions) { o.conditions = lib }
+}
+
+// WithOutputHistory provides the store LLM node drift guards keep their
+// output history in. History keys are "<scope>/<node id>"; scope is usually
+// the workflow ID.
+func WithOutputHistory(store nodes.OutputHistoryStore, scope string) LiveNodeOption {
+	return func(o *liveFactoryOptions) {
+		o.history = store
+		o.historyScope = scope
+	}
+}
+
+// WithArmStats provides the store model_select and reward nodes keep model
+// selections and arm statistics in. Statistics keys are "<scope>/<node id>";
+// scope is usually the workflow ID.
+func WithArmStats(store nodes.ArmStatsStore, scope string) LiveNodeOption {
+	return func(o *liveFactoryOptions) {
+		o.armStats = store
+		o.armScope = scope
+	}
+}
+
+// WithStateStore provides the store state_get, state_set and state_incr
+// nodes keep values in, and binds "state" in conditional and rule_router
+// expressions. Nodes use scope, usually the workflow ID, as their
+// namespace unless they configure one.
+func WithStateStore(store nodes.StateStore, scope string) LiveNodeOption {
+	return func(o *liveFactoryOptions) {
+		o.state = store
+		o.stateScope = scope
+	}
+}
+
+// WithHolidays provides the holiday calendars isHoliday looks days up in
+// conditional and rule_router expressions. A node's own holidays config
+// adds to them.
+func WithHolidays(holidays expr.Holidays) LiveNodeOption {
+	return func(o *liveFactoryOptions) { o.holidays = holidays }
+}
+
+// WithOutbox records the requests of webhook_call nodes with outbox
+// delivery in outbox instead of sending them while the node runs.
+func WithOutbox(outbox nodes.Outbox) LiveNodeOption {
+	return func(o *liveFactoryOptions) { o.outbox = outbox }
+}
+
+// WithToolCallbacks lets tool nodes wait for the results of asynchronous
+// invocations that callbacks delivers. Without it, only tools that can be
+// polled may answer asynchronously.
+func WithToolCallbacks(callbacks *nodes.ToolCallbacks) LiveNodeOption {
+	return func(o *liveFactoryOptions) { o.callbacks = callbacks }
+}
+
+// WithToolCache caches the responses of tools whose manifests declare
+// them cacheable in store, shared by every workflow given the same store.
+func WithToolCache(store nodes.CacheStore) LiveNodeOption {
+	return func(o *liveFactoryOptions) { o.toolCache = store }
+}
+
+// WithTemplateSandbox restricts the templates of llm_prompt, transform,
+// webhook_call and cache nodes.
+func WithTemplateSandbox(sandbox *nodes.TemplateSandbox) LiveNodeOption {
+	return func(o *liveFactoryOptions) { o.sandbox = sandbox }
+}
+
+// WithCredentialVerifier checks each provider's credentials, through v's
+// cache, the first time the factory creates its client. A failed check
+// fails hydration of the node; an unchecked provider is used as is.
+func WithCredentialVerifier(v *CredentialVerifier) LiveNodeOption {
+	return func(o *liveFactoryOptions) { o.credentials = v }
+}
+
+// WithRegionRouter routes the requests of providers that configure regions
+// through router, so region health and latency are shared by every factory
+// given the same router. Without it, each factory probes regions itself.
+func WithRegionRouter(router *RegionRouter) LiveNodeOption {
+	return func(o *liveFactoryOptions) { o.regions = router }
+}
+
 // NewLiveNodeFactory returns a NodeFactory that creates executable nodes for
 // supported graph node types. Unsupported node types fail fast so wiring
 // issues are surfaced during hydration instead of silently no-oping.
 func NewLiveNodeFactory(providers ProviderMap, clientFactory ClientFactory, opts ...LiveNodeOption) NodeFactory {
+	options := collectLiveFactoryOptions(opts)
 	runtime := liveFactoryRuntime{
-		options:   collectLiveFactoryOptions(opts),
-		getClient: newLiveFactoryClientGetter(providers, clientFactory),
+		options:   options,
+		getClient: newLiveFactoryClientGetter(providers, clientFactory, options.credentials, options.regions),
 	}
 	return runtime.buildNode
 }
@@ -65,10 +168,15 @@ func collectLiveFactoryOptions(opts []LiveNodeOption) liveFactoryOptions {
 	for _, o := range opts {
 		o(&options)
 	}
+	if options.simulation != nil {
+		// Simulated selections and state writes stay out of the real stores.
+		options.armStats = nodes.NewMemoryArmStats()
+		options.state = nodes.NewMemoryStateStore()
+	}
 	return options
 }
 
-func newLiveFactoryClientGetter(providers ProviderMap, clientFactory ClientFactory) func(string) (core.LLMClient, error) {
+func newLiveFactoryClientGetter(providers ProviderMap, clientFactory ClientFactory, credentials *CredentialVerifier, regions *RegionRouter) func(string) (core.LLMClient, error) {
 	// Cache one client per provider name so multiple nodes sharing a provider reuse it.
 	clients := make(map[string]core.LLMClient)
 	return func(providerName string) (core.LLMClient, error) {
@@ -79,45 +187,106 @@ func newLiveFactoryClientGetter(providers ProviderMap, clientFactory ClientFacto
 		if !ok {
 			return nil, fmt.Errorf("provider %q not configured", providerName)
 		}
-		c, err := clientFactory(providerName, cfg)
+		var c core.LLMClient
+		var err error
+		if len(cfg.Regions) > 0 {
+			if regions == nil {
+				regions = NewRegionRouter(clientFactory, 0)
+			}
+			c, err = newRegionalClient(providerName, cfg, clientFactory, regions)
+		} else {
+			c, err = clientFactory(providerName, cfg)
+		}
 		if err != nil {
 			return nil, err
 		}
+		if credentials != nil {
+			status := credentials.Verify(context.Background(), providerName, cfg, "")
+			if status.State == CredentialFailed {
+				return nil, fmt.Errorf("provider %q credentials failed verification: %s", providerName, status.failure())
+			}
+		}
 		clients[providerName] = c
 		return c, nil
 	}
 }
 
 func (r liveFactoryRuntime) buildNode(nd graph.NodeDef) (core.Node, error) {
+	if node, ok, err := r.buildSimulatedNode(nd); ok {
+		return node, err
+	}
+
 	switch nd.Type {
 	case "llm_prompt":
-		return buildLLMNode(nd, r.getClient)
+		guard, err := buildDriftGuard(nd, r.options)
+		if err != nil {
+			return nil, err
+		}
+		return buildLLMNode(nd, r.getClient, guard, r.options.sandbox)
 	case "llm_router":
 		return buildLLMRouter(nd, r.getClient)
+	case "compact_messages":
+		return buildCompactMessagesNode(nd, r.getClient)
+	case "translate":
+		return buildTranslateNode(nd, r.getClient)
+	case "summarize":
+		return buildSummarizeNode(nd, r.getClient)
+	case "classify":
+		return buildClassifyNode(nd, r.getClient)
+	case "extract_entities":
+		return buildExtractEntitiesNode(nd, r.getClient)
+	case "model_select":
+		return buildModelSelectNode(nd, r.getClient, r.options, nil)
+	case "reward":
+		return buildRewardNode(nd, r.options)
 	case "rule_router":
-		return buildRuleRouter(nd)
+		holidays, err := nodeHolidays(nd, r.options.holidays)
+		if err != nil {
+			return nil, err
+		}
+		return buildRuleRouter(nd, r.options.conditions, stateLookup(nd, r.options), holidays)
 	case "filter":
 		return buildFilterNode(nd)
 	case "transform":
-		return buildTransformNode(nd)
+		return buildTransformNode(nd, r.options.sandbox)
 	case "gate":
 		return buildGateNode(nd)
 	case "guardian":
 		return buildGuardianNode(nd)
+	case "validate_json":
+		return buildValidateJSONNode(nd)
+	case "normalize_content":
+		return buildNormalizeContentNode(nd)
+	case "calc":
+		return buildCalcNode(nd)
+	case "publish":
+		return buildPublishNode(nd)
 	case "webhook_trigger":
 		return buildWebhookTriggerNode(nd)
 	case "webhook_call":
-		return buildWebhookCallNode(nd)
+		return buildWebhookCallNode(nd, r.options)
 	case "map":
 		return buildMapNode(r, nd)
 	case "cache":
 		return buildCacheNode(r, nd)
+	case graph.SubgraphNodeType:
+		return buildSubgraphNode(r, nd)
 	case "merge":
 		return buildMergeNode(nd)
 	case "human":
 		return buildHumanNode(nd, r.options.humanHandler)
 	case "conditional":
-		return buildConditionalNode(nd)
+		holidays, err := nodeHolidays(nd, r.options.holidays)
+		if err != nil {
+			return nil, err
+		}
+		return buildConditionalNode(nd, r.options.conditions, stateLookup(nd, r.options), holidays)
+	case "state_get":
+		return buildStateGetNode(nd, r.options)
+	case "state_set":
+		return buildStateSetNode(nd, r.options)
+	case "state_incr":
+		return buildStateIncrNode(nd, r.options)
 	case "noop":
 		return core.NewNoopNode(nd.ID), nil
 	case "func":
@@ -133,7 +302,7 @@ func (r liveFactoryRuntime) buildDynamicToolNode(nd graph.NodeDef) (core.Node, e
 	// Check if the type matches a registered tool.
 	if r.options.toolRegistry != nil {
 		if tool, ok := r.options.toolRegistry.Get(nd.Type); ok {
-			return buildToolNode(nd, tool), nil
+			return buildToolNode(nd, tool, r.options.callbacks, r.options.toolCache)
 		}
 	}
 	return nil, fmt.Errorf("node %q: unsupported node type %q", nd.ID, nd.Type)
@@ -165,10 +334,67 @@ func buildMapNode(r liveFactoryRuntime, nd graph.NodeDef) (core.Node, error) {
 	if v, ok := nd.Config["preserve_order"].(bool); ok {
 		cfg.PreserveOrder = v
 	}
+	if err := applyMapStreamConfig(r, nd, &cfg); err != nil {
+		return nil, err
+	}
 
 	return nodes.NewMapNode(nd.ID, cfg), nil
 }
 
+func applyMapStreamConfig(r liveFactoryRuntime, nd graph.NodeDef, cfg *nodes.MapNodeConfig) error {
+	if v, ok := nd.Config["stream"].(bool); ok {
+		cfg.Stream = v
+	}
+	cfg.StreamVar = configString(nd.Config, "stream_var")
+
+	switch order := nodes.MapStreamOrder(configString(nd.Config, "stream_order")); order {
+	case "", nodes.MapStreamOrderCompletion, nodes.MapStreamOrderInput:
+		cfg.StreamOrder = order
+	default:
+		return fmt.Errorf("node %q: stream_order must be one of: completion, input", nd.ID)
+	}
+
+	_, hasBinding := nd.Config["stream_binding"]
+	_, hasNode := nd.Config["stream_node"]
+	if !hasBinding && !hasNode {
+		return nil
+	}
+	consumerDef, err := boundNodeDefFromConfig(nd, []string{"stream_binding", "stream_node"}, nd.ID+"__stream")
+	if err != nil {
+		return err
+	}
+	consumer, err := r.buildNode(consumerDef)
+	if err != nil {
+		return fmt.Errorf("node %q: map stream binding hydration failed: %w", nd.ID, err)
+	}
+	cfg.StreamNode = consumer
+	return nil
+}
+
+// buildSubgraphNode hydrates a subgraph node's nested graph with the same
+// factory as its parent.
+func buildSubgraphNode(r liveFactoryRuntime, nd graph.NodeDef) (core.Node, error) {
+	def, err := graph.SubgraphDefinition(nd)
+	if err != nil {
+		return nil, err
+	}
+	if def == nil {
+		if ref := configString(nd.Config, "workflow_ref"); ref != "" {
+			return nil, fmt.Errorf("node %q: workflow_ref %q was not resolved; load the workflow from its file, or inline the graph (petalflow refactor extract --inline)", nd.ID, ref)
+		}
+		return nil, fmt.Errorf("node %q: subgraph requires graph", nd.ID)
+	}
+	inner, err := HydrateGraph(def, nil, r.buildNode)
+	if err != nil {
+		return nil, fmt.Errorf("node %q: subgraph hydration failed: %w", nd.ID, err)
+	}
+	cfg := nodes.SubgraphNodeConfig{Graph: inner}
+	if v, ok := configInt(nd.Config, "max_hops"); ok {
+		cfg.MaxHops = v
+	}
+	return nodes.NewSubgraphNode(nd.ID, cfg), nil
+}
+
 func buildCacheNode(r liveFactoryRuntime, nd graph.NodeDef) (core.Node, error) {
 	wrappedDef, err := boundNodeDefFromConfig(nd, []string{"wrapped_binding", "wrapped_node"}, nd.ID+"__wrapped")
 	if err != nil {
@@ -180,10 +406,11 @@ func buildCacheNode(r liveFactoryRuntime, nd graph.NodeDef) (core.Node, error) {
 	}
 
 	cfg := nodes.CacheNodeConfig{
-		CacheKey:    configString(nd.Config, "cache_key"),
-		WrappedNode: wrappedNode,
-		TTL:         configDuration(nd.Config, "ttl"),
-		OutputVar:   configString(nd.Config, "output_var"),
+		CacheKey:        configString(nd.Config, "cache_key"),
+		TemplateSandbox: r.options.sandbox,
+		WrappedNode:     wrappedNode,
+		TTL:             configDuration(nd.Config, "ttl"),
+		OutputVar:       configString(nd.Config, "output_var"),
 	}
 	// Backward-compatible alias used in some tests/examples.
 	if cfg.OutputVar == "" {
@@ -276,11 +503,12 @@ func buildConfiguredToolNode(r liveFactoryRuntime, nd graph.NodeDef) (core.Node,
 	if !ok {
 		return nil, fmt.Errorf("node %q: tool %q not found in registry", nd.ID, toolName)
 	}
-	return buildToolNodeWithName(nd, toolName, tool), nil
+	return buildToolNodeWithName(nd, toolName, tool, r.options.callbacks, r.options.toolCache)
 }
 
-// buildLLMNode extracts config from a NodeDef and returns an LLMNode.
-func buildLLMNode(nd graph.NodeDef, getClient func(string) (core.LLMClient, error)) (core.Node, error) {
+// buildLLMNode extracts config from a NodeDef and returns an LLMNode. guard
+// and sandbox may be nil.
+func buildLLMNode(nd graph.NodeDef, getClient func(string) (core.LLMClient, error), guard *nodes.DriftGuardConfig, sandbox *nodes.TemplateSandbox) (core.Node, error) {
 	providerName, _ := nd.Config["provider"].(string)
 	if providerName == "" {
 		return nil, fmt.Errorf("node %q: missing \"provider\" in config", nd.ID)
@@ -304,10 +532,493 @@ func buildLLMNode(nd graph.NodeDef, getClient func(string) (core.LLMClient, erro
 	if v, ok := configInt(nd.Config, "max_tokens"); ok {
 		cfg.MaxTokens = &v
 	}
+	cfg.DriftGuard = guard
+	cfg.TemplateSandbox = sandbox
+	if cfg.PromptCache, err = buildPromptCache(nd); err != nil {
+		return nil, err
+	}
+	if cfg.TemplateVars, err = buildTemplateVars(nd); err != nil {
+		return nil, err
+	}
 
 	return nodes.NewLLMNode(nd.ID, client, cfg), nil
 }
 
+// buildTemplateVars parses a node's template_vars config: variable name to
+// {required, default}. It returns nil when the node has none.
+func buildTemplateVars(nd graph.NodeDef) (map[string]nodes.TemplateVar, error) {
+	raw, ok := nd.Config["template_vars"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("node %q: template_vars must be an object", nd.ID)
+	}
+	vars := make(map[string]nodes.TemplateVar, len(m))
+	for name, rawVar := range m {
+		vm, ok := rawVar.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("node %q: template_vars.%s must be an object", nd.ID, name)
+		}
+		tv := nodes.TemplateVar{Default: vm["default"]}
+		if rawRequired, ok := vm["required"]; ok {
+			if tv.Required, ok = rawRequired.(bool); !ok {
+				return nil, fmt.Errorf("node %q: template_vars.%s.required must be a boolean", nd.ID, name)
+			}
+		}
+		vars[name] = tv
+	}
+	return vars, nil
+}
+
+// buildPromptCache parses an llm_prompt node's prompt_cache config. It
+// returns nil when the node has none.
+func buildPromptCache(nd graph.NodeDef) (*nodes.PromptCacheConfig, error) {
+	raw, ok := nd.Config["prompt_cache"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("node %q: prompt_cache must be an object", nd.ID)
+	}
+	pc := &nodes.PromptCacheConfig{
+		TTL: configDuration(m, "ttl"),
+		Key: configString(m, "key"),
+	}
+	pc.System, _ = m["system"].(bool)
+	if vars, ok := configStringSlice(m, "context_vars"); ok {
+		pc.ContextVars = vars
+	}
+	if pc.TTL < 0 {
+		return nil, fmt.Errorf("node %q: prompt_cache ttl must not be negative", nd.ID)
+	}
+	return pc, nil
+}
+
+// buildDriftGuard parses an llm_prompt node's drift_guard config. It returns
+// nil when the node has none.
+func buildDriftGuard(nd graph.NodeDef, opts liveFactoryOptions) (*nodes.DriftGuardConfig, error) {
+	raw, ok := nd.Config["drift_guard"]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	m, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("node %q: drift_guard must be an object", nd.ID)
+	}
+	if opts.history == nil {
+		return nil, fmt.Errorf("node %q: drift_guard needs an output history store", nd.ID)
+	}
+
+	key := configString(m, "key")
+	if key == "" {
+		key = nd.ID
+		if opts.historyScope != "" {
+			key = opts.historyScope + "/" + nd.ID
+		}
+	}
+	guard := &nodes.DriftGuardConfig{
+		Store:  opts.history,
+		Key:    key,
+		Action: configString(m, "action"),
+	}
+	if v, ok := configInt(m, "window"); ok {
+		guard.Window = v
+	}
+	if v, ok := configInt(m, "min_samples"); ok {
+		guard.MinSamples = v
+	}
+	if v, ok := configFloat64(m, "max_length_z"); ok {
+		guard.MaxLengthZ = v
+	}
+	if v, ok := configFloat64(m, "min_similarity"); ok {
+		guard.MinSimilarity = v
+	}
+	if fields, ok := configStringSlice(m, "fields"); ok {
+		guard.Fields = fields
+	}
+	if err := guard.Validate(); err != nil {
+		return nil, fmt.Errorf("node %q: %w", nd.ID, err)
+	}
+	return guard, nil
+}
+
+func buildCompactMessagesNode(nd graph.NodeDef, getClient func(string) (core.LLMClient, error)) (core.Node, error) {
+	providerName, _ := nd.Config["provider"].(string)
+	if providerName == "" {
+		return nil, fmt.Errorf("node %q: missing \"provider\" in config", nd.ID)
+	}
+	maxTokens, ok := configInt(nd.Config, "max_tokens")
+	if !ok || maxTokens <= 0 {
+		return nil, fmt.Errorf("node %q: compact_messages requires a positive max_tokens", nd.ID)
+	}
+
+	client, err := getClient(providerName)
+	if err != nil {
+		return nil, fmt.Errorf("node %q: %w", nd.ID, err)
+	}
+
+	cfg := nodes.CompactMessagesNodeConfig{
+		MaxTokens:     maxTokens,
+		Model:         configString(nd.Config, "model"),
+		SummaryPrompt: configString(nd.Config, "summary_prompt"),
+		OutputKey:     configString(nd.Config, "output_key"),
+		Timeout:       configDuration(nd.Config, "timeout"),
+	}
+	if v, ok := configInt(nd.Config, "keep_last_turns"); ok {
+		cfg.KeepLastTurns = v
+	}
+	if v, ok := nd.Config["keep_tool_messages"].(bool); ok {
+		cfg.KeepToolMessages = v
+	}
+
+	return nodes.NewCompactMessagesNode(nd.ID, client, cfg), nil
+}
+
+// buildTranslateNode extracts config from a NodeDef and returns a
+// TranslateNode.
+func buildTranslateNode(nd graph.NodeDef, getClient func(string) (core.LLMClient, error)) (core.Node, error) {
+	providerName, _ := nd.Config["provider"].(string)
+	if providerName == "" {
+		return nil, fmt.Errorf("node %q: missing \"provider\" in config", nd.ID)
+	}
+	inputVar := configString(nd.Config, "input_var")
+	if inputVar == "" {
+		return nil, fmt.Errorf("node %q: translate requires input_var", nd.ID)
+	}
+	targetLanguage := configString(nd.Config, "target_language")
+	if targetLanguage == "" {
+		return nil, fmt.Errorf("node %q: translate requires target_language", nd.ID)
+	}
+
+	client, err := getClient(providerName)
+	if err != nil {
+		return nil, fmt.Errorf("node %q: %w", nd.ID, err)
+	}
+
+	cfg := nodes.TranslateNodeConfig{
+		InputVar:       inputVar,
+		Field:          configString(nd.Config, "field"),
+		OutputKey:      configString(nd.Config, "output_key"),
+		QualityKey:     configString(nd.Config, "quality_key"),
+		SourceLanguage: configString(nd.Config, "source_language"),
+		TargetLanguage: targetLanguage,
+		Glossary:       configStringMap(nd.Config, "glossary"),
+		Instructions:   configString(nd.Config, "instructions"),
+		Model:          configString(nd.Config, "model"),
+		Timeout:        configDuration(nd.Config, "timeout"),
+	}
+	if v, ok := configStringSlice(nd.Config, "do_not_translate"); ok {
+		cfg.DoNotTranslate = v
+	}
+	if v, ok := configInt(nd.Config, "batch_size"); ok {
+		cfg.BatchSize = v
+	}
+	if v, ok := nd.Config["strict"].(bool); ok {
+		cfg.Strict = v
+	}
+
+	return nodes.NewTranslateNode(nd.ID, client, cfg), nil
+}
+
+func buildSummarizeNode(nd graph.NodeDef, getClient func(string) (core.LLMClient, error)) (core.Node, error) {
+	providerName, _ := nd.Config["provider"].(string)
+	if providerName == "" {
+		return nil, fmt.Errorf("node %q: missing \"provider\" in config", nd.ID)
+	}
+	inputVar := configString(nd.Config, "input_var")
+	if inputVar == "" {
+		return nil, fmt.Errorf("node %q: summarize requires input_var", nd.ID)
+	}
+	cfg := nodes.SummarizeNodeConfig{
+		InputVar:     inputVar,
+		Field:        configString(nd.Config, "field"),
+		OutputKey:    configString(nd.Config, "output_key"),
+		ResultKey:    configString(nd.Config, "result_key"),
+		Preset:       configString(nd.Config, "preset"),
+		Mode:         configString(nd.Config, "mode"),
+		Citations:    configString(nd.Config, "citations"),
+		Instructions: configString(nd.Config, "instructions"),
+		Model:        configString(nd.Config, "model"),
+		Timeout:      configDuration(nd.Config, "timeout"),
+	}
+	if _, ok := nodes.SummaryPresets[cfg.Preset]; cfg.Preset != "" && !ok {
+		return nil, fmt.Errorf("node %q: summarize preset %q must be one of: bullet, abstract, executive, tweet", nd.ID, cfg.Preset)
+	}
+	switch cfg.Mode {
+	case "", nodes.SummarizeAuto, nodes.SummarizeSingle, nodes.SummarizeHierarchical:
+	default:
+		return nil, fmt.Errorf("node %q: summarize mode %q must be one of: auto, single, hierarchical", nd.ID, cfg.Mode)
+	}
+	switch cfg.Citations {
+	case "", nodes.CitationsNone, nodes.CitationsKeep, nodes.CitationsSources:
+	default:
+		return nil, fmt.Errorf("node %q: summarize citations %q must be one of: none, keep, sources", nd.ID, cfg.Citations)
+	}
+	if v, ok := configInt(nd.Config, "target_words"); ok {
+		cfg.TargetWords = v
+	}
+	if v, ok := configInt(nd.Config, "target_tokens"); ok {
+		cfg.TargetTokens = v
+	}
+	if v, ok := configInt(nd.Config, "max_input_tokens"); ok {
+		cfg.MaxInputTokens = v
+	}
+	if v, ok := configInt(nd.Config, "chunk_tokens"); ok {
+		cfg.ChunkTokens = v
+	}
+
+	client, err := getClient(providerName)
+	if err != nil {
+		return nil, fmt.Errorf("node %q: %w", nd.ID, err)
+	}
+	return nodes.NewSummarizeNode(nd.ID, client, cfg), nil
+}
+
+func buildClassifyNode(nd graph.NodeDef, getClient func(string) (core.LLMClient, error)) (core.Node, error) {
+	inputVar := configString(nd.Config, "input_var")
+	if inputVar == "" {
+		return nil, fmt.Errorf("node %q: classify requires input_var", nd.ID)
+	}
+	cfg := nodes.ClassifyNodeConfig{
+		InputVar:          inputVar,
+		OutputKey:         configString(nd.Config, "output_key"),
+		ResultKey:         configString(nd.Config, "result_key"),
+		DefaultLabel:      configString(nd.Config, "default_label"),
+		Method:            configString(nd.Config, "method"),
+		Instructions:      configString(nd.Config, "instructions"),
+		Model:             configString(nd.Config, "model"),
+		Timeout:           configDuration(nd.Config, "timeout"),
+		EmbeddingFallback: configString(nd.Config, "fallback") == nodes.ClassifyMethodEmbedding,
+	}
+	if fallback := configString(nd.Config, "fallback"); fallback != "" && fallback != "none" && fallback != nodes.ClassifyMethodEmbedding {
+		return nil, fmt.Errorf("node %q: classify fallback %q must be one of: none, embedding", nd.ID, fallback)
+	}
+	switch cfg.Method {
+	case "", nodes.ClassifyMethodLLM, nodes.ClassifyMethodEmbedding:
+	default:
+		return nil, fmt.Errorf("node %q: classify method %q must be one of: llm, embedding", nd.ID, cfg.Method)
+	}
+	raw, err := json.Marshal(nd.Config["labels"])
+	if err == nil {
+		err = json.Unmarshal(raw, &cfg.Labels)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("node %q: classify labels: %w", nd.ID, err)
+	}
+	if err := nodes.ValidateTaxonomy(cfg.Labels); err != nil {
+		return nil, fmt.Errorf("node %q: classify labels: %w", nd.ID, err)
+	}
+	if v, ok := nd.Config["multi_label"].(bool); ok {
+		cfg.MultiLabel = v
+	}
+	if v, ok := configInt(nd.Config, "max_labels"); ok {
+		cfg.MaxLabels = v
+	}
+	if v, ok := configFloat64(nd.Config, "min_confidence"); ok {
+		if v < 0 || v > 1 {
+			return nil, fmt.Errorf("node %q: classify min_confidence must be between 0 and 1", nd.ID)
+		}
+		cfg.MinConfidence = v
+	}
+
+	if cfg.Method == nodes.ClassifyMethodEmbedding {
+		return nodes.NewClassifyNode(nd.ID, nil, cfg), nil
+	}
+	providerName, _ := nd.Config["provider"].(string)
+	if providerName == "" {
+		return nil, fmt.Errorf("node %q: missing \"provider\" in config", nd.ID)
+	}
+	client, err := getClient(providerName)
+	if err != nil {
+		return nil, fmt.Errorf("node %q: %w", nd.ID, err)
+	}
+	return nodes.NewClassifyNode(nd.ID, client, cfg), nil
+}
+
+func buildExtractEntitiesNode(nd graph.NodeDef, getClient func(string) (core.LLMClient, error)) (core.Node, error) {
+	providerName, _ := nd.Config["provider"].(string)
+	if providerName == "" {
+		return nil, fmt.Errorf("node %q: missing \"provider\" in config", nd.ID)
+	}
+	inputVar := configString(nd.Config, "input_var")
+	if inputVar == "" {
+		return nil, fmt.Errorf("node %q: extract_entities requires input_var", nd.ID)
+	}
+	cfg := nodes.ExtractEntitiesNodeConfig{
+		InputVar:     inputVar,
+		Field:        configString(nd.Config, "field"),
+		OutputKey:    configString(nd.Config, "output_key"),
+		ResultKey:    configString(nd.Config, "result_key"),
+		Entity:       configString(nd.Config, "entity"),
+		Description:  configString(nd.Config, "description"),
+		Dedupe:       configString(nd.Config, "dedupe"),
+		OnInvalid:    configString(nd.Config, "on_invalid"),
+		Instructions: configString(nd.Config, "instructions"),
+		Model:        configString(nd.Config, "model"),
+		Timeout:      configDuration(nd.Config, "timeout"),
+	}
+	raw, err := json.Marshal(nd.Config["fields"])
+	if err == nil {
+		err = json.Unmarshal(raw, &cfg.Fields)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("node %q: extract_entities fields: %w", nd.ID, err)
+	}
+	if err := nodes.ValidateEntityFields(cfg.Fields); err != nil {
+		return nil, fmt.Errorf("node %q: extract_entities %w", nd.ID, err)
+	}
+	cfg.DedupeKeys, _ = configStringSlice(nd.Config, "dedupe_keys")
+	switch cfg.Dedupe {
+	case "", nodes.DedupeNone, nodes.DedupeExact:
+	case nodes.DedupeKey:
+		if len(cfg.DedupeKeys) == 0 {
+			return nil, fmt.Errorf("node %q: extract_entities dedupe \"key\" requires dedupe_keys", nd.ID)
+		}
+		for _, key := range cfg.DedupeKeys {
+			if !slices.ContainsFunc(cfg.Fields, func(f nodes.EntityField) bool { return f.Name == key }) {
+				return nil, fmt.Errorf("node %q: extract_entities dedupe key %q is not a field", nd.ID, key)
+			}
+		}
+	default:
+		return nil, fmt.Errorf("node %q: extract_entities dedupe %q must be one of: none, exact, key", nd.ID, cfg.Dedupe)
+	}
+	switch cfg.OnInvalid {
+	case "", nodes.InvalidDrop, nodes.InvalidKeep, nodes.InvalidFail:
+	default:
+		return nil, fmt.Errorf("node %q: extract_entities on_invalid %q must be one of: drop, keep, fail", nd.ID, cfg.OnInvalid)
+	}
+
+	client, err := getClient(providerName)
+	if err != nil {
+		return nil, fmt.Errorf("node %q: %w", nd.ID, err)
+	}
+	return nodes.NewExtractEntitiesNode(nd.ID, client, cfg), nil
+}
+
+// modelArmDef is one entry of a model_select node's arms.
+type modelArmDef struct {
+	Name     string
+	Provider string
+	Model    string
+}
+
+// parseModelArms reads a model_select node's arms config.
+func parseModelArms(nd graph.NodeDef) ([]modelArmDef, error) {
+	raw, ok := nd.Config["arms"].([]any)
+	if !ok || len(raw) == 0 {
+		return nil, fmt.Errorf("node %q: model_select requires a non-empty arms list", nd.ID)
+	}
+	arms := make([]modelArmDef, 0, len(raw))
+	for i, item := range raw {
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("node %q: arms[%d] must be an object", nd.ID, i)
+		}
+		arm := modelArmDef{
+			Name:     configString(m, "name"),
+			Provider: configString(m, "provider"),
+			Model:    configString(m, "model"),
+		}
+		if arm.Provider == "" || arm.Model == "" {
+			return nil, fmt.Errorf("node %q: arms[%d] requires provider and model", nd.ID, i)
+		}
+		arms = append(arms, arm)
+	}
+	return arms, nil
+}
+
+// buildModelSelectNode extracts config from a NodeDef and returns a
+// ModelSelectNode. random may be nil.
+func buildModelSelectNode(nd graph.NodeDef, getClient func(string) (core.LLMClient, error), opts liveFactoryOptions, random func() float64) (core.Node, error) {
+	armDefs, err := parseModelArms(nd)
+	if err != nil {
+		return nil, err
+	}
+	if opts.armStats == nil {
+		return nil, fmt.Errorf("node %q: model_select needs an arm statistics store", nd.ID)
+	}
+
+	arms := make([]nodes.ModelArm, len(armDefs))
+	for i, a := range armDefs {
+		client, err := getClient(a.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("node %q: %w", nd.ID, err)
+		}
+		arms[i] = nodes.ModelArm{Name: a.Name, Provider: a.Provider, Model: a.Model, Client: client}
+	}
+
+	cfg := nodes.ModelSelectNodeConfig{
+		Arms:   arms,
+		Policy: configString(nd.Config, "policy"),
+		Store:  opts.armStats,
+		Key:    ArmStatsKey(nd, opts.armScope),
+		Random: random,
+		LLM: nodes.LLMNodeConfig{
+			System:          configString(nd.Config, "system_prompt"),
+			PromptTemplate:  configString(nd.Config, "prompt_template"),
+			OutputKey:       configString(nd.Config, "output_key"),
+			Timeout:         configDuration(nd.Config, "timeout"),
+			TemplateSandbox: opts.sandbox,
+		},
+	}
+	if v, ok := configFloat64(nd.Config, "epsilon"); ok {
+		cfg.Epsilon = v
+	}
+	if v, ok := configFloat64(nd.Config, "temperature"); ok {
+		cfg.LLM.Temperature = &v
+	}
+	if v, ok := configInt(nd.Config, "max_tokens"); ok {
+		cfg.LLM.MaxTokens = &v
+	}
+	templateVars, err := buildTemplateVars(nd)
+	if err != nil {
+		return nil, err
+	}
+	cfg.LLM.TemplateVars = templateVars
+
+	node, err := nodes.NewModelSelectNode(nd.ID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// ArmStatsKey returns the statistics key of a model_select node hydrated
+// with WithArmStats scope: its "key" config or "<scope>/<node id>".
+func ArmStatsKey(nd graph.NodeDef, scope string) string {
+	if key := configString(nd.Config, "key"); key != "" {
+		return key
+	}
+	if scope != "" {
+		return scope + "/" + nd.ID
+	}
+	return nd.ID
+}
+
+// buildRewardNode extracts config from a NodeDef and returns a RewardNode.
+func buildRewardNode(nd graph.NodeDef, opts liveFactoryOptions) (core.Node, error) {
+	if opts.armStats == nil {
+		return nil, fmt.Errorf("node %q: reward needs an arm statistics store", nd.ID)
+	}
+	cfg := nodes.RewardNodeConfig{
+		SelectionVar: configString(nd.Config, "selection_var"),
+		RewardVar:    configString(nd.Config, "reward_var"),
+		OutputKey:    configString(nd.Config, "output_key"),
+		Store:        opts.armStats,
+	}
+	if v, ok := configFloat64(nd.Config, "reward"); ok {
+		cfg.Reward = v
+	}
+	node, err := nodes.NewRewardNode(nd.ID, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
 // buildLLMRouter extracts config from a NodeDef and returns an LLMRouter.
 func buildLLMRouter(nd graph.NodeDef, getClient func(string) (core.LLMClient, error)) (core.Node, error) {
 	providerName, _ := nd.Config["provider"].(string)
@@ -339,6 +1050,23 @@ func buildLLMRouter(nd graph.NodeDef, getClient func(string) (core.LLMClient, er
 			}
 		}
 	}
+	if inputVars, ok := configStringSlice(nd.Config, "input_vars"); ok {
+		cfg.InputVars = inputVars
+	}
+	if handoff := configMapAnyMap(nd.Config, "handoff"); handoff != nil {
+		cfg.Handoff = &nodes.RouterHandoff{
+			Task:      configMapString(handoff, "task"),
+			FromAgent: configMapString(handoff, "from_agent"),
+			Targets:   make(map[string]string),
+		}
+		if targets, ok := handoff["targets"].(map[string]any); ok {
+			for target, agent := range targets {
+				if s, ok := agent.(string); ok {
+					cfg.Handoff.Targets[target] = s
+				}
+			}
+		}
+	}
 
 	return nodes.NewLLMRouter(nd.ID, client, cfg), nil
 }
@@ -429,15 +1157,77 @@ func buildHumanNode(nd graph.NodeDef, handler nodes.HumanHandler) (core.Node, er
 		Handler:     handler,
 	}
 
+	if raw, ok := nd.Config["fields"]; ok {
+		fields, err := decodeHumanFields(raw)
+		if err != nil {
+			return nil, fmt.Errorf("node %q: %w", nd.ID, err)
+		}
+		cfg.Fields = fields
+		if cfg.RequestType == "" {
+			cfg.RequestType = nodes.HumanRequestForm
+		}
+	}
+
 	return nodes.NewHumanNode(nd.ID, cfg), nil
 }
 
-// buildConditionalNode creates a ConditionalNode from a NodeDef.
-func buildConditionalNode(nd graph.NodeDef) (core.Node, error) {
+// decodeHumanFields converts the generic "fields" config into validated
+// HumanField definitions.
+func decodeHumanFields(raw any) ([]nodes.HumanField, error) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("fields: %w", err)
+	}
+	var fields []nodes.HumanField
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("fields must be a list of field objects: %w", err)
+	}
+	if err := nodes.ValidateHumanFieldDefs(fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// nodeHolidays returns the factory's holiday calendars with those of the
+// node's holidays config, a map of calendar name to dates, added.
+func nodeHolidays(nd graph.NodeDef, base expr.Holidays) (expr.Holidays, error) {
+	raw, ok := nd.Config["holidays"]
+	if !ok {
+		return base, nil
+	}
+	calendars, ok := raw.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("node %q: holidays must be a map of calendar name to dates", nd.ID)
+	}
+	own := make(expr.Holidays, len(calendars))
+	for name, rawDays := range calendars {
+		days, ok := rawDays.([]any)
+		if !ok {
+			return nil, fmt.Errorf("node %q: holidays %q must be a list of dates", nd.ID, name)
+		}
+		for _, day := range days {
+			s, ok := day.(string)
+			if !ok {
+				return nil, fmt.Errorf("node %q: holidays %q: dates must be strings", nd.ID, name)
+			}
+			own[name] = append(own[name], s)
+		}
+	}
+	if err := own.Validate(); err != nil {
+		return nil, fmt.Errorf("node %q: %w", nd.ID, err)
+	}
+	return base.Merge(own), nil
+}
+
+// buildConditionalNode creates a ConditionalNode from a NodeDef. state may
+// be nil.
+func buildConditionalNode(nd graph.NodeDef, lib *conditional.Library, state func(context.Context) expr.Lookup, holidays expr.Holidays) (core.Node, error) {
 	cfg := conditional.Config{
 		Default:     configString(nd.Config, "default"),
 		PassThrough: true,
 		OutputKey:   configString(nd.Config, "output_key"),
+		State:       state,
+		Holidays:    holidays,
 	}
 
 	if order := configString(nd.Config, "evaluation_order"); order != "" {
@@ -460,12 +1250,43 @@ func buildConditionalNode(nd graph.NodeDef) (core.Node, error) {
 			Expression:  configMapString(m, "expression"),
 			Description: configMapString(m, "description"),
 		}
+		if ref := configMapString(m, "ref"); ref != "" {
+			if cond.Expression != "" {
+				return nil, fmt.Errorf("conditional node %q: condition %q sets both expression and ref", nd.ID, cond.Name)
+			}
+			parsed, expression, params, err := resolveConditionRef(lib, ref)
+			if err != nil {
+				return nil, fmt.Errorf("conditional node %q: %w", nd.ID, err)
+			}
+			if cond.Name == "" {
+				cond.Name = parsed.Name
+			}
+			cond.Expression = expression
+			cond.Params = params
+		}
 		cfg.Conditions = append(cfg.Conditions, cond)
 	}
 
 	return conditional.NewConditionalNode(nd.ID, cfg)
 }
 
+// resolveConditionRef looks up a condition library reference such as
+// business_hours(tz: "UTC").
+func resolveConditionRef(lib *conditional.Library, ref string) (conditional.Ref, string, map[string]any, error) {
+	parsed, err := conditional.ParseRef(ref)
+	if err != nil {
+		return conditional.Ref{}, "", nil, err
+	}
+	if lib == nil {
+		return conditional.Ref{}, "", nil, fmt.Errorf("condition ref %q: no condition library is configured", ref)
+	}
+	expression, params, err := lib.Resolve(ref)
+	if err != nil {
+		return conditional.Ref{}, "", nil, fmt.Errorf("condition ref %q: %w", ref, err)
+	}
+	return parsed, expression, params, nil
+}
+
 func configMapString(m map[string]any, key string) string {
 	v, _ := m[key].(string)
 	return v
@@ -557,35 +1378,57 @@ func cloneAnyMap(m map[string]any) map[string]any {
 }
 
 // buildToolNode creates a ToolNode from a NodeDef and a resolved tool.
-func buildToolNode(nd graph.NodeDef, tool core.PetalTool) *nodes.ToolNode {
-	cfg := nodes.ToolNodeConfig{
-		ToolName:     nd.Type,
-		ArgsTemplate: configStringMap(nd.Config, "args_template"),
-		StaticArgs:   cloneAnyMap(configMapAnyMap(nd.Config, "static_args")),
-		OutputKey:    configString(nd.Config, "output_key"),
-		Timeout:      configDuration(nd.Config, "timeout"),
-	}
-
-	return nodes.NewToolNode(nd.ID, tool, cfg)
+// callbacks and cache may be nil.
+func buildToolNode(nd graph.NodeDef, tool core.PetalTool, callbacks *nodes.ToolCallbacks, cache nodes.CacheStore) (*nodes.ToolNode, error) {
+	return buildToolNodeWithName(nd, nd.Type, tool, callbacks, cache)
 }
 
 // buildToolNodeWithName creates a ToolNode from a NodeDef using an explicit tool name.
-func buildToolNodeWithName(nd graph.NodeDef, toolName string, tool core.PetalTool) *nodes.ToolNode {
+func buildToolNodeWithName(nd graph.NodeDef, toolName string, tool core.PetalTool, callbacks *nodes.ToolCallbacks, cache nodes.CacheStore) (*nodes.ToolNode, error) {
 	cfg := nodes.ToolNodeConfig{
 		ToolName:     toolName,
 		ArgsTemplate: configStringMap(nd.Config, "args_template"),
 		StaticArgs:   cloneAnyMap(configMapAnyMap(nd.Config, "static_args")),
 		OutputKey:    configString(nd.Config, "output_key"),
 		Timeout:      configDuration(nd.Config, "timeout"),
+		Callbacks:    callbacks,
+		AsyncTimeout: configDuration(nd.Config, "async_timeout"),
+		PollInterval: configDuration(nd.Config, "poll_interval"),
+		Cache:        cache,
+	}
+	cfg.OnError = core.ErrorPolicy(configString(nd.Config, "on_error"))
+	cfg.StrictArgs, _ = nd.Config["strict_args"].(bool)
+	bypass, err := nodes.ParseToolCacheBypass(configString(nd.Config, "cache_bypass"))
+	if err != nil {
+		return nil, fmt.Errorf("node %q: %w", nd.ID, err)
+	}
+	cfg.CacheBypass = bypass
+	cfg.PropagateHeaders, err = propagate.ParseOverride(nd.Config["propagate_headers"])
+	if err != nil {
+		return nil, fmt.Errorf("node %q: %w", nd.ID, err)
+	}
+	// Manifest defaults of asynchronous actions apply unless the node
+	// configures its own.
+	if action, ok := tool.(asyncActionTool); ok {
+		if spec := action.asyncSpec(); spec != nil {
+			if cfg.AsyncTimeout == 0 {
+				cfg.AsyncTimeout = time.Duration(spec.TimeoutMS) * time.Millisecond
+			}
+			if cfg.PollInterval == 0 {
+				cfg.PollInterval = time.Duration(spec.PollIntervalMS) * time.Millisecond
+			}
+		}
 	}
 
-	return nodes.NewToolNode(nd.ID, tool, cfg)
+	return nodes.NewToolNode(nd.ID, tool, cfg), nil
 }
 
-func buildRuleRouter(nd graph.NodeDef) (core.Node, error) {
+func buildRuleRouter(nd graph.NodeDef, lib *conditional.Library, state func(context.Context) expr.Lookup, holidays expr.Holidays) (core.Node, error) {
 	cfg := nodes.RuleRouterConfig{
 		DefaultTarget: configString(nd.Config, "default_target"),
 		DecisionKey:   configString(nd.Config, "decision_key"),
+		State:         state,
+		Holidays:      holidays,
 	}
 	if allow, ok := nd.Config["allow_multiple"].(bool); ok {
 		cfg.AllowMultiple = allow
@@ -615,6 +1458,20 @@ func buildRuleRouter(nd graph.NodeDef) (core.Node, error) {
 			if values, ok := condMap["values"].([]any); ok {
 				cond.Values = values
 			}
+			if ref := configMapString(condMap, "ref"); ref != "" {
+				_, expression, params, err := resolveConditionRef(lib, ref)
+				if err != nil {
+					return nil, fmt.Errorf("rule_router node %q: rule %q: %w", nd.ID, rule.Target, err)
+				}
+				cond.Op = nodes.OpExpression
+				cond.Expression = expression
+				cond.Params = params
+			} else if cond.Op == nodes.OpExpression {
+				cond.Expression = configMapString(condMap, "expression")
+				if err := expr.ValidateSyntax(cond.Expression); err != nil {
+					return nil, fmt.Errorf("rule_router node %q: rule %q: %w", nd.ID, rule.Target, err)
+				}
+			}
 			rule.Conditions = append(rule.Conditions, cond)
 		}
 		cfg.Rules = append(cfg.Rules, rule)
@@ -625,10 +1482,11 @@ func buildRuleRouter(nd graph.NodeDef) (core.Node, error) {
 
 func buildFilterNode(nd graph.NodeDef) (core.Node, error) {
 	cfg := nodes.FilterNodeConfig{
-		Target:    nodes.FilterTarget(configString(nd.Config, "target")),
-		InputVar:  configString(nd.Config, "input_var"),
-		OutputVar: configString(nd.Config, "output_var"),
-		StatsVar:  configString(nd.Config, "stats_var"),
+		Target:      nodes.FilterTarget(configString(nd.Config, "target")),
+		InputVar:    configString(nd.Config, "input_var"),
+		OutputVar:   configString(nd.Config, "output_var"),
+		StatsVar:    configString(nd.Config, "stats_var"),
+		Performance: configCollectionPerformance(nd.Config),
 	}
 
 	filtersRaw, _ := nd.Config["filters"].([]any)
@@ -667,8 +1525,9 @@ func buildFilterNode(nd graph.NodeDef) (core.Node, error) {
 	return nodes.NewFilterNode(nd.ID, cfg), nil
 }
 
-func buildTransformNode(nd graph.NodeDef) (core.Node, error) {
+func buildTransformNode(nd graph.NodeDef, sandbox *nodes.TemplateSandbox) (core.Node, error) {
 	cfg := parseTransformConfig(nd.Config)
+	cfg.TemplateSandbox = sandbox
 	return nodes.NewTransformNode(nd.ID, cfg), nil
 }
 
@@ -681,6 +1540,7 @@ func parseTransformConfig(m map[string]any) nodes.TransformNodeConfig {
 		Format:        configString(m, "format"),
 		Separator:     configString(m, "separator"),
 		MergeStrategy: configString(m, "merge_strategy"),
+		Performance:   configCollectionPerformance(m),
 	}
 
 	if inputVars, ok := configStringSlice(m, "input_vars"); ok {
@@ -709,6 +1569,27 @@ func parseTransformConfig(m map[string]any) nodes.TransformNodeConfig {
 	return cfg
 }
 
+// configCollectionPerformance reads "performance", which is either true or
+// an object with parallelism and min_parallel_items.
+func configCollectionPerformance(m map[string]any) *nodes.CollectionPerformance {
+	switch raw := m["performance"].(type) {
+	case bool:
+		if raw {
+			return &nodes.CollectionPerformance{}
+		}
+	case map[string]any:
+		perf := &nodes.CollectionPerformance{}
+		if v, ok := configMapInt(raw, "parallelism"); ok {
+			perf.Parallelism = v
+		}
+		if v, ok := configMapInt(raw, "min_parallel_items"); ok {
+			perf.MinParallelItems = v
+		}
+		return perf
+	}
+	return nil
+}
+
 func buildGateNode(nd graph.NodeDef) (core.Node, error) {
 	cfg := nodes.GateNodeConfig{
 		ConditionVar:   configString(nd.Config, "condition_var"),
@@ -781,6 +1662,59 @@ func buildGuardianNode(nd graph.NodeDef) (core.Node, error) {
 	return nodes.NewGuardianNode(nd.ID, cfg), nil
 }
 
+func buildValidateJSONNode(nd graph.NodeDef) (core.Node, error) {
+	return nodes.NewValidateJSONNode(nd.ID, nodes.ValidateJSONNodeConfig{
+		InputVar:    configString(nd.Config, "input_var"),
+		Schema:      configMapAnyMap(nd.Config, "schema"),
+		ResultVar:   configString(nd.Config, "result_var"),
+		OnFail:      nodes.ValidateJSONAction(configString(nd.Config, "on_fail")),
+		ValidTarget: configString(nd.Config, "valid_target"),
+		ErrorTarget: configString(nd.Config, "error_target"),
+	})
+}
+
+func buildNormalizeContentNode(nd graph.NodeDef) (core.Node, error) {
+	keepQuotes, _ := nd.Config["keep_quoted_replies"].(bool)
+	keepSignatures, _ := nd.Config["keep_signatures"].(bool)
+	return nodes.NewNormalizeContentNode(nd.ID, nodes.NormalizeContentNodeConfig{
+		InputVar:          configString(nd.Config, "input_var"),
+		Field:             configString(nd.Config, "field"),
+		OutputKey:         configString(nd.Config, "output_key"),
+		MetadataKey:       configString(nd.Config, "metadata_key"),
+		InputFormat:       nodes.ContentFormat(configString(nd.Config, "input_format")),
+		OutputFormat:      nodes.ContentFormat(configString(nd.Config, "output_format")),
+		KeepQuotedReplies: keepQuotes,
+		KeepSignatures:    keepSignatures,
+		Emoji:             nodes.EmojiMode(configString(nd.Config, "emoji")),
+	})
+}
+
+func buildCalcNode(nd graph.NodeDef) (core.Node, error) {
+	cfg := nodes.CalcNodeConfig{
+		Rounding:       nodes.RoundingMode(configString(nd.Config, "rounding")),
+		OnDivideByZero: configString(nd.Config, "on_divide_by_zero"),
+		Output:         configString(nd.Config, "output"),
+		AuditKey:       configString(nd.Config, "audit_key"),
+	}
+	if v, ok := configInt(nd.Config, "scale"); ok {
+		cfg.Scale = &v
+	}
+	raw, err := json.Marshal(nd.Config["formulas"])
+	if err == nil {
+		err = json.Unmarshal(raw, &cfg.Formulas)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("node %q: calc formulas: %w", nd.ID, err)
+	}
+	return nodes.NewCalcNode(nd.ID, cfg)
+}
+
+func buildPublishNode(nd graph.NodeDef) (core.Node, error) {
+	vars, _ := configStringSlice(nd.Config, "vars")
+	require, _ := nd.Config["require"].(bool)
+	return nodes.NewPublishNode(nd.ID, nodes.PublishNodeConfig{Vars: vars, Require: require})
+}
+
 func buildWebhookTriggerNode(nd graph.NodeDef) (core.Node, error) {
 	cfg, err := nodes.ParseWebhookTriggerConfig(nd.Config)
 	if err != nil {
@@ -789,10 +1723,12 @@ func buildWebhookTriggerNode(nd graph.NodeDef) (core.Node, error) {
 	return nodes.NewWebhookTriggerNode(nd.ID, cfg), nil
 }
 
-func buildWebhookCallNode(nd graph.NodeDef) (core.Node, error) {
+func buildWebhookCallNode(nd graph.NodeDef, opts liveFactoryOptions) (core.Node, error) {
 	cfg, err := nodes.ParseWebhookCallConfig(nd.Config)
 	if err != nil {
 		return nil, fmt.Errorf("node %q: invalid webhook_call config: %w", nd.ID, err)
 	}
+	cfg.TemplateSandbox = opts.sandbox
+	cfg.Outbox = opts.outbox
 	return nodes.NewWebhookCallNode(nd.ID, cfg), nil
 }
@@ -2,6 +2,7 @@ package hydrate
 
 import (
 	"context"
+	"errors"
 	"math"
 	"strings"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	"github.com/petal-labs/petalflow/nodes"
 	condnode "github.com/petal-labs/petalflow/nodes/conditional"
 	"github.com/petal-labs/petalflow/registry"
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 // mockLLMClient implements core.LLMClient for testing.
@@ -387,6 +389,216 @@ func TestNewLiveNodeFactory_HumanNode_MissingHandler(t *testing.T) {
 	}
 }
 
+func TestNewLiveNodeFactory_HumanNode_Fields(t *testing.T) {
+	factory, _ := newMockClientFactory()
+	nodeFactory := NewLiveNodeFactory(ProviderMap{}, factory, WithHumanHandler(&mockHumanHandler{}))
+
+	node, err := nodeFactory(graph.NodeDef{
+		ID:   "refund",
+		Type: "human",
+		Config: map[string]any{
+			"prompt": "Approve refund",
+			"fields": []any{
+				map[string]any{"name": "amount", "type": "number", "required": true},
+				map[string]any{"name": "reason", "type": "choice", "options": []any{"late", "damaged"}},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := node.(*nodes.HumanNode).Config()
+	if cfg.RequestType != nodes.HumanRequestForm {
+		t.Errorf("RequestType = %q, want form when fields are set", cfg.RequestType)
+	}
+	if len(cfg.Fields) != 2 || cfg.Fields[1].Options[1] != "damaged" {
+		t.Errorf("Fields = %+v", cfg.Fields)
+	}
+
+	_, err = nodeFactory(graph.NodeDef{
+		ID:     "refund",
+		Type:   "human",
+		Config: map[string]any{"fields": []any{map[string]any{"name": "reason", "type": "choice"}}},
+	})
+	if err == nil || !strings.Contains(err.Error(), "needs options") {
+		t.Fatalf("expected invalid field error, got %v", err)
+	}
+}
+
+func TestNewLiveNodeFactory_ValidateJSONRoutesInvalidInput(t *testing.T) {
+	factory, _ := newMockClientFactory()
+	gd := &graph.GraphDefinition{
+		ID:      "orders",
+		Version: "1.0",
+		Nodes: []graph.NodeDef{
+			{ID: "check", Type: "validate_json", Config: map[string]any{
+				"input_var":    "order",
+				"schema":       map[string]any{"type": "object", "required": []any{"id"}},
+				"on_fail":      "route",
+				"valid_target": "process",
+				"error_target": "reject",
+			}},
+			{ID: "process", Type: "transform", Config: map[string]any{"transform": "template", "template": "ok", "output_var": "path"}},
+			{ID: "reject", Type: "transform", Config: map[string]any{"transform": "template", "template": "rejected", "output_var": "path"}},
+		},
+		Edges: []graph.EdgeDef{
+			{Source: "check", Target: "process"},
+			{Source: "check", Target: "reject"},
+		},
+		Entry: "check",
+	}
+	g, err := HydrateGraph(gd, ProviderMap{}, NewLiveNodeFactory(ProviderMap{}, factory))
+	if err != nil {
+		t.Fatalf("HydrateGraph: %v", err)
+	}
+
+	env := core.NewEnvelope()
+	env.SetVar("order", map[string]any{"total": 3})
+	result, err := runtime.NewRuntime().Run(context.Background(), g, env, runtime.DefaultRunOptions())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if path, _ := result.GetVar("path"); path != "rejected" {
+		t.Errorf("path = %v, want rejected", path)
+	}
+	validation, _ := result.GetVar("check_result")
+	if r, ok := validation.(nodes.ValidateJSONResult); !ok || r.Valid || r.Errors[0].SchemaPath != "/required" {
+		t.Errorf("check_result = %#v", validation)
+	}
+}
+
+func TestNewLiveNodeFactory_NormalizeContent(t *testing.T) {
+	factory, _ := newMockClientFactory()
+	build := NewLiveNodeFactory(ProviderMap{}, factory)
+	node, err := build(graph.NodeDef{ID: "clean", Type: "normalize_content", Config: map[string]any{
+		"input_var":           "ticket.body",
+		"field":               "text",
+		"output_key":          "clean_body",
+		"metadata_key":        "clean_meta",
+		"input_format":        "html",
+		"output_format":       "markdown",
+		"keep_quoted_replies": true,
+		"emoji":               "strip",
+	}})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	cfg := node.(*nodes.NormalizeContentNode).Config()
+	if cfg.InputVar != "ticket.body" || cfg.Field != "text" || cfg.OutputKey != "clean_body" || cfg.MetadataKey != "clean_meta" ||
+		cfg.InputFormat != nodes.ContentFormatHTML || cfg.OutputFormat != nodes.ContentFormatMarkdown ||
+		!cfg.KeepQuotedReplies || cfg.KeepSignatures || cfg.Emoji != nodes.EmojiStrip {
+		t.Errorf("config = %+v", cfg)
+	}
+
+	_, err = build(graph.NodeDef{ID: "bad", Type: "normalize_content", Config: map[string]any{"input_var": "x", "emoji": "remove"}})
+	if err == nil || !strings.Contains(err.Error(), "emoji") {
+		t.Errorf("expected emoji mode error, got %v", err)
+	}
+}
+
+func TestNewLiveNodeFactory_Calc(t *testing.T) {
+	factory, _ := newMockClientFactory()
+	build := NewLiveNodeFactory(ProviderMap{}, factory)
+	node, err := build(graph.NodeDef{ID: "totals", Type: "calc", Config: map[string]any{
+		"formulas": []any{
+			map[string]any{"key": "total", "expr": "price * qty", "currency": "JPY"},
+			map[string]any{"key": "share", "expr": "1 / 3", "scale": 4},
+		},
+		"rounding":          "half_even",
+		"on_divide_by_zero": "zero",
+		"audit_key":         "math",
+	}})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	cfg := node.(*nodes.CalcNode).Config()
+	if len(cfg.Formulas) != 2 || cfg.Rounding != nodes.RoundHalfEven || cfg.OnDivideByZero != nodes.DivideByZeroZero || cfg.AuditKey != "math" {
+		t.Errorf("config = %+v", cfg)
+	}
+
+	env := core.NewEnvelope().
+		WithVar("price", map[string]any{"amount": "1250.5", "currency": "JPY"}).
+		WithVar("qty", 3)
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if total, _ := result.GetVar("total"); total != "3752" {
+		t.Errorf("total = %v, want 3752", total)
+	}
+	if share, _ := result.GetVar("share"); share != "0.3333" {
+		t.Errorf("share = %v, want 0.3333", share)
+	}
+
+	_, err = build(graph.NodeDef{ID: "bad", Type: "calc", Config: map[string]any{
+		"formulas": []any{map[string]any{"key": "x", "expr": "1 +"}},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "formula \"x\"") {
+		t.Errorf("expected formula parse error, got %v", err)
+	}
+}
+
+func TestNewLiveNodeFactory_ModelSelect(t *testing.T) {
+	providers := ProviderMap{
+		"anthropic": {APIKey: "sk-test"},
+		"openai":    {APIKey: "sk-test"},
+	}
+	factory, calls := newMockClientFactory()
+	store := nodes.NewMemoryArmStats()
+	build := NewLiveNodeFactory(providers, factory, WithArmStats(store, "wf-1"))
+
+	node, err := build(graph.NodeDef{ID: "pick", Type: "model_select", Config: map[string]any{
+		"arms": []any{
+			map[string]any{"provider": "anthropic", "model": "claude-haiku-4-5"},
+			map[string]any{"provider": "openai", "model": "gpt-4o-mini", "name": "mini"},
+		},
+		"policy":          "ucb1",
+		"prompt_template": "Answer: {{.question}}",
+		"output_key":      "answer",
+		"max_tokens":      float64(256),
+	}})
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	cfg := node.(*nodes.ModelSelectNode).Config()
+	if cfg.Policy != nodes.BanditUCB1 || cfg.Key != "wf-1/pick" || cfg.Store != store || cfg.LLM.OutputKey != "answer" ||
+		cfg.LLM.MaxTokens == nil || *cfg.LLM.MaxTokens != 256 {
+		t.Errorf("config = %+v", cfg)
+	}
+	if len(cfg.Arms) != 2 || cfg.Arms[0].Name != "anthropic/claude-haiku-4-5" || cfg.Arms[1].Name != "mini" {
+		t.Errorf("arms = %+v", cfg.Arms)
+	}
+	if calls["anthropic"] != 1 || calls["openai"] != 1 {
+		t.Errorf("client factory calls = %v", calls)
+	}
+
+	reward, err := build(graph.NodeDef{ID: "score", Type: "reward", Config: map[string]any{
+		"selection_var": "answer_selection",
+		"reward_var":    "rating",
+	}})
+	if err != nil {
+		t.Fatalf("build reward: %v", err)
+	}
+	if rc := reward.(*nodes.RewardNode).Config(); rc.SelectionVar != "answer_selection" || rc.RewardVar != "rating" || rc.Store != store {
+		t.Errorf("reward config = %+v", rc)
+	}
+
+	_, err = build(graph.NodeDef{ID: "bad", Type: "model_select", Config: map[string]any{
+		"arms": []any{map[string]any{"provider": "mistral", "model": "small"}},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "mistral") {
+		t.Errorf("expected unconfigured provider error, got %v", err)
+	}
+
+	noStore := NewLiveNodeFactory(providers, factory)
+	_, err = noStore(graph.NodeDef{ID: "pick", Type: "model_select", Config: map[string]any{
+		"arms": []any{map[string]any{"provider": "openai", "model": "gpt-4o-mini"}},
+	}})
+	if err == nil || !strings.Contains(err.Error(), "arm statistics store") {
+		t.Errorf("expected missing store error, got %v", err)
+	}
+}
+
 // --- Tool node tests ---
 
 // mockTool implements core.PetalTool for testing.
@@ -484,6 +696,50 @@ func TestNewLiveNodeFactory_NoopNode(t *testing.T) {
 	}
 }
 
+func TestNewLiveNodeFactory_Holidays(t *testing.T) {
+	nodeFactory := NewLiveNodeFactory(ProviderMap{}, nil, WithHolidays(map[string][]string{
+		"default": {"12-25"},
+	}))
+
+	node, err := nodeFactory(graph.NodeDef{ID: "route", Type: "conditional", Config: map[string]any{
+		"default":    "open",
+		"conditions": []any{map[string]any{"name": "closed", "expression": "isHoliday()"}},
+		"holidays":   map[string]any{"default": []any{"2026-01-02"}, "uk": []any{"12-26"}},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	holidays := node.(*condnode.ConditionalNode).Config().Holidays
+	if got := holidays["default"]; len(got) != 2 || got[0] != "12-25" || got[1] != "2026-01-02" {
+		t.Errorf("default calendar = %v, want [12-25 2026-01-02]", got)
+	}
+	if got := holidays["uk"]; len(got) != 1 {
+		t.Errorf("uk calendar = %v, want [12-26]", got)
+	}
+
+	node, err = nodeFactory(graph.NodeDef{ID: "router", Type: "rule_router", Config: map[string]any{
+		"rules": []any{map[string]any{
+			"target":     "queue",
+			"conditions": []any{map[string]any{"op": "expression", "expression": "isHoliday()"}},
+		}},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := node.(*nodes.RuleRouter).Config().Holidays["default"]; len(got) != 1 {
+		t.Errorf("rule_router default calendar = %v, want [12-25]", got)
+	}
+
+	_, err = nodeFactory(graph.NodeDef{ID: "bad", Type: "conditional", Config: map[string]any{
+		"default":    "open",
+		"conditions": []any{map[string]any{"name": "closed", "expression": "isHoliday()"}},
+		"holidays":   map[string]any{"default": []any{"Christmas"}},
+	}})
+	if err == nil || !strings.Contains(err.Error(), `"Christmas" must be YYYY-MM-DD or MM-DD`) {
+		t.Fatalf("error = %v, want invalid date error", err)
+	}
+}
+
 func TestNewLiveNodeFactory_ConditionalNode(t *testing.T) {
 	factory, _ := newMockClientFactory()
 	nodeFactory := NewLiveNodeFactory(ProviderMap{}, factory)
@@ -717,6 +973,52 @@ func TestNewLiveNodeFactory_MapAndCacheBindingErrors(t *testing.T) {
 	}
 }
 
+func TestNewLiveNodeFactory_MapStreamBinding(t *testing.T) {
+	factory, _ := newMockClientFactory()
+	nodeFactory := NewLiveNodeFactory(ProviderMap{}, factory)
+
+	mapNode, err := nodeFactory(graph.NodeDef{
+		ID:   "m1",
+		Type: "map",
+		Config: map[string]any{
+			"input_var":    "items",
+			"stream_order": "input",
+			"stream_var":   "partial",
+			"mapper_binding": map[string]any{
+				"type": "noop",
+			},
+			"stream_binding": map[string]any{
+				"type": "noop",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("map node: unexpected error: %v", err)
+	}
+	cfg := mapNode.(*nodes.MapNode).Config()
+	if cfg.StreamNode == nil || !cfg.Stream {
+		t.Fatal("stream binding should enable streaming with a consumer")
+	}
+	if cfg.StreamNode.ID() != "m1__stream" {
+		t.Fatalf("stream node id = %q, want m1__stream", cfg.StreamNode.ID())
+	}
+	if cfg.StreamOrder != nodes.MapStreamOrderInput || cfg.StreamVar != "partial" {
+		t.Fatalf("stream order/var = %q/%q", cfg.StreamOrder, cfg.StreamVar)
+	}
+
+	_, err = nodeFactory(graph.NodeDef{
+		ID:   "m2",
+		Type: "map",
+		Config: map[string]any{
+			"stream_order":   "random",
+			"mapper_binding": map[string]any{"type": "noop"},
+		},
+	})
+	if err == nil || !strings.Contains(err.Error(), "stream_order") {
+		t.Fatalf("invalid stream_order error = %v", err)
+	}
+}
+
 func TestNewLiveNodeFactory_RuleRouterNode(t *testing.T) {
 	factory, _ := newMockClientFactory()
 	nodeFactory := NewLiveNodeFactory(ProviderMap{}, factory)
@@ -783,6 +1085,10 @@ func TestNewLiveNodeFactory_FilterNode(t *testing.T) {
 			"input_var":  "items",
 			"output_var": "filtered",
 			"stats_var":  "stats",
+			"performance": map[string]any{
+				"parallelism":        float64(4),
+				"min_parallel_items": float64(500),
+			},
 			"filters": []any{
 				map[string]any{
 					"type":          "top_n",
@@ -829,6 +1135,9 @@ func TestNewLiveNodeFactory_FilterNode(t *testing.T) {
 	if len(op.IncludeTypes) != 2 || len(op.ExcludeTypes) != 1 {
 		t.Fatalf("unexpected type filters: include=%v exclude=%v", op.IncludeTypes, op.ExcludeTypes)
 	}
+	if cfg.Performance == nil || cfg.Performance.Parallelism != 4 || cfg.Performance.MinParallelItems != 500 {
+		t.Fatalf("Performance = %#v, want parallelism 4, min_parallel_items 500", cfg.Performance)
+	}
 }
 
 func TestNewLiveNodeFactory_TransformNode(t *testing.T) {
@@ -849,6 +1158,7 @@ func TestNewLiveNodeFactory_TransformNode(t *testing.T) {
 			"input_vars":     []any{"a", "b"},
 			"fields":         []any{"name", "meta.score"},
 			"max_depth":      float64(3),
+			"performance":    true,
 			"mapping": map[string]any{
 				"old": "new",
 				"bad": float64(1),
@@ -887,6 +1197,12 @@ func TestNewLiveNodeFactory_TransformNode(t *testing.T) {
 	if cfg.ItemTransform == nil || cfg.ItemTransform.Transform != nodes.TransformPick {
 		t.Fatalf("unexpected item transform: %#v", cfg.ItemTransform)
 	}
+	if cfg.Performance == nil || *cfg.Performance != (nodes.CollectionPerformance{}) {
+		t.Fatalf("Performance = %#v, want defaults", cfg.Performance)
+	}
+	if cfg.ItemTransform.Performance != nil {
+		t.Fatal("item transform should not inherit performance")
+	}
 }
 
 func TestNewLiveNodeFactory_GateNode(t *testing.T) {
@@ -1024,6 +1340,37 @@ func TestNewLiveNodeFactory_WebhookCallNode(t *testing.T) {
 	}
 }
 
+type stubOutbox struct{}
+
+func (stubOutbox) EnqueueDelivery(_ context.Context, d nodes.OutboxDelivery) (nodes.OutboxDelivery, error) {
+	return d, nil
+}
+
+func TestNewLiveNodeFactory_WebhookCallNodeOutbox(t *testing.T) {
+	factory, _ := newMockClientFactory()
+	outbox := stubOutbox{}
+	nodeFactory := NewLiveNodeFactory(ProviderMap{}, factory, WithOutbox(outbox))
+
+	node, err := nodeFactory(graph.NodeDef{
+		ID:   "webhook_call",
+		Type: "webhook_call",
+		Config: map[string]any{
+			"url":      "https://example.com/webhook",
+			"delivery": "outbox",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg := node.(*nodes.WebhookCallNode).Config()
+	if cfg.Delivery != nodes.WebhookCallDeliveryOutbox {
+		t.Fatalf("Delivery = %q, want %q", cfg.Delivery, nodes.WebhookCallDeliveryOutbox)
+	}
+	if cfg.Outbox == nil {
+		t.Fatal("Outbox = nil, want the factory outbox")
+	}
+}
+
 func TestNewLiveNodeFactory_WebhookTriggerNode(t *testing.T) {
 	factory, _ := newMockClientFactory()
 	nodeFactory := NewLiveNodeFactory(ProviderMap{}, factory)
@@ -1082,6 +1429,8 @@ func TestNewLiveNodeFactory_BuiltinTypeConformance(t *testing.T) {
 		factory,
 		WithToolRegistry(toolRegistry),
 		WithHumanHandler(handler),
+		WithArmStats(nodes.NewMemoryArmStats(), "wf"),
+		WithStateStore(nodes.NewMemoryStateStore(), "wf"),
 	)
 
 	type caseDef struct {
@@ -1110,12 +1459,108 @@ func TestNewLiveNodeFactory_BuiltinTypeConformance(t *testing.T) {
 				},
 			},
 		},
+		"compact_messages": {
+			node: graph.NodeDef{
+				ID:   "n-compact-messages",
+				Type: "compact_messages",
+				Config: map[string]any{
+					"provider":   "anthropic",
+					"max_tokens": float64(4000),
+				},
+			},
+		},
+		"translate": {
+			node: graph.NodeDef{
+				ID:   "n-translate",
+				Type: "translate",
+				Config: map[string]any{
+					"provider":        "anthropic",
+					"input_var":       "text",
+					"target_language": "de",
+				},
+			},
+		},
+		"summarize": {
+			node: graph.NodeDef{
+				ID:   "n-summarize",
+				Type: "summarize",
+				Config: map[string]any{
+					"provider":  "anthropic",
+					"input_var": "text",
+					"preset":    "bullet",
+				},
+			},
+		},
+		"classify": {
+			node: graph.NodeDef{
+				ID:   "n-classify",
+				Type: "classify",
+				Config: map[string]any{
+					"provider":  "anthropic",
+					"input_var": "text",
+					"labels":    []any{"billing", "shipping"},
+				},
+			},
+		},
+		"extract_entities": {
+			node: graph.NodeDef{
+				ID:   "n-extract-entities",
+				Type: "extract_entities",
+				Config: map[string]any{
+					"provider":  "anthropic",
+					"input_var": "text",
+					"fields":    []any{map[string]any{"name": "name", "required": true}},
+				},
+			},
+		},
+		"model_select": {
+			node: graph.NodeDef{
+				ID:   "n-model-select",
+				Type: "model_select",
+				Config: map[string]any{
+					"arms": []any{
+						map[string]any{"provider": "anthropic", "model": "claude-haiku-4-5"},
+					},
+				},
+			},
+		},
+		"reward": {
+			node: graph.NodeDef{
+				ID:   "n-reward",
+				Type: "reward",
+				Config: map[string]any{
+					"selection_var": "n-model-select_output_selection",
+					"reward":        1.0,
+				},
+			},
+		},
 		"rule_router": {
 			node: graph.NodeDef{
 				ID:   "n-rule-router",
 				Type: "rule_router",
 			},
 		},
+		"state_get": {
+			node: graph.NodeDef{
+				ID:     "n-state-get",
+				Type:   "state_get",
+				Config: map[string]any{"key": "visits"},
+			},
+		},
+		"state_set": {
+			node: graph.NodeDef{
+				ID:     "n-state-set",
+				Type:   "state_set",
+				Config: map[string]any{"key": "visits", "value": 0.0},
+			},
+		},
+		"state_incr": {
+			node: graph.NodeDef{
+				ID:     "n-state-incr",
+				Type:   "state_incr",
+				Config: map[string]any{"key": "visits"},
+			},
+		},
 		"filter": {
 			node: graph.NodeDef{
 				ID:   "n-filter",
@@ -1155,6 +1600,43 @@ func TestNewLiveNodeFactory_BuiltinTypeConformance(t *testing.T) {
 				Type: "guardian",
 			},
 		},
+		"validate_json": {
+			node: graph.NodeDef{
+				ID:   "n-validate-json",
+				Type: "validate_json",
+				Config: map[string]any{
+					"input_var": "payload",
+					"schema":    map[string]any{"type": "object"},
+				},
+			},
+		},
+		"normalize_content": {
+			node: graph.NodeDef{
+				ID:   "n-normalize-content",
+				Type: "normalize_content",
+				Config: map[string]any{
+					"input_var": "body",
+				},
+			},
+		},
+		"publish": {
+			node: graph.NodeDef{
+				ID:   "n-publish",
+				Type: "publish",
+				Config: map[string]any{
+					"vars": []any{"documents"},
+				},
+			},
+		},
+		"calc": {
+			node: graph.NodeDef{
+				ID:   "n-calc",
+				Type: "calc",
+				Config: map[string]any{
+					"formulas": []any{map[string]any{"key": "total", "expr": "1 + 2"}},
+				},
+			},
+		},
 		"human": {
 			node: graph.NodeDef{
 				ID:   "n-human",
@@ -1221,6 +1703,20 @@ func TestNewLiveNodeFactory_BuiltinTypeConformance(t *testing.T) {
 				Type: "noop",
 			},
 		},
+		"subgraph": {
+			node: graph.NodeDef{
+				ID:   "n-subgraph",
+				Type: "subgraph",
+				Config: map[string]any{
+					"graph": map[string]any{
+						"id":      "inner",
+						"version": "1.0",
+						"nodes":   []any{map[string]any{"id": "x", "type": "noop"}},
+						"entry":   "x",
+					},
+				},
+			},
+		},
 		"func": {
 			node: graph.NodeDef{
 				ID:   "n-func",
@@ -1300,3 +1796,280 @@ func TestConfigHelpers_EdgeCases(t *testing.T) {
 		t.Fatalf("configMapAnyMap returned %v, want key k=v", got)
 	}
 }
+
+func TestNewLiveNodeFactory_ConditionRefs(t *testing.T) {
+	lib, err := condnode.NewLibrary([]condnode.Definition{{
+		Name:       "over_limit",
+		Expression: "input.amount > limit",
+		Params:     []condnode.Param{{Name: "limit", Type: condnode.ParamTypeNumber, Required: true}},
+	}})
+	if err != nil {
+		t.Fatalf("NewLibrary: %v", err)
+	}
+	factory, _ := newMockClientFactory()
+	nodeFactory := NewLiveNodeFactory(ProviderMap{}, factory, WithConditionLibrary(lib))
+
+	node, err := nodeFactory(graph.NodeDef{
+		ID:   "route",
+		Type: "conditional",
+		Config: map[string]any{
+			"default":    "ok",
+			"conditions": []any{map[string]any{"ref": "over_limit(limit: 100)"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("conditional: %v", err)
+	}
+	cond := node.(*condnode.ConditionalNode).Config().Conditions[0]
+	if cond.Name != "over_limit" || cond.Expression != "input.amount > limit" || cond.Params["limit"] != float64(100) {
+		t.Fatalf("resolved condition = %+v", cond)
+	}
+
+	node, err = nodeFactory(graph.NodeDef{
+		ID:   "router",
+		Type: "rule_router",
+		Config: map[string]any{
+			"rules": []any{map[string]any{
+				"target":     "review",
+				"conditions": []any{map[string]any{"ref": "over_limit(limit: 5)"}},
+			}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("rule_router: %v", err)
+	}
+	rc := node.(*nodes.RuleRouter).Config().Rules[0].Conditions[0]
+	if rc.Op != nodes.OpExpression || rc.Params["limit"] != float64(5) {
+		t.Fatalf("resolved route condition = %+v", rc)
+	}
+
+	tests := map[string]struct {
+		factory NodeFactory
+		config  map[string]any
+		wantErr string
+	}{
+		"unknown ref": {
+			factory: nodeFactory,
+			config:  map[string]any{"conditions": []any{map[string]any{"ref": "missing"}}},
+			wantErr: `unknown condition "missing"`,
+		},
+		"bad params": {
+			factory: nodeFactory,
+			config:  map[string]any{"conditions": []any{map[string]any{"ref": "over_limit"}}},
+			wantErr: `missing required parameter "limit"`,
+		},
+		"ref and expression": {
+			factory: nodeFactory,
+			config:  map[string]any{"conditions": []any{map[string]any{"name": "a", "ref": "over_limit(limit: 1)", "expression": "true"}}},
+			wantErr: "sets both expression and ref",
+		},
+		"no library": {
+			factory: NewLiveNodeFactory(ProviderMap{}, factory),
+			config:  map[string]any{"conditions": []any{map[string]any{"ref": "over_limit(limit: 1)"}}},
+			wantErr: "no condition library is configured",
+		},
+	}
+	for name, tt := range tests {
+		_, err := tt.factory(graph.NodeDef{ID: "route", Type: "conditional", Config: tt.config})
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: error = %v, want %q", name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewLiveNodeFactory_LLMPromptDriftGuard(t *testing.T) {
+	providers := ProviderMap{"anthropic": {APIKey: "sk-test"}}
+	factory, _ := newMockClientFactory()
+	history := nodes.NewMemoryOutputHistory()
+	nd := graph.NodeDef{
+		ID:   "summarizer",
+		Type: "llm_prompt",
+		Config: map[string]any{
+			"provider": "anthropic",
+			"model":    "claude-haiku-4-5",
+			"drift_guard": map[string]any{
+				"action":         "fail",
+				"min_samples":    float64(5),
+				"min_similarity": 0.4,
+				"fields":         []any{"title"},
+			},
+		},
+	}
+
+	node, err := NewLiveNodeFactory(providers, factory, WithOutputHistory(history, "wf-1"))(nd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	guard := node.(*nodes.LLMNode).Config().DriftGuard
+	if guard == nil {
+		t.Fatal("expected drift guard")
+	}
+	if guard.Key != "wf-1/summarizer" {
+		t.Errorf("Key = %q, want %q", guard.Key, "wf-1/summarizer")
+	}
+	if guard.Action != nodes.DriftActionFail || guard.MinSamples != 5 || guard.MinSimilarity != 0.4 {
+		t.Errorf("guard = %+v", guard)
+	}
+	if len(guard.Fields) != 1 || guard.Fields[0] != "title" {
+		t.Errorf("Fields = %v, want [title]", guard.Fields)
+	}
+
+	if _, err := NewLiveNodeFactory(providers, factory)(nd); err == nil || !strings.Contains(err.Error(), "output history store") {
+		t.Fatalf("expected missing history store error, got %v", err)
+	}
+
+	nd.Config["drift_guard"] = map[string]any{"action": "explode"}
+	if _, err := NewLiveNodeFactory(providers, factory, WithOutputHistory(history, ""))(nd); err == nil {
+		t.Fatal("expected invalid action error")
+	}
+}
+
+func TestNewLiveNodeFactory_LLMPromptCache(t *testing.T) {
+	providers := ProviderMap{"anthropic": {APIKey: "sk-test"}}
+	factory, _ := newMockClientFactory()
+	nd := graph.NodeDef{
+		ID:   "answer",
+		Type: "llm_prompt",
+		Config: map[string]any{
+			"provider": "anthropic",
+			"model":    "claude-haiku-4-5",
+			"prompt_cache": map[string]any{
+				"system":       true,
+				"context_vars": []any{"handbook"},
+				"ttl":          "1h",
+			},
+		},
+	}
+
+	node, err := NewLiveNodeFactory(providers, factory)(nd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pc := node.(*nodes.LLMNode).Config().PromptCache
+	if pc == nil {
+		t.Fatal("expected prompt cache config")
+	}
+	if !pc.System || pc.TTL != time.Hour || len(pc.ContextVars) != 1 || pc.ContextVars[0] != "handbook" {
+		t.Errorf("PromptCache = %+v", pc)
+	}
+
+	nd.Config["prompt_cache"] = true
+	if _, err := NewLiveNodeFactory(providers, factory)(nd); err == nil || !strings.Contains(err.Error(), "prompt_cache must be an object") {
+		t.Fatalf("expected prompt_cache error, got %v", err)
+	}
+}
+
+func TestNewLiveNodeFactory_LLMTemplateVars(t *testing.T) {
+	providers := ProviderMap{"anthropic": {APIKey: "sk-test"}}
+	factory, _ := newMockClientFactory()
+	nd := graph.NodeDef{
+		ID:   "answer",
+		Type: "llm_prompt",
+		Config: map[string]any{
+			"provider":        "anthropic",
+			"model":           "claude-haiku-4-5",
+			"prompt_template": "{{.tone}} {{.question}}",
+			"template_vars": map[string]any{
+				"tone":     map[string]any{"default": "brief"},
+				"question": map[string]any{"required": true},
+			},
+		},
+	}
+
+	node, err := NewLiveNodeFactory(providers, factory)(nd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	vars := node.(*nodes.LLMNode).Config().TemplateVars
+	if vars["tone"].Default != "brief" || vars["tone"].Required || !vars["question"].Required {
+		t.Errorf("TemplateVars = %+v", vars)
+	}
+
+	nd.Config["template_vars"] = map[string]any{"question": map[string]any{"required": "yes"}}
+	if _, err := NewLiveNodeFactory(providers, factory)(nd); err == nil || !strings.Contains(err.Error(), "required must be a boolean") {
+		t.Fatalf("expected required error, got %v", err)
+	}
+}
+
+func TestNewLiveNodeFactory_TemplateSandbox(t *testing.T) {
+	nd := graph.NodeDef{
+		ID:   "render",
+		Type: "transform",
+		Config: map[string]any{
+			"transform":  "template",
+			"template":   `{{call .fn}}`,
+			"output_var": "out",
+		},
+	}
+	env := core.NewEnvelope().WithVar("fn", func() string { return "called" })
+
+	open, err := NewLiveNodeFactory(nil, nil)(nd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := open.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("unsandboxed run: %v", err)
+	}
+	if out, _ := result.GetVar("out"); out != "called" {
+		t.Fatalf("out = %v, want called", out)
+	}
+
+	sandboxed, err := NewLiveNodeFactory(nil, nil, WithTemplateSandbox(nodes.DefaultTemplateSandbox()))(nd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = sandboxed.Run(context.Background(), env)
+	var sbErr *nodes.TemplateSandboxError
+	if !errors.As(err, &sbErr) || sbErr.Rule != nodes.SandboxRuleFunction {
+		t.Fatalf("err = %v, want function sandbox violation", err)
+	}
+}
+
+func TestNewLiveNodeFactory_Translate(t *testing.T) {
+	factory, _ := newMockClientFactory()
+	nodeFactory := NewLiveNodeFactory(ProviderMap{"anthropic": {APIKey: "sk-test"}}, factory)
+
+	node, err := nodeFactory(graph.NodeDef{
+		ID:   "localize",
+		Type: "translate",
+		Config: map[string]any{
+			"provider":         "anthropic",
+			"model":            "claude-haiku-4-5",
+			"input_var":        "tickets",
+			"field":            "body",
+			"source_language":  "auto",
+			"target_language":  "de",
+			"glossary":         map[string]any{"workspace": "Arbeitsbereich"},
+			"do_not_translate": []any{"PetalFlow"},
+			"batch_size":       float64(5),
+			"strict":           true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tn, ok := node.(*nodes.TranslateNode)
+	if !ok {
+		t.Fatalf("expected *nodes.TranslateNode, got %T", node)
+	}
+	cfg := tn.Config()
+	if cfg.InputVar != "tickets" || cfg.Field != "body" || cfg.SourceLanguage != "" || cfg.TargetLanguage != "de" {
+		t.Errorf("config = %+v", cfg)
+	}
+	if cfg.Glossary["workspace"] != "Arbeitsbereich" || len(cfg.DoNotTranslate) != 1 || cfg.BatchSize != 5 || !cfg.Strict {
+		t.Errorf("config = %+v", cfg)
+	}
+	if cfg.OutputKey != "localize_output" || cfg.QualityKey != "localize_output_quality" {
+		t.Errorf("keys = %q, %q", cfg.OutputKey, cfg.QualityKey)
+	}
+
+	_, err = nodeFactory(graph.NodeDef{
+		ID:     "localize",
+		Type:   "translate",
+		Config: map[string]any{"provider": "anthropic", "input_var": "text"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "target_language") {
+		t.Fatalf("err = %v, want target_language error", err)
+	}
+}
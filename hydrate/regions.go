@@ -0,0 +1,472 @@
+package hydrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// Region routing policies.
+const (
+	// RoutingFailover sends requests to the first available region in
+	// configuration order.
+	RoutingFailover = "failover"
+	// RoutingLowestLatency sends requests to the available region with the
+	// lowest probe latency.
+	RoutingLowestLatency = "lowest_latency"
+)
+
+// Region health states.
+const (
+	RegionHealthy = "healthy"
+	RegionDown    = "down"
+	// RegionUnknown means the region has not been probed yet.
+	RegionUnknown = "unknown"
+)
+
+// DefaultRegionProbeInterval is how long a region's probe result is reused.
+const DefaultRegionProbeInterval = time.Minute
+
+// defaultRegionCooldown is how long a region that failed a request is
+// skipped before it is tried again.
+const defaultRegionCooldown = 30 * time.Second
+
+// defaultRegionProbeTimeout bounds a single region probe.
+const defaultRegionProbeTimeout = 5 * time.Second
+
+// RegionConfig is one regional endpoint of a provider.
+type RegionConfig struct {
+	Name    string `json:"name"`
+	BaseURL string `json:"base_url"`
+	// APIKey overrides the provider's key for this region.
+	APIKey string `json:"api_key,omitempty"`
+}
+
+// ValidateRegions checks the provider's regions and routing policy.
+func (c ProviderConfig) ValidateRegions() error {
+	switch c.Routing {
+	case "", RoutingFailover, RoutingLowestLatency:
+	default:
+		return fmt.Errorf("routing %q must be one of: %s, %s", c.Routing, RoutingFailover, RoutingLowestLatency)
+	}
+	if c.Routing != "" && len(c.Regions) == 0 {
+		return errors.New("routing needs regions")
+	}
+	seen := make(map[string]bool, len(c.Regions))
+	for i, region := range c.Regions {
+		if strings.TrimSpace(region.Name) == "" {
+			return fmt.Errorf("regions[%d]: name is required", i)
+		}
+		if seen[region.Name] {
+			return fmt.Errorf("regions[%d]: duplicate region %q", i, region.Name)
+		}
+		seen[region.Name] = true
+		if u, err := url.Parse(region.BaseURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("regions[%d]: base_url must be an absolute URL", i)
+		}
+	}
+	return nil
+}
+
+// forRegion returns the configuration of a client for one region.
+func (c ProviderConfig) forRegion(region RegionConfig) ProviderConfig {
+	key := region.APIKey
+	if key == "" {
+		key = c.APIKey
+	}
+	return ProviderConfig{APIKey: key, BaseURL: region.BaseURL}
+}
+
+// RegionStatus is the health of one region of a provider.
+type RegionStatus struct {
+	Name    string `json:"name"`
+	BaseURL string `json:"base_url"`
+	State   string `json:"state"`
+	// LatencyMS is the smoothed probe latency.
+	LatencyMS int64     `json:"latency_ms,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+	// DownUntil is set while the region is skipped after a failed request.
+	DownUntil *time.Time `json:"down_until,omitempty"`
+}
+
+// RegionRouter probes the regions of providers for health and latency and
+// orders them for each request by the provider's routing policy. Regions
+// that fail a request are skipped for a cooldown. It is safe for
+// concurrent use and meant to be shared across runs.
+type RegionRouter struct {
+	factory  ClientFactory
+	interval time.Duration
+	cooldown time.Duration
+	timeout  time.Duration
+	now      func() time.Time
+	http     *http.Client
+
+	mu      sync.Mutex
+	regions map[string]*regionEntry
+}
+
+type regionEntry struct {
+	fingerprint string
+	probed      bool
+	probing     bool
+	healthy     bool
+	latency     time.Duration
+	err         string
+	checkedAt   time.Time
+	downUntil   time.Time
+}
+
+// NewRegionRouter creates a router that builds probe clients with factory.
+// An interval of 0 uses DefaultRegionProbeInterval.
+func NewRegionRouter(factory ClientFactory, interval time.Duration) *RegionRouter {
+	if interval <= 0 {
+		interval = DefaultRegionProbeInterval
+	}
+	return &RegionRouter{
+		factory:  factory,
+		interval: interval,
+		cooldown: defaultRegionCooldown,
+		timeout:  defaultRegionProbeTimeout,
+		now:      time.Now,
+		http: &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}},
+		regions: make(map[string]*regionEntry),
+	}
+}
+
+// Route returns the names of the provider's regions in the order requests
+// should try them: available regions by the routing policy, then regions
+// that are down, in configuration order, as a last resort. Regions whose
+// probe result is missing or stale are probed first.
+func (r *RegionRouter) Route(ctx context.Context, provider string, cfg ProviderConfig) []string {
+	r.probeStale(ctx, provider, cfg)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	type candidate struct {
+		name     string
+		index    int
+		up       bool
+		measured bool
+		latency  time.Duration
+	}
+	candidates := make([]candidate, len(cfg.Regions))
+	for i, region := range cfg.Regions {
+		entry := r.entry(provider, region, cfg)
+		candidates[i] = candidate{
+			name:     region.Name,
+			index:    i,
+			up:       (entry.healthy || !entry.probed) && !now.Before(entry.downUntil),
+			measured: entry.probed && entry.healthy,
+			latency:  entry.latency,
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.up != b.up {
+			return a.up
+		}
+		if a.up && cfg.Routing == RoutingLowestLatency {
+			if a.measured != b.measured {
+				return a.measured
+			}
+			if a.latency != b.latency {
+				return a.latency < b.latency
+			}
+		}
+		return a.index < b.index
+	})
+	names := make([]string, len(candidates))
+	for i, c := range candidates {
+		names[i] = c.name
+	}
+	return names
+}
+
+// Report records the outcome of a request sent to a region. A failure
+// that another region might not have marks the region down for the
+// cooldown; a success marks it healthy.
+func (r *RegionRouter) Report(provider string, cfg ProviderConfig, regionName string, err error) {
+	region, ok := findRegion(cfg, regionName)
+	if !ok {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry := r.entry(provider, region, cfg)
+	if err == nil {
+		entry.downUntil = time.Time{}
+		if entry.probed {
+			entry.healthy = true
+			entry.err = ""
+		}
+		return
+	}
+	if regionFailover(err) {
+		entry.downUntil = r.now().Add(r.cooldown)
+		entry.err = err.Error()
+	}
+}
+
+// Status returns the health of the provider's regions in configuration
+// order.
+func (r *RegionRouter) Status(provider string, cfg ProviderConfig) []RegionStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	statuses := make([]RegionStatus, 0, len(cfg.Regions))
+	for _, region := range cfg.Regions {
+		entry := r.entry(provider, region, cfg)
+		status := RegionStatus{
+			Name:      region.Name,
+			BaseURL:   region.BaseURL,
+			State:     RegionUnknown,
+			LatencyMS: entry.latency.Milliseconds(),
+			Error:     entry.err,
+			CheckedAt: entry.checkedAt,
+		}
+		if now.Before(entry.downUntil) {
+			downUntil := entry.downUntil
+			status.DownUntil = &downUntil
+		}
+		switch {
+		case status.DownUntil != nil || (entry.probed && !entry.healthy):
+			status.State = RegionDown
+		case entry.probed:
+			status.State = RegionHealthy
+			status.Error = ""
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// entry returns the region's health record, resetting it when the
+// region's endpoint or key changed. The caller holds r.mu.
+func (r *RegionRouter) entry(provider string, region RegionConfig, cfg ProviderConfig) *regionEntry {
+	key := provider + "/" + region.Name
+	fingerprint := credentialFingerprint(cfg.forRegion(region))
+	entry, ok := r.regions[key]
+	if !ok || entry.fingerprint != fingerprint {
+		entry = &regionEntry{fingerprint: fingerprint}
+		r.regions[key] = entry
+	}
+	return entry
+}
+
+// probeStale probes, concurrently, the regions whose last probe is older
+// than the interval and that no other caller is probing.
+func (r *RegionRouter) probeStale(ctx context.Context, provider string, cfg ProviderConfig) {
+	r.mu.Lock()
+	now := r.now()
+	var stale []RegionConfig
+	for _, region := range cfg.Regions {
+		entry := r.entry(provider, region, cfg)
+		if entry.probing || (entry.probed && now.Sub(entry.checkedAt) < r.interval) {
+			continue
+		}
+		entry.probing = true
+		stale = append(stale, region)
+	}
+	r.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, region := range stale {
+		wg.Add(1)
+		go func(region RegionConfig) {
+			defer wg.Done()
+			latency, err := r.probe(ctx, provider, cfg.forRegion(region))
+
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			entry := r.entry(provider, region, cfg)
+			entry.probing = false
+			entry.probed = true
+			entry.checkedAt = r.now().UTC()
+			entry.healthy = err == nil
+			entry.err = ""
+			if err != nil {
+				entry.err = err.Error()
+				return
+			}
+			// Smooth the latency so one slow probe does not flip the order.
+			if entry.latency == 0 {
+				entry.latency = latency
+			} else {
+				entry.latency = (entry.latency*7 + latency*3) / 10
+			}
+		}(region)
+	}
+	wg.Wait()
+}
+
+// probe checks that a region answers, with the client's credential check
+// when it has one, or else an HTTP request to the region's base URL, and
+// returns how long it took.
+func (r *RegionRouter) probe(ctx context.Context, provider string, cfg ProviderConfig) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	start := time.Now()
+
+	if r.factory != nil {
+		client, err := r.factory(provider, cfg)
+		if err != nil {
+			return 0, err
+		}
+		if checker, ok := client.(CredentialChecker); ok {
+			err := checker.VerifyCredentials(ctx)
+			if !errors.Is(err, ErrCredentialUnchecked) {
+				return time.Since(start), err
+			}
+			start = time.Now()
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cfg.BaseURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := r.http.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	// Any answer short of a server error means the endpoint is up.
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return 0, fmt.Errorf("probe returned HTTP %d", resp.StatusCode)
+	}
+	return time.Since(start), nil
+}
+
+func findRegion(cfg ProviderConfig, name string) (RegionConfig, bool) {
+	for _, region := range cfg.Regions {
+		if region.Name == name {
+			return region, true
+		}
+	}
+	return RegionConfig{}, false
+}
+
+// regionFailover reports whether a failed request should be retried in
+// another region. Classified failures that every region would repeat,
+// such as a bad key or an oversized prompt, are not.
+func regionFailover(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if pe, ok := core.AsProviderError(err); ok {
+		return pe.Code.Retryable()
+	}
+	return true
+}
+
+// regionalClient sends each request to the region its router picks,
+// failing over to the next region when one fails.
+type regionalClient struct {
+	provider string
+	cfg      ProviderConfig
+	router   *RegionRouter
+	clients  map[string]core.LLMClient
+}
+
+// newRegionalClient builds a client for each of the provider's regions
+// with factory and routes requests across them with router.
+func newRegionalClient(provider string, cfg ProviderConfig, factory ClientFactory, router *RegionRouter) (core.LLMClient, error) {
+	if err := cfg.ValidateRegions(); err != nil {
+		return nil, fmt.Errorf("provider %q: %w", provider, err)
+	}
+	c := &regionalClient{provider: provider, cfg: cfg, router: router, clients: make(map[string]core.LLMClient, len(cfg.Regions))}
+	streaming := true
+	for _, region := range cfg.Regions {
+		client, err := factory(provider, cfg.forRegion(region))
+		if err != nil {
+			return nil, fmt.Errorf("provider %q region %q: %w", provider, region.Name, err)
+		}
+		if _, ok := client.(core.StreamingLLMClient); !ok {
+			streaming = false
+		}
+		c.clients[region.Name] = client
+	}
+	if streaming {
+		return &regionalStreamingClient{c}, nil
+	}
+	return c, nil
+}
+
+// Complete sends the request to the best region, failing over in route
+// order. The response records the region that answered.
+func (c *regionalClient) Complete(ctx context.Context, req core.LLMRequest) (core.LLMResponse, error) {
+	var lastErr error
+	for _, region := range c.router.Route(ctx, c.provider, c.cfg) {
+		resp, err := c.clients[region].Complete(ctx, req)
+		c.router.Report(c.provider, c.cfg, region, err)
+		if err == nil {
+			resp.Region = region
+			return resp, nil
+		}
+		lastErr = fmt.Errorf("region %s: %w", region, err)
+		if ctx.Err() != nil || !regionFailover(err) {
+			break
+		}
+	}
+	return core.LLMResponse{}, lastErr
+}
+
+// regionalStreamingClient is a regionalClient whose regions all stream.
+type regionalStreamingClient struct {
+	*regionalClient
+}
+
+// CompleteStream opens the stream in the best region, failing over in
+// route order while opening it. The final chunk records the region.
+func (c *regionalStreamingClient) CompleteStream(ctx context.Context, req core.LLMRequest) (<-chan core.StreamChunk, error) {
+	var lastErr error
+	for _, region := range c.router.Route(ctx, c.provider, c.cfg) {
+		stream, err := c.clients[region].(core.StreamingLLMClient).CompleteStream(ctx, req)
+		c.router.Report(c.provider, c.cfg, region, err)
+		if err == nil {
+			return stampRegion(ctx, stream, region), nil
+		}
+		lastErr = fmt.Errorf("region %s: %w", region, err)
+		if ctx.Err() != nil || !regionFailover(err) {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// stampRegion forwards stream, setting region on the final chunk.
+func stampRegion(ctx context.Context, stream <-chan core.StreamChunk, region string) <-chan core.StreamChunk {
+	out := make(chan core.StreamChunk, 1)
+	go func() {
+		defer close(out)
+		for chunk := range stream {
+			if chunk.Done {
+				chunk.Region = region
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// Compile-time interface checks.
+var (
+	_ core.LLMClient          = (*regionalClient)(nil)
+	_ core.StreamingLLMClient = (*regionalStreamingClient)(nil)
+)
@@ -0,0 +1,212 @@
+package hydrate
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// regionBackend is the behavior of one fake regional endpoint.
+type regionBackend struct {
+	probeDelay time.Duration
+	probeErr   error
+	err        error
+	calls      int
+}
+
+// regionClient answers for the backend its base URL names.
+type regionClient struct {
+	mu      *sync.Mutex
+	backend *regionBackend
+}
+
+func (c *regionClient) Complete(context.Context, core.LLMRequest) (core.LLMResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.backend.calls++
+	if c.backend.err != nil {
+		return core.LLMResponse{}, c.backend.err
+	}
+	return core.LLMResponse{Text: "ok"}, nil
+}
+
+func (c *regionClient) CompleteStream(ctx context.Context, req core.LLMRequest) (<-chan core.StreamChunk, error) {
+	resp, err := c.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan core.StreamChunk, 2)
+	ch <- core.StreamChunk{Delta: resp.Text}
+	ch <- core.StreamChunk{Done: true, Accumulated: resp.Text}
+	close(ch)
+	return ch, nil
+}
+
+func (c *regionClient) VerifyCredentials(context.Context) error {
+	time.Sleep(c.backend.probeDelay)
+	return c.backend.probeErr
+}
+
+// plainRegionClient has no credential check, so probes fall back to HTTP.
+type plainRegionClient struct{ core.LLMClient }
+
+func newRegionFactory(backends map[string]*regionBackend) ClientFactory {
+	var mu sync.Mutex
+	return func(_ string, cfg ProviderConfig) (core.LLMClient, error) {
+		backend, ok := backends[cfg.BaseURL]
+		if !ok {
+			return plainRegionClient{&regionClient{mu: &mu, backend: &regionBackend{}}}, nil
+		}
+		return &regionClient{mu: &mu, backend: backend}, nil
+	}
+}
+
+func threeRegions(routing string) ProviderConfig {
+	return ProviderConfig{
+		APIKey:  "key",
+		Routing: routing,
+		Regions: []RegionConfig{
+			{Name: "us", BaseURL: "https://us.example.com"},
+			{Name: "eu", BaseURL: "https://eu.example.com"},
+			{Name: "ap", BaseURL: "https://ap.example.com"},
+		},
+	}
+}
+
+func TestRegionRouter_Policies(t *testing.T) {
+	backends := map[string]*regionBackend{
+		"https://us.example.com": {probeDelay: 40 * time.Millisecond},
+		"https://eu.example.com": {probeErr: &core.ProviderError{Provider: "openai", Code: core.ProviderErrUnavailable, Status: 503, Err: errors.New("provider failed")}},
+		"https://ap.example.com": {},
+	}
+	router := NewRegionRouter(newRegionFactory(backends), time.Hour)
+
+	if got := router.Route(context.Background(), "openai", threeRegions("")); !reflect.DeepEqual(got, []string{"us", "ap", "eu"}) {
+		t.Errorf("failover route = %v, want us, ap, eu", got)
+	}
+	if got := router.Route(context.Background(), "openai", threeRegions(RoutingLowestLatency)); !reflect.DeepEqual(got, []string{"ap", "us", "eu"}) {
+		t.Errorf("lowest latency route = %v, want ap, us, eu", got)
+	}
+
+	status := router.Status("openai", threeRegions(""))
+	if status[0].State != RegionHealthy || status[0].LatencyMS < 40 || status[1].State != RegionDown || status[1].Error == "" {
+		t.Errorf("status = %+v", status)
+	}
+}
+
+func TestRegionRouter_HTTPProbe(t *testing.T) {
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer up.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	cfg := ProviderConfig{Regions: []RegionConfig{
+		{Name: "a", BaseURL: failing.URL},
+		{Name: "b", BaseURL: up.URL},
+	}}
+	router := NewRegionRouter(newRegionFactory(nil), time.Hour)
+	if got := router.Route(context.Background(), "ollama", cfg); !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("route = %v, want b, a", got)
+	}
+}
+
+func TestRegionalClient_FailsOver(t *testing.T) {
+	backends := map[string]*regionBackend{
+		"https://us.example.com": {err: &core.ProviderError{Provider: "openai", Code: core.ProviderErrUnavailable, Status: 503, Err: errors.New("provider failed")}},
+		"https://eu.example.com": {},
+		"https://ap.example.com": {},
+	}
+	factory := newRegionFactory(backends)
+	router := NewRegionRouter(factory, time.Hour)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	router.now = func() time.Time { return now }
+	getClient := newLiveFactoryClientGetter(ProviderMap{"openai": threeRegions("")}, factory, nil, router)
+
+	client, err := getClient("openai")
+	if err != nil {
+		t.Fatalf("getClient: %v", err)
+	}
+	resp, err := client.Complete(context.Background(), core.LLMRequest{Model: "gpt-4o"})
+	if err != nil || resp.Region != "eu" {
+		t.Fatalf("Complete = %+v, %v; want an answer from eu", resp, err)
+	}
+
+	// The failed region is skipped until its cooldown passes.
+	if _, err := client.Complete(context.Background(), core.LLMRequest{Model: "gpt-4o"}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if us, eu := backends["https://us.example.com"].calls, backends["https://eu.example.com"].calls; us != 1 || eu != 2 {
+		t.Errorf("calls us=%d eu=%d, want 1 and 2", us, eu)
+	}
+	if status := router.Status("openai", threeRegions("")); status[0].State != RegionDown || status[0].DownUntil == nil {
+		t.Errorf("us status = %+v, want down", status[0])
+	}
+	now = now.Add(time.Minute)
+	backends["https://us.example.com"].err = nil
+	if resp, _ := client.Complete(context.Background(), core.LLMRequest{}); resp.Region != "us" {
+		t.Errorf("region after cooldown = %q, want us", resp.Region)
+	}
+
+	// Streams record the region on the final chunk.
+	stream, err := client.(core.StreamingLLMClient).CompleteStream(context.Background(), core.LLMRequest{})
+	if err != nil {
+		t.Fatalf("CompleteStream: %v", err)
+	}
+	var region string
+	for chunk := range stream {
+		if chunk.Done {
+			region = chunk.Region
+		}
+	}
+	if region != "us" {
+		t.Errorf("stream region = %q, want us", region)
+	}
+}
+
+func TestRegionalClient_DoesNotFailOverPermanentErrors(t *testing.T) {
+	backends := map[string]*regionBackend{
+		"https://us.example.com": {err: &core.ProviderError{Provider: "openai", Code: core.ProviderErrInvalidAPIKey, Status: 401, Err: errors.New("provider failed")}},
+		"https://eu.example.com": {},
+	}
+	factory := newRegionFactory(backends)
+	cfg := threeRegions("")
+	cfg.Regions = cfg.Regions[:2]
+	client, err := newRegionalClient("openai", cfg, factory, NewRegionRouter(factory, time.Hour))
+	if err != nil {
+		t.Fatalf("newRegionalClient: %v", err)
+	}
+	if _, err := client.Complete(context.Background(), core.LLMRequest{}); err == nil {
+		t.Fatal("expected the invalid key error")
+	}
+	if calls := backends["https://eu.example.com"].calls; calls != 0 {
+		t.Errorf("eu called %d times, want 0", calls)
+	}
+}
+
+func TestProviderConfig_ValidateRegions(t *testing.T) {
+	if err := threeRegions(RoutingLowestLatency).ValidateRegions(); err != nil {
+		t.Errorf("ValidateRegions: %v", err)
+	}
+	for name, cfg := range map[string]ProviderConfig{
+		"unknown routing":   {Routing: "random", Regions: []RegionConfig{{Name: "us", BaseURL: "https://us.example.com"}}},
+		"routing no region": {Routing: RoutingFailover},
+		"missing name":      {Regions: []RegionConfig{{BaseURL: "https://us.example.com"}}},
+		"duplicate":         {Regions: []RegionConfig{{Name: "us", BaseURL: "https://a.example.com"}, {Name: "us", BaseURL: "https://b.example.com"}}},
+		"relative url":      {Regions: []RegionConfig{{Name: "us", BaseURL: "us.example.com"}}},
+	} {
+		if err := cfg.ValidateRegions(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package hydrate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// SecretValueKey marks a config value as secret. A secret value is written
+// as an object with this one key, {"secret": "..."}. Daemons encrypt it on
+// save and decrypt it only when the workflow is hydrated.
+const SecretValueKey = "secret"
+
+// encryptedSecretPrefix marks a secret value encrypted by a SecretCodec.
+const encryptedSecretPrefix = "enc:"
+
+// SecretCodec encrypts and decrypts secret config values. Encrypted values
+// start with "enc:".
+type SecretCodec interface {
+	Encrypt(value string) (string, error)
+	Decrypt(value string) (string, error)
+}
+
+// SecretValue returns the value of a {"secret": "..."} wrapper.
+func SecretValue(v any) (string, bool) {
+	m, ok := v.(map[string]any)
+	if !ok || len(m) != 1 {
+		return "", false
+	}
+	s, ok := m[SecretValueKey].(string)
+	return s, ok
+}
+
+// IsEncryptedSecret reports whether a secret value was encrypted by a
+// SecretCodec.
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, encryptedSecretPrefix)
+}
+
+// MapSecretValues returns a copy of v with every secret wrapper replaced by
+// fn's result. v itself is returned when it holds no secret values. fn gets
+// the wrapper's dotted path; list elements that are objects with a string
+// "id" are addressed by their ID, other elements by their index.
+func MapSecretValues(v any, fn func(path, value string) (any, error)) (any, error) {
+	out, _, err := mapSecretValues(v, "", fn)
+	return out, err
+}
+
+func mapSecretValues(v any, path string, fn func(path, value string) (any, error)) (any, bool, error) {
+	if secret, ok := SecretValue(v); ok {
+		out, err := fn(path, secret)
+		return out, true, err
+	}
+	switch t := v.(type) {
+	case map[string]any:
+		var out map[string]any
+		for key, child := range t {
+			mapped, changed, err := mapSecretValues(child, joinSecretPath(path, key), fn)
+			if err != nil {
+				return nil, false, err
+			}
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = make(map[string]any, len(t))
+				for k, c := range t {
+					out[k] = c
+				}
+			}
+			out[key] = mapped
+		}
+		if out == nil {
+			return v, false, nil
+		}
+		return out, true, nil
+	case []any:
+		var out []any
+		for i, child := range t {
+			segment := strconv.Itoa(i)
+			if m, ok := child.(map[string]any); ok {
+				if id, ok := m["id"].(string); ok && id != "" {
+					segment = id
+				}
+			}
+			mapped, changed, err := mapSecretValues(child, joinSecretPath(path, segment), fn)
+			if err != nil {
+				return nil, false, err
+			}
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = append([]any(nil), t...)
+			}
+			out[i] = mapped
+		}
+		if out == nil {
+			return v, false, nil
+		}
+		return out, true, nil
+	default:
+		return v, false, nil
+	}
+}
+
+func joinSecretPath(path, segment string) string {
+	if path == "" {
+		return segment
+	}
+	return path + "." + segment
+}
+
+// UnwrapSecrets returns def with each node's secret config values replaced
+// by their plaintext. Encrypted values are decrypted with codec, which may
+// be nil when def holds none. def itself is returned when it holds no
+// secret values.
+func UnwrapSecrets(def *graph.GraphDefinition, codec SecretCodec) (*graph.GraphDefinition, error) {
+	if def == nil {
+		return nil, nil
+	}
+	var nodes []graph.NodeDef
+	for i, node := range def.Nodes {
+		config, changed, err := mapSecretValues(node.Config, "config", func(path, value string) (any, error) {
+			if !IsEncryptedSecret(value) {
+				return value, nil
+			}
+			if codec == nil {
+				return nil, fmt.Errorf("node %q: config %s is encrypted and no secret key is available", node.ID, path)
+			}
+			plain, err := codec.Decrypt(value)
+			if err != nil {
+				return nil, fmt.Errorf("node %q: decrypt config %s: %w", node.ID, path, err)
+			}
+			return plain, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !changed {
+			continue
+		}
+		if nodes == nil {
+			nodes = append([]graph.NodeDef(nil), def.Nodes...)
+		}
+		nodes[i].Config, _ = config.(map[string]any)
+	}
+	if nodes == nil {
+		return def, nil
+	}
+	out := *def
+	out.Nodes = nodes
+	return &out, nil
+}
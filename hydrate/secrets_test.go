@@ -0,0 +1,107 @@
+package hydrate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/graph"
+)
+
+type reverseCodec struct{}
+
+func (reverseCodec) Encrypt(value string) (string, error) {
+	return encryptedSecretPrefix + reverse(value), nil
+}
+
+func (reverseCodec) Decrypt(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+	if value == encryptedSecretPrefix+"bad" {
+		return "", errors.New("bad ciphertext")
+	}
+	return reverse(strings.TrimPrefix(value, encryptedSecretPrefix)), nil
+}
+
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+func TestMapSecretValues_Paths(t *testing.T) {
+	doc := map[string]any{
+		"nodes": []any{
+			map[string]any{"id": "hook", "config": map[string]any{"auth": map[string]any{"token": map[string]any{"secret": "a"}}}},
+			map[string]any{"config": map[string]any{"headers": []any{map[string]any{"secret": "b"}}}},
+		},
+		"plain":  map[string]any{"secret": "c", "other": true},
+		"number": 1,
+	}
+	var paths []string
+	out, err := MapSecretValues(doc, func(path, value string) (any, error) {
+		paths = append(paths, path+"="+value)
+		return "x", nil
+	})
+	if err != nil {
+		t.Fatalf("MapSecretValues: %v", err)
+	}
+	got := strings.Join(paths, ",")
+	if got != "nodes.hook.config.auth.token=a,nodes.1.config.headers.0=b" {
+		t.Errorf("paths = %s", got)
+	}
+	if _, ok := SecretValue(doc["nodes"].([]any)[0].(map[string]any)["config"].(map[string]any)["auth"].(map[string]any)["token"]); !ok {
+		t.Error("input was modified")
+	}
+	if out.(map[string]any)["plain"].(map[string]any)["secret"] != "c" {
+		t.Error("objects with more keys than secret must not be treated as secret values")
+	}
+
+	untouched := map[string]any{"a": 1}
+	if out, _ := MapSecretValues(untouched, nil); out.(map[string]any)["a"] != 1 {
+		t.Error("document without secrets changed")
+	}
+}
+
+func TestUnwrapSecrets(t *testing.T) {
+	def := &graph.GraphDefinition{
+		ID: "g",
+		Nodes: []graph.NodeDef{
+			{ID: "hook", Type: "webhook_trigger", Config: map[string]any{
+				"auth": map[string]any{"type": "header_token", "token": map[string]any{"secret": encryptedSecretPrefix + "terces"}},
+			}},
+			{ID: "plain", Type: "noop", Config: map[string]any{"key": map[string]any{"secret": "inline"}}},
+			{ID: "other", Type: "noop"},
+		},
+	}
+
+	opened, err := UnwrapSecrets(def, reverseCodec{})
+	if err != nil {
+		t.Fatalf("UnwrapSecrets: %v", err)
+	}
+	if token := opened.Nodes[0].Config["auth"].(map[string]any)["token"]; token != "secret" {
+		t.Errorf("token = %v, want decrypted", token)
+	}
+	if key := opened.Nodes[1].Config["key"]; key != "inline" {
+		t.Errorf("key = %v, want plaintext", key)
+	}
+	if _, ok := SecretValue(def.Nodes[1].Config["key"]); !ok {
+		t.Error("UnwrapSecrets modified its input")
+	}
+
+	if _, err := UnwrapSecrets(def, nil); err == nil || !strings.Contains(err.Error(), "config.auth.token") {
+		t.Errorf("without codec err = %v, want encrypted path", err)
+	}
+	def.Nodes[0].Config["auth"].(map[string]any)["token"] = map[string]any{"secret": encryptedSecretPrefix + "bad"}
+	if _, err := UnwrapSecrets(def, reverseCodec{}); err == nil || !strings.Contains(err.Error(), `node "hook"`) {
+		t.Errorf("bad ciphertext err = %v", err)
+	}
+
+	clean := &graph.GraphDefinition{Nodes: []graph.NodeDef{{ID: "n", Config: map[string]any{"a": "b"}}}}
+	if out, _ := UnwrapSecrets(clean, nil); out != clean {
+		t.Error("definition without secrets should be returned as is")
+	}
+}
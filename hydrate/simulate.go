@@ -0,0 +1,210 @@
+package hydrate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/registry"
+)
+
+// WithSimulation replaces LLM and tool nodes with canned responses from def.
+// Every LLM node must have a simulated response so that a simulated run never
+// reaches a provider; tool nodes without one run normally.
+func WithSimulation(def *graph.SimulationDef) LiveNodeOption {
+	return func(o *liveFactoryOptions) {
+		if def == nil {
+			def = &graph.SimulationDef{}
+		}
+		o.simulation = newSimulator(def)
+	}
+}
+
+// simulator renders simulated responses. It is shared by every node in a
+// hydrated graph so random choices draw from a single seeded source.
+type simulator struct {
+	def *graph.SimulationDef
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func newSimulator(def *graph.SimulationDef) *simulator {
+	seed := def.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return &simulator{def: def, rng: rand.New(rand.NewSource(seed))} // #nosec G404 -- demo data, not security sensitive
+}
+
+// float64 draws from the simulation's random source.
+func (s *simulator) float64() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rng.Float64()
+}
+
+func (s *simulator) response(nodeID string) (graph.SimulatedResponse, bool) {
+	resp, ok := s.def.Nodes[nodeID]
+	return resp, ok
+}
+
+// render produces the value for one simulated call.
+func (s *simulator) render(resp graph.SimulatedResponse, data map[string]any) (any, error) {
+	switch {
+	case resp.Template != "":
+		tmpl, err := template.New("simulate").Parse(resp.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid simulate template: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("simulate template execution failed: %w", err)
+		}
+		return buf.String(), nil
+	case len(resp.Choices) > 0:
+		s.mu.Lock()
+		idx := s.rng.Intn(len(resp.Choices))
+		s.mu.Unlock()
+		return resp.Choices[idx], nil
+	default:
+		return resp.Response, nil
+	}
+}
+
+// buildSimulatedNode builds nd around its simulated response. ok is false
+// when nd should be built normally.
+func (r liveFactoryRuntime) buildSimulatedNode(nd graph.NodeDef) (node core.Node, ok bool, err error) {
+	sim := r.options.simulation
+	if sim == nil {
+		return nil, false, nil
+	}
+	resp, hasResp := sim.response(nd.ID)
+	if nd.Type == "classify" && configString(nd.Config, "method") == "embedding" {
+		// Embedding classification makes no provider call to simulate.
+		return nil, false, nil
+	}
+
+	if graph.IsLLMNodeType(nd.Type) {
+		if !hasResp {
+			return nil, true, fmt.Errorf("node %q: simulation enabled but no simulated response defined", nd.ID)
+		}
+		client := &simulatedLLMClient{sim: sim, nodeID: nd.ID, resp: resp}
+		getClient := func(string) (core.LLMClient, error) { return client, nil }
+		switch nd.Type {
+		case "llm_router":
+			node, err = buildLLMRouter(nd, getClient)
+		case "compact_messages":
+			node, err = buildCompactMessagesNode(nd, getClient)
+		case "translate":
+			node, err = buildTranslateNode(nd, getClient)
+		case "summarize":
+			node, err = buildSummarizeNode(nd, getClient)
+		case "classify":
+			node, err = buildClassifyNode(nd, getClient)
+		case "extract_entities":
+			node, err = buildExtractEntitiesNode(nd, getClient)
+		case "model_select":
+			node, err = buildModelSelectNode(nd, getClient, r.options, sim.float64)
+		default:
+			// Simulated outputs stay out of the drift history.
+			node, err = buildLLMNode(nd, getClient, nil, r.options.sandbox)
+		}
+		return node, true, err
+	}
+
+	if !hasResp {
+		return nil, false, nil
+	}
+	if nd.Type == "tool" {
+		toolName := configString(nd.Config, "tool_name")
+		if toolName == "" {
+			return nil, true, fmt.Errorf("node %q: tool node requires config.tool_name", nd.ID)
+		}
+		node, err = buildToolNodeWithName(nd, toolName, &simulatedTool{sim: sim, nodeID: nd.ID, name: toolName, resp: resp}, nil, nil)
+		return node, true, err
+	}
+	if def, builtin := registry.Global().Get(nd.Type); builtin && !def.IsTool {
+		return nil, true, fmt.Errorf("node %q: simulated responses are only supported for LLM and tool nodes, not %q", nd.ID, nd.Type)
+	}
+	node, err = buildToolNode(nd, &simulatedTool{sim: sim, nodeID: nd.ID, name: nd.Type, resp: resp}, nil, nil)
+	return node, true, err
+}
+
+// simulatedLLMClient answers every completion with a canned response.
+type simulatedLLMClient struct {
+	sim    *simulator
+	nodeID string
+	resp   graph.SimulatedResponse
+}
+
+func (c *simulatedLLMClient) Complete(_ context.Context, req core.LLMRequest) (core.LLMResponse, error) {
+	prompt := req.InputText
+	for _, msg := range req.Messages {
+		if msg.Role == "user" {
+			prompt = msg.Content
+		}
+	}
+	system := req.System
+	if system == "" {
+		system = req.Instructions
+	}
+
+	value, err := c.sim.render(c.resp, map[string]any{
+		"prompt": prompt,
+		"system": system,
+		"model":  req.Model,
+		"node":   c.nodeID,
+	})
+	if err != nil {
+		return core.LLMResponse{}, fmt.Errorf("node %q: %w", c.nodeID, err)
+	}
+
+	out := core.LLMResponse{Provider: "simulated", Model: req.Model, Status: "completed"}
+	switch v := value.(type) {
+	case string:
+		out.Text = v
+		if req.JSONSchema != nil {
+			_ = json.Unmarshal([]byte(v), &out.JSON)
+		}
+	case map[string]any:
+		out.JSON = v
+		data, _ := json.Marshal(v)
+		out.Text = string(data)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return core.LLMResponse{}, fmt.Errorf("node %q: encoding simulated response: %w", c.nodeID, err)
+		}
+		out.Text = string(data)
+	}
+	return out, nil
+}
+
+// simulatedTool returns a canned result instead of invoking a real tool.
+type simulatedTool struct {
+	sim    *simulator
+	nodeID string
+	name   string
+	resp   graph.SimulatedResponse
+}
+
+func (t *simulatedTool) Name() string { return t.name }
+
+func (t *simulatedTool) Invoke(_ context.Context, args map[string]any) (map[string]any, error) {
+	value, err := t.sim.render(t.resp, map[string]any{"args": args, "node": t.nodeID})
+	if err != nil {
+		return nil, fmt.Errorf("node %q: %w", t.nodeID, err)
+	}
+	if result, ok := value.(map[string]any); ok {
+		return result, nil
+	}
+	return map[string]any{"result": value}, nil
+}
@@ -0,0 +1,132 @@
+package hydrate
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func failingClientFactory(t *testing.T) ClientFactory {
+	return func(name string, _ ProviderConfig) (core.LLMClient, error) {
+		t.Fatalf("simulated run must not create a %q client", name)
+		return nil, nil
+	}
+}
+
+func simulatedTriageGraph() *graph.GraphDefinition {
+	return &graph.GraphDefinition{
+		ID:      "triage",
+		Version: "1.0",
+		Nodes: []graph.NodeDef{
+			{ID: "draft", Type: "llm_prompt", Config: map[string]any{
+				"provider":        "anthropic",
+				"prompt_template": "Ticket: {{.ticket}}",
+				"output_key":      "draft",
+			}},
+			{ID: "route", Type: "llm_router", Config: map[string]any{
+				"provider":        "anthropic",
+				"allowed_targets": map[string]any{"bug": "bug_path", "question": "faq_path"},
+			}},
+			{ID: "lookup", Type: "tool", Config: map[string]any{
+				"tool_name":  "kb_search",
+				"output_key": "kb",
+			}},
+			{ID: "bug_path", Type: "noop"},
+			{ID: "faq_path", Type: "noop"},
+		},
+		Edges: []graph.EdgeDef{
+			{Source: "draft", Target: "lookup"},
+			{Source: "lookup", Target: "route"},
+			{Source: "route", Target: "bug_path"},
+			{Source: "route", Target: "faq_path"},
+		},
+		Entry: "draft",
+		Simulate: &graph.SimulationDef{
+			Seed: 7,
+			Nodes: map[string]graph.SimulatedResponse{
+				"draft":  {Template: "echo: {{.prompt}}"},
+				"route":  {Response: map[string]any{"choice": "bug", "reason": "simulated"}},
+				"lookup": {Response: map[string]any{"articles": []any{"kb-1"}}},
+			},
+		},
+	}
+}
+
+func TestSimulation_RunsWithoutProviders(t *testing.T) {
+	gd := simulatedTriageGraph()
+	factory := NewLiveNodeFactory(ProviderMap{}, failingClientFactory(t), WithSimulation(gd.Simulate))
+
+	g, err := HydrateGraph(gd, ProviderMap{}, factory)
+	if err != nil {
+		t.Fatalf("HydrateGraph: %v", err)
+	}
+
+	env := core.NewEnvelope()
+	env.SetVar("ticket", "login fails")
+	result, err := runtime.NewRuntime().Run(context.Background(), g, env, runtime.DefaultRunOptions())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	if got, _ := result.GetVar("draft"); got != "echo: Ticket: login fails" {
+		t.Errorf("draft = %v", got)
+	}
+	kb, _ := result.GetVar("kb")
+	if m, ok := kb.(map[string]any); !ok || m["articles"] == nil {
+		t.Errorf("kb = %#v, want simulated tool result", kb)
+	}
+	decision, _ := result.GetVar("route_decision")
+	if d, ok := decision.(core.RouteDecision); !ok || len(d.Targets) != 1 || d.Targets[0] != "bug_path" {
+		t.Errorf("route decision = %#v, want bug_path", decision)
+	}
+}
+
+func TestSimulation_MissingLLMResponseFailsHydration(t *testing.T) {
+	gd := simulatedTriageGraph()
+	delete(gd.Simulate.Nodes, "route")
+	factory := NewLiveNodeFactory(ProviderMap{}, failingClientFactory(t), WithSimulation(gd.Simulate))
+
+	_, err := HydrateGraph(gd, ProviderMap{}, factory)
+	if err == nil || !strings.Contains(err.Error(), `node "route": simulation enabled`) {
+		t.Fatalf("expected missing simulation error, got %v", err)
+	}
+}
+
+func TestSimulation_RejectsNonSimulatableNode(t *testing.T) {
+	gd := simulatedTriageGraph()
+	gd.Simulate.Nodes["bug_path"] = graph.SimulatedResponse{Response: "x"}
+	factory := NewLiveNodeFactory(ProviderMap{}, failingClientFactory(t), WithSimulation(gd.Simulate))
+
+	_, err := HydrateGraph(gd, ProviderMap{}, factory)
+	if err == nil || !strings.Contains(err.Error(), "only supported for LLM and tool nodes") {
+		t.Fatalf("expected unsupported node error, got %v", err)
+	}
+}
+
+func TestSimulation_ChoicesAreSeeded(t *testing.T) {
+	def := &graph.SimulationDef{Seed: 42, Nodes: map[string]graph.SimulatedResponse{
+		"n": {Choices: []any{"a", "b", "c", "d"}},
+	}}
+	draw := func() []any {
+		sim := newSimulator(def)
+		var out []any
+		for i := 0; i < 8; i++ {
+			v, err := sim.render(def.Nodes["n"], nil)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+			out = append(out, v)
+		}
+		return out
+	}
+	first, second := draw(), draw()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("same seed produced different choices: %v vs %v", first, second)
+		}
+	}
+}
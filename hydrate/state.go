@@ -0,0 +1,97 @@
+package hydrate
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+)
+
+// stateNamespace returns the namespace a node reads and writes state in:
+// its "namespace" config, or the WithStateStore scope.
+func stateNamespace(nd graph.NodeDef, opts liveFactoryOptions, key string) string {
+	if ns := configString(nd.Config, key); ns != "" {
+		return ns
+	}
+	return opts.stateScope
+}
+
+// stateNodeConfig extracts the store, namespace and key of a state node.
+func stateNodeConfig(nd graph.NodeDef, opts liveFactoryOptions) (nodes.StateNodeConfig, error) {
+	if opts.state == nil {
+		return nodes.StateNodeConfig{}, fmt.Errorf("node %q: %s needs a state store", nd.ID, nd.Type)
+	}
+	return nodes.StateNodeConfig{
+		Store:           opts.state,
+		Namespace:       stateNamespace(nd, opts, "namespace"),
+		Key:             configString(nd.Config, "key"),
+		TemplateSandbox: opts.sandbox,
+	}, nil
+}
+
+// buildStateGetNode extracts config from a NodeDef and returns a
+// StateGetNode.
+func buildStateGetNode(nd graph.NodeDef, opts liveFactoryOptions) (core.Node, error) {
+	base, err := stateNodeConfig(nd, opts)
+	if err != nil {
+		return nil, err
+	}
+	return nodes.NewStateGetNode(nd.ID, nodes.StateGetNodeConfig{
+		StateNodeConfig: base,
+		Default:         nd.Config["default"],
+		OutputKey:       configString(nd.Config, "output_key"),
+	})
+}
+
+// buildStateSetNode extracts config from a NodeDef and returns a
+// StateSetNode.
+func buildStateSetNode(nd graph.NodeDef, opts liveFactoryOptions) (core.Node, error) {
+	base, err := stateNodeConfig(nd, opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg := nodes.StateSetNodeConfig{
+		StateNodeConfig: base,
+		ValueVar:        configString(nd.Config, "value_var"),
+		Value:           nd.Config["value"],
+		TTL:             configDuration(nd.Config, "ttl"),
+	}
+	if cfg.ValueVar == "" && cfg.Value == nil {
+		return nil, fmt.Errorf("node %q: state_set needs value or value_var", nd.ID)
+	}
+	return nodes.NewStateSetNode(nd.ID, cfg)
+}
+
+// buildStateIncrNode extracts config from a NodeDef and returns a
+// StateIncrNode.
+func buildStateIncrNode(nd graph.NodeDef, opts liveFactoryOptions) (core.Node, error) {
+	base, err := stateNodeConfig(nd, opts)
+	if err != nil {
+		return nil, err
+	}
+	cfg := nodes.StateIncrNodeConfig{
+		StateNodeConfig: base,
+		TTL:             configDuration(nd.Config, "ttl"),
+		OutputKey:       configString(nd.Config, "output_key"),
+	}
+	if v, ok := configInt(nd.Config, "by"); ok {
+		cfg.By = int64(v)
+	}
+	return nodes.NewStateIncrNode(nd.ID, cfg)
+}
+
+// stateLookup returns the "state" binding of a conditional or rule_router
+// node's expressions, reading its "state_namespace" config or the
+// WithStateStore scope. It is nil without a state store.
+func stateLookup(nd graph.NodeDef, opts liveFactoryOptions) func(context.Context) expr.Lookup {
+	if opts.state == nil {
+		return nil
+	}
+	store, namespace := opts.state, stateNamespace(nd, opts, "state_namespace")
+	return func(ctx context.Context) expr.Lookup {
+		return nodes.NewStateView(ctx, store, namespace)
+	}
+}
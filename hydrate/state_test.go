@@ -0,0 +1,91 @@
+package hydrate
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/nodes"
+)
+
+func TestNewLiveNodeFactory_StateNodes(t *testing.T) {
+	store := nodes.NewMemoryStateStore()
+	build := NewLiveNodeFactory(nil, nil, WithStateStore(store, "wf-1"))
+
+	node, err := build(graph.NodeDef{ID: "count", Type: "state_incr", Config: map[string]any{
+		"key":        "emails:{{.customer_id}}",
+		"by":         float64(2),
+		"ttl":        "24h",
+		"output_key": "sent",
+	}})
+	if err != nil {
+		t.Fatalf("build state_incr: %v", err)
+	}
+	cfg := node.(*nodes.StateIncrNode).Config()
+	if cfg.Store != store || cfg.Namespace != "wf-1" || cfg.By != 2 || cfg.TTL != 24*time.Hour || cfg.OutputKey != "sent" {
+		t.Errorf("state_incr config = %+v", cfg)
+	}
+
+	node, err = build(graph.NodeDef{ID: "save", Type: "state_set", Config: map[string]any{
+		"key":       "profile",
+		"namespace": "shared",
+		"value_var": "profile",
+	}})
+	if err != nil {
+		t.Fatalf("build state_set: %v", err)
+	}
+	if sc := node.(*nodes.StateSetNode).Config(); sc.Namespace != "shared" || sc.ValueVar != "profile" {
+		t.Errorf("state_set config = %+v", sc)
+	}
+
+	node, err = build(graph.NodeDef{ID: "load", Type: "state_get", Config: map[string]any{
+		"key":     "profile",
+		"default": "none",
+	}})
+	if err != nil {
+		t.Fatalf("build state_get: %v", err)
+	}
+	if gc := node.(*nodes.StateGetNode).Config(); gc.Default != "none" || gc.OutputKey != "load_output" {
+		t.Errorf("state_get config = %+v", gc)
+	}
+
+	if _, err := build(graph.NodeDef{ID: "bad", Type: "state_set", Config: map[string]any{"key": "k"}}); err == nil ||
+		!strings.Contains(err.Error(), "value or value_var") {
+		t.Errorf("expected a missing value error, got %v", err)
+	}
+	noStore := NewLiveNodeFactory(nil, nil)
+	if _, err := noStore(graph.NodeDef{ID: "load", Type: "state_get", Config: map[string]any{"key": "k"}}); err == nil ||
+		!strings.Contains(err.Error(), "state store") {
+		t.Errorf("expected a missing store error, got %v", err)
+	}
+}
+
+func TestNewLiveNodeFactory_StateInConditions(t *testing.T) {
+	ctx := context.Background()
+	store := nodes.NewMemoryStateStore()
+	if _, err := store.IncrState(ctx, "wf-1", "emails_sent", 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	build := NewLiveNodeFactory(nil, nil, WithStateStore(store, "wf-1"))
+
+	node, err := build(graph.NodeDef{ID: "limit", Type: "rule_router", Config: map[string]any{
+		"rules": []any{map[string]any{
+			"target": "send",
+			"conditions": []any{map[string]any{
+				"op":         "expression",
+				"expression": "state.emails_sent < 3",
+			}},
+		}},
+		"default_target": "skip",
+	}})
+	if err != nil {
+		t.Fatalf("build rule_router: %v", err)
+	}
+	decision, err := node.(core.RouterNode).Route(ctx, core.NewEnvelope())
+	if err != nil || len(decision.Targets) != 1 || decision.Targets[0] != "skip" {
+		t.Fatalf("decision = %+v, %v", decision, err)
+	}
+}
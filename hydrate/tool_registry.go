@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/petal-labs/petalflow/core"
 	"github.com/petal-labs/petalflow/tool"
@@ -46,12 +47,21 @@ func BuildActionToolRegistry(ctx context.Context, store tool.Store) (*core.ToolR
 			if reference == "" {
 				continue
 			}
-			registry.Register(serviceActionTool{
+			action := registration.Manifest.Actions[actionName]
+			actionTool := serviceActionTool{
 				name:       reference,
 				toolName:   registration.Name,
 				actionName: actionName,
+				inputs:     action.Inputs,
+				async:      action.Async,
+				cache:      action.Cache,
 				service:    service,
-			})
+			}
+			if action.Async != nil && action.Async.PollAction != "" {
+				registry.Register(pollingActionTool{serviceActionTool: actionTool, pollAction: action.Async.PollAction})
+				continue
+			}
+			registry.Register(actionTool)
 		}
 	}
 
@@ -62,15 +72,48 @@ type serviceActionTool struct {
 	name       string
 	toolName   string
 	actionName string
+	inputs     map[string]tool.FieldSpec
+	async      *tool.AsyncSpec
+	cache      *tool.CacheSpec
 	service    *tool.DaemonToolService
 }
 
+// asyncActionTool is implemented by tools backed by manifest actions, which
+// may declare defaults for asynchronous invocations.
+type asyncActionTool interface {
+	asyncSpec() *tool.AsyncSpec
+}
+
 func (t serviceActionTool) Name() string {
 	return t.name
 }
 
+// ArgsSchema describes the action's inputs from the tool manifest.
+func (t serviceActionTool) ArgsSchema() map[string]any {
+	return tool.InputsJSONSchema(t.inputs)
+}
+
+func (t serviceActionTool) asyncSpec() *tool.AsyncSpec {
+	return t.async
+}
+
+// CachePolicy returns the action's manifest cache declaration, if any.
+func (t serviceActionTool) CachePolicy() *core.ToolCachePolicy {
+	if t.cache == nil || t.cache.TTLMS <= 0 {
+		return nil
+	}
+	return &core.ToolCachePolicy{
+		TTL:    time.Duration(t.cache.TTLMS) * time.Millisecond,
+		VaryOn: t.cache.VaryOn,
+	}
+}
+
 func (t serviceActionTool) Invoke(ctx context.Context, args map[string]any) (map[string]any, error) {
-	result, err := t.service.TestAction(ctx, t.toolName, t.actionName, args)
+	return t.invokeAction(ctx, t.actionName, args)
+}
+
+func (t serviceActionTool) invokeAction(ctx context.Context, actionName string, args map[string]any) (map[string]any, error) {
+	result, err := t.service.TestAction(ctx, t.toolName, actionName, args)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +123,18 @@ func (t serviceActionTool) Invoke(ctx context.Context, args map[string]any) (map
 	return result.Outputs, nil
 }
 
+// pollingActionTool is an asynchronous action whose pending invocations
+// are polled through the manifest's poll action.
+type pollingActionTool struct {
+	serviceActionTool
+	pollAction string
+}
+
+// Poll invokes the poll action with the invocation's token.
+func (t pollingActionTool) Poll(ctx context.Context, token string) (map[string]any, error) {
+	return t.invokeAction(ctx, t.pollAction, map[string]any{"token": token})
+}
+
 func actionReference(toolName, actionName string) string {
 	toolName = strings.TrimSpace(toolName)
 	actionName = strings.TrimSpace(actionName)
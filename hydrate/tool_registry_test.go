@@ -3,7 +3,11 @@ package hydrate
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/nodes"
 	"github.com/petal-labs/petalflow/tool"
 )
 
@@ -117,3 +121,78 @@ func TestBuildActionToolRegistry_SkipsDisabledTools(t *testing.T) {
 		t.Fatal("disabled_tool.execute should not be registered")
 	}
 }
+
+func TestBuildActionToolRegistry_AsyncActionsPoll(t *testing.T) {
+	manifest := tool.NewManifest("exporter")
+	manifest.Transport = tool.NewNativeTransport()
+	manifest.Actions["export"] = tool.ActionSpec{
+		Async: &tool.AsyncSpec{PollAction: "status", PollIntervalMS: 2000, TimeoutMS: 600000},
+	}
+	manifest.Actions["status"] = tool.ActionSpec{}
+	store := &testToolStore{regs: map[string]tool.ToolRegistration{
+		"exporter": {Name: "exporter", Origin: tool.OriginNative, Manifest: manifest, Status: tool.StatusReady, Enabled: true},
+	}}
+
+	registry, err := BuildActionToolRegistry(context.Background(), store)
+	if err != nil {
+		t.Fatalf("BuildActionToolRegistry() error = %v", err)
+	}
+	export, _ := registry.Get("exporter.export")
+	if _, ok := export.(core.ToolPoller); !ok {
+		t.Fatalf("exporter.export is %T, want a core.ToolPoller", export)
+	}
+	if status, _ := registry.Get("exporter.status"); status == nil {
+		t.Fatal("expected exporter.status to be registered")
+	} else if _, ok := status.(core.ToolPoller); ok {
+		t.Error("exporter.status should not poll")
+	}
+
+	node, err := buildToolNode(graph.NodeDef{ID: "export", Type: "exporter.export", Config: map[string]any{"poll_interval": "1s"}}, export, nil, nil)
+	if err != nil {
+		t.Fatalf("buildToolNode() error = %v", err)
+	}
+	if cfg := node.Config(); cfg.PollInterval != time.Second || cfg.AsyncTimeout != 10*time.Minute {
+		t.Errorf("PollInterval = %s, AsyncTimeout = %s", cfg.PollInterval, cfg.AsyncTimeout)
+	}
+}
+
+func TestBuildActionToolRegistry_CacheableActions(t *testing.T) {
+	manifest := tool.NewManifest("geo")
+	manifest.Transport = tool.NewNativeTransport()
+	manifest.Actions["geocode"] = tool.ActionSpec{
+		Idempotent: true,
+		Inputs:     map[string]tool.FieldSpec{"address": {Type: tool.TypeString}},
+		Cache:      &tool.CacheSpec{TTLMS: 60000, VaryOn: []string{"address"}},
+	}
+	store := &testToolStore{regs: map[string]tool.ToolRegistration{
+		"geo": {Name: "geo", Origin: tool.OriginNative, Manifest: manifest, Status: tool.StatusReady, Enabled: true},
+	}}
+
+	registry, err := BuildActionToolRegistry(context.Background(), store)
+	if err != nil {
+		t.Fatalf("BuildActionToolRegistry() error = %v", err)
+	}
+	geocode, _ := registry.Get("geo.geocode")
+	cacheable, ok := geocode.(core.CacheableTool)
+	if !ok {
+		t.Fatalf("geo.geocode is %T, want a core.CacheableTool", geocode)
+	}
+	policy := cacheable.CachePolicy()
+	if policy == nil || policy.TTL != time.Minute || len(policy.VaryOn) != 1 || policy.VaryOn[0] != "address" {
+		t.Fatalf("CachePolicy() = %+v", policy)
+	}
+
+	nd := graph.NodeDef{ID: "geo", Type: "geo.geocode", Config: map[string]any{"cache_bypass": "refresh"}}
+	node, err := buildToolNode(nd, geocode, nil, nodes.NewMemoryCacheStore())
+	if err != nil {
+		t.Fatalf("buildToolNode() error = %v", err)
+	}
+	if cfg := node.Config(); cfg.CacheBypass != nodes.ToolCacheRefresh || cfg.Cache == nil {
+		t.Errorf("CacheBypass = %q, Cache = %v", cfg.CacheBypass, cfg.Cache)
+	}
+
+	nd.Config["cache_bypass"] = "always"
+	if _, err := buildToolNode(nd, geocode, nil, nil); err == nil {
+		t.Error("expected an error for an unknown cache_bypass")
+	}
+}
@@ -0,0 +1,240 @@
+package hydrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// Credential verification states.
+const (
+	CredentialVerified = "verified"
+	CredentialFailed   = "failed"
+	// CredentialUnchecked means no check was possible: the client has no
+	// CredentialChecker and no model was known to send a completion to.
+	CredentialUnchecked = "unchecked"
+)
+
+// DefaultCredentialTTL is how long a verification result is reused.
+const DefaultCredentialTTL = 15 * time.Minute
+
+// defaultCredentialTimeout bounds a single verification call.
+const defaultCredentialTimeout = 10 * time.Second
+
+// CredentialChecker is implemented by LLM clients that can check their
+// credentials with a cheap call, such as listing models. Clients without
+// it are checked with a one-token completion.
+type CredentialChecker interface {
+	VerifyCredentials(ctx context.Context) error
+}
+
+// CredentialStatus is the result of verifying a provider's credentials.
+type CredentialStatus struct {
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Error    string `json:"error,omitempty"`
+	// ErrorCode and Remediation are set when a failure was classified as
+	// a core.ProviderError.
+	ErrorCode   core.ProviderErrorCode `json:"error_code,omitempty"`
+	Remediation string                 `json:"remediation,omitempty"`
+	Model       string                 `json:"model,omitempty"`
+	CheckedAt   time.Time              `json:"checked_at"`
+	ExpiresAt   time.Time              `json:"expires_at"`
+}
+
+// CredentialVerifier checks provider credentials and caches the results
+// for a TTL. A result is dropped early when the provider's key or base URL
+// changes. It is safe for concurrent use.
+type CredentialVerifier struct {
+	factory ClientFactory
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	results map[string]credentialEntry
+}
+
+type credentialEntry struct {
+	fingerprint string
+	status      CredentialStatus
+}
+
+// NewCredentialVerifier creates a verifier that builds clients with
+// factory. A ttl of 0 uses DefaultCredentialTTL.
+func NewCredentialVerifier(factory ClientFactory, ttl time.Duration) *CredentialVerifier {
+	if ttl <= 0 {
+		ttl = DefaultCredentialTTL
+	}
+	return &CredentialVerifier{
+		factory: factory,
+		ttl:     ttl,
+		timeout: defaultCredentialTimeout,
+		now:     time.Now,
+		results: make(map[string]credentialEntry),
+	}
+}
+
+// Status returns the cached, unexpired result for the provider.
+func (v *CredentialVerifier) Status(name string, cfg ProviderConfig) (CredentialStatus, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	entry, ok := v.results[name]
+	if !ok || entry.fingerprint != credentialFingerprint(cfg) || !v.now().Before(entry.status.ExpiresAt) {
+		return CredentialStatus{}, false
+	}
+	return entry.status, true
+}
+
+// Verify returns the cached result for the provider, checking the
+// credentials when there is none. model is used for the completion
+// fallback and may be empty. A cached unchecked result is checked again
+// when a model is given.
+func (v *CredentialVerifier) Verify(ctx context.Context, name string, cfg ProviderConfig, model string) CredentialStatus {
+	if status, ok := v.Status(name, cfg); ok && (status.State != CredentialUnchecked || model == "") {
+		return status
+	}
+	return v.Refresh(ctx, name, cfg, model)
+}
+
+// Refresh checks the provider's credentials, ignoring any cached result.
+func (v *CredentialVerifier) Refresh(ctx context.Context, name string, cfg ProviderConfig, model string) CredentialStatus {
+	status := CredentialStatus{Provider: name, CheckedAt: v.now().UTC()}
+	switch err := v.check(ctx, name, cfg, model, &status); {
+	case errors.Is(err, ErrCredentialUnchecked):
+		status.State = CredentialUnchecked
+	case err != nil:
+		status.State = CredentialFailed
+		status.Error = err.Error()
+		if pe, ok := core.AsProviderError(err); ok {
+			status.ErrorCode = pe.Code
+			status.Remediation = pe.Remediation()
+		}
+	default:
+		status.State = CredentialVerified
+	}
+	status.ExpiresAt = status.CheckedAt.Add(v.ttl)
+
+	v.mu.Lock()
+	v.results[name] = credentialEntry{fingerprint: credentialFingerprint(cfg), status: status}
+	v.mu.Unlock()
+	return status
+}
+
+// failure describes a failed check, with the remediation hint when the
+// error was classified.
+func (s CredentialStatus) failure() string {
+	if s.ErrorCode == "" {
+		return s.Error
+	}
+	return fmt.Sprintf("%s (%s: %s)", s.Error, s.ErrorCode, s.Remediation)
+}
+
+// ErrCredentialUnchecked is returned by a CredentialChecker that has no
+// way to check its credentials. The provider is reported unchecked.
+var ErrCredentialUnchecked = errors.New("no credential check available")
+
+func (v *CredentialVerifier) check(ctx context.Context, name string, cfg ProviderConfig, model string, status *CredentialStatus) error {
+	client, err := v.factory(name, cfg)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, v.timeout)
+	defer cancel()
+
+	if checker, ok := client.(CredentialChecker); ok {
+		return checker.VerifyCredentials(ctx)
+	}
+	if model == "" {
+		return ErrCredentialUnchecked
+	}
+	status.Model = model
+	maxTokens := 1
+	_, err = client.Complete(ctx, core.LLMRequest{
+		Model:     model,
+		InputText: "ping",
+		MaxTokens: &maxTokens,
+	})
+	return err
+}
+
+// VerifyGraph verifies every provider the definition's nodes reference
+// through a "provider" config value or a model_select arm, using the first
+// model configured with each. It returns an error naming each provider that failed, or
+// that the definition references but providers does not configure.
+func (v *CredentialVerifier) VerifyGraph(ctx context.Context, def *graph.GraphDefinition, providers ProviderMap) error {
+	var problems []string
+	for _, ref := range GraphProviderRefs(def) {
+		cfg, ok := providers[ref.Provider]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("provider %q is not configured", ref.Provider))
+			continue
+		}
+		status := v.Verify(ctx, ref.Provider, cfg, ref.Model)
+		if status.State == CredentialFailed {
+			problems = append(problems, fmt.Sprintf("provider %q credentials failed verification: %s", ref.Provider, status.failure()))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// ProviderRef is a provider referenced by a graph, with the first model a
+// node configures for it.
+type ProviderRef struct {
+	Provider string
+	Model    string
+}
+
+// GraphProviderRefs lists the providers def's nodes reference, including
+// model_select arms, sorted by name.
+func GraphProviderRefs(def *graph.GraphDefinition) []ProviderRef {
+	if def == nil {
+		return nil
+	}
+	def = def.ApplyLLMDefaults()
+	models := make(map[string]string)
+	add := func(name, model string) {
+		if name == "" {
+			return
+		}
+		if existing, ok := models[name]; !ok || existing == "" {
+			models[name] = model
+		}
+	}
+	for _, nd := range def.Nodes {
+		name, _ := nd.Config["provider"].(string)
+		model, _ := nd.Config["model"].(string)
+		add(name, model)
+		if nd.Type == "model_select" {
+			arms, _ := parseModelArms(nd)
+			for _, arm := range arms {
+				add(arm.Provider, arm.Model)
+			}
+		}
+	}
+	refs := make([]ProviderRef, 0, len(models))
+	for name, model := range models {
+		refs = append(refs, ProviderRef{Provider: name, Model: model})
+	}
+	sort.Slice(refs, func(i, j int) bool { return refs[i].Provider < refs[j].Provider })
+	return refs
+}
+
+// credentialFingerprint identifies a provider config without keeping its
+// key.
+func credentialFingerprint(cfg ProviderConfig) string {
+	sum := sha256.Sum256([]byte(cfg.APIKey + "\x00" + cfg.BaseURL))
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,179 @@
+package hydrate
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// keyCheckClient accepts requests only when its key is "good".
+type keyCheckClient struct {
+	key      string
+	requests *[]core.LLMRequest
+}
+
+func (c *keyCheckClient) Complete(_ context.Context, req core.LLMRequest) (core.LLMResponse, error) {
+	*c.requests = append(*c.requests, req)
+	if c.key != "good" {
+		return core.LLMResponse{}, errors.New("401 invalid api key")
+	}
+	return core.LLMResponse{Text: "p"}, nil
+}
+
+type checkerClient struct {
+	keyCheckClient
+	err error
+}
+
+func (c *checkerClient) VerifyCredentials(context.Context) error { return c.err }
+
+func newKeyCheckFactory() (ClientFactory, *[]core.LLMRequest) {
+	var requests []core.LLMRequest
+	return func(_ string, cfg ProviderConfig) (core.LLMClient, error) {
+		return &keyCheckClient{key: cfg.APIKey, requests: &requests}, nil
+	}, &requests
+}
+
+func TestCredentialVerifier_CompletionFallbackAndCache(t *testing.T) {
+	factory, requests := newKeyCheckFactory()
+	v := NewCredentialVerifier(factory, time.Minute)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	v.now = func() time.Time { return now }
+
+	good := ProviderConfig{APIKey: "good"}
+	if status := v.Verify(context.Background(), "openai", good, ""); status.State != CredentialUnchecked {
+		t.Fatalf("state without model = %q, want %q", status.State, CredentialUnchecked)
+	}
+	status := v.Verify(context.Background(), "openai", good, "gpt-4o-mini")
+	if status.State != CredentialVerified || status.Model != "gpt-4o-mini" {
+		t.Fatalf("status = %+v, want verified with model", status)
+	}
+	if len(*requests) != 1 || (*requests)[0].MaxTokens == nil || *(*requests)[0].MaxTokens != 1 {
+		t.Fatalf("requests = %+v, want one 1-token completion", *requests)
+	}
+
+	v.Verify(context.Background(), "openai", good, "gpt-4o-mini")
+	if len(*requests) != 1 {
+		t.Fatalf("cached result was not reused: %d requests", len(*requests))
+	}
+
+	bad := ProviderConfig{APIKey: "bad"}
+	if _, ok := v.Status("openai", bad); ok {
+		t.Fatal("a changed key must not reuse the cached result")
+	}
+	status = v.Verify(context.Background(), "openai", bad, "gpt-4o-mini")
+	if status.State != CredentialFailed || !strings.Contains(status.Error, "invalid api key") {
+		t.Fatalf("status = %+v, want failed", status)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := v.Status("openai", bad); ok {
+		t.Fatal("expired result should not be returned")
+	}
+}
+
+func TestCredentialVerifier_UsesChecker(t *testing.T) {
+	var requests []core.LLMRequest
+	checkErr := errors.New("403 forbidden")
+	v := NewCredentialVerifier(func(string, ProviderConfig) (core.LLMClient, error) {
+		return &checkerClient{keyCheckClient: keyCheckClient{requests: &requests}, err: checkErr}, nil
+	}, 0)
+
+	status := v.Refresh(context.Background(), "anthropic", ProviderConfig{APIKey: "k"}, "")
+	if status.State != CredentialFailed || status.Error != "403 forbidden" {
+		t.Fatalf("status = %+v, want checker failure", status)
+	}
+	if len(requests) != 0 {
+		t.Fatalf("checker clients should not get completions, got %d", len(requests))
+	}
+	if got := status.ExpiresAt.Sub(status.CheckedAt); got != DefaultCredentialTTL {
+		t.Fatalf("ttl = %s, want %s", got, DefaultCredentialTTL)
+	}
+}
+
+func TestCredentialVerifier_VerifyGraph(t *testing.T) {
+	factory, _ := newKeyCheckFactory()
+	v := NewCredentialVerifier(factory, 0)
+	def := &graph.GraphDefinition{Nodes: []graph.NodeDef{
+		{ID: "a", Type: "llm_prompt", Config: map[string]any{"provider": "openai"}},
+		{ID: "b", Type: "llm_prompt", Config: map[string]any{"provider": "openai", "model": "gpt-4o"}},
+		{ID: "c", Type: "llm_router", Config: map[string]any{"provider": "anthropic", "model": "claude"}},
+		{ID: "d", Type: "llm_prompt", Config: map[string]any{"provider": "missing", "model": "m"}},
+		{ID: "e", Type: "transform", Config: map[string]any{"transform": "pick"}},
+		{ID: "f", Type: "model_select", Config: map[string]any{"arms": []any{
+			map[string]any{"provider": "anthropic", "model": "haiku"},
+			map[string]any{"provider": "mistral", "model": "small"},
+		}}},
+	}}
+
+	refs := GraphProviderRefs(def)
+	want := []ProviderRef{{"anthropic", "claude"}, {"missing", "m"}, {"mistral", "small"}, {"openai", "gpt-4o"}}
+	if len(refs) != len(want) {
+		t.Fatalf("refs = %+v, want %+v", refs, want)
+	}
+	for i := range want {
+		if refs[i] != want[i] {
+			t.Fatalf("refs = %+v, want %+v", refs, want)
+		}
+	}
+
+	err := v.VerifyGraph(context.Background(), def, ProviderMap{
+		"openai":    {APIKey: "good"},
+		"anthropic": {APIKey: "revoked"},
+	})
+	if err == nil {
+		t.Fatal("expected verification error")
+	}
+	for _, part := range []string{`provider "anthropic" credentials failed verification`, `provider "missing" is not configured`, `provider "mistral" is not configured`} {
+		if !strings.Contains(err.Error(), part) {
+			t.Fatalf("error %q does not mention %q", err, part)
+		}
+	}
+	if strings.Contains(err.Error(), `"openai"`) {
+		t.Fatalf("error %q mentions the verified provider", err)
+	}
+}
+
+func TestNewLiveNodeFactory_CredentialVerifier(t *testing.T) {
+	var requests []core.LLMRequest
+	factory := func(_ string, cfg ProviderConfig) (core.LLMClient, error) {
+		return &checkerClient{keyCheckClient: keyCheckClient{key: cfg.APIKey, requests: &requests}, err: errors.New("bad key")}, nil
+	}
+	verifier := NewCredentialVerifier(factory, 0)
+	nodeFactory := NewLiveNodeFactory(ProviderMap{"openai": {APIKey: "k"}}, factory, WithCredentialVerifier(verifier))
+
+	_, err := nodeFactory(graph.NodeDef{
+		ID:     "llm",
+		Type:   "llm_prompt",
+		Config: map[string]any{"provider": "openai", "model": "gpt-4o", "prompt_template": "hi"},
+	})
+	if err == nil || !strings.Contains(err.Error(), `provider "openai" credentials failed verification: bad key`) {
+		t.Fatalf("err = %v, want credential failure", err)
+	}
+}
+
+func TestCredentialVerifier_ProviderErrorCode(t *testing.T) {
+	var requests []core.LLMRequest
+	checkErr := &core.ProviderError{Provider: "openai", Code: core.ProviderErrInvalidAPIKey, Status: 401, Err: errors.New("401 invalid api key")}
+	v := NewCredentialVerifier(func(string, ProviderConfig) (core.LLMClient, error) {
+		return &checkerClient{keyCheckClient: keyCheckClient{requests: &requests}, err: checkErr}, nil
+	}, 0)
+
+	status := v.Refresh(context.Background(), "openai", ProviderConfig{APIKey: "k"}, "")
+	if status.ErrorCode != core.ProviderErrInvalidAPIKey || status.Remediation != core.ProviderErrInvalidAPIKey.Remediation() {
+		t.Fatalf("status = %+v, want the provider error code and remediation", status)
+	}
+
+	def := &graph.GraphDefinition{Nodes: []graph.NodeDef{
+		{ID: "a", Type: "llm_prompt", Config: map[string]any{"provider": "openai", "model": "gpt-4o"}},
+	}}
+	err := v.VerifyGraph(context.Background(), def, ProviderMap{"openai": {APIKey: "k"}})
+	if err == nil || !strings.Contains(err.Error(), "(invalid_api_key: Check the provider's API key") {
+		t.Fatalf("err = %v, want the remediation hint", err)
+	}
+}
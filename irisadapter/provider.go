@@ -8,9 +8,16 @@ import (
 
 	"github.com/petal-labs/iris/core"
 	"github.com/petal-labs/petalflow"
+	"github.com/petal-labs/petalflow/llmprovider"
+	"github.com/petal-labs/petalflow/promptcache"
 )
 
 // ProviderAdapter adapts a core.Provider to the petalflow.LLMClient interface.
+//
+// Prompt caching hints (LLMRequest.Cache) reach Anthropic and OpenAI
+// providers, and their cache usage is reported, when the provider is
+// created with llmprovider.NewPromptCacheClient as its HTTP client, e.g.
+// anthropic.New(key, anthropic.WithHTTPClient(promptcache.NewClient())).
 type ProviderAdapter struct {
 	provider core.Provider
 }
@@ -24,15 +31,18 @@ func NewProviderAdapter(provider core.Provider) *ProviderAdapter {
 func (a *ProviderAdapter) Complete(ctx context.Context, req petalflow.LLMRequest) (petalflow.LLMResponse, error) {
 	// Convert LLMRequest to core.ChatRequest
 	chatReq := a.toCoreChatRequest(req)
+	ctx, cacheUsage := promptcache.WithHints(ctx, req.Cache)
 
 	// Call the provider
 	chatResp, err := a.provider.Chat(ctx, chatReq)
 	if err != nil {
-		return petalflow.LLMResponse{}, fmt.Errorf("provider chat failed: %w", err)
+		return petalflow.LLMResponse{}, llmprovider.ClassifyError(a.provider.ID(), fmt.Errorf("provider chat failed: %w", err))
 	}
 
 	// Convert core.ChatResponse to LLMResponse
-	return a.fromCoreChatResponse(chatResp, req), nil
+	resp := a.fromCoreChatResponse(chatResp, req)
+	resp.Usage.CacheReadTokens, resp.Usage.CacheWriteTokens = cacheUsage.Tokens()
+	return resp, nil
 }
 
 // toCoreChatRequest converts a petalflow.LLMRequest to core.ChatRequest.
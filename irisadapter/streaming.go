@@ -6,6 +6,8 @@ import (
 	"strings"
 
 	"github.com/petal-labs/petalflow"
+	"github.com/petal-labs/petalflow/llmprovider"
+	"github.com/petal-labs/petalflow/promptcache"
 )
 
 // CompleteStream sends a streaming completion request to the underlying provider.
@@ -15,11 +17,12 @@ import (
 func (a *ProviderAdapter) CompleteStream(ctx context.Context, req petalflow.LLMRequest) (<-chan petalflow.StreamChunk, error) {
 	// Convert LLMRequest to core.ChatRequest (reuse existing conversion)
 	chatReq := a.toCoreChatRequest(req)
+	streamCtx, cacheUsage := promptcache.WithHints(ctx, req.Cache)
 
 	// Call the provider's StreamChat
-	stream, err := a.provider.StreamChat(ctx, chatReq)
+	stream, err := a.provider.StreamChat(streamCtx, chatReq)
 	if err != nil {
-		return nil, fmt.Errorf("provider stream chat failed: %w", err)
+		return nil, llmprovider.ClassifyError(a.provider.ID(), fmt.Errorf("provider stream chat failed: %w", err))
 	}
 
 	out := make(chan petalflow.StreamChunk, 1)
@@ -64,7 +67,7 @@ func (a *ProviderAdapter) CompleteStream(ctx context.Context, req petalflow.LLMR
 		case err, ok := <-stream.Err:
 			if ok && err != nil {
 				out <- petalflow.StreamChunk{
-					Error: err,
+					Error: llmprovider.ClassifyError(a.provider.ID(), err),
 					Done:  true,
 				}
 				return
@@ -86,6 +89,7 @@ func (a *ProviderAdapter) CompleteStream(ctx context.Context, req petalflow.LLMR
 					OutputTokens: resp.Usage.CompletionTokens,
 					TotalTokens:  resp.Usage.TotalTokens,
 				}
+				finalChunk.Usage.CacheReadTokens, finalChunk.Usage.CacheWriteTokens = cacheUsage.Tokens()
 			}
 		case <-ctx.Done():
 			finalChunk.Error = ctx.Err()
@@ -12,6 +12,8 @@ import (
 	iriscore "github.com/petal-labs/iris/core"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/promptcache"
 )
 
 // irisAdapter wraps an iris Provider to implement core.LLMClient.
@@ -22,13 +24,35 @@ type irisAdapter struct {
 // Complete sends a synchronous completion request via the iris provider.
 func (a *irisAdapter) Complete(ctx context.Context, req core.LLMRequest) (core.LLMResponse, error) {
 	chatReq := a.toRequest(req)
+	ctx, cacheUsage := promptcache.WithHints(ctx, req.Cache)
 
 	chatResp, err := a.provider.Chat(ctx, chatReq)
 	if err != nil {
-		return core.LLMResponse{}, fmt.Errorf("provider chat failed: %w", err)
+		return core.LLMResponse{}, ClassifyError(a.provider.ID(), fmt.Errorf("provider chat failed: %w", err))
 	}
 
-	return a.fromResponse(chatResp, req), nil
+	resp := a.fromResponse(chatResp, req)
+	resp.Usage.CacheReadTokens, resp.Usage.CacheWriteTokens = cacheUsage.Tokens()
+	return resp, nil
+}
+
+// VerifyCredentials sends a one-token completion to the provider's first
+// listed model. Providers that list no models are left unchecked.
+func (a *irisAdapter) VerifyCredentials(ctx context.Context) error {
+	models := a.provider.Models()
+	if len(models) == 0 {
+		return hydrate.ErrCredentialUnchecked
+	}
+	maxTokens := 1
+	_, err := a.provider.Chat(ctx, &iriscore.ChatRequest{
+		Model:     models[0].ID,
+		Messages:  []iriscore.Message{{Role: iriscore.RoleUser, Content: "ping"}},
+		MaxTokens: &maxTokens,
+	})
+	if err != nil {
+		return ClassifyError(a.provider.ID(), fmt.Errorf("provider chat failed: %w", err))
+	}
+	return nil
 }
 
 // toRequest converts a core.LLMRequest to an iris ChatRequest.
@@ -179,10 +203,11 @@ func toIrisRole(role string) iriscore.Role {
 // has Done=true and includes Usage if available from the provider.
 func (a *irisAdapter) CompleteStream(ctx context.Context, req core.LLMRequest) (<-chan core.StreamChunk, error) {
 	chatReq := a.toRequest(req)
+	streamCtx, cacheUsage := promptcache.WithHints(ctx, req.Cache)
 
-	stream, err := a.provider.StreamChat(ctx, chatReq)
+	stream, err := a.provider.StreamChat(streamCtx, chatReq)
 	if err != nil {
-		return nil, fmt.Errorf("provider stream chat failed: %w", err)
+		return nil, ClassifyError(a.provider.ID(), fmt.Errorf("provider stream chat failed: %w", err))
 	}
 
 	out := make(chan core.StreamChunk, 1)
@@ -224,7 +249,7 @@ func (a *irisAdapter) CompleteStream(ctx context.Context, req core.LLMRequest) (
 		case err, ok := <-stream.Err:
 			if ok && err != nil {
 				out <- core.StreamChunk{
-					Error: err,
+					Error: ClassifyError(a.provider.ID(), err),
 					Done:  true,
 				}
 				return
@@ -245,6 +270,7 @@ func (a *irisAdapter) CompleteStream(ctx context.Context, req core.LLMRequest) (
 					OutputTokens: resp.Usage.CompletionTokens,
 					TotalTokens:  resp.Usage.TotalTokens,
 				}
+				finalChunk.Usage.CacheReadTokens, finalChunk.Usage.CacheWriteTokens = cacheUsage.Tokens()
 			}
 		case <-ctx.Done():
 			finalChunk.Error = ctx.Err()
@@ -257,4 +283,7 @@ func (a *irisAdapter) CompleteStream(ctx context.Context, req core.LLMRequest) (
 }
 
 // Compile-time interface check.
-var _ core.StreamingLLMClient = (*irisAdapter)(nil)
+var (
+	_ core.StreamingLLMClient   = (*irisAdapter)(nil)
+	_ hydrate.CredentialChecker = (*irisAdapter)(nil)
+)
@@ -0,0 +1,96 @@
+package llmprovider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	iriscore "github.com/petal-labs/iris/core"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// providerErrorPatterns match provider error codes and prose, lowercased,
+// in priority order: a 400 that mentions the context window is a context
+// error, not a bad request.
+var providerErrorPatterns = []struct {
+	code    core.ProviderErrorCode
+	phrases []string
+}{
+	{core.ProviderErrContextLength, []string{
+		"context_length_exceeded", "context length", "context window", "maximum context",
+		"prompt is too long", "too many tokens", "reduce the length",
+	}},
+	{core.ProviderErrContentFiltered, []string{
+		"content_filter", "content filter", "content management policy", "content_policy",
+		"safety system", "blocked by safety", "responsibleaipolicyviolation",
+	}},
+	{core.ProviderErrQuotaExceeded, []string{
+		"insufficient_quota", "exceeded your current quota", "credit balance", "billing",
+	}},
+	{core.ProviderErrInvalidAPIKey, []string{
+		"invalid_api_key", "invalid api key", "incorrect api key", "invalid x-api-key",
+		"authentication_error", "permission_error", "unauthorized",
+	}},
+	{core.ProviderErrModelNotFound, []string{
+		"model_not_found", "model not found", "does not exist", "unknown model", "not_found_error",
+	}},
+	{core.ProviderErrRateLimited, []string{
+		"rate_limit", "rate limit", "too many requests",
+	}},
+	{core.ProviderErrUnavailable, []string{
+		"overloaded", "service unavailable", "server error",
+	}},
+}
+
+// ClassifyError wraps a failed provider call in a core.ProviderError with
+// a normalized code. Cancellations and errors it cannot classify are
+// returned unchanged, as is an error that is already classified.
+func ClassifyError(provider string, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if _, ok := core.AsProviderError(err); ok {
+		return err
+	}
+
+	status := 0
+	var irisErr *iriscore.ProviderError
+	if errors.As(err, &irisErr) {
+		status = irisErr.Status
+		if irisErr.Provider != "" {
+			provider = irisErr.Provider
+		}
+	}
+	code, ok := classifyProviderError(err, status)
+	if !ok {
+		return err
+	}
+	return &core.ProviderError{Provider: provider, Code: code, Status: status, Err: err}
+}
+
+func classifyProviderError(err error, status int) (core.ProviderErrorCode, bool) {
+	text := strings.ToLower(err.Error())
+	for _, p := range providerErrorPatterns {
+		for _, phrase := range p.phrases {
+			if strings.Contains(text, phrase) {
+				return p.code, true
+			}
+		}
+	}
+
+	switch {
+	case status == http.StatusUnauthorized || status == http.StatusForbidden ||
+		errors.Is(err, iriscore.ErrUnauthorized):
+		return core.ProviderErrInvalidAPIKey, true
+	case status == http.StatusNotFound || errors.Is(err, iriscore.ErrNotFound):
+		return core.ProviderErrModelNotFound, true
+	case status == http.StatusTooManyRequests || errors.Is(err, iriscore.ErrRateLimited):
+		return core.ProviderErrRateLimited, true
+	case status >= http.StatusInternalServerError || errors.Is(err, iriscore.ErrServer) ||
+		errors.Is(err, iriscore.ErrNetwork):
+		return core.ProviderErrUnavailable, true
+	}
+	return "", false
+}
@@ -0,0 +1,79 @@
+package llmprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	iriscore "github.com/petal-labs/iris/core"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func TestClassifyError(t *testing.T) {
+	irisErr := func(status int, code, message string, sentinel error) error {
+		return fmt.Errorf("provider chat failed: %w", &iriscore.ProviderError{
+			Provider: "openai", Status: status, Code: code, Message: message, Err: sentinel,
+		})
+	}
+	tests := []struct {
+		name string
+		err  error
+		want core.ProviderErrorCode
+	}{
+		{"invalid key", irisErr(401, "invalid_api_key", "Incorrect API key provided", iriscore.ErrUnauthorized), core.ProviderErrInvalidAPIKey},
+		{"anthropic auth", irisErr(401, "authentication_error", "invalid x-api-key", iriscore.ErrUnauthorized), core.ProviderErrInvalidAPIKey},
+		{"model", irisErr(404, "model_not_found", "The model `gpt-9` does not exist", iriscore.ErrNotFound), core.ProviderErrModelNotFound},
+		{"context", irisErr(400, "context_length_exceeded", "This model's maximum context length is 8192 tokens", iriscore.ErrBadRequest), core.ProviderErrContextLength},
+		{"anthropic context", irisErr(400, "invalid_request_error", "prompt is too long: 210000 tokens > 200000 maximum", iriscore.ErrBadRequest), core.ProviderErrContextLength},
+		{"filtered", irisErr(400, "content_filter", "The response was filtered", iriscore.ErrBadRequest), core.ProviderErrContentFiltered},
+		{"rate limited", irisErr(429, "rate_limit_exceeded", "Rate limit reached for requests", iriscore.ErrRateLimited), core.ProviderErrRateLimited},
+		{"quota", irisErr(429, "insufficient_quota", "You exceeded your current quota", iriscore.ErrRateLimited), core.ProviderErrQuotaExceeded},
+		{"overloaded", irisErr(529, "overloaded_error", "Overloaded", iriscore.ErrServer), core.ProviderErrUnavailable},
+		{"network", fmt.Errorf("provider chat failed: %w", iriscore.ErrNetwork), core.ProviderErrUnavailable},
+		{"status only", irisErr(403, "", "forbidden", nil), core.ProviderErrInvalidAPIKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ClassifyError("openai", tt.err)
+			pe, ok := core.AsProviderError(err)
+			if !ok {
+				t.Fatalf("ClassifyError(%v) was not classified", tt.err)
+			}
+			if pe.Code != tt.want || pe.Provider != "openai" || err.Error() != tt.err.Error() {
+				t.Fatalf("classified %+v, want %s", pe, tt.want)
+			}
+		})
+	}
+}
+
+func TestClassifyError_LeavesOthersAlone(t *testing.T) {
+	for _, err := range []error{
+		nil,
+		context.Canceled,
+		fmt.Errorf("provider chat failed: %w", context.DeadlineExceeded),
+		errors.New("tool args invalid json"),
+	} {
+		if got := ClassifyError("openai", err); got != err {
+			t.Errorf("ClassifyError(%v) = %v, want it unchanged", err, got)
+		}
+	}
+
+	classified := ClassifyError("anthropic", errors.New("rate limit exceeded"))
+	if again := ClassifyError("other", classified); again != classified {
+		t.Error("an already classified error should be returned as is")
+	}
+}
+
+func TestComplete_ClassifiesProviderErrors(t *testing.T) {
+	adapter := &irisAdapter{provider: &mockProvider{
+		id:        "anthropic",
+		chatError: &iriscore.ProviderError{Provider: "anthropic", Status: 401, Code: "authentication_error", Err: iriscore.ErrUnauthorized},
+	}}
+	_, err := adapter.Complete(context.Background(), core.LLMRequest{Model: "m", InputText: "hi"})
+	pe, ok := core.AsProviderError(err)
+	if !ok || pe.Code != core.ProviderErrInvalidAPIKey || pe.Status != 401 {
+		t.Fatalf("err = %v (%+v)", err, pe)
+	}
+}
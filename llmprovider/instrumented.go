@@ -126,6 +126,9 @@ func (c *InstrumentedClient) emitLLMResponseEvent(req core.LLMRequest, resp core
 		event.Payload["status"] = "success"
 		event.Payload["provider"] = resp.Provider
 		event.Payload["response_model"] = resp.Model
+		if resp.Region != "" {
+			event.Payload["region"] = resp.Region
+		}
 		event.Payload["completion"] = resp.Text
 		event.Payload["stop_reason"] = resp.Status
 
@@ -263,6 +266,9 @@ func (c *InstrumentedStreamingClient) CompleteStream(ctx context.Context, req co
 							event.Payload["output_tokens"] = finalUsage.OutputTokens
 							event.Payload["total_tokens"] = finalUsage.TotalTokens
 						}
+						if chunk.Region != "" {
+							event.Payload["region"] = chunk.Region
+						}
 					}
 
 					c.emitter(event)
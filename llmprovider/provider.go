@@ -11,6 +11,7 @@ import (
 
 	"github.com/petal-labs/petalflow/core"
 	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/promptcache"
 )
 
 // NewClient creates a core.LLMClient for the named provider using the given config.
@@ -28,13 +29,13 @@ func createProvider(name string, cfg hydrate.ProviderConfig) (providers.Provider
 
 	switch normalized {
 	case "openai":
-		opts := make([]openaiprovider.Option, 0, 1)
+		opts := []openaiprovider.Option{openaiprovider.WithHTTPClient(promptcache.NewClient())}
 		if cfg.BaseURL != "" {
 			opts = append(opts, openaiprovider.WithBaseURL(cfg.BaseURL))
 		}
 		return openaiprovider.New(cfg.APIKey, opts...), nil
 	case "anthropic":
-		opts := make([]anthropicprovider.Option, 0, 1)
+		opts := []anthropicprovider.Option{anthropicprovider.WithHTTPClient(promptcache.NewClient())}
 		if cfg.BaseURL != "" {
 			opts = append(opts, anthropicprovider.WithBaseURL(cfg.BaseURL))
 		}
@@ -1,11 +1,19 @@
 package llmprovider
 
 import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/petal-labs/iris/providers"
+	"github.com/petal-labs/petalflow/core"
 	"github.com/petal-labs/petalflow/hydrate"
 )
 
@@ -117,3 +125,187 @@ func readProviderConfigStringField(t *testing.T, p providers.Provider, field str
 
 	return v.String()
 }
+
+// captureServer records the request bodies and headers it receives and
+// answers with respond.
+type captureServer struct {
+	mu      sync.Mutex
+	bodies  []map[string]any
+	headers []http.Header
+}
+
+func (c *captureServer) start(t *testing.T, contentType, respond string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		_ = json.Unmarshal(data, &body)
+		c.mu.Lock()
+		c.bodies = append(c.bodies, body)
+		c.headers = append(c.headers, r.Header.Clone())
+		c.mu.Unlock()
+		w.Header().Set("Content-Type", contentType)
+		_, _ = io.WriteString(w, respond)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func cachedRequest() core.LLMRequest {
+	return core.LLMRequest{
+		Model:     "claude-test",
+		System:    "Answer from the handbook.",
+		Messages:  []core.LLMMessage{{Role: "user", Content: "Employees get twenty days of leave."}},
+		InputText: "How much leave do I get?",
+		Cache:     &core.LLMCacheHints{System: true, Messages: 1, TTL: time.Hour, Key: "answer"},
+	}
+}
+
+func TestPromptCache_Anthropic(t *testing.T) {
+	var capture captureServer
+	srv := capture.start(t, "application/json", `{
+		"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-test",
+		"content": [{"type": "text", "text": "Twenty days."}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 12, "output_tokens": 4, "cache_read_input_tokens": 2048, "cache_creation_input_tokens": 0}
+	}`)
+
+	client, err := NewClient("anthropic", hydrate.ProviderConfig{APIKey: "sk-test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	resp, err := client.Complete(context.Background(), cachedRequest())
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if resp.Text != "Twenty days." {
+		t.Errorf("Text = %q", resp.Text)
+	}
+	if resp.Usage.CacheReadTokens != 2048 || resp.Usage.CacheWriteTokens != 0 {
+		t.Errorf("Usage = %+v, want 2048 cache read tokens", resp.Usage)
+	}
+
+	body := capture.bodies[0]
+	system, ok := body["system"].([]any)
+	if !ok || len(system) != 1 {
+		t.Fatalf("system = %#v, want one cached block", body["system"])
+	}
+	block := system[0].(map[string]any)
+	if block["text"] != "Answer from the handbook." {
+		t.Errorf("system text = %v", block["text"])
+	}
+	wantControl := map[string]any{"type": "ephemeral", "ttl": "1h"}
+	if got := block["cache_control"]; !equalJSON(got, wantControl) {
+		t.Errorf("system cache_control = %v, want %v", got, wantControl)
+	}
+
+	messages := body["messages"].([]any)
+	if len(messages) != 2 {
+		t.Fatalf("messages = %d, want 2", len(messages))
+	}
+	first := messages[0].(map[string]any)["content"].([]any)
+	if got := first[len(first)-1].(map[string]any)["cache_control"]; !equalJSON(got, wantControl) {
+		t.Errorf("context message cache_control = %v, want %v", got, wantControl)
+	}
+	second := messages[1].(map[string]any)["content"].([]any)
+	if _, ok := second[0].(map[string]any)["cache_control"]; ok {
+		t.Error("prompt message should not be cached")
+	}
+	if got := capture.headers[0].Get("anthropic-beta"); got != "extended-cache-ttl-2025-04-11" {
+		t.Errorf("anthropic-beta = %q, want %q", got, "extended-cache-ttl-2025-04-11")
+	}
+}
+
+func TestPromptCache_AnthropicStream(t *testing.T) {
+	var capture captureServer
+	srv := capture.start(t, "text/event-stream", strings.Join([]string{
+		"event: message_start",
+		`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-test","content":[],"usage":{"input_tokens":12,"output_tokens":1,"cache_read_input_tokens":0,"cache_creation_input_tokens":2048}}}`,
+		"",
+		"event: content_block_start",
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		"",
+		"event: content_block_delta",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Twenty days."}}`,
+		"",
+		"event: content_block_stop",
+		`data: {"type":"content_block_stop","index":0}`,
+		"",
+		"event: message_delta",
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":4}}`,
+		"",
+		"event: message_stop",
+		`data: {"type":"message_stop"}`,
+		"",
+		"",
+	}, "\n"))
+
+	client, err := NewClient("anthropic", hydrate.ProviderConfig{APIKey: "sk-test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	ch, err := client.(core.StreamingLLMClient).CompleteStream(context.Background(), cachedRequest())
+	if err != nil {
+		t.Fatalf("CompleteStream() error = %v", err)
+	}
+	var final core.StreamChunk
+	for chunk := range ch {
+		if chunk.Done {
+			final = chunk
+		}
+	}
+	if final.Error != nil {
+		t.Fatalf("stream error = %v", final.Error)
+	}
+	if final.Usage == nil || final.Usage.CacheWriteTokens != 2048 || final.Usage.CacheReadTokens != 0 {
+		t.Errorf("Usage = %+v, want 2048 cache write tokens", final.Usage)
+	}
+	if _, ok := capture.bodies[0]["system"].([]any); !ok {
+		t.Errorf("system = %#v, want cached blocks", capture.bodies[0]["system"])
+	}
+}
+
+func TestPromptCache_OpenAI(t *testing.T) {
+	var capture captureServer
+	srv := capture.start(t, "application/json", `{
+		"id": "chatcmpl-1", "object": "chat.completion", "model": "gpt-test",
+		"choices": [{"index": 0, "message": {"role": "assistant", "content": "Twenty days."}, "finish_reason": "stop"}],
+		"usage": {"prompt_tokens": 2060, "completion_tokens": 4, "total_tokens": 2064, "prompt_tokens_details": {"cached_tokens": 1920}}
+	}`)
+
+	client, err := NewClient("openai", hydrate.ProviderConfig{APIKey: "sk-test", BaseURL: srv.URL})
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	req := cachedRequest()
+	req.Model = "gpt-test"
+	resp, err := client.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if resp.Usage.CacheReadTokens != 1920 {
+		t.Errorf("CacheReadTokens = %d, want 1920", resp.Usage.CacheReadTokens)
+	}
+	if got := capture.bodies[0]["prompt_cache_key"]; got != "answer" {
+		t.Errorf("prompt_cache_key = %v, want answer", got)
+	}
+
+	// Cached tokens are reported even when the request carries no hints.
+	req.Cache = nil
+	resp, err = client.Complete(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if resp.Usage.CacheReadTokens != 1920 {
+		t.Errorf("CacheReadTokens without hints = %d, want 1920", resp.Usage.CacheReadTokens)
+	}
+	if _, ok := capture.bodies[1]["prompt_cache_key"]; ok {
+		t.Error("prompt_cache_key set without hints")
+	}
+}
+
+func equalJSON(a, b any) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
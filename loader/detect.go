@@ -23,7 +23,8 @@ const (
 
 // DetectSchema auto-detects the schema kind from file content and path.
 // Detection order:
-//  1. Determine parse format from extension (.yaml/.yml -> YAML, else JSON)
+//  1. Determine parse format from extension (.yaml/.yml -> YAML, .json -> JSON,
+//     else sniffed from the content)
 //  2. If parsed.kind is present, normalize/validate it and use it as source of truth
 //  3. If parsed.schema_version is present, validate semver and supported major
 //  4. Otherwise use legacy shape fallback (agents/tasks vs nodes/edges)
@@ -31,7 +32,7 @@ const (
 func DetectSchema(data []byte, filePath string) (SchemaKind, error) {
 	// Step 1: Parse based on file extension
 	var raw map[string]any
-	if isYAML(filePath) {
+	if FormatForPath(data, filePath) == FormatYAML {
 		if err := yaml.Unmarshal(data, &raw); err != nil {
 			return "", fmt.Errorf("parsing YAML: %w", err)
 		}
@@ -0,0 +1,293 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// KindAgents marks a document of a multi-document file as a library of
+// shared agent definitions rather than a workflow.
+const KindAgents = "agents"
+
+// SplitDocuments splits a workflow file into the workflows it defines, each
+// as canonical JSON, in file order.
+//
+// A YAML stream may hold several documents separated by "---", and anchors
+// defined in one document may be used in the later ones. Documents of kind
+// "agents" hold shared agent definitions: a workflow names them by id in
+// agents_ref (a string or a list) and gets their agents added to its own,
+// which win on conflicting names. A JSON file is a single document.
+func SplitDocuments(data []byte, format SourceFormat) ([][]byte, error) {
+	var raws []any
+	switch format {
+	case FormatYAML:
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var raw any
+			err := dec.Decode(&raw)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("parsing YAML: %w", err)
+			}
+			if raw != nil {
+				raws = append(raws, raw)
+			}
+		}
+	case FormatJSON:
+		var raw any
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("parsing JSON: %w", err)
+		}
+		if m, ok := raw.(map[string]any); !ok || !hasKey(m, "agents_ref") {
+			// Kept byte for byte, like CanonicalJSON.
+			return [][]byte{data}, nil
+		}
+		raws = []any{raw}
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+
+	docs := make([]map[string]any, 0, len(raws))
+	libraries := map[string]map[string]any{}
+	ids := map[string]bool{}
+	for i, raw := range raws {
+		doc, ok := raw.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("document %d is not a mapping", i+1)
+		}
+		id, _ := doc["id"].(string)
+		if id != "" {
+			if ids[id] {
+				return nil, fmt.Errorf("document %d: id %q is used by an earlier document", i+1, id)
+			}
+			ids[id] = true
+		}
+		if kind, _ := doc["kind"].(string); kind != KindAgents {
+			docs = append(docs, doc)
+			continue
+		}
+		agents, ok := doc["agents"].(map[string]any)
+		if id == "" || !ok {
+			return nil, fmt.Errorf("document %d: agents documents need an id and an agents mapping", i+1)
+		}
+		libraries[id] = agents
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("file defines no workflows")
+	}
+
+	out := make([][]byte, 0, len(docs))
+	for _, doc := range docs {
+		if err := resolveAgentsRef(doc, libraries); err != nil {
+			return nil, err
+		}
+		canonical, err := json.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, canonical)
+	}
+	return out, nil
+}
+
+// resolveAgentsRef adds the agents of the libraries doc names in
+// agents_ref to its agents and removes agents_ref.
+func resolveAgentsRef(doc map[string]any, libraries map[string]map[string]any) error {
+	ref, ok := doc["agents_ref"]
+	if !ok {
+		return nil
+	}
+	var names []string
+	switch ref := ref.(type) {
+	case string:
+		names = []string{ref}
+	case []any:
+		for _, name := range ref {
+			s, ok := name.(string)
+			if !ok {
+				return fmt.Errorf("workflow %q: agents_ref must name agents documents by id", doc["id"])
+			}
+			names = append(names, s)
+		}
+	default:
+		return fmt.Errorf("workflow %q: agents_ref must be a string or a list of strings", doc["id"])
+	}
+
+	agents, _ := doc["agents"].(map[string]any)
+	merged := make(map[string]any, len(agents))
+	for _, name := range names {
+		library, ok := libraries[name]
+		if !ok {
+			return fmt.Errorf("workflow %q: agents_ref %q names no agents document", doc["id"], name)
+		}
+		for agentID, def := range library {
+			merged[agentID] = def
+		}
+	}
+	for agentID, def := range agents {
+		merged[agentID] = def
+	}
+	doc["agents"] = merged
+	delete(doc, "agents_ref")
+	return nil
+}
+
+// LoadWorkflows loads every workflow a file defines, in file order, and
+// returns them compiled.
+func LoadWorkflows(path string) ([]*graph.GraphDefinition, error) {
+	docs, err := readDocuments(path)
+	if err != nil {
+		return nil, err
+	}
+	gds := make([]*graph.GraphDefinition, 0, len(docs))
+	for _, doc := range docs {
+		gd, _, err := LoadDocument(doc)
+		if err != nil {
+			return nil, err
+		}
+		if err := resolveWorkflowRefs(gd, path, nil); err != nil {
+			return nil, err
+		}
+		gds = append(gds, gd)
+	}
+	return gds, nil
+}
+
+// LoadWorkflowByID loads the workflow with the given id from a file that
+// may define several. An empty id requires the file to define exactly one.
+func LoadWorkflowByID(path, id string) (*graph.GraphDefinition, SchemaKind, error) {
+	return loadWorkflowFile(path, id, nil)
+}
+
+// loadWorkflowFile loads a workflow and the workflow files its subgraph
+// nodes refer to. loading holds the files being loaded above it.
+func loadWorkflowFile(path, id string, loading []string) (*graph.GraphDefinition, SchemaKind, error) {
+	docs, err := readDocuments(path)
+	if err != nil {
+		return nil, "", err
+	}
+	doc, err := SelectDocument(docs, id)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	gd, kind, err := LoadDocument(doc)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := resolveWorkflowRefs(gd, path, loading); err != nil {
+		return nil, "", err
+	}
+	return gd, kind, nil
+}
+
+// resolveWorkflowRefs sets the graph of every subgraph node that names a
+// workflow file in workflow_ref, relative to the file at path, so the
+// workflow runs without the referenced files.
+func resolveWorkflowRefs(gd *graph.GraphDefinition, path string, loading []string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	loading = append(loading, abs)
+	for i, node := range gd.Nodes {
+		ref, _ := node.Config["workflow_ref"].(string)
+		if node.Type != graph.SubgraphNodeType || ref == "" || node.Config["graph"] != nil {
+			continue
+		}
+		refPath := filepath.Clean(ref)
+		if !filepath.IsAbs(refPath) {
+			refPath = filepath.Join(filepath.Dir(abs), ref)
+		}
+		if slices.Contains(loading, refPath) {
+			return fmt.Errorf("%s: node %q: workflow_ref %q includes a workflow that includes it", path, node.ID, ref)
+		}
+		sub, _, err := loadWorkflowFile(refPath, "", loading)
+		if err != nil {
+			return fmt.Errorf("%s: node %q: workflow_ref %q: %w", path, node.ID, ref, err)
+		}
+		data, err := json.Marshal(sub)
+		if err != nil {
+			return err
+		}
+		var value map[string]any
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		config := maps.Clone(node.Config)
+		config["graph"] = value
+		gd.Nodes[i].Config = config
+	}
+	return nil
+}
+
+// SelectDocument picks the workflow with the given id from a file's
+// documents. An empty id requires there to be exactly one.
+func SelectDocument(docs [][]byte, id string) ([]byte, error) {
+	if id == "" && len(docs) == 1 {
+		return docs[0], nil
+	}
+	ids := DocumentIDs(docs)
+	if id == "" {
+		return nil, fmt.Errorf("file defines %d workflows (%s); choose one by id", len(docs), strings.Join(ids, ", "))
+	}
+	for i, docID := range ids {
+		if docID == id {
+			return docs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("file defines no workflow %q (has %s)", id, strings.Join(ids, ", "))
+}
+
+// DocumentIDs returns the id of each document.
+func DocumentIDs(docs [][]byte) []string {
+	ids := make([]string, len(docs))
+	for i, doc := range docs {
+		var head struct {
+			ID string `json:"id"`
+		}
+		_ = json.Unmarshal(doc, &head)
+		ids[i] = head.ID
+	}
+	return ids
+}
+
+// LoadDocument detects the schema kind of one canonical JSON document,
+// validates it and returns the compiled GraphDefinition.
+func LoadDocument(doc []byte) (*graph.GraphDefinition, SchemaKind, error) {
+	kind, err := DetectSchema(doc, "")
+	if err != nil {
+		return nil, "", err
+	}
+	switch kind {
+	case SchemaKindAgent:
+		gd, err := loadAgentWorkflow(doc, "")
+		return gd, SchemaKindAgent, err
+	case SchemaKindGraph:
+		gd, err := loadGraphDefinition(doc, "")
+		return gd, SchemaKindGraph, err
+	default:
+		return nil, "", fmt.Errorf("unknown schema kind %q", kind)
+	}
+}
+
+func readDocuments(path string) ([][]byte, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- path from caller
+	if err != nil {
+		return nil, fmt.Errorf("reading file %s: %w", path, err)
+	}
+	return SplitDocuments(data, FormatForPath(data, path))
+}
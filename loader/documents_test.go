@@ -0,0 +1,150 @@
+package loader
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const multiDocAgents = `kind: agents
+id: shared
+agents:
+  researcher: &researcher
+    role: Researcher
+    goal: Find information
+    provider: anthropic
+    model: claude-sonnet-4-6
+---
+version: "1.0"
+kind: agent_workflow
+id: research
+name: Research
+agents_ref: shared
+tasks:
+  research:
+    description: Research the topic
+    agent: researcher
+    expected_output: Summary
+execution:
+  strategy: sequential
+  task_order: [research]
+---
+version: "1.0"
+kind: agent_workflow
+id: review
+name: Review
+agents:
+  reviewer:
+    <<: *researcher
+    role: Reviewer
+tasks:
+  review:
+    description: Review the topic
+    agent: reviewer
+    expected_output: Verdict
+execution:
+  strategy: sequential
+  task_order: [review]
+`
+
+func TestSplitDocuments_AgentsRefAndAnchors(t *testing.T) {
+	docs, err := SplitDocuments([]byte(multiDocAgents), FormatYAML)
+	if err != nil {
+		t.Fatalf("SplitDocuments() error = %v", err)
+	}
+	if ids := DocumentIDs(docs); strings.Join(ids, ",") != "research,review" {
+		t.Fatalf("ids = %v", ids)
+	}
+
+	var research, review struct {
+		AgentsRef any                       `json:"agents_ref"`
+		Agents    map[string]map[string]any `json:"agents"`
+	}
+	_ = json.Unmarshal(docs[0], &research)
+	_ = json.Unmarshal(docs[1], &review)
+	if research.AgentsRef != nil || research.Agents["researcher"]["role"] != "Researcher" {
+		t.Errorf("research = %s", docs[0])
+	}
+	if review.Agents["reviewer"]["role"] != "Reviewer" || review.Agents["reviewer"]["model"] != "claude-sonnet-4-6" {
+		t.Errorf("review = %s", docs[1])
+	}
+}
+
+func TestSplitDocuments_SingleDocumentUnchanged(t *testing.T) {
+	data := []byte(`{"id": "g",  "nodes": []}`)
+	docs, err := SplitDocuments(data, FormatJSON)
+	if err != nil || len(docs) != 1 || string(docs[0]) != string(data) {
+		t.Fatalf("SplitDocuments() = %s, %v", docs, err)
+	}
+}
+
+func TestSplitDocuments_Errors(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown ref":   "id: a\nagents_ref: missing\n",
+		"ref not names": "id: a\nagents_ref: [1]\n",
+		"duplicate id":  "id: a\n---\nid: a\n",
+		"library only":  "kind: agents\nid: shared\nagents: {}\n",
+		"library no id": "kind: agents\nagents: {}\n---\nid: a\n",
+		"not a mapping": "id: a\n---\n- b\n",
+	} {
+		if _, err := SplitDocuments([]byte(data), FormatYAML); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadWorkflowByID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "team.yaml")
+	if err := os.WriteFile(path, []byte(multiDocAgents), 0600); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+
+	gds, err := LoadWorkflows(path)
+	if err != nil || len(gds) != 2 {
+		t.Fatalf("LoadWorkflows() = %d, %v", len(gds), err)
+	}
+
+	gd, kind, err := LoadWorkflowByID(path, "review")
+	if err != nil || kind != SchemaKindAgent || gd.ID != "review" {
+		t.Fatalf("LoadWorkflowByID(review) = %v, %s, %v", gd, kind, err)
+	}
+	if _, _, err := LoadWorkflow(path); err == nil || !strings.Contains(err.Error(), "research, review") {
+		t.Errorf("LoadWorkflow() error = %v, want the workflow ids", err)
+	}
+	if _, _, err := LoadWorkflowByID(path, "missing"); err == nil {
+		t.Error("expected error for unknown workflow id")
+	}
+}
+
+func TestLoadWorkflow_ResolvesWorkflowRefs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatalf("write temp file: %v", err)
+		}
+		return path
+	}
+	write("enrich.json", `{"id": "enrich", "version": "1.0", "nodes": [{"id": "x", "type": "noop"}], "edges": [], "entry": "x"}`)
+	path := write("main.json", `{"id": "main", "version": "1.0", "nodes": [
+		{"id": "sub", "type": "subgraph", "config": {"workflow_ref": "enrich.json"}}
+	], "edges": [], "entry": "sub"}`)
+
+	gd, _, err := LoadWorkflow(path)
+	if err != nil {
+		t.Fatalf("LoadWorkflow() error = %v", err)
+	}
+	nested, _ := gd.Nodes[0].Config["graph"].(map[string]any)
+	if nested["id"] != "enrich" {
+		t.Errorf("config.graph = %v, want the enrich workflow", gd.Nodes[0].Config["graph"])
+	}
+
+	write("loop.json", `{"id": "loop", "version": "1.0", "nodes": [
+		{"id": "again", "type": "subgraph", "config": {"workflow_ref": "loop.json"}}
+	], "edges": [], "entry": "again"}`)
+	if _, _, err := LoadWorkflow(filepath.Join(dir, "loop.json")); err == nil || !strings.Contains(err.Error(), "includes it") {
+		t.Errorf("LoadWorkflow(loop) error = %v, want a cycle error", err)
+	}
+}
@@ -0,0 +1,101 @@
+package loader
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SourceFormat identifies how a workflow definition is serialized.
+type SourceFormat string
+
+const (
+	FormatJSON SourceFormat = "json"
+	FormatYAML SourceFormat = "yaml"
+)
+
+// ParseSourceFormat normalizes a format name. "yml" is accepted as YAML.
+func ParseSourceFormat(name string) (SourceFormat, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q (use json or yaml)", name)
+	}
+}
+
+// SniffFormat guesses the format of a definition from its content: JSON
+// documents start with '{' or '[', anything else is treated as YAML.
+func SniffFormat(data []byte) SourceFormat {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return FormatJSON
+	}
+	return FormatYAML
+}
+
+// FormatForPath picks the format from the file extension, sniffing the
+// content when the extension is neither .json nor .yaml/.yml.
+func FormatForPath(data []byte, path string) SourceFormat {
+	if isYAML(path) {
+		return FormatYAML
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return FormatJSON
+	}
+	return SniffFormat(data)
+}
+
+// CanonicalJSON converts a definition in the given format to JSON, the
+// representation every schema is decoded and stored from.
+func CanonicalJSON(data []byte, format SourceFormat) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return yamlToJSON(data)
+	case FormatJSON:
+		var v any
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("parsing JSON: %w", err)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// ToYAML renders a JSON definition as block-style YAML, keeping the key
+// order of the JSON document.
+func ToYAML(jsonData []byte) ([]byte, error) {
+	var node yaml.Node
+	if err := yaml.Unmarshal(jsonData, &node); err != nil {
+		return nil, fmt.Errorf("parsing JSON: %w", err)
+	}
+	clearYAMLStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, fmt.Errorf("encoding YAML: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("encoding YAML: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// clearYAMLStyle drops the flow and quoting styles a JSON document decodes
+// with, so the encoder picks block style and only quotes strings that
+// would otherwise read back as another type.
+func clearYAMLStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearYAMLStyle(child)
+	}
+}
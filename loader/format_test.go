@@ -0,0 +1,88 @@
+package loader
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSniffFormat(t *testing.T) {
+	tests := map[string]SourceFormat{
+		`{"id": "x"}`:      FormatJSON,
+		"\n  [1, 2]":       FormatJSON,
+		"id: x\nnodes: []": FormatYAML,
+		"":                 FormatYAML,
+	}
+	for input, want := range tests {
+		if got := SniffFormat([]byte(input)); got != want {
+			t.Errorf("SniffFormat(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestParseSourceFormat(t *testing.T) {
+	if f, err := ParseSourceFormat("YML"); err != nil || f != FormatYAML {
+		t.Fatalf("ParseSourceFormat(YML) = %q, %v", f, err)
+	}
+	if _, err := ParseSourceFormat("hcl"); err == nil {
+		t.Fatal("expected error for unsupported format")
+	}
+}
+
+func TestCanonicalJSON(t *testing.T) {
+	out, err := CanonicalJSON([]byte("id: x\nversion: \"1.0\"\n"), FormatYAML)
+	if err != nil {
+		t.Fatalf("CanonicalJSON: %v", err)
+	}
+	if string(out) != `{"id":"x","version":"1.0"}` {
+		t.Fatalf("CanonicalJSON = %s", out)
+	}
+	if _, err := CanonicalJSON([]byte(`{"id":`), FormatJSON); err == nil {
+		t.Fatal("expected error for invalid JSON")
+	}
+}
+
+func TestToYAML_KeepsOrderAndTypes(t *testing.T) {
+	out, err := ToYAML([]byte(`{"id":"g","version":"1.0","flag":"yes","count":3,"nodes":[{"id":"a","type":"noop"}]}`))
+	if err != nil {
+		t.Fatalf("ToYAML: %v", err)
+	}
+	want := `id: g
+version: "1.0"
+flag: yes
+count: 3
+nodes:
+  - id: a
+    type: noop
+`
+	if string(out) != want {
+		t.Fatalf("ToYAML =\n%s\nwant\n%s", out, want)
+	}
+
+	back, err := CanonicalJSON(out, FormatYAML)
+	if err != nil {
+		t.Fatalf("round trip: %v", err)
+	}
+	if !strings.Contains(string(back), `"version":"1.0"`) || !strings.Contains(string(back), `"flag":"yes"`) {
+		t.Fatalf("round trip lost string types: %s", back)
+	}
+}
+
+func TestLoadWorkflow_SniffsYAMLWithoutExtension(t *testing.T) {
+	data, err := os.ReadFile(testdataPath("graph.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "workflow")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	gd, kind, err := LoadWorkflow(path)
+	if err != nil {
+		t.Fatalf("LoadWorkflow() error = %v", err)
+	}
+	if kind != SchemaKindGraph || gd.ID != "test_graph_yaml" {
+		t.Fatalf("kind = %q, id = %q", kind, gd.ID)
+	}
+}
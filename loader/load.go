@@ -12,27 +12,9 @@ import (
 
 // LoadWorkflow is the unified entry point that loads a workflow file,
 // auto-detects its schema kind, and returns the compiled GraphDefinition.
+// Files defining several workflows need LoadWorkflowByID.
 func LoadWorkflow(path string) (*graph.GraphDefinition, SchemaKind, error) {
-	data, err := os.ReadFile(path) // #nosec G304 -- path from caller
-	if err != nil {
-		return nil, "", fmt.Errorf("reading file %s: %w", path, err)
-	}
-
-	kind, err := DetectSchema(data, path)
-	if err != nil {
-		return nil, "", err
-	}
-
-	switch kind {
-	case SchemaKindAgent:
-		gd, err := loadAgentWorkflow(data, path)
-		return gd, SchemaKindAgent, err
-	case SchemaKindGraph:
-		gd, err := loadGraphDefinition(data, path)
-		return gd, SchemaKindGraph, err
-	default:
-		return nil, "", fmt.Errorf("unknown schema kind %q", kind)
-	}
+	return LoadWorkflowByID(path, "")
 }
 
 // LoadAgentWorkflow loads an Agent/Task workflow file, validates it,
@@ -103,9 +85,9 @@ func loadGraphDefinition(data []byte, path string) (*graph.GraphDefinition, erro
 }
 
 // toJSON converts data to JSON bytes, handling YAML conversion if the path
-// indicates a YAML file.
+// or content indicates a YAML file.
 func toJSON(data []byte, path string) ([]byte, error) {
-	if isYAML(path) {
+	if FormatForPath(data, path) == FormatYAML {
 		return yamlToJSON(data)
 	}
 	return data, nil
@@ -0,0 +1,247 @@
+// Package mask applies declarative data masking policies to workflow data
+// before it leaves the runtime. A Policy is a list of rules mapping field
+// patterns to a masking strategy; the same policy is applied to persisted
+// events, SSE payloads and outbound webhook bodies so sensitive values only
+// need to be configured once per workflow.
+package mask
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Strategy selects how a matched value is masked.
+type Strategy string
+
+const (
+	// StrategyHash replaces the value with a stable SHA-256 digest so equal
+	// values remain correlatable without exposing the original.
+	StrategyHash Strategy = "hash"
+	// StrategyPartial keeps the trailing characters of the value and replaces
+	// the rest with asterisks.
+	StrategyPartial Strategy = "partial"
+	// StrategyDrop removes the field entirely.
+	StrategyDrop Strategy = "drop"
+)
+
+// DefaultPartialKeep is the number of trailing characters kept by the
+// partial strategy when Rule.Keep is not set.
+const DefaultPartialKeep = 4
+
+// Rule maps a field pattern to a masking strategy.
+//
+// Field is a dotted path whose segments may use path.Match globs. Patterns
+// are matched against the trailing segments of a field's path, so "email"
+// masks any key named email at any depth while "customer.*.ssn" only masks
+// ssn keys two levels below a customer key.
+type Rule struct {
+	Field    string   `json:"field"`
+	Strategy Strategy `json:"strategy"`
+	Keep     int      `json:"keep,omitempty"`
+}
+
+// Policy is an ordered list of masking rules. The first matching rule wins.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Validate reports the first malformed rule in the policy.
+func (p *Policy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for i, rule := range p.Rules {
+		if strings.TrimSpace(rule.Field) == "" {
+			return fmt.Errorf("rules[%d].field is required", i)
+		}
+		for _, seg := range strings.Split(rule.Field, ".") {
+			if seg == "" {
+				return fmt.Errorf("rules[%d].field %q has an empty segment", i, rule.Field)
+			}
+			if _, err := path.Match(seg, ""); err != nil {
+				return fmt.Errorf("rules[%d].field %q: %w", i, rule.Field, err)
+			}
+		}
+		switch rule.Strategy {
+		case StrategyHash, StrategyDrop:
+		case StrategyPartial:
+			if rule.Keep < 0 {
+				return fmt.Errorf("rules[%d].keep must be >= 0", i)
+			}
+		default:
+			return fmt.Errorf("rules[%d].strategy %q must be one of: hash, partial, drop", i, rule.Strategy)
+		}
+	}
+	return nil
+}
+
+// Empty reports whether the policy has no rules.
+func (p *Policy) Empty() bool {
+	return p == nil || len(p.Rules) == 0
+}
+
+// Apply returns a masked deep copy of v. Maps and slices are copied; the
+// input is never modified. A nil or empty policy returns v unchanged.
+func (p *Policy) Apply(v any) any {
+	if p.Empty() {
+		return v
+	}
+	return p.apply(nil, v)
+}
+
+// ApplyMap is Apply specialised for the map payloads carried by events and
+// webhook bodies.
+func (p *Policy) ApplyMap(m map[string]any) map[string]any {
+	if p.Empty() || m == nil {
+		return m
+	}
+	return p.applyMap(nil, m)
+}
+
+// Matches reports whether the policy masks any field of v, that is,
+// whether Apply would change it.
+func (p *Policy) Matches(v any) bool {
+	if p.Empty() {
+		return false
+	}
+	return p.matches(nil, v)
+}
+
+func (p *Policy) matches(fieldPath []string, v any) bool {
+	switch val := v.(type) {
+	case map[string]any:
+		for key, value := range val {
+			childPath := append(fieldPath[:len(fieldPath):len(fieldPath)], key)
+			if _, ok := p.match(childPath); ok || p.matches(childPath, value) {
+				return true
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if p.matches(fieldPath, item) {
+				return true
+			}
+		}
+	case []map[string]any:
+		for _, item := range val {
+			if p.matches(fieldPath, item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (p *Policy) apply(fieldPath []string, v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		return p.applyMap(fieldPath, val)
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			// Slice elements share their parent's path so "items.email"
+			// reaches into every element of an items array.
+			out[i] = p.apply(fieldPath, item)
+		}
+		return out
+	case []map[string]any:
+		out := make([]map[string]any, len(val))
+		for i, item := range val {
+			out[i] = p.applyMap(fieldPath, item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func (p *Policy) applyMap(fieldPath []string, m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for key, value := range m {
+		childPath := append(fieldPath[:len(fieldPath):len(fieldPath)], key)
+		rule, ok := p.match(childPath)
+		if !ok {
+			out[key] = p.apply(childPath, value)
+			continue
+		}
+		if rule.Strategy == StrategyDrop {
+			continue
+		}
+		out[key] = maskValue(rule, value)
+	}
+	return out
+}
+
+func (p *Policy) match(fieldPath []string) (Rule, bool) {
+	for _, rule := range p.Rules {
+		if matchSuffix(strings.Split(rule.Field, "."), fieldPath) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+func matchSuffix(pattern, fieldPath []string) bool {
+	if len(pattern) > len(fieldPath) {
+		return false
+	}
+	offset := len(fieldPath) - len(pattern)
+	for i, seg := range pattern {
+		ok, err := path.Match(seg, fieldPath[offset+i])
+		if err != nil || !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func maskValue(rule Rule, v any) any {
+	if v == nil {
+		return nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		s = fmt.Sprint(v)
+	}
+	switch rule.Strategy {
+	case StrategyHash:
+		sum := sha256.Sum256([]byte(s))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	case StrategyPartial:
+		keep := rule.Keep
+		if keep == 0 {
+			keep = DefaultPartialKeep
+		}
+		runes := []rune(s)
+		if keep >= len(runes) {
+			return strings.Repeat("*", len(runes))
+		}
+		return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+	default:
+		return nil
+	}
+}
+
+type policyContextKey struct{}
+
+// ContextWithPolicy returns a context carrying the given policy so nodes
+// that send data outside the runtime can mask it.
+func ContextWithPolicy(ctx context.Context, p *Policy) context.Context {
+	if p.Empty() {
+		return ctx
+	}
+	return context.WithValue(ctx, policyContextKey{}, p)
+}
+
+// PolicyFromContext returns the policy attached to ctx, or nil.
+func PolicyFromContext(ctx context.Context) *Policy {
+	if ctx == nil {
+		return nil
+	}
+	p, _ := ctx.Value(policyContextKey{}).(*Policy)
+	return p
+}
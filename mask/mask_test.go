@@ -0,0 +1,142 @@
+package mask
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPolicyApply_Strategies(t *testing.T) {
+	p := &Policy{Rules: []Rule{
+		{Field: "password", Strategy: StrategyDrop},
+		{Field: "card", Strategy: StrategyPartial},
+		{Field: "email", Strategy: StrategyHash},
+	}}
+
+	in := map[string]any{
+		"user": map[string]any{
+			"email":    "a@example.com",
+			"password": "hunter2",
+			"name":     "Ada",
+		},
+		"card": "4111111111111111",
+	}
+	out := p.ApplyMap(in)
+
+	user := out["user"].(map[string]any)
+	if _, ok := user["password"]; ok {
+		t.Fatal("password should be dropped")
+	}
+	if user["name"] != "Ada" {
+		t.Fatalf("name = %v, want unchanged", user["name"])
+	}
+	email, _ := user["email"].(string)
+	if !strings.HasPrefix(email, "sha256:") || strings.Contains(email, "example.com") {
+		t.Fatalf("email = %q, want sha256 digest", email)
+	}
+	if out["card"] != "************1111" {
+		t.Fatalf("card = %v", out["card"])
+	}
+
+	// The input must be left untouched.
+	if in["user"].(map[string]any)["password"] != "hunter2" {
+		t.Fatal("input was mutated")
+	}
+}
+
+func TestPolicyApply_HashIsStable(t *testing.T) {
+	p := &Policy{Rules: []Rule{{Field: "id", Strategy: StrategyHash}}}
+	a := p.ApplyMap(map[string]any{"id": "abc"})["id"]
+	b := p.ApplyMap(map[string]any{"id": "abc"})["id"]
+	if a != b {
+		t.Fatalf("hash not stable: %v != %v", a, b)
+	}
+}
+
+func TestPolicyApply_PathPatterns(t *testing.T) {
+	p := &Policy{Rules: []Rule{{Field: "customer.*.ssn", Strategy: StrategyDrop}}}
+	out := p.ApplyMap(map[string]any{
+		"customer": map[string]any{
+			"primary": map[string]any{"ssn": "123", "zip": "94110"},
+		},
+		"ssn": "kept",
+	})
+
+	primary := out["customer"].(map[string]any)["primary"].(map[string]any)
+	if _, ok := primary["ssn"]; ok {
+		t.Fatal("customer.primary.ssn should be dropped")
+	}
+	if primary["zip"] != "94110" {
+		t.Fatalf("zip = %v", primary["zip"])
+	}
+	if out["ssn"] != "kept" {
+		t.Fatalf("top-level ssn = %v, want kept", out["ssn"])
+	}
+}
+
+func TestPolicyApply_Slices(t *testing.T) {
+	p := &Policy{Rules: []Rule{{Field: "items.token", Strategy: StrategyPartial, Keep: 2}}}
+	out := p.ApplyMap(map[string]any{
+		"items": []any{
+			map[string]any{"token": "abcdef"},
+			map[string]any{"token": "xy"},
+		},
+	})
+	items := out["items"].([]any)
+	if got := items[0].(map[string]any)["token"]; got != "****ef" {
+		t.Fatalf("items[0].token = %v", got)
+	}
+	if got := items[1].(map[string]any)["token"]; got != "**" {
+		t.Fatalf("items[1].token = %v", got)
+	}
+}
+
+func TestPolicyMatches(t *testing.T) {
+	p := &Policy{Rules: []Rule{{Field: "customer.*.ssn", Strategy: StrategyDrop}}}
+	if p.Matches(map[string]any{"ssn": "123", "customer": map[string]any{"zip": "94110"}}) {
+		t.Fatal("policy should not match a top-level ssn")
+	}
+	if !p.Matches(map[string]any{"customer": []any{map[string]any{"primary": map[string]any{"ssn": "123"}}}}) {
+		t.Fatal("policy should match customer.primary.ssn inside a slice")
+	}
+	if (*Policy)(nil).Matches(map[string]any{"ssn": "123"}) {
+		t.Fatal("nil policy should match nothing")
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		wantErr bool
+	}{
+		{name: "valid", rule: Rule{Field: "a.b", Strategy: StrategyHash}},
+		{name: "empty field", rule: Rule{Strategy: StrategyHash}, wantErr: true},
+		{name: "empty segment", rule: Rule{Field: "a..b", Strategy: StrategyDrop}, wantErr: true},
+		{name: "bad glob", rule: Rule{Field: "a[", Strategy: StrategyDrop}, wantErr: true},
+		{name: "unknown strategy", rule: Rule{Field: "a", Strategy: "redact"}, wantErr: true},
+		{name: "negative keep", rule: Rule{Field: "a", Strategy: StrategyPartial, Keep: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := (&Policy{Rules: []Rule{tt.rule}}).Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPolicyContext(t *testing.T) {
+	if PolicyFromContext(context.Background()) != nil {
+		t.Fatal("expected nil policy")
+	}
+	p := &Policy{Rules: []Rule{{Field: "x", Strategy: StrategyDrop}}}
+	ctx := ContextWithPolicy(context.Background(), p)
+	if PolicyFromContext(ctx) != p {
+		t.Fatal("policy not found in context")
+	}
+	if ContextWithPolicy(context.Background(), &Policy{}) != context.Background() {
+		t.Fatal("empty policy should not be attached")
+	}
+}
@@ -0,0 +1,203 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// AssertAction is what an AssertNode does when an assertion is not met.
+type AssertAction string
+
+const (
+	// AssertActionFail fails the node.
+	AssertActionFail AssertAction = "fail"
+
+	// AssertActionWarn emits a node.assertion_failed event and continues.
+	AssertActionWarn AssertAction = "warn"
+
+	// AssertActionRoute sends the output to ErrorTarget only.
+	AssertActionRoute AssertAction = "route"
+)
+
+// Assertion is a condition on a node's outputs.
+type Assertion struct {
+	// Expr is evaluated with "output" (the vars the node set or changed),
+	// "vars" (every var after the node ran) and "input" (the envelope
+	// input). Values are seen as they serialize to JSON, so struct fields
+	// use their JSON names.
+	Expr string
+
+	// Action defaults to AssertActionFail.
+	Action AssertAction
+
+	// Message replaces Expr in errors and events.
+	Message string
+}
+
+// AssertNodeConfig configures an AssertNode.
+type AssertNodeConfig struct {
+	// Node is the node whose outputs are checked.
+	Node core.Node
+
+	// Assertions are checked in order after Node runs.
+	Assertions []Assertion
+
+	// ErrorTarget is the successor that route assertions send failing
+	// output to. Passing output goes to Targets.
+	ErrorTarget string
+
+	// Targets are the node's other successors.
+	Targets []string
+}
+
+type compiledAssertion struct {
+	Assertion
+	parsed expr.Expr
+}
+
+// AssertNode runs another node and checks its outputs against assertions,
+// so a broken data contract fails at the node that broke it. It takes the
+// wrapped node's ID and kind.
+type AssertNode struct {
+	core.BaseNode
+	config     AssertNodeConfig
+	assertions []compiledAssertion
+}
+
+var _ core.RouterNode = (*AssertNode)(nil)
+
+// NewAssertNode creates an AssertNode. It fails on invalid expressions and
+// on route assertions without an ErrorTarget.
+func NewAssertNode(config AssertNodeConfig) (*AssertNode, error) {
+	if config.Node == nil {
+		return nil, fmt.Errorf("assert: node is required")
+	}
+	n := &AssertNode{
+		BaseNode: core.NewBaseNode(config.Node.ID(), config.Node.Kind()),
+		config:   config,
+	}
+	for i, a := range config.Assertions {
+		parsed, err := expr.Parse(a.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("assert[%d]: invalid expression %q: %w", i, a.Expr, err)
+		}
+		switch a.Action {
+		case "":
+			a.Action = AssertActionFail
+		case AssertActionFail, AssertActionWarn:
+		case AssertActionRoute:
+			if config.ErrorTarget == "" {
+				return nil, fmt.Errorf("assert[%d]: route requires an edge from the error port", i)
+			}
+		default:
+			return nil, fmt.Errorf("assert[%d]: unknown action %q", i, a.Action)
+		}
+		n.assertions = append(n.assertions, compiledAssertion{Assertion: a, parsed: parsed})
+	}
+	return n, nil
+}
+
+// Config returns the node's configuration.
+func (n *AssertNode) Config() AssertNodeConfig {
+	return n.config
+}
+
+// Run runs the wrapped node and checks its outputs. A failed fail
+// assertion returns an error. Failed warn and route assertions emit a
+// node.assertion_failed event; a failed route assertion then routes to
+// ErrorTarget.
+func (n *AssertNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	before := make(map[string]any, len(env.Vars))
+	for k, v := range env.Vars {
+		before[k] = v
+	}
+
+	result, err := n.config.Node.Run(ctx, env)
+	if err != nil || result == nil {
+		return result, err
+	}
+
+	output := make(map[string]any)
+	for k, v := range result.Vars {
+		if old, ok := before[k]; !ok || !reflect.DeepEqual(old, v) {
+			output[k] = v
+		}
+	}
+	vars := map[string]any{
+		"output": jsonValue(output),
+		"vars":   jsonValue(result.Vars),
+		"input":  jsonValue(result.Input),
+	}
+
+	emit := runtime.EmitterFromContext(ctx)
+	routed := false
+	for _, a := range n.assertions {
+		value, err := expr.Eval(a.parsed, vars)
+		if err == nil && expr.IsTruthy(value) {
+			continue
+		}
+		message := a.Message
+		if message == "" {
+			message = a.Expr
+		}
+		if err != nil {
+			message = fmt.Sprintf("%s (%v)", message, err)
+		}
+		if a.Action == AssertActionFail {
+			return nil, fmt.Errorf("assertion failed: %s", message)
+		}
+		emit(runtime.NewEvent(runtime.EventNodeAssertionFailed, env.Trace.RunID).
+			WithNode(n.ID(), n.Kind()).
+			WithPayload("expr", a.Expr).
+			WithPayload("message", message).
+			WithPayload("action", string(a.Action)))
+		if a.Action == AssertActionRoute && !routed {
+			routed = true
+			result.SetVar(n.ID()+"_decision", core.RouteDecision{
+				Targets: []string{n.config.ErrorTarget},
+				Reason:  "assertion failed: " + message,
+			})
+		}
+	}
+
+	if !routed && n.config.ErrorTarget != "" {
+		if _, isRouter := n.config.Node.(core.RouterNode); !isRouter {
+			result.SetVar(n.ID()+"_decision", core.RouteDecision{
+				Targets: n.config.Targets,
+				Reason:  "assertions passed",
+			})
+		}
+	}
+	return result, nil
+}
+
+// Route returns the decision Run stored, so the runtime follows the error
+// port only when a route assertion failed.
+func (n *AssertNode) Route(_ context.Context, env *core.Envelope) (core.RouteDecision, error) {
+	if v, ok := env.GetVar(n.ID() + "_decision"); ok {
+		if decision, ok := v.(core.RouteDecision); ok {
+			return decision, nil
+		}
+	}
+	return core.RouteDecision{Targets: n.config.Targets}, nil
+}
+
+// jsonValue returns v as it decodes from JSON, or v itself if it does not
+// encode.
+func jsonValue(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package nodes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+type retrievedDoc struct {
+	ID    string  `json:"id"`
+	Score float64 `json:"score"`
+}
+
+func retrieveNode(docs []retrievedDoc) core.Node {
+	return core.NewFuncNode("retrieve", func(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+		out := env.Clone()
+		out.SetVar("documents", docs)
+		return out, nil
+	})
+}
+
+func TestAssertNode_Actions(t *testing.T) {
+	docs := []retrievedDoc{{ID: "a", Score: 0.9}}
+	tests := []struct {
+		name      string
+		docs      []retrievedDoc
+		assertion Assertion
+		wantErr   string
+		wantEvent bool
+		wantRoute []string
+	}{
+		{name: "pass", docs: docs, assertion: Assertion{Expr: "output.documents.length > 0 && output.documents[0].score >= 0.5"}, wantRoute: []string{"answer"}},
+		{name: "fail", assertion: Assertion{Expr: "output.documents.length > 0"}, wantErr: "assertion failed: output.documents.length > 0"},
+		{name: "fail with message", assertion: Assertion{Expr: "output.documents.length > 0", Message: "no documents"}, wantErr: "assertion failed: no documents"},
+		{name: "warn", assertion: Assertion{Expr: "output.documents.length > 0", Action: AssertActionWarn}, wantEvent: true, wantRoute: []string{"answer"}},
+		{name: "route", assertion: Assertion{Expr: "output.documents.length > 0", Action: AssertActionRoute}, wantEvent: true, wantRoute: []string{"no_results"}},
+		{name: "unchanged vars are not output", docs: docs, assertion: Assertion{Expr: "output.query == null && vars.query == \"q\""}, wantRoute: []string{"answer"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := NewAssertNode(AssertNodeConfig{
+				Node:        retrieveNode(tt.docs),
+				Assertions:  []Assertion{tt.assertion},
+				ErrorTarget: "no_results",
+				Targets:     []string{"answer"},
+			})
+			if err != nil {
+				t.Fatalf("NewAssertNode: %v", err)
+			}
+			var events []runtime.Event
+			ctx := runtime.ContextWithEmitter(context.Background(), func(e runtime.Event) { events = append(events, e) })
+			env := core.NewEnvelope()
+			env.SetVar("query", "q")
+
+			result, err := node.Run(ctx, env)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			if got := len(events) == 1 && events[0].Kind == runtime.EventNodeAssertionFailed; got != tt.wantEvent {
+				t.Errorf("events = %+v, want assertion event %v", events, tt.wantEvent)
+			}
+			decision, err := node.Route(ctx, result)
+			if err != nil || strings.Join(decision.Targets, ",") != strings.Join(tt.wantRoute, ",") {
+				t.Errorf("route = %v (%v), want %v", decision.Targets, err, tt.wantRoute)
+			}
+		})
+	}
+}
+
+func TestAssertNode_KeepsWrappedIdentity(t *testing.T) {
+	inner := core.NewFuncNode("retrieve", nil).WithKind(core.NodeKindTool)
+	node, err := NewAssertNode(AssertNodeConfig{Node: inner, Assertions: []Assertion{{Expr: "true"}}})
+	if err != nil {
+		t.Fatalf("NewAssertNode: %v", err)
+	}
+	if node.ID() != "retrieve" || node.Kind() != core.NodeKindTool {
+		t.Errorf("node = %s/%s, want retrieve/tool", node.ID(), node.Kind())
+	}
+
+	// Without an error edge, no decision is stored and the runtime follows
+	// every successor.
+	result, err := node.Run(context.Background(), core.NewEnvelope())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if _, ok := result.GetVar("retrieve_decision"); ok {
+		t.Error("unexpected routing decision")
+	}
+}
+
+func TestNewAssertNode_Errors(t *testing.T) {
+	inner := core.NewNoopNode("n")
+	for _, tt := range []struct {
+		assertion Assertion
+		want      string
+	}{
+		{Assertion{Expr: "output.x >"}, "invalid expression"},
+		{Assertion{Expr: "true", Action: "explode"}, "unknown action"},
+		{Assertion{Expr: "true", Action: AssertActionRoute}, "error port"},
+	} {
+		_, err := NewAssertNode(AssertNodeConfig{Node: inner, Assertions: []Assertion{tt.assertion}})
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: err = %v, want %q", tt.assertion, err, tt.want)
+		}
+	}
+}
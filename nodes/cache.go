@@ -1,7 +1,6 @@
 package nodes
 
 import (
-	"bytes"
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
@@ -10,7 +9,6 @@ import (
 	"sort"
 	"strings"
 	"sync"
-	"text/template"
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
@@ -45,6 +43,10 @@ type CacheNodeConfig struct {
 	// If empty, the key is computed from InputVars.
 	CacheKey string
 
+	// TemplateSandbox, when set, restricts what the CacheKey template may
+	// call and how much it may render.
+	TemplateSandbox *TemplateSandbox
+
 	// InputVars lists variables to include in the cache key hash.
 	// If empty and CacheKey is empty, uses all Vars.
 	InputVars []string
@@ -182,7 +184,7 @@ func (n *CacheNode) generateCacheKey(env *core.Envelope) (string, error) {
 
 // renderCacheKeyTemplate renders the CacheKey template with envelope data.
 func (n *CacheNode) renderCacheKeyTemplate(env *core.Envelope) (string, error) {
-	tmpl, err := template.New("cachekey").Parse(n.config.CacheKey)
+	tmpl, err := parseNodeTemplate(n.config.TemplateSandbox, "cachekey", n.config.CacheKey, nil)
 	if err != nil {
 		return "", fmt.Errorf("invalid cache key template: %w", err)
 	}
@@ -192,13 +194,13 @@ func (n *CacheNode) renderCacheKeyTemplate(env *core.Envelope) (string, error) {
 	data["input"] = env.Input
 	data["vars"] = env.Vars
 
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
+	key, err := tmpl.render(data)
+	if err != nil {
 		return "", fmt.Errorf("failed to execute cache key template: %w", err)
 	}
 
 	// Prefix with node ID for uniqueness
-	return fmt.Sprintf("%s:%s", n.ID(), buf.String()), nil
+	return fmt.Sprintf("%s:%s", n.ID(), key), nil
 }
 
 // computeCacheKeyHash computes a deterministic hash from envelope data.
@@ -0,0 +1,377 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// RoundingMode is how a CalcNode rounds results to their scale.
+type RoundingMode string
+
+const (
+	// RoundHalfUp rounds halves away from zero: 2.345 → 2.35.
+	RoundHalfUp RoundingMode = "half_up"
+	// RoundHalfEven rounds halves to the even digit (banker's rounding):
+	// 2.345 → 2.34, 2.355 → 2.36.
+	RoundHalfEven RoundingMode = "half_even"
+	// RoundHalfDown rounds halves toward zero: 2.345 → 2.34.
+	RoundHalfDown RoundingMode = "half_down"
+	// RoundUp rounds away from zero: 2.341 → 2.35.
+	RoundUp RoundingMode = "up"
+	// RoundDown truncates toward zero: 2.349 → 2.34.
+	RoundDown RoundingMode = "down"
+	// RoundCeiling rounds toward positive infinity.
+	RoundCeiling RoundingMode = "ceiling"
+	// RoundFloor rounds toward negative infinity.
+	RoundFloor RoundingMode = "floor"
+)
+
+// Division by zero handling of a CalcNode.
+const (
+	DivideByZeroFail = "fail"
+	DivideByZeroNull = "null"
+	DivideByZeroZero = "zero"
+)
+
+// Result formats of a CalcNode.
+const (
+	CalcOutputString = "string"
+	CalcOutputNumber = "number"
+)
+
+// DefaultCalcScale is the scale of results without a currency or scale of
+// their own. Trailing zeros are trimmed from them.
+const DefaultCalcScale = 10
+
+// maxCalcScale bounds configured scales and round places.
+const maxCalcScale = 30
+
+// currencyScales lists the ISO 4217 currencies whose minor unit is not
+// hundredths.
+var currencyScales = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0,
+	"KRW": 0, "PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0,
+	"XOF": 0, "XPF": 0,
+}
+
+// CurrencyScale returns the number of decimal places of an ISO 4217
+// currency: 2 unless the currency is known to use another minor unit.
+func CurrencyScale(code string) int {
+	if scale, ok := currencyScales[strings.ToUpper(code)]; ok {
+		return scale
+	}
+	return 2
+}
+
+var calcKeyPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// CalcFormula is one calculation of a CalcNode.
+type CalcFormula struct {
+	// Key is the variable the result is stored in. Later formulas of the
+	// node can use it.
+	Key string `json:"key"`
+	// Expr is the arithmetic expression. See CalcNode.
+	Expr string `json:"expr"`
+	// Currency is the ISO 4217 code of the result. Its unit must match, and
+	// its minor unit is the default scale.
+	Currency string `json:"currency,omitempty"`
+	// Scale is the number of decimal places of the result. Defaults to the
+	// currency's minor unit, or the node's scale.
+	Scale *int `json:"scale,omitempty"`
+	// Rounding overrides the node's rounding mode.
+	Rounding RoundingMode `json:"rounding,omitempty"`
+}
+
+// CalcNodeConfig configures a CalcNode.
+type CalcNodeConfig struct {
+	Formulas []CalcFormula
+	// Rounding is the rounding mode of results and round(). Defaults to
+	// RoundHalfUp.
+	Rounding RoundingMode
+	// Scale is the default scale of results without a currency. Nil uses
+	// DefaultCalcScale and trims trailing zeros.
+	Scale *int
+	// OnDivideByZero is DivideByZeroFail (default), DivideByZeroNull or
+	// DivideByZeroZero: fail the node, or store null or zero as the
+	// formula's result.
+	OnDivideByZero string
+	// Output is CalcOutputString (default), which keeps every digit, or
+	// CalcOutputNumber.
+	Output string
+	// AuditKey stores the CalcAudit of every formula. Defaults to
+	// "{id}_audit".
+	AuditKey string
+}
+
+// CalcAudit records how a formula was computed.
+type CalcAudit struct {
+	Key  string `json:"key"`
+	Expr string `json:"expr"`
+	// Formula is Expr with the values it was computed from.
+	Formula string `json:"formula"`
+	// Exact is the unrounded result. Non-terminating decimals end in
+	// "...".
+	Exact    string       `json:"exact,omitempty"`
+	Result   any          `json:"result"`
+	Unit     string       `json:"unit,omitempty"`
+	Scale    int          `json:"scale"`
+	Rounding RoundingMode `json:"rounding"`
+	// Note explains a result the division guard replaced.
+	Note string `json:"note,omitempty"`
+}
+
+type compiledCalcFormula struct {
+	CalcFormula
+	expr calcExpr
+}
+
+// CalcNode evaluates arithmetic formulas over envelope variables with exact
+// decimal arithmetic instead of floating point, so 0.1 + 0.2 is 0.3 and
+// totals add up to the cent.
+//
+// Expressions combine numbers, variable paths such as order.items[0].price,
+// parentheses, + - * / % and the functions round(x, places), floor, ceil,
+// abs, min, max, pct(part, whole) and sum(list) or sum(list, "field").
+// Variables may be numbers, decimal strings, or {"amount", "currency"}
+// objects, whose currency is checked: amounts of different currencies do
+// not add, money does not multiply by money, and dividing an amount by one
+// of the same currency gives a plain ratio.
+type CalcNode struct {
+	core.BaseNode
+	config   CalcNodeConfig
+	formulas []compiledCalcFormula
+}
+
+// NewCalcNode creates a CalcNode. Formulas are parsed eagerly, so a syntax
+// error is reported at construction time.
+func NewCalcNode(id string, config CalcNodeConfig) (*CalcNode, error) {
+	if len(config.Formulas) == 0 {
+		return nil, fmt.Errorf("calc node %q: formulas are required", id)
+	}
+	if config.Rounding == "" {
+		config.Rounding = RoundHalfUp
+	}
+	if !validRoundingMode(config.Rounding) {
+		return nil, fmt.Errorf("calc node %q: unknown rounding mode %q", id, config.Rounding)
+	}
+	if config.Scale != nil && (*config.Scale < 0 || *config.Scale > maxCalcScale) {
+		return nil, fmt.Errorf("calc node %q: scale must be from 0 to %d", id, maxCalcScale)
+	}
+	switch config.OnDivideByZero {
+	case "":
+		config.OnDivideByZero = DivideByZeroFail
+	case DivideByZeroFail, DivideByZeroNull, DivideByZeroZero:
+	default:
+		return nil, fmt.Errorf("calc node %q: on_divide_by_zero must be fail, null or zero, got %q", id, config.OnDivideByZero)
+	}
+	switch config.Output {
+	case "":
+		config.Output = CalcOutputString
+	case CalcOutputString, CalcOutputNumber:
+	default:
+		return nil, fmt.Errorf("calc node %q: output must be string or number, got %q", id, config.Output)
+	}
+	if config.AuditKey == "" {
+		config.AuditKey = id + "_audit"
+	}
+
+	seen := make(map[string]bool, len(config.Formulas))
+	formulas := make([]compiledCalcFormula, 0, len(config.Formulas))
+	for i, f := range config.Formulas {
+		if !calcKeyPattern.MatchString(f.Key) {
+			return nil, fmt.Errorf("calc node %q: formulas[%d].key %q must be a variable name", id, i, f.Key)
+		}
+		if seen[f.Key] {
+			return nil, fmt.Errorf("calc node %q: duplicate formula key %q", id, f.Key)
+		}
+		seen[f.Key] = true
+		if f.Rounding != "" && !validRoundingMode(f.Rounding) {
+			return nil, fmt.Errorf("calc node %q: formula %q: unknown rounding mode %q", id, f.Key, f.Rounding)
+		}
+		if f.Scale != nil && (*f.Scale < 0 || *f.Scale > maxCalcScale) {
+			return nil, fmt.Errorf("calc node %q: formula %q: scale must be from 0 to %d", id, f.Key, maxCalcScale)
+		}
+		f.Currency = strings.ToUpper(f.Currency)
+		expr, err := parseCalcExpr(f.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("calc node %q: formula %q: %w", id, f.Key, err)
+		}
+		formulas = append(formulas, compiledCalcFormula{CalcFormula: f, expr: expr})
+	}
+
+	return &CalcNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindTransform),
+		config:   config,
+		formulas: formulas,
+	}, nil
+}
+
+// Config returns the node's configuration.
+func (n *CalcNode) Config() CalcNodeConfig {
+	return n.config
+}
+
+// Run evaluates the formulas in order and stores each result under its key
+// and the audit of all of them under AuditKey.
+func (n *CalcNode) Run(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+	out := env.Clone()
+	results := make(map[string]calcValue, len(n.formulas))
+	audits := make([]CalcAudit, 0, len(n.formulas))
+	for _, f := range n.formulas {
+		audit, value, err := n.evaluate(f, env, results)
+		if err != nil {
+			return nil, fmt.Errorf("calc node %s: formula %q: %w", n.ID(), f.Key, err)
+		}
+		if value != nil {
+			results[f.Key] = *value
+		}
+		out.SetVar(f.Key, audit.Result)
+		audits = append(audits, audit)
+	}
+	out.SetVar(n.config.AuditKey, audits)
+	return out, nil
+}
+
+// evaluate computes one formula. The value is nil when a division by zero
+// made the result null.
+func (n *CalcNode) evaluate(f compiledCalcFormula, env *core.Envelope, results map[string]calcValue) (CalcAudit, *calcValue, error) {
+	rounding := f.Rounding
+	if rounding == "" {
+		rounding = n.config.Rounding
+	}
+	audit := CalcAudit{Key: f.Key, Expr: f.Expr, Rounding: rounding}
+	c := &calcEval{rounding: rounding, lookup: func(name string) (any, bool) {
+		if v, ok := results[name]; ok {
+			if v.unit == "" {
+				return v.num, true
+			}
+			return map[string]any{"amount": v.num, "currency": v.unit}, true
+		}
+		return env.GetVar(name)
+	}}
+
+	value, formula, err := f.expr.eval(c)
+	audit.Formula = formula
+	if err == errCalcDivideByZero && n.config.OnDivideByZero != DivideByZeroFail {
+		audit.Note = "division by zero: result set to " + n.config.OnDivideByZero
+		if n.config.OnDivideByZero == DivideByZeroNull {
+			return audit, nil, nil
+		}
+		value, err = calcValue{num: new(big.Rat), unit: f.Currency}, nil
+	}
+	if err != nil {
+		return audit, nil, err
+	}
+
+	if f.Currency != "" {
+		if value.unit != "" && value.unit != f.Currency {
+			return audit, nil, fmt.Errorf("result is in %s, not %s", value.unit, f.Currency)
+		}
+		value.unit = f.Currency
+	}
+	audit.Unit = value.unit
+	audit.Exact = formatExactDecimal(value.num)
+
+	scale, trim := n.scale(f, value.unit)
+	audit.Scale = scale
+	value.num = roundRat(value.num, scale, rounding)
+	text := value.num.FloatString(scale)
+	if trim && strings.Contains(text, ".") {
+		text = strings.TrimRight(strings.TrimRight(text, "0"), ".")
+	}
+	audit.Result = text
+	if n.config.Output == CalcOutputNumber {
+		audit.Result, _ = strconv.ParseFloat(text, 64)
+	}
+	return audit, &value, nil
+}
+
+// scale returns a formula's scale, and whether trailing zeros are trimmed.
+func (n *CalcNode) scale(f compiledCalcFormula, unit string) (int, bool) {
+	switch {
+	case f.Scale != nil:
+		return *f.Scale, false
+	case unit != "":
+		return CurrencyScale(unit), false
+	case n.config.Scale != nil:
+		return *n.config.Scale, false
+	default:
+		return DefaultCalcScale, true
+	}
+}
+
+func validRoundingMode(mode RoundingMode) bool {
+	switch mode {
+	case RoundHalfUp, RoundHalfEven, RoundHalfDown, RoundUp, RoundDown, RoundCeiling, RoundFloor:
+		return true
+	}
+	return false
+}
+
+// roundRat rounds x to scale decimal places.
+func roundRat(x *big.Rat, scale int, mode RoundingMode) *big.Rat {
+	factor := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)
+	scaled := new(big.Int).Mul(x.Num(), factor)
+	quo, rem := new(big.Int).QuoRem(scaled, x.Denom(), new(big.Int))
+	if rem.Sign() != 0 {
+		negative := x.Sign() < 0
+		// Compare the discarded fraction with one half.
+		half := new(big.Int).Abs(rem)
+		half.Mul(half, big.NewInt(2))
+		cmp := half.Cmp(x.Denom())
+		away := false
+		switch mode {
+		case RoundUp:
+			away = true
+		case RoundDown:
+		case RoundCeiling:
+			away = !negative
+		case RoundFloor:
+			away = negative
+		case RoundHalfUp:
+			away = cmp >= 0
+		case RoundHalfDown:
+			away = cmp > 0
+		case RoundHalfEven:
+			away = cmp > 0 || (cmp == 0 && quo.Bit(0) == 1)
+		}
+		if away {
+			if negative {
+				quo.Sub(quo, big.NewInt(1))
+			} else {
+				quo.Add(quo, big.NewInt(1))
+			}
+		}
+	}
+	return new(big.Rat).SetFrac(quo, factor)
+}
+
+// formatExactDecimal prints x in full when it is a terminating decimal,
+// and to 20 places followed by "..." otherwise.
+func formatExactDecimal(x *big.Rat) string {
+	if x.IsInt() {
+		return x.Num().String()
+	}
+	den := new(big.Int).Set(x.Denom())
+	places := 0
+	for _, p := range []int64{2, 5} {
+		prime := big.NewInt(p)
+		n := 0
+		for new(big.Int).Mod(den, prime).Sign() == 0 {
+			den.Quo(den, prime)
+			n++
+		}
+		places = max(places, n)
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return x.FloatString(20) + "..."
+	}
+	return x.FloatString(places)
+}
@@ -0,0 +1,629 @@
+package nodes
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// calcExpr is a parsed calc expression.
+type calcExpr interface {
+	eval(c *calcEval) (calcValue, string, error)
+}
+
+// calcValue is an exact decimal with an optional unit, such as a currency
+// code.
+type calcValue struct {
+	num  *big.Rat
+	unit string
+}
+
+func (v calcValue) String() string {
+	s := formatExactDecimal(v.num)
+	if v.unit != "" {
+		s += " " + v.unit
+	}
+	return s
+}
+
+// errCalcDivideByZero is returned for divisions by zero, which
+// CalcNodeConfig.OnDivideByZero decides about.
+var errCalcDivideByZero = fmt.Errorf("division by zero")
+
+// calcFuncArity lists the calc functions with their minimum and maximum
+// argument counts; -1 is unbounded.
+var calcFuncArity = map[string][2]int{
+	"round": {1, 2},
+	"floor": {1, 1},
+	"ceil":  {1, 1},
+	"abs":   {1, 1},
+	"min":   {1, -1},
+	"max":   {1, -1},
+	"sum":   {1, 2},
+	"pct":   {2, 2},
+}
+
+type calcNumber struct {
+	value *big.Rat
+	text  string
+}
+
+type calcString struct{ value string }
+
+type calcVar struct{ path []calcPathPart }
+
+type calcPathPart struct {
+	name  string
+	index int // -1 for a name
+}
+
+type calcUnary struct{ operand calcExpr }
+
+type calcBinary struct {
+	op          byte
+	left, right calcExpr
+}
+
+type calcParen struct{ inner calcExpr }
+
+type calcCall struct {
+	name string
+	args []calcExpr
+}
+
+// calcParser is a recursive descent parser for calc expressions:
+//
+//	expr    = term { ("+" | "-") term }
+//	term    = unary { ("*" | "/" | "%") unary }
+//	unary   = "-" unary | primary
+//	primary = number | string | path | name "(" [expr { "," expr }] ")" | "(" expr ")"
+type calcParser struct {
+	src string
+	pos int
+}
+
+func parseCalcExpr(src string) (calcExpr, error) {
+	p := &calcParser{src: src}
+	e, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:p.pos+1])
+	}
+	return e, nil
+}
+
+func (p *calcParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("at offset %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *calcParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(rune(p.src[p.pos])) {
+		p.pos++
+	}
+}
+
+// peek skips whitespace and returns the next byte, or 0 at the end.
+func (p *calcParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *calcParser) expr() (calcExpr, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		left = calcBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *calcParser) term() (calcExpr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' && op != '%' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = calcBinary{op: op, left: left, right: right}
+	}
+}
+
+func (p *calcParser) unary() (calcExpr, error) {
+	if p.peek() == '-' {
+		p.pos++
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return calcUnary{operand: operand}, nil
+	}
+	return p.primary()
+}
+
+func (p *calcParser) primary() (calcExpr, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, p.errorf("unexpected end of expression")
+	case c == '(':
+		p.pos++
+		inner, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("missing )")
+		}
+		p.pos++
+		return calcParen{inner: inner}, nil
+	case c == '"' || c == '\'':
+		return p.stringLit(c)
+	case c == '.' || (c >= '0' && c <= '9'):
+		return p.number()
+	case c == '_' || unicode.IsLetter(rune(c)):
+		return p.pathOrCall()
+	default:
+		return nil, p.errorf("unexpected %q", string(c))
+	}
+}
+
+func (p *calcParser) number() (calcExpr, error) {
+	start := p.pos
+	for p.pos < len(p.src) && (p.src[p.pos] == '.' || (p.src[p.pos] >= '0' && p.src[p.pos] <= '9')) {
+		p.pos++
+	}
+	text := p.src[start:p.pos]
+	value, ok := new(big.Rat).SetString(text)
+	if !ok {
+		return nil, p.errorf("invalid number %q", text)
+	}
+	return calcNumber{value: value, text: text}, nil
+}
+
+func (p *calcParser) stringLit(quote byte) (calcExpr, error) {
+	p.pos++
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != quote {
+		p.pos++
+	}
+	if p.pos >= len(p.src) {
+		return nil, p.errorf("unterminated string")
+	}
+	value := p.src[start:p.pos]
+	p.pos++
+	return calcString{value: value}, nil
+}
+
+func (p *calcParser) name() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := rune(p.src[p.pos])
+		if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			break
+		}
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *calcParser) pathOrCall() (calcExpr, error) {
+	first := p.name()
+	if p.peek() == '(' {
+		arity, ok := calcFuncArity[first]
+		if !ok {
+			return nil, p.errorf("unknown function %q", first)
+		}
+		p.pos++
+		var args []calcExpr
+		if p.peek() != ')' {
+			for {
+				arg, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				args = append(args, arg)
+				if p.peek() != ',' {
+					break
+				}
+				p.pos++
+			}
+		}
+		if p.peek() != ')' {
+			return nil, p.errorf("missing ) after arguments of %s", first)
+		}
+		p.pos++
+		if len(args) < arity[0] || (arity[1] >= 0 && len(args) > arity[1]) {
+			return nil, p.errorf("%s takes %s arguments, got %d", first, calcArityText(arity), len(args))
+		}
+		return calcCall{name: first, args: args}, nil
+	}
+
+	path := []calcPathPart{{name: first, index: -1}}
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case '.':
+			p.pos++
+			name := p.name()
+			if name == "" {
+				return nil, p.errorf("missing field name after .")
+			}
+			path = append(path, calcPathPart{name: name, index: -1})
+		case '[':
+			p.pos++
+			start := p.pos
+			for p.pos < len(p.src) && p.src[p.pos] >= '0' && p.src[p.pos] <= '9' {
+				p.pos++
+			}
+			index, err := strconv.Atoi(p.src[start:p.pos])
+			if err != nil || p.pos >= len(p.src) || p.src[p.pos] != ']' {
+				return nil, p.errorf("invalid list index")
+			}
+			p.pos++
+			path = append(path, calcPathPart{index: index})
+		default:
+			return calcVar{path: path}, nil
+		}
+	}
+	return calcVar{path: path}, nil
+}
+
+func calcArityText(arity [2]int) string {
+	switch {
+	case arity[1] < 0:
+		return fmt.Sprintf("at least %d", arity[0])
+	case arity[0] == arity[1]:
+		return strconv.Itoa(arity[0])
+	default:
+		return fmt.Sprintf("%d to %d", arity[0], arity[1])
+	}
+}
+
+func (v calcVar) String() string {
+	var b strings.Builder
+	for i, part := range v.path {
+		switch {
+		case part.index >= 0:
+			fmt.Fprintf(&b, "[%d]", part.index)
+		case i > 0:
+			b.WriteString("." + part.name)
+		default:
+			b.WriteString(part.name)
+		}
+	}
+	return b.String()
+}
+
+// calcEval evaluates expressions of one formula.
+type calcEval struct {
+	// lookup resolves the first segment of a variable path.
+	lookup   func(name string) (any, bool)
+	rounding RoundingMode
+}
+
+func (n calcNumber) eval(*calcEval) (calcValue, string, error) {
+	return calcValue{num: n.value}, n.text, nil
+}
+
+func (s calcString) eval(*calcEval) (calcValue, string, error) {
+	return calcValue{}, "", fmt.Errorf("string %q is only allowed as the field argument of sum", s.value)
+}
+
+func (v calcVar) resolve(c *calcEval) (any, error) {
+	current, ok := c.lookup(v.path[0].name)
+	if !ok {
+		return nil, fmt.Errorf("variable %q not found", v)
+	}
+	for _, part := range v.path[1:] {
+		if part.index >= 0 {
+			list, ok := current.([]any)
+			if !ok || part.index >= len(list) {
+				return nil, fmt.Errorf("variable %q not found", v)
+			}
+			current = list[part.index]
+			continue
+		}
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("variable %q not found", v)
+		}
+		if current, ok = m[part.name]; !ok {
+			return nil, fmt.Errorf("variable %q not found", v)
+		}
+	}
+	return current, nil
+}
+
+func (v calcVar) eval(c *calcEval) (calcValue, string, error) {
+	raw, err := v.resolve(c)
+	if err != nil {
+		return calcValue{}, "", err
+	}
+	value, err := toCalcValue(raw)
+	if err != nil {
+		return calcValue{}, "", fmt.Errorf("variable %q: %w", v, err)
+	}
+	return value, value.String(), nil
+}
+
+func (u calcUnary) eval(c *calcEval) (calcValue, string, error) {
+	value, text, err := u.operand.eval(c)
+	if err != nil {
+		return calcValue{}, "", err
+	}
+	return calcValue{num: new(big.Rat).Neg(value.num), unit: value.unit}, "-" + text, nil
+}
+
+func (p calcParen) eval(c *calcEval) (calcValue, string, error) {
+	value, text, err := p.inner.eval(c)
+	if err != nil {
+		return calcValue{}, "", err
+	}
+	return value, "(" + text + ")", nil
+}
+
+func (b calcBinary) eval(c *calcEval) (calcValue, string, error) {
+	left, leftText, err := b.left.eval(c)
+	if err != nil {
+		return calcValue{}, "", err
+	}
+	right, rightText, err := b.right.eval(c)
+	if err != nil {
+		return calcValue{}, "", err
+	}
+	text := leftText + " " + string(b.op) + " " + rightText
+	value, err := calcArith(b.op, left, right)
+	return value, text, err
+}
+
+// calcArith applies op with unit checks: sums need matching units, with
+// unitless operands taking the other's unit; products allow one unit;
+// quotients of the same unit are unitless and nothing divides by a unit
+// alone.
+func calcArith(op byte, left, right calcValue) (calcValue, error) {
+	out := calcValue{num: new(big.Rat)}
+	switch op {
+	case '+', '-':
+		unit, err := mergeCalcUnits(string(op), left.unit, right.unit)
+		if err != nil {
+			return calcValue{}, err
+		}
+		out.unit = unit
+		if op == '+' {
+			out.num.Add(left.num, right.num)
+		} else {
+			out.num.Sub(left.num, right.num)
+		}
+	case '*':
+		if left.unit != "" && right.unit != "" {
+			return calcValue{}, fmt.Errorf("cannot multiply %s by %s", left.unit, right.unit)
+		}
+		out.unit = left.unit + right.unit
+		out.num.Mul(left.num, right.num)
+	case '/', '%':
+		switch {
+		case right.unit == "":
+			out.unit = left.unit
+		case left.unit == right.unit:
+			if op == '%' {
+				out.unit = left.unit
+			}
+		case left.unit == "":
+			return calcValue{}, fmt.Errorf("cannot divide a number by %s", right.unit)
+		default:
+			return calcValue{}, fmt.Errorf("cannot divide %s by %s", left.unit, right.unit)
+		}
+		if right.num.Sign() == 0 {
+			return calcValue{}, errCalcDivideByZero
+		}
+		quotient := new(big.Rat).Quo(left.num, right.num)
+		if op == '/' {
+			out.num = quotient
+			break
+		}
+		// Truncated remainder, with the sign of the dividend.
+		whole := roundRat(quotient, 0, RoundDown)
+		out.num.Sub(left.num, whole.Mul(whole, right.num))
+	}
+	return out, nil
+}
+
+func mergeCalcUnits(op, a, b string) (string, error) {
+	switch {
+	case a == "" || a == b:
+		return b, nil
+	case b == "":
+		return a, nil
+	default:
+		return "", fmt.Errorf("cannot %s %s and %s", map[string]string{"+": "add", "-": "subtract", "min": "compare", "max": "compare", "sum": "sum"}[op], a, b)
+	}
+}
+
+func (f calcCall) eval(c *calcEval) (calcValue, string, error) {
+	if f.name == "sum" {
+		return f.evalSum(c)
+	}
+	values := make([]calcValue, len(f.args))
+	texts := make([]string, len(f.args))
+	for i, arg := range f.args {
+		var err error
+		if values[i], texts[i], err = arg.eval(c); err != nil {
+			return calcValue{}, "", err
+		}
+	}
+	text := f.name + "(" + strings.Join(texts, ", ") + ")"
+	x := values[0]
+	out := calcValue{unit: x.unit}
+	switch f.name {
+	case "round":
+		places := 0
+		if len(values) == 2 {
+			p := values[1]
+			if p.unit != "" || !p.num.IsInt() || p.num.Num().Int64() < 0 || p.num.Num().Int64() > maxCalcScale {
+				return calcValue{}, "", fmt.Errorf("round: places must be a whole number from 0 to %d", maxCalcScale)
+			}
+			places = int(p.num.Num().Int64())
+		}
+		out.num = roundRat(x.num, places, c.rounding)
+	case "floor":
+		out.num = roundRat(x.num, 0, RoundFloor)
+	case "ceil":
+		out.num = roundRat(x.num, 0, RoundCeiling)
+	case "abs":
+		out.num = new(big.Rat).Abs(x.num)
+	case "min", "max":
+		out.num = x.num
+		for _, v := range values[1:] {
+			unit, err := mergeCalcUnits(f.name, out.unit, v.unit)
+			if err != nil {
+				return calcValue{}, "", err
+			}
+			out.unit = unit
+			if cmp := v.num.Cmp(out.num); (f.name == "min" && cmp < 0) || (f.name == "max" && cmp > 0) {
+				out.num = v.num
+			}
+		}
+	case "pct":
+		quotient, err := calcArith('/', values[0], values[1])
+		if err != nil {
+			return calcValue{}, "", err
+		}
+		out.unit = quotient.unit
+		out.num = quotient.num.Mul(quotient.num, big.NewRat(100, 1))
+	}
+	return out, text, nil
+}
+
+// evalSum adds the items of a list variable, or their field when a second
+// argument names one.
+func (f calcCall) evalSum(c *calcEval) (calcValue, string, error) {
+	list, ok := f.args[0].(calcVar)
+	if !ok {
+		return calcValue{}, "", fmt.Errorf("sum: the first argument must be a list variable")
+	}
+	var field []string
+	if len(f.args) == 2 {
+		name, ok := f.args[1].(calcString)
+		if !ok {
+			return calcValue{}, "", fmt.Errorf("sum: the field argument must be a string")
+		}
+		field = strings.Split(name.value, ".")
+	}
+	raw, err := list.resolve(c)
+	if err != nil {
+		return calcValue{}, "", err
+	}
+	items, ok := raw.([]any)
+	if !ok {
+		return calcValue{}, "", fmt.Errorf("sum: variable %q is not a list", list)
+	}
+
+	out := calcValue{num: new(big.Rat)}
+	texts := make([]string, 0, len(items))
+	for i, item := range items {
+		for _, name := range field {
+			m, ok := item.(map[string]any)
+			if !ok {
+				return calcValue{}, "", fmt.Errorf("sum: %s[%d] has no field %q", list, i, strings.Join(field, "."))
+			}
+			item = m[name]
+		}
+		value, err := toCalcValue(item)
+		if err != nil {
+			return calcValue{}, "", fmt.Errorf("sum: %s[%d]: %w", list, i, err)
+		}
+		unit, err := mergeCalcUnits("sum", out.unit, value.unit)
+		if err != nil {
+			return calcValue{}, "", fmt.Errorf("sum: %s[%d]: %w", list, i, err)
+		}
+		out.unit = unit
+		out.num.Add(out.num, value.num)
+		texts = append(texts, value.String())
+	}
+	if len(texts) == 0 {
+		return out, "0", nil
+	}
+	return out, "(" + strings.Join(texts, " + ") + ")", nil
+}
+
+// toCalcValue converts a variable to an exact decimal. Numbers keep the
+// decimal digits of their shortest representation, so 0.1 is exactly one
+// tenth; strings hold a decimal number; objects with "amount" and
+// "currency" or "unit" carry a unit.
+func toCalcValue(v any) (calcValue, error) {
+	switch x := v.(type) {
+	case map[string]any:
+		unit, _ := x["currency"].(string)
+		if unit == "" {
+			unit, _ = x["unit"].(string)
+		}
+		amount, ok := x["amount"]
+		if !ok {
+			return calcValue{}, fmt.Errorf("object has no amount")
+		}
+		value, err := toCalcValue(amount)
+		if err != nil {
+			return calcValue{}, err
+		}
+		value.unit = strings.ToUpper(unit)
+		return value, nil
+	case *big.Rat:
+		return calcValue{num: x}, nil
+	case string:
+		num, ok := new(big.Rat).SetString(strings.TrimSpace(x))
+		if !ok || strings.ContainsAny(x, "/eE") {
+			return calcValue{}, fmt.Errorf("%q is not a decimal number", x)
+		}
+		return calcValue{num: num}, nil
+	case float64:
+		return decimalFromFloat(x, 64)
+	case float32:
+		return decimalFromFloat(float64(x), 32)
+	case int:
+		return calcValue{num: new(big.Rat).SetInt64(int64(x))}, nil
+	case int64:
+		return calcValue{num: new(big.Rat).SetInt64(x)}, nil
+	case int32:
+		return calcValue{num: new(big.Rat).SetInt64(int64(x))}, nil
+	case interface{ String() string }:
+		// json.Number and decimal types.
+		return toCalcValue(x.String())
+	case nil:
+		return calcValue{}, fmt.Errorf("value is null")
+	default:
+		return calcValue{}, fmt.Errorf("%T is not a number", v)
+	}
+}
+
+func decimalFromFloat(f float64, bits int) (calcValue, error) {
+	num, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, bits))
+	if !ok {
+		return calcValue{}, fmt.Errorf("%v is not a finite number", f)
+	}
+	return calcValue{num: num}, nil
+}
@@ -0,0 +1,161 @@
+package nodes
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func TestCalcNode_DecimalTotals(t *testing.T) {
+	node, err := NewCalcNode("totals", CalcNodeConfig{Formulas: []CalcFormula{
+		{Key: "subtotal", Expr: `sum(order.items, "price")`, Currency: "usd"},
+		{Key: "tax", Expr: "subtotal * order.tax_rate"},
+		{Key: "total", Expr: "subtotal + tax"},
+		{Key: "tenths", Expr: "0.1 + 0.2"},
+		{Key: "discount_pct", Expr: "pct(order.discount, subtotal)", Scale: intPtr(1)},
+	}})
+	if err != nil {
+		t.Fatalf("NewCalcNode: %v", err)
+	}
+
+	env := core.NewEnvelope().WithVar("order", map[string]any{
+		"items": []any{
+			map[string]any{"price": map[string]any{"amount": 19.99, "currency": "USD"}},
+			map[string]any{"price": map[string]any{"amount": "5.01", "currency": "USD"}},
+		},
+		"tax_rate": 0.0825,
+		"discount": map[string]any{"amount": 2.5, "currency": "USD"},
+	})
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := map[string]string{
+		"subtotal":     "25.00",
+		"tax":          "2.06", // 2.0625 rounded half up
+		"total":        "27.06",
+		"tenths":       "0.3",
+		"discount_pct": "10.0",
+	}
+	for key, w := range want {
+		if got, _ := result.GetVar(key); got != w {
+			t.Errorf("%s = %v, want %s", key, got, w)
+		}
+	}
+
+	raw, _ := result.GetVar("totals_audit")
+	audits := raw.([]CalcAudit)
+	if tax := audits[1]; tax.Formula != "25 USD * 0.0825" || tax.Exact != "2.0625" || tax.Unit != "USD" || tax.Scale != 2 {
+		t.Errorf("tax audit = %+v", tax)
+	}
+	if subtotal := audits[0]; subtotal.Formula != "(19.99 USD + 5.01 USD)" {
+		t.Errorf("subtotal formula = %q", subtotal.Formula)
+	}
+}
+
+func TestCalcNode_RoundingModes(t *testing.T) {
+	cases := []struct {
+		mode RoundingMode
+		in   string
+		want string
+	}{
+		{RoundHalfUp, "2.345", "2.35"},
+		{RoundHalfUp, "-2.345", "-2.35"},
+		{RoundHalfEven, "2.345", "2.34"},
+		{RoundHalfEven, "2.355", "2.36"},
+		{RoundHalfDown, "2.345", "2.34"},
+		{RoundUp, "2.341", "2.35"},
+		{RoundDown, "2.349", "2.34"},
+		{RoundCeiling, "-2.349", "-2.34"},
+		{RoundFloor, "-2.341", "-2.35"},
+	}
+	for _, tc := range cases {
+		x, _ := new(big.Rat).SetString(tc.in)
+		if got := roundRat(x, 2, tc.mode).FloatString(2); got != tc.want {
+			t.Errorf("%s(%s) = %s, want %s", tc.mode, tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestCalcNode_UnitAndDivisionGuards(t *testing.T) {
+	env := core.NewEnvelope().
+		WithVar("usd", map[string]any{"amount": 10, "currency": "USD"}).
+		WithVar("eur", map[string]any{"amount": 10, "currency": "EUR"}).
+		WithVar("refunds", 0)
+
+	failing := map[string]string{
+		"usd + eur":   "cannot add USD and EUR",
+		"usd * usd":   "cannot multiply USD by USD",
+		"1 / usd":     "cannot divide a number by USD",
+		"usd / eur":   "cannot divide USD by EUR",
+		"usd / 0":     "division by zero",
+		"missing + 1": `variable "missing" not found`,
+	}
+	for expr, want := range failing {
+		node, err := NewCalcNode("calc", CalcNodeConfig{Formulas: []CalcFormula{{Key: "out", Expr: expr}}})
+		if err != nil {
+			t.Fatalf("NewCalcNode(%s): %v", expr, err)
+		}
+		if _, err := node.Run(context.Background(), env); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: error = %v, want %q", expr, err, want)
+		}
+	}
+
+	node, err := NewCalcNode("calc", CalcNodeConfig{
+		OnDivideByZero: DivideByZeroNull,
+		Output:         CalcOutputNumber,
+		Formulas: []CalcFormula{
+			{Key: "ratio", Expr: "usd / usd"},
+			{Key: "refund_rate", Expr: "usd / refunds"},
+			{Key: "third", Expr: "usd / 3"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewCalcNode: %v", err)
+	}
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if ratio, _ := result.GetVar("ratio"); ratio != float64(1) {
+		t.Errorf("ratio = %v", ratio)
+	}
+	if rate, ok := result.GetVar("refund_rate"); !ok || rate != nil {
+		t.Errorf("refund_rate = %v, want null", rate)
+	}
+	if third, _ := result.GetVar("third"); third != 3.33 {
+		t.Errorf("third = %v", third)
+	}
+	raw, _ := result.GetVar("calc_audit")
+	audits := raw.([]CalcAudit)
+	if !strings.Contains(audits[1].Note, "division by zero") {
+		t.Errorf("refund_rate audit = %+v", audits[1])
+	}
+	if audits[2].Exact != "3.33333333333333333333..." {
+		t.Errorf("third exact = %q", audits[2].Exact)
+	}
+}
+
+func TestNewCalcNode_Errors(t *testing.T) {
+	cases := map[string]CalcNodeConfig{
+		"formulas are required":  {},
+		"unknown function":       {Formulas: []CalcFormula{{Key: "x", Expr: "sqrt(2)"}}},
+		"missing )":              {Formulas: []CalcFormula{{Key: "x", Expr: "(1 + 2"}}},
+		"must be a variable":     {Formulas: []CalcFormula{{Key: "a.b", Expr: "1"}}},
+		"duplicate formula key":  {Formulas: []CalcFormula{{Key: "x", Expr: "1"}, {Key: "x", Expr: "2"}}},
+		"unknown rounding mode":  {Rounding: "nearest", Formulas: []CalcFormula{{Key: "x", Expr: "1"}}},
+		"round takes 1 to 2":     {Formulas: []CalcFormula{{Key: "x", Expr: "round(1, 2, 3)"}}},
+		"on_divide_by_zero must": {OnDivideByZero: "ignore", Formulas: []CalcFormula{{Key: "x", Expr: "1"}}},
+	}
+	for want, cfg := range cases {
+		if _, err := NewCalcNode("calc", cfg); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want %q", err, want)
+		}
+	}
+}
+
+func intPtr(v int) *int { return &v }
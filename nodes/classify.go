@@ -0,0 +1,524 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// Classification methods.
+const (
+	// ClassifyMethodLLM asks the model to pick labels. It is the default.
+	ClassifyMethodLLM = "llm"
+	// ClassifyMethodEmbedding picks the labels whose descriptions and
+	// examples are nearest to the input, without a model call.
+	ClassifyMethodEmbedding = "embedding"
+)
+
+// ClassLabelSeparator joins the names along a label's path in a
+// hierarchical taxonomy, as in "billing/refund".
+const ClassLabelSeparator = "/"
+
+// ClassLabel is one label of a classification taxonomy. A label with
+// children is a category: only labels without children are assigned.
+type ClassLabel struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Examples    []string     `json:"examples,omitempty"`
+	Children    []ClassLabel `json:"children,omitempty"`
+}
+
+// UnmarshalJSON accepts the object form and a bare label name.
+func (l *ClassLabel) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*l = ClassLabel{Name: name}
+		return nil
+	}
+	type plain ClassLabel
+	return json.Unmarshal(data, (*plain)(l))
+}
+
+// ValidateTaxonomy checks that labels are named, that names do not contain
+// ClassLabelSeparator, and that siblings have distinct names.
+func ValidateTaxonomy(labels []ClassLabel) error {
+	if len(labels) == 0 {
+		return fmt.Errorf("taxonomy has no labels")
+	}
+	return validateLabels(labels, "")
+}
+
+func validateLabels(labels []ClassLabel, parent string) error {
+	seen := make(map[string]bool, len(labels))
+	for i, label := range labels {
+		name := strings.TrimSpace(label.Name)
+		switch {
+		case name == "":
+			return fmt.Errorf("label %d under %q has no name", i, parent)
+		case strings.Contains(name, ClassLabelSeparator):
+			return fmt.Errorf("label %q: names must not contain %q", name, ClassLabelSeparator)
+		case seen[name]:
+			return fmt.Errorf("label %q is defined twice", joinLabelPath(parent, name))
+		}
+		seen[name] = true
+		if err := validateLabels(label.Children, joinLabelPath(parent, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func joinLabelPath(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + ClassLabelSeparator + name
+}
+
+// assignableLabel is a label without children with its full path.
+type assignableLabel struct {
+	Path  string
+	Label ClassLabel
+	// Parents holds the descriptions of the categories above the label.
+	Parents []string
+}
+
+// leafLabels flattens a taxonomy into the labels that can be assigned, in
+// taxonomy order.
+func leafLabels(labels []ClassLabel, parent string, parents []string) []assignableLabel {
+	var out []assignableLabel
+	for _, label := range labels {
+		path := joinLabelPath(parent, strings.TrimSpace(label.Name))
+		if len(label.Children) == 0 {
+			out = append(out, assignableLabel{Path: path, Label: label, Parents: parents})
+			continue
+		}
+		out = append(out, leafLabels(label.Children, path, append(slices.Clone(parents), label.Description))...)
+	}
+	return out
+}
+
+// ClassifyNodeConfig configures a ClassifyNode.
+type ClassifyNodeConfig struct {
+	// InputVar holds the text to classify. Values other than strings are
+	// classified as JSON. Required.
+	InputVar string
+
+	// OutputKey stores the label, or the list of labels with MultiLabel.
+	// Defaults to "{id}_output".
+	OutputKey string
+
+	// ResultKey stores a ClassificationResult. Defaults to
+	// "{OutputKey}_result".
+	ResultKey string
+
+	// Labels is the taxonomy. Required.
+	Labels []ClassLabel
+
+	// MultiLabel assigns every label at or above MinConfidence instead of
+	// the single best one.
+	MultiLabel bool
+
+	// MaxLabels caps the labels assigned with MultiLabel. 0 means no cap.
+	MaxLabels int
+
+	// MinConfidence is the lowest confidence a label is assigned with.
+	// Defaults to 0.5 with MultiLabel and 0 otherwise.
+	MinConfidence float64
+
+	// DefaultLabel is assigned when no label reaches MinConfidence. Empty
+	// assigns nothing.
+	DefaultLabel string
+
+	// Method is ClassifyMethodLLM (default) or ClassifyMethodEmbedding.
+	Method string
+
+	// EmbeddingFallback classifies by embedding when the model call fails
+	// or returns no label of the taxonomy.
+	EmbeddingFallback bool
+
+	// Embed computes embeddings for the embedding method. Defaults to a
+	// hashed bag-of-words vector, which matches shared vocabulary rather
+	// than meaning.
+	Embed EmbedFunc
+
+	// Instructions adds guidance to the classification prompt.
+	Instructions string
+
+	// Model is the model used for classification.
+	Model string
+
+	// RetryPolicy configures retries for the classification call.
+	RetryPolicy core.RetryPolicy
+
+	// Timeout bounds the classification call. Defaults to 60s.
+	Timeout time.Duration
+}
+
+// LabelScore is a label with the confidence it was assigned with.
+type LabelScore struct {
+	Label      string  `json:"label"`
+	Confidence float64 `json:"confidence"`
+}
+
+// ClassificationResult reports how a ClassifyNode run went.
+type ClassificationResult struct {
+	// Labels are the assigned labels, most confident first.
+	Labels []LabelScore `json:"labels"`
+	// Scores are every label the method scored, most confident first.
+	Scores []LabelScore `json:"scores,omitempty"`
+	// Method is the method that produced the labels.
+	Method string `json:"method"`
+	// Fallback is set when the embedding method replaced the model, with
+	// the reason in FallbackReason.
+	Fallback       bool   `json:"fallback,omitempty"`
+	FallbackReason string `json:"fallback_reason,omitempty"`
+	// Rejected lists labels the model returned that are not in the
+	// taxonomy.
+	Rejected []string `json:"rejected,omitempty"`
+	// Defaulted is set when DefaultLabel was assigned.
+	Defaulted bool            `json:"defaulted,omitempty"`
+	Usage     core.TokenUsage `json:"usage"`
+}
+
+// ClassifyNode assigns labels from a taxonomy to a text, with confidence
+// scores. The model's answer is constrained to the taxonomy's labels; the
+// embedding method, or the embedding fallback, picks the labels nearest to
+// the text instead.
+type ClassifyNode struct {
+	core.BaseNode
+	config ClassifyNodeConfig
+	client core.LLMClient
+	leaves []assignableLabel
+
+	embedMu    sync.Mutex
+	labelEmbed [][][]float64
+}
+
+// NewClassifyNode creates a new ClassifyNode. client may be nil with the
+// embedding method.
+func NewClassifyNode(id string, client core.LLMClient, config ClassifyNodeConfig) *ClassifyNode {
+	if config.OutputKey == "" {
+		config.OutputKey = id + "_output"
+	}
+	if config.ResultKey == "" {
+		config.ResultKey = config.OutputKey + "_result"
+	}
+	if config.Method == "" {
+		config.Method = ClassifyMethodLLM
+	}
+	if config.MinConfidence == 0 && config.MultiLabel {
+		config.MinConfidence = 0.5
+	}
+	if config.Embed == nil {
+		config.Embed = hashedEmbedding
+	}
+	if config.RetryPolicy.MaxAttempts == 0 {
+		config.RetryPolicy = core.DefaultRetryPolicy()
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 60 * time.Second
+	}
+
+	return &ClassifyNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindLLM),
+		config:   config,
+		client:   client,
+		leaves:   leafLabels(config.Labels, "", nil),
+	}
+}
+
+// Config returns the node's configuration.
+func (n *ClassifyNode) Config() ClassifyNodeConfig {
+	return n.config
+}
+
+// Run classifies the input variable.
+func (n *ClassifyNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	if err := ValidateTaxonomy(n.config.Labels); err != nil {
+		return nil, fmt.Errorf("classify node %s: %w", n.ID(), err)
+	}
+	input, ok := env.GetVarNested(n.config.InputVar)
+	if !ok {
+		return nil, fmt.Errorf("classify node %s: input var %q not found", n.ID(), n.config.InputVar)
+	}
+	text, ok := input.(string)
+	if !ok {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return nil, fmt.Errorf("classify node %s: encoding input: %w", n.ID(), err)
+		}
+		text = string(data)
+	}
+
+	result := ClassificationResult{Method: n.config.Method}
+	var scores []LabelScore
+	var err error
+	if n.config.Method == ClassifyMethodLLM {
+		scores, err = n.classifyLLM(ctx, env, text, &result)
+		if err == nil && len(scores) == 0 {
+			err = fmt.Errorf("model returned no label of the taxonomy")
+		}
+		if err != nil {
+			if !n.config.EmbeddingFallback {
+				return nil, fmt.Errorf("classify node %s: %w", n.ID(), err)
+			}
+			result.Fallback = true
+			result.FallbackReason = err.Error()
+			result.Method = ClassifyMethodEmbedding
+		}
+	}
+	if result.Method == ClassifyMethodEmbedding {
+		scores, err = n.classifyEmbedding(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("classify node %s: %w", n.ID(), err)
+		}
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool { return scores[i].Confidence > scores[j].Confidence })
+	result.Scores = scores
+	result.Labels = n.assign(scores)
+	if len(result.Labels) == 0 && n.config.DefaultLabel != "" {
+		result.Labels = []LabelScore{{Label: n.config.DefaultLabel}}
+		result.Defaulted = true
+	}
+
+	if n.config.MultiLabel {
+		labels := make([]string, len(result.Labels))
+		for i, l := range result.Labels {
+			labels[i] = l.Label
+		}
+		env.SetVar(n.config.OutputKey, labels)
+	} else {
+		label := ""
+		if len(result.Labels) > 0 {
+			label = result.Labels[0].Label
+		}
+		env.SetVar(n.config.OutputKey, label)
+	}
+	env.SetVar(n.config.ResultKey, result)
+	return env, nil
+}
+
+// assign picks the labels to assign from scores sorted by confidence.
+func (n *ClassifyNode) assign(scores []LabelScore) []LabelScore {
+	var out []LabelScore
+	for _, s := range scores {
+		if s.Confidence < n.config.MinConfidence {
+			break
+		}
+		out = append(out, s)
+		if !n.config.MultiLabel || (n.config.MaxLabels > 0 && len(out) == n.config.MaxLabels) {
+			break
+		}
+	}
+	return out
+}
+
+// classifyLLM asks the model to score the labels and keeps the ones in the
+// taxonomy.
+func (n *ClassifyNode) classifyLLM(ctx context.Context, env *core.Envelope, text string, result *ClassificationResult) ([]LabelScore, error) {
+	if n.client == nil {
+		return nil, fmt.Errorf("no LLM client configured")
+	}
+	if n.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.Timeout)
+		defer cancel()
+	}
+	paths := make([]string, len(n.leaves))
+	for i, leaf := range n.leaves {
+		paths[i] = leaf.Path
+	}
+	req := core.LLMRequest{
+		Model:     n.config.Model,
+		System:    n.prompt(),
+		InputText: text,
+		JSONSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"labels": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type": "object",
+						"properties": map[string]any{
+							"label":      map[string]any{"type": "string", "enum": paths},
+							"confidence": map[string]any{"type": "number", "minimum": 0, "maximum": 1},
+						},
+						"required": []string{"label", "confidence"},
+					},
+				},
+			},
+			"required": []string{"labels"},
+		},
+	}
+
+	var resp core.LLMResponse
+	var err error
+	for attempt := 1; attempt <= n.config.RetryPolicy.MaxAttempts; attempt++ {
+		err = runtime.InjectProviderFault(ctx, env.Trace.RunID, n.ID(), n.Kind())
+		if err == nil {
+			resp, err = n.client.Complete(ctx, req)
+		}
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if attempt < n.config.RetryPolicy.MaxAttempts {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(n.config.RetryPolicy.Backoff * time.Duration(attempt)):
+			}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("classification call failed after %d attempts: %w", n.config.RetryPolicy.MaxAttempts, err)
+	}
+	result.Usage = core.TokenUsage{
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+		TotalTokens:  resp.Usage.TotalTokens,
+		CostUSD:      resp.Usage.CostUSD,
+	}
+
+	var parsed struct {
+		Labels []LabelScore `json:"labels"`
+	}
+	data := []byte(stripCodeFence(resp.Text))
+	if resp.JSON != nil {
+		data, _ = json.Marshal(resp.JSON)
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, fmt.Errorf("could not parse labels from: %s", resp.Text)
+	}
+
+	known := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		known[p] = true
+	}
+	best := make(map[string]float64)
+	var order []string
+	for _, l := range parsed.Labels {
+		label := strings.TrimSpace(l.Label)
+		if !known[label] {
+			result.Rejected = append(result.Rejected, l.Label)
+			continue
+		}
+		confidence := min(max(l.Confidence, 0), 1)
+		if prev, seen := best[label]; !seen {
+			order = append(order, label)
+		} else if prev >= confidence {
+			continue
+		}
+		best[label] = confidence
+	}
+	scores := make([]LabelScore, len(order))
+	for i, label := range order {
+		scores[i] = LabelScore{Label: label, Confidence: best[label]}
+	}
+	return scores, nil
+}
+
+// prompt describes the taxonomy and the expected answer.
+func (n *ClassifyNode) prompt() string {
+	var b strings.Builder
+	b.WriteString("You classify text into the labels of a fixed taxonomy. Use only the labels listed below, exactly as written.\n\nLabels:\n")
+	for _, leaf := range n.leaves {
+		fmt.Fprintf(&b, "- %s", leaf.Path)
+		var context []string
+		for _, p := range leaf.Parents {
+			if p != "" {
+				context = append(context, p)
+			}
+		}
+		if leaf.Label.Description != "" {
+			context = append(context, leaf.Label.Description)
+		}
+		if len(context) > 0 {
+			b.WriteString(": " + strings.Join(context, "; "))
+		}
+		b.WriteString("\n")
+		for _, ex := range leaf.Label.Examples {
+			fmt.Fprintf(&b, "  Example: %q\n", ex)
+		}
+	}
+	if n.config.MultiLabel {
+		b.WriteString("\nAssign every label that applies")
+		if n.config.MaxLabels > 0 {
+			fmt.Fprintf(&b, ", at most %d", n.config.MaxLabels)
+		}
+		b.WriteString(".")
+	} else {
+		b.WriteString("\nAssign the one label that fits best; you may list runners-up after it.")
+	}
+	b.WriteString(" Give each label a confidence from 0 to 1.")
+	if n.config.Instructions != "" {
+		b.WriteString("\n\n" + n.config.Instructions)
+	}
+	b.WriteString("\n\nRespond with JSON: {\"labels\": [{\"label\": \"<label>\", \"confidence\": <0-1>}]}, most confident first.")
+	return b.String()
+}
+
+// classifyEmbedding scores each label by the highest cosine similarity of
+// the text to its name, description and examples.
+func (n *ClassifyNode) classifyEmbedding(ctx context.Context, text string) ([]LabelScore, error) {
+	refs, err := n.labelEmbeddings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vec, err := n.config.Embed(ctx, text)
+	if err != nil {
+		return nil, fmt.Errorf("embedding input: %w", err)
+	}
+	scores := make([]LabelScore, len(n.leaves))
+	for i, leaf := range n.leaves {
+		best := 0.0
+		for _, ref := range refs[i] {
+			if len(ref) == len(vec) {
+				best = max(best, cosineSimilarity(vec, ref))
+			}
+		}
+		scores[i] = LabelScore{Label: leaf.Path, Confidence: round2(best)}
+	}
+	return scores, nil
+}
+
+// labelEmbeddings embeds the reference texts of each label once.
+func (n *ClassifyNode) labelEmbeddings(ctx context.Context) ([][][]float64, error) {
+	n.embedMu.Lock()
+	defer n.embedMu.Unlock()
+	if n.labelEmbed != nil {
+		return n.labelEmbed, nil
+	}
+	refs := make([][][]float64, len(n.leaves))
+	for i, leaf := range n.leaves {
+		texts := []string{strings.ReplaceAll(leaf.Path, ClassLabelSeparator, " ")}
+		if leaf.Label.Description != "" {
+			texts = append(texts, leaf.Label.Description)
+		}
+		texts = append(texts, leaf.Label.Examples...)
+		for _, text := range texts {
+			vec, err := n.config.Embed(ctx, text)
+			if err != nil {
+				return nil, fmt.Errorf("embedding label %q: %w", leaf.Path, err)
+			}
+			refs[i] = append(refs[i], vec)
+		}
+	}
+	n.labelEmbed = refs
+	return refs, nil
+}
+
+// Ensure interface compliance at compile time.
+var _ core.Node = (*ClassifyNode)(nil)
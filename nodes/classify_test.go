@@ -0,0 +1,154 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+var supportTaxonomy = []ClassLabel{
+	{Name: "billing", Description: "Payments and invoices", Children: []ClassLabel{
+		{Name: "refund", Description: "Requests for money back", Examples: []string{"I want my money back"}},
+		{Name: "invoice", Description: "Invoice copies and corrections"},
+	}},
+	{Name: "shipping", Description: "Delivery status and delays", Examples: []string{"where is my parcel"}},
+}
+
+func TestClassifyNode_SingleLabel(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{
+		JSON: map[string]any{"labels": []any{
+			map[string]any{"label": "billing/refund", "confidence": 0.9},
+			map[string]any{"label": "billing", "confidence": 0.8},
+			map[string]any{"label": "shipping", "confidence": 0.2},
+		}},
+		Usage: core.LLMTokenUsage{TotalTokens: 7},
+	}}
+	node := NewClassifyNode("cls", client, ClassifyNodeConfig{InputVar: "ticket", Labels: supportTaxonomy})
+
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("ticket", "Please refund my order"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out, _ := result.GetVar("cls_output"); out != "billing/refund" {
+		t.Errorf("output = %v", out)
+	}
+	raw, _ := result.GetVar("cls_output_result")
+	res := raw.(ClassificationResult)
+	if res.Method != ClassifyMethodLLM || res.Fallback || res.Usage.TotalTokens != 7 {
+		t.Errorf("result = %+v", res)
+	}
+	if !reflect.DeepEqual(res.Rejected, []string{"billing"}) || len(res.Scores) != 2 {
+		t.Errorf("rejected = %v, scores = %v", res.Rejected, res.Scores)
+	}
+
+	req := client.requests[0]
+	enum := req.JSONSchema["properties"].(map[string]any)["labels"].(map[string]any)["items"].(map[string]any)["properties"].(map[string]any)["label"].(map[string]any)["enum"]
+	if !reflect.DeepEqual(enum, []string{"billing/refund", "billing/invoice", "shipping"}) {
+		t.Errorf("enum = %v", enum)
+	}
+	for _, want := range []string{"- billing/refund: Payments and invoices; Requests for money back", `Example: "I want my money back"`, "one label"} {
+		if !strings.Contains(req.System, want) {
+			t.Errorf("system prompt missing %q: %q", want, req.System)
+		}
+	}
+}
+
+func TestClassifyNode_MultiLabel(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{
+		Text: "```json\n" + `{"labels": [{"label": "shipping", "confidence": 0.7}, {"label": "billing/refund", "confidence": 0.95}, {"label": "billing/invoice", "confidence": 0.3}]}` + "\n```",
+	}}
+	node := NewClassifyNode("cls", client, ClassifyNodeConfig{InputVar: "ticket", Labels: supportTaxonomy, MultiLabel: true})
+
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("ticket", "Late parcel, refund please"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out, _ := result.GetVar("cls_output"); !reflect.DeepEqual(out, []string{"billing/refund", "shipping"}) {
+		t.Errorf("output = %v", out)
+	}
+}
+
+func TestClassifyNode_DefaultLabel(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{Text: `{"labels": [{"label": "shipping", "confidence": 0.4}]}`}}
+	node := NewClassifyNode("cls", client, ClassifyNodeConfig{
+		InputVar: "ticket", Labels: supportTaxonomy, MinConfidence: 0.6, DefaultLabel: "other",
+	})
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("ticket", "hello"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	raw, _ := result.GetVar("cls_output_result")
+	if out, _ := result.GetVar("cls_output"); out != "other" || !raw.(ClassificationResult).Defaulted {
+		t.Errorf("output = %v, result = %+v", out, raw)
+	}
+}
+
+func TestClassifyNode_EmbeddingFallback(t *testing.T) {
+	client := &mockLLMClient{err: errors.New("provider down")}
+	node := NewClassifyNode("cls", client, ClassifyNodeConfig{
+		InputVar:          "ticket",
+		Labels:            supportTaxonomy,
+		EmbeddingFallback: true,
+		RetryPolicy:       core.RetryPolicy{MaxAttempts: 1},
+	})
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("ticket", "where is my parcel, the delivery is late"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out, _ := result.GetVar("cls_output"); out != "shipping" {
+		t.Errorf("output = %v", out)
+	}
+	raw, _ := result.GetVar("cls_output_result")
+	res := raw.(ClassificationResult)
+	if !res.Fallback || res.Method != ClassifyMethodEmbedding || !strings.Contains(res.FallbackReason, "provider down") {
+		t.Errorf("result = %+v", res)
+	}
+
+	node.config.EmbeddingFallback = false
+	if _, err := node.Run(context.Background(), core.NewEnvelope().WithVar("ticket", "x")); err == nil {
+		t.Error("expected the provider error without a fallback")
+	}
+}
+
+func TestClassifyNode_EmbeddingMethod(t *testing.T) {
+	node := NewClassifyNode("cls", nil, ClassifyNodeConfig{
+		InputVar: "ticket",
+		Labels:   supportTaxonomy,
+		Method:   ClassifyMethodEmbedding,
+	})
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("ticket", "I want my money back"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out, _ := result.GetVar("cls_output"); out != "billing/refund" {
+		t.Errorf("output = %v", out)
+	}
+}
+
+func TestValidateTaxonomy(t *testing.T) {
+	var labels []ClassLabel
+	if err := json.Unmarshal([]byte(`["a", {"name": "b", "children": ["c", "d"]}]`), &labels); err != nil {
+		t.Fatal(err)
+	}
+	if err := ValidateTaxonomy(labels); err != nil {
+		t.Errorf("ValidateTaxonomy: %v", err)
+	}
+	if got := leafLabels(labels, "", nil); len(got) != 3 || got[2].Path != "b/d" {
+		t.Errorf("leaves = %+v", got)
+	}
+
+	for _, bad := range []string{`[]`, `["a", "a"]`, `["a/b"]`, `[{"name": "x", "children": [""]}]`} {
+		var labels []ClassLabel
+		if err := json.Unmarshal([]byte(bad), &labels); err != nil {
+			t.Fatal(err)
+		}
+		if err := ValidateTaxonomy(labels); err == nil {
+			t.Errorf("ValidateTaxonomy(%s) = nil, want an error", bad)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// DefaultMinParallelItems is the smallest collection a performance mode
+// node partitions across goroutines.
+const DefaultMinParallelItems = 10000
+
+// CollectionPerformance switches FilterNode and TransformNode map
+// operations to a mode tuned for large collections: field paths are parsed
+// once, results go into preallocated buffers, consecutive per-item filters
+// run in one pass, and per-item work that does not depend on order can be
+// partitioned across goroutines. Results are the same as without it, in
+// the same order.
+type CollectionPerformance struct {
+	// Parallelism is the number of goroutines per-item work is split
+	// across. 0 uses GOMAXPROCS; 1 keeps the work on one goroutine.
+	Parallelism int
+
+	// MinParallelItems is the smallest collection that is partitioned.
+	// Defaults to DefaultMinParallelItems.
+	MinParallelItems int
+}
+
+// workers returns how many goroutines should process n items.
+func (p *CollectionPerformance) workers(n int) int {
+	if p == nil {
+		return 1
+	}
+	minItems := p.MinParallelItems
+	if minItems <= 0 {
+		minItems = DefaultMinParallelItems
+	}
+	if n < minItems {
+		return 1
+	}
+	workers := p.Parallelism
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > n {
+		workers = n
+	}
+	return max(workers, 1)
+}
+
+// partitionItems calls fn for contiguous ranges of [0, n) on workers
+// goroutines. A failing range stops only the ranges after it, so the error
+// returned is the one of the lowest failing range, as with a sequential
+// loop, whatever the scheduling.
+func partitionItems(ctx context.Context, n, workers int, fn func(ctx context.Context, lo, hi int) error) error {
+	if workers <= 1 {
+		return fn(ctx, 0, n)
+	}
+
+	chunk := (n + workers - 1) / workers
+	errs := make([]error, workers)
+	cancels := make([]context.CancelFunc, workers)
+	ctxs := make([]context.Context, workers)
+	for w := range ctxs {
+		ctxs[w], cancels[w] = context.WithCancel(ctx)
+		defer cancels[w]()
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		lo, hi := w*chunk, min((w+1)*chunk, n)
+		if lo >= hi {
+			break
+		}
+		wg.Add(1)
+		go func(w, lo, hi int) {
+			defer wg.Done()
+			if err := fn(ctxs[w], lo, hi); err != nil {
+				errs[w] = err
+				for _, cancel := range cancels[w+1:] {
+					cancel()
+				}
+			}
+		}(w, lo, hi)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkEvery is how many items per-item loops process between context
+// checks.
+const checkEvery = 1024
+
+// fieldPath is a dot-notation field path split once, for lookups repeated
+// across a collection.
+type fieldPath []string
+
+func newFieldPath(path string) fieldPath {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, ".")
+}
+
+// lookup matches extractValue.
+func (p fieldPath) lookup(item any) (any, bool) {
+	if len(p) == 0 {
+		return nil, false
+	}
+	current := item
+	for _, part := range p {
+		switch v := current.(type) {
+		case map[string]any:
+			val, ok := v[part]
+			if !ok {
+				return nil, false
+			}
+			current = val
+		case core.Artifact:
+			if current = getArtifactField(v, part); current == nil {
+				return nil, false
+			}
+		case *core.Artifact:
+			if current = getArtifactField(*v, part); current == nil {
+				return nil, false
+			}
+		case core.Message:
+			if current = getMessageField(v, part); current == nil {
+				return nil, false
+			}
+		case *core.Message:
+			if current = getMessageField(*v, part); current == nil {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// lookupFloat matches extractFloat, reporting found instead of an error.
+func (p fieldPath) lookupFloat(item any) (float64, bool) {
+	val, found := p.lookup(item)
+	if !found {
+		return 0, false
+	}
+	switch v := val.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// lookupMap matches getNestedValue.
+func (p fieldPath) lookupMap(m map[string]any) (any, bool) {
+	current := any(m)
+	for _, part := range p {
+		currentMap, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		current, ok = currentMap[part]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// setMap matches setNestedValue.
+func (p fieldPath) setMap(m map[string]any, value any) {
+	current := m
+	for _, part := range p[:len(p)-1] {
+		next, ok := current[part].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			current[part] = next
+		}
+		current = next
+	}
+	current[p[len(p)-1]] = value
+}
+
+// deleteMap matches deleteNestedValue.
+func (p fieldPath) deleteMap(m map[string]any) {
+	current := m
+	for _, part := range p[:len(p)-1] {
+		next, ok := current[part].(map[string]any)
+		if !ok {
+			return
+		}
+		current = next
+	}
+	delete(current, p[len(p)-1])
+}
+
+// dedupeKey returns fmt.Sprintf("%v", v) without going through fmt for
+// the common scalar types.
+func dedupeKey(v any) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'g', -1, 64)
+	case int:
+		return strconv.Itoa(t)
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case bool:
+		return strconv.FormatBool(t)
+	case nil:
+		return "<nil>"
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
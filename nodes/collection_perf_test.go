@@ -0,0 +1,298 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// syntheticItems builds n map items with repeated keys, scores, types and
+// a nested field, for comparing the default and performance modes.
+func syntheticItems(n int) []any {
+	types := []string{"chunk", "note", "metadata"}
+	items := make([]any, n)
+	for i := range items {
+		items[i] = map[string]any{
+			"id":    fmt.Sprintf("item-%d", i),
+			"key":   i % (n/3 + 1),
+			"type":  types[i%len(types)],
+			"score": float64((i*7919)%1000) / 1000,
+			"meta":  map[string]any{"source": fmt.Sprintf("src-%d", i%17), "rank": i % 50},
+		}
+	}
+	return items
+}
+
+func filterOps() []FilterOp {
+	minScore, maxScore := 0.1, 0.95
+	return []FilterOp{
+		{Type: FilterOpThreshold, ScoreField: "score", Min: &minScore, Max: &maxScore},
+		{Type: FilterOpByType, IncludeTypes: []string{"chunk", "note"}},
+		{Type: FilterOpExclude, Field: "meta.source", Pattern: "^src-1[0-3]$"},
+		{Type: FilterOpDedupe, Field: "key", Keep: "highest_score", ScoreField: "score"},
+		{Type: FilterOpMatch, Field: "meta.rank", Pattern: "[0-7]$"},
+		{Type: FilterOpTopN, N: 500, ScoreField: "score", Order: "asc"},
+	}
+}
+
+func runFilter(t testing.TB, perf *CollectionPerformance, ops []FilterOp, items []any) []any {
+	t.Helper()
+	node := NewFilterNode("filter", FilterNodeConfig{
+		Target:      FilterTargetVar,
+		InputVar:    "items",
+		OutputVar:   "result",
+		Filters:     ops,
+		Performance: perf,
+	})
+	env := core.NewEnvelope().WithVar("items", items)
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	return result.Vars["result"].([]any)
+}
+
+func TestFilterNode_PerformanceModeMatchesDefault(t *testing.T) {
+	items := syntheticItems(20000)
+	// top_n is left out: the default mode sorts with an unstable sort, so
+	// tied scores may come out in either order.
+	ops := filterOps()[:5]
+	want := runFilter(t, nil, ops, items)
+
+	for _, perf := range []*CollectionPerformance{
+		{Parallelism: 1},
+		{Parallelism: 4, MinParallelItems: 100},
+		{},
+	} {
+		t.Run(fmt.Sprintf("parallelism=%d", perf.Parallelism), func(t *testing.T) {
+			got := runFilter(t, perf, ops, items)
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("performance mode kept %d items, default kept %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestFilterNode_PerformanceTopN(t *testing.T) {
+	items := []any{
+		map[string]any{"id": "a", "score": 0.5},
+		map[string]any{"id": "b", "score": 0.9},
+		map[string]any{"id": "c"},
+		map[string]any{"id": "d", "score": 0.9},
+		map[string]any{"id": "e", "score": 0.1},
+	}
+
+	ids := func(items []any) []string {
+		out := make([]string, len(items))
+		for i, item := range items {
+			out[i] = item.(map[string]any)["id"].(string)
+		}
+		return out
+	}
+
+	got := ids(runFilter(t, &CollectionPerformance{}, []FilterOp{{Type: FilterOpTopN, N: 3, ScoreField: "score"}}, items))
+	if want := []string{"b", "d", "a"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("desc top_n = %v, want %v", got, want)
+	}
+	got = ids(runFilter(t, &CollectionPerformance{}, []FilterOp{{Type: FilterOpTopN, N: 2, ScoreField: "score", Order: "asc"}}, items))
+	if want := []string{"c", "e"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("asc top_n = %v, want %v", got, want)
+	}
+}
+
+func TestFilterNode_PerformanceKeepsInputAndErrors(t *testing.T) {
+	items := syntheticItems(50)
+	before := append([]any(nil), items...)
+	runFilter(t, &CollectionPerformance{}, filterOps(), items)
+	if !reflect.DeepEqual(items, before) {
+		t.Fatal("performance mode modified the input slice")
+	}
+
+	node := NewFilterNode("filter", FilterNodeConfig{
+		Target:   FilterTargetVar,
+		InputVar: "items",
+		Filters: []FilterOp{
+			{Type: FilterOpByType, IncludeTypes: []string{"chunk"}},
+			{Type: FilterOpMatch, Field: "id", Pattern: "("},
+		},
+		Performance: &CollectionPerformance{},
+	})
+	_, err := node.Run(context.Background(), core.NewEnvelope().WithVar("items", items))
+	if err == nil || !strings.Contains(err.Error(), "filter node filter: filter 1 (match): invalid pattern") {
+		t.Fatalf("Run() error = %v, want invalid pattern at filter 1", err)
+	}
+}
+
+func TestFilterNode_PerformanceCustom(t *testing.T) {
+	var seen []int
+	ops := []FilterOp{{
+		Type: FilterOpCustom,
+		CustomFunc: func(item any, index int, _ *core.Envelope) (bool, error) {
+			seen = append(seen, index)
+			return index%2 == 0, nil
+		},
+	}}
+	got := runFilter(t, &CollectionPerformance{MinParallelItems: 1}, ops, syntheticItems(5))
+	if len(got) != 3 || !reflect.DeepEqual(seen, []int{0, 1, 2, 3, 4}) {
+		t.Fatalf("custom filter kept %d items and saw %v", len(got), seen)
+	}
+}
+
+func runMap(t testing.TB, perf *CollectionPerformance, item TransformNodeConfig, env *core.Envelope) ([]any, error) {
+	t.Helper()
+	node := NewTransformNode("map", TransformNodeConfig{
+		Transform:     TransformMap,
+		InputVar:      "items",
+		OutputVar:     "result",
+		ItemTransform: &item,
+		Performance:   perf,
+	})
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		return nil, err
+	}
+	return result.Vars["result"].([]any), nil
+}
+
+func TestTransformNode_PerformanceMapMatchesDefault(t *testing.T) {
+	env := core.NewEnvelope().
+		WithVar("items", syntheticItems(3000)).
+		WithVar("prefix", "doc")
+	env.Input = "query"
+
+	itemTransforms := map[string]TransformNodeConfig{
+		"pick":      {Transform: TransformPick, Fields: []string{"id", "meta.source", "missing.field"}},
+		"omit":      {Transform: TransformOmit, Fields: []string{"score", "meta.rank"}},
+		"rename":    {Transform: TransformRename, Mapping: map[string]string{"id": "doc.id", "meta.source": "source"}},
+		"flatten":   {Transform: TransformFlatten, Separator: "_"},
+		"stringify": {Transform: TransformStringify},
+		"template":  {Transform: TransformTemplate, Template: `{{.prefix}}:{{._item.id}}:{{._input}}:{{upper ._item.type}}`},
+	}
+	for name, item := range itemTransforms {
+		t.Run(name, func(t *testing.T) {
+			item.InputVar = "_item"
+			item.OutputVar = "out"
+			want, err := runMap(t, nil, item, env)
+			if err != nil {
+				t.Fatalf("default Run() error = %v", err)
+			}
+			for _, perf := range []*CollectionPerformance{{Parallelism: 1}, {Parallelism: 4, MinParallelItems: 10}} {
+				got, err := runMap(t, perf, item, env)
+				if err != nil {
+					t.Fatalf("performance Run() error = %v", err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Fatalf("parallelism %d: results differ from default mode", perf.Parallelism)
+				}
+			}
+		})
+	}
+}
+
+func TestTransformNode_PerformanceMapErrors(t *testing.T) {
+	env := core.NewEnvelope().WithVar("items", []any{`{"a":1}`, `{"a":`, "nope"})
+	item := TransformNodeConfig{Transform: TransformParse, InputVar: "_item", OutputVar: "out"}
+
+	_, want := runMap(t, nil, item, env)
+	_, got := runMap(t, &CollectionPerformance{Parallelism: 3, MinParallelItems: 1}, item, env)
+	if want == nil || got == nil || got.Error() != want.Error() {
+		t.Fatalf("performance error = %v, want %v", got, want)
+	}
+}
+
+func TestTransformNode_PerformanceMapFallsBack(t *testing.T) {
+	env := core.NewEnvelope().WithVar("items", []any{"a", "b"})
+	item := TransformNodeConfig{
+		Transform: TransformTemplate,
+		InputVar:  "_item",
+		OutputVar: "out",
+		Template:  `{{._item}}-{{len ._env.Vars}}`,
+	}
+	if (&TransformNode{config: item}).itemFuncSupported() {
+		t.Fatal("templates reading _env should use the per-item envelope")
+	}
+	got, err := runMap(t, &CollectionPerformance{}, item, env)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := []any{"a-2", "b-2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("results = %v, want %v", got, want)
+	}
+}
+
+func TestCollectionPerformanceWorkers(t *testing.T) {
+	var nilPerf *CollectionPerformance
+	if got := nilPerf.workers(1_000_000); got != 1 {
+		t.Fatalf("nil workers = %d, want 1", got)
+	}
+	perf := &CollectionPerformance{Parallelism: 8}
+	if got := perf.workers(DefaultMinParallelItems - 1); got != 1 {
+		t.Fatalf("workers below minimum = %d, want 1", got)
+	}
+	if got := perf.workers(DefaultMinParallelItems); got != 8 {
+		t.Fatalf("workers = %d, want 8", got)
+	}
+	perf = &CollectionPerformance{Parallelism: 8, MinParallelItems: 1}
+	if got := perf.workers(3); got != 3 {
+		t.Fatalf("workers for 3 items = %d, want 3", got)
+	}
+}
+
+func TestDedupeKeyMatchesSprintf(t *testing.T) {
+	for _, v := range []any{"x", 1.5, 1e21, 0.1 + 0.2, 42, int64(-7), true, nil, []any{1, "a"}, map[string]any{"k": 1}} {
+		if got, want := dedupeKey(v), fmt.Sprintf("%v", v); got != want {
+			t.Errorf("dedupeKey(%#v) = %q, want %q", v, got, want)
+		}
+	}
+}
+
+func BenchmarkFilterNode_LargeCollection(b *testing.B) {
+	items := syntheticItems(100_000)
+	ops := filterOps()
+	for _, bc := range []struct {
+		name string
+		perf *CollectionPerformance
+	}{
+		{"default", nil},
+		{"performance", &CollectionPerformance{Parallelism: 1}},
+		{"performance_parallel", &CollectionPerformance{}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				runFilter(b, bc.perf, ops, items)
+			}
+		})
+	}
+}
+
+func BenchmarkTransformNode_MapLargeCollection(b *testing.B) {
+	env := core.NewEnvelope().WithVar("items", syntheticItems(100_000))
+	item := TransformNodeConfig{
+		Transform: TransformPick,
+		InputVar:  "_item",
+		OutputVar: "out",
+		Fields:    []string{"id", "score", "meta.source"},
+	}
+	for _, bc := range []struct {
+		name string
+		perf *CollectionPerformance
+	}{
+		{"default", nil},
+		{"performance", &CollectionPerformance{Parallelism: 1}},
+		{"performance_parallel", &CollectionPerformance{}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := runMap(b, bc.perf, item, env); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// defaultCompactSummaryPrompt instructs the model when no SummaryPrompt is set.
+const defaultCompactSummaryPrompt = "Summarize the conversation below for an assistant that will continue it. " +
+	"Keep facts, decisions, open questions and user preferences. Be concise and do not invent details."
+
+// CompactMessagesNodeConfig configures a CompactMessagesNode.
+type CompactMessagesNodeConfig struct {
+	// MaxTokens is the estimated token budget for Envelope.Messages.
+	// Compaction only happens when the messages exceed it. Required.
+	MaxTokens int
+
+	// KeepLastTurns is the number of most recent user/assistant messages
+	// kept verbatim. Defaults to 4.
+	KeepLastTurns int
+
+	// KeepToolMessages keeps older "tool" role messages instead of dropping
+	// them before summarizing.
+	KeepToolMessages bool
+
+	// Model is the model used to write the summary.
+	Model string
+
+	// SummaryPrompt is the system prompt for the summary call.
+	SummaryPrompt string
+
+	// OutputKey stores a CompactMessagesResult. Defaults to "{id}_output".
+	OutputKey string
+
+	// TokenEstimator estimates a message's token count. Defaults to
+	// EstimateMessageTokens.
+	TokenEstimator func(core.Message) int
+
+	// RetryPolicy configures retries for the summary call.
+	RetryPolicy core.RetryPolicy
+
+	// Timeout bounds the summary call. Defaults to 60s.
+	Timeout time.Duration
+}
+
+// CompactMessagesResult reports what a CompactMessagesNode did.
+type CompactMessagesResult struct {
+	Compacted    bool `json:"compacted"`
+	TokensBefore int  `json:"tokens_before"`
+	TokensAfter  int  `json:"tokens_after"`
+	DroppedTool  int  `json:"dropped_tool_messages"`
+	Summarized   int  `json:"summarized_messages"`
+	OverBudget   bool `json:"over_budget,omitempty"`
+}
+
+// CompactMessagesNode keeps Envelope.Messages under a token budget so long
+// chat sessions fit the model's context window. Leading system messages
+// and the last KeepLastTurns user/assistant turns are kept verbatim; older
+// tool messages are dropped and the remaining older turns are replaced by a
+// single LLM-written summary message.
+type CompactMessagesNode struct {
+	core.BaseNode
+	config CompactMessagesNodeConfig
+	client core.LLMClient
+}
+
+// NewCompactMessagesNode creates a new CompactMessagesNode.
+func NewCompactMessagesNode(id string, client core.LLMClient, config CompactMessagesNodeConfig) *CompactMessagesNode {
+	if config.KeepLastTurns <= 0 {
+		config.KeepLastTurns = 4
+	}
+	if config.SummaryPrompt == "" {
+		config.SummaryPrompt = defaultCompactSummaryPrompt
+	}
+	if config.OutputKey == "" {
+		config.OutputKey = id + "_output"
+	}
+	if config.TokenEstimator == nil {
+		config.TokenEstimator = EstimateMessageTokens
+	}
+	if config.RetryPolicy.MaxAttempts == 0 {
+		config.RetryPolicy = core.DefaultRetryPolicy()
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 60 * time.Second
+	}
+
+	return &CompactMessagesNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindLLM),
+		config:   config,
+		client:   client,
+	}
+}
+
+// Config returns the node's configuration.
+func (n *CompactMessagesNode) Config() CompactMessagesNodeConfig {
+	return n.config
+}
+
+// EstimateMessageTokens approximates a message's token count at four
+// characters per token plus a small per-message overhead.
+func EstimateMessageTokens(m core.Message) int {
+	return (len(m.Role)+len(m.Name)+len(m.Content))/4 + 4
+}
+
+func (n *CompactMessagesNode) tokens(msgs []core.Message) int {
+	total := 0
+	for _, m := range msgs {
+		total += n.config.TokenEstimator(m)
+	}
+	return total
+}
+
+// Run compacts env.Messages when they exceed the budget.
+func (n *CompactMessagesNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	if n.config.MaxTokens <= 0 {
+		return nil, fmt.Errorf("compact_messages node %s: max_tokens must be positive", n.ID())
+	}
+
+	result := CompactMessagesResult{TokensBefore: n.tokens(env.Messages)}
+	if result.TokensBefore <= n.config.MaxTokens {
+		result.TokensAfter = result.TokensBefore
+		env.SetVar(n.config.OutputKey, result)
+		return env, nil
+	}
+
+	head, older, tail := n.split(env.Messages)
+
+	var kept []core.Message
+	for _, m := range older {
+		if m.Role == "tool" && !n.config.KeepToolMessages {
+			result.DroppedTool++
+			continue
+		}
+		kept = append(kept, m)
+	}
+	older = kept
+
+	compacted := joinMessages(head, older, tail)
+	if n.tokens(compacted) > n.config.MaxTokens && len(older) > 0 {
+		summary, err := n.summarize(ctx, older)
+		if err != nil {
+			return nil, fmt.Errorf("compact_messages node %s: %w", n.ID(), err)
+		}
+		result.Summarized = len(older)
+		compacted = joinMessages(head, []core.Message{summary}, tail)
+	}
+
+	result.Compacted = result.DroppedTool > 0 || result.Summarized > 0
+	result.TokensAfter = n.tokens(compacted)
+	result.OverBudget = result.TokensAfter > n.config.MaxTokens
+
+	env.Messages = compacted
+	env.SetVar(n.config.OutputKey, result)
+	return env, nil
+}
+
+// split returns the leading system messages, the older messages eligible
+// for compaction and the recent tail kept verbatim.
+func (n *CompactMessagesNode) split(msgs []core.Message) (head, older, tail []core.Message) {
+	start := 0
+	for start < len(msgs) && msgs[start].Role == "system" {
+		start++
+	}
+
+	cut := len(msgs)
+	turns := 0
+	for i := len(msgs) - 1; i >= start && turns < n.config.KeepLastTurns; i-- {
+		if msgs[i].Role == "user" || msgs[i].Role == "assistant" {
+			turns++
+			cut = i
+		}
+	}
+	return msgs[:start], msgs[start:cut], msgs[cut:]
+}
+
+func joinMessages(parts ...[]core.Message) []core.Message {
+	var out []core.Message
+	for _, p := range parts {
+		out = append(out, p...)
+	}
+	return out
+}
+
+// summarize asks the LLM to condense msgs into a single system message.
+func (n *CompactMessagesNode) summarize(ctx context.Context, msgs []core.Message) (core.Message, error) {
+	if n.client == nil {
+		return core.Message{}, fmt.Errorf("no LLM client configured for summary")
+	}
+	if n.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.Timeout)
+		defer cancel()
+	}
+
+	var transcript strings.Builder
+	for _, m := range msgs {
+		role := m.Role
+		if m.Name != "" {
+			role += " (" + m.Name + ")"
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n", role, m.Content)
+	}
+	req := core.LLMRequest{
+		Model:     n.config.Model,
+		System:    n.config.SummaryPrompt,
+		InputText: transcript.String(),
+	}
+
+	var resp core.LLMResponse
+	var err error
+	for attempt := 1; attempt <= n.config.RetryPolicy.MaxAttempts; attempt++ {
+		resp, err = n.client.Complete(ctx, req)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return core.Message{}, ctx.Err()
+		}
+		if attempt < n.config.RetryPolicy.MaxAttempts {
+			select {
+			case <-ctx.Done():
+				return core.Message{}, ctx.Err()
+			case <-time.After(n.config.RetryPolicy.Backoff * time.Duration(attempt)):
+			}
+		}
+	}
+	if err != nil {
+		return core.Message{}, fmt.Errorf("summary call failed after %d attempts: %w", n.config.RetryPolicy.MaxAttempts, err)
+	}
+
+	return core.Message{
+		Role:    "system",
+		Name:    "summary",
+		Content: "Summary of earlier conversation: " + strings.TrimSpace(resp.Text),
+		Meta: map[string]any{
+			"compacted":           true,
+			"summarized_messages": len(msgs),
+		},
+	}, nil
+}
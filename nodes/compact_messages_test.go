@@ -0,0 +1,127 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// countTokens treats every message as one token per content character.
+func countTokens(m core.Message) int { return len(m.Content) }
+
+func chatEnvelope() *core.Envelope {
+	env := core.NewEnvelope()
+	env.Messages = []core.Message{
+		{Role: "system", Content: "be nice"},
+		{Role: "user", Content: "hello there, I need help finding my order from last week"},
+		{Role: "assistant", Content: "calling lookup"},
+		{Role: "tool", Name: "lookup", Content: strings.Repeat("x", 200)},
+		{Role: "assistant", Content: "found it"},
+		{Role: "user", Content: "thanks"},
+		{Role: "assistant", Content: "anything else?"},
+	}
+	return env
+}
+
+func TestCompactMessagesNode_UnderBudgetIsUntouched(t *testing.T) {
+	client := &mockLLMClient{}
+	node := NewCompactMessagesNode("compact", client, CompactMessagesNodeConfig{MaxTokens: 1000, TokenEstimator: countTokens})
+
+	env := chatEnvelope()
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(result.Messages) != 7 || len(client.requests) != 0 {
+		t.Errorf("messages = %d, requests = %d; want untouched", len(result.Messages), len(client.requests))
+	}
+	out, _ := result.GetVar("compact_output")
+	if r := out.(CompactMessagesResult); r.Compacted || r.TokensBefore != r.TokensAfter {
+		t.Errorf("result = %+v", r)
+	}
+}
+
+func TestCompactMessagesNode_DropsToolNoiseFirst(t *testing.T) {
+	client := &mockLLMClient{}
+	node := NewCompactMessagesNode("compact", client, CompactMessagesNodeConfig{
+		MaxTokens:      120,
+		KeepLastTurns:  2,
+		TokenEstimator: countTokens,
+	})
+
+	result, err := node.Run(context.Background(), chatEnvelope())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(client.requests) != 0 {
+		t.Error("dropping tool messages was enough; no summary expected")
+	}
+	for _, m := range result.Messages {
+		if m.Role == "tool" {
+			t.Fatalf("tool message should have been dropped: %+v", result.Messages)
+		}
+	}
+	out, _ := result.GetVar("compact_output")
+	if r := out.(CompactMessagesResult); !r.Compacted || r.DroppedTool != 1 || r.Summarized != 0 {
+		t.Errorf("result = %+v", r)
+	}
+}
+
+func TestCompactMessagesNode_SummarizesOlderTurns(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{Text: "user greeted; lookup succeeded"}}
+	node := NewCompactMessagesNode("compact", client, CompactMessagesNodeConfig{
+		MaxTokens:      95,
+		KeepLastTurns:  2,
+		Model:          "small",
+		TokenEstimator: countTokens,
+	})
+
+	result, err := node.Run(context.Background(), chatEnvelope())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	msgs := result.Messages
+	if len(msgs) != 4 {
+		t.Fatalf("messages = %+v, want system, summary and two recent turns", msgs)
+	}
+	if msgs[0].Content != "be nice" {
+		t.Errorf("leading system message not preserved: %+v", msgs[0])
+	}
+	if msgs[1].Name != "summary" || !strings.Contains(msgs[1].Content, "lookup succeeded") {
+		t.Errorf("summary message = %+v", msgs[1])
+	}
+	if msgs[2].Content != "thanks" || msgs[3].Content != "anything else?" {
+		t.Errorf("recent turns not kept verbatim: %+v", msgs[2:])
+	}
+
+	if len(client.requests) != 1 {
+		t.Fatalf("requests = %d, want 1", len(client.requests))
+	}
+	req := client.requests[0]
+	if req.Model != "small" || !strings.Contains(req.InputText, "user: hello there") || strings.Contains(req.InputText, "xxxx") {
+		t.Errorf("summary request = %+v", req)
+	}
+
+	out, _ := result.GetVar("compact_output")
+	if r := out.(CompactMessagesResult); r.Summarized != 3 || r.DroppedTool != 1 || r.OverBudget {
+		t.Errorf("result = %+v", r)
+	}
+}
+
+func TestCompactMessagesNode_SummaryError(t *testing.T) {
+	client := &mockLLMClient{err: errors.New("provider down")}
+	node := NewCompactMessagesNode("compact", client, CompactMessagesNodeConfig{
+		MaxTokens:      10,
+		TokenEstimator: countTokens,
+		RetryPolicy:    core.RetryPolicy{MaxAttempts: 1},
+	})
+
+	_, err := node.Run(context.Background(), chatEnvelope())
+	if err == nil || !strings.Contains(err.Error(), "provider down") {
+		t.Fatalf("error = %v, want provider error", err)
+	}
+}
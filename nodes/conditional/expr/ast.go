@@ -2,7 +2,10 @@
 // routing in PetalFlow graphs. Expressions are stateless and side-effect-free.
 package expr
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Expr is the interface implemented by all AST nodes.
 type Expr interface {
@@ -78,6 +81,21 @@ func (e *IndexExpr) String() string {
 	return fmt.Sprintf("%s[%s]", e.Object, e.Index)
 }
 
+// CallExpr represents a call of a built-in function (e.g. hour("UTC")).
+type CallExpr struct {
+	Name string
+	Args []Expr
+}
+
+func (e *CallExpr) expr() {}
+func (e *CallExpr) String() string {
+	args := make([]string, len(e.Args))
+	for i, arg := range e.Args {
+		args[i] = arg.String()
+	}
+	return fmt.Sprintf("%s(%s)", e.Name, strings.Join(args, ", "))
+}
+
 // ArrayLiteral represents an inline array (e.g. ["a", "b"]).
 type ArrayLiteral struct {
 	Elements []Expr
@@ -10,13 +10,28 @@ import (
 
 // Eval evaluates a parsed expression against a variable map.
 // The vars map is the top-level namespace (e.g. {"input": {...}}).
+// Time functions read the system clock and no day is a holiday.
 func Eval(e Expr, vars map[string]any) (any, error) {
-	ev := &evaluator{vars: vars}
+	return EvalEnv(e, vars, Env{})
+}
+
+// EvalEnv evaluates a parsed expression like Eval, with env supplying the
+// clock and holiday calendars of the time functions.
+func EvalEnv(e Expr, vars map[string]any, env Env) (any, error) {
+	ev := &evaluator{vars: vars, env: env}
 	return ev.eval(e)
 }
 
+// Lookup is a value whose members are resolved when they are accessed,
+// such as stored workflow state. Member and string index access on a
+// Lookup call it with the member name; a nil result reads as undefined.
+type Lookup interface {
+	Lookup(key string) (any, error)
+}
+
 type evaluator struct {
 	vars map[string]any
+	env  Env
 }
 
 func (ev *evaluator) eval(e Expr) (any, error) {
@@ -60,6 +75,9 @@ func (ev *evaluator) eval(e Expr) (any, error) {
 		}
 		return result, nil
 
+	case *CallExpr:
+		return ev.evalCall(n)
+
 	case *UnaryExpr:
 		return ev.evalUnary(n)
 
@@ -277,6 +295,9 @@ func accessMember(obj any, prop string) (any, error) {
 	if obj == nil {
 		return nil, nil
 	}
+	if l, ok := obj.(Lookup); ok {
+		return l.Lookup(prop)
+	}
 
 	// Special built-in: .length
 	if prop == "length" {
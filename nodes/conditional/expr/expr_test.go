@@ -1297,6 +1297,31 @@ func TestEval_LengthOnArray(t *testing.T) {
 	assertFloat64(t, "arr.length", result, 3)
 }
 
+type lookupFunc func(key string) (any, error)
+
+func (f lookupFunc) Lookup(key string) (any, error) { return f(key) }
+
+func TestEval_Lookup(t *testing.T) {
+	var keys []string
+	vars := map[string]any{
+		"state": lookupFunc(func(key string) (any, error) {
+			keys = append(keys, key)
+			if key == "broken" {
+				return nil, fmt.Errorf("store unavailable")
+			}
+			return float64(len(key)), nil
+		}),
+	}
+	assertBool(t, "member", evalExpr(t, "state.sent < 5", vars), true)
+	assertFloat64(t, "index", evalExpr(t, `state["emails:42"]`, vars), 9)
+	if len(keys) != 2 || keys[0] != "sent" || keys[1] != "emails:42" {
+		t.Fatalf("looked up %v", keys)
+	}
+	if _, err := evalExprErr(t, "state.broken", vars); err == nil || !strings.Contains(err.Error(), "store unavailable") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
 func TestEval_LengthOnString(t *testing.T) {
 	vars := map[string]any{
 		"s": "hello",
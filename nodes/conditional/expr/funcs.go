@@ -0,0 +1,203 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCalendar is the holiday calendar isHoliday uses when it is not
+// given one.
+const DefaultCalendar = "default"
+
+// Holidays are holiday calendars by name. Each lists its days as
+// "2006-01-02" for a single date or "01-02" for a date every year.
+type Holidays map[string][]string
+
+// Validate checks that every day is in one of the accepted formats.
+func (h Holidays) Validate() error {
+	for name, days := range h {
+		for _, day := range days {
+			if _, err := time.Parse("2006-01-02", day); err == nil {
+				continue
+			}
+			if _, err := time.Parse("01-02", day); err == nil {
+				continue
+			}
+			return fmt.Errorf("holiday calendar %q: %q must be YYYY-MM-DD or MM-DD", name, day)
+		}
+	}
+	return nil
+}
+
+// Merge returns h with the calendars of other added. Days of a calendar in
+// both are combined.
+func (h Holidays) Merge(other Holidays) Holidays {
+	if len(other) == 0 {
+		return h
+	}
+	merged := make(Holidays, len(h)+len(other))
+	for name, days := range h {
+		merged[name] = append([]string(nil), days...)
+	}
+	for name, days := range other {
+		merged[name] = append(merged[name], days...)
+	}
+	return merged
+}
+
+// contains reports whether t's date is a holiday in the named calendar.
+func (h Holidays) contains(name string, t time.Time) (bool, error) {
+	days, ok := h[name]
+	if !ok {
+		if name == DefaultCalendar {
+			return false, nil
+		}
+		return false, fmt.Errorf("unknown holiday calendar %q", name)
+	}
+	date, yearly := t.Format("2006-01-02"), t.Format("01-02")
+	for _, day := range days {
+		if day == date || day == yearly {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Env supplies the clock and holiday calendars of the time functions.
+type Env struct {
+	// Now returns the current time. Nil uses time.Now.
+	Now func() time.Time
+	// Holidays are the calendars isHoliday looks days up in.
+	Holidays Holidays
+}
+
+func (env Env) now() time.Time {
+	if env.Now != nil {
+		return env.Now()
+	}
+	return time.Now()
+}
+
+// builtin is a function expressions may call.
+type builtin struct {
+	minArgs, maxArgs int
+	call             func(env Env, args []any) (any, error)
+}
+
+func (b builtin) arity() string {
+	switch {
+	case b.minArgs == b.maxArgs && b.maxArgs == 0:
+		return "no arguments"
+	case b.minArgs == b.maxArgs:
+		return fmt.Sprintf("%d arguments", b.maxArgs)
+	default:
+		return fmt.Sprintf("%d to %d arguments", b.minArgs, b.maxArgs)
+	}
+}
+
+// builtins are the functions expressions may call. The time functions take
+// an optional IANA time zone name, defaulting to UTC.
+var builtins = map[string]builtin{
+	// now returns the current time in Unix seconds.
+	"now": {0, 0, func(env Env, _ []any) (any, error) {
+		return float64(env.now().UnixNano()) / 1e9, nil
+	}},
+	// hour returns the hour of the day, 0 to 23.
+	"hour": {0, 1, func(env Env, args []any) (any, error) {
+		return localTime(env, args, func(t time.Time) any { return float64(t.Hour()) })
+	}},
+	// minute returns the minute of the hour, 0 to 59.
+	"minute": {0, 1, func(env Env, args []any) (any, error) {
+		return localTime(env, args, func(t time.Time) any { return float64(t.Minute()) })
+	}},
+	// weekday returns the lowercase day name, e.g. "saturday".
+	"weekday": {0, 1, func(env Env, args []any) (any, error) {
+		return localTime(env, args, func(t time.Time) any { return strings.ToLower(t.Weekday().String()) })
+	}},
+	// isWeekend reports whether it is Saturday or Sunday.
+	"isWeekend": {0, 1, func(env Env, args []any) (any, error) {
+		return localTime(env, args, func(t time.Time) any {
+			return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+		})
+	}},
+	// date returns the date as "2006-01-02".
+	"date": {0, 1, func(env Env, args []any) (any, error) {
+		return localTime(env, args, func(t time.Time) any { return t.Format("2006-01-02") })
+	}},
+	// isHoliday reports whether today is in a holiday calendar, the
+	// default calendar unless a second argument names one.
+	"isHoliday": {0, 2, func(env Env, args []any) (any, error) {
+		t, err := zonedNow(env, args)
+		if err != nil {
+			return nil, err
+		}
+		name := DefaultCalendar
+		if len(args) > 1 {
+			s, ok := args[1].(string)
+			if !ok {
+				return nil, fmt.Errorf("isHoliday: calendar must be a string, got %T", args[1])
+			}
+			name = s
+		}
+		return env.Holidays.contains(name, t)
+	}},
+}
+
+func localTime(env Env, args []any, field func(time.Time) any) (any, error) {
+	t, err := zonedNow(env, args)
+	if err != nil {
+		return nil, err
+	}
+	return field(t), nil
+}
+
+// zonedNow returns the current time in the zone named by args[0].
+func zonedNow(env Env, args []any) (time.Time, error) {
+	name := "UTC"
+	if len(args) > 0 && args[0] != nil {
+		s, ok := args[0].(string)
+		if !ok {
+			return time.Time{}, fmt.Errorf("time zone must be a string, got %T", args[0])
+		}
+		if s != "" {
+			name = s
+		}
+	}
+	loc, err := loadLocation(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return env.now().In(loc), nil
+}
+
+var locations sync.Map // name -> *time.Location
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+func (ev *evaluator) evalCall(n *CallExpr) (any, error) {
+	fn, ok := builtins[n.Name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", n.Name)
+	}
+	args := make([]any, len(n.Args))
+	for i, arg := range n.Args {
+		val, err := ev.eval(arg)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = val
+	}
+	return fn.call(ev.env, args)
+}
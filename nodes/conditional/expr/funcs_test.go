@@ -0,0 +1,130 @@
+package expr
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func evalAt(t *testing.T, input string, now time.Time, holidays Holidays) any {
+	t.Helper()
+	e, err := Parse(input)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", input, err)
+	}
+	got, err := EvalEnv(e, nil, Env{Now: func() time.Time { return now }, Holidays: holidays})
+	if err != nil {
+		t.Fatalf("EvalEnv(%q): %v", input, err)
+	}
+	return got
+}
+
+func TestTimeFunctions(t *testing.T) {
+	// Saturday 2025-12-27 22:30 UTC is 17:30 in New York and Sunday 07:30
+	// in Tokyo.
+	now := time.Date(2025, 12, 27, 22, 30, 0, 0, time.UTC)
+	tests := []struct {
+		input string
+		want  any
+	}{
+		{"now()", float64(now.Unix())},
+		{"now() > 1700000000", true},
+		{"hour()", float64(22)},
+		{`hour("Asia/Tokyo")`, float64(7)},
+		{`minute("UTC")`, float64(30)},
+		{"weekday()", "saturday"},
+		{`weekday("Asia/Tokyo")`, "sunday"},
+		{`isWeekend("America/New_York")`, true},
+		{`date("Asia/Tokyo")`, "2025-12-28"},
+		{`hour("America/New_York") >= 9 && hour("America/New_York") < 17`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := evalAt(t, tt.input, now, nil); got != tt.want {
+				t.Errorf("got %v (%T), want %v (%T)", got, got, tt.want, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsHoliday(t *testing.T) {
+	now := time.Date(2025, 12, 25, 10, 0, 0, 0, time.UTC)
+	holidays := Holidays{
+		DefaultCalendar: {"12-25", "2026-01-01"},
+		"uk":            {"2025-12-26"},
+	}
+	tests := []struct {
+		input string
+		now   time.Time
+		want  bool
+	}{
+		{"isHoliday()", now, true},
+		{`isHoliday("UTC", "uk")`, now, false},
+		{`isHoliday("Pacific/Kiritimati", "uk")`, now, true},
+		{"isHoliday()", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"isHoliday()", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), false},
+		{"isHoliday()", time.Date(2030, 12, 25, 0, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := evalAt(t, tt.input, tt.now, holidays); got != tt.want {
+			t.Errorf("%s at %s = %v, want %v", tt.input, tt.now, got, tt.want)
+		}
+	}
+
+	if got := evalAt(t, "isHoliday()", now, nil); got != false {
+		t.Errorf("isHoliday() without calendars = %v, want false", got)
+	}
+	e, _ := Parse(`isHoliday("UTC", "fr")`)
+	if _, err := EvalEnv(e, nil, Env{Holidays: holidays}); err == nil || !strings.Contains(err.Error(), `unknown holiday calendar "fr"`) {
+		t.Errorf("unknown calendar error = %v", err)
+	}
+}
+
+func TestCallErrors(t *testing.T) {
+	for input, want := range map[string]string{
+		"later()":         `unknown function "later"`,
+		"now(1)":          "function now takes no arguments",
+		`hour("UTC", 1)`:  "function hour takes 0 to 1 arguments",
+		"isHoliday(1, 2,": "",
+	} {
+		_, err := Parse(input)
+		if err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", input)
+			continue
+		}
+		if want != "" && !strings.Contains(err.Error(), want) {
+			t.Errorf("Parse(%q) error = %v, want %q", input, err, want)
+		}
+	}
+
+	for input, want := range map[string]string{
+		`hour("Mars/Olympus")`: `unknown time zone "Mars/Olympus"`,
+		"hour(3)":              "time zone must be a string",
+	} {
+		e, err := Parse(input)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", input, err)
+		}
+		if _, err := Eval(e, nil); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Eval(%q) error = %v, want %q", input, err, want)
+		}
+	}
+}
+
+func TestHolidays_ValidateAndMerge(t *testing.T) {
+	if err := (Holidays{"us": {"2025-07-04", "12-25"}}).Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+	if err := (Holidays{"us": {"July 4"}}).Validate(); err == nil || !strings.Contains(err.Error(), `"July 4"`) {
+		t.Errorf("Validate() = %v, want date format error", err)
+	}
+
+	base := Holidays{"us": {"07-04"}}
+	merged := base.Merge(Holidays{"us": {"12-25"}, "uk": {"12-26"}})
+	if len(merged["us"]) != 2 || len(merged["uk"]) != 1 {
+		t.Errorf("Merge() = %v", merged)
+	}
+	if len(base["us"]) != 1 {
+		t.Errorf("Merge() modified its receiver: %v", base)
+	}
+}
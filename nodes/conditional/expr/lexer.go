@@ -147,7 +147,7 @@ func (l *Lexer) lexAll() error {
 		case isIdentStart(ch):
 			l.lexIdent()
 		default:
-			return fmt.Errorf("unexpected character %q at position %d", string(ch), l.pos)
+			return syntaxErrorf(l.pos, "unexpected character %q", string(ch))
 		}
 	}
 }
@@ -238,7 +238,7 @@ func (l *Lexer) lexString() error {
 		if ch == '\\' {
 			l.pos++
 			if l.pos >= len(l.src) {
-				return fmt.Errorf("unterminated string at position %d", start)
+				return syntaxErrorf(start, "unterminated string")
 			}
 			esc := l.src[l.pos]
 			switch esc {
@@ -270,7 +270,7 @@ func (l *Lexer) lexString() error {
 		l.pos++
 	}
 
-	return fmt.Errorf("unterminated string at position %d", start)
+	return syntaxErrorf(start, "unterminated string")
 }
 
 func (l *Lexer) lexNumber() {
@@ -1,9 +1,6 @@
 package expr
 
-import (
-	"fmt"
-	"strconv"
-)
+import "strconv"
 
 // Parse parses an expression string into an AST.
 func Parse(input string) (Expr, error) {
@@ -17,7 +14,7 @@ func Parse(input string) (Expr, error) {
 		return nil, err
 	}
 	if p.current().Kind != TokenEOF {
-		return nil, fmt.Errorf("unexpected token %s at position %d", p.current().Kind, p.current().Pos)
+		return nil, syntaxErrorf(p.current().Pos, "unexpected token %s", p.current().Kind)
 	}
 	return expr, nil
 }
@@ -45,7 +42,7 @@ func (p *parser) advance() Token {
 func (p *parser) expect(kind TokenKind) (Token, error) {
 	tok := p.current()
 	if tok.Kind != kind {
-		return tok, fmt.Errorf("expected %s but got %s at position %d", kind, tok.Kind, tok.Pos)
+		return tok, syntaxErrorf(tok.Pos, "expected %s but got %s", kind, tok.Kind)
 	}
 	p.advance()
 	return tok, nil
@@ -223,7 +220,7 @@ func (p *parser) parsePrimary() (Expr, error) {
 		p.advance()
 		val, err := strconv.ParseFloat(tok.Value, 64)
 		if err != nil {
-			return nil, fmt.Errorf("invalid number %q at position %d", tok.Value, tok.Pos)
+			return nil, syntaxErrorf(tok.Pos, "invalid number %q", tok.Value)
 		}
 		return &LiteralExpr{Value: val}, nil
 
@@ -245,6 +242,9 @@ func (p *parser) parsePrimary() (Expr, error) {
 
 	case TokenIdent:
 		p.advance()
+		if p.current().Kind == TokenLParen {
+			return p.parseCall(tok)
+		}
 		return &IdentExpr{Name: tok.Value}, nil
 
 	case TokenLParen:
@@ -262,8 +262,39 @@ func (p *parser) parsePrimary() (Expr, error) {
 		return p.parseArrayLiteral()
 
 	default:
-		return nil, fmt.Errorf("unexpected token %s at position %d", tok.Kind, tok.Pos)
+		return nil, syntaxErrorf(tok.Pos, "unexpected token %s", tok.Kind)
+	}
+}
+
+// parseCall parses the arguments of a call of the function named by tok.
+// Unknown functions and wrong argument counts are parse errors.
+func (p *parser) parseCall(tok Token) (Expr, error) {
+	fn, ok := builtins[tok.Value]
+	if !ok {
+		return nil, syntaxErrorf(tok.Pos, "unknown function %q", tok.Value)
+	}
+	p.advance() // skip (
+	call := &CallExpr{Name: tok.Value}
+	if p.current().Kind != TokenRParen {
+		for {
+			arg, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, arg)
+			if p.current().Kind != TokenComma {
+				break
+			}
+			p.advance() // skip comma
+		}
+	}
+	if _, err := p.expect(TokenRParen); err != nil {
+		return nil, err
+	}
+	if len(call.Args) < fn.minArgs || len(call.Args) > fn.maxArgs {
+		return nil, syntaxErrorf(tok.Pos, "function %s takes %s", tok.Value, fn.arity())
 	}
+	return call, nil
 }
 
 func (p *parser) parseArrayLiteral() (Expr, error) {
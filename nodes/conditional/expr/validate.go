@@ -1,8 +1,25 @@
 package expr
 
+import "fmt"
+
 // ValidateSyntax checks whether an expression string is syntactically valid.
 // Returns nil if valid, or a parse error describing the problem.
 func ValidateSyntax(expression string) error {
 	_, err := Parse(expression)
 	return err
 }
+
+// SyntaxError reports where an expression failed to lex or parse.
+type SyntaxError struct {
+	// Pos is the byte offset of the offending token in the expression.
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+func syntaxErrorf(pos int, format string, args ...any) error {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
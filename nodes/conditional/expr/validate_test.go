@@ -0,0 +1,29 @@
+package expr
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSyntaxError_Position(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{`a == "open`, 5, "unterminated string"},
+		{"a == b)", 6, "unexpected token )"},
+		{"a # b", 2, `unexpected character "#"`},
+		{"nope(1)", 0, `unknown function "nope"`},
+	}
+	for _, tt := range tests {
+		err := ValidateSyntax(tt.input)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Fatalf("%q: err = %v, want *SyntaxError", tt.input, err)
+		}
+		if syntaxErr.Pos != tt.pos || syntaxErr.Msg != tt.msg {
+			t.Errorf("%q: got %d %q, want %d %q", tt.input, syntaxErr.Pos, syntaxErr.Msg, tt.pos, tt.msg)
+		}
+	}
+}
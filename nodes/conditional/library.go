@@ -0,0 +1,301 @@
+package conditional
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+)
+
+// Parameter types accepted by Param.Type.
+const (
+	ParamTypeString  = "string"
+	ParamTypeNumber  = "number"
+	ParamTypeBoolean = "boolean"
+	ParamTypeArray   = "array"
+	ParamTypeAny     = "any"
+)
+
+var definitionNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Definition is a named, reusable condition. Its expression can use each
+// declared parameter as a top-level identifier; parameters shadow envelope
+// variables of the same name.
+type Definition struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Expression  string    `json:"expression"`
+	Params      []Param   `json:"params,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Param declares a parameter of a Definition.
+type Param struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     any    `json:"default,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Validate checks the definition's name, expression and parameters.
+func (d Definition) Validate() error {
+	if !definitionNamePattern.MatchString(d.Name) {
+		return fmt.Errorf("condition name %q must start with a letter or underscore and contain only letters, digits and underscores", d.Name)
+	}
+	if strings.TrimSpace(d.Expression) == "" {
+		return fmt.Errorf("condition %q: expression is required", d.Name)
+	}
+	if err := expr.ValidateSyntax(d.Expression); err != nil {
+		return fmt.Errorf("condition %q: %w", d.Name, err)
+	}
+	seen := make(map[string]bool, len(d.Params))
+	for _, p := range d.Params {
+		if !definitionNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("condition %q: invalid parameter name %q", d.Name, p.Name)
+		}
+		if p.Name == "input" {
+			return fmt.Errorf("condition %q: parameter name \"input\" is reserved", d.Name)
+		}
+		if seen[p.Name] {
+			return fmt.Errorf("condition %q: duplicate parameter %q", d.Name, p.Name)
+		}
+		seen[p.Name] = true
+		switch p.Type {
+		case "", ParamTypeString, ParamTypeNumber, ParamTypeBoolean, ParamTypeArray, ParamTypeAny:
+		default:
+			return fmt.Errorf("condition %q: parameter %q has unknown type %q", d.Name, p.Name, p.Type)
+		}
+		if p.Default != nil {
+			if p.Required {
+				return fmt.Errorf("condition %q: parameter %q is required and cannot have a default", d.Name, p.Name)
+			}
+			if err := checkParamType(p, p.Default); err != nil {
+				return fmt.Errorf("condition %q: default: %w", d.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Ref is a parsed reference to a library condition, for example
+// business_hours(tz: "UTC", start: 9).
+type Ref struct {
+	Name string
+	Args map[string]any
+}
+
+// ParseRef parses a condition reference. The parentheses are optional when
+// no arguments are passed. Argument values are expression literals: strings,
+// numbers, booleans, null and arrays of those.
+func ParseRef(ref string) (Ref, error) {
+	ref = strings.TrimSpace(ref)
+	name, rest, hasArgs := strings.Cut(ref, "(")
+	name = strings.TrimSpace(name)
+	if !definitionNamePattern.MatchString(name) {
+		return Ref{}, fmt.Errorf("invalid condition reference %q", ref)
+	}
+	out := Ref{Name: name, Args: map[string]any{}}
+	if !hasArgs {
+		return out, nil
+	}
+	rest = strings.TrimSpace(rest)
+	if !strings.HasSuffix(rest, ")") {
+		return Ref{}, fmt.Errorf("condition reference %q: missing closing parenthesis", ref)
+	}
+	body := strings.TrimSpace(strings.TrimSuffix(rest, ")"))
+	if body == "" {
+		return out, nil
+	}
+
+	args, err := splitTopLevel(body, ',')
+	if err != nil {
+		return Ref{}, fmt.Errorf("condition reference %q: %w", ref, err)
+	}
+	for _, arg := range args {
+		parts, err := splitTopLevel(arg, ':')
+		if err != nil {
+			return Ref{}, fmt.Errorf("condition reference %q: %w", ref, err)
+		}
+		if len(parts) != 2 {
+			return Ref{}, fmt.Errorf("condition reference %q: argument %q must be name: value", ref, strings.TrimSpace(arg))
+		}
+		key := strings.TrimSpace(parts[0])
+		if !definitionNamePattern.MatchString(key) {
+			return Ref{}, fmt.Errorf("condition reference %q: invalid argument name %q", ref, key)
+		}
+		if _, dup := out.Args[key]; dup {
+			return Ref{}, fmt.Errorf("condition reference %q: duplicate argument %q", ref, key)
+		}
+		parsed, err := expr.Parse(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return Ref{}, fmt.Errorf("condition reference %q: argument %q: %w", ref, key, err)
+		}
+		value, err := literalValue(parsed)
+		if err != nil {
+			return Ref{}, fmt.Errorf("condition reference %q: argument %q: %w", ref, key, err)
+		}
+		out.Args[key] = value
+	}
+	return out, nil
+}
+
+// splitTopLevel splits s on sep, ignoring separators inside string literals
+// and brackets.
+func splitTopLevel(s string, sep byte) ([]string, error) {
+	var parts []string
+	depth, start := 0, 0
+	inString, escaped := false, false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case inString:
+			if escaped {
+				escaped = false
+			} else if ch == '\\' {
+				escaped = true
+			} else if ch == '"' {
+				inString = false
+			}
+		case ch == '"':
+			inString = true
+		case ch == '[' || ch == '(':
+			depth++
+		case ch == ']' || ch == ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced %q", ch)
+			}
+		case ch == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	if inString {
+		return nil, fmt.Errorf("unterminated string")
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced brackets")
+	}
+	return append(parts, s[start:]), nil
+}
+
+func literalValue(e expr.Expr) (any, error) {
+	switch n := e.(type) {
+	case *expr.LiteralExpr:
+		return n.Value, nil
+	case *expr.ArrayLiteral:
+		out := make([]any, 0, len(n.Elements))
+		for _, el := range n.Elements {
+			v, err := literalValue(el)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, v)
+		}
+		return out, nil
+	default:
+		return nil, fmt.Errorf("value must be a literal, got %s", e)
+	}
+}
+
+// Library resolves condition references against a set of definitions.
+type Library struct {
+	defs map[string]Definition
+}
+
+// NewLibrary creates a library from validated definitions.
+func NewLibrary(defs []Definition) (*Library, error) {
+	lib := &Library{defs: make(map[string]Definition, len(defs))}
+	for _, d := range defs {
+		if err := d.Validate(); err != nil {
+			return nil, err
+		}
+		if _, dup := lib.defs[d.Name]; dup {
+			return nil, fmt.Errorf("duplicate condition %q", d.Name)
+		}
+		lib.defs[d.Name] = d
+	}
+	return lib, nil
+}
+
+// Resolve parses ref and returns the referenced expression with its bound
+// parameters. Unknown conditions, unknown or missing arguments and values
+// of the wrong type are errors; omitted optional parameters take their
+// defaults.
+func (l *Library) Resolve(ref string) (string, map[string]any, error) {
+	parsed, err := ParseRef(ref)
+	if err != nil {
+		return "", nil, err
+	}
+	var def Definition
+	ok := false
+	if l != nil {
+		def, ok = l.defs[parsed.Name]
+	}
+	if !ok {
+		return "", nil, fmt.Errorf("unknown condition %q", parsed.Name)
+	}
+
+	declared := make(map[string]bool, len(def.Params))
+	params := make(map[string]any, len(def.Params))
+	for _, p := range def.Params {
+		declared[p.Name] = true
+		value, given := parsed.Args[p.Name]
+		switch {
+		case given:
+			if err := checkParamType(p, value); err != nil {
+				return "", nil, fmt.Errorf("condition %q: %w", def.Name, err)
+			}
+			params[p.Name] = value
+		case p.Required:
+			return "", nil, fmt.Errorf("condition %q: missing required parameter %q", def.Name, p.Name)
+		default:
+			params[p.Name] = p.Default
+		}
+	}
+	for _, key := range sortedKeys(parsed.Args) {
+		if !declared[key] {
+			return "", nil, fmt.Errorf("condition %q: unknown parameter %q", def.Name, key)
+		}
+	}
+	return def.Expression, params, nil
+}
+
+func checkParamType(p Param, value any) error {
+	if value == nil {
+		return nil
+	}
+	ok := true
+	switch p.Type {
+	case ParamTypeString:
+		_, ok = value.(string)
+	case ParamTypeNumber:
+		switch value.(type) {
+		case float64, float32, int, int64:
+		default:
+			ok = false
+		}
+	case ParamTypeBoolean:
+		_, ok = value.(bool)
+	case ParamTypeArray:
+		_, ok = value.([]any)
+	}
+	if !ok {
+		return fmt.Errorf("parameter %q must be of type %s, got %T", p.Name, p.Type, value)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
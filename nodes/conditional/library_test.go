@@ -0,0 +1,147 @@
+package conditional
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func testLibrary(t *testing.T) *Library {
+	t.Helper()
+	lib, err := NewLibrary([]Definition{
+		{
+			Name:       "business_hours",
+			Expression: `input.tz == tz && input.hour >= start && input.hour < end`,
+			Params: []Param{
+				{Name: "tz", Type: ParamTypeString, Required: true},
+				{Name: "start", Type: ParamTypeNumber, Default: float64(9)},
+				{Name: "end", Type: ParamTypeNumber, Default: float64(17)},
+			},
+		},
+		{Name: "vip", Expression: `input.tier in tiers`, Params: []Param{{Name: "tiers", Type: ParamTypeArray, Default: []any{"gold"}}}},
+	})
+	if err != nil {
+		t.Fatalf("NewLibrary: %v", err)
+	}
+	return lib
+}
+
+func TestParseRef(t *testing.T) {
+	ref, err := ParseRef(`business_hours(tz: "UTC, or not", start: 8, tags: ["a", "b:c"], strict: true)`)
+	if err != nil {
+		t.Fatalf("ParseRef: %v", err)
+	}
+	want := Ref{Name: "business_hours", Args: map[string]any{
+		"tz":     "UTC, or not",
+		"start":  float64(8),
+		"tags":   []any{"a", "b:c"},
+		"strict": true,
+	}}
+	if !reflect.DeepEqual(ref, want) {
+		t.Fatalf("ParseRef = %#v, want %#v", ref, want)
+	}
+
+	bare, err := ParseRef("vip")
+	if err != nil || bare.Name != "vip" || len(bare.Args) != 0 {
+		t.Fatalf("ParseRef(vip) = %#v, %v", bare, err)
+	}
+
+	for _, bad := range []string{
+		"",
+		"1abc",
+		`business_hours(tz: "UTC"`,
+		`business_hours(tz)`,
+		`business_hours(tz: input.tz)`,
+		`business_hours(tz: "a", tz: "b")`,
+		`business_hours(tz: "UTC)`,
+	} {
+		if _, err := ParseRef(bad); err == nil {
+			t.Errorf("ParseRef(%q) = nil error", bad)
+		}
+	}
+}
+
+func TestLibraryResolve(t *testing.T) {
+	lib := testLibrary(t)
+
+	expression, params, err := lib.Resolve(`business_hours(tz: "UTC", end: 18)`)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if !strings.Contains(expression, "input.hour >= start") {
+		t.Fatalf("expression = %q", expression)
+	}
+	want := map[string]any{"tz": "UTC", "start": float64(9), "end": float64(18)}
+	if !reflect.DeepEqual(params, want) {
+		t.Fatalf("params = %#v, want %#v", params, want)
+	}
+
+	tests := map[string]string{
+		`unknown()`:                          `unknown condition "unknown"`,
+		`business_hours`:                     `missing required parameter "tz"`,
+		`business_hours(tz: 5)`:              `parameter "tz" must be of type string`,
+		`business_hours(tz: "UTC", days: 5)`: `unknown parameter "days"`,
+		`vip(tiers: "gold")`:                 `parameter "tiers" must be of type array`,
+	}
+	for ref, wantErr := range tests {
+		_, _, err := lib.Resolve(ref)
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Errorf("Resolve(%q) error = %v, want %q", ref, err, wantErr)
+		}
+	}
+}
+
+func TestDefinitionValidate(t *testing.T) {
+	tests := []Definition{
+		{Name: "bad-name", Expression: "true"},
+		{Name: "empty"},
+		{Name: "syntax", Expression: "a &&"},
+		{Name: "reserved", Expression: "true", Params: []Param{{Name: "input"}}},
+		{Name: "dup", Expression: "true", Params: []Param{{Name: "a"}, {Name: "a"}}},
+		{Name: "kind", Expression: "true", Params: []Param{{Name: "a", Type: "date"}}},
+		{Name: "default", Expression: "true", Params: []Param{{Name: "a", Type: ParamTypeNumber, Default: "x"}}},
+		{Name: "required_default", Expression: "true", Params: []Param{{Name: "a", Required: true, Default: "x"}}},
+	}
+	for _, def := range tests {
+		if err := def.Validate(); err == nil {
+			t.Errorf("Validate(%s) = nil error", def.Name)
+		}
+	}
+}
+
+func TestConditionalNode_ParamsShadowVars(t *testing.T) {
+	lib := testLibrary(t)
+	expression, params, err := lib.Resolve(`business_hours(tz: "UTC")`)
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	node, err := NewConditionalNode("route", Config{
+		Conditions: []Condition{{Name: "open", Expression: expression, Params: params}},
+		Default:    "closed",
+	})
+	if err != nil {
+		t.Fatalf("NewConditionalNode: %v", err)
+	}
+
+	// An envelope variable named like a parameter must not change the result.
+	env := core.NewEnvelope().WithVar("tz", "UTC").WithVar("hour", float64(10)).WithVar("start", float64(11))
+	decision, err := node.Route(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if len(decision.Targets) != 1 || decision.Targets[0] != "open" {
+		t.Fatalf("targets = %v, want [open]", decision.Targets)
+	}
+
+	env = core.NewEnvelope().WithVar("tz", "UTC").WithVar("hour", float64(20))
+	decision, err = node.Route(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if decision.Targets[0] != "closed" {
+		t.Fatalf("targets = %v, want [closed]", decision.Targets)
+	}
+}
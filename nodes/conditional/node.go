@@ -6,6 +6,7 @@ package conditional
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/petal-labs/petalflow/core"
 	"github.com/petal-labs/petalflow/nodes/conditional/expr"
@@ -30,6 +31,18 @@ type Config struct {
 	// OutputKey is the envelope variable key for the output.
 	// Defaults to "{id}_output".
 	OutputKey string
+
+	// State, when set, binds "state" in expressions to stored workflow
+	// state, as in state.emails_sent < 3. An envelope variable named
+	// "state" takes precedence.
+	State func(ctx context.Context) expr.Lookup
+
+	// Holidays are the calendars isHoliday looks days up in.
+	Holidays expr.Holidays
+
+	// Now returns the current time for the time functions, such as hour
+	// and isWeekend. Nil uses time.Now.
+	Now func() time.Time
 }
 
 // Condition is a single named condition with an expression.
@@ -38,6 +51,11 @@ type Condition struct {
 	Expression  string
 	Description string
 
+	// Params are bound as top-level identifiers when the expression is
+	// evaluated, shadowing envelope variables. Set for conditions resolved
+	// from a Library.
+	Params map[string]any
+
 	parsed expr.Expr // cached parsed AST
 }
 
@@ -149,12 +167,16 @@ func (n *ConditionalNode) Route(ctx context.Context, env *core.Envelope) (core.R
 	if _, hasInput := vars["input"]; !hasInput {
 		vars["input"] = env.Vars
 	}
+	if _, hasState := vars["state"]; !hasState && n.config.State != nil {
+		vars["state"] = n.config.State(ctx)
+	}
 
 	var targets []string
 	var reasons []string
 
+	exprEnv := expr.Env{Now: n.config.Now, Holidays: n.config.Holidays}
 	for _, cond := range n.config.Conditions {
-		result, err := expr.Eval(cond.parsed, vars)
+		result, err := expr.EvalEnv(cond.parsed, WithParams(vars, cond.Params), exprEnv)
 		if err != nil {
 			return core.RouteDecision{}, fmt.Errorf("conditional node %q: condition %q: %w", n.ID(), cond.Name, err)
 		}
@@ -197,6 +219,23 @@ func (n *ConditionalNode) Route(ctx context.Context, env *core.Envelope) (core.R
 	}, nil
 }
 
+// WithParams overlays a condition's parameters on the evaluation namespace
+// vars, returning vars itself when there are none. Other nodes that evaluate
+// library conditions bind their parameters with it.
+func WithParams(vars map[string]any, params map[string]any) map[string]any {
+	if len(params) == 0 {
+		return vars
+	}
+	out := make(map[string]any, len(vars)+len(params))
+	for k, v := range vars {
+		out[k] = v
+	}
+	for k, v := range params {
+		out[k] = v
+	}
+	return out
+}
+
 // isTruthy checks if a value is truthy using the same rules as the expression
 // evaluator: 0, "", nil, false, empty slice/map are falsy.
 func isTruthy(val any) bool {
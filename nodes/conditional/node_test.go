@@ -3,6 +3,7 @@ package conditional
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/petal-labs/petalflow/core"
 )
@@ -349,6 +350,42 @@ func TestConditionalNode_ComplexExpressions(t *testing.T) {
 	})
 }
 
+func TestConditionalNode_BusinessHours(t *testing.T) {
+	var now time.Time
+	node, err := NewConditionalNode("hours", Config{
+		Conditions: []Condition{
+			{Name: "closed", Expression: `isWeekend("Europe/London") || isHoliday("Europe/London")`},
+			{Name: "open", Expression: `hour("Europe/London") >= 9 && hour("Europe/London") < 17`},
+		},
+		Default:  "queue",
+		Holidays: map[string][]string{"default": {"12-25"}},
+		Now:      func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		now  time.Time
+		want string
+	}{
+		{time.Date(2025, 6, 10, 10, 0, 0, 0, time.UTC), "open"},   // 11:00 BST, Tuesday
+		{time.Date(2025, 6, 10, 16, 30, 0, 0, time.UTC), "queue"}, // 17:30 BST
+		{time.Date(2025, 6, 14, 10, 0, 0, 0, time.UTC), "closed"}, // Saturday
+		{time.Date(2025, 12, 25, 10, 0, 0, 0, time.UTC), "closed"},
+	}
+	for _, tt := range tests {
+		now = tt.now
+		decision, err := node.Route(context.Background(), core.NewEnvelope())
+		if err != nil {
+			t.Fatalf("Route at %s: %v", tt.now, err)
+		}
+		if len(decision.Targets) != 1 || decision.Targets[0] != tt.want {
+			t.Errorf("Route at %s = %v, want [%s]", tt.now, decision.Targets, tt.want)
+		}
+	}
+}
+
 func TestConditionalNode_InterfaceCompliance(t *testing.T) {
 	var _ core.Node = (*ConditionalNode)(nil)
 	var _ core.RouterNode = (*ConditionalNode)(nil)
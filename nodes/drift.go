@@ -0,0 +1,395 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Drift guard actions.
+const (
+	// DriftActionFlag records the deviation and lets the run continue.
+	DriftActionFlag = "flag"
+	// DriftActionFail fails the node when its output deviates.
+	DriftActionFail = "fail"
+)
+
+// Drift guard defaults.
+const (
+	defaultDriftWindow     = 50
+	defaultDriftMinSamples = 10
+	defaultDriftMaxLengthZ = 3.0
+	// driftFieldPresence is the share of previous outputs a field must
+	// appear in before its absence counts as a deviation.
+	driftFieldPresence = 0.9
+	// driftEmbeddingDims is the size of the built-in hashed embedding.
+	driftEmbeddingDims = 256
+)
+
+// EmbedFunc turns text into a vector for drift similarity checks.
+type EmbedFunc func(ctx context.Context, text string) ([]float64, error)
+
+// DriftGuardConfig compares an LLM node's output with the rolling
+// distribution of its previous outputs and flags or fails outputs that
+// deviate beyond the thresholds.
+type DriftGuardConfig struct {
+	// Store keeps the output history. Required.
+	Store OutputHistoryStore
+	// Key identifies the history, usually "<workflow>/<node>".
+	Key string
+	// Action is DriftActionFlag (default) or DriftActionFail.
+	Action string
+	// Window is how many previous outputs are kept. Defaults to 50.
+	Window int
+	// MinSamples is how many previous outputs are needed before outputs
+	// are checked. Defaults to 10.
+	MinSamples int
+	// MaxLengthZ is the largest allowed distance of the output length from
+	// the mean, in standard deviations. Defaults to 3; negative disables.
+	MaxLengthZ float64
+	// MinSimilarity is the lowest allowed cosine similarity between the
+	// output's embedding and the mean of previous embeddings. 0 disables.
+	MinSimilarity float64
+	// Fields are dot paths into JSON outputs that previous outputs
+	// consistently contained. When empty, top-level keys of JSON outputs
+	// are tracked.
+	Fields []string
+	// Embed computes embeddings for the similarity check. Defaults to a
+	// hashed bag-of-words vector, which catches changes in vocabulary and
+	// language rather than meaning.
+	Embed EmbedFunc
+}
+
+// OutputSample is the summary of one output kept in the history.
+type OutputSample struct {
+	Length    int       `json:"length"`
+	Embedding []float64 `json:"embedding,omitempty"`
+	Fields    []string  `json:"fields,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// OutputHistoryStore persists output samples per key.
+type OutputHistoryStore interface {
+	// RecentOutputs returns up to limit of the newest samples for key,
+	// oldest first.
+	RecentOutputs(ctx context.Context, key string, limit int) ([]OutputSample, error)
+	// AppendOutput adds a sample and keeps only the newest keep samples.
+	AppendOutput(ctx context.Context, key string, sample OutputSample, keep int) error
+}
+
+// DriftDeviation describes one failed drift check.
+type DriftDeviation struct {
+	// Check is "length", "similarity" or "missing_field".
+	Check     string  `json:"check"`
+	Field     string  `json:"field,omitempty"`
+	Value     float64 `json:"value"`
+	Threshold float64 `json:"threshold"`
+	Message   string  `json:"message"`
+}
+
+// DriftReport is stored in "<output_key>_drift" after each guarded call.
+type DriftReport struct {
+	Key        string           `json:"key"`
+	Samples    int              `json:"samples"`
+	Checked    bool             `json:"checked"`
+	Drifted    bool             `json:"drifted"`
+	Deviations []DriftDeviation `json:"deviations,omitempty"`
+}
+
+func (c DriftGuardConfig) withDefaults() DriftGuardConfig {
+	if c.Action == "" {
+		c.Action = DriftActionFlag
+	}
+	if c.Window <= 0 {
+		c.Window = defaultDriftWindow
+	}
+	if c.MinSamples <= 0 {
+		c.MinSamples = defaultDriftMinSamples
+	}
+	if c.MinSamples > c.Window {
+		c.MinSamples = c.Window
+	}
+	if c.MaxLengthZ == 0 {
+		c.MaxLengthZ = defaultDriftMaxLengthZ
+	}
+	if c.Embed == nil {
+		c.Embed = hashedEmbedding
+	}
+	return c
+}
+
+// Validate checks the guard's settings.
+func (c DriftGuardConfig) Validate() error {
+	switch c.Action {
+	case "", DriftActionFlag, DriftActionFail:
+	default:
+		return fmt.Errorf("drift_guard: unknown action %q (use flag or fail)", c.Action)
+	}
+	if c.MinSimilarity < 0 || c.MinSimilarity > 1 {
+		return fmt.Errorf("drift_guard: min_similarity must be between 0 and 1")
+	}
+	if c.Window < 0 || c.MinSamples < 0 {
+		return fmt.Errorf("drift_guard: window and min_samples must be >= 0")
+	}
+	return nil
+}
+
+// guardDrift checks output against the history and records it. It returns
+// the report and whether the output should fail the node.
+func guardDrift(ctx context.Context, cfg DriftGuardConfig, output any) (DriftReport, bool, error) {
+	cfg = cfg.withDefaults()
+	report := DriftReport{Key: cfg.Key}
+
+	history, err := cfg.Store.RecentOutputs(ctx, cfg.Key, cfg.Window)
+	if err != nil {
+		return report, false, fmt.Errorf("drift guard: loading history: %w", err)
+	}
+	report.Samples = len(history)
+
+	sample, err := summarizeOutput(ctx, cfg, output)
+	if err != nil {
+		return report, false, fmt.Errorf("drift guard: %w", err)
+	}
+
+	if len(history) >= cfg.MinSamples {
+		report.Checked = true
+		report.Deviations = checkDrift(cfg, history, sample)
+		report.Drifted = len(report.Deviations) > 0
+	}
+
+	fail := report.Drifted && cfg.Action == DriftActionFail
+	// Failed outputs stay out of the history so the baseline does not
+	// absorb the change until someone looks at it.
+	if !fail {
+		if err := cfg.Store.AppendOutput(ctx, cfg.Key, sample, cfg.Window); err != nil {
+			return report, false, fmt.Errorf("drift guard: saving history: %w", err)
+		}
+	}
+	return report, fail, nil
+}
+
+func summarizeOutput(ctx context.Context, cfg DriftGuardConfig, output any) (OutputSample, error) {
+	text, ok := output.(string)
+	if !ok {
+		data, err := json.Marshal(output)
+		if err != nil {
+			return OutputSample{}, err
+		}
+		text = string(data)
+	}
+	sample := OutputSample{Length: utf8.RuneCountInString(text), At: time.Now().UTC()}
+
+	if cfg.MinSimilarity > 0 {
+		embedding, err := cfg.Embed(ctx, text)
+		if err != nil {
+			return OutputSample{}, fmt.Errorf("embedding output: %w", err)
+		}
+		sample.Embedding = embedding
+	}
+
+	doc, ok := output.(map[string]any)
+	if !ok {
+		// Text outputs may still hold JSON.
+		_ = json.Unmarshal([]byte(text), &doc)
+	}
+	if doc != nil {
+		if len(cfg.Fields) > 0 {
+			for _, field := range cfg.Fields {
+				if v, ok := getNestedValue(doc, field); ok && v != nil {
+					sample.Fields = append(sample.Fields, field)
+				}
+			}
+		} else {
+			for key, v := range doc {
+				if v != nil {
+					sample.Fields = append(sample.Fields, key)
+				}
+			}
+			sort.Strings(sample.Fields)
+		}
+	}
+	return sample, nil
+}
+
+func checkDrift(cfg DriftGuardConfig, history []OutputSample, sample OutputSample) []DriftDeviation {
+	var deviations []DriftDeviation
+
+	if cfg.MaxLengthZ > 0 {
+		var sum float64
+		for _, h := range history {
+			sum += float64(h.Length)
+		}
+		mean := sum / float64(len(history))
+		var variance float64
+		for _, h := range history {
+			d := float64(h.Length) - mean
+			variance += d * d
+		}
+		// A floor on the deviation keeps near-constant histories from
+		// flagging every small change.
+		std := math.Max(math.Sqrt(variance/float64(len(history))), math.Max(mean*0.05, 1))
+		z := math.Abs(float64(sample.Length)-mean) / std
+		if z > cfg.MaxLengthZ {
+			deviations = append(deviations, DriftDeviation{
+				Check:     "length",
+				Value:     round2(z),
+				Threshold: cfg.MaxLengthZ,
+				Message:   fmt.Sprintf("output length %d is %.1f standard deviations from the mean %.0f", sample.Length, z, mean),
+			})
+		}
+	}
+
+	if cfg.MinSimilarity > 0 && len(sample.Embedding) > 0 {
+		var centroid []float64
+		n := 0
+		for _, h := range history {
+			if len(h.Embedding) != len(sample.Embedding) {
+				continue
+			}
+			if centroid == nil {
+				centroid = make([]float64, len(h.Embedding))
+			}
+			for i, v := range h.Embedding {
+				centroid[i] += v
+			}
+			n++
+		}
+		if n >= cfg.MinSamples {
+			similarity := cosineSimilarity(sample.Embedding, centroid)
+			if similarity < cfg.MinSimilarity {
+				deviations = append(deviations, DriftDeviation{
+					Check:     "similarity",
+					Value:     round2(similarity),
+					Threshold: cfg.MinSimilarity,
+					Message:   fmt.Sprintf("output similarity %.2f to previous outputs is below %.2f", similarity, cfg.MinSimilarity),
+				})
+			}
+		}
+	}
+
+	present := make(map[string]bool, len(sample.Fields))
+	for _, f := range sample.Fields {
+		present[f] = true
+	}
+	counts := map[string]int{}
+	for _, h := range history {
+		for _, f := range h.Fields {
+			counts[f]++
+		}
+	}
+	candidates := cfg.Fields
+	if len(candidates) == 0 {
+		for f := range counts {
+			candidates = append(candidates, f)
+		}
+		sort.Strings(candidates)
+	}
+	for _, f := range candidates {
+		rate := float64(counts[f]) / float64(len(history))
+		if rate >= driftFieldPresence && !present[f] {
+			deviations = append(deviations, DriftDeviation{
+				Check:     "missing_field",
+				Field:     f,
+				Value:     round2(rate),
+				Threshold: driftFieldPresence,
+				Message:   fmt.Sprintf("field %q is missing but was present in %.0f%% of previous outputs", f, rate*100),
+			})
+		}
+	}
+	return deviations
+}
+
+func (r DriftReport) summary() string {
+	messages := make([]string, len(r.Deviations))
+	for i, d := range r.Deviations {
+		messages[i] = d.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// hashedEmbedding is the default EmbedFunc: word counts hashed into a
+// fixed number of buckets and normalized.
+func hashedEmbedding(_ context.Context, text string) ([]float64, error) {
+	vec := make([]float64, driftEmbeddingDims)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(w))
+		vec[h.Sum32()%driftEmbeddingDims]++
+	}
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	if norm > 0 {
+		norm = math.Sqrt(norm)
+		for i := range vec {
+			vec[i] /= norm
+		}
+	}
+	return vec, nil
+}
+
+func cosineSimilarity(a, b []float64) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// MemoryOutputHistory is an in-memory OutputHistoryStore.
+type MemoryOutputHistory struct {
+	mu      sync.Mutex
+	samples map[string][]OutputSample
+}
+
+// NewMemoryOutputHistory creates an empty in-memory history.
+func NewMemoryOutputHistory() *MemoryOutputHistory {
+	return &MemoryOutputHistory{samples: make(map[string][]OutputSample)}
+}
+
+// RecentOutputs returns up to limit of the newest samples for key.
+func (m *MemoryOutputHistory) RecentOutputs(_ context.Context, key string, limit int) ([]OutputSample, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	samples := m.samples[key]
+	if limit > 0 && len(samples) > limit {
+		samples = samples[len(samples)-limit:]
+	}
+	return append([]OutputSample(nil), samples...), nil
+}
+
+// AppendOutput adds a sample and keeps only the newest keep samples.
+func (m *MemoryOutputHistory) AppendOutput(_ context.Context, key string, sample OutputSample, keep int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	samples := append(m.samples[key], sample)
+	if keep > 0 && len(samples) > keep {
+		samples = append([]OutputSample(nil), samples[len(samples)-keep:]...)
+	}
+	m.samples[key] = samples
+	return nil
+}
+
+// Ensure interface compliance at compile time.
+var _ OutputHistoryStore = (*MemoryOutputHistory)(nil)
@@ -0,0 +1,202 @@
+package nodes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func seedHistory(t *testing.T, store OutputHistoryStore, key string, outputs ...any) {
+	t.Helper()
+	cfg := DriftGuardConfig{Store: store, Key: key, MinSimilarity: 0.5}.withDefaults()
+	for _, out := range outputs {
+		sample, err := summarizeOutput(context.Background(), cfg, out)
+		if err != nil {
+			t.Fatalf("summarizeOutput: %v", err)
+		}
+		if err := store.AppendOutput(context.Background(), key, sample, cfg.Window); err != nil {
+			t.Fatalf("AppendOutput: %v", err)
+		}
+	}
+}
+
+func repeatOutputs(n int, out any) []any {
+	outputs := make([]any, n)
+	for i := range outputs {
+		outputs[i] = out
+	}
+	return outputs
+}
+
+func TestGuardDrift_WaitsForMinSamples(t *testing.T) {
+	store := NewMemoryOutputHistory()
+	seedHistory(t, store, "k", repeatOutputs(2, "short answer")...)
+
+	report, fail, err := guardDrift(context.Background(), DriftGuardConfig{Store: store, Key: "k", MinSamples: 3}, strings.Repeat("long ", 200))
+	if err != nil {
+		t.Fatalf("guardDrift: %v", err)
+	}
+	if report.Checked || report.Drifted || fail {
+		t.Fatalf("expected unchecked report, got %+v fail=%v", report, fail)
+	}
+	history, _ := store.RecentOutputs(context.Background(), "k", 0)
+	if len(history) != 3 {
+		t.Fatalf("history = %d samples, want 3", len(history))
+	}
+}
+
+func TestGuardDrift_Length(t *testing.T) {
+	store := NewMemoryOutputHistory()
+	seedHistory(t, store, "k", "a normal answer", "another answer", "a third answer here")
+
+	cfg := DriftGuardConfig{Store: store, Key: "k", MinSamples: 3}
+	report, fail, err := guardDrift(context.Background(), cfg, "an answer for you")
+	if err != nil {
+		t.Fatalf("guardDrift: %v", err)
+	}
+	if !report.Checked || report.Drifted || fail {
+		t.Fatalf("similar output flagged: %+v", report)
+	}
+
+	report, fail, err = guardDrift(context.Background(), cfg, strings.Repeat("x", 2000))
+	if err != nil {
+		t.Fatalf("guardDrift: %v", err)
+	}
+	if !report.Drifted || fail {
+		t.Fatalf("expected flagged drift, got %+v fail=%v", report, fail)
+	}
+	if report.Deviations[0].Check != "length" {
+		t.Fatalf("deviation = %+v, want length", report.Deviations[0])
+	}
+}
+
+func TestGuardDrift_MissingField(t *testing.T) {
+	store := NewMemoryOutputHistory()
+	seedHistory(t, store, "k", repeatOutputs(5, map[string]any{"name": "Ada", "email": "ada@example.com"})...)
+
+	report, _, err := guardDrift(context.Background(), DriftGuardConfig{Store: store, Key: "k", MinSamples: 5, MaxLengthZ: -1},
+		`{"name": "Bob"}`)
+	if err != nil {
+		t.Fatalf("guardDrift: %v", err)
+	}
+	if len(report.Deviations) != 1 || report.Deviations[0].Check != "missing_field" || report.Deviations[0].Field != "email" {
+		t.Fatalf("deviations = %+v, want missing email", report.Deviations)
+	}
+}
+
+func TestGuardDrift_Similarity(t *testing.T) {
+	store := NewMemoryOutputHistory()
+	seedHistory(t, store, "k",
+		"the weather today is sunny and warm",
+		"the weather today is cloudy and warm",
+		"the weather today is sunny and cool",
+	)
+
+	cfg := DriftGuardConfig{Store: store, Key: "k", MinSamples: 3, MaxLengthZ: -1, MinSimilarity: 0.5}
+	report, _, err := guardDrift(context.Background(), cfg, "the weather today is rainy and warm")
+	if err != nil {
+		t.Fatalf("guardDrift: %v", err)
+	}
+	if report.Drifted {
+		t.Fatalf("similar output flagged: %+v", report)
+	}
+
+	report, _, err = guardDrift(context.Background(), cfg, "lo siento, no puedo ayudar con eso")
+	if err != nil {
+		t.Fatalf("guardDrift: %v", err)
+	}
+	if !report.Drifted || report.Deviations[0].Check != "similarity" {
+		t.Fatalf("expected similarity drift, got %+v", report)
+	}
+}
+
+func TestMemoryOutputHistory_Prunes(t *testing.T) {
+	store := NewMemoryOutputHistory()
+	for i := 1; i <= 5; i++ {
+		if err := store.AppendOutput(context.Background(), "k", OutputSample{Length: i}, 3); err != nil {
+			t.Fatalf("AppendOutput: %v", err)
+		}
+	}
+	samples, _ := store.RecentOutputs(context.Background(), "k", 2)
+	if len(samples) != 2 || samples[0].Length != 4 || samples[1].Length != 5 {
+		t.Fatalf("samples = %+v, want lengths 4, 5", samples)
+	}
+	all, _ := store.RecentOutputs(context.Background(), "k", 0)
+	if len(all) != 3 {
+		t.Fatalf("kept %d samples, want 3", len(all))
+	}
+}
+
+func TestDriftGuardConfig_Validate(t *testing.T) {
+	if err := (DriftGuardConfig{Action: "explode"}).Validate(); err == nil {
+		t.Error("expected unknown action error")
+	}
+	if err := (DriftGuardConfig{MinSimilarity: 1.5}).Validate(); err == nil {
+		t.Error("expected min_similarity range error")
+	}
+	if err := (DriftGuardConfig{Action: DriftActionFail, MinSimilarity: 0.8}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLLMNode_DriftGuardFlag(t *testing.T) {
+	store := NewMemoryOutputHistory()
+	seedHistory(t, store, "summarize", repeatOutputs(3, "a short summary")...)
+
+	client := &mockLLMClient{response: core.LLMResponse{Text: strings.Repeat("very long output ", 100)}}
+	node := NewLLMNode("summarize", client, LLMNodeConfig{
+		Model:      "gpt-4",
+		OutputKey:  "summary",
+		DriftGuard: &DriftGuardConfig{Store: store, MinSamples: 3},
+	})
+
+	var events []runtime.Event
+	ctx := runtime.ContextWithEmitter(context.Background(), func(e runtime.Event) { events = append(events, e) })
+	result, err := node.Run(ctx, core.NewEnvelope())
+	if err != nil {
+		t.Fatalf("flag action should not fail: %v", err)
+	}
+	v, ok := result.GetVar("summary_drift")
+	if !ok {
+		t.Fatal("expected summary_drift var")
+	}
+	if report := v.(DriftReport); !report.Drifted || report.Key != "summarize" {
+		t.Fatalf("report = %+v", report)
+	}
+
+	var drift *runtime.Event
+	for i := range events {
+		if events[i].Kind == runtime.EventNodeOutputDrift {
+			drift = &events[i]
+		}
+	}
+	if drift == nil {
+		t.Fatal("expected node.output.drift event")
+	}
+	if drift.Payload["action"] != DriftActionFlag {
+		t.Errorf("action = %v, want flag", drift.Payload["action"])
+	}
+}
+
+func TestLLMNode_DriftGuardFail(t *testing.T) {
+	store := NewMemoryOutputHistory()
+	seedHistory(t, store, "k", repeatOutputs(3, "a short summary")...)
+
+	client := &mockLLMClient{response: core.LLMResponse{Text: strings.Repeat("very long output ", 100)}}
+	node := NewLLMNode("summarize", client, LLMNodeConfig{
+		Model:      "gpt-4",
+		DriftGuard: &DriftGuardConfig{Store: store, Key: "k", Action: DriftActionFail, MinSamples: 3},
+	})
+
+	_, err := node.Run(context.Background(), core.NewEnvelope())
+	if err == nil || !strings.Contains(err.Error(), "output drift detected") {
+		t.Fatalf("expected drift error, got %v", err)
+	}
+	history, _ := store.RecentOutputs(context.Background(), "k", 0)
+	if len(history) != 3 {
+		t.Fatalf("failed output was recorded: %d samples", len(history))
+	}
+}
@@ -0,0 +1,611 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// Entity field types.
+const (
+	EntityFieldString  = "string"
+	EntityFieldNumber  = "number"
+	EntityFieldInteger = "integer"
+	EntityFieldBoolean = "boolean"
+	// EntityFieldDate accepts YYYY-MM-DD or RFC 3339 and stores YYYY-MM-DD.
+	EntityFieldDate = "date"
+	// EntityFieldEnum accepts one of the field's Options.
+	EntityFieldEnum = "enum"
+)
+
+// Deduplication modes.
+const (
+	// DedupeNone keeps every record. It is the default.
+	DedupeNone = "none"
+	// DedupeExact merges records whose fields are all equal.
+	DedupeExact = "exact"
+	// DedupeKey merges records whose DedupeKeys fields are equal, ignoring
+	// case and surrounding whitespace.
+	DedupeKey = "key"
+)
+
+// Policies for records that fail schema validation.
+const (
+	// InvalidDrop leaves invalid records out of the output. It is the
+	// default.
+	InvalidDrop = "drop"
+	// InvalidKeep outputs invalid records with the values that did
+	// validate.
+	InvalidKeep = "keep"
+	// InvalidFail fails the node.
+	InvalidFail = "fail"
+)
+
+// entityQuoteField is the property the model puts each record's source
+// text in. Schema fields may not use it.
+const entityQuoteField = "quote"
+
+// EntityField is one field of an entity schema.
+type EntityField struct {
+	Name string `json:"name"`
+	// Type is one of the EntityField constants. Defaults to string.
+	Type        string `json:"type,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Options lists the values of an enum field.
+	Options []string `json:"options,omitempty"`
+}
+
+func (f EntityField) fieldType() string {
+	if f.Type == "" {
+		return EntityFieldString
+	}
+	return f.Type
+}
+
+// ValidateEntityFields checks that an entity schema is well formed.
+func ValidateEntityFields(fields []EntityField) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("entity schema has no fields")
+	}
+	seen := make(map[string]bool, len(fields))
+	for i, f := range fields {
+		switch {
+		case strings.TrimSpace(f.Name) == "":
+			return fmt.Errorf("fields[%d]: name is required", i)
+		case f.Name == entityQuoteField:
+			return fmt.Errorf("fields[%d]: %q is reserved for the source text", i, entityQuoteField)
+		case seen[f.Name]:
+			return fmt.Errorf("fields[%d]: duplicate field %q", i, f.Name)
+		}
+		seen[f.Name] = true
+		switch f.fieldType() {
+		case EntityFieldString, EntityFieldNumber, EntityFieldInteger, EntityFieldBoolean, EntityFieldDate:
+		case EntityFieldEnum:
+			if len(f.Options) == 0 {
+				return fmt.Errorf("fields[%d]: enum field %q needs options", i, f.Name)
+			}
+		default:
+			return fmt.Errorf("fields[%d]: unknown type %q", i, f.Type)
+		}
+	}
+	return nil
+}
+
+// coerce converts a model-provided value to the field's type.
+func (f EntityField) coerce(raw any) (any, error) {
+	switch f.fieldType() {
+	case EntityFieldString:
+		switch v := raw.(type) {
+		case string:
+			return strings.TrimSpace(v), nil
+		case float64, bool:
+			return fmt.Sprint(v), nil
+		}
+		return nil, fmt.Errorf("expected string, got %T", raw)
+	case EntityFieldNumber, EntityFieldInteger:
+		var n float64
+		switch v := raw.(type) {
+		case float64:
+			n = v
+		case string:
+			parsed, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(v), ",", ""), 64)
+			if err != nil {
+				return nil, fmt.Errorf("expected number, got %q", v)
+			}
+			n = parsed
+		default:
+			return nil, fmt.Errorf("expected number, got %T", raw)
+		}
+		if f.fieldType() == EntityFieldNumber {
+			return n, nil
+		}
+		if n != math.Trunc(n) {
+			return nil, fmt.Errorf("expected integer, got %v", n)
+		}
+		return int(n), nil
+	case EntityFieldBoolean:
+		switch v := raw.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("expected boolean, got %q", v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected boolean, got %T", raw)
+	case EntityFieldDate:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected date, got %T", raw)
+		}
+		s = strings.TrimSpace(s)
+		for _, layout := range []string{time.DateOnly, time.RFC3339} {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t.Format(time.DateOnly), nil
+			}
+		}
+		return nil, fmt.Errorf("expected YYYY-MM-DD date, got %q", s)
+	case EntityFieldEnum:
+		s, ok := raw.(string)
+		if ok {
+			for _, opt := range f.Options {
+				if strings.EqualFold(strings.TrimSpace(s), opt) {
+					return opt, nil
+				}
+			}
+		}
+		return nil, fmt.Errorf("expected one of %v, got %v", f.Options, raw)
+	}
+	return nil, fmt.Errorf("unknown type %q", f.Type)
+}
+
+// jsonSchema returns the JSON Schema of the field's value.
+func (f EntityField) jsonSchema() map[string]any {
+	s := map[string]any{}
+	switch f.fieldType() {
+	case EntityFieldDate:
+		s["type"] = "string"
+		s["format"] = "date"
+	case EntityFieldEnum:
+		s["type"] = "string"
+		s["enum"] = f.Options
+	default:
+		s["type"] = f.fieldType()
+	}
+	if f.Description != "" {
+		s["description"] = f.Description
+	}
+	return s
+}
+
+// ExtractEntitiesNodeConfig configures an ExtractEntitiesNode.
+type ExtractEntitiesNodeConfig struct {
+	// InputVar holds the text: a string, a list of strings, or a list of
+	// objects whose Field holds the text. Required.
+	InputVar string
+
+	// Field is the dot path of the text inside object items.
+	Field string
+
+	// OutputKey stores the records as a list of objects. Defaults to
+	// "{id}_output".
+	OutputKey string
+
+	// ResultKey stores an ExtractionResult. Defaults to
+	// "{OutputKey}_result".
+	ResultKey string
+
+	// Entity names what is extracted, as in "invoice line item".
+	Entity string
+
+	// Description explains the entity to the model.
+	Description string
+
+	// Fields is the entity schema. Required.
+	Fields []EntityField
+
+	// Dedupe is one of the Dedupe constants. Defaults to DedupeNone.
+	Dedupe string
+
+	// DedupeKeys are the fields compared by DedupeKey.
+	DedupeKeys []string
+
+	// OnInvalid is one of the Invalid constants. Defaults to InvalidDrop.
+	OnInvalid string
+
+	// Instructions adds guidance to the extraction prompt.
+	Instructions string
+
+	// Model is the model used for extraction.
+	Model string
+
+	// RetryPolicy configures retries for each extraction call.
+	RetryPolicy core.RetryPolicy
+
+	// Timeout bounds each extraction call. Defaults to 60s.
+	Timeout time.Duration
+}
+
+// EntitySpan locates an entity mention in the input. Start and End are
+// character offsets into the text of input Source.
+type EntitySpan struct {
+	Source int    `json:"source"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Text   string `json:"text"`
+}
+
+// EntityRecord is one extracted entity.
+type EntityRecord struct {
+	Fields map[string]any `json:"fields"`
+	// Spans are the mentions the record was extracted from; merged
+	// duplicates contribute theirs.
+	Spans []EntitySpan `json:"spans,omitempty"`
+	// Errors lists the schema violations of an invalid record.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ExtractionResult reports how an ExtractEntitiesNode run went.
+type ExtractionResult struct {
+	Entity string `json:"entity,omitempty"`
+	// Records are the output records, in output order.
+	Records []EntityRecord `json:"records"`
+	// Invalid are the records that failed validation, kept or not.
+	Invalid []EntityRecord `json:"invalid,omitempty"`
+	// Duplicates counts records merged into an earlier one.
+	Duplicates int `json:"duplicates,omitempty"`
+	// Unlocated counts mentions whose quote was not found in the input.
+	Unlocated int             `json:"unlocated,omitempty"`
+	Sources   int             `json:"sources"`
+	Usage     core.TokenUsage `json:"usage"`
+}
+
+// ExtractEntitiesNode extracts typed records from text with an LLM. Each
+// record is validated against the entity schema and located in the input
+// by the quote the model extracted it from.
+type ExtractEntitiesNode struct {
+	core.BaseNode
+	config ExtractEntitiesNodeConfig
+	client core.LLMClient
+}
+
+// NewExtractEntitiesNode creates a new ExtractEntitiesNode.
+func NewExtractEntitiesNode(id string, client core.LLMClient, config ExtractEntitiesNodeConfig) *ExtractEntitiesNode {
+	if config.OutputKey == "" {
+		config.OutputKey = id + "_output"
+	}
+	if config.ResultKey == "" {
+		config.ResultKey = config.OutputKey + "_result"
+	}
+	if config.Entity == "" {
+		config.Entity = "entity"
+	}
+	if config.Dedupe == "" {
+		config.Dedupe = DedupeNone
+	}
+	if config.OnInvalid == "" {
+		config.OnInvalid = InvalidDrop
+	}
+	if config.RetryPolicy.MaxAttempts == 0 {
+		config.RetryPolicy = core.DefaultRetryPolicy()
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 60 * time.Second
+	}
+
+	return &ExtractEntitiesNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindLLM),
+		config:   config,
+		client:   client,
+	}
+}
+
+// Config returns the node's configuration.
+func (n *ExtractEntitiesNode) Config() ExtractEntitiesNodeConfig {
+	return n.config
+}
+
+// Run extracts records from the input variable.
+func (n *ExtractEntitiesNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	if err := ValidateEntityFields(n.config.Fields); err != nil {
+		return nil, fmt.Errorf("extract_entities node %s: %w", n.ID(), err)
+	}
+	input, ok := env.GetVarNested(n.config.InputVar)
+	if !ok {
+		return nil, fmt.Errorf("extract_entities node %s: input var %q not found", n.ID(), n.config.InputVar)
+	}
+	texts, _, err := collectTexts(n.config.InputVar, n.config.Field, input)
+	if err != nil {
+		return nil, fmt.Errorf("extract_entities node %s: %w", n.ID(), err)
+	}
+
+	result := ExtractionResult{Entity: n.config.Entity, Sources: len(texts), Records: []EntityRecord{}}
+	for source, text := range texts {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		raw, usage, err := n.extract(ctx, env, text)
+		if err != nil {
+			return nil, fmt.Errorf("extract_entities node %s: source %d: %w", n.ID(), source, err)
+		}
+		result.Usage.InputTokens += usage.InputTokens
+		result.Usage.OutputTokens += usage.OutputTokens
+		result.Usage.TotalTokens += usage.TotalTokens
+		result.Usage.CostUSD += usage.CostUSD
+
+		cursor := 0
+		for _, item := range raw {
+			record := n.validate(item)
+			if quote, _ := item[entityQuoteField].(string); quote != "" {
+				if span, end, ok := locateSpan(text, quote, cursor); ok {
+					span.Source = source
+					record.Spans = []EntitySpan{span}
+					cursor = end
+				} else {
+					result.Unlocated++
+				}
+			}
+			if len(record.Errors) > 0 {
+				result.Invalid = append(result.Invalid, record)
+				switch n.config.OnInvalid {
+				case InvalidFail:
+					return nil, fmt.Errorf("extract_entities node %s: invalid %s: %s", n.ID(), n.config.Entity, strings.Join(record.Errors, "; "))
+				case InvalidDrop:
+					continue
+				}
+			}
+			if n.merge(result.Records, record) {
+				result.Duplicates++
+				continue
+			}
+			result.Records = append(result.Records, record)
+		}
+	}
+
+	output := make([]any, len(result.Records))
+	for i, record := range result.Records {
+		output[i] = record.Fields
+	}
+	env.SetVar(n.config.OutputKey, output)
+	env.SetVar(n.config.ResultKey, result)
+	return env, nil
+}
+
+// validate coerces a model-provided record to the schema. Values that do
+// not validate are left out and reported in Errors; properties outside the
+// schema are ignored.
+func (n *ExtractEntitiesNode) validate(item map[string]any) EntityRecord {
+	record := EntityRecord{Fields: make(map[string]any, len(n.config.Fields))}
+	for _, f := range n.config.Fields {
+		raw, ok := item[f.Name]
+		if !ok || raw == nil || raw == "" {
+			if f.Required {
+				record.Errors = append(record.Errors, f.Name+": required")
+			}
+			continue
+		}
+		v, err := f.coerce(raw)
+		if err != nil {
+			record.Errors = append(record.Errors, fmt.Sprintf("%s: %v", f.Name, err))
+			continue
+		}
+		record.Fields[f.Name] = v
+	}
+	return record
+}
+
+// merge folds record into the first earlier record it duplicates, filling
+// that record's missing fields and adding its spans. It reports whether
+// record was a duplicate.
+func (n *ExtractEntitiesNode) merge(records []EntityRecord, record EntityRecord) bool {
+	if n.config.Dedupe == DedupeNone {
+		return false
+	}
+	for i := range records {
+		if !n.duplicates(records[i], record) {
+			continue
+		}
+		for k, v := range record.Fields {
+			if _, ok := records[i].Fields[k]; !ok {
+				records[i].Fields[k] = v
+			}
+		}
+		records[i].Spans = append(records[i].Spans, record.Spans...)
+		return true
+	}
+	return false
+}
+
+func (n *ExtractEntitiesNode) duplicates(a, b EntityRecord) bool {
+	if n.config.Dedupe == DedupeExact {
+		if len(a.Fields) != len(b.Fields) {
+			return false
+		}
+		for k, v := range a.Fields {
+			if !valuesEqual(v, b.Fields[k]) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, key := range n.config.DedupeKeys {
+		av, aok := a.Fields[key]
+		bv, bok := b.Fields[key]
+		if !aok || !bok {
+			return false
+		}
+		as, bs := fmt.Sprint(av), fmt.Sprint(bv)
+		if !strings.EqualFold(strings.Join(strings.Fields(as), " "), strings.Join(strings.Fields(bs), " ")) {
+			return false
+		}
+	}
+	return len(n.config.DedupeKeys) > 0
+}
+
+// extract sends one extraction call with retries and returns the records
+// the model found.
+func (n *ExtractEntitiesNode) extract(ctx context.Context, env *core.Envelope, text string) ([]map[string]any, core.TokenUsage, error) {
+	if n.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.Timeout)
+		defer cancel()
+	}
+	properties := map[string]any{
+		entityQuoteField: map[string]any{
+			"type":        "string",
+			"description": "The exact text the record was extracted from, copied verbatim.",
+		},
+	}
+	required := []string{entityQuoteField}
+	for _, f := range n.config.Fields {
+		properties[f.Name] = f.jsonSchema()
+		if f.Required {
+			required = append(required, f.Name)
+		}
+	}
+	req := core.LLMRequest{
+		Model:     n.config.Model,
+		System:    n.prompt(),
+		InputText: text,
+		JSONSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"entities": map[string]any{
+					"type": "array",
+					"items": map[string]any{
+						"type":       "object",
+						"properties": properties,
+						"required":   required,
+					},
+				},
+			},
+			"required": []string{"entities"},
+		},
+	}
+
+	var resp core.LLMResponse
+	var err error
+	for attempt := 1; attempt <= n.config.RetryPolicy.MaxAttempts; attempt++ {
+		err = runtime.InjectProviderFault(ctx, env.Trace.RunID, n.ID(), n.Kind())
+		if err == nil {
+			resp, err = n.client.Complete(ctx, req)
+		}
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return nil, core.TokenUsage{}, ctx.Err()
+		}
+		if attempt < n.config.RetryPolicy.MaxAttempts {
+			select {
+			case <-ctx.Done():
+				return nil, core.TokenUsage{}, ctx.Err()
+			case <-time.After(n.config.RetryPolicy.Backoff * time.Duration(attempt)):
+			}
+		}
+	}
+	if err != nil {
+		return nil, core.TokenUsage{}, fmt.Errorf("extraction call failed after %d attempts: %w", n.config.RetryPolicy.MaxAttempts, err)
+	}
+	usage := core.TokenUsage{
+		InputTokens:  resp.Usage.InputTokens,
+		OutputTokens: resp.Usage.OutputTokens,
+		TotalTokens:  resp.Usage.TotalTokens,
+		CostUSD:      resp.Usage.CostUSD,
+	}
+
+	var parsed struct {
+		Entities []map[string]any `json:"entities"`
+	}
+	data := []byte(stripCodeFence(resp.Text))
+	if resp.JSON != nil {
+		data, _ = json.Marshal(resp.JSON)
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, usage, fmt.Errorf("could not parse entities from: %s", resp.Text)
+	}
+	return parsed.Entities, usage, nil
+}
+
+// prompt describes the entity schema and the expected answer.
+func (n *ExtractEntitiesNode) prompt() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Extract every %s mentioned in the text.", n.config.Entity)
+	if n.config.Description != "" {
+		b.WriteString(" " + n.config.Description)
+	}
+	b.WriteString("\n\nFields:\n")
+	for _, f := range n.config.Fields {
+		fmt.Fprintf(&b, "- %s (%s", f.Name, f.fieldType())
+		if f.Required {
+			b.WriteString(", required")
+		}
+		b.WriteString(")")
+		var notes []string
+		if f.Description != "" {
+			notes = append(notes, f.Description)
+		}
+		if len(f.Options) > 0 {
+			notes = append(notes, "one of "+strings.Join(f.Options, ", "))
+		}
+		if len(notes) > 0 {
+			b.WriteString(": " + strings.Join(notes, "; "))
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("\nOnly extract what the text states; leave out fields it does not give. Dates are YYYY-MM-DD.")
+	if n.config.Instructions != "" {
+		b.WriteString("\n\n" + n.config.Instructions)
+	}
+	fmt.Fprintf(&b, "\n\nRespond with JSON: {\"entities\": [{%q: \"<exact source text>\", ...fields}]}, in the order they appear.", entityQuoteField)
+	return b.String()
+}
+
+// locateSpan finds quote in text, preferring the first occurrence at or
+// after the byte offset from, then any occurrence, then a case-insensitive
+// match. Offsets in the span are in characters; end is the byte offset
+// after the match.
+func locateSpan(text, quote string, from int) (span EntitySpan, end int, ok bool) {
+	quote = strings.TrimSpace(quote)
+	idx := -1
+	if from < len(text) {
+		if i := strings.Index(text[from:], quote); i >= 0 {
+			idx = from + i
+		}
+	}
+	if idx < 0 {
+		idx = strings.Index(text, quote)
+	}
+	if idx < 0 {
+		// ToLower can change byte lengths, so only trust it when it
+		// does not.
+		lower := strings.ToLower(text)
+		if len(lower) == len(text) {
+			idx = strings.Index(lower, strings.ToLower(quote))
+		}
+	}
+	if idx < 0 {
+		return EntitySpan{}, 0, false
+	}
+	start := utf8.RuneCountInString(text[:idx])
+	matched := text[idx : idx+len(quote)]
+	return EntitySpan{
+		Start: start,
+		End:   start + utf8.RuneCountInString(matched),
+		Text:  matched,
+	}, idx + len(quote), true
+}
+
+// Ensure interface compliance at compile time.
+var _ core.Node = (*ExtractEntitiesNode)(nil)
@@ -0,0 +1,137 @@
+package nodes
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+var personFields = []EntityField{
+	{Name: "name", Required: true},
+	{Name: "age", Type: EntityFieldInteger},
+	{Name: "born", Type: EntityFieldDate},
+	{Name: "role", Type: EntityFieldEnum, Options: []string{"buyer", "seller"}},
+}
+
+func TestExtractEntitiesNode_TypedRecordsWithSpans(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{
+		JSON: map[string]any{"entities": []any{
+			map[string]any{"quote": "Zoë Park, 41, the buyer", "name": "Zoë Park", "age": "41", "role": "Buyer"},
+			map[string]any{"quote": "Sam Lee", "name": "Sam Lee", "born": "1990-02-03T00:00:00Z", "extra": "ignored"},
+			map[string]any{"quote": "somebody", "age": 3.5},
+		}},
+		Usage: core.LLMTokenUsage{TotalTokens: 20},
+	}}
+	node := NewExtractEntitiesNode("ex", client, ExtractEntitiesNodeConfig{
+		InputVar: "doc",
+		Entity:   "party",
+		Fields:   personFields,
+	})
+
+	text := "Parties: Zoë Park, 41, the buyer, and Sam Lee."
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("doc", text))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	out, _ := result.GetVar("ex_output")
+	want := []any{
+		map[string]any{"name": "Zoë Park", "age": 41, "role": "buyer"},
+		map[string]any{"name": "Sam Lee", "born": "1990-02-03"},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Errorf("output = %#v", out)
+	}
+
+	raw, _ := result.GetVar("ex_output_result")
+	res := raw.(ExtractionResult)
+	if len(res.Invalid) != 1 || len(res.Invalid[0].Errors) != 2 || res.Unlocated != 1 || res.Usage.TotalTokens != 20 {
+		t.Errorf("result = %+v", res)
+	}
+	span := res.Records[0].Spans[0]
+	if span.Start != 9 || span.End != 32 || []rune(text)[span.Start] != 'Z' {
+		t.Errorf("span = %+v", span)
+	}
+	if span := res.Records[1].Spans[0]; string([]rune(text)[span.Start:span.End]) != "Sam Lee" {
+		t.Errorf("span = %+v", span)
+	}
+
+	req := client.requests[0]
+	for _, want := range []string{"Extract every party", "- role (enum): one of buyer, seller", "- name (string, required)"} {
+		if !strings.Contains(req.System, want) {
+			t.Errorf("system prompt missing %q: %q", want, req.System)
+		}
+	}
+	items := req.JSONSchema["properties"].(map[string]any)["entities"].(map[string]any)["items"].(map[string]any)
+	if !reflect.DeepEqual(items["required"], []string{"quote", "name"}) {
+		t.Errorf("required = %v", items["required"])
+	}
+}
+
+func TestExtractEntitiesNode_DedupeByKey(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{
+		Text: `{"entities": [{"quote": "Acme", "name": "Acme"}, {"quote": "ACME ", "name": "ACME ", "age": 7}]}`,
+	}}
+	node := NewExtractEntitiesNode("ex", client, ExtractEntitiesNodeConfig{
+		InputVar:   "docs",
+		Fields:     personFields,
+		Dedupe:     DedupeKey,
+		DedupeKeys: []string{"name"},
+	})
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("docs", []any{"Acme", "ACME corp"}))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	raw, _ := result.GetVar("ex_output_result")
+	res := raw.(ExtractionResult)
+	if len(res.Records) != 1 || res.Duplicates != 3 || res.Sources != 2 {
+		t.Fatalf("result = %+v", res)
+	}
+	if res.Records[0].Fields["age"] != 7 || len(res.Records[0].Spans) != 4 || res.Records[0].Spans[3].Source != 1 {
+		t.Errorf("merged record = %+v", res.Records[0])
+	}
+}
+
+func TestExtractEntitiesNode_InvalidPolicies(t *testing.T) {
+	response := core.LLMResponse{Text: `{"entities": [{"quote": "x", "age": "old"}]}`}
+	for policy, wantRecords := range map[string]int{InvalidDrop: 0, InvalidKeep: 1} {
+		node := NewExtractEntitiesNode("ex", &mockLLMClient{response: response}, ExtractEntitiesNodeConfig{
+			InputVar: "doc", Fields: personFields, OnInvalid: policy,
+		})
+		result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("doc", "x"))
+		if err != nil {
+			t.Fatalf("%s: Run: %v", policy, err)
+		}
+		out, _ := result.GetVar("ex_output")
+		if len(out.([]any)) != wantRecords {
+			t.Errorf("%s: output = %v", policy, out)
+		}
+	}
+
+	node := NewExtractEntitiesNode("ex", &mockLLMClient{response: response}, ExtractEntitiesNodeConfig{
+		InputVar: "doc", Fields: personFields, OnInvalid: InvalidFail,
+	})
+	_, err := node.Run(context.Background(), core.NewEnvelope().WithVar("doc", "x"))
+	if err == nil || !strings.Contains(err.Error(), "name: required") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestValidateEntityFields(t *testing.T) {
+	if err := ValidateEntityFields(personFields); err != nil {
+		t.Errorf("ValidateEntityFields: %v", err)
+	}
+	for _, bad := range [][]EntityField{
+		nil,
+		{{Name: "quote"}},
+		{{Name: "a"}, {Name: "a"}},
+		{{Name: "a", Type: "enum"}},
+		{{Name: "a", Type: "money"}},
+	} {
+		if err := ValidateEntityFields(bad); err == nil {
+			t.Errorf("ValidateEntityFields(%+v) = nil, want an error", bad)
+		}
+	}
+}
@@ -120,6 +120,11 @@ type FilterNodeConfig struct {
 
 	// StatsVar stores filter statistics (FilterStats).
 	StatsVar string
+
+	// Performance, when set, runs the filters in the large-collection
+	// mode described on CollectionPerformance. Custom filters still run
+	// on one goroutine, in order.
+	Performance *CollectionPerformance
 }
 
 // FilterNode prunes items from collections based on configured operations.
@@ -179,10 +184,17 @@ func (n *FilterNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelop
 	inputCount := len(items)
 
 	// Apply each filter operation in order
-	for i, op := range n.config.Filters {
-		items, err = n.applyFilter(ctx, result, items, op)
+	if n.config.Performance != nil {
+		items, err = n.filterFast(ctx, result, items)
 		if err != nil {
-			return nil, fmt.Errorf("filter node %s: filter %d (%s): %w", n.ID(), i, op.Type, err)
+			return nil, fmt.Errorf("filter node %s: %w", n.ID(), err)
+		}
+	} else {
+		for i, op := range n.config.Filters {
+			items, err = n.applyFilter(ctx, result, items, op)
+			if err != nil {
+				return nil, fmt.Errorf("filter node %s: filter %d (%s): %w", n.ID(), i, op.Type, err)
+			}
 		}
 	}
 
@@ -0,0 +1,353 @@
+package nodes
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// itemPredicate reports whether a filter keeps an item.
+type itemPredicate func(item any) bool
+
+// filterFast applies the filters in performance mode. The items are copied
+// once into a buffer the filters compact in place; runs of consecutive
+// per-item filters are fused into a single pass.
+func (n *FilterNode) filterFast(ctx context.Context, env *core.Envelope, items []any) ([]any, error) {
+	buf := make([]any, len(items))
+	copy(buf, items)
+
+	var fused []itemPredicate
+	flush := func() error {
+		if len(fused) == 0 {
+			return nil
+		}
+		var err error
+		buf, err = n.compact(ctx, buf, fused)
+		fused = fused[:0]
+		return err
+	}
+
+	for i, op := range n.config.Filters {
+		wrap := func(err error) error {
+			return fmt.Errorf("filter %d (%s): %w", i, op.Type, err)
+		}
+		switch op.Type {
+		case FilterOpThreshold, FilterOpByType, FilterOpMatch, FilterOpExclude:
+			pred, err := compileFilterPredicate(op)
+			if err != nil {
+				return nil, wrap(err)
+			}
+			if pred != nil {
+				fused = append(fused, pred)
+			}
+			continue
+		}
+
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		var err error
+		switch op.Type {
+		case FilterOpTopN:
+			buf = topNFast(buf, op)
+		case FilterOpDedupe:
+			buf, err = dedupeFast(buf, op)
+		case FilterOpCustom:
+			buf, err = n.customFast(ctx, env, buf, op)
+		default:
+			err = fmt.Errorf("unknown filter type: %s", op.Type)
+		}
+		if err != nil {
+			return nil, wrap(err)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// compileFilterPredicate builds the per-item test of a threshold, type,
+// match or exclude filter. A nil predicate keeps every item.
+func compileFilterPredicate(op FilterOp) (itemPredicate, error) {
+	switch op.Type {
+	case FilterOpThreshold:
+		if op.Min == nil && op.Max == nil {
+			return nil, nil
+		}
+		score := newFieldPath(op.ScoreField)
+		return func(item any) bool {
+			v, ok := score.lookupFloat(item)
+			if !ok {
+				return false
+			}
+			return (op.Min == nil || v >= *op.Min) && (op.Max == nil || v <= *op.Max)
+		}, nil
+
+	case FilterOpByType:
+		if len(op.IncludeTypes) == 0 && len(op.ExcludeTypes) == 0 {
+			return nil, nil
+		}
+		include := make(map[string]bool, len(op.IncludeTypes))
+		for _, t := range op.IncludeTypes {
+			include[t] = true
+		}
+		exclude := make(map[string]bool, len(op.ExcludeTypes))
+		for _, t := range op.ExcludeTypes {
+			exclude[t] = true
+		}
+		return func(item any) bool {
+			itemType := itemTypeOf(item)
+			if len(include) > 0 && !include[itemType] {
+				return false
+			}
+			return !exclude[itemType]
+		}, nil
+
+	case FilterOpMatch, FilterOpExclude:
+		var re *regexp.Regexp
+		if op.Pattern != "" {
+			var err error
+			if re, err = regexp.Compile(op.Pattern); err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", op.Pattern, err)
+			}
+		}
+		field := newFieldPath(op.Field)
+		keepMatches := op.Type == FilterOpMatch
+		return func(item any) bool {
+			matches := false
+			if val, found := field.lookup(item); found {
+				switch {
+				case re != nil:
+					matches = re.MatchString(dedupeKey(val))
+				case op.Value != nil:
+					matches = valuesEqual(val, op.Value)
+				default:
+					matches = true
+				}
+			}
+			return matches == keepMatches
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown filter type: %s", op.Type)
+}
+
+// itemTypeOf returns the type filterByType reads from an item.
+func itemTypeOf(item any) string {
+	switch v := item.(type) {
+	case core.Artifact:
+		return v.Type
+	case *core.Artifact:
+		return v.Type
+	case map[string]any:
+		if t, ok := v["Type"].(string); ok {
+			return t
+		}
+		if t, ok := v["type"].(string); ok {
+			return t
+		}
+	}
+	return ""
+}
+
+// compact keeps the items every predicate accepts, in order, reusing buf.
+// Large collections are tested in parallel partitions.
+func (n *FilterNode) compact(ctx context.Context, buf []any, preds []itemPredicate) ([]any, error) {
+	keepAll := func(item any) bool {
+		for _, pred := range preds {
+			if !pred(item) {
+				return false
+			}
+		}
+		return true
+	}
+
+	workers := n.config.Performance.workers(len(buf))
+	if workers <= 1 {
+		kept := 0
+		for i, item := range buf {
+			if i%checkEvery == 0 {
+				if err := ctx.Err(); err != nil {
+					return nil, err
+				}
+			}
+			if keepAll(item) {
+				buf[kept] = item
+				kept++
+			}
+		}
+		clear(buf[kept:])
+		return buf[:kept], nil
+	}
+
+	keep := make([]bool, len(buf))
+	err := partitionItems(ctx, len(buf), workers, func(ctx context.Context, lo, hi int) error {
+		for i := lo; i < hi; i++ {
+			if (i-lo)%checkEvery == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			keep[i] = keepAll(buf[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	kept := 0
+	for i, item := range buf {
+		if keep[i] {
+			buf[kept] = item
+			kept++
+		}
+	}
+	clear(buf[kept:])
+	return buf[:kept], nil
+}
+
+// scoredItem is an item with its precomputed top_n score.
+type scoredItem struct {
+	index int
+	score float64
+}
+
+// scoredHeap keeps the N best items seen so far with the worst on top.
+type scoredHeap struct {
+	items  []scoredItem
+	better func(a, b scoredItem) bool
+}
+
+func (h *scoredHeap) Len() int           { return len(h.items) }
+func (h *scoredHeap) Less(i, j int) bool { return h.better(h.items[j], h.items[i]) }
+func (h *scoredHeap) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
+func (h *scoredHeap) Push(x any)         { h.items = append(h.items, x.(scoredItem)) }
+func (h *scoredHeap) Pop() any {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+// topNFast selects the top N with a bounded heap instead of sorting every
+// item. Ties keep the earlier item.
+func topNFast(items []any, op FilterOp) []any {
+	if op.N <= 0 || len(items) <= op.N {
+		return items
+	}
+	desc := op.Order != "asc"
+	better := func(a, b scoredItem) bool {
+		if a.score != b.score {
+			if desc {
+				return a.score > b.score
+			}
+			return a.score < b.score
+		}
+		return a.index < b.index
+	}
+
+	score := newFieldPath(op.ScoreField)
+	h := &scoredHeap{items: make([]scoredItem, 0, op.N), better: better}
+	for i, item := range items {
+		s, _ := score.lookupFloat(item)
+		candidate := scoredItem{index: i, score: s}
+		if h.Len() < op.N {
+			heap.Push(h, candidate)
+			continue
+		}
+		if better(candidate, h.items[0]) {
+			h.items[0] = candidate
+			heap.Fix(h, 0)
+		}
+	}
+
+	result := make([]any, h.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = items[heap.Pop(h).(scoredItem).index]
+	}
+	return result
+}
+
+// dedupeFast matches filterDedupe, marking the kept index of each key so
+// the result comes out in order without sorting.
+func dedupeFast(items []any, op FilterOp) ([]any, error) {
+	if op.Field == "" {
+		return nil, fmt.Errorf("dedupe requires Field")
+	}
+	keep := op.Keep
+	if keep == "" {
+		keep = "first"
+	}
+
+	type entry struct {
+		index int
+		score float64
+	}
+	field := newFieldPath(op.Field)
+	score := newFieldPath(op.ScoreField)
+	seen := make(map[string]entry, len(items)/4)
+	for i, item := range items {
+		val, _ := field.lookup(item)
+		key := dedupeKey(val)
+
+		existing, exists := seen[key]
+		if !exists {
+			s := 0.0
+			if keep == "highest_score" && op.ScoreField != "" {
+				s, _ = score.lookupFloat(item)
+			}
+			seen[key] = entry{index: i, score: s}
+			continue
+		}
+		switch keep {
+		case "last":
+			seen[key] = entry{index: i, score: existing.score}
+		case "highest_score":
+			if s, _ := score.lookupFloat(item); s > existing.score {
+				seen[key] = entry{index: i, score: s}
+			}
+		}
+	}
+
+	kept := make([]bool, len(items))
+	for _, e := range seen {
+		kept[e.index] = true
+	}
+	out := 0
+	for i, item := range items {
+		if kept[i] {
+			items[out] = item
+			out++
+		}
+	}
+	clear(items[out:])
+	return items[:out], nil
+}
+
+// customFast runs a custom filter in order on one goroutine, compacting in
+// place.
+func (n *FilterNode) customFast(ctx context.Context, env *core.Envelope, items []any, op FilterOp) ([]any, error) {
+	if op.CustomFunc == nil {
+		return nil, fmt.Errorf("custom filter requires CustomFunc")
+	}
+	kept := 0
+	for i, item := range items {
+		if i%checkEvery == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		keep, err := op.CustomFunc(item, i, env)
+		if err != nil {
+			return nil, fmt.Errorf("custom filter error at index %d: %w", i, err)
+		}
+		if keep {
+			items[kept] = item
+			kept++
+		}
+	}
+	clear(items[kept:])
+	return items[:kept], nil
+}
@@ -1,16 +1,15 @@
 package nodes
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"sync"
-	"text/template"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 // HumanRequestType specifies what kind of human input is needed.
@@ -31,6 +30,9 @@ const (
 
 	// HumanRequestReview is reviewing with notes.
 	HumanRequestReview HumanRequestType = "review"
+
+	// HumanRequestForm is filling in the structured fields of a form.
+	HumanRequestForm HumanRequestType = "form"
 )
 
 // HumanTimeoutAction specifies behavior when timeout is reached.
@@ -62,6 +64,7 @@ type HumanRequest struct {
 	Data        any              `json:"data,omitempty"`
 	Options     []HumanOption    `json:"options,omitempty"`
 	Schema      map[string]any   `json:"schema,omitempty"`
+	Fields      []HumanField     `json:"fields,omitempty"`
 	Timeout     time.Duration    `json:"timeout,omitempty"`
 	CreatedAt   time.Time        `json:"created_at"`
 	EnvelopeRef string           `json:"envelope_ref,omitempty"`
@@ -97,6 +100,10 @@ type HumanNodeConfig struct {
 	// If set, overrides Prompt.
 	PromptTemplate string
 
+	// TemplateSandbox, when set, restricts what PromptTemplate may call and
+	// how much it may render.
+	TemplateSandbox *TemplateSandbox
+
 	// InputVars specifies which variables to show for review.
 	InputVars []string
 
@@ -109,6 +116,11 @@ type HumanNodeConfig struct {
 	// Schema specifies expected response format (for edit type).
 	Schema map[string]any
 
+	// Fields describes a structured form. When set, HumanResponse.Data must
+	// be an object that satisfies the fields, and each typed value is
+	// stored in its own envelope variable (see HumanField.Var).
+	Fields []HumanField
+
 	// Timeout is the maximum wait time (0 = no timeout).
 	Timeout time.Duration
 
@@ -179,6 +191,7 @@ func (n *HumanNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope
 		Data:        data,
 		Options:     n.config.Options,
 		Schema:      n.config.Schema,
+		Fields:      n.config.Fields,
 		Timeout:     n.config.Timeout,
 		CreatedAt:   time.Now(),
 		EnvelopeRef: env.Trace.RunID,
@@ -192,7 +205,15 @@ func (n *HumanNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope
 	}
 
 	// Request human input
+	endWait := runtime.BeginWait(ctx, runtime.Wait{
+		RunID:    env.Trace.RunID,
+		NodeID:   n.ID(),
+		NodeKind: n.Kind(),
+		Reason:   runtime.WaitReasonHuman,
+		Details:  map[string]any{"request_id": req.ID, "request_type": string(req.Type)},
+	})
 	resp, err := n.config.Handler.Request(ctx, req)
+	endWait()
 
 	// Handle timeout
 	if err != nil && ctx.Err() == context.DeadlineExceeded {
@@ -203,8 +224,17 @@ func (n *HumanNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope
 		return nil, fmt.Errorf("human node %s: handler error: %w", n.ID(), err)
 	}
 
+	var fieldValues map[string]any
+	if len(n.config.Fields) > 0 {
+		fieldValues, err = validateHumanResponseFields(n.config.Fields, resp)
+		if err != nil {
+			return nil, fmt.Errorf("human node %s: %w", n.ID(), err)
+		}
+	}
+
 	// Clone envelope for result
 	result := env.Clone()
+	n.setFieldVars(result, fieldValues)
 
 	// Store response if configured
 	if n.config.OutputVar != "" {
@@ -217,7 +247,7 @@ func (n *HumanNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope
 	}
 
 	// For edit type, merge edited data back into envelope
-	if n.config.RequestType == HumanRequestEdit && resp.Data != nil {
+	if n.config.RequestType == HumanRequestEdit && len(n.config.Fields) == 0 && resp.Data != nil {
 		if editedMap, ok := resp.Data.(map[string]any); ok {
 			for k, v := range editedMap {
 				result.SetVar(k, v)
@@ -232,23 +262,17 @@ func (n *HumanNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope
 func (n *HumanNode) buildPrompt(env *core.Envelope) (string, error) {
 	// Use template if provided
 	if n.config.PromptTemplate != "" {
-		tmpl, err := template.New("prompt").Parse(n.config.PromptTemplate)
+		tmpl, err := parseNodeTemplate(n.config.TemplateSandbox, "prompt", n.config.PromptTemplate, localeTemplateFuncs(EnvelopeLocale(env)))
 		if err != nil {
 			return "", fmt.Errorf("invalid prompt template: %w", err)
 		}
 
-		data := map[string]any{
-			"input": env.Input,
-			"vars":  env.Vars,
-			"trace": env.Trace,
-		}
-
-		var buf bytes.Buffer
-		if err := tmpl.Execute(&buf, data); err != nil {
+		prompt, err := tmpl.render(humanTemplateData(env))
+		if err != nil {
 			return "", fmt.Errorf("template execution failed: %w", err)
 		}
 
-		return buf.String(), nil
+		return prompt, nil
 	}
 
 	// Use static prompt
@@ -275,6 +299,31 @@ func (n *HumanNode) buildRequestData(env *core.Envelope) map[string]any {
 	return data
 }
 
+// setFieldVars stores each validated form value in its field's variable.
+func (n *HumanNode) setFieldVars(env *core.Envelope, values map[string]any) {
+	for _, f := range n.config.Fields {
+		if v, ok := values[f.Name]; ok {
+			env.SetVar(f.VarName(), v)
+		}
+	}
+}
+
+// validateHumanResponseFields validates resp.Data against fields.
+func validateHumanResponseFields(fields []HumanField, resp *HumanResponse) (map[string]any, error) {
+	if resp == nil {
+		return ValidateHumanFields(fields, nil)
+	}
+	var data map[string]any
+	if resp.Data != nil {
+		m, ok := resp.Data.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("invalid form response: data must be an object, got %T", resp.Data)
+		}
+		data = m
+	}
+	return ValidateHumanFields(fields, data)
+}
+
 // handleTimeout handles timeout based on configured action.
 func (n *HumanNode) handleTimeout(env *core.Envelope, req *HumanRequest) (*core.Envelope, error) {
 	switch n.config.OnTimeout {
@@ -299,6 +348,11 @@ func (n *HumanNode) handleTimeout(env *core.Envelope, req *HumanRequest) (*core.
 		if n.config.OutputVar != "" {
 			result.SetVar(n.config.OutputVar, resp)
 		}
+		// Form fields fall back to their defaults when every required
+		// field has one; otherwise they are left unset.
+		if defaults, err := ValidateHumanFields(n.config.Fields, nil); err == nil {
+			n.setFieldVars(result, defaults)
+		}
 		return result, nil
 
 	case HumanTimeoutSkip:
@@ -384,14 +438,21 @@ func (h *ChannelHumanHandler) Requests() <-chan *HumanRequest {
 }
 
 // Respond sends a response to a pending request.
+// Responses to form requests are validated first; an invalid response is
+// rejected and the request stays pending.
 func (h *ChannelHumanHandler) Respond(resp *HumanResponse) error {
 	h.mu.Lock()
-	_, exists := h.pending[resp.RequestID]
+	req, exists := h.pending[resp.RequestID]
 	h.mu.Unlock()
 
 	if !exists {
 		return fmt.Errorf("no pending request with ID: %s", resp.RequestID)
 	}
+	if len(req.Fields) > 0 {
+		if _, err := validateHumanResponseFields(req.Fields, resp); err != nil {
+			return err
+		}
+	}
 
 	h.responses <- resp
 	return nil
@@ -560,7 +621,9 @@ func (h *QueuedHumanHandler) GetRequest(id string) (*HumanRequest, bool) {
 	return qr.Request, true
 }
 
-// Respond sends a response to a pending request.
+// Respond sends a response to a pending request. Responses to form
+// requests are validated first; an invalid response is rejected and the
+// request stays pending so the caller can correct it.
 func (h *QueuedHumanHandler) Respond(id string, resp *HumanResponse) error {
 	h.mu.RLock()
 	qr, ok := h.requests[id]
@@ -569,6 +632,11 @@ func (h *QueuedHumanHandler) Respond(id string, resp *HumanResponse) error {
 	if !ok {
 		return fmt.Errorf("no pending request with ID: %s", id)
 	}
+	if len(qr.Request.Fields) > 0 {
+		if _, err := validateHumanResponseFields(qr.Request.Fields, resp); err != nil {
+			return err
+		}
+	}
 
 	resp.RequestID = id
 	if resp.RespondedAt.IsZero() {
@@ -0,0 +1,226 @@
+package nodes
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HumanFieldType is the value type of a HumanField.
+type HumanFieldType string
+
+const (
+	// HumanFieldString accepts any string.
+	HumanFieldString HumanFieldType = "string"
+
+	// HumanFieldNumber accepts a number (stored as float64).
+	HumanFieldNumber HumanFieldType = "number"
+
+	// HumanFieldInteger accepts a whole number (stored as int).
+	HumanFieldInteger HumanFieldType = "integer"
+
+	// HumanFieldBoolean accepts true or false.
+	HumanFieldBoolean HumanFieldType = "boolean"
+
+	// HumanFieldChoice accepts one of Options.
+	HumanFieldChoice HumanFieldType = "choice"
+)
+
+// HumanField describes one input of a structured human form. Handlers use
+// the field list to render a form; responses are validated against it and
+// each value is stored in its own envelope variable.
+type HumanField struct {
+	// Name keys the value in HumanResponse.Data.
+	Name string `json:"name"`
+
+	// Type defaults to HumanFieldString.
+	Type HumanFieldType `json:"type,omitempty"`
+
+	// Label is shown to the human; defaults to Name.
+	Label string `json:"label,omitempty"`
+
+	// Required fields must be present unless Default is set.
+	Required bool `json:"required,omitempty"`
+
+	// Options lists the allowed values for choice fields.
+	Options []string `json:"options,omitempty"`
+
+	// Default is used when the response omits the field.
+	Default any `json:"default,omitempty"`
+
+	// Var names the envelope variable for the value; defaults to Name.
+	Var string `json:"var,omitempty"`
+}
+
+// HumanFieldErrors lists every field that failed validation.
+type HumanFieldErrors []string
+
+func (e HumanFieldErrors) Error() string {
+	return "invalid form response: " + strings.Join(e, "; ")
+}
+
+// ValidateHumanFieldDefs checks that a field list is well formed.
+func ValidateHumanFieldDefs(fields []HumanField) error {
+	seen := make(map[string]bool, len(fields))
+	for i, f := range fields {
+		if strings.TrimSpace(f.Name) == "" {
+			return fmt.Errorf("fields[%d]: name is required", i)
+		}
+		if seen[f.Name] {
+			return fmt.Errorf("fields[%d]: duplicate field %q", i, f.Name)
+		}
+		seen[f.Name] = true
+
+		switch f.fieldType() {
+		case HumanFieldString, HumanFieldNumber, HumanFieldInteger, HumanFieldBoolean:
+		case HumanFieldChoice:
+			if len(f.Options) == 0 {
+				return fmt.Errorf("fields[%d]: choice field %q needs options", i, f.Name)
+			}
+		default:
+			return fmt.Errorf("fields[%d]: unknown type %q", i, f.Type)
+		}
+
+		if f.Default != nil {
+			if _, err := f.coerce(f.Default); err != nil {
+				return fmt.Errorf("fields[%d]: default: %w", i, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ValidateHumanFields checks data against fields and returns the typed
+// values keyed by field name. Missing fields take their default; string
+// values are parsed for number, integer and boolean fields so text-only
+// channels can submit forms. Fields not in the schema are rejected.
+func ValidateHumanFields(fields []HumanField, data map[string]any) (map[string]any, error) {
+	var errs HumanFieldErrors
+	values := make(map[string]any, len(fields))
+	known := make(map[string]bool, len(fields))
+
+	for _, f := range fields {
+		known[f.Name] = true
+		raw, ok := data[f.Name]
+		if !ok || raw == nil || raw == "" {
+			switch {
+			case f.Default != nil:
+				raw = f.Default
+			case f.Required:
+				errs = append(errs, fmt.Sprintf("%s: required", f.Name))
+				continue
+			default:
+				continue
+			}
+		}
+		v, err := f.coerce(raw)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", f.Name, err))
+			continue
+		}
+		values[f.Name] = v
+	}
+
+	var unknown []string
+	for name := range data {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, fmt.Sprintf("%s: unknown field", name))
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return values, nil
+}
+
+func (f HumanField) fieldType() HumanFieldType {
+	if f.Type == "" {
+		return HumanFieldString
+	}
+	return f.Type
+}
+
+// VarName returns the envelope variable that receives the field's value.
+func (f HumanField) VarName() string {
+	if f.Var != "" {
+		return f.Var
+	}
+	return f.Name
+}
+
+func (f HumanField) coerce(raw any) (any, error) {
+	switch f.fieldType() {
+	case HumanFieldString:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected string, got %T", raw)
+		}
+		return s, nil
+
+	case HumanFieldNumber:
+		return coerceHumanNumber(raw)
+
+	case HumanFieldInteger:
+		n, err := coerceHumanNumber(raw)
+		if err != nil {
+			return nil, err
+		}
+		if n != math.Trunc(n) {
+			return nil, fmt.Errorf("expected integer, got %v", n)
+		}
+		return int(n), nil
+
+	case HumanFieldBoolean:
+		switch v := raw.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("expected boolean, got %q", v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected boolean, got %T", raw)
+
+	case HumanFieldChoice:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected one of %v, got %T", f.Options, raw)
+		}
+		for _, opt := range f.Options {
+			if s == opt {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("expected one of %v, got %q", f.Options, s)
+	}
+	return nil, fmt.Errorf("unknown type %q", f.Type)
+}
+
+func coerceHumanNumber(raw any) (float64, error) {
+	switch v := raw.(type) {
+	case float64:
+		return v, nil
+	case float32:
+		return float64(v), nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("expected number, got %q", v)
+		}
+		return n, nil
+	}
+	return 0, fmt.Errorf("expected number, got %T", raw)
+}
@@ -0,0 +1,152 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+var refundFormFields = []HumanField{
+	{Name: "amount", Type: HumanFieldNumber, Required: true, Var: "refund_amount"},
+	{Name: "reason", Type: HumanFieldChoice, Required: true, Options: []string{"damaged", "late", "other"}},
+	{Name: "notify", Type: HumanFieldBoolean, Default: true},
+	{Name: "items", Type: HumanFieldInteger},
+	{Name: "comment"},
+}
+
+func TestValidateHumanFields(t *testing.T) {
+	values, err := ValidateHumanFields(refundFormFields, map[string]any{
+		"amount": "12.50",
+		"reason": "late",
+		"items":  float64(3),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if values["amount"] != 12.5 || values["reason"] != "late" || values["notify"] != true || values["items"] != 3 {
+		t.Errorf("values = %#v", values)
+	}
+	if _, ok := values["comment"]; ok {
+		t.Error("optional field without default should be omitted")
+	}
+
+	_, err = ValidateHumanFields(refundFormFields, map[string]any{
+		"reason": "unhappy",
+		"items":  1.5,
+		"extra":  "x",
+	})
+	var fieldErrs HumanFieldErrors
+	if !errors.As(err, &fieldErrs) {
+		t.Fatalf("expected HumanFieldErrors, got %v", err)
+	}
+	want := []string{"amount: required", "reason: expected one of", "items: expected integer", "extra: unknown field"}
+	if len(fieldErrs) != len(want) {
+		t.Fatalf("errors = %v, want %d entries", fieldErrs, len(want))
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(fieldErrs[i], prefix) {
+			t.Errorf("error[%d] = %q, want prefix %q", i, fieldErrs[i], prefix)
+		}
+	}
+}
+
+func TestValidateHumanFieldDefs(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []HumanField
+		errSub string
+	}{
+		{"missing name", []HumanField{{Type: HumanFieldString}}, "name is required"},
+		{"duplicate", []HumanField{{Name: "a"}, {Name: "a"}}, "duplicate"},
+		{"unknown type", []HumanField{{Name: "a", Type: "date"}}, "unknown type"},
+		{"choice without options", []HumanField{{Name: "a", Type: HumanFieldChoice}}, "needs options"},
+		{"bad default", []HumanField{{Name: "a", Type: HumanFieldInteger, Default: "x"}}, "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateHumanFieldDefs(tt.fields)
+			if err == nil || !strings.Contains(err.Error(), tt.errSub) {
+				t.Fatalf("error = %v, want containing %q", err, tt.errSub)
+			}
+		})
+	}
+	if err := ValidateHumanFieldDefs(refundFormFields); err != nil {
+		t.Fatalf("valid fields rejected: %v", err)
+	}
+}
+
+func TestHumanNode_FormFieldsLandInVars(t *testing.T) {
+	var seen *HumanRequest
+	handler := NewCallbackHumanHandler(func(_ context.Context, req *HumanRequest) (*HumanResponse, error) {
+		seen = req
+		return &HumanResponse{RequestID: req.ID, Data: map[string]any{"amount": 40.0, "reason": "damaged"}}, nil
+	})
+	node := NewHumanNode("refund", HumanNodeConfig{
+		RequestType: HumanRequestForm,
+		OutputVar:   "refund_response",
+		Fields:      refundFormFields,
+		Handler:     handler,
+	})
+
+	result, err := node.Run(context.Background(), core.NewEnvelope())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen.Fields) != len(refundFormFields) {
+		t.Errorf("request should carry the field schema, got %d fields", len(seen.Fields))
+	}
+	if v, _ := result.GetVar("refund_amount"); v != 40.0 {
+		t.Errorf("refund_amount = %v", v)
+	}
+	if v, _ := result.GetVar("reason"); v != "damaged" {
+		t.Errorf("reason = %v", v)
+	}
+	if v, _ := result.GetVar("notify"); v != true {
+		t.Errorf("notify = %v, want default true", v)
+	}
+}
+
+func TestHumanNode_FormRejectsInvalidResponse(t *testing.T) {
+	handler := &AutoApproveHandler{Approved: true, Data: map[string]any{"reason": "late"}}
+	node := NewHumanNode("refund", HumanNodeConfig{
+		RequestType: HumanRequestForm,
+		Fields:      refundFormFields,
+		Handler:     handler,
+	})
+
+	_, err := node.Run(context.Background(), core.NewEnvelope())
+	if err == nil || !strings.Contains(err.Error(), "amount: required") {
+		t.Fatalf("error = %v, want amount required", err)
+	}
+}
+
+func TestQueuedHumanHandler_RespondValidatesFields(t *testing.T) {
+	handler := NewQueuedHumanHandler()
+	done := make(chan *HumanResponse)
+	go func() {
+		resp, _ := handler.Request(context.Background(), &HumanRequest{ID: "req-1", Type: HumanRequestForm, Fields: refundFormFields})
+		done <- resp
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for len(handler.ListPending()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := handler.Respond("req-1", &HumanResponse{Data: map[string]any{"amount": "lots"}}); err == nil {
+		t.Fatal("expected invalid response to be rejected")
+	}
+	if len(handler.ListPending()) != 1 {
+		t.Fatal("request should stay pending after an invalid response")
+	}
+	if err := handler.Respond("req-1", &HumanResponse{Data: map[string]any{"amount": 5, "reason": "other"}}); err != nil {
+		t.Fatalf("valid response rejected: %v", err)
+	}
+	if resp := <-done; resp == nil {
+		t.Fatal("expected a response")
+	}
+}
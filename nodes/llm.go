@@ -1,11 +1,9 @@
 package nodes
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"strings"
-	"text/template"
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
@@ -52,6 +50,42 @@ type LLMNodeConfig struct {
 
 	// RecordMessages appends the conversation to envelope.Messages.
 	RecordMessages bool
+
+	// DriftGuard, when set, compares each output with previous outputs of
+	// the same node and flags or fails drastic changes.
+	DriftGuard *DriftGuardConfig
+
+	// TemplateSandbox, when set, restricts what PromptTemplate may call and
+	// how much it may render.
+	TemplateSandbox *TemplateSandbox
+
+	// PromptCache, when set, marks the stable prefix of the prompt as
+	// cacheable by providers that support prompt caching.
+	PromptCache *PromptCacheConfig
+
+	// TemplateVars gives top-level PromptTemplate variables a default or
+	// marks them required, keyed by name.
+	TemplateVars map[string]TemplateVar
+}
+
+// PromptCacheConfig selects the parts of an LLM node's prompt that stay the
+// same between runs. Providers cache them, so later calls bill those input
+// tokens at the cache rate; the cached and written token counts land in
+// "<output_key>_usage".
+type PromptCacheConfig struct {
+	// System caches the system prompt.
+	System bool
+
+	// ContextVars names envelope variables, such as long reference
+	// documents, sent ahead of the prompt as a cached message.
+	ContextVars []string
+
+	// TTL requests a cache lifetime. Anthropic supports the default five
+	// minutes and one hour; OpenAI ignores it.
+	TTL time.Duration
+
+	// Key groups calls that share the prefix. Defaults to the node ID.
+	Key string
 }
 
 // LLMNode executes an LLM call as a workflow step.
@@ -107,27 +141,19 @@ func (n *LLMNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope,
 
 // runSync executes a synchronous (non-streaming) LLM call.
 func (n *LLMNode) runSync(ctx context.Context, env *core.Envelope, emit runtime.EventEmitter, prompt string) (*core.Envelope, error) {
-	// Build the LLM request
-	req := core.LLMRequest{
-		Model:      n.config.Model,
-		System:     n.config.System,
-		InputText:  prompt,
-		JSONSchema: n.config.JSONSchema,
-	}
-
-	if n.config.Temperature != nil {
-		req.Temperature = n.config.Temperature
-	}
-	if n.config.MaxTokens != nil {
-		req.MaxTokens = n.config.MaxTokens
-	}
+	req := n.buildRequest(env, prompt)
 
 	// Execute with retries
 	var resp core.LLMResponse
 	var lastErr error
+	attempts := 0
 
 	for attempt := 1; attempt <= n.config.RetryPolicy.MaxAttempts; attempt++ {
-		resp, lastErr = n.client.Complete(ctx, req)
+		attempts = attempt
+		lastErr = runtime.InjectProviderFault(ctx, env.Trace.RunID, n.ID(), n.Kind())
+		if lastErr == nil {
+			resp, lastErr = n.client.Complete(ctx, req)
+		}
 		if lastErr == nil {
 			break
 		}
@@ -137,6 +163,11 @@ func (n *LLMNode) runSync(ctx context.Context, env *core.Envelope, emit runtime.
 			return nil, ctx.Err()
 		}
 
+		// A bad key or an oversized prompt fails the same way every time.
+		if pe, ok := core.AsProviderError(lastErr); ok && !pe.Code.Retryable() {
+			break
+		}
+
 		// Wait before retry (except on last attempt)
 		if attempt < n.config.RetryPolicy.MaxAttempts {
 			select {
@@ -148,7 +179,7 @@ func (n *LLMNode) runSync(ctx context.Context, env *core.Envelope, emit runtime.
 	}
 
 	if lastErr != nil {
-		return nil, fmt.Errorf("LLM call failed after %d attempts: %w", n.config.RetryPolicy.MaxAttempts, lastErr)
+		return nil, fmt.Errorf("LLM call failed after %d attempts: %w", attempts, lastErr)
 	}
 
 	// Check budget if configured
@@ -159,24 +190,32 @@ func (n *LLMNode) runSync(ctx context.Context, env *core.Envelope, emit runtime.
 	}
 
 	// Emit node.output.final event
-	emit(runtime.NewEvent(runtime.EventNodeOutputFinal, env.Trace.RunID).
+	final := runtime.NewEvent(runtime.EventNodeOutputFinal, env.Trace.RunID).
 		WithNode(n.ID(), n.Kind()).
-		WithPayload("text", resp.Text))
+		WithPayload("text", resp.Text).
+		WithPayload("input_tokens", resp.Usage.InputTokens).
+		WithPayload("output_tokens", resp.Usage.OutputTokens).
+		WithPayload("total_tokens", resp.Usage.TotalTokens).
+		WithPayload("cost_usd", resp.Usage.CostUSD).
+		WithPayload("cache_read_tokens", resp.Usage.CacheReadTokens).
+		WithPayload("cache_write_tokens", resp.Usage.CacheWriteTokens)
+	if resp.Region != "" {
+		final = final.WithPayload("region", resp.Region)
+	}
+	emit(final)
 
 	// Store output in envelope
+	var output any = resp.Text
 	if n.config.JSONSchema != nil && resp.JSON != nil {
-		env.SetVar(n.config.OutputKey, resp.JSON)
-	} else {
-		env.SetVar(n.config.OutputKey, resp.Text)
+		output = resp.JSON
+	}
+	env.SetVar(n.config.OutputKey, output)
+	if err := n.guardDrift(ctx, env, emit, output); err != nil {
+		return nil, err
 	}
 
 	// Record token usage
-	env.SetVar(n.config.OutputKey+"_usage", core.TokenUsage{
-		InputTokens:  resp.Usage.InputTokens,
-		OutputTokens: resp.Usage.OutputTokens,
-		TotalTokens:  resp.Usage.TotalTokens,
-		CostUSD:      resp.Usage.CostUSD,
-	})
+	env.SetVar(n.config.OutputKey+"_usage", core.TokenUsage(resp.Usage))
 
 	// Record messages if configured
 	if n.config.RecordMessages {
@@ -201,22 +240,12 @@ func (n *LLMNode) runSync(ctx context.Context, env *core.Envelope, emit runtime.
 
 // runStreaming executes a streaming LLM call, emitting delta events for each chunk.
 func (n *LLMNode) runStreaming(ctx context.Context, env *core.Envelope, streamClient core.StreamingLLMClient, emit runtime.EventEmitter, prompt string) (*core.Envelope, error) {
-	// Build the LLM request
-	req := core.LLMRequest{
-		Model:      n.config.Model,
-		System:     n.config.System,
-		InputText:  prompt,
-		JSONSchema: n.config.JSONSchema,
-	}
-
-	if n.config.Temperature != nil {
-		req.Temperature = n.config.Temperature
-	}
-	if n.config.MaxTokens != nil {
-		req.MaxTokens = n.config.MaxTokens
-	}
+	req := n.buildRequest(env, prompt)
 
 	// Start streaming
+	if err := runtime.InjectProviderFault(ctx, env.Trace.RunID, n.ID(), n.Kind()); err != nil {
+		return nil, fmt.Errorf("streaming LLM call failed: %w", err)
+	}
 	ch, err := streamClient.CompleteStream(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("streaming LLM call failed: %w", err)
@@ -225,6 +254,7 @@ func (n *LLMNode) runStreaming(ctx context.Context, env *core.Envelope, streamCl
 	// Read chunks, accumulate text, emit delta events
 	var accumulated strings.Builder
 	var usage core.LLMTokenUsage
+	var region string
 
 	for chunk := range ch {
 		// Handle chunk errors
@@ -237,6 +267,7 @@ func (n *LLMNode) runStreaming(ctx context.Context, env *core.Envelope, streamCl
 			if chunk.Usage != nil {
 				usage = *chunk.Usage
 			}
+			region = chunk.Region
 			break
 		}
 
@@ -260,12 +291,25 @@ func (n *LLMNode) runStreaming(ctx context.Context, env *core.Envelope, streamCl
 	}
 
 	// Emit node.output.final event
-	emit(runtime.NewEvent(runtime.EventNodeOutputFinal, env.Trace.RunID).
+	final := runtime.NewEvent(runtime.EventNodeOutputFinal, env.Trace.RunID).
 		WithNode(n.ID(), n.Kind()).
-		WithPayload("text", text))
+		WithPayload("text", text).
+		WithPayload("input_tokens", usage.InputTokens).
+		WithPayload("output_tokens", usage.OutputTokens).
+		WithPayload("total_tokens", usage.TotalTokens).
+		WithPayload("cost_usd", usage.CostUSD).
+		WithPayload("cache_read_tokens", usage.CacheReadTokens).
+		WithPayload("cache_write_tokens", usage.CacheWriteTokens)
+	if region != "" {
+		final = final.WithPayload("region", region)
+	}
+	emit(final)
 
 	// Store output in envelope
 	env.SetVar(n.config.OutputKey, text)
+	if err := n.guardDrift(ctx, env, emit, text); err != nil {
+		return nil, err
+	}
 
 	// Record token usage
 	env.SetVar(n.config.OutputKey+"_usage", core.TokenUsage(usage))
@@ -287,6 +331,80 @@ func (n *LLMNode) runStreaming(ctx context.Context, env *core.Envelope, streamCl
 	return env, nil
 }
 
+// buildRequest builds the LLM request for prompt.
+func (n *LLMNode) buildRequest(env *core.Envelope, prompt string) core.LLMRequest {
+	req := core.LLMRequest{
+		Model:      n.config.Model,
+		System:     n.config.System,
+		InputText:  prompt,
+		JSONSchema: n.config.JSONSchema,
+	}
+
+	if n.config.Temperature != nil {
+		req.Temperature = n.config.Temperature
+	}
+	if n.config.MaxTokens != nil {
+		req.MaxTokens = n.config.MaxTokens
+	}
+
+	if pc := n.config.PromptCache; pc != nil {
+		req.Cache = &core.LLMCacheHints{
+			System: pc.System && req.System != "",
+			TTL:    pc.TTL,
+			Key:    pc.Key,
+		}
+		if req.Cache.Key == "" {
+			req.Cache.Key = n.ID()
+		}
+		var parts []string
+		for _, name := range pc.ContextVars {
+			if val, ok := env.GetVar(name); ok {
+				parts = append(parts, toString(val))
+			}
+		}
+		if len(parts) > 0 {
+			req.Messages = []core.LLMMessage{{Role: "user", Content: strings.Join(parts, "\n\n")}}
+			req.Cache.Messages = 1
+		}
+	}
+
+	return req
+}
+
+// guardDrift runs the drift guard, when configured, on the node's output.
+// The report is stored in "<output_key>_drift".
+func (n *LLMNode) guardDrift(ctx context.Context, env *core.Envelope, emit runtime.EventEmitter, output any) error {
+	if n.config.DriftGuard == nil || n.config.DriftGuard.Store == nil {
+		return nil
+	}
+	cfg := *n.config.DriftGuard
+	if cfg.Key == "" {
+		cfg.Key = n.ID()
+	}
+	report, fail, err := guardDrift(ctx, cfg, output)
+	if err != nil {
+		return err
+	}
+	env.SetVar(n.config.OutputKey+"_drift", report)
+	if !report.Drifted {
+		return nil
+	}
+
+	action := cfg.Action
+	if action == "" {
+		action = DriftActionFlag
+	}
+	emit(runtime.NewEvent(runtime.EventNodeOutputDrift, env.Trace.RunID).
+		WithNode(n.ID(), n.Kind()).
+		WithPayload("key", report.Key).
+		WithPayload("action", action).
+		WithPayload("deviations", report.Deviations))
+	if fail {
+		return fmt.Errorf("output drift detected: %s", report.summary())
+	}
+	return nil
+}
+
 // buildPrompt constructs the prompt from envelope variables.
 func (n *LLMNode) buildPrompt(env *core.Envelope) (string, error) {
 	// If a template is provided, use it
@@ -307,29 +425,21 @@ func (n *LLMNode) buildPrompt(env *core.Envelope) (string, error) {
 
 // executeTemplate executes the prompt template with envelope variables.
 func (n *LLMNode) executeTemplate(env *core.Envelope) (string, error) {
-	tmpl, err := template.New("prompt").Parse(n.config.PromptTemplate)
+	tmpl, err := parseNodeTemplate(n.config.TemplateSandbox, "prompt", n.config.PromptTemplate, localeTemplateFuncs(EnvelopeLocale(env)))
 	if err != nil {
 		return "", fmt.Errorf("invalid prompt template: %w", err)
 	}
 
-	// Create template data from vars
-	data := make(map[string]any)
-	if env.Vars != nil {
-		for k, v := range env.Vars {
-			data[k] = v
-		}
-	}
-	// Also add input if present
-	if env.Input != nil {
-		data["input"] = env.Input
+	data := llmTemplateData(env)
+	if err := applyTemplateVars(data, n.config.TemplateVars); err != nil {
+		return "", err
 	}
-
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
+	out, err := tmpl.render(data)
+	if err != nil {
 		return "", fmt.Errorf("template execution failed: %w", err)
 	}
 
-	return buf.String(), nil
+	return out, nil
 }
 
 // checkBudget verifies the response is within budget limits.
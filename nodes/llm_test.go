@@ -3,10 +3,12 @@ package nodes
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
 	"github.com/petal-labs/petalflow/runtime"
 )
 
@@ -117,6 +119,74 @@ func TestLLMNode_Run_SimplePrompt(t *testing.T) {
 	}
 }
 
+func TestLLMNode_Run_PromptCache(t *testing.T) {
+	client := &mockLLMClient{
+		response: core.LLMResponse{
+			Text:  "Twenty days.",
+			Usage: core.LLMTokenUsage{InputTokens: 40, OutputTokens: 4, TotalTokens: 44, CacheReadTokens: 2048},
+		},
+	}
+
+	node := NewLLMNode("answer", client, LLMNodeConfig{
+		Model:     "claude-haiku-4-5",
+		System:    "Answer from the handbook.",
+		InputVars: []string{"question"},
+		OutputKey: "answer",
+		PromptCache: &PromptCacheConfig{
+			System:      true,
+			ContextVars: []string{"handbook", "missing", "appendix"},
+			TTL:         time.Hour,
+		},
+	})
+
+	env := core.NewEnvelope().
+		WithVar("question", "How much leave do I get?").
+		WithVar("handbook", "Employees get twenty days of leave.").
+		WithVar("appendix", "Leave carries over.")
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	req := client.requests[0]
+	if req.Cache == nil {
+		t.Fatal("expected cache hints")
+	}
+	want := core.LLMCacheHints{System: true, Messages: 1, TTL: time.Hour, Key: "answer"}
+	if *req.Cache != want {
+		t.Errorf("Cache = %+v, want %+v", *req.Cache, want)
+	}
+	if len(req.Messages) != 1 || req.Messages[0].Role != "user" ||
+		req.Messages[0].Content != "Employees get twenty days of leave.\n\nLeave carries over." {
+		t.Errorf("Messages = %+v", req.Messages)
+	}
+	if req.InputText != "How much leave do I get?" {
+		t.Errorf("InputText = %q", req.InputText)
+	}
+
+	usage, _ := result.GetVar("answer_usage")
+	if got := usage.(core.TokenUsage).CacheReadTokens; got != 2048 {
+		t.Errorf("CacheReadTokens = %d, want 2048", got)
+	}
+
+	// Without context vars and a system prompt nothing is marked, but the
+	// key still lets providers route the call to a warm cache.
+	client.requests = nil
+	node = NewLLMNode("plain", client, LLMNodeConfig{
+		InputVars:   []string{"question"},
+		PromptCache: &PromptCacheConfig{System: true, ContextVars: []string{"missing"}, Key: "faq"},
+	})
+	if _, err := node.Run(context.Background(), env); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := *client.requests[0].Cache; got != (core.LLMCacheHints{Key: "faq"}) {
+		t.Errorf("Cache = %+v", got)
+	}
+	if len(client.requests[0].Messages) != 0 {
+		t.Errorf("Messages = %+v, want none", client.requests[0].Messages)
+	}
+}
+
 func TestLLMNode_Run_WithTemplate(t *testing.T) {
 	client := &mockLLMClient{
 		response: core.LLMResponse{Text: "Answer"},
@@ -147,6 +217,38 @@ func TestLLMNode_Run_WithTemplate(t *testing.T) {
 	}
 }
 
+func TestLLMNode_Run_TemplateVars(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{Text: "Answer"}}
+	node := NewLLMNode("test", client, LLMNodeConfig{
+		PromptTemplate: "{{.tone}}: {{.question}} ({{.customer}})",
+		TemplateVars: map[string]TemplateVar{
+			"tone":     {Default: "Friendly"},
+			"question": {Required: true},
+			"customer": {Required: true},
+		},
+	})
+
+	_, err := node.Run(context.Background(), core.NewEnvelope().WithVar("customer", nil))
+	var missing *MissingTemplateVarsError
+	if !errors.As(err, &missing) || strings.Join(missing.Vars, ",") != "customer,question" {
+		t.Fatalf("expected missing customer and question, got %v", err)
+	}
+	if len(client.requests) != 0 {
+		t.Fatal("the LLM should not be called with missing required vars")
+	}
+
+	env := core.NewEnvelope().WithVar("question", "Why?").WithVar("customer", "acme")
+	if _, err := node.Run(context.Background(), env); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := client.requests[0].InputText; got != "Friendly: Why? (acme)" {
+		t.Errorf("prompt = %q", got)
+	}
+	if _, ok := env.GetVar("tone"); ok {
+		t.Error("defaults should not be written to the envelope")
+	}
+}
+
 func TestLLMNode_Run_WithJSONSchema(t *testing.T) {
 	client := &mockLLMClient{
 		response: core.LLMResponse{
@@ -236,6 +338,30 @@ func TestLLMNode_Run_RetryOnError(t *testing.T) {
 	}
 }
 
+func TestLLMNode_Run_NoRetryOnPermanentProviderError(t *testing.T) {
+	for _, tt := range []struct {
+		code  core.ProviderErrorCode
+		calls int
+	}{
+		{core.ProviderErrContextLength, 1},
+		{core.ProviderErrRateLimited, 3},
+	} {
+		client := &mockLLMClient{err: &core.ProviderError{Provider: "openai", Code: tt.code, Err: errors.New("provider failed")}}
+		node := NewLLMNode("test", client, LLMNodeConfig{
+			Model:       "gpt-4",
+			RetryPolicy: core.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+		})
+
+		_, err := node.Run(context.Background(), core.NewEnvelope())
+		if pe, ok := core.AsProviderError(err); !ok || pe.Code != tt.code {
+			t.Fatalf("%s: err = %v, want the provider error", tt.code, err)
+		}
+		if len(client.requests) != tt.calls {
+			t.Errorf("%s: %d calls, want %d", tt.code, len(client.requests), tt.calls)
+		}
+	}
+}
+
 func TestLLMNode_Run_ContextCancellation(t *testing.T) {
 	// Use a slow mock that checks context
 	client := &slowMockLLMClient{
@@ -349,9 +475,10 @@ func TestLLMNode_InterfaceCompliance(t *testing.T) {
 func TestLLMNode_Run_EmitsOutputFinalEvent(t *testing.T) {
 	client := &mockLLMClient{
 		response: core.LLMResponse{
-			Text:  "Hello!",
-			Model: "gpt-4",
-			Usage: core.LLMTokenUsage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15},
+			Text:   "Hello!",
+			Model:  "gpt-4",
+			Region: "eu-west",
+			Usage:  core.LLMTokenUsage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15},
 		},
 	}
 
@@ -384,6 +511,9 @@ func TestLLMNode_Run_EmitsOutputFinalEvent(t *testing.T) {
 	if events[0].Payload["text"] != "Hello!" {
 		t.Errorf("text = %v, want 'Hello!'", events[0].Payload["text"])
 	}
+	if events[0].Payload["region"] != "eu-west" {
+		t.Errorf("region = %v, want eu-west", events[0].Payload["region"])
+	}
 }
 
 // mockStreamingLLMClient implements core.StreamingLLMClient
@@ -488,3 +618,25 @@ func (m *slowMockLLMClient) Complete(ctx context.Context, req core.LLMRequest) (
 		return core.LLMResponse{Text: "OK"}, nil
 	}
 }
+
+func TestLLMNode_ChaosProviderFaults(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{Text: "ok"}}
+	node := NewLLMNode("llm", client, LLMNodeConfig{
+		Model:       "m",
+		RetryPolicy: core.RetryPolicy{MaxAttempts: 2, Backoff: time.Millisecond},
+	})
+	g := graph.NewGraph("chaos")
+	g.AddNode(node)
+	_ = g.SetEntry("llm")
+
+	opts := runtime.DefaultRunOptions()
+	opts.Chaos = &runtime.ChaosConfig{ProviderErrorRate: 1, ProviderErrors: []runtime.ChaosFault{runtime.ChaosFaultRateLimit}}
+	_, err := runtime.NewRuntime().Run(context.Background(), g, nil, opts)
+
+	if err == nil || !strings.Contains(err.Error(), "injected rate_limit (429)") {
+		t.Fatalf("err = %v, want injected 429", err)
+	}
+	if len(client.requests) != 0 {
+		t.Errorf("client called %d times, want 0", len(client.requests))
+	}
+}
@@ -0,0 +1,383 @@
+package nodes
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/currency"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// LocaleVar is the envelope variable holding the BCP 47 locale, such as
+// "de-DE", that templates format numbers, currencies and dates for when a
+// formatting function is not given one.
+const LocaleVar = "locale"
+
+// DefaultLocale is used when neither the template nor the envelope sets a
+// locale.
+const DefaultLocale = "en-US"
+
+// Date styles accepted by formatDate.
+const (
+	DateStyleShort  = "short"
+	DateStyleMedium = "medium"
+	DateStyleLong   = "long"
+	DateStyleFull   = "full"
+)
+
+// EnvelopeLocale returns the locale set in env's LocaleVar, or "".
+func EnvelopeLocale(env *core.Envelope) string {
+	if env == nil {
+		return ""
+	}
+	v, _ := env.GetVar(LocaleVar)
+	s, _ := v.(string)
+	return s
+}
+
+// localeTemplateFuncs returns the locale-aware template functions. Calls
+// without a locale argument use defaultLocale, and DefaultLocale when that
+// is empty.
+//
+//	{{formatNumber .total}}                    1,234.5
+//	{{formatNumber .total 2 "de-DE"}}          1.234,50
+//	{{formatCurrency .total "EUR" "fr-FR"}}    1 234,50 €
+//	{{formatDate .created_at "long" "es"}}     6 de enero de 2025
+func localeTemplateFuncs(defaultLocale string) template.FuncMap {
+	f := localeFormatter{defaultLocale: defaultLocale}
+	return template.FuncMap{
+		"formatNumber":   f.formatNumber,
+		"formatCurrency": f.formatCurrency,
+		"formatDate":     f.formatDate,
+	}
+}
+
+type localeFormatter struct {
+	defaultLocale string
+}
+
+// tag parses the locale given to a function, falling back to the default.
+func (f localeFormatter) tag(locale string) (language.Tag, error) {
+	if locale == "" {
+		locale = f.defaultLocale
+	}
+	if locale == "" {
+		locale = DefaultLocale
+	}
+	tag, err := language.Parse(strings.ReplaceAll(locale, "_", "-"))
+	if err != nil {
+		return language.Und, fmt.Errorf("invalid locale %q: %w", locale, err)
+	}
+	return tag, nil
+}
+
+// formatNumber formats value with the locale's grouping and decimal
+// separators. Optional arguments are the number of decimals (an integer;
+// by default up to three) and the locale (a string), in either order.
+func (f localeFormatter) formatNumber(value any, args ...any) (string, error) {
+	v, err := localeNumber(value)
+	if err != nil {
+		return "", fmt.Errorf("formatNumber: %w", err)
+	}
+	decimals := -1
+	locale := ""
+	for _, arg := range args {
+		switch a := arg.(type) {
+		case string:
+			locale = a
+		case int:
+			if a < 0 {
+				return "", fmt.Errorf("formatNumber: decimals must not be negative")
+			}
+			decimals = a
+		default:
+			return "", fmt.Errorf("formatNumber: unexpected argument %v (%T)", arg, arg)
+		}
+	}
+	tag, err := f.tag(locale)
+	if err != nil {
+		return "", fmt.Errorf("formatNumber: %w", err)
+	}
+	var opts []number.Option
+	if decimals >= 0 {
+		opts = append(opts, number.Scale(decimals))
+	}
+	return message.NewPrinter(tag).Sprint(number.Decimal(v, opts...)), nil
+}
+
+// formatCurrency formats amount in the ISO 4217 currency code, rounded to
+// the currency's minor units, with the locale's symbol and placement.
+func (f localeFormatter) formatCurrency(amount any, code string, locale ...string) (string, error) {
+	v, err := localeNumber(amount)
+	if err != nil {
+		return "", fmt.Errorf("formatCurrency: %w", err)
+	}
+	unit, err := currency.ParseISO(code)
+	if err != nil {
+		return "", fmt.Errorf("formatCurrency: invalid currency %q", code)
+	}
+	tag, err := f.tag(optionalLocale(locale))
+	if err != nil {
+		return "", fmt.Errorf("formatCurrency: %w", err)
+	}
+
+	p := message.NewPrinter(tag)
+	scale, _ := currency.Standard.Rounding(unit)
+	digits := p.Sprint(number.Decimal(math.Abs(v), number.Scale(scale)))
+	symbol := p.Sprint(currency.Symbol(unit))
+	sign := ""
+	if v < 0 && strings.ContainsFunc(digits, func(r rune) bool { return r >= '1' && r <= '9' }) {
+		sign = "-"
+	}
+
+	// Symbols are joined with a no-break space, as CLDR does, so that they
+	// never wrap apart from the amount.
+	if symbolFollowsAmount(tag) {
+		return sign + digits + "\u00a0" + symbol, nil
+	}
+	// Codes used as symbols, such as "CHF", are always set apart.
+	if spacedSymbolPrefix(tag) || strings.IndexFunc(symbol, func(r rune) bool { return !unicode.IsLetter(r) }) < 0 {
+		return sign + symbol + "\u00a0" + digits, nil
+	}
+	return sign + symbol + digits, nil
+}
+
+// formatDate formats value, a time.Time, an RFC 3339 or YYYY-MM-DD string
+// or Unix seconds, in the locale's date style: short, medium (the default),
+// long or full. Any other style is used as a Go time layout. Dates keep the
+// value's time zone.
+func (f localeFormatter) formatDate(value any, args ...string) (string, error) {
+	t, err := localeTime(value)
+	if err != nil {
+		return "", fmt.Errorf("formatDate: %w", err)
+	}
+	if len(args) > 2 {
+		return "", fmt.Errorf("formatDate: expected at most a style and a locale")
+	}
+	style := DateStyleMedium
+	if len(args) > 0 && args[0] != "" {
+		style = args[0]
+	}
+	locale := ""
+	if len(args) > 1 {
+		locale = args[1]
+	}
+	tag, err := f.tag(locale)
+	if err != nil {
+		return "", fmt.Errorf("formatDate: %w", err)
+	}
+
+	names := dateNamesFor(tag)
+	var pattern string
+	switch style {
+	case DateStyleShort:
+		pattern = names.short
+	case DateStyleMedium:
+		pattern = names.medium
+	case DateStyleLong:
+		pattern = names.long
+	case DateStyleFull:
+		pattern = names.full
+	default:
+		return t.Format(style), nil
+	}
+	return names.format(pattern, t), nil
+}
+
+func optionalLocale(locale []string) string {
+	if len(locale) > 0 {
+		return locale[0]
+	}
+	return ""
+}
+
+// localeNumber converts template values, including numeric strings, to
+// float64.
+func localeNumber(value any) (float64, error) {
+	if v, ok := toFloat64(value); ok {
+		return v, nil
+	}
+	switch v := value.(type) {
+	case int32:
+		return float64(v), nil
+	case uint:
+		return float64(v), nil
+	case uint32:
+		return float64(v), nil
+	case uint64:
+		return float64(v), nil
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return f, nil
+		}
+	}
+	return 0, fmt.Errorf("%v (%T) is not a number", value, value)
+}
+
+// localeTime converts template values to a time.
+func localeTime(value any) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v != nil {
+			return *v, nil
+		}
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, time.DateTime, time.DateOnly} {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse %q as a date", v)
+	default:
+		if secs, ok := toFloat64(value); ok {
+			whole, frac := math.Modf(secs)
+			return time.Unix(int64(whole), int64(frac*1e9)).UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%v (%T) is not a date", value, value)
+}
+
+// symbolFollowsAmount reports whether the locale writes currency symbols
+// after the amount, as in "1.234,50 €".
+func symbolFollowsAmount(tag language.Tag) bool {
+	base, _ := tag.Base()
+	region, _ := tag.Region()
+	switch base.String() {
+	case "de":
+		return region.String() != "CH" && region.String() != "AT" && region.String() != "LI"
+	case "pt":
+		return region.String() == "PT"
+	case "fr", "es", "it", "sv", "nb", "no", "nn", "da", "fi", "pl", "cs", "sk",
+		"ru", "uk", "be", "hu", "ro", "bg", "el", "hr", "sr", "sl", "lt", "lv", "et", "vi":
+		return true
+	}
+	return false
+}
+
+// spacedSymbolPrefix reports whether the locale separates a leading
+// currency symbol from the amount, as in "€ 1.234,50".
+func spacedSymbolPrefix(tag language.Tag) bool {
+	base, _ := tag.Base()
+	switch base.String() {
+	case "nl", "pt", "de":
+		return true
+	}
+	return false
+}
+
+// dateNames holds a language's month and weekday names and its date style
+// patterns. Patterns use {d}, {dd}, {M}, {MM}, {MMM}, {MMMM}, {yy}, {yyyy}
+// and {EEEE}.
+type dateNames struct {
+	months      [12]string
+	shortMonths [12]string
+	weekdays    [7]string // Sunday first, as time.Weekday
+	short       string
+	medium      string
+	long        string
+	full        string
+}
+
+func (n *dateNames) format(pattern string, t time.Time) string {
+	return strings.NewReplacer(
+		"{dd}", fmt.Sprintf("%02d", t.Day()),
+		"{d}", strconv.Itoa(t.Day()),
+		"{MMMM}", n.months[t.Month()-1],
+		"{MMM}", n.shortMonths[t.Month()-1],
+		"{MM}", fmt.Sprintf("%02d", int(t.Month())),
+		"{M}", strconv.Itoa(int(t.Month())),
+		"{yyyy}", strconv.Itoa(t.Year()),
+		"{yy}", fmt.Sprintf("%02d", t.Year()%100),
+		"{EEEE}", n.weekdays[t.Weekday()],
+	).Replace(pattern)
+}
+
+// dateNamesFor returns the date names of tag's language. Languages without
+// their own names format dates in US English.
+func dateNamesFor(tag language.Tag) *dateNames {
+	base, _ := tag.Base()
+	if base.String() == "en" {
+		region, _ := tag.Region()
+		switch region.String() {
+		case "GB", "IE", "AU", "NZ", "IN", "ZA":
+			return dateNamesByLanguage["en-GB"]
+		}
+	}
+	if names, ok := dateNamesByLanguage[base.String()]; ok {
+		return names
+	}
+	return dateNamesByLanguage["en"]
+}
+
+var englishMonths = [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+var englishShortMonths = [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+var englishWeekdays = [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+var numericMonths = [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"}
+
+var dateNamesByLanguage = map[string]*dateNames{
+	"en": {
+		months: englishMonths, shortMonths: englishShortMonths, weekdays: englishWeekdays,
+		short: "{M}/{d}/{yy}", medium: "{MMM} {d}, {yyyy}", long: "{MMMM} {d}, {yyyy}", full: "{EEEE}, {MMMM} {d}, {yyyy}",
+	},
+	"en-GB": {
+		months: englishMonths, shortMonths: englishShortMonths, weekdays: englishWeekdays,
+		short: "{dd}/{MM}/{yyyy}", medium: "{d} {MMM} {yyyy}", long: "{d} {MMMM} {yyyy}", full: "{EEEE} {d} {MMMM} {yyyy}",
+	},
+	"de": {
+		months:      [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		shortMonths: [12]string{"Jan.", "Feb.", "März", "Apr.", "Mai", "Juni", "Juli", "Aug.", "Sept.", "Okt.", "Nov.", "Dez."},
+		weekdays:    [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		short:       "{dd}.{MM}.{yy}", medium: "{dd}.{MM}.{yyyy}", long: "{d}. {MMMM} {yyyy}", full: "{EEEE}, {d}. {MMMM} {yyyy}",
+	},
+	"fr": {
+		months:      [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		shortMonths: [12]string{"janv.", "févr.", "mars", "avr.", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		weekdays:    [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		short:       "{dd}/{MM}/{yyyy}", medium: "{d} {MMM} {yyyy}", long: "{d} {MMMM} {yyyy}", full: "{EEEE} {d} {MMMM} {yyyy}",
+	},
+	"es": {
+		months:      [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		shortMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sept", "oct", "nov", "dic"},
+		weekdays:    [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		short:       "{d}/{M}/{yy}", medium: "{d} {MMM} {yyyy}", long: "{d} de {MMMM} de {yyyy}", full: "{EEEE}, {d} de {MMMM} de {yyyy}",
+	},
+	"it": {
+		months:      [12]string{"gennaio", "febbraio", "marzo", "aprile", "maggio", "giugno", "luglio", "agosto", "settembre", "ottobre", "novembre", "dicembre"},
+		shortMonths: [12]string{"gen", "feb", "mar", "apr", "mag", "giu", "lug", "ago", "set", "ott", "nov", "dic"},
+		weekdays:    [7]string{"domenica", "lunedì", "martedì", "mercoledì", "giovedì", "venerdì", "sabato"},
+		short:       "{dd}/{MM}/{yy}", medium: "{d} {MMM} {yyyy}", long: "{d} {MMMM} {yyyy}", full: "{EEEE} {d} {MMMM} {yyyy}",
+	},
+	"pt": {
+		months:      [12]string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"},
+		shortMonths: [12]string{"jan.", "fev.", "mar.", "abr.", "mai.", "jun.", "jul.", "ago.", "set.", "out.", "nov.", "dez."},
+		weekdays:    [7]string{"domingo", "segunda-feira", "terça-feira", "quarta-feira", "quinta-feira", "sexta-feira", "sábado"},
+		short:       "{dd}/{MM}/{yyyy}", medium: "{d} de {MMM} de {yyyy}", long: "{d} de {MMMM} de {yyyy}", full: "{EEEE}, {d} de {MMMM} de {yyyy}",
+	},
+	"nl": {
+		months:      [12]string{"januari", "februari", "maart", "april", "mei", "juni", "juli", "augustus", "september", "oktober", "november", "december"},
+		shortMonths: [12]string{"jan", "feb", "mrt", "apr", "mei", "jun", "jul", "aug", "sep", "okt", "nov", "dec"},
+		weekdays:    [7]string{"zondag", "maandag", "dinsdag", "woensdag", "donderdag", "vrijdag", "zaterdag"},
+		short:       "{dd}-{MM}-{yyyy}", medium: "{d} {MMM} {yyyy}", long: "{d} {MMMM} {yyyy}", full: "{EEEE} {d} {MMMM} {yyyy}",
+	},
+	"ja": {
+		months: numericMonths, shortMonths: numericMonths,
+		weekdays: [7]string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"},
+		short:    "{yyyy}/{MM}/{dd}", medium: "{yyyy}/{MM}/{dd}", long: "{yyyy}年{M}月{d}日", full: "{yyyy}年{M}月{d}日{EEEE}",
+	},
+	"zh": {
+		months: numericMonths, shortMonths: numericMonths,
+		weekdays: [7]string{"星期日", "星期一", "星期二", "星期三", "星期四", "星期五", "星期六"},
+		short:    "{yyyy}/{M}/{d}", medium: "{yyyy}年{M}月{d}日", long: "{yyyy}年{M}月{d}日", full: "{yyyy}年{M}月{d}日{EEEE}",
+	},
+}
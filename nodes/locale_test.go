@@ -0,0 +1,154 @@
+package nodes
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func renderLocaleTemplate(t *testing.T, locale, text string, data map[string]any) (string, error) {
+	t.Helper()
+	tmpl, err := parseNodeTemplate(nil, "test", text, localeTemplateFuncs(locale))
+	if err != nil {
+		t.Fatalf("parse %q: %v", text, err)
+	}
+	return tmpl.render(data)
+}
+
+func TestLocaleFormatNumber(t *testing.T) {
+	tests := []struct {
+		locale string
+		text   string
+		want   string
+	}{
+		{"", `{{formatNumber .n}}`, "1,234,567.891"},
+		{"de-DE", `{{formatNumber .n}}`, "1.234.567,891"},
+		{"", `{{formatNumber .n 2 "de-DE"}}`, "1.234.567,89"},
+		{"", `{{formatNumber .n "en-IN" 0}}`, "12,34,568"},
+		{"fr", `{{formatNumber .s}}`, "1\u00a0234,5"},
+		{"de_CH", `{{formatNumber .i}}`, "42"},
+	}
+	data := map[string]any{"n": 1234567.891, "s": "1234.5", "i": 42}
+	for _, tt := range tests {
+		got, err := renderLocaleTemplate(t, tt.locale, tt.text, data)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.locale, tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.locale, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleFormatCurrency(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{`{{formatCurrency .amount "USD"}}`, "$1,234.50"},
+		{`{{formatCurrency .amount "EUR" "de-DE"}}`, "1.234,50\u00a0€"},
+		{`{{formatCurrency .amount "EUR" "nl-NL"}}`, "€\u00a01.234,50"},
+		{`{{formatCurrency .amount "JPY" "ja-JP"}}`, "￥1,234"},
+		{`{{formatCurrency .negative "USD" "en-US"}}`, "-$12.00"},
+		{`{{formatCurrency .amount "CHF" "en-US"}}`, "CHF\u00a01,234.50"},
+		{`{{formatCurrency .tiny "USD"}}`, "$0.00"},
+	}
+	data := map[string]any{"amount": 1234.5, "negative": -12, "tiny": -0.001}
+	for _, tt := range tests {
+		got, err := renderLocaleTemplate(t, "", tt.text, data)
+		if err != nil {
+			t.Errorf("%s: %v", tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleFormatDate(t *testing.T) {
+	date := time.Date(2025, time.January, 6, 9, 30, 0, 0, time.UTC)
+	tests := []struct {
+		locale string
+		text   string
+		want   string
+	}{
+		{"", `{{formatDate .date}}`, "Jan 6, 2025"},
+		{"", `{{formatDate .date "short"}}`, "1/6/25"},
+		{"en-GB", `{{formatDate .date "full"}}`, "Monday 6 January 2025"},
+		{"", `{{formatDate .date "long" "de-DE"}}`, "6. Januar 2025"},
+		{"fr-FR", `{{formatDate .date "full"}}`, "lundi 6 janvier 2025"},
+		{"es", `{{formatDate .iso "long"}}`, "6 de enero de 2025"},
+		{"ja", `{{formatDate .day "long"}}`, "2025年1月6日"},
+		{"sv-SE", `{{formatDate .date "long"}}`, "January 6, 2025"},
+		{"", `{{formatDate .unix "2006-01-02 15:04"}}`, "2025-01-06 09:30"},
+	}
+	data := map[string]any{
+		"date": date,
+		"iso":  "2025-01-06T09:30:00Z",
+		"day":  "2025-01-06",
+		"unix": date.Unix(),
+	}
+	for _, tt := range tests {
+		got, err := renderLocaleTemplate(t, tt.locale, tt.text, data)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.locale, tt.text, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s %s = %q, want %q", tt.locale, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestLocaleFormatErrors(t *testing.T) {
+	data := map[string]any{"n": 1.5, "s": "abc"}
+	for _, text := range []string{
+		`{{formatNumber .s}}`,
+		`{{formatNumber .n "not a locale!"}}`,
+		`{{formatNumber .n 1.5}}`,
+		`{{formatCurrency .n "EURO"}}`,
+		`{{formatDate .s}}`,
+		`{{formatDate .n "short" "en" "extra"}}`,
+	} {
+		if _, err := renderLocaleTemplate(t, "", text, data); err == nil {
+			t.Errorf("%s: expected error", text)
+		}
+	}
+}
+
+func TestLocaleEnvelopeConvention(t *testing.T) {
+	env := core.NewEnvelope().
+		WithVar(LocaleVar, "de-DE").
+		WithVar("total", 1234.5)
+
+	node := NewTransformNode("fmt", TransformNodeConfig{
+		Transform: TransformTemplate,
+		Template:  `{{formatCurrency .total "EUR"}} / {{formatNumber .total 2 "en-US"}}`,
+		OutputVar: "text",
+	})
+	out, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := out.GetVar("text"); got != "1.234,50\u00a0€ / 1,234.50" {
+		t.Errorf("transform output = %q", got)
+	}
+
+	client := &mockLLMClient{response: core.LLMResponse{Text: "ok"}}
+	llm := NewLLMNode("ask", client, LLMNodeConfig{
+		Model:          "test",
+		PromptTemplate: `Total: {{formatNumber .total 2}}`,
+		OutputKey:      "answer",
+	})
+	if _, err := llm.Run(context.Background(), env); err != nil {
+		t.Fatal(err)
+	}
+	if len(client.requests) != 1 || !strings.Contains(client.requests[0].InputText, "Total: 1.234,50") {
+		t.Errorf("requests = %+v", client.requests)
+	}
+}
@@ -6,6 +6,18 @@ import (
 	"sync"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// MapStreamOrder controls the order in which streamed item results reach
+// the stream consumer.
+type MapStreamOrder string
+
+const (
+	// MapStreamOrderCompletion delivers results as soon as each item finishes.
+	MapStreamOrderCompletion MapStreamOrder = "completion"
+	// MapStreamOrderInput buffers results so they are delivered in input order.
+	MapStreamOrderInput MapStreamOrder = "input"
 )
 
 // MapNodeConfig configures a MapNode.
@@ -45,6 +57,26 @@ type MapNodeConfig struct {
 	// PreserveOrder ensures output order matches input order even with concurrent execution.
 	// Default is true.
 	PreserveOrder bool
+
+	// Stream enables incremental delivery of item results as they complete.
+	// Each result is emitted as a node.output.delta event and, when
+	// StreamNode is set, passed to that node. Setting StreamNode implies Stream.
+	Stream bool
+
+	// StreamNode consumes streamed results one at a time. Invocations are
+	// serialized and each one receives the envelope returned by the previous
+	// invocation, so the consumer can act as a progressive reducer. The item
+	// is passed via StreamVar as {"index", "result", "done": false}; after the
+	// last item the node runs once more with {"done": true, "count": n}.
+	StreamNode core.Node
+
+	// StreamVar is the variable name used to pass streamed results to
+	// StreamNode. Defaults to "map_stream".
+	StreamVar string
+
+	// StreamOrder selects completion-order or input-order delivery.
+	// Defaults to MapStreamOrderCompletion.
+	StreamOrder MapStreamOrder
 }
 
 // MapNode applies a transformation to each item in a collection.
@@ -66,6 +98,15 @@ func NewMapNode(id string, config MapNodeConfig) *MapNode {
 	if config.Concurrency <= 0 {
 		config.Concurrency = 1
 	}
+	if config.StreamNode != nil {
+		config.Stream = true
+	}
+	if config.StreamVar == "" {
+		config.StreamVar = "map_stream"
+	}
+	if config.StreamOrder == "" {
+		config.StreamOrder = MapStreamOrderCompletion
+	}
 	// PreserveOrder defaults to true (zero value is false, so we check explicitly)
 	// Note: We can't distinguish "not set" from "set to false" with bool
 	// So we default to true in the implementation
@@ -100,14 +141,30 @@ func (n *MapNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope,
 		return nil, fmt.Errorf("map node %s: no mapper configured", n.ID())
 	}
 
+	var stream *mapStreamer
+	if n.config.Stream {
+		stream = n.startStream(ctx, env, len(items))
+	}
+
 	// Execute map operation
 	var results []any
 	var mapErr error
 
 	if n.config.Concurrency == 1 {
-		results, mapErr = n.mapSequential(ctx, env, items)
+		results, mapErr = n.mapSequential(ctx, env, items, stream)
 	} else {
-		results, mapErr = n.mapConcurrent(ctx, env, items)
+		results, mapErr = n.mapConcurrent(ctx, env, items, stream)
+	}
+
+	result := env.Clone()
+	if stream != nil {
+		streamEnv, streamErr := stream.finish(mapErr == nil || n.config.ContinueOnError)
+		if streamErr != nil && (mapErr == nil || n.config.ContinueOnError) {
+			return nil, fmt.Errorf("map node %s: stream consumer: %w", n.ID(), streamErr)
+		}
+		if streamEnv != nil {
+			result = streamEnv
+		}
 	}
 
 	if mapErr != nil && !n.config.ContinueOnError {
@@ -115,14 +172,13 @@ func (n *MapNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope,
 	}
 
 	// Store results
-	result := env.Clone()
 	result.SetVar(n.config.OutputVar, results)
 
 	return result, nil
 }
 
 // mapSequential processes items one at a time.
-func (n *MapNode) mapSequential(ctx context.Context, env *core.Envelope, items []any) ([]any, error) {
+func (n *MapNode) mapSequential(ctx context.Context, env *core.Envelope, items []any, stream *mapStreamer) ([]any, error) {
 	results := make([]any, len(items))
 
 	for i, item := range items {
@@ -136,18 +192,20 @@ func (n *MapNode) mapSequential(ctx context.Context, env *core.Envelope, items [
 		if err != nil {
 			if n.config.ContinueOnError {
 				results[i] = nil
+				stream.push(i, nil, err)
 				continue
 			}
 			return nil, fmt.Errorf("map item %d: %w", i, err)
 		}
 		results[i] = result
+		stream.push(i, result, nil)
 	}
 
 	return results, nil
 }
 
 // mapConcurrent processes items with a worker pool.
-func (n *MapNode) mapConcurrent(ctx context.Context, env *core.Envelope, items []any) ([]any, error) {
+func (n *MapNode) mapConcurrent(ctx context.Context, env *core.Envelope, items []any, stream *mapStreamer) ([]any, error) {
 	results := make([]any, len(items))
 	var resultsMu sync.Mutex
 	var firstErr error
@@ -186,6 +244,7 @@ func (n *MapNode) mapConcurrent(ctx context.Context, env *core.Envelope, items [
 							resultsMu.Lock()
 							results[work.index] = nil
 							resultsMu.Unlock()
+							stream.push(work.index, nil, err)
 						} else {
 							errOnce.Do(func() {
 								firstErr = fmt.Errorf("map item %d: %w", work.index, err)
@@ -198,6 +257,7 @@ func (n *MapNode) mapConcurrent(ctx context.Context, env *core.Envelope, items [
 					resultsMu.Lock()
 					results[work.index] = result
 					resultsMu.Unlock()
+					stream.push(work.index, result, nil)
 				}
 			}
 		}()
@@ -251,5 +311,136 @@ func (n *MapNode) processItem(ctx context.Context, env *core.Envelope, item any,
 	return resultEnv.Vars, nil
 }
 
+// mapStreamItem is a completed item waiting to be delivered downstream.
+type mapStreamItem struct {
+	index  int
+	result any
+	err    error
+}
+
+// mapStreamer delivers item results to the stream consumer from a single
+// goroutine so workers never block on downstream processing.
+type mapStreamer struct {
+	node  *MapNode
+	ctx   context.Context
+	emit  runtime.EventEmitter
+	runID string
+
+	items chan mapStreamItem
+	done  chan struct{}
+
+	// Owned by the delivery goroutine until done is closed.
+	env     *core.Envelope
+	pending map[int]mapStreamItem
+	next    int
+	count   int
+	err     error
+}
+
+func (n *MapNode) startStream(ctx context.Context, env *core.Envelope, total int) *mapStreamer {
+	s := &mapStreamer{
+		node:    n,
+		ctx:     ctx,
+		emit:    runtime.EmitterFromContext(ctx),
+		runID:   env.Trace.RunID,
+		items:   make(chan mapStreamItem, total),
+		done:    make(chan struct{}),
+		env:     env.Clone(),
+		pending: make(map[int]mapStreamItem),
+	}
+	go s.loop()
+	return s
+}
+
+// push queues a completed item. It is safe to call on a nil streamer.
+func (s *mapStreamer) push(index int, result any, err error) {
+	if s == nil {
+		return
+	}
+	s.items <- mapStreamItem{index: index, result: result, err: err}
+}
+
+func (s *mapStreamer) loop() {
+	defer close(s.done)
+	for item := range s.items {
+		if s.node.config.StreamOrder != MapStreamOrderInput {
+			s.deliver(item)
+			continue
+		}
+		s.pending[item.index] = item
+		for {
+			next, ok := s.pending[s.next]
+			if !ok {
+				break
+			}
+			delete(s.pending, s.next)
+			s.next++
+			s.deliver(next)
+		}
+	}
+}
+
+func (s *mapStreamer) deliver(item mapStreamItem) {
+	s.count++
+	payload := map[string]any{
+		"index":  item.index,
+		"result": item.result,
+		"done":   false,
+	}
+	if item.err != nil {
+		payload["error"] = item.err.Error()
+	}
+
+	s.emit(runtime.NewEvent(runtime.EventNodeOutputDelta, s.runID).
+		WithNode(s.node.ID(), s.node.Kind()).
+		WithPayload("index", item.index).
+		WithPayload("result", item.result))
+
+	// Stop feeding the consumer after its first failure but keep draining
+	// so workers are never blocked.
+	if s.err != nil || s.node.config.StreamNode == nil {
+		return
+	}
+	s.invoke(payload)
+}
+
+func (s *mapStreamer) invoke(payload map[string]any) {
+	s.env.SetVar(s.node.config.StreamVar, payload)
+	out, err := s.node.config.StreamNode.Run(s.ctx, s.env)
+	if err != nil {
+		s.err = err
+		return
+	}
+	if out != nil {
+		s.env = out
+	}
+}
+
+// finish closes the stream, waits for pending deliveries and signals
+// end-of-stream to the consumer. When complete is false the map failed and
+// no end-of-stream signal is sent. It returns the consumer's final envelope,
+// or nil when no consumer is configured.
+func (s *mapStreamer) finish(complete bool) (*core.Envelope, error) {
+	close(s.items)
+	<-s.done
+
+	if complete {
+		s.emit(runtime.NewEvent(runtime.EventNodeOutputFinal, s.runID).
+			WithNode(s.node.ID(), s.node.Kind()).
+			WithPayload("count", s.count))
+	}
+	if s.node.config.StreamNode == nil {
+		return nil, nil
+	}
+	if complete && s.err == nil {
+		s.invoke(map[string]any{"done": true, "count": s.count})
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	delete(s.env.Vars, s.node.config.StreamVar)
+	return s.env, nil
+}
+
 // Ensure interface compliance at compile time.
 var _ core.Node = (*MapNode)(nil)
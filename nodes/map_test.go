@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 func TestNewMapNode(t *testing.T) {
@@ -729,3 +730,101 @@ func (n *testMapperNode) Kind() core.NodeKind {
 func (n *testMapperNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
 	return n.transform(env), nil
 }
+
+func TestMapNode_Run_Stream(t *testing.T) {
+	t.Run("consumer reduces in input order", func(t *testing.T) {
+		var seen []int
+		consumer := core.NewFuncNode("reducer", func(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+			msg := env.Vars["map_stream"].(map[string]any)
+			if msg["done"] == true {
+				env.SetVar("final_count", msg["count"])
+				return env, nil
+			}
+			seen = append(seen, msg["index"].(int))
+			sum, _ := env.Vars["sum"].(int)
+			env.SetVar("sum", sum+msg["result"].(int))
+			return env, nil
+		})
+
+		node := NewMapNode("streamMap", MapNodeConfig{
+			InputVar:    "items",
+			Concurrency: 4,
+			StreamNode:  consumer,
+			StreamOrder: MapStreamOrderInput,
+			Mapper: func(_ context.Context, item any, index int) (any, error) {
+				// Later items finish first so input ordering has to buffer.
+				time.Sleep(time.Duration(5-index) * 2 * time.Millisecond)
+				return item.(int) * 2, nil
+			},
+		})
+
+		env := core.NewEnvelope()
+		env.SetVar("items", []int{1, 2, 3, 4, 5})
+
+		result, err := node.Run(context.Background(), env)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := result.Vars["sum"]; got != 30 {
+			t.Errorf("sum = %v, want 30", got)
+		}
+		if got := result.Vars["final_count"]; got != 5 {
+			t.Errorf("final_count = %v, want 5", got)
+		}
+		for i, idx := range seen {
+			if idx != i {
+				t.Fatalf("delivery order = %v, want input order", seen)
+			}
+		}
+		if _, ok := result.Vars["map_stream"]; ok {
+			t.Error("stream var should be cleared after end-of-stream")
+		}
+		if len(result.Vars["streamMap_output"].([]any)) != 5 {
+			t.Error("expected full results in output var")
+		}
+	})
+
+	t.Run("emits delta and final events", func(t *testing.T) {
+		var deltas, finals atomic.Int32
+		ctx := runtime.ContextWithEmitter(context.Background(), func(e runtime.Event) {
+			switch e.Kind {
+			case runtime.EventNodeOutputDelta:
+				deltas.Add(1)
+			case runtime.EventNodeOutputFinal:
+				finals.Add(1)
+			}
+		})
+
+		node := NewMapNode("eventsMap", MapNodeConfig{
+			InputVar: "items",
+			Stream:   true,
+			Mapper:   func(_ context.Context, item any, _ int) (any, error) { return item, nil },
+		})
+		env := core.NewEnvelope()
+		env.SetVar("items", []any{"a", "b", "c"})
+
+		if _, err := node.Run(ctx, env); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if deltas.Load() != 3 || finals.Load() != 1 {
+			t.Errorf("deltas=%d finals=%d, want 3 and 1", deltas.Load(), finals.Load())
+		}
+	})
+
+	t.Run("consumer error fails the map", func(t *testing.T) {
+		consumer := core.NewFuncNode("bad", func(_ context.Context, _ *core.Envelope) (*core.Envelope, error) {
+			return nil, errors.New("sink unavailable")
+		})
+		node := NewMapNode("failMap", MapNodeConfig{
+			InputVar:   "items",
+			StreamNode: consumer,
+			Mapper:     func(_ context.Context, item any, _ int) (any, error) { return item, nil },
+		})
+		env := core.NewEnvelope()
+		env.SetVar("items", []any{1, 2})
+
+		if _, err := node.Run(context.Background(), env); err == nil {
+			t.Fatal("expected stream consumer error")
+		}
+	})
+}
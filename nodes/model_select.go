@@ -0,0 +1,520 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// Bandit policies for ModelSelectNode.
+const (
+	// BanditEpsilonGreedy picks the arm with the best mean reward, and a
+	// random arm with probability Epsilon.
+	BanditEpsilonGreedy = "epsilon_greedy"
+	// BanditUCB1 picks the arm with the highest upper confidence bound on
+	// its mean reward.
+	BanditUCB1 = "ucb1"
+)
+
+// DefaultBanditEpsilon is the exploration rate of BanditEpsilonGreedy when
+// none is configured.
+const DefaultBanditEpsilon = 0.1
+
+var (
+	// ErrSelectionNotFound is returned when a reward names an unknown
+	// selection.
+	ErrSelectionNotFound = errors.New("model selection not found")
+	// ErrSelectionRewarded is returned when a selection already has a
+	// reward.
+	ErrSelectionRewarded = errors.New("model selection already rewarded")
+)
+
+// ModelArm is a provider and model a ModelSelectNode can choose.
+type ModelArm struct {
+	// Name identifies the arm in statistics. Defaults to "provider/model".
+	Name     string
+	Provider string
+	Model    string
+	// Client sends the arm's requests.
+	Client core.LLMClient
+}
+
+// ModelSelection records one choice of a ModelSelectNode.
+type ModelSelection struct {
+	ID         string     `json:"selection_id"`
+	Key        string     `json:"key"`
+	Arm        string     `json:"arm"`
+	Provider   string     `json:"provider"`
+	Model      string     `json:"model"`
+	Policy     string     `json:"policy"`
+	Explored   bool       `json:"explored"`
+	SelectedAt time.Time  `json:"selected_at"`
+	Reward     *float64   `json:"reward,omitempty"`
+	RewardedAt *time.Time `json:"rewarded_at,omitempty"`
+}
+
+// ArmStats are the accumulated statistics of one arm.
+type ArmStats struct {
+	Arm string `json:"arm"`
+	// Pulls counts how often the arm was selected.
+	Pulls int64 `json:"pulls"`
+	// Rewards counts the selections that received a reward.
+	Rewards   int64     `json:"rewards"`
+	RewardSum float64   `json:"reward_sum"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MeanReward is the average reward of the arm's rewarded selections.
+func (s ArmStats) MeanReward() float64 {
+	if s.Rewards == 0 {
+		return 0
+	}
+	return s.RewardSum / float64(s.Rewards)
+}
+
+// ArmStatsStore persists model selections and arm statistics per key.
+type ArmStatsStore interface {
+	// ArmStats returns the statistics of every arm recorded under key.
+	ArmStats(ctx context.Context, key string) ([]ArmStats, error)
+	// RecordSelection stores a selection and counts a pull of its arm.
+	RecordSelection(ctx context.Context, sel ModelSelection) error
+	// RecordReward credits reward to a selection's arm and returns the
+	// updated selection. It returns ErrSelectionNotFound for an unknown
+	// selection and ErrSelectionRewarded when it already has a reward.
+	RecordReward(ctx context.Context, selectionID string, reward float64) (ModelSelection, error)
+}
+
+// ValidateReward checks that a reward is within [0, 1], the range the
+// bandit policies assume.
+func ValidateReward(reward float64) error {
+	if math.IsNaN(reward) || reward < 0 || reward > 1 {
+		return fmt.Errorf("reward %v must be between 0 and 1", reward)
+	}
+	return nil
+}
+
+// ModelSelectNodeConfig configures a ModelSelectNode.
+type ModelSelectNodeConfig struct {
+	// Arms are the provider/model pairs to choose from.
+	Arms []ModelArm
+
+	// Policy is BanditEpsilonGreedy (default) or BanditUCB1.
+	Policy string
+
+	// Epsilon is the exploration rate of BanditEpsilonGreedy. Defaults to
+	// DefaultBanditEpsilon.
+	Epsilon float64
+
+	// Store keeps the selections and arm statistics.
+	Store ArmStatsStore
+
+	// Key scopes the statistics. Defaults to the node ID.
+	Key string
+
+	// LLM configures the call made with the selected arm. Its Model is
+	// replaced by the arm's.
+	LLM LLMNodeConfig
+
+	// Random returns a number in [0, 1). Defaults to math/rand.
+	Random func() float64
+}
+
+// ModelSelectNode chooses among provider/model arms with a multi-armed
+// bandit policy and makes the LLM call with the chosen one. The selection
+// is stored in "<output_key>_selection"; a RewardNode or the daemon's
+// reward API later reports how well it did. A failed call counts as a
+// reward of 0.
+type ModelSelectNode struct {
+	core.BaseNode
+	config ModelSelectNodeConfig
+	llms   []*LLMNode
+}
+
+// NewModelSelectNode creates a model selection node.
+func NewModelSelectNode(id string, config ModelSelectNodeConfig) (*ModelSelectNode, error) {
+	if len(config.Arms) == 0 {
+		return nil, fmt.Errorf("model_select node %q: at least one arm is required", id)
+	}
+	if config.Store == nil {
+		return nil, fmt.Errorf("model_select node %q: an arm statistics store is required", id)
+	}
+	switch config.Policy {
+	case "":
+		config.Policy = BanditEpsilonGreedy
+	case BanditEpsilonGreedy, BanditUCB1:
+	default:
+		return nil, fmt.Errorf("model_select node %q: unknown policy %q (want %s or %s)", id, config.Policy, BanditEpsilonGreedy, BanditUCB1)
+	}
+	if config.Epsilon < 0 || config.Epsilon > 1 {
+		return nil, fmt.Errorf("model_select node %q: epsilon must be between 0 and 1", id)
+	}
+	if config.Epsilon == 0 {
+		config.Epsilon = DefaultBanditEpsilon
+	}
+	if config.Key == "" {
+		config.Key = id
+	}
+	if config.Random == nil {
+		config.Random = rand.Float64
+	}
+	if config.LLM.OutputKey == "" {
+		config.LLM.OutputKey = id + "_output"
+	}
+
+	arms := make([]ModelArm, len(config.Arms))
+	llms := make([]*LLMNode, len(config.Arms))
+	seen := make(map[string]bool, len(config.Arms))
+	for i, arm := range config.Arms {
+		if arm.Model == "" {
+			return nil, fmt.Errorf("model_select node %q: arm %d has no model", id, i)
+		}
+		if arm.Client == nil {
+			return nil, fmt.Errorf("model_select node %q: arm %d has no client", id, i)
+		}
+		if arm.Name == "" {
+			arm.Name = arm.Provider + "/" + arm.Model
+		}
+		if seen[arm.Name] {
+			return nil, fmt.Errorf("model_select node %q: duplicate arm %q", id, arm.Name)
+		}
+		seen[arm.Name] = true
+		arms[i] = arm
+
+		llmConfig := config.LLM
+		llmConfig.Model = arm.Model
+		llms[i] = NewLLMNode(id, arm.Client, llmConfig)
+	}
+	config.Arms = arms
+
+	return &ModelSelectNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindLLM),
+		config:   config,
+		llms:     llms,
+	}, nil
+}
+
+// Run picks an arm, records the selection and makes the LLM call with it.
+func (n *ModelSelectNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	stats, err := n.config.Store.ArmStats(ctx, n.config.Key)
+	if err != nil {
+		return nil, fmt.Errorf("model_select: loading arm statistics: %w", err)
+	}
+	byArm := make(map[string]ArmStats, len(stats))
+	for _, s := range stats {
+		byArm[s.Arm] = s
+	}
+	armStats := make([]ArmStats, len(n.config.Arms))
+	for i, arm := range n.config.Arms {
+		armStats[i] = byArm[arm.Name]
+	}
+
+	idx, explored := selectArm(n.config.Policy, n.config.Epsilon, armStats, n.config.Random)
+	arm := n.config.Arms[idx]
+	sel := ModelSelection{
+		ID:         uuid.New().String(),
+		Key:        n.config.Key,
+		Arm:        arm.Name,
+		Provider:   arm.Provider,
+		Model:      arm.Model,
+		Policy:     n.config.Policy,
+		Explored:   explored,
+		SelectedAt: time.Now().UTC(),
+	}
+	if err := n.config.Store.RecordSelection(ctx, sel); err != nil {
+		return nil, fmt.Errorf("model_select: recording selection: %w", err)
+	}
+
+	emit := runtime.EmitterFromContext(ctx)
+	emit(runtime.NewEvent(runtime.EventModelSelected, env.Trace.RunID).
+		WithNode(n.ID(), n.Kind()).
+		WithPayload("selection_id", sel.ID).
+		WithPayload("arm", sel.Arm).
+		WithPayload("provider", sel.Provider).
+		WithPayload("model", sel.Model).
+		WithPayload("policy", sel.Policy).
+		WithPayload("explored", sel.Explored))
+
+	out, err := n.llms[idx].Run(ctx, env)
+	if err != nil {
+		// Penalize the arm so unreliable models lose out. The run context
+		// may already be done, so the reward is recorded without it.
+		_, _ = n.config.Store.RecordReward(context.WithoutCancel(ctx), sel.ID, 0)
+		return nil, fmt.Errorf("model_select arm %s: %w", arm.Name, err)
+	}
+	out.SetVar(n.config.LLM.OutputKey+"_selection", sel)
+	return out, nil
+}
+
+// Config returns the node's configuration.
+func (n *ModelSelectNode) Config() ModelSelectNodeConfig {
+	return n.config
+}
+
+// selectArm returns the index of the arm to pull and whether it was an
+// exploratory choice. Arms that have not been rewarded yet are tried first,
+// least pulled first, so every arm gets a score before the policy applies.
+// Ties go to the less pulled arm, then to the earlier one.
+func selectArm(policy string, epsilon float64, stats []ArmStats, random func() float64) (int, bool) {
+	unrewarded := -1
+	var total int64
+	for i, s := range stats {
+		total += s.Rewards
+		if s.Rewards == 0 && (unrewarded < 0 || s.Pulls < stats[unrewarded].Pulls) {
+			unrewarded = i
+		}
+	}
+	if unrewarded >= 0 {
+		return unrewarded, true
+	}
+
+	score := func(s ArmStats) float64 { return s.MeanReward() }
+	switch policy {
+	case BanditUCB1:
+		score = func(s ArmStats) float64 {
+			return s.MeanReward() + math.Sqrt(2*math.Log(float64(total))/float64(s.Rewards))
+		}
+	default:
+		if random() < epsilon {
+			i := int(random() * float64(len(stats)))
+			return min(i, len(stats)-1), true
+		}
+	}
+
+	best := 0
+	for i := 1; i < len(stats); i++ {
+		a, b := score(stats[i]), score(stats[best])
+		if a > b || (a == b && stats[i].Pulls < stats[best].Pulls) {
+			best = i
+		}
+	}
+	return best, false
+}
+
+// RewardNodeConfig configures a RewardNode.
+type RewardNodeConfig struct {
+	// SelectionVar names the envelope variable holding the selection to
+	// reward, usually "<output_key>_selection" of a model_select node.
+	SelectionVar string
+
+	// RewardVar names the envelope variable holding the reward: a number
+	// in [0, 1] or a boolean (true = 1). When empty, Reward is used.
+	RewardVar string
+
+	// Reward is the reward reported when RewardVar is empty.
+	Reward float64
+
+	// Store must be the store of the model_select node.
+	Store ArmStatsStore
+
+	// OutputKey stores the rewarded selection. Defaults to
+	// "<node id>_output".
+	OutputKey string
+}
+
+// RewardNode reports a reward for a model_select node's selection.
+type RewardNode struct {
+	core.BaseNode
+	config RewardNodeConfig
+}
+
+// NewRewardNode creates a reward node.
+func NewRewardNode(id string, config RewardNodeConfig) (*RewardNode, error) {
+	if config.SelectionVar == "" {
+		return nil, fmt.Errorf("reward node %q: selection_var is required", id)
+	}
+	if config.Store == nil {
+		return nil, fmt.Errorf("reward node %q: an arm statistics store is required", id)
+	}
+	if config.RewardVar == "" {
+		if err := ValidateReward(config.Reward); err != nil {
+			return nil, fmt.Errorf("reward node %q: %w", id, err)
+		}
+	}
+	if config.OutputKey == "" {
+		config.OutputKey = id + "_output"
+	}
+	return &RewardNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindTransform),
+		config:   config,
+	}, nil
+}
+
+// Run records the reward and stores the updated selection.
+func (n *RewardNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	raw, ok := env.GetVar(n.config.SelectionVar)
+	if !ok {
+		return nil, fmt.Errorf("reward: selection variable %q not found", n.config.SelectionVar)
+	}
+	selectionID, err := selectionIDOf(raw)
+	if err != nil {
+		return nil, fmt.Errorf("reward: %w", err)
+	}
+
+	reward := n.config.Reward
+	if n.config.RewardVar != "" {
+		v, ok := env.GetVar(n.config.RewardVar)
+		if !ok {
+			return nil, fmt.Errorf("reward: reward variable %q not found", n.config.RewardVar)
+		}
+		if reward, err = rewardValue(v); err != nil {
+			return nil, fmt.Errorf("reward: %s: %w", n.config.RewardVar, err)
+		}
+	}
+
+	sel, err := n.config.Store.RecordReward(ctx, selectionID, reward)
+	if err != nil {
+		return nil, fmt.Errorf("reward: %w", err)
+	}
+	out := env.Clone()
+	out.SetVar(n.config.OutputKey, sel)
+	return out, nil
+}
+
+// Config returns the node's configuration.
+func (n *RewardNode) Config() RewardNodeConfig {
+	return n.config
+}
+
+// selectionIDOf extracts a selection ID from a ModelSelection, its JSON
+// object form or a plain ID string.
+func selectionIDOf(v any) (string, error) {
+	var id string
+	switch s := v.(type) {
+	case ModelSelection:
+		id = s.ID
+	case *ModelSelection:
+		if s != nil {
+			id = s.ID
+		}
+	case map[string]any:
+		id, _ = s["selection_id"].(string)
+	case string:
+		id = s
+	}
+	if id == "" {
+		return "", fmt.Errorf("no selection ID in %T value", v)
+	}
+	return id, nil
+}
+
+// rewardValue converts a number, numeric string or boolean to a reward.
+func rewardValue(v any) (float64, error) {
+	var reward float64
+	switch r := v.(type) {
+	case bool:
+		if r {
+			reward = 1
+		}
+	case string:
+		f, err := strconv.ParseFloat(r, 64)
+		if err != nil {
+			return 0, fmt.Errorf("reward %q is not a number", r)
+		}
+		reward = f
+	default:
+		f, ok := toFloat64(v)
+		if !ok {
+			return 0, fmt.Errorf("reward must be a number or boolean, got %T", v)
+		}
+		reward = f
+	}
+	if err := ValidateReward(reward); err != nil {
+		return 0, err
+	}
+	return reward, nil
+}
+
+// MemoryArmStats is an in-memory ArmStatsStore.
+type MemoryArmStats struct {
+	mu         sync.Mutex
+	stats      map[string]map[string]*ArmStats
+	selections map[string]ModelSelection
+}
+
+// NewMemoryArmStats creates an empty in-memory store.
+func NewMemoryArmStats() *MemoryArmStats {
+	return &MemoryArmStats{
+		stats:      make(map[string]map[string]*ArmStats),
+		selections: make(map[string]ModelSelection),
+	}
+}
+
+// ArmStats returns the statistics recorded under key, sorted by arm.
+func (m *MemoryArmStats) ArmStats(_ context.Context, key string) ([]ArmStats, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]ArmStats, 0, len(m.stats[key]))
+	for _, s := range m.stats[key] {
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Arm < out[j].Arm })
+	return out, nil
+}
+
+// RecordSelection stores the selection and counts a pull.
+func (m *MemoryArmStats) RecordSelection(_ context.Context, sel ModelSelection) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.selections[sel.ID] = sel
+	m.arm(sel.Key, sel.Arm, sel.SelectedAt).Pulls++
+	return nil
+}
+
+// RecordReward credits the reward to the selection's arm.
+func (m *MemoryArmStats) RecordReward(_ context.Context, selectionID string, reward float64) (ModelSelection, error) {
+	if err := ValidateReward(reward); err != nil {
+		return ModelSelection{}, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sel, ok := m.selections[selectionID]
+	if !ok {
+		return ModelSelection{}, ErrSelectionNotFound
+	}
+	if sel.Reward != nil {
+		return sel, ErrSelectionRewarded
+	}
+	now := time.Now().UTC()
+	sel.Reward = &reward
+	sel.RewardedAt = &now
+	m.selections[selectionID] = sel
+
+	s := m.arm(sel.Key, sel.Arm, now)
+	s.Rewards++
+	s.RewardSum += reward
+	return sel, nil
+}
+
+// arm returns the statistics of an arm, creating them when missing. The
+// caller holds m.mu.
+func (m *MemoryArmStats) arm(key, name string, at time.Time) *ArmStats {
+	arms := m.stats[key]
+	if arms == nil {
+		arms = make(map[string]*ArmStats)
+		m.stats[key] = arms
+	}
+	s := arms[name]
+	if s == nil {
+		s = &ArmStats{Arm: name}
+		arms[name] = s
+	}
+	s.UpdatedAt = at
+	return s
+}
+
+// Ensure interface compliance at compile time.
+var (
+	_ core.Node     = (*ModelSelectNode)(nil)
+	_ core.Node     = (*RewardNode)(nil)
+	_ ArmStatsStore = (*MemoryArmStats)(nil)
+)
@@ -0,0 +1,202 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func TestSelectArm(t *testing.T) {
+	never := func() float64 { return 0.99 }
+	always := func() float64 { return 0 }
+
+	// Unrewarded arms are tried first, least pulled first.
+	stats := []ArmStats{
+		{Arm: "a", Pulls: 5, Rewards: 5, RewardSum: 5},
+		{Arm: "b", Pulls: 2},
+		{Arm: "c", Pulls: 1},
+	}
+	if i, explored := selectArm(BanditEpsilonGreedy, 0.1, stats, never); i != 2 || !explored {
+		t.Errorf("untried arm: got %d (explored %v), want 2", i, explored)
+	}
+
+	stats = []ArmStats{
+		{Arm: "a", Pulls: 10, Rewards: 10, RewardSum: 4},
+		{Arm: "b", Pulls: 10, Rewards: 10, RewardSum: 9},
+		{Arm: "c", Pulls: 3, Rewards: 3, RewardSum: 2.7},
+	}
+	// b and c share the best mean; c has fewer pulls.
+	if i, explored := selectArm(BanditEpsilonGreedy, 0.1, stats, never); i != 2 || explored {
+		t.Errorf("greedy: got %d (explored %v), want 2", i, explored)
+	}
+	if _, explored := selectArm(BanditEpsilonGreedy, 0.1, stats, always); !explored {
+		t.Error("epsilon draw should explore")
+	}
+
+	// UCB1 favors the rarely observed arm despite a lower mean.
+	stats = []ArmStats{
+		{Arm: "a", Pulls: 100, Rewards: 100, RewardSum: 70},
+		{Arm: "b", Pulls: 2, Rewards: 2, RewardSum: 1},
+	}
+	if i, _ := selectArm(BanditUCB1, 0, stats, never); i != 1 {
+		t.Errorf("ucb1: got %d, want 1", i)
+	}
+	stats[1] = ArmStats{Arm: "b", Pulls: 100, Rewards: 100, RewardSum: 50}
+	if i, _ := selectArm(BanditUCB1, 0, stats, never); i != 0 {
+		t.Errorf("ucb1 after observations: got %d, want 0", i)
+	}
+}
+
+func TestModelSelectNode_SelectsAndRewards(t *testing.T) {
+	store := NewMemoryArmStats()
+	fast := &mockLLMClient{response: core.LLMResponse{Text: "fast answer"}}
+	smart := &mockLLMClient{response: core.LLMResponse{Text: "smart answer"}}
+	node, err := NewModelSelectNode("pick", ModelSelectNodeConfig{
+		Arms: []ModelArm{
+			{Provider: "openai", Model: "mini", Client: fast},
+			{Name: "smart", Provider: "anthropic", Model: "opus", Client: smart},
+		},
+		Store:  store,
+		Key:    "wf/pick",
+		Random: func() float64 { return 0.5 },
+		LLM:    LLMNodeConfig{PromptTemplate: "Q: {{.q}}", OutputKey: "answer"},
+	})
+	if err != nil {
+		t.Fatalf("NewModelSelectNode: %v", err)
+	}
+	reward, err := NewRewardNode("score", RewardNodeConfig{
+		SelectionVar: "answer_selection",
+		RewardVar:    "good",
+		Store:        store,
+	})
+	if err != nil {
+		t.Fatalf("NewRewardNode: %v", err)
+	}
+
+	run := func(good bool) ModelSelection {
+		t.Helper()
+		env := core.NewEnvelope().WithVar("q", "why").WithVar("good", good)
+		out, err := node.Run(context.Background(), env)
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if out, err = reward.Run(context.Background(), out); err != nil {
+			t.Fatalf("reward Run: %v", err)
+		}
+		got, _ := out.GetVar("score_output")
+		return got.(ModelSelection)
+	}
+
+	// Each arm is tried once before the policy applies.
+	first := run(false)
+	second := run(true)
+	if first.Arm != "openai/mini" || second.Arm != "smart" || !first.Explored {
+		t.Fatalf("first selections = %+v, %+v", first, second)
+	}
+	if len(smart.requests) != 1 || smart.requests[0].Model != "opus" || smart.requests[0].InputText != "Q: why" {
+		t.Errorf("smart arm requests = %+v", smart.requests)
+	}
+	if third := run(true); third.Arm != "smart" || third.Explored || third.Reward == nil || *third.Reward != 1 {
+		t.Errorf("third selection = %+v, want exploit smart", third)
+	}
+
+	stats, _ := store.ArmStats(context.Background(), "wf/pick")
+	if len(stats) != 2 || stats[1].Arm != "smart" || stats[1].Pulls != 2 || stats[1].MeanReward() != 1 || stats[0].MeanReward() != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	if _, err := store.RecordReward(context.Background(), second.ID, 1); !errors.Is(err, ErrSelectionRewarded) {
+		t.Errorf("second reward error = %v", err)
+	}
+	if _, err := store.RecordReward(context.Background(), "missing", 1); !errors.Is(err, ErrSelectionNotFound) {
+		t.Errorf("unknown selection error = %v", err)
+	}
+}
+
+func TestModelSelectNode_FailedCallIsZeroReward(t *testing.T) {
+	store := NewMemoryArmStats()
+	node, err := NewModelSelectNode("pick", ModelSelectNodeConfig{
+		Arms:  []ModelArm{{Provider: "openai", Model: "mini", Client: &mockLLMClient{err: errors.New("boom")}}},
+		Store: store,
+		LLM:   LLMNodeConfig{RetryPolicy: core.RetryPolicy{MaxAttempts: 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := node.Run(context.Background(), core.NewEnvelope()); err == nil {
+		t.Fatal("expected error")
+	}
+	stats, _ := store.ArmStats(context.Background(), "pick")
+	if len(stats) != 1 || stats[0].Pulls != 1 || stats[0].Rewards != 1 || stats[0].RewardSum != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestModelSelectNode_Config(t *testing.T) {
+	store := NewMemoryArmStats()
+	arm := ModelArm{Provider: "openai", Model: "mini", Client: &mockLLMClient{}}
+	tests := []struct {
+		name string
+		cfg  ModelSelectNodeConfig
+	}{
+		{"no arms", ModelSelectNodeConfig{Store: store}},
+		{"no store", ModelSelectNodeConfig{Arms: []ModelArm{arm}}},
+		{"bad policy", ModelSelectNodeConfig{Arms: []ModelArm{arm}, Store: store, Policy: "thompson"}},
+		{"bad epsilon", ModelSelectNodeConfig{Arms: []ModelArm{arm}, Store: store, Epsilon: 1.5}},
+		{"duplicate arm", ModelSelectNodeConfig{Arms: []ModelArm{arm, arm}, Store: store}},
+	}
+	for _, tt := range tests {
+		if _, err := NewModelSelectNode("pick", tt.cfg); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+
+	if _, err := NewRewardNode("score", RewardNodeConfig{Store: store}); err == nil {
+		t.Error("expected error without selection_var")
+	}
+	if _, err := NewRewardNode("score", RewardNodeConfig{Store: store, SelectionVar: "s", Reward: 2}); err == nil {
+		t.Error("expected error for reward out of range")
+	}
+}
+
+func TestRewardNode_Values(t *testing.T) {
+	store := NewMemoryArmStats()
+	ctx := context.Background()
+	for _, id := range []string{"s1", "s2", "s3"} {
+		if err := store.RecordSelection(ctx, ModelSelection{ID: id, Key: "k", Arm: "a"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	node, err := NewRewardNode("score", RewardNodeConfig{SelectionVar: "sel", RewardVar: "r", Store: store})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The selection may arrive in its JSON object form or as an ID.
+	for _, tt := range []struct {
+		sel    any
+		reward any
+	}{
+		{map[string]any{"selection_id": "s1"}, 0.25},
+		{"s2", "0.75"},
+		{&ModelSelection{ID: "s3"}, 1},
+	} {
+		env := core.NewEnvelope().WithVar("sel", tt.sel).WithVar("r", tt.reward)
+		if _, err := node.Run(ctx, env); err != nil {
+			t.Fatalf("Run(%v, %v): %v", tt.sel, tt.reward, err)
+		}
+	}
+	stats, _ := store.ArmStats(ctx, "k")
+	if stats[0].Rewards != 3 || stats[0].RewardSum != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	if _, err := node.Run(ctx, core.NewEnvelope().WithVar("sel", "s1").WithVar("r", -1)); err == nil {
+		t.Error("expected error for negative reward")
+	}
+	if _, err := node.Run(ctx, core.NewEnvelope().WithVar("r", 1)); err == nil {
+		t.Error("expected error without selection")
+	}
+}
@@ -0,0 +1,600 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// ContentFormat names the markup of content a NormalizeContentNode reads
+// or writes.
+type ContentFormat string
+
+const (
+	// ContentFormatAuto treats input as HTML when it contains HTML tags.
+	ContentFormatAuto     ContentFormat = "auto"
+	ContentFormatHTML     ContentFormat = "html"
+	ContentFormatText     ContentFormat = "text"
+	ContentFormatMarkdown ContentFormat = "markdown"
+)
+
+// EmojiMode controls what a NormalizeContentNode does with emoji.
+type EmojiMode string
+
+const (
+	// EmojiKeep leaves emoji as they are.
+	EmojiKeep EmojiMode = "keep"
+	// EmojiNormalize drops variation selectors and skin tone modifiers, so
+	// the same emoji is always written the same way.
+	EmojiNormalize EmojiMode = "normalize"
+	// EmojiStrip removes emoji.
+	EmojiStrip EmojiMode = "strip"
+)
+
+// NormalizeContentNodeConfig configures a NormalizeContentNode.
+type NormalizeContentNodeConfig struct {
+	// InputVar holds the content: a string, a []string, or a []any of
+	// strings or objects. Required.
+	InputVar string
+
+	// Field is the dot path of the content inside each object when
+	// InputVar is a collection of objects. Objects are copied with the
+	// field replaced.
+	Field string
+
+	// OutputKey stores the normalized content, shaped like the input.
+	// Defaults to "{id}_output".
+	OutputKey string
+
+	// MetadataKey stores a ContentNormalization, or one per item for a
+	// collection. Defaults to "{OutputKey}_meta".
+	MetadataKey string
+
+	// InputFormat is ContentFormatAuto (default), ContentFormatHTML or
+	// ContentFormatText.
+	InputFormat ContentFormat
+
+	// OutputFormat is what HTML is converted to: ContentFormatText
+	// (default) or ContentFormatMarkdown.
+	OutputFormat ContentFormat
+
+	// KeepQuotedReplies keeps quoted earlier messages of an email thread.
+	KeepQuotedReplies bool
+
+	// KeepSignatures keeps email signatures and mobile "Sent from" lines.
+	KeepSignatures bool
+
+	// Emoji defaults to EmojiKeep.
+	Emoji EmojiMode
+}
+
+// ContentNormalization describes what a NormalizeContentNode did to one
+// piece of content.
+type ContentNormalization struct {
+	InputFormat ContentFormat `json:"input_format"`
+	// Language is the detected ISO 639-1 code, or "und" when the content
+	// is too short or ambiguous to tell.
+	Language           string  `json:"language"`
+	LanguageConfidence float64 `json:"language_confidence"`
+	QuotedLinesRemoved int     `json:"quoted_lines_removed"`
+	SignatureRemoved   bool    `json:"signature_removed"`
+	EmojiRemoved       int     `json:"emoji_removed"`
+	CharsBefore        int     `json:"chars_before"`
+	CharsAfter         int     `json:"chars_after"`
+}
+
+// NormalizeContentNode cleans inbound human-written content, such as
+// support emails and chat messages, before it reaches an LLM: HTML is
+// converted to text or Markdown, quoted replies and signatures are
+// stripped, whitespace and emoji are normalized, and the language is
+// detected.
+type NormalizeContentNode struct {
+	core.BaseNode
+	config NormalizeContentNodeConfig
+}
+
+// NewNormalizeContentNode creates a NormalizeContentNode.
+func NewNormalizeContentNode(id string, config NormalizeContentNodeConfig) (*NormalizeContentNode, error) {
+	if config.InputVar == "" {
+		return nil, fmt.Errorf("normalize_content node %q: input_var is required", id)
+	}
+	if config.OutputKey == "" {
+		config.OutputKey = id + "_output"
+	}
+	if config.MetadataKey == "" {
+		config.MetadataKey = config.OutputKey + "_meta"
+	}
+
+	switch config.InputFormat {
+	case "":
+		config.InputFormat = ContentFormatAuto
+	case ContentFormatAuto, ContentFormatHTML, ContentFormatText:
+	default:
+		return nil, fmt.Errorf("normalize_content node %q: input_format must be auto, html or text, got %q", id, config.InputFormat)
+	}
+	switch config.OutputFormat {
+	case "":
+		config.OutputFormat = ContentFormatText
+	case ContentFormatText, ContentFormatMarkdown:
+	default:
+		return nil, fmt.Errorf("normalize_content node %q: output_format must be text or markdown, got %q", id, config.OutputFormat)
+	}
+	switch config.Emoji {
+	case "":
+		config.Emoji = EmojiKeep
+	case EmojiKeep, EmojiNormalize, EmojiStrip:
+	default:
+		return nil, fmt.Errorf("normalize_content node %q: emoji must be keep, normalize or strip, got %q", id, config.Emoji)
+	}
+
+	return &NormalizeContentNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindTransform),
+		config:   config,
+	}, nil
+}
+
+// Config returns the node's configuration.
+func (n *NormalizeContentNode) Config() NormalizeContentNodeConfig {
+	return n.config
+}
+
+// Run normalizes the input variable.
+func (n *NormalizeContentNode) Run(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+	input, ok := env.GetVarNested(n.config.InputVar)
+	if !ok {
+		return nil, fmt.Errorf("normalize_content node %s: input var %q not found", n.ID(), n.config.InputVar)
+	}
+	texts, rebuild, err := collectTexts(n.config.InputVar, n.config.Field, input)
+	if err != nil {
+		return nil, fmt.Errorf("normalize_content node %s: %w", n.ID(), err)
+	}
+
+	normalized := make([]string, len(texts))
+	metas := make([]ContentNormalization, len(texts))
+	for i, text := range texts {
+		normalized[i], metas[i], err = n.normalize(text)
+		if err != nil {
+			return nil, fmt.Errorf("normalize_content node %s: item %d: %w", n.ID(), i, err)
+		}
+	}
+
+	out := env.Clone()
+	out.SetVar(n.config.OutputKey, rebuild(normalized))
+	if _, single := input.(string); single {
+		out.SetVar(n.config.MetadataKey, metas[0])
+	} else {
+		out.SetVar(n.config.MetadataKey, metas)
+	}
+	return out, nil
+}
+
+// normalize cleans one piece of content.
+func (n *NormalizeContentNode) normalize(text string) (string, ContentNormalization, error) {
+	meta := ContentNormalization{
+		InputFormat: n.config.InputFormat,
+		CharsBefore: utf8.RuneCountInString(text),
+	}
+	if meta.InputFormat == ContentFormatAuto {
+		meta.InputFormat = ContentFormatText
+		if looksLikeHTML(text) {
+			meta.InputFormat = ContentFormatHTML
+		}
+	}
+
+	if meta.InputFormat == ContentFormatHTML {
+		conv, err := htmlToText(text, n.config.OutputFormat == ContentFormatMarkdown, n.config.KeepQuotedReplies, n.config.KeepSignatures)
+		if err != nil {
+			return "", meta, err
+		}
+		text = conv.text
+		meta.QuotedLinesRemoved = conv.quotedLines
+		meta.SignatureRemoved = conv.signatureRemoved
+	}
+
+	text = normalizeWhitespace(norm.NFC.String(text))
+	if !n.config.KeepQuotedReplies {
+		var removed int
+		text, removed = stripQuotedReplies(text)
+		meta.QuotedLinesRemoved += removed
+	}
+	if !n.config.KeepSignatures {
+		var removed bool
+		text, removed = stripSignature(text)
+		meta.SignatureRemoved = meta.SignatureRemoved || removed
+	}
+	text, meta.EmojiRemoved = normalizeEmoji(text, n.config.Emoji)
+	text = normalizeWhitespace(text)
+
+	meta.Language, meta.LanguageConfidence = DetectLanguage(text)
+	meta.CharsAfter = utf8.RuneCountInString(text)
+	return text, meta, nil
+}
+
+// normalizeWhitespace unifies line endings, turns Unicode spaces into
+// ASCII spaces, drops zero-width characters, collapses runs of spaces
+// inside lines and runs of blank lines, and trims the result. Leading
+// indentation and the contents of Markdown code fences are kept.
+func normalizeWhitespace(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\u200b' || r == '\ufeff' || r == '\u2060' || r == '\u00ad':
+			return -1
+		case r == '\t' || r == '\n':
+			return r
+		case unicode.IsSpace(r):
+			return ' '
+		}
+		return r
+	}, s)
+
+	lines := strings.Split(s, "\n")
+	out := make([]string, 0, len(lines))
+	blank, fenced := true, false
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if strings.HasPrefix(line, "```") {
+			fenced = !fenced
+		} else if fenced {
+			out = append(out, line)
+			continue
+		}
+		if line == "" {
+			if !blank {
+				out = append(out, "")
+			}
+			blank = true
+			continue
+		}
+		body := strings.TrimLeft(line, " \t")
+		indent := line[:len(line)-len(body)]
+		out = append(out, indent+innerSpaceRun.ReplaceAllString(body, " "))
+		blank = false
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+var innerSpaceRun = regexp.MustCompile(`[ \t]{2,}`)
+
+// replyHeaderPatterns match the line a mail client puts above a quoted
+// earlier message. Everything from that line on is dropped.
+var replyHeaderPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^On\s.{1,200}\swrote:$`),
+	regexp.MustCompile(`(?i)^Le\s.{1,200}\sa écrit\s?:$`),
+	regexp.MustCompile(`(?i)^Am\s.{1,200}\sschrieb.{0,100}:$`),
+	regexp.MustCompile(`(?i)^El\s.{1,200}\sescribió:$`),
+	regexp.MustCompile(`(?i)^Il\s.{1,200}\sha scritto:$`),
+	regexp.MustCompile(`(?i)^Em\s.{1,200}\sescreveu:$`),
+	regexp.MustCompile(`(?i)^Op\s.{1,200}\sschreef.{0,100}:$`),
+	regexp.MustCompile(`(?i)^-{2,}\s*Original Message\s*-{2,}$`),
+}
+
+var (
+	outlookFromLine = regexp.MustCompile(`(?i)^\*?From:\*?\s`)
+	outlookSentLine = regexp.MustCompile(`(?i)^\*?(Sent|Date):\*?\s`)
+	underscoreRule  = regexp.MustCompile(`^_{10,}$`)
+)
+
+// stripQuotedReplies drops ">"-quoted lines and the quoted earlier message
+// below a reply header. It returns the number of non-blank lines dropped.
+func stripQuotedReplies(s string) (string, int) {
+	lines := strings.Split(s, "\n")
+	cut := len(lines)
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if isReplyHeader(trimmed) || (i+1 < len(lines) && isReplyHeader(trimmed+" "+strings.TrimSpace(lines[i+1]))) {
+			cut = i
+			break
+		}
+		if outlookFromLine.MatchString(trimmed) && outlookHeaderFollows(lines[i+1:]) {
+			cut = i
+			if i > 0 && underscoreRule.MatchString(strings.TrimSpace(lines[i-1])) {
+				cut = i - 1
+			}
+			break
+		}
+	}
+
+	removed := countTextLines(strings.Join(lines[cut:], "\n"))
+	kept := make([]string, 0, cut)
+	for _, line := range lines[:cut] {
+		if strings.HasPrefix(strings.TrimSpace(line), ">") {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n"), removed
+}
+
+func isReplyHeader(line string) bool {
+	for _, p := range replyHeaderPatterns {
+		if p.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// outlookHeaderFollows reports whether a Sent: or Date: line follows a
+// From: line within the header block.
+func outlookHeaderFollows(lines []string) bool {
+	for _, line := range lines[:min(4, len(lines))] {
+		if outlookSentLine.MatchString(strings.TrimSpace(line)) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	mobileFooterLine = regexp.MustCompile(`(?i)^(Sent from my .+|Sent from (Mail|Outlook|Yahoo Mail) for .+|Sent from Yahoo Mail.*|Get Outlook for .+)$`)
+	valedictionLine  = regexp.MustCompile(`(?i)^(best|best regards|kind regards|warm regards|regards|cheers|thanks|thank you|many thanks|thanks again|sincerely|yours truly|all the best)[,.!]?$`)
+)
+
+// maxSignatureLines bounds how many short lines after a closing such as
+// "Thanks," are treated as a signature.
+const maxSignatureLines = 5
+
+// stripSignature drops a "-- " delimited signature, mobile "Sent from"
+// footers, and the short lines (name, title, phone) after a closing such
+// as "Best regards,". The closing itself is kept.
+func stripSignature(s string) (string, bool) {
+	lines := strings.Split(s, "\n")
+	removed := false
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "--" {
+			lines = lines[:i]
+			removed = true
+			break
+		}
+	}
+
+	kept := lines[:0]
+	for _, line := range lines {
+		if mobileFooterLine.MatchString(strings.TrimSpace(line)) {
+			removed = true
+			continue
+		}
+		kept = append(kept, line)
+	}
+	lines = kept
+
+	for i := len(lines) - 1; i >= 0 && i >= len(lines)-maxSignatureLines-1; i-- {
+		if !valedictionLine.MatchString(strings.TrimSpace(lines[i])) {
+			continue
+		}
+		tail := lines[i+1:]
+		if countTextLines(strings.Join(tail, "\n")) > 0 && shortLines(tail, 60) {
+			lines = lines[:i+1]
+			removed = true
+		}
+		break
+	}
+	return strings.Join(lines, "\n"), removed
+}
+
+func shortLines(lines []string, limit int) bool {
+	for _, line := range lines {
+		if utf8.RuneCountInString(strings.TrimSpace(line)) > limit {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeEmoji applies mode to s and returns the number of emoji
+// removed. A sequence joined with zero width joiners and a flag's pair of
+// regional indicators count as one emoji.
+func normalizeEmoji(s string, mode EmojiMode) (string, int) {
+	if mode != EmojiNormalize && mode != EmojiStrip {
+		return s, 0
+	}
+	var sb strings.Builder
+	sb.Grow(len(s))
+	removed, regional := 0, 0
+	inEmoji, joined := false, false
+	for _, r := range s {
+		switch {
+		case inEmoji && r == '\u200d':
+			if mode == EmojiNormalize {
+				sb.WriteRune(r)
+			}
+			joined = true
+		case inEmoji && isEmojiModifier(r):
+		case isEmoji(r):
+			flag := r >= 0x1F1E6 && r <= 0x1F1FF
+			if flag {
+				regional++
+			} else {
+				regional = 0
+			}
+			if mode == EmojiNormalize {
+				sb.WriteRune(r)
+			} else if !joined && (!flag || regional%2 == 1) {
+				// Joined emoji and the second half of a flag continue the
+				// emoji before them.
+				removed++
+			}
+			inEmoji, joined = true, false
+		default:
+			sb.WriteRune(r)
+			inEmoji, joined, regional = false, false, 0
+		}
+	}
+	return sb.String(), removed
+}
+
+// isEmoji reports whether r is an emoji or pictograph.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF:
+		return !isSkinTone(r)
+	case r >= 0x2600 && r <= 0x27BF:
+		return true
+	case r == 0x2B50 || r == 0x2B55 || r == 0x2B1B || r == 0x2B1C || r == 0x2B06 || r == 0x2B07 || r == 0x2B05:
+		return true
+	}
+	return false
+}
+
+// isEmojiModifier reports whether r only changes how an adjacent emoji
+// looks: variation selectors, skin tones, the zero width joiner, the
+// keycap mark and tag characters.
+func isEmojiModifier(r rune) bool {
+	return r == 0xFE0F || r == 0xFE0E || r == 0x200D || r == 0x20E3 || isSkinTone(r) || (r >= 0xE0020 && r <= 0xE007F)
+}
+
+func isSkinTone(r rune) bool {
+	return r >= 0x1F3FB && r <= 0x1F3FF
+}
+
+// scriptLanguages maps scripts used by a single main language to its
+// ISO 639-1 code.
+var scriptLanguages = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Greek, "el"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// latinStopwords lists frequent words of languages written in Latin
+// script.
+var latinStopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "were", "you", "your", "this", "that", "with", "have", "has", "not", "for", "it", "of", "to", "in", "on", "be", "i", "my", "we", "can", "please", "what", "would", "but", "when"},
+	"es": {"el", "la", "los", "las", "que", "y", "es", "en", "un", "una", "por", "para", "con", "no", "su", "mi", "pero", "está", "hola", "gracias", "como", "muy", "tengo", "del", "al", "cuando", "puedo"},
+	"fr": {"le", "la", "les", "des", "est", "et", "je", "vous", "nous", "une", "un", "pas", "que", "pour", "avec", "dans", "sur", "mon", "bonjour", "merci", "ce", "il", "du", "au", "sont", "mais", "ne"},
+	"de": {"der", "die", "das", "und", "ist", "ich", "nicht", "sie", "ein", "eine", "mit", "auf", "zu", "den", "dem", "für", "es", "wir", "bitte", "danke", "hallo", "auch", "aber", "mein", "kann", "haben", "wenn"},
+	"it": {"il", "lo", "gli", "che", "di", "è", "non", "un", "una", "per", "con", "sono", "mi", "ho", "ciao", "grazie", "come", "della", "del", "ma", "questo", "quando", "posso"},
+	"pt": {"o", "os", "que", "de", "é", "não", "um", "uma", "para", "com", "por", "meu", "minha", "eu", "obrigado", "obrigada", "olá", "está", "mas", "você", "do", "da", "isso", "quando", "posso"},
+	"nl": {"de", "het", "een", "en", "is", "ik", "niet", "van", "dat", "die", "met", "voor", "op", "zijn", "je", "wij", "maar", "ook", "bedankt", "hallo", "graag", "mijn", "kan", "wanneer"},
+}
+
+var latinStopwordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(latinStopwords))
+	for lang, words := range latinStopwords {
+		set := make(map[string]bool, len(words))
+		for _, w := range words {
+			set[w] = true
+		}
+		sets[lang] = set
+	}
+	return sets
+}()
+
+// minLanguageEvidence is the fewest matching stopwords a Latin-script text
+// needs for a language to be reported.
+const minLanguageEvidence = 2
+
+// DetectLanguage guesses the ISO 639-1 code of s from its script and, for
+// Latin script, its most frequent words. It returns "und" with confidence
+// 0 when s is too short or ambiguous. Confidence is between 0 and 1.
+func DetectLanguage(s string) (string, float64) {
+	counts := make(map[string]int)
+	letters, latin, han, kana := 0, 0, 0, 0
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		default:
+			for _, sl := range scriptLanguages {
+				if unicode.Is(sl.table, r) {
+					counts[sl.lang]++
+					break
+				}
+			}
+		}
+	}
+	if letters < 3 {
+		return "und", 0
+	}
+	if kana > 0 {
+		counts["ja"] = kana + han
+	} else if han > 0 {
+		counts["zh"] = han
+	}
+
+	best, bestCount := "", 0
+	for lang, c := range counts {
+		if c > bestCount || (c == bestCount && lang < best) {
+			best, bestCount = lang, c
+		}
+	}
+	if bestCount > latin {
+		if best == "ru" && strings.ContainsAny(strings.ToLower(s), "іїєґ") {
+			best = "uk"
+		}
+		return best, roundConfidence(float64(bestCount) / float64(letters))
+	}
+	return detectLatinLanguage(s)
+}
+
+// detectLatinLanguage scores s against each language's stopwords.
+func detectLatinLanguage(s string) (string, float64) {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	})
+	scores := make(map[string]int, len(latinStopwordSets))
+	total := 0
+	for _, w := range words {
+		for lang, set := range latinStopwordSets {
+			if set[w] {
+				scores[lang]++
+				total++
+			}
+		}
+	}
+
+	langs := make([]string, 0, len(scores))
+	for lang := range scores {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	best, bestScore, second := "", 0, 0
+	for _, lang := range langs {
+		if score := scores[lang]; score > bestScore {
+			best, bestScore, second = lang, score, bestScore
+		} else if score > second {
+			second = score
+		}
+	}
+	if bestScore < minLanguageEvidence || bestScore == second {
+		return "und", 0
+	}
+	// Share of stopword hits, scaled down until there is enough evidence.
+	share := float64(bestScore) / float64(total)
+	evidence := math.Min(1, float64(bestScore)/5)
+	return best, roundConfidence(share * evidence)
+}
+
+func roundConfidence(c float64) float64 {
+	return math.Round(c*100) / 100
+}
+
+// Ensure interface compliance at compile time.
+var _ core.Node = (*NormalizeContentNode)(nil)
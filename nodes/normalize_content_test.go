@@ -0,0 +1,214 @@
+package nodes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func runNormalizeContent(t *testing.T, config NormalizeContentNodeConfig, input any) (any, any) {
+	t.Helper()
+	config.InputVar = "body"
+	node, err := NewNormalizeContentNode("clean", config)
+	if err != nil {
+		t.Fatalf("NewNormalizeContentNode: %v", err)
+	}
+	env := core.NewEnvelope().WithVar("body", input)
+	out, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	output, _ := out.GetVar("clean_output")
+	meta, _ := out.GetVar("clean_output_meta")
+	return output, meta
+}
+
+func TestNormalizeContent_HTMLEmail(t *testing.T) {
+	input := `<html><head><style>p{color:red}</style></head><body>
+<div dir="ltr"><p>Hi&nbsp;team,</p>
+<p>The <b>export</b> button fails with <a href="https://status.example.com/42">this error</a>:</p>
+<ul><li>Step one</li><li>Step two</li></ul>
+<p>Thanks,</p>
+<div class="gmail_signature">Jane Doe<br>Support Lead</div>
+<div class="gmail_quote"><div>On Mon, Jan 6, 2025 at 9:00 AM Bob wrote:</div>
+<blockquote type="cite">Can you send details?<br>Line two</blockquote></div>
+</div></body></html>`
+
+	output, metaAny := runNormalizeContent(t, NormalizeContentNodeConfig{}, input)
+	want := "Hi team,\n\nThe export button fails with this error (https://status.example.com/42):\n\n- Step one\n- Step two\n\nThanks,"
+	if output != want {
+		t.Fatalf("output =\n%q\nwant\n%q", output, want)
+	}
+	meta := metaAny.(ContentNormalization)
+	if meta.InputFormat != ContentFormatHTML || !meta.SignatureRemoved || meta.QuotedLinesRemoved != 3 {
+		t.Errorf("meta = %+v, want html with signature and 3 quoted lines removed", meta)
+	}
+	if meta.Language != "en" || meta.LanguageConfidence < 0.3 {
+		t.Errorf("language = %s (%.2f), want en", meta.Language, meta.LanguageConfidence)
+	}
+}
+
+func TestNormalizeContent_Markdown(t *testing.T) {
+	input := `<h2>Steps</h2><ol><li>Open <em>Settings</em></li><li>Click <a href="https://example.com/x">Export</a></li></ol><pre><code>  code  here</code></pre><blockquote><p>kept quote</p></blockquote>`
+
+	output, _ := runNormalizeContent(t, NormalizeContentNodeConfig{
+		OutputFormat:      ContentFormatMarkdown,
+		KeepQuotedReplies: true,
+	}, input)
+	want := "## Steps\n\n1. Open _Settings_\n2. Click [Export](https://example.com/x)\n\n```\n  code  here\n```\n\n> kept quote"
+	if output != want {
+		t.Fatalf("output =\n%q\nwant\n%q", output, want)
+	}
+}
+
+func TestNormalizeContent_PlainTextReply(t *testing.T) {
+	input := "Hola,\r\n\r\n\r\nNo   puedo   entrar a mi cuenta desde ayer y tengo que enviar la factura.\n> old quoted line\n\nSaludos cordiales\n--\nMaría\nSoporte\n\nEl lun, 6 ene 2025 a las 9:00, Bob <bob@example.com> escribió:\n> Hola María\n"
+
+	output, metaAny := runNormalizeContent(t, NormalizeContentNodeConfig{}, input)
+	want := "Hola,\n\nNo puedo entrar a mi cuenta desde ayer y tengo que enviar la factura.\n\nSaludos cordiales"
+	if output != want {
+		t.Fatalf("output =\n%q\nwant\n%q", output, want)
+	}
+	meta := metaAny.(ContentNormalization)
+	if meta.InputFormat != ContentFormatText || !meta.SignatureRemoved || meta.QuotedLinesRemoved != 3 {
+		t.Errorf("meta = %+v", meta)
+	}
+	if meta.Language != "es" {
+		t.Errorf("language = %s, want es", meta.Language)
+	}
+}
+
+func TestNormalizeContent_OutlookHeaderAndFooter(t *testing.T) {
+	input := "Please reset my password.\n\nSent from my iPhone\n\n________________________________\nFrom: Support <help@example.com>\nSent: Monday, January 6, 2025\nSubject: Re: ticket\n\nEarlier message"
+
+	output, metaAny := runNormalizeContent(t, NormalizeContentNodeConfig{}, input)
+	if output != "Please reset my password." {
+		t.Fatalf("output = %q", output)
+	}
+	meta := metaAny.(ContentNormalization)
+	if meta.QuotedLinesRemoved != 5 || !meta.SignatureRemoved {
+		t.Errorf("meta = %+v", meta)
+	}
+}
+
+func TestNormalizeContent_Valediction(t *testing.T) {
+	input := "The invoice total is wrong.\n\nBest regards,\nJane Doe\nAcme Corp | +1 555 0100"
+	output, _ := runNormalizeContent(t, NormalizeContentNodeConfig{}, input)
+	if output != "The invoice total is wrong.\n\nBest regards," {
+		t.Fatalf("output = %q", output)
+	}
+
+	output, _ = runNormalizeContent(t, NormalizeContentNodeConfig{KeepSignatures: true}, input)
+	if output != input {
+		t.Fatalf("KeepSignatures output = %q", output)
+	}
+}
+
+func TestNormalizeContent_Emoji(t *testing.T) {
+	input := "Great job 👍\U0001F3FD team ❤\ufe0f 🇩🇪 👨\u200d👩\u200d👧\u200b!"
+
+	output, metaAny := runNormalizeContent(t, NormalizeContentNodeConfig{Emoji: EmojiStrip}, input)
+	if output != "Great job team !" {
+		t.Fatalf("strip output = %q", output)
+	}
+	if got := metaAny.(ContentNormalization).EmojiRemoved; got != 4 {
+		t.Errorf("EmojiRemoved = %d, want 4", got)
+	}
+
+	output, _ = runNormalizeContent(t, NormalizeContentNodeConfig{Emoji: EmojiNormalize}, input)
+	if output != "Great job 👍 team ❤ 🇩🇪 👨\u200d👩\u200d👧!" {
+		t.Fatalf("normalize output = %q", output)
+	}
+
+	output, _ = runNormalizeContent(t, NormalizeContentNodeConfig{}, "धन्यवाद क्ष\u200dा")
+	if !strings.Contains(output.(string), "\u200d") {
+		t.Errorf("zero width joiner outside emoji should be kept: %q", output)
+	}
+}
+
+func TestNormalizeContent_Collection(t *testing.T) {
+	input := []any{
+		map[string]any{"id": 1, "message": map[string]any{"body": "<p>Bonjour, je ne peux pas me connecter.</p>"}},
+		map[string]any{"id": 2},
+		"Plain   text",
+	}
+	output, metaAny := runNormalizeContent(t, NormalizeContentNodeConfig{Field: "message.body"}, input)
+
+	items := output.([]any)
+	first := items[0].(map[string]any)["message"].(map[string]any)["body"]
+	if first != "Bonjour, je ne peux pas me connecter." {
+		t.Errorf("item 0 body = %q", first)
+	}
+	if _, ok := items[1].(map[string]any)["message"]; ok {
+		t.Error("item without the field should pass through unchanged")
+	}
+	if items[2] != "Plain text" {
+		t.Errorf("item 2 = %q", items[2])
+	}
+	if input[0].(map[string]any)["message"].(map[string]any)["body"] == first {
+		t.Error("input objects must not be modified")
+	}
+
+	metas := metaAny.([]ContentNormalization)
+	if len(metas) != 3 || metas[0].Language != "fr" || metas[0].InputFormat != ContentFormatHTML {
+		t.Errorf("metas = %+v", metas)
+	}
+}
+
+func TestNormalizeContent_Config(t *testing.T) {
+	if _, err := NewNormalizeContentNode("n", NormalizeContentNodeConfig{}); err == nil {
+		t.Error("expected error without input_var")
+	}
+	if _, err := NewNormalizeContentNode("n", NormalizeContentNodeConfig{InputVar: "x", OutputFormat: ContentFormatHTML}); err == nil {
+		t.Error("expected error for html output_format")
+	}
+	if _, err := NewNormalizeContentNode("n", NormalizeContentNodeConfig{InputVar: "x", Emoji: "remove"}); err == nil {
+		t.Error("expected error for unknown emoji mode")
+	}
+
+	node, err := NewNormalizeContentNode("n", NormalizeContentNodeConfig{InputVar: "x", InputFormat: ContentFormatText})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := node.Run(context.Background(), core.NewEnvelope().WithVar("x", "<b>literal</b> tags"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := out.GetVar("n_output"); got != "<b>literal</b> tags" {
+		t.Errorf("text input_format output = %q", got)
+	}
+	if _, err := node.Run(context.Background(), core.NewEnvelope().WithVar("x", 42)); err == nil {
+		t.Error("expected error for non-string input")
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"I cannot log in to my account and the reset link is not working", "en"},
+		{"Ich kann mich nicht anmelden und der Link ist auch nicht gültig", "de"},
+		{"Non riesco ad accedere, il link non funziona e ho bisogno di aiuto", "it"},
+		{"Não consigo entrar na minha conta e o link não funciona", "pt"},
+		{"Ik kan niet inloggen en de link werkt ook niet", "nl"},
+		{"Не могу войти в аккаунт", "ru"},
+		{"Не можу увійти в обліковий запис, ї", "uk"},
+		{"ログインできません", "ja"},
+		{"我无法登录我的账户", "zh"},
+		{"로그인할 수 없습니다", "ko"},
+		{"ok", "und"},
+		{"12345 !!!", "und"},
+	}
+	for _, tt := range tests {
+		got, conf := DetectLanguage(tt.text)
+		if got != tt.want {
+			t.Errorf("DetectLanguage(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+		if (got == "und") != (conf == 0) || conf > 1 {
+			t.Errorf("DetectLanguage(%q) confidence = %.2f", tt.text, conf)
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package nodes
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// htmlTagPattern matches the tags that mark content as HTML for
+// ContentFormatAuto.
+var htmlTagPattern = regexp.MustCompile(`(?i)<(html|body|div|p|br|span|table|a|b|i|strong|em|ul|ol|li|blockquote|h[1-6]|font|img|pre)(\s[^>]*)?/?>`)
+
+var whitespaceRun = regexp.MustCompile(`\s+`)
+
+// looksLikeHTML reports whether s contains HTML markup.
+func looksLikeHTML(s string) bool {
+	return htmlTagPattern.MatchString(s)
+}
+
+// htmlConversion is the result of converting an HTML document to text.
+type htmlConversion struct {
+	text             string
+	quotedLines      int
+	signatureRemoved bool
+}
+
+// htmlToText converts an HTML document to plain text, or Markdown when
+// markdown is set. Quoted replies (blockquotes and mail client quote
+// containers) and mail client signature containers are dropped unless
+// kept.
+func htmlToText(src string, markdown, keepQuotes, keepSignatures bool) (htmlConversion, error) {
+	doc, err := html.Parse(strings.NewReader(src))
+	if err != nil {
+		return htmlConversion{}, err
+	}
+	w := &htmlTextWriter{markdown: markdown, keepQuotes: keepQuotes, keepSignatures: keepSignatures}
+	w.children(doc)
+	return htmlConversion{
+		text:             w.sb.String(),
+		quotedLines:      w.quotedLines,
+		signatureRemoved: w.signatureRemoved,
+	}, nil
+}
+
+// htmlTextWriter renders an HTML tree as text.
+type htmlTextWriter struct {
+	markdown       bool
+	keepQuotes     bool
+	keepSignatures bool
+
+	sb    strings.Builder
+	pre   int
+	lists []htmlList
+
+	quotedLines      int
+	signatureRemoved bool
+}
+
+type htmlList struct {
+	ordered bool
+	next    int
+}
+
+// sub returns a writer for rendering a subtree on its own, sharing the
+// options and list nesting.
+func (w *htmlTextWriter) sub() *htmlTextWriter {
+	return &htmlTextWriter{
+		markdown:       w.markdown,
+		keepQuotes:     w.keepQuotes,
+		keepSignatures: w.keepSignatures,
+		pre:            w.pre,
+		lists:          w.lists,
+	}
+}
+
+// merge adds the counters of a sub writer.
+func (w *htmlTextWriter) merge(sub *htmlTextWriter) {
+	w.quotedLines += sub.quotedLines
+	w.signatureRemoved = w.signatureRemoved || sub.signatureRemoved
+}
+
+// render returns the text of n's children.
+func (w *htmlTextWriter) render(n *html.Node) string {
+	sub := w.sub()
+	sub.children(n)
+	w.merge(sub)
+	return sub.sb.String()
+}
+
+func (w *htmlTextWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+func (w *htmlTextWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.DocumentNode:
+		w.children(n)
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if isQuoteContainer(n) && !w.keepQuotes {
+		w.quotedLines += countTextLines(w.render(n))
+		return
+	}
+	if isSignatureContainer(n) && !w.keepSignatures {
+		w.render(n)
+		w.signatureRemoved = true
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Head, atom.Title, atom.Noscript, atom.Template:
+	case atom.Br:
+		w.trimTrailingSpace()
+		w.sb.WriteByte('\n')
+	case atom.P, atom.Table, atom.Address, atom.Dl:
+		w.block(2)
+		w.children(n)
+		w.block(2)
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		w.block(2)
+		if w.markdown {
+			level, _ := strconv.Atoi(n.Data[1:])
+			w.sb.WriteString(strings.Repeat("#", level) + " ")
+		}
+		w.children(n)
+		w.block(2)
+	case atom.Ul, atom.Ol:
+		w.block(1)
+		w.lists = append(w.lists, htmlList{ordered: n.DataAtom == atom.Ol, next: 1})
+		w.children(n)
+		w.lists = w.lists[:len(w.lists)-1]
+		w.block(1)
+	case atom.Li:
+		w.block(1)
+		w.listMarker()
+		w.children(n)
+		w.block(1)
+	case atom.Blockquote:
+		w.block(2)
+		w.prefixed(strings.TrimSpace(w.render(n)), "> ")
+		w.block(2)
+	case atom.Pre:
+		w.block(2)
+		w.pre++
+		body := strings.Trim(w.render(n), "\n")
+		w.pre--
+		if w.markdown {
+			w.sb.WriteString("```\n" + body + "\n```")
+		} else {
+			w.sb.WriteString(body)
+		}
+		w.block(2)
+	case atom.Hr:
+		w.block(2)
+		if w.markdown {
+			w.sb.WriteString("---")
+		}
+		w.block(2)
+	case atom.Tr:
+		w.block(1)
+		w.children(n)
+		w.block(1)
+	case atom.Td, atom.Th:
+		if hasElementBefore(n) {
+			w.sb.WriteString(" | ")
+		}
+		w.children(n)
+	case atom.A:
+		w.link(n)
+	case atom.Strong, atom.B:
+		w.wrap(n, "**")
+	case atom.Em, atom.I:
+		w.wrap(n, "_")
+	case atom.Code:
+		if w.pre > 0 {
+			w.children(n)
+		} else {
+			w.wrap(n, "`")
+		}
+	case atom.Img:
+		w.text(attr(n, "alt"))
+	default:
+		if isBlockElement(n.DataAtom) {
+			w.block(1)
+			w.children(n)
+			w.block(1)
+			return
+		}
+		w.children(n)
+	}
+}
+
+// text writes a text node, collapsing whitespace outside preformatted
+// blocks.
+func (w *htmlTextWriter) text(s string) {
+	if w.pre > 0 {
+		w.sb.WriteString(s)
+		return
+	}
+	s = whitespaceRun.ReplaceAllString(s, " ")
+	if w.endsWithSpace() {
+		s = strings.TrimLeft(s, " ")
+	}
+	w.sb.WriteString(s)
+}
+
+func (w *htmlTextWriter) endsWithSpace() bool {
+	if w.sb.Len() == 0 {
+		return true
+	}
+	last := w.sb.String()[w.sb.Len()-1]
+	return last == ' ' || last == '\n'
+}
+
+// block ends the current line and adds blank lines so that at least n
+// newlines separate what follows from what came before.
+func (w *htmlTextWriter) block(n int) {
+	if w.sb.Len() == 0 {
+		return
+	}
+	w.trimTrailingSpace()
+	s := w.sb.String()
+	have := len(s) - len(strings.TrimRight(s, "\n"))
+	for ; have < n; have++ {
+		w.sb.WriteByte('\n')
+	}
+}
+
+func (w *htmlTextWriter) trimTrailingSpace() {
+	s := w.sb.String()
+	if trimmed := strings.TrimRight(s, " \t"); len(trimmed) != len(s) {
+		w.sb.Reset()
+		w.sb.WriteString(trimmed)
+	}
+}
+
+func (w *htmlTextWriter) listMarker() {
+	if len(w.lists) == 0 {
+		w.sb.WriteString("- ")
+		return
+	}
+	list := &w.lists[len(w.lists)-1]
+	w.sb.WriteString(strings.Repeat("  ", len(w.lists)-1))
+	if list.ordered {
+		w.sb.WriteString(strconv.Itoa(list.next) + ". ")
+		list.next++
+		return
+	}
+	w.sb.WriteString("- ")
+}
+
+// prefixed writes each line of s with prefix.
+func (w *htmlTextWriter) prefixed(s, prefix string) {
+	if s == "" {
+		return
+	}
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(prefix+line, " ")
+	}
+	w.sb.WriteString(strings.Join(lines, "\n"))
+}
+
+// wrap writes n's text between Markdown emphasis markers.
+func (w *htmlTextWriter) wrap(n *html.Node, marker string) {
+	inner := strings.TrimSpace(w.render(n))
+	if inner == "" {
+		return
+	}
+	if !w.markdown {
+		marker = ""
+	}
+	w.sb.WriteString(marker + inner + marker)
+}
+
+// link writes a link as [text](href) in Markdown and "text (href)" in
+// text, or just its text when it has no useful href.
+func (w *htmlTextWriter) link(n *html.Node) {
+	inner := strings.TrimSpace(w.render(n))
+	href := attr(n, "href")
+	useful := strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") || strings.HasPrefix(href, "mailto:")
+	if !useful || inner == href || strings.TrimPrefix(href, "mailto:") == inner {
+		if inner == "" {
+			inner = href
+		}
+		w.sb.WriteString(inner)
+		return
+	}
+	switch {
+	case inner == "":
+		w.sb.WriteString(href)
+	case w.markdown:
+		w.sb.WriteString("[" + inner + "](" + href + ")")
+	default:
+		w.sb.WriteString(inner + " (" + href + ")")
+	}
+}
+
+// isQuoteContainer reports whether n holds a quoted earlier message.
+func isQuoteContainer(n *html.Node) bool {
+	if n.DataAtom == atom.Blockquote && (attr(n, "type") == "cite" || hasClass(n, "gmail_quote")) {
+		return true
+	}
+	return hasClass(n, "gmail_quote") || hasClass(n, "yahoo_quoted") || hasClass(n, "moz-cite-prefix") ||
+		attr(n, "id") == "divRplyFwdMsg" || attr(n, "id") == "appendonsend"
+}
+
+// isSignatureContainer reports whether n is a mail client's signature
+// block.
+func isSignatureContainer(n *html.Node) bool {
+	return hasClass(n, "gmail_signature") || hasClass(n, "moz-signature") || attr(n, "id") == "Signature"
+}
+
+func isBlockElement(a atom.Atom) bool {
+	switch a {
+	case atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Nav, atom.Aside,
+		atom.Main, atom.Form, atom.Center, atom.Figure, atom.Figcaption, atom.Dt, atom.Dd,
+		atom.Tbody, atom.Thead, atom.Tfoot, atom.Caption, atom.Body, atom.Html:
+		return true
+	}
+	return false
+}
+
+func hasElementBefore(n *html.Node) bool {
+	for p := n.PrevSibling; p != nil; p = p.PrevSibling {
+		if p.Type == html.ElementNode {
+			return true
+		}
+	}
+	return false
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasClass(n *html.Node, class string) bool {
+	for _, c := range strings.Fields(attr(n, "class")) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// countTextLines counts the non-blank lines of s.
+func countTextLines(s string) int {
+	count := 0
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+	return count
+}
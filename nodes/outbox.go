@@ -0,0 +1,62 @@
+package nodes
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Outbox delivery statuses.
+const (
+	// DeliveryPending deliveries are waiting for their next attempt.
+	DeliveryPending = "pending"
+	// DeliveryDelivered deliveries got a 2xx response.
+	DeliveryDelivered = "delivered"
+	// DeliveryFailed deliveries ran out of attempts or were rejected with
+	// a status that retrying cannot fix.
+	DeliveryFailed = "failed"
+)
+
+// OutboxDelivery is an outbound HTTP request recorded by a webhook_call
+// node for a delivery worker to send.
+type OutboxDelivery struct {
+	ID      string            `json:"id"`
+	RunID   string            `json:"run_id"`
+	NodeID  string            `json:"node_id"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+
+	Status     string `json:"status"`
+	Attempts   int    `json:"attempts"`
+	StatusCode int    `json:"status_code,omitempty"`
+	LastError  string `json:"last_error,omitempty"`
+
+	NextAttemptAt time.Time  `json:"next_attempt_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeliveredAt   *time.Time `json:"delivered_at,omitempty"`
+}
+
+// Outbox records outbound deliveries so they survive a crash of the
+// process that made them.
+type Outbox interface {
+	// EnqueueDelivery records d as pending and due now. When a delivery
+	// with d's ID exists it is left alone and returned instead, so a node
+	// that runs again for the same request never delivers it twice.
+	EnqueueDelivery(ctx context.Context, d OutboxDelivery) (OutboxDelivery, error)
+}
+
+// OutboxDeliveryID derives the ID of the delivery a node makes in a run.
+// The same run, node and body always give the same ID.
+func OutboxDeliveryID(runID, nodeID string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(runID))
+	h.Write([]byte{0})
+	h.Write([]byte(nodeID))
+	h.Write([]byte{0})
+	h.Write(body)
+	return "dlv_" + hex.EncodeToString(h.Sum(nil))[:32]
+}
@@ -0,0 +1,25 @@
+package nodes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutboxDeliveryID(t *testing.T) {
+	id := OutboxDeliveryID("run-1", "notify", []byte(`{"a":1}`))
+	if id != OutboxDeliveryID("run-1", "notify", []byte(`{"a":1}`)) {
+		t.Fatal("OutboxDeliveryID is not deterministic")
+	}
+	for _, other := range []string{
+		OutboxDeliveryID("run-2", "notify", []byte(`{"a":1}`)),
+		OutboxDeliveryID("run-1", "alert", []byte(`{"a":1}`)),
+		OutboxDeliveryID("run-1", "notify", []byte(`{"a":2}`)),
+	} {
+		if other == id {
+			t.Fatalf("OutboxDeliveryID collision: %s", id)
+		}
+	}
+	if !strings.HasPrefix(id, "dlv_") {
+		t.Fatalf("OutboxDeliveryID = %q, want dlv_ prefix", id)
+	}
+}
@@ -0,0 +1,99 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// PublishNodeConfig configures a PublishNode.
+type PublishNodeConfig struct {
+	// Vars are the variables to publish (dot notation supported). Each is
+	// published under its name as written here.
+	Vars []string
+
+	// Require fails the node when a listed variable is not set. By
+	// default missing variables are left out of the update.
+	Require bool
+}
+
+// PublishNode adds variables to the run's public output while the run
+// continues, so clients can show intermediate results such as retrieved
+// documents before the final answer. Each run emits an output.updated
+// event with a snapshot of the values; the daemon folds them into
+// GET /api/runs/{id}/output. The envelope passes through unchanged.
+type PublishNode struct {
+	core.BaseNode
+	config PublishNodeConfig
+}
+
+// NewPublishNode creates a PublishNode.
+func NewPublishNode(id string, config PublishNodeConfig) (*PublishNode, error) {
+	if len(config.Vars) == 0 {
+		return nil, fmt.Errorf("publish node %q: vars are required", id)
+	}
+	seen := make(map[string]bool, len(config.Vars))
+	for _, name := range config.Vars {
+		if name == "" {
+			return nil, fmt.Errorf("publish node %q: vars must not be empty", id)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("publish node %q: duplicate var %q", id, name)
+		}
+		seen[name] = true
+	}
+	return &PublishNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindNoop),
+		config:   config,
+	}, nil
+}
+
+// Config returns the node's configuration.
+func (n *PublishNode) Config() PublishNodeConfig {
+	return n.config
+}
+
+// Run snapshots the listed variables and emits them as an output.updated
+// event. Nothing is emitted when none of them is set.
+func (n *PublishNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	output := make(map[string]any, len(n.config.Vars))
+	var missing []string
+	for _, name := range n.config.Vars {
+		value, ok := env.GetVarNested(name)
+		if !ok {
+			if n.config.Require {
+				return nil, fmt.Errorf("publish node %s: variable %q is not set", n.ID(), name)
+			}
+			missing = append(missing, name)
+			continue
+		}
+		// Copied so later nodes changing the value in place do not
+		// change what was published.
+		output[name] = copyPublishedValue(value)
+	}
+	if len(output) == 0 {
+		return env, nil
+	}
+
+	event := runtime.NewEvent(runtime.EventOutputUpdated, env.Trace.RunID).
+		WithNode(n.ID(), n.Kind()).
+		WithPayload("output", output)
+	if len(missing) > 0 {
+		event = event.WithPayload("missing", missing)
+	}
+	runtime.EmitterFromContext(ctx)(event)
+	return env, nil
+}
+
+func copyPublishedValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		return deepCopyMap(v)
+	case []any:
+		return deepCopySlice(v)
+	default:
+		return v
+	}
+}
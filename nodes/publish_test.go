@@ -0,0 +1,69 @@
+package nodes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func TestPublishNode_Run(t *testing.T) {
+	node, err := NewPublishNode("publish", PublishNodeConfig{Vars: []string{"retrieval.documents", "draft"}})
+	if err != nil {
+		t.Fatalf("NewPublishNode: %v", err)
+	}
+
+	var events []runtime.Event
+	ctx := runtime.ContextWithEmitter(context.Background(), func(e runtime.Event) { events = append(events, e) })
+	docs := []any{map[string]any{"title": "Refunds"}}
+	env := core.NewEnvelope().WithVar("retrieval", map[string]any{"documents": docs})
+	env.Trace.RunID = "run-1"
+
+	result, err := node.Run(ctx, env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if result != env {
+		t.Error("publish should pass the envelope through")
+	}
+	if len(events) != 1 || events[0].Kind != runtime.EventOutputUpdated || events[0].RunID != "run-1" || events[0].NodeID != "publish" {
+		t.Fatalf("events = %+v", events)
+	}
+	output := events[0].Payload["output"].(map[string]any)
+	published := output["retrieval.documents"].([]any)
+	if len(output) != 1 || len(published) != 1 {
+		t.Fatalf("output = %v", output)
+	}
+	if missing := events[0].Payload["missing"].([]string); len(missing) != 1 || missing[0] != "draft" {
+		t.Errorf("missing = %v", missing)
+	}
+
+	// Later changes to the variable do not change the published snapshot.
+	docs[0].(map[string]any)["title"] = "Changed"
+	if title := published[0].(map[string]any)["title"]; title != "Refunds" {
+		t.Errorf("published title = %v", title)
+	}
+
+	events = nil
+	if _, err := node.Run(ctx, core.NewEnvelope()); err != nil || len(events) != 0 {
+		t.Errorf("nothing set: err = %v, events = %v", err, events)
+	}
+}
+
+func TestPublishNode_Require(t *testing.T) {
+	node, err := NewPublishNode("publish", PublishNodeConfig{Vars: []string{"answer"}, Require: true})
+	if err != nil {
+		t.Fatalf("NewPublishNode: %v", err)
+	}
+	if _, err := node.Run(context.Background(), core.NewEnvelope()); err == nil || !strings.Contains(err.Error(), `"answer" is not set`) {
+		t.Errorf("error = %v", err)
+	}
+
+	for _, vars := range [][]string{nil, {""}, {"a", "a"}} {
+		if _, err := NewPublishNode("publish", PublishNodeConfig{Vars: vars}); err == nil {
+			t.Errorf("vars %q: expected error", vars)
+		}
+	}
+}
@@ -8,6 +8,9 @@ import (
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/nodes/conditional"
+	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 // ConditionOp is an operator for rule conditions.
@@ -22,6 +25,9 @@ const (
 	OpExists      ConditionOp = "exists"
 	OpNotExists   ConditionOp = "not_exists"
 	OpIn          ConditionOp = "in"
+	// OpExpression evaluates RouteCondition.Expression with the conditional
+	// node's expression language.
+	OpExpression ConditionOp = "expr"
 )
 
 // RouteCondition defines a condition for rule-based routing.
@@ -37,6 +43,17 @@ type RouteCondition struct {
 
 	// Values is used with OpIn for multiple value matching.
 	Values []any
+
+	// Expression is evaluated with OpExpression against the envelope
+	// variables, which are also available under "input".
+	Expression string
+
+	// Params are bound as top-level identifiers for Expression, shadowing
+	// envelope variables. Set for conditions resolved from a condition
+	// library.
+	Params map[string]any
+
+	parsed expr.Expr
 }
 
 // RouteRule defines a routing rule.
@@ -64,6 +81,18 @@ type RuleRouterConfig struct {
 
 	// AllowMultiple allows multiple rules to match (fan-out).
 	AllowMultiple bool
+
+	// State, when set, binds "state" in expression conditions to stored
+	// workflow state, as in state.emails_sent < 3. An envelope variable
+	// named "state" takes precedence.
+	State func(ctx context.Context) expr.Lookup
+
+	// Holidays are the calendars isHoliday looks days up in.
+	Holidays expr.Holidays
+
+	// Now returns the current time for the time functions of expression
+	// conditions. Nil uses time.Now.
+	Now func() time.Time
 }
 
 // RuleRouter routes based on envelope variable values.
@@ -78,6 +107,20 @@ func NewRuleRouter(id string, config RuleRouterConfig) *RuleRouter {
 		config.DecisionKey = id + "_decision"
 	}
 
+	// Invalid expressions never match; hydration rejects them before a
+	// router is built.
+	config.Rules = append([]RouteRule(nil), config.Rules...)
+	for i := range config.Rules {
+		conds := make([]RouteCondition, len(config.Rules[i].Conditions))
+		copy(conds, config.Rules[i].Conditions)
+		for j := range conds {
+			if conds[j].Op == OpExpression {
+				conds[j].parsed, _ = expr.Parse(conds[j].Expression)
+			}
+		}
+		config.Rules[i].Conditions = conds
+	}
+
 	return &RuleRouter{
 		BaseNode: core.NewBaseNode(id, core.NodeKindRouter),
 		config:   config,
@@ -102,8 +145,12 @@ func (r *RuleRouter) Route(ctx context.Context, env *core.Envelope) (core.RouteD
 	var targets []string
 	var reasons []string
 
+	var state expr.Lookup
+	if r.config.State != nil {
+		state = r.config.State(ctx)
+	}
 	for _, rule := range r.config.Rules {
-		if r.evaluateRule(env, rule) {
+		if r.evaluateRule(env, rule, state) {
 			targets = append(targets, rule.Target)
 			reasons = append(reasons, rule.Reason)
 
@@ -126,9 +173,9 @@ func (r *RuleRouter) Route(ctx context.Context, env *core.Envelope) (core.RouteD
 }
 
 // evaluateRule checks if all conditions in a rule are satisfied.
-func (r *RuleRouter) evaluateRule(env *core.Envelope, rule RouteRule) bool {
+func (r *RuleRouter) evaluateRule(env *core.Envelope, rule RouteRule, state expr.Lookup) bool {
 	for _, cond := range rule.Conditions {
-		if !r.evaluateCondition(env, cond) {
+		if !r.evaluateCondition(env, cond, state) {
 			return false
 		}
 	}
@@ -136,7 +183,11 @@ func (r *RuleRouter) evaluateRule(env *core.Envelope, rule RouteRule) bool {
 }
 
 // evaluateCondition checks if a single condition is satisfied.
-func (r *RuleRouter) evaluateCondition(env *core.Envelope, cond RouteCondition) bool {
+func (r *RuleRouter) evaluateCondition(env *core.Envelope, cond RouteCondition, state expr.Lookup) bool {
+	if cond.Op == OpExpression {
+		return evaluateExpressionCondition(env, cond, state, expr.Env{Now: r.config.Now, Holidays: r.config.Holidays})
+	}
+
 	// Get the value from envelope
 	val, exists := env.GetVarNested(cond.VarPath)
 	if !exists {
@@ -165,6 +216,26 @@ func (r *RuleRouter) evaluateCondition(env *core.Envelope, cond RouteCondition)
 	}
 }
 
+// evaluateExpressionCondition evaluates an OpExpression condition. Errors
+// count as no match. A non-nil state is bound as "state".
+func evaluateExpressionCondition(env *core.Envelope, cond RouteCondition, state expr.Lookup, exprEnv expr.Env) bool {
+	if cond.parsed == nil {
+		return false
+	}
+	vars := make(map[string]any, len(env.Vars)+2)
+	for k, v := range env.Vars {
+		vars[k] = v
+	}
+	if _, ok := vars["input"]; !ok {
+		vars["input"] = env.Vars
+	}
+	if _, ok := vars["state"]; !ok && state != nil {
+		vars["state"] = state
+	}
+	result, err := expr.EvalEnv(cond.parsed, conditional.WithParams(vars, cond.Params), exprEnv)
+	return err == nil && expr.IsTruthy(result)
+}
+
 // LLMRouterConfig configures an LLM-based router.
 type LLMRouterConfig struct {
 	// Model is the LLM model to use for routing decisions.
@@ -191,6 +262,22 @@ type LLMRouterConfig struct {
 
 	// RetryPolicy for the LLM call.
 	RetryPolicy core.RetryPolicy
+
+	// Handoff marks the router as an agent handoff point. Choosing one of
+	// its targets emits an agent.handoff event.
+	Handoff *RouterHandoff
+}
+
+// RouterHandoff describes the agent handoffs an LLMRouter can choose.
+type RouterHandoff struct {
+	// Task is the task being handed off.
+	Task string
+
+	// FromAgent is the agent currently working on the task.
+	FromAgent string
+
+	// Targets maps target node IDs to the agent that takes over there.
+	Targets map[string]string
 }
 
 // LLMRouter routes based on LLM classification.
@@ -234,9 +321,37 @@ func (r *LLMRouter) Run(ctx context.Context, env *core.Envelope) (*core.Envelope
 	// Store decision in envelope
 	env.SetVar(r.config.DecisionKey, decision)
 
+	r.emitHandoff(ctx, env, decision)
+
 	return env, nil
 }
 
+// emitHandoff records an agent.handoff event when the decision selects a
+// handoff target.
+func (r *LLMRouter) emitHandoff(ctx context.Context, env *core.Envelope, decision core.RouteDecision) {
+	if r.config.Handoff == nil {
+		return
+	}
+	emit := runtime.EmitterFromContext(ctx)
+	for _, target := range decision.Targets {
+		toAgent, ok := r.config.Handoff.Targets[target]
+		if !ok {
+			continue
+		}
+		event := runtime.NewEvent(runtime.EventAgentHandoff, env.Trace.RunID).
+			WithNode(r.ID(), r.Kind()).
+			WithPayload("task", r.config.Handoff.Task).
+			WithPayload("from_agent", r.config.Handoff.FromAgent).
+			WithPayload("to_agent", toAgent).
+			WithPayload("target", target).
+			WithPayload("reason", decision.Reason)
+		if decision.Confidence != nil {
+			event = event.WithPayload("confidence", *decision.Confidence)
+		}
+		emit(event)
+	}
+}
+
 // Route uses an LLM to make routing decisions.
 func (r *LLMRouter) Route(ctx context.Context, env *core.Envelope) (core.RouteDecision, error) {
 	// Apply timeout
@@ -286,7 +401,10 @@ func (r *LLMRouter) Route(ctx context.Context, env *core.Envelope) (core.RouteDe
 	var lastErr error
 
 	for attempt := 1; attempt <= r.config.RetryPolicy.MaxAttempts; attempt++ {
-		resp, lastErr = r.client.Complete(ctx, req)
+		lastErr = runtime.InjectProviderFault(ctx, env.Trace.RunID, r.ID(), r.Kind())
+		if lastErr == nil {
+			resp, lastErr = r.client.Complete(ctx, req)
+		}
 		if lastErr == nil {
 			break
 		}
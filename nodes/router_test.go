@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 func TestNewRuleRouter(t *testing.T) {
@@ -366,6 +367,52 @@ func TestLLMRouter_Route_JSONResponse(t *testing.T) {
 	}
 }
 
+func TestLLMRouter_Run_EmitsHandoffEvent(t *testing.T) {
+	client := &mockLLMClient{
+		response: core.LLMResponse{Text: `{"choice": "analyst", "reason": "needs statistics", "confidence": 0.8}`},
+	}
+	router := NewLLMRouter("research__handoff", client, LLMRouterConfig{
+		InputVars: []string{"draft"},
+		AllowedTargets: map[string]string{
+			"complete": "research__done",
+			"analyst":  "research__handoff__analyst",
+		},
+		Handoff: &RouterHandoff{
+			Task:      "research",
+			FromAgent: "researcher",
+			Targets:   map[string]string{"research__handoff__analyst": "analyst"},
+		},
+	})
+
+	var handoffs []runtime.Event
+	ctx := runtime.ContextWithEmitter(context.Background(), func(e runtime.Event) {
+		if e.Kind == runtime.EventAgentHandoff {
+			handoffs = append(handoffs, e)
+		}
+	})
+	if _, err := router.Run(ctx, core.NewEnvelope().WithVar("draft", "numbers")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(handoffs) != 1 {
+		t.Fatalf("handoff events = %d, want 1", len(handoffs))
+	}
+	p := handoffs[0].Payload
+	if p["from_agent"] != "researcher" || p["to_agent"] != "analyst" || p["reason"] != "needs statistics" || p["confidence"] != 0.8 {
+		t.Errorf("payload = %v", p)
+	}
+
+	// Completing the task is not a handoff.
+	client.response.Text = `{"choice": "complete"}`
+	handoffs = nil
+	if _, err := router.Run(ctx, core.NewEnvelope()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(handoffs) != 0 {
+		t.Errorf("unexpected handoff events: %v", handoffs)
+	}
+}
+
 func TestLLMRouter_Route_WithJSONField(t *testing.T) {
 	client := &mockLLMClient{
 		response: core.LLMResponse{
@@ -508,3 +555,72 @@ func TestLLMRouter_InterfaceCompliance(t *testing.T) {
 	var _ core.Node = (*LLMRouter)(nil)
 	var _ core.RouterNode = (*LLMRouter)(nil)
 }
+
+func TestRuleRouter_Route_ExpressionCondition(t *testing.T) {
+	router := NewRuleRouter("test", RuleRouterConfig{
+		Rules: []RouteRule{
+			{
+				Conditions: []RouteCondition{
+					{Op: OpExpression, Expression: "input.amount > limit", Params: map[string]any{"limit": float64(100)}},
+				},
+				Target: "review",
+			},
+			{
+				Conditions: []RouteCondition{{Op: OpExpression, Expression: "amount &&"}},
+				Target:     "never",
+			},
+		},
+		DefaultTarget: "approve",
+	})
+
+	decision, err := router.Route(context.Background(), core.NewEnvelope().WithVar("amount", float64(250)).WithVar("limit", float64(1000)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(decision.Targets) != 1 || decision.Targets[0] != "review" {
+		t.Fatalf("targets = %v, want [review]", decision.Targets)
+	}
+
+	decision, err = router.Route(context.Background(), core.NewEnvelope().WithVar("amount", float64(50)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(decision.Targets) != 1 || decision.Targets[0] != "approve" {
+		t.Fatalf("targets = %v, want [approve]", decision.Targets)
+	}
+}
+
+func TestRuleRouter_Route_TimeConditions(t *testing.T) {
+	now := time.Date(2025, 11, 27, 15, 0, 0, 0, time.UTC) // Thanksgiving, 10:00 in New York
+	router := NewRuleRouter("test", RuleRouterConfig{
+		Rules: []RouteRule{
+			{
+				Conditions: []RouteCondition{{Op: OpExpression, Expression: `isHoliday("America/New_York", "us")`}},
+				Target:     "queue_for_tomorrow",
+			},
+			{
+				Conditions: []RouteCondition{{Op: OpExpression, Expression: `hour("America/New_York") >= 9`}},
+				Target:     "agent",
+			},
+		},
+		Holidays: map[string][]string{"us": {"2025-11-27"}},
+		Now:      func() time.Time { return now },
+	})
+
+	decision, err := router.Route(context.Background(), core.NewEnvelope())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(decision.Targets) != 1 || decision.Targets[0] != "queue_for_tomorrow" {
+		t.Fatalf("targets = %v, want [queue_for_tomorrow]", decision.Targets)
+	}
+
+	now = now.AddDate(0, 0, 1)
+	decision, err = router.Route(context.Background(), core.NewEnvelope())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(decision.Targets) != 1 || decision.Targets[0] != "agent" {
+		t.Fatalf("targets = %v, want [agent]", decision.Targets)
+	}
+}
@@ -0,0 +1,375 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+)
+
+// ErrStateNotCounter is returned when state_incr finds a value that is not
+// an integer under its key.
+var ErrStateNotCounter = errors.New("state value is not an integer counter")
+
+// StateStore persists named values across runs. Keys live in namespaces,
+// usually one per workflow. Values are stored as JSON, so they read back as
+// they decode from JSON.
+type StateStore interface {
+	// GetState returns the value under key. Expired values are not found.
+	GetState(ctx context.Context, namespace, key string) (any, bool, error)
+	// SetState stores value under key, replacing the previous value and its
+	// expiry. A ttl of 0 keeps the value until it is replaced.
+	SetState(ctx context.Context, namespace, key string, value any, ttl time.Duration) error
+	// IncrState atomically adds delta to the integer counter under key and
+	// returns the new count. A missing or expired key starts from 0 and
+	// expires after ttl (0 for never); an existing counter keeps its
+	// expiry, so a TTL set on the first increment bounds a counting window.
+	// It returns ErrStateNotCounter when the key holds another value.
+	IncrState(ctx context.Context, namespace, key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// StateNodeConfig is the part of the state node configs that addresses a
+// value.
+type StateNodeConfig struct {
+	// Store keeps the values.
+	Store StateStore
+
+	// Namespace scopes Key. The daemon defaults it to the workflow ID.
+	Namespace string
+
+	// Key names the value. It may be a template over the envelope vars,
+	// such as "emails:{{.customer_id}}".
+	Key string
+
+	// TemplateSandbox, when set, restricts the Key template.
+	TemplateSandbox *TemplateSandbox
+}
+
+// key renders the configured key for env.
+func (c StateNodeConfig) key(env *core.Envelope) (string, error) {
+	if !strings.Contains(c.Key, "{{") {
+		return c.Key, nil
+	}
+	tmpl, err := parseNodeTemplate(c.TemplateSandbox, "state_key", c.Key, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid key template: %w", err)
+	}
+	data := make(map[string]any, len(env.Vars)+1)
+	for k, v := range env.Vars {
+		data[k] = v
+	}
+	if env.Input != nil {
+		data["input"] = env.Input
+	}
+	key, err := tmpl.render(data)
+	if err != nil {
+		return "", fmt.Errorf("key template failed: %w", err)
+	}
+	if key == "" {
+		return "", errors.New("key template rendered an empty key")
+	}
+	return key, nil
+}
+
+func (c StateNodeConfig) validate(kind, id string) error {
+	if c.Store == nil {
+		return fmt.Errorf("%s node %q: a state store is required", kind, id)
+	}
+	if c.Key == "" {
+		return fmt.Errorf("%s node %q: key is required", kind, id)
+	}
+	return nil
+}
+
+// StateGetNodeConfig configures a StateGetNode.
+type StateGetNodeConfig struct {
+	StateNodeConfig
+
+	// Default is stored when the key has no value.
+	Default any
+
+	// OutputKey stores the value. Defaults to "<node id>_output".
+	OutputKey string
+}
+
+// StateGetNode reads a stored value into the envelope.
+type StateGetNode struct {
+	core.BaseNode
+	config StateGetNodeConfig
+}
+
+// NewStateGetNode creates a state_get node.
+func NewStateGetNode(id string, config StateGetNodeConfig) (*StateGetNode, error) {
+	if err := config.validate("state_get", id); err != nil {
+		return nil, err
+	}
+	if config.OutputKey == "" {
+		config.OutputKey = id + "_output"
+	}
+	return &StateGetNode{BaseNode: core.NewBaseNode(id, core.NodeKindTransform), config: config}, nil
+}
+
+// Run stores the value, or Default when there is none, in OutputKey.
+func (n *StateGetNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	key, err := n.config.key(env)
+	if err != nil {
+		return nil, fmt.Errorf("state_get: %w", err)
+	}
+	value, ok, err := n.config.Store.GetState(ctx, n.config.Namespace, key)
+	if err != nil {
+		return nil, fmt.Errorf("state_get %q: %w", key, err)
+	}
+	if !ok {
+		value = n.config.Default
+	}
+	out := env.Clone()
+	out.SetVar(n.config.OutputKey, value)
+	return out, nil
+}
+
+// Config returns the node's configuration.
+func (n *StateGetNode) Config() StateGetNodeConfig {
+	return n.config
+}
+
+// StateSetNodeConfig configures a StateSetNode.
+type StateSetNodeConfig struct {
+	StateNodeConfig
+
+	// ValueVar is the envelope variable, or dotted path, to store. When
+	// empty, Value is stored.
+	ValueVar string
+
+	// Value is stored when ValueVar is empty.
+	Value any
+
+	// TTL expires the value. 0 keeps it until it is replaced.
+	TTL time.Duration
+}
+
+// StateSetNode stores a value.
+type StateSetNode struct {
+	core.BaseNode
+	config StateSetNodeConfig
+}
+
+// NewStateSetNode creates a state_set node.
+func NewStateSetNode(id string, config StateSetNodeConfig) (*StateSetNode, error) {
+	if err := config.validate("state_set", id); err != nil {
+		return nil, err
+	}
+	if config.TTL < 0 {
+		return nil, fmt.Errorf("state_set node %q: ttl must not be negative", id)
+	}
+	return &StateSetNode{BaseNode: core.NewBaseNode(id, core.NodeKindTransform), config: config}, nil
+}
+
+// Run stores the value. The envelope passes through unchanged.
+func (n *StateSetNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	key, err := n.config.key(env)
+	if err != nil {
+		return nil, fmt.Errorf("state_set: %w", err)
+	}
+	value := n.config.Value
+	if n.config.ValueVar != "" {
+		v, ok := env.GetVarNested(n.config.ValueVar)
+		if !ok {
+			return nil, fmt.Errorf("state_set: variable %q not found", n.config.ValueVar)
+		}
+		value = v
+	}
+	if err := n.config.Store.SetState(ctx, n.config.Namespace, key, value, n.config.TTL); err != nil {
+		return nil, fmt.Errorf("state_set %q: %w", key, err)
+	}
+	return env, nil
+}
+
+// Config returns the node's configuration.
+func (n *StateSetNode) Config() StateSetNodeConfig {
+	return n.config
+}
+
+// StateIncrNodeConfig configures a StateIncrNode.
+type StateIncrNodeConfig struct {
+	StateNodeConfig
+
+	// By is added to the counter. Defaults to 1; negative values count
+	// down.
+	By int64
+
+	// TTL expires a counter this long after the increment that created
+	// it. 0 keeps it until it is replaced.
+	TTL time.Duration
+
+	// OutputKey stores the new count. Defaults to "<node id>_output".
+	OutputKey string
+}
+
+// StateIncrNode atomically increments a stored counter.
+type StateIncrNode struct {
+	core.BaseNode
+	config StateIncrNodeConfig
+}
+
+// NewStateIncrNode creates a state_incr node.
+func NewStateIncrNode(id string, config StateIncrNodeConfig) (*StateIncrNode, error) {
+	if err := config.validate("state_incr", id); err != nil {
+		return nil, err
+	}
+	if config.TTL < 0 {
+		return nil, fmt.Errorf("state_incr node %q: ttl must not be negative", id)
+	}
+	if config.By == 0 {
+		config.By = 1
+	}
+	if config.OutputKey == "" {
+		config.OutputKey = id + "_output"
+	}
+	return &StateIncrNode{BaseNode: core.NewBaseNode(id, core.NodeKindTransform), config: config}, nil
+}
+
+// Run increments the counter and stores the new count in OutputKey.
+func (n *StateIncrNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	key, err := n.config.key(env)
+	if err != nil {
+		return nil, fmt.Errorf("state_incr: %w", err)
+	}
+	count, err := n.config.Store.IncrState(ctx, n.config.Namespace, key, n.config.By, n.config.TTL)
+	if err != nil {
+		return nil, fmt.Errorf("state_incr %q: %w", key, err)
+	}
+	out := env.Clone()
+	out.SetVar(n.config.OutputKey, count)
+	return out, nil
+}
+
+// Config returns the node's configuration.
+func (n *StateIncrNode) Config() StateIncrNodeConfig {
+	return n.config
+}
+
+// NewStateView returns the "state" value of condition expressions: member
+// access such as state.emails_sent reads that key from namespace. Keys
+// that are not identifiers use index access, as in state["emails:42"].
+func NewStateView(ctx context.Context, store StateStore, namespace string) expr.Lookup {
+	return stateView{ctx: ctx, store: store, namespace: namespace}
+}
+
+type stateView struct {
+	ctx       context.Context
+	store     StateStore
+	namespace string
+}
+
+func (v stateView) Lookup(key string) (any, error) {
+	value, ok, err := v.store.GetState(v.ctx, v.namespace, key)
+	if err != nil {
+		return nil, fmt.Errorf("reading state %q: %w", key, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// MemoryStateStore is an in-memory StateStore.
+type MemoryStateStore struct {
+	mu      sync.Mutex
+	entries map[string]map[string]memoryStateEntry
+	now     func() time.Time
+}
+
+type memoryStateEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+func (e memoryStateEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
+
+// NewMemoryStateStore creates an empty in-memory store.
+func NewMemoryStateStore() *MemoryStateStore {
+	return &MemoryStateStore{entries: make(map[string]map[string]memoryStateEntry), now: time.Now}
+}
+
+// GetState implements StateStore.
+func (m *MemoryStateStore) GetState(_ context.Context, namespace, key string) (any, bool, error) {
+	m.mu.Lock()
+	entry, ok := m.entries[namespace][key]
+	now := m.now()
+	m.mu.Unlock()
+	if !ok || entry.expired(now) {
+		return nil, false, nil
+	}
+	var value any
+	if err := json.Unmarshal(entry.value, &value); err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+// SetState implements StateStore.
+func (m *MemoryStateStore) SetState(_ context.Context, namespace, key string, value any, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("encoding state value: %w", err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.put(namespace, key, memoryStateEntry{value: data, expiresAt: expiry(m.now(), ttl)})
+	return nil
+}
+
+// IncrState implements StateStore.
+func (m *MemoryStateStore) IncrState(_ context.Context, namespace, key string, delta int64, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	entry, ok := m.entries[namespace][key]
+	if !ok || entry.expired(now) {
+		entry = memoryStateEntry{value: []byte("0"), expiresAt: expiry(now, ttl)}
+	}
+	var current float64
+	if err := json.Unmarshal(entry.value, &current); err != nil || current != math.Trunc(current) {
+		return 0, ErrStateNotCounter
+	}
+	count := int64(current) + delta
+	entry.value = []byte(fmt.Sprint(count))
+	m.put(namespace, key, entry)
+	return count, nil
+}
+
+// put stores an entry. The caller holds m.mu.
+func (m *MemoryStateStore) put(namespace, key string, entry memoryStateEntry) {
+	ns := m.entries[namespace]
+	if ns == nil {
+		ns = make(map[string]memoryStateEntry)
+		m.entries[namespace] = ns
+	}
+	ns[key] = entry
+}
+
+// expiry returns when a value stored at now with ttl expires, or the zero
+// time for a ttl of 0.
+func expiry(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// Ensure interface compliance at compile time.
+var (
+	_ core.Node  = (*StateGetNode)(nil)
+	_ core.Node  = (*StateSetNode)(nil)
+	_ core.Node  = (*StateIncrNode)(nil)
+	_ StateStore = (*MemoryStateStore)(nil)
+)
@@ -0,0 +1,161 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/nodes/conditional"
+	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+)
+
+func TestMemoryStateStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStateStore()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { return now }
+
+	if err := store.SetState(ctx, "wf", "profile", map[string]any{"tier": "gold"}, 0); err != nil {
+		t.Fatalf("SetState: %v", err)
+	}
+	value, ok, err := store.GetState(ctx, "wf", "profile")
+	if err != nil || !ok || value.(map[string]any)["tier"] != "gold" {
+		t.Fatalf("GetState = %v, %v, %v", value, ok, err)
+	}
+	if _, ok, _ := store.GetState(ctx, "other", "profile"); ok {
+		t.Fatal("namespaces should not share keys")
+	}
+
+	if err := store.SetState(ctx, "wf", "token", "abc", time.Minute); err != nil {
+		t.Fatalf("SetState: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, ok, _ := store.GetState(ctx, "wf", "token"); ok {
+		t.Fatal("expired value should not be found")
+	}
+
+	// The first increment sets the window; later ones keep it.
+	for i, want := range []int64{1, 3} {
+		got, err := store.IncrState(ctx, "wf", "sent", int64(i+1), time.Hour)
+		if err != nil || got != want {
+			t.Fatalf("IncrState #%d = %d, %v, want %d", i, got, err, want)
+		}
+		now = now.Add(40 * time.Minute)
+	}
+	if got, err := store.IncrState(ctx, "wf", "sent", 1, time.Hour); err != nil || got != 1 {
+		t.Fatalf("IncrState after window = %d, %v, want 1", got, err)
+	}
+
+	if _, err := store.IncrState(ctx, "wf", "profile", 1, 0); !errors.Is(err, ErrStateNotCounter) {
+		t.Fatalf("IncrState on an object: err = %v", err)
+	}
+}
+
+func TestMemoryStateStore_ConcurrentIncr(t *testing.T) {
+	store := NewMemoryStateStore()
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.IncrState(context.Background(), "wf", "n", 1, 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if value, _, _ := store.GetState(context.Background(), "wf", "n"); value != float64(50) {
+		t.Fatalf("count = %v, want 50", value)
+	}
+}
+
+func TestStateNodes(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStateStore()
+	base := StateNodeConfig{Store: store, Namespace: "wf", Key: "emails:{{.customer_id}}"}
+	env := core.NewEnvelope().WithVar("customer_id", "42").WithVar("address", "a@example.com")
+
+	get, err := NewStateGetNode("get", StateGetNodeConfig{StateNodeConfig: base, Default: float64(0)})
+	if err != nil {
+		t.Fatalf("NewStateGetNode: %v", err)
+	}
+	out, err := get.Run(ctx, env)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if v, _ := out.GetVar("get_output"); v != float64(0) {
+		t.Fatalf("default = %v", v)
+	}
+
+	incr, err := NewStateIncrNode("incr", StateIncrNodeConfig{StateNodeConfig: base, OutputKey: "sent"})
+	if err != nil {
+		t.Fatalf("NewStateIncrNode: %v", err)
+	}
+	for range 2 {
+		if out, err = incr.Run(ctx, env); err != nil {
+			t.Fatalf("incr: %v", err)
+		}
+	}
+	if v, _ := out.GetVar("sent"); v != int64(2) {
+		t.Fatalf("sent = %v, want 2", v)
+	}
+
+	set, err := NewStateSetNode("set", StateSetNodeConfig{
+		StateNodeConfig: StateNodeConfig{Store: store, Namespace: "wf", Key: "last_address"},
+		ValueVar:        "address",
+	})
+	if err != nil {
+		t.Fatalf("NewStateSetNode: %v", err)
+	}
+	if _, err := set.Run(ctx, env); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if v, _, _ := store.GetState(ctx, "wf", "last_address"); v != "a@example.com" {
+		t.Fatalf("stored = %v", v)
+	}
+
+	if _, err := NewStateGetNode("bad", StateGetNodeConfig{StateNodeConfig: StateNodeConfig{Store: store}}); err == nil {
+		t.Fatal("expected a missing key error")
+	}
+	if _, err := NewStateSetNode("bad", StateSetNodeConfig{StateNodeConfig: base, TTL: -time.Second}); err == nil {
+		t.Fatal("expected a negative ttl error")
+	}
+}
+
+func TestStateView_InExpressions(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStateStore()
+	if _, err := store.IncrState(ctx, "wf", "emails_sent", 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	state := func(ctx context.Context) expr.Lookup { return NewStateView(ctx, store, "wf") }
+
+	router := NewRuleRouter("r", RuleRouterConfig{
+		Rules: []RouteRule{{
+			Conditions: []RouteCondition{{Op: OpExpression, Expression: "state.emails_sent < 3"}},
+			Target:     "send",
+		}},
+		DefaultTarget: "skip",
+		State:         state,
+	})
+	decision, err := router.Route(ctx, core.NewEnvelope())
+	if err != nil || len(decision.Targets) != 1 || decision.Targets[0] != "skip" {
+		t.Fatalf("decision = %+v, %v", decision, err)
+	}
+
+	cond, err := conditional.NewConditionalNode("c", conditional.Config{
+		Conditions: []conditional.Condition{{Name: "limited", Expression: `state["emails_sent"] >= 3`}},
+		Default:    "open",
+		State:      state,
+	})
+	if err != nil {
+		t.Fatalf("NewConditionalNode: %v", err)
+	}
+	decision, err = cond.Route(ctx, core.NewEnvelope())
+	if err != nil || len(decision.Targets) != 1 || decision.Targets[0] != "limited" {
+		t.Fatalf("conditional decision = %+v, %v", decision, err)
+	}
+}
@@ -0,0 +1,71 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// SubgraphNodeConfig configures a SubgraphNode.
+type SubgraphNodeConfig struct {
+	// Graph is the nested graph. It runs on a copy of the node's input
+	// envelope, and the envelope it finishes with is the node's output.
+	Graph graph.Graph
+
+	// MaxHops bounds the nested run like RunOptions.MaxHops. Defaults to
+	// the runtime's default.
+	MaxHops int
+}
+
+// SubgraphNode runs a nested graph as one step of its parent. The nested
+// nodes' events are emitted into the parent run, so they appear in its
+// event stream under their own node IDs.
+type SubgraphNode struct {
+	core.BaseNode
+	config SubgraphNodeConfig
+}
+
+// NewSubgraphNode creates a new SubgraphNode.
+func NewSubgraphNode(id string, config SubgraphNodeConfig) *SubgraphNode {
+	return &SubgraphNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindSubgraph),
+		config:   config,
+	}
+}
+
+// Config returns the node's configuration.
+func (n *SubgraphNode) Config() SubgraphNodeConfig {
+	return n.config
+}
+
+// Run executes the nested graph.
+func (n *SubgraphNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	if n.config.Graph == nil {
+		return nil, fmt.Errorf("subgraph node %s: no graph configured", n.ID())
+	}
+	emit := runtime.EmitterFromContext(ctx)
+	opts := runtime.DefaultRunOptions()
+	opts.RunID = env.Trace.RunID
+	if n.config.MaxHops > 0 {
+		opts.MaxHops = n.config.MaxHops
+	}
+	// The parent run reports its own start and finish.
+	opts.EventHandler = func(e runtime.Event) {
+		if e.Kind != runtime.EventRunStarted && e.Kind != runtime.EventRunFinished {
+			emit(e)
+		}
+	}
+
+	result, err := runtime.NewRuntime().Run(ctx, n.config.Graph, env.Clone(), opts)
+	if err != nil {
+		return nil, fmt.Errorf("subgraph node %s: %w", n.ID(), err)
+	}
+	result.Trace = env.Trace
+	return result, nil
+}
+
+// Ensure interface compliance at compile time.
+var _ core.Node = (*SubgraphNode)(nil)
@@ -0,0 +1,70 @@
+package nodes
+
+import (
+	"context"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func setVarNode(id, name string, value any) core.Node {
+	return core.NewFuncNode(id, func(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+		env.SetVar(name, value)
+		return env, nil
+	})
+}
+
+func TestSubgraphNode_RunsNestedGraph(t *testing.T) {
+	inner := graph.NewGraph("inner")
+	_ = inner.AddNode(setVarNode("first", "a", 1))
+	_ = inner.AddNode(setVarNode("second", "b", 2))
+	_ = inner.AddEdge("first", "second")
+	_ = inner.SetEntry("first")
+
+	outer := graph.NewGraph("outer")
+	_ = outer.AddNode(NewSubgraphNode("sub", SubgraphNodeConfig{Graph: inner}))
+	_ = outer.AddNode(setVarNode("after", "c", 3))
+	_ = outer.AddEdge("sub", "after")
+	_ = outer.SetEntry("sub")
+
+	var events []runtime.Event
+	opts := runtime.DefaultRunOptions()
+	opts.EventHandler = func(e runtime.Event) { events = append(events, e) }
+	env, err := runtime.NewRuntime().Run(context.Background(), outer, nil, opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	for _, name := range []string{"a", "b", "c"} {
+		if _, ok := env.GetVar(name); !ok {
+			t.Errorf("var %q missing from result", name)
+		}
+	}
+
+	started := map[string]bool{}
+	runsStarted := 0
+	for _, e := range events {
+		switch e.Kind {
+		case runtime.EventNodeStarted:
+			started[e.NodeID] = true
+		case runtime.EventRunStarted:
+			runsStarted++
+		}
+	}
+	for _, id := range []string{"sub", "first", "second", "after"} {
+		if !started[id] {
+			t.Errorf("no node.started event for %q", id)
+		}
+	}
+	if runsStarted != 1 {
+		t.Errorf("run.started emitted %d times, want 1", runsStarted)
+	}
+}
+
+func TestSubgraphNode_NoGraph(t *testing.T) {
+	node := NewSubgraphNode("sub", SubgraphNodeConfig{})
+	if _, err := node.Run(context.Background(), core.NewEnvelope()); err == nil {
+		t.Fatal("expected an error without a graph")
+	}
+}
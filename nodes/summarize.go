@@ -0,0 +1,517 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// Summary presets.
+const (
+	SummaryBullet    = "bullet"
+	SummaryAbstract  = "abstract"
+	SummaryExecutive = "executive"
+	SummaryTweet     = "tweet"
+)
+
+// Summarize modes.
+const (
+	// SummarizeAuto summarizes in one call when the input fits
+	// MaxInputTokens and hierarchically otherwise. It is the default.
+	SummarizeAuto = "auto"
+	// SummarizeSingle always summarizes in one call.
+	SummarizeSingle = "single"
+	// SummarizeHierarchical always summarizes chunks first and then
+	// their summaries.
+	SummarizeHierarchical = "hierarchical"
+)
+
+// Citation modes.
+const (
+	// CitationsNone leaves citations out of the summary. It is the default.
+	CitationsNone = "none"
+	// CitationsKeep keeps citation markers found in the input, such as [3]
+	// or (Smith, 2021), next to the claims they support.
+	CitationsKeep = "keep"
+	// CitationsSources numbers the input texts and cites them as [n].
+	CitationsSources = "sources"
+)
+
+// DefaultSummarizeMaxInputTokens is the input size above which the auto
+// mode summarizes hierarchically.
+const DefaultSummarizeMaxInputTokens = 12000
+
+// TweetMaxChars is the length limit of the tweet preset.
+const TweetMaxChars = 280
+
+// maxSummaryLevels bounds the reduce rounds of a hierarchical summary.
+const maxSummaryLevels = 5
+
+// SummaryPreset is the style and default length of a summary preset.
+type SummaryPreset struct {
+	Instructions string
+	Words        int
+	// MaxChars is a hard character limit, if any.
+	MaxChars int
+}
+
+// SummaryPresets holds the built-in presets.
+var SummaryPresets = map[string]SummaryPreset{
+	SummaryBullet: {
+		Instructions: "Write 3 to 7 bullet points, one per line starting with \"- \", each stating one key point in a short sentence.",
+		Words:        120,
+	},
+	SummaryAbstract: {
+		Instructions: "Write a single paragraph of neutral, third-person prose, like the abstract of a paper: the subject, the main points and the conclusion.",
+		Words:        150,
+	},
+	SummaryExecutive: {
+		Instructions: "Write for a busy executive. Start with a one-sentence bottom line, then short sections headed \"Key points:\", \"Risks:\" and \"Recommended actions:\". Leave out a section that would be empty.",
+		Words:        250,
+	},
+	SummaryTweet: {
+		Instructions: "Write one or two plain sentences that could be posted as a tweet, without hashtags or emoji.",
+		Words:        40,
+		MaxChars:     TweetMaxChars,
+	},
+}
+
+// SummarizeNodeConfig configures a SummarizeNode.
+type SummarizeNodeConfig struct {
+	// InputVar holds the text to summarize: a string, a []string, or a
+	// []any of strings or objects. Several texts are summarized together.
+	// Required.
+	InputVar string
+
+	// Field is the dot path of the text inside each object when InputVar
+	// is a collection of objects.
+	Field string
+
+	// OutputKey stores the summary string. Defaults to "{id}_output".
+	OutputKey string
+
+	// ResultKey stores a SummaryResult. Defaults to "{OutputKey}_result".
+	ResultKey string
+
+	// Preset is bullet, abstract (default), executive or tweet.
+	Preset string
+
+	// TargetWords is the length to aim for. Defaults to the preset's.
+	TargetWords int
+
+	// TargetTokens sets TargetWords at three words per four tokens when
+	// TargetWords is unset.
+	TargetTokens int
+
+	// Mode is auto (default), single or hierarchical.
+	Mode string
+
+	// MaxInputTokens is the most input one call gets. Defaults to
+	// DefaultSummarizeMaxInputTokens.
+	MaxInputTokens int
+
+	// ChunkTokens is the size of the chunks a hierarchical summary
+	// starts from. Defaults to MaxInputTokens.
+	ChunkTokens int
+
+	// Citations is none (default), keep or sources.
+	Citations string
+
+	// Instructions adds guidance, such as the audience or what to focus
+	// on, to every prompt.
+	Instructions string
+
+	// Model is the model used for the summary calls.
+	Model string
+
+	// RetryPolicy configures retries for each summary call.
+	RetryPolicy core.RetryPolicy
+
+	// Timeout bounds each summary call. Defaults to 60s.
+	Timeout time.Duration
+}
+
+// SummaryResult reports how a SummarizeNode run went.
+type SummaryResult struct {
+	Preset string `json:"preset"`
+	// Sources is the number of input texts.
+	Sources int `json:"sources"`
+	// InputTokens estimates the input size at four characters per token.
+	InputTokens  int  `json:"input_tokens"`
+	Hierarchical bool `json:"hierarchical"`
+	// Chunks counts the chunk summaries written before the final one.
+	Chunks int `json:"chunks,omitempty"`
+	// Levels counts the rounds of chunk summaries.
+	Levels      int `json:"levels,omitempty"`
+	Words       int `json:"words"`
+	Characters  int `json:"characters"`
+	TargetWords int `json:"target_words"`
+	// OverLength is true when the summary breaks the preset's character
+	// limit or runs over half again the target words.
+	OverLength bool `json:"over_length,omitempty"`
+	// Bullets holds the points of a bullet summary.
+	Bullets []string `json:"bullets,omitempty"`
+	// Cited lists the sources cited, by number, with citations "sources".
+	Cited []int           `json:"cited,omitempty"`
+	Usage core.TokenUsage `json:"usage"`
+}
+
+// SummarizeNode summarizes text with a standard prompt per preset. Input
+// larger than the model's budget is summarized hierarchically: chunks are
+// summarized first, then the summaries of the chunks.
+type SummarizeNode struct {
+	core.BaseNode
+	config SummarizeNodeConfig
+	client core.LLMClient
+}
+
+// NewSummarizeNode creates a new SummarizeNode.
+func NewSummarizeNode(id string, client core.LLMClient, config SummarizeNodeConfig) *SummarizeNode {
+	if config.OutputKey == "" {
+		config.OutputKey = id + "_output"
+	}
+	if config.ResultKey == "" {
+		config.ResultKey = config.OutputKey + "_result"
+	}
+	if config.Preset == "" {
+		config.Preset = SummaryAbstract
+	}
+	if config.TargetWords <= 0 && config.TargetTokens > 0 {
+		config.TargetWords = config.TargetTokens * 3 / 4
+	}
+	if config.TargetWords <= 0 {
+		config.TargetWords = SummaryPresets[config.Preset].Words
+	}
+	if config.Mode == "" {
+		config.Mode = SummarizeAuto
+	}
+	if config.MaxInputTokens <= 0 {
+		config.MaxInputTokens = DefaultSummarizeMaxInputTokens
+	}
+	if config.ChunkTokens <= 0 || config.ChunkTokens > config.MaxInputTokens {
+		config.ChunkTokens = config.MaxInputTokens
+	}
+	if config.Citations == "" {
+		config.Citations = CitationsNone
+	}
+	if config.RetryPolicy.MaxAttempts == 0 {
+		config.RetryPolicy = core.DefaultRetryPolicy()
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 60 * time.Second
+	}
+
+	return &SummarizeNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindLLM),
+		config:   config,
+		client:   client,
+	}
+}
+
+// Config returns the node's configuration.
+func (n *SummarizeNode) Config() SummarizeNodeConfig {
+	return n.config
+}
+
+// Run summarizes the input variable.
+func (n *SummarizeNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	preset, ok := SummaryPresets[n.config.Preset]
+	if !ok {
+		return nil, fmt.Errorf("summarize node %s: unknown preset %q", n.ID(), n.config.Preset)
+	}
+	if n.client == nil {
+		return nil, fmt.Errorf("summarize node %s: no LLM client configured", n.ID())
+	}
+	input, ok := env.GetVarNested(n.config.InputVar)
+	if !ok {
+		return nil, fmt.Errorf("summarize node %s: input var %q not found", n.ID(), n.config.InputVar)
+	}
+	texts, _, err := collectTexts(n.config.InputVar, n.config.Field, input)
+	if err != nil {
+		return nil, fmt.Errorf("summarize node %s: %w", n.ID(), err)
+	}
+	var sources []string
+	for _, text := range texts {
+		if strings.TrimSpace(text) != "" {
+			sources = append(sources, text)
+		}
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("summarize node %s: input var %q has no text", n.ID(), n.config.InputVar)
+	}
+
+	result := SummaryResult{
+		Preset:      n.config.Preset,
+		Sources:     len(sources),
+		TargetWords: n.config.TargetWords,
+	}
+	labeled := labelSources(sources, n.config.Citations == CitationsSources)
+	text := strings.Join(labeled, "\n\n")
+	result.InputTokens = estimateTextTokens(text)
+
+	if n.config.Mode == SummarizeHierarchical ||
+		(n.config.Mode == SummarizeAuto && result.InputTokens > n.config.MaxInputTokens) {
+		result.Hierarchical = true
+		text, err = n.reduce(ctx, env, labeled, &result)
+		if err != nil {
+			return nil, fmt.Errorf("summarize node %s: %w", n.ID(), err)
+		}
+	}
+
+	summary, usage, err := n.complete(ctx, env, n.prompt(preset.Instructions, n.config.TargetWords, preset.MaxChars), text)
+	if err != nil {
+		return nil, fmt.Errorf("summarize node %s: %w", n.ID(), err)
+	}
+	result.Usage = result.Usage.Add(usage)
+	summary = strings.TrimSpace(summary)
+
+	result.Words = len(strings.Fields(summary))
+	result.Characters = utf8.RuneCountInString(summary)
+	result.OverLength = (preset.MaxChars > 0 && result.Characters > preset.MaxChars) ||
+		result.Words > n.config.TargetWords*3/2
+	if n.config.Preset == SummaryBullet {
+		result.Bullets = parseBullets(summary)
+	}
+	if n.config.Citations == CitationsSources {
+		result.Cited = citedSources(summary, len(sources))
+	}
+
+	env.SetVar(n.config.OutputKey, summary)
+	env.SetVar(n.config.ResultKey, result)
+	return env, nil
+}
+
+// reduce summarizes chunks of the labeled sources, and then chunks of
+// those summaries, until they fit MaxInputTokens, and returns them joined.
+func (n *SummarizeNode) reduce(ctx context.Context, env *core.Envelope, texts []string, result *SummaryResult) (string, error) {
+	pieces := chunkTexts(texts, n.config.ChunkTokens)
+	previous := 0
+	for _, text := range texts {
+		previous += estimateTextTokens(text)
+	}
+	for level := 1; ; level++ {
+		result.Levels = level
+		// Leave room for the separators and the final prompt.
+		words := max(n.config.MaxInputTokens*3/4*4/5/len(pieces), 50)
+		prompt := n.prompt("This text is one part of a longer input. Summarize it as plain prose for a later summary of the whole input, "+
+			"keeping facts, figures, names and dates.", words, 0)
+
+		partials := make([]string, len(pieces))
+		for i, piece := range pieces {
+			partial, usage, err := n.complete(ctx, env, prompt, piece)
+			if err != nil {
+				return "", fmt.Errorf("chunk %d of level %d: %w", i+1, level, err)
+			}
+			result.Usage = result.Usage.Add(usage)
+			partials[i] = strings.TrimSpace(partial)
+		}
+		result.Chunks += len(pieces)
+
+		combined := strings.Join(partials, "\n\n")
+		tokens := estimateTextTokens(combined)
+		if tokens <= n.config.MaxInputTokens {
+			return combined, nil
+		}
+		if level == maxSummaryLevels || tokens >= previous {
+			return "", fmt.Errorf("chunk summaries still hold ~%d tokens after %d levels; raise max_input_tokens or lower chunk_tokens", tokens, level)
+		}
+		previous = tokens
+		pieces = chunkTexts(partials, n.config.ChunkTokens)
+	}
+}
+
+// prompt builds the system prompt of a summary call.
+func (n *SummarizeNode) prompt(style string, words, maxChars int) string {
+	var b strings.Builder
+	b.WriteString("You summarize text accurately. Use only information from the input; do not add facts or opinions.\n\n")
+	b.WriteString(style)
+	fmt.Fprintf(&b, "\nAim for about %d words.", words)
+	if maxChars > 0 {
+		fmt.Fprintf(&b, " Never exceed %d characters.", maxChars)
+	}
+	switch n.config.Citations {
+	case CitationsKeep:
+		b.WriteString("\nKeep the citation markers that appear in the input, such as [3] or (Smith, 2021), next to the claims they support.")
+	case CitationsSources:
+		b.WriteString("\nThe input is made of sources that start with a number in brackets, like [2]. After each claim, cite the sources it comes from as [n].")
+	default:
+		b.WriteString("\nDo not include citation markers, source numbers or references.")
+	}
+	if n.config.Instructions != "" {
+		b.WriteString("\n\n" + n.config.Instructions)
+	}
+	b.WriteString("\n\nRespond with the summary only.")
+	return b.String()
+}
+
+// complete sends one summary call with retries.
+func (n *SummarizeNode) complete(ctx context.Context, env *core.Envelope, system, input string) (string, core.TokenUsage, error) {
+	if n.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.Timeout)
+		defer cancel()
+	}
+	req := core.LLMRequest{
+		Model:     n.config.Model,
+		System:    system,
+		InputText: input,
+	}
+
+	var resp core.LLMResponse
+	var err error
+	for attempt := 1; attempt <= n.config.RetryPolicy.MaxAttempts; attempt++ {
+		err = runtime.InjectProviderFault(ctx, env.Trace.RunID, n.ID(), n.Kind())
+		if err == nil {
+			resp, err = n.client.Complete(ctx, req)
+		}
+		if err == nil {
+			return resp.Text, core.TokenUsage{
+				InputTokens:  resp.Usage.InputTokens,
+				OutputTokens: resp.Usage.OutputTokens,
+				TotalTokens:  resp.Usage.TotalTokens,
+				CostUSD:      resp.Usage.CostUSD,
+			}, nil
+		}
+		if ctx.Err() != nil {
+			return "", core.TokenUsage{}, ctx.Err()
+		}
+		if attempt < n.config.RetryPolicy.MaxAttempts {
+			select {
+			case <-ctx.Done():
+				return "", core.TokenUsage{}, ctx.Err()
+			case <-time.After(n.config.RetryPolicy.Backoff * time.Duration(attempt)):
+			}
+		}
+	}
+	return "", core.TokenUsage{}, fmt.Errorf("summary call failed after %d attempts: %w", n.config.RetryPolicy.MaxAttempts, err)
+}
+
+// estimateTextTokens approximates a text's token count at four characters
+// per token, like EstimateMessageTokens.
+func estimateTextTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+// labelSources numbers each text "[n] " when number is set.
+func labelSources(texts []string, number bool) []string {
+	if !number {
+		return texts
+	}
+	out := make([]string, len(texts))
+	for i, text := range texts {
+		out[i] = fmt.Sprintf("[%d] %s", i+1, text)
+	}
+	return out
+}
+
+// chunkTexts packs texts into chunks of at most maxTokens, splitting at
+// paragraph breaks, then at spaces, where a text is too long. A text that
+// is split keeps its leading source label on every piece.
+func chunkTexts(texts []string, maxTokens int) []string {
+	limit := max(maxTokens*4, 1)
+	var chunks []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+	}
+	add := func(piece string) {
+		if current.Len() > 0 && current.Len()+2+len(piece) > limit {
+			flush()
+		}
+		if current.Len() > 0 {
+			current.WriteString("\n\n")
+		}
+		current.WriteString(piece)
+	}
+
+	for _, text := range texts {
+		label := sourceLabel.FindString(text)
+		for i, para := range splitLong(strings.Split(text, "\n\n"), limit-len(label)) {
+			if i > 0 && label != "" && !strings.HasPrefix(para, label) {
+				para = label + para
+			}
+			add(para)
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitLong splits paragraphs longer than limit bytes at the last space
+// before the limit, or at the limit when there is none.
+func splitLong(paras []string, limit int) []string {
+	limit = max(limit, 1)
+	var out []string
+	for _, para := range paras {
+		para = strings.TrimSpace(para)
+		for len(para) > limit {
+			cut := strings.LastIndexByte(para[:limit], ' ')
+			if cut <= 0 {
+				cut = limit
+				for cut > 0 && !utf8.RuneStart(para[cut]) {
+					cut--
+				}
+			}
+			out = append(out, strings.TrimSpace(para[:cut]))
+			para = strings.TrimSpace(para[cut:])
+		}
+		if para != "" {
+			out = append(out, para)
+		}
+	}
+	return out
+}
+
+var (
+	sourceLabel = regexp.MustCompile(`^\[\d+\] `)
+	citation    = regexp.MustCompile(`\[(\d+(?:\s*,\s*\d+)*)\]`)
+)
+
+// citedSources returns the source numbers from 1 to n cited as [n] or
+// [n, m] in s, sorted.
+func citedSources(s string, n int) []int {
+	seen := make(map[int]bool)
+	for _, match := range citation.FindAllStringSubmatch(s, -1) {
+		for _, part := range strings.Split(match[1], ",") {
+			num, err := strconv.Atoi(strings.TrimSpace(part))
+			if err == nil && num >= 1 && num <= n {
+				seen[num] = true
+			}
+		}
+	}
+	cited := make([]int, 0, len(seen))
+	for num := range seen {
+		cited = append(cited, num)
+	}
+	sort.Ints(cited)
+	return cited
+}
+
+// parseBullets returns the bullet lines of s without their markers.
+func parseBullets(s string) []string {
+	var bullets []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		for _, marker := range []string{"- ", "* ", "• "} {
+			if strings.HasPrefix(line, marker) {
+				bullets = append(bullets, strings.TrimSpace(line[len(marker):]))
+				break
+			}
+		}
+	}
+	return bullets
+}
+
+// Ensure interface compliance at compile time.
+var _ core.Node = (*SummarizeNode)(nil)
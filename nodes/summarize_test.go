@@ -0,0 +1,139 @@
+package nodes
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func TestSummarizeNode_Bullet(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{
+		Text:  "- Refunds take 5 days [1]\n- Shipping is free [2, 1]\n",
+		Usage: core.LLMTokenUsage{TotalTokens: 12},
+	}}
+	node := NewSummarizeNode("sum", client, SummarizeNodeConfig{
+		InputVar:  "docs",
+		Preset:    SummaryBullet,
+		Citations: CitationsSources,
+	})
+
+	env := core.NewEnvelope().WithVar("docs", []any{"Refunds take five days.", "Shipping is free.", "  "})
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out, _ := result.GetVar("sum_output"); !strings.HasPrefix(out.(string), "- Refunds") {
+		t.Errorf("output = %v", out)
+	}
+	raw, _ := result.GetVar("sum_output_result")
+	res := raw.(SummaryResult)
+	if res.Sources != 2 || res.Hierarchical || res.TargetWords != 120 || res.Usage.TotalTokens != 12 {
+		t.Errorf("result = %+v", res)
+	}
+	if !reflect.DeepEqual(res.Bullets, []string{"Refunds take 5 days [1]", "Shipping is free [2, 1]"}) {
+		t.Errorf("bullets = %q", res.Bullets)
+	}
+	if !reflect.DeepEqual(res.Cited, []int{1, 2}) {
+		t.Errorf("cited = %v", res.Cited)
+	}
+
+	req := client.requests[0]
+	if !strings.Contains(req.InputText, "[1] Refunds take five days.\n\n[2] Shipping is free.") {
+		t.Errorf("input = %q", req.InputText)
+	}
+	for _, want := range []string{"bullet points", "about 120 words", "cite the sources"} {
+		if !strings.Contains(req.System, want) {
+			t.Errorf("system prompt missing %q: %q", want, req.System)
+		}
+	}
+}
+
+func TestSummarizeNode_Tweet(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{Text: strings.Repeat("word ", 70)}}
+	node := NewSummarizeNode("sum", client, SummarizeNodeConfig{
+		InputVar:     "text",
+		Preset:       SummaryTweet,
+		TargetTokens: 40,
+	})
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("text", "A long article."))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	raw, _ := result.GetVar("sum_output_result")
+	res := raw.(SummaryResult)
+	if res.TargetWords != 30 || !res.OverLength || res.Characters != 349 {
+		t.Errorf("result = %+v", res)
+	}
+	if !strings.Contains(client.requests[0].System, "Never exceed 280 characters") {
+		t.Errorf("system prompt = %q", client.requests[0].System)
+	}
+	if !strings.Contains(client.requests[0].System, "Do not include citation markers") {
+		t.Errorf("system prompt = %q", client.requests[0].System)
+	}
+}
+
+func TestSummarizeNode_Hierarchical(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{Text: "short summary"}}
+	node := NewSummarizeNode("sum", client, SummarizeNodeConfig{
+		InputVar:       "text",
+		MaxInputTokens: 100,
+		ChunkTokens:    50,
+		Citations:      CitationsKeep,
+	})
+
+	paragraphs := make([]string, 8)
+	for i := range paragraphs {
+		paragraphs[i] = strings.Repeat("fact ", 30)
+	}
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("text", strings.Join(paragraphs, "\n\n")))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	raw, _ := result.GetVar("sum_output_result")
+	res := raw.(SummaryResult)
+	if !res.Hierarchical || res.Levels != 1 || res.Chunks != 8 {
+		t.Errorf("result = %+v", res)
+	}
+	if len(client.requests) != 9 {
+		t.Fatalf("calls = %d, want 8 chunks and the final summary", len(client.requests))
+	}
+	for _, req := range client.requests[:8] {
+		if len(req.InputText) > 200 || !strings.Contains(req.System, "one part of a longer input") {
+			t.Errorf("chunk request = %d bytes, %q", len(req.InputText), req.System)
+		}
+	}
+	final := client.requests[8]
+	if final.InputText != strings.TrimSuffix(strings.Repeat("short summary\n\n", 8), "\n\n") {
+		t.Errorf("final input = %q", final.InputText)
+	}
+	if !strings.Contains(final.System, "Keep the citation markers") {
+		t.Errorf("final system prompt = %q", final.System)
+	}
+}
+
+func TestSummarizeNode_HierarchicalDoesNotShrink(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{Text: strings.Repeat("padding ", 100)}}
+	node := NewSummarizeNode("sum", client, SummarizeNodeConfig{
+		InputVar:       "text",
+		MaxInputTokens: 50,
+	})
+	_, err := node.Run(context.Background(), core.NewEnvelope().WithVar("text", strings.Repeat("x ", 200)))
+	if err == nil || !strings.Contains(err.Error(), "raise max_input_tokens") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestChunkTexts_KeepsSourceLabels(t *testing.T) {
+	chunks := chunkTexts([]string{"[1] " + strings.Repeat("alpha ", 20), "[2] beta"}, 10)
+	for _, chunk := range chunks[:len(chunks)-1] {
+		if !strings.HasPrefix(chunk, "[1] ") || len(chunk) > 40 {
+			t.Errorf("chunk = %q", chunk)
+		}
+	}
+	if last := chunks[len(chunks)-1]; !strings.Contains(last, "[2] beta") {
+		t.Errorf("last chunk = %q", last)
+	}
+}
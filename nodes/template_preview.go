@@ -0,0 +1,90 @@
+package nodes
+
+import (
+	"fmt"
+	"text/template"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// TemplateKind selects which node's data and functions a previewed
+// template sees.
+type TemplateKind string
+
+const (
+	// TemplateKindLLM renders like an llm_prompt template: envelope vars at
+	// the top level plus input.
+	TemplateKindLLM TemplateKind = "llm"
+	// TemplateKindHuman renders like a human node prompt: input, vars and
+	// trace.
+	TemplateKindHuman TemplateKind = "human"
+	// TemplateKindTransform renders like a transform template: envelope
+	// vars at the top level plus _input and, unsandboxed, _env.
+	TemplateKindTransform TemplateKind = "transform"
+)
+
+// PreviewTemplate renders text against env the way a node of the given
+// kind would, so templates can be tried out without running a workflow.
+// Parse errors keep text/template's "name:line:col" location.
+func PreviewTemplate(kind TemplateKind, text string, env *core.Envelope, sandbox *TemplateSandbox) (string, error) {
+	if env == nil {
+		env = core.NewEnvelope()
+	}
+	var (
+		funcs template.FuncMap
+		data  map[string]any
+	)
+	switch kind {
+	case TemplateKindLLM, "":
+		kind = TemplateKindLLM
+		funcs, data = localeTemplateFuncs(EnvelopeLocale(env)), llmTemplateData(env)
+	case TemplateKindHuman:
+		funcs, data = localeTemplateFuncs(EnvelopeLocale(env)), humanTemplateData(env)
+	case TemplateKindTransform:
+		funcs, data = transformTemplateFuncs(EnvelopeLocale(env)), transformTemplateData(env, sandbox != nil)
+	default:
+		return "", fmt.Errorf("unknown template kind %q (want llm, human or transform)", kind)
+	}
+
+	tmpl, err := parseNodeTemplate(sandbox, string(kind), text, funcs)
+	if err != nil {
+		return "", err
+	}
+	return tmpl.render(data)
+}
+
+// llmTemplateData exposes envelope vars at the top level, plus input.
+func llmTemplateData(env *core.Envelope) map[string]any {
+	data := make(map[string]any, len(env.Vars)+1)
+	for k, v := range env.Vars {
+		data[k] = v
+	}
+	if env.Input != nil {
+		data["input"] = env.Input
+	}
+	return data
+}
+
+// humanTemplateData exposes the envelope's input, vars and trace.
+func humanTemplateData(env *core.Envelope) map[string]any {
+	return map[string]any{
+		"input": env.Input,
+		"vars":  env.Vars,
+		"trace": env.Trace,
+	}
+}
+
+// transformTemplateData exposes envelope vars at the top level, plus
+// _input and the envelope itself as _env. Its methods can change the
+// envelope, so sandboxed templates go without it.
+func transformTemplateData(env *core.Envelope, sandboxed bool) map[string]any {
+	data := make(map[string]any, len(env.Vars)+3)
+	for k, v := range env.Vars {
+		data[k] = v
+	}
+	if !sandboxed {
+		data["_env"] = env
+	}
+	data["_input"] = env.Input
+	return data
+}
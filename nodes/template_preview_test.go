@@ -0,0 +1,48 @@
+package nodes
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func TestPreviewTemplate_Kinds(t *testing.T) {
+	env := core.NewEnvelope().WithVar("name", "Ada")
+	env.Input = "hello"
+
+	tests := []struct {
+		kind TemplateKind
+		text string
+		want string
+	}{
+		{TemplateKindLLM, "{{.name}} {{.input}}", "Ada hello"},
+		{TemplateKindHuman, "{{.vars.name}} {{.input}}", "Ada hello"},
+		{TemplateKindTransform, "{{.name}} {{._input}}", "Ada hello"},
+	}
+	for _, tt := range tests {
+		got, err := PreviewTemplate(tt.kind, tt.text, env, nil)
+		if err != nil || got != tt.want {
+			t.Errorf("%s: got %q, %v; want %q", tt.kind, got, err, tt.want)
+		}
+	}
+
+	if _, err := PreviewTemplate("webhook", "x", env, nil); err == nil || !strings.Contains(err.Error(), "unknown template kind") {
+		t.Errorf("unknown kind: err = %v", err)
+	}
+}
+
+func TestPreviewTemplate_Sandbox(t *testing.T) {
+	env := core.NewEnvelope()
+	if _, err := PreviewTemplate(TemplateKindTransform, "{{._env}}", env, DefaultTemplateSandbox()); err != nil {
+		t.Fatalf("sandboxed transform: %v", err)
+	}
+	sandbox := DefaultTemplateSandbox()
+	sandbox.MaxOutputBytes = 4
+	_, err := PreviewTemplate(TemplateKindLLM, "too long", env, sandbox)
+	var sbErr *TemplateSandboxError
+	if !errors.As(err, &sbErr) {
+		t.Errorf("err = %v, want *TemplateSandboxError", err)
+	}
+}
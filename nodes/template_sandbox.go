@@ -0,0 +1,467 @@
+package nodes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// Template sandbox rules reported by TemplateSandboxError.
+const (
+	SandboxRuleFunction   = "function"
+	SandboxRuleTimeout    = "timeout"
+	SandboxRuleOutputSize = "output_size"
+	SandboxRuleRange      = "range"
+	SandboxRuleRecursion  = "recursion"
+)
+
+// Default template sandbox limits.
+const (
+	DefaultTemplateTimeout            = time.Second
+	DefaultTemplateMaxOutputBytes     = 1 << 20
+	DefaultTemplateMaxRangeIterations = 10000
+	DefaultTemplateMaxDepth           = 10
+)
+
+// DefaultTemplateFuncs is the function allowlist of sandboxes that do not
+// set their own: the text/template builtins except call, plus the helpers
+// node templates register.
+var DefaultTemplateFuncs = []string{
+	"and", "or", "not", "len", "index", "slice",
+	"eq", "ne", "lt", "le", "gt", "ge",
+	"print", "printf", "println", "html", "js", "urlquery",
+	"json", "jsonPretty", "join", "split", "upper", "lower", "trim",
+	"contains", "hasPrefix", "hasSuffix", "default", "coalesce",
+	"formatNumber", "formatCurrency", "formatDate",
+}
+
+// Internal sandbox hooks. Templates may not call them directly.
+const (
+	sandboxFuncPrefix = "__sandbox_"
+	sandboxRangeFunc  = sandboxFuncPrefix + "range"
+	sandboxEnterFunc  = sandboxFuncPrefix + "enter"
+	sandboxLeaveFunc  = sandboxFuncPrefix + "leave"
+)
+
+// TemplateSandbox restricts what a node template may call and how much
+// work it may do. The daemon applies one to every stored workflow; nil
+// leaves templates unrestricted.
+type TemplateSandbox struct {
+	// AllowedFuncs lists the functions templates may call. Nil uses
+	// DefaultTemplateFuncs.
+	AllowedFuncs []string
+	// Timeout bounds a single render. It is checked as the template writes
+	// output, iterates and invokes nested templates.
+	Timeout time.Duration
+	// MaxOutputBytes caps the rendered size.
+	MaxOutputBytes int
+	// MaxRangeIterations caps the range iterations of a render, summed over
+	// all range actions.
+	MaxRangeIterations int
+	// MaxDepth caps how deeply {{template}} invocations may nest.
+	MaxDepth int
+}
+
+// DefaultTemplateSandbox returns the sandbox the daemon applies to stored
+// workflows.
+func DefaultTemplateSandbox() *TemplateSandbox {
+	return &TemplateSandbox{
+		Timeout:            DefaultTemplateTimeout,
+		MaxOutputBytes:     DefaultTemplateMaxOutputBytes,
+		MaxRangeIterations: DefaultTemplateMaxRangeIterations,
+		MaxDepth:           DefaultTemplateMaxDepth,
+	}
+}
+
+// Validate checks the sandbox limits.
+func (s TemplateSandbox) Validate() error {
+	if s.Timeout < 0 {
+		return fmt.Errorf("template sandbox timeout must not be negative")
+	}
+	if s.MaxOutputBytes < 0 || s.MaxRangeIterations < 0 || s.MaxDepth < 0 {
+		return fmt.Errorf("template sandbox limits must not be negative")
+	}
+	for _, name := range s.AllowedFuncs {
+		if strings.HasPrefix(name, sandboxFuncPrefix) {
+			return fmt.Errorf("template sandbox cannot allow reserved function %q", name)
+		}
+	}
+	return nil
+}
+
+// withDefaults fills zero limits with the defaults.
+func (s TemplateSandbox) withDefaults() TemplateSandbox {
+	if s.AllowedFuncs == nil {
+		s.AllowedFuncs = DefaultTemplateFuncs
+	}
+	if s.Timeout == 0 {
+		s.Timeout = DefaultTemplateTimeout
+	}
+	if s.MaxOutputBytes == 0 {
+		s.MaxOutputBytes = DefaultTemplateMaxOutputBytes
+	}
+	if s.MaxRangeIterations == 0 {
+		s.MaxRangeIterations = DefaultTemplateMaxRangeIterations
+	}
+	if s.MaxDepth == 0 {
+		s.MaxDepth = DefaultTemplateMaxDepth
+	}
+	return s
+}
+
+// TemplateSandboxError reports a template that broke a sandbox rule. Its
+// details are recorded on the node error and the node.failed event.
+type TemplateSandboxError struct {
+	// Template is the name of the node template, such as "prompt".
+	Template string
+	// Rule is one of the SandboxRule constants.
+	Rule    string
+	Message string
+	// Limit is the limit that was exceeded, if the rule has one. Timeouts
+	// are in milliseconds.
+	Limit int64
+}
+
+func (e *TemplateSandboxError) Error() string {
+	return fmt.Sprintf("template %q violates sandbox %s rule: %s", e.Template, e.Rule, e.Message)
+}
+
+// ErrorDetails implements core.DetailedError.
+func (e *TemplateSandboxError) ErrorDetails() map[string]any {
+	details := map[string]any{
+		"sandbox_rule": e.Rule,
+		"template":     e.Template,
+	}
+	if e.Limit > 0 {
+		details["limit"] = e.Limit
+	}
+	return details
+}
+
+// nodeTemplate is a parsed node template, sandboxed when sandbox is set.
+type nodeTemplate struct {
+	name    string
+	tmpl    *template.Template
+	sandbox *TemplateSandbox
+	run     *sandboxRun
+}
+
+// parseNodeTemplate parses text with funcs. With a sandbox, calls to
+// functions outside the allowlist are rejected and the parse tree is
+// instrumented to enforce the sandbox limits.
+func parseNodeTemplate(sandbox *TemplateSandbox, name, text string, funcs template.FuncMap) (*nodeTemplate, error) {
+	nt := &nodeTemplate{name: name}
+	tmpl := template.New(name)
+	if sandbox == nil {
+		if funcs != nil {
+			tmpl = tmpl.Funcs(funcs)
+		}
+		parsed, err := tmpl.Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		nt.tmpl = parsed
+		return nt, nil
+	}
+
+	sb := sandbox.withDefaults()
+	nt.sandbox = &sb
+	nt.run = &sandboxRun{name: name, sandbox: nt.sandbox}
+	all := nt.run.funcs()
+	for k, v := range funcs {
+		all[k] = v
+	}
+	// printf is replaced so that padding cannot allocate past the output
+	// limit before the writer sees it.
+	all["printf"] = nt.run.printf
+
+	parsed, err := tmpl.Funcs(all).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make(map[string]bool, len(sb.AllowedFuncs))
+	for _, fn := range sb.AllowedFuncs {
+		allowed[fn] = true
+	}
+	in := &sandboxInstrumenter{name: name, allowed: allowed}
+	if in.enter, err = sandboxHook(all, "{{"+sandboxEnterFunc+"}}"); err != nil {
+		return nil, err
+	}
+	if in.leave, err = sandboxHook(all, "{{"+sandboxLeaveFunc+"}}"); err != nil {
+		return nil, err
+	}
+	rangeHook, err := sandboxHook(all, "{{. | "+sandboxRangeFunc+"}}")
+	if err != nil {
+		return nil, err
+	}
+	in.rangeCmd = rangeHook.Pipe.Cmds[1]
+	for _, t := range parsed.Templates() {
+		if t.Tree == nil || t.Tree.Root == nil {
+			continue
+		}
+		if err := in.list(t.Tree.Root); err != nil {
+			return nil, err
+		}
+	}
+	nt.tmpl = parsed
+	return nt, nil
+}
+
+// render executes the template with data.
+func (t *nodeTemplate) render(data any) (string, error) {
+	if t.sandbox == nil {
+		var buf bytes.Buffer
+		if err := t.tmpl.Execute(&buf, data); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+
+	t.run.reset()
+	w := &sandboxWriter{run: t.run}
+	if err := t.tmpl.Execute(w, data); err != nil {
+		var sbErr *TemplateSandboxError
+		if errors.As(err, &sbErr) {
+			return "", sbErr
+		}
+		return "", err
+	}
+	return w.buf.String(), nil
+}
+
+// sandboxHook parses a one-action template that calls a sandbox hook.
+func sandboxHook(funcs template.FuncMap, text string) (*parse.ActionNode, error) {
+	t, err := template.New("sandbox").Funcs(funcs).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return t.Tree.Root.Nodes[0].(*parse.ActionNode), nil
+}
+
+// sandboxInstrumenter checks function calls against the allowlist, wraps
+// {{template}} invocations in depth tracking and pipes range values
+// through the iteration counter.
+type sandboxInstrumenter struct {
+	name     string
+	allowed  map[string]bool
+	enter    *parse.ActionNode
+	leave    *parse.ActionNode
+	rangeCmd *parse.CommandNode
+}
+
+func (in *sandboxInstrumenter) list(list *parse.ListNode) error {
+	if list == nil {
+		return nil
+	}
+	nodes := make([]parse.Node, 0, len(list.Nodes))
+	for _, node := range list.Nodes {
+		if err := in.node(node); err != nil {
+			return err
+		}
+		if _, ok := node.(*parse.TemplateNode); ok {
+			nodes = append(nodes, in.enter, node, in.leave)
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+	list.Nodes = nodes
+	return nil
+}
+
+func (in *sandboxInstrumenter) node(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ActionNode:
+		return in.pipe(n.Pipe)
+	case *parse.TemplateNode:
+		return in.pipe(n.Pipe)
+	case *parse.IfNode:
+		return in.branch(&n.BranchNode)
+	case *parse.WithNode:
+		return in.branch(&n.BranchNode)
+	case *parse.RangeNode:
+		if err := in.branch(&n.BranchNode); err != nil {
+			return err
+		}
+		n.Pipe.Cmds = append(n.Pipe.Cmds, in.rangeCmd)
+	}
+	return nil
+}
+
+func (in *sandboxInstrumenter) branch(b *parse.BranchNode) error {
+	if err := in.pipe(b.Pipe); err != nil {
+		return err
+	}
+	if err := in.list(b.List); err != nil {
+		return err
+	}
+	return in.list(b.ElseList)
+}
+
+func (in *sandboxInstrumenter) pipe(pipe *parse.PipeNode) error {
+	if pipe == nil {
+		return nil
+	}
+	for _, cmd := range pipe.Cmds {
+		for _, arg := range cmd.Args {
+			switch a := arg.(type) {
+			case *parse.IdentifierNode:
+				if !in.allowed[a.Ident] {
+					return &TemplateSandboxError{
+						Template: in.name,
+						Rule:     SandboxRuleFunction,
+						Message:  fmt.Sprintf("function %q is not allowed", a.Ident),
+					}
+				}
+			case *parse.PipeNode:
+				if err := in.pipe(a); err != nil {
+					return err
+				}
+			case *parse.ChainNode:
+				if p, ok := a.Node.(*parse.PipeNode); ok {
+					if err := in.pipe(p); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// sandboxRun holds the per-render state the sandbox hooks share.
+type sandboxRun struct {
+	name       string
+	sandbox    *TemplateSandbox
+	deadline   time.Time
+	iterations int
+	depth      int
+}
+
+func (r *sandboxRun) reset() {
+	r.deadline = time.Now().Add(r.sandbox.Timeout)
+	r.iterations = 0
+	r.depth = 0
+}
+
+func (r *sandboxRun) violation(rule string, limit int64, format string, args ...any) error {
+	return &TemplateSandboxError{
+		Template: r.name,
+		Rule:     rule,
+		Message:  fmt.Sprintf(format, args...),
+		Limit:    limit,
+	}
+}
+
+func (r *sandboxRun) checkDeadline() error {
+	if time.Now().After(r.deadline) {
+		return r.violation(SandboxRuleTimeout, r.sandbox.Timeout.Milliseconds(),
+			"rendering took longer than %s", r.sandbox.Timeout)
+	}
+	return nil
+}
+
+func (r *sandboxRun) funcs() template.FuncMap {
+	return template.FuncMap{
+		sandboxRangeFunc: r.countRange,
+		sandboxEnterFunc: r.enter,
+		sandboxLeaveFunc: r.leave,
+	}
+}
+
+// countRange adds the length of a range value to the iteration count.
+func (r *sandboxRun) countRange(v any) (any, error) {
+	if err := r.checkDeadline(); err != nil {
+		return nil, err
+	}
+	var n int
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Invalid:
+	case reflect.Array, reflect.Slice, reflect.Map:
+		n = rv.Len()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n = int(max(rv.Int(), 0))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n = int(min(rv.Uint(), uint64(r.sandbox.MaxRangeIterations)+1))
+	default:
+		return nil, r.violation(SandboxRuleRange, int64(r.sandbox.MaxRangeIterations),
+			"cannot bound iterations over a %s", rv.Kind())
+	}
+	r.iterations += n
+	if r.iterations > r.sandbox.MaxRangeIterations {
+		return nil, r.violation(SandboxRuleRange, int64(r.sandbox.MaxRangeIterations),
+			"more than %d range iterations", r.sandbox.MaxRangeIterations)
+	}
+	return v, nil
+}
+
+func (r *sandboxRun) enter() (string, error) {
+	if err := r.checkDeadline(); err != nil {
+		return "", err
+	}
+	r.depth++
+	if r.depth > r.sandbox.MaxDepth {
+		return "", r.violation(SandboxRuleRecursion, int64(r.sandbox.MaxDepth),
+			"template calls nested deeper than %d", r.sandbox.MaxDepth)
+	}
+	return "", nil
+}
+
+func (r *sandboxRun) leave() string {
+	r.depth--
+	return ""
+}
+
+// printf is fmt.Sprintf with widths and precisions capped at the output
+// limit.
+func (r *sandboxRun) printf(format string, args ...any) (string, error) {
+	limit := r.sandbox.MaxOutputBytes
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		for i++; i < len(format) && !isPrintfVerb(format[i]); i++ {
+			switch c := format[i]; {
+			case c == '*':
+				return "", r.violation(SandboxRuleOutputSize, int64(limit), "printf * widths are not allowed")
+			case c >= '0' && c <= '9':
+				n := 0
+				for ; i < len(format) && format[i] >= '0' && format[i] <= '9'; i++ {
+					if n = n*10 + int(format[i]-'0'); n > limit {
+						return "", r.violation(SandboxRuleOutputSize, int64(limit),
+							"printf width exceeds %d bytes", limit)
+					}
+				}
+				i--
+			}
+		}
+	}
+	return fmt.Sprintf(format, args...), nil
+}
+
+func isPrintfVerb(c byte) bool {
+	return c == '%' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// sandboxWriter buffers output up to the sandbox limit.
+type sandboxWriter struct {
+	buf bytes.Buffer
+	run *sandboxRun
+}
+
+func (w *sandboxWriter) Write(p []byte) (int, error) {
+	if err := w.run.checkDeadline(); err != nil {
+		return 0, err
+	}
+	limit := w.run.sandbox.MaxOutputBytes
+	if w.buf.Len()+len(p) > limit {
+		return 0, w.run.violation(SandboxRuleOutputSize, int64(limit),
+			"rendered output exceeds %d bytes", limit)
+	}
+	return w.buf.Write(p)
+}
@@ -0,0 +1,168 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func renderSandboxed(t *testing.T, sandbox *TemplateSandbox, text string, data any) (string, error) {
+	t.Helper()
+	tmpl, err := parseNodeTemplate(sandbox, "test", text, transformTemplateFuncs(""))
+	if err != nil {
+		return "", err
+	}
+	return tmpl.render(data)
+}
+
+func wantSandboxRule(t *testing.T, err error, rule string) *TemplateSandboxError {
+	t.Helper()
+	var sbErr *TemplateSandboxError
+	if !errors.As(err, &sbErr) {
+		t.Fatalf("err = %v, want a sandbox %s violation", err, rule)
+	}
+	if sbErr.Rule != rule {
+		t.Fatalf("rule = %q, want %q (%v)", sbErr.Rule, rule, sbErr)
+	}
+	return sbErr
+}
+
+func TestTemplateSandbox_RendersOrdinaryTemplates(t *testing.T) {
+	text := `{{define "item"}}<{{upper .}}>{{end}}` +
+		`{{range $i, $v := .items}}{{if $i}},{{end}}{{template "item" $v}}{{end}} {{printf "%05.1f" .score}} {{len .items}}`
+	data := map[string]any{"items": []any{"a", "b"}, "score": 3.14159}
+
+	got, err := renderSandboxed(t, DefaultTemplateSandbox(), text, data)
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	want, _ := renderSandboxed(t, nil, text, data)
+	if got != want || got != "<A>,<B> 003.1 2" {
+		t.Fatalf("sandboxed = %q, unsandboxed = %q", got, want)
+	}
+}
+
+func TestTemplateSandbox_FunctionAllowlist(t *testing.T) {
+	_, err := renderSandboxed(t, DefaultTemplateSandbox(), `{{call .fn}}`, nil)
+	wantSandboxRule(t, err, SandboxRuleFunction)
+
+	narrow := &TemplateSandbox{AllowedFuncs: []string{"lower"}}
+	if _, err := renderSandboxed(t, narrow, `{{lower .x}}`, map[string]any{"x": "A"}); err != nil {
+		t.Fatalf("allowed func rejected: %v", err)
+	}
+	_, err = renderSandboxed(t, narrow, `{{if true}}{{(upper .x)}}{{end}}`, map[string]any{"x": "a"})
+	if sbErr := wantSandboxRule(t, err, SandboxRuleFunction); !strings.Contains(sbErr.Message, `"upper"`) {
+		t.Errorf("message = %q", sbErr.Message)
+	}
+
+	_, err = renderSandboxed(t, DefaultTemplateSandbox(), `{{__sandbox_leave}}`, nil)
+	wantSandboxRule(t, err, SandboxRuleFunction)
+}
+
+func TestTemplateSandbox_OutputSize(t *testing.T) {
+	sandbox := &TemplateSandbox{MaxOutputBytes: 10}
+	if _, err := renderSandboxed(t, sandbox, `0123456789`, nil); err != nil {
+		t.Fatalf("output at the limit rejected: %v", err)
+	}
+	_, err := renderSandboxed(t, sandbox, `{{range .}}abc{{end}}`, []int{1, 2, 3, 4})
+	if sbErr := wantSandboxRule(t, err, SandboxRuleOutputSize); sbErr.Limit != 10 {
+		t.Errorf("limit = %d, want 10", sbErr.Limit)
+	}
+
+	_, err = renderSandboxed(t, sandbox, `{{printf "%999999999d" 1}}`, nil)
+	wantSandboxRule(t, err, SandboxRuleOutputSize)
+}
+
+func TestTemplateSandbox_RangeIterations(t *testing.T) {
+	sandbox := &TemplateSandbox{MaxRangeIterations: 10}
+	data := map[string]any{"rows": [][]int{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}}}
+	_, err := renderSandboxed(t, sandbox, `{{range .rows}}{{range .}}{{.}}{{end}}{{end}}`, data)
+	wantSandboxRule(t, err, SandboxRuleRange)
+
+	if _, err := renderSandboxed(t, sandbox, `{{range .missing}}x{{end}}{{range 3}}{{.}}{{end}}`, map[string]any{}); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	_, err = renderSandboxed(t, sandbox, `{{range 11}}{{end}}`, nil)
+	wantSandboxRule(t, err, SandboxRuleRange)
+}
+
+func TestTemplateSandbox_Recursion(t *testing.T) {
+	text := `{{define "down"}}{{if .}}.{{template "down" (slice . 1)}}{{end}}{{end}}{{template "down" .}}`
+	sandbox := &TemplateSandbox{MaxDepth: 5}
+	if got, err := renderSandboxed(t, sandbox, text, "abcd"); err != nil || got != "...." {
+		t.Fatalf("render = %q, %v", got, err)
+	}
+	_, err := renderSandboxed(t, sandbox, text, "abcdefgh")
+	wantSandboxRule(t, err, SandboxRuleRecursion)
+}
+
+func TestTemplateSandbox_Timeout(t *testing.T) {
+	sandbox := &TemplateSandbox{Timeout: time.Nanosecond}
+	_, err := renderSandboxed(t, sandbox, `{{range .}}x{{end}}`, make([]int, 1000))
+	sbErr := wantSandboxRule(t, err, SandboxRuleTimeout)
+	details := core.ErrorDetails(err)
+	if details["sandbox_rule"] != SandboxRuleTimeout || details["template"] != "test" {
+		t.Errorf("details = %v", details)
+	}
+	if sbErr.Error() != `template "test" violates sandbox timeout rule: rendering took longer than 1ns` {
+		t.Errorf("Error() = %q", sbErr.Error())
+	}
+}
+
+func TestTemplateSandbox_Validate(t *testing.T) {
+	if err := (TemplateSandbox{MaxDepth: -1}).Validate(); err == nil {
+		t.Error("expected negative limit error")
+	}
+	if err := (TemplateSandbox{AllowedFuncs: []string{"__sandbox_range"}}).Validate(); err == nil {
+		t.Error("expected reserved function error")
+	}
+	if err := DefaultTemplateSandbox().Validate(); err != nil {
+		t.Errorf("default sandbox invalid: %v", err)
+	}
+}
+
+func TestTransformNode_SandboxHidesEnvelope(t *testing.T) {
+	env := core.NewEnvelope().WithVar("name", "ada")
+	text := `{{.name}}{{if ._env}} env{{end}}`
+
+	open := NewTransformNode("t", TransformNodeConfig{Transform: TransformTemplate, Template: text, OutputVar: "out"})
+	result, err := open.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out, _ := result.GetVar("out"); out != "ada env" {
+		t.Fatalf("unsandboxed out = %q", out)
+	}
+
+	sandboxed := NewTransformNode("t", TransformNodeConfig{
+		Transform:       TransformTemplate,
+		Template:        text,
+		OutputVar:       "out",
+		TemplateSandbox: DefaultTemplateSandbox(),
+	})
+	result, err = sandboxed.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out, _ := result.GetVar("out"); out != "ada" {
+		t.Fatalf("sandboxed out = %q, want %q", out, "ada")
+	}
+}
+
+func TestLLMNode_SandboxViolation(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{Text: "ok"}}
+	node := NewLLMNode("ask", client, LLMNodeConfig{
+		Model:           "gpt-4",
+		PromptTemplate:  `{{range .items}}{{.}}{{end}}`,
+		TemplateSandbox: &TemplateSandbox{MaxRangeIterations: 2},
+	})
+	_, err := node.Run(context.Background(), core.NewEnvelope().WithVar("items", []string{"a", "b", "c"}))
+	wantSandboxRule(t, err, SandboxRuleRange)
+	if details := core.ErrorDetails(err); details["limit"] != int64(2) {
+		t.Errorf("details = %v", details)
+	}
+}
@@ -0,0 +1,51 @@
+package nodes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// TemplateVar configures how a prompt template treats a variable that is
+// missing from the envelope, instead of rendering it as "<no value>".
+type TemplateVar struct {
+	// Required fails the node before the LLM is called when the variable
+	// is missing and has no default.
+	Required bool
+
+	// Default is rendered in place of the missing variable.
+	Default any
+}
+
+// MissingTemplateVarsError reports required template variables that were
+// missing when a prompt was built.
+type MissingTemplateVarsError struct {
+	Vars []string
+}
+
+func (e *MissingTemplateVarsError) Error() string {
+	return fmt.Sprintf("missing required template vars: %s", strings.Join(e.Vars, ", "))
+}
+
+// applyTemplateVars fills in the defaults of vars missing from data, a
+// template's top-level data, and returns a MissingTemplateVarsError naming
+// the required ones still missing. A var set to nil counts as missing.
+func applyTemplateVars(data map[string]any, vars map[string]TemplateVar) error {
+	var missing []string
+	for name, tv := range vars {
+		if v, ok := data[name]; ok && v != nil {
+			continue
+		}
+		switch {
+		case tv.Default != nil:
+			data[name] = tv.Default
+		case tv.Required:
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return &MissingTemplateVarsError{Vars: missing}
+	}
+	return nil
+}
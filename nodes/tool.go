@@ -2,10 +2,18 @@ package nodes
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/santhosh-tekuri/jsonschema/v6"
+
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/propagate"
 	"github.com/petal-labs/petalflow/runtime"
 )
 
@@ -32,6 +40,53 @@ type ToolNodeConfig struct {
 
 	// OnError defines how errors are handled.
 	OnError core.ErrorPolicy
+
+	// StrictArgs also rejects arguments the tool's schema does not declare.
+	// Declared arguments are always checked when the tool publishes a
+	// schema (see core.ArgsSchemaTool).
+	StrictArgs bool
+
+	// Callbacks delivers the results of asynchronous invocations, which
+	// answer with core.ToolPendingKey. Without it, only tools implementing
+	// core.ToolPoller can answer asynchronously.
+	Callbacks *ToolCallbacks
+
+	// AsyncTimeout bounds the wait for an asynchronous invocation's
+	// result. Defaults to DefaultToolAsyncTimeout; a negative value waits
+	// as long as the run does.
+	AsyncTimeout time.Duration
+
+	// PollInterval is how often a core.ToolPoller is polled for the
+	// result. Defaults to DefaultToolPollInterval.
+	PollInterval time.Duration
+
+	// Cache stores the responses of tools that declare a cache policy
+	// (see core.CacheableTool), shared by every node given the same store.
+	// Nil disables caching.
+	Cache CacheStore
+
+	// CacheBypass stops this node reading cached responses (refresh) or
+	// using the cache at all (skip).
+	CacheBypass ToolCacheBypass
+
+	// PropagateHeaders layers the node's correlation header setting over
+	// the run's for the tool's outbound HTTP requests.
+	PropagateHeaders *propagate.Override
+}
+
+// ToolArgsError reports rendered arguments that do not match the tool's
+// argument schema. It is handled by the node's OnError policy.
+type ToolArgsError struct {
+	Tool   string
+	Errors []JSONSchemaError
+}
+
+func (e *ToolArgsError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, se := range e.Errors {
+		parts[i] = fmt.Sprintf("%s: %s", displayPointer(se.InstancePath), se.Message)
+	}
+	return fmt.Sprintf("tool %q arguments invalid: %s", e.Tool, strings.Join(parts, "; "))
 }
 
 // ToolNode executes a tool as a workflow step.
@@ -40,6 +95,13 @@ type ToolNode struct {
 	config   ToolNodeConfig
 	tool     core.PetalTool
 	registry *core.ToolRegistry
+
+	// schemaMu guards the argument schema compiled for the last tool, which
+	// is reused while the tool and its schema stay the same.
+	schemaMu   sync.Mutex
+	schemaTool string
+	schemaDoc  map[string]any
+	schema     *jsonschema.Schema
 }
 
 // NewToolNode creates a new tool node with a specific tool.
@@ -57,6 +119,12 @@ func NewToolNode(id string, tool core.PetalTool, config ToolNodeConfig) *ToolNod
 	if config.OnError == "" {
 		config.OnError = core.ErrorPolicyFail
 	}
+	if config.AsyncTimeout == 0 {
+		config.AsyncTimeout = DefaultToolAsyncTimeout
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultToolPollInterval
+	}
 	if config.ToolName == "" && tool != nil {
 		config.ToolName = tool.Name()
 	}
@@ -83,6 +151,12 @@ func NewToolNodeWithRegistry(id string, registry *core.ToolRegistry, config Tool
 	if config.OnError == "" {
 		config.OnError = core.ErrorPolicyFail
 	}
+	if config.AsyncTimeout == 0 {
+		config.AsyncTimeout = DefaultToolAsyncTimeout
+	}
+	if config.PollInterval <= 0 {
+		config.PollInterval = DefaultToolPollInterval
+	}
 
 	return &ToolNode{
 		BaseNode: core.NewBaseNode(id, core.NodeKindTool),
@@ -93,12 +167,12 @@ func NewToolNodeWithRegistry(id string, registry *core.ToolRegistry, config Tool
 
 // Run executes the tool and stores the result in the envelope.
 func (n *ToolNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
-	// Apply timeout
-	if n.config.Timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, n.config.Timeout)
-		defer cancel()
-	}
+	// Apply timeout, clamped to the run deadline. Asynchronous results are
+	// awaited under AsyncTimeout instead.
+	ctx = n.config.PropagateHeaders.Apply(ctx)
+	runCtx := ctx
+	ctx, cancel := runtime.WithNodeTimeout(ctx, n.config.Timeout, env.Trace.RunID, n.ID(), n.Kind())
+	defer cancel()
 
 	// Get the tool
 	tool, err := n.getTool()
@@ -114,6 +188,9 @@ func (n *ToolNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope,
 	if err != nil {
 		return n.handleError(env, fmt.Errorf("failed to build args: %w", err))
 	}
+	if err := n.validateArgs(tool, args); err != nil {
+		return n.handleError(env, err)
+	}
 
 	// Emit tool.call event
 	emit(runtime.NewEvent(runtime.EventToolCall, env.Trace.RunID).
@@ -121,12 +198,44 @@ func (n *ToolNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope,
 		WithPayload("tool_name", tool.Name()).
 		WithPayload("arguments", args))
 
+	// Answer from the cache when the tool allows it
+	lookup, err := n.cacheLookup(ctx, tool, args)
+	if err != nil {
+		return n.handleError(env, err)
+	}
+	if result, hit := lookup.get(ctx, n.config.Cache); hit {
+		emit(runtime.NewEvent(runtime.EventToolResult, env.Trace.RunID).
+			WithNode(n.ID(), n.Kind()).
+			WithPayload("tool_name", tool.Name()).
+			WithPayload("is_error", false).
+			WithPayload("cache", lookup.outcome(true)))
+		env.SetVar(n.config.OutputKey, result)
+		return env, nil
+	}
+
+	// Issue the invocation's callback token before calling the tool, so a
+	// result posted before the tool answers reaches this node
+	var callbackResults <-chan toolCallbackResult
+	awaited := false
+	if n.config.Callbacks != nil {
+		token, results, done, err := n.config.Callbacks.issue()
+		if err != nil {
+			return n.handleError(env, err)
+		}
+		defer func() { done(awaited) }()
+		ctx = core.WithToolCallbackToken(ctx, token)
+		callbackResults = results
+	}
+
 	// Execute with retries
 	var result map[string]any
 	var lastErr error
 
 	for attempt := 1; attempt <= n.config.RetryPolicy.MaxAttempts; attempt++ {
 		result, lastErr = tool.Invoke(ctx, args)
+		if lastErr == nil {
+			lastErr = runtime.InjectToolDrop(ctx, env.Trace.RunID, n.ID(), n.Kind())
+		}
 		if lastErr == nil {
 			break
 		}
@@ -146,19 +255,58 @@ func (n *ToolNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope,
 		}
 	}
 
+	// An asynchronous invocation answers with a token; wait for its result
+	var asyncErr error
+	pending := false
+	if lastErr == nil {
+		var token string
+		if token, pending = core.ToolPendingToken(result); pending {
+			awaited = true
+			result, asyncErr = n.awaitAsync(runCtx, env, tool, token, callbackResults)
+		}
+	}
+
+	// Files the tool produced become artifacts
+	var artifacts []core.Artifact
+	var artifactErr error
+	if lastErr == nil && asyncErr == nil {
+		result, artifacts, artifactErr = extractToolArtifacts(result, n.ID(), tool.Name())
+	}
+
 	// Emit tool.result event (always, even on failure)
-	emit(runtime.NewEvent(runtime.EventToolResult, env.Trace.RunID).
+	resultEvent := runtime.NewEvent(runtime.EventToolResult, env.Trace.RunID).
 		WithNode(n.ID(), n.Kind()).
 		WithPayload("tool_name", tool.Name()).
-		WithPayload("is_error", lastErr != nil))
+		WithPayload("is_error", lastErr != nil || asyncErr != nil || artifactErr != nil)
+	if len(artifacts) > 0 {
+		resultEvent = resultEvent.WithPayload("artifacts", result[core.ToolArtifactsKey])
+	}
+	if lookup != nil {
+		resultEvent = resultEvent.WithPayload("cache", lookup.outcome(false))
+	}
+	emit(resultEvent)
 
 	if lastErr != nil {
 		return n.handleError(env, fmt.Errorf("tool %q failed after %d attempts: %w",
 			n.config.ToolName, n.config.RetryPolicy.MaxAttempts, lastErr))
 	}
+	if asyncErr != nil {
+		return n.handleError(env, asyncErr)
+	}
+	if artifactErr != nil {
+		return n.handleError(env, artifactErr)
+	}
+
+	// Asynchronous results and files are not replayed from the cache
+	if !pending && len(artifacts) == 0 {
+		lookup.put(ctx, n.config.Cache, result)
+	}
 
 	// Store output in envelope
 	env.SetVar(n.config.OutputKey, result)
+	for _, art := range artifacts {
+		env.AppendArtifact(art)
+	}
 
 	return env, nil
 }
@@ -207,20 +355,80 @@ func (n *ToolNode) buildArgs(env *core.Envelope) (map[string]any, error) {
 	return args, nil
 }
 
+// validateArgs checks args against the tool's argument schema, if any.
+func (n *ToolNode) validateArgs(tool core.PetalTool, args map[string]any) error {
+	schemaTool, ok := tool.(core.ArgsSchemaTool)
+	if !ok {
+		return nil
+	}
+	doc := schemaTool.ArgsSchema()
+	if doc == nil {
+		return nil
+	}
+	schema, err := n.argsSchema(tool.Name(), doc)
+	if err != nil {
+		return fmt.Errorf("tool %q argument schema: %w", tool.Name(), err)
+	}
+	instance, err := toJSONValue(args)
+	if err != nil {
+		return fmt.Errorf("tool %q arguments are not JSON-encodable: %w", tool.Name(), err)
+	}
+	err = schema.Validate(instance)
+	if err == nil {
+		return nil
+	}
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return fmt.Errorf("tool %q arguments: %w", tool.Name(), err)
+	}
+	errs := collectSchemaErrors(verr, nil)
+	sort.SliceStable(errs, func(i, j int) bool { return errs[i].InstancePath < errs[j].InstancePath })
+	return &ToolArgsError{Tool: tool.Name(), Errors: errs}
+}
+
+// argsSchema returns the compiled argument schema doc of the named tool,
+// compiling it only when the tool or its schema changed since the last call.
+func (n *ToolNode) argsSchema(name string, doc map[string]any) (*jsonschema.Schema, error) {
+	n.schemaMu.Lock()
+	defer n.schemaMu.Unlock()
+	if n.schema != nil && n.schemaTool == name && reflect.DeepEqual(n.schemaDoc, doc) {
+		return n.schema, nil
+	}
+	compiled := doc
+	if n.config.StrictArgs {
+		compiled = make(map[string]any, len(doc)+1)
+		for k, v := range doc {
+			compiled[k] = v
+		}
+		compiled["additionalProperties"] = false
+	}
+	schema, err := compileJSONSchema(compiled)
+	if err != nil {
+		return nil, err
+	}
+	n.schemaTool, n.schemaDoc, n.schema = name, doc, schema
+	return schema, nil
+}
+
 // handleError processes errors according to the OnError policy.
 func (n *ToolNode) handleError(env *core.Envelope, err error) (*core.Envelope, error) {
 	switch n.config.OnError {
 	case core.ErrorPolicyContinue, core.ErrorPolicyRecord:
 		// Record error and continue
+		details := map[string]any{
+			"tool": n.config.ToolName,
+		}
+		var argsErr *ToolArgsError
+		if errors.As(err, &argsErr) {
+			details["arg_errors"] = argsErr.Errors
+		}
 		env.AppendError(core.NodeError{
 			NodeID:  n.ID(),
 			Kind:    core.NodeKindTool,
 			Message: err.Error(),
 			At:      time.Now(),
 			Cause:   err,
-			Details: map[string]any{
-				"tool": n.config.ToolName,
-			},
+			Details: details,
 		})
 		// Set output to nil to indicate failure
 		env.SetVar(n.config.OutputKey, nil)
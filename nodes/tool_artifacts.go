@@ -0,0 +1,199 @@
+package nodes
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// extractToolArtifacts removes the core.ToolArtifactsKey entry from a tool
+// output and converts it into envelope artifacts attributed to nodeID and
+// toolName. The returned output lists a summary of each artifact under the
+// same key, so templates can refer to them without carrying their content.
+func extractToolArtifacts(output map[string]any, nodeID, toolName string) (map[string]any, []core.Artifact, error) {
+	raw, ok := output[core.ToolArtifactsKey]
+	if !ok {
+		return output, nil, nil
+	}
+	files, err := parseToolArtifacts(raw)
+	if err != nil {
+		return output, nil, fmt.Errorf("tool %q returned invalid %s: %w", toolName, core.ToolArtifactsKey, err)
+	}
+
+	artifacts := make([]core.Artifact, len(files))
+	summaries := make([]any, len(files))
+	for i, file := range files {
+		artifacts[i] = toolArtifact(file, i, nodeID, toolName)
+		summaries[i] = artifactSummary(artifacts[i])
+	}
+
+	cleaned := make(map[string]any, len(output))
+	for k, v := range output {
+		cleaned[k] = v
+	}
+	cleaned[core.ToolArtifactsKey] = summaries
+	return cleaned, artifacts, nil
+}
+
+// parseToolArtifacts accepts a single artifact or a list of them, as
+// core.ToolArtifact values or decoded JSON objects.
+func parseToolArtifacts(raw any) ([]core.ToolArtifact, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case core.ToolArtifact:
+		return []core.ToolArtifact{v}, validateToolArtifact(v, 0)
+	case *core.ToolArtifact:
+		if v == nil {
+			return nil, nil
+		}
+		return []core.ToolArtifact{*v}, validateToolArtifact(*v, 0)
+	case []core.ToolArtifact:
+		for i, file := range v {
+			if err := validateToolArtifact(file, i); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	case map[string]any:
+		file, err := toolArtifactFromMap(v, 0)
+		if err != nil {
+			return nil, err
+		}
+		return []core.ToolArtifact{file}, nil
+	case []map[string]any:
+		files := make([]core.ToolArtifact, len(v))
+		for i, item := range v {
+			file, err := toolArtifactFromMap(item, i)
+			if err != nil {
+				return nil, err
+			}
+			files[i] = file
+		}
+		return files, nil
+	case []any:
+		files := make([]core.ToolArtifact, len(v))
+		for i, item := range v {
+			var err error
+			switch typed := item.(type) {
+			case core.ToolArtifact:
+				files[i], err = typed, validateToolArtifact(typed, i)
+			case map[string]any:
+				files[i], err = toolArtifactFromMap(typed, i)
+			default:
+				err = fmt.Errorf("entry %d: expected an object, got %T", i, item)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return files, nil
+	default:
+		return nil, fmt.Errorf("expected an artifact or a list of artifacts, got %T", raw)
+	}
+}
+
+func toolArtifactFromMap(m map[string]any, index int) (core.ToolArtifact, error) {
+	var file core.ToolArtifact
+	for _, field := range []struct {
+		key string
+		dst *string
+	}{
+		{"filename", &file.Filename},
+		{"mime_type", &file.MimeType},
+		{"type", &file.Type},
+		{"text", &file.Text},
+		{"uri", &file.URI},
+	} {
+		if v, ok := m[field.key]; ok && v != nil {
+			s, ok := v.(string)
+			if !ok {
+				return file, fmt.Errorf("entry %d: %s must be a string, got %T", index, field.key, v)
+			}
+			*field.dst = s
+		}
+	}
+	switch data := m["data"].(type) {
+	case nil:
+	case []byte:
+		file.Data = data
+	case string:
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		if err != nil {
+			return file, fmt.Errorf("entry %d: data is not valid base64: %w", index, err)
+		}
+		file.Data = decoded
+	default:
+		return file, fmt.Errorf("entry %d: data must be a base64 string, got %T", index, data)
+	}
+	if meta, ok := m["meta"].(map[string]any); ok {
+		file.Meta = meta
+	}
+	return file, validateToolArtifact(file, index)
+}
+
+func validateToolArtifact(file core.ToolArtifact, index int) error {
+	if len(file.Data) == 0 && file.Text == "" && file.URI == "" {
+		return fmt.Errorf("entry %d: needs data, text or uri", index)
+	}
+	return nil
+}
+
+// toolArtifact builds the envelope artifact for the index-th file a tool
+// returned, filling in its type and media type when the tool left them out.
+func toolArtifact(file core.ToolArtifact, index int, nodeID, toolName string) core.Artifact {
+	name := file.Filename
+	if name == "" {
+		name = strconv.Itoa(index + 1)
+	}
+	art := core.Artifact{
+		ID:       nodeID + "/" + name,
+		Type:     file.Type,
+		MimeType: file.MimeType,
+		Text:     file.Text,
+		Bytes:    file.Data,
+		URI:      file.URI,
+		Meta:     make(map[string]any, len(file.Meta)+4),
+	}
+	if art.Type == "" {
+		art.Type = "file"
+	}
+	if art.MimeType == "" && file.Filename != "" {
+		art.MimeType = mime.TypeByExtension(path.Ext(file.Filename))
+	}
+	if art.MimeType == "" && len(file.Data) > 0 {
+		art.MimeType = http.DetectContentType(file.Data)
+	}
+	for k, v := range file.Meta {
+		art.Meta[k] = v
+	}
+	if file.Filename != "" {
+		art.Meta["filename"] = file.Filename
+	}
+	art.Meta["source_node"] = nodeID
+	art.Meta["tool"] = toolName
+	art.Meta["size"] = len(file.Data) + len(file.Text)
+	return art
+}
+
+// artifactSummary describes an artifact without its content.
+func artifactSummary(art core.Artifact) map[string]any {
+	summary := map[string]any{
+		"id":        art.ID,
+		"type":      art.Type,
+		"mime_type": art.MimeType,
+		"size":      art.Meta["size"],
+	}
+	if filename, ok := art.Meta["filename"]; ok {
+		summary["filename"] = filename
+	}
+	if art.URI != "" {
+		summary["uri"] = art.URI
+	}
+	return summary
+}
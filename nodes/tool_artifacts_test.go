@@ -0,0 +1,138 @@
+package nodes
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestToolNode_NativeArtifacts(t *testing.T) {
+	tool := &mockPetalTool{
+		name: "render",
+		result: map[string]any{
+			"pages": 1,
+			core.ToolArtifactsKey: []core.ToolArtifact{
+				{Filename: "chart.png", Data: pngHeader, Meta: map[string]any{"width": 640}},
+				{Type: "document", Text: "# Report"},
+			},
+		},
+	}
+	node := NewToolNode("render_step", tool, ToolNodeConfig{OutputKey: "render"})
+
+	var events []runtime.Event
+	ctx := runtime.ContextWithEmitter(context.Background(), func(e runtime.Event) { events = append(events, e) })
+	result, err := node.Run(ctx, core.NewEnvelope())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(result.Artifacts) != 2 {
+		t.Fatalf("got %d artifacts, want 2", len(result.Artifacts))
+	}
+	chart := result.Artifacts[0]
+	if chart.ID != "render_step/chart.png" || chart.Type != "file" || chart.MimeType != "image/png" || string(chart.Bytes) != string(pngHeader) {
+		t.Errorf("chart = %+v", chart)
+	}
+	if chart.Meta["filename"] != "chart.png" || chart.Meta["source_node"] != "render_step" || chart.Meta["tool"] != "render" || chart.Meta["width"] != 640 {
+		t.Errorf("chart meta = %v", chart.Meta)
+	}
+	doc := result.Artifacts[1]
+	if doc.ID != "render_step/2" || doc.Type != "document" || doc.Text != "# Report" {
+		t.Errorf("doc = %+v", doc)
+	}
+
+	output := result.Vars["render"].(map[string]any)
+	if output["pages"] != 1 {
+		t.Errorf("output = %v", output)
+	}
+	summaries := output[core.ToolArtifactsKey].([]any)
+	first := summaries[0].(map[string]any)
+	if len(summaries) != 2 || first["id"] != "render_step/chart.png" || first["size"] != len(pngHeader) {
+		t.Errorf("summaries = %v", summaries)
+	}
+	if _, ok := first["data"]; ok {
+		t.Error("summary should not carry the artifact content")
+	}
+
+	last := events[len(events)-1]
+	if last.Kind != runtime.EventToolResult || last.Payload["artifacts"] == nil {
+		t.Errorf("tool.result payload = %v", last.Payload)
+	}
+}
+
+func TestToolNode_JSONArtifacts(t *testing.T) {
+	tool := &mockPetalTool{
+		name: "export",
+		result: map[string]any{
+			core.ToolArtifactsKey: []any{
+				map[string]any{"filename": "data.csv", "data": base64.StdEncoding.EncodeToString([]byte("a,b\n1,2\n"))},
+				map[string]any{"data": base64.StdEncoding.EncodeToString(pngHeader)},
+				map[string]any{"uri": "s3://bucket/big.bin", "mime_type": "application/octet-stream"},
+			},
+		},
+	}
+	node := NewToolNode("export", tool, ToolNodeConfig{OutputKey: "out"})
+
+	result, err := node.Run(context.Background(), core.NewEnvelope())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(result.Artifacts) != 3 {
+		t.Fatalf("got %d artifacts, want 3", len(result.Artifacts))
+	}
+	if csv := result.Artifacts[0]; !strings.HasPrefix(csv.MimeType, "text/csv") || string(csv.Bytes) != "a,b\n1,2\n" {
+		t.Errorf("csv = %+v", csv)
+	}
+	if sniffed := result.Artifacts[1]; sniffed.MimeType != "image/png" || sniffed.ID != "export/2" {
+		t.Errorf("sniffed = %+v", sniffed)
+	}
+	if ref := result.Artifacts[2]; ref.URI != "s3://bucket/big.bin" || ref.MimeType != "application/octet-stream" {
+		t.Errorf("ref = %+v", ref)
+	}
+}
+
+func TestToolNode_InvalidArtifacts(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  any
+		want string
+	}{
+		{"bad base64", []any{map[string]any{"data": "not base64!"}}, "not valid base64"},
+		{"empty entry", []any{map[string]any{"filename": "x.txt"}}, "needs data, text or uri"},
+		{"wrong type", "chart.png", "expected an artifact"},
+		{"non-string field", []any{map[string]any{"text": "x", "filename": 3}}, "filename must be a string"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tool := &mockPetalTool{name: "broken", result: map[string]any{core.ToolArtifactsKey: tt.raw}}
+			node := NewToolNode("broken", tool, ToolNodeConfig{OutputKey: "out"})
+			_, err := node.Run(context.Background(), core.NewEnvelope())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Run() error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestToolNode_NoArtifactsKeepsOutput(t *testing.T) {
+	output := map[string]any{"artifacts": []any{"kept as data"}}
+	tool := &mockPetalTool{name: "plain", result: output}
+	node := NewToolNode("plain", tool, ToolNodeConfig{OutputKey: "out"})
+
+	result, err := node.Run(context.Background(), core.NewEnvelope())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(result.Artifacts) != 0 {
+		t.Errorf("got %d artifacts, want none", len(result.Artifacts))
+	}
+	if got := result.Vars["out"].(map[string]any)["artifacts"].([]any); got[0] != "kept as data" {
+		t.Errorf("output = %v", result.Vars["out"])
+	}
+}
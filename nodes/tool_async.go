@@ -0,0 +1,216 @@
+package nodes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// Defaults of asynchronous tool invocations.
+const (
+	DefaultToolAsyncTimeout = time.Hour
+	DefaultToolPollInterval = 5 * time.Second
+)
+
+// toolCallbackRetention is how long ToolCallbacks remembers a token after
+// its result was delivered or its node stopped waiting.
+const toolCallbackRetention = 24 * time.Hour
+
+var (
+	// ErrUnknownToolCallback is returned by ToolCallbacks.Deliver for a
+	// token that was never issued to an invocation.
+	ErrUnknownToolCallback = errors.New("unknown tool callback token")
+	// ErrDuplicateToolCallback is returned by ToolCallbacks.Deliver for a
+	// token whose result was already delivered.
+	ErrDuplicateToolCallback = errors.New("tool callback already delivered")
+	// ErrExpiredToolCallback is returned by ToolCallbacks.Deliver for a
+	// token no node waits for any longer, because it timed out or its run
+	// was canceled.
+	ErrExpiredToolCallback = errors.New("tool callback no longer awaited")
+)
+
+// toolCallbackResult is the outcome of an asynchronous invocation.
+type toolCallbackResult struct {
+	outputs map[string]any
+	err     error
+}
+
+// ToolCallbacks correlates the results of asynchronous tool invocations,
+// delivered by callback, with the tool nodes waiting for them. Each
+// invocation is issued a random token before the tool is called (see
+// core.ToolCallbackToken), and only issued tokens accept results, so a
+// result that arrives before the tool answers is kept for its node. It is
+// safe for concurrent use.
+type ToolCallbacks struct {
+	mu      sync.Mutex
+	waiting map[string]chan toolCallbackResult
+	closed  map[string]closedToolCallback
+	now     func() time.Time
+}
+
+type closedToolCallback struct {
+	delivered bool
+	at        time.Time
+}
+
+// NewToolCallbacks returns an empty ToolCallbacks.
+func NewToolCallbacks() *ToolCallbacks {
+	return &ToolCallbacks{
+		waiting: make(map[string]chan toolCallbackResult),
+		closed:  make(map[string]closedToolCallback),
+		now:     time.Now,
+	}
+}
+
+// Deliver hands the result of the invocation issued token to the node
+// waiting for it. A non-nil invokeErr fails the invocation. Tokens that
+// were never issued return ErrUnknownToolCallback. Each token takes one
+// result: later deliveries return ErrDuplicateToolCallback, and deliveries
+// after the node stopped waiting return ErrExpiredToolCallback.
+func (c *ToolCallbacks) Deliver(token string, outputs map[string]any, invokeErr error) error {
+	if !IsToolCallbackToken(token) {
+		return ErrUnknownToolCallback
+	}
+	result := toolCallbackResult{outputs: outputs, err: invokeErr}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune()
+	if closed, ok := c.closed[token]; ok {
+		if closed.delivered {
+			return ErrDuplicateToolCallback
+		}
+		return ErrExpiredToolCallback
+	}
+	ch, ok := c.waiting[token]
+	if !ok {
+		return ErrUnknownToolCallback
+	}
+	ch <- result
+	delete(c.waiting, token)
+	c.closed[token] = closedToolCallback{delivered: true, at: c.now()}
+	return nil
+}
+
+// toolCallbackTokenBytes is the size of the random secret in an issued
+// token: 128 bits.
+const toolCallbackTokenBytes = 16
+
+// IsToolCallbackToken reports whether token has the form of a token issued
+// by ToolCallbacks: the hex encoding of a 128-bit secret.
+func IsToolCallbackToken(token string) bool {
+	if len(token) != 2*toolCallbackTokenBytes {
+		return false
+	}
+	for _, r := range token {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// issue mints a token for one invocation and registers interest in its
+// result. done must be called once the caller no longer waits: awaited
+// reports whether the invocation went asynchronous, in which case later
+// deliveries are answered as expired rather than unknown.
+func (c *ToolCallbacks) issue() (token string, results <-chan toolCallbackResult, done func(awaited bool), err error) {
+	var secret [toolCallbackTokenBytes]byte
+	if _, err := rand.Read(secret[:]); err != nil {
+		return "", nil, nil, fmt.Errorf("issue tool callback token: %w", err)
+	}
+	token = hex.EncodeToString(secret[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune()
+	ch := make(chan toolCallbackResult, 1)
+	c.waiting[token] = ch
+	return token, ch, func(awaited bool) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.waiting[token] != ch {
+			return
+		}
+		delete(c.waiting, token)
+		if awaited {
+			c.closed[token] = closedToolCallback{at: c.now()}
+		}
+	}, nil
+}
+
+// prune forgets closed tokens past their retention. The caller holds c.mu.
+func (c *ToolCallbacks) prune() {
+	cutoff := c.now().Add(-toolCallbackRetention)
+	for token, closed := range c.closed {
+		if closed.at.Before(cutoff) {
+			delete(c.closed, token)
+		}
+	}
+}
+
+// awaitAsync waits for the result of an asynchronous invocation that
+// answered with token: through results, the invocation's callbacks, by
+// polling a core.ToolPoller, or both, whichever answers first. results is
+// nil without callbacks.
+func (n *ToolNode) awaitAsync(ctx context.Context, env *core.Envelope, tool core.PetalTool, token string, results <-chan toolCallbackResult) (map[string]any, error) {
+	poller, canPoll := tool.(core.ToolPoller)
+	if token == "" {
+		return nil, fmt.Errorf("tool %q answered %s without a token", tool.Name(), core.ToolPendingKey)
+	}
+	if results == nil && !canPoll {
+		return nil, fmt.Errorf("tool %q answered asynchronously, but neither callbacks nor polling are available", tool.Name())
+	}
+
+	ctx, cancel := runtime.WithNodeTimeout(ctx, n.config.AsyncTimeout, env.Trace.RunID, n.ID(), n.Kind())
+	defer cancel()
+
+	var tick <-chan time.Time
+	if canPoll {
+		ticker := time.NewTicker(n.config.PollInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	endWait := runtime.BeginWait(ctx, runtime.Wait{
+		RunID:    env.Trace.RunID,
+		NodeID:   n.ID(),
+		NodeKind: n.Kind(),
+		Reason:   runtime.WaitReasonTool,
+		Details:  map[string]any{"tool_name": tool.Name()},
+	})
+	defer endWait()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("tool %q: no result for token %q within %s: %w",
+					tool.Name(), token, n.config.AsyncTimeout, ctx.Err())
+			}
+			return nil, ctx.Err()
+		case result := <-results:
+			if result.err == nil && result.outputs == nil {
+				return map[string]any{}, nil
+			}
+			return result.outputs, result.err
+		case <-tick:
+			pollCtx, cancelPoll := context.WithTimeout(ctx, n.config.Timeout)
+			outputs, err := poller.Poll(pollCtx, token)
+			cancelPoll()
+			if err != nil {
+				return nil, fmt.Errorf("tool %q: polling token %q: %w", tool.Name(), token, err)
+			}
+			if _, pending := outputs[core.ToolPendingKey]; !pending {
+				return outputs, nil
+			}
+		}
+	}
+}
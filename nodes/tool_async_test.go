@@ -0,0 +1,166 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// pollingTool answers asynchronously and finishes after a number of polls.
+type pollingTool struct {
+	polls     atomic.Int32
+	doneAfter int32
+}
+
+func (p *pollingTool) Name() string { return "export" }
+
+func (p *pollingTool) Invoke(context.Context, map[string]any) (map[string]any, error) {
+	return map[string]any{core.ToolPendingKey: map[string]any{"token": "job-1"}}, nil
+}
+
+func (p *pollingTool) Poll(_ context.Context, token string) (map[string]any, error) {
+	if p.polls.Add(1) < p.doneAfter {
+		return map[string]any{core.ToolPendingKey: map[string]any{"token": token}}, nil
+	}
+	return map[string]any{"rows": 42, "token": token}, nil
+}
+
+// callbackTool answers asynchronously with a tool-chosen job ID and
+// records the callback token the node issued it.
+func callbackTool(issued *string) core.PetalTool {
+	return core.NewFuncTool("export", "", func(ctx context.Context, _ map[string]any) (map[string]any, error) {
+		*issued = core.ToolCallbackToken(ctx)
+		return map[string]any{core.ToolPendingKey: map[string]any{"token": "job-1"}}, nil
+	})
+}
+
+func TestToolNode_AsyncResultByCallback(t *testing.T) {
+	callbacks := NewToolCallbacks()
+	var issued string
+	node := NewToolNode("export", callbackTool(&issued), ToolNodeConfig{Callbacks: callbacks})
+
+	var events []runtime.Event
+	waiting := make(chan struct{})
+	ctx := runtime.ContextWithEmitter(context.Background(), func(e runtime.Event) {
+		events = append(events, e)
+		if e.Kind == runtime.EventWaitStarted {
+			close(waiting)
+		}
+	})
+	go func() {
+		<-waiting
+		if err := callbacks.Deliver("job-1", map[string]any{"rows": 1}, nil); !errors.Is(err, ErrUnknownToolCallback) {
+			t.Errorf("Deliver to the tool's job ID = %v, want ErrUnknownToolCallback", err)
+		}
+		if err := callbacks.Deliver(issued, map[string]any{"rows": 42}, nil); err != nil {
+			t.Errorf("Deliver: %v", err)
+		}
+	}()
+
+	result, err := node.Run(ctx, core.NewEnvelope())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if output, _ := result.GetVar("export_output"); output.(map[string]any)["rows"] != 42 {
+		t.Errorf("export_output = %v", output)
+	}
+	var started runtime.Event
+	for _, e := range events {
+		if e.Kind == runtime.EventWaitStarted {
+			started = e
+		}
+	}
+	if !IsToolCallbackToken(issued) {
+		t.Errorf("issued token = %q", issued)
+	}
+	if started.Payload["reason"] != runtime.WaitReasonTool || started.Payload["token"] != nil {
+		t.Errorf("wait.started = %+v", started.Payload)
+	}
+
+	if err := callbacks.Deliver(issued, map[string]any{"rows": 1}, nil); !errors.Is(err, ErrDuplicateToolCallback) {
+		t.Errorf("second Deliver = %v, want ErrDuplicateToolCallback", err)
+	}
+}
+
+func TestToolNode_AsyncCallbackBeforeWait(t *testing.T) {
+	callbacks := NewToolCallbacks()
+	tool := core.NewFuncTool("export", "", func(ctx context.Context, _ map[string]any) (map[string]any, error) {
+		// The job finishes before the tool even answers.
+		if err := callbacks.Deliver(core.ToolCallbackToken(ctx), nil, errors.New("export quota exceeded")); err != nil {
+			t.Errorf("Deliver: %v", err)
+		}
+		return map[string]any{core.ToolPendingKey: map[string]any{"token": "job-1"}}, nil
+	})
+	node := NewToolNode("export", tool, ToolNodeConfig{Callbacks: callbacks})
+
+	_, err := node.Run(context.Background(), core.NewEnvelope())
+	if err == nil || !strings.Contains(err.Error(), "export quota exceeded") {
+		t.Fatalf("Run error = %v", err)
+	}
+}
+
+func TestToolNode_AsyncResultByPolling(t *testing.T) {
+	tool := &pollingTool{doneAfter: 3}
+	node := NewToolNode("export", tool, ToolNodeConfig{PollInterval: time.Millisecond})
+
+	result, err := node.Run(context.Background(), core.NewEnvelope())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if output, _ := result.GetVar("export_output"); output.(map[string]any)["rows"] != 42 {
+		t.Errorf("export_output = %v", output)
+	}
+	if polls := tool.polls.Load(); polls != 3 {
+		t.Errorf("polls = %d, want 3", polls)
+	}
+}
+
+func TestToolNode_AsyncTimeout(t *testing.T) {
+	callbacks := NewToolCallbacks()
+	var issued string
+	node := NewToolNode("export", callbackTool(&issued), ToolNodeConfig{Callbacks: callbacks, AsyncTimeout: 10 * time.Millisecond})
+
+	_, err := node.Run(context.Background(), core.NewEnvelope())
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run error = %v, want deadline exceeded", err)
+	}
+	if err := callbacks.Deliver(issued, map[string]any{}, nil); !errors.Is(err, ErrExpiredToolCallback) {
+		t.Errorf("late Deliver = %v, want ErrExpiredToolCallback", err)
+	}
+}
+
+func TestToolNode_AsyncWithoutCallbacksOrPolling(t *testing.T) {
+	tool := &mockPetalTool{name: "export", result: map[string]any{core.ToolPendingKey: map[string]any{"token": "job-1"}}}
+	node := NewToolNode("export", tool, ToolNodeConfig{})
+
+	if _, err := node.Run(context.Background(), core.NewEnvelope()); err == nil || !strings.Contains(err.Error(), "asynchronously") {
+		t.Fatalf("Run error = %v", err)
+	}
+}
+
+func TestToolCallbacks_OnlyIssuedTokensAcceptResults(t *testing.T) {
+	callbacks := NewToolCallbacks()
+	var issued string
+	tool := core.NewFuncTool("lookup", "", func(ctx context.Context, _ map[string]any) (map[string]any, error) {
+		issued = core.ToolCallbackToken(ctx)
+		return map[string]any{"ok": true}, nil
+	})
+	if _, err := NewToolNode("lookup", tool, ToolNodeConfig{Callbacks: callbacks}).Run(context.Background(), core.NewEnvelope()); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	for _, token := range []string{issued, "job-1", "0123456789abcdef0123456789abcdef"} {
+		if err := callbacks.Deliver(token, map[string]any{}, nil); !errors.Is(err, ErrUnknownToolCallback) {
+			t.Errorf("Deliver(%q) = %v, want ErrUnknownToolCallback", token, err)
+		}
+	}
+	if len(callbacks.waiting) != 0 || len(callbacks.closed) != 0 {
+		t.Errorf("a synchronous invocation left state behind: waiting %v, closed %v", callbacks.waiting, callbacks.closed)
+	}
+}
@@ -0,0 +1,150 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// ToolCacheBypass controls whether a tool invocation may use the tool
+// cache.
+type ToolCacheBypass string
+
+const (
+	// ToolCacheUse answers from the cache when it can. It is the default.
+	ToolCacheUse ToolCacheBypass = ""
+	// ToolCacheRefresh invokes the tool and replaces the cached response.
+	ToolCacheRefresh ToolCacheBypass = "refresh"
+	// ToolCacheSkip invokes the tool and leaves the cache untouched.
+	ToolCacheSkip ToolCacheBypass = "skip"
+)
+
+// Tool cache outcomes reported in the cache field of tool.result events.
+const (
+	ToolCacheHit      = "hit"
+	ToolCacheMiss     = "miss"
+	ToolCacheBypassed = "bypass"
+)
+
+// ParseToolCacheBypass validates a bypass mode.
+func ParseToolCacheBypass(s string) (ToolCacheBypass, error) {
+	switch mode := ToolCacheBypass(s); mode {
+	case ToolCacheUse, ToolCacheRefresh, ToolCacheSkip:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("tool cache bypass %q must be one of: refresh, skip", s)
+	}
+}
+
+type toolCacheBypassKey struct{}
+
+// WithToolCacheBypass returns a context whose tool invocations bypass the
+// tool cache as mode says, such as for one run that needs fresh answers.
+// A node's own bypass setting applies when it is stricter.
+func WithToolCacheBypass(ctx context.Context, mode ToolCacheBypass) context.Context {
+	if mode == ToolCacheUse {
+		return ctx
+	}
+	return context.WithValue(ctx, toolCacheBypassKey{}, mode)
+}
+
+func toolCacheBypassFromContext(ctx context.Context) ToolCacheBypass {
+	mode, _ := ctx.Value(toolCacheBypassKey{}).(ToolCacheBypass)
+	return mode
+}
+
+// stricterBypass returns the bypass mode that uses the cache least.
+func stricterBypass(a, b ToolCacheBypass) ToolCacheBypass {
+	rank := map[ToolCacheBypass]int{ToolCacheUse: 0, ToolCacheRefresh: 1, ToolCacheSkip: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// toolCacheOutputVar holds a cached response in its cache envelope.
+const toolCacheOutputVar = "output"
+
+// toolCacheLookup is the cache state of one tool invocation.
+type toolCacheLookup struct {
+	key    string
+	ttl    time.Duration
+	bypass ToolCacheBypass
+}
+
+// cacheLookup returns the invocation's cache state, or nil when the tool
+// is not cacheable or the node has no cache store.
+func (n *ToolNode) cacheLookup(ctx context.Context, tool core.PetalTool, args map[string]any) (*toolCacheLookup, error) {
+	if n.config.Cache == nil {
+		return nil, nil
+	}
+	cacheable, ok := tool.(core.CacheableTool)
+	if !ok {
+		return nil, nil
+	}
+	policy := cacheable.CachePolicy()
+	if policy == nil || policy.TTL <= 0 {
+		return nil, nil
+	}
+
+	keyed := args
+	if len(policy.VaryOn) > 0 {
+		keyed = make(map[string]any, len(policy.VaryOn))
+		for _, name := range policy.VaryOn {
+			if v, ok := args[name]; ok {
+				keyed[name] = v
+			}
+		}
+	}
+	hash, err := computeStableHash(keyed)
+	if err != nil {
+		return nil, fmt.Errorf("tool cache key: %w", err)
+	}
+	return &toolCacheLookup{
+		// Keyed by tool rather than node so every workflow shares entries.
+		key:    "tool:" + tool.Name() + ":" + hash,
+		ttl:    policy.TTL,
+		bypass: stricterBypass(n.config.CacheBypass, toolCacheBypassFromContext(ctx)),
+	}, nil
+}
+
+// get returns the cached response, if the lookup may use one and it is
+// fresh. Cache failures count as misses so they never fail the node.
+func (l *toolCacheLookup) get(ctx context.Context, store CacheStore) (map[string]any, bool) {
+	if l == nil || l.bypass != ToolCacheUse {
+		return nil, false
+	}
+	cached, found, err := store.Get(ctx, l.key)
+	if err != nil || !found {
+		return nil, false
+	}
+	raw, _ := cached.GetVar(toolCacheOutputVar)
+	result, ok := raw.(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	return deepCopyMap(result), true
+}
+
+// put stores a response unless the lookup skips the cache.
+func (l *toolCacheLookup) put(ctx context.Context, store CacheStore, result map[string]any) {
+	if l == nil || l.bypass == ToolCacheSkip {
+		return
+	}
+	env := core.NewEnvelope().WithVar(toolCacheOutputVar, deepCopyMap(result))
+	_ = store.Set(ctx, l.key, env, l.ttl)
+}
+
+// outcome reports the lookup for the tool.result event.
+func (l *toolCacheLookup) outcome(hit bool) string {
+	switch {
+	case hit:
+		return ToolCacheHit
+	case l.bypass != ToolCacheUse:
+		return ToolCacheBypassed
+	default:
+		return ToolCacheMiss
+	}
+}
@@ -0,0 +1,144 @@
+package nodes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// cacheableTool is a mockPetalTool whose responses may be cached.
+type cacheableTool struct {
+	mockPetalTool
+	policy *core.ToolCachePolicy
+}
+
+func (c *cacheableTool) CachePolicy() *core.ToolCachePolicy { return c.policy }
+
+func newGeocodeTool() *cacheableTool {
+	return &cacheableTool{
+		mockPetalTool: mockPetalTool{name: "geo.geocode", result: map[string]any{"lat": 52.5}},
+		policy:        &core.ToolCachePolicy{TTL: time.Minute, VaryOn: []string{"address"}},
+	}
+}
+
+// runGeocode runs a geocode node for address and returns the cache result
+// its tool.result event reported.
+func runGeocode(t *testing.T, ctx context.Context, tool core.PetalTool, cfg ToolNodeConfig, address string) string {
+	t.Helper()
+	var cache string
+	ctx = runtime.ContextWithEmitter(ctx, func(e runtime.Event) {
+		if e.Kind == runtime.EventToolResult {
+			cache, _ = e.Payload["cache"].(string)
+		}
+	})
+	cfg.ArgsTemplate = map[string]string{"address": "address", "request_id": "request_id"}
+	env := core.NewEnvelope().WithVar("address", address).WithVar("request_id", time.Now().String())
+	result, err := NewToolNode("geo", tool, cfg).Run(ctx, env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out, _ := result.GetVar("geo_output"); out.(map[string]any)["lat"] != 52.5 {
+		t.Errorf("output = %v", out)
+	}
+	return cache
+}
+
+func TestToolNode_CachesResponses(t *testing.T) {
+	tool := newGeocodeTool()
+	cfg := ToolNodeConfig{Cache: NewMemoryCacheStore()}
+	ctx := context.Background()
+
+	if got := runGeocode(t, ctx, tool, cfg, "Berlin"); got != ToolCacheMiss {
+		t.Errorf("first run cache = %q, want miss", got)
+	}
+	// Arguments outside vary_on do not change the key.
+	if got := runGeocode(t, ctx, tool, cfg, "Berlin"); got != ToolCacheHit {
+		t.Errorf("second run cache = %q, want hit", got)
+	}
+	if got := runGeocode(t, ctx, tool, cfg, "Paris"); got != ToolCacheMiss {
+		t.Errorf("other address cache = %q, want miss", got)
+	}
+	if len(tool.calls) != 2 {
+		t.Errorf("tool invoked %d times, want 2", len(tool.calls))
+	}
+}
+
+func TestToolNode_CacheBypass(t *testing.T) {
+	tool := newGeocodeTool()
+	store := NewMemoryCacheStore()
+	ctx := context.Background()
+
+	// skip neither reads nor writes the cache.
+	if got := runGeocode(t, ctx, tool, ToolNodeConfig{Cache: store, CacheBypass: ToolCacheSkip}, "Berlin"); got != ToolCacheBypassed {
+		t.Errorf("skip cache = %q, want bypass", got)
+	}
+	if got := runGeocode(t, ctx, tool, ToolNodeConfig{Cache: store}, "Berlin"); got != ToolCacheMiss {
+		t.Errorf("after skip cache = %q, want miss", got)
+	}
+
+	// refresh from the run context invokes the tool and rewrites the entry.
+	tool.result = map[string]any{"lat": 52.5, "refreshed": true}
+	refresh := WithToolCacheBypass(ctx, ToolCacheRefresh)
+	if got := runGeocode(t, refresh, tool, ToolNodeConfig{Cache: store}, "Berlin"); got != ToolCacheBypassed {
+		t.Errorf("refresh cache = %q, want bypass", got)
+	}
+	if len(tool.calls) != 3 {
+		t.Fatalf("tool invoked %d times, want 3", len(tool.calls))
+	}
+	cached, found, _ := store.Get(ctx, mustCacheKey(t, tool, "Berlin"))
+	if !found {
+		t.Fatal("refresh did not store the response")
+	}
+	if out, _ := cached.GetVar(toolCacheOutputVar); out.(map[string]any)["refreshed"] != true {
+		t.Errorf("cached output = %v", out)
+	}
+
+	// The stricter of the node and run settings applies.
+	if got := stricterBypass(ToolCacheSkip, ToolCacheRefresh); got != ToolCacheSkip {
+		t.Errorf("stricterBypass = %q, want skip", got)
+	}
+}
+
+func TestToolNode_DoesNotCacheFailures(t *testing.T) {
+	tool := newGeocodeTool()
+	tool.err = context.DeadlineExceeded
+	cfg := ToolNodeConfig{
+		Cache:       NewMemoryCacheStore(),
+		OnError:     core.ErrorPolicyContinue,
+		RetryPolicy: core.RetryPolicy{MaxAttempts: 1},
+	}
+	env := core.NewEnvelope().WithVar("address", "Berlin")
+	cfg.ArgsTemplate = map[string]string{"address": "address"}
+	for i := 0; i < 2; i++ {
+		if _, err := NewToolNode("geo", tool, cfg).Run(context.Background(), env.Clone()); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	}
+	if len(tool.calls) != 2 {
+		t.Errorf("tool invoked %d times, want 2", len(tool.calls))
+	}
+}
+
+func TestParseToolCacheBypass(t *testing.T) {
+	for _, mode := range []string{"", "refresh", "skip"} {
+		if _, err := ParseToolCacheBypass(mode); err != nil {
+			t.Errorf("ParseToolCacheBypass(%q): %v", mode, err)
+		}
+	}
+	if _, err := ParseToolCacheBypass("always"); err == nil {
+		t.Error("expected an error for an unknown mode")
+	}
+}
+
+func mustCacheKey(t *testing.T, tool core.PetalTool, address string) string {
+	t.Helper()
+	node := NewToolNode("geo", tool, ToolNodeConfig{Cache: NewMemoryCacheStore()})
+	lookup, err := node.cacheLookup(context.Background(), tool, map[string]any{"address": address})
+	if err != nil || lookup == nil {
+		t.Fatalf("cacheLookup = %v, %v", lookup, err)
+	}
+	return lookup.key
+}
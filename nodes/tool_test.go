@@ -3,10 +3,12 @@ package nodes
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
 	"github.com/petal-labs/petalflow/runtime"
 )
 
@@ -439,3 +441,136 @@ func (m *slowMockTool) Invoke(ctx context.Context, args map[string]any) (map[str
 		return map[string]any{"status": "ok"}, nil
 	}
 }
+
+func TestToolNode_ChaosDropsResponse(t *testing.T) {
+	tool := &mockPetalTool{name: "t", result: map[string]any{"ok": true}}
+	node := NewToolNode("call", tool, ToolNodeConfig{
+		RetryPolicy: core.RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond},
+		OnError:     core.ErrorPolicyRecord,
+	})
+	g := graph.NewGraph("chaos")
+	g.AddNode(node)
+	_ = g.SetEntry("call")
+
+	opts := runtime.DefaultRunOptions()
+	opts.Chaos = &runtime.ChaosConfig{ToolDropRate: 1}
+	env, err := runtime.NewRuntime().Run(context.Background(), g, nil, opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	// The tool ran on every attempt; only its responses were lost.
+	if len(tool.calls) != 3 {
+		t.Errorf("tool calls = %d, want 3", len(tool.calls))
+	}
+	if msg, _ := env.GetVar("call_output_error"); msg == nil {
+		t.Errorf("expected recorded error, vars = %v", env.Vars)
+	}
+}
+
+// schemaTool is a mockPetalTool that publishes an argument schema.
+type schemaTool struct {
+	mockPetalTool
+	schema map[string]any
+}
+
+func (s *schemaTool) ArgsSchema() map[string]any { return s.schema }
+
+func TestToolNode_ValidatesArgsAgainstSchema(t *testing.T) {
+	newTool := func() *schemaTool {
+		return &schemaTool{
+			mockPetalTool: mockPetalTool{name: "s3.list", result: map[string]any{}},
+			schema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"bucket": map[string]any{"type": "string"},
+					"limit":  map[string]any{"type": "integer"},
+				},
+				"required": []any{"bucket"},
+			},
+		}
+	}
+
+	env := core.NewEnvelope().WithVar("limit", "ten")
+	tool := newTool()
+	node := NewToolNode("list", tool, ToolNodeConfig{
+		ArgsTemplate: map[string]string{"limit": "limit"},
+		OnError:      core.ErrorPolicyRecord,
+	})
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(tool.calls) != 0 {
+		t.Fatal("tool invoked with invalid args")
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("errors = %+v", result.Errors)
+	}
+	argErrs, _ := result.Errors[0].Details["arg_errors"].([]JSONSchemaError)
+	if len(argErrs) != 2 || argErrs[0].InstancePath != "" || argErrs[1].InstancePath != "/limit" {
+		t.Errorf("arg_errors = %+v", argErrs)
+	}
+
+	// With the default fail policy the structured error is returned.
+	node = NewToolNode("list", newTool(), ToolNodeConfig{ArgsTemplate: map[string]string{"limit": "limit"}})
+	_, err = node.Run(context.Background(), core.NewEnvelope().WithVar("limit", "ten"))
+	var argsErr *ToolArgsError
+	if !errors.As(err, &argsErr) || argsErr.Tool != "s3.list" {
+		t.Fatalf("err = %v, want *ToolArgsError", err)
+	}
+}
+
+func TestToolNode_StrictArgsRejectsUndeclared(t *testing.T) {
+	env := core.NewEnvelope().WithVar("bucket", "b").WithVar("typo", "x")
+	cfg := ToolNodeConfig{ArgsTemplate: map[string]string{"bucket": "bucket", "bukket": "typo"}}
+	tool := &schemaTool{
+		mockPetalTool: mockPetalTool{name: "s3.list", result: map[string]any{}},
+		schema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"bucket": map[string]any{"type": "string"}},
+		},
+	}
+
+	if _, err := NewToolNode("list", tool, cfg).Run(context.Background(), env); err != nil {
+		t.Fatalf("lenient run: %v", err)
+	}
+
+	cfg.StrictArgs = true
+	_, err := NewToolNode("list", tool, cfg).Run(context.Background(), env)
+	if err == nil || !strings.Contains(err.Error(), "bukket") {
+		t.Fatalf("strict run err = %v, want undeclared bukket", err)
+	}
+}
+
+func TestToolNode_ReusesCompiledArgsSchema(t *testing.T) {
+	tool := &schemaTool{
+		mockPetalTool: mockPetalTool{name: "s3.list", result: map[string]any{}},
+		schema: map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"bucket": map[string]any{"type": "string"}},
+		},
+	}
+	node := NewToolNode("list", tool, ToolNodeConfig{ArgsTemplate: map[string]string{"bucket": "bucket"}})
+	env := core.NewEnvelope().WithVar("bucket", "b")
+
+	if _, err := node.Run(context.Background(), env); err != nil {
+		t.Fatalf("first run: %v", err)
+	}
+	compiled := node.schema
+	if _, err := node.Run(context.Background(), env); err != nil {
+		t.Fatalf("second run: %v", err)
+	}
+	if node.schema != compiled {
+		t.Error("unchanged schema was compiled again")
+	}
+
+	// A changed schema is compiled afresh.
+	tool.schema = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"bucket": map[string]any{"type": "integer"}},
+	}
+	var argsErr *ToolArgsError
+	if _, err := node.Run(context.Background(), env); !errors.As(err, &argsErr) {
+		t.Fatalf("err = %v, want *ToolArgsError for the new schema", err)
+	}
+}
@@ -1,7 +1,6 @@
 package nodes
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -72,6 +71,11 @@ type TransformNodeConfig struct {
 	// Uses {{.varname}} syntax to access envelope variables.
 	Template string
 
+	// TemplateSandbox, when set, restricts what Template may call and how
+	// much it may render. Sandboxed templates cannot reach the envelope
+	// through _env.
+	TemplateSandbox *TemplateSandbox
+
 	// Format specifies the format for stringify/parse ("json" or "yaml").
 	// Defaults to "json".
 	Format string
@@ -89,6 +93,13 @@ type TransformNodeConfig struct {
 	// ItemTransform specifies nested transformation for map operations.
 	ItemTransform *TransformNodeConfig
 
+	// Performance, when set, runs map operations in the large-collection
+	// mode described on CollectionPerformance. It applies when
+	// ItemTransform reads InputVar "_item" with a pick, omit, rename,
+	// flatten, stringify, parse or template transform; other item
+	// transforms run as usual.
+	Performance *CollectionPerformance
+
 	// CustomFunc provides custom transformation logic.
 	CustomFunc func(ctx context.Context, env *core.Envelope) (any, error)
 }
@@ -313,26 +324,17 @@ func (n *TransformNode) transformTemplate(env *core.Envelope) (any, error) {
 	}
 
 	// Create template with custom functions
-	tmpl, err := template.New("transform").Funcs(transformTemplateFuncs()).Parse(n.config.Template)
+	tmpl, err := parseNodeTemplate(n.config.TemplateSandbox, "transform", n.config.Template, transformTemplateFuncs(EnvelopeLocale(env)))
 	if err != nil {
 		return nil, fmt.Errorf("invalid template: %w", err)
 	}
 
-	// Build template data from envelope vars
-	data := make(map[string]any)
-	for k, v := range env.Vars {
-		data[k] = v
-	}
-	// Also include envelope itself for access to Input, Artifacts, etc.
-	data["_env"] = env
-	data["_input"] = env.Input
-
-	var buf bytes.Buffer
-	if err := tmpl.Execute(&buf, data); err != nil {
+	out, err := tmpl.render(transformTemplateData(env, n.config.TemplateSandbox != nil))
+	if err != nil {
 		return nil, fmt.Errorf("template execution failed: %w", err)
 	}
 
-	return buf.String(), nil
+	return out, nil
 }
 
 // transformStringify converts input to a string.
@@ -341,8 +343,12 @@ func (n *TransformNode) transformStringify(env *core.Envelope) (any, error) {
 	if err != nil {
 		return nil, err
 	}
+	return stringifyValue(input, n.config.Format)
+}
 
-	switch n.config.Format {
+// stringifyValue formats input as a string for transformStringify.
+func stringifyValue(input any, format string) (any, error) {
+	switch format {
 	case "json":
 		data, err := json.MarshalIndent(input, "", "  ")
 		if err != nil {
@@ -354,7 +360,7 @@ func (n *TransformNode) transformStringify(env *core.Envelope) (any, error) {
 		return fmt.Sprintf("%v", input), nil
 
 	default:
-		return nil, fmt.Errorf("unsupported stringify format: %s", n.config.Format)
+		return nil, fmt.Errorf("unsupported stringify format: %s", format)
 	}
 }
 
@@ -364,13 +370,17 @@ func (n *TransformNode) transformParse(env *core.Envelope) (any, error) {
 	if err != nil {
 		return nil, err
 	}
+	return parseValue(input, n.config.Format)
+}
 
+// parseValue parses input for transformParse.
+func parseValue(input any, format string) (any, error) {
 	inputStr, ok := input.(string)
 	if !ok {
 		return nil, fmt.Errorf("parse requires string input, got %T", input)
 	}
 
-	switch n.config.Format {
+	switch format {
 	case "json":
 		var result any
 		if err := json.Unmarshal([]byte(inputStr), &result); err != nil {
@@ -379,7 +389,7 @@ func (n *TransformNode) transformParse(env *core.Envelope) (any, error) {
 		return result, nil
 
 	default:
-		return nil, fmt.Errorf("unsupported parse format: %s", n.config.Format)
+		return nil, fmt.Errorf("unsupported parse format: %s", format)
 	}
 }
 
@@ -401,6 +411,9 @@ func (n *TransformNode) transformMap(ctx context.Context, env *core.Envelope) (a
 
 	// Create a child transform node for processing items
 	childNode := NewTransformNode(n.ID()+"_item", *n.config.ItemTransform)
+	if n.config.Performance != nil && childNode.itemFuncSupported() {
+		return n.mapFast(ctx, env, childNode, items)
+	}
 
 	results := make([]any, len(items))
 	for i, item := range items {
@@ -456,8 +469,9 @@ func (n *TransformNode) getInput(env *core.Envelope) (any, error) {
 }
 
 // transformTemplateFuncs returns custom template functions for transform.
-func transformTemplateFuncs() template.FuncMap {
-	return template.FuncMap{
+// The locale functions default to locale.
+func transformTemplateFuncs(locale string) template.FuncMap {
+	funcs := template.FuncMap{
 		"json": func(v any) string {
 			data, err := json.Marshal(v)
 			if err != nil {
@@ -495,6 +509,10 @@ func transformTemplateFuncs() template.FuncMap {
 			return nil
 		},
 	}
+	for name, fn := range localeTemplateFuncs(locale) {
+		funcs[name] = fn
+	}
+	return funcs
 }
 
 // Ensure interface compliance at compile time.
@@ -0,0 +1,194 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// itemFunc transforms one map item without going through an envelope.
+type itemFunc func(item any) (any, error)
+
+// itemFuncSupported reports whether the node, run as a map ItemTransform,
+// can be replaced by an itemFunc. Templates that read _env need the
+// per-item envelope and are not.
+func (n *TransformNode) itemFuncSupported() bool {
+	if n.config.InputVar != "_item" || n.config.OutputVar == "" {
+		return false
+	}
+	switch n.config.Transform {
+	case TransformPick, TransformOmit, TransformRename, TransformFlatten,
+		TransformStringify, TransformParse:
+		return true
+	case TransformTemplate:
+		return n.config.TemplateSandbox != nil || !strings.Contains(n.config.Template, "_env")
+	}
+	return false
+}
+
+// newItemFunc returns an itemFunc that gives the same output as running the
+// node on a clone of env with _item set. Each goroutine needs its own: the
+// template one reuses its data map between items.
+func (n *TransformNode) newItemFunc(env *core.Envelope) itemFunc {
+	cfg := n.config
+	switch cfg.Transform {
+	case TransformPick:
+		paths := splitFieldPaths(cfg.Fields)
+		return func(item any) (any, error) {
+			if len(paths) == 0 {
+				return nil, fmt.Errorf("pick requires Fields")
+			}
+			inputMap, ok := toMap(item)
+			if !ok {
+				return nil, fmt.Errorf("pick requires map input, got %T", item)
+			}
+			result := make(map[string]any, len(paths))
+			for _, path := range paths {
+				if val, found := path.lookupMap(inputMap); found {
+					path.setMap(result, val)
+				}
+			}
+			return result, nil
+		}
+
+	case TransformOmit:
+		paths := splitFieldPaths(cfg.Fields)
+		return func(item any) (any, error) {
+			if len(paths) == 0 {
+				return nil, fmt.Errorf("omit requires Fields")
+			}
+			inputMap, ok := toMap(item)
+			if !ok {
+				return nil, fmt.Errorf("omit requires map input, got %T", item)
+			}
+			result := deepCopyMap(inputMap)
+			for _, path := range paths {
+				path.deleteMap(result)
+			}
+			return result, nil
+		}
+
+	case TransformRename:
+		type rename struct{ from, to fieldPath }
+		renames := make([]rename, 0, len(cfg.Mapping))
+		for from, to := range cfg.Mapping {
+			renames = append(renames, rename{
+				from: fieldPath(strings.Split(from, ".")),
+				to:   fieldPath(strings.Split(to, ".")),
+			})
+		}
+		return func(item any) (any, error) {
+			if len(renames) == 0 {
+				return nil, fmt.Errorf("rename requires Mapping")
+			}
+			inputMap, ok := toMap(item)
+			if !ok {
+				return nil, fmt.Errorf("rename requires map input, got %T", item)
+			}
+			result := deepCopyMap(inputMap)
+			for _, r := range renames {
+				if val, found := r.from.lookupMap(result); found {
+					r.from.deleteMap(result)
+					r.to.setMap(result, val)
+				}
+			}
+			return result, nil
+		}
+
+	case TransformFlatten:
+		return func(item any) (any, error) {
+			inputMap, ok := toMap(item)
+			if !ok {
+				return nil, fmt.Errorf("flatten requires map input, got %T", item)
+			}
+			result := make(map[string]any, len(inputMap))
+			flattenMap(inputMap, "", cfg.Separator, cfg.MaxDepth, 0, result)
+			return result, nil
+		}
+
+	case TransformStringify:
+		return func(item any) (any, error) {
+			return stringifyValue(item, cfg.Format)
+		}
+
+	case TransformParse:
+		return func(item any) (any, error) {
+			return parseValue(item, cfg.Format)
+		}
+
+	case TransformTemplate:
+		return n.newTemplateItemFunc(env)
+	}
+	return func(any) (any, error) {
+		return nil, fmt.Errorf("unknown transform type %q", cfg.Transform)
+	}
+}
+
+// newTemplateItemFunc parses the template once and renders every item with
+// one data map, changing only _item between renders.
+func (n *TransformNode) newTemplateItemFunc(env *core.Envelope) itemFunc {
+	cfg := n.config
+	if cfg.Template == "" {
+		return func(any) (any, error) {
+			return nil, fmt.Errorf("template requires Template string")
+		}
+	}
+	tmpl, err := parseNodeTemplate(cfg.TemplateSandbox, "transform", cfg.Template, transformTemplateFuncs(EnvelopeLocale(env)))
+	if err != nil {
+		return func(any) (any, error) {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+	}
+
+	data := transformTemplateData(env, cfg.TemplateSandbox != nil)
+	return func(item any) (any, error) {
+		data["_item"] = item
+		out, err := tmpl.render(data)
+		if err != nil {
+			return nil, fmt.Errorf("template execution failed: %w", err)
+		}
+		return out, nil
+	}
+}
+
+// splitFieldPaths splits each path as getNestedValue does.
+func splitFieldPaths(paths []string) []fieldPath {
+	out := make([]fieldPath, len(paths))
+	for i, p := range paths {
+		out[i] = fieldPath(strings.Split(p, "."))
+	}
+	return out
+}
+
+// mapFast runs a map transform in performance mode: items are transformed
+// straight into a preallocated result slice, without an envelope per item,
+// and large collections are split across goroutines.
+func (n *TransformNode) mapFast(ctx context.Context, env *core.Envelope, child *TransformNode, items []any) (any, error) {
+	results := make([]any, len(items))
+	workers := n.config.Performance.workers(len(items))
+	err := partitionItems(ctx, len(items), workers, func(ctx context.Context, lo, hi int) error {
+		if lo >= hi {
+			return nil
+		}
+		fn := child.newItemFunc(env)
+		for i := lo; i < hi; i++ {
+			if (i-lo)%checkEvery == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			out, err := fn(items[i])
+			if err != nil {
+				return fmt.Errorf("map item %d: transform node %s: %w", i, child.ID(), err)
+			}
+			results[i] = out
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,544 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// DefaultTranslateBatchSize is the number of texts sent per translation
+// call when TranslateNodeConfig.BatchSize is unset.
+const DefaultTranslateBatchSize = 20
+
+// TranslationRequest is one batch of texts to translate.
+type TranslationRequest struct {
+	Texts []string
+	// SourceLanguage is empty when the source language should be detected.
+	SourceLanguage string
+	TargetLanguage string
+	// Glossary maps source terms to the translation they must get. Only
+	// terms that occur in Texts are included.
+	Glossary map[string]string
+	// DoNotTranslate lists terms that must be kept verbatim. Only terms
+	// that occur in Texts are included.
+	DoNotTranslate []string
+	Instructions   string
+}
+
+// TranslationResponse holds one translation per request text, in order.
+type TranslationResponse struct {
+	Translations []string
+	// DetectedLanguage is the source language reported by the translator,
+	// if any.
+	DetectedLanguage string
+	Usage            core.TokenUsage
+}
+
+// Translator translates batches of texts. TranslateNode uses an LLM by
+// default; implement Translator to use a machine translation API instead.
+type Translator interface {
+	Translate(ctx context.Context, req TranslationRequest) (TranslationResponse, error)
+}
+
+// TranslateNodeConfig configures a TranslateNode.
+type TranslateNodeConfig struct {
+	// InputVar holds the text to translate: a string, a []string, or a
+	// []any of strings or objects. Required.
+	InputVar string
+
+	// Field is the dot path of the text inside each object when InputVar
+	// is a collection of objects. Objects are copied with the field
+	// replaced.
+	Field string
+
+	// OutputKey stores the translation, shaped like the input. Defaults to
+	// "{id}_output".
+	OutputKey string
+
+	// QualityKey stores a TranslationQuality. Defaults to
+	// "{OutputKey}_quality".
+	QualityKey string
+
+	// SourceLanguage is the language of the input. Empty or "auto" detects
+	// it.
+	SourceLanguage string
+
+	// TargetLanguage is the language to translate into. Required.
+	TargetLanguage string
+
+	// Glossary maps source terms to the translation they must always get.
+	Glossary map[string]string
+
+	// DoNotTranslate lists terms such as product names that must be kept
+	// verbatim.
+	DoNotTranslate []string
+
+	// Instructions adds style guidance, such as tone or formality, to the
+	// translation prompt.
+	Instructions string
+
+	// BatchSize is the number of texts per translation call. Defaults to
+	// DefaultTranslateBatchSize.
+	BatchSize int
+
+	// Strict fails the node when a glossary term or do-not-translate term
+	// is missing from a translation. Otherwise misses are only reported.
+	Strict bool
+
+	// Model is the model used by the default LLM translator.
+	Model string
+
+	// Translator replaces the default LLM translator.
+	Translator Translator
+
+	// RetryPolicy configures retries for each translation call.
+	RetryPolicy core.RetryPolicy
+
+	// Timeout bounds each translation call. Defaults to 60s.
+	Timeout time.Duration
+}
+
+// TranslationIssue is a glossary or do-not-translate term missing from one
+// translated item.
+type TranslationIssue struct {
+	Index    int    `json:"index"`
+	Term     string `json:"term"`
+	Expected string `json:"expected"`
+}
+
+// TranslationQuality reports how a TranslateNode run went.
+type TranslationQuality struct {
+	SourceLanguage string `json:"source_language,omitempty"`
+	// DetectedLanguages lists the languages the translator detected when
+	// SourceLanguage was not configured.
+	DetectedLanguages []string `json:"detected_languages,omitempty"`
+	TargetLanguage    string   `json:"target_language"`
+	Items             int      `json:"items"`
+	Batches           int      `json:"batches"`
+	// GlossaryApplied counts glossary terms found in a source text whose
+	// required translation appears in the output.
+	GlossaryApplied int                `json:"glossary_applied"`
+	GlossaryMissed  []TranslationIssue `json:"glossary_missed,omitempty"`
+	// PreservedMissed lists do-not-translate terms that were altered.
+	PreservedMissed []TranslationIssue `json:"preserved_missed,omitempty"`
+	// Unchanged lists items returned identical to their source, which
+	// usually means they were not translated.
+	Unchanged []int `json:"unchanged,omitempty"`
+	// LengthRatio is the translated length divided by the source length.
+	LengthRatio float64         `json:"length_ratio"`
+	Usage       core.TokenUsage `json:"usage"`
+	// Passed is false when any glossary or do-not-translate check missed.
+	Passed bool `json:"passed"`
+}
+
+// TranslateNode translates text between languages with glossary and
+// do-not-translate term lists, batching collections and recording quality
+// metadata next to the output.
+type TranslateNode struct {
+	core.BaseNode
+	config TranslateNodeConfig
+}
+
+// NewTranslateNode creates a new TranslateNode. client backs the default
+// LLM translator and may be nil when config.Translator is set.
+func NewTranslateNode(id string, client core.LLMClient, config TranslateNodeConfig) *TranslateNode {
+	if strings.EqualFold(config.SourceLanguage, "auto") {
+		config.SourceLanguage = ""
+	}
+	if config.OutputKey == "" {
+		config.OutputKey = id + "_output"
+	}
+	if config.QualityKey == "" {
+		config.QualityKey = config.OutputKey + "_quality"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultTranslateBatchSize
+	}
+	if config.Translator == nil && client != nil {
+		config.Translator = &LLMTranslator{Client: client, Model: config.Model}
+	}
+	if config.RetryPolicy.MaxAttempts == 0 {
+		config.RetryPolicy = core.DefaultRetryPolicy()
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 60 * time.Second
+	}
+
+	return &TranslateNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindLLM),
+		config:   config,
+	}
+}
+
+// Config returns the node's configuration.
+func (n *TranslateNode) Config() TranslateNodeConfig {
+	return n.config
+}
+
+// Run translates the input variable.
+func (n *TranslateNode) Run(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+	if n.config.TargetLanguage == "" {
+		return nil, fmt.Errorf("translate node %s: target_language is required", n.ID())
+	}
+	if n.config.Translator == nil {
+		return nil, fmt.Errorf("translate node %s: no translator configured", n.ID())
+	}
+	input, ok := env.GetVarNested(n.config.InputVar)
+	if !ok {
+		return nil, fmt.Errorf("translate node %s: input var %q not found", n.ID(), n.config.InputVar)
+	}
+	texts, rebuild, err := collectTexts(n.config.InputVar, n.config.Field, input)
+	if err != nil {
+		return nil, fmt.Errorf("translate node %s: %w", n.ID(), err)
+	}
+
+	quality := TranslationQuality{
+		SourceLanguage: n.config.SourceLanguage,
+		TargetLanguage: n.config.TargetLanguage,
+		Items:          len(texts),
+	}
+	translated := make([]string, len(texts))
+	copy(translated, texts)
+
+	// Blank items are passed through without a translation call.
+	var pending []int
+	for i, text := range texts {
+		if strings.TrimSpace(text) != "" {
+			pending = append(pending, i)
+		}
+	}
+	detected := make(map[string]bool)
+	for start := 0; start < len(pending); start += n.config.BatchSize {
+		batch := pending[start:min(start+n.config.BatchSize, len(pending))]
+		batchTexts := make([]string, len(batch))
+		for i, idx := range batch {
+			batchTexts[i] = texts[idx]
+		}
+		resp, err := n.translate(ctx, env, batchTexts)
+		if err != nil {
+			return nil, fmt.Errorf("translate node %s: %w", n.ID(), err)
+		}
+		for i, idx := range batch {
+			translated[idx] = resp.Translations[i]
+		}
+		if resp.DetectedLanguage != "" {
+			detected[strings.ToLower(resp.DetectedLanguage)] = true
+		}
+		quality.Usage = quality.Usage.Add(resp.Usage)
+		quality.Batches++
+	}
+
+	if quality.SourceLanguage == "" {
+		for lang := range detected {
+			quality.DetectedLanguages = append(quality.DetectedLanguages, lang)
+		}
+		sort.Strings(quality.DetectedLanguages)
+		if len(quality.DetectedLanguages) == 1 {
+			quality.SourceLanguage = quality.DetectedLanguages[0]
+		}
+	}
+	n.checkQuality(&quality, texts, translated)
+
+	if n.config.Strict && !quality.Passed {
+		env.SetVar(n.config.QualityKey, quality)
+		return nil, fmt.Errorf("translate node %s: %d glossary and %d do-not-translate terms missing from the translation",
+			n.ID(), len(quality.GlossaryMissed), len(quality.PreservedMissed))
+	}
+	env.SetVar(n.config.OutputKey, rebuild(translated))
+	env.SetVar(n.config.QualityKey, quality)
+	return env, nil
+}
+
+// collectTexts flattens the input into texts and returns a function that
+// puts processed texts back into the input's shape. field is the dot path
+// of the text inside object items; objects without it are passed through.
+func collectTexts(inputVar, field string, input any) ([]string, func([]string) any, error) {
+	switch v := input.(type) {
+	case string:
+		return []string{v}, func(out []string) any { return out[0] }, nil
+	case []string:
+		return v, func(out []string) any { return out }, nil
+	case []any:
+		texts := make([]string, len(v))
+		for i, item := range v {
+			switch typed := item.(type) {
+			case string:
+				texts[i] = typed
+			case map[string]any:
+				if field == "" {
+					return nil, nil, fmt.Errorf("item %d is an object; set field to the path of its text", i)
+				}
+				text, ok := getNestedValue(typed, field)
+				if !ok {
+					continue
+				}
+				s, ok := text.(string)
+				if !ok {
+					return nil, nil, fmt.Errorf("item %d: field %q is %T, not a string", i, field, text)
+				}
+				texts[i] = s
+			default:
+				return nil, nil, fmt.Errorf("item %d is %T, not a string or object", i, item)
+			}
+		}
+		return texts, func(out []string) any {
+			result := make([]any, len(v))
+			for i, item := range v {
+				obj, ok := item.(map[string]any)
+				if !ok {
+					result[i] = out[i]
+					continue
+				}
+				if _, has := getNestedValue(obj, field); !has {
+					result[i] = obj
+					continue
+				}
+				result[i] = setNestedCopy(obj, strings.Split(field, "."), out[i])
+			}
+			return result
+		}, nil
+	default:
+		return nil, nil, fmt.Errorf("input var %q is %T, not a string or collection", inputVar, input)
+	}
+}
+
+// setNestedCopy returns a copy of obj with the value at path replaced.
+// Maps along the path are copied; the input is left unchanged.
+func setNestedCopy(obj map[string]any, path []string, value any) map[string]any {
+	out := make(map[string]any, len(obj))
+	for k, v := range obj {
+		out[k] = v
+	}
+	if len(path) == 1 {
+		out[path[0]] = value
+		return out
+	}
+	child, _ := obj[path[0]].(map[string]any)
+	out[path[0]] = setNestedCopy(child, path[1:], value)
+	return out
+}
+
+// translate sends one batch with retries.
+func (n *TranslateNode) translate(ctx context.Context, env *core.Envelope, texts []string) (TranslationResponse, error) {
+	if n.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, n.config.Timeout)
+		defer cancel()
+	}
+	req := TranslationRequest{
+		Texts:          texts,
+		SourceLanguage: n.config.SourceLanguage,
+		TargetLanguage: n.config.TargetLanguage,
+		Instructions:   n.config.Instructions,
+	}
+	for term, translation := range n.config.Glossary {
+		if containsTerm(texts, term) {
+			if req.Glossary == nil {
+				req.Glossary = make(map[string]string)
+			}
+			req.Glossary[term] = translation
+		}
+	}
+	for _, term := range n.config.DoNotTranslate {
+		if containsTerm(texts, term) {
+			req.DoNotTranslate = append(req.DoNotTranslate, term)
+		}
+	}
+
+	var resp TranslationResponse
+	var err error
+	for attempt := 1; attempt <= n.config.RetryPolicy.MaxAttempts; attempt++ {
+		err = runtime.InjectProviderFault(ctx, env.Trace.RunID, n.ID(), n.Kind())
+		if err == nil {
+			resp, err = n.config.Translator.Translate(ctx, req)
+		}
+		if err == nil && len(resp.Translations) != len(texts) {
+			err = fmt.Errorf("translator returned %d translations for %d texts", len(resp.Translations), len(texts))
+		}
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil {
+			return TranslationResponse{}, ctx.Err()
+		}
+		if attempt < n.config.RetryPolicy.MaxAttempts {
+			select {
+			case <-ctx.Done():
+				return TranslationResponse{}, ctx.Err()
+			case <-time.After(n.config.RetryPolicy.Backoff * time.Duration(attempt)):
+			}
+		}
+	}
+	return TranslationResponse{}, err
+}
+
+// checkQuality fills in the glossary, do-not-translate, unchanged and
+// length checks.
+func (n *TranslateNode) checkQuality(q *TranslationQuality, source, translated []string) {
+	terms := make([]string, 0, len(n.config.Glossary))
+	for term := range n.config.Glossary {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	var inLen, outLen int
+	for i, text := range source {
+		inLen += len(text)
+		outLen += len(translated[i])
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		for _, term := range terms {
+			if !containsFold(text, term) {
+				continue
+			}
+			want := n.config.Glossary[term]
+			if containsFold(translated[i], want) {
+				q.GlossaryApplied++
+				continue
+			}
+			q.GlossaryMissed = append(q.GlossaryMissed, TranslationIssue{Index: i, Term: term, Expected: want})
+		}
+		for _, term := range n.config.DoNotTranslate {
+			if strings.Contains(text, term) && !strings.Contains(translated[i], term) {
+				q.PreservedMissed = append(q.PreservedMissed, TranslationIssue{Index: i, Term: term, Expected: term})
+			}
+		}
+		if translated[i] == text && !strings.EqualFold(q.SourceLanguage, q.TargetLanguage) {
+			q.Unchanged = append(q.Unchanged, i)
+		}
+	}
+	if inLen > 0 {
+		q.LengthRatio = float64(outLen) / float64(inLen)
+	}
+	q.Passed = len(q.GlossaryMissed) == 0 && len(q.PreservedMissed) == 0
+}
+
+func containsTerm(texts []string, term string) bool {
+	for _, text := range texts {
+		if containsFold(text, term) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// LLMTranslator translates with an LLM, asking for one JSON-encoded
+// translation per text.
+type LLMTranslator struct {
+	Client core.LLMClient
+	Model  string
+}
+
+// Translate implements Translator.
+func (t *LLMTranslator) Translate(ctx context.Context, req TranslationRequest) (TranslationResponse, error) {
+	input, err := json.Marshal(map[string]any{"texts": req.Texts})
+	if err != nil {
+		return TranslationResponse{}, err
+	}
+	resp, err := t.Client.Complete(ctx, core.LLMRequest{
+		Model:     t.Model,
+		System:    translateSystemPrompt(req),
+		InputText: string(input),
+		JSONSchema: map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"source_language": map[string]any{"type": "string"},
+				"translations": map[string]any{
+					"type":  "array",
+					"items": map[string]any{"type": "string"},
+				},
+			},
+			"required": []string{"translations"},
+		},
+	})
+	if err != nil {
+		return TranslationResponse{}, err
+	}
+
+	var parsed struct {
+		SourceLanguage string   `json:"source_language"`
+		Translations   []string `json:"translations"`
+	}
+	data := []byte(stripCodeFence(resp.Text))
+	if resp.JSON != nil {
+		data, _ = json.Marshal(resp.JSON)
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return TranslationResponse{}, fmt.Errorf("could not parse translations from: %s", resp.Text)
+	}
+	return TranslationResponse{
+		Translations:     parsed.Translations,
+		DetectedLanguage: parsed.SourceLanguage,
+		Usage: core.TokenUsage{
+			InputTokens:  resp.Usage.InputTokens,
+			OutputTokens: resp.Usage.OutputTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+			CostUSD:      resp.Usage.CostUSD,
+		},
+	}, nil
+}
+
+// translateSystemPrompt builds the instructions for one batch.
+func translateSystemPrompt(req TranslationRequest) string {
+	source := "the detected source language"
+	if req.SourceLanguage != "" {
+		source = req.SourceLanguage
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "You are a professional translator. Translate each text in the \"texts\" array from %s into %s. ", source, req.TargetLanguage)
+	b.WriteString("Preserve meaning, tone, formatting, markup and placeholders such as {{name}} or %s.\n")
+
+	if len(req.Glossary) > 0 {
+		terms := make([]string, 0, len(req.Glossary))
+		for term := range req.Glossary {
+			terms = append(terms, term)
+		}
+		sort.Strings(terms)
+		b.WriteString("\nAlways translate these terms as given:\n")
+		for _, term := range terms {
+			fmt.Fprintf(&b, "- %q -> %q\n", term, req.Glossary[term])
+		}
+	}
+	if len(req.DoNotTranslate) > 0 {
+		b.WriteString("\nNever translate these terms; keep them exactly as written:\n")
+		for _, term := range req.DoNotTranslate {
+			fmt.Fprintf(&b, "- %q\n", term)
+		}
+	}
+	if req.Instructions != "" {
+		b.WriteString("\n" + req.Instructions + "\n")
+	}
+	b.WriteString("\nRespond with JSON: {\"source_language\": \"<ISO 639-1 code of the source text>\", " +
+		"\"translations\": [<one translated string per input text, in order>]}.")
+	return b.String()
+}
+
+// stripCodeFence removes a Markdown code fence around a JSON reply.
+func stripCodeFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
+
+// Ensure interface compliance at compile time.
+var (
+	_ core.Node  = (*TranslateNode)(nil)
+	_ Translator = (*LLMTranslator)(nil)
+)
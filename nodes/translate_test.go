@@ -0,0 +1,232 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// dictTranslator translates word by word from a fixed dictionary.
+type dictTranslator struct {
+	words    map[string]string
+	detected string
+	requests []TranslationRequest
+	err      error
+}
+
+func (d *dictTranslator) Translate(_ context.Context, req TranslationRequest) (TranslationResponse, error) {
+	d.requests = append(d.requests, req)
+	if d.err != nil {
+		return TranslationResponse{}, d.err
+	}
+	out := make([]string, len(req.Texts))
+	for i, text := range req.Texts {
+		words := strings.Fields(text)
+		for j, w := range words {
+			if tr, ok := d.words[w]; ok {
+				words[j] = tr
+			}
+		}
+		out[i] = strings.Join(words, " ")
+	}
+	return TranslationResponse{Translations: out, DetectedLanguage: d.detected, Usage: core.TokenUsage{TotalTokens: 10}}, nil
+}
+
+func TestTranslateNode_String(t *testing.T) {
+	tr := &dictTranslator{words: map[string]string{"hello": "hallo", "world": "Welt"}, detected: "EN"}
+	node := NewTranslateNode("tr", nil, TranslateNodeConfig{
+		InputVar:       "text",
+		TargetLanguage: "de",
+		Translator:     tr,
+	})
+
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("text", "hello world"))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if out, _ := result.GetVar("tr_output"); out != "hallo Welt" {
+		t.Errorf("output = %v", out)
+	}
+	raw, _ := result.GetVar("tr_output_quality")
+	q := raw.(TranslationQuality)
+	if q.SourceLanguage != "en" || q.Items != 1 || q.Batches != 1 || !q.Passed || q.Usage.TotalTokens != 10 {
+		t.Errorf("quality = %+v", q)
+	}
+	if tr.requests[0].SourceLanguage != "" {
+		t.Errorf("source language = %q, want auto-detect", tr.requests[0].SourceLanguage)
+	}
+}
+
+func TestTranslateNode_BatchesObjects(t *testing.T) {
+	tr := &dictTranslator{words: map[string]string{"open": "offen", "closed": "geschlossen"}}
+	node := NewTranslateNode("tr", nil, TranslateNodeConfig{
+		InputVar:       "tickets",
+		Field:          "fields.status",
+		OutputKey:      "translated",
+		SourceLanguage: "en",
+		TargetLanguage: "de",
+		BatchSize:      2,
+		Translator:     tr,
+	})
+	tickets := []any{
+		map[string]any{"id": 1, "fields": map[string]any{"status": "open"}},
+		map[string]any{"id": 2, "fields": map[string]any{"status": ""}},
+		map[string]any{"id": 3, "fields": map[string]any{"status": "closed"}},
+		map[string]any{"id": 4},
+		map[string]any{"id": 5, "fields": map[string]any{"status": "open"}},
+	}
+
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("tickets", tickets))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	raw, _ := result.GetVar("translated")
+	out := raw.([]any)
+	var statuses []string
+	for _, item := range out {
+		status, _ := getNestedValue(item.(map[string]any), "fields.status")
+		s, _ := status.(string)
+		statuses = append(statuses, s)
+	}
+	if got := strings.Join(statuses, ","); got != "offen,,geschlossen,,offen" {
+		t.Errorf("statuses = %s", got)
+	}
+	if _, has := out[3].(map[string]any)["fields"]; has {
+		t.Error("item without the field should be unchanged")
+	}
+	if status, _ := getNestedValue(tickets[0].(map[string]any), "fields.status"); status != "open" {
+		t.Error("input was modified")
+	}
+	if len(tr.requests) != 2 || len(tr.requests[0].Texts) != 2 || len(tr.requests[1].Texts) != 1 {
+		t.Errorf("requests = %+v, want batches of 2 and 1 non-blank texts", tr.requests)
+	}
+}
+
+func TestTranslateNode_GlossaryAndDoNotTranslate(t *testing.T) {
+	tr := &dictTranslator{words: map[string]string{"workspace": "Arbeitsplatz", "PetalFlow": "Blütenfluss", "open": "öffnen"}}
+	node := NewTranslateNode("tr", nil, TranslateNodeConfig{
+		InputVar:       "texts",
+		SourceLanguage: "en",
+		TargetLanguage: "de",
+		Glossary:       map[string]string{"workspace": "Arbeitsbereich", "invoice": "Rechnung"},
+		DoNotTranslate: []string{"PetalFlow"},
+		Translator:     tr,
+	})
+	env := core.NewEnvelope().WithVar("texts", []string{"open workspace", "PetalFlow", "nothing"})
+
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	req := tr.requests[0]
+	if len(req.Glossary) != 1 || req.Glossary["workspace"] != "Arbeitsbereich" {
+		t.Errorf("glossary = %v, want only terms present in the batch", req.Glossary)
+	}
+	if len(req.DoNotTranslate) != 1 {
+		t.Errorf("do not translate = %v", req.DoNotTranslate)
+	}
+	raw, _ := result.GetVar("tr_output_quality")
+	q := raw.(TranslationQuality)
+	if q.Passed || len(q.GlossaryMissed) != 1 || q.GlossaryMissed[0].Index != 0 || len(q.PreservedMissed) != 1 {
+		t.Errorf("quality = %+v", q)
+	}
+	if len(q.Unchanged) != 1 || q.Unchanged[0] != 2 {
+		t.Errorf("unchanged = %v, want [2]", q.Unchanged)
+	}
+
+	strict := NewTranslateNode("tr", nil, TranslateNodeConfig{
+		InputVar:       "texts",
+		TargetLanguage: "de",
+		Glossary:       map[string]string{"workspace": "Arbeitsbereich"},
+		Strict:         true,
+		Translator:     tr,
+	})
+	if _, err := strict.Run(context.Background(), env); err == nil || !strings.Contains(err.Error(), "1 glossary") {
+		t.Fatalf("err = %v, want strict glossary failure", err)
+	}
+}
+
+func TestTranslateNode_Errors(t *testing.T) {
+	tr := &dictTranslator{}
+	node := NewTranslateNode("tr", nil, TranslateNodeConfig{InputVar: "text", Translator: tr})
+	if _, err := node.Run(context.Background(), core.NewEnvelope().WithVar("text", "hi")); err == nil {
+		t.Error("expected error without target language")
+	}
+
+	node = NewTranslateNode("tr", nil, TranslateNodeConfig{InputVar: "text", TargetLanguage: "de", Translator: tr})
+	if _, err := node.Run(context.Background(), core.NewEnvelope().WithVar("text", 42)); err == nil {
+		t.Error("expected error for a non-string input")
+	}
+	if _, err := node.Run(context.Background(), core.NewEnvelope().WithVar("text", []any{map[string]any{"a": "b"}})); err == nil {
+		t.Error("expected error for objects without field")
+	}
+
+	tr.err = errors.New("quota exceeded")
+	node = NewTranslateNode("tr", nil, TranslateNodeConfig{
+		InputVar:       "text",
+		TargetLanguage: "de",
+		Translator:     tr,
+		RetryPolicy:    core.RetryPolicy{MaxAttempts: 2},
+	})
+	if _, err := node.Run(context.Background(), core.NewEnvelope().WithVar("text", "hi")); err == nil || !strings.Contains(err.Error(), "quota exceeded") {
+		t.Fatalf("err = %v", err)
+	}
+	if len(tr.requests) != 2 {
+		t.Errorf("requests = %d, want 2 attempts", len(tr.requests))
+	}
+}
+
+func TestLLMTranslator(t *testing.T) {
+	client := &mockLLMClient{response: core.LLMResponse{
+		Text:  "```json\n{\"source_language\": \"fr\", \"translations\": [\"good morning\", \"thanks\"]}\n```",
+		Usage: core.LLMTokenUsage{InputTokens: 30, OutputTokens: 12, TotalTokens: 42},
+	}}
+	node := NewTranslateNode("tr", client, TranslateNodeConfig{
+		InputVar:       "texts",
+		TargetLanguage: "en",
+		Model:          "claude-haiku-4-5",
+		DoNotTranslate: []string{"Acme"},
+		Instructions:   "Use a formal tone.",
+	})
+
+	result, err := node.Run(context.Background(), core.NewEnvelope().WithVar("texts", []any{"bonjour", "merci Acme"}))
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	out, _ := result.GetVar("tr_output")
+	if got, _ := json.Marshal(out); string(got) != `["good morning","thanks"]` {
+		t.Errorf("output = %s", got)
+	}
+	raw, _ := result.GetVar("tr_output_quality")
+	q := raw.(TranslationQuality)
+	if q.SourceLanguage != "fr" || q.Usage.TotalTokens != 42 || len(q.PreservedMissed) != 1 {
+		t.Errorf("quality = %+v", q)
+	}
+
+	req := client.requests[0]
+	if req.Model != "claude-haiku-4-5" || req.JSONSchema == nil {
+		t.Errorf("request = %+v", req)
+	}
+	for _, want := range []string{"the detected source language into en", `"Acme"`, "Use a formal tone."} {
+		if !strings.Contains(req.System, want) {
+			t.Errorf("system prompt missing %q:\n%s", want, req.System)
+		}
+	}
+	if req.InputText != `{"texts":["bonjour","merci Acme"]}` {
+		t.Errorf("input = %s", req.InputText)
+	}
+
+	client.response = core.LLMResponse{JSON: map[string]any{"translations": []any{"only one"}}}
+	node = NewTranslateNode("tr", client, TranslateNodeConfig{
+		InputVar:       "texts",
+		TargetLanguage: "en",
+		RetryPolicy:    core.RetryPolicy{MaxAttempts: 1},
+	})
+	if _, err := node.Run(context.Background(), core.NewEnvelope().WithVar("texts", []any{"a", "b"})); err == nil || !strings.Contains(err.Error(), "1 translations for 2 texts") {
+		t.Fatalf("err = %v, want count mismatch", err)
+	}
+}
@@ -0,0 +1,274 @@
+package nodes
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// ValidateJSONAction defines what a ValidateJSONNode does with an invalid value.
+type ValidateJSONAction string
+
+const (
+	// ValidateJSONActionFail stops execution with an error listing every
+	// schema violation.
+	ValidateJSONActionFail ValidateJSONAction = "fail"
+
+	// ValidateJSONActionContinue records the result and continues to all
+	// successors.
+	ValidateJSONActionContinue ValidateJSONAction = "continue"
+
+	// ValidateJSONActionRoute records the result and routes to ErrorTarget
+	// instead of ValidTarget.
+	ValidateJSONActionRoute ValidateJSONAction = "route"
+)
+
+// ValidateJSONNodeConfig configures a ValidateJSONNode.
+type ValidateJSONNodeConfig struct {
+	// InputVar is the variable to validate (dot notation supported).
+	InputVar string
+
+	// Schema is a JSON Schema document. Schemas without a $schema keyword
+	// are treated as draft 2020-12. External $ref URLs are not fetched.
+	Schema map[string]any
+
+	// ResultVar stores the ValidateJSONResult. Defaults to "{id}_result".
+	ResultVar string
+
+	// OnFail determines behavior when validation fails.
+	// Defaults to ValidateJSONActionFail.
+	OnFail ValidateJSONAction
+
+	// ValidTarget and ErrorTarget are the successor node IDs used by
+	// ValidateJSONActionRoute. Both are required for that action.
+	ValidTarget string
+	ErrorTarget string
+}
+
+// JSONSchemaError describes one schema violation. Paths are JSON Pointers.
+type JSONSchemaError struct {
+	// InstancePath locates the offending value within the input.
+	InstancePath string `json:"instance_path"`
+
+	// SchemaPath locates the failing keyword within the schema.
+	SchemaPath string `json:"schema_path"`
+
+	Message string `json:"message"`
+}
+
+// ValidateJSONResult is stored in the envelope under ResultVar.
+type ValidateJSONResult struct {
+	Valid  bool              `json:"valid"`
+	Errors []JSONSchemaError `json:"errors,omitempty"`
+}
+
+// ValidateJSONNode validates a variable against a full JSON Schema. Unlike
+// the guardian's schema check, it supports the whole specification
+// ($ref, combinators, formats, conditionals) and reports every violation
+// with its instance and schema location.
+type ValidateJSONNode struct {
+	core.BaseNode
+	config ValidateJSONNodeConfig
+	schema *jsonschema.Schema
+}
+
+// schemaResourceURL names the in-memory schema document. It only appears
+// in $ref resolution and is stripped from reported schema paths.
+const schemaResourceURL = "mem://petalflow/schema.json"
+
+var schemaMessagePrinter = message.NewPrinter(language.English)
+
+// NewValidateJSONNode creates a ValidateJSONNode. The schema is compiled
+// eagerly, so an invalid schema is reported at construction time.
+func NewValidateJSONNode(id string, config ValidateJSONNodeConfig) (*ValidateJSONNode, error) {
+	if config.InputVar == "" {
+		return nil, fmt.Errorf("validate_json node %q: input_var is required", id)
+	}
+	if len(config.Schema) == 0 {
+		return nil, fmt.Errorf("validate_json node %q: schema is required", id)
+	}
+	if config.ResultVar == "" {
+		config.ResultVar = id + "_result"
+	}
+
+	switch config.OnFail {
+	case "":
+		config.OnFail = ValidateJSONActionFail
+	case ValidateJSONActionFail, ValidateJSONActionContinue:
+	case ValidateJSONActionRoute:
+		if config.ValidTarget == "" || config.ErrorTarget == "" {
+			return nil, fmt.Errorf("validate_json node %q: on_fail %q requires valid_target and error_target", id, config.OnFail)
+		}
+	default:
+		return nil, fmt.Errorf("validate_json node %q: unknown on_fail action %q", id, config.OnFail)
+	}
+
+	schema, err := compileJSONSchema(config.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("validate_json node %q: %w", id, err)
+	}
+
+	return &ValidateJSONNode{
+		BaseNode: core.NewBaseNode(id, core.NodeKindGuardian),
+		config:   config,
+		schema:   schema,
+	}, nil
+}
+
+// Config returns the node's configuration.
+func (n *ValidateJSONNode) Config() ValidateJSONNodeConfig {
+	return n.config
+}
+
+// Run validates the input variable and stores the result.
+func (n *ValidateJSONNode) Run(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+	result, err := n.validate(env)
+	if err != nil {
+		return nil, err
+	}
+
+	out := env.Clone()
+	out.SetVar(n.config.ResultVar, result)
+	if n.config.OnFail == ValidateJSONActionRoute {
+		out.SetVar(n.ID()+"_decision", n.decision(result))
+	}
+
+	if result.Valid || n.config.OnFail != ValidateJSONActionFail {
+		return out, nil
+	}
+
+	msgs := make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", displayPointer(e.InstancePath), e.Message))
+	}
+	return nil, fmt.Errorf("validate_json node %s: %q does not match schema: %s", n.ID(), n.config.InputVar, strings.Join(msgs, "; "))
+}
+
+// Route validates the input and selects ValidTarget or ErrorTarget. It
+// only affects execution when OnFail is ValidateJSONActionRoute.
+func (n *ValidateJSONNode) Route(_ context.Context, env *core.Envelope) (core.RouteDecision, error) {
+	result, err := n.validate(env)
+	if err != nil {
+		return core.RouteDecision{}, err
+	}
+	return n.decision(result), nil
+}
+
+func (n *ValidateJSONNode) decision(result ValidateJSONResult) core.RouteDecision {
+	if result.Valid {
+		return core.RouteDecision{Targets: []string{n.config.ValidTarget}, Reason: "schema valid"}
+	}
+	return core.RouteDecision{
+		Targets: []string{n.config.ErrorTarget},
+		Reason:  fmt.Sprintf("schema invalid: %d error(s)", len(result.Errors)),
+	}
+}
+
+func (n *ValidateJSONNode) validate(env *core.Envelope) (ValidateJSONResult, error) {
+	value, ok := env.GetVarNested(n.config.InputVar)
+	if !ok {
+		return ValidateJSONResult{}, fmt.Errorf("validate_json node %s: variable %q not found", n.ID(), n.config.InputVar)
+	}
+	instance, err := toJSONValue(value)
+	if err != nil {
+		return ValidateJSONResult{}, fmt.Errorf("validate_json node %s: %q is not JSON-encodable: %w", n.ID(), n.config.InputVar, err)
+	}
+
+	err = n.schema.Validate(instance)
+	if err == nil {
+		return ValidateJSONResult{Valid: true}, nil
+	}
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return ValidateJSONResult{}, fmt.Errorf("validate_json node %s: %w", n.ID(), err)
+	}
+	errs := collectSchemaErrors(verr, nil)
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].InstancePath != errs[j].InstancePath {
+			return errs[i].InstancePath < errs[j].InstancePath
+		}
+		return errs[i].SchemaPath < errs[j].SchemaPath
+	})
+	return ValidateJSONResult{Errors: errs}, nil
+}
+
+// compileJSONSchema compiles doc with draft 2020-12 as the default dialect.
+func compileJSONSchema(doc map[string]any) (*jsonschema.Schema, error) {
+	normalized, err := toJSONValue(doc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	c := jsonschema.NewCompiler()
+	c.DefaultDraft(jsonschema.Draft2020)
+	c.UseLoader(noRemoteSchemas{})
+	if err := c.AddResource(schemaResourceURL, normalized); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	schema, err := c.Compile(schemaResourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return schema, nil
+}
+
+// noRemoteSchemas refuses to load $ref targets outside the schema document,
+// so workflow definitions cannot make the server read files or fetch URLs.
+type noRemoteSchemas struct{}
+
+func (noRemoteSchemas) Load(url string) (any, error) {
+	return nil, fmt.Errorf("external schema %q is not allowed", url)
+}
+
+// toJSONValue converts v to the generic form the validator expects
+// (json.Number for numbers), so Go ints and structs validate the same way
+// as decoded JSON.
+func toJSONValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return jsonschema.UnmarshalJSON(bytes.NewReader(data))
+}
+
+// collectSchemaErrors flattens the validation error tree into its leaves,
+// which carry the specific violations.
+func collectSchemaErrors(verr *jsonschema.ValidationError, out []JSONSchemaError) []JSONSchemaError {
+	if len(verr.Causes) == 0 {
+		return append(out, JSONSchemaError{
+			InstancePath: jsonPointer(verr.InstanceLocation),
+			SchemaPath:   strings.TrimPrefix(verr.SchemaURL, schemaResourceURL+"#") + jsonPointer(verr.ErrorKind.KeywordPath()),
+			Message:      verr.ErrorKind.LocalizedString(schemaMessagePrinter),
+		})
+	}
+	for _, cause := range verr.Causes {
+		out = collectSchemaErrors(cause, out)
+	}
+	return out
+}
+
+func jsonPointer(tokens []string) string {
+	var sb strings.Builder
+	for _, tok := range tokens {
+		sb.WriteByte('/')
+		tok = strings.ReplaceAll(tok, "~", "~0")
+		sb.WriteString(strings.ReplaceAll(tok, "/", "~1"))
+	}
+	return sb.String()
+}
+
+func displayPointer(p string) string {
+	if p == "" {
+		return "(root)"
+	}
+	return p
+}
@@ -0,0 +1,168 @@
+package nodes
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+var orderSchema = map[string]any{
+	"type":     "object",
+	"required": []any{"id", "items"},
+	"properties": map[string]any{
+		"id":       map[string]any{"type": "string", "pattern": "^ord-"},
+		"items":    map[string]any{"type": "array", "minItems": 1, "items": map[string]any{"$ref": "#/$defs/item"}},
+		"discount": map[string]any{"oneOf": []any{map[string]any{"type": "null"}, map[string]any{"type": "number", "maximum": 1}}},
+	},
+	"additionalProperties": false,
+	"$defs": map[string]any{
+		"item": map[string]any{
+			"type":     "object",
+			"required": []any{"sku", "qty"},
+			"properties": map[string]any{
+				"sku": map[string]any{"type": "string"},
+				"qty": map[string]any{"type": "integer", "minimum": 1},
+			},
+		},
+	},
+}
+
+func TestValidateJSONNode_Valid(t *testing.T) {
+	node, err := NewValidateJSONNode("check", ValidateJSONNodeConfig{InputVar: "order", Schema: orderSchema})
+	if err != nil {
+		t.Fatalf("NewValidateJSONNode: %v", err)
+	}
+
+	env := core.NewEnvelope()
+	env.SetVar("order", map[string]any{
+		"id":       "ord-1",
+		"items":    []map[string]any{{"sku": "a", "qty": 2}},
+		"discount": nil,
+	})
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	got, _ := result.GetVar("check_result")
+	if r, ok := got.(ValidateJSONResult); !ok || !r.Valid || len(r.Errors) != 0 {
+		t.Errorf("check_result = %#v, want valid", got)
+	}
+}
+
+func TestValidateJSONNode_ReportsEveryViolation(t *testing.T) {
+	node, err := NewValidateJSONNode("check", ValidateJSONNodeConfig{
+		InputVar:  "order",
+		Schema:    orderSchema,
+		ResultVar: "validation",
+		OnFail:    ValidateJSONActionContinue,
+	})
+	if err != nil {
+		t.Fatalf("NewValidateJSONNode: %v", err)
+	}
+
+	env := core.NewEnvelope()
+	env.SetVar("order", map[string]any{
+		"id":       "x-1",
+		"items":    []any{map[string]any{"sku": "a", "qty": 0}, map[string]any{"qty": 1}},
+		"discount": 2,
+		"note":     "extra",
+	})
+	result, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	got, _ := result.GetVar("validation")
+	r := got.(ValidateJSONResult)
+	if r.Valid {
+		t.Fatal("expected invalid result")
+	}
+
+	want := []JSONSchemaError{
+		{InstancePath: "", SchemaPath: "/additionalProperties"},
+		{InstancePath: "/discount", SchemaPath: "/properties/discount/oneOf/0/type"},
+		{InstancePath: "/discount", SchemaPath: "/properties/discount/oneOf/1/maximum"},
+		{InstancePath: "/id", SchemaPath: "/properties/id/pattern"},
+		{InstancePath: "/items/0/qty", SchemaPath: "/$defs/item/properties/qty/minimum"},
+		{InstancePath: "/items/1", SchemaPath: "/$defs/item/required"},
+	}
+	if len(r.Errors) != len(want) {
+		t.Fatalf("errors = %+v, want %d entries", r.Errors, len(want))
+	}
+	for i, w := range want {
+		e := r.Errors[i]
+		if e.InstancePath != w.InstancePath || e.SchemaPath != w.SchemaPath || e.Message == "" {
+			t.Errorf("error[%d] = %+v, want instance %q schema %q", i, e, w.InstancePath, w.SchemaPath)
+		}
+	}
+}
+
+func TestValidateJSONNode_FailListsPaths(t *testing.T) {
+	node, err := NewValidateJSONNode("check", ValidateJSONNodeConfig{InputVar: "order", Schema: orderSchema})
+	if err != nil {
+		t.Fatalf("NewValidateJSONNode: %v", err)
+	}
+	env := core.NewEnvelope()
+	env.SetVar("order", map[string]any{"id": "ord-1"})
+
+	_, err = node.Run(context.Background(), env)
+	if err == nil || !strings.Contains(err.Error(), "(root): missing property 'items'") {
+		t.Fatalf("error = %v, want missing items at root", err)
+	}
+}
+
+func TestValidateJSONNode_RouteToErrorTarget(t *testing.T) {
+	node, err := NewValidateJSONNode("check", ValidateJSONNodeConfig{
+		InputVar:    "order",
+		Schema:      orderSchema,
+		OnFail:      ValidateJSONActionRoute,
+		ValidTarget: "process",
+		ErrorTarget: "reject",
+	})
+	if err != nil {
+		t.Fatalf("NewValidateJSONNode: %v", err)
+	}
+
+	for _, tt := range []struct {
+		order  map[string]any
+		target string
+	}{
+		{map[string]any{"id": "ord-1", "items": []any{map[string]any{"sku": "a", "qty": 1}}}, "process"},
+		{map[string]any{"id": "ord-1"}, "reject"},
+	} {
+		env := core.NewEnvelope()
+		env.SetVar("order", tt.order)
+		result, err := node.Run(context.Background(), env)
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		got, _ := result.GetVar("check_decision")
+		if d, ok := got.(core.RouteDecision); !ok || len(d.Targets) != 1 || d.Targets[0] != tt.target {
+			t.Errorf("decision = %#v, want %s", got, tt.target)
+		}
+	}
+}
+
+func TestNewValidateJSONNode_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ValidateJSONNodeConfig
+		errSub string
+	}{
+		{"missing input", ValidateJSONNodeConfig{Schema: orderSchema}, "input_var is required"},
+		{"missing schema", ValidateJSONNodeConfig{InputVar: "x"}, "schema is required"},
+		{"bad schema", ValidateJSONNodeConfig{InputVar: "x", Schema: map[string]any{"type": 5}}, "invalid schema"},
+		{"remote ref", ValidateJSONNodeConfig{InputVar: "x", Schema: map[string]any{"$ref": "file:///etc/passwd"}}, "invalid schema"},
+		{"route without targets", ValidateJSONNodeConfig{InputVar: "x", Schema: orderSchema, OnFail: ValidateJSONActionRoute}, "requires valid_target and error_target"},
+		{"unknown action", ValidateJSONNodeConfig{InputVar: "x", Schema: orderSchema, OnFail: "ignore"}, "unknown on_fail"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewValidateJSONNode("check", tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.errSub) {
+				t.Fatalf("error = %v, want containing %q", err, tt.errSub)
+			}
+		})
+	}
+}
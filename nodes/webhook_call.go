@@ -7,11 +7,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 	"text/template"
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/mask"
+	"github.com/petal-labs/petalflow/propagate"
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 // HTTPClient abstracts outbound HTTP execution.
@@ -28,6 +32,18 @@ const (
 	WebhookCallErrorPolicyRecord   WebhookCallErrorPolicy = "record"
 )
 
+// WebhookCallDelivery controls how a webhook_call node sends its request.
+type WebhookCallDelivery string
+
+const (
+	// WebhookCallDeliveryDirect sends the request while the node runs.
+	WebhookCallDeliveryDirect WebhookCallDelivery = "direct"
+	// WebhookCallDeliveryOutbox records the request in the node's Outbox
+	// for a delivery worker to send and retry. Without an Outbox the node
+	// sends directly.
+	WebhookCallDeliveryOutbox WebhookCallDelivery = "outbox"
+)
+
 // WebhookCallNodeConfig configures a WebhookCallNode.
 type WebhookCallNodeConfig struct {
 	URL              string
@@ -41,7 +57,16 @@ type WebhookCallNodeConfig struct {
 	Template         string
 	ResultVar        string
 	ErrorPolicy      WebhookCallErrorPolicy
+	Delivery         WebhookCallDelivery
 	HTTPClient       HTTPClient
+	// Outbox records requests of outbox delivery.
+	Outbox Outbox
+	// TemplateSandbox, when set, restricts what Template may call and how
+	// much it may render.
+	TemplateSandbox *TemplateSandbox
+	// PropagateHeaders layers the node's correlation header setting over
+	// the run's. Configured Headers win over correlation headers.
+	PropagateHeaders *propagate.Override
 }
 
 // ParseWebhookCallConfig normalizes webhook_call config from graph JSON.
@@ -52,6 +77,7 @@ func ParseWebhookCallConfig(m map[string]any) (WebhookCallNodeConfig, error) {
 		Template:    webhookConfigString(m, "template"),
 		ResultVar:   strings.TrimSpace(webhookConfigString(m, "result_var")),
 		ErrorPolicy: WebhookCallErrorPolicy(strings.TrimSpace(webhookConfigString(m, "error_policy"))),
+		Delivery:    WebhookCallDelivery(strings.TrimSpace(webhookConfigString(m, "delivery"))),
 		Timeout:     webhookConfigDuration(m, "timeout"),
 	}
 	if inputVars, ok := webhookConfigStringSlice(m, "input_vars"); ok {
@@ -74,6 +100,11 @@ func ParseWebhookCallConfig(m map[string]any) (WebhookCallNodeConfig, error) {
 			}
 		}
 	}
+	propagation, err := propagate.ParseOverride(m["propagate_headers"])
+	if err != nil {
+		return WebhookCallNodeConfig{}, err
+	}
+	cfg.PropagateHeaders = propagation
 
 	return normalizeWebhookCallConfig(cfg)
 }
@@ -98,6 +129,15 @@ func normalizeWebhookCallConfig(cfg WebhookCallNodeConfig) (WebhookCallNodeConfi
 	default:
 		return WebhookCallNodeConfig{}, fmt.Errorf("error_policy must be one of: fail, continue, record")
 	}
+	if cfg.Delivery == "" {
+		cfg.Delivery = WebhookCallDeliveryDirect
+	}
+	switch cfg.Delivery {
+	case WebhookCallDeliveryDirect, WebhookCallDeliveryOutbox:
+		// valid
+	default:
+		return WebhookCallNodeConfig{}, fmt.Errorf("delivery must be one of: direct, outbox")
+	}
 	if cfg.HTTPClient == nil {
 		cfg.HTTPClient = http.DefaultClient
 	}
@@ -127,6 +167,9 @@ func NewWebhookCallNode(id string, config WebhookCallNodeConfig) *WebhookCallNod
 		if normalized.ErrorPolicy == "" {
 			normalized.ErrorPolicy = WebhookCallErrorPolicyFail
 		}
+		if normalized.Delivery == "" {
+			normalized.Delivery = WebhookCallDeliveryDirect
+		}
 	}
 
 	return &WebhookCallNode{
@@ -146,17 +189,14 @@ func (n *WebhookCallNode) Run(ctx context.Context, env *core.Envelope) (*core.En
 		return nil, err
 	}
 
-	outputData := n.buildOutputData(env)
-	body, err := n.buildBody(outputData)
+	// Mask before templating so custom bodies can't leak masked fields.
+	outputData := mask.PolicyFromContext(ctx).ApplyMap(n.buildOutputData(env))
+	body, err := n.buildBody(outputData, EnvelopeLocale(env))
 	if err != nil {
 		return nil, fmt.Errorf("webhook_call node %s: %w", n.ID(), err)
 	}
 
-	requestCtx := ctx
-	cancel := func() {}
-	if n.config.Timeout > 0 {
-		requestCtx, cancel = context.WithTimeout(ctx, n.config.Timeout)
-	}
+	requestCtx, cancel := runtime.WithNodeTimeout(ctx, n.config.Timeout, env.Trace.RunID, n.ID(), n.Kind())
 	defer cancel()
 
 	req, err := http.NewRequestWithContext(requestCtx, n.config.Method, n.config.URL, bytes.NewReader(body))
@@ -168,14 +208,28 @@ func (n *WebhookCallNode) Run(ctx context.Context, env *core.Envelope) (*core.En
 	for key, value := range n.config.Headers {
 		req.Header.Set(key, value)
 	}
+	propagate.SetHeaders(n.config.PropagateHeaders.Apply(ctx), req.Header)
 
+	if n.config.Delivery == WebhookCallDeliveryOutbox && n.config.Outbox != nil {
+		return n.enqueue(ctx, env, req.Header, body)
+	}
+
+	endWait := runtime.BeginWait(requestCtx, runtime.Wait{
+		RunID:    env.Trace.RunID,
+		NodeID:   n.ID(),
+		NodeKind: n.Kind(),
+		Reason:   runtime.WaitReasonWebhook,
+		Details:  map[string]any{"method": n.config.Method, "url": redactedURL(req.URL)},
+	})
 	resp, err := n.config.HTTPClient.Do(req)
 	if err != nil {
+		endWait()
 		return n.handleFailure(env, 0, nil, nil, err)
 	}
 	defer resp.Body.Close()
 
 	respBody, readErr := io.ReadAll(resp.Body)
+	endWait()
 	if readErr != nil {
 		return n.handleFailure(env, resp.StatusCode, resp.Header, nil, fmt.Errorf("read response body: %w", readErr))
 	}
@@ -200,6 +254,40 @@ func (n *WebhookCallNode) Run(ctx context.Context, env *core.Envelope) (*core.En
 	return result, nil
 }
 
+// enqueue records the request in the outbox instead of sending it. The
+// result var reports the delivery as queued.
+func (n *WebhookCallNode) enqueue(ctx context.Context, env *core.Envelope, header http.Header, body []byte) (*core.Envelope, error) {
+	headers := make(map[string]string, len(header))
+	for key := range header {
+		headers[key] = header.Get(key)
+	}
+	delivery, err := n.config.Outbox.EnqueueDelivery(ctx, OutboxDelivery{
+		ID:      OutboxDeliveryID(env.Trace.RunID, n.ID(), body),
+		RunID:   env.Trace.RunID,
+		NodeID:  n.ID(),
+		Method:  n.config.Method,
+		URL:     n.config.URL,
+		Headers: headers,
+		Body:    string(body),
+	})
+	if err != nil {
+		return n.handleFailure(env, 0, nil, nil, fmt.Errorf("enqueue delivery: %w", err))
+	}
+
+	result := env.Clone()
+	if n.config.ResultVar != "" {
+		result.SetVar(n.config.ResultVar, map[string]any{
+			"ok":          true,
+			"queued":      true,
+			"delivery_id": delivery.ID,
+			"status":      delivery.Status,
+			"url":         n.config.URL,
+			"method":      n.config.Method,
+		})
+	}
+	return result, nil
+}
+
 func (n *WebhookCallNode) buildOutputData(env *core.Envelope) map[string]any {
 	data := make(map[string]any)
 
@@ -263,7 +351,7 @@ func (n *WebhookCallNode) buildOutputData(env *core.Envelope) map[string]any {
 	return data
 }
 
-func (n *WebhookCallNode) buildBody(payload map[string]any) ([]byte, error) {
+func (n *WebhookCallNode) buildBody(payload map[string]any, locale string) ([]byte, error) {
 	if n.config.Template == "" {
 		body, err := json.Marshal(payload)
 		if err != nil {
@@ -272,19 +360,29 @@ func (n *WebhookCallNode) buildBody(payload map[string]any) ([]byte, error) {
 		return body, nil
 	}
 
-	tpl, err := template.New("webhook_call").Funcs(webhookCallTemplateFuncs()).Parse(n.config.Template)
+	tpl, err := parseNodeTemplate(n.config.TemplateSandbox, "webhook_call", n.config.Template, webhookCallTemplateFuncs(locale))
 	if err != nil {
 		return nil, fmt.Errorf("parse template: %w", err)
 	}
-	var buf bytes.Buffer
-	if err := tpl.Execute(&buf, payload); err != nil {
+	body, err := tpl.render(payload)
+	if err != nil {
 		return nil, fmt.Errorf("execute template: %w", err)
 	}
-	return buf.Bytes(), nil
+	return []byte(body), nil
+}
+
+// redactedURL drops the query and credentials of u, which may carry
+// tokens, for wait events.
+func redactedURL(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	clean.RawQuery = ""
+	clean.Fragment = ""
+	return clean.String()
 }
 
-func webhookCallTemplateFuncs() template.FuncMap {
-	return template.FuncMap{
+func webhookCallTemplateFuncs(locale string) template.FuncMap {
+	funcs := template.FuncMap{
 		"json": func(v any) string {
 			data, err := json.Marshal(v)
 			if err != nil {
@@ -300,6 +398,10 @@ func webhookCallTemplateFuncs() template.FuncMap {
 			return string(data)
 		},
 	}
+	for name, fn := range localeTemplateFuncs(locale) {
+		funcs[name] = fn
+	}
+	return funcs
 }
 
 func (n *WebhookCallNode) handleFailure(
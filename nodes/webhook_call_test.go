@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/propagate"
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 func TestParseWebhookCallConfig_ValidatesURL(t *testing.T) {
@@ -173,6 +175,35 @@ func TestWebhookCallNode_Timeout(t *testing.T) {
 	}
 }
 
+func TestWebhookCallNode_TimeoutClampedToRunDeadline(t *testing.T) {
+	node := NewWebhookCallNode("call", WebhookCallNodeConfig{
+		URL:         "https://example.com/webhook",
+		Timeout:     time.Minute,
+		ErrorPolicy: WebhookCallErrorPolicyFail,
+		HTTPClient:  timeoutHTTPClient{delay: time.Second},
+	})
+
+	var clamped []runtime.Event
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ctx = runtime.ContextWithEmitter(ctx, func(e runtime.Event) {
+		if e.Kind == runtime.EventNodeDeadlineClamped {
+			clamped = append(clamped, e)
+		}
+	})
+
+	start := time.Now()
+	if _, err := node.Run(ctx, core.NewEnvelope()); err == nil {
+		t.Fatal("expected deadline error, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("call outlived the run deadline: %v", elapsed)
+	}
+	if len(clamped) != 1 || clamped[0].NodeID != "call" {
+		t.Fatalf("clamped events = %+v, want one for node call", clamped)
+	}
+}
+
 func TestWebhookCallNode_InvalidPolicy(t *testing.T) {
 	_, err := ParseWebhookCallConfig(map[string]any{
 		"url":          "https://example.com",
@@ -236,3 +267,139 @@ func ExampleWebhookCallNode() {
 	fmt.Println(len(mockClient.Requests))
 	// Output: 1
 }
+
+type recordingOutbox struct {
+	deliveries map[string]OutboxDelivery
+	order      []string
+}
+
+func (o *recordingOutbox) EnqueueDelivery(_ context.Context, d OutboxDelivery) (OutboxDelivery, error) {
+	if existing, ok := o.deliveries[d.ID]; ok {
+		return existing, nil
+	}
+	d.Status = DeliveryPending
+	o.deliveries[d.ID] = d
+	o.order = append(o.order, d.ID)
+	return d, nil
+}
+
+func TestWebhookCallNode_OutboxDelivery(t *testing.T) {
+	mockClient := NewMockHTTPClient(200)
+	outbox := &recordingOutbox{deliveries: map[string]OutboxDelivery{}}
+	cfg, err := ParseWebhookCallConfig(map[string]any{
+		"url":        "https://example.com/hooks/orders",
+		"delivery":   "outbox",
+		"headers":    map[string]any{"Authorization": "Bearer t0k"},
+		"result_var": "sent",
+	})
+	if err != nil {
+		t.Fatalf("ParseWebhookCallConfig() error = %v", err)
+	}
+	cfg.HTTPClient = mockClient
+	cfg.Outbox = outbox
+	node := NewWebhookCallNode("notify", cfg)
+
+	env := core.NewEnvelope().WithVar("order_id", "ord_123")
+	env.Trace.RunID = "run-1"
+	out, err := node.Run(context.Background(), env)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(mockClient.Requests) != 0 {
+		t.Fatalf("outbox delivery sent %d requests directly", len(mockClient.Requests))
+	}
+	if len(outbox.order) != 1 {
+		t.Fatalf("enqueued %d deliveries, want 1", len(outbox.order))
+	}
+	d := outbox.deliveries[outbox.order[0]]
+	if d.RunID != "run-1" || d.NodeID != "notify" || d.Method != http.MethodPost || d.URL != "https://example.com/hooks/orders" {
+		t.Fatalf("delivery = %+v", d)
+	}
+	if d.Headers["Authorization"] != "Bearer t0k" || d.Headers["Content-Type"] != "application/json" {
+		t.Fatalf("delivery headers = %v", d.Headers)
+	}
+	if !strings.Contains(d.Body, `"order_id":"ord_123"`) {
+		t.Fatalf("delivery body = %s", d.Body)
+	}
+	sent, _ := out.GetVar("sent")
+	result, _ := sent.(map[string]any)
+	if result["queued"] != true || result["delivery_id"] != d.ID || result["status"] != DeliveryPending {
+		t.Fatalf("sent = %v", sent)
+	}
+
+	// Running again for the same request records no second delivery.
+	if _, err := node.Run(context.Background(), env); err != nil {
+		t.Fatalf("second Run() error = %v", err)
+	}
+	if len(outbox.order) != 1 {
+		t.Fatalf("enqueued %d deliveries after rerun, want 1", len(outbox.order))
+	}
+}
+
+func TestWebhookCallNode_PropagatesRunHeaders(t *testing.T) {
+	cfg, err := ParseWebhookCallConfig(map[string]any{
+		"url":               "https://example.com/hooks/orders",
+		"headers":           map[string]any{"X-PetalFlow-Node-ID": "custom"},
+		"propagate_headers": map[string]any{"extra": map[string]any{"X-Team": "geo"}},
+	})
+	if err != nil {
+		t.Fatalf("ParseWebhookCallConfig() error = %v", err)
+	}
+	mockClient := NewMockHTTPClient(200)
+	cfg.HTTPClient = mockClient
+	env := core.NewEnvelope()
+	env.Trace.RunID = "run-1"
+	ctx := propagate.WithNode(context.Background(), "run-1", "notify")
+
+	// The node's extra headers turn propagation on for it alone.
+	if _, err := NewWebhookCallNode("notify", cfg).Run(ctx, env); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	header := mockClient.Requests[0].Header
+	if header.Get(propagate.HeaderRunID) != "run-1" || header.Get("X-Team") != "geo" || header.Get(propagate.HeaderTraceParent) == "" {
+		t.Fatalf("request headers = %v", header)
+	}
+	if got := header.Get(propagate.HeaderNodeID); got != "custom" {
+		t.Errorf("node ID header = %q, want the configured value", got)
+	}
+
+	// A node can opt out of propagation the run enables.
+	cfg.PropagateHeaders, _ = propagate.ParseOverride(false)
+	ctx = propagate.WithConfig(ctx, propagate.Config{Enabled: true})
+	if _, err := NewWebhookCallNode("notify", cfg).Run(ctx, env); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if got := mockClient.Requests[1].Header.Get(propagate.HeaderRunID); got != "" {
+		t.Errorf("opted-out request run ID header = %q", got)
+	}
+
+	if _, err := ParseWebhookCallConfig(map[string]any{"url": "https://example.com", "propagate_headers": "yes"}); err == nil {
+		t.Error("expected an error for an invalid propagate_headers")
+	}
+}
+
+func TestWebhookCallNode_OutboxWithoutStoreSendsDirectly(t *testing.T) {
+	mockClient := NewMockHTTPClient(200)
+	node := NewWebhookCallNode("notify", WebhookCallNodeConfig{
+		URL:        "https://example.com/hooks/orders",
+		Delivery:   WebhookCallDeliveryOutbox,
+		HTTPClient: mockClient,
+	})
+	if _, err := node.Run(context.Background(), core.NewEnvelope()); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(mockClient.Requests) != 1 {
+		t.Fatalf("request count = %d, want 1", len(mockClient.Requests))
+	}
+}
+
+func TestParseWebhookCallConfig_Delivery(t *testing.T) {
+	cfg, err := ParseWebhookCallConfig(map[string]any{"url": "https://example.com"})
+	if err != nil || cfg.Delivery != WebhookCallDeliveryDirect {
+		t.Fatalf("default delivery = %q, %v; want direct", cfg.Delivery, err)
+	}
+	_, err = ParseWebhookCallConfig(map[string]any{"url": "https://example.com", "delivery": "carrier-pigeon"})
+	if err == nil || !strings.Contains(err.Error(), "delivery must be one of") {
+		t.Fatalf("invalid delivery error = %v", err)
+	}
+}
@@ -38,3 +38,16 @@ func webhookConfigDuration(m map[string]any, key string) time.Duration {
 		return 0
 	}
 }
+
+func webhookConfigInt(m map[string]any, key string) int64 {
+	switch v := m[key].(type) {
+	case float64:
+		return int64(v)
+	case int:
+		return int64(v)
+	case int64:
+		return v
+	default:
+		return 0
+	}
+}
@@ -1,9 +1,14 @@
 package nodes
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"mime"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -33,6 +38,140 @@ type WebhookTriggerAuthConfig struct {
 	Token  string
 }
 
+// DefaultWebhookDedupeWindow is how long webhook event IDs are remembered
+// when WebhookDedupeConfig.Window is unset.
+const DefaultWebhookDedupeWindow = 24 * time.Hour
+
+// WebhookDedupeConfig suppresses redelivered webhook events.
+type WebhookDedupeConfig struct {
+	// EventID locates the event ID in the request: "body.<path>",
+	// "headers.<name>" or "query.<name>".
+	EventID string
+	// Window is how long an event ID is remembered.
+	Window time.Duration
+}
+
+// EventIDFrom returns the event ID in a normalized webhook request. ok is
+// false when the request does not carry one.
+func (c WebhookDedupeConfig) EventIDFrom(request map[string]any) (id string, ok bool) {
+	source, path, _ := strings.Cut(c.EventID, ".")
+	var value any
+	switch source {
+	case "body":
+		body, isMap := request["body"].(map[string]any)
+		if !isMap {
+			return "", false
+		}
+		value, ok = getNestedValue(body, path)
+	case "headers":
+		headers, _ := request["headers"].(map[string]any)
+		value, ok = headers[strings.ToLower(path)]
+	case "query":
+		query, _ := request["query"].(map[string]any)
+		if values, isList := query[path].([]string); isList && len(values) > 0 {
+			value, ok = values[0], true
+		}
+	}
+	if !ok {
+		return "", false
+	}
+	switch v := value.(type) {
+	case string:
+		id = v
+	case float64:
+		id = strconv.FormatFloat(v, 'f', -1, 64)
+	case bool, int, int64:
+		id = fmt.Sprint(v)
+	default:
+		return "", false
+	}
+	id = strings.TrimSpace(id)
+	return id, id != ""
+}
+
+// Errors of WebhookRequestLimits.CheckJSON.
+var (
+	ErrWebhookJSONTooDeep      = errors.New("JSON body is nested too deeply")
+	ErrWebhookJSONArrayTooLong = errors.New("JSON body has an array that is too long")
+)
+
+// WebhookRequestLimits shapes the requests a webhook trigger accepts.
+// Zero values leave a limit off.
+type WebhookRequestLimits struct {
+	// MaxBodyBytes caps the request body. The server's max_body still
+	// applies, so it can only lower the limit.
+	MaxBodyBytes int64
+	// ContentTypes lists the accepted media types of request bodies.
+	// Entries may end in "/*" to accept a whole family. Empty accepts any.
+	ContentTypes []string
+	// MaxJSONDepth caps the nesting of objects and arrays in JSON bodies.
+	MaxJSONDepth int
+	// MaxArrayLength caps the elements of every array in JSON bodies.
+	MaxArrayLength int
+}
+
+// AcceptsContentType reports whether a body of the media type may be
+// delivered.
+func (l WebhookRequestLimits) AcceptsContentType(mediaType string) bool {
+	if len(l.ContentTypes) == 0 {
+		return true
+	}
+	for _, allowed := range l.ContentTypes {
+		if allowed == mediaType {
+			return true
+		}
+		if family, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, family+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckJSON scans a JSON body against MaxJSONDepth and MaxArrayLength
+// without decoding it, so oversized structures are refused before they
+// are built. Malformed JSON is left for the decoder to report.
+func (l WebhookRequestLimits) CheckJSON(data []byte) error {
+	if l.MaxJSONDepth <= 0 && l.MaxArrayLength <= 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// lengths holds the element count of each open array, or -1 for
+	// open objects.
+	var lengths []int
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			// The end of the body, or malformed JSON.
+			return nil
+		}
+		if n := len(lengths); n > 0 && lengths[n-1] >= 0 {
+			if delim, ok := tok.(json.Delim); !ok || delim != ']' {
+				lengths[n-1]++
+				if l.MaxArrayLength > 0 && lengths[n-1] > l.MaxArrayLength {
+					return fmt.Errorf("%w: more than %d elements", ErrWebhookJSONArrayTooLong, l.MaxArrayLength)
+				}
+			}
+		}
+		delim, ok := tok.(json.Delim)
+		if !ok {
+			continue
+		}
+		switch delim {
+		case '[', '{':
+			if l.MaxJSONDepth > 0 && len(lengths) >= l.MaxJSONDepth {
+				return fmt.Errorf("%w: more than %d levels", ErrWebhookJSONTooDeep, l.MaxJSONDepth)
+			}
+			if delim == '[' {
+				lengths = append(lengths, 0)
+			} else {
+				lengths = append(lengths, -1)
+			}
+		case ']', '}':
+			lengths = lengths[:len(lengths)-1]
+		}
+	}
+}
+
 // WebhookTriggerNodeConfig configures a WebhookTriggerNode.
 type WebhookTriggerNodeConfig struct {
 	Methods     []string
@@ -43,6 +182,11 @@ type WebhookTriggerNodeConfig struct {
 	QueryVar    string
 	MetadataVar string
 	Timeout     time.Duration
+	// Dedupe answers redeliveries of an event with the original run
+	// instead of starting another. Nil disables it.
+	Dedupe *WebhookDedupeConfig
+	// Limits are checked before a delivery starts a run.
+	Limits WebhookRequestLimits
 }
 
 // ParseWebhookTriggerConfig normalizes webhook trigger config from graph JSON.
@@ -70,6 +214,21 @@ func ParseWebhookTriggerConfig(m map[string]any) (WebhookTriggerNodeConfig, erro
 	cfg.QueryVar = strings.TrimSpace(webhookConfigString(m, "query_var"))
 	cfg.MetadataVar = strings.TrimSpace(webhookConfigString(m, "metadata_var"))
 	cfg.Timeout = webhookConfigDuration(m, "timeout")
+	if dedupeRaw, ok := m["dedupe"].(map[string]any); ok {
+		cfg.Dedupe = &WebhookDedupeConfig{
+			EventID: strings.TrimSpace(webhookConfigMapString(dedupeRaw, "event_id")),
+			Window:  webhookConfigDuration(dedupeRaw, "window"),
+		}
+	}
+	if limitsRaw, ok := m["limits"].(map[string]any); ok {
+		contentTypes, _ := webhookConfigStringSlice(limitsRaw, "content_types")
+		cfg.Limits = WebhookRequestLimits{
+			MaxBodyBytes:   webhookConfigInt(limitsRaw, "max_body_bytes"),
+			ContentTypes:   contentTypes,
+			MaxJSONDepth:   int(webhookConfigInt(limitsRaw, "max_json_depth")),
+			MaxArrayLength: int(webhookConfigInt(limitsRaw, "max_array_length")),
+		}
+	}
 
 	return normalizeWebhookTriggerConfig(cfg)
 }
@@ -106,6 +265,39 @@ func normalizeWebhookTriggerConfig(cfg WebhookTriggerNodeConfig) (WebhookTrigger
 		return WebhookTriggerNodeConfig{}, fmt.Errorf("auth.type must be one of: none, header_token")
 	}
 
+	if cfg.Dedupe != nil {
+		source, path, _ := strings.Cut(cfg.Dedupe.EventID, ".")
+		switch {
+		case source != "body" && source != "headers" && source != "query", path == "":
+			return WebhookTriggerNodeConfig{}, fmt.Errorf("dedupe.event_id must be body.<path>, headers.<name> or query.<name>")
+		case cfg.Dedupe.Window < 0:
+			return WebhookTriggerNodeConfig{}, fmt.Errorf("dedupe.window must not be negative")
+		case cfg.Dedupe.Window == 0:
+			cfg.Dedupe.Window = DefaultWebhookDedupeWindow
+		}
+	}
+
+	switch {
+	case cfg.Limits.MaxBodyBytes < 0:
+		return WebhookTriggerNodeConfig{}, fmt.Errorf("limits.max_body_bytes must not be negative")
+	case cfg.Limits.MaxJSONDepth < 0:
+		return WebhookTriggerNodeConfig{}, fmt.Errorf("limits.max_json_depth must not be negative")
+	case cfg.Limits.MaxArrayLength < 0:
+		return WebhookTriggerNodeConfig{}, fmt.Errorf("limits.max_array_length must not be negative")
+	}
+	for i, raw := range cfg.Limits.ContentTypes {
+		contentType := strings.ToLower(strings.TrimSpace(raw))
+		// A family such as text/* must name a valid type.
+		check := contentType
+		if family, ok := strings.CutSuffix(contentType, "/*"); ok {
+			check = family + "/any"
+		}
+		if mediaType, params, err := mime.ParseMediaType(check); err != nil || mediaType != check || len(params) > 0 || !strings.Contains(check, "/") {
+			return WebhookTriggerNodeConfig{}, fmt.Errorf("limits.content_types[%d] %q is not a media type", i, raw)
+		}
+		cfg.Limits.ContentTypes[i] = contentType
+	}
+
 	if cfg.RequestVar == "" {
 		cfg.RequestVar = "webhook_request"
 	}
@@ -181,7 +373,7 @@ func (n *WebhookTriggerNode) Run(ctx context.Context, env *core.Envelope) (*core
 	}
 
 	metadata := map[string]any{}
-	for _, key := range []string{"workflow_id", "trigger_id", "method", "path", "remote_addr", "received_at"} {
+	for _, key := range []string{"workflow_id", "trigger_id", "method", "path", "remote_addr", "received_at", "event_id"} {
 		if value, exists := requestMap[key]; exists {
 			metadata[key] = value
 		}
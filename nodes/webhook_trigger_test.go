@@ -2,6 +2,7 @@ package nodes
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/petal-labs/petalflow/core"
@@ -103,3 +104,102 @@ func TestWebhookTriggerNode_Run_MissingRequest(t *testing.T) {
 		t.Fatal("expected error for missing __webhook_request, got nil")
 	}
 }
+
+func TestParseWebhookTriggerConfig_Dedupe(t *testing.T) {
+	cfg, err := ParseWebhookTriggerConfig(map[string]any{
+		"dedupe": map[string]any{"event_id": "headers.X-GitHub-Delivery"},
+	})
+	if err != nil {
+		t.Fatalf("ParseWebhookTriggerConfig() error = %v", err)
+	}
+	if cfg.Dedupe == nil || cfg.Dedupe.Window != DefaultWebhookDedupeWindow {
+		t.Fatalf("Dedupe = %+v, want default window", cfg.Dedupe)
+	}
+
+	for _, raw := range []map[string]any{
+		{"event_id": "id"},
+		{"event_id": "body."},
+		{"event_id": "body.id", "window": "-1h"},
+	} {
+		if _, err := ParseWebhookTriggerConfig(map[string]any{"dedupe": raw}); err == nil {
+			t.Errorf("dedupe %v: expected error", raw)
+		}
+	}
+}
+
+func TestWebhookDedupeConfig_EventIDFrom(t *testing.T) {
+	request := map[string]any{
+		"body":    map[string]any{"id": "evt_1", "seq": float64(1234567), "data": map[string]any{"object": map[string]any{"id": "obj_9"}}},
+		"headers": map[string]any{"x-github-delivery": "d-42"},
+		"query":   map[string]any{"event": []string{"q-7"}},
+	}
+	tests := map[string]string{
+		"body.id":                   "evt_1",
+		"body.seq":                  "1234567",
+		"body.data.object.id":       "obj_9",
+		"headers.X-GitHub-Delivery": "d-42",
+		"query.event":               "q-7",
+	}
+	for path, want := range tests {
+		got, ok := WebhookDedupeConfig{EventID: path}.EventIDFrom(request)
+		if !ok || got != want {
+			t.Errorf("EventIDFrom(%s) = %q, %v; want %q", path, got, ok, want)
+		}
+	}
+	if _, ok := (WebhookDedupeConfig{EventID: "body.missing"}).EventIDFrom(request); ok {
+		t.Error("missing path should report no event ID")
+	}
+	if _, ok := (WebhookDedupeConfig{EventID: "body.data"}).EventIDFrom(request); ok {
+		t.Error("object value should not be used as an event ID")
+	}
+}
+
+func TestParseWebhookTriggerConfig_Limits(t *testing.T) {
+	cfg, err := ParseWebhookTriggerConfig(map[string]any{
+		"limits": map[string]any{
+			"max_body_bytes":   float64(4096),
+			"content_types":    []any{"Application/JSON", "text/*"},
+			"max_json_depth":   float64(4),
+			"max_array_length": float64(100),
+		},
+	})
+	if err != nil {
+		t.Fatalf("ParseWebhookTriggerConfig() error = %v", err)
+	}
+	limits := cfg.Limits
+	if limits.MaxBodyBytes != 4096 || limits.MaxJSONDepth != 4 || limits.MaxArrayLength != 100 {
+		t.Fatalf("Limits = %+v", limits)
+	}
+	for mediaType, want := range map[string]bool{"application/json": true, "text/plain": true, "application/xml": false} {
+		if got := limits.AcceptsContentType(mediaType); got != want {
+			t.Errorf("AcceptsContentType(%q) = %v, want %v", mediaType, got, want)
+		}
+	}
+
+	for _, raw := range []map[string]any{
+		{"max_body_bytes": float64(-1)},
+		{"max_json_depth": float64(-1)},
+		{"content_types": []any{"json"}},
+		{"content_types": []any{"application/json; charset=utf-8"}},
+	} {
+		if _, err := ParseWebhookTriggerConfig(map[string]any{"limits": raw}); err == nil {
+			t.Errorf("limits %v: expected error", raw)
+		}
+	}
+}
+
+func TestWebhookRequestLimits_CheckJSON(t *testing.T) {
+	limits := WebhookRequestLimits{MaxJSONDepth: 2, MaxArrayLength: 3}
+	for body, want := range map[string]error{
+		`{"a": [1, 2, 3], "b": {"c": "[[[["}}`: nil,
+		`[{"a": 1}, {"b": [1]}]`:               ErrWebhookJSONTooDeep,
+		`{"a": {"b": {"c": 1}}}`:               ErrWebhookJSONTooDeep,
+		`{"a": [1, 2, 3, 4]}`:                  ErrWebhookJSONArrayTooLong,
+		`[[1], [2], [3], [4]]`:                 ErrWebhookJSONArrayTooLong,
+		`{"a": [1, `:                           nil,
+	} {
+		if err := limits.CheckJSON([]byte(body)); !errors.Is(err, want) {
+			t.Errorf("CheckJSON(%s) = %v, want %v", body, err, want)
+		}
+	}
+}
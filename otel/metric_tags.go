@@ -0,0 +1,117 @@
+package otel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// MetricTagPolicy controls which attribute values leave the process on
+// metrics recorded by a MetricsHandler. The zero value forwards every tag
+// and records every event, matching the handler's historical behavior.
+//
+// Transforms are applied in order: tags outside Allow are dropped, then
+// Hash and Bucket rewrite the remaining values. A tag listed in both Hash
+// and Bucket is bucketed.
+type MetricTagPolicy struct {
+	// Allow, when non-empty, is the complete list of tag keys that may be
+	// recorded. All other tags are dropped.
+	Allow []string `json:"allow,omitempty"`
+
+	// Hash lists tags whose values are replaced by a truncated SHA-256
+	// digest. Hashed values stay joinable across metrics but are not
+	// reversible.
+	Hash []string `json:"hash,omitempty"`
+
+	// Bucket maps tag keys to a bucket count. Values are hashed into one of
+	// N buckets, bounding the tag's cardinality at N.
+	Bucket map[string]int `json:"bucket,omitempty"`
+
+	// SampleRate is the fraction of runs whose events are recorded, in
+	// (0, 1]. Zero means 1. Sampling is keyed by run ID, so a sampled run
+	// contributes all of its events and an unsampled run contributes none.
+	// Recorded counts are not rescaled.
+	SampleRate float64 `json:"sample_rate,omitempty"`
+}
+
+// Validate reports configuration errors in the policy.
+func (p MetricTagPolicy) Validate() error {
+	if p.SampleRate < 0 || p.SampleRate > 1 {
+		return fmt.Errorf("metric tag policy: sample_rate must be between 0 and 1, got %v", p.SampleRate)
+	}
+	for key, n := range p.Bucket {
+		if n <= 0 {
+			return fmt.Errorf("metric tag policy: bucket count for %q must be positive, got %d", key, n)
+		}
+	}
+	return nil
+}
+
+// compiledTagPolicy is the lookup-friendly form of a MetricTagPolicy.
+type compiledTagPolicy struct {
+	allow      map[string]bool
+	hash       map[string]bool
+	bucket     map[string]int
+	sampleRate float64
+}
+
+func compileTagPolicy(p MetricTagPolicy) compiledTagPolicy {
+	c := compiledTagPolicy{bucket: p.Bucket, sampleRate: p.SampleRate}
+	if len(p.Allow) > 0 {
+		c.allow = make(map[string]bool, len(p.Allow))
+		for _, key := range p.Allow {
+			c.allow[key] = true
+		}
+	}
+	if len(p.Hash) > 0 {
+		c.hash = make(map[string]bool, len(p.Hash))
+		for _, key := range p.Hash {
+			c.hash[key] = true
+		}
+	}
+	return c
+}
+
+// sampled reports whether events for runID should be recorded.
+func (c compiledTagPolicy) sampled(runID string) bool {
+	if c.sampleRate == 0 || c.sampleRate >= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(runID))
+	// Map the hash onto [0, 1) using the top 53 bits for an exact float.
+	return float64(h.Sum64()>>11)/(1<<53) < c.sampleRate
+}
+
+// apply filters and rewrites attrs according to the policy.
+func (c compiledTagPolicy) apply(attrs []attribute.KeyValue) []attribute.KeyValue {
+	out := attrs[:0]
+	for _, kv := range attrs {
+		key := string(kv.Key)
+		if c.allow != nil && !c.allow[key] {
+			continue
+		}
+		if n, ok := c.bucket[key]; ok {
+			kv = attribute.String(key, bucketTagValue(kv.Value.Emit(), n))
+		} else if c.hash[key] {
+			kv = attribute.String(key, hashTagValue(kv.Value.Emit()))
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+func hashTagValue(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:8])
+}
+
+func bucketTagValue(value string, buckets int) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(value))
+	return strconv.Itoa(int(h.Sum32() % uint32(buckets)))
+}
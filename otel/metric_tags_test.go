@@ -0,0 +1,115 @@
+package otel_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/petal-labs/petalflow/core"
+	petalotel "github.com/petal-labs/petalflow/otel"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func TestMetricsHandler_TagPolicyAllowHashAndBucket(t *testing.T) {
+	reader, mp := newTestMeter()
+
+	h, err := petalotel.NewMetricsHandlerWithPolicy(mp.Meter("test"), petalotel.MetricTagPolicy{
+		Allow:  []string{"run_id", "workflow_id", "trigger"},
+		Hash:   []string{"workflow_id"},
+		Bucket: map[string]int{"run_id": 4},
+	})
+	if err != nil {
+		t.Fatalf("NewMetricsHandlerWithPolicy: %v", err)
+	}
+
+	h.Handle(runtime.Event{
+		Kind:    runtime.EventRunFinished,
+		RunID:   "run-user-42",
+		Time:    time.Now(),
+		Elapsed: time.Second,
+		Payload: map[string]any{
+			"trigger":     "webhook",
+			"workflow_id": "customer-jane@example.com",
+			"schedule_id": "nightly",
+		},
+	})
+
+	rm := collectMetrics(t, reader)
+	hist := findMetric(rm, "petalflow.run.duration").Data.(metricdata.Histogram[float64])
+	if len(hist.DataPoints) != 1 {
+		t.Fatalf("expected 1 data point, got %d", len(hist.DataPoints))
+	}
+
+	got := map[string]string{}
+	for _, attr := range hist.DataPoints[0].Attributes.ToSlice() {
+		got[string(attr.Key)] = attr.Value.AsString()
+	}
+	if _, ok := got["schedule_id"]; ok {
+		t.Error("schedule_id should be dropped by the allowlist")
+	}
+	if got["trigger"] != "webhook" {
+		t.Errorf("trigger = %q, want webhook", got["trigger"])
+	}
+	if wf := got["workflow_id"]; wf == "" || wf == "customer-jane@example.com" || len(wf) != 16 {
+		t.Errorf("workflow_id should be hashed, got %q", wf)
+	}
+	switch got["run_id"] {
+	case "0", "1", "2", "3":
+	default:
+		t.Errorf("run_id should be bucketed into 0-3, got %q", got["run_id"])
+	}
+}
+
+func TestMetricsHandler_TagPolicySamplesWholeRuns(t *testing.T) {
+	reader, mp := newTestMeter()
+
+	h, err := petalotel.NewMetricsHandlerWithPolicy(mp.Meter("test"), petalotel.MetricTagPolicy{SampleRate: 0.5})
+	if err != nil {
+		t.Fatalf("NewMetricsHandlerWithPolicy: %v", err)
+	}
+
+	const runs = 200
+	for i := 0; i < runs; i++ {
+		for _, node := range []string{"a", "b"} {
+			h.Handle(runtime.Event{
+				Kind:     runtime.EventNodeFinished,
+				RunID:    fmt.Sprintf("run-%d", i),
+				NodeID:   node,
+				NodeKind: core.NodeKindTool,
+				Elapsed:  time.Millisecond,
+			})
+		}
+	}
+
+	rm := collectMetrics(t, reader)
+	sum := findMetric(rm, "petalflow.node.executions").Data.(metricdata.Sum[int64])
+	counts := map[string]int64{}
+	for _, dp := range sum.DataPoints {
+		id, _ := dp.Attributes.Value("node_id")
+		counts[id.AsString()] = dp.Value
+	}
+	if counts["a"] != counts["b"] {
+		t.Fatalf("sampling should keep or drop whole runs, got a=%d b=%d", counts["a"], counts["b"])
+	}
+	if counts["a"] < runs/4 || counts["a"] > runs*3/4 {
+		t.Fatalf("sampled %d of %d runs at rate 0.5", counts["a"], runs)
+	}
+}
+
+func TestMetricTagPolicy_Validate(t *testing.T) {
+	tests := []petalotel.MetricTagPolicy{
+		{SampleRate: 1.5},
+		{SampleRate: -0.1},
+		{Bucket: map[string]int{"run_id": 0}},
+	}
+	for _, policy := range tests {
+		if err := policy.Validate(); err == nil {
+			t.Errorf("expected validation error for %+v", policy)
+		}
+	}
+	if err := (petalotel.MetricTagPolicy{}).Validate(); err != nil {
+		t.Errorf("zero policy should be valid: %v", err)
+	}
+}
@@ -10,17 +10,31 @@ import (
 )
 
 // MetricsHandler translates PetalFlow runtime events into OpenTelemetry metrics.
-// It records counters and histograms for node executions, failures, and run durations.
+// It records counters and histograms for node executions, failures, run
+// durations, tool cache lookups and LLM token usage.
 type MetricsHandler struct {
-	nodeExecutions metric.Int64Counter
-	nodeFailures   metric.Int64Counter
-	nodeDuration   metric.Float64Histogram
-	runDuration    metric.Float64Histogram
+	nodeExecutions   metric.Int64Counter
+	nodeFailures     metric.Int64Counter
+	nodeDuration     metric.Float64Histogram
+	runDuration      metric.Float64Histogram
+	toolCacheLookups metric.Int64Counter
+	llmTokens        metric.Int64Counter
+	tags             compiledTagPolicy
 }
 
 // NewMetricsHandler creates a MetricsHandler that uses the given meter to create
 // instruments for recording PetalFlow runtime metrics.
 func NewMetricsHandler(meter metric.Meter) (*MetricsHandler, error) {
+	return NewMetricsHandlerWithPolicy(meter, MetricTagPolicy{})
+}
+
+// NewMetricsHandlerWithPolicy is like NewMetricsHandler but filters, rewrites
+// and samples tags according to policy before anything reaches the meter.
+func NewMetricsHandlerWithPolicy(meter metric.Meter, policy MetricTagPolicy) (*MetricsHandler, error) {
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+
 	nodeExec, err := meter.Int64Counter("petalflow.node.executions",
 		metric.WithDescription("Number of node executions"),
 	)
@@ -51,17 +65,37 @@ func NewMetricsHandler(meter metric.Meter) (*MetricsHandler, error) {
 		return nil, err
 	}
 
+	toolCache, err := meter.Int64Counter("petalflow.tool.cache.lookups",
+		metric.WithDescription("Number of cacheable tool invocations by cache result"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	llmTokens, err := meter.Int64Counter("petalflow.llm.tokens",
+		metric.WithDescription("Tokens used by LLM nodes, by node and provider region"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &MetricsHandler{
-		nodeExecutions: nodeExec,
-		nodeFailures:   nodeFail,
-		nodeDuration:   nodeDur,
-		runDuration:    runDur,
+		nodeExecutions:   nodeExec,
+		nodeFailures:     nodeFail,
+		nodeDuration:     nodeDur,
+		runDuration:      runDur,
+		toolCacheLookups: toolCache,
+		llmTokens:        llmTokens,
+		tags:             compileTagPolicy(policy),
 	}, nil
 }
 
 // Handle processes a runtime event and records the appropriate metrics.
 // It implements runtime.EventHandler semantics.
 func (h *MetricsHandler) Handle(e runtime.Event) {
+	if !h.tags.sampled(e.RunID) {
+		return
+	}
 	switch e.Kind {
 	case runtime.EventNodeFinished:
 		h.handleNodeFinished(e)
@@ -69,13 +103,17 @@ func (h *MetricsHandler) Handle(e runtime.Event) {
 		h.handleNodeFailed(e)
 	case runtime.EventRunFinished:
 		h.handleRunFinished(e)
+	case runtime.EventToolResult:
+		h.handleToolResult(e)
+	case runtime.EventNodeOutputFinal:
+		h.handleOutputFinal(e)
 	}
 }
 
 // handleNodeFinished increments the execution counter and records duration.
 func (h *MetricsHandler) handleNodeFinished(e runtime.Event) {
 	ctx := context.Background()
-	attrs := metric.WithAttributes(
+	attrs := h.attributes(
 		attribute.String("node_kind", string(e.NodeKind)),
 		attribute.String("node_id", e.NodeID),
 	)
@@ -86,13 +124,51 @@ func (h *MetricsHandler) handleNodeFinished(e runtime.Event) {
 // handleNodeFailed increments the failure counter.
 func (h *MetricsHandler) handleNodeFailed(e runtime.Event) {
 	ctx := context.Background()
-	attrs := metric.WithAttributes(
+	attrs := h.attributes(
 		attribute.String("node_kind", string(e.NodeKind)),
 		attribute.String("node_id", e.NodeID),
 	)
 	h.nodeFailures.Add(ctx, 1, attrs)
 }
 
+// handleToolResult counts the cache result of cacheable tool invocations.
+func (h *MetricsHandler) handleToolResult(e runtime.Event) {
+	result, ok := e.Payload["cache"].(string)
+	if !ok || result == "" {
+		return
+	}
+	toolName, _ := e.Payload["tool_name"].(string)
+	attrs := h.attributes(
+		attribute.String("tool", toolName),
+		attribute.String("result", result),
+	)
+	h.toolCacheLookups.Add(context.Background(), 1, attrs)
+}
+
+// handleOutputFinal counts the tokens of LLM node outputs. The region
+// attribute is set for providers that route across regions.
+func (h *MetricsHandler) handleOutputFinal(e runtime.Event) {
+	var tokens int64
+	switch v := e.Payload["total_tokens"].(type) {
+	case int:
+		tokens = int64(v)
+	case int64:
+		tokens = v
+	case float64:
+		tokens = int64(v)
+	}
+	if tokens <= 0 {
+		return
+	}
+	attrList := []attribute.KeyValue{
+		attribute.String("node_id", e.NodeID),
+	}
+	if region, ok := e.Payload["region"].(string); ok && region != "" {
+		attrList = append(attrList, attribute.String("region", region))
+	}
+	h.llmTokens.Add(context.Background(), tokens, h.attributes(attrList...))
+}
+
 // handleRunFinished records the workflow run duration.
 func (h *MetricsHandler) handleRunFinished(e runtime.Event) {
 	ctx := context.Background()
@@ -114,6 +190,12 @@ func (h *MetricsHandler) handleRunFinished(e runtime.Event) {
 	if method, ok := e.Payload["webhook_method"].(string); ok && method != "" {
 		attrList = append(attrList, attribute.String("webhook_method", method))
 	}
-	attrs := metric.WithAttributes(attrList...)
+	attrs := h.attributes(attrList...)
 	h.runDuration.Record(ctx, e.Elapsed.Seconds(), attrs)
 }
+
+// attributes applies the handler's tag policy and wraps the result as a
+// measurement option.
+func (h *MetricsHandler) attributes(attrs ...attribute.KeyValue) metric.MeasurementOption {
+	return metric.WithAttributes(h.tags.apply(attrs)...)
+}
@@ -177,6 +177,106 @@ func TestMetricsHandler_NodeFailedIncrementsFailureCounter(t *testing.T) {
 	}
 }
 
+func TestMetricsHandler_ToolResultCountsCacheLookups(t *testing.T) {
+	reader, mp := newTestMeter()
+	meter := mp.Meter("test")
+
+	h, err := petalotel.NewMetricsHandler(meter)
+	if err != nil {
+		t.Fatalf("NewMetricsHandler: %v", err)
+	}
+
+	for _, result := range []string{"miss", "hit", "hit"} {
+		h.Handle(runtime.Event{
+			Kind:     runtime.EventToolResult,
+			RunID:    "run-1",
+			NodeID:   "geo",
+			NodeKind: core.NodeKindTool,
+			Time:     time.Now(),
+			Payload:  map[string]any{"tool_name": "geo.geocode", "cache": result},
+		})
+	}
+	// Tools that are not cacheable report no cache result.
+	h.Handle(runtime.Event{
+		Kind:     runtime.EventToolResult,
+		RunID:    "run-1",
+		NodeID:   "search",
+		NodeKind: core.NodeKindTool,
+		Time:     time.Now(),
+		Payload:  map[string]any{"tool_name": "search"},
+	})
+
+	rm := collectMetrics(t, reader)
+
+	lookups := findMetric(rm, "petalflow.tool.cache.lookups")
+	if lookups == nil {
+		t.Fatal("petalflow.tool.cache.lookups metric not found")
+	}
+	sumData, ok := lookups.Data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("expected Sum[int64] data, got %T", lookups.Data)
+	}
+	counts := map[string]int64{}
+	for _, dp := range sumData.DataPoints {
+		tool, _ := dp.Attributes.Value("tool")
+		if tool.AsString() != "geo.geocode" {
+			t.Errorf("unexpected tool attribute %q", tool.AsString())
+		}
+		result, _ := dp.Attributes.Value("result")
+		counts[result.AsString()] = dp.Value
+	}
+	if counts["hit"] != 2 || counts["miss"] != 1 || len(counts) != 2 {
+		t.Errorf("cache lookups = %v, want 2 hits and 1 miss", counts)
+	}
+}
+
+func TestMetricsHandler_OutputFinalCountsTokensByRegion(t *testing.T) {
+	reader, mp := newTestMeter()
+	meter := mp.Meter("test")
+
+	h, err := petalotel.NewMetricsHandler(meter)
+	if err != nil {
+		t.Fatalf("NewMetricsHandler: %v", err)
+	}
+
+	for _, payload := range []map[string]any{
+		{"total_tokens": 100, "region": "us-east"},
+		// Decoded from the event store.
+		{"total_tokens": float64(50), "region": "us-east"},
+		{"total_tokens": 30, "region": "eu-west"},
+		// Outputs without usage are not counted.
+		{"text": "done"},
+	} {
+		h.Handle(runtime.Event{
+			Kind:     runtime.EventNodeOutputFinal,
+			RunID:    "run-1",
+			NodeID:   "answer",
+			NodeKind: core.NodeKindLLM,
+			Time:     time.Now(),
+			Payload:  payload,
+		})
+	}
+
+	rm := collectMetrics(t, reader)
+
+	tokens := findMetric(rm, "petalflow.llm.tokens")
+	if tokens == nil {
+		t.Fatal("petalflow.llm.tokens metric not found")
+	}
+	sumData, ok := tokens.Data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("expected Sum[int64] data, got %T", tokens.Data)
+	}
+	byRegion := map[string]int64{}
+	for _, dp := range sumData.DataPoints {
+		region, _ := dp.Attributes.Value("region")
+		byRegion[region.AsString()] = dp.Value
+	}
+	if byRegion["us-east"] != 150 || byRegion["eu-west"] != 30 || len(byRegion) != 2 {
+		t.Errorf("tokens by region = %v, want 150 us-east and 30 eu-west", byRegion)
+	}
+}
+
 func TestMetricsHandler_RunFinishedRecordsWorkflowDuration(t *testing.T) {
 	reader, mp := newTestMeter()
 	meter := mp.Meter("test")
@@ -3,6 +3,7 @@ package otel
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
 	"go.opentelemetry.io/otel/attribute"
@@ -321,6 +322,17 @@ func (h *TracingHandler) ActiveRunSpanContext(runID string) trace.SpanContext {
 	return span.SpanContext()
 }
 
+// TraceParent returns the W3C traceparent of the active node span, or ""
+// when there is none. Set it as propagate.Config.TraceParent so outbound
+// requests join the run's trace.
+func (h *TracingHandler) TraceParent(runID, nodeID string) string {
+	sc := h.ActiveSpanContext(runID, nodeID)
+	if !sc.IsValid() {
+		return ""
+	}
+	return fmt.Sprintf("00-%s-%s-%s", sc.TraceID(), sc.SpanID(), sc.TraceFlags())
+}
+
 // handleLLMCall creates a child span under the node span for LLM requests.
 // It uses OpenTelemetry GenAI semantic conventions plus PetalFlow-specific attributes.
 func (h *TracingHandler) handleLLMCall(e runtime.Event) {
@@ -223,6 +223,10 @@ func TestTracingHandler_NodeFinishedEndsSpan(t *testing.T) {
 	if !sc.IsValid() {
 		t.Fatal("expected valid span before finish")
 	}
+	want := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-" + sc.TraceFlags().String()
+	if got := h.TraceParent("run-1", "node-a"); got != want {
+		t.Errorf("TraceParent = %q, want %q", got, want)
+	}
 
 	// Finish node
 	h.Handle(runtime.Event{
@@ -239,6 +243,9 @@ func TestTracingHandler_NodeFinishedEndsSpan(t *testing.T) {
 	if sc.IsValid() {
 		t.Error("expected invalid span context after node.finished")
 	}
+	if got := h.TraceParent("run-1", "node-a"); got != "" {
+		t.Errorf("TraceParent after node.finished = %q", got)
+	}
 
 	// End run to flush
 	h.Handle(runtime.Event{
@@ -44,6 +44,12 @@ type (
 	// NodeError is recorded when nodes fail but the graph continues.
 	NodeError = core.NodeError
 
+	// ProviderError is a failed LLM provider call with a normalized code.
+	ProviderError = core.ProviderError
+
+	// ProviderErrorCode is the normalized cause of a failed provider call.
+	ProviderErrorCode = core.ProviderErrorCode
+
 	// RouteDecision is produced by RouterNode to indicate which targets to activate.
 	RouteDecision = core.RouteDecision
 
@@ -80,6 +86,9 @@ type (
 	// LLMTokenUsage tracks token consumption for LLM calls.
 	LLMTokenUsage = core.LLMTokenUsage
 
+	// LLMCacheHints mark the stable prefix of an LLMRequest as cacheable.
+	LLMCacheHints = core.LLMCacheHints
+
 	// LLMToolCall represents a tool invocation requested by the model.
 	LLMToolCall = core.LLMToolCall
 
@@ -92,6 +101,13 @@ type (
 	// PetalTool is the tool interface for PetalFlow.
 	PetalTool = core.PetalTool
 
+	// CacheableTool is a PetalTool whose responses may be cached.
+	CacheableTool = core.CacheableTool
+
+	// ToolCachePolicy says how long and by which arguments a tool's
+	// responses are cached.
+	ToolCachePolicy = core.ToolCachePolicy
+
 	// FuncTool is a simple function-backed tool for PetalFlow.
 	FuncTool = core.FuncTool
 
@@ -148,6 +164,17 @@ const (
 	ErrorPolicyRecord   = core.ErrorPolicyRecord
 )
 
+// ProviderErrorCode constants
+const (
+	ProviderErrInvalidAPIKey   = core.ProviderErrInvalidAPIKey
+	ProviderErrModelNotFound   = core.ProviderErrModelNotFound
+	ProviderErrContextLength   = core.ProviderErrContextLength
+	ProviderErrContentFiltered = core.ProviderErrContentFiltered
+	ProviderErrRateLimited     = core.ProviderErrRateLimited
+	ProviderErrQuotaExceeded   = core.ProviderErrQuotaExceeded
+	ProviderErrUnavailable     = core.ProviderErrUnavailable
+)
+
 // Core package constructors
 var (
 	NewEnvelope        = core.NewEnvelope
@@ -157,6 +184,7 @@ var (
 	NewFuncTool        = core.NewFuncTool
 	NewToolRegistry    = core.NewToolRegistry
 	DefaultRetryPolicy = core.DefaultRetryPolicy
+	AsProviderError    = core.AsProviderError
 )
 
 // =============================================================================
@@ -212,6 +240,9 @@ type (
 	// RunOptions controls execution behavior.
 	RunOptions = runtime.RunOptions
 
+	// ResumeState restores completed nodes when resuming or retrying a run.
+	ResumeState = runtime.ResumeState
+
 	// BasicRuntime is a simple sequential runtime implementation.
 	BasicRuntime = runtime.BasicRuntime
 
@@ -283,6 +314,7 @@ const (
 	EventNodeOutput    = runtime.EventNodeOutput
 	EventNodeFailed    = runtime.EventNodeFailed
 	EventNodeFinished  = runtime.EventNodeFinished
+	EventNodeRestored  = runtime.EventNodeRestored
 	EventRouteDecision = runtime.EventRouteDecision
 	EventRunFinished   = runtime.EventRunFinished
 	EventStepPaused    = runtime.EventStepPaused
@@ -318,6 +350,7 @@ var (
 var (
 	NewRuntime                  = runtime.NewRuntime
 	DefaultRunOptions           = runtime.DefaultRunOptions
+	NewResumeState              = runtime.NewResumeState
 	NewEvent                    = runtime.NewEvent
 	MultiEventHandler           = runtime.MultiEventHandler
 	ChannelEventHandler         = runtime.ChannelEventHandler
@@ -340,12 +373,28 @@ type (
 	// LLMNodeConfig configures an LLMNode.
 	LLMNodeConfig = nodes.LLMNodeConfig
 
+	// PromptCacheConfig selects the cacheable prefix of an LLM node's prompt.
+	PromptCacheConfig = nodes.PromptCacheConfig
+
+	// CompactMessagesNode keeps envelope messages under a token budget.
+	CompactMessagesNode = nodes.CompactMessagesNode
+
+	// CompactMessagesNodeConfig configures a CompactMessagesNode.
+	CompactMessagesNodeConfig = nodes.CompactMessagesNodeConfig
+
+	// CompactMessagesResult reports what a compaction did.
+	CompactMessagesResult = nodes.CompactMessagesResult
+
 	// ToolNode executes a tool and stores the result.
 	ToolNode = nodes.ToolNode
 
 	// ToolNodeConfig configures a ToolNode.
 	ToolNodeConfig = nodes.ToolNodeConfig
 
+	// ToolCacheBypass controls whether a tool invocation may use the tool
+	// cache.
+	ToolCacheBypass = nodes.ToolCacheBypass
+
 	// RuleRouter routes based on configured rules.
 	RuleRouter = nodes.RuleRouter
 
@@ -490,6 +539,99 @@ type (
 	// PIIType identifies the type of PII detected.
 	PIIType = nodes.PIIType
 
+	// ValidateJSONNode validates a variable against a JSON Schema.
+	ValidateJSONNode = nodes.ValidateJSONNode
+
+	// ValidateJSONNodeConfig configures a ValidateJSONNode.
+	ValidateJSONNodeConfig = nodes.ValidateJSONNodeConfig
+
+	// ValidateJSONAction defines what happens when validation fails.
+	ValidateJSONAction = nodes.ValidateJSONAction
+
+	// ValidateJSONResult holds the validity and every schema violation.
+	ValidateJSONResult = nodes.ValidateJSONResult
+
+	// JSONSchemaError describes a single schema violation.
+	JSONSchemaError = nodes.JSONSchemaError
+
+	// NormalizeContentNode cleans inbound email and chat content.
+	NormalizeContentNode = nodes.NormalizeContentNode
+
+	// NormalizeContentNodeConfig configures a NormalizeContentNode.
+	NormalizeContentNodeConfig = nodes.NormalizeContentNodeConfig
+
+	// ContentNormalization describes what was done to one piece of content.
+	ContentNormalization = nodes.ContentNormalization
+
+	// CalcNode evaluates arithmetic formulas with exact decimal arithmetic.
+	CalcNode = nodes.CalcNode
+
+	// CalcNodeConfig configures a CalcNode.
+	CalcNodeConfig = nodes.CalcNodeConfig
+
+	// CalcFormula is one calculation of a CalcNode.
+	CalcFormula = nodes.CalcFormula
+
+	// CalcAudit records how a formula was computed.
+	CalcAudit = nodes.CalcAudit
+
+	// RoundingMode is how a CalcNode rounds results.
+	RoundingMode = nodes.RoundingMode
+
+	// PublishNode adds variables to the run's public output mid-run.
+	PublishNode = nodes.PublishNode
+
+	// PublishNodeConfig configures a PublishNode.
+	PublishNodeConfig = nodes.PublishNodeConfig
+
+	// ModelSelectNode chooses a provider/model with a bandit policy.
+	ModelSelectNode = nodes.ModelSelectNode
+
+	// ModelSelectNodeConfig configures a ModelSelectNode.
+	ModelSelectNodeConfig = nodes.ModelSelectNodeConfig
+
+	// ModelArm is a provider and model a ModelSelectNode can choose.
+	ModelArm = nodes.ModelArm
+
+	// ModelSelection records one choice of a ModelSelectNode.
+	ModelSelection = nodes.ModelSelection
+
+	// RewardNode reports a reward for a ModelSelectNode's choice.
+	RewardNode = nodes.RewardNode
+
+	// RewardNodeConfig configures a RewardNode.
+	RewardNodeConfig = nodes.RewardNodeConfig
+
+	// ArmStats are the accumulated statistics of one arm.
+	ArmStats = nodes.ArmStats
+
+	// ArmStatsStore persists model selections and arm statistics.
+	ArmStatsStore = nodes.ArmStatsStore
+
+	// StateGetNode reads a value kept across runs.
+	StateGetNode = nodes.StateGetNode
+
+	// StateGetNodeConfig configures a StateGetNode.
+	StateGetNodeConfig = nodes.StateGetNodeConfig
+
+	// StateSetNode stores a value across runs.
+	StateSetNode = nodes.StateSetNode
+
+	// StateSetNodeConfig configures a StateSetNode.
+	StateSetNodeConfig = nodes.StateSetNodeConfig
+
+	// StateIncrNode atomically increments a counter kept across runs.
+	StateIncrNode = nodes.StateIncrNode
+
+	// StateIncrNodeConfig configures a StateIncrNode.
+	StateIncrNodeConfig = nodes.StateIncrNodeConfig
+
+	// StateNodeConfig addresses the value of a state node.
+	StateNodeConfig = nodes.StateNodeConfig
+
+	// StateStore persists workflow state across runs.
+	StateStore = nodes.StateStore
+
 	// HumanNode requests human input or approval.
 	HumanNode = nodes.HumanNode
 
@@ -626,6 +768,13 @@ const (
 	GuardianActionRedirect = nodes.GuardianActionRedirect
 )
 
+// ValidateJSONAction constants
+const (
+	ValidateJSONActionFail     = nodes.ValidateJSONActionFail
+	ValidateJSONActionContinue = nodes.ValidateJSONActionContinue
+	ValidateJSONActionRoute    = nodes.ValidateJSONActionRoute
+)
+
 // PIIType constants
 const (
 	PIITypeSSN         = nodes.PIITypeSSN
@@ -659,6 +808,13 @@ const (
 	WebhookCallErrorPolicyRecord   = nodes.WebhookCallErrorPolicyRecord
 )
 
+// ToolCacheBypass constants
+const (
+	ToolCacheUse     = nodes.ToolCacheUse
+	ToolCacheRefresh = nodes.ToolCacheRefresh
+	ToolCacheSkip    = nodes.ToolCacheSkip
+)
+
 // Webhook auth mode constants.
 const (
 	WebhookAuthTypeNone        = nodes.WebhookAuthTypeNone
@@ -668,8 +824,10 @@ const (
 // Nodes package constructors
 var (
 	NewLLMNode                = nodes.NewLLMNode
+	NewCompactMessagesNode    = nodes.NewCompactMessagesNode
 	NewToolNode               = nodes.NewToolNode
 	NewToolNodeWithRegistry   = nodes.NewToolNodeWithRegistry
+	WithToolCacheBypass       = nodes.WithToolCacheBypass
 	NewRuleRouter             = nodes.NewRuleRouter
 	NewLLMRouter              = nodes.NewLLMRouter
 	NewMergeNode              = nodes.NewMergeNode
@@ -687,6 +845,17 @@ var (
 	NewCacheKeyBuilder        = nodes.NewCacheKeyBuilder
 	NewMockNode               = nodes.NewMockNode
 	NewGuardianNode           = nodes.NewGuardianNode
+	NewValidateJSONNode       = nodes.NewValidateJSONNode
+	NewNormalizeContentNode   = nodes.NewNormalizeContentNode
+	NewCalcNode               = nodes.NewCalcNode
+	NewPublishNode            = nodes.NewPublishNode
+	NewModelSelectNode        = nodes.NewModelSelectNode
+	NewRewardNode             = nodes.NewRewardNode
+	NewMemoryArmStats         = nodes.NewMemoryArmStats
+	NewStateGetNode           = nodes.NewStateGetNode
+	NewStateSetNode           = nodes.NewStateSetNode
+	NewStateIncrNode          = nodes.NewStateIncrNode
+	NewMemoryStateStore       = nodes.NewMemoryStateStore
 	NewHumanNode              = nodes.NewHumanNode
 	NewChannelHumanHandler    = nodes.NewChannelHumanHandler
 	NewCallbackHumanHandler   = nodes.NewCallbackHumanHandler
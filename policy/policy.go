@@ -0,0 +1,296 @@
+// Package policy checks workflow definitions against an organization's
+// rules: where webhook_call nodes may send data, which providers and
+// models LLM nodes may use, guardian nodes in front of webhook-triggered
+// workflows and run budgets. Checks are static; they read the definition
+// and never run it.
+package policy
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// Rules, as named in Policy.Severity.
+const (
+	RuleEgress   = "egress"
+	RuleModels   = "models"
+	RuleGuardian = "guardian"
+	RuleBudget   = "budget"
+)
+
+// Diagnostic codes, one per rule.
+const (
+	CodeEgress   = "POL-001"
+	CodeModels   = "POL-002"
+	CodeGuardian = "POL-003"
+	CodeBudget   = "POL-004"
+)
+
+// Rule severities. SeverityOff disables a rule.
+const (
+	SeverityError   = graph.SeverityError
+	SeverityWarning = graph.SeverityWarning
+	SeverityOff     = "off"
+)
+
+var rules = []string{RuleEgress, RuleModels, RuleGuardian, RuleBudget}
+
+// Policy is an organization's rules for workflow definitions. Zero fields
+// do not restrict.
+type Policy struct {
+	// AllowedHosts lists the hosts webhook_call nodes may call.
+	// "*.example.com" matches any subdomain of example.com.
+	AllowedHosts []string `yaml:"allowed_hosts,omitempty" json:"allowed_hosts,omitempty"`
+	// AllowedProviders lists the providers LLM nodes and model_select arms
+	// may use.
+	AllowedProviders []string `yaml:"allowed_providers,omitempty" json:"allowed_providers,omitempty"`
+	// AllowedModels lists the models they may use, as "model" or
+	// "provider/model". Entries may use path.Match wildcards, such as
+	// "anthropic/claude-*".
+	AllowedModels []string `yaml:"allowed_models,omitempty" json:"allowed_models,omitempty"`
+	// RequireGuardian makes workflows with a webhook_trigger node include
+	// a guardian node.
+	RequireGuardian bool `yaml:"require_guardian,omitempty" json:"require_guardian,omitempty"`
+	// MaxNodeExecutions makes workflows declare run_defaults
+	// max_node_executions no higher than this.
+	MaxNodeExecutions int `yaml:"max_node_executions,omitempty" json:"max_node_executions,omitempty"`
+	// MaxTimeout makes workflows declare a run_defaults timeout no longer
+	// than this.
+	MaxTimeout time.Duration `yaml:"max_timeout,omitempty" json:"max_timeout,omitempty"`
+	// Severity sets how each rule's violations are reported: error (the
+	// default) blocks the workflow, warning flags it and off skips the
+	// rule. Keys are egress, models, guardian and budget.
+	Severity map[string]string `yaml:"severity,omitempty" json:"severity,omitempty"`
+}
+
+// Load reads a policy from a YAML or JSON file. Unknown keys are rejected.
+func Load(path string) (*Policy, error) {
+	// #nosec G304 -- path from explicit operator configuration.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading policy %q: %w", path, err)
+	}
+	var p Policy
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil {
+		return nil, fmt.Errorf("parsing policy %q: %w", path, err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("policy %q: %w", path, err)
+	}
+	return &p, nil
+}
+
+// Validate reports malformed settings.
+func (p *Policy) Validate() error {
+	var errs []error
+	for _, host := range p.AllowedHosts {
+		if strings.TrimSpace(host) == "" || strings.Contains(strings.TrimPrefix(host, "*."), "*") {
+			errs = append(errs, fmt.Errorf("allowed_hosts: %q must be a host or *.domain", host))
+		}
+	}
+	for _, model := range p.AllowedModels {
+		if _, err := path.Match(model, ""); err != nil {
+			errs = append(errs, fmt.Errorf("allowed_models: %q: %w", model, err))
+		}
+	}
+	if p.MaxNodeExecutions < 0 {
+		errs = append(errs, errors.New("max_node_executions must not be negative"))
+	}
+	if p.MaxTimeout < 0 {
+		errs = append(errs, errors.New("max_timeout must not be negative"))
+	}
+	keys := make([]string, 0, len(p.Severity))
+	for rule := range p.Severity {
+		keys = append(keys, rule)
+	}
+	sort.Strings(keys)
+	for _, rule := range keys {
+		switch {
+		case !knownRule(rule):
+			errs = append(errs, fmt.Errorf("severity: unknown rule %q (use %s)", rule, strings.Join(rules, ", ")))
+		case !knownSeverity(p.Severity[rule]):
+			errs = append(errs, fmt.Errorf("severity.%s: %q must be error, warning or off", rule, p.Severity[rule]))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func knownRule(rule string) bool {
+	for _, r := range rules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func knownSeverity(severity string) bool {
+	switch severity {
+	case SeverityError, SeverityWarning, SeverityOff:
+		return true
+	}
+	return false
+}
+
+// severity returns the severity of rule's violations.
+func (p *Policy) severity(rule string) string {
+	if s := p.Severity[rule]; s != "" {
+		return s
+	}
+	return SeverityError
+}
+
+// Check returns the policy violations of gd as diagnostics, at the
+// severity the policy sets for each rule. Nodes are checked with the
+// settings they inherit from llm_defaults. A nil policy allows everything.
+func (p *Policy) Check(gd *graph.GraphDefinition) []graph.Diagnostic {
+	if p == nil || gd == nil {
+		return nil
+	}
+	gd = gd.ApplyLLMDefaults()
+	var diags []graph.Diagnostic
+	report := func(rule, code, node, path, format string, args ...any) {
+		severity := p.severity(rule)
+		if severity == SeverityOff {
+			return
+		}
+		diags = append(diags, graph.Diagnostic{
+			Code:     code,
+			Severity: severity,
+			Message:  fmt.Sprintf("Policy: "+format, args...),
+			Node:     node,
+			Path:     path,
+		})
+	}
+
+	var triggered, guarded bool
+	for i, nd := range gd.Nodes {
+		configPath := fmt.Sprintf("nodes[%d].config", i)
+		switch nd.Type {
+		case "webhook_trigger":
+			triggered = true
+		case "guardian":
+			guarded = true
+		case "webhook_call":
+			if len(p.AllowedHosts) > 0 {
+				rawURL, _ := nd.Config["url"].(string)
+				if host, ok := p.allowedURL(rawURL); !ok {
+					report(RuleEgress, CodeEgress, nd.ID, configPath+".url", "node %q calls host %q, which is not in allowed_hosts", nd.ID, host)
+				}
+			}
+		}
+
+		provider, _ := nd.Config["provider"].(string)
+		model, _ := nd.Config["model"].(string)
+		if provider != "" {
+			if reason := p.modelViolation(provider, model); reason != "" {
+				report(RuleModels, CodeModels, nd.ID, configPath, "node %q %s", nd.ID, reason)
+			}
+		}
+		arms, _ := nd.Config["arms"].([]any)
+		for j, raw := range arms {
+			arm, _ := raw.(map[string]any)
+			armProvider, _ := arm["provider"].(string)
+			armModel, _ := arm["model"].(string)
+			if reason := p.modelViolation(armProvider, armModel); reason != "" {
+				report(RuleModels, CodeModels, nd.ID, fmt.Sprintf("%s.arms[%d]", configPath, j), "node %q arm %d %s", nd.ID, j, reason)
+			}
+		}
+	}
+
+	if p.RequireGuardian && triggered && !guarded {
+		report(RuleGuardian, CodeGuardian, "", "nodes", "workflows with a webhook_trigger node must include a guardian node")
+	}
+
+	p.checkBudget(gd, report)
+	return diags
+}
+
+// checkBudget reports run defaults missing or above the policy's budget.
+func (p *Policy) checkBudget(gd *graph.GraphDefinition, report func(rule, code, node, path, format string, args ...any)) {
+	defaults := gd.RunDefaults
+	if defaults == nil {
+		defaults = &graph.RunDefaults{}
+	}
+	if p.MaxNodeExecutions > 0 {
+		switch {
+		case defaults.MaxNodeExecutions == 0:
+			report(RuleBudget, CodeBudget, "", "run_defaults.max_node_executions", "run_defaults.max_node_executions must be set (at most %d)", p.MaxNodeExecutions)
+		case defaults.MaxNodeExecutions > p.MaxNodeExecutions:
+			report(RuleBudget, CodeBudget, "", "run_defaults.max_node_executions", "run_defaults.max_node_executions %d exceeds the maximum of %d", defaults.MaxNodeExecutions, p.MaxNodeExecutions)
+		}
+	}
+	if p.MaxTimeout > 0 {
+		timeout, err := time.ParseDuration(defaults.Timeout)
+		switch {
+		case defaults.Timeout == "" || err != nil:
+			report(RuleBudget, CodeBudget, "", "run_defaults.timeout", "run_defaults.timeout must be set (at most %s)", p.MaxTimeout)
+		case timeout > p.MaxTimeout:
+			report(RuleBudget, CodeBudget, "", "run_defaults.timeout", "run_defaults.timeout %s exceeds the maximum of %s", timeout, p.MaxTimeout)
+		}
+	}
+}
+
+// allowedURL reports whether rawURL's host is allowed, returning the host.
+func (p *Policy) allowedURL(rawURL string) (string, bool) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return rawURL, false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range p.AllowedHosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if domain, ok := strings.CutPrefix(allowed, "*."); ok {
+			if strings.HasSuffix(host, "."+domain) {
+				return host, true
+			}
+			continue
+		}
+		if host == allowed {
+			return host, true
+		}
+	}
+	return host, false
+}
+
+// modelViolation explains why provider and model are not allowed, or
+// returns "".
+func (p *Policy) modelViolation(provider, model string) string {
+	if len(p.AllowedProviders) > 0 {
+		allowed := false
+		for _, name := range p.AllowedProviders {
+			if strings.EqualFold(name, provider) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Sprintf("uses provider %q, which is not in allowed_providers", provider)
+		}
+	}
+	if len(p.AllowedModels) > 0 && model != "" {
+		for _, pattern := range p.AllowedModels {
+			if ok, _ := path.Match(pattern, model); ok {
+				return ""
+			}
+			if ok, _ := path.Match(pattern, provider+"/"+model); ok {
+				return ""
+			}
+		}
+		return fmt.Sprintf("uses model %q, which is not in allowed_models", provider+"/"+model)
+	}
+	return ""
+}
@@ -0,0 +1,116 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/graph"
+)
+
+func policyGraph() *graph.GraphDefinition {
+	return &graph.GraphDefinition{
+		ID:      "support",
+		Version: "1.0",
+		Nodes: []graph.NodeDef{
+			{ID: "hook", Type: "webhook_trigger"},
+			{ID: "draft", Type: "llm_prompt", Config: map[string]any{"provider": "openai", "model": "gpt-4o"}},
+			{ID: "pick", Type: "model_select", Config: map[string]any{"arms": []any{
+				map[string]any{"provider": "anthropic", "model": "claude-sonnet-4-6"},
+				map[string]any{"provider": "anthropic", "model": "claude-opus-4"},
+			}}},
+			{ID: "notify", Type: "webhook_call", Config: map[string]any{"url": "https://hooks.evil.example/x"}},
+			{ID: "audit", Type: "webhook_call", Config: map[string]any{"url": "https://audit.corp.example.com/log"}},
+		},
+		RunDefaults: &graph.RunDefaults{MaxNodeExecutions: 500, Timeout: "10m"},
+	}
+}
+
+func codesAt(diags []graph.Diagnostic) map[string]string {
+	out := make(map[string]string)
+	for _, d := range diags {
+		out[d.Code+" "+d.Path] = d.Severity
+	}
+	return out
+}
+
+func TestCheck_ReportsEachRule(t *testing.T) {
+	p := &Policy{
+		AllowedHosts:      []string{"*.corp.example.com"},
+		AllowedProviders:  []string{"anthropic"},
+		AllowedModels:     []string{"anthropic/claude-sonnet-*"},
+		RequireGuardian:   true,
+		MaxNodeExecutions: 200,
+		MaxTimeout:        5 * time.Minute,
+	}
+	got := codesAt(p.Check(policyGraph()))
+	want := []string{
+		"POL-001 nodes[3].config.url",
+		"POL-002 nodes[1].config",
+		"POL-002 nodes[2].config.arms[1]",
+		"POL-003 nodes",
+		"POL-004 run_defaults.max_node_executions",
+		"POL-004 run_defaults.timeout",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("diagnostics = %v, want %v", got, want)
+	}
+	for _, key := range want {
+		if got[key] != SeverityError {
+			t.Errorf("%s = %q, want error", key, got[key])
+		}
+	}
+}
+
+func TestCheck_SeverityOverrides(t *testing.T) {
+	p := &Policy{
+		AllowedHosts:    []string{"audit.corp.example.com"},
+		RequireGuardian: true,
+		Severity:        map[string]string{RuleEgress: SeverityWarning, RuleGuardian: SeverityOff},
+	}
+	diags := p.Check(policyGraph())
+	if len(diags) != 1 || diags[0].Code != CodeEgress || diags[0].Severity != SeverityWarning || diags[0].Node != "notify" {
+		t.Fatalf("diagnostics = %+v, want one egress warning", diags)
+	}
+}
+
+func TestCheck_GuardedAndBudgetedWorkflowPasses(t *testing.T) {
+	gd := policyGraph()
+	gd.Nodes = append(gd.Nodes[:1], graph.NodeDef{ID: "guard", Type: "guardian"})
+	p := &Policy{RequireGuardian: true, MaxNodeExecutions: 500, MaxTimeout: 10 * time.Minute}
+	if diags := p.Check(gd); len(diags) != 0 {
+		t.Fatalf("diagnostics = %+v, want none", diags)
+	}
+	if diags := (*Policy)(nil).Check(gd); diags != nil {
+		t.Fatalf("nil policy diagnostics = %+v", diags)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "policy.yaml")
+	body := "allowed_hosts: [\"*.corp.example.com\"]\nmax_timeout: 5m\nseverity:\n  budget: warning\n"
+	if err := os.WriteFile(path, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if p.MaxTimeout != 5*time.Minute || p.Severity[RuleBudget] != SeverityWarning {
+		t.Errorf("policy = %+v", p)
+	}
+
+	bad := filepath.Join(dir, "bad.yaml")
+	_ = os.WriteFile(bad, []byte("severity:\n  egres: loud\n"), 0o600)
+	if _, err := Load(bad); err == nil || !strings.Contains(err.Error(), "unknown rule") {
+		t.Errorf("Load(bad) = %v, want unknown rule error", err)
+	}
+	typo := filepath.Join(dir, "typo.yaml")
+	_ = os.WriteFile(typo, []byte("allowed_host: [x]\n"), 0o600)
+	if _, err := Load(typo); err == nil {
+		t.Error("Load(typo) succeeded, want unknown key error")
+	}
+}
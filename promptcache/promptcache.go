@@ -0,0 +1,306 @@
+// Package promptcache carries prompt caching hints from an LLM request to
+// the HTTP calls of the Anthropic and OpenAI provider clients, and reports
+// the cache usage those providers return. iris has no request fields for
+// either, so Transport rewrites the provider requests and reads the
+// responses on the way through.
+package promptcache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// anthropicExtendedTTLBeta enables the one-hour cache lifetime.
+const anthropicExtendedTTLBeta = "extended-cache-ttl-2025-04-11"
+
+// Usage collects the prompt cache usage providers report for the calls made
+// with a context from WithHints.
+type Usage struct {
+	mu          sync.Mutex
+	readTokens  int
+	writeTokens int
+}
+
+// Tokens returns the input tokens read from and written to the cache.
+func (u *Usage) Tokens() (read, write int) {
+	if u == nil {
+		return 0, 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.readTokens, u.writeTokens
+}
+
+func (u *Usage) record(read, write int) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.readTokens = read
+	u.writeTokens = write
+}
+
+type contextKey struct{}
+
+type callState struct {
+	hints *core.LLMCacheHints
+	usage *Usage
+}
+
+// WithHints returns a context whose provider calls, when made through a
+// Transport, carry hints and report their cache usage to the returned Usage.
+// hints may be nil to only collect usage.
+func WithHints(ctx context.Context, hints *core.LLMCacheHints) (context.Context, *Usage) {
+	usage := &Usage{}
+	return context.WithValue(ctx, contextKey{}, &callState{hints: hints, usage: usage}), usage
+}
+
+// Transport is an http.RoundTripper for the Anthropic and OpenAI provider
+// clients. It rewrites requests to set the provider's prompt caching
+// parameters from the hints of the request's context and records the cache
+// usage of responses, including streamed ones. Requests without a WithHints
+// context, and other API calls, pass through unchanged.
+type Transport struct {
+	// Base performs the requests. Nil means http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+// NewClient returns an HTTP client using a Transport over
+// http.DefaultTransport.
+func NewClient() *http.Client {
+	return &http.Client{Transport: &Transport{}}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	state, _ := req.Context().Value(contextKey{}).(*callState)
+	api := apiOf(req)
+	if state == nil || api == "" {
+		return base.RoundTrip(req)
+	}
+
+	if state.hints != nil && req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		_ = req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		if rewritten, ok := applyCacheHints(api, body, state.hints); ok {
+			body = rewritten
+		}
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		if api == "anthropic" && state.hints.TTL >= time.Hour {
+			req.Header.Add("anthropic-beta", anthropicExtendedTTLBeta)
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		resp.Body = &sseUsageReader{body: resp.Body, usage: state.usage}
+		return resp, nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	recordCacheUsage(data, state.usage)
+	resp.Body = io.NopCloser(bytes.NewReader(data))
+	return resp, nil
+}
+
+// apiOf reports which provider API req calls: "anthropic" for
+// the Messages API, "openai" for Chat Completions and Responses, or "".
+func apiOf(req *http.Request) string {
+	if req.Method != http.MethodPost {
+		return ""
+	}
+	switch path := req.URL.Path; {
+	case strings.HasSuffix(path, "/messages"):
+		return "anthropic"
+	case strings.HasSuffix(path, "/chat/completions"), strings.HasSuffix(path, "/responses"):
+		return "openai"
+	}
+	return ""
+}
+
+// applyCacheHints sets the caching parameters of api in a JSON request body.
+// It reports false when the body is left as is.
+func applyCacheHints(api string, body []byte, hints *core.LLMCacheHints) ([]byte, bool) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var payload map[string]any
+	if err := dec.Decode(&payload); err != nil {
+		return nil, false
+	}
+
+	switch api {
+	case "anthropic":
+		if !markAnthropicCache(payload, hints) {
+			return nil, false
+		}
+	case "openai":
+		// OpenAI caches long prompt prefixes on its own; a key routes
+		// requests sharing a prefix to the same cache.
+		if hints.Key == "" {
+			return nil, false
+		}
+		payload["prompt_cache_key"] = hints.Key
+	}
+
+	out, err := json.Marshal(payload)
+	if err != nil {
+		return nil, false
+	}
+	return out, true
+}
+
+// markAnthropicCache adds cache_control breakpoints after the system prompt
+// and after the last cacheable message.
+func markAnthropicCache(payload map[string]any, hints *core.LLMCacheHints) bool {
+	control := map[string]any{"type": "ephemeral"}
+	if hints.TTL >= time.Hour {
+		control["ttl"] = "1h"
+	}
+
+	marked := false
+	if hints.System {
+		if system, ok := payload["system"].(string); ok && system != "" {
+			payload["system"] = []any{map[string]any{
+				"type":          "text",
+				"text":          system,
+				"cache_control": control,
+			}}
+			marked = true
+		}
+	}
+	if hints.Messages > 0 {
+		messages, _ := payload["messages"].([]any)
+		if hints.Messages <= len(messages) {
+			msg, _ := messages[hints.Messages-1].(map[string]any)
+			if content, ok := msg["content"].([]any); ok && len(content) > 0 {
+				if block, ok := content[len(content)-1].(map[string]any); ok {
+					block["cache_control"] = control
+					marked = true
+				}
+			}
+		}
+	}
+	return marked
+}
+
+// recordCacheUsage records the cache usage of a JSON response or stream
+// event. Anthropic reports cache_read_input_tokens and
+// cache_creation_input_tokens; OpenAI reports cached prompt tokens under
+// prompt_tokens_details (Chat Completions) or input_tokens_details
+// (Responses).
+func recordCacheUsage(data []byte, usage *Usage) {
+	var payload struct {
+		Usage   *cacheUsageFields `json:"usage"`
+		Message *struct {
+			Usage *cacheUsageFields `json:"usage"`
+		} `json:"message"`
+		Response *struct {
+			Usage *cacheUsageFields `json:"usage"`
+		} `json:"response"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return
+	}
+	u := payload.Usage
+	if payload.Message != nil && payload.Message.Usage != nil {
+		u = payload.Message.Usage
+	}
+	if payload.Response != nil && payload.Response.Usage != nil {
+		u = payload.Response.Usage
+	}
+	if u == nil {
+		return
+	}
+	read, write := u.tokens()
+	if read == 0 && write == 0 {
+		// Anthropic's closing message_delta repeats usage without the
+		// cache fields; keep what message_start reported.
+		if r, w := usage.Tokens(); r != 0 || w != 0 {
+			return
+		}
+	}
+	usage.record(read, write)
+}
+
+type cacheUsageFields struct {
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	PromptTokensDetails      *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details"`
+	InputTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"input_tokens_details"`
+}
+
+func (f *cacheUsageFields) tokens() (read, write int) {
+	read, write = f.CacheReadInputTokens, f.CacheCreationInputTokens
+	if f.PromptTokensDetails != nil {
+		read += f.PromptTokensDetails.CachedTokens
+	}
+	if f.InputTokensDetails != nil {
+		read += f.InputTokensDetails.CachedTokens
+	}
+	return read, write
+}
+
+// sseUsageReader passes a server-sent event stream through and records the
+// cache usage of its data lines as they are read.
+type sseUsageReader struct {
+	body    io.ReadCloser
+	usage   *Usage
+	partial []byte
+}
+
+func (r *sseUsageReader) Read(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if n > 0 {
+		r.partial = append(r.partial, p[:n]...)
+		r.scan()
+	}
+	return n, err
+}
+
+func (r *sseUsageReader) Close() error {
+	return r.body.Close()
+}
+
+// scan records the usage of the complete lines buffered so far.
+func (r *sseUsageReader) scan() {
+	end := bytes.LastIndexByte(r.partial, '\n')
+	if end < 0 {
+		return
+	}
+	for _, line := range bytes.Split(r.partial[:end], []byte("\n")) {
+		data, ok := bytes.CutPrefix(line, []byte("data:"))
+		if !ok || !bytes.Contains(data, []byte(`"usage"`)) {
+			continue
+		}
+		recordCacheUsage(bytes.TrimSpace(data), r.usage)
+	}
+	r.partial = append(r.partial[:0], r.partial[end+1:]...)
+}
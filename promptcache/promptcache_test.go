@@ -0,0 +1,158 @@
+package promptcache
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+func TestApplyCacheHints_Anthropic(t *testing.T) {
+	body := []byte(`{"model":"m","max_tokens":1024,"system":"Be brief.","messages":[` +
+		`{"role":"user","content":[{"type":"text","text":"doc"}]},` +
+		`{"role":"user","content":[{"type":"text","text":"question"}]}]}`)
+
+	out, ok := applyCacheHints("anthropic", body, &core.LLMCacheHints{System: true, Messages: 1})
+	if !ok {
+		t.Fatal("expected the body to be rewritten")
+	}
+	var got map[string]any
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatalf("rewritten body: %v", err)
+	}
+	if got["max_tokens"] != float64(1024) {
+		t.Errorf("max_tokens = %v, want 1024", got["max_tokens"])
+	}
+	system := got["system"].([]any)[0].(map[string]any)
+	if system["text"] != "Be brief." || system["cache_control"] == nil {
+		t.Errorf("system = %v", system)
+	}
+	messages := got["messages"].([]any)
+	doc := messages[0].(map[string]any)["content"].([]any)[0].(map[string]any)
+	if cc, _ := doc["cache_control"].(map[string]any); cc["type"] != "ephemeral" || cc["ttl"] != nil {
+		t.Errorf("doc cache_control = %v, want ephemeral with the default ttl", doc["cache_control"])
+	}
+	question := messages[1].(map[string]any)["content"].([]any)[0].(map[string]any)
+	if _, ok := question["cache_control"]; ok {
+		t.Error("question should not be cached")
+	}
+
+	// Nothing to mark leaves the body alone.
+	if _, ok := applyCacheHints("anthropic", []byte(`{"messages":[]}`), &core.LLMCacheHints{System: true, Messages: 3}); ok {
+		t.Error("expected no rewrite without a system prompt or enough messages")
+	}
+	if _, ok := applyCacheHints("openai", []byte(`{"messages":[]}`), &core.LLMCacheHints{System: true}); ok {
+		t.Error("expected no OpenAI rewrite without a key")
+	}
+}
+
+func TestRecordCacheUsage(t *testing.T) {
+	tests := []struct {
+		name      string
+		events    []string
+		wantRead  int
+		wantWrite int
+	}{
+		{
+			name:      "anthropic",
+			events:    []string{`{"usage":{"input_tokens":3,"cache_read_input_tokens":100,"cache_creation_input_tokens":20}}`},
+			wantRead:  100,
+			wantWrite: 20,
+		},
+		{
+			name: "anthropic stream keeps message_start usage",
+			events: []string{
+				`{"type":"message_start","message":{"usage":{"cache_read_input_tokens":100}}}`,
+				`{"type":"message_delta","usage":{"output_tokens":9}}`,
+			},
+			wantRead: 100,
+		},
+		{
+			name:     "openai chat completions",
+			events:   []string{`{"usage":{"prompt_tokens":1100,"prompt_tokens_details":{"cached_tokens":1024}}}`},
+			wantRead: 1024,
+		},
+		{
+			name:     "openai responses stream",
+			events:   []string{`{"type":"response.completed","response":{"usage":{"input_tokens_details":{"cached_tokens":512}}}}`},
+			wantRead: 512,
+		},
+		{
+			name:   "no usage",
+			events: []string{`{"id":"x"}`, `not json`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := &Usage{}
+			for _, e := range tt.events {
+				recordCacheUsage([]byte(e), usage)
+			}
+			if read, write := usage.Tokens(); read != tt.wantRead || write != tt.wantWrite {
+				t.Errorf("Tokens() = %d, %d, want %d, %d", read, write, tt.wantRead, tt.wantWrite)
+			}
+		})
+	}
+}
+
+func TestSSEUsageReader(t *testing.T) {
+	stream := "event: message_start\n" +
+		`data: {"type":"message_start","message":{"usage":{"cache_creation_input_tokens":4096}}}` + "\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+	usage := &Usage{}
+	r := &sseUsageReader{body: io.NopCloser(iotest.OneByteReader(strings.NewReader(stream))), usage: usage}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(data) != stream {
+		t.Error("stream was altered")
+	}
+	if _, write := usage.Tokens(); write != 4096 {
+		t.Errorf("write tokens = %d, want 4096", write)
+	}
+}
+
+func TestTransport_PassesThroughOtherRequests(t *testing.T) {
+	var gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		gotBody = string(data)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"usage":{"prompt_tokens_details":{"cached_tokens":7}}}`)
+	}))
+	defer srv.Close()
+
+	ctx, usage := WithHints(context.Background(), &core.LLMCacheHints{System: true, Key: "k", TTL: time.Hour})
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, srv.URL+"/v1/embeddings", strings.NewReader(`{"input":"x"}`))
+	resp, err := NewClient().Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if gotBody != `{"input":"x"}` {
+		t.Errorf("body = %s, want it unchanged", gotBody)
+	}
+	if read, _ := usage.Tokens(); read != 0 {
+		t.Errorf("read tokens = %d, want 0 for a non-chat call", read)
+	}
+
+	// Without hints in the context, chat calls are untouched too.
+	req, _ = http.NewRequest(http.MethodPost, srv.URL+"/v1/chat/completions", strings.NewReader(`{"model":"m"}`))
+	resp, err = NewClient().Do(req)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	_ = resp.Body.Close()
+	if gotBody != `{"model":"m"}` {
+		t.Errorf("body = %s, want it unchanged", gotBody)
+	}
+}
@@ -0,0 +1,229 @@
+// Package propagate attaches run correlation headers to the outbound HTTP
+// requests of webhook_call nodes and tools, so downstream services can tie
+// their logs to the PetalFlow run and node that called them.
+//
+// A run turns propagation on by carrying a Config in its context; the
+// runtime adds the run and node IDs as each node executes, and nodes may
+// layer their own Override on top.
+package propagate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Header names set on outbound requests.
+const (
+	HeaderTraceParent = "traceparent"
+	HeaderRunID       = "X-PetalFlow-Run-ID"
+	HeaderNodeID      = "X-PetalFlow-Node-ID"
+)
+
+// Config controls the correlation headers outbound requests carry.
+type Config struct {
+	// Enabled turns propagation on.
+	Enabled bool
+	// Extra headers are added to every request, such as a deployment
+	// name.
+	Extra map[string]string
+	// TraceParent returns the W3C traceparent of a node's span, or "" to
+	// fall back to one derived from the run and node IDs. Nil always
+	// derives it.
+	TraceParent func(runID, nodeID string) string
+}
+
+// Validate checks the names of the extra headers.
+func (c Config) Validate() error {
+	return validateExtra(c.Extra)
+}
+
+// Override is a node's propagation setting, layered over its run's.
+type Override struct {
+	// Enabled, when set, turns propagation on or off for the node.
+	Enabled *bool
+	// Extra headers are added over the run's; they also turn propagation
+	// on unless Enabled is false.
+	Extra map[string]string
+}
+
+// ParseOverride reads a node's propagate_headers config: a boolean, or an
+// object with optional enabled and extra fields. Nil yields nil.
+func ParseOverride(raw any) (*Override, error) {
+	switch v := raw.(type) {
+	case nil:
+		return nil, nil
+	case bool:
+		return &Override{Enabled: &v}, nil
+	case map[string]any:
+		o := &Override{}
+		for key, value := range v {
+			switch key {
+			case "enabled":
+				enabled, ok := value.(bool)
+				if !ok {
+					return nil, fmt.Errorf("propagate_headers.enabled must be a boolean")
+				}
+				o.Enabled = &enabled
+			case "extra":
+				extra, ok := value.(map[string]any)
+				if !ok {
+					return nil, fmt.Errorf("propagate_headers.extra must be an object of strings")
+				}
+				o.Extra = make(map[string]string, len(extra))
+				for name, headerValue := range extra {
+					s, ok := headerValue.(string)
+					if !ok {
+						return nil, fmt.Errorf("propagate_headers.extra[%q] must be a string", name)
+					}
+					o.Extra[name] = s
+				}
+			default:
+				return nil, fmt.Errorf("propagate_headers: unknown field %q", key)
+			}
+		}
+		if err := validateExtra(o.Extra); err != nil {
+			return nil, fmt.Errorf("propagate_headers.%w", err)
+		}
+		return o, nil
+	default:
+		return nil, fmt.Errorf("propagate_headers must be a boolean or an object")
+	}
+}
+
+type configKey struct{}
+
+type nodeKey struct{}
+
+type nodeScope struct {
+	runID  string
+	nodeID string
+}
+
+// WithConfig returns a context whose outbound requests carry the headers
+// cfg describes.
+func WithConfig(ctx context.Context, cfg Config) context.Context {
+	return context.WithValue(ctx, configKey{}, cfg)
+}
+
+// ConfigFromContext returns the configuration attached to ctx.
+func ConfigFromContext(ctx context.Context) (Config, bool) {
+	if ctx == nil {
+		return Config{}, false
+	}
+	cfg, ok := ctx.Value(configKey{}).(Config)
+	return cfg, ok
+}
+
+// WithNode records the run and node making requests with ctx.
+func WithNode(ctx context.Context, runID, nodeID string) context.Context {
+	return context.WithValue(ctx, nodeKey{}, nodeScope{runID: runID, nodeID: nodeID})
+}
+
+// Apply layers o over the configuration attached to ctx. A nil Override
+// returns ctx unchanged.
+func (o *Override) Apply(ctx context.Context) context.Context {
+	if o == nil {
+		return ctx
+	}
+	cfg, _ := ConfigFromContext(ctx)
+	if len(o.Extra) > 0 {
+		cfg.Enabled = true
+		extra := make(map[string]string, len(cfg.Extra)+len(o.Extra))
+		for name, value := range cfg.Extra {
+			extra[name] = value
+		}
+		for name, value := range o.Extra {
+			extra[name] = value
+		}
+		cfg.Extra = extra
+	}
+	if o.Enabled != nil {
+		cfg.Enabled = *o.Enabled
+	}
+	return WithConfig(ctx, cfg)
+}
+
+// Headers returns the correlation headers for requests made with ctx, or
+// nil when propagation is off.
+func Headers(ctx context.Context) map[string]string {
+	cfg, ok := ConfigFromContext(ctx)
+	if !ok || !cfg.Enabled {
+		return nil
+	}
+	headers := make(map[string]string, len(cfg.Extra)+3)
+	for name, value := range cfg.Extra {
+		headers[name] = value
+	}
+	if scope, ok := ctx.Value(nodeKey{}).(nodeScope); ok && scope.runID != "" {
+		headers[HeaderRunID] = scope.runID
+		if scope.nodeID != "" {
+			headers[HeaderNodeID] = scope.nodeID
+		}
+		traceParent := ""
+		if cfg.TraceParent != nil {
+			traceParent = cfg.TraceParent(scope.runID, scope.nodeID)
+		}
+		if traceParent == "" {
+			traceParent = DeriveTraceParent(scope.runID, scope.nodeID)
+		}
+		headers[HeaderTraceParent] = traceParent
+	}
+	return headers
+}
+
+// SetHeaders adds the correlation headers for requests made with ctx to h.
+// Headers h already has win, so explicitly configured values are kept.
+func SetHeaders(ctx context.Context, h http.Header) {
+	for name, value := range Headers(ctx) {
+		if h.Get(name) == "" {
+			h.Set(name, value)
+		}
+	}
+}
+
+// DeriveTraceParent returns a W3C traceparent whose trace ID is derived
+// from the run ID and parent ID from the node ID, so every request of a
+// run shares a trace even without a tracer.
+func DeriveTraceParent(runID, nodeID string) string {
+	traceID := sha256.Sum256([]byte(runID))
+	spanID := sha256.Sum256([]byte(runID + "/" + nodeID))
+	return "00-" + hex.EncodeToString(traceID[:16]) + "-" + hex.EncodeToString(spanID[:8]) + "-01"
+}
+
+func validateExtra(extra map[string]string) error {
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !validHeaderName(name) {
+			return fmt.Errorf("extra: %q is not a valid header name", name)
+		}
+		if strings.ContainsAny(extra[name], "\r\n") {
+			return fmt.Errorf("extra[%q]: value must not contain line breaks", name)
+		}
+	}
+	return nil
+}
+
+// validHeaderName reports whether name is an RFC 7230 token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package propagate
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestHeaders(t *testing.T) {
+	ctx := WithNode(context.Background(), "run-1", "fetch")
+	if got := Headers(ctx); got != nil {
+		t.Fatalf("Headers without config = %v, want nil", got)
+	}
+
+	ctx = WithConfig(ctx, Config{Enabled: true, Extra: map[string]string{"X-Env": "prod"}})
+	got := Headers(ctx)
+	if got[HeaderRunID] != "run-1" || got[HeaderNodeID] != "fetch" || got["X-Env"] != "prod" {
+		t.Fatalf("Headers = %v", got)
+	}
+	traceParent := got[HeaderTraceParent]
+	if !regexp.MustCompile(`^00-[0-9a-f]{32}-[0-9a-f]{16}-01$`).MatchString(traceParent) {
+		t.Fatalf("traceparent = %q", traceParent)
+	}
+	// Nodes of a run share the trace ID but not the parent ID.
+	other := DeriveTraceParent("run-1", "store")
+	if other[:35] != traceParent[:35] || other == traceParent {
+		t.Errorf("traceparents %q and %q", traceParent, other)
+	}
+
+	ctx = WithConfig(ctx, Config{Enabled: true, TraceParent: func(runID, nodeID string) string {
+		return "00-" + strings.Repeat("a", 32) + "-" + strings.Repeat("b", 16) + "-01"
+	}})
+	if got := Headers(ctx)[HeaderTraceParent]; !strings.HasPrefix(got, "00-aaaa") {
+		t.Errorf("traceparent from hook = %q", got)
+	}
+}
+
+func TestSetHeadersKeepsExplicitValues(t *testing.T) {
+	ctx := WithConfig(WithNode(context.Background(), "run-1", "fetch"), Config{Enabled: true})
+	h := http.Header{}
+	h.Set(HeaderRunID, "mine")
+	SetHeaders(ctx, h)
+	if h.Get(HeaderRunID) != "mine" || h.Get(HeaderNodeID) != "fetch" || h.Get("Traceparent") == "" {
+		t.Errorf("headers = %v", h)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	base := WithConfig(WithNode(context.Background(), "run-1", "fetch"), Config{Extra: map[string]string{"X-Env": "prod"}})
+
+	on, err := ParseOverride(map[string]any{"extra": map[string]any{"X-Team": "geo"}})
+	if err != nil {
+		t.Fatalf("ParseOverride: %v", err)
+	}
+	got := Headers(on.Apply(base))
+	if got["X-Env"] != "prod" || got["X-Team"] != "geo" || got[HeaderRunID] != "run-1" {
+		t.Errorf("headers with extra override = %v", got)
+	}
+
+	off, err := ParseOverride(false)
+	if err != nil {
+		t.Fatalf("ParseOverride: %v", err)
+	}
+	if got := Headers(off.Apply(WithConfig(base, Config{Enabled: true}))); got != nil {
+		t.Errorf("headers with propagation off = %v", got)
+	}
+
+	var none *Override
+	if none.Apply(base) != base {
+		t.Error("nil override changed the context")
+	}
+
+	for _, bad := range []any{"yes", map[string]any{"enabled": "true"}, map[string]any{"extra": map[string]any{"bad header": "x"}}, map[string]any{"headers": nil}} {
+		if _, err := ParseOverride(bad); err == nil {
+			t.Errorf("ParseOverride(%v) expected error", bad)
+		}
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := (Config{Extra: map[string]string{"X-Env": "prod"}}).Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	if err := (Config{Extra: map[string]string{"X-Env": "a\r\nX-Injected: 1"}}).Validate(); err == nil {
+		t.Error("expected an error for a value with line breaks")
+	}
+}
@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+)
+
+// NodeTypeAlias maps a deprecated node type name to the type that replaced
+// it. Stored workflows that still use the old name keep validating and
+// running, with a deprecation warning, until they are migrated.
+type NodeTypeAlias struct {
+	Type   string `json:"type"`   // deprecated type name
+	Target string `json:"target"` // replacement type name
+	// Message is an optional hint shown with deprecation warnings.
+	Message string `json:"message,omitempty"`
+
+	// RemapConfig converts a config written for Type into one for Target.
+	// Nil keeps the config unchanged. It must not modify its argument.
+	RemapConfig func(config map[string]any) (map[string]any, error) `json:"-"`
+}
+
+// ResolvedType is the outcome of following a type name through aliases.
+type ResolvedType struct {
+	Type   string
+	Config map[string]any
+	// Via lists the deprecated aliases crossed, oldest first. Empty when
+	// the type name was current.
+	Via []NodeTypeAlias
+}
+
+// RegisterAlias registers a deprecated type name. An existing alias with
+// the same name is overwritten.
+func (r *Registry) RegisterAlias(alias NodeTypeAlias) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.aliases[alias.Type] = alias
+}
+
+// Alias returns the alias registered for a deprecated type name.
+func (r *Registry) Alias(typeName string) (NodeTypeAlias, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	alias, ok := r.aliases[typeName]
+	return alias, ok
+}
+
+// Aliases returns all registered aliases sorted by deprecated type name.
+func (r *Registry) Aliases() []NodeTypeAlias {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	result := make([]NodeTypeAlias, 0, len(r.aliases))
+	for _, alias := range r.aliases {
+		result = append(result, alias)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
+
+// ResolveType follows aliases from typeName to a current type, remapping
+// config at every step. Names that are not aliases resolve to themselves,
+// whether or not they are registered.
+func (r *Registry) ResolveType(typeName string, config map[string]any) (ResolvedType, error) {
+	resolved := ResolvedType{Type: typeName, Config: config}
+	seen := map[string]bool{}
+	for {
+		alias, ok := r.Alias(resolved.Type)
+		if !ok {
+			return resolved, nil
+		}
+		if seen[alias.Type] {
+			return resolved, fmt.Errorf("node type alias cycle at %q", alias.Type)
+		}
+		seen[alias.Type] = true
+
+		if alias.RemapConfig != nil {
+			remapped, err := alias.RemapConfig(resolved.Config)
+			if err != nil {
+				return resolved, fmt.Errorf("remapping %s config for %s: %w", alias.Type, alias.Target, err)
+			}
+			resolved.Config = remapped
+		}
+		resolved.Type = alias.Target
+		resolved.Via = append(resolved.Via, alias)
+	}
+}
+
+// RenameConfigKeys returns a RemapConfig that moves values from old keys to
+// new ones. Keys already present under the new name are left alone.
+func RenameConfigKeys(renames map[string]string) func(map[string]any) (map[string]any, error) {
+	return func(config map[string]any) (map[string]any, error) {
+		if config == nil {
+			return nil, nil
+		}
+		out := make(map[string]any, len(config))
+		for key, value := range config {
+			out[key] = value
+		}
+		for from, to := range renames {
+			value, ok := out[from]
+			if !ok {
+				continue
+			}
+			delete(out, from)
+			if _, exists := out[to]; !exists {
+				out[to] = value
+			}
+		}
+		return out, nil
+	}
+}
@@ -35,6 +35,117 @@ func registerBuiltins(r *Registry) {
 		},
 	})
 
+	r.Register(NodeTypeDef{
+		Type:        "compact_messages",
+		Category:    "ai",
+		DisplayName: "Compact Messages",
+		Description: "Keep chat messages under a token budget by dropping tool noise and summarizing older turns",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "messages", Type: "array", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "messages", Type: "array"},
+				{Name: "result", Type: "object"},
+			},
+		},
+	})
+
+	r.Register(NodeTypeDef{
+		Type:        "translate",
+		Category:    "ai",
+		DisplayName: "Translate",
+		Description: "Translate text or collections between languages with glossary and do-not-translate terms",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "any"},
+				{Name: "quality", Type: "object"},
+			},
+		},
+	})
+
+	r.Register(NodeTypeDef{
+		Type:        "summarize",
+		Category:    "ai",
+		DisplayName: "Summarize",
+		Description: "Summarize text with bullet, abstract, executive or tweet presets, splitting long input into chunks first",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "string"},
+				{Name: "result", Type: "object"},
+			},
+		},
+	})
+
+	r.Register(NodeTypeDef{
+		Type:        "classify",
+		Category:    "ai",
+		DisplayName: "Classify",
+		Description: "Assign labels from a flat or hierarchical taxonomy with confidence scores, by model or by embedding similarity",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "any"},
+				{Name: "result", Type: "object"},
+			},
+		},
+	})
+
+	r.Register(NodeTypeDef{
+		Type:        "extract_entities",
+		Category:    "ai",
+		DisplayName: "Extract Entities",
+		Description: "Extract typed records matching an entity schema from text, with source spans, deduplication and a policy for invalid records",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "array"},
+				{Name: "result", Type: "object"},
+			},
+		},
+	})
+
+	r.Register(NodeTypeDef{
+		Type:        "model_select",
+		Category:    "ai",
+		DisplayName: "Model Select",
+		Description: "Choose among provider/model arms with an epsilon-greedy or UCB bandit that learns from rewards",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "string"},
+				{Name: "selection", Type: "object"},
+			},
+		},
+	})
+
+	r.Register(NodeTypeDef{
+		Type:        "reward",
+		Category:    "ai",
+		DisplayName: "Reward",
+		Description: "Report a reward between 0 and 1 for a model_select node's choice",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "object"},
+			},
+		},
+	})
+
 	r.Register(NodeTypeDef{
 		Type:        "rule_router",
 		Category:    "control",
@@ -144,6 +255,69 @@ func registerBuiltins(r *Registry) {
 		},
 	})
 
+	r.Register(NodeTypeDef{
+		Type:        "normalize_content",
+		Category:    "data",
+		DisplayName: "Normalize Content",
+		Description: "Clean inbound email or chat content: HTML to text, quoted reply and signature stripping, language detection",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "any"},
+				{Name: "metadata", Type: "object"},
+			},
+		},
+	})
+
+	r.Register(NodeTypeDef{
+		Type:        "calc",
+		Category:    "data",
+		DisplayName: "Calc",
+		Description: "Evaluate arithmetic formulas over variables with exact decimals, currency rounding and unit checks",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "any"},
+				{Name: "audit", Type: "array"},
+			},
+		},
+	})
+
+	r.Register(NodeTypeDef{
+		Type:        "publish",
+		Category:    "data",
+		DisplayName: "Publish",
+		Description: "Add variables to the run's public output while the run continues",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "any"},
+			},
+		},
+	})
+
+	r.Register(NodeTypeDef{
+		Type:        "validate_json",
+		Category:    "control",
+		DisplayName: "Validate JSON",
+		Description: "Validate a variable against a JSON Schema and report every violation",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "any"},
+				{Name: "result", Type: "object"},
+			},
+		},
+	})
+
 	r.Register(NodeTypeDef{
 		Type:        "human",
 		Category:    "control",
@@ -175,6 +349,21 @@ func registerBuiltins(r *Registry) {
 		},
 	})
 
+	r.Register(NodeTypeDef{
+		Type:        "subgraph",
+		Category:    "control",
+		DisplayName: "Subgraph",
+		Description: "Run a nested graph on the envelope and pass on its result",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "any"},
+			},
+		},
+	})
+
 	r.Register(NodeTypeDef{
 		Type:        "cache",
 		Category:    "data",
@@ -190,6 +379,51 @@ func registerBuiltins(r *Registry) {
 		},
 	})
 
+	r.Register(NodeTypeDef{
+		Type:        "state_get",
+		Category:    "data",
+		DisplayName: "State Get",
+		Description: "Read a value kept across runs, or a default when it is missing or expired",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "any"},
+			},
+		},
+	})
+
+	r.Register(NodeTypeDef{
+		Type:        "state_set",
+		Category:    "data",
+		DisplayName: "State Set",
+		Description: "Store a value across runs, optionally expiring after a TTL",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "any"},
+			},
+		},
+	})
+
+	r.Register(NodeTypeDef{
+		Type:        "state_incr",
+		Category:    "data",
+		DisplayName: "State Increment",
+		Description: "Atomically increment a counter kept across runs and output the new count",
+		Ports: PortSchema{
+			Inputs: []PortDef{
+				{Name: "input", Type: "any", Required: true},
+			},
+			Outputs: []PortDef{
+				{Name: "output", Type: "number"},
+			},
+		},
+	})
+
 	r.Register(NodeTypeDef{
 		Type:        "webhook_trigger",
 		Category:    "control",
@@ -267,4 +501,14 @@ func registerBuiltins(r *Registry) {
 			},
 		},
 	})
+	// Deprecated names kept working for stored workflows.
+	r.RegisterAlias(NodeTypeAlias{
+		Type:    "webhook_send",
+		Target:  "webhook_call",
+		Message: "endpoint is now url and output_var is now result_var",
+		RemapConfig: RenameConfigKeys(map[string]string{
+			"endpoint":   "url",
+			"output_var": "result_var",
+		}),
+	})
 }
@@ -15,8 +15,16 @@ type NodeTypeDef struct {
 	ConfigSchema any        `json:"config_schema"` // JSON Schema for config validation
 	IsTool       bool       `json:"is_tool"`       // usable as an agent tool
 	ToolMode     string     `json:"tool_mode"`     // "function_call" | "standalone" | ""
+	// Version is the node type's version, checked against workflow
+	// requires.node_types constraints. It moves to a new major version
+	// when the type's config or behavior changes incompatibly.
+	Version string `json:"version"`
 }
 
+// DefaultNodeTypeVersion is the version of node types registered without
+// one.
+const DefaultNodeTypeVersion = "1.0.0"
+
 // PortSchema defines the input and output ports for a node type.
 type PortSchema struct {
 	Inputs  []PortDef `json:"inputs"`
@@ -47,20 +55,26 @@ func Global() *Registry {
 
 // Registry holds all known node types.
 type Registry struct {
-	mu    sync.RWMutex
-	types map[string]NodeTypeDef
-	order []string // preserves registration order
+	mu      sync.RWMutex
+	types   map[string]NodeTypeDef
+	order   []string // preserves registration order
+	aliases map[string]NodeTypeAlias
 }
 
 func newRegistry() *Registry {
 	return &Registry{
-		types: make(map[string]NodeTypeDef),
+		types:   make(map[string]NodeTypeDef),
+		aliases: make(map[string]NodeTypeAlias),
 	}
 }
 
 // Register adds a node type definition. If a type with the same name
-// already exists it is overwritten.
+// already exists it is overwritten. A definition without a version gets
+// DefaultNodeTypeVersion.
 func (r *Registry) Register(def NodeTypeDef) {
+	if def.Version == "" {
+		def.Version = DefaultNodeTypeVersion
+	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if _, exists := r.types[def.Type]; !exists {
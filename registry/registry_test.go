@@ -218,6 +218,7 @@ func TestBuiltins_AllExpectedTypesRegistered(t *testing.T) {
 	expected := []string{
 		"llm_prompt",
 		"llm_router",
+		"compact_messages",
 		"rule_router",
 		"filter",
 		"transform",
@@ -225,9 +226,13 @@ func TestBuiltins_AllExpectedTypesRegistered(t *testing.T) {
 		"tool",
 		"gate",
 		"guardian",
+		"validate_json",
 		"human",
 		"map",
 		"cache",
+		"state_get",
+		"state_set",
+		"state_incr",
 		"webhook_trigger",
 		"webhook_call",
 		"noop",
@@ -249,6 +254,7 @@ func TestBuiltins_Categories(t *testing.T) {
 	}{
 		{"llm_prompt", "ai"},
 		{"llm_router", "ai"},
+		{"compact_messages", "ai"},
 		{"rule_router", "control"},
 		{"filter", "data"},
 		{"transform", "data"},
@@ -256,9 +262,13 @@ func TestBuiltins_Categories(t *testing.T) {
 		{"tool", "tool"},
 		{"gate", "control"},
 		{"guardian", "control"},
+		{"validate_json", "control"},
 		{"human", "control"},
 		{"map", "control"},
 		{"cache", "data"},
+		{"state_get", "data"},
+		{"state_set", "data"},
+		{"state_incr", "data"},
 		{"webhook_trigger", "control"},
 		{"webhook_call", "data"},
 		{"noop", "control"},
@@ -319,3 +329,42 @@ func TestBuiltins_AllHaveDisplayName(t *testing.T) {
 		}
 	}
 }
+
+func TestRegistry_ResolveTypeFollowsAliases(t *testing.T) {
+	r := newRegistry()
+	r.Register(NodeTypeDef{Type: "http_call"})
+	r.RegisterAlias(NodeTypeAlias{Type: "http_v1", Target: "http_v2", RemapConfig: RenameConfigKeys(map[string]string{"endpoint": "url"})})
+	r.RegisterAlias(NodeTypeAlias{Type: "http_v2", Target: "http_call", RemapConfig: RenameConfigKeys(map[string]string{"verb": "method"})})
+
+	config := map[string]any{"endpoint": "https://example.com", "verb": "PUT"}
+	resolved, err := r.ResolveType("http_v1", config)
+	if err != nil {
+		t.Fatalf("ResolveType: %v", err)
+	}
+	if resolved.Type != "http_call" || len(resolved.Via) != 2 {
+		t.Fatalf("resolved = %+v, want http_call via two aliases", resolved)
+	}
+	if resolved.Config["url"] != "https://example.com" || resolved.Config["method"] != "PUT" || resolved.Config["endpoint"] != nil {
+		t.Errorf("config = %v", resolved.Config)
+	}
+	if config["endpoint"] == nil {
+		t.Error("ResolveType must not modify the input config")
+	}
+
+	current, err := r.ResolveType("http_call", config)
+	if err != nil || current.Type != "http_call" || len(current.Via) != 0 {
+		t.Errorf("current type resolved to %+v, %v", current, err)
+	}
+	if r.Has("http_v1") {
+		t.Error("aliases must not be listed as node types")
+	}
+}
+
+func TestRegistry_ResolveTypeDetectsCycles(t *testing.T) {
+	r := newRegistry()
+	r.RegisterAlias(NodeTypeAlias{Type: "a", Target: "b"})
+	r.RegisterAlias(NodeTypeAlias{Type: "b", Target: "a"})
+	if _, err := r.ResolveType("a", nil); err == nil {
+		t.Fatal("expected alias cycle error")
+	}
+}
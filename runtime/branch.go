@@ -0,0 +1,184 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// ErrBranchTimeout is returned when a branch with the fail policy runs
+// past its timeout.
+var ErrBranchTimeout = errors.New("branch timed out")
+
+// branchRun is one execution of a branch started by an edge with a
+// graph.BranchPolicy. Branches nest: a policy edge inside a branch starts
+// a child branch, which ends at its own merge node.
+type branchRun struct {
+	source, target string
+	policy         graph.BranchPolicy
+	// merge is the first merge node downstream of target, where the branch
+	// ends. It is empty when the branch never merges.
+	merge string
+	// fork is a copy of the envelope the branch started with.
+	fork   *core.Envelope
+	parent *branchRun
+
+	mu       sync.Mutex
+	deadline time.Time
+
+	// timedOut is only touched by the executor's scheduling loop.
+	timedOut bool
+}
+
+// nextBranch returns the branch the node to runs in when the run moves
+// along the edge from -> to out of branch b: a new branch when the edge
+// has a policy, the parent branch when to is where b merges, and b
+// otherwise.
+func nextBranch(g graph.Graph, from, to string, env *core.Envelope, b *branchRun) *branchRun {
+	for b != nil && b.merge == to {
+		b = b.parent
+	}
+	bg, ok := g.(graph.BranchPolicyGraph)
+	if !ok {
+		return b
+	}
+	policy := bg.EdgeBranch(from, to)
+	if policy == nil || policy.TimeoutDuration() == 0 {
+		return b
+	}
+	if node, ok := g.NodeByID(to); ok {
+		if _, isMerge := node.(core.MergeCapable); isMerge {
+			return b
+		}
+	}
+	return &branchRun{
+		source: from,
+		target: to,
+		policy: *policy,
+		merge:  downstreamMerge(g, to),
+		fork:   env.Clone(),
+		parent: b,
+	}
+}
+
+// downstreamMerge returns the first merge node reachable from nodeID, in
+// breadth-first order, or "".
+func downstreamMerge(g graph.Graph, nodeID string) string {
+	seen := map[string]bool{nodeID: true}
+	queue := g.Successors(nodeID)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if node, ok := g.NodeByID(id); ok {
+			if _, isMerge := node.(core.MergeCapable); isMerge {
+				return id
+			}
+		}
+		queue = append(queue, g.Successors(id)...)
+	}
+	return ""
+}
+
+// deadlineFrom returns the branch's deadline, starting its clock at now
+// when its first node starts.
+func (b *branchRun) deadlineFrom(now time.Time) time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.deadline.IsZero() {
+		b.deadline = now.Add(b.policy.TimeoutDuration())
+	}
+	return b.deadline
+}
+
+// abandoned reports whether b or a branch it runs in timed out, so its
+// results are no longer wanted.
+func (b *branchRun) abandoned() bool {
+	for ; b != nil; b = b.parent {
+		if b.timedOut {
+			return true
+		}
+	}
+	return false
+}
+
+// branchContext bounds ctx by the earliest deadline of b and the branches
+// it runs in, and returns the branch that deadline belongs to.
+func branchContext(ctx context.Context, b *branchRun) (context.Context, *branchRun, context.CancelFunc) {
+	if b == nil {
+		return ctx, nil, func() {}
+	}
+	now := time.Now()
+	var limit *branchRun
+	var deadline time.Time
+	for cur := b; cur != nil; cur = cur.parent {
+		if d := cur.deadlineFrom(now); limit == nil || d.Before(deadline) {
+			limit, deadline = cur, d
+		}
+	}
+	nodeCtx, cancel := context.WithDeadline(ctx, deadline)
+	return nodeCtx, limit, cancel
+}
+
+// branchTimedOut returns limit when the node context ran out because of
+// the branch deadline rather than the run's own. A node that ignores its
+// context and finishes late also counts as timed out.
+func branchTimedOut(runCtx, nodeCtx context.Context, limit *branchRun) *branchRun {
+	if limit == nil || runCtx.Err() != nil || !errors.Is(nodeCtx.Err(), context.DeadlineExceeded) {
+		return nil
+	}
+	return limit
+}
+
+// timeoutError is the error of a node whose branch timed out under the
+// fail policy.
+func (b *branchRun) timeoutError(nodeID string) error {
+	return fmt.Errorf("%w: edge %s -> %s: node %s did not finish within %s",
+		ErrBranchTimeout, b.source, b.target, nodeID, b.policy.Timeout)
+}
+
+func (b *branchRun) report(nodeID string) map[string]any {
+	return map[string]any{
+		"source":  b.source,
+		"target":  b.target,
+		"node":    nodeID,
+		"timeout": b.policy.Timeout,
+		"policy":  b.policy.Policy(),
+	}
+}
+
+// substitute returns the input merged in place of the branch under the
+// default policy: the envelope the branch started with, plus the policy's
+// defaults.
+func (b *branchRun) substitute() *core.Envelope {
+	env := b.fork.Clone()
+	for name, value := range b.policy.Defaults {
+		env.SetVar(name, value)
+	}
+	return env
+}
+
+func emitBranchTimedOut(emit EventEmitter, opts RunOptions, runStart time.Time, runID string, node core.Node, b *branchRun) {
+	emit(NewEvent(EventBranchTimedOut, runID).
+		WithNode(node.ID(), node.Kind()).
+		WithElapsed(opts.Now().Sub(runStart)).
+		WithPayload("source", b.source).
+		WithPayload("target", b.target).
+		WithPayload("timeout", b.policy.Timeout).
+		WithPayload("on_timeout", b.policy.Policy()))
+}
+
+// addMissingBranch appends a report to the envelope's missing branches.
+func addMissingBranch(env *core.Envelope, report map[string]any) {
+	missing, _ := env.GetVar(graph.MissingBranchesVar)
+	list, _ := missing.([]any)
+	env.SetVar(graph.MissingBranchesVar, append(list, report))
+}
@@ -0,0 +1,124 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// branchTimeoutGraph fans out from start to a fast and a hanging source,
+// merges them and finishes. The edge to the hanging source has policy.
+func branchTimeoutGraph(t *testing.T, policy graph.BranchPolicy) (*graph.BasicGraph, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var ran []string
+	record := func(id string, fn func(ctx context.Context, env *core.Envelope) error) core.Node {
+		return core.NewFuncNode(id, func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+			mu.Lock()
+			ran = append(ran, id)
+			mu.Unlock()
+			return env, fn(ctx, env)
+		})
+	}
+
+	g := graph.NewGraph("branch_timeout")
+	g.AddNode(record("start", func(context.Context, *core.Envelope) error { return nil }))
+	g.AddNode(record("prices", func(_ context.Context, env *core.Envelope) error {
+		env.SetVar("price", 10)
+		return nil
+	}))
+	g.AddNode(record("weather", func(ctx context.Context, env *core.Envelope) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}))
+	g.AddNode(record("after_weather", func(context.Context, *core.Envelope) error { return nil }))
+	g.AddNode(nodes.NewMergeNode("merge", nodes.MergeNodeConfig{
+		Strategy: nodes.NewJSONMergeStrategy(nodes.JSONMergeConfig{}),
+	}))
+	g.AddNode(record("final", func(context.Context, *core.Envelope) error { return nil }))
+	g.AddEdge("start", "prices")
+	g.AddEdge("start", "weather")
+	g.AddEdge("weather", "after_weather")
+	g.AddEdge("prices", "merge")
+	g.AddEdge("after_weather", "merge")
+	g.AddEdge("merge", "final")
+	g.SetEntry("start")
+	if err := g.SetEdgeBranch("start", "weather", &policy); err != nil {
+		t.Fatal(err)
+	}
+	return g, &ran
+}
+
+func TestRuntime_Run_BranchTimeoutPolicies(t *testing.T) {
+	for _, concurrency := range []int{1, 4} {
+		for _, policy := range []string{graph.BranchOnTimeoutPartial, graph.BranchOnTimeoutDefault} {
+			g, ran := branchTimeoutGraph(t, graph.BranchPolicy{
+				Timeout:   "20ms",
+				OnTimeout: policy,
+				Defaults:  map[string]any{"forecast": "unknown"},
+			})
+			var timedOut []runtime.Event
+			opts := runtime.DefaultRunOptions()
+			opts.Concurrency = concurrency
+			opts.EventHandler = func(e runtime.Event) {
+				if e.Kind == runtime.EventBranchTimedOut {
+					timedOut = append(timedOut, e)
+				}
+			}
+
+			result, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+			if err != nil {
+				t.Fatalf("%s/%d: Run() error = %v", policy, concurrency, err)
+			}
+			if (*ran)[len(*ran)-1] != "final" {
+				t.Errorf("%s/%d: ran = %v", policy, concurrency, *ran)
+			}
+			for _, id := range *ran {
+				if id == "after_weather" {
+					t.Errorf("%s/%d: the timed-out branch went on", policy, concurrency)
+				}
+			}
+			if price, _ := result.GetVar("price"); price != 10 {
+				t.Errorf("%s/%d: price = %v", policy, concurrency, price)
+			}
+			forecast, hasForecast := result.GetVar("forecast")
+			if wantDefault := policy == graph.BranchOnTimeoutDefault; hasForecast != wantDefault || (wantDefault && forecast != "unknown") {
+				t.Errorf("%s/%d: forecast = %v, %v", policy, concurrency, forecast, hasForecast)
+			}
+
+			missing, _ := result.GetVar(graph.MissingBranchesVar)
+			list, _ := missing.([]any)
+			if len(list) != 1 {
+				t.Fatalf("%s/%d: missing_branches = %#v", policy, concurrency, missing)
+			}
+			report := list[0].(map[string]any)
+			if report["source"] != "start" || report["target"] != "weather" || report["node"] != "weather" || report["policy"] != policy {
+				t.Errorf("%s/%d: report = %v", policy, concurrency, report)
+			}
+			if len(timedOut) != 1 || timedOut[0].NodeID != "weather" || timedOut[0].Payload["on_timeout"] != policy {
+				t.Errorf("%s/%d: events = %+v", policy, concurrency, timedOut)
+			}
+			if err := runtime.ValidateEvent(timedOut[0]); err != nil {
+				t.Errorf("%s/%d: %v", policy, concurrency, err)
+			}
+		}
+	}
+}
+
+func TestRuntime_Run_BranchTimeoutFails(t *testing.T) {
+	for _, concurrency := range []int{1, 4} {
+		g, _ := branchTimeoutGraph(t, graph.BranchPolicy{Timeout: "20ms"})
+		opts := runtime.DefaultRunOptions()
+		opts.Concurrency = concurrency
+		_, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+		if !errors.Is(err, runtime.ErrBranchTimeout) {
+			t.Errorf("concurrency %d: err = %v, want a branch timeout", concurrency, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrBudgetExhausted is matched (via errors.Is) by every BudgetExhaustedError.
+var ErrBudgetExhausted = errors.New("budget_exhausted")
+
+// Budget exhaustion reasons reported in BudgetExhaustedError.Reason.
+const (
+	// BudgetReasonRunLimit means RunOptions.MaxNodeExecutions was reached.
+	BudgetReasonRunLimit = "run_limit"
+	// BudgetReasonNodeLimit means a per-node visit limit was reached.
+	BudgetReasonNodeLimit = "node_limit"
+)
+
+// maxBudgetPath bounds how much of the execution path is kept on the error.
+const maxBudgetPath = 50
+
+// BudgetExhaustedError reports that a run exceeded its node execution
+// budget. Path lists the most recent node dispatches (oldest first) that
+// consumed the budget, which usually makes the offending loop obvious.
+type BudgetExhaustedError struct {
+	Reason     string
+	NodeID     string
+	Limit      int
+	Executions int
+	Path       []string
+}
+
+// Error implements error.
+func (e *BudgetExhaustedError) Error() string {
+	var what string
+	switch e.Reason {
+	case BudgetReasonNodeLimit:
+		what = fmt.Sprintf("node %s reached its visit limit of %d", e.NodeID, e.Limit)
+	default:
+		what = fmt.Sprintf("run reached its limit of %d node executions at node %s", e.Limit, e.NodeID)
+	}
+	return fmt.Sprintf("%s: %s (path: %s)", ErrBudgetExhausted, what, strings.Join(e.Path, " -> "))
+}
+
+// Is reports whether target is ErrBudgetExhausted, or ErrMaxHopsExceeded
+// for node limits so callers matching the legacy error keep working.
+func (e *BudgetExhaustedError) Is(target error) bool {
+	if target == ErrBudgetExhausted {
+		return true
+	}
+	return target == ErrMaxHopsExceeded && e.Reason == BudgetReasonNodeLimit
+}
+
+// Payload returns a JSON-friendly description of the error for events and
+// API responses.
+func (e *BudgetExhaustedError) Payload() map[string]any {
+	return map[string]any{
+		"reason":     e.Reason,
+		"node_id":    e.NodeID,
+		"limit":      e.Limit,
+		"executions": e.Executions,
+		"path":       append([]string(nil), e.Path...),
+	}
+}
+
+// executionBudget tracks node dispatches for a single run. It is safe for
+// concurrent use, although both executors only admit from one goroutine.
+type executionBudget struct {
+	mu         sync.Mutex
+	maxTotal   int
+	nodeLimits map[string]int
+	total      int
+	visits     map[string]int
+	path       []string
+}
+
+func newExecutionBudget(opts RunOptions) *executionBudget {
+	return &executionBudget{
+		maxTotal:   opts.MaxNodeExecutions,
+		nodeLimits: opts.NodeVisitLimits,
+		visits:     make(map[string]int),
+	}
+}
+
+// admit records a dispatch of nodeID, or returns a BudgetExhaustedError
+// without recording it when a limit would be exceeded.
+func (b *executionBudget) admit(nodeID string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.maxTotal > 0 && b.total >= b.maxTotal {
+		return b.exhausted(BudgetReasonRunLimit, nodeID, b.maxTotal)
+	}
+	if limit, ok := b.nodeLimits[nodeID]; ok && limit > 0 && b.visits[nodeID] >= limit {
+		return b.exhausted(BudgetReasonNodeLimit, nodeID, limit)
+	}
+
+	b.total++
+	b.visits[nodeID]++
+	b.path = append(b.path, nodeID)
+	if len(b.path) > maxBudgetPath {
+		b.path = b.path[len(b.path)-maxBudgetPath:]
+	}
+	return nil
+}
+
+func (b *executionBudget) exhausted(reason, nodeID string, limit int) *BudgetExhaustedError {
+	path := make([]string, 0, len(b.path)+1)
+	path = append(path, b.path...)
+	path = append(path, nodeID)
+	return &BudgetExhaustedError{
+		Reason:     reason,
+		NodeID:     nodeID,
+		Limit:      limit,
+		Executions: b.total,
+		Path:       path,
+	}
+}
@@ -0,0 +1,109 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// newLoopGraph builds a -> b -> a, an unbounded loop.
+func newLoopGraph(t *testing.T) *graph.BasicGraph {
+	t.Helper()
+	g := graph.NewGraph("loop")
+	g.AddNode(core.NewNoopNode("a"))
+	g.AddNode(core.NewNoopNode("b"))
+	g.AddEdge("a", "b")
+	g.AddEdge("b", "a")
+	g.SetEntry("a")
+	return g
+}
+
+func TestBudget_RunLimit(t *testing.T) {
+	for _, concurrency := range []int{1, 4} {
+		opts := DefaultRunOptions()
+		opts.Concurrency = concurrency
+		opts.MaxNodeExecutions = 5
+
+		var finished Event
+		opts.EventHandler = func(e Event) {
+			if e.Kind == EventRunFinished {
+				finished = e
+			}
+		}
+
+		_, err := NewRuntime().Run(context.Background(), newLoopGraph(t), core.NewEnvelope(), opts)
+
+		var budgetErr *BudgetExhaustedError
+		if !errors.As(err, &budgetErr) {
+			t.Fatalf("concurrency=%d: err = %v, want *BudgetExhaustedError", concurrency, err)
+		}
+		if !errors.Is(err, ErrBudgetExhausted) {
+			t.Errorf("concurrency=%d: errors.Is(ErrBudgetExhausted) = false", concurrency)
+		}
+		if budgetErr.Reason != BudgetReasonRunLimit || budgetErr.Executions != 5 || budgetErr.Limit != 5 {
+			t.Errorf("concurrency=%d: unexpected budget error %+v", concurrency, budgetErr)
+		}
+		want := []string{"a", "b", "a", "b", "a", "b"}
+		if len(budgetErr.Path) != len(want) {
+			t.Fatalf("concurrency=%d: path = %v, want %v", concurrency, budgetErr.Path, want)
+		}
+		for i := range want {
+			if budgetErr.Path[i] != want[i] {
+				t.Fatalf("concurrency=%d: path = %v, want %v", concurrency, budgetErr.Path, want)
+			}
+		}
+		if finished.Payload["error_code"] != "budget_exhausted" {
+			t.Errorf("concurrency=%d: run.finished error_code = %v", concurrency, finished.Payload["error_code"])
+		}
+	}
+}
+
+func TestBudget_NodeVisitLimit(t *testing.T) {
+	opts := DefaultRunOptions()
+	opts.NodeVisitLimits = map[string]int{"b": 2}
+
+	_, err := NewRuntime().Run(context.Background(), newLoopGraph(t), core.NewEnvelope(), opts)
+
+	var budgetErr *BudgetExhaustedError
+	if !errors.As(err, &budgetErr) {
+		t.Fatalf("err = %v, want *BudgetExhaustedError", err)
+	}
+	if budgetErr.Reason != BudgetReasonNodeLimit || budgetErr.NodeID != "b" || budgetErr.Limit != 2 {
+		t.Errorf("unexpected budget error %+v", budgetErr)
+	}
+	if !errors.Is(err, ErrMaxHopsExceeded) {
+		t.Error("node limit errors should still match ErrMaxHopsExceeded")
+	}
+}
+
+func TestBudget_WithinLimitSucceeds(t *testing.T) {
+	g := graph.NewGraph("fanout")
+	for _, id := range []string{"start", "left", "right"} {
+		g.AddNode(core.NewNoopNode(id))
+	}
+	g.AddEdge("start", "left")
+	g.AddEdge("start", "right")
+	g.SetEntry("start")
+
+	opts := DefaultRunOptions()
+	opts.Concurrency = 2
+	opts.MaxNodeExecutions = 3
+
+	if _, err := NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestBudgetExhaustedError_Payload(t *testing.T) {
+	err := &BudgetExhaustedError{Reason: BudgetReasonRunLimit, NodeID: "x", Limit: 1, Executions: 1, Path: []string{"w", "x"}}
+	payload := err.Payload()
+	if payload["reason"] != BudgetReasonRunLimit || payload["node_id"] != "x" {
+		t.Fatalf("payload = %v", payload)
+	}
+	if got := err.Error(); got != "budget_exhausted: run reached its limit of 1 node executions at node x (path: w -> x)" {
+		t.Fatalf("Error() = %q", got)
+	}
+}
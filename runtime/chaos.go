@@ -0,0 +1,253 @@
+package runtime
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// ChaosFault names a kind of injected fault.
+type ChaosFault string
+
+const (
+	// ChaosFaultLatency delays a node before it executes.
+	ChaosFaultLatency ChaosFault = "latency"
+	// ChaosFaultRateLimit fails an LLM call as a provider 429.
+	ChaosFaultRateLimit ChaosFault = "rate_limit"
+	// ChaosFaultServerError fails an LLM call as a provider 500.
+	ChaosFaultServerError ChaosFault = "server_error"
+	// ChaosFaultTimeout fails an LLM call as a timed-out request.
+	ChaosFaultTimeout ChaosFault = "timeout"
+	// ChaosFaultToolDrop discards a tool's response after the tool ran.
+	ChaosFaultToolDrop ChaosFault = "tool_drop"
+)
+
+// defaultProviderFaults are drawn from when ChaosConfig.ProviderErrors is empty.
+var defaultProviderFaults = []ChaosFault{ChaosFaultRateLimit, ChaosFaultServerError, ChaosFaultTimeout}
+
+// ChaosConfig injects faults into a run so retry, fallback and error-port
+// configuration can be exercised before a real outage does it. Every
+// decision is derived from Seed, the node ID and how many times that node
+// has hit the injection point, so a seed reproduces the same faults in
+// sequential and concurrent runs alike.
+type ChaosConfig struct {
+	Seed int64 `json:"seed"`
+
+	// Nodes limits injection to these node IDs. Empty targets every node.
+	Nodes []string `json:"nodes,omitempty"`
+
+	// LatencyRate is the probability a node is delayed by a random duration
+	// between LatencyMinMs and LatencyMaxMs before it runs.
+	LatencyRate  float64 `json:"latency_rate,omitempty"`
+	LatencyMinMs int     `json:"latency_min_ms,omitempty"`
+	LatencyMaxMs int     `json:"latency_max_ms,omitempty"`
+
+	// ProviderErrorRate is the probability an LLM call attempt fails with
+	// one of ProviderErrors (default: rate_limit, server_error, timeout).
+	ProviderErrorRate float64      `json:"provider_error_rate,omitempty"`
+	ProviderErrors    []ChaosFault `json:"provider_errors,omitempty"`
+
+	// ToolDropRate is the probability a successful tool invocation's
+	// response is dropped and reported as an error.
+	ToolDropRate float64 `json:"tool_drop_rate,omitempty"`
+}
+
+// Validate checks rates and latency bounds.
+func (c *ChaosConfig) Validate() error {
+	var errs []error
+	rates := []struct {
+		name string
+		rate float64
+	}{
+		{"latency_rate", c.LatencyRate},
+		{"provider_error_rate", c.ProviderErrorRate},
+		{"tool_drop_rate", c.ToolDropRate},
+	}
+	for _, r := range rates {
+		if r.rate < 0 || r.rate > 1 {
+			errs = append(errs, fmt.Errorf("chaos.%s must be between 0 and 1, got %v", r.name, r.rate))
+		}
+	}
+	if c.LatencyMinMs < 0 || c.LatencyMaxMs < 0 {
+		errs = append(errs, errors.New("chaos latency bounds must not be negative"))
+	}
+	if c.LatencyMaxMs < c.LatencyMinMs {
+		errs = append(errs, fmt.Errorf("chaos.latency_max_ms (%d) is below latency_min_ms (%d)", c.LatencyMaxMs, c.LatencyMinMs))
+	}
+	for _, fault := range c.ProviderErrors {
+		if !slices.Contains(defaultProviderFaults, fault) {
+			errs = append(errs, fmt.Errorf("chaos.provider_errors: unsupported fault %q", fault))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ChaosError is returned by injected provider and tool faults.
+type ChaosError struct {
+	Fault  ChaosFault
+	NodeID string
+	// StatusCode is the HTTP status the fault imitates, if any.
+	StatusCode int
+}
+
+func (e *ChaosError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("chaos: injected %s (%d) at node %q", e.Fault, e.StatusCode, e.NodeID)
+	}
+	return fmt.Sprintf("chaos: injected %s at node %q", e.Fault, e.NodeID)
+}
+
+// Unwrap lets injected timeouts match context.DeadlineExceeded.
+func (e *ChaosError) Unwrap() error {
+	if e.Fault == ChaosFaultTimeout {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+type chaosInjector struct {
+	cfg ChaosConfig
+
+	mu    sync.Mutex
+	draws map[string]uint64
+}
+
+func newChaosInjector(cfg *ChaosConfig) *chaosInjector {
+	if cfg == nil {
+		return nil
+	}
+	return &chaosInjector{cfg: *cfg, draws: make(map[string]uint64)}
+}
+
+type chaosKey struct{}
+
+func contextWithChaos(ctx context.Context, c *chaosInjector) context.Context {
+	if c == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, chaosKey{}, c)
+}
+
+func chaosFromContext(ctx context.Context) *chaosInjector {
+	c, _ := ctx.Value(chaosKey{}).(*chaosInjector)
+	return c
+}
+
+func (c *chaosInjector) targets(nodeID string) bool {
+	return len(c.cfg.Nodes) == 0 || slices.Contains(c.cfg.Nodes, nodeID)
+}
+
+// roll returns a uniform value in [0, 1) for the next draw at site for
+// nodeID.
+func (c *chaosInjector) roll(site, nodeID string) float64 {
+	key := site + "\x00" + nodeID
+	c.mu.Lock()
+	n := c.draws[key]
+	c.draws[key] = n + 1
+	c.mu.Unlock()
+
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(c.cfg.Seed))
+	_, _ = h.Write(buf[:])
+	_, _ = h.Write([]byte(key))
+	binary.LittleEndian.PutUint64(buf[:], n)
+	_, _ = h.Write(buf[:])
+	return float64(h.Sum64()>>11) / (1 << 53)
+}
+
+func emitChaos(ctx context.Context, runID, nodeID string, kind core.NodeKind, fault ChaosFault, payload map[string]any) {
+	e := NewEvent(EventChaosInjected, runID).
+		WithNode(nodeID, kind).
+		WithPayload("fault", string(fault))
+	for k, v := range payload {
+		e = e.WithPayload(k, v)
+	}
+	EmitterFromContext(ctx)(e)
+}
+
+// injectLatency delays a node when the run's chaos config says so. It
+// returns early with the context error if the run is cancelled.
+func (c *chaosInjector) injectLatency(ctx context.Context, runID, nodeID string, kind core.NodeKind) error {
+	if c == nil || c.cfg.LatencyRate <= 0 || !c.targets(nodeID) {
+		return nil
+	}
+	if c.roll("latency", nodeID) >= c.cfg.LatencyRate {
+		return nil
+	}
+	spread := c.cfg.LatencyMaxMs - c.cfg.LatencyMinMs
+	delay := time.Duration(c.cfg.LatencyMinMs) * time.Millisecond
+	if spread > 0 {
+		delay += time.Duration(c.roll("latency.delay", nodeID)*float64(spread)) * time.Millisecond
+	}
+	emitChaos(ctx, runID, nodeID, kind, ChaosFaultLatency, map[string]any{"delay_ms": delay.Milliseconds()})
+
+	endWait := BeginWait(ctx, Wait{
+		RunID:    runID,
+		NodeID:   nodeID,
+		NodeKind: kind,
+		Reason:   WaitReasonDelay,
+		Deadline: time.Now().Add(delay),
+		Details:  map[string]any{"delay_ms": delay.Milliseconds(), "source": "chaos"},
+	})
+	defer endWait()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// InjectProviderFault is called by nodes before each LLM call attempt. It
+// returns a *ChaosError when the run's chaos config injects a provider
+// failure, and nil otherwise or when chaos is not enabled.
+func InjectProviderFault(ctx context.Context, runID, nodeID string, kind core.NodeKind) error {
+	c := chaosFromContext(ctx)
+	if c == nil || c.cfg.ProviderErrorRate <= 0 || !c.targets(nodeID) {
+		return nil
+	}
+	if c.roll("provider", nodeID) >= c.cfg.ProviderErrorRate {
+		return nil
+	}
+	faults := c.cfg.ProviderErrors
+	if len(faults) == 0 {
+		faults = defaultProviderFaults
+	}
+	fault := faults[int(c.roll("provider.fault", nodeID)*float64(len(faults)))]
+
+	err := &ChaosError{Fault: fault, NodeID: nodeID}
+	switch fault {
+	case ChaosFaultRateLimit:
+		err.StatusCode = http.StatusTooManyRequests
+	case ChaosFaultServerError:
+		err.StatusCode = http.StatusInternalServerError
+	}
+	emitChaos(ctx, runID, nodeID, kind, fault, map[string]any{"status_code": err.StatusCode})
+	return err
+}
+
+// InjectToolDrop is called by nodes after a tool invocation succeeds. It
+// returns a *ChaosError when the response should be treated as lost. The
+// tool has already run, which is the point: retries must be safe.
+func InjectToolDrop(ctx context.Context, runID, nodeID string, kind core.NodeKind) error {
+	c := chaosFromContext(ctx)
+	if c == nil || c.cfg.ToolDropRate <= 0 || !c.targets(nodeID) {
+		return nil
+	}
+	if c.roll("tool_drop", nodeID) >= c.cfg.ToolDropRate {
+		return nil
+	}
+	emitChaos(ctx, runID, nodeID, kind, ChaosFaultToolDrop, nil)
+	return &ChaosError{Fault: ChaosFaultToolDrop, NodeID: nodeID}
+}
@@ -0,0 +1,118 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+)
+
+func chaosFaults(cfg ChaosConfig, nodeIDs []string, rounds int) []string {
+	ctx := contextWithChaos(context.Background(), newChaosInjector(&cfg))
+	var out []string
+	for i := 0; i < rounds; i++ {
+		for _, id := range nodeIDs {
+			err := InjectProviderFault(ctx, "run", id, core.NodeKindLLM)
+			var chaosErr *ChaosError
+			if errors.As(err, &chaosErr) {
+				out = append(out, id+":"+string(chaosErr.Fault))
+			} else {
+				out = append(out, id+":ok")
+			}
+		}
+	}
+	return out
+}
+
+func TestChaos_SeededFaultsAreReproducible(t *testing.T) {
+	cfg := ChaosConfig{Seed: 42, ProviderErrorRate: 0.5}
+
+	first := chaosFaults(cfg, []string{"a", "b"}, 20)
+	if got := chaosFaults(cfg, []string{"a", "b"}, 20); strings.Join(got, ",") != strings.Join(first, ",") {
+		t.Fatalf("same seed gave different faults:\n%v\n%v", first, got)
+	}
+
+	// Interleaving nodes differently must not change each node's sequence.
+	var onlyA []string
+	for _, f := range first {
+		if strings.HasPrefix(f, "a:") {
+			onlyA = append(onlyA, f)
+		}
+	}
+	if got := chaosFaults(cfg, []string{"a"}, 20); strings.Join(got, ",") != strings.Join(onlyA, ",") {
+		t.Errorf("per-node sequence depends on scheduling:\n%v\n%v", onlyA, got)
+	}
+
+	cfg.Seed = 7
+	if got := chaosFaults(cfg, []string{"a", "b"}, 20); strings.Join(got, ",") == strings.Join(first, ",") {
+		t.Error("different seeds gave identical faults")
+	}
+}
+
+func TestChaos_ProviderFaultKinds(t *testing.T) {
+	ctx := contextWithChaos(context.Background(), newChaosInjector(&ChaosConfig{
+		ProviderErrorRate: 1,
+		ProviderErrors:    []ChaosFault{ChaosFaultTimeout},
+		Nodes:             []string{"llm"},
+	}))
+
+	err := InjectProviderFault(ctx, "run", "llm", core.NodeKindLLM)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("timeout fault = %v, want DeadlineExceeded", err)
+	}
+	if err := InjectProviderFault(ctx, "run", "other", core.NodeKindLLM); err != nil {
+		t.Errorf("untargeted node got fault %v", err)
+	}
+	if err := InjectProviderFault(context.Background(), "run", "llm", core.NodeKindLLM); err != nil {
+		t.Errorf("fault without chaos config: %v", err)
+	}
+}
+
+func TestChaos_Validate(t *testing.T) {
+	cfg := ChaosConfig{
+		LatencyRate:    1.5,
+		LatencyMinMs:   100,
+		LatencyMaxMs:   10,
+		ProviderErrors: []ChaosFault{"meteor"},
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{"latency_rate", "latency_max_ms", "meteor"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+
+	_, err = NewRuntime().Run(context.Background(), graph.NewGraph("g"), nil, RunOptions{Chaos: &cfg})
+	if err == nil {
+		t.Error("Run accepted an invalid chaos config")
+	}
+}
+
+func TestChaos_LatencyEmitsEvent(t *testing.T) {
+	g := graph.NewGraph("latency")
+	g.AddNode(core.NewFuncNode("a", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		return env, nil
+	}))
+	_ = g.SetEntry("a")
+
+	var injected []Event
+	opts := DefaultRunOptions()
+	opts.Chaos = &ChaosConfig{Seed: 1, LatencyRate: 1, LatencyMinMs: 1, LatencyMaxMs: 2}
+	opts.EventHandler = func(e Event) {
+		if e.Kind == EventChaosInjected {
+			injected = append(injected, e)
+		}
+	}
+	if _, err := NewRuntime().Run(context.Background(), g, nil, opts); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(injected) != 1 || injected[0].NodeID != "a" || injected[0].Payload["fault"] != "latency" {
+		t.Fatalf("chaos events = %+v", injected)
+	}
+}
@@ -1,6 +1,10 @@
 package runtime
 
-import "context"
+import (
+	"context"
+
+	"github.com/petal-labs/petalflow/core"
+)
 
 // emitterKey is an unexported type used as the context key for EventEmitter.
 // Using an unexported struct type prevents collisions with keys from other packages.
@@ -19,3 +23,17 @@ func EmitterFromContext(ctx context.Context) EventEmitter {
 	}
 	return func(Event) {}
 }
+
+// nodeKey is the context key for the node a context is executing.
+type nodeKey struct{}
+
+type nodeScope struct {
+	runID  string
+	nodeID string
+	kind   core.NodeKind
+}
+
+// contextWithNode records the run and node executing with ctx.
+func contextWithNode(ctx context.Context, runID, nodeID string, kind core.NodeKind) context.Context {
+	return context.WithValue(ctx, nodeKey{}, nodeScope{runID: runID, nodeID: nodeID, kind: kind})
+}
@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// ErrDeadlineExceeded is matched (via errors.Is) by the error of a run that
+// was still executing at its RunOptions.Deadline.
+var ErrDeadlineExceeded = errors.New("deadline_exceeded")
+
+// withRunDeadline bounds ctx by the run's deadline. A zero deadline
+// returns ctx unchanged with a no-op cancel.
+func withRunDeadline(ctx context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	if deadline.IsZero() {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, deadline)
+}
+
+// runDeadlineError marks err as ErrDeadlineExceeded when the run failed
+// because its deadline passed. Other errors are returned unchanged.
+func runDeadlineError(err error, deadline, now time.Time) error {
+	if err == nil || deadline.IsZero() || now.Before(deadline) || !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%w at %s: %w", ErrDeadlineExceeded, deadline.UTC().Format(time.RFC3339), err)
+}
+
+// EffectiveTimeout returns min(timeout, time remaining until ctx's deadline)
+// and whether the deadline was the tighter bound. A non-positive timeout
+// means "no node timeout", so only the deadline applies.
+func EffectiveTimeout(ctx context.Context, timeout time.Duration) (time.Duration, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return timeout, false
+	}
+	remaining := time.Until(deadline)
+	if remaining < 0 {
+		remaining = 0
+	}
+	if timeout > 0 && timeout <= remaining {
+		return timeout, false
+	}
+	return remaining, timeout > 0
+}
+
+// WithNodeTimeout derives a context for an external call made by a node.
+// The call is bounded by both timeout and the run deadline already on ctx;
+// when the run deadline is tighter, EventNodeDeadlineClamped is emitted on
+// the context's emitter so the shortened budget is visible in the run log.
+//
+// A non-positive timeout returns ctx unchanged with a no-op cancel.
+func WithNodeTimeout(
+	ctx context.Context,
+	timeout time.Duration,
+	runID, nodeID string,
+	nodeKind core.NodeKind,
+) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	effective, clamped := EffectiveTimeout(ctx, timeout)
+	if clamped {
+		EmitterFromContext(ctx)(NewEvent(EventNodeDeadlineClamped, runID).
+			WithNode(nodeID, nodeKind).
+			WithPayload("configured_timeout_ms", timeout.Milliseconds()).
+			WithPayload("effective_timeout_ms", effective.Milliseconds()))
+	}
+	// context.WithTimeout already honors an earlier parent deadline, so the
+	// configured timeout is passed through unchanged.
+	return context.WithTimeout(ctx, timeout)
+}
+
+// WithCallTimeout is WithNodeTimeout for code that is handed a node's
+// context but not the node itself, such as a tool adapter applying its
+// manifest timeout. The event names the run and node executing ctx.
+func WithCallTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	scope, _ := ctx.Value(nodeKey{}).(nodeScope)
+	return WithNodeTimeout(ctx, timeout, scope.runID, scope.nodeID, scope.kind)
+}
@@ -0,0 +1,130 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+)
+
+func TestEffectiveTimeout(t *testing.T) {
+	if got, clamped := EffectiveTimeout(context.Background(), time.Second); got != time.Second || clamped {
+		t.Errorf("no deadline: got (%v, %v), want (1s, false)", got, clamped)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	if got, clamped := EffectiveTimeout(ctx, time.Second); got != time.Second || clamped {
+		t.Errorf("distant deadline: got (%v, %v), want (1s, false)", got, clamped)
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	got, clamped := EffectiveTimeout(ctx, time.Minute)
+	if !clamped || got > 100*time.Millisecond {
+		t.Errorf("near deadline: got (%v, %v), want (<=100ms, true)", got, clamped)
+	}
+	if _, clamped := EffectiveTimeout(ctx, 0); clamped {
+		t.Error("a zero timeout is never reported as clamped")
+	}
+}
+
+func TestWithNodeTimeout_EmitsWhenClamped(t *testing.T) {
+	var events []Event
+	parent, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	parent = ContextWithEmitter(parent, func(e Event) { events = append(events, e) })
+
+	ctx, cancelNode := WithNodeTimeout(parent, time.Minute, "run-1", "call", core.NodeKindTool)
+	defer cancelNode()
+
+	parentDeadline, _ := parent.Deadline()
+	if deadline, _ := ctx.Deadline(); !deadline.Equal(parentDeadline) {
+		t.Errorf("deadline = %v, want run deadline %v", deadline, parentDeadline)
+	}
+	if len(events) != 1 || events[0].Kind != EventNodeDeadlineClamped {
+		t.Fatalf("events = %+v, want one %s", events, EventNodeDeadlineClamped)
+	}
+	if events[0].NodeID != "call" || events[0].Payload["configured_timeout_ms"] != int64(60000) {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+
+	events = nil
+	ctx, cancelNode = WithNodeTimeout(parent, 10*time.Millisecond, "run-1", "call", core.NodeKindTool)
+	defer cancelNode()
+	if deadline, _ := ctx.Deadline(); !deadline.Before(parentDeadline) {
+		t.Error("a shorter node timeout should tighten the deadline")
+	}
+	if len(events) != 0 {
+		t.Errorf("unexpected events when not clamped: %+v", events)
+	}
+}
+
+func TestWithCallTimeout_NamesExecutingNode(t *testing.T) {
+	var events []Event
+	parent, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	parent = contextWithNode(ContextWithEmitter(parent, func(e Event) { events = append(events, e) }),
+		"run-1", "call", core.NodeKindTool)
+
+	ctx, cancelCall := WithCallTimeout(parent, time.Minute)
+	defer cancelCall()
+
+	parentDeadline, _ := parent.Deadline()
+	if deadline, _ := ctx.Deadline(); !deadline.Equal(parentDeadline) {
+		t.Errorf("deadline = %v, want run deadline %v", deadline, parentDeadline)
+	}
+	if len(events) != 1 || events[0].Kind != EventNodeDeadlineClamped {
+		t.Fatalf("events = %+v, want one %s", events, EventNodeDeadlineClamped)
+	}
+	if events[0].RunID != "run-1" || events[0].NodeID != "call" || events[0].NodeKind != core.NodeKindTool {
+		t.Errorf("unexpected event %+v", events[0])
+	}
+}
+
+func TestRun_Deadline(t *testing.T) {
+	run := func(sleep time.Duration, deadline time.Time) ([]Event, error) {
+		g := graph.NewGraph("deadline")
+		g.AddNode(core.NewFuncNode("work", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+			select {
+			case <-time.After(sleep):
+				return env, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}))
+		g.SetEntry("work")
+
+		var events []Event
+		opts := DefaultRunOptions()
+		opts.Priority = 3
+		opts.Deadline = deadline
+		opts.EventHandler = func(e Event) { events = append(events, e) }
+		_, err := NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+		return events, err
+	}
+
+	events, err := run(0, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	started, finished := events[0], events[len(events)-1]
+	if started.Payload["priority"] != 3 || started.Payload["deadline"] == nil {
+		t.Errorf("run.started payload = %v", started.Payload)
+	}
+	if finished.Payload["deadline_met"] != true {
+		t.Errorf("run.finished payload = %v, want deadline met", finished.Payload)
+	}
+
+	events, err = run(time.Minute, time.Now().Add(20*time.Millisecond))
+	if !errors.Is(err, ErrDeadlineExceeded) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run() error = %v, want deadline exceeded", err)
+	}
+	finished = events[len(events)-1]
+	if finished.Payload["deadline_met"] != false || finished.Payload["error_code"] != "deadline_exceeded" {
+		t.Errorf("run.finished payload = %v, want deadline missed", finished.Payload)
+	}
+}
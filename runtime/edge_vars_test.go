@@ -0,0 +1,68 @@
+package runtime_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func TestRuntime_Run_EdgeVars(t *testing.T) {
+	var mu sync.Mutex
+	seen := make(map[string]any)
+	reader := func(id string) core.Node {
+		return core.NewFuncNode(id, func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+			mu.Lock()
+			seen[id], _ = env.GetVar("text")
+			mu.Unlock()
+			return env, nil
+		})
+	}
+
+	g := graph.NewGraph("edge_vars")
+	g.AddNode(core.NewFuncNode("fetch", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		env.SetVar("ticket", map[string]any{"body": "  Refund please ", "lang": "EN"})
+		return env, nil
+	}))
+	g.AddNode(reader("summarize"))
+	g.AddNode(reader("translate"))
+	g.AddEdge("fetch", "summarize")
+	g.AddEdge("fetch", "translate")
+	g.SetEntry("fetch")
+	if err := g.SetEdgeVars("fetch", "summarize", []graph.VarMapping{
+		{From: "ticket.body", To: "text", Transform: graph.VarTransformTrim},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.SetEdgeVars("fetch", "translate", []graph.VarMapping{
+		{From: "ticket.lang", To: "text", Transform: graph.VarTransformLower},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Parallel branches each get their own edge's mapping.
+	opts := runtime.DefaultRunOptions()
+	opts.Concurrency = 2
+	if _, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if seen["summarize"] != "Refund please" || seen["translate"] != "en" {
+		t.Errorf("seen = %v", seen)
+	}
+
+	if err := g.SetEdgeVars("fetch", "summarize", []graph.VarMapping{{From: "ticket.missing", To: "text"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, concurrency := range []int{1, 2} {
+		opts.Concurrency = concurrency
+		_, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+		var missing *graph.MissingEdgeVarError
+		if !errors.As(err, &missing) || missing.Var != "ticket.missing" {
+			t.Errorf("concurrency %d: err = %v, want a missing edge var error", concurrency, err)
+		}
+	}
+}
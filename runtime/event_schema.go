@@ -0,0 +1,399 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PayloadSchemaVersion is the payload key carrying the version of the
+// event's payload schema. The run emitter adds it to every event of a
+// known kind.
+const PayloadSchemaVersion = "schema_version"
+
+// Payload field types, as in JSON Schema. An empty type allows any value.
+const (
+	FieldString  = "string"
+	FieldInteger = "integer"
+	FieldNumber  = "number"
+	FieldBoolean = "boolean"
+	FieldArray   = "array"
+	FieldObject  = "object"
+)
+
+// EventField describes one payload field of an event kind.
+type EventField struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// EventSchema describes the payload of an event kind. Version goes up
+// whenever a field is removed, renamed or changes type; new optional
+// fields keep it. Payloads may carry fields the schema does not list.
+type EventSchema struct {
+	Kind        EventKind    `json:"kind"`
+	Version     int          `json:"version"`
+	Description string       `json:"description"`
+	Fields      []EventField `json:"fields"`
+}
+
+func stringField(name, description string) EventField {
+	return EventField{Name: name, Type: FieldString, Description: description}
+}
+
+func integerField(name, description string) EventField {
+	return EventField{Name: name, Type: FieldInteger, Description: description}
+}
+
+func requiredField(f EventField) EventField {
+	f.Required = true
+	return f
+}
+
+func typedField(name, typ, description string) EventField {
+	return EventField{Name: name, Type: typ, Description: description}
+}
+
+var nodeDocFields = []EventField{
+	stringField("description", "The node's description, when it has one."),
+	stringField("doc_url", "The node's documentation link, when it has one."),
+}
+
+var tokenFields = []EventField{
+	integerField("input_tokens", "Input tokens used."),
+	integerField("output_tokens", "Output tokens used."),
+	integerField("total_tokens", "Total tokens used."),
+	typedField("cost_usd", FieldNumber, "Estimated cost in US dollars."),
+}
+
+// eventSchemas is the event taxonomy, one schema per kind.
+var eventSchemas = []EventSchema{
+	{Kind: EventRunStarted, Version: 1, Description: "A run began.", Fields: []EventField{
+		requiredField(stringField("graph", "Name of the graph.")),
+		requiredField(stringField("entry", "The entry node.")),
+		stringField("trigger", "What started the run, such as api, webhook, schedule or rerun."),
+		stringField("workflow_id", "The stored workflow run."),
+		stringField("workflow_version", "The workflow's version."),
+		typedField("environment", FieldObject, "What the run was hydrated with: workflow revision, models, prompts, providers and tools."),
+		typedField("features", FieldObject, "The node types, config fields, template functions and expression operators each node uses, by node ID."),
+		typedField("graph_definition", FieldObject, "The graph definition, when snapshots are captured."),
+		typedField("inputs", "", "The run's starting variables, when recorded."),
+		typedField("sla", FieldObject, "The workflow's SLA targets in milliseconds."),
+		stringField("rerun_of", "The run this run reruns."),
+		typedField("deprecation", FieldObject, "The reason, sunset date and replacement of a deprecated workflow."),
+		integerField("priority", "The caller's priority hint, when not 0."),
+		stringField("deadline", "When the caller needs the run finished, RFC 3339."),
+	}},
+	{Kind: EventRunFinished, Version: 1, Description: "A run ended.", Fields: []EventField{
+		requiredField(stringField("status", "completed, failed or interrupted.")),
+		stringField("error", "Why the run failed."),
+		stringField("error_code", "A machine-readable failure cause, such as a budget or memory limit."),
+		typedField("budget", FieldObject, "The exhausted budget."),
+		typedField("memory", FieldObject, "The exceeded memory limit."),
+		typedField("sla_breaches", FieldArray, "The SLA targets the run missed."),
+		typedField("deadline_met", FieldBoolean, "Whether a run with a deadline completed by it."),
+		stringField("workflow_id", "The stored workflow run."),
+		stringField("last_heartbeat_at", "The last heartbeat of an interrupted run."),
+	}},
+	{Kind: EventNodeStarted, Version: 1, Description: "A node began executing.", Fields: nodeDocFields},
+	{Kind: EventNodeOutput, Version: 1, Description: "A node produced intermediate output."},
+	{Kind: EventNodeFailed, Version: 1, Description: "A node failed.", Fields: append([]EventField{
+		requiredField(stringField("error", "The error.")),
+		typedField("details", FieldObject, "Structured error details, such as a provider error."),
+	}, nodeDocFields...)},
+	{Kind: EventNodeFinished, Version: 1, Description: "A node completed.", Fields: []EventField{
+		typedField("output", FieldObject, "The node's outputs, when recorded for resumes."),
+	}},
+	{Kind: EventNodeRestored, Version: 1, Description: "A resumed run restored a node's output instead of executing it.", Fields: []EventField{
+		stringField("reason", "Why the node was restored."),
+	}},
+	{Kind: EventRouteDecision, Version: 1, Description: "A router or gate picked its targets.", Fields: []EventField{
+		requiredField(typedField("targets", FieldArray, "The chosen target nodes.")),
+		stringField("reason", "Why they were chosen."),
+		typedField("confidence", FieldNumber, "Confidence in the decision, 0 to 1."),
+	}},
+	{Kind: EventStepPaused, Version: 1, Description: "Execution paused at a step point.", Fields: []EventField{
+		requiredField(stringField("step_id", "The step request.")),
+		stringField("step_point", "Where execution paused."),
+		integerField("hop_count", "Edges followed so far."),
+	}},
+	{Kind: EventStepResumed, Version: 1, Description: "Execution resumed after a step.", Fields: []EventField{
+		requiredField(stringField("step_id", "The step request.")),
+		stringField("action", "The step action taken."),
+	}},
+	{Kind: EventStepSkipped, Version: 1, Description: "A node was skipped by a step action.", Fields: []EventField{
+		stringField("reason", "Why the node was skipped."),
+	}},
+	{Kind: EventStepAborted, Version: 1, Description: "Execution was aborted by a step action.", Fields: []EventField{
+		stringField("reason", "Why execution was aborted."),
+	}},
+	{Kind: EventToolCall, Version: 1, Description: "A tool invocation began.", Fields: []EventField{
+		requiredField(stringField("tool_name", "The tool.")),
+		typedField("arguments", FieldObject, "The invocation's arguments."),
+	}},
+	{Kind: EventToolResult, Version: 1, Description: "A tool invocation completed.", Fields: []EventField{
+		requiredField(stringField("tool_name", "The tool.")),
+		typedField("is_error", FieldBoolean, "Whether the invocation failed."),
+		typedField("artifacts", FieldArray, "Files the tool returned, stored as run artifacts."),
+		stringField("cache", "Tool cache outcome: hit, miss or bypass. Absent for tools that are not cacheable."),
+	}},
+	{Kind: EventNodeOutputDelta, Version: 1, Description: "Incremental streaming output of a node.", Fields: []EventField{
+		stringField("delta", "Streamed text."),
+		integerField("index", "Position of the chunk or item."),
+		typedField("result", "", "A map node's item result."),
+	}},
+	{Kind: EventNodeOutputFinal, Version: 1, Description: "The final consolidated output of a node.", Fields: append([]EventField{
+		stringField("text", "The complete output text."),
+		integerField("cache_read_tokens", "Input tokens read from the provider's prompt cache."),
+		integerField("cache_write_tokens", "Input tokens written to the provider's prompt cache."),
+		integerField("count", "Items a map node processed."),
+		stringField("region", "The provider region that answered an LLM node."),
+	}, tokenFields...)},
+	{Kind: EventNodeOutputPreview, Version: 1, Description: "A preview of a node's output before it completes."},
+	{Kind: EventRunSnapshot, Version: 1, Description: "A point-in-time snapshot of run state."},
+	{Kind: EventLLMCall, Version: 1, Description: "An LLM request is about to be sent.", Fields: []EventField{
+		stringField("model", "The requested model."),
+		stringField("system_prompt", "The system prompt."),
+		stringField("instructions", "Provider instructions."),
+		stringField("messages", "The messages, JSON-encoded."),
+		stringField("input_text", "The prompt."),
+		typedField("temperature", FieldNumber, "The sampling temperature."),
+		integerField("max_tokens", "The output token limit."),
+		stringField("json_schema", "The structured output schema, JSON-encoded."),
+	}},
+	{Kind: EventLLMResponse, Version: 1, Description: "An LLM response arrived.", Fields: append([]EventField{
+		requiredField(stringField("status", "success or error.")),
+		requiredField(integerField("latency_ms", "Time to the full response.")),
+		stringField("model", "The requested model."),
+		stringField("error", "Why the call failed."),
+		stringField("provider", "The provider that answered."),
+		stringField("response_model", "The model that answered."),
+		stringField("region", "The provider region that answered."),
+		stringField("completion", "The response text."),
+		stringField("stop_reason", "Why generation stopped."),
+	}, tokenFields...)},
+	{Kind: EventEdgeTransfer, Version: 1, Description: "Data flowed between nodes along an edge.", Fields: []EventField{
+		stringField("source_node", "The source node."),
+		stringField("source_port", "The source handle."),
+		stringField("target_node", "The target node."),
+		stringField("target_port", "The target handle."),
+		integerField("data_size_bytes", "Size of the data."),
+		stringField("data_preview", "The start of the data."),
+	}},
+	{Kind: EventNodeDeadlineClamped, Version: 1, Description: "A node's timeout was shortened to fit the run deadline.", Fields: []EventField{
+		requiredField(integerField("configured_timeout_ms", "The node's configured timeout.")),
+		requiredField(integerField("effective_timeout_ms", "The timeout applied.")),
+	}},
+	{Kind: EventAgentHandoff, Version: 1, Description: "An agent transferred a task to another agent.", Fields: []EventField{
+		requiredField(stringField("task", "The task.")),
+		requiredField(stringField("from_agent", "The agent handing off.")),
+		requiredField(stringField("to_agent", "The agent taking over.")),
+		stringField("target", "The node taking over."),
+		stringField("reason", "Why the task was handed off."),
+		typedField("confidence", FieldNumber, "Confidence in the handoff, 0 to 1."),
+	}},
+	{Kind: EventChaosInjected, Version: 1, Description: "The run's chaos config injected a fault.", Fields: []EventField{
+		requiredField(stringField("fault", "The fault injected.")),
+		integerField("delay_ms", "Injected latency."),
+		integerField("status_code", "Injected provider error status."),
+	}},
+	{Kind: EventNodeOutputDrift, Version: 1, Description: "An LLM node's output deviated from its previous outputs.", Fields: []EventField{
+		requiredField(stringField("key", "The drift history key.")),
+		requiredField(stringField("action", "flag or fail.")),
+		typedField("deviations", FieldArray, "The checks that found drift."),
+	}},
+	{Kind: EventModelSelected, Version: 1, Description: "A model_select node picked an arm.", Fields: []EventField{
+		requiredField(stringField("selection_id", "Identifies the choice for rewards.")),
+		requiredField(stringField("arm", "The chosen arm.")),
+		stringField("provider", "The arm's provider."),
+		stringField("model", "The arm's model."),
+		stringField("policy", "The bandit policy."),
+		typedField("explored", FieldBoolean, "Whether the arm was picked to explore."),
+	}},
+	{Kind: EventWaitStarted, Version: 1, Description: "A node blocked on something outside the run.", Fields: []EventField{
+		requiredField(stringField("wait_id", "Identifies the wait.")),
+		requiredField(stringField("reason", "What the node waits for, such as a human response.")),
+		stringField("deadline", "When the wait gives up, if ever."),
+	}},
+	{Kind: EventWaitFinished, Version: 1, Description: "A wait ended.", Fields: []EventField{
+		requiredField(stringField("wait_id", "Identifies the wait.")),
+		requiredField(stringField("reason", "What the node waited for.")),
+	}},
+	{Kind: EventNodeAssertionFailed, Version: 1, Description: "An assertion on a node's outputs was not met.", Fields: []EventField{
+		requiredField(stringField("expr", "The assertion.")),
+		stringField("message", "The assertion's message."),
+		stringField("action", "What the failed assertion does."),
+	}},
+	{Kind: EventDeliveryFinished, Version: 1, Description: "A webhook_call outbox request was delivered or given up on.", Fields: []EventField{
+		requiredField(stringField("delivery_id", "The outbox delivery.")),
+		requiredField(stringField("status", "The delivery's final status.")),
+		integerField("attempts", "Delivery attempts made."),
+		integerField("status_code", "The last response status."),
+		stringField("error", "The last delivery error."),
+	}},
+	{Kind: EventMemoryWarning, Version: 1, Description: "A run's memory use crossed its soft limit.", Fields: []EventField{
+		stringField("node_id", "The node whose output crossed the limit."),
+		integerField("limit", "The soft limit in bytes."),
+		integerField("envelope_bytes", "Approximate envelope size."),
+		integerField("artifact_bytes", "Approximate artifact size."),
+		integerField("event_bytes", "Approximate size of buffered events."),
+		integerField("total_bytes", "Approximate total."),
+	}},
+	{Kind: EventSLABreached, Version: 1, Description: "A run missed one of its workflow's SLA targets.", Fields: []EventField{
+		requiredField(stringField("sla", "The missed target.")),
+		requiredField(integerField("limit_ms", "The target.")),
+		requiredField(integerField("actual_ms", "What the run took.")),
+	}},
+	{Kind: EventBranchTimedOut, Version: 1, Description: "A branch of a fan-out ran past its timeout.", Fields: []EventField{
+		requiredField(stringField("source", "Source of the edge that started the branch.")),
+		requiredField(stringField("target", "Target of the edge that started the branch.")),
+		requiredField(stringField("timeout", "The branch timeout.")),
+		requiredField(stringField("on_timeout", "The policy applied: fail, partial or default.")),
+	}},
+	{Kind: EventOutputUpdated, Version: 1, Description: "A publish node added variables to the run's public output.", Fields: []EventField{
+		requiredField(typedField("output", FieldObject, "The published values by name. They replace earlier values of the same name.")),
+		typedField("missing", FieldArray, "Listed variables that were not set."),
+	}},
+}
+
+var eventSchemasByKind = func() map[EventKind]EventSchema {
+	m := make(map[EventKind]EventSchema, len(eventSchemas))
+	for _, s := range eventSchemas {
+		m[s.Kind] = s
+	}
+	return m
+}()
+
+// EventSchemas returns the payload schema of every event kind, sorted by
+// kind.
+func EventSchemas() []EventSchema {
+	out := append([]EventSchema(nil), eventSchemas...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Kind < out[j].Kind })
+	return out
+}
+
+// LookupEventSchema returns the payload schema of an event kind.
+func LookupEventSchema(kind EventKind) (EventSchema, bool) {
+	s, ok := eventSchemasByKind[kind]
+	return s, ok
+}
+
+// JSONSchema renders the payload schema as a JSON Schema (draft 2020-12)
+// document.
+func (s EventSchema) JSONSchema() map[string]any {
+	props := map[string]any{
+		PayloadSchemaVersion: map[string]any{"const": s.Version},
+	}
+	var req []string
+	for _, f := range s.Fields {
+		prop := map[string]any{}
+		if f.Type != "" {
+			prop["type"] = f.Type
+		}
+		if f.Description != "" {
+			prop["description"] = f.Description
+		}
+		props[f.Name] = prop
+		if f.Required {
+			req = append(req, f.Name)
+		}
+	}
+	doc := map[string]any{
+		"$schema":     "https://json-schema.org/draft/2020-12/schema",
+		"$id":         fmt.Sprintf("https://github.com/petal-labs/petalflow/schemas/events/%s/v%d.json", s.Kind, s.Version),
+		"title":       string(s.Kind),
+		"description": s.Description,
+		"type":        "object",
+		"properties":  props,
+	}
+	if len(req) > 0 {
+		doc["required"] = req
+	}
+	return doc
+}
+
+// WithSchemaVersion stamps the event with its kind's payload schema
+// version. Events of unknown kinds are returned unchanged.
+func (e Event) WithSchemaVersion() Event {
+	s, ok := eventSchemasByKind[e.Kind]
+	if !ok {
+		return e
+	}
+	return e.WithPayload(PayloadSchemaVersion, s.Version)
+}
+
+// EventSchemaError reports an event whose payload does not match its
+// kind's schema.
+type EventSchemaError struct {
+	Kind     EventKind
+	Problems []string
+}
+
+func (e *EventSchemaError) Error() string {
+	return fmt.Sprintf("%s event payload: %s", e.Kind, strings.Join(e.Problems, "; "))
+}
+
+// ValidateEvent checks an event's payload against its kind's schema:
+// required fields must be set and listed fields must have their type.
+// Fields the schema does not list, and events of unknown kinds, pass.
+func ValidateEvent(e Event) error {
+	s, ok := eventSchemasByKind[e.Kind]
+	if !ok {
+		return nil
+	}
+	// Checked as JSON, the form consumers see.
+	raw, err := json.Marshal(e.Payload)
+	if err != nil {
+		return &EventSchemaError{Kind: e.Kind, Problems: []string{err.Error()}}
+	}
+	var payload map[string]any
+	_ = json.Unmarshal(raw, &payload)
+
+	var problems []string
+	if v, ok := payload[PayloadSchemaVersion]; ok && v != float64(s.Version) {
+		problems = append(problems, fmt.Sprintf("schema_version is %v, want %d", v, s.Version))
+	}
+	for _, f := range s.Fields {
+		v, ok := payload[f.Name]
+		if !ok || v == nil {
+			if f.Required {
+				problems = append(problems, fmt.Sprintf("missing %s", f.Name))
+			}
+			continue
+		}
+		if f.Type != "" && !jsonTypeIs(v, f.Type) {
+			problems = append(problems, fmt.Sprintf("%s is not %s", f.Name, article(f.Type)))
+		}
+	}
+	if len(problems) > 0 {
+		return &EventSchemaError{Kind: e.Kind, Problems: problems}
+	}
+	return nil
+}
+
+// jsonTypeIs reports whether a decoded JSON value has a JSON Schema type.
+func jsonTypeIs(v any, typ string) bool {
+	switch v := v.(type) {
+	case string:
+		return typ == FieldString
+	case bool:
+		return typ == FieldBoolean
+	case float64:
+		return typ == FieldNumber || (typ == FieldInteger && v == float64(int64(v)))
+	case []any:
+		return typ == FieldArray
+	case map[string]any:
+		return typ == FieldObject
+	}
+	return false
+}
+
+func article(typ string) string {
+	if typ == FieldArray || typ == FieldInteger || typ == FieldObject {
+		return "an " + typ
+	}
+	return "a " + typ
+}
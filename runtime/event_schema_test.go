@@ -0,0 +1,125 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+)
+
+func TestEventSchemas_CoverEveryKind(t *testing.T) {
+	kinds := []EventKind{
+		EventRunStarted, EventRunFinished, EventNodeStarted, EventNodeOutput,
+		EventNodeFailed, EventNodeFinished, EventNodeRestored, EventRouteDecision,
+		EventStepPaused, EventStepResumed, EventStepSkipped, EventStepAborted,
+		EventToolCall, EventToolResult, EventNodeOutputDelta, EventNodeOutputFinal,
+		EventNodeOutputPreview, EventRunSnapshot, EventLLMCall, EventLLMResponse,
+		EventEdgeTransfer, EventNodeDeadlineClamped, EventAgentHandoff,
+		EventChaosInjected, EventNodeOutputDrift, EventModelSelected,
+		EventWaitStarted, EventWaitFinished, EventNodeAssertionFailed,
+		EventDeliveryFinished, EventMemoryWarning, EventSLABreached, EventBranchTimedOut,
+		EventOutputUpdated,
+	}
+	for _, kind := range kinds {
+		s, ok := LookupEventSchema(kind)
+		if !ok {
+			t.Errorf("no schema for %s", kind)
+			continue
+		}
+		if s.Version < 1 {
+			t.Errorf("%s: version = %d", kind, s.Version)
+		}
+	}
+	if got := len(EventSchemas()); got != len(kinds) {
+		t.Errorf("EventSchemas() has %d kinds, want %d", got, len(kinds))
+	}
+}
+
+func TestEventSchema_JSONSchema(t *testing.T) {
+	s, _ := LookupEventSchema(EventToolCall)
+	doc := s.JSONSchema()
+	if doc["$id"] != "https://github.com/petal-labs/petalflow/schemas/events/tool.call/v1.json" {
+		t.Errorf("$id = %v", doc["$id"])
+	}
+	if req, _ := doc["required"].([]string); len(req) != 1 || req[0] != "tool_name" {
+		t.Errorf("required = %v", doc["required"])
+	}
+	props := doc["properties"].(map[string]any)
+	if _, ok := props[PayloadSchemaVersion]; !ok {
+		t.Error("schema_version missing from properties")
+	}
+}
+
+func TestValidateEvent(t *testing.T) {
+	ok := NewEvent(EventToolCall, "run-1").
+		WithPayload("tool_name", "search").
+		WithPayload("arguments", map[string]any{"q": "go"}).
+		WithPayload("extra", true).
+		WithSchemaVersion()
+	if err := ValidateEvent(ok); err != nil {
+		t.Errorf("ValidateEvent(valid) = %v", err)
+	}
+
+	bad := NewEvent(EventLLMResponse, "run-1").
+		WithPayload("status", "success").
+		WithPayload("latency_ms", 1.5).
+		WithPayload("model", 4)
+	err := ValidateEvent(bad)
+	var schemaErr *EventSchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("ValidateEvent(invalid) = %v, want EventSchemaError", err)
+	}
+	want := []string{"latency_ms is not an integer", "model is not a string"}
+	if strings.Join(schemaErr.Problems, "; ") != strings.Join(want, "; ") {
+		t.Errorf("problems = %v, want %v", schemaErr.Problems, want)
+	}
+
+	if err := ValidateEvent(NewEvent(EventSLABreached, "run-1")); err == nil || !strings.Contains(err.Error(), "missing sla") {
+		t.Errorf("ValidateEvent(missing) = %v", err)
+	}
+	if err := ValidateEvent(NewEvent("custom.kind", "run-1")); err != nil {
+		t.Errorf("ValidateEvent(unknown kind) = %v", err)
+	}
+}
+
+func TestRun_StampsAndValidatesEvents(t *testing.T) {
+	g := graph.NewGraph("schemas")
+	g.AddNode(core.NewFuncNode("work", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		EmitterFromContext(ctx)(NewEvent(EventToolCall, env.Trace.RunID))
+		return env, nil
+	}))
+	g.SetEntry("work")
+
+	var mu sync.Mutex
+	var events []Event
+	var invalid []error
+	opts := DefaultRunOptions()
+	opts.EventHandler = func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+	opts.OnInvalidEvent = func(_ Event, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		invalid = append(invalid, err)
+	}
+	if _, err := NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, e := range events {
+		if e.Payload[PayloadSchemaVersion] != 1 {
+			t.Errorf("%s: schema_version = %v", e.Kind, e.Payload[PayloadSchemaVersion])
+		}
+	}
+	if len(invalid) != 1 || !strings.Contains(invalid[0].Error(), "tool.call event payload: missing tool_name") {
+		t.Errorf("invalid events = %v, want the tool.call without tool_name", invalid)
+	}
+}
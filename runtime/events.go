@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
 )
 
 // EventKind identifies the type of event emitted by the runtime.
@@ -27,6 +28,10 @@ const (
 	// EventNodeFinished is emitted when a node completes successfully.
 	EventNodeFinished EventKind = "node.finished"
 
+	// EventNodeRestored is emitted instead of node.started/node.finished when
+	// a resumed run restores a node's output from an earlier attempt.
+	EventNodeRestored EventKind = "node.restored"
+
 	// EventRouteDecision is emitted when a router node makes a routing decision.
 	EventRouteDecision EventKind = "route.decision"
 
@@ -55,6 +60,8 @@ const (
 	EventNodeOutputDelta EventKind = "node.output.delta"
 
 	// EventNodeOutputFinal is emitted for the final consolidated output from a node.
+	// LLM nodes include their token usage (input_tokens, output_tokens,
+	// total_tokens, cost_usd) in the payload.
 	EventNodeOutputFinal EventKind = "node.output.final"
 
 	// EventNodeOutputPreview is emitted for a preview of node output before completion.
@@ -77,6 +84,73 @@ const (
 	// Payload includes: source_node, source_port, target_node, target_port,
 	// data_size_bytes, data_preview.
 	EventEdgeTransfer EventKind = "edge.transfer"
+
+	// EventNodeDeadlineClamped is emitted when a node's configured timeout
+	// would outlive the run deadline and is shortened to fit it.
+	// Payload includes: configured_timeout_ms, effective_timeout_ms.
+	EventNodeDeadlineClamped EventKind = "node.deadline_clamped"
+
+	// EventAgentHandoff is emitted when an agent transfers a task to another
+	// agent. Payload includes: task, from_agent, to_agent, target, reason,
+	// confidence.
+	EventAgentHandoff EventKind = "agent.handoff"
+
+	// EventChaosInjected is emitted when a run's chaos config injects a
+	// fault. Payload includes: fault, and delay_ms or status_code where
+	// they apply.
+	EventChaosInjected EventKind = "chaos.injected"
+
+	// EventNodeOutputDrift is emitted when an LLM node's drift guard finds
+	// its output deviates from previous outputs. Payload includes: key,
+	// action and deviations.
+	EventNodeOutputDrift EventKind = "node.output.drift"
+
+	// EventModelSelected is emitted when a model_select node picks an arm.
+	// Payload includes: selection_id, arm, provider, model, policy and
+	// explored.
+	EventModelSelected EventKind = "model.selected"
+
+	// EventWaitStarted is emitted when a node blocks on something outside
+	// the run, such as a human response. Payload includes: wait_id,
+	// reason, deadline (when the wait gives up, if ever) and
+	// reason-specific details.
+	EventWaitStarted EventKind = "wait.started"
+
+	// EventWaitFinished is emitted when a wait ends, however it ends.
+	// Payload includes: wait_id and reason.
+	EventWaitFinished EventKind = "wait.finished"
+
+	// EventNodeAssertionFailed is emitted when an assertion on a node's
+	// outputs is not met. Payload includes: expr, message and action.
+	EventNodeAssertionFailed EventKind = "node.assertion_failed"
+
+	// EventDeliveryFinished is emitted, after the run, when the delivery
+	// worker delivers a webhook_call node's outbox request or gives up on
+	// it. Payload includes: delivery_id, status, attempts and, when known,
+	// status_code and error.
+	EventDeliveryFinished EventKind = "delivery.finished"
+
+	// EventMemoryWarning is emitted once per run when its approximate
+	// memory use first crosses MemoryConfig.SoftLimitBytes. Payload
+	// includes: node_id, limit, envelope_bytes, artifact_bytes,
+	// event_bytes and total_bytes.
+	EventMemoryWarning EventKind = "memory.warning"
+
+	// EventSLABreached is emitted when a run misses one of its workflow's
+	// SLA targets, at most once per target. Payload includes: sla,
+	// limit_ms and actual_ms.
+	EventSLABreached EventKind = "sla.breached"
+
+	// EventBranchTimedOut is emitted when a branch started by an edge with
+	// a branch policy runs past its timeout. The node is the one that was
+	// running. Payload includes: source, target, timeout and on_timeout.
+	EventBranchTimedOut EventKind = "branch.timed_out"
+
+	// EventOutputUpdated is emitted when a publish node adds variables to
+	// the run's public output while the run continues. Payload includes:
+	// output (the published values by name) and missing (listed variables
+	// that were not set).
+	EventOutputUpdated EventKind = "output.updated"
 )
 
 // String returns the string representation of the EventKind.
@@ -162,6 +236,17 @@ func (e Event) WithPayload(key string, value any) Event {
 	return e
 }
 
+// withNodeDoc adds a node's description and doc URL to the payload.
+func (e Event) withNodeDoc(doc graph.NodeDoc) Event {
+	if doc.Description != "" {
+		e = e.WithPayload("description", doc.Description)
+	}
+	if doc.DocURL != "" {
+		e = e.WithPayload("doc_url", doc.DocURL)
+	}
+	return e
+}
+
 // EventEmitter is a function type for emitting events.
 // The runtime provides an emitter to nodes that need to emit intermediate events.
 type EventEmitter func(Event)
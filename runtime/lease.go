@@ -0,0 +1,104 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DefaultLeaseTTL is used when LeaseConfig.TTL is zero.
+const DefaultLeaseTTL = 30 * time.Second
+
+// RunLease records that a run is executing. The runtime renews it while
+// the run is alive, so a lease whose ExpiresAt has passed belongs to a run
+// whose process stopped without finishing it.
+type RunLease struct {
+	RunID       string    `json:"run_id"`
+	WorkflowID  string    `json:"workflow_id,omitempty"`
+	Owner       string    `json:"owner"`
+	StartedAt   time.Time `json:"started_at"`
+	HeartbeatAt time.Time `json:"heartbeat_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	// Request is opaque data the lease owner needs to requeue the run.
+	Request json.RawMessage `json:"request,omitempty"`
+}
+
+// LeaseStore persists run leases.
+type LeaseStore interface {
+	AcquireLease(ctx context.Context, lease RunLease) error
+	RenewLease(ctx context.Context, runID string, heartbeatAt, expiresAt time.Time) error
+	ReleaseLease(ctx context.Context, runID string) error
+}
+
+// LeaseConfig makes a run hold a lease for as long as it executes.
+type LeaseConfig struct {
+	Store LeaseStore
+	// Owner identifies the executing process, for example host and pid.
+	Owner string
+	// TTL is how long a lease outlives its last heartbeat. Heartbeats are
+	// sent every TTL/3. Defaults to DefaultLeaseTTL.
+	TTL time.Duration
+	// Request is stored on the lease; see RunLease.Request.
+	Request json.RawMessage
+}
+
+// runHeartbeat renews a run's lease until stopped.
+type runHeartbeat struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// startLease acquires the run's lease and starts renewing it. A run that
+// cannot record its lease does not start, since nothing could recover it.
+func startLease(ctx context.Context, cfg *LeaseConfig, runID, workflowID string, now func() time.Time) (*runHeartbeat, error) {
+	if cfg == nil || cfg.Store == nil {
+		return nil, nil
+	}
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = DefaultLeaseTTL
+	}
+	started := now()
+	if err := cfg.Store.AcquireLease(ctx, RunLease{
+		RunID:       runID,
+		WorkflowID:  workflowID,
+		Owner:       cfg.Owner,
+		StartedAt:   started,
+		HeartbeatAt: started,
+		ExpiresAt:   started.Add(ttl),
+		Request:     cfg.Request,
+	}); err != nil {
+		return nil, fmt.Errorf("acquiring run lease: %w", err)
+	}
+
+	hb := &runHeartbeat{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		defer close(hb.done)
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-hb.stop:
+				return
+			case <-ticker.C:
+				// A failed renewal is retried on the next tick; if the
+				// lease lapses meanwhile, the janitor treats the run as
+				// interrupted.
+				at := now()
+				_ = cfg.Store.RenewLease(context.WithoutCancel(ctx), runID, at, at.Add(ttl))
+			}
+		}
+	}()
+	return hb, nil
+}
+
+// finish stops heartbeats and releases the lease.
+func (hb *runHeartbeat) finish(ctx context.Context, cfg *LeaseConfig, runID string) {
+	if hb == nil {
+		return
+	}
+	close(hb.stop)
+	<-hb.done
+	_ = cfg.Store.ReleaseLease(context.WithoutCancel(ctx), runID)
+}
@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+)
+
+type memLeaseStore struct {
+	mu         sync.Mutex
+	acquireErr error
+	acquired   []RunLease
+	renewals   int
+	released   []string
+}
+
+func (s *memLeaseStore) AcquireLease(_ context.Context, lease RunLease) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.acquireErr != nil {
+		return s.acquireErr
+	}
+	s.acquired = append(s.acquired, lease)
+	return nil
+}
+
+func (s *memLeaseStore) RenewLease(_ context.Context, runID string, heartbeatAt, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renewals++
+	return nil
+}
+
+func (s *memLeaseStore) ReleaseLease(_ context.Context, runID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.released = append(s.released, runID)
+	return nil
+}
+
+func sleepGraph(d time.Duration) *graph.BasicGraph {
+	g := graph.NewGraph("lease")
+	g.AddNode(core.NewFuncNode("slow", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		time.Sleep(d)
+		return env, nil
+	}))
+	_ = g.SetEntry("slow")
+	return g
+}
+
+func TestLease_HeartbeatsWhileRunning(t *testing.T) {
+	store := &memLeaseStore{}
+	opts := DefaultRunOptions()
+	opts.WorkflowID = "wf"
+	opts.Lease = &LeaseConfig{Store: store, Owner: "host:1", TTL: 30 * time.Millisecond, Request: []byte(`{"x":1}`)}
+
+	env, err := NewRuntime().Run(context.Background(), sleepGraph(60*time.Millisecond), nil, opts)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if len(store.acquired) != 1 {
+		t.Fatalf("acquired %d leases, want 1", len(store.acquired))
+	}
+	lease := store.acquired[0]
+	if lease.RunID != env.Trace.RunID || lease.WorkflowID != "wf" || lease.Owner != "host:1" || string(lease.Request) != `{"x":1}` {
+		t.Errorf("lease = %+v", lease)
+	}
+	if got := lease.ExpiresAt.Sub(lease.StartedAt); got != 30*time.Millisecond {
+		t.Errorf("lease TTL = %s, want 30ms", got)
+	}
+	if store.renewals == 0 {
+		t.Error("lease was never renewed during a run longer than its TTL")
+	}
+	if len(store.released) != 1 || store.released[0] != env.Trace.RunID {
+		t.Errorf("released = %v", store.released)
+	}
+}
+
+func TestLease_AcquireFailureStopsRun(t *testing.T) {
+	store := &memLeaseStore{acquireErr: errors.New("db locked")}
+	opts := DefaultRunOptions()
+	opts.Lease = &LeaseConfig{Store: store}
+
+	ran := false
+	g := graph.NewGraph("lease")
+	g.AddNode(core.NewFuncNode("a", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		ran = true
+		return env, nil
+	}))
+	_ = g.SetEntry("a")
+
+	if _, err := NewRuntime().Run(context.Background(), g, nil, opts); err == nil {
+		t.Fatal("expected error when the lease cannot be acquired")
+	}
+	if ran {
+		t.Error("run executed without a lease")
+	}
+}
@@ -0,0 +1,82 @@
+package runtime
+
+import (
+	"reflect"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// varLifetimes enforces RunOptions.VarLifetimes.
+type varLifetimes map[string]graph.VarLifetime
+
+// nodeScoped returns the node-scoped variables env holds before a node
+// runs, so afterNode can tell which of them the node set itself.
+func (l varLifetimes) nodeScoped(env *core.Envelope) map[string]any {
+	if len(l) == 0 || env == nil {
+		return nil
+	}
+	held := make(map[string]any)
+	for name, lt := range l {
+		if lt.Scope != graph.VarScopeNode {
+			continue
+		}
+		if v, ok := env.Vars[name]; ok {
+			held[name] = v
+		}
+	}
+	return held
+}
+
+// afterNode drops the node-scoped variables the node inherited and left
+// unchanged, and the branch-scoped variables when the node merges
+// branches.
+func (l varLifetimes) afterNode(node core.Node, held map[string]any, result *core.Envelope) {
+	if len(l) == 0 || result == nil {
+		return
+	}
+	for name, before := range held {
+		if after, ok := result.Vars[name]; ok && reflect.DeepEqual(before, after) {
+			delete(result.Vars, name)
+		}
+	}
+	if _, merges := node.(core.MergeCapable); merges {
+		l.drop(result, graph.VarScopeBranch)
+	}
+}
+
+// drop removes the variables with any of the given scopes.
+func (l varLifetimes) drop(env *core.Envelope, scopes ...string) {
+	if len(l) == 0 || env == nil {
+		return
+	}
+	for name, lt := range l {
+		for _, scope := range scopes {
+			if lt.Scope == scope {
+				delete(env.Vars, name)
+			}
+		}
+	}
+}
+
+// persistable returns vars without the variables marked
+// drop_before_persist. vars is returned as is when nothing is dropped.
+func (l varLifetimes) persistable(vars map[string]any) map[string]any {
+	drop := false
+	for name := range vars {
+		if l[name].DropBeforePersist {
+			drop = true
+			break
+		}
+	}
+	if !drop {
+		return vars
+	}
+	out := make(map[string]any, len(vars))
+	for name, v := range vars {
+		if !l[name].DropBeforePersist {
+			out[name] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package runtime_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func TestRuntime_Run_VarLifetimes(t *testing.T) {
+	seen := make(map[string][]string)
+	record := func(id string, env *core.Envelope) {
+		for _, name := range []string{"raw_body", "parsed", "token", "scratch"} {
+			if _, ok := env.GetVar(name); ok {
+				seen[id] = append(seen[id], name)
+			}
+		}
+	}
+
+	g := graph.NewGraph("lifetimes")
+	g.AddNode(core.NewFuncNode("parse", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		record("parse", env)
+		env.SetVar("parsed", map[string]any{"id": 1})
+		env.SetVar("token", "s3cr3t")
+		env.SetVar("scratch", "draft")
+		return env, nil
+	}))
+	g.AddNode(core.NewFuncNode("enrich", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		record("enrich", env)
+		return env, nil
+	}))
+	g.AddNode(core.NewFuncNode("store", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		record("store", env)
+		return env, nil
+	}))
+	g.AddEdge("parse", "enrich")
+	g.AddEdge("enrich", "store")
+	g.SetEntry("parse")
+
+	outputs := make(map[string]map[string]any)
+	var inputs map[string]any
+	opts := runtime.DefaultRunOptions()
+	opts.RecordNodeOutputs = true
+	opts.CaptureSnapshots = true
+	opts.Inputs = map[string]any{"raw_body": "{}", "token": "s3cr3t"}
+	opts.VarLifetimes = map[string]graph.VarLifetime{
+		"raw_body": {Scope: graph.VarScopeNode, DropBeforePersist: true},
+		"scratch":  {Scope: graph.VarScopeNode},
+		"token":    {DropBeforePersist: true},
+		"parsed":   {Scope: graph.VarScopeBranch},
+	}
+	opts.EventHandler = func(e runtime.Event) {
+		switch e.Kind {
+		case runtime.EventRunStarted:
+			inputs, _ = e.Payload["inputs"].(map[string]any)
+		case runtime.EventNodeFinished:
+			output, _ := e.Payload["output"].(map[string]any)
+			outputs[e.NodeID], _ = output["vars"].(map[string]any)
+		}
+	}
+
+	env := core.NewEnvelope().WithVar("raw_body", "{}")
+	result, err := runtime.NewRuntime().Run(context.Background(), g, env, opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := map[string]string{
+		"parse":  "[raw_body]",
+		"enrich": "[parsed token scratch]",
+		"store":  "[parsed token]",
+	}
+	for id, w := range want {
+		if got := fmtNames(seen[id]); got != w {
+			t.Errorf("%s saw %s, want %s", id, got, w)
+		}
+	}
+	if _, ok := outputs["parse"]["token"]; ok {
+		t.Error("drop_before_persist var recorded in node.finished output")
+	}
+	if _, ok := outputs["parse"]["raw_body"]; ok {
+		t.Error("node-scoped var should be dropped after the node that inherited it")
+	}
+	if _, ok := outputs["parse"]["scratch"]; !ok {
+		t.Errorf("parse output = %v, want the node-scoped var it set", outputs["parse"])
+	}
+	if _, ok := inputs["token"]; ok || inputs == nil {
+		t.Errorf("run.started inputs = %v, want token dropped", inputs)
+	}
+	if _, ok := result.GetVar("parsed"); ok {
+		t.Error("branch-scoped var should not reach the run result")
+	}
+	if _, ok := result.GetVar("token"); !ok {
+		t.Error("drop_before_persist var should stay in the live envelope")
+	}
+}
+
+func fmtNames(names []string) string {
+	out := "["
+	for i, name := range names {
+		if i > 0 {
+			out += " "
+		}
+		out += name
+	}
+	return out + "]"
+}
+
+func TestRuntime_Run_VarLifetimes_BranchDroppedAtMerge(t *testing.T) {
+	var mu sync.Mutex
+	var finalSaw bool
+
+	g := graph.NewGraph("branch-lifetimes")
+	g.AddNode(core.NewFuncNode("start", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		return env, nil
+	}))
+	for _, id := range []string{"a", "b"} {
+		g.AddNode(core.NewFuncNode(id, func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+			env.SetVar("draft", "from "+id)
+			env.SetVar("result_"+id, true)
+			return env, nil
+		}))
+		g.AddEdge("start", id)
+		g.AddEdge(id, "merge")
+	}
+	g.AddNode(nodes.NewMergeNode("merge", nodes.MergeNodeConfig{
+		Strategy: nodes.NewJSONMergeStrategy(nodes.JSONMergeConfig{}),
+	}))
+	g.AddNode(core.NewFuncNode("final", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		mu.Lock()
+		_, finalSaw = env.GetVar("draft")
+		mu.Unlock()
+		return env, nil
+	}))
+	g.AddEdge("merge", "final")
+	g.SetEntry("start")
+
+	for _, concurrency := range []int{1, 4} {
+		opts := runtime.DefaultRunOptions()
+		opts.Concurrency = concurrency
+		opts.VarLifetimes = map[string]graph.VarLifetime{"draft": {Scope: graph.VarScopeBranch}}
+
+		result, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+		if err != nil {
+			t.Fatalf("concurrency %d: Run() error = %v", concurrency, err)
+		}
+		if finalSaw {
+			t.Errorf("concurrency %d: node after the merge saw the branch-scoped var", concurrency)
+		}
+		if _, ok := result.GetVar("draft"); ok {
+			t.Errorf("concurrency %d: result = %v, want draft dropped", concurrency, result.Vars)
+		}
+		_, hasA := result.GetVar("result_a")
+		_, hasB := result.GetVar("result_b")
+		if !hasA && !hasB {
+			t.Errorf("concurrency %d: result = %v, want branch results kept", concurrency, result.Vars)
+		}
+	}
+}
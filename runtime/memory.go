@@ -0,0 +1,360 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// ErrMemoryLimitExceeded is matched (via errors.Is) by every
+// MemoryLimitError.
+var ErrMemoryLimitExceeded = errors.New("memory_limit_exceeded")
+
+// eventOverheadBytes approximates what an event costs beyond its IDs and
+// payload: the struct itself, timestamps and the map header.
+const eventOverheadBytes = 128
+
+// maxSizeDepth stops approxSize from following deeply nested values.
+const maxSizeDepth = 64
+
+// MemoryConfig enables approximate memory accounting for a run. The
+// runtime measures the envelope after every node, along with the events
+// its event channel may still be buffering, and compares the total with
+// the limits.
+type MemoryConfig struct {
+	// SoftLimitBytes emits a memory.warning event the first time the run
+	// uses more than this. 0 disables the warning.
+	SoftLimitBytes int64
+
+	// HardLimitBytes fails the run with a *MemoryLimitError when it uses
+	// more than this. 0 disables the limit.
+	HardLimitBytes int64
+
+	// Accountant collects the usage of every run it is shared by, for
+	// reporting. Nil keeps the accounting private to the run.
+	Accountant *MemoryAccountant
+}
+
+// MemoryUsage is the approximate memory a run holds.
+type MemoryUsage struct {
+	// EnvelopeBytes covers the input, vars, messages and errors of the
+	// most recent node output.
+	EnvelopeBytes int64 `json:"envelope_bytes"`
+	// ArtifactBytes covers the artifacts of the most recent node output.
+	ArtifactBytes int64 `json:"artifact_bytes"`
+	// EventBytes covers the run's most recent events, as many as the
+	// runtime's event channel buffers.
+	EventBytes int64 `json:"event_bytes"`
+	TotalBytes int64 `json:"total_bytes"`
+}
+
+// payload returns the usage as event payload fields.
+func (u MemoryUsage) payload() map[string]any {
+	return map[string]any{
+		"envelope_bytes": u.EnvelopeBytes,
+		"artifact_bytes": u.ArtifactBytes,
+		"event_bytes":    u.EventBytes,
+		"total_bytes":    u.TotalBytes,
+	}
+}
+
+// MemoryLimitError reports that a run used more memory than
+// MemoryConfig.HardLimitBytes allows.
+type MemoryLimitError struct {
+	NodeID string
+	Limit  int64
+	Usage  MemoryUsage
+}
+
+// Error implements error.
+func (e *MemoryLimitError) Error() string {
+	return fmt.Sprintf("%s: run holds about %d bytes after node %s, over its limit of %d (envelope %d, artifacts %d, events %d)",
+		ErrMemoryLimitExceeded, e.Usage.TotalBytes, e.NodeID, e.Limit,
+		e.Usage.EnvelopeBytes, e.Usage.ArtifactBytes, e.Usage.EventBytes)
+}
+
+// Is reports whether target is ErrMemoryLimitExceeded.
+func (e *MemoryLimitError) Is(target error) bool {
+	return target == ErrMemoryLimitExceeded
+}
+
+// Payload returns a JSON-friendly description of the error for events and
+// API responses.
+func (e *MemoryLimitError) Payload() map[string]any {
+	p := e.Usage.payload()
+	p["node_id"] = e.NodeID
+	p["limit"] = e.Limit
+	return p
+}
+
+// RunMemoryUsage is one active run's entry in a MemoryReport.
+type RunMemoryUsage struct {
+	RunID      string    `json:"run_id"`
+	WorkflowID string    `json:"workflow_id,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	MemoryUsage
+	// PeakBytes is the highest TotalBytes the run has reached.
+	PeakBytes int64 `json:"peak_bytes"`
+}
+
+// MemoryReport is the aggregate usage of the runs a MemoryAccountant
+// tracks.
+type MemoryReport struct {
+	ActiveRuns int   `json:"active_runs"`
+	TotalBytes int64 `json:"total_bytes"`
+	// Runs lists the active runs, largest first.
+	Runs []RunMemoryUsage `json:"runs"`
+}
+
+// MemoryAccountant aggregates memory usage across concurrent runs. It is
+// safe for concurrent use.
+type MemoryAccountant struct {
+	mu   sync.Mutex
+	runs map[string]*runMemory
+}
+
+// NewMemoryAccountant returns an accountant with no runs.
+func NewMemoryAccountant() *MemoryAccountant {
+	return &MemoryAccountant{runs: make(map[string]*runMemory)}
+}
+
+// Report returns the usage of the runs active now.
+func (a *MemoryAccountant) Report() MemoryReport {
+	a.mu.Lock()
+	runs := make([]*runMemory, 0, len(a.runs))
+	for _, m := range a.runs {
+		runs = append(runs, m)
+	}
+	a.mu.Unlock()
+
+	report := MemoryReport{ActiveRuns: len(runs), Runs: make([]RunMemoryUsage, 0, len(runs))}
+	for _, m := range runs {
+		usage := m.snapshot()
+		report.TotalBytes += usage.TotalBytes
+		report.Runs = append(report.Runs, usage)
+	}
+	sort.Slice(report.Runs, func(i, j int) bool {
+		if report.Runs[i].TotalBytes != report.Runs[j].TotalBytes {
+			return report.Runs[i].TotalBytes > report.Runs[j].TotalBytes
+		}
+		return report.Runs[i].RunID < report.Runs[j].RunID
+	})
+	return report
+}
+
+func (a *MemoryAccountant) add(m *runMemory) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.runs[m.runID] = m
+}
+
+func (a *MemoryAccountant) remove(m *runMemory) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.runs[m.runID] == m {
+		delete(a.runs, m.runID)
+	}
+}
+
+// runMemory tracks one run's usage. A nil *runMemory accounts nothing, so
+// runs without a MemoryConfig pay nothing for it.
+type runMemory struct {
+	cfg        MemoryConfig
+	runID      string
+	workflowID string
+	startedAt  time.Time
+
+	mu         sync.Mutex
+	usage      MemoryUsage
+	peak       int64
+	warned     bool
+	eventSizes []int64
+	nextEvent  int
+}
+
+// newRunMemory starts accounting for a run, registering it with the
+// config's accountant. eventBuffer is how many events the run's event
+// channel holds.
+func newRunMemory(cfg *MemoryConfig, runID, workflowID string, startedAt time.Time, eventBuffer int) *runMemory {
+	if cfg == nil {
+		return nil
+	}
+	if eventBuffer < 1 {
+		eventBuffer = 1
+	}
+	m := &runMemory{
+		cfg:        *cfg,
+		runID:      runID,
+		workflowID: workflowID,
+		startedAt:  startedAt,
+		eventSizes: make([]int64, eventBuffer),
+	}
+	if cfg.Accountant != nil {
+		cfg.Accountant.add(m)
+	}
+	return m
+}
+
+// finish unregisters the run from its accountant.
+func (m *runMemory) finish() {
+	if m == nil || m.cfg.Accountant == nil {
+		return
+	}
+	m.cfg.Accountant.remove(m)
+}
+
+// recordEvent counts e in place of the oldest event the channel could
+// still hold.
+func (m *runMemory) recordEvent(e Event) {
+	if m == nil {
+		return
+	}
+	size := int64(eventOverheadBytes+len(e.Kind)+len(e.RunID)+len(e.NodeID)) + approxSize(e.Payload, 0)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.usage.EventBytes += size - m.eventSizes[m.nextEvent]
+	m.eventSizes[m.nextEvent] = size
+	m.nextEvent = (m.nextEvent + 1) % len(m.eventSizes)
+	m.updateTotal()
+}
+
+// observe measures env, the output of nodeID, and checks the limits. It
+// emits a memory.warning event the first time the soft limit is crossed
+// and returns a *MemoryLimitError when the hard limit is.
+func (m *runMemory) observe(nodeID string, env *core.Envelope, emit EventEmitter, elapsed time.Duration) error {
+	if m == nil || env == nil {
+		return nil
+	}
+	envelopeBytes, artifactBytes := envelopeSize(env)
+
+	m.mu.Lock()
+	m.usage.EnvelopeBytes = envelopeBytes
+	m.usage.ArtifactBytes = artifactBytes
+	m.updateTotal()
+	usage := m.usage
+	warn := m.cfg.SoftLimitBytes > 0 && usage.TotalBytes > m.cfg.SoftLimitBytes && !m.warned
+	if warn {
+		m.warned = true
+	}
+	m.mu.Unlock()
+
+	if warn {
+		event := NewEvent(EventMemoryWarning, m.runID).
+			WithElapsed(elapsed).
+			WithPayload("node_id", nodeID).
+			WithPayload("limit", m.cfg.SoftLimitBytes)
+		for k, v := range usage.payload() {
+			event = event.WithPayload(k, v)
+		}
+		emit(event)
+	}
+	if m.cfg.HardLimitBytes > 0 && usage.TotalBytes > m.cfg.HardLimitBytes {
+		return &MemoryLimitError{NodeID: nodeID, Limit: m.cfg.HardLimitBytes, Usage: usage}
+	}
+	return nil
+}
+
+// updateTotal recomputes the total and peak. Callers hold m.mu.
+func (m *runMemory) updateTotal() {
+	m.usage.TotalBytes = m.usage.EnvelopeBytes + m.usage.ArtifactBytes + m.usage.EventBytes
+	if m.usage.TotalBytes > m.peak {
+		m.peak = m.usage.TotalBytes
+	}
+}
+
+func (m *runMemory) snapshot() RunMemoryUsage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return RunMemoryUsage{
+		RunID:       m.runID,
+		WorkflowID:  m.workflowID,
+		StartedAt:   m.startedAt,
+		MemoryUsage: m.usage,
+		PeakBytes:   m.peak,
+	}
+}
+
+// envelopeSize approximates the bytes env holds, with its artifacts
+// counted apart from the rest.
+func envelopeSize(env *core.Envelope) (envelopeBytes, artifactBytes int64) {
+	envelopeBytes = approxSize(env.Input, 0) + approxSize(env.Vars, 0)
+	for _, msg := range env.Messages {
+		envelopeBytes += int64(len(msg.Role)+len(msg.Content)+len(msg.Name)) + approxSize(msg.Meta, 0)
+	}
+	for _, nodeErr := range env.Errors {
+		envelopeBytes += int64(len(nodeErr.NodeID)+len(nodeErr.Message)) + approxSize(nodeErr.Details, 0)
+	}
+	for _, a := range env.Artifacts {
+		artifactBytes += int64(len(a.ID)+len(a.Type)+len(a.MimeType)+len(a.Text)+len(a.Bytes)+len(a.URI)) + approxSize(a.Meta, 0)
+	}
+	return envelopeBytes, artifactBytes
+}
+
+// approxSize estimates the bytes a value decoded from JSON, or built like
+// one, occupies. Other values are measured by their JSON encoding.
+func approxSize(v any, depth int) int64 {
+	if depth > maxSizeDepth {
+		return 0
+	}
+	switch val := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return int64(len(val))
+	case []byte:
+		return int64(len(val))
+	case bool:
+		return 1
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, json.Number:
+		return 8
+	case map[string]any:
+		var n int64
+		for k, item := range val {
+			n += int64(len(k)) + approxSize(item, depth+1)
+		}
+		return n
+	case map[string]string:
+		var n int64
+		for k, item := range val {
+			n += int64(len(k) + len(item))
+		}
+		return n
+	case []any:
+		var n int64
+		for _, item := range val {
+			n += approxSize(item, depth+1)
+		}
+		return n
+	case []string:
+		var n int64
+		for _, item := range val {
+			n += int64(len(item))
+		}
+		return n
+	case []map[string]any:
+		var n int64
+		for _, item := range val {
+			n += approxSize(item, depth+1)
+		}
+		return n
+	case core.Message:
+		return int64(len(val.Role)+len(val.Content)+len(val.Name)) + approxSize(val.Meta, depth+1)
+	case []core.Message:
+		var n int64
+		for _, item := range val {
+			n += approxSize(item, depth+1)
+		}
+		return n
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0
+	}
+	return int64(len(data))
+}
@@ -0,0 +1,166 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// newGrowingGraph builds small -> big, where big adds a 10 KB var and a
+// 5 KB artifact.
+func newGrowingGraph(t *testing.T) *graph.BasicGraph {
+	t.Helper()
+	g := graph.NewGraph("growing")
+	g.AddNode(core.NewFuncNode("small", func(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+		env.SetVar("note", "hi")
+		return env, nil
+	}))
+	g.AddNode(core.NewFuncNode("big", func(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+		env.SetVar("blob", strings.Repeat("x", 10_000))
+		env.AppendArtifact(core.Artifact{Type: "file", Bytes: make([]byte, 5_000)})
+		return env, nil
+	}))
+	g.AddEdge("small", "big")
+	g.SetEntry("small")
+	return g
+}
+
+func TestMemory_HardLimitFailsRun(t *testing.T) {
+	for _, concurrency := range []int{1, 4} {
+		opts := DefaultRunOptions()
+		opts.Concurrency = concurrency
+		opts.Memory = &MemoryConfig{HardLimitBytes: 8_000}
+
+		var finished Event
+		opts.EventHandler = func(e Event) {
+			if e.Kind == EventRunFinished {
+				finished = e
+			}
+		}
+
+		_, err := NewRuntime().Run(context.Background(), newGrowingGraph(t), core.NewEnvelope(), opts)
+
+		var memErr *MemoryLimitError
+		if !errors.As(err, &memErr) {
+			t.Fatalf("concurrency=%d: err = %v, want *MemoryLimitError", concurrency, err)
+		}
+		if !errors.Is(err, ErrMemoryLimitExceeded) {
+			t.Errorf("concurrency=%d: errors.Is(ErrMemoryLimitExceeded) = false", concurrency)
+		}
+		if memErr.NodeID != "big" || memErr.Limit != 8_000 {
+			t.Errorf("concurrency=%d: unexpected error %+v", concurrency, memErr)
+		}
+		if memErr.Usage.ArtifactBytes < 5_000 || memErr.Usage.EnvelopeBytes < 10_000 || memErr.Usage.EventBytes == 0 {
+			t.Errorf("concurrency=%d: usage = %+v", concurrency, memErr.Usage)
+		}
+		if finished.Payload["error_code"] != "memory_limit_exceeded" {
+			t.Errorf("concurrency=%d: run.finished error_code = %v", concurrency, finished.Payload["error_code"])
+		}
+	}
+}
+
+func TestMemory_SoftLimitWarnsOnce(t *testing.T) {
+	g := graph.NewGraph("loop")
+	g.AddNode(core.NewFuncNode("a", func(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+		env.SetVar("blob", strings.Repeat("x", 2_000))
+		return env, nil
+	}))
+	g.AddNode(core.NewNoopNode("b"))
+	g.AddEdge("a", "b")
+	g.SetEntry("a")
+
+	opts := DefaultRunOptions()
+	opts.Memory = &MemoryConfig{SoftLimitBytes: 1_000}
+	var warnings []Event
+	opts.EventHandler = func(e Event) {
+		if e.Kind == EventMemoryWarning {
+			warnings = append(warnings, e)
+		}
+	}
+
+	if _, err := NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("got %d memory.warning events, want 1", len(warnings))
+	}
+	if warnings[0].Payload["node_id"] != "a" || warnings[0].Payload["limit"] != int64(1_000) {
+		t.Errorf("warning payload = %v", warnings[0].Payload)
+	}
+}
+
+func TestMemoryAccountant_Report(t *testing.T) {
+	accountant := NewMemoryAccountant()
+	release := make(chan struct{})
+	reached := make(chan struct{})
+
+	g := graph.NewGraph("wait")
+	g.AddNode(core.NewFuncNode("fill", func(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+		env.SetVar("blob", strings.Repeat("x", 4_000))
+		return env, nil
+	}))
+	g.AddNode(core.NewFuncNode("hold", func(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+		close(reached)
+		<-release
+		return env, nil
+	}))
+	g.AddEdge("fill", "hold")
+	g.SetEntry("fill")
+
+	opts := DefaultRunOptions()
+	opts.RunID = "run-1"
+	opts.WorkflowID = "wf"
+	opts.Memory = &MemoryConfig{Accountant: accountant}
+	done := make(chan error, 1)
+	go func() {
+		_, err := NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+		done <- err
+	}()
+
+	<-reached
+	report := accountant.Report()
+	if report.ActiveRuns != 1 || len(report.Runs) != 1 {
+		t.Fatalf("report = %+v, want one active run", report)
+	}
+	run := report.Runs[0]
+	if run.RunID != "run-1" || run.WorkflowID != "wf" {
+		t.Errorf("run = %+v", run)
+	}
+	if run.EnvelopeBytes < 4_000 || run.TotalBytes != report.TotalBytes || run.PeakBytes < run.TotalBytes {
+		t.Errorf("run usage = %+v, report total %d", run, report.TotalBytes)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if report := accountant.Report(); report.ActiveRuns != 0 || report.TotalBytes != 0 {
+		t.Errorf("report after run = %+v, want empty", report)
+	}
+}
+
+func TestApproxSize(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want int64
+	}{
+		{"nil", nil, 0},
+		{"string", "hello", 5},
+		{"number", 3.5, 8},
+		{"map", map[string]any{"ab": "cde", "n": true}, 2 + 3 + 1 + 1},
+		{"list", []any{"ab", []string{"c", "de"}}, 5},
+		{"struct", struct {
+			A string `json:"a"`
+		}{"x"}, int64(len(`{"a":"x"}`))},
+	}
+	for _, tt := range tests {
+		if got := approxSize(tt.v, 0); got != tt.want {
+			t.Errorf("%s: approxSize() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
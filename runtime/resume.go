@@ -0,0 +1,175 @@
+package runtime
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// ErrResumeOutputMissing is matched (via errors.Is) by the error of a
+// resumed run that reached a node which completed in the earlier attempt
+// without a recorded output. The node is neither restored nor executed
+// again.
+var ErrResumeOutputMissing = errors.New("resume: completed node has no recorded output")
+
+// ResumeState describes an earlier attempt of a run that is being resumed
+// or retried. Nodes that completed in that attempt and are not declared
+// idempotent are not executed again: their recorded output is restored and
+// a node.restored event is emitted instead. Idempotent nodes re-execute.
+type ResumeState struct {
+	// Outputs maps node IDs to the envelopes they produced in the earlier
+	// attempt, one per completed execution in order.
+	Outputs map[string][]*core.Envelope
+
+	// Unrecorded counts, per node ID, completed executions whose output
+	// was not recorded or was left out by masking. Such nodes cannot be
+	// restored and must not execute again.
+	Unrecorded map[string]int
+
+	// Idempotent lists nodes declared safe to re-execute.
+	Idempotent map[string]bool
+
+	mu       sync.Mutex
+	restored map[string]int
+}
+
+// NewResumeState rebuilds the outputs of a previous attempt from its
+// node.finished events. Events only carry outputs when that attempt ran with
+// RunOptions.RecordNodeOutputs; completions without one are counted in
+// Unrecorded.
+func NewResumeState(events []Event, idempotent map[string]bool) *ResumeState {
+	outputs := make(map[string][]*core.Envelope)
+	unrecorded := make(map[string]int)
+	for _, e := range events {
+		if e.Kind != EventNodeFinished || e.NodeID == "" {
+			continue
+		}
+		raw, ok := e.Payload["output"]
+		if !ok {
+			unrecorded[e.NodeID]++
+			continue
+		}
+		env, ok := decodeRecordedOutput(e.NodeID, raw)
+		if !ok {
+			unrecorded[e.NodeID]++
+			continue
+		}
+		outputs[e.NodeID] = append(outputs[e.NodeID], env)
+	}
+	return &ResumeState{Outputs: outputs, Unrecorded: unrecorded, Idempotent: idempotent}
+}
+
+// Unrestorable returns, sorted, the nodes that completed in the earlier
+// attempt without a recorded output and are not idempotent. Resuming
+// reaches them only to fail with ErrResumeOutputMissing.
+func (s *ResumeState) Unrestorable() []string {
+	if s == nil {
+		return nil
+	}
+	var nodes []string
+	for nodeID, n := range s.Unrecorded {
+		if n > 0 && !s.Idempotent[nodeID] {
+			nodes = append(nodes, nodeID)
+		}
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// take returns the next recorded output for a node that must not
+// re-execute. Visits beyond those recorded execute normally, unless the
+// node has completions without a recorded output: those fail with
+// ErrResumeOutputMissing rather than run again.
+func (s *ResumeState) take(nodeID string) (*core.Envelope, bool, error) {
+	if s == nil || s.Idempotent[nodeID] {
+		return nil, false, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := s.restored[nodeID]
+	if next >= len(s.Outputs[nodeID]) {
+		if s.Unrecorded[nodeID] > 0 {
+			return nil, false, fmt.Errorf("node %q: %w", nodeID, ErrResumeOutputMissing)
+		}
+		return nil, false, nil
+	}
+	if s.restored == nil {
+		s.restored = make(map[string]int)
+	}
+	s.restored[nodeID] = next + 1
+	return s.Outputs[nodeID][next].Clone(), true, nil
+}
+
+// recordedOutput is the decoded form of the node.finished "output" payload.
+type recordedOutput struct {
+	Vars     map[string]any `json:"vars"`
+	Messages []core.Message `json:"messages"`
+}
+
+// recordOutput builds the node.finished "output" payload. It is made of
+// plain maps and slices so masking policies reach into it like any other
+// event payload. Variables marked drop_before_persist are left out.
+func recordOutput(env *core.Envelope, lifetimes varLifetimes) map[string]any {
+	kept := lifetimes.persistable(env.Vars)
+	vars := make(map[string]any, len(kept))
+	for k, v := range kept {
+		vars[k] = v
+	}
+	messages := make([]any, 0, len(env.Messages))
+	for _, m := range env.Messages {
+		msg := map[string]any{"role": m.Role, "content": m.Content}
+		if m.Name != "" {
+			msg["name"] = m.Name
+		}
+		if len(m.Meta) > 0 {
+			msg["meta"] = m.Meta
+		}
+		messages = append(messages, msg)
+	}
+	return map[string]any{"vars": vars, "messages": messages}
+}
+
+// decodeRecordedOutput accepts the payload both as emitted in-process and
+// after a round trip through a persistent event store.
+func decodeRecordedOutput(nodeID string, raw any) (*core.Envelope, bool) {
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return nil, false
+	}
+	var rec recordedOutput
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, false
+	}
+
+	env := core.NewEnvelope()
+	for k, v := range rec.Vars {
+		env.SetVar(k, v)
+	}
+	env.Messages = append(env.Messages, rec.Messages...)
+	restoreRouteDecision(env, nodeID)
+	return env, true
+}
+
+// restoreRouteDecision converts a router's decision back to a
+// core.RouteDecision after JSON decoding turned it into a map, so the
+// restored router still selects the successors it chose originally.
+func restoreRouteDecision(env *core.Envelope, nodeID string) {
+	key := nodeID + "_decision"
+	raw, ok := env.Vars[key].(map[string]any)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return
+	}
+	var decision core.RouteDecision
+	if err := json.Unmarshal(data, &decision); err != nil || len(decision.Targets) == 0 {
+		return
+	}
+	env.Vars[key] = decision
+}
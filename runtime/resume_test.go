@@ -0,0 +1,172 @@
+package runtime_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// resumeGraph builds charge -> route -> ship|refund. ship fails until
+// shipOK is set; charges counts executions of the non-idempotent charge node.
+func resumeGraph(charges *int, shipOK *bool) *graph.BasicGraph {
+	g := graph.NewGraph("resume")
+	g.AddNode(core.NewFuncNode("charge", func(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+		*charges++
+		env.SetVar("charge_id", "ch_1")
+		env.Messages = append(env.Messages, core.Message{Role: "assistant", Content: "charged"})
+		return env, nil
+	}))
+	g.AddNode(nodes.NewRuleRouter("route", nodes.RuleRouterConfig{
+		Rules: []nodes.RouteRule{{Target: "ship", Reason: "paid"}},
+	}))
+	g.AddNode(core.NewFuncNode("ship", func(_ context.Context, env *core.Envelope) (*core.Envelope, error) {
+		if !*shipOK {
+			return nil, errors.New("carrier unavailable")
+		}
+		env.SetVar("shipped", env.GetVarString("charge_id"))
+		return env, nil
+	}))
+	g.AddNode(core.NewNoopNode("refund"))
+	g.AddEdge("charge", "route")
+	g.AddEdge("route", "ship")
+	g.AddEdge("route", "refund")
+	g.SetEntry("charge")
+	return g
+}
+
+// storedEvents round-trips events through JSON like a persistent store.
+func storedEvents(t *testing.T, events []runtime.Event) []runtime.Event {
+	t.Helper()
+	data, err := json.Marshal(events)
+	if err != nil {
+		t.Fatalf("marshal events: %v", err)
+	}
+	var out []runtime.Event
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshal events: %v", err)
+	}
+	return out
+}
+
+func TestRun_ResumeRestoresCompletedNodes(t *testing.T) {
+	for _, concurrency := range []int{1, 4} {
+		charges := 0
+		shipOK := false
+		g := resumeGraph(&charges, &shipOK)
+
+		var first []runtime.Event
+		opts := runtime.DefaultRunOptions()
+		opts.Concurrency = concurrency
+		opts.RecordNodeOutputs = true
+		opts.EventHandler = func(e runtime.Event) { first = append(first, e) }
+		if _, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts); err == nil {
+			t.Fatalf("concurrency=%d: first attempt should fail", concurrency)
+		}
+
+		shipOK = true
+		var restored []string
+		opts = runtime.DefaultRunOptions()
+		opts.Concurrency = concurrency
+		opts.Resume = runtime.NewResumeState(storedEvents(t, first), nil)
+		opts.EventHandler = func(e runtime.Event) {
+			if e.Kind == runtime.EventNodeRestored {
+				restored = append(restored, e.NodeID)
+			}
+		}
+		result, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+		if err != nil {
+			t.Fatalf("concurrency=%d: resume failed: %v", concurrency, err)
+		}
+
+		if charges != 1 {
+			t.Errorf("concurrency=%d: charge ran %d times, want 1", concurrency, charges)
+		}
+		if len(restored) != 2 || restored[0] != "charge" || restored[1] != "route" {
+			t.Errorf("concurrency=%d: restored = %v, want [charge route]", concurrency, restored)
+		}
+		if got := result.GetVarString("shipped"); got != "ch_1" {
+			t.Errorf("concurrency=%d: shipped = %q, want restored charge_id", concurrency, got)
+		}
+		if len(result.Messages) != 1 || result.Messages[0].Content != "charged" {
+			t.Errorf("concurrency=%d: messages = %+v", concurrency, result.Messages)
+		}
+	}
+}
+
+func TestRun_ResumeReexecutesIdempotentNodes(t *testing.T) {
+	charges := 0
+	shipOK := false
+	g := resumeGraph(&charges, &shipOK)
+
+	var first []runtime.Event
+	opts := runtime.DefaultRunOptions()
+	opts.RecordNodeOutputs = true
+	opts.EventHandler = func(e runtime.Event) { first = append(first, e) }
+	_, _ = runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+
+	shipOK = true
+	opts = runtime.DefaultRunOptions()
+	opts.Resume = runtime.NewResumeState(first, map[string]bool{"charge": true})
+	if _, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts); err != nil {
+		t.Fatalf("resume failed: %v", err)
+	}
+	if charges != 2 {
+		t.Errorf("charge ran %d times, want 2 (declared idempotent)", charges)
+	}
+}
+
+func TestRun_NodeOutputsOnlyRecordedWhenEnabled(t *testing.T) {
+	g := graph.NewGraph("plain")
+	g.AddNode(core.NewNoopNode("a"))
+	g.SetEntry("a")
+
+	for _, record := range []bool{false, true} {
+		var finished runtime.Event
+		opts := runtime.DefaultRunOptions()
+		opts.RecordNodeOutputs = record
+		opts.EventHandler = func(e runtime.Event) {
+			if e.Kind == runtime.EventNodeFinished {
+				finished = e
+			}
+		}
+		if _, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+		if _, ok := finished.Payload["output"]; ok != record {
+			t.Errorf("record=%v: output in payload = %v", record, ok)
+		}
+	}
+}
+
+func TestRun_ResumeWithoutRecordedOutputsFailsClosed(t *testing.T) {
+	charges := 0
+	shipOK := false
+	g := resumeGraph(&charges, &shipOK)
+
+	var first []runtime.Event
+	opts := runtime.DefaultRunOptions()
+	opts.EventHandler = func(e runtime.Event) { first = append(first, e) }
+	_, _ = runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+
+	state := runtime.NewResumeState(storedEvents(t, first), nil)
+	if got := state.Unrestorable(); len(got) != 2 || got[0] != "charge" || got[1] != "route" {
+		t.Errorf("Unrestorable() = %v, want [charge route]", got)
+	}
+
+	shipOK = true
+	opts = runtime.DefaultRunOptions()
+	opts.Resume = state
+	_, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+	if !errors.Is(err, runtime.ErrResumeOutputMissing) {
+		t.Fatalf("resume err = %v, want ErrResumeOutputMissing", err)
+	}
+	if charges != 1 {
+		t.Errorf("charge ran %d times, want 1", charges)
+	}
+}
@@ -6,11 +6,13 @@ import (
 	"crypto/rand"
 	"errors"
 	"fmt"
+	"maps"
 	"sync"
 	"time"
 
 	"github.com/petal-labs/petalflow/core"
 	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/propagate"
 )
 
 // Runtime errors
@@ -35,6 +37,15 @@ type RunOptions struct {
 	// MaxHops protects against infinite cycles (default: 100).
 	MaxHops int
 
+	// MaxNodeExecutions caps the total number of node executions in a run,
+	// counted identically in sequential and concurrent mode (0 = unlimited).
+	// Exceeding it fails the run with a *BudgetExhaustedError.
+	MaxNodeExecutions int
+
+	// NodeVisitLimits caps how many times individual nodes may execute in a
+	// run. Exceeding a limit fails the run with a *BudgetExhaustedError.
+	NodeVisitLimits map[string]int
+
 	// ContinueOnError records errors and continues when possible.
 	ContinueOnError bool
 
@@ -78,11 +89,87 @@ type RunOptions struct {
 	// TriggerSource identifies what triggered this run (e.g., "cli", "api", "ui", "schedule").
 	TriggerSource string
 
+	// RunID sets the run's ID, for callers that must refer to the run
+	// before it starts. A new ID is generated when empty.
+	RunID string
+
 	// WorkflowID is the workflow identifier for tracing.
 	WorkflowID string
 
 	// WorkflowVersion is the workflow version for tracing.
 	WorkflowVersion string
+
+	// Environment pins what the run was hydrated with, such as models and
+	// prompt and provider config hashes. It is added to the run.started
+	// event as "environment" so two runs can be compared later.
+	Environment any
+
+	// Features lists the features each node of the run uses, such as its
+	// config fields and template functions. It is added to the run.started
+	// event as "features" for feature usage reports.
+	Features any
+
+	// RecordNodeOutputs adds each node's output variables and messages to
+	// its node.finished event, so a later attempt can resume from them.
+	RecordNodeOutputs bool
+
+	// RecordInputs adds the envelope's starting variables to the
+	// run.started event as "inputs", so the run can be rerun later.
+	// Snapshot inputs take precedence when CaptureSnapshots is set.
+	RecordInputs bool
+
+	// OnInvalidEvent, when set, checks every emitted event against its
+	// kind's payload schema and reports those that do not match. Meant
+	// for development: a mismatch is a bug in the emitting code.
+	OnInvalidEvent func(Event, error)
+
+	// Resume restores outputs from an earlier attempt instead of
+	// re-executing completed, non-idempotent nodes. See ResumeState.
+	Resume *ResumeState
+
+	// Chaos injects seeded faults for resilience testing. Nil disables it.
+	Chaos *ChaosConfig
+
+	// Lease makes the run hold a heartbeated lease while it executes, so a
+	// crash leaves an expiring record instead of a run that never ends.
+	// Nil disables it.
+	Lease *LeaseConfig
+
+	// NodeDocs adds each node's description and doc URL to its
+	// node.started and node.failed events. See GraphDefinition.NodeDocs.
+	NodeDocs map[string]graph.NodeDoc
+
+	// VarLifetimes drops node- and branch-scoped variables as the run
+	// moves on and keeps drop_before_persist variables out of recorded
+	// outputs and snapshots. See GraphDefinition.VarLifetimes.
+	VarLifetimes map[string]graph.VarLifetime
+
+	// Memory accounts for the run's approximate memory use and enforces
+	// limits on it. Nil disables accounting. See MemoryConfig.
+	Memory *MemoryConfig
+
+	// SLA makes the run emit sla.breached events when it misses a target
+	// and list the breaches on run.finished. Zero targets disable it. See
+	// GraphDefinition.SLA.
+	SLA graph.SLATargets
+
+	// QueueWait is how long the run waited for a slot before Run was
+	// called, checked against SLA.MaxQueueWait.
+	QueueWait time.Duration
+
+	// Priority is the caller's priority hint, recorded on run.started.
+	Priority int
+
+	// Deadline, when set, is when the caller needs the run finished. The
+	// run's context ends then, failing the run with ErrDeadlineExceeded,
+	// and run.finished reports whether the run completed by it.
+	Deadline time.Time
+
+	// NodeSnapshot, when set, receives each node's variables and messages
+	// before it runs (phase "input") and after it finishes (phase
+	// "output"), for debug logging. Like recorded outputs, snapshots leave
+	// out drop_before_persist variables.
+	NodeSnapshot func(nodeID, phase string, snapshot map[string]any)
 }
 
 // DefaultRunOptions returns sensible default options.
@@ -125,6 +212,15 @@ func (r *BasicRuntime) Run(ctx context.Context, g graph.Graph, env *core.Envelop
 	if err := validateGraph(g); err != nil {
 		return nil, err
 	}
+	if opts.Chaos != nil {
+		if err := opts.Chaos.Validate(); err != nil {
+			return nil, err
+		}
+		ctx = contextWithChaos(ctx, newChaosInjector(opts.Chaos))
+	}
+
+	ctx, cancelDeadline := withRunDeadline(ctx, opts.Deadline)
+	defer cancelDeadline()
 
 	// Initialize envelope if nil
 	if env == nil {
@@ -132,14 +228,34 @@ func (r *BasicRuntime) Run(ctx context.Context, g graph.Graph, env *core.Envelop
 	}
 
 	// Generate run ID
-	runID := generateRunID()
+	runID := opts.RunID
+	if runID == "" {
+		runID = generateRunID()
+	}
 	env.Trace.RunID = runID
 	env.Trace.Started = opts.Now()
 
+	heartbeat, err := startLease(ctx, opts.Lease, runID, opts.WorkflowID, opts.Now)
+	if err != nil {
+		return nil, err
+	}
+	defer heartbeat.finish(ctx, opts.Lease, runID)
+
+	mem := newRunMemory(opts.Memory, runID, opts.WorkflowID, env.Trace.Started, cap(r.eventCh))
+	defer mem.finish()
+	sla := newSLATracker(opts.SLA, runID)
+
 	// Create event emitter
 	seq := newSeqGen()
 	emit := func(e Event) {
 		e.Seq = seq.Next()
+		e = e.WithSchemaVersion()
+		if opts.OnInvalidEvent != nil {
+			if err := ValidateEvent(e); err != nil {
+				opts.OnInvalidEvent(e, err)
+			}
+		}
+		mem.recordEvent(e)
 		if opts.EventBus != nil {
 			opts.EventBus.Publish(e)
 		}
@@ -151,6 +267,7 @@ func (r *BasicRuntime) Run(ctx context.Context, g graph.Graph, env *core.Envelop
 		default:
 			// Drop if channel is full
 		}
+		sla.observe(e)
 	}
 	if opts.EventEmitterDecorator != nil {
 		emit = opts.EventEmitterDecorator(emit)
@@ -172,6 +289,12 @@ func (r *BasicRuntime) Run(ctx context.Context, g graph.Graph, env *core.Envelop
 	if opts.WorkflowVersion != "" {
 		runStartEvent = runStartEvent.WithPayload("workflow_version", opts.WorkflowVersion)
 	}
+	if opts.Environment != nil {
+		runStartEvent = runStartEvent.WithPayload("environment", opts.Environment)
+	}
+	if opts.Features != nil {
+		runStartEvent = runStartEvent.WithPayload("features", opts.Features)
+	}
 
 	// Add snapshot data for PetalTrace replay support
 	if opts.CaptureSnapshots {
@@ -179,28 +302,71 @@ func (r *BasicRuntime) Run(ctx context.Context, g graph.Graph, env *core.Envelop
 			runStartEvent = runStartEvent.WithPayload("graph_definition", opts.GraphDefinition)
 		}
 		if opts.Inputs != nil {
-			runStartEvent = runStartEvent.WithPayload("inputs", opts.Inputs)
+			inputs := opts.Inputs
+			if vars, ok := inputs.(map[string]any); ok {
+				inputs = varLifetimes(opts.VarLifetimes).persistable(vars)
+			}
+			runStartEvent = runStartEvent.WithPayload("inputs", inputs)
 		}
 	}
+	if _, ok := runStartEvent.Payload["inputs"]; !ok && opts.RecordInputs {
+		// Copied, since nodes go on to change env.Vars.
+		runStartEvent = runStartEvent.WithPayload("inputs", maps.Clone(varLifetimes(opts.VarLifetimes).persistable(env.Vars)))
+	}
+
+	if sla != nil {
+		runStartEvent = runStartEvent.WithPayload("sla", sla.targetsPayload())
+	}
+	if opts.Priority != 0 {
+		runStartEvent = runStartEvent.WithPayload("priority", opts.Priority)
+	}
+	if !opts.Deadline.IsZero() {
+		runStartEvent = runStartEvent.WithPayload("deadline", opts.Deadline.UTC().Format(time.RFC3339Nano))
+	}
 
 	emit(runStartEvent)
+	sla.start(emit, opts.QueueWait)
 
 	// Execute graph
-	result, err := r.executeGraph(ctx, g, env, opts, emit, runStart)
+	result, err := r.executeGraph(ctx, g, env, opts, emit, runStart, mem)
+	varLifetimes(opts.VarLifetimes).drop(result, graph.VarScopeNode, graph.VarScopeBranch)
 
 	// Emit run finished
-	runElapsed := opts.Now().Sub(runStart)
+	runEnd := opts.Now()
+	runElapsed := runEnd.Sub(runStart)
 	finishEvent := NewEvent(EventRunFinished, runID).
 		WithElapsed(runElapsed)
 
+	err = runDeadlineError(err, opts.Deadline, runEnd)
 	if err != nil {
 		finishEvent = finishEvent.
 			WithPayload("status", "failed").
 			WithPayload("error", err.Error())
+		var budgetErr *BudgetExhaustedError
+		if errors.As(err, &budgetErr) {
+			finishEvent = finishEvent.
+				WithPayload("error_code", ErrBudgetExhausted.Error()).
+				WithPayload("budget", budgetErr.Payload())
+		}
+		var memErr *MemoryLimitError
+		if errors.As(err, &memErr) {
+			finishEvent = finishEvent.
+				WithPayload("error_code", ErrMemoryLimitExceeded.Error()).
+				WithPayload("memory", memErr.Payload())
+		}
+		if errors.Is(err, ErrDeadlineExceeded) {
+			finishEvent = finishEvent.WithPayload("error_code", ErrDeadlineExceeded.Error())
+		}
 	} else {
 		finishEvent = finishEvent.
 			WithPayload("status", "completed")
 	}
+	if sla != nil {
+		finishEvent = finishEvent.WithPayload("sla_breaches", sla.stop(runElapsed))
+	}
+	if !opts.Deadline.IsZero() {
+		finishEvent = finishEvent.WithPayload("deadline_met", err == nil && !runEnd.After(opts.Deadline))
+	}
 	emit(finishEvent)
 
 	return result, err
@@ -216,14 +382,15 @@ func (r *BasicRuntime) executeGraph(
 	opts RunOptions,
 	emit EventEmitter,
 	runStart time.Time,
+	mem *runMemory,
 ) (*core.Envelope, error) {
 	// For concurrent execution, use the parallel executor
 	if opts.Concurrency > 1 {
-		return r.executeGraphParallel(ctx, g, env, opts, emit, runStart)
+		return r.executeGraphParallel(ctx, g, env, opts, emit, runStart, mem)
 	}
 
 	// Sequential execution (original behavior)
-	return r.executeGraphSequential(ctx, g, env, opts, emit, runStart)
+	return r.executeGraphSequential(ctx, g, env, opts, emit, runStart, mem)
 }
 
 // executeGraphSequential is the original sequential execution logic.
@@ -234,19 +401,27 @@ func (r *BasicRuntime) executeGraphSequential(
 	opts RunOptions,
 	emit EventEmitter,
 	runStart time.Time,
+	mem *runMemory,
 ) (*core.Envelope, error) {
 	hopCount := make(map[string]int)
+	budget := newExecutionBudget(opts)
 	current := env
 
 	// Use a queue for dynamic execution order
 	// Start with the entry node
-	queue := []string{g.Entry()}
+	queue := []queuedNode{{nodeID: g.Entry()}}
 	visited := make(map[string]bool)
 
 	for len(queue) > 0 {
 		// Pop next node from queue
-		nodeID := queue[0]
+		item := queue[0]
 		queue = queue[1:]
+		nodeID := item.nodeID
+
+		// Nodes left in the queue by a timed-out branch do not run.
+		if item.branch.abandoned() {
+			continue
+		}
 
 		if shouldSkipVisitedNode(nodeID, visited, hopCount, opts.MaxHops) {
 			continue
@@ -256,6 +431,10 @@ func (r *BasicRuntime) executeGraphSequential(
 			return current, err
 		}
 
+		if err := budget.admit(nodeID); err != nil {
+			return current, err
+		}
+
 		attempt, err := incrementAndValidateHop(nodeID, hopCount, opts.MaxHops)
 		if err != nil {
 			return current, err
@@ -275,12 +454,36 @@ func (r *BasicRuntime) executeGraphSequential(
 		}
 		if skipNode {
 			visited[nodeID] = true
-			queue = append(queue, r.determineSuccessors(g, node, current, emit, runStart, opts)...)
+			nextNodes := r.determineSuccessors(g, node, current, emit, runStart, opts)
+			if err := applySequentialEdgeVars(g, nodeID, nextNodes, current); err != nil {
+				return current, err
+			}
+			queue = append(queue, queueSuccessors(g, nodeID, nextNodes, current, item.branch)...)
 			continue
 		}
 
 		// Execute node
-		result, nodeErr := r.executeNode(ctx, node, current, opts, emit, runStart)
+		nodeCtx, limit, cancel := branchContext(ctx, item.branch)
+		result, nodeErr := r.executeNode(nodeCtx, node, current, opts, emit, runStart)
+		timedOut := branchTimedOut(ctx, nodeCtx, limit)
+		cancel()
+		if timedOut != nil {
+			emitBranchTimedOut(emit, opts, runStart, current.Trace.RunID, node, timedOut)
+			if timedOut.policy.Policy() != graph.BranchOnTimeoutFail {
+				// Drop the rest of the branch and carry on with the
+				// envelope as it was before the node.
+				timedOut.timedOut = true
+				visited[nodeID] = true
+				addMissingBranch(current, timedOut.report(nodeID))
+				if timedOut.policy.Policy() == graph.BranchOnTimeoutDefault {
+					for name, value := range timedOut.policy.Defaults {
+						current.SetVar(name, value)
+					}
+				}
+				continue
+			}
+			result, nodeErr = nil, timedOut.timeoutError(nodeID)
+		}
 
 		err = r.handleSequentialAfterStep(
 			ctx, g, node, current, result, nodeErr, opts, emit, runStart, attempt,
@@ -296,17 +499,61 @@ func (r *BasicRuntime) executeGraphSequential(
 			return current, err
 		}
 
+		if err := mem.observe(nodeID, current, emit, opts.Now().Sub(runStart)); err != nil {
+			return current, err
+		}
+
 		// Mark as visited
 		visited[nodeID] = true
 
 		// Determine next nodes to execute
 		nextNodes := r.determineSuccessors(g, node, current, emit, runStart, opts)
-		queue = append(queue, nextNodes...)
+		if err := applySequentialEdgeVars(g, nodeID, nextNodes, current); err != nil {
+			return current, err
+		}
+		queue = append(queue, queueSuccessors(g, nodeID, nextNodes, current, item.branch)...)
 	}
 
 	return current, nil
 }
 
+// queuedNode is a node waiting in the sequential queue, with the branch
+// it runs in.
+type queuedNode struct {
+	nodeID string
+	branch *branchRun
+}
+
+func queueSuccessors(g graph.Graph, from string, next []string, env *core.Envelope, branch *branchRun) []queuedNode {
+	items := make([]queuedNode, len(next))
+	for i, to := range next {
+		items[i] = queuedNode{nodeID: to, branch: nextBranch(g, from, to, env, branch)}
+	}
+	return items
+}
+
+// applySequentialEdgeVars applies the variable mappings of the edges to
+// the next nodes. Sequential runs share one envelope, so the mappings of
+// every edge taken land in it.
+func applySequentialEdgeVars(g graph.Graph, from string, next []string, env *core.Envelope) error {
+	for _, to := range next {
+		if err := applyEdgeVars(g, from, to, env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyEdgeVars applies the variable mappings of the edge from -> to, if
+// the graph has any.
+func applyEdgeVars(g graph.Graph, from, to string, env *core.Envelope) error {
+	eg, ok := g.(graph.EdgeVarsGraph)
+	if !ok {
+		return nil
+	}
+	return graph.ApplyEdgeVars(env, from, to, eg.EdgeVars(from, to))
+}
+
 func shouldSkipVisitedNode(nodeID string, visited map[string]bool, hopCount map[string]int, maxHops int) bool {
 	if !visited[nodeID] || hopCount[nodeID] == 0 {
 		return false
@@ -442,7 +689,7 @@ func resolveSequentialNodeOutcome(
 		return result, nil
 	}
 	if !opts.ContinueOnError {
-		return current, fmt.Errorf("%w: node %s: %v", ErrNodeExecution, nodeID, nodeErr)
+		return current, fmt.Errorf("%w: node %s: %w", ErrNodeExecution, nodeID, nodeErr)
 	}
 
 	current.AppendError(core.NodeError{
@@ -451,6 +698,7 @@ func resolveSequentialNodeOutcome(
 		Message: nodeErr.Error(),
 		Attempt: attempt,
 		At:      opts.Now(),
+		Details: core.ErrorDetails(nodeErr),
 		Cause:   nodeErr,
 	})
 	return current, nil
@@ -461,6 +709,9 @@ type nodeResult struct {
 	nodeID   string
 	envelope *core.Envelope
 	err      error
+	branch   *branchRun
+	// timedOut is the branch whose deadline the node ran past, if any.
+	timedOut *branchRun
 }
 
 type nodeState struct {
@@ -472,6 +723,7 @@ type nodeState struct {
 type workItem struct {
 	nodeID   string
 	envelope *core.Envelope
+	branch   *branchRun
 }
 
 type mergeRunner interface {
@@ -487,11 +739,25 @@ type parallelState struct {
 
 	// mergeInputs[mergeNodeID] = list of envelopes from predecessors
 	mergeInputs map[string][]*core.Envelope
-	mergeMu     sync.Mutex
+	// mergeMissing[mergeNodeID] = number of timed-out branches that will
+	// not deliver an input
+	mergeMissing map[string]int
+	// mergeReports[mergeNodeID] = reports of the timed-out branches
+	mergeReports map[string][]map[string]any
+	// mergeForks[mergeNodeID] = envelope a missing branch started with,
+	// merged when no branch arrives
+	mergeForks map[string]*core.Envelope
+	// unmergedMissing reports timed-out branches with no merge downstream
+	unmergedMissing []map[string]any
+	mergeMu         sync.Mutex
+
+	budget *executionBudget
+	mem    *runMemory
 }
 
-func newParallelState(entryID string, entryEnv *core.Envelope) *parallelState {
+func newParallelState(entryID string, entryEnv *core.Envelope, budget *executionBudget) *parallelState {
 	return &parallelState{
+		budget: budget,
 		states: map[string]*nodeState{
 			entryID: {
 				hopCount:  0,
@@ -499,7 +765,10 @@ func newParallelState(entryID string, entryEnv *core.Envelope) *parallelState {
 				envelope:  entryEnv,
 			},
 		},
-		mergeInputs: make(map[string][]*core.Envelope),
+		mergeInputs:  make(map[string][]*core.Envelope),
+		mergeMissing: make(map[string]int),
+		mergeReports: make(map[string][]map[string]any),
+		mergeForks:   make(map[string]*core.Envelope),
 	}
 }
 
@@ -552,12 +821,55 @@ func (p *parallelState) addMergeInput(nodeID string, env *core.Envelope, expecte
 	defer p.mergeMu.Unlock()
 
 	p.mergeInputs[nodeID] = append(p.mergeInputs[nodeID], env)
-	if len(p.mergeInputs[nodeID]) < expectedInputs {
+	return p.mergeReady(nodeID, expectedInputs)
+}
+
+// addMissingMergeInput counts a timed-out branch as an input of the merge
+// node that arrives empty, so the merge goes ahead without it.
+func (p *parallelState) addMissingMergeInput(nodeID string, report map[string]any, fork *core.Envelope, expectedInputs int) ([]*core.Envelope, bool) {
+	p.mergeMu.Lock()
+	defer p.mergeMu.Unlock()
+
+	p.mergeMissing[nodeID]++
+	p.mergeReports[nodeID] = append(p.mergeReports[nodeID], report)
+	if p.mergeForks[nodeID] == nil {
+		p.mergeForks[nodeID] = fork
+	}
+	return p.mergeReady(nodeID, expectedInputs)
+}
+
+// mergeReady reports whether every expected input of the merge node has
+// arrived or is missing. The caller holds mergeMu.
+func (p *parallelState) mergeReady(nodeID string, expectedInputs int) ([]*core.Envelope, bool) {
+	if len(p.mergeInputs[nodeID])+p.mergeMissing[nodeID] < expectedInputs {
 		return nil, false
 	}
+	if len(p.mergeInputs[nodeID]) == 0 {
+		return []*core.Envelope{p.mergeForks[nodeID].Clone()}, true
+	}
 	return p.mergeInputs[nodeID], true
 }
 
+// addMergeReport records a timed-out branch whose substitute input the
+// merge node receives.
+func (p *parallelState) addMergeReport(nodeID string, report map[string]any) {
+	p.mergeMu.Lock()
+	defer p.mergeMu.Unlock()
+	p.mergeReports[nodeID] = append(p.mergeReports[nodeID], report)
+}
+
+// takeMergeReports returns the reports of the branches the merge node went
+// without.
+func (p *parallelState) takeMergeReports(nodeID string) []map[string]any {
+	p.mergeMu.Lock()
+	defer p.mergeMu.Unlock()
+	reports := p.mergeReports[nodeID]
+	delete(p.mergeReports, nodeID)
+	delete(p.mergeMissing, nodeID)
+	delete(p.mergeForks, nodeID)
+	return reports
+}
+
 func (p *parallelState) addRecordedError(nodeErr core.NodeError) {
 	p.errorsMu.Lock()
 	defer p.errorsMu.Unlock()
@@ -580,10 +892,12 @@ func (r *BasicRuntime) executeGraphParallel(
 	opts RunOptions,
 	emit EventEmitter,
 	runStart time.Time,
+	mem *runMemory,
 ) (*core.Envelope, error) {
 	workCh := make(chan workItem, opts.Concurrency*2)
 	resultCh := make(chan nodeResult, opts.Concurrency*2)
-	state := newParallelState(g.Entry(), env)
+	state := newParallelState(g.Entry(), env, newExecutionBudget(opts))
+	state.mem = mem
 
 	// Context with cancellation for worker shutdown
 	workerCtx, cancelWorkers := context.WithCancel(ctx)
@@ -606,6 +920,9 @@ func (r *BasicRuntime) executeGraphParallel(
 	defer stopWorkers()
 
 	// Submit entry node
+	if err := state.budget.admit(g.Entry()); err != nil {
+		return env, err
+	}
 	pendingCount := 1
 	workCh <- workItem{nodeID: g.Entry(), envelope: env}
 
@@ -626,7 +943,9 @@ func (r *BasicRuntime) executeGraphParallel(
 				stopWorkers()
 				return env, err
 			}
-			finalEnvelope = resultEnvelope
+			if resultEnvelope != nil {
+				finalEnvelope = resultEnvelope
+			}
 			pendingCount += addedPending
 		}
 	}
@@ -640,6 +959,9 @@ func (r *BasicRuntime) executeGraphParallel(
 
 	// Merge recorded errors into final envelope
 	state.appendRecordedErrors(finalEnvelope)
+	for _, report := range state.unmergedMissing {
+		addMissingBranch(finalEnvelope, report)
+	}
 
 	return finalEnvelope, nil
 }
@@ -675,11 +997,16 @@ func (r *BasicRuntime) startParallelWorkers(
 						continue
 					}
 
-					result, err := r.executeNode(workerCtx, node, work.envelope, opts, emit, runStart)
+					nodeCtx, limit, cancel := branchContext(workerCtx, work.branch)
+					result, err := r.executeNode(nodeCtx, node, work.envelope, opts, emit, runStart)
+					timedOut := branchTimedOut(workerCtx, nodeCtx, limit)
+					cancel()
 					resultCh <- nodeResult{
 						nodeID:   work.nodeID,
 						envelope: result,
 						err:      err,
+						branch:   work.branch,
+						timedOut: timedOut,
 					}
 				}
 			}
@@ -697,6 +1024,20 @@ func (r *BasicRuntime) handleParallelResult(
 	state *parallelState,
 	workCh chan<- workItem,
 ) (*core.Envelope, int, error) {
+	// Results of a timed-out branch arriving late are dropped.
+	if result.branch.abandoned() {
+		return nil, 0, nil
+	}
+	if b := result.timedOut; b != nil {
+		node, _ := g.NodeByID(result.nodeID)
+		emitBranchTimedOut(emit, opts, runStart, b.fork.Trace.RunID, node, b)
+		if b.policy.Policy() != graph.BranchOnTimeoutFail {
+			addedPending, err := r.handleBranchTimeout(ctx, g, result.nodeID, b, opts, state, workCh)
+			return nil, addedPending, err
+		}
+		result.envelope, result.err = nil, b.timeoutError(result.nodeID)
+	}
+
 	attempt, previousEnvelope := state.incrementHop(result.nodeID)
 	resultEnvelope, err := resolveParallelResultEnvelope(g, result, opts, attempt, previousEnvelope, state)
 	if err != nil {
@@ -704,6 +1045,9 @@ func (r *BasicRuntime) handleParallelResult(
 	}
 
 	state.markNodeCompleted(result.nodeID, resultEnvelope)
+	if err := state.mem.observe(result.nodeID, resultEnvelope, emit, opts.Now().Sub(runStart)); err != nil {
+		return nil, 0, err
+	}
 
 	node, exists := g.NodeByID(result.nodeID)
 	if !exists {
@@ -711,13 +1055,48 @@ func (r *BasicRuntime) handleParallelResult(
 	}
 	successors := r.determineSuccessors(g, node, resultEnvelope, emit, runStart, opts)
 
-	addedPending, err := r.scheduleParallelSuccessors(ctx, g, resultEnvelope, successors, opts, state, workCh)
+	addedPending, err := r.scheduleParallelSuccessors(ctx, g, result.nodeID, resultEnvelope, successors, opts, state, workCh, result.branch)
 	if err != nil {
 		return nil, 0, err
 	}
 	return resultEnvelope, addedPending, nil
 }
 
+// handleBranchTimeout drops a timed-out branch under the partial and
+// default policies. Its merge node goes ahead without it, or with the
+// envelope the branch started with plus the policy's defaults.
+func (r *BasicRuntime) handleBranchTimeout(
+	ctx context.Context,
+	g graph.Graph,
+	nodeID string,
+	b *branchRun,
+	opts RunOptions,
+	state *parallelState,
+	workCh chan<- workItem,
+) (int, error) {
+	b.timedOut = true
+	report := b.report(nodeID)
+	mergeNode, ok := g.NodeByID(b.merge)
+	if !ok {
+		state.unmergedMissing = append(state.unmergedMissing, report)
+		return 0, nil
+	}
+	merge := mergeNode.(core.MergeCapable)
+
+	var scheduled bool
+	var err error
+	if b.policy.Policy() == graph.BranchOnTimeoutDefault {
+		state.addMergeReport(b.merge, report)
+		scheduled, err = scheduleMergeSuccessor(ctx, g, b.merge, mergeNode, merge, b.substitute(), opts, state, workCh, b.parent)
+	} else if inputs, ready := state.addMissingMergeInput(b.merge, report, b.fork, expectedMergeInputs(g, b.merge, merge)); ready {
+		scheduled, err = runMerge(ctx, b.merge, mergeNode, merge, inputs, opts, state, workCh, b.parent)
+	}
+	if err != nil || !scheduled {
+		return 0, err
+	}
+	return 1, nil
+}
+
 func resolveParallelResultEnvelope(
 	g graph.Graph,
 	result nodeResult,
@@ -730,7 +1109,7 @@ func resolveParallelResultEnvelope(
 		return result.envelope, nil
 	}
 	if !opts.ContinueOnError {
-		return nil, fmt.Errorf("%w: node %s: %v", ErrNodeExecution, result.nodeID, result.err)
+		return nil, fmt.Errorf("%w: node %s: %w", ErrNodeExecution, result.nodeID, result.err)
 	}
 
 	node, _ := g.NodeByID(result.nodeID)
@@ -740,6 +1119,7 @@ func resolveParallelResultEnvelope(
 		Message: result.err.Error(),
 		Attempt: attempt,
 		At:      opts.Now(),
+		Details: core.ErrorDetails(result.err),
 		Cause:   result.err,
 	})
 	return previousEnvelope, nil
@@ -755,11 +1135,13 @@ func nodeKindOrUnknown(node core.Node) core.NodeKind {
 func (r *BasicRuntime) scheduleParallelSuccessors(
 	ctx context.Context,
 	g graph.Graph,
+	fromID string,
 	resultEnvelope *core.Envelope,
 	successors []string,
 	opts RunOptions,
 	state *parallelState,
 	workCh chan<- workItem,
+	branch *branchRun,
 ) (int, error) {
 	addedPending := 0
 	for _, succID := range successors {
@@ -769,7 +1151,14 @@ func (r *BasicRuntime) scheduleParallelSuccessors(
 		}
 
 		if mergeNode, ok := succNode.(core.MergeCapable); ok {
-			scheduled, err := scheduleMergeSuccessor(ctx, g, succID, succNode, mergeNode, resultEnvelope, opts, state, workCh)
+			input := resultEnvelope
+			if eg, ok := g.(graph.EdgeVarsGraph); ok && len(eg.EdgeVars(fromID, succID)) > 0 {
+				input = resultEnvelope.Clone()
+				if err := applyEdgeVars(g, fromID, succID, input); err != nil {
+					return addedPending, err
+				}
+			}
+			scheduled, err := scheduleMergeSuccessor(ctx, g, succID, succNode, mergeNode, input, opts, state, workCh, nextBranch(g, fromID, succID, input, branch))
 			if err != nil {
 				return addedPending, err
 			}
@@ -782,10 +1171,16 @@ func (r *BasicRuntime) scheduleParallelSuccessors(
 		if !state.canScheduleSuccessor(succID, opts.MaxHops) {
 			continue
 		}
+		if err := state.budget.admit(succID); err != nil {
+			return addedPending, err
+		}
 
 		// Clone envelope for parallel branches.
 		branchEnv := resultEnvelope.Clone()
-		workCh <- workItem{nodeID: succID, envelope: branchEnv}
+		if err := applyEdgeVars(g, fromID, succID, branchEnv); err != nil {
+			return addedPending, err
+		}
+		workCh <- workItem{nodeID: succID, envelope: branchEnv, branch: nextBranch(g, fromID, succID, branchEnv, branch)}
 		addedPending++
 	}
 	return addedPending, nil
@@ -801,25 +1196,53 @@ func scheduleMergeSuccessor(
 	opts RunOptions,
 	state *parallelState,
 	workCh chan<- workItem,
+	branch *branchRun,
 ) (bool, error) {
-	expectedInputs := mergeNode.ExpectedInputs()
-	if expectedInputs == 0 {
-		expectedInputs = len(g.Predecessors(succID))
-	}
-
-	inputs, ready := state.addMergeInput(succID, resultEnvelope, expectedInputs)
+	inputs, ready := state.addMergeInput(succID, resultEnvelope, expectedMergeInputs(g, succID, mergeNode))
 	if !ready {
 		return false, nil
 	}
+	return runMerge(ctx, succID, succNode, mergeNode, inputs, opts, state, workCh, branch)
+}
+
+func expectedMergeInputs(g graph.Graph, mergeID string, mergeNode core.MergeCapable) int {
+	if n := mergeNode.ExpectedInputs(); n != 0 {
+		return n
+	}
+	return len(g.Predecessors(mergeID))
+}
+
+// runMerge merges the inputs of a ready merge node and schedules it. The
+// merged envelope lists the timed-out branches the merge went without.
+func runMerge(
+	ctx context.Context,
+	succID string,
+	succNode core.Node,
+	mergeNode core.MergeCapable,
+	inputs []*core.Envelope,
+	opts RunOptions,
+	state *parallelState,
+	workCh chan<- workItem,
+	branch *branchRun,
+) (bool, error) {
+	if err := state.budget.admit(succID); err != nil {
+		return false, err
+	}
 
 	merger, hasMerge := succNode.(mergeRunner)
 	if !hasMerge {
 		// Fallback: just use first input.
+		for _, report := range state.takeMergeReports(succID) {
+			addMissingBranch(inputs[0], report)
+		}
 		state.resetNode(succID, inputs[0])
-		workCh <- workItem{nodeID: succID, envelope: inputs[0]}
+		workCh <- workItem{nodeID: succID, envelope: inputs[0], branch: branch}
 		return true, nil
 	}
 
+	for _, input := range inputs {
+		varLifetimes(opts.VarLifetimes).drop(input, graph.VarScopeBranch)
+	}
 	mergedEnv, mergeErr := merger.MergeInputs(ctx, inputs)
 	if mergeErr != nil {
 		if !opts.ContinueOnError {
@@ -830,13 +1253,17 @@ func scheduleMergeSuccessor(
 			Kind:    mergeNode.Kind(),
 			Message: mergeErr.Error(),
 			At:      opts.Now(),
+			Details: core.ErrorDetails(mergeErr),
 			Cause:   mergeErr,
 		})
 		mergedEnv = inputs[0] // fallback to first input
 	}
+	for _, report := range state.takeMergeReports(succID) {
+		addMissingBranch(mergedEnv, report)
+	}
 
 	state.resetNode(succID, mergedEnv)
-	workCh <- workItem{nodeID: succID, envelope: mergedEnv}
+	workCh <- workItem{nodeID: succID, envelope: mergedEnv, branch: branch}
 	return true, nil
 }
 
@@ -930,6 +1357,17 @@ func (r *BasicRuntime) determineSuccessors(
 	return filteredSuccessors
 }
 
+// NextNodes returns the nodes a sequential run moves to after node
+// finished with env: a gate's redirect target, the targets a router chose,
+// or every successor. Like a run, it clears a gate redirect it follows.
+// Executors that run one node at a time outside Run use it to follow the
+// graph.
+func NextNodes(g graph.Graph, node core.Node, env *core.Envelope) []string {
+	opts := DefaultRunOptions()
+	opts.Now = time.Now
+	return (&BasicRuntime{}).determineSuccessors(g, node, env, func(Event) {}, opts.Now(), opts)
+}
+
 // executeNode executes a single node with event emission.
 func (r *BasicRuntime) executeNode(
 	ctx context.Context,
@@ -942,15 +1380,49 @@ func (r *BasicRuntime) executeNode(
 	nodeID := node.ID()
 	nodeKind := node.Kind()
 	runID := env.Trace.RunID
+	lifetimes := varLifetimes(opts.VarLifetimes)
+	held := lifetimes.nodeScoped(env)
+
+	restored, ok, err := opts.Resume.take(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		restored.Input = env.Input
+		restored.Artifacts = env.Artifacts
+		restored.Errors = env.Errors
+		restored.Trace = env.Trace
+		lifetimes.afterNode(node, held, restored)
+		emit(NewEvent(EventNodeRestored, runID).
+			WithNode(nodeID, nodeKind).
+			WithElapsed(opts.Now().Sub(runStart)).
+			WithPayload("reason", "completed in earlier attempt"))
+		return restored, nil
+	}
 
 	// Emit node started
 	nodeStart := opts.Now()
+	doc := opts.NodeDocs[nodeID]
 	emit(NewEvent(EventNodeStarted, runID).
 		WithNode(nodeID, nodeKind).
-		WithElapsed(nodeStart.Sub(runStart)))
+		WithElapsed(nodeStart.Sub(runStart)).
+		withNodeDoc(doc))
+	if opts.NodeSnapshot != nil {
+		opts.NodeSnapshot(nodeID, "input", recordOutput(env, lifetimes))
+	}
+
+	// Inject emitter into context for node use, and the run and node IDs
+	// for the correlation headers and timeout clamping of outbound requests.
+	nodeCtx := propagate.WithNode(ContextWithEmitter(ctx, emit), runID, nodeID)
+	nodeCtx = contextWithNode(nodeCtx, runID, nodeID, nodeKind)
 
-	// Inject emitter into context for node use
-	nodeCtx := ContextWithEmitter(ctx, emit)
+	if err := chaosFromContext(ctx).injectLatency(nodeCtx, runID, nodeID, nodeKind); err != nil {
+		emit(NewEvent(EventNodeFailed, runID).
+			WithNode(nodeID, nodeKind).
+			WithElapsed(opts.Now().Sub(nodeStart)).
+			WithPayload("error", err.Error()))
+		return nil, err
+	}
 
 	// Execute node
 	result, err := node.Run(nodeCtx, env)
@@ -960,17 +1432,31 @@ func (r *BasicRuntime) executeNode(
 
 	if err != nil {
 		// Emit node failed
-		emit(NewEvent(EventNodeFailed, runID).
+		failed := NewEvent(EventNodeFailed, runID).
 			WithNode(nodeID, nodeKind).
 			WithElapsed(nodeElapsed).
-			WithPayload("error", err.Error()))
+			WithPayload("error", err.Error()).
+			withNodeDoc(doc)
+		if details := core.ErrorDetails(err); details != nil {
+			failed = failed.WithPayload("details", details)
+		}
+		emit(failed)
 		return nil, err
 	}
 
+	lifetimes.afterNode(node, held, result)
+	if opts.NodeSnapshot != nil && result != nil {
+		opts.NodeSnapshot(nodeID, "output", recordOutput(result, lifetimes))
+	}
+
 	// Emit node finished
-	emit(NewEvent(EventNodeFinished, runID).
+	finished := NewEvent(EventNodeFinished, runID).
 		WithNode(nodeID, nodeKind).
-		WithElapsed(nodeElapsed))
+		WithElapsed(nodeElapsed)
+	if opts.RecordNodeOutputs && result != nil {
+		finished = finished.WithPayload("output", recordOutput(result, lifetimes))
+	}
+	emit(finished)
 
 	return result, nil
 }
@@ -1093,6 +1579,11 @@ func validateGraph(g graph.Graph) error {
 	return nil
 }
 
+// NewRunID returns a new random run ID in the format used by Run.
+func NewRunID() string {
+	return generateRunID()
+}
+
 // generateRunID creates a unique run identifier.
 // Uses crypto/rand for secure random generation.
 func generateRunID() string {
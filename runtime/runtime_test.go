@@ -3,6 +3,7 @@ package runtime_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"sync"
 	"testing"
 	"time"
@@ -279,6 +280,69 @@ func TestRuntime_Run_EventNodeFailed(t *testing.T) {
 	}
 }
 
+type detailedTestError struct{}
+
+func (detailedTestError) Error() string { return "limit exceeded" }
+
+func (detailedTestError) ErrorDetails() map[string]any {
+	return map[string]any{"rule": "size"}
+}
+
+func TestRuntime_Run_NodeDocs(t *testing.T) {
+	g := graph.NewGraph("docs")
+	g.AddNode(core.NewFuncNode("enrich", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		return nil, errors.New("crm unavailable")
+	}))
+	g.SetEntry("enrich")
+
+	events := make(map[runtime.EventKind]runtime.Event)
+	opts := runtime.DefaultRunOptions()
+	opts.NodeDocs = map[string]graph.NodeDoc{
+		"enrich": {Description: "Adds CRM fields", DocURL: "https://runbooks.example.com/enrich"},
+	}
+	opts.EventHandler = func(e runtime.Event) { events[e.Kind] = e }
+
+	_, _ = runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+
+	for _, kind := range []runtime.EventKind{runtime.EventNodeStarted, runtime.EventNodeFailed} {
+		e := events[kind]
+		if e.Payload["description"] != "Adds CRM fields" || e.Payload["doc_url"] != "https://runbooks.example.com/enrich" {
+			t.Errorf("%s payload = %v, want node docs", kind, e.Payload)
+		}
+	}
+}
+
+func TestRuntime_Run_ErrorDetails(t *testing.T) {
+	g := graph.NewGraph("details")
+	g.AddNode(core.NewFuncNode("fail", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		return nil, fmt.Errorf("render: %w", detailedTestError{})
+	}))
+	g.SetEntry("fail")
+
+	var failedEvent *runtime.Event
+	opts := runtime.DefaultRunOptions()
+	opts.ContinueOnError = true
+	opts.EventHandler = func(e runtime.Event) {
+		if e.Kind == runtime.EventNodeFailed {
+			failedEvent = &e
+		}
+	}
+
+	result, err := runtime.NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Details["rule"] != "size" {
+		t.Errorf("Errors = %+v, want details recorded", result.Errors)
+	}
+	if failedEvent == nil {
+		t.Fatal("EventNodeFailed should be emitted")
+	}
+	if details, _ := failedEvent.Payload["details"].(map[string]any); details["rule"] != "size" {
+		t.Errorf("event payload = %v, want details", failedEvent.Payload)
+	}
+}
+
 func TestRuntime_Run_ExecutionOrder(t *testing.T) {
 	order := make([]string, 0)
 
@@ -1288,3 +1352,33 @@ func TestRuntime_Run_MapNode(t *testing.T) {
 		t.Error("expected end node to execute")
 	}
 }
+
+func TestRuntime_Run_NodeSnapshot(t *testing.T) {
+	g := graph.NewGraph("snapshots")
+	g.AddNode(core.NewFuncNode("greet", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		env.SetVar("greeting", "hello "+env.GetVarString("name"))
+		return env, nil
+	}))
+	g.SetEntry("greet")
+
+	var phases []string
+	var output map[string]any
+	opts := runtime.DefaultRunOptions()
+	opts.NodeSnapshot = func(nodeID, phase string, snapshot map[string]any) {
+		phases = append(phases, nodeID+":"+phase)
+		if phase == "output" {
+			output, _ = snapshot["vars"].(map[string]any)
+		}
+	}
+
+	env := core.NewEnvelope().WithVar("name", "ada")
+	if _, err := runtime.NewRuntime().Run(context.Background(), g, env, opts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if fmt.Sprint(phases) != "[greet:input greet:output]" {
+		t.Errorf("phases = %v", phases)
+	}
+	if output["greeting"] != "hello ada" {
+		t.Errorf("output snapshot = %v", output)
+	}
+}
@@ -0,0 +1,132 @@
+package runtime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// slaTracker watches one run against its SLA targets. Deadlines are
+// checked by timers as the run executes, so a breach is reported while the
+// run is still going rather than only once it ends. A nil tracker tracks
+// nothing.
+type slaTracker struct {
+	targets   graph.SLATargets
+	runID     string
+	emit      EventEmitter
+	wallStart time.Time
+
+	mu       sync.Mutex
+	timers   []*time.Timer
+	breaches []string
+	sawLLM   bool
+	done     bool
+}
+
+func newSLATracker(targets graph.SLATargets, runID string) *slaTracker {
+	if targets.IsZero() {
+		return nil
+	}
+	return &slaTracker{targets: targets, runID: runID, breaches: []string{}}
+}
+
+// start begins tracking. queueWait is how long the run waited before it
+// started; a wait over the target is a breach straight away.
+func (t *slaTracker) start(emit EventEmitter, queueWait time.Duration) {
+	if t == nil {
+		return
+	}
+	t.emit = emit
+	t.wallStart = time.Now()
+	if t.targets.MaxQueueWait > 0 && queueWait > t.targets.MaxQueueWait {
+		t.breach(graph.SLAMaxQueueWait, t.targets.MaxQueueWait, queueWait)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if d := t.targets.MaxDuration; d > 0 {
+		t.timers = append(t.timers, time.AfterFunc(d, func() {
+			t.breach(graph.SLAMaxDuration, d, time.Since(t.wallStart))
+		}))
+	}
+	if d := t.targets.MaxFirstLLMResponse; d > 0 {
+		t.timers = append(t.timers, time.AfterFunc(d, func() {
+			t.mu.Lock()
+			sawLLM := t.sawLLM
+			t.mu.Unlock()
+			if !sawLLM {
+				t.breach(graph.SLAMaxFirstLLMResponse, d, time.Since(t.wallStart))
+			}
+		}))
+	}
+}
+
+// observe notes the run's first LLM response.
+func (t *slaTracker) observe(e Event) {
+	if t == nil || e.Kind != EventLLMResponse {
+		return
+	}
+	t.mu.Lock()
+	t.sawLLM = true
+	t.mu.Unlock()
+}
+
+// breach records and emits a breach of the named SLA, once per SLA and
+// never after the run has finished.
+func (t *slaTracker) breach(name string, limit, actual time.Duration) {
+	t.mu.Lock()
+	if t.done {
+		t.mu.Unlock()
+		return
+	}
+	for _, b := range t.breaches {
+		if b == name {
+			t.mu.Unlock()
+			return
+		}
+	}
+	t.breaches = append(t.breaches, name)
+	t.mu.Unlock()
+
+	t.emit(NewEvent(EventSLABreached, t.runID).
+		WithPayload("sla", name).
+		WithPayload("limit_ms", limit.Milliseconds()).
+		WithPayload("actual_ms", actual.Milliseconds()))
+}
+
+// stop checks the run's final duration, stops the timers and returns the
+// names of the SLAs the run breached.
+func (t *slaTracker) stop(elapsed time.Duration) []string {
+	if t == nil {
+		return nil
+	}
+	if t.targets.MaxDuration > 0 && elapsed > t.targets.MaxDuration {
+		t.breach(graph.SLAMaxDuration, t.targets.MaxDuration, elapsed)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.done = true
+	for _, timer := range t.timers {
+		timer.Stop()
+	}
+	breaches := make([]string, len(t.breaches))
+	copy(breaches, t.breaches)
+	return breaches
+}
+
+// targetsPayload returns the SLA targets as run.started payload fields.
+func (t *slaTracker) targetsPayload() map[string]any {
+	payload := map[string]any{}
+	for name, d := range map[string]time.Duration{
+		graph.SLAMaxDuration:         t.targets.MaxDuration,
+		graph.SLAMaxFirstLLMResponse: t.targets.MaxFirstLLMResponse,
+		graph.SLAMaxQueueWait:        t.targets.MaxQueueWait,
+	} {
+		if d > 0 {
+			payload[name+"_ms"] = d.Milliseconds()
+		}
+	}
+	return payload
+}
@@ -0,0 +1,130 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// runSLAGraph runs a single node that emits an llm.response after llmAfter
+// (or never, when negative) and returns after sleep.
+func runSLAGraph(t *testing.T, targets graph.SLATargets, queueWait, llmAfter, sleep time.Duration) []Event {
+	t.Helper()
+	g := graph.NewGraph("sla")
+	g.AddNode(core.NewFuncNode("work", func(ctx context.Context, env *core.Envelope) (*core.Envelope, error) {
+		if llmAfter >= 0 {
+			time.Sleep(llmAfter)
+			EmitterFromContext(ctx)(NewEvent(EventLLMResponse, env.Trace.RunID))
+		}
+		time.Sleep(sleep)
+		return env, nil
+	}))
+	g.SetEntry("work")
+
+	var mu sync.Mutex
+	var events []Event
+	opts := DefaultRunOptions()
+	opts.SLA = targets
+	opts.QueueWait = queueWait
+	opts.EventHandler = func(e Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, e)
+	}
+	if _, err := NewRuntime().Run(context.Background(), g, core.NewEnvelope(), opts); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return events
+}
+
+func slaBreaches(t *testing.T, events []Event) (breached []string, finished []string) {
+	t.Helper()
+	for _, e := range events {
+		switch e.Kind {
+		case EventSLABreached:
+			breached = append(breached, e.Payload["sla"].(string))
+		case EventRunFinished:
+			finished = e.Payload["sla_breaches"].([]string)
+		}
+	}
+	return breached, finished
+}
+
+func TestSLA_Met(t *testing.T) {
+	events := runSLAGraph(t, graph.SLATargets{
+		MaxDuration:         time.Second,
+		MaxFirstLLMResponse: time.Second,
+		MaxQueueWait:        time.Second,
+	}, 10*time.Millisecond, 0, 0)
+
+	breached, finished := slaBreaches(t, events)
+	if len(breached) != 0 || finished == nil || len(finished) != 0 {
+		t.Errorf("breached = %v, run.finished sla_breaches = %#v, want none", breached, finished)
+	}
+	targets, _ := events[0].Payload["sla"].(map[string]any)
+	if targets["max_duration_ms"] != int64(1000) || targets["max_queue_wait_ms"] != int64(1000) {
+		t.Errorf("run.started sla = %v", events[0].Payload["sla"])
+	}
+}
+
+func TestSLA_BreachedWhileRunning(t *testing.T) {
+	events := runSLAGraph(t, graph.SLATargets{
+		MaxDuration:         20 * time.Millisecond,
+		MaxFirstLLMResponse: 10 * time.Millisecond,
+	}, 0, -1, 80*time.Millisecond)
+
+	breached, finished := slaBreaches(t, events)
+	if len(breached) != 2 || len(finished) != 2 {
+		t.Fatalf("breached = %v, run.finished sla_breaches = %v, want two", breached, finished)
+	}
+	if finished[0] != graph.SLAMaxFirstLLMResponse || finished[1] != graph.SLAMaxDuration {
+		t.Errorf("sla_breaches = %v", finished)
+	}
+	// The timers report both breaches while the node is still running.
+	var nodeFinished bool
+	for _, e := range events {
+		switch e.Kind {
+		case EventNodeFinished:
+			nodeFinished = true
+		case EventSLABreached:
+			if nodeFinished {
+				t.Errorf("%v breach reported after the node finished", e.Payload["sla"])
+			}
+			if e.Payload["sla"] == graph.SLAMaxDuration && e.Payload["limit_ms"] != int64(20) {
+				t.Errorf("payload = %v", e.Payload)
+			}
+		}
+	}
+}
+
+func TestSLA_QueueWaitAndLateLLM(t *testing.T) {
+	events := runSLAGraph(t, graph.SLATargets{
+		MaxFirstLLMResponse: 10 * time.Millisecond,
+		MaxQueueWait:        5 * time.Millisecond,
+	}, 50*time.Millisecond, 40*time.Millisecond, 0)
+
+	_, finished := slaBreaches(t, events)
+	if len(finished) != 2 || finished[0] != graph.SLAMaxQueueWait || finished[1] != graph.SLAMaxFirstLLMResponse {
+		t.Errorf("sla_breaches = %v", finished)
+	}
+	for _, e := range events {
+		if e.Kind == EventSLABreached && e.Payload["sla"] == graph.SLAMaxQueueWait && e.Payload["actual_ms"] != int64(50) {
+			t.Errorf("queue wait payload = %v", e.Payload)
+		}
+	}
+}
+
+func TestSLA_NoTargetsLeavesRunFinishedUntouched(t *testing.T) {
+	events := runSLAGraph(t, graph.SLATargets{}, time.Hour, -1, 0)
+	for _, e := range events {
+		if _, ok := e.Payload["sla_breaches"]; ok || e.Kind == EventSLABreached {
+			t.Errorf("event %s carries SLA data without targets", e.Kind)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package runtime
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/petal-labs/petalflow/core"
+)
+
+// Wait reasons reported by EventWaitStarted.
+const (
+	// WaitReasonHuman is a human node waiting for a response.
+	WaitReasonHuman = "human"
+	// WaitReasonWebhook is a webhook_call node waiting for the remote
+	// endpoint to answer.
+	WaitReasonWebhook = "webhook"
+	// WaitReasonDelay is a node sleeping before it continues.
+	WaitReasonDelay = "delay"
+	// WaitReasonTool is a tool node waiting for the result of an
+	// asynchronous invocation.
+	WaitReasonTool = "tool"
+)
+
+// Wait describes a node blocked on something outside the run.
+type Wait struct {
+	RunID    string
+	NodeID   string
+	NodeKind core.NodeKind
+	// Reason is one of the WaitReason constants.
+	Reason string
+	// Deadline is when the wait ends at the latest, if known. The earlier
+	// of it and the context's deadline is reported.
+	Deadline time.Time
+	// Details are added to the wait.started payload.
+	Details map[string]any
+}
+
+// BeginWait emits EventWaitStarted on ctx's emitter and returns a function
+// that emits the matching EventWaitFinished. Callers should apply their
+// own timeouts to ctx first, so the reported deadline includes them.
+func BeginWait(ctx context.Context, w Wait) (end func()) {
+	emit := EmitterFromContext(ctx)
+	waitID := uuid.New().String()
+
+	started := NewEvent(EventWaitStarted, w.RunID).
+		WithNode(w.NodeID, w.NodeKind).
+		WithPayload("wait_id", waitID).
+		WithPayload("reason", w.Reason)
+	deadline := w.Deadline
+	if ctxDeadline, ok := ctx.Deadline(); ok && (deadline.IsZero() || ctxDeadline.Before(deadline)) {
+		deadline = ctxDeadline
+	}
+	if !deadline.IsZero() {
+		started = started.WithPayload("deadline", deadline.UTC().Format(time.RFC3339Nano))
+	}
+	for k, v := range w.Details {
+		started = started.WithPayload(k, v)
+	}
+	emit(started)
+
+	begun := started.Time
+	return func() {
+		emit(NewEvent(EventWaitFinished, w.RunID).
+			WithNode(w.NodeID, w.NodeKind).
+			WithElapsed(time.Since(begun)).
+			WithPayload("wait_id", waitID).
+			WithPayload("reason", w.Reason))
+	}
+}
@@ -0,0 +1,41 @@
+package runtime
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBeginWait(t *testing.T) {
+	var events []Event
+	ctx := ContextWithEmitter(context.Background(), func(e Event) { events = append(events, e) })
+	ctx, cancel := context.WithTimeout(ctx, time.Hour)
+	defer cancel()
+
+	end := BeginWait(ctx, Wait{
+		RunID:    "run-1",
+		NodeID:   "approve",
+		NodeKind: "human",
+		Reason:   WaitReasonHuman,
+		Deadline: time.Now().Add(time.Minute),
+		Details:  map[string]any{"request_id": "req-1"},
+	})
+	end()
+
+	if len(events) != 2 || events[0].Kind != EventWaitStarted || events[1].Kind != EventWaitFinished {
+		t.Fatalf("events = %+v", events)
+	}
+	started := events[0]
+	if started.RunID != "run-1" || started.NodeID != "approve" || started.Payload["reason"] != WaitReasonHuman || started.Payload["request_id"] != "req-1" {
+		t.Errorf("started = %+v", started)
+	}
+	waitID, _ := started.Payload["wait_id"].(string)
+	if waitID == "" || events[1].Payload["wait_id"] != waitID {
+		t.Errorf("wait IDs = %v, %v", started.Payload["wait_id"], events[1].Payload["wait_id"])
+	}
+	deadline, err := time.Parse(time.RFC3339Nano, started.Payload["deadline"].(string))
+	if err != nil || time.Until(deadline) > time.Minute {
+		t.Errorf("deadline = %v (%v), want the earlier wait deadline", deadline, err)
+	}
+
+}
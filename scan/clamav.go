@@ -0,0 +1,107 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// DefaultClamAVTimeout bounds a ClamAV scan, from connecting to reading
+// the verdict.
+const DefaultClamAVTimeout = 30 * time.Second
+
+// clamAVChunkSize is the size of the INSTREAM chunks sent to clamd.
+const clamAVChunkSize = 64 << 10
+
+// ClamAV is a Scanner backed by a clamd daemon, using its INSTREAM
+// command. Artifacts larger than clamd's StreamMaxLength make the scan
+// fail, so keep that limit above the daemon's upload limit.
+type ClamAV struct {
+	// Address is host:port for TCP or unix:/path/to/clamd.sock.
+	Address string
+	// Timeout bounds a scan. 0 uses DefaultClamAVTimeout.
+	Timeout time.Duration
+}
+
+// NewClamAV returns a ClamAV scanner for the clamd at address.
+func NewClamAV(address string, timeout time.Duration) *ClamAV {
+	return &ClamAV{Address: address, Timeout: timeout}
+}
+
+// Scan implements Scanner.
+func (c *ClamAV) Scan(ctx context.Context, artifact Artifact) (Result, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultClamAVTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	network, address := "tcp", c.Address
+	if socket, ok := strings.CutPrefix(c.Address, "unix:"); ok {
+		network, address = "unix", socket
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return Result{}, fmt.Errorf("clamav: connecting to %s: %w", c.Address, err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	if err := writeInstream(conn, artifact.Data); err != nil {
+		return Result{}, fmt.Errorf("clamav: sending stream: %w", err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return Result{}, fmt.Errorf("clamav: reading reply: %w", err)
+	}
+	return parseClamAVReply(reply)
+}
+
+// writeInstream sends data as a zINSTREAM command: length-prefixed chunks
+// ended by a zero-length chunk.
+func writeInstream(conn net.Conn, data []byte) error {
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return err
+	}
+	var size [4]byte
+	for len(data) > 0 {
+		n := min(len(data), clamAVChunkSize)
+		binary.BigEndian.PutUint32(size[:], uint32(n))
+		if _, err := conn.Write(size[:]); err != nil {
+			return err
+		}
+		if _, err := conn.Write(data[:n]); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	_, err := conn.Write(size[:])
+	return err
+}
+
+// parseClamAVReply turns "stream: OK", "stream: <signature> FOUND" or an
+// ERROR reply into a Result.
+func parseClamAVReply(reply string) (Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	body := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case body == "OK":
+		return Result{Clean: true, Scanner: "clamav"}, nil
+	case strings.HasSuffix(body, " FOUND"):
+		return Result{Threat: strings.TrimSuffix(body, " FOUND"), Scanner: "clamav"}, nil
+	case reply == "":
+		return Result{}, errors.New("clamav: empty reply")
+	default:
+		return Result{}, fmt.Errorf("clamav: %s", reply)
+	}
+}
@@ -0,0 +1,104 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd accepts one INSTREAM connection, records the streamed bytes
+// and answers with reply.
+func fakeClamd(t *testing.T, reply func(data []byte) string) (string, <-chan []byte) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		cmd, err := r.ReadString(0)
+		if err != nil || cmd != "zINSTREAM\x00" {
+			_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			return
+		}
+		var data bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&data, r, int64(size)); err != nil {
+				return
+			}
+		}
+		received <- data.Bytes()
+		_, _ = conn.Write([]byte(reply(data.Bytes()) + "\x00"))
+	}()
+	return ln.Addr().String(), received
+}
+
+func TestClamAV_Scan(t *testing.T) {
+	reply := func(data []byte) string {
+		if bytes.Contains(data, []byte("EICAR")) {
+			return "stream: Eicar-Test-Signature FOUND"
+		}
+		return "stream: OK"
+	}
+
+	addr, received := fakeClamd(t, reply)
+	data := []byte(strings.Repeat("a", clamAVChunkSize+10))
+	result, err := NewClamAV(addr, time.Second).Scan(context.Background(), Artifact{Data: data})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if !result.Clean || result.Scanner != "clamav" {
+		t.Fatalf("result = %+v", result)
+	}
+	if got := <-received; !bytes.Equal(got, data) {
+		t.Fatalf("clamd received %d bytes, want %d", len(got), len(data))
+	}
+
+	addr, _ = fakeClamd(t, reply)
+	result, err = NewClamAV(addr, time.Second).Scan(context.Background(), Artifact{Data: []byte("X5O!P%@AP EICAR")})
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if result.Clean || result.Threat != "Eicar-Test-Signature" {
+		t.Fatalf("result = %+v", result)
+	}
+}
+
+func TestClamAV_Errors(t *testing.T) {
+	addr, _ := fakeClamd(t, func([]byte) string { return "INSTREAM size limit exceeded. ERROR" })
+	if _, err := NewClamAV(addr, time.Second).Scan(context.Background(), Artifact{Data: []byte("x")}); err == nil ||
+		!strings.Contains(err.Error(), "size limit exceeded") {
+		t.Fatalf("err = %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closed := ln.Addr().String()
+	_ = ln.Close()
+	if _, err := NewClamAV(closed, time.Second).Scan(context.Background(), Artifact{Data: []byte("x")}); err == nil {
+		t.Fatal("expected a connection error")
+	}
+}
@@ -0,0 +1,152 @@
+// Package scan checks inbound artifacts, such as run input uploads, before
+// any node reads them. A Scanner returns a verdict per artifact; artifacts
+// that are not clean are quarantined by the caller. Scanners combine with
+// Chain, so a size and type Policy can run ahead of a virus scanner such
+// as ClamAV.
+package scan
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Artifact is the content being scanned.
+type Artifact struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+// Result is a scanner's verdict.
+type Result struct {
+	// Clean is false when the artifact must be quarantined.
+	Clean bool `json:"clean"`
+	// Threat names what was found, such as a virus signature or the
+	// policy rule that was broken.
+	Threat string `json:"threat,omitempty"`
+	// Scanner names the scanner that produced the verdict.
+	Scanner string `json:"scanner"`
+}
+
+// Scanner inspects an artifact. It returns an error only when it could
+// not reach a verdict; callers should then refuse the artifact rather
+// than pass it on unscanned.
+type Scanner interface {
+	Scan(ctx context.Context, artifact Artifact) (Result, error)
+}
+
+// Chain runs scanners in order and returns the first verdict that is not
+// clean. Nil scanners are skipped.
+func Chain(scanners ...Scanner) Scanner {
+	var c chain
+	for _, s := range scanners {
+		if s != nil {
+			c = append(c, s)
+		}
+	}
+	return c
+}
+
+type chain []Scanner
+
+func (c chain) Scan(ctx context.Context, artifact Artifact) (Result, error) {
+	names := make([]string, 0, len(c))
+	for _, s := range c {
+		result, err := s.Scan(ctx, artifact)
+		if err != nil {
+			return Result{}, err
+		}
+		if !result.Clean {
+			return result, nil
+		}
+		names = append(names, result.Scanner)
+	}
+	return Result{Clean: true, Scanner: strings.Join(names, ",")}, nil
+}
+
+// Policy is a Scanner that checks size, type and name rules without
+// looking for malware.
+type Policy struct {
+	// MaxBytes quarantines larger artifacts. 0 disables the check.
+	MaxBytes int64
+	// BlockedTypes are media types to quarantine, matched against both
+	// the declared and the sniffed type. Entries may end in "/*".
+	BlockedTypes []string
+	// BlockedExtensions are file name extensions to quarantine, such as
+	// ".exe". Matching ignores case.
+	BlockedExtensions []string
+	// BlockExecutables quarantines content that starts like a native
+	// executable or a script with a #! line, whatever its declared type.
+	BlockExecutables bool
+}
+
+// executableMagic are the leading bytes of native executables and scripts.
+var executableMagic = []struct {
+	prefix []byte
+	kind   string
+}{
+	{[]byte("MZ"), "a Windows executable"},
+	{[]byte("\x7fELF"), "an ELF executable"},
+	{[]byte("\xfe\xed\xfa\xce"), "a Mach-O executable"},
+	{[]byte("\xfe\xed\xfa\xcf"), "a Mach-O executable"},
+	{[]byte("\xce\xfa\xed\xfe"), "a Mach-O executable"},
+	{[]byte("\xcf\xfa\xed\xfe"), "a Mach-O executable"},
+	{[]byte("\xca\xfe\xba\xbe"), "a Mach-O universal binary"},
+	{[]byte("#!"), "a script"},
+}
+
+// Scan implements Scanner.
+func (p *Policy) Scan(_ context.Context, artifact Artifact) (Result, error) {
+	quarantine := func(format string, args ...any) (Result, error) {
+		return Result{Threat: fmt.Sprintf(format, args...), Scanner: "policy"}, nil
+	}
+
+	if p.MaxBytes > 0 && int64(len(artifact.Data)) > p.MaxBytes {
+		return quarantine("size %d exceeds %d bytes", len(artifact.Data), p.MaxBytes)
+	}
+	if artifact.Name != "" {
+		ext := strings.ToLower(path.Ext(artifact.Name))
+		for _, blocked := range p.BlockedExtensions {
+			if ext != "" && ext == strings.ToLower(blocked) {
+				return quarantine("file extension %q is blocked", ext)
+			}
+		}
+	}
+	if len(p.BlockedTypes) > 0 {
+		declared, _, _ := mime.ParseMediaType(artifact.ContentType)
+		sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(artifact.Data))
+		for _, mediaType := range []string{declared, sniffed} {
+			if mediaType != "" && matchType(p.BlockedTypes, mediaType) {
+				return quarantine("content type %q is blocked", mediaType)
+			}
+		}
+	}
+	if p.BlockExecutables {
+		for _, m := range executableMagic {
+			if bytes.HasPrefix(artifact.Data, m.prefix) {
+				return quarantine("content looks like %s", m.kind)
+			}
+		}
+	}
+	return Result{Clean: true, Scanner: "policy"}, nil
+}
+
+// matchType reports whether mediaType is in patterns, which may end in
+// "/*" to match a whole family.
+func matchType(patterns []string, mediaType string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == mediaType {
+			return true
+		}
+		if family, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, family+"/") {
+			return true
+		}
+	}
+	return false
+}
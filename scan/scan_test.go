@@ -0,0 +1,75 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPolicy_Scan(t *testing.T) {
+	p := &Policy{
+		MaxBytes:          16,
+		BlockedTypes:      []string{"application/zip", "video/*"},
+		BlockedExtensions: []string{".EXE"},
+		BlockExecutables:  true,
+	}
+	tests := []struct {
+		name     string
+		artifact Artifact
+		threat   string
+	}{
+		{"clean", Artifact{Name: "notes.txt", ContentType: "text/plain", Data: []byte("hello")}, ""},
+		{"too large", Artifact{Data: []byte(strings.Repeat("x", 17))}, "size 17 exceeds 16 bytes"},
+		{"extension", Artifact{Name: "setup.exe", Data: []byte("x")}, `file extension ".exe" is blocked`},
+		{"declared type", Artifact{ContentType: "video/mp4", Data: []byte("x")}, `content type "video/mp4" is blocked`},
+		{"sniffed type", Artifact{ContentType: "text/plain", Data: []byte("PK\x03\x04abc")}, `content type "application/zip" is blocked`},
+		{"elf", Artifact{ContentType: "application/pdf", Data: []byte("\x7fELF\x02")}, "content looks like an ELF executable"},
+		{"script", Artifact{ContentType: "text/plain", Data: []byte("#!/bin/sh\n")}, "content looks like a script"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := p.Scan(context.Background(), tt.artifact)
+			if err != nil {
+				t.Fatalf("Scan: %v", err)
+			}
+			if result.Clean != (tt.threat == "") || result.Threat != tt.threat || result.Scanner != "policy" {
+				t.Fatalf("result = %+v, want threat %q", result, tt.threat)
+			}
+		})
+	}
+}
+
+type scannerFunc func(Artifact) (Result, error)
+
+func (f scannerFunc) Scan(_ context.Context, a Artifact) (Result, error) { return f(a) }
+
+func TestChain(t *testing.T) {
+	calls := 0
+	clean := func(name string) Scanner {
+		return scannerFunc(func(Artifact) (Result, error) {
+			calls++
+			return Result{Clean: true, Scanner: name}, nil
+		})
+	}
+	infected := scannerFunc(func(Artifact) (Result, error) {
+		calls++
+		return Result{Threat: "Eicar-Test-Signature", Scanner: "av"}, nil
+	})
+
+	result, err := Chain(clean("a"), nil, clean("b")).Scan(context.Background(), Artifact{})
+	if err != nil || !result.Clean || result.Scanner != "a,b" {
+		t.Fatalf("clean chain = %+v, %v", result, err)
+	}
+
+	calls = 0
+	result, err = Chain(infected, clean("b")).Scan(context.Background(), Artifact{})
+	if err != nil || result.Clean || result.Threat != "Eicar-Test-Signature" || calls != 1 {
+		t.Fatalf("infected chain = %+v, %v (calls %d)", result, err, calls)
+	}
+
+	broken := scannerFunc(func(Artifact) (Result, error) { return Result{}, errors.New("unreachable") })
+	if _, err := Chain(broken, infected).Scan(context.Background(), Artifact{}); err == nil {
+		t.Fatal("expected the scanner error")
+	}
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/petal-labs/petalflow/backup"
+)
+
+// AdminRestorePath restores a snapshot produced by GET /api/admin/backup.
+const AdminRestorePath = "/api/admin/restore"
+
+// maxRestoreBytes caps a restore body. Snapshots carry uploads and run
+// history, so they are exempt from MaxBody.
+const maxRestoreBytes = 1 << 30
+
+// IsRestoreRequest reports whether r restores a snapshot. Such requests
+// carry their own size limit and bypass the general max body middleware.
+func IsRestoreRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.TrimSuffix(r.URL.Path, "/") == AdminRestorePath
+}
+
+// handleAdminBackup streams a gzip snapshot of the daemon database. Query
+// parameters: events=true adds run history; since=<RFC3339> makes the
+// snapshot incremental.
+func (s *Server) handleAdminBackup(w http.ResponseWriter, r *http.Request) {
+	if s.backups == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "admin API is not enabled")
+		return
+	}
+	var opts backup.Options
+	if raw := r.URL.Query().Get("events"); raw != "" {
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_PARAM", "events must be a boolean")
+			return
+		}
+		opts.IncludeEvents = v
+	}
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339Nano, raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_PARAM", "since must be an RFC3339 timestamp")
+			return
+		}
+		opts.Since = since
+	}
+
+	snap, err := s.backups.Create(r.Context(), opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "BACKUP_FAILED", err.Error())
+		return
+	}
+	name := "petalflow-" + snap.CreatedAt.Format("20060102T150405Z") + ".backup"
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.WriteHeader(http.StatusOK)
+	if err := backup.Write(w, snap); err != nil {
+		s.logger.Error("writing backup", "error", err)
+	}
+}
+
+// handleAdminRestore applies one snapshot, gzipped or plain JSON.
+// Incremental snapshots must be restored in the order they were taken.
+func (s *Server) handleAdminRestore(w http.ResponseWriter, r *http.Request) {
+	if s.backups == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "admin API is not enabled")
+		return
+	}
+	snap, err := backup.Read(http.MaxBytesReader(w, r.Body, maxRestoreBytes))
+	if err != nil {
+		if isMaxBytesError(err) {
+			writeError(w, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE", "request body exceeds size limit")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "INVALID_SNAPSHOT", err.Error())
+		return
+	}
+	result, err := s.backups.Restore(r.Context(), snap)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "RESTORE_FAILED", err.Error())
+		return
+	}
+	s.logger.Warn("daemon state restored from snapshot", "created_at", snap.CreatedAt, "incremental", snap.Incremental())
+	writeJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/backup"
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/loader"
+)
+
+func adminTestServer(t *testing.T) (http.Handler, *SQLiteStore) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "petalflow.db")
+	store, err := NewSQLiteStore(SQLiteStoreConfig{DSN: path})
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close() })
+	backups, err := backup.Open(path)
+	if err != nil {
+		t.Fatalf("backup.Open: %v", err)
+	}
+	t.Cleanup(func() { _ = backups.Close() })
+	return NewServer(ServerConfig{
+		Store:     store,
+		Providers: hydrate.ProviderMap{},
+		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+			return nil, nil
+		},
+		MaxBody: 64,
+		Backups: backups,
+	}).Handler(), store
+}
+
+func TestAdminBackupRestore(t *testing.T) {
+	handler, store := adminTestServer(t)
+	now := time.Now().UTC()
+	if err := store.Create(context.Background(), WorkflowRecord{
+		ID:         "wf-backup",
+		SchemaKind: loader.SchemaKindGraph,
+		Source:     validGraphJSON("wf-backup"),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/backup", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/gzip" {
+		t.Fatalf("backup status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	snapshot := w.Body.Bytes()
+
+	other, otherStore := adminTestServer(t)
+	// Restores are larger than MaxBody and must bypass it.
+	r = httptest.NewRequest(http.MethodPost, AdminRestorePath, bytes.NewReader(snapshot))
+	w = httptest.NewRecorder()
+	other.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("restore status %d: %s", w.Code, w.Body.String())
+	}
+	var result backup.RestoreResult
+	if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+		t.Fatalf("decode restore result: %v", err)
+	}
+	if len(result.Tables) == 0 || result.Tables[0].Name != "workflows" || result.Tables[0].Restored != 1 {
+		t.Fatalf("restore result = %+v", result)
+	}
+	if _, ok, err := otherStore.Get(context.Background(), "wf-backup"); err != nil || !ok {
+		t.Fatalf("restored workflow missing: ok=%v err=%v", ok, err)
+	}
+}
+
+func TestAdminBackup_InvalidParams(t *testing.T) {
+	handler, _ := adminTestServer(t)
+	for _, query := range []string{"?events=maybe", "?since=yesterday"} {
+		r := httptest.NewRequest(http.MethodGet, "/api/admin/backup"+query, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want 400", query, w.Code)
+		}
+	}
+
+	r := httptest.NewRequest(http.MethodPost, AdminRestorePath, bytes.NewBufferString(`{"format":"other"}`))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("restore of unknown format: status %d, want 400", w.Code)
+	}
+}
+
+func TestAdminRoutes_DisabledByDefault(t *testing.T) {
+	srv := testServer(t)
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/backup", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("status %d, want 501", w.Code)
+	}
+}
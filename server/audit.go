@@ -0,0 +1,314 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// auditSummaryBytes bounds how much of a request body is kept in memory
+// to list its fields. Larger bodies are summarized by content type.
+const auditSummaryBytes = 64 << 10
+
+// AuditMiddleware records every management request that could change
+// state in the audit log: requests other than GET, HEAD and OPTIONS that
+// AuthorizationMiddleware classifies as edit or admin. Runs, uploads and
+// webhook deliveries are not management requests. Refused requests are
+// recorded with their status, so the middleware belongs outside
+// AuthorizationMiddleware and inside authentication. Without an audit
+// store every request passes unrecorded.
+func (s *Server) AuditMiddleware(next http.Handler) http.Handler {
+	if s.audit == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		action, resource, ok := auditedRequest(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		statePath, beforeHash := s.auditResourceState(next, r)
+		body := &auditBody{ReadCloser: r.Body, hash: sha256.New()}
+		r.Body = body
+		rec := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		if statePath == "" {
+			// The request creates its resource: the response describes it.
+			rec.hash = sha256.New()
+		}
+		next.ServeHTTP(rec, r)
+		// Hash what the handler left unread, such as the body of a refused
+		// request, so the hash covers the body.
+		_, _ = io.CopyN(io.Discard, body, s.maxBody)
+
+		entry := AuditEntry{
+			At:           time.Now().UTC(),
+			Action:       action,
+			Method:       r.Method,
+			Path:         r.URL.Path,
+			Resource:     resource,
+			Status:       rec.status,
+			Summary:      body.summary(r.Header.Get("Content-Type")),
+			RequestBytes: body.n,
+			BeforeHash:   beforeHash,
+			RemoteAddr:   r.RemoteAddr,
+		}
+		if principal, ok := PrincipalFromContext(r.Context()); ok {
+			entry.Actor = principal.ID
+		}
+		if body.n > 0 {
+			entry.RequestHash = hashString(body.hash)
+		}
+		switch {
+		case statePath != "":
+			entry.AfterHash, _ = s.resourceStateHash(next, r, statePath)
+		case rec.hash != nil && rec.status >= 200 && rec.status < 300:
+			entry.AfterHash = hashString(rec.hash)
+			createdResource(&entry.Resource, rec.head.Bytes())
+		}
+
+		if _, err := s.audit.AppendAudit(r.Context(), entry); err != nil {
+			s.logger.Error("audit log append failed", "error", err, "method", r.Method, "path", r.URL.Path, "actor", entry.Actor)
+		}
+	})
+}
+
+// auditedRequest reports whether r is a management request the audit log
+// records, with its authorization action and resource.
+func auditedRequest(r *http.Request) (string, Resource, bool) {
+	if !strings.HasPrefix(r.URL.Path, "/api/") || IsWebhookRequest(r) {
+		return "", Resource{}, false
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return "", Resource{}, false
+	}
+	action, resource := requestAuthorization(r)
+	if action != ActionEdit && action != ActionAdmin {
+		return "", Resource{}, false
+	}
+	return action, resource, true
+}
+
+// auditResourceState finds the resource a request changes: the request
+// path, or the nearest parent path under /api/{kind}/{id}, that answers
+// GET. It returns that path and the hash of its state, or an empty path
+// when there is none yet, as for requests that create a resource.
+func (s *Server) auditResourceState(next http.Handler, r *http.Request) (string, string) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	// parts[0] is "api"; collections themselves are not resources.
+	for n := len(parts); n >= 3; n-- {
+		path := "/" + strings.Join(parts[:n], "/")
+		if h, ok := s.resourceStateHash(next, r, path); ok {
+			return path, h
+		}
+	}
+	return "", ""
+}
+
+// resourceStateHash hashes what GET path returns for the caller of r. It
+// reports false unless GET answers 200.
+func (s *Server) resourceStateHash(next http.Handler, r *http.Request, path string) (string, bool) {
+	get, err := http.NewRequestWithContext(r.Context(), http.MethodGet, path, nil)
+	if err != nil {
+		return "", false
+	}
+	get.Header = r.Header.Clone()
+	get.Header.Del("Content-Type")
+	get.RemoteAddr = r.RemoteAddr
+	rec := &auditResponseWriter{ResponseWriter: discardResponseWriter{header: http.Header{}}, status: http.StatusOK, hash: sha256.New()}
+	next.ServeHTTP(rec, get)
+	if rec.status != http.StatusOK {
+		return "", false
+	}
+	return hashString(rec.hash), true
+}
+
+// createdResource names the resource a create request made, from the
+// "id" or "name" of the JSON object it answered with, when the path did
+// not name it.
+func createdResource(resource *Resource, response []byte) {
+	if resource.ID != "" {
+		return
+	}
+	var created struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(response, &created) != nil {
+		return
+	}
+	resource.ID = created.ID
+	if resource.ID == "" {
+		resource.ID = created.Name
+	}
+	if resource.Kind == "workflows" {
+		resource.Workflow = resource.ID
+	}
+}
+
+// auditResponseWriter records a response's status and, when hash is set,
+// hashes its body and keeps its start.
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	hash        hash.Hash
+	head        bytes.Buffer
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(p []byte) (int, error) {
+	w.wroteHeader = true
+	if w.hash != nil {
+		w.hash.Write(p)
+		if room := auditSummaryBytes - w.head.Len(); room > 0 {
+			w.head.Write(p[:min(len(p), room)])
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (w *auditResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// discardResponseWriter is the target of the GET requests that capture
+// resource state.
+type discardResponseWriter struct {
+	header http.Header
+}
+
+func (w discardResponseWriter) Header() http.Header         { return w.header }
+func (w discardResponseWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w discardResponseWriter) WriteHeader(int)             {}
+
+// auditBody hashes a request body as the handler reads it and keeps its
+// start for the summary.
+type auditBody struct {
+	io.ReadCloser
+	hash hash.Hash
+	n    int64
+	head bytes.Buffer
+}
+
+func (b *auditBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		b.hash.Write(p[:n])
+		if room := auditSummaryBytes - b.head.Len(); room > 0 {
+			b.head.Write(p[:min(n, room)])
+		}
+		b.n += int64(n)
+	}
+	return n, err
+}
+
+// summary lists the top-level fields of a JSON object body. Other bodies,
+// and JSON bodies too large to keep, are described by their content type.
+func (b *auditBody) summary(contentType string) string {
+	if b.n == 0 {
+		return ""
+	}
+	if b.n <= auditSummaryBytes {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(b.head.Bytes(), &fields); err == nil && fields != nil {
+			names := make([]string, 0, len(fields))
+			for name := range fields {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return "fields: " + strings.Join(names, ", ")
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		mediaType = "unknown content type"
+	}
+	return fmt.Sprintf("%s, %d bytes", mediaType, b.n)
+}
+
+func hashString(h hash.Hash) string {
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// AuditPage is one page of the audit log. NextCursor is empty on the last
+// page.
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
+
+// handleListAudit lists audit entries newest first, filtered by actor,
+// resource kind, workflow, method and time range, one page at a time.
+func (s *Server) handleListAudit(w http.ResponseWriter, r *http.Request) {
+	if s.audit == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "audit log requires an audit store")
+		return
+	}
+	params := r.URL.Query()
+	q := AuditQuery{
+		Actor:    params.Get("actor"),
+		Kind:     params.Get("kind"),
+		Workflow: params.Get("workflow_id"),
+		Method:   strings.ToUpper(params.Get("method")),
+		Limit:    DefaultAuditLimit,
+	}
+	var err error
+	if q.Since, err = parseRunHistoryTime(params.Get("since")); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_TIME", err.Error())
+		return
+	}
+	if q.Until, err = parseRunHistoryTime(params.Get("until")); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_TIME", err.Error())
+		return
+	}
+	if raw := params.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > MaxAuditLimit {
+			writeError(w, http.StatusBadRequest, "INVALID_LIMIT", fmt.Sprintf("limit must be between 1 and %d", MaxAuditLimit))
+			return
+		}
+		q.Limit = n
+	}
+	if raw := params.Get("cursor"); raw != "" {
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, "INVALID_CURSOR", "cursor was not returned by this endpoint")
+			return
+		}
+		q.Before = id
+	}
+
+	entries, err := s.audit.ListAudit(r.Context(), q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	page := AuditPage{Entries: entries}
+	if page.Entries == nil {
+		page.Entries = []AuditEntry{}
+	}
+	if len(entries) == q.Limit {
+		page.NextCursor = strconv.FormatInt(entries[len(entries)-1].ID, 10)
+	}
+	writeJSON(w, http.StatusOK, page)
+}
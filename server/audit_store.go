@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+// Audit log page sizes.
+const (
+	DefaultAuditLimit = 100
+	MaxAuditLimit     = 1000
+)
+
+// AuditEntry records one management API request that could change state.
+type AuditEntry struct {
+	// ID orders entries; later entries have higher IDs.
+	ID int64     `json:"id"`
+	At time.Time `json:"at"`
+	// Actor is the principal that made the request, empty for anonymous
+	// callers.
+	Actor string `json:"actor,omitempty"`
+	// Action is edit or admin, as AuthorizationMiddleware classifies the
+	// request.
+	Action   string   `json:"action"`
+	Method   string   `json:"method"`
+	Path     string   `json:"path"`
+	Resource Resource `json:"resource"`
+	// Status is the response status. Requests that were refused are
+	// recorded too.
+	Status int `json:"status"`
+	// Summary names the top-level fields of a JSON object body, or the
+	// content type of other bodies. Body values are not kept.
+	Summary string `json:"summary,omitempty"`
+	// RequestHash is the SHA-256 of the request body, which is not kept.
+	RequestHash  string `json:"request_hash,omitempty"`
+	RequestBytes int64  `json:"request_bytes"`
+	// BeforeHash and AfterHash are the SHA-256 of what GET returned for
+	// the changed resource before and after the request. AfterHash is
+	// empty when the request deleted the resource. For a request that
+	// created it, BeforeHash is empty and AfterHash hashes the response.
+	BeforeHash string `json:"before_hash,omitempty"`
+	AfterHash  string `json:"after_hash,omitempty"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+}
+
+// AuditQuery filters the audit log. Zero fields match everything.
+type AuditQuery struct {
+	Actor string
+	// Kind is the resource kind, such as workflows or tools.
+	Kind     string
+	Workflow string
+	Method   string
+	// Since and Until bound At: at or after Since and before Until.
+	Since, Until time.Time
+	// Before is the ID of the last entry of the previous page.
+	Before int64
+	// Limit caps the number of entries returned (0 means no limit).
+	Limit int
+}
+
+// AuditStore keeps the audit log. It is append-only: entries cannot be
+// changed or deleted through it, and are kept when the resources they
+// concern are deleted.
+type AuditStore interface {
+	// AppendAudit stores entry and returns its ID.
+	AppendAudit(ctx context.Context, entry AuditEntry) (int64, error)
+	// ListAudit returns the entries matching q, newest first.
+	ListAudit(ctx context.Context, q AuditQuery) ([]AuditEntry, error)
+}
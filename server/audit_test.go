@@ -0,0 +1,139 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+func TestAuditMiddleware_RecordsManagementRequests(t *testing.T) {
+	rbac, err := NewRBACAuthorizer([]RoleBinding{
+		{Principal: "alice", Role: RoleEditor},
+		{Principal: "bob", Role: RoleRunner},
+	})
+	if err != nil {
+		t.Fatalf("NewRBACAuthorizer: %v", err)
+	}
+	store := newTestSQLiteStore(t)
+	handler := NewServer(ServerConfig{
+		Store:      store,
+		AuditStore: store,
+		Providers:  hydrate.ProviderMap{},
+		Bus:        bus.NewMemBus(bus.MemBusConfig{}),
+		Authorizer: rbac,
+	}).Handler()
+	as := func(principal, method, path string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		var data string
+		if body != nil {
+			raw, _ := json.Marshal(body)
+			data = string(raw)
+		}
+		r := httptest.NewRequest(method, path, strings.NewReader(data))
+		r = r.WithContext(WithPrincipal(r.Context(), Principal{ID: principal}))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := as("alice", http.MethodPost, "/api/workflows/graph", presetWorkflow()); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	updated := presetWorkflow()
+	updated["version"] = "1.1"
+	if w := as("alice", http.MethodPut, "/api/workflows/greeter", updated); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	// Runs are not management requests.
+	if w := as("bob", http.MethodPost, "/api/workflows/greeter/run", map[string]any{"input": map[string]any{"customer": "acme"}}); w.Code != http.StatusOK {
+		t.Fatalf("run: %d %s", w.Code, w.Body.String())
+	}
+	if w := as("bob", http.MethodDelete, "/api/workflows/greeter", nil); w.Code != http.StatusForbidden {
+		t.Fatalf("delete as runner: %d %s", w.Code, w.Body.String())
+	}
+	if w := as("alice", http.MethodDelete, "/api/workflows/greeter", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete: %d %s", w.Code, w.Body.String())
+	}
+
+	w := as("bob", http.MethodGet, "/api/audit?workflow_id=greeter", nil)
+	var page AuditPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("audit: %d %s", w.Code, w.Body.String())
+	}
+	if len(page.Entries) != 4 {
+		t.Fatalf("entries = %+v", page.Entries)
+	}
+	deleted, refused, update, create := page.Entries[0], page.Entries[1], page.Entries[2], page.Entries[3]
+
+	if create.Actor != "alice" || create.Method != http.MethodPost || create.Status != http.StatusCreated ||
+		create.Resource.ID != "greeter" || create.BeforeHash != "" || create.AfterHash == "" ||
+		!strings.HasPrefix(create.RequestHash, "sha256:") || create.Summary != "fields: entry, id, nodes, presets, version" {
+		t.Errorf("create entry = %+v", create)
+	}
+	if update.Action != ActionEdit || update.BeforeHash == "" || update.AfterHash == "" || update.BeforeHash == update.AfterHash {
+		t.Errorf("update entry = %+v", update)
+	}
+	if refused.Actor != "bob" || refused.Status != http.StatusForbidden || refused.BeforeHash != refused.AfterHash {
+		t.Errorf("refused entry = %+v", refused)
+	}
+	if deleted.Status != http.StatusNoContent || deleted.BeforeHash != update.AfterHash || deleted.AfterHash != "" {
+		t.Errorf("delete entry = %+v", deleted)
+	}
+	if create.ID >= update.ID || update.ID >= deleted.ID {
+		t.Errorf("entries are not newest first: %d %d %d", deleted.ID, update.ID, create.ID)
+	}
+
+	w = as("bob", http.MethodGet, "/api/audit?actor=alice&method=delete", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Entries) != 1 || page.Entries[0].ID != deleted.ID {
+		t.Fatalf("filtered = %s", w.Body.String())
+	}
+
+	w = as("bob", http.MethodGet, "/api/audit?limit=2", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Entries) != 2 || page.NextCursor == "" {
+		t.Fatalf("first page = %s", w.Body.String())
+	}
+	w = as("bob", http.MethodGet, "/api/audit?limit=2&cursor="+page.NextCursor, nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Entries) != 2 || page.Entries[1].ID != create.ID {
+		t.Fatalf("second page = %s", w.Body.String())
+	}
+
+	for _, path := range []string{"/api/audit?limit=0", "/api/audit?cursor=abc", "/api/audit?since=yesterday"} {
+		if w := as("bob", http.MethodGet, path, nil); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", path, w.Code)
+		}
+	}
+}
+
+func TestAuditedRequest(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         bool
+	}{
+		{http.MethodPut, "/api/workflows/greeter", true},
+		{http.MethodPut, "/api/workflows/webhooks", true},
+		{http.MethodDelete, "/api/workflows/webhooks", true},
+		{http.MethodPost, "/api/workflows/greeter/webhooks/incoming", false},
+		{http.MethodPost, "/api/workflows/greeter/run", false},
+		{http.MethodGet, "/api/workflows/greeter", false},
+	}
+	for _, tt := range tests {
+		if _, _, got := auditedRequest(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+			t.Errorf("%s %s audited = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHandleListAudit_WithoutStore(t *testing.T) {
+	w := doConditionRequest(t, testServer(t).Handler(), http.MethodGet, "/api/audit", nil)
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("status = %d, want 501", w.Code)
+	}
+}
@@ -0,0 +1,307 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Authorization actions, from least to most privileged.
+const (
+	// ActionView reads workflows, runs, stats and other state.
+	ActionView = "view"
+	// ActionRun starts runs and uploads their inputs.
+	ActionRun = "run"
+	// ActionEdit creates, changes and deletes workflows, schedules,
+	// conditions, datasets and tools, and instantiates templates.
+	ActionEdit = "edit"
+	// ActionAdmin covers the /api/admin routes and registering templates.
+	ActionAdmin = "admin"
+)
+
+// RBAC roles. Each role may do everything the roles before it may.
+const (
+	RoleViewer = "viewer"
+	RoleRunner = "runner"
+	RoleEditor = "editor"
+	RoleAdmin  = "admin"
+)
+
+// ErrForbidden is returned, possibly wrapped, by authorizers that deny a
+// request. Other errors mean the decision could not be made.
+var ErrForbidden = errors.New("forbidden")
+
+// Principal is the authenticated caller of a request. The zero Principal
+// is an anonymous caller.
+type Principal struct {
+	ID string `json:"id"`
+}
+
+// Resource is what a request acts on.
+type Resource struct {
+	// Kind is the API collection, such as workflows, runs, schedules,
+	// tools or admin.
+	Kind string `json:"kind"`
+	// ID is the addressed item, empty for collection routes.
+	ID string `json:"id,omitempty"`
+	// Workflow is the workflow the request concerns, when the path names
+	// one.
+	Workflow string `json:"workflow,omitempty"`
+	// Workspace comes from the caller-supplied X-Workspace-ID header. Only
+	// kinds partitioned by workspace, such as uploads, are confined to it;
+	// see WorkspaceScoped.
+	Workspace string `json:"workspace"`
+}
+
+// WorkspaceScoped reports whether resources of kind are partitioned by
+// workspace. For other kinds the workspace is only what the caller claims,
+// so it cannot limit what a request reaches.
+func WorkspaceScoped(kind string) bool {
+	return kind == "uploads"
+}
+
+// Authorizer decides whether a principal may perform an action on a
+// resource. It returns nil to allow the request and an error wrapping
+// ErrForbidden to deny it.
+type Authorizer interface {
+	Authorize(ctx context.Context, principal Principal, action string, resource Resource) error
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the caller of a request.
+// Authentication middleware sets it before AuthorizationMiddleware runs.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the caller set by WithPrincipal.
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// AuthorizationMiddleware asks the configured Authorizer about every API
+// request. Denied requests get 403; requests the authorizer cannot decide
+// get 503. Health checks, CORS preflights, webhook deliveries and tool
+// callbacks, which authenticate themselves, are not checked. Without an Authorizer every
+// request passes.
+func (s *Server) AuthorizationMiddleware(next http.Handler) http.Handler {
+	if s.authorizer == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || IsWebhookRequest(r) || IsToolCallbackRequest(r) || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		principal, _ := PrincipalFromContext(r.Context())
+		action, resource := requestAuthorization(r)
+		if err := s.authorizer.Authorize(r.Context(), principal, action, resource); err != nil {
+			if errors.Is(err, ErrForbidden) {
+				writeError(w, http.StatusForbidden, "FORBIDDEN", err.Error())
+				return
+			}
+			s.logger.Error("authorization failed", "error", err, "principal", principal.ID, "action", action, "path", r.URL.Path)
+			writeError(w, http.StatusServiceUnavailable, "AUTHORIZATION_UNAVAILABLE", "authorization decision unavailable")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requestAuthorization maps an API request to its action and resource.
+func requestAuthorization(r *http.Request) (string, Resource) {
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/"), "/"), "/")
+	resource := Resource{Kind: parts[0], Workspace: requestWorkspace(r)}
+	if len(parts) > 1 {
+		resource.ID = parts[1]
+	}
+	if resource.Kind == "workflows" {
+		switch resource.ID {
+		case "agent", "graph":
+			// Create routes: the workflow ID is in the body.
+			resource.ID = ""
+		default:
+			resource.Workflow = resource.ID
+		}
+	}
+
+	switch {
+	case resource.Kind == "admin":
+		return ActionAdmin, resource
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		return ActionView, resource
+	case resource.Kind == "templates":
+		if len(parts) == 3 && parts[2] == "instantiate" {
+			return ActionEdit, resource
+		}
+		return ActionAdmin, resource
+	case resource.Kind == "workflows" && len(parts) == 3 && (parts[2] == "run" || parts[2] == "reruns"),
+		resource.Kind == "uploads",
+		resource.Kind == "model-selections":
+		return ActionRun, resource
+	default:
+		return ActionEdit, resource
+	}
+}
+
+var roleRanks = map[string]int{RoleViewer: 1, RoleRunner: 2, RoleEditor: 3, RoleAdmin: 4}
+
+var actionRanks = map[string]int{ActionView: 1, ActionRun: 2, ActionEdit: 3, ActionAdmin: 4}
+
+// RoleBinding grants a role to a principal, optionally scoped to a
+// workspace or a single workflow.
+type RoleBinding struct {
+	// Principal is a principal ID, or "*" for every caller including
+	// anonymous ones.
+	Principal string `json:"principal"`
+	Role      string `json:"role"`
+	// Workspace limits the binding to requests for one workspace, on
+	// resource kinds that are partitioned by workspace.
+	Workspace string `json:"workspace,omitempty"`
+	// Workflow limits the binding to routes under /api/workflows/{id}.
+	Workflow string `json:"workflow,omitempty"`
+}
+
+// RBACAuthorizer is the built-in role-based Authorizer. A request is
+// allowed when one of the caller's bindings in scope has a role at least
+// as privileged as the action: viewer views, runner also runs, editor also
+// edits and admin may do everything. Admin actions are daemon-wide and
+// need an unscoped admin binding.
+type RBACAuthorizer struct {
+	bindings []RoleBinding
+}
+
+// NewRBACAuthorizer returns an RBACAuthorizer for bindings.
+func NewRBACAuthorizer(bindings []RoleBinding) (*RBACAuthorizer, error) {
+	for i, b := range bindings {
+		if strings.TrimSpace(b.Principal) == "" {
+			return nil, fmt.Errorf("binding %d: principal is required", i)
+		}
+		if _, ok := roleRanks[b.Role]; !ok {
+			return nil, fmt.Errorf("binding %d: unknown role %q (use viewer, runner, editor or admin)", i, b.Role)
+		}
+	}
+	return &RBACAuthorizer{bindings: append([]RoleBinding(nil), bindings...)}, nil
+}
+
+// Authorize implements Authorizer.
+func (a *RBACAuthorizer) Authorize(_ context.Context, principal Principal, action string, resource Resource) error {
+	need, ok := actionRanks[action]
+	if !ok {
+		return fmt.Errorf("%w: unknown action %q", ErrForbidden, action)
+	}
+	for _, b := range a.bindings {
+		if b.Principal != "*" && b.Principal != principal.ID {
+			continue
+		}
+		scoped := b.Workspace != "" || b.Workflow != ""
+		if action == ActionAdmin && scoped {
+			continue
+		}
+		if b.Workspace != "" && (!WorkspaceScoped(resource.Kind) || b.Workspace != resource.Workspace) {
+			continue
+		}
+		if b.Workflow != "" && b.Workflow != resource.Workflow {
+			continue
+		}
+		if roleRanks[b.Role] >= need {
+			return nil
+		}
+	}
+	who := principal.ID
+	if who == "" {
+		who = "anonymous"
+	}
+	return fmt.Errorf("%w: %s may not %s %s", ErrForbidden, who, action, describeResource(resource))
+}
+
+func describeResource(r Resource) string {
+	if r.ID == "" {
+		return r.Kind
+	}
+	return r.Kind + "/" + r.ID
+}
+
+// AuthorizationRequest is the body a WebhookAuthorizer posts to the
+// policy service.
+type AuthorizationRequest struct {
+	Principal Principal `json:"principal"`
+	Action    string    `json:"action"`
+	Resource  Resource  `json:"resource"`
+}
+
+// AuthorizationDecision is the policy service's answer.
+type AuthorizationDecision struct {
+	Allow bool `json:"allow"`
+	// Reason is returned to denied callers.
+	Reason string `json:"reason,omitempty"`
+}
+
+// WebhookAuthorizer delegates decisions to an external policy service. It
+// POSTs an AuthorizationRequest to URL and expects a 200 with an
+// AuthorizationDecision. Any other answer fails the request closed.
+type WebhookAuthorizer struct {
+	URL string
+	// Token, when set, is sent as a bearer token.
+	Token  string
+	Client *http.Client
+}
+
+// DefaultAuthorizationTimeout bounds a policy service call.
+const DefaultAuthorizationTimeout = 5 * time.Second
+
+// NewWebhookAuthorizer returns a WebhookAuthorizer for url. A timeout of
+// 0 uses DefaultAuthorizationTimeout.
+func NewWebhookAuthorizer(url, token string, timeout time.Duration) *WebhookAuthorizer {
+	if timeout <= 0 {
+		timeout = DefaultAuthorizationTimeout
+	}
+	return &WebhookAuthorizer{URL: url, Token: token, Client: &http.Client{Timeout: timeout}}
+}
+
+// Authorize implements Authorizer.
+func (a *WebhookAuthorizer) Authorize(ctx context.Context, principal Principal, action string, resource Resource) error {
+	body, err := json.Marshal(AuthorizationRequest{Principal: principal, Action: action, Resource: resource})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.Token)
+	}
+	client := a.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("calling policy service: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("policy service answered %s", resp.Status)
+	}
+	var decision AuthorizationDecision
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&decision); err != nil {
+		return fmt.Errorf("decoding policy decision: %w", err)
+	}
+	if !decision.Allow {
+		if decision.Reason != "" {
+			return fmt.Errorf("%w: %s", ErrForbidden, decision.Reason)
+		}
+		return fmt.Errorf("%w: denied by policy", ErrForbidden)
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+func TestAuthorizationMiddleware(t *testing.T) {
+	rbac, err := NewRBACAuthorizer([]RoleBinding{
+		{Principal: "alice", Role: RoleViewer},
+		{Principal: "alice", Role: RoleRunner, Workflow: "nightly"},
+		{Principal: "bob", Role: RoleEditor, Workspace: "team-a"},
+		{Principal: "root", Role: RoleAdmin},
+	})
+	if err != nil {
+		t.Fatalf("NewRBACAuthorizer: %v", err)
+	}
+	s := NewServer(ServerConfig{
+		Store:      newTestSQLiteStore(t),
+		Providers:  hydrate.ProviderMap{},
+		Bus:        bus.NewMemBus(bus.MemBusConfig{}),
+		Authorizer: rbac,
+	})
+	handler := s.AuthorizationMiddleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		principal, method, path, workspace string
+		want                               int
+	}{
+		{"alice", http.MethodGet, "/api/workflows", "", http.StatusOK},
+		{"alice", http.MethodPost, "/api/workflows/nightly/run", "", http.StatusOK},
+		{"alice", http.MethodPost, "/api/workflows/other/run", "", http.StatusForbidden},
+		{"alice", http.MethodPut, "/api/workflows/nightly", "", http.StatusForbidden},
+		{"bob", http.MethodPost, UploadsPath, "team-a", http.StatusOK},
+		{"bob", http.MethodPost, UploadsPath, "team-b", http.StatusForbidden},
+		{"bob", http.MethodPut, "/api/workflows/nightly", "team-a", http.StatusForbidden},
+		{"bob", http.MethodPut, "/api/workflows/nightly", "", http.StatusForbidden},
+		{"bob", http.MethodPut, AdminMaintenancePath, "team-a", http.StatusForbidden},
+		{"root", http.MethodPut, AdminMaintenancePath, "", http.StatusOK},
+		{"", http.MethodGet, "/api/workflows", "", http.StatusForbidden},
+		{"", http.MethodGet, "/health", "", http.StatusOK},
+		{"", http.MethodPost, "/api/workflows/nightly/webhooks/incoming", "", http.StatusOK},
+		{"", http.MethodPost, "/api/tool-callbacks/3f2a9c0d8e7b6a5f4e3d2c1b0a998877", "", http.StatusOK},
+		{"", http.MethodPost, "/api/tool-callbacks/job-8841", "", http.StatusForbidden},
+		{"", http.MethodPut, "/api/workflows/webhooks", "", http.StatusForbidden},
+		{"", http.MethodPost, "/api/workflows/webhooks/run", "", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.principal != "" {
+			r = r.WithContext(WithPrincipal(r.Context(), Principal{ID: tt.principal}))
+		}
+		if tt.workspace != "" {
+			r.Header.Set(WorkspaceHeader, tt.workspace)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tt.want {
+			t.Errorf("%s %s %s: got %d, want %d (%s)", tt.principal, tt.method, tt.path, w.Code, tt.want, w.Body.String())
+		}
+	}
+}
+
+func TestRequestAuthorization(t *testing.T) {
+	tests := []struct {
+		method, path string
+		action       string
+		resource     Resource
+	}{
+		{http.MethodGet, "/api/runs/r1/events", ActionView, Resource{Kind: "runs", ID: "r1"}},
+		{http.MethodPost, "/api/workflows/graph", ActionEdit, Resource{Kind: "workflows"}},
+		{http.MethodPost, "/api/workflows/wf/run", ActionRun, Resource{Kind: "workflows", ID: "wf", Workflow: "wf"}},
+		{http.MethodPost, "/api/workflows/wf/schedules", ActionEdit, Resource{Kind: "workflows", ID: "wf", Workflow: "wf"}},
+		{http.MethodPost, UploadsPath, ActionRun, Resource{Kind: "uploads"}},
+		{http.MethodGet, "/api/admin/backup", ActionAdmin, Resource{Kind: "admin", ID: "backup"}},
+		{http.MethodDelete, "/api/tools/crm", ActionEdit, Resource{Kind: "tools", ID: "crm"}},
+		{http.MethodPost, "/api/templates/rag-qa/instantiate", ActionEdit, Resource{Kind: "templates", ID: "rag-qa"}},
+		{http.MethodPost, "/api/templates", ActionAdmin, Resource{Kind: "templates"}},
+	}
+	for _, tt := range tests {
+		action, resource := requestAuthorization(httptest.NewRequest(tt.method, tt.path, nil))
+		tt.resource.Workspace = DefaultWorkspace
+		if action != tt.action || resource != tt.resource {
+			t.Errorf("%s %s = %s %+v, want %s %+v", tt.method, tt.path, action, resource, tt.action, tt.resource)
+		}
+	}
+}
+
+func TestNewRBACAuthorizer_RejectsUnknownRoles(t *testing.T) {
+	if _, err := NewRBACAuthorizer([]RoleBinding{{Principal: "alice", Role: "owner"}}); err == nil {
+		t.Error("expected an error for an unknown role")
+	}
+	if _, err := NewRBACAuthorizer([]RoleBinding{{Role: RoleViewer}}); err == nil {
+		t.Error("expected an error for a missing principal")
+	}
+}
+
+func TestWebhookAuthorizer(t *testing.T) {
+	var got AuthorizationRequest
+	var gotToken string
+	policy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotToken = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		switch got.Principal.ID {
+		case "alice":
+			writeJSON(w, http.StatusOK, AuthorizationDecision{Allow: true})
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			writeJSON(w, http.StatusOK, AuthorizationDecision{Reason: "not on the rota"})
+		}
+	}))
+	defer policy.Close()
+
+	authz := NewWebhookAuthorizer(policy.URL, "policy-token", 0)
+	resource := Resource{Kind: "workflows", ID: "wf", Workflow: "wf", Workspace: DefaultWorkspace}
+	if err := authz.Authorize(context.Background(), Principal{ID: "alice"}, ActionRun, resource); err != nil {
+		t.Fatalf("alice: %v", err)
+	}
+	if got.Action != ActionRun || got.Resource != resource || gotToken != "Bearer policy-token" {
+		t.Errorf("request = %+v, token %q", got, gotToken)
+	}
+
+	err := authz.Authorize(context.Background(), Principal{ID: "mallory"}, ActionRun, resource)
+	if !errors.Is(err, ErrForbidden) || err.Error() != "forbidden: not on the rota" {
+		t.Errorf("mallory: got %v, want forbidden with the policy's reason", err)
+	}
+	err = authz.Authorize(context.Background(), Principal{ID: "broken"}, ActionRun, resource)
+	if err == nil || errors.Is(err, ErrForbidden) {
+		t.Errorf("broken policy service: got %v, want an undecided error", err)
+	}
+
+	// Undecided requests fail closed with 503.
+	s := NewServer(ServerConfig{Store: newTestSQLiteStore(t), Authorizer: authz})
+	handler := s.AuthorizationMiddleware(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/api/workflows", nil)
+	r = r.WithContext(WithPrincipal(r.Context(), Principal{ID: "broken"}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want 503", w.Code)
+	}
+}
@@ -0,0 +1,172 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/petal-labs/petalflow/nodes/conditional"
+)
+
+type conditionRequest struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Expression  string              `json:"expression"`
+	Params      []conditional.Param `json:"params,omitempty"`
+}
+
+func (s *Server) handleListConditions(w http.ResponseWriter, r *http.Request) {
+	if s.conditions == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "condition library is not configured")
+		return
+	}
+	defs, err := s.conditions.ListConditions(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if defs == nil {
+		defs = []conditional.Definition{}
+	}
+	writeJSON(w, http.StatusOK, defs)
+}
+
+func (s *Server) handleCreateCondition(w http.ResponseWriter, r *http.Request) {
+	if s.conditions == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "condition library is not configured")
+		return
+	}
+	var req conditionRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
+		return
+	}
+
+	now := time.Now().UTC()
+	def := conditional.Definition{
+		Name:        req.Name,
+		Description: req.Description,
+		Expression:  req.Expression,
+		Params:      req.Params,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := def.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_CONDITION", err.Error())
+		return
+	}
+	if err := s.conditions.CreateCondition(r.Context(), def); err != nil {
+		if errors.Is(err, ErrConditionExists) {
+			writeError(w, http.StatusConflict, "CONFLICT", fmt.Sprintf("condition %q already exists", def.Name))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, def)
+}
+
+func (s *Server) handleGetCondition(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if s.conditions == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "condition library is not configured")
+		return
+	}
+	def, found, err := s.conditions.GetCondition(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("condition %q not found", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, def)
+}
+
+// handleUpdateCondition replaces a condition's expression, description and
+// parameters. Workflows pick up the change on their next run.
+func (s *Server) handleUpdateCondition(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if s.conditions == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "condition library is not configured")
+		return
+	}
+	existing, found, err := s.conditions.GetCondition(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("condition %q not found", name))
+		return
+	}
+
+	var req conditionRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
+		return
+	}
+	if req.Name != "" && req.Name != name {
+		writeError(w, http.StatusBadRequest, "INVALID_CONDITION", fmt.Sprintf("body name %q does not match %q", req.Name, name))
+		return
+	}
+
+	def := conditional.Definition{
+		Name:        name,
+		Description: req.Description,
+		Expression:  req.Expression,
+		Params:      req.Params,
+		CreatedAt:   existing.CreatedAt,
+		UpdatedAt:   time.Now().UTC(),
+	}
+	if err := def.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_CONDITION", err.Error())
+		return
+	}
+	if err := s.conditions.UpdateCondition(r.Context(), def); err != nil {
+		if errors.Is(err, ErrConditionNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("condition %q not found", name))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, def)
+}
+
+func (s *Server) handleDeleteCondition(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if s.conditions == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "condition library is not configured")
+		return
+	}
+	if err := s.conditions.DeleteCondition(r.Context(), name); err != nil {
+		if errors.Is(err, ErrConditionNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("condition %q not found", name))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// conditionLibrary loads the condition library a run hydrates against, or
+// nil when no condition store is configured.
+func (s *Server) conditionLibrary(ctx context.Context) (*conditional.Library, error) {
+	if s.conditions == nil {
+		return nil, nil
+	}
+	defs, err := s.conditions.ListConditions(ctx)
+	if err != nil {
+		return nil, &runAPIError{Status: http.StatusInternalServerError, Code: "STORE_ERROR", Message: err.Error()}
+	}
+	lib, err := conditional.NewLibrary(defs)
+	if err != nil {
+		return nil, &runAPIError{Status: http.StatusInternalServerError, Code: "CONDITION_LIBRARY_ERROR", Message: err.Error()}
+	}
+	return lib, nil
+}
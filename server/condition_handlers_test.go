@@ -0,0 +1,146 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/nodes/conditional"
+)
+
+func conditionTestServer(t *testing.T) (http.Handler, *SQLiteStore) {
+	t.Helper()
+	store := newTestSQLiteStore(t)
+	return NewServer(ServerConfig{
+		Store:          store,
+		ConditionStore: store,
+		Providers:      hydrate.ProviderMap{},
+		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+			return nil, nil
+		},
+	}).Handler(), store
+}
+
+func doConditionRequest(t *testing.T, handler http.Handler, method, path string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			t.Fatalf("marshal: %v", err)
+		}
+	}
+	r := httptest.NewRequest(method, path, bytes.NewReader(data))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestConditionCRUD(t *testing.T) {
+	handler, _ := conditionTestServer(t)
+
+	def := map[string]any{
+		"name":       "over_limit",
+		"expression": "input.amount > limit",
+		"params":     []any{map[string]any{"name": "limit", "type": "number", "default": 100}},
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/conditions", def); w.Code != http.StatusCreated {
+		t.Fatalf("create status %d: %s", w.Code, w.Body.String())
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/conditions", def); w.Code != http.StatusConflict {
+		t.Fatalf("duplicate create status %d, want 409", w.Code)
+	}
+
+	invalid := map[string]any{"name": "broken", "expression": "input.amount >"}
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/conditions", invalid)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_CONDITION") {
+		t.Fatalf("invalid create status %d: %s", w.Code, w.Body.String())
+	}
+
+	def["expression"] = "input.amount >= limit"
+	w = doConditionRequest(t, handler, http.MethodPut, "/api/conditions/over_limit", def)
+	if w.Code != http.StatusOK {
+		t.Fatalf("update status %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/conditions", nil)
+	var defs []conditional.Definition
+	if err := json.Unmarshal(w.Body.Bytes(), &defs); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	if len(defs) != 1 || defs[0].Expression != "input.amount >= limit" || defs[0].CreatedAt.IsZero() {
+		t.Fatalf("list = %+v", defs)
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodDelete, "/api/conditions/over_limit", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete status %d", w.Code)
+	}
+	if w := doConditionRequest(t, handler, http.MethodGet, "/api/conditions/over_limit", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("get after delete status %d, want 404", w.Code)
+	}
+}
+
+func TestConditionRef_ResolvedAtRun(t *testing.T) {
+	handler, store := conditionTestServer(t)
+	ctx := context.Background()
+	if err := store.CreateCondition(ctx, conditional.Definition{
+		Name:       "over_limit",
+		Expression: "input.amount > limit",
+		Params:     []conditional.Param{{Name: "limit", Type: conditional.ParamTypeNumber, Required: true}},
+	}); err != nil {
+		t.Fatalf("CreateCondition: %v", err)
+	}
+
+	source, _ := json.Marshal(map[string]any{
+		"id":      "wf-ref",
+		"version": "1.0",
+		"nodes": []map[string]any{
+			{"id": "route", "type": "conditional", "config": map[string]any{
+				"default":      "small",
+				"pass_through": false,
+				"output_key":   "route_result",
+				"conditions":   []any{map[string]any{"name": "large", "ref": "over_limit(limit: 100)"}},
+			}},
+			{"id": "large", "type": "noop"},
+			{"id": "small", "type": "noop"},
+		},
+		"edges": []map[string]any{
+			{"source": "route", "sourceHandle": "large", "target": "large"},
+			{"source": "route", "sourceHandle": "small", "target": "small"},
+		},
+		"entry": "route",
+	})
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(source))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create workflow status %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doConditionRequest(t, handler, http.MethodPost, "/api/workflows/wf-ref/run", map[string]any{"input": map[string]any{"amount": 250}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("run status %d: %s", w.Code, w.Body.String())
+	}
+	var resp RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode run: %v", err)
+	}
+	result, _ := resp.Output.Vars["route_result"].(map[string]any)
+	if result["condition"] != "large" {
+		t.Fatalf("route_result = %v, want condition large", resp.Output.Vars["route_result"])
+	}
+
+	if err := store.DeleteCondition(ctx, "over_limit"); err != nil {
+		t.Fatalf("DeleteCondition: %v", err)
+	}
+	w = doConditionRequest(t, handler, http.MethodPost, "/api/workflows/wf-ref/run", map[string]any{"input": map[string]any{"amount": 250}})
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), `unknown condition \"over_limit\"`) {
+		t.Fatalf("run with missing condition status %d: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/petal-labs/petalflow/nodes/conditional"
+)
+
+var (
+	ErrConditionExists   = errors.New("condition already exists")
+	ErrConditionNotFound = errors.New("condition not found")
+)
+
+// ConditionStore persists the named condition library shared by workflows.
+type ConditionStore interface {
+	ListConditions(ctx context.Context) ([]conditional.Definition, error)
+	GetCondition(ctx context.Context, name string) (conditional.Definition, bool, error)
+	CreateCondition(ctx context.Context, def conditional.Definition) error
+	UpdateCondition(ctx context.Context, def conditional.Definition) error
+	DeleteCondition(ctx context.Context, name string) error
+}
@@ -0,0 +1,177 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig controls cross-origin access to the API.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to call the API. "*" allows
+	// any origin and an entry such as "https://*.example.com" allows its
+	// subdomains. Defaults to ["*"].
+	AllowedOrigins []string
+	// AllowedMethods defaults to GET, POST, PUT, DELETE and OPTIONS.
+	AllowedMethods []string
+	// AllowedHeaders defaults to Content-Type and Authorization.
+	AllowedHeaders []string
+	// ExposedHeaders lists response headers readable by browser clients.
+	ExposedHeaders []string
+	// RouteMethods narrows AllowedMethods for paths under a prefix, for
+	// example {"/api/runs": {"GET"}}. The longest matching prefix wins.
+	RouteMethods map[string][]string
+	// AllowCredentials lets browsers send cookies and auth headers. It is
+	// never advertised for wildcard origins.
+	AllowCredentials bool
+	// MaxAge lets browsers cache preflight responses. Zero omits the header.
+	MaxAge time.Duration
+}
+
+var (
+	defaultCORSMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions}
+	defaultCORSHeaders = []string{"Content-Type", "Authorization"}
+)
+
+// CORSMiddleware applies cfg to every response and answers preflight
+// requests. Requests from origins that are not allowed get no CORS headers,
+// which makes browsers reject them.
+func CORSMiddleware(cfg CORSConfig) func(http.Handler) http.Handler {
+	if len(cfg.AllowedOrigins) == 0 {
+		cfg.AllowedOrigins = []string{"*"}
+	}
+	if len(cfg.AllowedMethods) == 0 {
+		cfg.AllowedMethods = defaultCORSMethods
+	}
+	if len(cfg.AllowedHeaders) == 0 {
+		cfg.AllowedHeaders = defaultCORSHeaders
+	}
+	wildcard := slices.Contains(cfg.AllowedOrigins, "*")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			allowOrigin := ""
+			switch {
+			case wildcard:
+				allowOrigin = "*"
+			case origin != "" && originAllowed(cfg.AllowedOrigins, origin):
+				allowOrigin = origin
+			}
+			if !wildcard {
+				w.Header().Add("Vary", "Origin")
+			}
+
+			if allowOrigin != "" {
+				h := w.Header()
+				h.Set("Access-Control-Allow-Origin", allowOrigin)
+				h.Set("Access-Control-Allow-Methods", strings.Join(cfg.routeMethods(r.URL.Path), ", "))
+				h.Set("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+				if len(cfg.ExposedHeaders) > 0 {
+					h.Set("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
+				}
+				if cfg.AllowCredentials && !wildcard {
+					h.Set("Access-Control-Allow-Credentials", "true")
+				}
+				if cfg.MaxAge > 0 && r.Method == http.MethodOptions {
+					h.Set("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+				}
+			}
+
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// routeMethods returns the methods allowed for path.
+func (c CORSConfig) routeMethods(path string) []string {
+	best := ""
+	for prefix := range c.RouteMethods {
+		if strings.HasPrefix(path, prefix) && len(prefix) > len(best) {
+			best = prefix
+		}
+	}
+	if best == "" {
+		return c.AllowedMethods
+	}
+	return c.RouteMethods[best]
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	for _, pattern := range allowed {
+		if strings.EqualFold(pattern, origin) {
+			return true
+		}
+		// "https://*.example.com" matches any subdomain of example.com.
+		if scheme, host, ok := strings.Cut(pattern, "://*."); ok {
+			rest, found := strings.CutPrefix(strings.ToLower(origin), strings.ToLower(scheme)+"://")
+			if found && strings.HasSuffix(rest, "."+strings.ToLower(host)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SecurityHeadersConfig sets standard hardening headers on every response.
+type SecurityHeadersConfig struct {
+	// HSTSMaxAge enables Strict-Transport-Security on TLS requests.
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	// ContentTypeNosniff sends X-Content-Type-Options: nosniff.
+	ContentTypeNosniff bool
+	// FrameOptions is the X-Frame-Options value ("DENY" or "SAMEORIGIN").
+	FrameOptions          string
+	ReferrerPolicy        string
+	ContentSecurityPolicy string
+}
+
+// DefaultSecurityHeaders is used when ServerConfig.SecurityHeaders is nil.
+// HSTS stays off because the daemon often runs behind a TLS-terminating
+// proxy that owns that decision.
+func DefaultSecurityHeaders() SecurityHeadersConfig {
+	return SecurityHeadersConfig{
+		ContentTypeNosniff: true,
+		FrameOptions:       "DENY",
+		ReferrerPolicy:     "no-referrer",
+	}
+}
+
+// SecurityHeadersMiddleware sets the headers cfg enables.
+func SecurityHeadersMiddleware(cfg SecurityHeadersConfig) func(http.Handler) http.Handler {
+	hsts := ""
+	if cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			if hsts != "" && (r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")) {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+			if cfg.ContentTypeNosniff {
+				h.Set("X-Content-Type-Options", "nosniff")
+			}
+			if cfg.FrameOptions != "" {
+				h.Set("X-Frame-Options", cfg.FrameOptions)
+			}
+			if cfg.ReferrerPolicy != "" {
+				h.Set("Referrer-Policy", cfg.ReferrerPolicy)
+			}
+			if cfg.ContentSecurityPolicy != "" {
+				h.Set("Content-Security-Policy", cfg.ContentSecurityPolicy)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serveCORS(t *testing.T, cfg CORSConfig, method, path, origin string) *httptest.ResponseRecorder {
+	t.Helper()
+	handler := CORSMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	r := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		r.Header.Set("Origin", origin)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestCORSMiddleware_MultipleOrigins(t *testing.T) {
+	cfg := CORSConfig{
+		AllowedOrigins:   []string{"https://ui.example.com", "https://*.internal.example"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+
+	for _, origin := range []string{"https://ui.example.com", "https://dash.internal.example"} {
+		w := serveCORS(t, cfg, http.MethodOptions, "/api/workflows", origin)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("%s: preflight status = %d", origin, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != origin {
+			t.Errorf("%s: allow origin = %q", origin, got)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+			t.Errorf("%s: allow credentials = %q", origin, got)
+		}
+		if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+			t.Errorf("%s: max age = %q", origin, got)
+		}
+		if got := w.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("%s: vary = %q", origin, got)
+		}
+	}
+
+	w := serveCORS(t, cfg, http.MethodGet, "/api/workflows", "https://evil.example.com")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("disallowed origin got allow origin %q", got)
+	}
+}
+
+func TestCORSMiddleware_WildcardNeverAllowsCredentials(t *testing.T) {
+	w := serveCORS(t, CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, http.MethodGet, "/health", "https://a.example.com")
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+		t.Errorf("allow origin = %q", got)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Errorf("allow credentials = %q, want none", got)
+	}
+}
+
+func TestCORSMiddleware_RouteMethods(t *testing.T) {
+	cfg := CORSConfig{RouteMethods: map[string][]string{
+		"/api/runs":            {"GET"},
+		"/api/runs/export/now": {"GET", "POST"},
+	}}
+
+	cases := map[string]string{
+		"/api/runs/abc/events": "GET",
+		"/api/runs/export/now": "GET, POST",
+		"/api/workflows":       "GET, POST, PUT, DELETE, OPTIONS",
+	}
+	for path, want := range cases {
+		w := serveCORS(t, cfg, http.MethodOptions, path, "https://ui.example.com")
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != want {
+			t.Errorf("%s: allow methods = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	handler := SecurityHeadersMiddleware(SecurityHeadersConfig{
+		HSTSMaxAge:            24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		ContentTypeNosniff:    true,
+		FrameOptions:          "SAMEORIGIN",
+		ContentSecurityPolicy: "default-src 'self'",
+	})(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+
+	r := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if got := w.Header().Get("Strict-Transport-Security"); got != "" {
+		t.Errorf("HSTS sent over plain HTTP: %q", got)
+	}
+	if got := w.Header().Get("X-Frame-Options"); got != "SAMEORIGIN" {
+		t.Errorf("frame options = %q", got)
+	}
+	if got := w.Header().Get("Content-Security-Policy"); got != "default-src 'self'" {
+		t.Errorf("csp = %q", got)
+	}
+
+	r.Header.Set("X-Forwarded-Proto", "https")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if got := w.Header().Get("Strict-Transport-Security"); got != "max-age=86400; includeSubDomains" {
+		t.Errorf("HSTS = %q", got)
+	}
+}
+
+func TestServer_DefaultSecurityHeaders(t *testing.T) {
+	srv := testServer(t)
+	r := httptest.NewRequest(http.MethodGet, "/health", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+
+	if got := w.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("nosniff = %q", got)
+	}
+	if got := w.Header().Get("X-Frame-Options"); got != "DENY" {
+		t.Errorf("frame options = %q", got)
+	}
+}
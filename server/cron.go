@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"hash/fnv"
 	"strings"
 	"time"
 
@@ -16,12 +17,140 @@ var standardCronParser = cron.NewParser(
 		cron.Dow,
 )
 
-func nextCronRunUTC(expr string, now time.Time) (time.Time, error) {
+// cronStarBit marks a field written as "*" in a parsed cron.SpecSchedule.
+const cronStarBit = 1 << 63
+
+// maxMissedFireScan bounds how many missed fire times are enumerated after
+// a long outage of a frequent schedule.
+const maxMissedFireScan = 100000
+
+// cronSchedule is a cron expression read in a timezone.
+type cronSchedule struct {
+	spec *cron.SpecSchedule
+	loc  *time.Location
+}
+
+// parseCronSchedule parses a 5-field cron expression read in timezone, an
+// IANA name. An empty timezone means UTC.
+func parseCronSchedule(expr, timezone string) (cronSchedule, error) {
+	loc, err := loadScheduleLocation(timezone)
+	if err != nil {
+		return cronSchedule{}, err
+	}
 	schedule, err := parseCronExpressionUTC(expr)
 	if err != nil {
-		return time.Time{}, err
+		return cronSchedule{}, err
+	}
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok {
+		return cronSchedule{}, fmt.Errorf("invalid cron expression: unsupported schedule")
+	}
+	return cronSchedule{spec: spec, loc: loc}, nil
+}
+
+func loadScheduleLocation(timezone string) (*time.Location, error) {
+	if strings.TrimSpace(timezone) == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", timezone, err)
+	}
+	return loc, nil
+}
+
+// Next returns the first fire time after t, in UTC.
+//
+// Expressions with a wildcard hour follow elapsed time: across a daylight
+// saving change they fire every real minute or hour, so a repeated hour
+// runs twice and a skipped hour not at all. Expressions naming hours
+// follow the wall clock instead: a time in the hour skipped when clocks go
+// forward fires once, shifted forward by the change, and a time in the
+// hour repeated when clocks go back fires once, at its first occurrence.
+func (c cronSchedule) Next(t time.Time) time.Time {
+	if c.spec.Hour&cronStarBit != 0 {
+		next := c.spec.Next(t.In(c.loc))
+		if next.IsZero() {
+			return next
+		}
+		return next.UTC()
+	}
+
+	// Walk the wall clock with the expression read in UTC, and map each
+	// wall time back to an instant in the schedule's timezone.
+	local := t.In(c.loc)
+	wall := time.Date(local.Year(), local.Month(), local.Day(), local.Hour(), local.Minute(), local.Second(), local.Nanosecond(), time.UTC)
+	for i := 0; i < 8; i++ {
+		wall = c.wallSpec().Next(wall)
+		if wall.IsZero() {
+			return wall
+		}
+		if instant := wallTimeIn(wall, c.loc); instant.After(t) {
+			return instant.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+func (c cronSchedule) wallSpec() *cron.SpecSchedule {
+	spec := *c.spec
+	spec.Location = time.UTC
+	return &spec
+}
+
+// wallTimeIn returns the instant wall clock time wall, read as UTC, has in
+// loc. A time skipped by a daylight saving change is shifted forward by
+// the change; a repeated time resolves to its first occurrence.
+func wallTimeIn(wall time.Time, loc *time.Location) time.Time {
+	t := time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), 0, 0, loc)
+	_, offset := t.Zone()
+	_, earlierOffset := t.Add(-3 * time.Hour).Zone()
+	if !sameWallClock(t, wall) {
+		// Skipped: read the wall time with the offset in force before the
+		// change, which lands the same distance past it.
+		return wall.Add(-time.Duration(earlierOffset) * time.Second).In(loc)
+	}
+	if shift := time.Duration(earlierOffset-offset) * time.Second; shift > 0 {
+		if earlier := t.Add(-shift); sameWallClock(earlier, t) {
+			return earlier
+		}
+	}
+	return t
+}
+
+func sameWallClock(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay() && a.Hour() == b.Hour() && a.Minute() == b.Minute()
+}
+
+// missedFires returns the fire times from first up to now, keeping the
+// last keep of them, and how many there were in all.
+func (c cronSchedule) missedFires(first, now time.Time, keep int) ([]time.Time, int) {
+	var fires []time.Time
+	total := 0
+	for t := first; !t.IsZero() && !t.After(now) && total < maxMissedFireScan; t = c.Next(t) {
+		total++
+		if keep <= 0 {
+			continue
+		}
+		if len(fires) == keep {
+			fires = fires[1:]
+		}
+		fires = append(fires, t)
+	}
+	return fires, total
+}
+
+// scheduleJitter returns the delay added to a schedule's fire time: a
+// stable pseudo-random duration below jitter, derived from the schedule
+// and fire time so schedules sharing a cron expression spread out.
+func scheduleJitter(scheduleID string, fireAt time.Time, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return 0
 	}
-	return schedule.Next(now.UTC()), nil
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(scheduleID))
+	_, _ = h.Write([]byte(fireAt.UTC().Format(time.RFC3339)))
+	return time.Duration(h.Sum64() % uint64(jitter))
 }
 
 func parseCronExpressionUTC(expr string) (cron.Schedule, error) {
@@ -32,7 +161,7 @@ func parseCronExpressionUTC(expr string) (cron.Schedule, error) {
 
 	upper := strings.ToUpper(clean)
 	if strings.Contains(upper, "CRON_TZ=") || strings.Contains(upper, "TZ=") {
-		return nil, fmt.Errorf("cron expression must be UTC-only (timezone prefixes are not allowed)")
+		return nil, fmt.Errorf("cron expression must not have a timezone prefix (set the schedule's timezone instead)")
 	}
 
 	schedule, err := standardCronParser.Parse(clean)
@@ -28,3 +28,103 @@ func TestParseCronExpressionUTC_RejectsTimezonePrefixes(t *testing.T) {
 		}
 	}
 }
+
+func TestCronSchedule_DaylightSaving(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	cases := []struct {
+		name string
+		expr string
+		from time.Time
+		want []time.Time
+	}{
+		{
+			// 2026-03-08 02:00 EST jumps to 03:00 EDT.
+			name: "skipped hour shifts forward",
+			expr: "30 2 * * *",
+			from: time.Date(2026, 3, 7, 3, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, 3, 8, 3, 30, 0, 0, ny),
+				time.Date(2026, 3, 9, 2, 30, 0, 0, ny),
+			},
+		},
+		{
+			// 2026-11-01 02:00 EDT falls back to 01:00 EST.
+			name: "repeated hour fires once",
+			expr: "30 1 * * *",
+			from: time.Date(2026, 10, 31, 3, 0, 0, 0, ny),
+			want: []time.Time{
+				time.Date(2026, 11, 1, 5, 30, 0, 0, time.UTC), // 01:30 EDT
+				time.Date(2026, 11, 2, 6, 30, 0, 0, time.UTC), // 01:30 EST
+			},
+		},
+		{
+			name: "wildcard hour follows elapsed time",
+			expr: "0 * * * *",
+			from: time.Date(2026, 11, 1, 4, 30, 0, 0, time.UTC), // 00:30 EDT
+			want: []time.Time{
+				time.Date(2026, 11, 1, 5, 0, 0, 0, time.UTC), // 01:00 EDT
+				time.Date(2026, 11, 1, 6, 0, 0, 0, time.UTC), // 01:00 EST
+				time.Date(2026, 11, 1, 7, 0, 0, 0, time.UTC), // 02:00 EST
+			},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			cs, err := parseCronSchedule(tc.expr, "America/New_York")
+			if err != nil {
+				t.Fatalf("parseCronSchedule: %v", err)
+			}
+			next := tc.from
+			for i, want := range tc.want {
+				next = cs.Next(next)
+				if !next.Equal(want) {
+					t.Fatalf("fire %d = %s, want %s", i, next, want.UTC())
+				}
+			}
+		})
+	}
+}
+
+func TestParseCronSchedule_RejectsUnknownTimezone(t *testing.T) {
+	if _, err := parseCronSchedule("* * * * *", "Mars/Olympus_Mons"); err == nil {
+		t.Fatal("expected error for unknown timezone")
+	}
+}
+
+func TestCronSchedule_MissedFires(t *testing.T) {
+	cs, err := parseCronSchedule("0 * * * *", "")
+	if err != nil {
+		t.Fatalf("parseCronSchedule: %v", err)
+	}
+	first := time.Date(2026, 2, 16, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2026, 2, 16, 5, 30, 0, 0, time.UTC)
+
+	fires, total := cs.missedFires(first, now, 2)
+	if total != 6 {
+		t.Fatalf("total = %d, want 6", total)
+	}
+	want := []time.Time{first.Add(4 * time.Hour), first.Add(5 * time.Hour)}
+	if len(fires) != len(want) || !fires[0].Equal(want[0]) || !fires[1].Equal(want[1]) {
+		t.Fatalf("fires = %v, want %v", fires, want)
+	}
+	if fires, total := cs.missedFires(first, now, 0); fires != nil || total != 6 {
+		t.Fatalf("keep 0: fires = %v total = %d", fires, total)
+	}
+}
+
+func TestScheduleJitter_StableAndBounded(t *testing.T) {
+	fireAt := time.Date(2026, 2, 16, 12, 0, 0, 0, time.UTC)
+	a := scheduleJitter("sched-a", fireAt, time.Minute)
+	if a < 0 || a >= time.Minute {
+		t.Fatalf("jitter = %s, want within [0, 1m)", a)
+	}
+	if again := scheduleJitter("sched-a", fireAt, time.Minute); again != a {
+		t.Fatalf("jitter not stable: %s then %s", a, again)
+	}
+	if scheduleJitter("sched-a", fireAt, 0) != 0 {
+		t.Fatal("zero jitter should add no delay")
+	}
+}
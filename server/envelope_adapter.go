@@ -31,6 +31,8 @@ type ArtifactJSON struct {
 	Text     string `json:"text,omitempty"`
 	Content  string `json:"content,omitempty"` // base64 for binary data
 	URI      string `json:"uri,omitempty"`
+	// SourceNode is the node that produced the artifact, when known.
+	SourceNode string `json:"source_node,omitempty"`
 }
 
 // TraceJSON is the JSON-serializable representation of TraceInfo.
@@ -40,6 +42,41 @@ type TraceJSON struct {
 	TraceID   string `json:"trace_id,omitempty"`
 }
 
+// NodeErrorJSON is the JSON-serializable representation of a NodeError.
+// ErrorCode and Remediation are set for classified LLM provider failures.
+type NodeErrorJSON struct {
+	NodeID      string         `json:"node_id"`
+	Message     string         `json:"message"`
+	Attempt     int            `json:"attempt,omitempty"`
+	At          time.Time      `json:"at"`
+	ErrorCode   string         `json:"error_code,omitempty"`
+	Remediation string         `json:"remediation,omitempty"`
+	Details     map[string]any `json:"details,omitempty"`
+}
+
+// NodeErrorsToJSON converts the node failures recorded on env.
+func NodeErrorsToJSON(env *core.Envelope) []NodeErrorJSON {
+	if env == nil || len(env.Errors) == 0 {
+		return nil
+	}
+	out := make([]NodeErrorJSON, 0, len(env.Errors))
+	for _, ne := range env.Errors {
+		ej := NodeErrorJSON{
+			NodeID:  ne.NodeID,
+			Message: ne.Message,
+			Attempt: ne.Attempt,
+			At:      ne.At,
+			Details: ne.Details,
+		}
+		if pe, ok := core.AsProviderError(ne.Cause); ok {
+			ej.ErrorCode = string(pe.Code)
+			ej.Remediation = pe.Remediation()
+		}
+		out = append(out, ej)
+	}
+	return out
+}
+
 // EnvelopeToJSON converts a live Envelope to the JSON-serializable form.
 func EnvelopeToJSON(env *core.Envelope) EnvelopeJSON {
 	if env == nil {
@@ -72,6 +109,12 @@ func EnvelopeToJSON(env *core.Envelope) EnvelopeJSON {
 			Text:     art.Text,
 			URI:      art.URI,
 		}
+		if filename, ok := art.Meta["filename"].(string); ok && filename != "" {
+			aj.Name = filename
+		}
+		if source, ok := art.Meta["source_node"].(string); ok {
+			aj.SourceNode = source
+		}
 		// Base64-encode binary content.
 		if len(art.Bytes) > 0 {
 			aj.Content = base64.StdEncoding.EncodeToString(art.Bytes)
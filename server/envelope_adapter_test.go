@@ -195,6 +195,21 @@ func TestEnvelopeToJSON_WithArtifacts(t *testing.T) {
 	}
 }
 
+func TestEnvelopeToJSON_ToolArtifact(t *testing.T) {
+	env := core.NewEnvelope()
+	env.AppendArtifact(core.Artifact{
+		ID:    "render/chart.png",
+		Type:  "file",
+		Bytes: []byte{0x89},
+		Meta:  map[string]any{"filename": "chart.png", "source_node": "render"},
+	})
+
+	a := EnvelopeToJSON(env).Artifacts[0]
+	if a.Name != "chart.png" || a.SourceNode != "render" {
+		t.Errorf("artifact = %+v, want name chart.png from render", a)
+	}
+}
+
 func TestEnvelopeToJSON_WithTrace(t *testing.T) {
 	env := core.NewEnvelope()
 	started := time.Date(2026, 2, 7, 12, 0, 0, 0, time.UTC)
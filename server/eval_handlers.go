@@ -0,0 +1,137 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/petal-labs/petalflow/evals"
+)
+
+func (s *Server) handleListEvalDatasets(w http.ResponseWriter, r *http.Request) {
+	if s.evalDatasets == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "eval datasets are not configured")
+		return
+	}
+	datasets, err := s.evalDatasets.ListEvalDatasets(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if datasets == nil {
+		datasets = []evals.Dataset{}
+	}
+	writeJSON(w, http.StatusOK, datasets)
+}
+
+func (s *Server) handleCreateEvalDataset(w http.ResponseWriter, r *http.Request) {
+	if s.evalDatasets == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "eval datasets are not configured")
+		return
+	}
+	var ds evals.Dataset
+	if err := decodeJSONBody(r, &ds); err != nil {
+		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
+		return
+	}
+	ds.Normalize()
+	if err := ds.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_DATASET", err.Error())
+		return
+	}
+	now := time.Now().UTC()
+	ds.CreatedAt = now
+	ds.UpdatedAt = now
+
+	if err := s.evalDatasets.CreateEvalDataset(r.Context(), ds); err != nil {
+		if errors.Is(err, ErrEvalDatasetExists) {
+			writeError(w, http.StatusConflict, "CONFLICT", fmt.Sprintf("dataset %q already exists", ds.Name))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, ds)
+}
+
+func (s *Server) handleGetEvalDataset(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if s.evalDatasets == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "eval datasets are not configured")
+		return
+	}
+	ds, found, err := s.evalDatasets.GetEvalDataset(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("dataset %q not found", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, ds)
+}
+
+// handleUpdateEvalDataset replaces a dataset's examples and scorers.
+func (s *Server) handleUpdateEvalDataset(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if s.evalDatasets == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "eval datasets are not configured")
+		return
+	}
+	existing, found, err := s.evalDatasets.GetEvalDataset(r.Context(), name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("dataset %q not found", name))
+		return
+	}
+
+	var ds evals.Dataset
+	if err := decodeJSONBody(r, &ds); err != nil {
+		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
+		return
+	}
+	if ds.Name != "" && ds.Name != name {
+		writeError(w, http.StatusBadRequest, "INVALID_DATASET", fmt.Sprintf("body name %q does not match %q", ds.Name, name))
+		return
+	}
+	ds.Name = name
+	ds.Normalize()
+	if err := ds.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_DATASET", err.Error())
+		return
+	}
+	ds.CreatedAt = existing.CreatedAt
+	ds.UpdatedAt = time.Now().UTC()
+
+	if err := s.evalDatasets.UpdateEvalDataset(r.Context(), ds); err != nil {
+		if errors.Is(err, ErrEvalDatasetNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("dataset %q not found", name))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, ds)
+}
+
+func (s *Server) handleDeleteEvalDataset(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if s.evalDatasets == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "eval datasets are not configured")
+		return
+	}
+	if err := s.evalDatasets.DeleteEvalDataset(r.Context(), name); err != nil {
+		if errors.Is(err, ErrEvalDatasetNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("dataset %q not found", name))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
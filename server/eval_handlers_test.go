@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/evals"
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+func TestEvalDatasetCRUD(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	handler := NewServer(ServerConfig{
+		Store:        store,
+		EvalDatasets: store,
+		Providers:    hydrate.ProviderMap{},
+		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+			return nil, nil
+		},
+	}).Handler()
+
+	ds := map[string]any{
+		"name":     "triage",
+		"scorers":  []any{map[string]any{"type": "contains", "ignore_case": true}},
+		"examples": []any{map[string]any{"input": map[string]any{"text": "refund please"}, "expected": "billing"}},
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/evals/datasets", ds); w.Code != http.StatusCreated {
+		t.Fatalf("create status %d: %s", w.Code, w.Body.String())
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/evals/datasets", ds); w.Code != http.StatusConflict {
+		t.Fatalf("duplicate create status %d, want 409", w.Code)
+	}
+	invalid := map[string]any{"name": "empty", "examples": []any{}}
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/evals/datasets", invalid)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_DATASET") {
+		t.Fatalf("invalid create status %d: %s", w.Code, w.Body.String())
+	}
+
+	ds["pass_threshold"] = 0.8
+	if w := doConditionRequest(t, handler, http.MethodPut, "/api/evals/datasets/triage", ds); w.Code != http.StatusOK {
+		t.Fatalf("update status %d: %s", w.Code, w.Body.String())
+	}
+	if w := doConditionRequest(t, handler, http.MethodPut, "/api/evals/datasets/other", ds); w.Code != http.StatusNotFound {
+		t.Fatalf("update missing status %d, want 404", w.Code)
+	}
+
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/evals/datasets/triage", nil)
+	var got evals.Dataset
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.PassThreshold == nil || *got.PassThreshold != 0.8 || got.Examples[0].ID != "1" || got.Scorers[0].Name != "contains" || got.CreatedAt.IsZero() {
+		t.Fatalf("dataset = %+v", got)
+	}
+
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/evals/datasets", nil)
+	var list []evals.Dataset
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list) != 1 {
+		t.Fatalf("list = %s (err %v)", w.Body.String(), err)
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodDelete, "/api/evals/datasets/triage", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete status %d", w.Code)
+	}
+	if w := doConditionRequest(t, handler, http.MethodGet, "/api/evals/datasets/triage", nil); w.Code != http.StatusNotFound {
+		t.Fatalf("get after delete status %d, want 404", w.Code)
+	}
+}
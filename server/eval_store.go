@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/petal-labs/petalflow/evals"
+)
+
+var (
+	ErrEvalDatasetExists   = errors.New("eval dataset already exists")
+	ErrEvalDatasetNotFound = errors.New("eval dataset not found")
+)
+
+// EvalDatasetStore persists evaluation datasets.
+type EvalDatasetStore interface {
+	ListEvalDatasets(ctx context.Context) ([]evals.Dataset, error)
+	GetEvalDataset(ctx context.Context, name string) (evals.Dataset, bool, error)
+	CreateEvalDataset(ctx context.Context, ds evals.Dataset) error
+	UpdateEvalDataset(ctx context.Context, ds evals.Dataset) error
+	DeleteEvalDataset(ctx context.Context, name string) error
+}
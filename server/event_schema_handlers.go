@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// EventSchemaInfo describes one event kind's payload schema.
+type EventSchemaInfo struct {
+	Kind        runtime.EventKind `json:"kind"`
+	Version     int               `json:"version"`
+	Description string            `json:"description"`
+	JSONSchema  map[string]any    `json:"json_schema"`
+}
+
+// handleListEventSchemas returns the payload schema of every event kind.
+func (s *Server) handleListEventSchemas(w http.ResponseWriter, _ *http.Request) {
+	schemas := runtime.EventSchemas()
+	out := make([]EventSchemaInfo, len(schemas))
+	for i, es := range schemas {
+		out[i] = EventSchemaInfo{Kind: es.Kind, Version: es.Version, Description: es.Description, JSONSchema: es.JSONSchema()}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"events": out})
+}
+
+// handleGetEventSchema returns one event kind's payload schema as a JSON
+// Schema document.
+func (s *Server) handleGetEventSchema(w http.ResponseWriter, r *http.Request) {
+	kind := runtime.EventKind(r.PathValue("kind"))
+	es, ok := runtime.LookupEventSchema(kind)
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("event kind %q not found", kind))
+		return
+	}
+	writeJSON(w, http.StatusOK, es.JSONSchema())
+}
+
+// invalidEventHandler logs run events whose payload does not match their
+// kind's schema, when the server validates events.
+func (s *Server) invalidEventHandler() func(runtime.Event, error) {
+	if !s.validateEvents {
+		return nil
+	}
+	return func(e runtime.Event, err error) {
+		s.logger.Warn("invalid event payload", "run_id", e.RunID, "node_id", e.NodeID, "kind", e.Kind, "error", err)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventSchemas_Endpoints(t *testing.T) {
+	handler := testServer(t).Handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/events/schemas", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("list: got %d; body: %s", w.Code, w.Body.String())
+	}
+	var list struct {
+		Events []EventSchemaInfo `json:"events"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(list.Events) == 0 || list.Events[0].Version != 1 || list.Events[0].JSONSchema["type"] != "object" {
+		t.Fatalf("events = %+v", list.Events)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/events/schemas/run.finished", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: got %d; body: %s", w.Code, w.Body.String())
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if doc["title"] != "run.finished" {
+		t.Errorf("title = %v", doc["title"])
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/events/schemas/nope", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown kind: got %d, want 404", w.Code)
+	}
+}
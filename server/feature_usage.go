@@ -0,0 +1,369 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"text/template/parse"
+	"time"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// DefaultFeatureUsageWindow is the window of GET /api/usage/features when
+// the request does not set one.
+const DefaultFeatureUsageWindow = 30 * 24 * time.Hour
+
+// Feature categories.
+const (
+	FeatureNodeType     = "node_type"
+	FeatureConfigField  = "config_field"
+	FeatureTemplateFunc = "template_func"
+	FeatureExprOperator = "expr_operator"
+)
+
+// NodeFeatures lists the features a node uses. Runs record it per node ID
+// in the run.started event, and a feature counts as exercised by the run
+// when the node starts.
+type NodeFeatures struct {
+	Type string `json:"type"`
+	// Config lists the node's top-level config fields.
+	Config []string `json:"config,omitempty"`
+	// TemplateFuncs lists the functions the node's templates call,
+	// text/template builtins included.
+	TemplateFuncs []string `json:"template_funcs,omitempty"`
+	// ExprOperators lists the operators of the node's expressions, and
+	// their function calls as "name()".
+	ExprOperators []string `json:"expr_operators,omitempty"`
+}
+
+// names returns the node's features as category and name pairs. Config
+// fields are qualified by the node type, as in "llm_prompt.system_prompt".
+func (f NodeFeatures) names() [][2]string {
+	out := [][2]string{{FeatureNodeType, f.Type}}
+	for _, field := range f.Config {
+		out = append(out, [2]string{FeatureConfigField, f.Type + "." + field})
+	}
+	for _, fn := range f.TemplateFuncs {
+		out = append(out, [2]string{FeatureTemplateFunc, fn})
+	}
+	for _, op := range f.ExprOperators {
+		out = append(out, [2]string{FeatureExprOperator, op})
+	}
+	return out
+}
+
+// runFeatures lists the features of every node of compiled.
+func runFeatures(compiled *graph.GraphDefinition) map[string]NodeFeatures {
+	features := make(map[string]NodeFeatures, len(compiled.Nodes))
+	for _, nd := range compiled.Nodes {
+		f := NodeFeatures{Type: nd.Type}
+		funcs := map[string]bool{}
+		ops := map[string]bool{}
+		for key, value := range nd.Config {
+			f.Config = append(f.Config, key)
+			collectConfigFeatures(key, value, funcs, ops)
+		}
+		sort.Strings(f.Config)
+		f.TemplateFuncs = sortedKeys(funcs)
+		f.ExprOperators = sortedKeys(ops)
+		features[nd.ID] = f
+	}
+	return features
+}
+
+// expressionKeys are the config keys whose strings are expressions.
+var expressionKeys = map[string]bool{"expression": true, "expr": true, "assert": true}
+
+// collectConfigFeatures adds the template functions of every string in
+// value that contains an action, and the operators of every expression
+// under an expression key, to funcs and ops.
+func collectConfigFeatures(key string, value any, funcs, ops map[string]bool) {
+	switch v := value.(type) {
+	case string:
+		if expressionKeys[key] {
+			if e, err := expr.Parse(v); err == nil {
+				collectExprOperators(e, ops)
+			}
+			return
+		}
+		if strings.Contains(v, "{{") {
+			collectTemplateFuncs(v, funcs)
+		}
+	case map[string]any:
+		for k, item := range v {
+			collectConfigFeatures(k, item, funcs, ops)
+		}
+	case []any:
+		for _, item := range v {
+			// Items of an expression list, such as assert, stay
+			// expressions.
+			collectConfigFeatures(key, item, funcs, ops)
+		}
+	}
+}
+
+// collectTemplateFuncs adds the functions text calls to funcs. Text that
+// does not parse is skipped.
+func collectTemplateFuncs(text string, funcs map[string]bool) {
+	tree := parse.New("features")
+	tree.Mode = parse.SkipFuncCheck
+	trees := map[string]*parse.Tree{}
+	if _, err := tree.Parse(text, "", "", trees); err != nil {
+		return
+	}
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe)
+		case *parse.IfNode:
+			walk(&n.BranchNode)
+		case *parse.RangeNode:
+			walk(&n.BranchNode)
+		case *parse.WithNode:
+			walk(&n.BranchNode)
+		case *parse.BranchNode:
+			walk(n.Pipe)
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			walk(n.Pipe)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg)
+			}
+		case *parse.ChainNode:
+			walk(n.Node)
+		case *parse.IdentifierNode:
+			funcs[n.Ident] = true
+		}
+	}
+	for _, t := range trees {
+		walk(t.Root)
+	}
+}
+
+// collectExprOperators adds the operators and function calls of e to ops.
+func collectExprOperators(e expr.Expr, ops map[string]bool) {
+	switch n := e.(type) {
+	case *expr.BinaryExpr:
+		ops[n.Op.String()] = true
+		collectExprOperators(n.Left, ops)
+		collectExprOperators(n.Right, ops)
+	case *expr.UnaryExpr:
+		ops[n.Op.String()] = true
+		collectExprOperators(n.Operand, ops)
+	case *expr.CallExpr:
+		ops[n.Name+"()"] = true
+		for _, arg := range n.Args {
+			collectExprOperators(arg, ops)
+		}
+	case *expr.MemberExpr:
+		collectExprOperators(n.Object, ops)
+	case *expr.IndexExpr:
+		collectExprOperators(n.Object, ops)
+		collectExprOperators(n.Index, ops)
+	case *expr.ArrayLiteral:
+		for _, el := range n.Elements {
+			collectExprOperators(el, ops)
+		}
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	if len(set) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// featureEventLister is implemented by event stores that can select the
+// events of every run started within a window.
+type featureEventLister interface {
+	EventsSince(ctx context.Context, since time.Time, kinds ...runtime.EventKind) ([]runtime.Event, error)
+}
+
+// FeatureUsage reports how the runs of a window exercised one feature.
+type FeatureUsage struct {
+	Category string `json:"category"`
+	Name     string `json:"name"`
+	// Runs counts the runs in which a node using the feature started.
+	Runs int `json:"runs"`
+	// Executions counts the starts of nodes using the feature.
+	Executions int `json:"executions"`
+	// Workflows lists the workflows of those runs.
+	Workflows []string  `json:"workflows"`
+	LastUsed  time.Time `json:"last_used"`
+}
+
+// FeatureUsageReport is the body of GET /api/usage/features.
+type FeatureUsageReport struct {
+	Window     string    `json:"window"`
+	Since      time.Time `json:"since"`
+	WorkflowID string    `json:"workflow_id,omitempty"`
+	// Runs counts the runs of the window that recorded their features.
+	// Runs started before feature recording are left out.
+	Runs     int            `json:"runs"`
+	Features []FeatureUsage `json:"features"`
+}
+
+// aggregateFeatureUsage counts the features exercised by the runs of
+// events, which are ordered by run and sequence. Only runs of workflowID
+// are counted when it is set.
+func aggregateFeatureUsage(events []runtime.Event, workflowID string) FeatureUsageReport {
+	type usage struct {
+		FeatureUsage
+		workflows map[string]bool
+		lastRun   string
+	}
+	byFeature := map[[2]string]*usage{}
+	report := FeatureUsageReport{Features: []FeatureUsage{}}
+
+	var runID, runWorkflow string
+	var features map[string]NodeFeatures
+	for _, e := range events {
+		if e.RunID != runID {
+			runID, runWorkflow, features = e.RunID, "", nil
+		}
+		switch e.Kind {
+		case runtime.EventRunStarted:
+			runWorkflow, _ = e.Payload["workflow_id"].(string)
+			if workflowID != "" && runWorkflow != workflowID {
+				continue
+			}
+			raw, ok := e.Payload["features"]
+			if !ok {
+				continue
+			}
+			data, err := json.Marshal(raw)
+			if err == nil {
+				err = json.Unmarshal(data, &features)
+			}
+			if err != nil {
+				features = nil
+				continue
+			}
+			report.Runs++
+		case runtime.EventNodeStarted:
+			node, ok := features[e.NodeID]
+			if !ok {
+				continue
+			}
+			for _, key := range node.names() {
+				u := byFeature[key]
+				if u == nil {
+					u = &usage{
+						FeatureUsage: FeatureUsage{Category: key[0], Name: key[1]},
+						workflows:    map[string]bool{},
+					}
+					byFeature[key] = u
+				}
+				u.Executions++
+				if u.lastRun != runID {
+					u.lastRun = runID
+					u.Runs++
+				}
+				if runWorkflow != "" {
+					u.workflows[runWorkflow] = true
+				}
+				if e.Time.After(u.LastUsed) {
+					u.LastUsed = e.Time
+				}
+			}
+		}
+	}
+
+	for _, u := range byFeature {
+		u.Workflows = sortedKeys(u.workflows)
+		if u.Workflows == nil {
+			u.Workflows = []string{}
+		}
+		report.Features = append(report.Features, u.FeatureUsage)
+	}
+	sort.Slice(report.Features, func(i, j int) bool {
+		a, b := report.Features[i], report.Features[j]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Name < b.Name
+	})
+	return report
+}
+
+// handleFeatureUsage reports which node types, config fields, template
+// functions and expression operators recent runs exercised, computed from
+// the event store. workflow_id, category and name narrow the report.
+func (s *Server) handleFeatureUsage(w http.ResponseWriter, r *http.Request) {
+	lister, ok := s.eventStore.(featureEventLister)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "event store does not support feature usage reports")
+		return
+	}
+
+	q := r.URL.Query()
+	window := DefaultFeatureUsageWindow
+	windowLabel := "30d"
+	if raw := q.Get("window"); raw != "" {
+		d, err := parseStatsWindow(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_WINDOW", err.Error())
+			return
+		}
+		window, windowLabel = d, raw
+	}
+	category := q.Get("category")
+	switch category {
+	case "", FeatureNodeType, FeatureConfigField, FeatureTemplateFunc, FeatureExprOperator:
+	default:
+		writeError(w, http.StatusBadRequest, "INVALID_CATEGORY", fmt.Sprintf("category %q must be one of: %s, %s, %s, %s",
+			category, FeatureNodeType, FeatureConfigField, FeatureTemplateFunc, FeatureExprOperator))
+		return
+	}
+
+	since := time.Now().Add(-window)
+	events, err := lister.EventsSince(r.Context(), since, runtime.EventRunStarted, runtime.EventNodeStarted)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+
+	report := aggregateFeatureUsage(events, q.Get("workflow_id"))
+	if name := q.Get("name"); category != "" || name != "" {
+		filtered := report.Features[:0]
+		for _, f := range report.Features {
+			if (category == "" || f.Category == category) && (name == "" || f.Name == name) {
+				filtered = append(filtered, f)
+			}
+		}
+		report.Features = filtered
+	}
+	report.Window = windowLabel
+	report.Since = since.UTC()
+	report.WorkflowID = q.Get("workflow_id")
+	writeJSON(w, http.StatusOK, report)
+}
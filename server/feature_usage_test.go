@@ -0,0 +1,134 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+func TestRunFeatures(t *testing.T) {
+	features := runFeatures(&graph.GraphDefinition{Nodes: []graph.NodeDef{
+		{ID: "ask", Type: "llm_prompt", Config: map[string]any{
+			"prompt_template": `{{if .name}}{{upper (trim .name) | printf "%s"}}{{end}}`,
+			"assert":          []any{"hour() > 8 && !(output ?? false)"},
+		}},
+		{ID: "route", Type: "conditional", Config: map[string]any{
+			"conditions": []any{map[string]any{"name": "vip", "expression": `input.tier in ["gold"]`}},
+		}},
+	}})
+
+	ask := features["ask"]
+	if !reflect.DeepEqual(ask.Config, []string{"assert", "prompt_template"}) {
+		t.Errorf("config = %v", ask.Config)
+	}
+	if !reflect.DeepEqual(ask.TemplateFuncs, []string{"printf", "trim", "upper"}) {
+		t.Errorf("template funcs = %v", ask.TemplateFuncs)
+	}
+	if !reflect.DeepEqual(ask.ExprOperators, []string{"!", "&&", ">", "??", "hour()"}) {
+		t.Errorf("expr operators = %v", ask.ExprOperators)
+	}
+	if route := features["route"]; !reflect.DeepEqual(route.ExprOperators, []string{"in"}) || route.TemplateFuncs != nil {
+		t.Errorf("route = %+v", route)
+	}
+}
+
+func TestFeatureUsageEndpoint(t *testing.T) {
+	handler := NewServer(ServerConfig{
+		Store:     newTestSQLiteStore(t),
+		Providers: hydrate.ProviderMap{"openai": {APIKey: "sk-test"}},
+		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+			return &workflowLifecycleLLMClient{provider: name}, nil
+		},
+		Bus:        bus.NewMemBus(bus.MemBusConfig{}),
+		EventStore: newTestEventStore(t),
+	}).Handler()
+
+	workflow := map[string]any{
+		"id":      "triage",
+		"version": "1.0",
+		"nodes": []map[string]any{
+			{"id": "ask", "type": "llm_prompt", "config": map[string]any{
+				"provider":        "openai",
+				"model":           "gpt-4o-mini",
+				"prompt_template": `{{printf "%s?" .question}}`,
+			}},
+			{"id": "route", "type": "conditional", "config": map[string]any{
+				"default":    "small",
+				"conditions": []any{map[string]any{"name": "large", "expression": "input.size > 100"}},
+			}},
+			{"id": "large", "type": "transform", "config": map[string]any{"transform": "template", "template": "{{lower .x}}", "output_var": "y"}},
+			{"id": "small", "type": "noop"},
+		},
+		"edges": []map[string]any{
+			{"source": "ask", "target": "route"},
+			{"source": "route", "sourceHandle": "large", "target": "large"},
+			{"source": "route", "sourceHandle": "small", "target": "small"},
+		},
+		"entry": "ask",
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", workflow); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	for range 2 {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/workflows/triage/run", bytes.NewReader([]byte(`{"input": {"question": "hi", "size": 1}}`))))
+		if w.Code != http.StatusOK {
+			t.Fatalf("run: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	get := func(query string) (FeatureUsageReport, int) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/usage/features"+query, nil))
+		var report FeatureUsageReport
+		_ = json.Unmarshal(w.Body.Bytes(), &report)
+		return report, w.Code
+	}
+
+	report, code := get("")
+	if code != http.StatusOK || report.Runs != 2 || report.Window != "30d" {
+		t.Fatalf("report = %d %+v", code, report)
+	}
+	used := map[string]FeatureUsage{}
+	for _, f := range report.Features {
+		used[f.Category+":"+f.Name] = f
+	}
+	for _, key := range []string{"node_type:llm_prompt", "node_type:noop", "config_field:llm_prompt.prompt_template", "template_func:printf", "expr_operator:>"} {
+		f, ok := used[key]
+		if !ok {
+			t.Errorf("missing feature %s", key)
+			continue
+		}
+		if f.Runs != 2 || f.Executions != 2 || !reflect.DeepEqual(f.Workflows, []string{"triage"}) || f.LastUsed.IsZero() {
+			t.Errorf("%s = %+v", key, f)
+		}
+	}
+	// The large branch never ran.
+	for _, key := range []string{"node_type:transform", "template_func:lower"} {
+		if _, ok := used[key]; ok {
+			t.Errorf("unexercised feature %s reported", key)
+		}
+	}
+
+	report, _ = get("?category=template_func&workflow_id=triage")
+	if len(report.Features) != 1 || report.Features[0].Name != "printf" {
+		t.Errorf("filtered features = %+v", report.Features)
+	}
+	if report, _ = get("?workflow_id=other"); report.Runs != 0 || len(report.Features) != 0 {
+		t.Errorf("other workflow report = %+v", report)
+	}
+	if _, code := get("?category=colour"); code != http.StatusBadRequest {
+		t.Errorf("invalid category status = %d", code)
+	}
+	if _, code := get("?window=-1d"); code != http.StatusBadRequest {
+		t.Errorf("invalid window status = %d", code)
+	}
+}
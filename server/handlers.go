@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
+	"sort"
 	"strings"
 	"time"
 
@@ -14,8 +16,8 @@ import (
 
 	"github.com/petal-labs/petalflow/agent"
 	"github.com/petal-labs/petalflow/bus"
-	"github.com/petal-labs/petalflow/core"
 	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/hydrate"
 	"github.com/petal-labs/petalflow/loader"
 	"github.com/petal-labs/petalflow/nodes"
 	"github.com/petal-labs/petalflow/registry"
@@ -24,7 +26,18 @@ import (
 
 // handleHealth returns a simple health check response.
 func (s *Server) handleHealth(w http.ResponseWriter, _ *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	state := s.Maintenance()
+	writeJSON(w, http.StatusOK, healthResponse{Status: "ok", EngineVersion: graph.EngineVersion, ReadOnly: state.ReadOnly, Banner: state.Banner})
+}
+
+// healthResponse carries the maintenance flags so load balancers and UIs
+// can see them without a second request, and the engine version workflow
+// requires.engine constraints are checked against.
+type healthResponse struct {
+	Status        string  `json:"status"`
+	EngineVersion string  `json:"engine_version"`
+	ReadOnly      bool    `json:"read_only,omitempty"`
+	Banner        *Banner `json:"banner,omitempty"`
 }
 
 // handleNodeTypes returns all registered node types.
@@ -33,14 +46,38 @@ func (s *Server) handleNodeTypes(w http.ResponseWriter, _ *http.Request) {
 	writeJSON(w, http.StatusOK, types)
 }
 
-// handleListWorkflows returns all workflows.
+// handleListWorkflows returns all workflows with their lifecycle states.
+// ?lifecycle= lists only the workflows in that state.
 func (s *Server) handleListWorkflows(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("lifecycle")
+	if _, ok := lifecycleTransitions[state]; state != "" && !ok {
+		writeError(w, http.StatusBadRequest, "INVALID_LIFECYCLE", fmt.Sprintf("unknown lifecycle state %q", state))
+		return
+	}
 	records, err := s.store.List(r.Context())
 	if err != nil {
 		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
 		return
 	}
-	writeJSON(w, http.StatusOK, records)
+	statuses, err := s.lifecycleStatuses(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	listed := records[:0]
+	for _, rec := range records {
+		status, ok := statuses[rec.ID]
+		if !ok {
+			status = LifecycleStatus{State: LifecycleActive}
+		}
+		if state != "" && status.State != state {
+			continue
+		}
+		rec = redactWorkflowRecord(rec)
+		rec.Lifecycle = &status
+		listed = append(listed, rec)
+	}
+	writeJSON(w, http.StatusOK, listed)
 }
 
 // handleGetWorkflow returns a single workflow by ID.
@@ -55,11 +92,27 @@ func (s *Server) handleGetWorkflow(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
 		return
 	}
+	lifecycle, err := s.workflowLifecycle(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	rec = redactWorkflowRecord(rec)
+	rec.Lifecycle = &lifecycle.LifecycleStatus
 	writeJSON(w, http.StatusOK, rec)
 }
 
 // handleCreateAgentWorkflow creates a workflow from an agent schema body.
 func (s *Server) handleCreateAgentWorkflow(w http.ResponseWriter, r *http.Request) {
+	s.handleCreateWorkflow(w, r, loader.SchemaKindAgent)
+}
+
+// handleCreateGraphWorkflow creates a workflow from a graph schema body.
+func (s *Server) handleCreateGraphWorkflow(w http.ResponseWriter, r *http.Request) {
+	s.handleCreateWorkflow(w, r, loader.SchemaKindGraph)
+}
+
+func (s *Server) handleCreateWorkflow(w http.ResponseWriter, r *http.Request, kind loader.SchemaKind) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		if isMaxBytesError(err) {
@@ -70,62 +123,143 @@ func (s *Server) handleCreateAgentWorkflow(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	wf, err := agent.LoadFromBytes(body)
+	sources, format, verbatim, err := decodeWorkflowSources(r, body)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
 		return
 	}
-
-	diags := agent.Validate(wf)
-	if graph.HasErrors(diags) {
-		details := diagMessages(diags)
-		writeError(w, http.StatusUnprocessableEntity, "VALIDATION_ERROR", "agent workflow validation failed", details...)
-		return
+	if !verbatim {
+		body = nil
 	}
 
-	gd, err := agent.Compile(wf)
-	if err != nil {
-		writeError(w, http.StatusUnprocessableEntity, "COMPILE_ERROR", err.Error())
+	recs := make([]WorkflowRecord, len(sources))
+	for i, source := range sources {
+		if recs[i], err = newWorkflowRecord(kind, source); err != nil {
+			writeRunAPIError(w, err)
+			return
+		}
+		setWorkflowSource(&recs[i], source, body, format)
+	}
+	if len(recs) == 1 {
+		if err := s.createWorkflow(r.Context(), &recs[0]); err != nil {
+			writeRunAPIError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, redactWorkflowRecord(recs[0]))
 		return
 	}
 
-	gdDiags := gd.ValidateWithRegistry(registry.Global())
-	if graph.HasErrors(gdDiags) {
-		details := diagMessages(gdDiags)
-		writeError(w, http.StatusUnprocessableEntity, "VALIDATION_ERROR", "compiled graph validation failed", details...)
-		return
+	// A multi-document body creates all of its workflows or none.
+	for i := range recs {
+		if err := s.createWorkflow(r.Context(), &recs[i]); err != nil {
+			for _, created := range recs[:i] {
+				_ = s.store.Delete(r.Context(), created.ID)
+			}
+			writeRunAPIError(w, err)
+			return
+		}
+	}
+	created := make([]WorkflowRecord, len(recs))
+	for i, rec := range recs {
+		created[i] = redactWorkflowRecord(rec)
 	}
+	writeJSON(w, http.StatusCreated, map[string]any{"workflows": created})
+}
 
+// newWorkflowRecord validates and compiles a workflow definition of kind
+// into a record. Definitions without an ID get a random one.
+func newWorkflowRecord(kind loader.SchemaKind, source []byte) (WorkflowRecord, error) {
 	now := time.Now()
-	id := wf.ID
-	if id == "" {
-		id = uuid.New().String()
+	rec := WorkflowRecord{SchemaKind: kind, CreatedAt: now, UpdatedAt: now}
+
+	switch kind {
+	case loader.SchemaKindAgent:
+		wf, err := agent.LoadFromBytes(source)
+		if err != nil {
+			return WorkflowRecord{}, &runAPIError{Status: http.StatusBadRequest, Code: "PARSE_ERROR", Message: err.Error()}
+		}
+		if diags := agent.Validate(wf); graph.HasErrors(diags) {
+			return WorkflowRecord{}, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "VALIDATION_ERROR", Message: "agent workflow validation failed", Details: diagMessages(diags)}
+		}
+		gd, err := agent.Compile(wf)
+		if err != nil {
+			return WorkflowRecord{}, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "COMPILE_ERROR", Message: err.Error()}
+		}
+		if diags := gd.ValidateWithRegistry(registry.Global()); graph.HasErrors(diags) {
+			return WorkflowRecord{}, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "VALIDATION_ERROR", Message: "compiled graph validation failed", Details: diagMessages(diags)}
+		}
+		rec.ID, rec.Name, rec.Compiled = wf.ID, wf.Name, gd
+	default:
+		var gd graph.GraphDefinition
+		if err := json.Unmarshal(source, &gd); err != nil {
+			return WorkflowRecord{}, &runAPIError{Status: http.StatusBadRequest, Code: "PARSE_ERROR", Message: err.Error()}
+		}
+		if diags := gd.ValidateWithRegistry(registry.Global()); graph.HasErrors(diags) {
+			return WorkflowRecord{}, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "VALIDATION_ERROR", Message: "graph validation failed", Details: diagMessages(diags)}
+		}
+		rec.ID, rec.Compiled = gd.ID, &gd
 	}
 
-	rec := WorkflowRecord{
-		ID:         id,
-		SchemaKind: loader.SchemaKindAgent,
-		Name:       wf.Name,
-		Source:     json.RawMessage(body),
-		Compiled:   gd,
-		CreatedAt:  now,
-		UpdatedAt:  now,
+	if rec.ID == "" {
+		rec.ID = uuid.New().String()
+	}
+	if rec.Name == "" && kind != loader.SchemaKindAgent {
+		rec.Name = rec.ID
 	}
+	return rec, nil
+}
 
-	if err := s.store.Create(r.Context(), rec); err != nil {
+// reservedWorkflowIDs are the literal GET routes under /api/workflows/.
+// They take precedence over GET /api/workflows/{id}, so a workflow with
+// one of these IDs could be stored but never read.
+var reservedWorkflowIDs = []string{"search"}
+
+// createWorkflow checks a new workflow against the server policy,
+// verifies its providers, seals its secrets and stores it.
+func (s *Server) createWorkflow(ctx context.Context, rec *WorkflowRecord) error {
+	if slices.Contains(reservedWorkflowIDs, rec.ID) {
+		return &runAPIError{Status: http.StatusUnprocessableEntity, Code: "RESERVED_ID", Message: fmt.Sprintf("workflow id %q is reserved", rec.ID)}
+	}
+	if err := s.checkWorkflowPolicy(rec); err != nil {
+		return err
+	}
+	if err := s.verifyWorkflowProviders(ctx, rec); err != nil {
+		return err
+	}
+	if err := s.sealWorkflowSecrets(rec, nil); err != nil {
+		return err
+	}
+	if err := s.store.Create(ctx, *rec); err != nil {
 		if errors.Is(err, ErrWorkflowExists) {
-			writeError(w, http.StatusConflict, "CONFLICT", fmt.Sprintf("workflow %q already exists", id))
-			return
+			return &runAPIError{Status: http.StatusConflict, Code: "CONFLICT", Message: fmt.Sprintf("workflow %q already exists", rec.ID)}
 		}
+		return &runAPIError{Status: http.StatusInternalServerError, Code: "STORE_ERROR", Message: err.Error()}
+	}
+	return nil
+}
+
+// handleUpdateWorkflow updates an existing workflow. With ?rollout=canary
+// the update starts a canary rollout instead of replacing the workflow.
+func (s *Server) handleUpdateWorkflow(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	rec, ok, err := s.store.Get(r.Context(), id)
+	if err != nil {
 		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
 		return
 	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+		return
+	}
+	previous := rec
 
-	writeJSON(w, http.StatusCreated, rec)
-}
+	rollout, err := s.checkRolloutUpdate(r, id)
+	if err != nil {
+		writeRunAPIError(w, err)
+		return
+	}
 
-// handleCreateGraphWorkflow creates a workflow from a graph schema body.
-func (s *Server) handleCreateGraphWorkflow(w http.ResponseWriter, r *http.Request) {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
 		if isMaxBytesError(err) {
@@ -136,121 +270,90 @@ func (s *Server) handleCreateGraphWorkflow(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	var gd graph.GraphDefinition
-	if err := json.Unmarshal(body, &gd); err != nil {
+	sources, format, verbatim, err := decodeWorkflowSources(r, body)
+	if err != nil {
 		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
 		return
 	}
-
-	diags := gd.ValidateWithRegistry(registry.Global())
-	if graph.HasErrors(diags) {
-		details := diagMessages(diags)
-		writeError(w, http.StatusUnprocessableEntity, "VALIDATION_ERROR", "graph validation failed", details...)
+	if len(sources) != 1 {
+		writeError(w, http.StatusBadRequest, "PARSE_ERROR", fmt.Sprintf("update takes one workflow, got %d", len(sources)))
 		return
 	}
-
-	now := time.Now()
-	id := gd.ID
-	if id == "" {
-		id = uuid.New().String()
+	source := sources[0]
+	if !verbatim {
+		body = nil
 	}
 
-	rec := WorkflowRecord{
-		ID:         id,
-		SchemaKind: loader.SchemaKindGraph,
-		Name:       id,
-		Source:     json.RawMessage(body),
-		Compiled:   &gd,
-		CreatedAt:  now,
-		UpdatedAt:  now,
+	if err := recompileWorkflowRecord(&rec, source); err != nil {
+		writeRunAPIError(w, err)
+		return
 	}
+	setWorkflowSource(&rec, source, body, format)
 
-	if err := s.store.Create(r.Context(), rec); err != nil {
-		if errors.Is(err, ErrWorkflowExists) {
-			writeError(w, http.StatusConflict, "CONFLICT", fmt.Sprintf("workflow %q already exists", id))
-			return
-		}
-		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+	if err := s.checkWorkflowPolicy(&rec); err != nil {
+		writeRunAPIError(w, err)
 		return
 	}
-
-	writeJSON(w, http.StatusCreated, rec)
-}
-
-// handleUpdateWorkflow updates an existing workflow.
-func (s *Server) handleUpdateWorkflow(w http.ResponseWriter, r *http.Request) {
-	id := r.PathValue("id")
-
-	rec, ok, err := s.store.Get(r.Context(), id)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+	if err := s.verifyWorkflowProviders(r.Context(), &rec); err != nil {
+		writeRunAPIError(w, err)
 		return
 	}
-	if !ok {
-		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+	if err := s.sealWorkflowSecrets(&rec, &previous); err != nil {
+		writeRunAPIError(w, err)
 		return
 	}
+	rec.UpdatedAt = time.Now()
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		if isMaxBytesError(err) {
-			writeError(w, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE", "request body exceeds size limit")
+	if rollout != nil {
+		rollout.Candidate = rec
+		if err := s.startRollout(r.Context(), rollout); err != nil {
+			writeRunAPIError(w, err)
 			return
 		}
-		writeError(w, http.StatusBadRequest, "READ_ERROR", err.Error())
+		writeJSON(w, http.StatusAccepted, redactRollout(*rollout))
+		return
+	}
+	if err := s.store.Update(r.Context(), rec); err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
 		return
 	}
 
-	// Re-compile based on schema kind
+	writeJSON(w, http.StatusOK, redactWorkflowRecord(rec))
+}
+
+// recompileWorkflowRecord validates and compiles source as the new
+// definition of rec, keeping its schema kind.
+func recompileWorkflowRecord(rec *WorkflowRecord, source []byte) error {
 	switch rec.SchemaKind {
 	case loader.SchemaKindAgent:
-		wf, err := agent.LoadFromBytes(body)
+		wf, err := agent.LoadFromBytes(source)
 		if err != nil {
-			writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
-			return
+			return &runAPIError{Status: http.StatusBadRequest, Code: "PARSE_ERROR", Message: err.Error()}
 		}
-		diags := agent.Validate(wf)
-		if graph.HasErrors(diags) {
-			details := diagMessages(diags)
-			writeError(w, http.StatusUnprocessableEntity, "VALIDATION_ERROR", "agent workflow validation failed", details...)
-			return
+		if diags := agent.Validate(wf); graph.HasErrors(diags) {
+			return &runAPIError{Status: http.StatusUnprocessableEntity, Code: "VALIDATION_ERROR", Message: "agent workflow validation failed", Details: diagMessages(diags)}
 		}
 		gd, err := agent.Compile(wf)
 		if err != nil {
-			writeError(w, http.StatusUnprocessableEntity, "COMPILE_ERROR", err.Error())
-			return
+			return &runAPIError{Status: http.StatusUnprocessableEntity, Code: "COMPILE_ERROR", Message: err.Error()}
 		}
-		rec.Source = json.RawMessage(body)
 		rec.Compiled = gd
 		rec.Name = wf.Name
 
 	case loader.SchemaKindGraph:
 		var gd graph.GraphDefinition
-		if err := json.Unmarshal(body, &gd); err != nil {
-			writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
-			return
+		if err := json.Unmarshal(source, &gd); err != nil {
+			return &runAPIError{Status: http.StatusBadRequest, Code: "PARSE_ERROR", Message: err.Error()}
 		}
-		diags := gd.ValidateWithRegistry(registry.Global())
-		if graph.HasErrors(diags) {
-			details := diagMessages(diags)
-			writeError(w, http.StatusUnprocessableEntity, "VALIDATION_ERROR", "graph validation failed", details...)
-			return
+		if diags := gd.ValidateWithRegistry(registry.Global()); graph.HasErrors(diags) {
+			return &runAPIError{Status: http.StatusUnprocessableEntity, Code: "VALIDATION_ERROR", Message: "graph validation failed", Details: diagMessages(diags)}
 		}
-		rec.Source = json.RawMessage(body)
 		rec.Compiled = &gd
 
 	default:
-		writeError(w, http.StatusBadRequest, "UNKNOWN_KIND", fmt.Sprintf("unknown schema kind %q", rec.SchemaKind))
-		return
+		return &runAPIError{Status: http.StatusBadRequest, Code: "UNKNOWN_KIND", Message: fmt.Sprintf("unknown schema kind %q", rec.SchemaKind)}
 	}
-
-	rec.UpdatedAt = time.Now()
-	if err := s.store.Update(r.Context(), rec); err != nil {
-		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
-		return
-	}
-
-	writeJSON(w, http.StatusOK, rec)
+	return nil
 }
 
 // handleDeleteWorkflow deletes a workflow by ID.
@@ -271,6 +374,24 @@ func (s *Server) handleDeleteWorkflow(w http.ResponseWriter, r *http.Request) {
 type RunRequest struct {
 	Input   map[string]any `json:"input,omitempty"`
 	Options RunReqOptions  `json:"options,omitempty"`
+
+	// Presets names input presets of the workflow to start from, in order.
+	// Later presets replace variables of earlier ones and Input replaces
+	// them all. POST /api/workflows/{id}/run also takes them as repeated
+	// ?preset= query parameters.
+	Presets []string `json:"presets,omitempty"`
+
+	// Uploads lists upload IDs from POST /api/uploads to attach to the run
+	// envelope as "file" artifacts, in order.
+	Uploads []string `json:"uploads,omitempty"`
+
+	// Workspace scopes upload lookups. It is taken from the
+	// X-Workspace-ID header, never the body.
+	Workspace string `json:"-"`
+
+	// requeue marks the janitor's resumption of an interrupted run, which
+	// its lease already ties to the workflow.
+	requeue bool
 }
 
 // RunReqOptions holds optional run configuration.
@@ -278,6 +399,44 @@ type RunReqOptions struct {
 	Timeout string              `json:"timeout,omitempty"`
 	Stream  bool                `json:"stream,omitempty"`
 	Human   *RunReqHumanOptions `json:"human,omitempty"`
+
+	// MaxHops, Concurrency and ContinueOnError override the workflow's
+	// run_defaults. Values above the workflow's limits are rejected.
+	MaxHops         int   `json:"max_hops,omitempty"`
+	Concurrency     int   `json:"concurrency,omitempty"`
+	ContinueOnError *bool `json:"continue_on_error,omitempty"`
+
+	// MaxNodeExecutions caps total node executions for the run (0 = unlimited).
+	MaxNodeExecutions int `json:"max_node_executions,omitempty"`
+	// NodeVisitLimits caps executions of individual nodes by ID.
+	NodeVisitLimits map[string]int `json:"node_visit_limits,omitempty"`
+
+	// Simulate runs the workflow with canned LLM and tool responses. Entries
+	// here are layered over the workflow's own simulate section; an empty
+	// object simulates using the workflow's section alone.
+	Simulate *graph.SimulationDef `json:"simulate,omitempty"`
+
+	// ResumeFrom is the ID of an earlier run of this workflow to resume or
+	// retry. Nodes that completed in that run are restored from its events
+	// instead of re-executing, unless they declare idempotent: true.
+	ResumeFrom string `json:"resume_from,omitempty"`
+
+	// Chaos injects seeded latency, provider errors and dropped tool
+	// responses. Rejected unless the server was started with AllowChaos.
+	Chaos *runtime.ChaosConfig `json:"chaos,omitempty"`
+
+	// ToolCacheBypass makes the run's cacheable tool invocations ignore
+	// cached responses: "refresh" invokes the tools and replaces the
+	// cached responses, "skip" leaves the cache untouched.
+	ToolCacheBypass string `json:"tool_cache_bypass,omitempty"`
+
+	// Priority orders the run among queued runs of its class, higher
+	// first, from -10 to 10. Deadline is when the caller needs the run
+	// finished: queued runs with earlier deadlines go first among equal
+	// priorities, and the run fails with DEADLINE_EXCEEDED if it has not
+	// finished by then.
+	Priority int        `json:"priority,omitempty"`
+	Deadline *time.Time `json:"deadline,omitempty"`
 }
 
 // RunReqHumanOptions controls how daemon run requests handle human node prompts.
@@ -293,6 +452,9 @@ type RunReqHumanOptions struct {
 	Notes       string `json:"notes,omitempty"`
 	RespondedBy string `json:"responded_by,omitempty"`
 	Delay       string `json:"delay,omitempty"`
+
+	// Data is submitted as the response data, e.g. values for form fields.
+	Data map[string]any `json:"data,omitempty"`
 }
 
 // RunResponse is the JSON response for a completed run.
@@ -304,6 +466,8 @@ type RunResponse struct {
 	CompletedAt time.Time    `json:"completed_at"`
 	DurationMs  int64        `json:"duration_ms"`
 	Output      EnvelopeJSON `json:"output"`
+	// Errors lists node failures a continue_on_error run recorded.
+	Errors []NodeErrorJSON `json:"errors,omitempty"`
 }
 
 // handleRunWorkflow executes a workflow.
@@ -318,20 +482,23 @@ func (s *Server) handleRunWorkflow(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 	}
+	req.Workspace = requestWorkspace(r)
+	req.Presets = append(req.Presets, r.URL.Query()["preset"]...)
 
 	plan, err := s.planWorkflowRun(r.Context(), id, req)
 	if err != nil {
 		writeRunAPIError(w, err)
 		return
 	}
+	setDeprecationHeaders(w, plan.deprecation)
 
 	// Handle streaming vs non-streaming
 	if req.Options.Stream {
-		s.handleRunStreaming(w, r, id, plan.execGraph, plan.env, plan.timeout)
+		s.handleRunStreaming(w, r, id, plan)
 		return
 	}
 
-	s.handleRunSync(w, r, id, plan.execGraph, plan.env, plan.timeout)
+	s.handleRunSync(w, r, id, plan)
 }
 
 type strictRunHumanHandler struct{}
@@ -370,6 +537,9 @@ func buildRunHumanHandler(cfg *RunReqHumanOptions) (nodes.HumanHandler, error) {
 			if strings.TrimSpace(cfg.RespondedBy) != "" {
 				handler.RespondedBy = cfg.RespondedBy
 			}
+			if cfg.Data != nil {
+				handler.Data = cfg.Data
+			}
 			if strings.TrimSpace(cfg.Delay) != "" {
 				d, err := time.ParseDuration(cfg.Delay)
 				if err != nil {
@@ -389,15 +559,9 @@ func (s *Server) handleRunSync(
 	w http.ResponseWriter,
 	r *http.Request,
 	id string,
-	execGraph *graph.BasicGraph,
-	env *core.Envelope,
-	timeout time.Duration,
+	plan *workflowRunPlan,
 ) {
-	resp, err := s.executeWorkflowRunSync(r.Context(), id, &workflowRunPlan{
-		execGraph: execGraph,
-		env:       env,
-		timeout:   timeout,
-	}, nil)
+	resp, err := s.executeWorkflowRunSync(r.Context(), id, plan, nil)
 	if err != nil {
 		writeRunAPIError(w, err)
 		return
@@ -410,9 +574,7 @@ func (s *Server) handleRunStreaming(
 	w http.ResponseWriter,
 	r *http.Request,
 	id string,
-	execGraph *graph.BasicGraph,
-	env *core.Envelope,
-	timeout time.Duration,
+	plan *workflowRunPlan,
 ) {
 	writer, ok := newSSEWriter(w)
 	if !ok {
@@ -420,7 +582,7 @@ func (s *Server) handleRunStreaming(
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	ctx, cancel := context.WithTimeout(plan.runContext(r.Context()), plan.timeout)
 	defer cancel()
 	writer.startResponse()
 
@@ -430,7 +592,7 @@ func (s *Server) handleRunStreaming(
 		defer sub.Close()
 	}
 
-	doneCh := s.startStreamingRuntime(ctx, execGraph, env, runID)
+	doneCh := s.startStreamingRuntime(ctx, plan, runID)
 	writer.writeEvent("run.started", map[string]string{"run_id": runID, "workflow_id": id})
 
 	if sub == nil {
@@ -484,19 +646,11 @@ func (s *Server) subscribeRun(runID string) bus.Subscription {
 
 func (s *Server) startStreamingRuntime(
 	ctx context.Context,
-	execGraph *graph.BasicGraph,
-	env *core.Envelope,
+	plan *workflowRunPlan,
 	runID string,
 ) <-chan error {
 	rt := runtime.NewRuntime()
-	opts := runtime.DefaultRunOptions()
-	opts.EventEmitterDecorator = s.emitDecorator
-	if s.bus != nil {
-		opts.EventBus = s.bus
-	}
-	if s.runtimeEvents != nil {
-		opts.EventHandler = runtime.MultiEventHandler(opts.EventHandler, s.runtimeEvents)
-	}
+	opts := s.runOptions(plan, nil)
 
 	// Attach store subscriber.
 	if s.eventStore != nil {
@@ -505,11 +659,19 @@ func (s *Server) startStreamingRuntime(
 	}
 
 	// Set run ID on envelope before runtime execution.
-	env.Trace.RunID = runID
+	plan.env.Trace.RunID = runID
 
 	doneCh := make(chan error, 1)
 	go func() {
-		_, err := rt.Run(ctx, execGraph, env, opts)
+		queued := time.Now()
+		release, err := s.admitRun(ctx, plan.class, plan.hints)
+		if err != nil {
+			doneCh <- err
+			return
+		}
+		defer release()
+		opts.QueueWait = time.Since(queued)
+		_, err = rt.Run(ctx, plan.execGraph, plan.env, opts)
 		doneCh <- err
 	}()
 	return doneCh
@@ -610,7 +772,7 @@ func (s *Server) handleRunEvents(w http.ResponseWriter, r *http.Request) {
 	}
 
 	flusher, ok := w.(http.Flusher)
-	if !ok {
+	if !ok || strings.Contains(r.Header.Get("Accept"), "application/json") {
 		writeJSON(w, http.StatusOK, events)
 		return
 	}
@@ -627,13 +789,93 @@ func (s *Server) handleRunEvents(w http.ResponseWriter, r *http.Request) {
 	flusher.Flush()
 }
 
+// RunSummary identifies a run that has persisted events.
+type RunSummary struct {
+	RunID string `json:"run_id"`
+}
+
+// runIDLister is implemented by event stores that can enumerate runs.
+type runIDLister interface {
+	RunIDs(ctx context.Context) ([]string, error)
+}
+
+// handleListRuns returns the IDs of runs with persisted events.
+func (s *Server) handleListRuns(w http.ResponseWriter, r *http.Request) {
+	lister, ok := s.eventStore.(runIDLister)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "event store does not support listing runs")
+		return
+	}
+
+	ids, err := lister.RunIDs(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	runs := make([]RunSummary, 0, len(ids))
+	for _, id := range ids {
+		runs = append(runs, RunSummary{RunID: id})
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+// ProviderSummary describes a configured LLM provider without its credentials.
+type ProviderSummary struct {
+	Name    string `json:"name"`
+	BaseURL string `json:"base_url,omitempty"`
+	// Verification is the cached result of the last credential check.
+	// It is absent when verification is disabled or the result expired.
+	Verification *hydrate.CredentialStatus `json:"verification,omitempty"`
+	// Routing and Regions describe providers with regional endpoints.
+	// Regions report each region's last known health.
+	Routing string                 `json:"routing,omitempty"`
+	Regions []hydrate.RegionStatus `json:"regions,omitempty"`
+}
+
+// handleListProviders returns the names of configured providers.
+func (s *Server) handleListProviders(w http.ResponseWriter, _ *http.Request) {
+	providers := make([]ProviderSummary, 0, len(s.providers))
+	for name, cfg := range s.providers {
+		summary := ProviderSummary{Name: name, BaseURL: cfg.BaseURL}
+		if len(cfg.Regions) > 0 {
+			summary.Routing = cfg.Routing
+			if summary.Routing == "" {
+				summary.Routing = hydrate.RoutingFailover
+			}
+			if s.regions != nil {
+				summary.Regions = s.regions.Status(name, cfg)
+			}
+		}
+		if s.credentials != nil {
+			if status, ok := s.credentials.Status(name, cfg); ok {
+				summary.Verification = &status
+			}
+		}
+		providers = append(providers, summary)
+	}
+	sort.Slice(providers, func(i, j int) bool { return providers[i].Name < providers[j].Name })
+	writeJSON(w, http.StatusOK, providers)
+}
+
 // --- helpers ---
 
-// diagMessages extracts error messages from diagnostics.
+// diagMessages extracts error messages from diagnostics, followed by the
+// offending node's description and doc URL when it has them.
 func diagMessages(diags []graph.Diagnostic) []string {
 	errs := graph.Errors(diags)
 	msgs := make([]string, 0, len(errs))
 	for _, d := range errs {
+		var notes []string
+		if d.NodeDescription != "" {
+			notes = append(notes, d.NodeDescription)
+		}
+		if d.DocURL != "" {
+			notes = append(notes, "docs: "+d.DocURL)
+		}
+		if len(notes) > 0 {
+			msgs = append(msgs, fmt.Sprintf("%s (%s)", d.Message, strings.Join(notes, "; ")))
+			continue
+		}
 		msgs = append(msgs, d.Message)
 	}
 	return msgs
@@ -648,7 +890,7 @@ func isMaxBytesError(err error) bool {
 func writeRunAPIError(w http.ResponseWriter, err error) {
 	var runErr *runAPIError
 	if errors.As(err, &runErr) {
-		writeError(w, runErr.Status, runErr.Code, runErr.Message)
+		writeError(w, runErr.Status, runErr.Code, runErr.Message, runErr.Details...)
 		return
 	}
 	writeError(w, http.StatusInternalServerError, "RUNTIME_ERROR", err.Error())
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+const (
+	RunLeaseStatusRunning     = "running"
+	RunLeaseStatusInterrupted = "interrupted"
+)
+
+// RunLeaseRecord is a stored run lease. Leases of finished runs are
+// released; the ones left are running or were interrupted.
+type RunLeaseRecord struct {
+	runtime.RunLease
+	Status        string     `json:"status"`
+	InterruptedAt *time.Time `json:"interrupted_at,omitempty"`
+	// RequeuedRunID is the run that resumed an interrupted run.
+	RequeuedRunID string `json:"requeued_run_id,omitempty"`
+}
+
+// RunLeaseStore persists run leases and lets the janitor claim expired
+// ones.
+type RunLeaseStore interface {
+	runtime.LeaseStore
+	ListRunLeases(ctx context.Context) ([]RunLeaseRecord, error)
+	ListExpiredLeases(ctx context.Context, now time.Time, limit int) ([]RunLeaseRecord, error)
+	// MarkLeaseInterrupted moves a running lease that expired before now
+	// to interrupted. It reports false when the lease was renewed,
+	// released or already claimed, so only one janitor acts on a run.
+	MarkLeaseInterrupted(ctx context.Context, runID string, now time.Time) (bool, error)
+	SetLeaseRequeuedRun(ctx context.Context, runID, requeuedRunID string) error
+}
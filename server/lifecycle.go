@@ -0,0 +1,261 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// lifecycleTransitions lists the states each state may move to. No state
+// moves back to draft: once a workflow left it, its runs are history.
+var lifecycleTransitions = map[string][]string{
+	LifecycleDraft:      {LifecycleActive, LifecycleDeprecated, LifecycleArchived},
+	LifecycleActive:     {LifecycleDeprecated, LifecycleArchived},
+	LifecycleDeprecated: {LifecycleActive, LifecycleDeprecated, LifecycleArchived},
+	LifecycleArchived:   {LifecycleActive, LifecycleDeprecated},
+}
+
+// lifecycleRequest is the body of POST /api/workflows/{id}/lifecycle.
+type lifecycleRequest struct {
+	State       string     `json:"state"`
+	Reason      string     `json:"reason,omitempty"`
+	SunsetAt    *time.Time `json:"sunset_at,omitempty"`
+	Replacement string     `json:"replacement,omitempty"`
+}
+
+// workflowLifecycle returns the stored lifecycle of a workflow, or an
+// active one without history when none is stored.
+func (s *Server) workflowLifecycle(ctx context.Context, workflowID string) (WorkflowLifecycle, error) {
+	if s.lifecycles != nil {
+		lifecycle, ok, err := s.lifecycles.GetLifecycle(ctx, workflowID)
+		if err != nil {
+			return WorkflowLifecycle{}, err
+		}
+		if ok {
+			return lifecycle, nil
+		}
+	}
+	return WorkflowLifecycle{
+		WorkflowID:      workflowID,
+		LifecycleStatus: LifecycleStatus{State: LifecycleActive},
+		History:         []LifecycleTransition{},
+	}, nil
+}
+
+// lifecycleStatuses returns the lifecycle status of every workflow with a
+// stored lifecycle, by workflow ID.
+func (s *Server) lifecycleStatuses(ctx context.Context) (map[string]LifecycleStatus, error) {
+	statuses := map[string]LifecycleStatus{}
+	if s.lifecycles == nil {
+		return statuses, nil
+	}
+	lifecycles, err := s.lifecycles.ListLifecycles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, lifecycle := range lifecycles {
+		statuses[lifecycle.WorkflowID] = lifecycle.LifecycleStatus
+	}
+	return statuses, nil
+}
+
+// checkLifecycleRun refuses new runs of archived workflows. Janitor
+// requeues resume runs that were interrupted before the workflow was
+// archived and may finish; runs resumed through the API are new runs. For
+// a deprecated workflow it logs a warning and returns its status, which
+// the run reports.
+func (s *Server) checkLifecycleRun(ctx context.Context, workflowID string, requeue bool) (*LifecycleStatus, error) {
+	if s.lifecycles == nil {
+		return nil, nil
+	}
+	lifecycle, err := s.workflowLifecycle(ctx, workflowID)
+	if err != nil {
+		return nil, &runAPIError{Status: http.StatusInternalServerError, Code: "STORE_ERROR", Message: err.Error()}
+	}
+	switch lifecycle.State {
+	case LifecycleArchived:
+		if requeue {
+			return nil, nil
+		}
+		message := fmt.Sprintf("workflow %q is archived", workflowID)
+		if lifecycle.Replacement != "" {
+			message += fmt.Sprintf("; use %q instead", lifecycle.Replacement)
+		}
+		return nil, &runAPIError{Status: http.StatusConflict, Code: "WORKFLOW_ARCHIVED", Message: message}
+	case LifecycleDeprecated:
+		s.logger.Warn("running deprecated workflow",
+			"workflow_id", workflowID,
+			"reason", lifecycle.Reason,
+			"replacement", lifecycle.Replacement,
+		)
+		status := lifecycle.LifecycleStatus
+		return &status, nil
+	}
+	return nil, nil
+}
+
+// lifecycleRunDecorator adds the deprecation of a deprecated workflow to
+// the run.started events of its runs.
+func lifecycleRunDecorator(deprecation *LifecycleStatus) runtime.EventEmitterDecorator {
+	if deprecation == nil {
+		return nil
+	}
+	notice := map[string]any{}
+	if deprecation.Reason != "" {
+		notice["reason"] = deprecation.Reason
+	}
+	if deprecation.SunsetAt != nil {
+		notice["sunset_at"] = deprecation.SunsetAt.UTC().Format(time.RFC3339)
+	}
+	if deprecation.Replacement != "" {
+		notice["replacement"] = deprecation.Replacement
+	}
+	return func(next runtime.EventEmitter) runtime.EventEmitter {
+		return func(e runtime.Event) {
+			if e.Kind == runtime.EventRunStarted {
+				if e.Payload == nil {
+					e.Payload = map[string]any{}
+				}
+				e.Payload["deprecation"] = notice
+			}
+			next(e)
+		}
+	}
+}
+
+// setDeprecationHeaders marks the response to a run of a deprecated
+// workflow with the Deprecation, Sunset and successor Link headers.
+func setDeprecationHeaders(w http.ResponseWriter, deprecation *LifecycleStatus) {
+	if deprecation == nil {
+		return
+	}
+	w.Header().Set("Deprecation", "true")
+	if deprecation.SunsetAt != nil {
+		w.Header().Set("Sunset", deprecation.SunsetAt.UTC().Format(http.TimeFormat))
+	}
+	if deprecation.Replacement != "" {
+		w.Header().Set("Link", fmt.Sprintf(`</api/workflows/%s>; rel="successor-version"`, deprecation.Replacement))
+	}
+}
+
+// handleGetLifecycle returns a workflow's lifecycle state and history.
+func (s *Server) handleGetLifecycle(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec, ok, err := s.store.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+		return
+	}
+	lifecycle, err := s.workflowLifecycle(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if lifecycle.UpdatedAt.IsZero() {
+		lifecycle.UpdatedAt = rec.CreatedAt
+	}
+	writeJSON(w, http.StatusOK, lifecycle)
+}
+
+// handleSetLifecycle moves a workflow to another lifecycle state and
+// records the transition.
+func (s *Server) handleSetLifecycle(w http.ResponseWriter, r *http.Request) {
+	if s.lifecycles == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "lifecycle states require a lifecycle store")
+		return
+	}
+	id := r.PathValue("id")
+	var req lifecycleRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
+		return
+	}
+	req.State = strings.ToLower(strings.TrimSpace(req.State))
+	if _, ok := lifecycleTransitions[req.State]; !ok {
+		writeError(w, http.StatusBadRequest, "INVALID_LIFECYCLE", fmt.Sprintf("state %q must be one of: draft, active, deprecated, archived", req.State))
+		return
+	}
+	if req.State == LifecycleDraft || req.State == LifecycleActive {
+		if req.SunsetAt != nil || req.Replacement != "" {
+			writeError(w, http.StatusBadRequest, "INVALID_LIFECYCLE", "sunset_at and replacement apply to deprecated and archived workflows only")
+			return
+		}
+	}
+	if req.Replacement == id {
+		writeError(w, http.StatusBadRequest, "INVALID_LIFECYCLE", "a workflow cannot replace itself")
+		return
+	}
+
+	s.lifecycleMu.Lock()
+	defer s.lifecycleMu.Unlock()
+
+	if _, ok, err := s.store.Get(r.Context(), id); err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	} else if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+		return
+	}
+	if req.Replacement != "" {
+		if _, ok, err := s.store.Get(r.Context(), req.Replacement); err != nil {
+			writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+			return
+		} else if !ok {
+			writeError(w, http.StatusBadRequest, "INVALID_LIFECYCLE", fmt.Sprintf("replacement workflow %q not found", req.Replacement))
+			return
+		}
+	}
+
+	lifecycle, err := s.workflowLifecycle(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !lifecycleAllows(lifecycle.State, req.State) {
+		writeError(w, http.StatusConflict, "INVALID_TRANSITION", fmt.Sprintf("workflow %q cannot move from %s to %s", id, lifecycle.State, req.State))
+		return
+	}
+
+	now := time.Now().UTC()
+	transition := LifecycleTransition{From: lifecycle.State, To: req.State, Reason: req.Reason, At: now}
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		transition.Actor = principal.ID
+	}
+	lifecycle.LifecycleStatus = LifecycleStatus{
+		State:       req.State,
+		Reason:      req.Reason,
+		SunsetAt:    req.SunsetAt,
+		Replacement: req.Replacement,
+	}
+	lifecycle.History = append(lifecycle.History, transition)
+	lifecycle.UpdatedAt = now
+	if err := s.lifecycles.SaveLifecycle(r.Context(), lifecycle); err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	s.logger.Info("workflow lifecycle changed",
+		"workflow_id", id,
+		"from", transition.From,
+		"to", transition.To,
+		"actor", transition.Actor,
+		"reason", transition.Reason,
+	)
+	writeJSON(w, http.StatusOK, lifecycle)
+}
+
+func lifecycleAllows(from, to string) bool {
+	for _, state := range lifecycleTransitions[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
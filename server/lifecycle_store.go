@@ -0,0 +1,63 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+// Workflow lifecycle states.
+const (
+	// LifecycleDraft marks a workflow that is still being built.
+	LifecycleDraft = "draft"
+	// LifecycleActive is the state of workflows that never had one set.
+	LifecycleActive = "active"
+	// LifecycleDeprecated workflows still run, but their runs carry a
+	// deprecation warning.
+	LifecycleDeprecated = "deprecated"
+	// LifecycleArchived workflows reject new runs. Their runs, events and
+	// stats are kept.
+	LifecycleArchived = "archived"
+)
+
+// LifecycleStatus is a workflow's current lifecycle state, as listings
+// report it.
+type LifecycleStatus struct {
+	State string `json:"state"`
+	// Reason explains the state, such as why the workflow is deprecated.
+	Reason string `json:"reason,omitempty"`
+	// SunsetAt is when a deprecated workflow is planned to be archived.
+	SunsetAt *time.Time `json:"sunset_at,omitempty"`
+	// Replacement names the workflow that replaces a deprecated or
+	// archived one.
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// LifecycleTransition is one recorded change of a workflow's state.
+type LifecycleTransition struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Reason string `json:"reason,omitempty"`
+	// Actor is the principal that made the change, when the server
+	// authenticates requests.
+	Actor string    `json:"actor,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+// WorkflowLifecycle is a workflow's lifecycle state and the transitions
+// that led to it, oldest first.
+type WorkflowLifecycle struct {
+	WorkflowID string `json:"workflow_id"`
+	LifecycleStatus
+	History   []LifecycleTransition `json:"history"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// LifecycleStore persists the lifecycle of workflows. Workflows without a
+// stored lifecycle are active. Deleting a workflow deletes its lifecycle.
+type LifecycleStore interface {
+	GetLifecycle(ctx context.Context, workflowID string) (WorkflowLifecycle, bool, error)
+	// ListLifecycles returns every stored lifecycle.
+	ListLifecycles(ctx context.Context) ([]WorkflowLifecycle, error)
+	// SaveLifecycle creates or replaces the workflow's lifecycle.
+	SaveLifecycle(ctx context.Context, lifecycle WorkflowLifecycle) error
+}
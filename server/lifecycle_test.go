@@ -0,0 +1,142 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func newLifecycleTestServer(t *testing.T) (http.Handler, func() map[string]any) {
+	t.Helper()
+	store := newTestSQLiteStore(t)
+	var (
+		mu      sync.Mutex
+		started map[string]any
+	)
+	handler := NewServer(ServerConfig{
+		Store:          store,
+		LifecycleStore: store,
+		Providers:      hydrate.ProviderMap{},
+		Bus:            bus.NewMemBus(bus.MemBusConfig{}),
+		RuntimeEvents: func(e runtime.Event) {
+			if e.Kind == runtime.EventRunStarted {
+				mu.Lock()
+				started = e.Payload
+				mu.Unlock()
+			}
+		},
+	}).Handler()
+	for _, id := range []string{"greeter", "greeter-v2"} {
+		def := presetWorkflow()
+		def["id"] = id
+		if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", def); w.Code != http.StatusCreated {
+			t.Fatalf("create %s: %d %s", id, w.Code, w.Body.String())
+		}
+	}
+	return handler, func() map[string]any {
+		mu.Lock()
+		defer mu.Unlock()
+		return started
+	}
+}
+
+func TestLifecycle_DeprecateAndArchive(t *testing.T) {
+	handler, runStarted := newLifecycleTestServer(t)
+
+	w := doConditionRequest(t, handler, http.MethodGet, "/api/workflows/greeter/lifecycle", nil)
+	var lifecycle WorkflowLifecycle
+	_ = json.Unmarshal(w.Body.Bytes(), &lifecycle)
+	if w.Code != http.StatusOK || lifecycle.State != LifecycleActive || len(lifecycle.History) != 0 {
+		t.Fatalf("initial lifecycle: %d %s", w.Code, w.Body.String())
+	}
+
+	w = doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/lifecycle", map[string]any{
+		"state":       "deprecated",
+		"reason":      "moved to v2",
+		"sunset_at":   "2026-12-31T00:00:00Z",
+		"replacement": "greeter-v2",
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("deprecate: %d %s", w.Code, w.Body.String())
+	}
+
+	// Deprecated workflows still run, with a warning.
+	w = doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/run", map[string]any{"input": map[string]any{"customer": "acme"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("run deprecated: %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Deprecation") != "true" || w.Header().Get("Sunset") != "Thu, 31 Dec 2026 00:00:00 GMT" {
+		t.Errorf("deprecation headers = %v", w.Header())
+	}
+	if notice, _ := runStarted()["deprecation"].(map[string]any); notice["replacement"] != "greeter-v2" || notice["reason"] != "moved to v2" {
+		t.Errorf("run.started deprecation = %v", runStarted()["deprecation"])
+	}
+
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/workflows?lifecycle=deprecated", nil)
+	var listed []WorkflowRecord
+	_ = json.Unmarshal(w.Body.Bytes(), &listed)
+	if len(listed) != 1 || listed[0].ID != "greeter" || listed[0].Lifecycle.Replacement != "greeter-v2" {
+		t.Fatalf("deprecated listing = %s", w.Body.String())
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/lifecycle", map[string]any{"state": "archived"}); w.Code != http.StatusOK {
+		t.Fatalf("archive: %d %s", w.Code, w.Body.String())
+	}
+	w = doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/run", nil)
+	if w.Code != http.StatusConflict || lifecycleErrorCode(w) != "WORKFLOW_ARCHIVED" {
+		t.Fatalf("run archived: %d %s", w.Code, w.Body.String())
+	}
+	w = doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/run", map[string]any{"options": map[string]any{"resume_from": "any-run"}})
+	if w.Code != http.StatusConflict || lifecycleErrorCode(w) != "WORKFLOW_ARCHIVED" {
+		t.Fatalf("resume archived: %d %s", w.Code, w.Body.String())
+	}
+
+	// Archived workflows keep their record and history.
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/workflows/greeter/lifecycle", nil)
+	_ = json.Unmarshal(w.Body.Bytes(), &lifecycle)
+	if len(lifecycle.History) != 2 || lifecycle.History[0].From != LifecycleActive || lifecycle.History[1].To != LifecycleArchived {
+		t.Fatalf("history = %+v", lifecycle.History)
+	}
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/workflows/greeter", nil)
+	var rec WorkflowRecord
+	_ = json.Unmarshal(w.Body.Bytes(), &rec)
+	if w.Code != http.StatusOK || rec.Lifecycle == nil || rec.Lifecycle.State != LifecycleArchived {
+		t.Fatalf("get archived: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestLifecycle_RejectsInvalidChanges(t *testing.T) {
+	handler, _ := newLifecycleTestServer(t)
+
+	for _, tc := range []struct {
+		body   map[string]any
+		status int
+		code   string
+	}{
+		{map[string]any{"state": "retired"}, http.StatusBadRequest, "INVALID_LIFECYCLE"},
+		{map[string]any{"state": "draft"}, http.StatusConflict, "INVALID_TRANSITION"},
+		{map[string]any{"state": "active", "replacement": "greeter-v2"}, http.StatusBadRequest, "INVALID_LIFECYCLE"},
+		{map[string]any{"state": "deprecated", "replacement": "ghost"}, http.StatusBadRequest, "INVALID_LIFECYCLE"},
+		{map[string]any{"state": "deprecated", "replacement": "greeter"}, http.StatusBadRequest, "INVALID_LIFECYCLE"},
+	} {
+		w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/lifecycle", tc.body)
+		if w.Code != tc.status || lifecycleErrorCode(w) != tc.code {
+			t.Errorf("%v: %d %s, want %d %s", tc.body, w.Code, w.Body.String(), tc.status, tc.code)
+		}
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/ghost/lifecycle", map[string]any{"state": "archived"}); w.Code != http.StatusNotFound {
+		t.Errorf("unknown workflow: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func lifecycleErrorCode(w *httptest.ResponseRecorder) string {
+	var apiErr apiError
+	_ = json.Unmarshal(w.Body.Bytes(), &apiErr)
+	return apiErr.Error.Code
+}
@@ -0,0 +1,372 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/mask"
+)
+
+// AdminLoggingPath changes the log level and the debug toggles.
+const AdminLoggingPath = "/api/admin/logging"
+
+// MaxLoggingWindow bounds how long workflow debug logging and LLM traffic
+// logging stay on after one request.
+const MaxLoggingWindow = 24 * time.Hour
+
+// ErrLogLevelFixed is returned for level changes on a server without
+// ServerConfig.LogLevel.
+var ErrLogLevelFixed = errors.New("the daemon's log level cannot be changed at runtime")
+
+// LogLeveler is an adjustable log level, such as *slog.LevelVar.
+type LogLeveler interface {
+	Level() slog.Level
+	Set(slog.Level)
+}
+
+// LoggingState is the daemon's log level and the debug logging in effect.
+type LoggingState struct {
+	// Level is debug, info, warn or error. Empty when the server does not
+	// know its logger's level.
+	Level string `json:"level,omitempty"`
+	// DebugWorkflows lists the workflows whose runs log node input and
+	// output snapshots, with when that stops.
+	DebugWorkflows []DebugWorkflow `json:"debug_workflows"`
+	// LLMTrafficUntil is when LLM request and response logging stops.
+	LLMTrafficUntil *time.Time `json:"llm_traffic_until,omitempty"`
+	// UpdatedAt is set when the state changes.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// DebugWorkflow is a workflow with debug logging on.
+type DebugWorkflow struct {
+	WorkflowID string    `json:"workflow_id"`
+	Until      time.Time `json:"until"`
+}
+
+// LoggingUpdate is the body of PUT /api/admin/logging. Omitted fields are
+// left alone. Durations are Go duration strings of at most
+// MaxLoggingWindow; "0" turns the toggle off.
+type LoggingUpdate struct {
+	Level string `json:"level,omitempty"`
+	// DebugWorkflows maps workflow IDs to how long their runs log node
+	// snapshots.
+	DebugWorkflows map[string]string `json:"debug_workflows,omitempty"`
+	// LLMTraffic is how long LLM requests and responses are logged.
+	LLMTraffic string `json:"llm_traffic,omitempty"`
+}
+
+// parseLoggingWindow parses a toggle duration.
+func parseLoggingWindow(field, raw string) (time.Duration, error) {
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %v", field, err)
+	}
+	if d < 0 || d > MaxLoggingWindow {
+		return 0, fmt.Errorf("%s must be between 0 and %s, got %s", field, MaxLoggingWindow, raw)
+	}
+	return d, nil
+}
+
+// parseLogLevel accepts debug, info, warn and error, in any case.
+func parseLogLevel(raw string) (slog.Level, error) {
+	switch strings.ToLower(raw) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("level must be debug, info, warn or error, got %q", raw)
+	}
+}
+
+// loggingSwitch guards the server's debug toggles.
+type loggingSwitch struct {
+	mu         sync.RWMutex
+	level      LogLeveler
+	debugUntil map[string]time.Time
+	llmUntil   time.Time
+	updatedAt  time.Time
+}
+
+func (l *loggingSwitch) state(now time.Time) LoggingState {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	state := LoggingState{DebugWorkflows: []DebugWorkflow{}, UpdatedAt: l.updatedAt}
+	if l.level != nil {
+		state.Level = strings.ToLower(l.level.Level().String())
+	}
+	for id, until := range l.debugUntil {
+		if now.Before(until) {
+			state.DebugWorkflows = append(state.DebugWorkflows, DebugWorkflow{WorkflowID: id, Until: until})
+		}
+	}
+	sort.Slice(state.DebugWorkflows, func(i, j int) bool {
+		return state.DebugWorkflows[i].WorkflowID < state.DebugWorkflows[j].WorkflowID
+	})
+	if now.Before(l.llmUntil) {
+		until := l.llmUntil
+		state.LLMTrafficUntil = &until
+	}
+	return state
+}
+
+// update validates u in full before applying any of it.
+func (l *loggingSwitch) update(u LoggingUpdate, now time.Time) error {
+	var level slog.Level
+	if u.Level != "" {
+		var err error
+		if level, err = parseLogLevel(u.Level); err != nil {
+			return err
+		}
+		if l.level == nil {
+			return ErrLogLevelFixed
+		}
+	}
+	debug := make(map[string]time.Duration, len(u.DebugWorkflows))
+	for id, raw := range u.DebugWorkflows {
+		if strings.TrimSpace(id) == "" {
+			return fmt.Errorf("debug_workflows: workflow id is required")
+		}
+		d, err := parseLoggingWindow("debug_workflows."+id, raw)
+		if err != nil {
+			return err
+		}
+		debug[id] = d
+	}
+	var llm time.Duration
+	if u.LLMTraffic != "" {
+		var err error
+		if llm, err = parseLoggingWindow("llm_traffic", u.LLMTraffic); err != nil {
+			return err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if u.Level != "" {
+		l.level.Set(level)
+	}
+	for id, d := range debug {
+		if d == 0 {
+			delete(l.debugUntil, id)
+			continue
+		}
+		if l.debugUntil == nil {
+			l.debugUntil = make(map[string]time.Time)
+		}
+		l.debugUntil[id] = now.Add(d)
+	}
+	if u.LLMTraffic != "" {
+		l.llmUntil = now.Add(llm)
+	}
+	l.updatedAt = now.UTC()
+	return nil
+}
+
+func (l *loggingSwitch) debugging(workflowID string, now time.Time) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	until, ok := l.debugUntil[workflowID]
+	return ok && now.Before(until)
+}
+
+func (l *loggingSwitch) loggingLLMTraffic(now time.Time) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return now.Before(l.llmUntil)
+}
+
+// Logging returns the current log level and debug toggles.
+func (s *Server) Logging() LoggingState {
+	return s.logging.state(time.Now())
+}
+
+// SetLogging applies a logging update. It returns ErrLogLevelFixed for
+// level changes when the server has no ServerConfig.LogLevel.
+func (s *Server) SetLogging(u LoggingUpdate) error {
+	return s.logging.update(u, time.Now())
+}
+
+// handleGetLogging returns the log level and debug toggles.
+func (s *Server) handleGetLogging(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.Logging())
+}
+
+// handleSetLogging changes the log level and debug toggles. Like the
+// maintenance switch, they live in memory; a restart turns them off.
+func (s *Server) handleSetLogging(w http.ResponseWriter, r *http.Request) {
+	if !s.allowAdmin {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "admin API is not enabled")
+		return
+	}
+	var u LoggingUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	if err := s.SetLogging(u); err != nil {
+		if errors.Is(err, ErrLogLevelFixed) {
+			writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", err.Error())
+			return
+		}
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	state := s.Logging()
+	s.logger.Warn("logging changed", "level", state.Level, "debug_workflows", len(state.DebugWorkflows), "llm_traffic", state.LLMTrafficUntil != nil)
+	writeJSON(w, http.StatusOK, state)
+}
+
+// nodeSnapshotLogger returns the run option that logs node snapshots of a
+// workflow with debug logging on, or nil when it is off as the run starts.
+// Snapshots are masked like the run's events, and stop when the debug
+// window closes mid-run.
+func (s *Server) nodeSnapshotLogger(plan *workflowRunPlan) func(nodeID, phase string, snapshot map[string]any) {
+	if !s.logging.debugging(plan.workflowID, time.Now()) {
+		return nil
+	}
+	policy := plan.masking
+	return func(nodeID, phase string, snapshot map[string]any) {
+		if !s.logging.debugging(plan.workflowID, time.Now()) {
+			return
+		}
+		if !policy.Empty() {
+			snapshot = policy.ApplyMap(snapshot)
+		}
+		s.logger.Info("node snapshot",
+			"workflow_id", plan.workflowID,
+			"run_id", plan.env.Trace.RunID,
+			"node_id", nodeID,
+			"phase", phase,
+			"snapshot", snapshot)
+	}
+}
+
+// llmTrafficFactory wraps the clients of factory so they log requests and
+// responses while LLM traffic logging is on.
+func (s *Server) llmTrafficFactory(factory hydrate.ClientFactory) hydrate.ClientFactory {
+	return func(providerName string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+		client, err := factory(providerName, cfg)
+		if err != nil {
+			return nil, err
+		}
+		logged := &trafficLoggingClient{client: client, provider: providerName, server: s}
+		if streaming, ok := client.(core.StreamingLLMClient); ok {
+			return &trafficLoggingStreamClient{trafficLoggingClient: logged, stream: streaming}, nil
+		}
+		return logged, nil
+	}
+}
+
+// trafficLoggingClient logs the traffic of an LLM client.
+type trafficLoggingClient struct {
+	client   core.LLMClient
+	provider string
+	server   *Server
+}
+
+func (c *trafficLoggingClient) Complete(ctx context.Context, req core.LLMRequest) (core.LLMResponse, error) {
+	if !c.server.logging.loggingLLMTraffic(time.Now()) {
+		return c.client.Complete(ctx, req)
+	}
+	c.logRequest(ctx, req)
+	start := time.Now()
+	resp, err := c.client.Complete(ctx, req)
+	c.logResponse(ctx, resp.Text, resp.Usage, time.Since(start), err)
+	return resp, err
+}
+
+// logRequest logs the request, masked by the run's masking policy.
+func (c *trafficLoggingClient) logRequest(ctx context.Context, req core.LLMRequest) {
+	messages := make([]map[string]any, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		messages = append(messages, map[string]any{"role": m.Role, "content": m.Content})
+	}
+	request := map[string]any{"system": req.System, "messages": messages}
+	if req.Instructions != "" {
+		request["instructions"] = req.Instructions
+	}
+	if req.InputText != "" {
+		request["input_text"] = req.InputText
+	}
+	if policy := mask.PolicyFromContext(ctx); !policy.Empty() {
+		request = policy.ApplyMap(request)
+	}
+	c.server.logger.Info("llm request", "provider", c.provider, "model", req.Model, "request", request)
+}
+
+func (c *trafficLoggingClient) logResponse(ctx context.Context, text string, usage core.LLMTokenUsage, elapsed time.Duration, err error) {
+	if err != nil {
+		c.server.logger.Info("llm response", "provider", c.provider, "error", err.Error(), "duration_ms", elapsed.Milliseconds())
+		return
+	}
+	response := map[string]any{"text": text}
+	if policy := mask.PolicyFromContext(ctx); !policy.Empty() {
+		response = policy.ApplyMap(response)
+	}
+	c.server.logger.Info("llm response",
+		"provider", c.provider,
+		"response", response,
+		"input_tokens", usage.InputTokens,
+		"output_tokens", usage.OutputTokens,
+		"duration_ms", elapsed.Milliseconds())
+}
+
+// trafficLoggingStreamClient keeps the streaming capability of the client
+// it logs, logging the accumulated text once the stream ends.
+type trafficLoggingStreamClient struct {
+	*trafficLoggingClient
+	stream core.StreamingLLMClient
+}
+
+func (c *trafficLoggingStreamClient) CompleteStream(ctx context.Context, req core.LLMRequest) (<-chan core.StreamChunk, error) {
+	if !c.server.logging.loggingLLMTraffic(time.Now()) {
+		return c.stream.CompleteStream(ctx, req)
+	}
+	c.logRequest(ctx, req)
+	start := time.Now()
+	chunks, err := c.stream.CompleteStream(ctx, req)
+	if err != nil {
+		c.logResponse(ctx, "", core.LLMTokenUsage{}, time.Since(start), err)
+		return nil, err
+	}
+	out := make(chan core.StreamChunk)
+	go func() {
+		defer close(out)
+		var text strings.Builder
+		var usage core.LLMTokenUsage
+		var streamErr error
+		for chunk := range chunks {
+			text.WriteString(chunk.Delta)
+			if chunk.Usage != nil {
+				usage = *chunk.Usage
+			}
+			if chunk.Error != nil {
+				streamErr = chunk.Error
+			}
+			select {
+			case out <- chunk:
+			case <-ctx.Done():
+				// The reader is gone; drain so the provider can finish.
+				streamErr = ctx.Err()
+			}
+		}
+		c.logResponse(ctx, text.String(), usage, time.Since(start), streamErr)
+	}()
+	return out, nil
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+func TestAdminLogging_Level(t *testing.T) {
+	level := new(slog.LevelVar)
+	if w := serveRequest(NewServer(ServerConfig{LogLevel: level}), http.MethodPut, AdminLoggingPath, `{"level":"debug"}`); w.Code != http.StatusNotImplemented {
+		t.Fatalf("PUT level without AllowAdmin: %d, want 501", w.Code)
+	}
+	if level.Level() != slog.LevelInfo {
+		t.Fatalf("level changed without AllowAdmin: %v", level.Level())
+	}
+
+	srv := NewServer(ServerConfig{LogLevel: level, AllowAdmin: true})
+	w := serveRequest(srv, http.MethodPut, AdminLoggingPath, `{"level":"debug"}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"level":"debug"`) {
+		t.Fatalf("PUT level: %d %s", w.Code, w.Body.String())
+	}
+	if level.Level() != slog.LevelDebug {
+		t.Errorf("level = %v, want debug", level.Level())
+	}
+
+	for _, body := range []string{`{"level":"verbose"}`, `{"llm_traffic":"48h"}`, `{"debug_workflows":{"wf":"-1m"}}`} {
+		if w := serveRequest(srv, http.MethodPut, AdminLoggingPath, body); w.Code != http.StatusBadRequest {
+			t.Errorf("PUT %s: %d, want 400", body, w.Code)
+		}
+	}
+
+	fixed := NewServer(ServerConfig{AllowAdmin: true})
+	if w := serveRequest(fixed, http.MethodPut, AdminLoggingPath, `{"level":"debug","llm_traffic":"10m"}`); w.Code != http.StatusNotImplemented {
+		t.Fatalf("PUT level without LogLevel: %d, want 501", w.Code)
+	}
+	if state := fixed.Logging(); state.LLMTrafficUntil != nil {
+		t.Errorf("rejected update was partly applied: %+v", state)
+	}
+}
+
+func TestAdminLogging_DebugWorkflowAndLLMTraffic(t *testing.T) {
+	var logs bytes.Buffer
+	srv := NewServer(ServerConfig{
+		Store:     newTestSQLiteStore(t),
+		Providers: hydrate.ProviderMap{"openai": {APIKey: "k"}},
+		ClientFactory: func(name string, _ hydrate.ProviderConfig) (core.LLMClient, error) {
+			return &workflowLifecycleLLMClient{provider: name}, nil
+		},
+		Logger:     slog.New(slog.NewTextHandler(&logs, nil)),
+		AllowAdmin: true,
+	})
+	handler := srv.Handler()
+	createGraphWorkflow(t, handler, graphWorkflowPayloadFromParts("support", []map[string]any{
+		{"id": "answer", "type": "llm_prompt", "config": map[string]any{"provider": "openai", "model": "gpt-4o-mini", "prompt_template": "hello"}},
+	}, nil, "answer"))
+	run := func() {
+		t.Helper()
+		r := httptest.NewRequest(http.MethodPost, "/api/workflows/support/run", strings.NewReader(`{"input":{"ticket":"T-1"}}`))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("run: %d %s", w.Code, w.Body.String())
+		}
+	}
+
+	run()
+	if strings.Contains(logs.String(), "node snapshot") || strings.Contains(logs.String(), "llm request") {
+		t.Fatalf("debug logging before it was enabled:\n%s", logs.String())
+	}
+
+	w := serveRequest(srv, http.MethodPut, AdminLoggingPath, `{"debug_workflows":{"support":"15m"},"llm_traffic":"10m"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT: %d %s", w.Code, w.Body.String())
+	}
+	state := srv.Logging()
+	if len(state.DebugWorkflows) != 1 || state.DebugWorkflows[0].WorkflowID != "support" || state.LLMTrafficUntil == nil {
+		t.Fatalf("state = %+v", state)
+	}
+
+	logs.Reset()
+	run()
+	out := logs.String()
+	for _, want := range []string{"phase=input", "phase=output", "T-1", "llm request", "llm response", "openai::hello"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("logs missing %q:\n%s", want, out)
+		}
+	}
+
+	serveRequest(srv, http.MethodPut, AdminLoggingPath, `{"debug_workflows":{"support":"0"},"llm_traffic":"0"}`)
+	logs.Reset()
+	run()
+	if strings.Contains(logs.String(), "node snapshot") || strings.Contains(logs.String(), "llm request") {
+		t.Fatalf("debug logging after it was disabled:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,189 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AdminMaintenancePath switches read-only mode and the banner.
+const AdminMaintenancePath = "/api/admin/maintenance"
+
+// DefaultReadOnlyMessage explains rejected requests when the maintenance
+// state has no message of its own.
+const DefaultReadOnlyMessage = "the daemon is in read-only mode for maintenance"
+
+// Banner levels.
+const (
+	BannerInfo     = "info"
+	BannerWarning  = "warning"
+	BannerCritical = "critical"
+)
+
+// MaintenanceState is the daemon's read-only switch and announcement
+// banner.
+type MaintenanceState struct {
+	// ReadOnly rejects workflow, schedule and other changes and refuses to
+	// start runs. Reads, event streams and run history keep working.
+	ReadOnly bool `json:"read_only"`
+	// Message is returned with rejected requests.
+	Message string  `json:"message,omitempty"`
+	Banner  *Banner `json:"banner,omitempty"`
+	// UpdatedAt is set when the state changes.
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// Banner is an announcement for UIs, such as planned maintenance.
+type Banner struct {
+	Message string `json:"message"`
+	// Level is info (default), warning or critical.
+	Level string `json:"level,omitempty"`
+	// ExpiresAt hides the banner after this time.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Validate checks the banner.
+func (b *Banner) Validate() error {
+	if b == nil {
+		return nil
+	}
+	if strings.TrimSpace(b.Message) == "" {
+		return fmt.Errorf("banner.message is required")
+	}
+	switch b.Level {
+	case "", BannerInfo, BannerWarning, BannerCritical:
+	default:
+		return fmt.Errorf("banner.level must be info, warning or critical, got %q", b.Level)
+	}
+	return nil
+}
+
+// readOnlyMessage returns the message for rejected requests.
+func (m MaintenanceState) readOnlyMessage() string {
+	if strings.TrimSpace(m.Message) != "" {
+		return m.Message
+	}
+	return DefaultReadOnlyMessage
+}
+
+// visible drops an expired banner and fills in the default level.
+func (m MaintenanceState) visible(now time.Time) MaintenanceState {
+	if m.Banner == nil {
+		return m
+	}
+	if m.Banner.ExpiresAt != nil && !now.Before(*m.Banner.ExpiresAt) {
+		m.Banner = nil
+		return m
+	}
+	banner := *m.Banner
+	if banner.Level == "" {
+		banner.Level = BannerInfo
+	}
+	m.Banner = &banner
+	return m
+}
+
+// maintenanceSwitch guards the server's maintenance state.
+type maintenanceSwitch struct {
+	mu    sync.RWMutex
+	state MaintenanceState
+}
+
+func (m *maintenanceSwitch) get() MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+func (m *maintenanceSwitch) set(state MaintenanceState) {
+	m.mu.Lock()
+	m.state = state
+	m.mu.Unlock()
+}
+
+// Maintenance returns the current maintenance state.
+func (s *Server) Maintenance() MaintenanceState {
+	return s.maintenance.get().visible(time.Now())
+}
+
+// SetMaintenance replaces the maintenance state.
+func (s *Server) SetMaintenance(state MaintenanceState) error {
+	if err := state.Banner.Validate(); err != nil {
+		return err
+	}
+	state.UpdatedAt = time.Now().UTC()
+	s.maintenance.set(state)
+	return nil
+}
+
+// ReadOnly reports whether the daemon is in read-only mode.
+func (s *Server) ReadOnly() bool {
+	return s.maintenance.get().ReadOnly
+}
+
+func (s *Server) readOnlyError() *runAPIError {
+	return &runAPIError{
+		Status:  http.StatusServiceUnavailable,
+		Code:    "READ_ONLY",
+		Message: s.maintenance.get().readOnlyMessage(),
+	}
+}
+
+// ReadOnlyMiddleware rejects requests that change state while the daemon
+// is read-only: every method other than GET, HEAD and OPTIONS, and webhook
+// triggers of any method. Admin routes stay open so operators can restore
+// snapshots and leave read-only mode.
+func (s *Server) ReadOnlyMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.ReadOnly() || !isWriteRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		err := s.readOnlyError()
+		writeError(w, err.Status, err.Code, err.Message)
+	})
+}
+
+func isWriteRequest(r *http.Request) bool {
+	if strings.HasPrefix(r.URL.Path, "/api/admin/") {
+		return false
+	}
+	if IsWebhookRequest(r) {
+		return true
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
+
+// handleGetMaintenance returns the read-only flag and banner for UIs to
+// poll.
+func (s *Server) handleGetMaintenance(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.Maintenance())
+}
+
+// handleSetMaintenance replaces the maintenance state. The state lives in
+// memory; a restart returns to the configured state.
+func (s *Server) handleSetMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !s.allowAdmin {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "admin API is not enabled")
+		return
+	}
+	var state MaintenanceState
+	if err := json.NewDecoder(r.Body).Decode(&state); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	if err := s.SetMaintenance(state); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	s.logger.Info("maintenance state changed", "read_only", state.ReadOnly, "banner", state.Banner != nil)
+	writeJSON(w, http.StatusOK, s.Maintenance())
+}
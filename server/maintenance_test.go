@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serveRequest(srv *Server, method, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return w
+}
+
+func TestReadOnlyMode(t *testing.T) {
+	srv := testServer(t)
+	if err := srv.SetMaintenance(MaintenanceState{ReadOnly: true, Message: "migrating to v2, back at 10:00"}); err != nil {
+		t.Fatalf("SetMaintenance: %v", err)
+	}
+
+	w := serveRequest(srv, http.MethodPost, "/api/workflows/graph", `{"id":"wf","version":"1.0","nodes":[],"edges":[]}`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("create status = %d, want 503", w.Code)
+	}
+	var apiErr apiError
+	if err := json.Unmarshal(w.Body.Bytes(), &apiErr); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if apiErr.Error.Code != "READ_ONLY" || apiErr.Error.Message != "migrating to v2, back at 10:00" {
+		t.Fatalf("error = %+v", apiErr.Error)
+	}
+
+	if w := serveRequest(srv, http.MethodGet, "/api/workflows/wf/webhooks/hook", ""); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("webhook GET status = %d, want 503", w.Code)
+	}
+	if w := serveRequest(srv, http.MethodPut, "/api/workflows/webhooks", `{}`); w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("update of workflow \"webhooks\" status = %d, want 503", w.Code)
+	}
+	if w := serveRequest(srv, http.MethodGet, "/api/workflows", ""); w.Code != http.StatusOK {
+		t.Fatalf("list status = %d, want 200", w.Code)
+	}
+	if w := serveRequest(srv, http.MethodGet, "/api/runs", ""); w.Code != http.StatusOK {
+		t.Fatalf("runs status = %d, want 200", w.Code)
+	}
+
+	_, err := srv.admitRun(context.Background(), RunClassScheduled, RunHints{})
+	var runErr *runAPIError
+	if !errors.As(err, &runErr) || runErr.Code != "READ_ONLY" {
+		t.Fatalf("admitRun err = %v, want READ_ONLY", err)
+	}
+
+	w = serveRequest(srv, http.MethodPut, AdminMaintenancePath, `{"read_only":false}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("leave read-only status = %d, body = %s", w.Code, w.Body.String())
+	}
+	if srv.ReadOnly() {
+		t.Fatal("server still read-only")
+	}
+	if w := serveRequest(srv, http.MethodPost, "/api/workflows/graph", `{"id":"wf","version":"1.0","nodes":[{"id":"a","type":"noop"}],"edges":[],"entry":"a"}`); w.Code == http.StatusServiceUnavailable {
+		t.Fatalf("create after maintenance still rejected: %s", w.Body.String())
+	}
+}
+
+func TestReadOnlyMode_DefaultMessage(t *testing.T) {
+	srv := testServer(t)
+	_ = srv.SetMaintenance(MaintenanceState{ReadOnly: true})
+	w := serveRequest(srv, http.MethodDelete, "/api/workflows/wf", "")
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), DefaultReadOnlyMessage) {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
+
+func TestMaintenanceBanner(t *testing.T) {
+	srv := testServer(t)
+	w := serveRequest(srv, http.MethodPut, AdminMaintenancePath, `{"read_only":true,"banner":{"message":"Upgrade tonight"}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var health healthResponse
+	if err := json.Unmarshal(serveRequest(srv, http.MethodGet, "/health", "").Body.Bytes(), &health); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if health.Status != "ok" || !health.ReadOnly || health.Banner == nil || health.Banner.Level != BannerInfo {
+		t.Fatalf("health = %+v", health)
+	}
+
+	var state MaintenanceState
+	if err := json.Unmarshal(serveRequest(srv, http.MethodGet, "/api/maintenance", "").Body.Bytes(), &state); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if !state.ReadOnly || state.Banner == nil || state.Banner.Message != "Upgrade tonight" || state.UpdatedAt.IsZero() {
+		t.Fatalf("state = %+v", state)
+	}
+
+	expired := time.Now().Add(-time.Minute)
+	_ = srv.SetMaintenance(MaintenanceState{Banner: &Banner{Message: "old news", ExpiresAt: &expired}})
+	if srv.Maintenance().Banner != nil {
+		t.Fatal("expired banner still visible")
+	}
+
+	if w := serveRequest(srv, http.MethodPut, AdminMaintenancePath, `{"banner":{"message":"x","level":"loud"}}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid level status = %d, want 400", w.Code)
+	}
+}
+
+func TestSetMaintenance_RequiresAllowAdmin(t *testing.T) {
+	srv := NewServer(ServerConfig{})
+	if w := serveRequest(srv, http.MethodPut, AdminMaintenancePath, `{"read_only":true}`); w.Code != http.StatusNotImplemented {
+		t.Fatalf("status = %d, want 501", w.Code)
+	}
+	if srv.ReadOnly() {
+		t.Fatal("maintenance changed without allow_admin")
+	}
+}
+
+func TestWorkflowScheduler_PausedWhileReadOnly(t *testing.T) {
+	srv := testServer(t)
+	createWorkflowForScheduler(t, srv.Handler(), "paused")
+	_ = srv.SetMaintenance(MaintenanceState{ReadOnly: true})
+	store := srv.scheduleStore
+	now := time.Now().UTC()
+	if err := store.CreateSchedule(context.Background(), WorkflowSchedule{
+		ID:         "sched-paused",
+		WorkflowID: "paused",
+		Cron:       "* * * * *",
+		Enabled:    true,
+		NextRunAt:  now.Add(-time.Minute),
+		CreatedAt:  now.Add(-time.Hour),
+		UpdatedAt:  now.Add(-time.Hour),
+	}); err != nil {
+		t.Fatalf("CreateSchedule: %v", err)
+	}
+
+	scheduler, err := NewWorkflowScheduler(WorkflowSchedulerConfig{Runner: srv, Store: store, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("NewWorkflowScheduler: %v", err)
+	}
+	if err := scheduler.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	got, ok, err := store.GetSchedule(context.Background(), "paused", "sched-paused")
+	if err != nil || !ok {
+		t.Fatalf("GetSchedule: ok=%v err=%v", ok, err)
+	}
+	if !got.NextRunAt.Equal(now.Add(-time.Minute)) || got.LastRunAt != nil {
+		t.Fatalf("schedule advanced while read-only: %+v", got)
+	}
+}
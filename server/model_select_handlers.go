@@ -0,0 +1,101 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/nodes"
+)
+
+// ModelSelectStats is the response of GET /api/workflows/{id}/model-select.
+type ModelSelectStats struct {
+	WorkflowID string                 `json:"workflow_id"`
+	Nodes      []ModelSelectNodeStats `json:"nodes"`
+}
+
+// ModelSelectNodeStats are the arm statistics of one model_select node.
+type ModelSelectNodeStats struct {
+	NodeID string          `json:"node_id"`
+	Key    string          `json:"key"`
+	Policy string          `json:"policy"`
+	Arms   []ArmStatsEntry `json:"arms"`
+}
+
+// ArmStatsEntry is an arm's statistics with its mean reward.
+type ArmStatsEntry struct {
+	nodes.ArmStats
+	MeanReward float64 `json:"mean_reward"`
+}
+
+// RewardRequest is the body of POST /api/model-selections/{selection_id}/reward.
+type RewardRequest struct {
+	Reward *float64 `json:"reward"`
+}
+
+func (s *Server) handleModelSelectStats(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec, found, err := s.store.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+		return
+	}
+
+	resp := ModelSelectStats{WorkflowID: id, Nodes: []ModelSelectNodeStats{}}
+	if rec.Compiled != nil {
+		for _, nd := range rec.Compiled.Nodes {
+			if nd.Type != "model_select" {
+				continue
+			}
+			key := hydrate.ArmStatsKey(nd, id)
+			stats, err := s.armStats.ArmStats(r.Context(), key)
+			if err != nil {
+				writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+				return
+			}
+			policy, _ := nd.Config["policy"].(string)
+			if policy == "" {
+				policy = nodes.BanditEpsilonGreedy
+			}
+			entry := ModelSelectNodeStats{NodeID: nd.ID, Key: key, Policy: policy, Arms: make([]ArmStatsEntry, len(stats))}
+			for i, st := range stats {
+				entry.Arms[i] = ArmStatsEntry{ArmStats: st, MeanReward: st.MeanReward()}
+			}
+			resp.Nodes = append(resp.Nodes, entry)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleModelSelectionReward(w http.ResponseWriter, r *http.Request) {
+	var req RewardRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
+		return
+	}
+	if req.Reward == nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REWARD", "reward is required")
+		return
+	}
+	if err := nodes.ValidateReward(*req.Reward); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REWARD", err.Error())
+		return
+	}
+
+	sel, err := s.armStats.RecordReward(r.Context(), r.PathValue("selection_id"), *req.Reward)
+	switch {
+	case errors.Is(err, nodes.ErrSelectionNotFound):
+		writeError(w, http.StatusNotFound, "NOT_FOUND", err.Error())
+	case errors.Is(err, nodes.ErrSelectionRewarded):
+		writeError(w, http.StatusConflict, "ALREADY_REWARDED", err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+	default:
+		writeJSON(w, http.StatusOK, sel)
+	}
+}
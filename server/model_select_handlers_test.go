@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+func TestModelSelect_StatsAndRewardEndpoints(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	handler := NewServer(ServerConfig{
+		Store:     store,
+		ArmStats:  store,
+		Providers: hydrate.ProviderMap{"openai": {APIKey: "sk-test"}},
+		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+			return &workflowLifecycleLLMClient{provider: name}, nil
+		},
+	}).Handler()
+
+	gd := map[string]any{
+		"id":      "picker",
+		"version": "1.0",
+		"nodes": []map[string]any{{
+			"id":   "pick",
+			"type": "model_select",
+			"config": map[string]any{
+				"arms":            []any{map[string]any{"provider": "openai", "model": "gpt-4o-mini"}},
+				"prompt_template": "hello",
+			},
+		}},
+		"edges": []map[string]any{},
+		"entry": "pick",
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", gd); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/picker/run", bytes.NewReader([]byte(`{}`)))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("run: %d %s", w.Code, w.Body.String())
+	}
+	var run RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &run); err != nil {
+		t.Fatalf("decode run: %v", err)
+	}
+	sel, _ := run.Output.Vars["pick_output_selection"].(map[string]any)
+	selectionID, _ := sel["selection_id"].(string)
+	if selectionID == "" || sel["arm"] != "openai/gpt-4o-mini" {
+		t.Fatalf("selection = %v", run.Output.Vars["pick_output_selection"])
+	}
+
+	path := "/api/model-selections/" + selectionID + "/reward"
+	if w := doConditionRequest(t, handler, http.MethodPost, path, map[string]any{"reward": 2}); w.Code != http.StatusBadRequest {
+		t.Errorf("out of range reward: %d %s", w.Code, w.Body.String())
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, path, map[string]any{}); w.Code != http.StatusBadRequest {
+		t.Errorf("missing reward: %d %s", w.Code, w.Body.String())
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, path, map[string]any{"reward": 0.5}); w.Code != http.StatusOK {
+		t.Fatalf("reward: %d %s", w.Code, w.Body.String())
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, path, map[string]any{"reward": 1}); w.Code != http.StatusConflict {
+		t.Errorf("second reward: %d %s", w.Code, w.Body.String())
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/model-selections/nope/reward", map[string]any{"reward": 1}); w.Code != http.StatusNotFound {
+		t.Errorf("unknown selection: %d %s", w.Code, w.Body.String())
+	}
+
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/workflows/picker/model-select", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("stats: %d %s", w.Code, w.Body.String())
+	}
+	var stats ModelSelectStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	if len(stats.Nodes) != 1 || stats.Nodes[0].Key != "picker/pick" || stats.Nodes[0].Policy != "epsilon_greedy" || len(stats.Nodes[0].Arms) != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	arm := stats.Nodes[0].Arms[0]
+	if arm.Arm != "openai/gpt-4o-mini" || arm.Pulls != 1 || arm.Rewards != 1 || arm.MeanReward != 0.5 {
+		t.Errorf("arm = %+v", arm)
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodGet, "/api/workflows/missing/model-select", nil); w.Code != http.StatusNotFound {
+		t.Errorf("missing workflow: %d", w.Code)
+	}
+}
@@ -0,0 +1,414 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+const (
+	defaultOutboxPollInterval   = 2 * time.Second
+	defaultOutboxBatchLimit     = 50
+	defaultOutboxMaxAttempts    = 8
+	defaultOutboxBackoff        = 5 * time.Second
+	defaultOutboxMaxBackoff     = 10 * time.Minute
+	defaultOutboxRequestTimeout = 30 * time.Second
+)
+
+// ErrDeliveryNotFound is returned when no outbox delivery has the
+// requested ID.
+var ErrDeliveryNotFound = errors.New("delivery not found")
+
+// OutboxStore keeps the outbound requests of webhook_call nodes with
+// outbox delivery until an OutboxWorker sends them.
+type OutboxStore interface {
+	nodes.Outbox
+	// ClaimDueDeliveries takes up to limit pending deliveries due at now,
+	// counts an attempt on each and moves their next attempt to
+	// leaseUntil, so an attempt cut short by a crash is retried then.
+	// Concurrent claims never return the same attempt.
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]nodes.OutboxDelivery, error)
+	// FinishDeliveryAttempt stores the outcome of d's attempt: its Status,
+	// StatusCode, LastError, NextAttemptAt and DeliveredAt. It changes
+	// nothing and returns false when d.Attempts is no longer the
+	// delivery's latest attempt.
+	FinishDeliveryAttempt(ctx context.Context, d nodes.OutboxDelivery) (bool, error)
+	// ListDeliveries returns a run's deliveries in the order they were
+	// made.
+	ListDeliveries(ctx context.Context, runID string) ([]nodes.OutboxDelivery, error)
+	GetDelivery(ctx context.Context, id string) (nodes.OutboxDelivery, bool, error)
+	// RetryDelivery makes a failed delivery pending again, due at now and
+	// with its attempts reset. It returns false when the delivery is not
+	// failed and ErrDeliveryNotFound when there is none.
+	RetryDelivery(ctx context.Context, id string, now time.Time) (nodes.OutboxDelivery, bool, error)
+}
+
+// runOutbox is the outbox of one run. It encrypts the header values of
+// deliveries before they are stored, since they may hold resolved workflow
+// secrets. A run that resumes an earlier one reuses the deliveries that
+// run made, so a webhook_call node cut short after recording its request
+// does not deliver it twice.
+type runOutbox struct {
+	store      OutboxStore
+	secrets    hydrate.SecretCodec
+	resumeFrom string
+}
+
+// EnqueueDelivery implements nodes.Outbox.
+func (o runOutbox) EnqueueDelivery(ctx context.Context, d nodes.OutboxDelivery) (nodes.OutboxDelivery, error) {
+	if o.resumeFrom != "" {
+		earlier, ok, err := o.store.GetDelivery(ctx, nodes.OutboxDeliveryID(o.resumeFrom, d.NodeID, []byte(d.Body)))
+		if err != nil {
+			return nodes.OutboxDelivery{}, err
+		}
+		if ok {
+			return earlier, nil
+		}
+	}
+	if o.secrets != nil && len(d.Headers) > 0 {
+		sealed := make(map[string]string, len(d.Headers))
+		for key, value := range d.Headers {
+			enc, err := o.secrets.Encrypt(value)
+			if err != nil {
+				return nodes.OutboxDelivery{}, fmt.Errorf("sealing header %s: %w", key, err)
+			}
+			sealed[key] = enc
+		}
+		d.Headers = sealed
+	}
+	return o.store.EnqueueDelivery(ctx, d)
+}
+
+// OutboxWorkerConfig configures the background outbox delivery worker.
+type OutboxWorkerConfig struct {
+	Runner     *Server
+	Store      OutboxStore
+	HTTPClient nodes.HTTPClient
+	// PollInterval is how often due deliveries are claimed.
+	PollInterval time.Duration
+	// RequestTimeout bounds each attempt.
+	RequestTimeout time.Duration
+	// MaxAttempts is how many attempts a delivery gets before it fails.
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt. It doubles with
+	// each further attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	BatchLimit int
+	Now        func() time.Time
+	Logger     *slog.Logger
+}
+
+// OutboxWorker sends the deliveries recorded by webhook_call nodes with
+// outbox delivery. Each attempt is claimed in the store first, so one
+// worker sends it; requests carry the delivery ID as their Idempotency-Key
+// so receivers can drop the repeat of an attempt whose response was lost.
+// Network errors, 408, 425, 429 and 5xx responses are retried with
+// backoff; other responses fail the delivery. Delivered and failed
+// deliveries get a delivery.finished event on the run that made them.
+type OutboxWorker struct {
+	runner         *Server
+	store          OutboxStore
+	client         nodes.HTTPClient
+	pollInterval   time.Duration
+	requestTimeout time.Duration
+	maxAttempts    int
+	backoff        time.Duration
+	maxBackoff     time.Duration
+	batchLimit     int
+	now            func() time.Time
+	logger         *slog.Logger
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewOutboxWorker creates an outbox worker instance.
+func NewOutboxWorker(cfg OutboxWorkerConfig) (*OutboxWorker, error) {
+	if cfg.Runner == nil {
+		return nil, errors.New("outbox worker runner is nil")
+	}
+	if cfg.Store == nil {
+		return nil, errors.New("outbox worker store is nil")
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = defaultOutboxPollInterval
+	}
+	if cfg.RequestTimeout <= 0 {
+		cfg.RequestTimeout = defaultOutboxRequestTimeout
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = defaultOutboxMaxAttempts
+	}
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultOutboxBackoff
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = defaultOutboxMaxBackoff
+	}
+	if cfg.BatchLimit <= 0 {
+		cfg.BatchLimit = defaultOutboxBatchLimit
+	}
+	if cfg.Now == nil {
+		cfg.Now = func() time.Time { return time.Now().UTC() }
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	return &OutboxWorker{
+		runner:         cfg.Runner,
+		store:          cfg.Store,
+		client:         cfg.HTTPClient,
+		pollInterval:   cfg.PollInterval,
+		requestTimeout: cfg.RequestTimeout,
+		maxAttempts:    cfg.MaxAttempts,
+		backoff:        cfg.Backoff,
+		maxBackoff:     cfg.MaxBackoff,
+		batchLimit:     cfg.BatchLimit,
+		now:            cfg.Now,
+		logger:         cfg.Logger,
+	}, nil
+}
+
+// Start starts background delivery.
+func (w *OutboxWorker) Start(ctx context.Context) error {
+	if w == nil {
+		return errors.New("outbox worker is nil")
+	}
+
+	w.mu.Lock()
+	if w.cancel != nil {
+		w.mu.Unlock()
+		return nil
+	}
+	loopCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	w.cancel = cancel
+	w.done = done
+	w.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		_ = w.RunOnce(loopCtx)
+		ticker := time.NewTicker(w.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-loopCtx.Done():
+				return
+			case <-ticker.C:
+				_ = w.RunOnce(loopCtx)
+			}
+		}
+	}()
+
+	_ = ctx
+	return nil
+}
+
+// Stop stops background delivery and waits for attempts in flight.
+func (w *OutboxWorker) Stop(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	cancel := w.cancel
+	done := w.done
+	w.cancel = nil
+	w.done = nil
+	w.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunOnce claims the deliveries due now and attempts them concurrently.
+func (w *OutboxWorker) RunOnce(ctx context.Context) error {
+	if w == nil || w.store == nil || w.runner == nil {
+		return errors.New("outbox worker is not configured")
+	}
+	if w.runner.ReadOnly() {
+		// Deliveries wait until read-only mode ends.
+		return nil
+	}
+
+	now := w.now().UTC()
+	// The lease outlasts the attempt, so only a crash lets it lapse.
+	claimed, err := w.store.ClaimDueDeliveries(ctx, now, now.Add(2*w.requestTimeout), w.batchLimit)
+	if err != nil {
+		w.logger.Error("claim outbox deliveries", "error", err)
+		return err
+	}
+	var wg sync.WaitGroup
+	for _, d := range claimed {
+		wg.Add(1)
+		go func(d nodes.OutboxDelivery) {
+			defer wg.Done()
+			w.attempt(ctx, d)
+		}(d)
+	}
+	wg.Wait()
+	return nil
+}
+
+// attempt sends d once and records the outcome.
+func (w *OutboxWorker) attempt(ctx context.Context, d nodes.OutboxDelivery) {
+	statusCode, err := w.send(ctx, d)
+	// The outcome is recorded even when Stop cut the attempt short.
+	ctx = context.WithoutCancel(ctx)
+	now := w.now().UTC()
+	d.StatusCode = statusCode
+	d.LastError = ""
+	switch {
+	case err == nil:
+		d.Status = nodes.DeliveryDelivered
+		d.DeliveredAt = &now
+	case !retryableDelivery(statusCode) || d.Attempts >= w.maxAttempts:
+		d.Status = nodes.DeliveryFailed
+		d.LastError = err.Error()
+	default:
+		d.Status = nodes.DeliveryPending
+		d.LastError = err.Error()
+		d.NextAttemptAt = now.Add(w.retryDelay(d.Attempts))
+	}
+
+	current, err := w.store.FinishDeliveryAttempt(ctx, d)
+	if err != nil {
+		w.logger.Error("record outbox delivery attempt", "delivery_id", d.ID, "run_id", d.RunID, "error", err)
+		return
+	}
+	if !current {
+		// The attempt outlived its lease and was claimed again.
+		return
+	}
+
+	switch d.Status {
+	case nodes.DeliveryPending:
+		w.logger.Warn("outbox delivery attempt failed", "delivery_id", d.ID, "run_id", d.RunID, "node_id", d.NodeID,
+			"attempt", d.Attempts, "next_attempt_at", d.NextAttemptAt, "error", d.LastError)
+		return
+	case nodes.DeliveryFailed:
+		w.logger.Error("outbox delivery failed", "delivery_id", d.ID, "run_id", d.RunID, "node_id", d.NodeID,
+			"attempts", d.Attempts, "error", d.LastError)
+	}
+	if err := w.recordFinished(ctx, d); err != nil {
+		w.logger.Error("record outbox delivery event", "delivery_id", d.ID, "run_id", d.RunID, "error", err)
+	}
+}
+
+// send makes one attempt, returning the response status, if any, and an
+// error unless it got a 2xx response.
+func (w *OutboxWorker) send(ctx context.Context, d nodes.OutboxDelivery) (int, error) {
+	reqCtx, cancel := context.WithTimeout(ctx, w.requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(reqCtx, d.Method, d.URL, strings.NewReader(d.Body))
+	if err != nil {
+		return 0, fmt.Errorf("build request: %w", err)
+	}
+	for key, value := range d.Headers {
+		if w.runner.secrets != nil {
+			if value, err = w.runner.secrets.Decrypt(value); err != nil {
+				return 0, fmt.Errorf("unsealing header %s: %w", key, err)
+			}
+		}
+		req.Header.Set(key, value)
+	}
+	if req.Header.Get("Idempotency-Key") == "" {
+		req.Header.Set("Idempotency-Key", d.ID)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if body = bytes.TrimSpace(body); len(body) > 0 {
+			return resp.StatusCode, fmt.Errorf("unexpected status code: %d: %s", resp.StatusCode, body)
+		}
+		return resp.StatusCode, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryableDelivery reports whether an attempt that got statusCode (0 for
+// none) may succeed when repeated.
+func retryableDelivery(statusCode int) bool {
+	switch {
+	case statusCode == 0, statusCode >= 500:
+		return true
+	case statusCode == http.StatusRequestTimeout, statusCode == http.StatusTooEarly, statusCode == http.StatusTooManyRequests:
+		return true
+	}
+	return false
+}
+
+// retryDelay is the wait after failed attempt number attempts.
+func (w *OutboxWorker) retryDelay(attempts int) time.Duration {
+	delay := w.backoff
+	for i := 1; i < attempts && delay < w.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, w.maxBackoff)
+}
+
+// recordFinished appends a delivery.finished event to the run's history.
+func (w *OutboxWorker) recordFinished(ctx context.Context, d nodes.OutboxDelivery) error {
+	event := runtime.NewEvent(runtime.EventDeliveryFinished, d.RunID).
+		WithSchemaVersion().
+		WithNode(d.NodeID, core.NodeKindWebhookCall).
+		WithPayload("delivery_id", d.ID).
+		WithPayload("status", d.Status).
+		WithPayload("attempts", d.Attempts)
+	if d.StatusCode != 0 {
+		event = event.WithPayload("status_code", d.StatusCode)
+	}
+	if d.LastError != "" {
+		event = event.WithPayload("error", d.LastError)
+	}
+
+	if store := w.runner.eventStore; store != nil {
+		seq, err := store.LatestSeq(ctx, d.RunID)
+		if err != nil {
+			return err
+		}
+		event.Seq = seq + 1
+		if err := store.Append(ctx, event); err != nil {
+			return err
+		}
+	}
+	if w.runner.bus != nil {
+		w.runner.bus.Publish(event)
+	}
+	return nil
+}
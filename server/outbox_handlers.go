@@ -0,0 +1,82 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/tool"
+)
+
+// handleListRunDeliveries returns the outbox deliveries a run made.
+func (s *Server) handleListRunDeliveries(w http.ResponseWriter, r *http.Request) {
+	if s.outbox == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "outbox delivery is not configured")
+		return
+	}
+	deliveries, err := s.outbox.ListDeliveries(r.Context(), r.PathValue("run_id"))
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	for i := range deliveries {
+		deliveries[i] = redactDelivery(deliveries[i])
+	}
+	writeJSON(w, http.StatusOK, deliveries)
+}
+
+// handleGetDelivery returns one outbox delivery.
+func (s *Server) handleGetDelivery(w http.ResponseWriter, r *http.Request) {
+	if s.outbox == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "outbox delivery is not configured")
+		return
+	}
+	id := r.PathValue("delivery_id")
+	d, ok, err := s.outbox.GetDelivery(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "DELIVERY_NOT_FOUND", "delivery "+id+" not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, redactDelivery(d))
+}
+
+// handleRetryDelivery gives a failed outbox delivery a fresh set of
+// attempts, starting now.
+func (s *Server) handleRetryDelivery(w http.ResponseWriter, r *http.Request) {
+	if s.outbox == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "outbox delivery is not configured")
+		return
+	}
+	id := r.PathValue("delivery_id")
+	d, retried, err := s.outbox.RetryDelivery(r.Context(), id, time.Now().UTC())
+	switch {
+	case errors.Is(err, ErrDeliveryNotFound):
+		writeError(w, http.StatusNotFound, "DELIVERY_NOT_FOUND", "delivery "+id+" not found")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	case !retried:
+		writeError(w, http.StatusConflict, "DELIVERY_NOT_FAILED", "delivery "+id+" is "+d.Status+"; only failed deliveries can be retried")
+		return
+	}
+	writeJSON(w, http.StatusOK, redactDelivery(d))
+}
+
+// redactDelivery masks header values, which may hold secrets.
+func redactDelivery(d nodes.OutboxDelivery) nodes.OutboxDelivery {
+	if len(d.Headers) == 0 {
+		return d
+	}
+	headers := make(map[string]string, len(d.Headers))
+	for key := range d.Headers {
+		headers[key] = tool.MaskedSecretValue
+	}
+	d.Headers = headers
+	return d
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/tool"
+)
+
+func TestOutboxHandlers(t *testing.T) {
+	rcv := &outboxReceiver{}
+	target := httptest.NewServer(rcv)
+	defer target.Close()
+	f := newOutboxFixture(t, 3)
+	handler := f.server.Handler()
+
+	workflow := map[string]any{
+		"id":      "notifier",
+		"version": "1.0",
+		"nodes": []map[string]any{{
+			"id":   "notify",
+			"type": "webhook_call",
+			"config": map[string]any{
+				"url":        target.URL + "/orders",
+				"delivery":   "outbox",
+				"headers":    map[string]any{"X-Api-Key": "k3y"},
+				"result_var": "notified",
+				"idempotent": true,
+			},
+		}},
+		"entry": "notify",
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", workflow); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/notifier/run", map[string]any{"input": map[string]any{"order_id": "ord_1"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("run: %d %s", w.Code, w.Body.String())
+	}
+	var run RunResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &run)
+	if rcv.count() != 0 {
+		t.Fatalf("the run delivered %d requests itself, want 0", rcv.count())
+	}
+	notified, _ := run.Output.Vars["notified"].(map[string]any)
+	if notified["queued"] != true {
+		t.Fatalf("notified = %v", run.Output.Vars["notified"])
+	}
+
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/runs/"+run.RunID+"/deliveries", nil)
+	var deliveries []nodes.OutboxDelivery
+	if err := json.Unmarshal(w.Body.Bytes(), &deliveries); err != nil || w.Code != http.StatusOK || len(deliveries) != 1 {
+		t.Fatalf("list deliveries: %d %s", w.Code, w.Body.String())
+	}
+	d := deliveries[0]
+	if d.ID != notified["delivery_id"] || d.Status != nodes.DeliveryPending || d.NodeID != "notify" {
+		t.Fatalf("delivery = %+v", d)
+	}
+	if d.Headers["X-Api-Key"] != tool.MaskedSecretValue {
+		t.Fatalf("listed X-Api-Key header = %q, want it masked", d.Headers["X-Api-Key"])
+	}
+
+	f.now = d.NextAttemptAt
+	if err := f.worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if rcv.count() != 1 || rcv.requests[0].Header.Get("X-Api-Key") != "k3y" {
+		t.Fatalf("receiver got %d requests", rcv.count())
+	}
+
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/deliveries/"+d.ID, nil)
+	var got nodes.OutboxDelivery
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || got.Status != nodes.DeliveryDelivered {
+		t.Fatalf("get delivery: %d %s", w.Code, w.Body.String())
+	}
+
+	w = doConditionRequest(t, handler, http.MethodPost, "/api/deliveries/"+d.ID+"/retry", nil)
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "DELIVERY_NOT_FAILED") {
+		t.Fatalf("retry delivered: %d %s", w.Code, w.Body.String())
+	}
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/api/deliveries/dlv_missing"},
+		{http.MethodPost, "/api/deliveries/dlv_missing/retry"},
+	} {
+		if w := doConditionRequest(t, handler, req.method, req.path, nil); w.Code != http.StatusNotFound {
+			t.Fatalf("%s %s: %d %s", req.method, req.path, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestOutboxHandlers_RetryFailedDelivery(t *testing.T) {
+	rcv := &outboxReceiver{statuses: []int{http.StatusBadRequest}}
+	target := httptest.NewServer(rcv)
+	defer target.Close()
+	f := newOutboxFixture(t, 3)
+	d := f.enqueue(t, target.URL)
+	_ = f.worker.RunOnce(context.Background())
+
+	w := doConditionRequest(t, f.server.Handler(), http.MethodPost, "/api/deliveries/"+d.ID+"/retry", nil)
+	var got nodes.OutboxDelivery
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil || w.Code != http.StatusOK || got.Status != nodes.DeliveryPending || got.Attempts != 0 {
+		t.Fatalf("retry: %d %s", w.Code, w.Body.String())
+	}
+	f.now = got.NextAttemptAt
+	_ = f.worker.RunOnce(context.Background())
+	if got := f.delivery(t, d.ID); got.Status != nodes.DeliveryDelivered {
+		t.Fatalf("retried delivery = %+v", got)
+	}
+}
+
+func TestOutboxHandlers_NotConfigured(t *testing.T) {
+	handler := NewServer(ServerConfig{Store: newTestSQLiteStore(t), Providers: hydrate.ProviderMap{}}).Handler()
+	if w := doConditionRequest(t, handler, http.MethodGet, "/api/runs/run-1/deliveries", nil); w.Code != http.StatusNotImplemented {
+		t.Fatalf("list without outbox: %d %s", w.Code, w.Body.String())
+	}
+}
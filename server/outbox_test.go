@@ -0,0 +1,269 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// outboxReceiver answers deliveries with the queued status codes, then
+// 200, recording each request.
+type outboxReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func (rcv *outboxReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.requests = append(rcv.requests, r)
+	rcv.bodies = append(rcv.bodies, string(body))
+	status := http.StatusOK
+	if len(rcv.statuses) > 0 {
+		status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rcv *outboxReceiver) count() int {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return len(rcv.requests)
+}
+
+type outboxFixture struct {
+	server *Server
+	store  *SQLiteStore
+	events bus.EventStore
+	worker *OutboxWorker
+	now    time.Time
+}
+
+func newOutboxFixture(t *testing.T, maxAttempts int) *outboxFixture {
+	t.Helper()
+	f := &outboxFixture{store: newTestSQLiteStore(t), events: newTestEventStore(t), now: time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)}
+	f.server = NewServer(ServerConfig{
+		Store:      f.store,
+		Outbox:     f.store,
+		EventStore: f.events,
+		Bus:        bus.NewMemBus(bus.MemBusConfig{}),
+		Providers:  hydrate.ProviderMap{},
+	})
+	worker, err := NewOutboxWorker(OutboxWorkerConfig{
+		Runner:      f.server,
+		Store:       f.store,
+		MaxAttempts: maxAttempts,
+		Backoff:     time.Second,
+		MaxBackoff:  time.Minute,
+		Now:         func() time.Time { return f.now },
+	})
+	if err != nil {
+		t.Fatalf("NewOutboxWorker: %v", err)
+	}
+	f.worker = worker
+	return f
+}
+
+func (f *outboxFixture) enqueue(t *testing.T, url string) nodes.OutboxDelivery {
+	t.Helper()
+	outbox := runOutbox{store: f.store, secrets: f.server.secrets}
+	body := `{"order_id":"ord_123"}`
+	d, err := outbox.EnqueueDelivery(context.Background(), nodes.OutboxDelivery{
+		ID:      nodes.OutboxDeliveryID("run-1", "notify", []byte(body)),
+		RunID:   "run-1",
+		NodeID:  "notify",
+		Method:  http.MethodPost,
+		URL:     url,
+		Headers: map[string]string{"Authorization": "Bearer t0k", "Content-Type": "application/json"},
+		Body:    body,
+	})
+	if err != nil {
+		t.Fatalf("EnqueueDelivery: %v", err)
+	}
+	// Claims compare against the fixture clock, not the enqueue time.
+	f.now = time.Now().UTC()
+	return d
+}
+
+func (f *outboxFixture) delivery(t *testing.T, id string) nodes.OutboxDelivery {
+	t.Helper()
+	d, ok, err := f.store.GetDelivery(context.Background(), id)
+	if err != nil || !ok {
+		t.Fatalf("GetDelivery(%s) = %v, %v", id, ok, err)
+	}
+	return d
+}
+
+func TestOutboxWorker_Delivers(t *testing.T) {
+	rcv := &outboxReceiver{}
+	target := httptest.NewServer(rcv)
+	defer target.Close()
+	f := newOutboxFixture(t, 3)
+
+	d := f.enqueue(t, target.URL+"/hook")
+	if !strings.HasPrefix(d.Headers["Authorization"], "enc:") {
+		t.Fatalf("stored Authorization header = %q, want it sealed", d.Headers["Authorization"])
+	}
+
+	if err := f.worker.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if rcv.count() != 1 {
+		t.Fatalf("receiver got %d requests, want 1", rcv.count())
+	}
+	req := rcv.requests[0]
+	if req.Header.Get("Authorization") != "Bearer t0k" || req.Header.Get("Idempotency-Key") != d.ID || rcv.bodies[0] != `{"order_id":"ord_123"}` {
+		t.Fatalf("request headers = %v, body = %s", req.Header, rcv.bodies[0])
+	}
+
+	got := f.delivery(t, d.ID)
+	if got.Status != nodes.DeliveryDelivered || got.StatusCode != http.StatusOK || got.Attempts != 1 || got.DeliveredAt == nil {
+		t.Fatalf("delivery = %+v", got)
+	}
+	events, err := f.events.List(context.Background(), "run-1", 0, 0)
+	if err != nil || len(events) != 1 || events[0].Kind != runtime.EventDeliveryFinished {
+		t.Fatalf("events = %+v, %v", events, err)
+	}
+	if events[0].NodeID != "notify" || events[0].Payload["delivery_id"] != d.ID || events[0].Payload["status"] != nodes.DeliveryDelivered {
+		t.Fatalf("delivery.finished event = %+v", events[0])
+	}
+
+	// Delivered deliveries are never sent again.
+	f.now = f.now.Add(time.Hour)
+	_ = f.worker.RunOnce(context.Background())
+	if rcv.count() != 1 {
+		t.Fatalf("receiver got %d requests after a second pass, want 1", rcv.count())
+	}
+}
+
+func TestOutboxWorker_RetriesWithBackoff(t *testing.T) {
+	rcv := &outboxReceiver{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	target := httptest.NewServer(rcv)
+	defer target.Close()
+	f := newOutboxFixture(t, 5)
+	d := f.enqueue(t, target.URL)
+
+	_ = f.worker.RunOnce(context.Background())
+	got := f.delivery(t, d.ID)
+	if got.Status != nodes.DeliveryPending || got.Attempts != 1 || got.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("after first attempt = %+v", got)
+	}
+	if want := f.now.Add(time.Second); !got.NextAttemptAt.Equal(want.Truncate(time.Millisecond)) {
+		t.Fatalf("next attempt at %s, want %s", got.NextAttemptAt, want)
+	}
+
+	// Not yet due.
+	_ = f.worker.RunOnce(context.Background())
+	if rcv.count() != 1 {
+		t.Fatalf("receiver got %d requests before the backoff elapsed, want 1", rcv.count())
+	}
+
+	f.now = f.now.Add(time.Second)
+	_ = f.worker.RunOnce(context.Background())
+	got = f.delivery(t, d.ID)
+	if got.Attempts != 2 || !got.NextAttemptAt.Equal(f.now.Add(2*time.Second).Truncate(time.Millisecond)) {
+		t.Fatalf("after second attempt = %+v", got)
+	}
+
+	f.now = f.now.Add(2 * time.Second)
+	_ = f.worker.RunOnce(context.Background())
+	if got = f.delivery(t, d.ID); got.Status != nodes.DeliveryDelivered || got.Attempts != 3 || got.LastError != "" {
+		t.Fatalf("after third attempt = %+v", got)
+	}
+}
+
+func TestOutboxWorker_Fails(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		maxAttempts  int
+		wantAttempts int
+		wantStatus   int
+	}{
+		{"attempts exhausted", []int{502, 502}, 2, 2, 502},
+		{"permanent rejection", []int{http.StatusUnprocessableEntity}, 5, 1, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rcv := &outboxReceiver{statuses: tt.statuses}
+			target := httptest.NewServer(rcv)
+			defer target.Close()
+			f := newOutboxFixture(t, tt.maxAttempts)
+			d := f.enqueue(t, target.URL)
+
+			for range 5 {
+				_ = f.worker.RunOnce(context.Background())
+				f.now = f.now.Add(time.Minute)
+			}
+			got := f.delivery(t, d.ID)
+			if got.Status != nodes.DeliveryFailed || got.Attempts != tt.wantAttempts || got.StatusCode != tt.wantStatus || got.LastError == "" {
+				t.Fatalf("delivery = %+v", got)
+			}
+			if rcv.count() != tt.wantAttempts {
+				t.Fatalf("receiver got %d requests, want %d", rcv.count(), tt.wantAttempts)
+			}
+			events, _ := f.events.List(context.Background(), "run-1", 0, 0)
+			if len(events) != 1 || events[0].Payload["status"] != nodes.DeliveryFailed {
+				t.Fatalf("events = %+v", events)
+			}
+		})
+	}
+}
+
+func TestRunOutbox_ResumeReusesEarlierDelivery(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := context.Background()
+	body := `{"order_id":"ord_123"}`
+	first, err := runOutbox{store: store}.EnqueueDelivery(ctx, nodes.OutboxDelivery{
+		ID: nodes.OutboxDeliveryID("run-1", "notify", []byte(body)), RunID: "run-1", NodeID: "notify", Method: "POST", URL: "https://example.com", Body: body,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resumed := runOutbox{store: store, resumeFrom: "run-1"}
+	got, err := resumed.EnqueueDelivery(ctx, nodes.OutboxDelivery{
+		ID: nodes.OutboxDeliveryID("run-2", "notify", []byte(body)), RunID: "run-2", NodeID: "notify", Method: "POST", URL: "https://example.com", Body: body,
+	})
+	if err != nil || got.ID != first.ID {
+		t.Fatalf("resumed enqueue = %+v, %v; want delivery %s", got, err, first.ID)
+	}
+	if list, _ := store.ListDeliveries(ctx, "run-2"); len(list) != 0 {
+		t.Fatalf("resumed run recorded %d deliveries, want 0", len(list))
+	}
+
+	// A different request in the resumed run is a new delivery.
+	other, err := resumed.EnqueueDelivery(ctx, nodes.OutboxDelivery{
+		ID: nodes.OutboxDeliveryID("run-2", "notify", []byte(`{}`)), RunID: "run-2", NodeID: "notify", Method: "POST", URL: "https://example.com", Body: `{}`,
+	})
+	if err != nil || other.ID == first.ID || other.RunID != "run-2" {
+		t.Fatalf("new request in resumed run = %+v, %v", other, err)
+	}
+}
+
+func TestOutboxWorker_SkipsWhileReadOnly(t *testing.T) {
+	rcv := &outboxReceiver{}
+	target := httptest.NewServer(rcv)
+	defer target.Close()
+	f := newOutboxFixture(t, 3)
+	f.enqueue(t, target.URL)
+
+	f.server.maintenance.set(MaintenanceState{ReadOnly: true})
+	_ = f.worker.RunOnce(context.Background())
+	if rcv.count() != 0 {
+		t.Fatalf("receiver got %d requests in read-only mode, want 0", rcv.count())
+	}
+}
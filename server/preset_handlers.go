@@ -0,0 +1,238 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// Preset sources, as reported by GET /api/workflows/{id}/presets.
+const (
+	PresetSourceSaved      = "saved"
+	PresetSourceDefinition = "definition"
+)
+
+// presetRequest is the body of POST /api/workflows/{id}/presets.
+type presetRequest struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	Input       map[string]any `json:"input"`
+}
+
+// presetResponse is a preset along with where it comes from: saved through
+// the API or declared in the workflow definition.
+type presetResponse struct {
+	WorkflowPreset
+	Source string `json:"source"`
+}
+
+// handleSavePreset creates or replaces a saved input preset.
+func (s *Server) handleSavePreset(w http.ResponseWriter, r *http.Request) {
+	if s.presets == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "saving presets requires a preset store")
+		return
+	}
+	id := r.PathValue("id")
+	var req presetRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
+		return
+	}
+	if err := graph.ValidatePresetName(req.Name); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_PRESET", err.Error())
+		return
+	}
+	if req.Input == nil {
+		req.Input = map[string]any{}
+	}
+
+	existing, found, err := s.presets.GetPreset(r.Context(), id, req.Name)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	now := time.Now().UTC()
+	preset := WorkflowPreset{
+		WorkflowID:  id,
+		Name:        req.Name,
+		InputPreset: graph.InputPreset{Description: req.Description, Input: req.Input},
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if found {
+		preset.CreatedAt = existing.CreatedAt
+	}
+	if err := s.presets.SavePreset(r.Context(), preset); err != nil {
+		if errors.Is(err, ErrWorkflowNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	status := http.StatusCreated
+	if found {
+		status = http.StatusOK
+	}
+	writeJSON(w, status, presetResponse{WorkflowPreset: preset, Source: PresetSourceSaved})
+}
+
+// handleListPresets returns a workflow's saved presets and the presets of
+// its definition that no saved preset replaces, ordered by name.
+func (s *Server) handleListPresets(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec, ok, err := s.store.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+		return
+	}
+	saved, err := s.savedPresets(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+
+	byName := make(map[string]presetResponse)
+	if rec.Compiled != nil {
+		for name, preset := range rec.Compiled.Presets {
+			byName[name] = presetResponse{
+				WorkflowPreset: WorkflowPreset{WorkflowID: id, Name: name, InputPreset: preset},
+				Source:         PresetSourceDefinition,
+			}
+		}
+	}
+	for _, preset := range saved {
+		byName[preset.Name] = presetResponse{WorkflowPreset: preset, Source: PresetSourceSaved}
+	}
+	presets := make([]presetResponse, 0, len(byName))
+	for _, preset := range byName {
+		presets = append(presets, preset)
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	writeJSON(w, http.StatusOK, map[string]any{"presets": presets})
+}
+
+// handleGetPreset returns one preset, saved or from the definition.
+func (s *Server) handleGetPreset(w http.ResponseWriter, r *http.Request) {
+	id, name := r.PathValue("id"), r.PathValue("name")
+	rec, ok, err := s.store.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+		return
+	}
+	preset, err := s.lookupPreset(r.Context(), id, rec.Compiled, name)
+	if err != nil {
+		writeRunAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, preset)
+}
+
+// handleDeletePreset deletes a saved preset. Presets declared in the
+// workflow definition are changed by updating the workflow.
+func (s *Server) handleDeletePreset(w http.ResponseWriter, r *http.Request) {
+	if s.presets == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "saving presets requires a preset store")
+		return
+	}
+	id, name := r.PathValue("id"), r.PathValue("name")
+	if err := s.presets.DeletePreset(r.Context(), id, name); err != nil {
+		if errors.Is(err, ErrPresetNotFound) {
+			writeError(w, http.StatusNotFound, "PRESET_NOT_FOUND", fmt.Sprintf("workflow %q has no saved preset %q", id, name))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// savedPresets lists a workflow's saved presets, or none without a store.
+func (s *Server) savedPresets(ctx context.Context, workflowID string) ([]WorkflowPreset, error) {
+	if s.presets == nil {
+		return nil, nil
+	}
+	return s.presets.ListPresets(ctx, workflowID)
+}
+
+// lookupPreset finds a preset by name, preferring a saved preset over the
+// definition's.
+func (s *Server) lookupPreset(ctx context.Context, workflowID string, compiled *graph.GraphDefinition, name string) (presetResponse, error) {
+	if s.presets != nil {
+		preset, ok, err := s.presets.GetPreset(ctx, workflowID, name)
+		if err != nil {
+			return presetResponse{}, &runAPIError{Status: http.StatusInternalServerError, Code: "STORE_ERROR", Message: err.Error()}
+		}
+		if ok {
+			return presetResponse{WorkflowPreset: preset, Source: PresetSourceSaved}, nil
+		}
+	}
+	if compiled != nil {
+		if preset, ok := compiled.Presets[name]; ok {
+			return presetResponse{
+				WorkflowPreset: WorkflowPreset{WorkflowID: workflowID, Name: name, InputPreset: preset},
+				Source:         PresetSourceDefinition,
+			}, nil
+		}
+	}
+	return presetResponse{}, &runAPIError{
+		Status:  http.StatusNotFound,
+		Code:    "PRESET_NOT_FOUND",
+		Message: fmt.Sprintf("workflow %q has no preset %q", workflowID, name),
+	}
+}
+
+// presetRunInput layers the named presets, in order, under a run's own
+// input.
+func (s *Server) presetRunInput(ctx context.Context, workflowID string, compiled *graph.GraphDefinition, names []string, input map[string]any) (map[string]any, error) {
+	if len(names) == 0 {
+		return input, nil
+	}
+	inputs := make([]map[string]any, 0, len(names)+1)
+	for _, name := range names {
+		preset, err := s.lookupPreset(ctx, workflowID, compiled, name)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, preset.Input)
+	}
+	return graph.MergePresetInputs(append(inputs, input)...), nil
+}
+
+// exportWithSavedPresets adds a workflow's saved presets to the presets
+// section of its JSON source, replacing definition presets of the same name.
+func exportWithSavedPresets(source json.RawMessage, saved []WorkflowPreset) (json.RawMessage, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(source, &doc); err != nil {
+		return nil, err
+	}
+	presets := map[string]graph.InputPreset{}
+	if raw, ok := doc["presets"]; ok {
+		if err := json.Unmarshal(raw, &presets); err != nil {
+			return nil, err
+		}
+	}
+	for _, preset := range saved {
+		presets[preset.Name] = preset.InputPreset
+	}
+	raw, err := json.Marshal(presets)
+	if err != nil {
+		return nil, err
+	}
+	doc["presets"] = raw
+	return json.Marshal(doc)
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+func presetWorkflow() map[string]any {
+	return map[string]any{
+		"id":      "greeter",
+		"version": "1.0",
+		"nodes": []map[string]any{{
+			"id":     "greet",
+			"type":   "transform",
+			"config": map[string]any{"transform": "template", "template": "{{.customer}}/{{.tier}}", "output_var": "greeting"},
+		}},
+		"entry": "greet",
+		"presets": map[string]any{
+			"demo customer": map[string]any{"input": map[string]any{"customer": "acme", "tier": "free"}},
+		},
+	}
+}
+
+func newPresetTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	store := newTestSQLiteStore(t)
+	handler := NewServer(ServerConfig{
+		Store:       store,
+		PresetStore: store,
+		Providers:   hydrate.ProviderMap{},
+		Bus:         bus.NewMemBus(bus.MemBusConfig{}),
+	}).Handler()
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", presetWorkflow()); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	return handler
+}
+
+func runPresetGreeting(t *testing.T, handler http.Handler, path string, body any) string {
+	t.Helper()
+	w := doConditionRequest(t, handler, http.MethodPost, path, body)
+	if w.Code != http.StatusOK {
+		t.Fatalf("run %s: %d %s", path, w.Code, w.Body.String())
+	}
+	var resp RunResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	greeting, _ := resp.Output.Vars["greeting"].(string)
+	return greeting
+}
+
+func TestPresets_RunLayersPresetsUnderInput(t *testing.T) {
+	handler := newPresetTestServer(t)
+
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/presets", map[string]any{
+		"name":  "regression case 17",
+		"input": map[string]any{"tier": "enterprise"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("save preset: %d %s", w.Code, w.Body.String())
+	}
+
+	if got := runPresetGreeting(t, handler, "/api/workflows/greeter/run?preset=demo+customer", nil); got != "acme/free" {
+		t.Errorf("definition preset greeting = %q, want acme/free", got)
+	}
+	if got := runPresetGreeting(t, handler, "/api/workflows/greeter/run?preset=demo+customer&preset=regression+case+17", nil); got != "acme/enterprise" {
+		t.Errorf("layered presets greeting = %q, want acme/enterprise", got)
+	}
+	got := runPresetGreeting(t, handler, "/api/workflows/greeter/run", map[string]any{
+		"presets": []string{"demo customer"},
+		"input":   map[string]any{"customer": "globex"},
+	})
+	if got != "globex/free" {
+		t.Errorf("input over preset greeting = %q, want globex/free", got)
+	}
+
+	w = doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/run?preset=ghost", nil)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "PRESET_NOT_FOUND") {
+		t.Errorf("unknown preset: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPresets_SavedPresetReplacesDefinitionPreset(t *testing.T) {
+	handler := newPresetTestServer(t)
+
+	body := map[string]any{"name": "demo customer", "input": map[string]any{"customer": "initech", "tier": "pro"}}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/presets", body); w.Code != http.StatusCreated {
+		t.Fatalf("save preset: %d %s", w.Code, w.Body.String())
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/presets", body); w.Code != http.StatusOK {
+		t.Fatalf("replace preset: %d %s", w.Code, w.Body.String())
+	}
+	if got := runPresetGreeting(t, handler, "/api/workflows/greeter/run?preset=demo+customer", nil); got != "initech/pro" {
+		t.Errorf("greeting = %q, want initech/pro", got)
+	}
+
+	w := doConditionRequest(t, handler, http.MethodGet, "/api/workflows/greeter/presets", nil)
+	var list struct {
+		Presets []presetResponse `json:"presets"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if len(list.Presets) != 1 || list.Presets[0].Source != PresetSourceSaved {
+		t.Fatalf("presets = %+v, want the saved preset only", list.Presets)
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodDelete, "/api/workflows/greeter/presets/demo%20customer", nil); w.Code != http.StatusNoContent {
+		t.Fatalf("delete preset: %d %s", w.Code, w.Body.String())
+	}
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/workflows/greeter/presets/demo%20customer", nil)
+	var preset presetResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &preset)
+	if w.Code != http.StatusOK || preset.Source != PresetSourceDefinition {
+		t.Errorf("get after delete: %d %s, want the definition preset", w.Code, w.Body.String())
+	}
+}
+
+func TestPresets_SaveValidates(t *testing.T) {
+	handler := newPresetTestServer(t)
+
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/presets", map[string]any{"name": " "})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_PRESET") {
+		t.Errorf("blank name: %d %s", w.Code, w.Body.String())
+	}
+	w = doConditionRequest(t, handler, http.MethodPost, "/api/workflows/ghost/presets", map[string]any{"name": "demo"})
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown workflow: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPresets_ExportIncludesSavedPresets(t *testing.T) {
+	handler := newPresetTestServer(t)
+
+	body := map[string]any{"name": "regression case 17", "description": "issue 17", "input": map[string]any{"tier": "enterprise"}}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/presets", body); w.Code != http.StatusCreated {
+		t.Fatalf("save preset: %d %s", w.Code, w.Body.String())
+	}
+
+	w := doConditionRequest(t, handler, http.MethodGet, "/api/workflows/greeter/export", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: %d %s", w.Code, w.Body.String())
+	}
+	var doc struct {
+		ID      string `json:"id"`
+		Presets map[string]struct {
+			Description string         `json:"description"`
+			Input       map[string]any `json:"input"`
+		} `json:"presets"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if doc.ID != "greeter" || len(doc.Presets) != 2 {
+		t.Fatalf("export = %s, want both presets", w.Body.String())
+	}
+	if p := doc.Presets["regression case 17"]; p.Description != "issue 17" || p.Input["tier"] != "enterprise" {
+		t.Errorf("saved preset exported as %+v", p)
+	}
+}
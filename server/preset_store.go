@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// ErrPresetNotFound is returned when a workflow has no saved preset of the
+// requested name.
+var ErrPresetNotFound = errors.New("preset not found")
+
+// WorkflowPreset is a named run input saved on a workflow through
+// POST /api/workflows/{id}/presets. Saved presets take precedence over the
+// presets section of the workflow's definition.
+type WorkflowPreset struct {
+	WorkflowID string `json:"workflow_id"`
+	Name       string `json:"name"`
+	graph.InputPreset
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PresetStore persists the saved input presets of workflows. Deleting a
+// workflow deletes its presets.
+type PresetStore interface {
+	// ListPresets returns a workflow's presets ordered by name.
+	ListPresets(ctx context.Context, workflowID string) ([]WorkflowPreset, error)
+	GetPreset(ctx context.Context, workflowID, name string) (WorkflowPreset, bool, error)
+	// SavePreset creates or replaces a preset.
+	SavePreset(ctx context.Context, preset WorkflowPreset) error
+	DeletePreset(ctx context.Context, workflowID, name string) error
+}
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+// verifyWorkflowProviders checks the credentials of the providers rec's
+// nodes reference. It does nothing unless credential verification is
+// enabled.
+func (s *Server) verifyWorkflowProviders(ctx context.Context, rec *WorkflowRecord) error {
+	if s.credentials == nil {
+		return nil
+	}
+	if err := s.credentials.VerifyGraph(ctx, rec.Compiled, s.providers); err != nil {
+		return &runAPIError{Status: http.StatusUnprocessableEntity, Code: "PROVIDER_VERIFICATION_FAILED", Message: err.Error()}
+	}
+	return nil
+}
+
+// VerifyProviders checks the credentials of every configured provider and
+// logs the ones that fail. It does nothing unless ServerConfig
+// VerifyCredentials is set. Daemons call it once at startup.
+func (s *Server) VerifyProviders(ctx context.Context) {
+	if s.credentials == nil {
+		return
+	}
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		status := s.credentials.Verify(ctx, name, s.providers[name], "")
+		switch status.State {
+		case hydrate.CredentialFailed:
+			s.logger.Warn("provider credentials failed verification", "provider", name, "error", status.Error)
+		case hydrate.CredentialVerified:
+			s.logger.Info("provider credentials verified", "provider", name)
+		}
+	}
+}
+
+// handleVerifyProvider checks a provider's credentials now, replacing any
+// cached result.
+func (s *Server) handleVerifyProvider(w http.ResponseWriter, r *http.Request) {
+	if s.credentials == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "credential verification not enabled")
+		return
+	}
+	name := r.PathValue("name")
+	cfg, ok := s.providers[name]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("provider %q not found", name))
+		return
+	}
+	writeJSON(w, http.StatusOK, s.credentials.Refresh(r.Context(), name, cfg, r.URL.Query().Get("model")))
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+// keyCheckLLM accepts completions only with the key "good".
+type keyCheckLLM struct {
+	key   string
+	calls *int
+}
+
+func (c keyCheckLLM) Complete(context.Context, core.LLMRequest) (core.LLMResponse, error) {
+	*c.calls++
+	if c.key != "good" {
+		return core.LLMResponse{}, errors.New("401 invalid api key")
+	}
+	return core.LLMResponse{Text: "ok"}, nil
+}
+
+func verifyingServer(t *testing.T, providers hydrate.ProviderMap) (*Server, *int) {
+	t.Helper()
+	calls := 0
+	srv := NewServer(ServerConfig{
+		Store:     newTestSQLiteStore(t),
+		Providers: providers,
+		ClientFactory: func(_ string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+			return keyCheckLLM{key: cfg.APIKey, calls: &calls}, nil
+		},
+		VerifyCredentials: true,
+	})
+	return srv, &calls
+}
+
+func llmWorkflowPayload(id, provider string) map[string]any {
+	return graphWorkflowPayload(id, map[string]any{
+		"id":   "answer",
+		"type": "llm_prompt",
+		"config": map[string]any{
+			"provider":        provider,
+			"model":           "small-model",
+			"prompt_template": "{{.question}}",
+			"output_key":      "answer",
+		},
+	})
+}
+
+func TestVerifyCredentials_RejectsSaveWithBadKey(t *testing.T) {
+	srv, calls := verifyingServer(t, hydrate.ProviderMap{
+		"good": {APIKey: "good"},
+		"bad":  {APIKey: "revoked"},
+	})
+	handler := srv.Handler()
+
+	createGraphWorkflow(t, handler, llmWorkflowPayload("ok", "good"))
+
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(mustJSON(t, llmWorkflowPayload("broken", "bad"))))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "PROVIDER_VERIFICATION_FAILED") {
+		t.Fatalf("status = %d body=%s, want 422 PROVIDER_VERIFICATION_FAILED", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "invalid api key") {
+		t.Fatalf("body does not carry the provider error: %s", w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodPut, "/api/workflows/ok", bytes.NewReader(mustJSON(t, llmWorkflowPayload("ok", "bad"))))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("update status = %d body=%s, want 422", w.Code, w.Body.String())
+	}
+
+	before := *calls
+	createGraphWorkflow(t, handler, llmWorkflowPayload("ok-again", "good"))
+	if *calls != before {
+		t.Fatalf("verification was not cached: %d provider calls, want %d", *calls, before)
+	}
+}
+
+func TestVerifyCredentials_ProvidersAPI(t *testing.T) {
+	srv, _ := verifyingServer(t, hydrate.ProviderMap{"openai": {APIKey: "revoked"}})
+	handler := srv.Handler()
+
+	listProviders := func() []ProviderSummary {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/providers", nil))
+		var providers []ProviderSummary
+		if err := json.Unmarshal(w.Body.Bytes(), &providers); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return providers
+	}
+
+	if providers := listProviders(); len(providers) != 1 || providers[0].Verification != nil {
+		t.Fatalf("providers before any check = %+v", providers)
+	}
+
+	srv.VerifyProviders(context.Background())
+	if providers := listProviders(); providers[0].Verification == nil || providers[0].Verification.State != hydrate.CredentialUnchecked {
+		t.Fatalf("startup check without a model should be unchecked: %+v", providers[0].Verification)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/providers/openai/verify?model=small-model", nil))
+	var status hydrate.CredentialStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if w.Code != http.StatusOK || status.State != hydrate.CredentialFailed {
+		t.Fatalf("verify status = %d %+v, want failed", w.Code, status)
+	}
+	if providers := listProviders(); providers[0].Verification.State != hydrate.CredentialFailed {
+		t.Fatalf("listing should show the refreshed result: %+v", providers[0].Verification)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/providers/nope/verify", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("unknown provider status = %d, want 404", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	testServer(t).Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/providers/openai/verify", nil))
+	if w.Code != http.StatusNotImplemented {
+		t.Fatalf("disabled verification status = %d, want 501", w.Code)
+	}
+}
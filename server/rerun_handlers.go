@@ -0,0 +1,291 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// Rerun batch limits.
+const (
+	DefaultRerunLimit = 20
+	MaxRerunLimit     = 100
+)
+
+// Rerun outcomes compare a run with its rerun.
+const (
+	RerunOutcomeFixed        = "fixed"
+	RerunOutcomeStillFailing = "still_failing"
+	RerunOutcomeRegressed    = "regressed"
+	RerunOutcomeStillPassing = "still_passing"
+	RerunOutcomeSkipped      = "skipped"
+)
+
+// RerunRequest is the body of POST /api/workflows/{id}/reruns.
+type RerunRequest struct {
+	// RunIDs reruns these runs of the workflow. When empty, the runs
+	// matching Status, Since and Until are rerun, oldest first.
+	RunIDs []string `json:"run_ids,omitempty"`
+	// Status is completed, failed or any. It defaults to failed.
+	Status string `json:"status,omitempty"`
+	// Since and Until bound the runs' start time. They take an RFC 3339
+	// time or a window such as 24h or 7d.
+	Since string `json:"since,omitempty"`
+	Until string `json:"until,omitempty"`
+	// Limit caps the runs rerun. It defaults to DefaultRerunLimit.
+	Limit int `json:"limit,omitempty"`
+}
+
+// RerunReport is the response of POST /api/workflows/{id}/reruns.
+type RerunReport struct {
+	WorkflowID string        `json:"workflow_id"`
+	Summary    RerunSummary  `json:"summary"`
+	Runs       []RerunResult `json:"runs"`
+}
+
+// RerunSummary counts the reruns by outcome.
+type RerunSummary struct {
+	Total        int `json:"total"`
+	Fixed        int `json:"fixed"`
+	StillFailing int `json:"still_failing"`
+	Regressed    int `json:"regressed"`
+	StillPassing int `json:"still_passing"`
+	Skipped      int `json:"skipped"`
+}
+
+// RerunResult compares one run with its rerun.
+type RerunResult struct {
+	RunID      string          `json:"run_id"`
+	RerunRunID string          `json:"rerun_run_id,omitempty"`
+	Before     RerunRunStatus  `json:"before"`
+	After      *RerunRunStatus `json:"after,omitempty"`
+	Outcome    string          `json:"outcome"`
+	// SkipReason says why a run was not rerun.
+	SkipReason string `json:"skip_reason,omitempty"`
+	// Changes lists what changed in the run environment, such as the
+	// workflow revision and prompts, between the run and its rerun.
+	Changes []RunEnvironmentChange `json:"changes,omitempty"`
+}
+
+// RerunRunStatus is how one run ended.
+type RerunRunStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// handleRerunWorkflow reruns earlier runs of a workflow, with their
+// recorded inputs, against its current revision and reports each run's
+// outcome before and after.
+func (s *Server) handleRerunWorkflow(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	querier, ok := s.eventStore.(runQuerier)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "event store does not support reruns")
+		return
+	}
+
+	var req RerunRequest
+	if r.ContentLength > 0 {
+		if err := decodeJSONBody(r, &req); err != nil {
+			writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
+			return
+		}
+	}
+	q, err := rerunQuery(id, req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+
+	if _, found, err := s.store.Get(r.Context(), id); err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	} else if !found {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+		return
+	}
+
+	runs, err := querier.Runs(r.Context(), q)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if len(req.RunIDs) > 0 {
+		if runs, err = selectRerunRuns(runs, req.RunIDs); err != nil {
+			writeError(w, http.StatusNotFound, "RUN_NOT_FOUND", err.Error())
+			return
+		}
+	}
+
+	report := RerunReport{WorkflowID: id, Runs: make([]RerunResult, 0, len(runs))}
+	for _, run := range runs {
+		result := s.rerunRun(r.Context(), id, requestWorkspace(r), run)
+		report.Runs = append(report.Runs, result)
+		report.Summary.add(result.Outcome)
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// rerunQuery turns a rerun request into the run history query selecting
+// its runs.
+func rerunQuery(workflowID string, req RerunRequest) (bus.RunQuery, error) {
+	q := bus.RunQuery{WorkflowID: workflowID, Status: req.Status, Limit: req.Limit}
+	switch q.Status {
+	case "":
+		q.Status = "failed"
+	case "any":
+		q.Status = ""
+	case "completed", "failed":
+	default:
+		return q, fmt.Errorf("status %q must be completed, failed or any", req.Status)
+	}
+	if q.Limit == 0 {
+		q.Limit = DefaultRerunLimit
+	}
+	if q.Limit < 0 || q.Limit > MaxRerunLimit {
+		return q, fmt.Errorf("limit must be between 1 and %d", MaxRerunLimit)
+	}
+	if len(req.RunIDs) > 0 {
+		if len(req.RunIDs) > MaxRerunLimit {
+			return q, fmt.Errorf("at most %d run_ids may be rerun at once", MaxRerunLimit)
+		}
+		// Named runs are rerun whatever their status.
+		q.Status, q.Limit = "", 0
+	}
+	var err error
+	if q.Since, err = parseRunHistoryTime(req.Since); err != nil {
+		return q, err
+	}
+	if q.Until, err = parseRunHistoryTime(req.Until); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// selectRerunRuns keeps the named runs, in the order they were named.
+func selectRerunRuns(runs []bus.RunInfo, runIDs []string) ([]bus.RunInfo, error) {
+	byID := make(map[string]bus.RunInfo, len(runs))
+	for _, run := range runs {
+		byID[run.RunID] = run
+	}
+	selected := make([]bus.RunInfo, 0, len(runIDs))
+	for _, runID := range runIDs {
+		run, ok := byID[runID]
+		if !ok {
+			return nil, fmt.Errorf("run %q of this workflow not found", runID)
+		}
+		selected = append(selected, run)
+	}
+	return selected, nil
+}
+
+// rerunRun runs the workflow again with a run's recorded inputs and
+// compares the outcomes.
+func (s *Server) rerunRun(ctx context.Context, workflowID, workspace string, run bus.RunInfo) RerunResult {
+	result := RerunResult{
+		RunID:  run.RunID,
+		Before: RerunRunStatus{Status: run.Status, Error: run.Error},
+	}
+	skip := func(reason string) RerunResult {
+		result.Outcome, result.SkipReason = RerunOutcomeSkipped, reason
+		return result
+	}
+	if run.Status == "running" {
+		return skip("run has not finished")
+	}
+	inputs, err := s.recordedRunInputs(ctx, run.RunID)
+	if err != nil {
+		return skip(err.Error())
+	}
+
+	plan, err := s.planWorkflowRun(ctx, workflowID, RunRequest{Input: inputs, Workspace: workspace})
+	if err != nil {
+		return skip(err.Error())
+	}
+	plan.runID = runtime.NewRunID()
+	plan.class = RunClassScheduled
+	result.RerunRunID = plan.runID
+
+	after := RerunRunStatus{Status: "completed"}
+	if _, err := s.executeWorkflowRunSync(ctx, workflowID, plan, rerunMetadataDecorator(run.RunID)); err != nil {
+		after = RerunRunStatus{Status: "failed", Error: err.Error()}
+	}
+	result.After = &after
+	result.Outcome = rerunOutcome(run.Status, after.Status)
+
+	if before, err := s.loadRunEnvironment(ctx, run.RunID); err == nil {
+		if rerun, err := s.loadRunEnvironment(ctx, plan.runID); err == nil {
+			result.Changes = diffRunEnvironments(before.Environment, rerun.Environment)
+		}
+	}
+	return result
+}
+
+// recordedRunInputs reads the inputs recorded in a run's run.started
+// event.
+func (s *Server) recordedRunInputs(ctx context.Context, runID string) (map[string]any, error) {
+	events, err := s.eventStore.List(ctx, runID, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events {
+		if e.Kind != runtime.EventRunStarted {
+			continue
+		}
+		inputs, ok := e.Payload["inputs"].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("run %q has no recorded inputs", runID)
+		}
+		return inputs, nil
+	}
+	return nil, fmt.Errorf("run %q has no run.started event", runID)
+}
+
+func rerunOutcome(before, after string) string {
+	switch {
+	case before == "completed" && after == "completed":
+		return RerunOutcomeStillPassing
+	case before == "completed":
+		return RerunOutcomeRegressed
+	case after == "completed":
+		return RerunOutcomeFixed
+	default:
+		return RerunOutcomeStillFailing
+	}
+}
+
+func (s *RerunSummary) add(outcome string) {
+	s.Total++
+	switch outcome {
+	case RerunOutcomeFixed:
+		s.Fixed++
+	case RerunOutcomeStillFailing:
+		s.StillFailing++
+	case RerunOutcomeRegressed:
+		s.Regressed++
+	case RerunOutcomeStillPassing:
+		s.StillPassing++
+	case RerunOutcomeSkipped:
+		s.Skipped++
+	}
+}
+
+// rerunMetadataDecorator tags the rerun's events with the run it reruns.
+func rerunMetadataDecorator(originalRunID string) runtime.EventEmitterDecorator {
+	return func(next runtime.EventEmitter) runtime.EventEmitter {
+		return func(e runtime.Event) {
+			if e.Kind == runtime.EventRunStarted {
+				if e.Payload == nil {
+					e.Payload = map[string]any{}
+				}
+				e.Payload["trigger"] = "rerun"
+				e.Payload["rerun_of"] = originalRunID
+			}
+			next(e)
+		}
+	}
+}
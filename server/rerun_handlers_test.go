@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func rerunWorkflow(template string) map[string]any {
+	return map[string]any{
+		"id":      "batch",
+		"version": "1.0",
+		"nodes": []map[string]any{{"id": "pick", "type": "transform", "config": map[string]any{
+			"transform": "template", "template": template, "output_var": "picked",
+		}}},
+		"entry": "pick",
+	}
+}
+
+func TestRerunWorkflow_FixedRuns(t *testing.T) {
+	handler := testServer(t).Handler()
+
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", rerunWorkflow("{{index .items 3}}")); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	for _, items := range [][]any{{"a"}, {"b", "c"}} {
+		w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/batch/run", map[string]any{"input": map[string]any{"items": items}})
+		if w.Code == http.StatusOK {
+			t.Fatalf("broken run succeeded: %s", w.Body.String())
+		}
+	}
+	if w := doConditionRequest(t, handler, http.MethodPut, "/api/workflows/batch", rerunWorkflow("{{index .items 0}}")); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/batch/reruns", map[string]any{"since": "1h"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("reruns: %d %s", w.Code, w.Body.String())
+	}
+	var report RerunReport
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if report.Summary != (RerunSummary{Total: 2, Fixed: 2}) {
+		t.Fatalf("summary = %+v", report.Summary)
+	}
+	first := report.Runs[0]
+	if first.Before.Status != "failed" || first.Before.Error == "" || first.After == nil || first.After.Status != "completed" || first.RerunRunID == "" {
+		t.Errorf("first = %+v", first)
+	}
+	if len(first.Changes) == 0 || first.Changes[0].Category != "workflow_revision" {
+		t.Errorf("changes = %+v, want the workflow revision", first.Changes)
+	}
+
+	// The reruns are linked to their runs and carry their inputs, so they
+	// can be rerun in turn.
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/runs/history?workflow_id=batch&status=completed", nil)
+	var page RunHistoryPage
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Runs) != 2 || page.Runs[0].Trigger != "rerun" {
+		t.Fatalf("rerun history = %+v", page.Runs)
+	}
+	w = doConditionRequest(t, handler, http.MethodPost, "/api/workflows/batch/reruns", map[string]any{"run_ids": []string{first.RerunRunID}})
+	_ = json.Unmarshal(w.Body.Bytes(), &report)
+	if w.Code != http.StatusOK || report.Summary != (RerunSummary{Total: 1, StillPassing: 1}) {
+		t.Errorf("rerun of a rerun = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRerunWorkflow_InvalidRequests(t *testing.T) {
+	handler := testServer(t).Handler()
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", rerunWorkflow("ok")); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+
+	for _, tc := range []struct {
+		path string
+		body map[string]any
+		want int
+	}{
+		{"/api/workflows/batch/reruns", map[string]any{"status": "running"}, http.StatusBadRequest},
+		{"/api/workflows/batch/reruns", map[string]any{"limit": MaxRerunLimit + 1}, http.StatusBadRequest},
+		{"/api/workflows/batch/reruns", map[string]any{"since": "yesterday"}, http.StatusBadRequest},
+		{"/api/workflows/batch/reruns", map[string]any{"run_ids": []string{"run-missing"}}, http.StatusNotFound},
+		{"/api/workflows/missing/reruns", nil, http.StatusNotFound},
+	} {
+		if w := doConditionRequest(t, handler, http.MethodPost, tc.path, tc.body); w.Code != tc.want {
+			t.Errorf("%s %v: got %d, want %d; body: %s", tc.path, tc.body, w.Code, tc.want, w.Body.String())
+		}
+	}
+
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/batch/reruns", nil)
+	var report RerunReport
+	_ = json.Unmarshal(w.Body.Bytes(), &report)
+	if w.Code != http.StatusOK || report.Summary.Total != 0 || report.Runs == nil {
+		t.Errorf("no failed runs: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRerunOutcome(t *testing.T) {
+	for _, tc := range []struct{ before, after, want string }{
+		{"failed", "completed", RerunOutcomeFixed},
+		{"failed", "failed", RerunOutcomeStillFailing},
+		{"interrupted", "failed", RerunOutcomeStillFailing},
+		{"completed", "failed", RerunOutcomeRegressed},
+		{"completed", "completed", RerunOutcomeStillPassing},
+	} {
+		if got := rerunOutcome(tc.before, tc.after); got != tc.want {
+			t.Errorf("rerunOutcome(%s, %s) = %s, want %s", tc.before, tc.after, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// Rollout defaults, used when the update request leaves them out.
+const (
+	DefaultRolloutPercent                = 10
+	DefaultRolloutWindow                 = 20
+	DefaultRolloutMaxFailureRateIncrease = 0.05
+	DefaultRolloutMaxCostIncrease        = 0.25
+)
+
+// rolloutRun ties a run to the revision it was given during a rollout.
+type rolloutRun struct {
+	revision string
+	// rolloutCreatedAt identifies the rollout, so a run that outlives it
+	// is not counted towards the next one.
+	rolloutCreatedAt time.Time
+}
+
+// checkRolloutUpdate refuses updates of a workflow with an active rollout
+// and parses the rollout an update asks for. It returns nil for plain
+// updates.
+func (s *Server) checkRolloutUpdate(r *http.Request, workflowID string) (*WorkflowRollout, error) {
+	if s.rollouts != nil {
+		current, ok, err := s.rollouts.GetRollout(r.Context(), workflowID)
+		if err != nil {
+			return nil, &runAPIError{Status: http.StatusInternalServerError, Code: "STORE_ERROR", Message: err.Error()}
+		}
+		if ok && current.Status == RolloutActive {
+			return nil, &runAPIError{
+				Status:  http.StatusConflict,
+				Code:    "ROLLOUT_ACTIVE",
+				Message: fmt.Sprintf("workflow %q has an active rollout; promote or roll it back first", workflowID),
+			}
+		}
+	}
+
+	q := r.URL.Query()
+	switch q.Get("rollout") {
+	case "":
+		return nil, nil
+	case "canary":
+	default:
+		return nil, &runAPIError{Status: http.StatusBadRequest, Code: "INVALID_ROLLOUT", Message: fmt.Sprintf("unknown rollout mode %q (use canary)", q.Get("rollout"))}
+	}
+	if s.rollouts == nil {
+		return nil, &runAPIError{Status: http.StatusNotImplemented, Code: "NOT_IMPLEMENTED", Message: "rollouts require a rollout store"}
+	}
+
+	rollout := &WorkflowRollout{
+		WorkflowID:             workflowID,
+		Status:                 RolloutActive,
+		Percent:                DefaultRolloutPercent,
+		Window:                 DefaultRolloutWindow,
+		MaxFailureRateIncrease: DefaultRolloutMaxFailureRateIncrease,
+		MaxCostIncrease:        DefaultRolloutMaxCostIncrease,
+	}
+	invalid := func(format string, args ...any) error {
+		return &runAPIError{Status: http.StatusBadRequest, Code: "INVALID_ROLLOUT", Message: fmt.Sprintf(format, args...)}
+	}
+	if raw := q.Get("percent"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > 99 {
+			return nil, invalid("percent %q must be an integer from 1 to 99", raw)
+		}
+		rollout.Percent = n
+	}
+	if raw := q.Get("window"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return nil, invalid("window %q must be a positive integer", raw)
+		}
+		rollout.Window = n
+	}
+	if raw := q.Get("max_failure_rate_increase"); raw != "" {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil || f < 0 || f > 1 {
+			return nil, invalid("max_failure_rate_increase %q must be a number from 0 to 1", raw)
+		}
+		rollout.MaxFailureRateIncrease = f
+	}
+	if raw := q.Get("max_cost_increase"); raw != "" {
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, invalid("max_cost_increase %q must be a number", raw)
+		}
+		rollout.MaxCostIncrease = f
+	}
+	return rollout, nil
+}
+
+// startRollout stores a new rollout, replacing the workflow's finished one.
+func (s *Server) startRollout(ctx context.Context, rollout *WorkflowRollout) error {
+	now := time.Now().UTC()
+	rollout.CreatedAt, rollout.UpdatedAt = now, now
+	if err := s.rollouts.SaveRollout(ctx, *rollout); err != nil {
+		if errors.Is(err, ErrWorkflowNotFound) {
+			return &runAPIError{Status: http.StatusNotFound, Code: "NOT_FOUND", Message: fmt.Sprintf("workflow %q not found", rollout.WorkflowID)}
+		}
+		return &runAPIError{Status: http.StatusInternalServerError, Code: "STORE_ERROR", Message: err.Error()}
+	}
+	s.logger.Info("workflow rollout started", "workflow_id", rollout.WorkflowID, "percent", rollout.Percent, "window", rollout.Window)
+	return nil
+}
+
+// redactRollout redacts the secrets of the candidate revision.
+func redactRollout(rollout WorkflowRollout) WorkflowRollout {
+	rollout.Candidate = redactWorkflowRecord(rollout.Candidate)
+	return rollout
+}
+
+// rolloutRevision picks the definition a new run of rec uses. Without an
+// active rollout it is the stored one and the run is not tracked.
+func (s *Server) rolloutRevision(ctx context.Context, rec WorkflowRecord) (*graph.GraphDefinition, *rolloutRun, error) {
+	if s.rollouts == nil {
+		return rec.Compiled, nil, nil
+	}
+	rollout, ok, err := s.rollouts.GetRollout(ctx, rec.ID)
+	if err != nil {
+		return nil, nil, &runAPIError{Status: http.StatusInternalServerError, Code: "STORE_ERROR", Message: err.Error()}
+	}
+	if !ok || rollout.Status != RolloutActive || rollout.Candidate.Compiled == nil {
+		return rec.Compiled, nil, nil
+	}
+	if s.rolloutDraw()*100 < float64(rollout.Percent) {
+		return rollout.Candidate.Compiled, &rolloutRun{revision: RolloutCandidate, rolloutCreatedAt: rollout.CreatedAt}, nil
+	}
+	return rec.Compiled, &rolloutRun{revision: RolloutStable, rolloutCreatedAt: rollout.CreatedAt}, nil
+}
+
+// rolloutRunDecorator tags the run.started and run.finished events of a
+// rollout run with its revision.
+func rolloutRunDecorator(run *rolloutRun) runtime.EventEmitterDecorator {
+	if run == nil {
+		return nil
+	}
+	return func(next runtime.EventEmitter) runtime.EventEmitter {
+		return func(e runtime.Event) {
+			if e.Kind == runtime.EventRunStarted || e.Kind == runtime.EventRunFinished {
+				if e.Payload == nil {
+					e.Payload = map[string]any{}
+				}
+				e.Payload["rollout_revision"] = run.revision
+			}
+			next(e)
+		}
+	}
+}
+
+// rolloutHandler returns the event handler that counts a rollout run's
+// outcome and cost once it finishes.
+func (s *Server) rolloutHandler(workflowID string, run *rolloutRun) runtime.EventHandler {
+	if run == nil {
+		return nil
+	}
+	var (
+		mu    sync.Mutex
+		usage runUsage
+	)
+	return func(e runtime.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch e.Kind {
+		case runtime.EventLLMResponse:
+			usage.hasLLM = true
+			usage.llmCost += payloadNumber(e.Payload, "cost_usd")
+		case runtime.EventNodeOutputFinal:
+			usage.nodeCost += payloadNumber(e.Payload, "cost_usd")
+		case runtime.EventRunFinished:
+			status, _ := e.Payload["status"].(string)
+			_, cost := usage.totals()
+			s.recordRolloutRun(workflowID, run, status != "completed", cost)
+		}
+	}
+}
+
+// recordRolloutRun adds a finished run to its rollout and decides the
+// rollout once both revisions have finished a window of runs.
+func (s *Server) recordRolloutRun(workflowID string, run *rolloutRun, failed bool, costUSD float64) {
+	s.rolloutMu.Lock()
+	defer s.rolloutMu.Unlock()
+
+	ctx := context.Background()
+	rollout, ok, err := s.rollouts.GetRollout(ctx, workflowID)
+	if err != nil {
+		s.logger.Error("record rollout run", "workflow_id", workflowID, "error", err)
+		return
+	}
+	if !ok || rollout.Status != RolloutActive || !rollout.CreatedAt.Equal(run.rolloutCreatedAt) {
+		return
+	}
+	if run.revision == RolloutCandidate {
+		rollout.CandidateStats.record(failed, costUSD)
+	} else {
+		rollout.StableStats.record(failed, costUSD)
+	}
+	rollout.UpdatedAt = time.Now().UTC()
+
+	if status, reason := decideRollout(rollout); status != "" {
+		err = s.finishRollout(ctx, &rollout, status, reason)
+	} else {
+		err = s.rollouts.SaveRollout(ctx, rollout)
+	}
+	if err != nil {
+		s.logger.Error("record rollout run", "workflow_id", workflowID, "error", err)
+	}
+}
+
+// decideRollout returns the status a rollout should end with and why, or
+// "" while either revision has fewer than Window finished runs.
+func decideRollout(r WorkflowRollout) (status, reason string) {
+	stable, candidate := r.StableStats, r.CandidateStats
+	if stable.Runs < r.Window || candidate.Runs < r.Window {
+		return "", ""
+	}
+	if candidate.FailureRate > stable.FailureRate+r.MaxFailureRateIncrease {
+		return RolloutRolledBack, fmt.Sprintf("candidate failure rate %.1f%% exceeds stable %.1f%% by more than %.1f points",
+			candidate.FailureRate*100, stable.FailureRate*100, r.MaxFailureRateIncrease*100)
+	}
+	if r.MaxCostIncrease >= 0 && candidate.AvgCostUSD > stable.AvgCostUSD*(1+r.MaxCostIncrease) {
+		return RolloutRolledBack, fmt.Sprintf("candidate average cost $%.4f exceeds stable $%.4f by more than %.0f%%",
+			candidate.AvgCostUSD, stable.AvgCostUSD, r.MaxCostIncrease*100)
+	}
+	return RolloutPromoted, fmt.Sprintf("candidate failure rate %.1f%% and average cost $%.4f are within limits of stable %.1f%% and $%.4f",
+		candidate.FailureRate*100, candidate.AvgCostUSD, stable.FailureRate*100, stable.AvgCostUSD)
+}
+
+// finishRollout ends a rollout. Promotion stores the candidate as the
+// workflow.
+func (s *Server) finishRollout(ctx context.Context, rollout *WorkflowRollout, status, reason string) error {
+	now := time.Now().UTC()
+	if status == RolloutPromoted {
+		rec := rollout.Candidate
+		rec.UpdatedAt = now
+		if err := s.store.Update(ctx, rec); err != nil {
+			return fmt.Errorf("promoting candidate: %w", err)
+		}
+	}
+	rollout.Status = status
+	rollout.Reason = reason
+	rollout.UpdatedAt = now
+	rollout.DecidedAt = &now
+	if err := s.rollouts.SaveRollout(ctx, *rollout); err != nil {
+		return err
+	}
+	s.logger.Info("workflow rollout finished", "workflow_id", rollout.WorkflowID, "status", status, "reason", reason)
+	return nil
+}
+
+// handleGetRollout returns a workflow's latest rollout.
+func (s *Server) handleGetRollout(w http.ResponseWriter, r *http.Request) {
+	rollout, ok := s.loadRollout(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, redactRollout(rollout))
+}
+
+// handlePromoteRollout promotes an active rollout's candidate now.
+func (s *Server) handlePromoteRollout(w http.ResponseWriter, r *http.Request) {
+	s.handleFinishRollout(w, r, RolloutPromoted, "promoted manually")
+}
+
+// handleRollbackRollout abandons an active rollout's candidate.
+func (s *Server) handleRollbackRollout(w http.ResponseWriter, r *http.Request) {
+	s.handleFinishRollout(w, r, RolloutRolledBack, "rolled back manually")
+}
+
+func (s *Server) handleFinishRollout(w http.ResponseWriter, r *http.Request, status, reason string) {
+	s.rolloutMu.Lock()
+	defer s.rolloutMu.Unlock()
+
+	rollout, ok := s.loadRollout(w, r)
+	if !ok {
+		return
+	}
+	if rollout.Status != RolloutActive {
+		writeError(w, http.StatusConflict, "ROLLOUT_FINISHED", fmt.Sprintf("rollout of workflow %q is already %s", rollout.WorkflowID, rollout.Status))
+		return
+	}
+	if err := s.finishRollout(r.Context(), &rollout, status, reason); err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, redactRollout(rollout))
+}
+
+// loadRollout reads the rollout of the request's workflow, writing the
+// error response when there is none.
+func (s *Server) loadRollout(w http.ResponseWriter, r *http.Request) (WorkflowRollout, bool) {
+	if s.rollouts == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "rollouts require a rollout store")
+		return WorkflowRollout{}, false
+	}
+	id := r.PathValue("id")
+	rollout, ok, err := s.rollouts.GetRollout(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return WorkflowRollout{}, false
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q has no rollout", id))
+		return WorkflowRollout{}, false
+	}
+	return rollout, true
+}
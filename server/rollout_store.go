@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"time"
+)
+
+// Rollout statuses.
+const (
+	RolloutActive     = "active"
+	RolloutPromoted   = "promoted"
+	RolloutRolledBack = "rolled_back"
+)
+
+// Rollout revisions, as recorded on the run.started events of runs made
+// during a rollout.
+const (
+	RolloutStable    = "stable"
+	RolloutCandidate = "candidate"
+)
+
+// WorkflowRollout is a canary rollout of a workflow update. While it is
+// active, Percent of new runs use the candidate revision and the rest the
+// stored (stable) one. Once both revisions have finished Window runs, the
+// candidate is promoted unless its failure rate or cost is worse than the
+// stable revision's by more than the allowed margins, in which case it is
+// rolled back.
+type WorkflowRollout struct {
+	WorkflowID string `json:"workflow_id"`
+	// Status is active, promoted or rolled_back.
+	Status string `json:"status"`
+	// Percent is the share of new runs given to the candidate, 1 to 99.
+	Percent int `json:"percent"`
+	// Window is how many runs each revision must finish before the
+	// rollout is decided.
+	Window int `json:"window"`
+	// MaxFailureRateIncrease is how much higher, in absolute terms, the
+	// candidate's failure rate may be. 0.05 allows 5 points.
+	MaxFailureRateIncrease float64 `json:"max_failure_rate_increase"`
+	// MaxCostIncrease is how much higher, relative to the stable
+	// revision, the candidate's average cost per run may be. Negative
+	// disables the check.
+	MaxCostIncrease float64 `json:"max_cost_increase"`
+
+	Candidate      WorkflowRecord `json:"candidate"`
+	StableStats    RolloutStats   `json:"stable_stats"`
+	CandidateStats RolloutStats   `json:"candidate_stats"`
+
+	// Reason explains the decision once the rollout is over.
+	Reason    string     `json:"reason,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
+// RolloutStats are the finished runs of one revision during a rollout.
+type RolloutStats struct {
+	Runs        int     `json:"runs"`
+	Failures    int     `json:"failures"`
+	CostUSD     float64 `json:"cost_usd"`
+	FailureRate float64 `json:"failure_rate"`
+	AvgCostUSD  float64 `json:"avg_cost_usd"`
+}
+
+// record adds a finished run.
+func (s *RolloutStats) record(failed bool, costUSD float64) {
+	s.Runs++
+	if failed {
+		s.Failures++
+	}
+	s.CostUSD += costUSD
+	s.FailureRate = float64(s.Failures) / float64(s.Runs)
+	s.AvgCostUSD = s.CostUSD / float64(s.Runs)
+}
+
+// RolloutStore persists the latest rollout of each workflow. Deleting a
+// workflow deletes its rollout.
+type RolloutStore interface {
+	GetRollout(ctx context.Context, workflowID string) (WorkflowRollout, bool, error)
+	// SaveRollout creates or replaces the workflow's rollout.
+	SaveRollout(ctx context.Context, rollout WorkflowRollout) error
+}
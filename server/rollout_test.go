@@ -0,0 +1,209 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+func rolloutWorkflow(version string, assert ...any) map[string]any {
+	config := map[string]any{"transform": "template", "template": version, "output_var": "version"}
+	if len(assert) > 0 {
+		config["assert"] = assert
+	}
+	return map[string]any{
+		"id":      "greeter",
+		"version": "1.0",
+		"nodes":   []map[string]any{{"id": "greet", "type": "transform", "config": config}},
+		"entry":   "greet",
+	}
+}
+
+func newRolloutTestServer(t *testing.T) (*Server, http.Handler) {
+	t.Helper()
+	store := newTestSQLiteStore(t)
+	s := NewServer(ServerConfig{
+		Store:        store,
+		RolloutStore: store,
+		Providers:    hydrate.ProviderMap{},
+		Bus:          bus.NewMemBus(bus.MemBusConfig{}),
+	})
+	// Alternate stable, candidate, stable, ...
+	var draws int
+	s.rolloutDraw = func() float64 {
+		draws++
+		if draws%2 == 1 {
+			return 0.99
+		}
+		return 0
+	}
+	handler := s.Handler()
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", rolloutWorkflow("v1")); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	return s, handler
+}
+
+func runRolloutWorkflow(t *testing.T, handler http.Handler) (version string, ok bool) {
+	t.Helper()
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/run", nil)
+	if w.Code != http.StatusOK {
+		return "", false
+	}
+	var resp RunResponse
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	version, _ = resp.Output.Vars["version"].(string)
+	return version, true
+}
+
+func getRollout(t *testing.T, handler http.Handler) WorkflowRollout {
+	t.Helper()
+	w := doConditionRequest(t, handler, http.MethodGet, "/api/workflows/greeter/rollout", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get rollout: %d %s", w.Code, w.Body.String())
+	}
+	var rollout WorkflowRollout
+	_ = json.Unmarshal(w.Body.Bytes(), &rollout)
+	return rollout
+}
+
+func TestRollout_PromotesHealthyCandidate(t *testing.T) {
+	_, handler := newRolloutTestServer(t)
+
+	w := doConditionRequest(t, handler, http.MethodPut, "/api/workflows/greeter?rollout=canary&percent=50&window=2", rolloutWorkflow("v2"))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("start rollout: %d %s", w.Code, w.Body.String())
+	}
+	rollout := getRollout(t, handler)
+	if rollout.Status != RolloutActive || rollout.Percent != 50 || rollout.Window != 2 || rollout.MaxFailureRateIncrease != DefaultRolloutMaxFailureRateIncrease {
+		t.Fatalf("rollout = %+v", rollout)
+	}
+
+	// Updates wait for the rollout to finish.
+	if w := doConditionRequest(t, handler, http.MethodPut, "/api/workflows/greeter", rolloutWorkflow("v3")); w.Code != http.StatusConflict {
+		t.Errorf("update during rollout: %d, want 409", w.Code)
+	}
+
+	var got []string
+	for range 4 {
+		version, ok := runRolloutWorkflow(t, handler)
+		if !ok {
+			t.Fatal("run failed")
+		}
+		got = append(got, version)
+	}
+	if got[0] != "v1" || got[1] != "v2" || got[2] != "v1" || got[3] != "v2" {
+		t.Errorf("versions = %v, want runs split between v1 and v2", got)
+	}
+
+	rollout = getRollout(t, handler)
+	if rollout.Status != RolloutPromoted || rollout.DecidedAt == nil || rollout.StableStats.Runs != 2 || rollout.CandidateStats.Runs != 2 {
+		t.Fatalf("rollout = %+v, want promoted after two runs of each", rollout)
+	}
+	if version, _ := runRolloutWorkflow(t, handler); version != "v2" {
+		t.Errorf("after promotion, version = %q, want v2", version)
+	}
+	// A finished rollout no longer blocks updates.
+	if w := doConditionRequest(t, handler, http.MethodPut, "/api/workflows/greeter", rolloutWorkflow("v3")); w.Code != http.StatusOK {
+		t.Errorf("update after rollout: %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRollout_RollsBackFailingCandidate(t *testing.T) {
+	_, handler := newRolloutTestServer(t)
+
+	broken := rolloutWorkflow("v2", map[string]any{"expr": `output.version == "v1"`, "message": "unexpected version"})
+	w := doConditionRequest(t, handler, http.MethodPut, "/api/workflows/greeter?rollout=canary&percent=50&window=1", broken)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("start rollout: %d %s", w.Code, w.Body.String())
+	}
+	if _, ok := runRolloutWorkflow(t, handler); !ok {
+		t.Fatal("stable run failed")
+	}
+	if _, ok := runRolloutWorkflow(t, handler); ok {
+		t.Fatal("expected the candidate run to fail")
+	}
+
+	rollout := getRollout(t, handler)
+	if rollout.Status != RolloutRolledBack || rollout.CandidateStats.Failures != 1 || rollout.CandidateStats.FailureRate != 1 {
+		t.Fatalf("rollout = %+v, want rolled back", rollout)
+	}
+	if version, _ := runRolloutWorkflow(t, handler); version != "v1" {
+		t.Errorf("after rollback, version = %q, want v1", version)
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/rollout/promote", nil); w.Code != http.StatusConflict {
+		t.Errorf("promote finished rollout: %d, want 409", w.Code)
+	}
+}
+
+func TestRollout_ManualDecisions(t *testing.T) {
+	_, handler := newRolloutTestServer(t)
+
+	for _, path := range []string{
+		"/api/workflows/greeter?rollout=blue-green",
+		"/api/workflows/greeter?rollout=canary&percent=100",
+		"/api/workflows/greeter?rollout=canary&window=0",
+		"/api/workflows/greeter?rollout=canary&max_failure_rate_increase=2",
+	} {
+		if w := doConditionRequest(t, handler, http.MethodPut, path, rolloutWorkflow("v2")); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", path, w.Code)
+		}
+	}
+	if w := doConditionRequest(t, handler, http.MethodGet, "/api/workflows/greeter/rollout", nil); w.Code != http.StatusNotFound {
+		t.Errorf("get without rollout: %d, want 404", w.Code)
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodPut, "/api/workflows/greeter?rollout=canary", rolloutWorkflow("v2")); w.Code != http.StatusAccepted {
+		t.Fatalf("start rollout: %d %s", w.Code, w.Body.String())
+	}
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/rollout/rollback", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("rollback: %d %s", w.Code, w.Body.String())
+	}
+	if rollout := getRollout(t, handler); rollout.Status != RolloutRolledBack || rollout.Reason != "rolled back manually" {
+		t.Errorf("rollout = %+v", rollout)
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodPut, "/api/workflows/greeter?rollout=canary", rolloutWorkflow("v3")); w.Code != http.StatusAccepted {
+		t.Fatalf("restart rollout: %d %s", w.Code, w.Body.String())
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/greeter/rollout/promote", nil); w.Code != http.StatusOK {
+		t.Fatalf("promote: %d %s", w.Code, w.Body.String())
+	}
+	if version, _ := runRolloutWorkflow(t, handler); version != "v3" {
+		t.Errorf("after promotion, version = %q, want v3", version)
+	}
+}
+
+func TestDecideRollout(t *testing.T) {
+	stats := func(runs, failures int, cost float64) RolloutStats {
+		var s RolloutStats
+		for i := range runs {
+			s.record(i < failures, cost)
+		}
+		return s
+	}
+	base := WorkflowRollout{Window: 10, MaxFailureRateIncrease: 0.05, MaxCostIncrease: 0.25}
+	tests := []struct {
+		name              string
+		stable, candidate RolloutStats
+		maxCost           float64
+		want              string
+	}{
+		{"window not reached", stats(10, 0, 1), stats(9, 0, 1), 0.25, ""},
+		{"healthy", stats(10, 1, 1), stats(10, 1, 1.2), 0.25, RolloutPromoted},
+		{"more failures", stats(10, 0, 1), stats(10, 1, 1), 0.25, RolloutRolledBack},
+		{"too expensive", stats(10, 0, 1), stats(10, 0, 1.3), 0.25, RolloutRolledBack},
+		{"cost check disabled", stats(10, 0, 1), stats(10, 0, 3), -1, RolloutPromoted},
+	}
+	for _, tt := range tests {
+		r := base
+		r.StableStats, r.CandidateStats, r.MaxCostIncrease = tt.stable, tt.candidate, tt.maxCost
+		if got, reason := decideRollout(r); got != tt.want {
+			t.Errorf("%s: decideRollout = %q (%s), want %q", tt.name, got, reason, tt.want)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/registry"
+	"github.com/petal-labs/petalflow/runtime"
+	"github.com/petal-labs/petalflow/tool"
+)
+
+// RunEnvironment pins what a run was hydrated with. It is recorded in the
+// run.started event, so the differences between two runs of the "same"
+// workflow can be looked up later. Hashes are the first 16 hex digits of a
+// SHA-256 and only tell whether something changed.
+type RunEnvironment struct {
+	// WorkflowRevision hashes the compiled workflow.
+	WorkflowRevision string `json:"workflow_revision"`
+	// Models maps node IDs to "provider/model", comma-separated for
+	// nodes with several arms.
+	Models map[string]string `json:"models,omitempty"`
+	// Prompts maps node IDs to a hash of their prompt config: the system
+	// prompt, prompt template and instructions.
+	Prompts map[string]string `json:"prompts,omitempty"`
+	// Providers maps the providers the workflow uses to a hash of their
+	// config, including the API key. Unconfigured providers are "missing".
+	Providers map[string]string `json:"providers,omitempty"`
+	// Tools maps the tools the workflow uses to a hash of their manifest,
+	// config and overlay. Unregistered tools are "missing".
+	Tools map[string]string `json:"tools,omitempty"`
+}
+
+// promptConfigKeys are the node config fields a prompt version covers.
+var promptConfigKeys = []string{"system_prompt", "prompt_template", "prompt", "instructions"}
+
+// runEnvironment builds the environment a run of compiled is hydrated with.
+func (s *Server) runEnvironment(ctx context.Context, compiled *graph.GraphDefinition) (RunEnvironment, error) {
+	env := RunEnvironment{
+		WorkflowRevision: envHash(compiled),
+		Models:           map[string]string{},
+		Prompts:          map[string]string{},
+		Providers:        map[string]string{},
+		Tools:            map[string]string{},
+	}
+
+	for _, nd := range compiled.ApplyLLMDefaults().Nodes {
+		var models []string
+		if model, _ := nd.Config["model"].(string); model != "" {
+			provider, _ := nd.Config["provider"].(string)
+			models = append(models, provider+"/"+model)
+		}
+		if arms, ok := nd.Config["arms"].([]any); ok {
+			for _, item := range arms {
+				arm, _ := item.(map[string]any)
+				provider, _ := arm["provider"].(string)
+				model, _ := arm["model"].(string)
+				if model != "" {
+					models = append(models, provider+"/"+model)
+				}
+			}
+		}
+		if len(models) > 0 {
+			sort.Strings(models)
+			env.Models[nd.ID] = strings.Join(models, ",")
+			for _, m := range models {
+				if provider, _, _ := strings.Cut(m, "/"); provider != "" {
+					env.Providers[provider] = ""
+				}
+			}
+		}
+
+		prompt := map[string]string{}
+		for _, key := range promptConfigKeys {
+			if v, _ := nd.Config[key].(string); v != "" {
+				prompt[key] = v
+			}
+		}
+		if len(prompt) > 0 {
+			env.Prompts[nd.ID] = envHash(prompt)
+		}
+
+		for _, name := range nodeToolNames(nd) {
+			env.Tools[name] = ""
+		}
+	}
+
+	for name := range env.Providers {
+		cfg, ok := s.providers[name]
+		if !ok {
+			env.Providers[name] = "missing"
+			continue
+		}
+		env.Providers[name] = envHash(cfg)
+	}
+
+	if len(env.Tools) > 0 {
+		regs := make(map[string]tool.ToolRegistration)
+		for _, reg := range tool.BuiltinRegistrations() {
+			regs[reg.Name] = reg
+		}
+		if s.toolStore != nil {
+			stored, err := s.toolStore.List(ctx)
+			if err != nil {
+				return RunEnvironment{}, fmt.Errorf("listing tool registrations: %w", err)
+			}
+			for _, reg := range stored {
+				regs[reg.Name] = reg
+			}
+		}
+		for name := range env.Tools {
+			reg, ok := regs[name]
+			if !ok {
+				env.Tools[name] = "missing"
+				continue
+			}
+			env.Tools[name] = envHash(map[string]any{
+				"manifest": reg.Manifest,
+				"config":   reg.Config,
+				"overlay":  reg.Overlay,
+			})
+		}
+	}
+	return env, nil
+}
+
+// nodeToolNames returns the registered tools a node invokes: the tool of a
+// tool node's tool_name, of a "tool.action" node type and of the entries
+// of a tools list.
+func nodeToolNames(nd graph.NodeDef) []string {
+	var refs []string
+	switch {
+	case nd.Type == "tool":
+		if name, _ := nd.Config["tool_name"].(string); name != "" {
+			refs = append(refs, name)
+		}
+	case strings.Contains(nd.Type, ".") || !registry.Global().Has(nd.Type):
+		refs = append(refs, nd.Type)
+	}
+	if tools, ok := nd.Config["tools"].([]any); ok {
+		for _, t := range tools {
+			if name, ok := t.(string); ok && name != "" {
+				refs = append(refs, name)
+			}
+		}
+	}
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		name, _, _ := strings.Cut(ref, ".")
+		names = append(names, name)
+	}
+	return names
+}
+
+// envHash returns the first 16 hex digits of the SHA-256 of v's JSON.
+func envHash(v any) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
+
+// RunEnvironmentRun identifies one side of an environment comparison.
+type RunEnvironmentRun struct {
+	RunID       string         `json:"run_id"`
+	WorkflowID  string         `json:"workflow_id,omitempty"`
+	StartedAt   time.Time      `json:"started_at"`
+	Environment RunEnvironment `json:"environment"`
+}
+
+// RunEnvironmentChange is one difference between two run environments.
+type RunEnvironmentChange struct {
+	// Category is workflow_revision, models, prompts, providers or tools.
+	Category string `json:"category"`
+	// Key is the node, provider or tool that changed. It is empty for
+	// the workflow revision.
+	Key string `json:"key,omitempty"`
+	// Change is added, removed or changed.
+	Change    string `json:"change"`
+	Base      string `json:"base,omitempty"`
+	Candidate string `json:"candidate,omitempty"`
+}
+
+// RunEnvironmentDiff is the response of GET /api/runs/compare-env.
+type RunEnvironmentDiff struct {
+	Base      RunEnvironmentRun      `json:"base"`
+	Candidate RunEnvironmentRun      `json:"candidate"`
+	Identical bool                   `json:"identical"`
+	Changes   []RunEnvironmentChange `json:"changes"`
+}
+
+// diffRunEnvironments lists the changes from base to candidate: the
+// workflow revision, then models, prompts, providers and tools by key.
+func diffRunEnvironments(base, candidate RunEnvironment) []RunEnvironmentChange {
+	changes := []RunEnvironmentChange{}
+	if base.WorkflowRevision != candidate.WorkflowRevision {
+		changes = append(changes, RunEnvironmentChange{
+			Category:  "workflow_revision",
+			Change:    "changed",
+			Base:      base.WorkflowRevision,
+			Candidate: candidate.WorkflowRevision,
+		})
+	}
+	for _, c := range []struct {
+		category        string
+		base, candidate map[string]string
+	}{
+		{"models", base.Models, candidate.Models},
+		{"prompts", base.Prompts, candidate.Prompts},
+		{"providers", base.Providers, candidate.Providers},
+		{"tools", base.Tools, candidate.Tools},
+	} {
+		keys := make([]string, 0, len(c.base)+len(c.candidate))
+		for k := range c.base {
+			keys = append(keys, k)
+		}
+		for k := range c.candidate {
+			if _, ok := c.base[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			b, inBase := c.base[k]
+			n, inCandidate := c.candidate[k]
+			change := RunEnvironmentChange{Category: c.category, Key: k, Base: b, Candidate: n}
+			switch {
+			case !inBase:
+				change.Change = "added"
+			case !inCandidate:
+				change.Change = "removed"
+			case b != n:
+				change.Change = "changed"
+			default:
+				continue
+			}
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+// handleCompareRunEnvironments diffs the environments two runs were
+// hydrated with.
+func (s *Server) handleCompareRunEnvironments(w http.ResponseWriter, r *http.Request) {
+	if s.eventStore == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "comparing runs requires an event store")
+		return
+	}
+	baseID, candidateID := r.URL.Query().Get("base"), r.URL.Query().Get("candidate")
+	if baseID == "" || candidateID == "" {
+		writeError(w, http.StatusBadRequest, "MISSING_RUN", "base and candidate run IDs are required")
+		return
+	}
+
+	base, err := s.loadRunEnvironment(r.Context(), baseID)
+	if err != nil {
+		writeRunAPIError(w, err)
+		return
+	}
+	candidate, err := s.loadRunEnvironment(r.Context(), candidateID)
+	if err != nil {
+		writeRunAPIError(w, err)
+		return
+	}
+
+	changes := diffRunEnvironments(base.Environment, candidate.Environment)
+	writeJSON(w, http.StatusOK, RunEnvironmentDiff{
+		Base:      base,
+		Candidate: candidate,
+		Identical: len(changes) == 0,
+		Changes:   changes,
+	})
+}
+
+// loadRunEnvironment reads the environment recorded in a run's run.started
+// event.
+func (s *Server) loadRunEnvironment(ctx context.Context, runID string) (RunEnvironmentRun, error) {
+	events, err := s.eventStore.List(ctx, runID, 0, 0)
+	if err != nil {
+		return RunEnvironmentRun{}, &runAPIError{Status: http.StatusInternalServerError, Code: "STORE_ERROR", Message: err.Error()}
+	}
+	for _, e := range events {
+		if e.Kind != runtime.EventRunStarted {
+			continue
+		}
+		run := RunEnvironmentRun{RunID: runID, StartedAt: e.Time.UTC()}
+		run.WorkflowID, _ = e.Payload["workflow_id"].(string)
+		raw, ok := e.Payload["environment"]
+		if !ok {
+			return RunEnvironmentRun{}, &runAPIError{
+				Status:  http.StatusUnprocessableEntity,
+				Code:    "NO_ENVIRONMENT",
+				Message: fmt.Sprintf("run %q has no recorded environment", runID),
+			}
+		}
+		// Stored events hold the environment as decoded JSON.
+		data, err := json.Marshal(raw)
+		if err == nil {
+			err = json.Unmarshal(data, &run.Environment)
+		}
+		if err != nil {
+			return RunEnvironmentRun{}, &runAPIError{Status: http.StatusInternalServerError, Code: "STORE_ERROR", Message: fmt.Sprintf("decoding environment of run %q: %v", runID, err)}
+		}
+		return run, nil
+	}
+	return RunEnvironmentRun{}, &runAPIError{Status: http.StatusNotFound, Code: "RUN_NOT_FOUND", Message: fmt.Sprintf("run %q has no recorded events", runID)}
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+func TestCompareRunEnvironments(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	handler := NewServer(ServerConfig{
+		Store:     store,
+		Providers: hydrate.ProviderMap{"openai": {APIKey: "sk-test"}},
+		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+			return &workflowLifecycleLLMClient{provider: name}, nil
+		},
+		Bus:        bus.NewMemBus(bus.MemBusConfig{}),
+		EventStore: newTestEventStore(t),
+	}).Handler()
+
+	workflow := func(model, system string) map[string]any {
+		return map[string]any{
+			"id":      "assistant",
+			"version": "1.0",
+			"nodes": []map[string]any{
+				{
+					"id":   "answer",
+					"type": "llm_prompt",
+					"config": map[string]any{
+						"provider":        "openai",
+						"model":           model,
+						"system_prompt":   system,
+						"prompt_template": "hello",
+					},
+				},
+				{"id": "done", "type": "noop"},
+			},
+			"edges": []map[string]any{{"source": "answer", "target": "done"}},
+			"entry": "answer",
+		}
+	}
+	run := func() string {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/workflows/assistant/run", bytes.NewReader([]byte(`{}`))))
+		if w.Code != http.StatusOK {
+			t.Fatalf("run: %d %s", w.Code, w.Body.String())
+		}
+		var resp RunResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+		return resp.RunID
+	}
+	compare := func(base, candidate string) (RunEnvironmentDiff, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/compare-env?base="+base+"&candidate="+candidate, nil))
+		var diff RunEnvironmentDiff
+		_ = json.Unmarshal(w.Body.Bytes(), &diff)
+		return diff, w
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", workflow("gpt-4o-mini", "Be brief.")); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	first, second := run(), run()
+
+	diff, w := compare(first, second)
+	if w.Code != http.StatusOK {
+		t.Fatalf("compare: %d %s", w.Code, w.Body.String())
+	}
+	if !diff.Identical || len(diff.Changes) != 0 {
+		t.Fatalf("same workflow diff = %+v", diff)
+	}
+	env := diff.Base.Environment
+	if env.Models["answer"] != "openai/gpt-4o-mini" || env.Prompts["answer"] == "" || env.Providers["openai"] == "" || env.WorkflowRevision == "" {
+		t.Errorf("environment = %+v", env)
+	}
+	if diff.Base.WorkflowID != "assistant" || diff.Candidate.RunID != second {
+		t.Errorf("runs = %+v / %+v", diff.Base, diff.Candidate)
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodPut, "/api/workflows/assistant", workflow("gpt-4o", "Be thorough.")); w.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", w.Code, w.Body.String())
+	}
+	third := run()
+
+	diff, _ = compare(first, third)
+	if diff.Identical {
+		t.Fatal("expected changes after updating the workflow")
+	}
+	got := map[string]RunEnvironmentChange{}
+	for _, c := range diff.Changes {
+		got[c.Category] = c
+	}
+	if len(diff.Changes) != 3 {
+		t.Errorf("changes = %+v, want workflow_revision, models and prompts", diff.Changes)
+	}
+	if c := got["models"]; c.Key != "answer" || c.Change != "changed" || c.Base != "openai/gpt-4o-mini" || c.Candidate != "openai/gpt-4o" {
+		t.Errorf("models change = %+v", c)
+	}
+	if c := got["prompts"]; c.Key != "answer" || c.Change != "changed" {
+		t.Errorf("prompts change = %+v", c)
+	}
+	if c := got["workflow_revision"]; c.Change != "changed" || c.Key != "" {
+		t.Errorf("workflow_revision change = %+v", c)
+	}
+
+	for _, tt := range []struct {
+		path string
+		code int
+	}{
+		{"/api/runs/compare-env?base=" + first, http.StatusBadRequest},
+		{"/api/runs/compare-env?base=" + first + "&candidate=missing-run", http.StatusNotFound},
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s: got %d, want %d", tt.path, w.Code, tt.code)
+		}
+	}
+}
+
+func TestDiffRunEnvironments(t *testing.T) {
+	base := RunEnvironment{
+		WorkflowRevision: "aaaa",
+		Providers:        map[string]string{"openai": "1111"},
+		Tools:            map[string]string{"search": "2222", "crm": "3333"},
+	}
+	candidate := RunEnvironment{
+		WorkflowRevision: "aaaa",
+		Providers:        map[string]string{"openai": "9999"},
+		Tools:            map[string]string{"crm": "3333", "mailer": "missing"},
+	}
+
+	changes := diffRunEnvironments(base, candidate)
+	want := []RunEnvironmentChange{
+		{Category: "providers", Key: "openai", Change: "changed", Base: "1111", Candidate: "9999"},
+		{Category: "tools", Key: "mailer", Change: "added", Candidate: "missing"},
+		{Category: "tools", Key: "search", Change: "removed", Base: "2222"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("changes = %+v, want %+v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Errorf("changes[%d] = %+v, want %+v", i, changes[i], want[i])
+		}
+	}
+}
+
+func TestNodeToolNames(t *testing.T) {
+	tests := []struct {
+		nd   graph.NodeDef
+		want []string
+	}{
+		{graph.NodeDef{Type: "tool", Config: map[string]any{"tool_name": "crm.lookup"}}, []string{"crm"}},
+		{graph.NodeDef{Type: "search.query"}, []string{"search"}},
+		{graph.NodeDef{Type: "llm_prompt", Config: map[string]any{"tools": []any{"mailer.send", "calendar"}}}, []string{"mailer", "calendar"}},
+		{graph.NodeDef{Type: "noop"}, []string{}},
+	}
+	for _, tt := range tests {
+		got := nodeToolNames(tt.nd)
+		if len(got) != len(tt.want) {
+			t.Errorf("nodeToolNames(%s) = %v, want %v", tt.nd.Type, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("nodeToolNames(%s) = %v, want %v", tt.nd.Type, got, tt.want)
+			}
+		}
+	}
+}
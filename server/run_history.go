@@ -0,0 +1,150 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/petal-labs/petalflow/bus"
+)
+
+// Run history page sizes.
+const (
+	DefaultRunHistoryLimit = 100
+	MaxRunHistoryLimit     = 1000
+)
+
+// runQuerier is implemented by event stores that can filter and page runs.
+type runQuerier interface {
+	Runs(ctx context.Context, q bus.RunQuery) ([]bus.RunInfo, error)
+}
+
+// RunHistoryEntry summarizes one run in the run history.
+type RunHistoryEntry struct {
+	RunID      string     `json:"run_id"`
+	WorkflowID string     `json:"workflow_id,omitempty"`
+	Trigger    string     `json:"trigger,omitempty"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMs int64      `json:"duration_ms,omitempty"`
+	// SLABreaches names the workflow SLAs the run breached.
+	SLABreaches []string `json:"sla_breaches,omitempty"`
+	// Priority and Deadline are the hints the run was requested with.
+	// DeadlineMet reports whether a finished run completed by its
+	// deadline.
+	Priority    int        `json:"priority,omitempty"`
+	Deadline    *time.Time `json:"deadline,omitempty"`
+	DeadlineMet *bool      `json:"deadline_met,omitempty"`
+	// Cursor resumes the listing after this run.
+	Cursor string `json:"cursor"`
+}
+
+// RunHistoryPage is one page of the run history. NextCursor is empty on
+// the last page.
+type RunHistoryPage struct {
+	Runs       []RunHistoryEntry `json:"runs"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// handleRunHistory lists runs by start time, filtered by workflow, status
+// and start time range, one page at a time.
+func (s *Server) handleRunHistory(w http.ResponseWriter, r *http.Request) {
+	querier, ok := s.eventStore.(runQuerier)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "event store does not support run history")
+		return
+	}
+
+	params := r.URL.Query()
+	q := bus.RunQuery{
+		WorkflowID: params.Get("workflow_id"),
+		Status:     params.Get("status"),
+		After:      params.Get("cursor"),
+		Limit:      DefaultRunHistoryLimit,
+	}
+	switch q.Status {
+	case "", "completed", "failed", "running":
+	default:
+		writeError(w, http.StatusBadRequest, "INVALID_STATUS", fmt.Sprintf("status %q must be completed, failed or running", q.Status))
+		return
+	}
+	var err error
+	if q.Since, err = parseRunHistoryTime(params.Get("since")); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_TIME", err.Error())
+		return
+	}
+	if q.Until, err = parseRunHistoryTime(params.Get("until")); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_TIME", err.Error())
+		return
+	}
+	if raw := params.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 || n > MaxRunHistoryLimit {
+			writeError(w, http.StatusBadRequest, "INVALID_LIMIT", fmt.Sprintf("limit must be between 1 and %d", MaxRunHistoryLimit))
+			return
+		}
+		q.Limit = n
+	}
+
+	runs, err := querier.Runs(r.Context(), q)
+	if errors.Is(err, bus.ErrInvalidCursor) {
+		writeError(w, http.StatusBadRequest, "INVALID_CURSOR", "cursor was not returned by this endpoint")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+
+	page := RunHistoryPage{Runs: make([]RunHistoryEntry, 0, len(runs))}
+	for _, run := range runs {
+		entry := RunHistoryEntry{
+			RunID:      run.RunID,
+			WorkflowID: run.WorkflowID,
+			Trigger:    run.Trigger,
+			Status:     run.Status,
+			Error:      run.Error,
+			StartedAt:  run.StartedAt.UTC(),
+			Cursor:     run.Cursor,
+
+			SLABreaches: run.SLABreaches,
+			Priority:    run.Priority,
+			DeadlineMet: run.DeadlineMet,
+		}
+		if run.Deadline != nil {
+			deadline := run.Deadline.UTC()
+			entry.Deadline = &deadline
+		}
+		if run.FinishedAt != nil {
+			finished := run.FinishedAt.UTC()
+			entry.FinishedAt = &finished
+			entry.DurationMs = finished.Sub(entry.StartedAt).Milliseconds()
+		}
+		page.Runs = append(page.Runs, entry)
+	}
+	if len(runs) == q.Limit {
+		page.NextCursor = runs[len(runs)-1].Cursor
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// parseRunHistoryTime accepts an RFC 3339 time or a window such as "24h"
+// or "7d" counted back from now. Empty leaves the bound open.
+func parseRunHistoryTime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	d, err := parseStatsWindow(raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("time %q must be RFC 3339 or a window such as 24h or 7d", raw)
+	}
+	return time.Now().Add(-d), nil
+}
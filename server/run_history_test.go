@@ -0,0 +1,107 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRunHistory_Endpoint(t *testing.T) {
+	handler := testServer(t).Handler()
+
+	for _, id := range []string{"history-a", "history-b"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(validGraphJSON(id))))
+		if w.Code != http.StatusCreated {
+			t.Fatalf("create %s: got %d; body: %s", id, w.Code, w.Body.String())
+		}
+	}
+	for _, id := range []string{"history-a", "history-b", "history-a"} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/workflows/"+id+"/run", bytes.NewReader([]byte(`{}`))))
+		if w.Code != http.StatusOK {
+			t.Fatalf("run %s: got %d; body: %s", id, w.Code, w.Body.String())
+		}
+	}
+
+	get := func(path string) (RunHistoryPage, int) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var page RunHistoryPage
+		_ = json.Unmarshal(w.Body.Bytes(), &page)
+		return page, w.Code
+	}
+
+	page, code := get("/api/runs/history?workflow_id=history-a&status=completed&since=1h")
+	if code != http.StatusOK || len(page.Runs) != 2 || page.NextCursor != "" {
+		t.Fatalf("filtered history = %d %+v", code, page)
+	}
+	if r := page.Runs[0]; r.WorkflowID != "history-a" || r.Status != "completed" || r.FinishedAt == nil || r.Cursor == "" {
+		t.Errorf("run = %+v", r)
+	}
+
+	page, _ = get("/api/runs/history?limit=2")
+	if len(page.Runs) != 2 || page.NextCursor != page.Runs[1].Cursor {
+		t.Fatalf("first page = %+v", page)
+	}
+	next, _ := get("/api/runs/history?limit=2&cursor=" + page.NextCursor)
+	if len(next.Runs) != 1 || next.NextCursor != "" || next.Runs[0].RunID == page.Runs[1].RunID {
+		t.Fatalf("second page = %+v", next)
+	}
+
+	for _, path := range []string{
+		"/api/runs/history?status=unknown",
+		"/api/runs/history?since=yesterday",
+		"/api/runs/history?limit=0",
+		"/api/runs/history?cursor=!!",
+	} {
+		if _, code := get(path); code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", path, code)
+		}
+	}
+}
+
+func TestRunHistory_PriorityAndDeadline(t *testing.T) {
+	handler := testServer(t).Handler()
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", json.RawMessage(validGraphJSON("hinted"))); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+
+	deadline := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/hinted/run", map[string]any{
+		"options": map[string]any{"priority": 4, "deadline": deadline},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("run: %d %s", w.Code, w.Body.String())
+	}
+	for _, options := range []map[string]any{
+		{"priority": 11},
+		{"deadline": time.Now().Add(-time.Minute)},
+	} {
+		w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/hinted/run", map[string]any{"options": options})
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("options %v: %d %s, want 400", options, w.Code, w.Body.String())
+		}
+	}
+
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/runs/history?workflow_id=hinted", nil)
+	var page RunHistoryPage
+	_ = json.Unmarshal(w.Body.Bytes(), &page)
+	if len(page.Runs) != 1 {
+		t.Fatalf("history = %s", w.Body.String())
+	}
+	run := page.Runs[0]
+	if run.Priority != 4 || run.Deadline == nil || !run.Deadline.Equal(deadline) || run.DeadlineMet == nil || !*run.DeadlineMet {
+		t.Errorf("run = %+v", run)
+	}
+
+	w = doConditionRequest(t, handler, http.MethodGet, "/api/workflows/hinted/stats", nil)
+	var stats WorkflowStats
+	_ = json.Unmarshal(w.Body.Bytes(), &stats)
+	if stats.Deadlines == nil || stats.Deadlines.Runs != 1 || stats.Deadlines.Met != 1 || stats.Deadlines.Attainment != 1 {
+		t.Errorf("stats deadlines = %+v", stats.Deadlines)
+	}
+}
@@ -0,0 +1,339 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+const (
+	defaultRunJanitorInterval   = 15 * time.Second
+	defaultRunJanitorBatchLimit = 100
+)
+
+// leasedRunRequest is stored on a run's lease so an interrupted run can be
+// requeued with the request that started it.
+type leasedRunRequest struct {
+	Workspace string     `json:"workspace,omitempty"`
+	Request   RunRequest `json:"request"`
+}
+
+// defaultLeaseOwner identifies this process on its leases.
+func defaultLeaseOwner() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "petalflow"
+	}
+	return host + ":" + strconv.Itoa(os.Getpid()) + ":" + uuid.NewString()[:8]
+}
+
+// runLease builds the lease a run holds, or nil when leases are disabled.
+func (s *Server) runLease(workflowID string, req RunRequest) (*runtime.LeaseConfig, error) {
+	if s.leaseStore == nil {
+		return nil, nil
+	}
+	request, err := json.Marshal(leasedRunRequest{Workspace: req.Workspace, Request: req})
+	if err != nil {
+		return nil, &runAPIError{Status: http.StatusInternalServerError, Code: "LEASE_ERROR", Message: fmt.Sprintf("encoding run request for workflow %q: %v", workflowID, err)}
+	}
+	return &runtime.LeaseConfig{
+		Store:   s.leaseStore,
+		Owner:   s.leaseOwner,
+		TTL:     s.leaseTTL,
+		Request: request,
+	}, nil
+}
+
+// handleListRunLeases returns the leases of running and interrupted runs.
+func (s *Server) handleListRunLeases(w http.ResponseWriter, r *http.Request) {
+	if s.leaseStore == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "run leases are not configured")
+		return
+	}
+	leases, err := s.leaseStore.ListRunLeases(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if leases == nil {
+		leases = []RunLeaseRecord{}
+	}
+	for i := range leases {
+		// The stored request can carry run inputs; it is only for requeues.
+		leases[i].Request = nil
+	}
+	writeJSON(w, http.StatusOK, leases)
+}
+
+// RunJanitorConfig configures the background run janitor.
+type RunJanitorConfig struct {
+	Runner   *Server
+	Store    RunLeaseStore
+	Interval time.Duration
+	// Requeue resumes each interrupted run from its recorded node outputs.
+	// It needs the server's event store.
+	Requeue    bool
+	BatchLimit int
+	Now        func() time.Time
+	Logger     *slog.Logger
+}
+
+// RunJanitor finds runs whose lease expired because the process running
+// them stopped. Each one is marked interrupted, gets a run.finished event
+// with status "interrupted" and is logged as an error; with Requeue set it
+// is resumed as a new run.
+type RunJanitor struct {
+	runner     *Server
+	store      RunLeaseStore
+	interval   time.Duration
+	requeue    bool
+	batchLimit int
+	now        func() time.Time
+	logger     *slog.Logger
+
+	mu       sync.Mutex
+	cancel   context.CancelFunc
+	done     chan struct{}
+	requeues sync.WaitGroup
+}
+
+// NewRunJanitor creates a run janitor instance.
+func NewRunJanitor(cfg RunJanitorConfig) (*RunJanitor, error) {
+	if cfg.Runner == nil {
+		return nil, errors.New("run janitor runner is nil")
+	}
+	if cfg.Store == nil {
+		return nil, errors.New("run janitor store is nil")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultRunJanitorInterval
+	}
+	if cfg.BatchLimit <= 0 {
+		cfg.BatchLimit = defaultRunJanitorBatchLimit
+	}
+	if cfg.Now == nil {
+		cfg.Now = func() time.Time { return time.Now().UTC() }
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	if cfg.Requeue && cfg.Runner.eventStore == nil {
+		return nil, errors.New("run janitor requeue requires an event store")
+	}
+
+	return &RunJanitor{
+		runner:     cfg.Runner,
+		store:      cfg.Store,
+		interval:   cfg.Interval,
+		requeue:    cfg.Requeue,
+		batchLimit: cfg.BatchLimit,
+		now:        cfg.Now,
+		logger:     cfg.Logger,
+	}, nil
+}
+
+// Start starts background sweeps.
+func (j *RunJanitor) Start(ctx context.Context) error {
+	if j == nil {
+		return errors.New("run janitor is nil")
+	}
+
+	j.mu.Lock()
+	if j.cancel != nil {
+		j.mu.Unlock()
+		return nil
+	}
+	loopCtx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	j.cancel = cancel
+	j.done = done
+	j.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		_ = j.RunOnce(loopCtx)
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-loopCtx.Done():
+				return
+			case <-ticker.C:
+				_ = j.RunOnce(loopCtx)
+			}
+		}
+	}()
+
+	_ = ctx
+	return nil
+}
+
+// Stop stops background sweeps and waits for requeued runs to finish.
+func (j *RunJanitor) Stop(ctx context.Context) error {
+	if j == nil {
+		return nil
+	}
+
+	j.mu.Lock()
+	cancel := j.cancel
+	done := j.done
+	j.cancel = nil
+	j.done = nil
+	j.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+
+	finished := make(chan struct{})
+	go func() {
+		if done != nil {
+			<-done
+		}
+		j.requeues.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// RunOnce executes a single janitor pass.
+func (j *RunJanitor) RunOnce(ctx context.Context) error {
+	if j == nil || j.store == nil || j.runner == nil {
+		return errors.New("run janitor is not configured")
+	}
+	if j.runner.ReadOnly() {
+		// Expired leases wait until read-only mode ends.
+		return nil
+	}
+
+	now := j.now().UTC()
+	expired, err := j.store.ListExpiredLeases(ctx, now, j.batchLimit)
+	if err != nil {
+		return err
+	}
+	for _, lease := range expired {
+		j.interrupt(ctx, lease, now)
+	}
+	return nil
+}
+
+func (j *RunJanitor) interrupt(ctx context.Context, lease RunLeaseRecord, now time.Time) {
+	claimed, err := j.store.MarkLeaseInterrupted(ctx, lease.RunID, now)
+	if err != nil {
+		j.logger.Error("mark run interrupted", "run_id", lease.RunID, "error", err)
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	j.logger.Error("run interrupted: lease expired",
+		"run_id", lease.RunID,
+		"workflow_id", lease.WorkflowID,
+		"owner", lease.Owner,
+		"last_heartbeat_at", lease.HeartbeatAt,
+	)
+	if err := j.recordInterrupted(ctx, lease); err != nil {
+		j.logger.Error("record interrupted run", "run_id", lease.RunID, "error", err)
+	}
+
+	if j.requeue && len(lease.Request) > 0 {
+		j.requeues.Add(1)
+		go func() {
+			defer j.requeues.Done()
+			j.requeueRun(lease)
+		}()
+	}
+}
+
+// recordInterrupted closes the run in its event history, so run lists,
+// stats and SSE subscribers see it end instead of staying "running".
+func (j *RunJanitor) recordInterrupted(ctx context.Context, lease RunLeaseRecord) error {
+	event := runtime.NewEvent(runtime.EventRunFinished, lease.RunID).
+		WithSchemaVersion().
+		WithElapsed(lease.HeartbeatAt.Sub(lease.StartedAt)).
+		WithPayload("status", RunLeaseStatusInterrupted).
+		WithPayload("error", fmt.Sprintf("run lease held by %s expired at %s", lease.Owner, lease.ExpiresAt.UTC().Format(time.RFC3339Nano))).
+		WithPayload("workflow_id", lease.WorkflowID).
+		WithPayload("last_heartbeat_at", lease.HeartbeatAt.UTC().Format(time.RFC3339Nano))
+
+	if store := j.runner.eventStore; store != nil {
+		seq, err := store.LatestSeq(ctx, lease.RunID)
+		if err != nil {
+			return err
+		}
+		event.Seq = seq + 1
+		if err := store.Append(ctx, event); err != nil {
+			return err
+		}
+	}
+	if j.runner.bus != nil {
+		j.runner.bus.Publish(event)
+	}
+	return nil
+}
+
+// requeueRun resumes an interrupted run. Completed nodes are restored from
+// its events, so only the unfinished part runs again.
+func (j *RunJanitor) requeueRun(lease RunLeaseRecord) {
+	var leased leasedRunRequest
+	if err := json.Unmarshal(lease.Request, &leased); err != nil {
+		j.logger.Error("requeue interrupted run: decode request", "run_id", lease.RunID, "error", err)
+		return
+	}
+	req := leased.Request
+	req.Workspace = leased.Workspace
+	req.Options.Stream = false
+	req.Options.ResumeFrom = lease.RunID
+	req.requeue = true
+
+	ctx := context.Background()
+	plan, err := j.runner.planWorkflowRun(ctx, lease.WorkflowID, req)
+	if err != nil {
+		j.logger.Error("requeue interrupted run", "run_id", lease.RunID, "workflow_id", lease.WorkflowID, "error", err)
+		return
+	}
+	plan.class = RunClassScheduled
+	resp, err := j.runner.executeWorkflowRunSync(ctx, lease.WorkflowID, plan, requeueRunMetadataDecorator(lease.RunID))
+	if err != nil {
+		j.logger.Error("requeued run failed", "run_id", lease.RunID, "workflow_id", lease.WorkflowID, "error", err)
+		return
+	}
+	if err := j.store.SetLeaseRequeuedRun(ctx, lease.RunID, resp.RunID); err != nil {
+		j.logger.Error("record requeued run", "run_id", lease.RunID, "requeued_run_id", resp.RunID, "error", err)
+	}
+	j.logger.Info("requeued interrupted run", "run_id", lease.RunID, "requeued_run_id", resp.RunID)
+}
+
+func requeueRunMetadataDecorator(interruptedRunID string) runtime.EventEmitterDecorator {
+	return func(next runtime.EventEmitter) runtime.EventEmitter {
+		return func(e runtime.Event) {
+			if e.Kind == runtime.EventRunStarted {
+				if e.Payload == nil {
+					e.Payload = map[string]any{}
+				}
+				e.Payload["trigger"] = "requeue"
+				e.Payload["requeued_from"] = interruptedRunID
+			}
+			next(e)
+		}
+	}
+}
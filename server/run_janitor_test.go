@@ -0,0 +1,176 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func leaseTestServer(t *testing.T) (*Server, *SQLiteStore, bus.EventStore) {
+	t.Helper()
+	store := newTestSQLiteStore(t)
+	eventStore := newTestEventStore(t)
+	srv := NewServer(ServerConfig{
+		Store:         store,
+		ScheduleStore: store,
+		Providers:     hydrate.ProviderMap{},
+		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) { return nil, nil },
+		Bus:           bus.NewMemBus(bus.MemBusConfig{}),
+		EventStore:    eventStore,
+		LeaseStore:    store,
+		LeaseOwner:    "test-host:1",
+	})
+	return srv, store, eventStore
+}
+
+func acquireExpiredLease(t *testing.T, store *SQLiteStore, runID, workflowID string, request json.RawMessage, expiredAt time.Time) {
+	t.Helper()
+	if err := store.AcquireLease(context.Background(), runtime.RunLease{
+		RunID:       runID,
+		WorkflowID:  workflowID,
+		Owner:       "crashed-host:42",
+		StartedAt:   expiredAt.Add(-time.Minute),
+		HeartbeatAt: expiredAt.Add(-30 * time.Second),
+		ExpiresAt:   expiredAt,
+		Request:     request,
+	}); err != nil {
+		t.Fatalf("AcquireLease: %v", err)
+	}
+}
+
+func TestRunJanitor_MarksExpiredRunsInterrupted(t *testing.T) {
+	srv, store, eventStore := leaseTestServer(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	acquireExpiredLease(t, store, "run-crashed", "wf", nil, now.Add(-time.Second))
+	if err := store.AcquireLease(ctx, runtime.RunLease{
+		RunID: "run-alive", WorkflowID: "wf", Owner: "other", StartedAt: now, HeartbeatAt: now, ExpiresAt: now.Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("AcquireLease: %v", err)
+	}
+	started := runtime.NewEvent(runtime.EventRunStarted, "run-crashed")
+	started.Seq = 1
+	if err := eventStore.Append(ctx, started); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	janitor, err := NewRunJanitor(RunJanitorConfig{Runner: srv, Store: store, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("NewRunJanitor: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := janitor.RunOnce(ctx); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+	}
+
+	leases, err := store.ListRunLeases(ctx)
+	if err != nil {
+		t.Fatalf("ListRunLeases: %v", err)
+	}
+	status := map[string]string{}
+	for _, l := range leases {
+		status[l.RunID] = l.Status
+	}
+	if status["run-crashed"] != RunLeaseStatusInterrupted || status["run-alive"] != RunLeaseStatusRunning {
+		t.Fatalf("lease statuses = %v", status)
+	}
+
+	events, err := eventStore.List(ctx, "run-crashed", 0, 0)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want run.started and one run.finished", len(events))
+	}
+	finished := events[1]
+	if finished.Kind != runtime.EventRunFinished || finished.Seq != 2 || finished.Payload["status"] != "interrupted" {
+		t.Fatalf("finish event = %+v", finished)
+	}
+}
+
+func TestRunJanitor_RequeuesInterruptedRun(t *testing.T) {
+	srv, store, eventStore := leaseTestServer(t)
+	ctx := context.Background()
+	createWorkflowForScheduler(t, srv.Handler(), "requeue-wf")
+
+	request, _ := json.Marshal(leasedRunRequest{Request: RunRequest{Input: map[string]any{"x": "y"}}})
+	now := time.Now().UTC()
+	acquireExpiredLease(t, store, "run-crashed", "requeue-wf", request, now.Add(-time.Second))
+
+	janitor, err := NewRunJanitor(RunJanitorConfig{Runner: srv, Store: store, Requeue: true})
+	if err != nil {
+		t.Fatalf("NewRunJanitor: %v", err)
+	}
+	if err := janitor.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	var interrupted RunLeaseRecord
+	for time.Now().Before(deadline) {
+		leases, err := store.ListRunLeases(ctx)
+		if err != nil {
+			t.Fatalf("ListRunLeases: %v", err)
+		}
+		if len(leases) == 1 && leases[0].RequeuedRunID != "" {
+			interrupted = leases[0]
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := janitor.Stop(ctx); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if interrupted.RequeuedRunID == "" {
+		t.Fatal("interrupted run was not requeued")
+	}
+
+	events, err := eventStore.List(ctx, interrupted.RequeuedRunID, 0, 0)
+	if err != nil || len(events) == 0 {
+		t.Fatalf("requeued run events: %v (%d)", err, len(events))
+	}
+	if events[0].Payload["trigger"] != "requeue" || events[0].Payload["requeued_from"] != "run-crashed" {
+		t.Errorf("run.started payload = %v", events[0].Payload)
+	}
+}
+
+func TestRunWorkflow_ReleasesLease(t *testing.T) {
+	srv, store, _ := leaseTestServer(t)
+	handler := srv.Handler()
+	createWorkflowForScheduler(t, handler, "leased")
+
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/leased/run", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("run status %d: %s", w.Code, w.Body.String())
+	}
+
+	acquireExpiredLease(t, store, "run-crashed", "leased", json.RawMessage(`{"request":{"input":{"secret":"x"}}}`), time.Now().Add(-time.Second))
+	r = httptest.NewRequest(http.MethodGet, "/api/runs/leases", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("list leases status %d: %s", w.Code, w.Body.String())
+	}
+	var leases []RunLeaseRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &leases); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// The finished run released its lease; only the seeded one remains.
+	if len(leases) != 1 || leases[0].RunID != "run-crashed" {
+		t.Fatalf("leases = %+v", leases)
+	}
+	if leases[0].Request != nil {
+		t.Error("lease listing exposed the stored run request")
+	}
+}
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// RunOutput is the public output a run published so far with publish
+// nodes.
+type RunOutput struct {
+	RunID string `json:"run_id"`
+	// Status is running until the run finishes, then the run.finished
+	// status.
+	Status string         `json:"status"`
+	Output map[string]any `json:"output"`
+	// Updates counts the output.updated events folded into Output.
+	Updates   int        `json:"updates"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+}
+
+// foldRunOutput replays a run's events into its public output. Later
+// updates replace earlier values of the same name.
+func foldRunOutput(runID string, events []runtime.Event) RunOutput {
+	out := RunOutput{RunID: runID, Status: "running", Output: map[string]any{}}
+	for _, e := range events {
+		switch e.Kind {
+		case runtime.EventOutputUpdated:
+			values, _ := e.Payload["output"].(map[string]any)
+			for name, value := range values {
+				out.Output[name] = value
+			}
+			out.Updates++
+			at := e.Time.UTC()
+			out.UpdatedAt = &at
+		case runtime.EventRunFinished:
+			if status, _ := e.Payload["status"].(string); status != "" {
+				out.Status = status
+			}
+		}
+	}
+	return out
+}
+
+// handleRunOutput returns what a run published so far. Clients follow
+// updates as output.updated events on the run's event stream.
+func (s *Server) handleRunOutput(w http.ResponseWriter, r *http.Request) {
+	runID := r.PathValue("run_id")
+	if s.eventStore == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "event store not configured")
+		return
+	}
+	events, err := s.eventStore.List(r.Context(), runID, 0, 0)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if len(events) == 0 {
+		writeError(w, http.StatusNotFound, "RUN_NOT_FOUND", fmt.Sprintf("run %q has no recorded events", runID))
+		return
+	}
+	writeJSON(w, http.StatusOK, foldRunOutput(runID, events))
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func TestRunOutput(t *testing.T) {
+	events := newTestEventStore(t)
+	handler := NewServer(ServerConfig{
+		Store:     newTestSQLiteStore(t),
+		Providers: hydrate.ProviderMap{"openai": {APIKey: "sk-test"}},
+		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+			return &workflowLifecycleLLMClient{provider: name}, nil
+		},
+		Bus:        bus.NewMemBus(bus.MemBusConfig{}),
+		EventStore: events,
+	}).Handler()
+
+	createGraphWorkflow(t, handler, graphWorkflowPayloadFromParts("research", []map[string]any{
+		{"id": "publish_docs", "type": "publish", "config": map[string]any{"vars": []any{"documents", "ranking"}}},
+		{"id": "answer", "type": "llm_prompt", "config": map[string]any{
+			"provider": "openai", "prompt_template": "Summarize {{.documents}}", "output_key": "answer",
+		}},
+		{"id": "publish_answer", "type": "publish", "config": map[string]any{"vars": []any{"answer", "documents"}}},
+	}, []map[string]any{
+		{"source": "publish_docs", "target": "answer"},
+		{"source": "answer", "target": "publish_answer"},
+	}, "publish_docs"))
+
+	resp := runWorkflowWithOptions(t, handler, "research", map[string]any{"documents": []any{"refunds.md", "faq.md"}}, RunReqOptions{})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/"+resp.RunID+"/output", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("output: %d %s", w.Code, w.Body.String())
+	}
+	var out RunOutput
+	if err := json.Unmarshal(w.Body.Bytes(), &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.Status != "completed" || out.Updates != 2 || out.UpdatedAt == nil {
+		t.Errorf("output = %+v", out)
+	}
+	if docs, _ := out.Output["documents"].([]any); len(docs) != 2 {
+		t.Errorf("documents = %v", out.Output["documents"])
+	}
+	if answer, _ := out.Output["answer"].(string); answer == "" {
+		t.Errorf("answer = %v", out.Output["answer"])
+	}
+
+	// The documents were published before the answer node started.
+	recorded, err := events.List(t.Context(), resp.RunID, 0, 0)
+	if err != nil {
+		t.Fatalf("list events: %v", err)
+	}
+	var order []string
+	for _, e := range recorded {
+		if e.Kind == runtime.EventOutputUpdated || (e.Kind == runtime.EventNodeStarted && e.NodeID == "answer") {
+			order = append(order, string(e.Kind)+":"+e.NodeID)
+		}
+	}
+	want := []string{"output.updated:publish_docs", "node.started:answer", "output.updated:publish_answer"}
+	if len(order) != len(want) {
+		t.Fatalf("events = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("events = %v, want %v", order, want)
+		}
+	}
+	for _, e := range recorded {
+		if e.Kind == runtime.EventOutputUpdated && e.NodeID == "publish_docs" {
+			if missing, _ := e.Payload["missing"].([]any); len(missing) != 1 || missing[0] != "ranking" {
+				t.Errorf("missing = %v, want [ranking]", e.Payload["missing"])
+			}
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/unknown/output", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown run: %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,420 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RunClass is a run's priority lane, derived from what triggered it.
+type RunClass string
+
+// Run priority classes.
+const (
+	// RunClassInteractive holds manual runs started through the API.
+	RunClassInteractive RunClass = "interactive"
+	// RunClassWebhook holds runs started by webhook triggers.
+	RunClassWebhook RunClass = "webhook"
+	// RunClassScheduled holds schedule runs and requeued interrupted runs.
+	RunClassScheduled RunClass = "scheduled"
+)
+
+// RunClasses lists the priority classes from highest to lowest default
+// weight.
+var RunClasses = []RunClass{RunClassInteractive, RunClassWebhook, RunClassScheduled}
+
+// DefaultRunClassWeights gives interactive runs six of every ten free
+// slots while all lanes are busy.
+var DefaultRunClassWeights = map[RunClass]int{
+	RunClassInteractive: 6,
+	RunClassWebhook:     3,
+	RunClassScheduled:   1,
+}
+
+// DefaultRunQueueMaxWait is how long a queued run waits before starvation
+// protection admits it ahead of higher-weight lanes.
+const DefaultRunQueueMaxWait = time.Minute
+
+// Bounds of RunHints.Priority. Runs default to priority 0.
+const (
+	MinRunPriority = -10
+	MaxRunPriority = 10
+)
+
+// RunHints order a run among the waiting runs of its lane. Runs with a
+// higher priority go first; among equal priorities, runs with the earliest
+// deadline go first, then runs without one, each in arrival order.
+type RunHints struct {
+	Priority int
+	// Deadline is when the caller needs the run finished. Zero means none.
+	Deadline time.Time
+}
+
+// before reports whether a run with hints h is admitted ahead of one with
+// hints o.
+func (h RunHints) before(o RunHints) bool {
+	if h.Priority != o.Priority {
+		return h.Priority > o.Priority
+	}
+	if h.Deadline.IsZero() || o.Deadline.IsZero() {
+		return !h.Deadline.IsZero() && o.Deadline.IsZero()
+	}
+	return h.Deadline.Before(o.Deadline)
+}
+
+// ParseRunClass validates a class name.
+func ParseRunClass(name string) (RunClass, error) {
+	for _, c := range RunClasses {
+		if string(c) == name {
+			return c, nil
+		}
+	}
+	return "", fmt.Errorf("unknown run class %q (use interactive, webhook or scheduled)", name)
+}
+
+// RunQueueConfig configures run admission.
+type RunQueueConfig struct {
+	// MaxConcurrent caps the runs executing at once. Further runs wait in
+	// their class's lane.
+	MaxConcurrent int
+	// Weights sets each class's share of the slots that free up while
+	// several lanes have waiting runs. Missing classes use
+	// DefaultRunClassWeights.
+	Weights map[RunClass]int
+	// MaxWait admits a run that has waited this long before any
+	// higher-weight run, so busy lanes cannot starve the others. Defaults
+	// to DefaultRunQueueMaxWait; a negative value disables it.
+	MaxWait time.Duration
+	// Now defaults to time.Now.
+	Now func() time.Time
+}
+
+// RunQueue admits runs up to a concurrency limit. Waiting runs are queued
+// per class, ordered by their RunHints, and the lanes are admitted from by
+// smooth weighted round robin, so manual runs overtake background batches
+// without shutting them out.
+type RunQueue struct {
+	maxConcurrent int
+	maxWait       time.Duration
+	now           func() time.Time
+
+	mu      sync.Mutex
+	running int
+	lanes   map[RunClass]*runLane
+}
+
+type runLane struct {
+	weight  int
+	current int
+	waiting []*runWaiter
+
+	running  int
+	admitted int64
+	starved  int64
+	canceled int64
+	waitSum  time.Duration
+	waitMax  time.Duration
+}
+
+type runWaiter struct {
+	queuedAt time.Time
+	hints    RunHints
+	ready    chan struct{}
+	admitted bool
+}
+
+// NewRunQueue creates a run queue.
+func NewRunQueue(cfg RunQueueConfig) (*RunQueue, error) {
+	if cfg.MaxConcurrent <= 0 {
+		return nil, fmt.Errorf("run queue max concurrent must be positive")
+	}
+	maxWait := cfg.MaxWait
+	if maxWait == 0 {
+		maxWait = DefaultRunQueueMaxWait
+	}
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+	q := &RunQueue{
+		maxConcurrent: cfg.MaxConcurrent,
+		maxWait:       maxWait,
+		now:           now,
+		lanes:         make(map[RunClass]*runLane, len(RunClasses)),
+	}
+	for _, class := range RunClasses {
+		weight := DefaultRunClassWeights[class]
+		if w, ok := cfg.Weights[class]; ok {
+			if w <= 0 {
+				return nil, fmt.Errorf("run queue weight for %s must be positive", class)
+			}
+			weight = w
+		}
+		q.lanes[class] = &runLane{weight: weight}
+	}
+	for class := range cfg.Weights {
+		if _, ok := q.lanes[class]; !ok {
+			return nil, fmt.Errorf("run queue: unknown run class %q", class)
+		}
+	}
+	return q, nil
+}
+
+// Acquire waits for a run slot in class's lane, queued by hints. The
+// returned release must be called when the run ends. Acquire returns ctx's
+// error if ctx ends first.
+func (q *RunQueue) Acquire(ctx context.Context, class RunClass, hints RunHints) (func(), error) {
+	q.mu.Lock()
+	lane := q.lane(class)
+	w := &runWaiter{queuedAt: q.now(), hints: hints, ready: make(chan struct{})}
+	lane.enqueue(w)
+	q.dispatchLocked()
+	q.mu.Unlock()
+
+	select {
+	case <-w.ready:
+	case <-ctx.Done():
+		q.mu.Lock()
+		if !w.admitted {
+			lane.remove(w)
+			lane.canceled++
+			q.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		q.mu.Unlock()
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			q.running--
+			lane.running--
+			q.dispatchLocked()
+			q.mu.Unlock()
+		})
+	}, nil
+}
+
+func (q *RunQueue) lane(class RunClass) *runLane {
+	if lane, ok := q.lanes[class]; ok {
+		return lane
+	}
+	return q.lanes[RunClassInteractive]
+}
+
+// dispatchLocked admits waiting runs while slots are free.
+func (q *RunQueue) dispatchLocked() {
+	for q.running < q.maxConcurrent {
+		class, starved := q.nextLocked()
+		if class == "" {
+			return
+		}
+		lane := q.lanes[class]
+		w := lane.waiting[0]
+		if starved {
+			w = lane.oldest()
+		}
+		lane.remove(w)
+
+		wait := q.now().Sub(w.queuedAt)
+		lane.waitSum += wait
+		if wait > lane.waitMax {
+			lane.waitMax = wait
+		}
+		lane.admitted++
+		if starved {
+			lane.starved++
+		}
+		lane.running++
+		q.running++
+		w.admitted = true
+		close(w.ready)
+	}
+}
+
+// nextLocked picks the lane to admit from. A lane whose oldest run has
+// waited longer than maxWait goes first, the longest waiting first, and
+// admits that run regardless of its hints; otherwise the lanes with
+// waiting runs share slots by weight.
+func (q *RunQueue) nextLocked() (RunClass, bool) {
+	now := q.now()
+	var (
+		starved    RunClass
+		oldestWait time.Duration
+	)
+	if q.maxWait > 0 {
+		for _, class := range RunClasses {
+			lane := q.lanes[class]
+			if len(lane.waiting) == 0 {
+				continue
+			}
+			if wait := now.Sub(lane.oldest().queuedAt); wait >= q.maxWait && wait > oldestWait {
+				starved, oldestWait = class, wait
+			}
+		}
+	}
+	if starved != "" {
+		return starved, true
+	}
+
+	var (
+		best  RunClass
+		total int
+	)
+	for _, class := range RunClasses {
+		lane := q.lanes[class]
+		if len(lane.waiting) == 0 {
+			continue
+		}
+		lane.current += lane.weight
+		total += lane.weight
+		if best == "" || lane.current > q.lanes[best].current {
+			best = class
+		}
+	}
+	if best != "" {
+		q.lanes[best].current -= total
+	}
+	return best, false
+}
+
+// enqueue inserts w after every waiting run its hints do not go before.
+func (l *runLane) enqueue(w *runWaiter) {
+	i := len(l.waiting)
+	for i > 0 && w.hints.before(l.waiting[i-1].hints) {
+		i--
+	}
+	l.waiting = append(l.waiting, nil)
+	copy(l.waiting[i+1:], l.waiting[i:])
+	l.waiting[i] = w
+}
+
+// oldest returns the lane's longest waiting run. The lane must not be
+// empty.
+func (l *runLane) oldest() *runWaiter {
+	oldest := l.waiting[0]
+	for _, w := range l.waiting[1:] {
+		if w.queuedAt.Before(oldest.queuedAt) {
+			oldest = w
+		}
+	}
+	return oldest
+}
+
+func (l *runLane) remove(w *runWaiter) {
+	for i, queued := range l.waiting {
+		if queued == w {
+			l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// RunQueueStats reports the queue's occupancy and per-class wait times.
+type RunQueueStats struct {
+	MaxConcurrent int                  `json:"max_concurrent"`
+	Running       int                  `json:"running"`
+	Queued        int                  `json:"queued"`
+	MaxWaitMs     int64                `json:"max_wait_ms"`
+	Classes       []RunClassQueueStats `json:"classes"`
+}
+
+// RunClassQueueStats reports one priority lane. Wait times cover runs
+// admitted since the daemon started.
+type RunClassQueueStats struct {
+	Class  RunClass `json:"class"`
+	Weight int      `json:"weight"`
+	// Queued is the lane's current queue depth.
+	Queued  int `json:"queued"`
+	Running int `json:"running"`
+	// OldestWaitMs is how long the lane's longest queued run has waited.
+	OldestWaitMs int64 `json:"oldest_wait_ms"`
+	Admitted     int64 `json:"admitted"`
+	// Starved counts runs admitted by starvation protection.
+	Starved int64 `json:"starved"`
+	// Canceled counts runs whose request ended while they were queued.
+	Canceled   int64 `json:"canceled"`
+	MeanWaitMs int64 `json:"mean_wait_ms"`
+	WaitMaxMs  int64 `json:"wait_max_ms"`
+}
+
+// Stats returns a snapshot of the queue.
+func (q *RunQueue) Stats() RunQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	stats := RunQueueStats{
+		MaxConcurrent: q.maxConcurrent,
+		Running:       q.running,
+		MaxWaitMs:     q.maxWait.Milliseconds(),
+		Classes:       make([]RunClassQueueStats, 0, len(RunClasses)),
+	}
+	for _, class := range RunClasses {
+		lane := q.lanes[class]
+		cs := RunClassQueueStats{
+			Class:     class,
+			Weight:    lane.weight,
+			Queued:    len(lane.waiting),
+			Running:   lane.running,
+			Admitted:  lane.admitted,
+			Starved:   lane.starved,
+			Canceled:  lane.canceled,
+			WaitMaxMs: lane.waitMax.Milliseconds(),
+		}
+		if len(lane.waiting) > 0 {
+			cs.OldestWaitMs = now.Sub(lane.oldest().queuedAt).Milliseconds()
+		}
+		if lane.admitted > 0 {
+			cs.MeanWaitMs = (lane.waitSum / time.Duration(lane.admitted)).Milliseconds()
+		}
+		stats.Queued += cs.Queued
+		stats.Classes = append(stats.Classes, cs)
+	}
+	return stats
+}
+
+// admitRun refuses runs while the daemon is read-only and otherwise waits
+// for a run slot when the server has a run queue. A run still queued at
+// its deadline is refused. The returned release is never nil.
+func (s *Server) admitRun(ctx context.Context, class RunClass, hints RunHints) (func(), error) {
+	if s.ReadOnly() {
+		return nil, s.readOnlyError()
+	}
+	if s.runQueue == nil {
+		return func() {}, nil
+	}
+	if !hints.Deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, hints.Deadline)
+		defer cancel()
+	}
+	release, err := s.runQueue.Acquire(ctx, class, hints)
+	if err != nil {
+		if !hints.Deadline.IsZero() && !time.Now().Before(hints.Deadline) {
+			return nil, &runAPIError{
+				Status:  http.StatusGatewayTimeout,
+				Code:    "DEADLINE_EXCEEDED",
+				Message: fmt.Sprintf("run deadline %s passed before the run was admitted", hints.Deadline.UTC().Format(time.RFC3339)),
+			}
+		}
+		return nil, &runAPIError{
+			Status:  http.StatusServiceUnavailable,
+			Code:    "RUN_QUEUE_TIMEOUT",
+			Message: fmt.Sprintf("run was not admitted before the request ended: %v", err),
+		}
+	}
+	return release, nil
+}
+
+// handleRunQueue returns the run queue's depth and wait-time metrics.
+func (s *Server) handleRunQueue(w http.ResponseWriter, r *http.Request) {
+	if s.runQueue == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "run queue is not configured")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.runQueue.Stats())
+}
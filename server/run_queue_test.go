@@ -0,0 +1,279 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// queueRun starts an Acquire in the background and waits until it is
+// queued. Admitted classes are sent to order.
+func queueRun(t *testing.T, q *RunQueue, class RunClass, order chan<- RunClass) {
+	t.Helper()
+	before := q.Stats().Queued
+	go func() {
+		release, err := q.Acquire(context.Background(), class, RunHints{})
+		if err != nil {
+			return
+		}
+		order <- class
+		release()
+	}()
+	waitFor(t, func() bool { return q.Stats().Queued == before+1 })
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunQueue_InteractiveOvertakesScheduled(t *testing.T) {
+	q, err := NewRunQueue(RunQueueConfig{MaxConcurrent: 1})
+	if err != nil {
+		t.Fatalf("NewRunQueue: %v", err)
+	}
+	hold, err := q.Acquire(context.Background(), RunClassScheduled, RunHints{})
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	order := make(chan RunClass, 3)
+	queueRun(t, q, RunClassScheduled, order)
+	queueRun(t, q, RunClassWebhook, order)
+	queueRun(t, q, RunClassInteractive, order)
+	hold()
+
+	var got []RunClass
+	for range 3 {
+		got = append(got, <-order)
+	}
+	want := []RunClass{RunClassInteractive, RunClassWebhook, RunClassScheduled}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("admission order = %v, want %v", got, want)
+		}
+	}
+
+	stats := q.Stats()
+	if stats.Running != 0 || stats.Queued != 0 {
+		t.Fatalf("stats = %+v, want idle", stats)
+	}
+	if stats.Classes[2].Class != RunClassScheduled || stats.Classes[2].Admitted != 2 {
+		t.Fatalf("scheduled stats = %+v, want 2 admitted", stats.Classes[2])
+	}
+}
+
+func TestRunQueue_WeightedShare(t *testing.T) {
+	q, err := NewRunQueue(RunQueueConfig{
+		MaxConcurrent: 1,
+		Weights:       map[RunClass]int{RunClassInteractive: 2, RunClassScheduled: 1},
+	})
+	if err != nil {
+		t.Fatalf("NewRunQueue: %v", err)
+	}
+	hold, _ := q.Acquire(context.Background(), RunClassInteractive, RunHints{})
+
+	order := make(chan RunClass, 6)
+	for range 3 {
+		queueRun(t, q, RunClassInteractive, order)
+		queueRun(t, q, RunClassScheduled, order)
+	}
+	hold()
+
+	scheduledInFirstThree := 0
+	for i := range 6 {
+		if class := <-order; i < 3 && class == RunClassScheduled {
+			scheduledInFirstThree++
+		}
+	}
+	if scheduledInFirstThree != 1 {
+		t.Fatalf("scheduled runs among the first three admitted = %d, want 1", scheduledInFirstThree)
+	}
+}
+
+func TestRunQueue_StarvationProtection(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	q, err := NewRunQueue(RunQueueConfig{MaxConcurrent: 1, MaxWait: time.Second, Now: clock.Now})
+	if err != nil {
+		t.Fatalf("NewRunQueue: %v", err)
+	}
+	hold, _ := q.Acquire(context.Background(), RunClassInteractive, RunHints{})
+
+	order := make(chan RunClass, 2)
+	queueRun(t, q, RunClassScheduled, order)
+	clock.Advance(2 * time.Second)
+	queueRun(t, q, RunClassInteractive, order)
+
+	stats := q.Stats()
+	if stats.Classes[2].OldestWaitMs != 2000 {
+		t.Fatalf("scheduled oldest wait = %d, want 2000", stats.Classes[2].OldestWaitMs)
+	}
+	hold()
+
+	if first := <-order; first != RunClassScheduled {
+		t.Fatalf("first admitted = %s, want starved scheduled run", first)
+	}
+	<-order
+	stats = q.Stats()
+	if stats.Classes[2].Starved != 1 || stats.Classes[2].WaitMaxMs != 2000 {
+		t.Fatalf("scheduled stats = %+v", stats.Classes[2])
+	}
+}
+
+func TestRunQueue_OrdersByPriorityAndDeadline(t *testing.T) {
+	q, err := NewRunQueue(RunQueueConfig{MaxConcurrent: 1})
+	if err != nil {
+		t.Fatalf("NewRunQueue: %v", err)
+	}
+	hold, _ := q.Acquire(context.Background(), RunClassInteractive, RunHints{})
+
+	soon := time.Now().Add(time.Hour)
+	runs := []struct {
+		name  string
+		hints RunHints
+	}{
+		{"plain", RunHints{}},
+		{"late", RunHints{Deadline: soon.Add(time.Hour)}},
+		{"low", RunHints{Priority: -1}},
+		{"soon", RunHints{Deadline: soon}},
+		{"urgent", RunHints{Priority: 5}},
+		{"plain-2", RunHints{}},
+	}
+	order := make(chan string, len(runs))
+	for i, run := range runs {
+		go func() {
+			release, err := q.Acquire(context.Background(), RunClassInteractive, run.hints)
+			if err != nil {
+				return
+			}
+			order <- run.name
+			release()
+		}()
+		waitFor(t, func() bool { return q.Stats().Queued == i+1 })
+	}
+	hold()
+
+	want := []string{"urgent", "soon", "late", "plain", "plain-2", "low"}
+	for i, name := range want {
+		if got := <-order; got != name {
+			t.Fatalf("admitted #%d = %s, want %s (order %v)", i, got, name, want)
+		}
+	}
+}
+
+func TestAdmitRun_DeadlinePassedWhileQueued(t *testing.T) {
+	q, _ := NewRunQueue(RunQueueConfig{MaxConcurrent: 1})
+	srv := NewServer(ServerConfig{Store: newTestWorkflowStore(t), RunQueue: q})
+	hold, _ := q.Acquire(context.Background(), RunClassInteractive, RunHints{})
+	defer hold()
+
+	_, err := srv.admitRun(context.Background(), RunClassInteractive, RunHints{Deadline: time.Now().Add(20 * time.Millisecond)})
+	var apiErr *runAPIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusGatewayTimeout || apiErr.Code != "DEADLINE_EXCEEDED" {
+		t.Fatalf("err = %v, want DEADLINE_EXCEEDED", err)
+	}
+}
+
+func TestRunQueue_CanceledWhileQueued(t *testing.T) {
+	q, err := NewRunQueue(RunQueueConfig{MaxConcurrent: 1})
+	if err != nil {
+		t.Fatalf("NewRunQueue: %v", err)
+	}
+	hold, _ := q.Acquire(context.Background(), RunClassInteractive, RunHints{})
+	defer hold()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := q.Acquire(ctx, RunClassWebhook, RunHints{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire error = %v, want deadline exceeded", err)
+	}
+	stats := q.Stats()
+	if stats.Queued != 0 || stats.Classes[1].Canceled != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestNewRunQueue_Validates(t *testing.T) {
+	if _, err := NewRunQueue(RunQueueConfig{}); err == nil {
+		t.Error("expected error for zero max concurrent")
+	}
+	if _, err := NewRunQueue(RunQueueConfig{MaxConcurrent: 1, Weights: map[RunClass]int{"batch": 1}}); err == nil {
+		t.Error("expected error for unknown class")
+	}
+	if _, err := NewRunQueue(RunQueueConfig{MaxConcurrent: 1, Weights: map[RunClass]int{RunClassWebhook: 0}}); err == nil {
+		t.Error("expected error for zero weight")
+	}
+}
+
+func TestHandleRunQueue(t *testing.T) {
+	srv := NewServer(ServerConfig{Store: newTestWorkflowStore(t)})
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/runs/queue", nil))
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf("status without queue = %d, want 501", rec.Code)
+	}
+
+	q, _ := NewRunQueue(RunQueueConfig{MaxConcurrent: 2})
+	srv = NewServer(ServerConfig{Store: newTestWorkflowStore(t), RunQueue: q})
+	release, err := srv.admitRun(context.Background(), RunClassWebhook, RunHints{})
+	if err != nil {
+		t.Fatalf("admitRun: %v", err)
+	}
+	defer release()
+
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/runs/queue", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var stats RunQueueStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if stats.MaxConcurrent != 2 || stats.Running != 1 || len(stats.Classes) != 3 || stats.Classes[1].Running != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+}
+
+func TestAdmitRun_CanceledRequest(t *testing.T) {
+	q, _ := NewRunQueue(RunQueueConfig{MaxConcurrent: 1})
+	srv := NewServer(ServerConfig{Store: newTestWorkflowStore(t), RunQueue: q})
+	hold, _ := q.Acquire(context.Background(), RunClassScheduled, RunHints{})
+	defer hold()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := srv.admitRun(ctx, RunClassInteractive, RunHints{})
+	var apiErr *runAPIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable || apiErr.Code != "RUN_QUEUE_TIMEOUT" {
+		t.Fatalf("err = %v, want RUN_QUEUE_TIMEOUT", err)
+	}
+}
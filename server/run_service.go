@@ -2,21 +2,32 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/petal-labs/petalflow/bus"
 	"github.com/petal-labs/petalflow/core"
 	"github.com/petal-labs/petalflow/graph"
 	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/mask"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/propagate"
+	"github.com/petal-labs/petalflow/registry"
 	"github.com/petal-labs/petalflow/runtime"
 )
 
+// defaultRunTimeout bounds runs whose request and workflow set no timeout.
+const defaultRunTimeout = 5 * time.Minute
+
 type runAPIError struct {
 	Status  int
 	Code    string
 	Message string
+	Details []string
 }
 
 func (e *runAPIError) Error() string {
@@ -27,9 +38,102 @@ func (e *runAPIError) Error() string {
 }
 
 type workflowRunPlan struct {
+	workflowID string
+	// runID fixes the run's ID when set, for callers that record it
+	// before the run starts.
+	runID     string
+	nodeDocs  map[string]graph.NodeDoc
+	lifetimes map[string]graph.VarLifetime
+	sla       graph.SLATargets
 	execGraph *graph.BasicGraph
 	env       *core.Envelope
 	timeout   time.Duration
+	masking   *mask.Policy
+
+	// settings are the request's run options layered over the
+	// workflow's run defaults.
+	settings graph.RunSettings
+
+	recordOutputs bool
+	recordInputs  bool
+	resume        *runtime.ResumeState
+
+	chaos *runtime.ChaosConfig
+	lease *runtime.LeaseConfig
+
+	toolCacheBypass nodes.ToolCacheBypass
+	outbound        propagate.Config
+
+	// environment pins what the run was hydrated with.
+	environment RunEnvironment
+
+	// features lists the features each node uses, for feature usage
+	// reports.
+	features map[string]NodeFeatures
+
+	// class is the run's priority lane. Planning sets interactive;
+	// schedule, webhook and requeue callers override it.
+	class RunClass
+	// hints are the caller's priority and deadline.
+	hints RunHints
+
+	// rollout is set for runs made during a workflow rollout.
+	rollout *rolloutRun
+	// deprecation is set for runs of deprecated workflows.
+	deprecation *LifecycleStatus
+}
+
+// runContext carries the plan's masking policy, tool cache bypass and
+// outbound header settings to the nodes of the run.
+func (p *workflowRunPlan) runContext(ctx context.Context) context.Context {
+	ctx = propagate.WithConfig(mask.ContextWithPolicy(ctx, p.masking), p.outbound)
+	return nodes.WithToolCacheBypass(ctx, p.toolCacheBypass)
+}
+
+// applySettings copies everything the plan resolved onto runtime options.
+// It is the only place plan fields reach the runtime, so synchronous and
+// streaming runs cannot drift apart.
+//
+//   - the hop limit, concurrency, error handling and execution budget, and
+//     the caller's priority and deadline;
+//   - node output recording for later resumes, when the server records
+//     them, input recording for later reruns and, when the request resumes
+//     an earlier run, that run's completed nodes;
+//   - the workflow, a "manual" trigger, the node documentation, variable
+//     lifetimes, the pinned environment, the node features and the SLA.
+//     Schedule and webhook runs overwrite the trigger through their
+//     metadata decorators;
+//   - chaos injection and the run lease.
+func (p *workflowRunPlan) applySettings(opts *runtime.RunOptions) {
+	opts.Priority = p.hints.Priority
+	opts.Deadline = p.hints.Deadline
+	opts.MaxHops = p.settings.MaxHops
+	opts.Concurrency = p.settings.Concurrency
+	opts.ContinueOnError = p.settings.ContinueOnError
+	opts.MaxNodeExecutions = p.settings.MaxNodeExecutions
+	opts.NodeVisitLimits = p.settings.NodeVisitLimits
+
+	opts.RecordNodeOutputs = p.recordOutputs
+	opts.RecordInputs = p.recordInputs
+	opts.Resume = p.resume
+
+	opts.RunID = p.runID
+	opts.WorkflowID = p.workflowID
+	opts.TriggerSource = "manual"
+	opts.NodeDocs = p.nodeDocs
+	opts.VarLifetimes = p.lifetimes
+	opts.Environment = p.environment
+	opts.Features = p.features
+	opts.SLA = p.sla
+
+	opts.Chaos = p.chaos
+	opts.Lease = p.lease
+}
+
+// runDecorator tags the run's events with its rollout revision and the
+// deprecation of its workflow.
+func (p *workflowRunPlan) runDecorator() runtime.EventEmitterDecorator {
+	return combineEmitDecorators(rolloutRunDecorator(p.rollout), lifecycleRunDecorator(p.deprecation))
 }
 
 type scheduledRunMetadata struct {
@@ -55,8 +159,26 @@ func (s *Server) planWorkflowRun(ctx context.Context, workflowID string, req Run
 	if rec.Compiled == nil {
 		return nil, &runAPIError{Status: http.StatusBadRequest, Code: "NOT_COMPILED", Message: "workflow has no compiled graph"}
 	}
+	deprecation, err := s.checkLifecycleRun(ctx, workflowID, req.requeue)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.planWorkflowRunWithDefinition(ctx, workflowID, rec.Compiled, req)
+	// Resumed runs keep to the stored revision and stay out of rollouts.
+	compiled, rollout := rec.Compiled, (*rolloutRun)(nil)
+	if req.Options.ResumeFrom == "" {
+		compiled, rollout, err = s.rolloutRevision(ctx, rec)
+		if err != nil {
+			return nil, err
+		}
+	}
+	plan, err := s.planWorkflowRunWithDefinition(ctx, workflowID, compiled, req)
+	if err != nil {
+		return nil, err
+	}
+	plan.rollout = rollout
+	plan.deprecation = deprecation
+	return plan, nil
 }
 
 func (s *Server) planWorkflowRunWithDefinition(
@@ -68,8 +190,11 @@ func (s *Server) planWorkflowRunWithDefinition(
 	if compiled == nil {
 		return nil, &runAPIError{Status: http.StatusBadRequest, Code: "NOT_COMPILED", Message: "workflow has no compiled graph"}
 	}
+	if diags := compiled.CheckRequires(registry.Global()); graph.HasErrors(diags) {
+		return nil, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "REQUIREMENTS_NOT_MET", Message: "workflow requires capabilities this engine does not have", Details: diagMessages(diags)}
+	}
 
-	timeout := 5 * time.Minute
+	var timeout time.Duration
 	if req.Options.Timeout != "" {
 		d, err := time.ParseDuration(req.Options.Timeout)
 		if err != nil {
@@ -77,6 +202,49 @@ func (s *Server) planWorkflowRunWithDefinition(
 		}
 		timeout = d
 	}
+	if req.Options.MaxNodeExecutions < 0 {
+		return nil, &runAPIError{Status: http.StatusBadRequest, Code: "INVALID_BUDGET", Message: "options.max_node_executions must be >= 0"}
+	}
+	for nodeID, limit := range req.Options.NodeVisitLimits {
+		if limit < 0 {
+			return nil, &runAPIError{
+				Status:  http.StatusBadRequest,
+				Code:    "INVALID_BUDGET",
+				Message: fmt.Sprintf("options.node_visit_limits[%q] must be >= 0", nodeID),
+			}
+		}
+	}
+	settings, err := resolveRunSettings(compiled, req.Options, timeout)
+	if err != nil {
+		return nil, err
+	}
+	toolCacheBypass, err := nodes.ParseToolCacheBypass(req.Options.ToolCacheBypass)
+	if err != nil {
+		return nil, &runAPIError{Status: http.StatusBadRequest, Code: "INVALID_TOOL_CACHE_BYPASS", Message: err.Error()}
+	}
+	hints, err := runHints(req.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Options.Chaos != nil {
+		if !s.allowChaos {
+			return nil, &runAPIError{Status: http.StatusForbidden, Code: "CHAOS_DISABLED", Message: "fault injection is disabled on this server"}
+		}
+		if err := req.Options.Chaos.Validate(); err != nil {
+			return nil, &runAPIError{Status: http.StatusBadRequest, Code: "INVALID_CHAOS", Message: err.Error()}
+		}
+	}
+
+	uploads, err := s.resolveRunUploads(ctx, req.Workspace, req.Uploads)
+	if err != nil {
+		return nil, err
+	}
+
+	resume, err := s.loadResumeState(ctx, workflowID, compiled, req)
+	if err != nil {
+		return nil, err
+	}
 
 	humanHandler, err := buildRunHumanHandler(req.Options.Human)
 	if err != nil {
@@ -88,34 +256,207 @@ func (s *Server) planWorkflowRunWithDefinition(
 		return nil, &runAPIError{Status: http.StatusInternalServerError, Code: "TOOL_REGISTRY_ERROR", Message: err.Error()}
 	}
 
-	factory := hydrate.NewLiveNodeFactory(s.providers, s.clientFactory,
+	conditions, err := s.conditionLibrary(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	factoryOpts := []hydrate.LiveNodeOption{
 		hydrate.WithToolRegistry(toolRegistry),
 		hydrate.WithHumanHandler(humanHandler),
-	)
-	execGraph, err := hydrate.HydrateGraph(compiled, s.providers, factory)
+		hydrate.WithConditionLibrary(conditions),
+		hydrate.WithOutputHistory(s.outputHistory, workflowID),
+		hydrate.WithArmStats(s.armStats, workflowID),
+		hydrate.WithStateStore(s.state, workflowID),
+		hydrate.WithTemplateSandbox(s.sandbox),
+		hydrate.WithHolidays(s.holidays),
+		hydrate.WithToolCallbacks(s.toolCallbacks),
+		hydrate.WithToolCache(s.toolCache),
+		hydrate.WithRegionRouter(s.regions),
+	}
+	if s.credentials != nil {
+		factoryOpts = append(factoryOpts, hydrate.WithCredentialVerifier(s.credentials))
+	}
+	if s.outbox != nil {
+		factoryOpts = append(factoryOpts, hydrate.WithOutbox(runOutbox{store: s.outbox, secrets: s.secrets, resumeFrom: req.Options.ResumeFrom}))
+	}
+	if req.Options.Simulate != nil {
+		simulation := graph.MergeSimulation(compiled.Simulate, req.Options.Simulate)
+		if err := simulation.Validate(); err != nil {
+			return nil, &runAPIError{Status: http.StatusBadRequest, Code: "INVALID_SIMULATION", Message: err.Error()}
+		}
+		factoryOpts = append(factoryOpts, hydrate.WithSimulation(simulation))
+	}
+	factory := hydrate.NewLiveNodeFactory(s.providers, s.clientFactory, factoryOpts...)
+	opened, err := s.openSecrets(compiled)
+	if err != nil {
+		return nil, err
+	}
+	execGraph, err := hydrate.HydrateGraph(opened, s.providers, factory)
 	if err != nil {
 		return nil, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "HYDRATE_ERROR", Message: err.Error()}
 	}
 
+	lease, err := s.runLease(workflowID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	environment, err := s.runEnvironment(ctx, compiled)
+	if err != nil {
+		return nil, &runAPIError{Status: http.StatusInternalServerError, Code: "TOOL_REGISTRY_ERROR", Message: err.Error()}
+	}
+
+	input, err := s.presetRunInput(ctx, workflowID, compiled, req.Presets, req.Input)
+	if err != nil {
+		return nil, err
+	}
+	env := EnvelopeFromJSON(input)
+	for _, art := range uploads {
+		env.AppendArtifact(art)
+	}
+
 	return &workflowRunPlan{
-		execGraph: execGraph,
-		env:       EnvelopeFromJSON(req.Input),
-		timeout:   timeout,
+		workflowID: workflowID,
+		nodeDocs:   compiled.NodeDocs(),
+		lifetimes:  compiled.VarLifetimes(),
+		sla:        compiled.SLATargets(),
+		execGraph:  execGraph,
+		env:        env,
+		timeout:    settings.Timeout,
+		masking:    compiled.Masking,
+		settings:   settings,
+
+		recordOutputs: s.recordOutputs && s.eventStore != nil,
+		recordInputs:  s.eventStore != nil,
+		resume:        resume,
+
+		chaos: req.Options.Chaos,
+		lease: lease,
+
+		toolCacheBypass: toolCacheBypass,
+		outbound:        s.outbound,
+
+		environment: environment,
+		features:    runFeatures(compiled),
+
+		class: RunClassInteractive,
+		hints: hints,
 	}, nil
 }
 
-func (s *Server) executeWorkflowRunSync(
-	ctx context.Context,
-	workflowID string,
-	plan *workflowRunPlan,
-	extraDecorator runtime.EventEmitterDecorator,
-) (RunResponse, error) {
-	runCtx, cancel := context.WithTimeout(ctx, plan.timeout)
-	defer cancel()
+// runHints validates the request's priority and deadline. A deadline must
+// be in the future.
+func runHints(opts RunReqOptions) (RunHints, error) {
+	if opts.Priority < MinRunPriority || opts.Priority > MaxRunPriority {
+		return RunHints{}, &runAPIError{
+			Status:  http.StatusBadRequest,
+			Code:    "INVALID_PRIORITY",
+			Message: fmt.Sprintf("options.priority must be between %d and %d", MinRunPriority, MaxRunPriority),
+		}
+	}
+	hints := RunHints{Priority: opts.Priority}
+	if opts.Deadline != nil {
+		if !opts.Deadline.After(time.Now()) {
+			return RunHints{}, &runAPIError{
+				Status:  http.StatusBadRequest,
+				Code:    "INVALID_DEADLINE",
+				Message: fmt.Sprintf("options.deadline %s has already passed", opts.Deadline.UTC().Format(time.RFC3339)),
+			}
+		}
+		hints.Deadline = *opts.Deadline
+	}
+	return hints, nil
+}
 
-	rt := runtime.NewRuntime()
+// resolveRunSettings layers the request's run options over the workflow's
+// run defaults and the runtime's.
+func resolveRunSettings(compiled *graph.GraphDefinition, opts RunReqOptions, timeout time.Duration) (graph.RunSettings, error) {
+	if opts.MaxHops < 0 || opts.Concurrency < 0 {
+		return graph.RunSettings{}, &runAPIError{
+			Status:  http.StatusBadRequest,
+			Code:    "INVALID_RUN_OPTIONS",
+			Message: "options.max_hops and options.concurrency must be >= 0",
+		}
+	}
+	defaults := runtime.DefaultRunOptions()
+	settings, err := compiled.ResolveRunSettings(graph.RunSettings{
+		MaxHops:         defaults.MaxHops,
+		Concurrency:     defaults.Concurrency,
+		ContinueOnError: defaults.ContinueOnError,
+		Timeout:         defaultRunTimeout,
+	}, graph.RunOverrides{
+		MaxHops:           opts.MaxHops,
+		Concurrency:       opts.Concurrency,
+		ContinueOnError:   opts.ContinueOnError,
+		Timeout:           timeout,
+		MaxNodeExecutions: opts.MaxNodeExecutions,
+		NodeVisitLimits:   opts.NodeVisitLimits,
+	})
+	if err != nil {
+		return graph.RunSettings{}, &runAPIError{Status: http.StatusBadRequest, Code: "INVALID_RUN_OPTIONS", Message: err.Error()}
+	}
+	return settings, nil
+}
+
+// loadResumeState rebuilds the completed nodes of the run the request
+// resumes from the event store. Nodes the workflow declares idempotent are
+// left to re-execute. Runs whose run.started event names another workflow
+// are not found, except for janitor requeues, whose lease names it. A run
+// with completed nodes whose outputs were not recorded is rejected rather
+// than re-executing them.
+func (s *Server) loadResumeState(ctx context.Context, workflowID string, compiled *graph.GraphDefinition, req RunRequest) (*runtime.ResumeState, error) {
+	runID := req.Options.ResumeFrom
+	if runID == "" {
+		return nil, nil
+	}
+	if s.eventStore == nil {
+		return nil, &runAPIError{Status: http.StatusNotImplemented, Code: "NOT_IMPLEMENTED", Message: "resuming runs requires an event store"}
+	}
+	events, err := s.eventStore.List(ctx, runID, 0, 0)
+	if err != nil {
+		return nil, &runAPIError{Status: http.StatusInternalServerError, Code: "STORE_ERROR", Message: err.Error()}
+	}
+	if len(events) == 0 {
+		return nil, &runAPIError{Status: http.StatusNotFound, Code: "RUN_NOT_FOUND", Message: fmt.Sprintf("run %q has no recorded events", runID)}
+	}
+	if !req.requeue && runWorkflowID(events) != workflowID {
+		return nil, &runAPIError{Status: http.StatusNotFound, Code: "RUN_NOT_FOUND", Message: fmt.Sprintf("run %q is not a run of workflow %q", runID, workflowID)}
+	}
+	state := runtime.NewResumeState(events, compiled.IdempotentNodes())
+	if missing := state.Unrestorable(); len(missing) > 0 {
+		return nil, &runAPIError{Status: http.StatusConflict, Code: "RESUME_OUTPUTS_MISSING", Message: fmt.Sprintf(
+			"run %q did not record the outputs of completed nodes %s; they cannot be restored or safely re-executed (enable server.stores.events.record_outputs)",
+			runID, strings.Join(missing, ", "))}
+	}
+	return state, nil
+}
+
+// runWorkflowID returns the workflow_id of a run's run.started event.
+func runWorkflowID(events []runtime.Event) string {
+	for _, e := range events {
+		if e.Kind == runtime.EventRunStarted {
+			id, _ := e.Payload["workflow_id"].(string)
+			return id
+		}
+	}
+	return ""
+}
+
+// runOptions builds the runtime options shared by synchronous and streaming
+// runs of plan: the plan's settings, the server's memory limits, hooks and
+// event decorators, and the event bus and handlers. Callers attach the
+// event store subscriber and the queue wait.
+func (s *Server) runOptions(plan *workflowRunPlan, extraDecorator runtime.EventEmitterDecorator) runtime.RunOptions {
 	opts := runtime.DefaultRunOptions()
-	opts.EventEmitterDecorator = combineEmitDecorators(s.emitDecorator, extraDecorator)
+	plan.applySettings(&opts)
+	opts.Memory = s.runMemoryConfig()
+	opts.OnInvalidEvent = s.invalidEventHandler()
+	opts.NodeSnapshot = s.nodeSnapshotLogger(plan)
+	opts.EventEmitterDecorator = combineEmitDecorators(
+		combineEmitDecorators(s.emitDecorator, combineEmitDecorators(extraDecorator, plan.runDecorator())),
+		maskingEmitDecorator(plan.masking),
+	)
 
 	if s.bus != nil {
 		opts.EventBus = s.bus
@@ -123,6 +464,30 @@ func (s *Server) executeWorkflowRunSync(
 	if s.runtimeEvents != nil {
 		opts.EventHandler = runtime.MultiEventHandler(opts.EventHandler, s.runtimeEvents)
 	}
+	opts.EventHandler = runtime.MultiEventHandler(opts.EventHandler, s.waits.handler(plan.workflowID), s.rolloutHandler(plan.workflowID, plan.rollout))
+	return opts
+}
+
+func (s *Server) executeWorkflowRunSync(
+	ctx context.Context,
+	workflowID string,
+	plan *workflowRunPlan,
+	extraDecorator runtime.EventEmitterDecorator,
+) (RunResponse, error) {
+	queued := time.Now()
+	release, err := s.admitRun(ctx, plan.class, plan.hints)
+	if err != nil {
+		return RunResponse{}, err
+	}
+	defer release()
+	queueWait := time.Since(queued)
+
+	runCtx, cancel := context.WithTimeout(plan.runContext(ctx), plan.timeout)
+	defer cancel()
+
+	rt := runtime.NewRuntime()
+	opts := s.runOptions(plan, extraDecorator)
+	opts.QueueWait = queueWait
 
 	if s.eventStore != nil && s.bus != nil {
 		sub := bus.NewStoreSubscriber(s.eventStore, s.logger)
@@ -134,9 +499,21 @@ func (s *Server) executeWorkflowRunSync(
 	completedAt := time.Now().UTC()
 
 	if err != nil {
+		if errors.Is(err, runtime.ErrDeadlineExceeded) {
+			return RunResponse{}, &runAPIError{Status: http.StatusGatewayTimeout, Code: "DEADLINE_EXCEEDED", Message: err.Error()}
+		}
 		if runCtx.Err() == context.DeadlineExceeded {
 			return RunResponse{}, &runAPIError{Status: http.StatusGatewayTimeout, Code: "TIMEOUT", Message: err.Error()}
 		}
+		if errors.Is(err, runtime.ErrBudgetExhausted) {
+			return RunResponse{}, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "BUDGET_EXHAUSTED", Message: err.Error()}
+		}
+		if errors.Is(err, runtime.ErrMemoryLimitExceeded) {
+			return RunResponse{}, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "MEMORY_LIMIT_EXCEEDED", Message: err.Error()}
+		}
+		if pe, ok := core.AsProviderError(err); ok {
+			return RunResponse{}, providerRunAPIError(pe, err)
+		}
 		return RunResponse{}, &runAPIError{Status: http.StatusInternalServerError, Code: "RUNTIME_ERROR", Message: err.Error()}
 	}
 
@@ -153,9 +530,22 @@ func (s *Server) executeWorkflowRunSync(
 		CompletedAt: completedAt,
 		DurationMs:  completedAt.Sub(startedAt).Milliseconds(),
 		Output:      EnvelopeToJSON(result),
+		Errors:      NodeErrorsToJSON(result),
 	}, nil
 }
 
+// providerRunAPIError reports a run that failed on an LLM provider call
+// under the provider error code, uppercased, with the remediation hint as
+// its detail.
+func providerRunAPIError(pe *core.ProviderError, err error) *runAPIError {
+	return &runAPIError{
+		Status:  http.StatusBadGateway,
+		Code:    strings.ToUpper(string(pe.Code)),
+		Message: err.Error(),
+		Details: []string{pe.Remediation()},
+	}
+}
+
 func (s *Server) runScheduledWorkflow(
 	ctx context.Context,
 	workflowID string,
@@ -166,6 +556,7 @@ func (s *Server) runScheduledWorkflow(
 	if err != nil {
 		return RunResponse{}, err
 	}
+	plan.class = RunClassScheduled
 
 	decorator := scheduleRunMetadataDecorator(meta)
 	return s.executeWorkflowRunSync(ctx, workflowID, plan, decorator)
@@ -187,6 +578,28 @@ func combineEmitDecorators(
 	}
 }
 
+// maskingEmitDecorator applies the workflow's masking policy to every event
+// payload. It must be the outermost decorator so the store, bus, SSE
+// subscribers and any server-level decorators only ever see masked data.
+func maskingEmitDecorator(policy *mask.Policy) runtime.EventEmitterDecorator {
+	if policy.Empty() {
+		return nil
+	}
+	return func(next runtime.EventEmitter) runtime.EventEmitter {
+		return func(e runtime.Event) {
+			if output, ok := e.Payload["output"]; ok && e.Kind == runtime.EventNodeFinished &&
+				policy.Matches(map[string]any{"output": output}) {
+				// A masked output would resume the node with masked values,
+				// so it is left out and a resumed run executes the node again.
+				e.Payload = maps.Clone(e.Payload)
+				delete(e.Payload, "output")
+			}
+			e.Payload = policy.ApplyMap(e.Payload)
+			next(e)
+		}
+	}
+}
+
 func scheduleRunMetadataDecorator(meta scheduledRunMetadata) runtime.EventEmitterDecorator {
 	return func(next runtime.EventEmitter) runtime.EventEmitter {
 		return func(e runtime.Event) {
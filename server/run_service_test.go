@@ -0,0 +1,350 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/mask"
+	"github.com/petal-labs/petalflow/propagate"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func TestMaskingEmitDecorator(t *testing.T) {
+	if maskingEmitDecorator(nil) != nil {
+		t.Fatal("nil policy should not produce a decorator")
+	}
+
+	policy := &mask.Policy{Rules: []mask.Rule{
+		{Field: "arguments.api_key", Strategy: mask.StrategyDrop},
+	}}
+	var got runtime.Event
+	emit := maskingEmitDecorator(policy)(func(e runtime.Event) { got = e })
+
+	args := map[string]any{"api_key": "sk-123", "query": "weather"}
+	emit(runtime.NewEvent(runtime.EventToolCall, "run-1").WithPayload("arguments", args))
+
+	masked, ok := got.Payload["arguments"].(map[string]any)
+	if !ok {
+		t.Fatalf("arguments type = %T, want map[string]any", got.Payload["arguments"])
+	}
+	if _, ok := masked["api_key"]; ok {
+		t.Fatal("arguments.api_key should be dropped from the emitted event")
+	}
+	if masked["query"] != "weather" {
+		t.Fatalf("arguments.query = %v, want weather", masked["query"])
+	}
+	if args["api_key"] != "sk-123" {
+		t.Fatal("masking must not mutate the original payload")
+	}
+}
+
+func TestMaskingEmitDecorator_LeavesOutMaskedOutputs(t *testing.T) {
+	policy := &mask.Policy{Rules: []mask.Rule{{Field: "email", Strategy: mask.StrategyHash}}}
+	var got runtime.Event
+	emit := maskingEmitDecorator(policy)(func(e runtime.Event) { got = e })
+
+	output := map[string]any{"vars": map[string]any{"email": "a@example.com"}}
+	emit(runtime.NewEvent(runtime.EventNodeFinished, "run-1").WithNode("lookup", "func").WithPayload("output", output))
+	if _, ok := got.Payload["output"]; ok {
+		t.Fatalf("masked output was recorded: %v", got.Payload)
+	}
+
+	output = map[string]any{"vars": map[string]any{"name": "Ada"}}
+	emit(runtime.NewEvent(runtime.EventNodeFinished, "run-1").WithNode("lookup", "func").WithPayload("output", output))
+	if _, ok := got.Payload["output"]; !ok {
+		t.Fatalf("unmasked output was left out: %v", got.Payload)
+	}
+}
+
+func TestRunWorkflow_MaskingAppliesToWebhookCall(t *testing.T) {
+	var received map[string]any
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	srv := testServer(t)
+	handler := srv.Handler()
+
+	payload := graphWorkflowPayload("masked_webhook", map[string]any{
+		"id":   "notify",
+		"type": "webhook_call",
+		"config": map[string]any{
+			"url":    target.URL,
+			"method": "POST",
+		},
+	})
+	payload["masking"] = map[string]any{
+		"rules": []any{
+			map[string]any{"field": "ssn", "strategy": "drop"},
+			map[string]any{"field": "card", "strategy": "partial"},
+		},
+	}
+	createGraphWorkflow(t, handler, payload)
+
+	run := runWorkflow(t, handler, "masked_webhook", map[string]any{
+		"ssn":  "123-45-6789",
+		"card": "4111111111111111",
+		"name": "Ada",
+	})
+
+	vars, ok := received["vars"].(map[string]any)
+	if !ok {
+		t.Fatalf("webhook body vars type = %T, want map[string]any", received["vars"])
+	}
+	if _, ok := vars["ssn"]; ok {
+		t.Fatal("ssn should be dropped from webhook body")
+	}
+	if vars["card"] != "************1111" {
+		t.Fatalf("card = %v, want partially masked", vars["card"])
+	}
+	if vars["name"] != "Ada" {
+		t.Fatalf("name = %v, want Ada", vars["name"])
+	}
+
+	// Masking applies to data leaving the runtime, not to the run's own output.
+	if run.Output.Vars["ssn"] != "123-45-6789" {
+		t.Fatalf("run output ssn = %v, want unmasked", run.Output.Vars["ssn"])
+	}
+}
+
+func TestRunWorkflow_PropagatesRunHeaders(t *testing.T) {
+	var received http.Header
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer target.Close()
+
+	srv := testServer(t)
+	srv.outbound = propagate.Config{Enabled: true, Extra: map[string]string{"X-Deployment": "eu-1"}}
+	handler := srv.Handler()
+
+	createGraphWorkflow(t, handler, graphWorkflowPayload("correlated_webhook", map[string]any{
+		"id":     "notify",
+		"type":   "webhook_call",
+		"config": map[string]any{"url": target.URL},
+	}))
+	run := runWorkflow(t, handler, "correlated_webhook", nil)
+
+	if got := received.Get(propagate.HeaderRunID); got == "" || got != run.RunID {
+		t.Errorf("run ID header = %q, want %q", got, run.RunID)
+	}
+	if received.Get(propagate.HeaderNodeID) != "notify" || received.Get("X-Deployment") != "eu-1" {
+		t.Errorf("headers = %v", received)
+	}
+	if got, want := received.Get(propagate.HeaderTraceParent), propagate.DeriveTraceParent(run.RunID, "notify"); got != want {
+		t.Errorf("traceparent = %q, want %q", got, want)
+	}
+}
+
+func TestRunWorkflow_BudgetExhausted(t *testing.T) {
+	srv := testServer(t)
+	handler := srv.Handler()
+
+	createGraphWorkflow(t, handler, graphWorkflowPayloadFromParts("budget_fanout", []map[string]any{
+		{"id": "a", "type": "noop"},
+		{"id": "b", "type": "noop"},
+		{"id": "c", "type": "noop"},
+	}, []map[string]any{
+		{"source": "a", "target": "b"},
+		{"source": "a", "target": "c"},
+	}, "a"))
+
+	body := mustJSON(t, RunRequest{
+		Options: RunReqOptions{Timeout: "30s", MaxNodeExecutions: 2},
+	})
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/budget_fanout/run", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422 body=%s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Body.String(), "BUDGET_EXHAUSTED") || !strings.Contains(w.Body.String(), "node c") {
+		t.Fatalf("unexpected body: %s", w.Body.String())
+	}
+
+	body = mustJSON(t, RunRequest{Options: RunReqOptions{Timeout: "30s", NodeVisitLimits: map[string]int{"a": -1}}})
+	r = httptest.NewRequest(http.MethodPost, "/api/workflows/budget_fanout/run", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_BUDGET") {
+		t.Fatalf("negative limit: status = %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestRunWorkflow_ToolCacheBypass(t *testing.T) {
+	srv := testServer(t)
+	handler := srv.Handler()
+
+	createGraphWorkflow(t, handler, graphWorkflowPayloadFromParts("cache_bypass", []map[string]any{
+		{"id": "a", "type": "noop"},
+	}, nil, "a"))
+
+	for _, tc := range []struct {
+		bypass string
+		status int
+	}{
+		{"refresh", http.StatusOK},
+		{"skip", http.StatusOK},
+		{"always", http.StatusBadRequest},
+	} {
+		body := mustJSON(t, RunRequest{Options: RunReqOptions{Timeout: "30s", ToolCacheBypass: tc.bypass}})
+		r := httptest.NewRequest(http.MethodPost, "/api/workflows/cache_bypass/run", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != tc.status {
+			t.Fatalf("%s: status = %d, want %d body=%s", tc.bypass, w.Code, tc.status, w.Body.String())
+		}
+		if tc.status == http.StatusBadRequest && !strings.Contains(w.Body.String(), "INVALID_TOOL_CACHE_BYPASS") {
+			t.Fatalf("%s: unexpected body: %s", tc.bypass, w.Body.String())
+		}
+	}
+}
+
+func TestRunWorkflow_RunDefaults(t *testing.T) {
+	srv := testServer(t)
+	handler := srv.Handler()
+
+	payload := graphWorkflowPayloadFromParts("defaults_fanout", []map[string]any{
+		{"id": "a", "type": "noop"},
+		{"id": "b", "type": "noop"},
+		{"id": "c", "type": "noop"},
+	}, []map[string]any{
+		{"source": "a", "target": "b"},
+		{"source": "a", "target": "c"},
+	}, "a")
+	payload["run_defaults"] = map[string]any{
+		"timeout":             "30s",
+		"max_node_executions": 2,
+		"limits":              map[string]any{"concurrency": 2, "max_node_executions": 3},
+	}
+	createGraphWorkflow(t, handler, payload)
+
+	run := func(opts RunReqOptions) *httptest.ResponseRecorder {
+		body := mustJSON(t, RunRequest{Options: opts})
+		r := httptest.NewRequest(http.MethodPost, "/api/workflows/defaults_fanout/run", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := run(RunReqOptions{}); w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "BUDGET_EXHAUSTED") {
+		t.Fatalf("default budget: status = %d body=%s", w.Code, w.Body.String())
+	}
+	if w := run(RunReqOptions{MaxNodeExecutions: 3, Concurrency: 2}); w.Code != http.StatusOK {
+		t.Fatalf("override within limits: status = %d body=%s", w.Code, w.Body.String())
+	}
+	if w := run(RunReqOptions{Concurrency: 4}); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_RUN_OPTIONS") {
+		t.Fatalf("override above limit: status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	payload["run_defaults"] = map[string]any{"timeout": "forever"}
+	payload["id"] = "defaults_invalid"
+	body := mustJSON(t, payload)
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "Run defaults") {
+		t.Fatalf("invalid run_defaults: status = %d body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestRunWorkflow_Simulate(t *testing.T) {
+	srv := testServer(t)
+	handler := srv.Handler()
+
+	payload := graphWorkflowPayload("simulated", map[string]any{
+		"id":   "answer",
+		"type": "llm_prompt",
+		"config": map[string]any{
+			"provider":        "anthropic",
+			"prompt_template": "{{.question}}",
+			"output_key":      "answer",
+		},
+	})
+	payload["simulate"] = map[string]any{
+		"nodes": map[string]any{"answer": map[string]any{"response": "from the workflow"}},
+	}
+	createGraphWorkflow(t, handler, payload)
+
+	run := runWorkflowWithOptions(t, handler, "simulated", map[string]any{"question": "hi"}, RunReqOptions{
+		Simulate: &graph.SimulationDef{},
+	})
+	if got := run.Output.Vars["answer"]; got != "from the workflow" {
+		t.Fatalf("answer = %v, want workflow simulation", got)
+	}
+
+	run = runWorkflowWithOptions(t, handler, "simulated", nil, RunReqOptions{
+		Simulate: &graph.SimulationDef{Nodes: map[string]graph.SimulatedResponse{"answer": {Response: "from the request"}}},
+	})
+	if got := run.Output.Vars["answer"]; got != "from the request" {
+		t.Fatalf("answer = %v, want request override", got)
+	}
+}
+
+// providerFailingLLM fails every completion with a classified provider error.
+type providerFailingLLM struct{}
+
+func (providerFailingLLM) Complete(context.Context, core.LLMRequest) (core.LLMResponse, error) {
+	return core.LLMResponse{}, &core.ProviderError{
+		Provider: "openai",
+		Code:     core.ProviderErrContextLength,
+		Status:   http.StatusBadRequest,
+		Err:      errors.New("openai: maximum context length is 8192 tokens (status=400)"),
+	}
+}
+
+func TestRunWorkflow_ProviderErrorCodes(t *testing.T) {
+	handler := NewServer(ServerConfig{
+		Store:     newTestSQLiteStore(t),
+		Providers: hydrate.ProviderMap{"openai": {APIKey: "k"}},
+		ClientFactory: func(string, hydrate.ProviderConfig) (core.LLMClient, error) {
+			return providerFailingLLM{}, nil
+		},
+	}).Handler()
+	createGraphWorkflow(t, handler, graphWorkflowPayloadFromParts("oversized", []map[string]any{
+		{"id": "answer", "type": "llm_prompt", "config": map[string]any{"provider": "openai", "model": "gpt-4o-mini"}},
+	}, nil, "answer"))
+
+	run := func(continueOnError bool) *httptest.ResponseRecorder {
+		body := mustJSON(t, RunRequest{Options: RunReqOptions{Timeout: "30s", ContinueOnError: &continueOnError}})
+		r := httptest.NewRequest(http.MethodPost, "/api/workflows/oversized/run", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := run(false)
+	var failed apiError
+	if err := json.Unmarshal(w.Body.Bytes(), &failed); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if w.Code != http.StatusBadGateway || failed.Error.Code != "CONTEXT_LENGTH_EXCEEDED" ||
+		len(failed.Error.Details) != 1 || failed.Error.Details[0] != core.ProviderErrContextLength.Remediation() {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	w = run(true)
+	var resp RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if w.Code != http.StatusOK || len(resp.Errors) != 1 || resp.Errors[0].NodeID != "answer" ||
+		resp.Errors[0].ErrorCode != "context_length_exceeded" || resp.Errors[0].Remediation == "" {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+}
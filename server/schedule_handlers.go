@@ -13,10 +13,14 @@ import (
 )
 
 type workflowScheduleRequest struct {
-	Cron    string         `json:"cron,omitempty"`
-	Enabled *bool          `json:"enabled,omitempty"`
-	Input   map[string]any `json:"input,omitempty"`
-	Options *RunReqOptions `json:"options,omitempty"`
+	Cron        string         `json:"cron,omitempty"`
+	Enabled     *bool          `json:"enabled,omitempty"`
+	Input       map[string]any `json:"input,omitempty"`
+	Options     *RunReqOptions `json:"options,omitempty"`
+	Timezone    *string        `json:"timezone,omitempty"`
+	Jitter      *string        `json:"jitter,omitempty"`
+	MissedFire  *string        `json:"missed_fire,omitempty"`
+	MaxBackfill *int           `json:"max_backfill,omitempty"`
 }
 
 func (s *Server) handleListWorkflowSchedules(w http.ResponseWriter, r *http.Request) {
@@ -183,7 +187,7 @@ func (s *Server) workflowExists(ctx context.Context, workflowID string, w http.R
 }
 
 func applyScheduleRequest(base WorkflowSchedule, req workflowScheduleRequest, creating bool, now time.Time) (WorkflowSchedule, error) {
-	currentCron := base.Cron
+	current := base
 	wasEnabled := base.Enabled
 
 	if cleanCron := strings.TrimSpace(req.Cron); cleanCron != "" {
@@ -198,6 +202,18 @@ func applyScheduleRequest(base WorkflowSchedule, req workflowScheduleRequest, cr
 	if req.Options != nil {
 		base.Options = *req.Options
 	}
+	if req.Timezone != nil {
+		base.Timezone = strings.TrimSpace(*req.Timezone)
+	}
+	if req.Jitter != nil {
+		base.Jitter = strings.TrimSpace(*req.Jitter)
+	}
+	if req.MissedFire != nil {
+		base.MissedFire = strings.TrimSpace(*req.MissedFire)
+	}
+	if req.MaxBackfill != nil {
+		base.MaxBackfill = *req.MaxBackfill
+	}
 
 	if strings.TrimSpace(base.Cron) == "" {
 		return WorkflowSchedule{}, fmt.Errorf("cron is required")
@@ -213,17 +229,29 @@ func applyScheduleRequest(base WorkflowSchedule, req workflowScheduleRequest, cr
 	if _, err := buildRunHumanHandler(base.Options.Human); err != nil {
 		return WorkflowSchedule{}, err
 	}
-	if _, err := parseCronExpressionUTC(base.Cron); err != nil {
+	cs, err := parseCronSchedule(base.Cron, base.Timezone)
+	if err != nil {
 		return WorkflowSchedule{}, err
 	}
+	if _, err := base.jitter(); err != nil {
+		return WorkflowSchedule{}, err
+	}
+	switch base.MissedFire {
+	case "", MissedFireImmediately, MissedFireSkip, MissedFireCatchUp:
+	default:
+		return WorkflowSchedule{}, fmt.Errorf("missed_fire must be %s, %s or %s, got %q", MissedFireImmediately, MissedFireSkip, MissedFireCatchUp, base.MissedFire)
+	}
+	if base.MaxBackfill < 0 || base.MaxBackfill > MaxScheduleMaxBackfill {
+		return WorkflowSchedule{}, fmt.Errorf("max_backfill must be from 0 to %d", MaxScheduleMaxBackfill)
+	}
+	if base.MaxBackfill > 0 && base.MissedFire != MissedFireCatchUp {
+		return WorkflowSchedule{}, fmt.Errorf("max_backfill only applies to missed_fire %q", MissedFireCatchUp)
+	}
 
-	cronChanged := strings.TrimSpace(currentCron) != "" && currentCron != base.Cron
-	if base.Enabled && (creating || cronChanged || (!wasEnabled && base.Enabled) || base.NextRunAt.IsZero()) {
-		nextRunAt, err := nextCronRunUTC(base.Cron, now.UTC())
-		if err != nil {
-			return WorkflowSchedule{}, err
-		}
-		base.NextRunAt = nextRunAt
+	timingChanged := strings.TrimSpace(current.Cron) != "" &&
+		(current.Cron != base.Cron || current.Timezone != base.Timezone || current.Jitter != base.Jitter)
+	if base.Enabled && (creating || timingChanged || !wasEnabled || base.NextRunAt.IsZero()) {
+		base.scheduleNext(cs, now.UTC())
 	}
 
 	return base, nil
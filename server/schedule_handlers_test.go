@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestWorkflowScheduleHandlers_CRUD(t *testing.T) {
@@ -118,6 +119,66 @@ func TestWorkflowScheduleHandlers_Validation(t *testing.T) {
 	if w.Code != http.StatusBadRequest {
 		t.Fatalf("stream option status=%d, want %d body=%s", w.Code, http.StatusBadRequest, w.Body.String())
 	}
+
+	for name, body := range map[string]workflowScheduleRequest{
+		"unknown timezone":           {Cron: "* * * * *", Timezone: stringPtr("Mars/Olympus_Mons")},
+		"jitter over an hour":        {Cron: "* * * * *", Jitter: stringPtr("2h")},
+		"unknown missed_fire":        {Cron: "* * * * *", MissedFire: stringPtr("later")},
+		"max_backfill without catch": {Cron: "* * * * *", MaxBackfill: intPtr(5)},
+		"max_backfill over limit":    {Cron: "* * * * *", MissedFire: stringPtr(MissedFireCatchUp), MaxBackfill: intPtr(MaxScheduleMaxBackfill + 1)},
+	} {
+		req = httptest.NewRequest(http.MethodPost, "/api/workflows/schedule-validation/schedules", bytes.NewReader(mustJSON(t, body)))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("%s status=%d, want %d body=%s", name, w.Code, http.StatusBadRequest, w.Body.String())
+		}
+	}
+}
+
+func TestWorkflowScheduleHandlers_TimezoneAndMissedFire(t *testing.T) {
+	srv := testServer(t)
+	handler := srv.Handler()
+	mustCreateWorkflowForScheduleHandlers(t, handler, "schedule-timezone")
+
+	body := mustJSON(t, workflowScheduleRequest{
+		Cron:        "30 2 * * *",
+		Timezone:    stringPtr("America/New_York"),
+		Jitter:      stringPtr("5m"),
+		MissedFire:  stringPtr(MissedFireCatchUp),
+		MaxBackfill: intPtr(3),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/workflows/schedule-timezone/schedules", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status=%d, want %d body=%s", w.Code, http.StatusCreated, w.Body.String())
+	}
+
+	var created WorkflowSchedule
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if created.Timezone != "America/New_York" || created.Jitter != "5m" || created.MissedFire != MissedFireCatchUp || created.MaxBackfill != 3 {
+		t.Fatalf("created=%+v", created)
+	}
+	if local := created.NextFireAt.In(mustLoadLocation(t, "America/New_York")); local.Minute() != 30 || (local.Hour() != 2 && local.Hour() != 3) {
+		t.Fatalf("next_fire_at=%s, want 02:30 New York time", local)
+	}
+	if delay := created.NextRunAt.Sub(created.NextFireAt); delay < 0 || delay >= 5*time.Minute {
+		t.Fatalf("next_run_at - next_fire_at=%s, want within jitter", delay)
+	}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	return loc
 }
 
 func mustCreateWorkflowForScheduleHandlers(t *testing.T, handler http.Handler, workflowID string) {
@@ -135,3 +196,11 @@ func mustCreateWorkflowForScheduleHandlers(t *testing.T, handler http.Handler, w
 func boolPtr(v bool) *bool {
 	return &v
 }
+
+func stringPtr(v string) *string {
+	return &v
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -16,6 +17,30 @@ const (
 	ScheduleRunStatusCompleted      = "completed"
 	ScheduleRunStatusFailed         = "failed"
 	ScheduleRunStatusSkippedOverlap = "skipped_overlap"
+	ScheduleRunStatusSkippedMissed  = "skipped_missed"
+)
+
+// Missed-fire policies decide what happens to fire times a schedule missed,
+// such as while the daemon was down.
+const (
+	// MissedFireImmediately runs once for the missed fire times, right
+	// away.
+	MissedFireImmediately = "fire_immediately"
+	// MissedFireSkip drops missed fire times and waits for the next one.
+	MissedFireSkip = "skip"
+	// MissedFireCatchUp runs once for each missed fire time, oldest first,
+	// up to MaxBackfill of the most recent ones.
+	MissedFireCatchUp = "catch_up"
+)
+
+const (
+	// DefaultScheduleMaxBackfill is the MaxBackfill of catch_up schedules
+	// that do not set one.
+	DefaultScheduleMaxBackfill = 10
+	// MaxScheduleMaxBackfill bounds MaxBackfill.
+	MaxScheduleMaxBackfill = 1000
+	// MaxScheduleJitter bounds Jitter.
+	MaxScheduleJitter = time.Hour
 )
 
 // WorkflowSchedule represents a persisted cron schedule for a workflow.
@@ -27,6 +52,22 @@ type WorkflowSchedule struct {
 	Input      map[string]any `json:"input,omitempty"`
 	Options    RunReqOptions  `json:"options,omitempty"`
 
+	// Timezone is the IANA timezone the cron expression is read in, such
+	// as "Europe/Berlin". Empty means UTC.
+	Timezone string `json:"timezone,omitempty"`
+	// Jitter delays each run by a stable pseudo-random duration below it,
+	// such as "30s", so schedules sharing a cron expression do not all
+	// start at the minute boundary.
+	Jitter string `json:"jitter,omitempty"`
+	// MissedFire is the missed-fire policy. Defaults to
+	// MissedFireImmediately.
+	MissedFire string `json:"missed_fire,omitempty"`
+	// MaxBackfill caps the runs a catch_up schedule makes up.
+	MaxBackfill int `json:"max_backfill,omitempty"`
+
+	// NextFireAt is the cron time of the next run. NextRunAt is NextFireAt
+	// plus the jitter, when the run starts.
+	NextFireAt time.Time  `json:"next_fire_at"`
 	NextRunAt  time.Time  `json:"next_run_at"`
 	LastRunAt  *time.Time `json:"last_run_at,omitempty"`
 	LastRunID  string     `json:"last_run_id,omitempty"`
@@ -37,6 +78,45 @@ type WorkflowSchedule struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// jitter returns the parsed Jitter.
+func (s WorkflowSchedule) jitter() (time.Duration, error) {
+	if s.Jitter == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(s.Jitter)
+	if err != nil {
+		return 0, fmt.Errorf("jitter: %w", err)
+	}
+	if d < 0 || d > MaxScheduleJitter {
+		return 0, fmt.Errorf("jitter must be from 0 to %s", MaxScheduleJitter)
+	}
+	return d, nil
+}
+
+// missedFirePolicy returns MissedFire with its default applied.
+func (s WorkflowSchedule) missedFirePolicy() string {
+	if s.MissedFire == "" {
+		return MissedFireImmediately
+	}
+	return s.MissedFire
+}
+
+// maxBackfill returns MaxBackfill with its default applied.
+func (s WorkflowSchedule) maxBackfill() int {
+	if s.MaxBackfill <= 0 {
+		return DefaultScheduleMaxBackfill
+	}
+	return s.MaxBackfill
+}
+
+// scheduleNext sets NextFireAt to the first fire time after t, and
+// NextRunAt to it plus the jitter.
+func (s *WorkflowSchedule) scheduleNext(cs cronSchedule, t time.Time) {
+	jitter, _ := s.jitter()
+	s.NextFireAt = cs.Next(t)
+	s.NextRunAt = s.NextFireAt.Add(scheduleJitter(s.ID, s.NextFireAt, jitter))
+}
+
 // WorkflowScheduleStore provides CRUD + due scheduling operations.
 type WorkflowScheduleStore interface {
 	ListSchedules(ctx context.Context, workflowID string) ([]WorkflowSchedule, error)
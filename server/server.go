@@ -3,11 +3,21 @@ package server
 import (
 	"encoding/json"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/petal-labs/petalflow/backup"
 	"github.com/petal-labs/petalflow/bus"
 	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/nodes/conditional/expr"
+	"github.com/petal-labs/petalflow/policy"
+	"github.com/petal-labs/petalflow/propagate"
 	"github.com/petal-labs/petalflow/runtime"
+	"github.com/petal-labs/petalflow/scan"
 	"github.com/petal-labs/petalflow/tool"
 )
 
@@ -22,9 +32,167 @@ type ServerConfig struct {
 	EventStore    bus.EventStore
 	RuntimeEvents runtime.EventHandler
 	EmitDecorator runtime.EventEmitterDecorator
-	CORSOrigin    string
-	MaxBody       int64
-	Logger        *slog.Logger
+	UploadStore   UploadStore
+	// RecordNodeOutputs adds each node's output to its node.finished
+	// event, so options.resume_from and janitor requeues restore completed
+	// nodes instead of re-executing them. It needs an EventStore. Off by
+	// default: recorded outputs copy run state into stored and streamed
+	// events.
+	RecordNodeOutputs bool
+	// ConditionStore holds the named condition library that conditional
+	// and rule_router nodes reference with `ref`. Nil disables the
+	// /api/conditions routes and refs fail hydration.
+	ConditionStore ConditionStore
+	// EvalDatasets stores evaluation datasets. Nil disables the
+	// /api/evals/datasets routes.
+	EvalDatasets EvalDatasetStore
+	// TemplateStore holds workflow templates registered through
+	// POST /api/templates. Nil serves only the built-in templates.
+	TemplateStore TemplateStore
+	// RolloutStore keeps canary rollouts started with
+	// PUT /api/workflows/{id}?rollout=canary. Nil disables rollouts.
+	RolloutStore RolloutStore
+	// PresetStore keeps the input presets saved with
+	// POST /api/workflows/{id}/presets. Nil leaves runs with the presets of
+	// workflow definitions only.
+	PresetStore PresetStore
+	// LifecycleStore keeps the lifecycle states set with
+	// POST /api/workflows/{id}/lifecycle. Nil leaves every workflow active.
+	LifecycleStore LifecycleStore
+	// AuditStore keeps the audit log of management requests; see
+	// AuditMiddleware. Nil disables auditing and GET /api/audit.
+	AuditStore AuditStore
+	// OutputHistory keeps the output history of LLM node drift guards.
+	// Defaults to an in-memory history that is lost on restart.
+	OutputHistory nodes.OutputHistoryStore
+	// ArmStats keeps the selections and arm statistics of model_select
+	// nodes. Defaults to an in-memory store that is lost on restart.
+	ArmStats nodes.ArmStatsStore
+	// State keeps the values of state_get, state_set and state_incr
+	// nodes. Defaults to an in-memory store that is lost on restart.
+	State nodes.StateStore
+	// OutboundHeaders adds run correlation headers to the outbound HTTP
+	// requests of webhook_call nodes and tools. Nodes may override it with
+	// their propagate_headers config.
+	OutboundHeaders propagate.Config
+	// ToolCache keeps the responses of tool actions whose manifests
+	// declare them cacheable, shared by every workflow. Defaults to an
+	// in-memory cache that is lost on restart.
+	ToolCache nodes.CacheStore
+	// WebhookDedupe remembers the event IDs of webhook triggers with
+	// dedupe enabled. Defaults to an in-memory store that is lost on
+	// restart.
+	WebhookDedupe WebhookDedupeStore
+	// Outbox keeps the requests of webhook_call nodes with outbox delivery
+	// until an OutboxWorker sends them. Nil makes those nodes send their
+	// requests directly.
+	Outbox OutboxStore
+	// Secrets encrypts the {"secret": "..."} values of node configs on
+	// save and decrypts them for runs. Defaults to a codec keyed by
+	// PETALFLOW_SECRET_KEY, or the user and host when it is unset.
+	Secrets hydrate.SecretCodec
+	// VerifyCredentials checks provider credentials with a cheap provider
+	// call when a workflow referencing the provider is saved, when it is
+	// hydrated and on VerifyProviders. Saves with failing credentials are
+	// rejected. Needs ClientFactory.
+	VerifyCredentials bool
+	// CredentialTTL is how long a verification result is reused. Defaults
+	// to hydrate.DefaultCredentialTTL.
+	CredentialTTL time.Duration
+	// RegionProbeInterval is how long the health and latency probe of a
+	// provider region is reused when routing LLM requests across the
+	// regions a provider configures. Defaults to
+	// hydrate.DefaultRegionProbeInterval.
+	RegionProbeInterval time.Duration
+	// Policy is checked whenever a workflow is created or updated.
+	// Violations of error severity reject the save and warnings are
+	// returned with the saved workflow. Nil allows every workflow.
+	Policy *policy.Policy
+	// Holidays are the holiday calendars isHoliday uses in
+	// conditional and rule_router expressions.
+	Holidays expr.Holidays
+	// TemplateSandbox limits the node templates of stored workflows. Nil
+	// uses nodes.DefaultTemplateSandbox.
+	TemplateSandbox *nodes.TemplateSandbox
+	CORSOrigin      string // shorthand for a single CORS.AllowedOrigins entry
+	MaxBody         int64
+	Logger          *slog.Logger
+
+	// CORS configures cross-origin access. When CORS.AllowedOrigins is
+	// empty, CORSOrigin (default "*") is the only allowed origin.
+	CORS CORSConfig
+	// SecurityHeaders sets hardening headers on every response. Nil uses
+	// DefaultSecurityHeaders; an empty config sends none.
+	SecurityHeaders *SecurityHeadersConfig
+
+	// MaxUploadBytes caps a single POST /api/uploads body. Uploads are
+	// exempt from MaxBody. Defaults to 32 MB.
+	MaxUploadBytes int64
+	// UploadQuotaBytes caps the total bytes stored per workspace.
+	// Defaults to 256 MB; a negative value disables the quota.
+	UploadQuotaBytes int64
+	// UploadContentTypes lists the accepted upload media types. Entries may
+	// end in "/*" to accept a whole family. Defaults to DefaultUploadContentTypes.
+	UploadContentTypes []string
+	// UploadScanner checks every upload before it is stored. Uploads it
+	// does not pass are stored quarantined and never reach a node. Nil
+	// disables scanning.
+	UploadScanner scan.Scanner
+	// QuarantineAction is what a run referencing a quarantined upload
+	// does: QuarantineFail (the default) rejects the run and
+	// QuarantineStrip runs it without the upload.
+	QuarantineAction string
+
+	// AllowChaos accepts options.chaos on run requests. Leave it off outside
+	// test environments.
+	AllowChaos bool
+
+	// ValidateEvents checks every run event against its kind's payload
+	// schema and logs those that do not match. Meant for development.
+	ValidateEvents bool
+
+	// Backups enables the admin backup and restore endpoints. Nil leaves
+	// them answering 501.
+	Backups *backup.Manager
+	// AllowAdmin enables the admin endpoints that change the running
+	// daemon: PUT /api/admin/maintenance and PUT /api/admin/logging.
+	// Without it they answer 501.
+	AllowAdmin bool
+
+	// LeaseStore makes every run hold a heartbeated lease; see RunJanitor.
+	// Nil disables leases.
+	LeaseStore RunLeaseStore
+	// LeaseTTL defaults to runtime.DefaultLeaseTTL.
+	LeaseTTL time.Duration
+	// LeaseOwner identifies this process on its leases. Defaults to
+	// hostname and pid.
+	LeaseOwner string
+
+	// RunQueue caps concurrent runs and orders waiting runs by priority
+	// class. Nil runs everything immediately.
+	RunQueue *RunQueue
+
+	// RunMemorySoftLimit makes a run emit a memory.warning event when its
+	// approximate memory use first exceeds it, in bytes. 0 disables the
+	// warning.
+	RunMemorySoftLimit int64
+	// RunMemoryHardLimit fails a run whose approximate memory use exceeds
+	// it, in bytes. 0 disables the limit.
+	RunMemoryHardLimit int64
+
+	// Maintenance is the initial read-only switch and banner. It can be
+	// changed at runtime through PUT /api/admin/maintenance.
+	Maintenance MaintenanceState
+
+	// LogLevel is the level of Logger's handler, such as the
+	// *slog.LevelVar in its options. PUT /api/admin/logging changes it.
+	// Nil leaves the level fixed.
+	LogLevel LogLeveler
+
+	// Authorizer is asked about every API request; see
+	// AuthorizationMiddleware. The caller comes from WithPrincipal. Nil
+	// allows everything.
+	Authorizer Authorizer
 }
 
 // Server is the PetalFlow HTTP API server.
@@ -36,11 +204,75 @@ type Server struct {
 	clientFactory hydrate.ClientFactory
 	bus           bus.EventBus
 	eventStore    bus.EventStore
+	recordOutputs bool
 	runtimeEvents runtime.EventHandler
 	emitDecorator runtime.EventEmitterDecorator
-	corsOrigin    string
-	maxBody       int64
-	logger        *slog.Logger
+	uploadStore   UploadStore
+	conditions    ConditionStore
+	evalDatasets  EvalDatasetStore
+	templates     TemplateStore
+	outputHistory nodes.OutputHistoryStore
+	armStats      nodes.ArmStatsStore
+	state         nodes.StateStore
+	toolCache     nodes.CacheStore
+	outbound      propagate.Config
+	sandbox       *nodes.TemplateSandbox
+
+	webhookDedupe      WebhookDedupeStore
+	outbox             OutboxStore
+	webhookDedupeStats webhookDedupeCounters
+	webhookRejections  webhookRejectionCounters
+	secrets            hydrate.SecretCodec
+	credentials        *hydrate.CredentialVerifier
+	regions            *hydrate.RegionRouter
+
+	cors     CORSConfig
+	security SecurityHeadersConfig
+	maxBody  int64
+	logger   *slog.Logger
+
+	maxUpload          int64
+	uploadQuota        int64
+	uploadContentTypes []string
+	uploadScanner      scan.Scanner
+	quarantineAction   string
+
+	allowChaos     bool
+	validateEvents bool
+	backups        *backup.Manager
+	allowAdmin     bool
+
+	leaseStore RunLeaseStore
+	leaseTTL   time.Duration
+	leaseOwner string
+
+	runQueue    *RunQueue
+	memory      *runtime.MemoryAccountant
+	memorySoft  int64
+	memoryHard  int64
+	maintenance maintenanceSwitch
+	logging     loggingSwitch
+	search      workflowSearchIndex
+	startup     atomic.Pointer[startupState]
+	authorizer  Authorizer
+	audit       AuditStore
+	waits       *waitTracker
+	// toolCallbacks delivers the results of asynchronous tool invocations
+	// posted to /api/tool-callbacks/{token}.
+	toolCallbacks *nodes.ToolCallbacks
+
+	rollouts  RolloutStore
+	rolloutMu sync.Mutex
+	// rolloutDraw returns a number in [0, 1) to assign runs to rollout
+	// revisions.
+	rolloutDraw func() float64
+
+	lifecycles  LifecycleStore
+	lifecycleMu sync.Mutex
+
+	presets  PresetStore
+	policy   *policy.Policy
+	holidays expr.Holidays
 }
 
 // NewServer creates a new Server with the given configuration.
@@ -49,15 +281,69 @@ func NewServer(cfg ServerConfig) *Server {
 	if logger == nil {
 		logger = slog.Default()
 	}
-	corsOrigin := cfg.CORSOrigin
-	if corsOrigin == "" {
-		corsOrigin = "*"
+	cors := cfg.CORS
+	if len(cors.AllowedOrigins) == 0 && cfg.CORSOrigin != "" {
+		cors.AllowedOrigins = []string{cfg.CORSOrigin}
+	}
+	security := DefaultSecurityHeaders()
+	if cfg.SecurityHeaders != nil {
+		security = *cfg.SecurityHeaders
 	}
 	maxBody := cfg.MaxBody
 	if maxBody <= 0 {
 		maxBody = 1 << 20 // 1 MB default
 	}
-	return &Server{
+	maxUpload := cfg.MaxUploadBytes
+	if maxUpload <= 0 {
+		maxUpload = 32 << 20
+	}
+	uploadQuota := cfg.UploadQuotaBytes
+	if uploadQuota == 0 {
+		uploadQuota = 256 << 20
+	}
+	leaseOwner := cfg.LeaseOwner
+	if leaseOwner == "" {
+		leaseOwner = defaultLeaseOwner()
+	}
+	uploadContentTypes := cfg.UploadContentTypes
+	if len(uploadContentTypes) == 0 {
+		uploadContentTypes = DefaultUploadContentTypes
+	}
+	quarantineAction := cfg.QuarantineAction
+	if quarantineAction == "" {
+		quarantineAction = QuarantineFail
+	}
+	outputHistory := cfg.OutputHistory
+	if outputHistory == nil {
+		outputHistory = nodes.NewMemoryOutputHistory()
+	}
+	armStats := cfg.ArmStats
+	if armStats == nil {
+		armStats = nodes.NewMemoryArmStats()
+	}
+	state := cfg.State
+	if state == nil {
+		state = nodes.NewMemoryStateStore()
+	}
+	toolCache := cfg.ToolCache
+	if toolCache == nil {
+		toolCache = nodes.NewMemoryCacheStore()
+	}
+	webhookDedupe := cfg.WebhookDedupe
+	if webhookDedupe == nil {
+		webhookDedupe = NewMemoryWebhookDedupeStore()
+	}
+	secrets := cfg.Secrets
+	if secrets == nil {
+		if codec, err := tool.NewSecretCodec("petalflow-server"); err == nil {
+			secrets = codec
+		}
+	}
+	sandbox := cfg.TemplateSandbox
+	if sandbox == nil {
+		sandbox = nodes.DefaultTemplateSandbox()
+	}
+	s := &Server{
 		store:         cfg.Store,
 		scheduleStore: cfg.ScheduleStore,
 		toolStore:     cfg.ToolStore,
@@ -65,12 +351,72 @@ func NewServer(cfg ServerConfig) *Server {
 		clientFactory: cfg.ClientFactory,
 		bus:           cfg.Bus,
 		eventStore:    cfg.EventStore,
+		recordOutputs: cfg.RecordNodeOutputs,
 		runtimeEvents: cfg.RuntimeEvents,
 		emitDecorator: cfg.EmitDecorator,
-		corsOrigin:    corsOrigin,
+		uploadStore:   cfg.UploadStore,
+		conditions:    cfg.ConditionStore,
+		evalDatasets:  cfg.EvalDatasets,
+		templates:     cfg.TemplateStore,
+		outputHistory: outputHistory,
+		armStats:      armStats,
+		state:         state,
+		toolCache:     toolCache,
+		outbound:      cfg.OutboundHeaders,
+		waits:         newWaitTracker(),
+		toolCallbacks: nodes.NewToolCallbacks(),
+		sandbox:       sandbox,
+		webhookDedupe: webhookDedupe,
+		outbox:        cfg.Outbox,
+		secrets:       secrets,
+		cors:          cors,
+		security:      security,
 		maxBody:       maxBody,
 		logger:        logger,
+
+		maxUpload:          maxUpload,
+		uploadQuota:        uploadQuota,
+		uploadContentTypes: uploadContentTypes,
+		uploadScanner:      cfg.UploadScanner,
+		quarantineAction:   quarantineAction,
+
+		allowChaos:     cfg.AllowChaos,
+		validateEvents: cfg.ValidateEvents,
+		backups:        cfg.Backups,
+		allowAdmin:     cfg.AllowAdmin,
+
+		leaseStore: cfg.LeaseStore,
+		leaseTTL:   cfg.LeaseTTL,
+		leaseOwner: leaseOwner,
+
+		runQueue:   cfg.RunQueue,
+		memory:     runtime.NewMemoryAccountant(),
+		memorySoft: cfg.RunMemorySoftLimit,
+		memoryHard: cfg.RunMemoryHardLimit,
+		authorizer: cfg.Authorizer,
+		audit:      cfg.AuditStore,
+
+		rollouts:    cfg.RolloutStore,
+		rolloutDraw: rand.Float64,
+
+		lifecycles: cfg.LifecycleStore,
+
+		presets:  cfg.PresetStore,
+		policy:   cfg.Policy,
+		holidays: cfg.Holidays,
+	}
+	if cfg.VerifyCredentials && cfg.ClientFactory != nil {
+		s.credentials = hydrate.NewCredentialVerifier(cfg.ClientFactory, cfg.CredentialTTL)
 	}
+	if cfg.ClientFactory != nil {
+		s.regions = hydrate.NewRegionRouter(cfg.ClientFactory, cfg.RegionProbeInterval)
+	}
+	s.maintenance.state = cfg.Maintenance
+	s.logging.level = cfg.LogLevel
+	if s.clientFactory != nil {
+		s.clientFactory = s.llmTrafficFactory(s.clientFactory)
+	}
+	return s
 }
 
 // Handler returns an http.Handler with all routes and middleware wired.
@@ -80,7 +426,11 @@ func (s *Server) Handler() http.Handler {
 	s.RegisterRoutes(mux)
 
 	var handler http.Handler = mux
-	handler = s.corsMiddleware(handler)
+	handler = s.ReadOnlyMiddleware(handler)
+	handler = s.AuthorizationMiddleware(handler)
+	handler = s.AuditMiddleware(handler)
+	handler = CORSMiddleware(s.cors)(handler)
+	handler = SecurityHeadersMiddleware(s.security)(handler)
 	handler = s.maxBodyMiddleware(handler)
 
 	return handler
@@ -90,40 +440,93 @@ func (s *Server) Handler() http.Handler {
 // Use this when composing with other handlers (e.g. daemon server).
 func (s *Server) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /health", s.handleHealth)
+	mux.HandleFunc("GET /health/startup", s.handleStartupHealth)
 	mux.HandleFunc("GET /api/node-types", s.handleNodeTypes)
 	mux.HandleFunc("GET /api/workflows", s.handleListWorkflows)
+	mux.HandleFunc("GET /api/workflows/search", s.handleSearchWorkflows)
 	mux.HandleFunc("POST /api/workflows/agent", s.handleCreateAgentWorkflow)
 	mux.HandleFunc("POST /api/workflows/graph", s.handleCreateGraphWorkflow)
 	mux.HandleFunc("GET /api/workflows/{id}", s.handleGetWorkflow)
 	mux.HandleFunc("PUT /api/workflows/{id}", s.handleUpdateWorkflow)
 	mux.HandleFunc("DELETE /api/workflows/{id}", s.handleDeleteWorkflow)
+	mux.HandleFunc("GET /api/workflows/{id}/export", s.handleExportWorkflow)
+	mux.HandleFunc("GET /api/workflows/{id}/diagram", s.handleWorkflowDiagram)
 	mux.HandleFunc("POST /api/workflows/{id}/run", s.handleRunWorkflow)
+	mux.HandleFunc("POST /api/workflows/{id}/reruns", s.handleRerunWorkflow)
+	mux.HandleFunc("GET /api/workflows/{id}/stats", s.handleWorkflowStats)
+	mux.HandleFunc("GET /api/workflows/{id}/model-select", s.handleModelSelectStats)
+	mux.HandleFunc("GET /api/workflows/{id}/waits", s.handleWorkflowWaits)
+	mux.HandleFunc("GET /api/workflows/{id}/rollout", s.handleGetRollout)
+	mux.HandleFunc("POST /api/workflows/{id}/rollout/promote", s.handlePromoteRollout)
+	mux.HandleFunc("POST /api/workflows/{id}/rollout/rollback", s.handleRollbackRollout)
+	mux.HandleFunc("GET /api/workflows/{id}/lifecycle", s.handleGetLifecycle)
+	mux.HandleFunc("POST /api/workflows/{id}/lifecycle", s.handleSetLifecycle)
+	mux.HandleFunc("GET /api/workflows/{id}/presets", s.handleListPresets)
+	mux.HandleFunc("POST /api/workflows/{id}/presets", s.handleSavePreset)
+	mux.HandleFunc("GET /api/workflows/{id}/presets/{name}", s.handleGetPreset)
+	mux.HandleFunc("DELETE /api/workflows/{id}/presets/{name}", s.handleDeletePreset)
 	mux.HandleFunc("/api/workflows/{id}/webhooks/{trigger_id}", s.handleWorkflowWebhook)
 	mux.HandleFunc("GET /api/workflows/{id}/schedules", s.handleListWorkflowSchedules)
 	mux.HandleFunc("POST /api/workflows/{id}/schedules", s.handleCreateWorkflowSchedule)
 	mux.HandleFunc("GET /api/workflows/{id}/schedules/{schedule_id}", s.handleGetWorkflowSchedule)
 	mux.HandleFunc("PUT /api/workflows/{id}/schedules/{schedule_id}", s.handleUpdateWorkflowSchedule)
 	mux.HandleFunc("DELETE /api/workflows/{id}/schedules/{schedule_id}", s.handleDeleteWorkflowSchedule)
+	mux.HandleFunc("POST /api/model-selections/{selection_id}/reward", s.handleModelSelectionReward)
+	mux.HandleFunc("POST /api/tool-callbacks/{token}", s.handleToolCallback)
+	mux.HandleFunc("GET /api/providers", s.handleListProviders)
+	mux.HandleFunc("POST /api/providers/{name}/verify", s.handleVerifyProvider)
+	mux.HandleFunc("GET /api/conditions", s.handleListConditions)
+	mux.HandleFunc("POST /api/conditions", s.handleCreateCondition)
+	mux.HandleFunc("GET /api/conditions/{name}", s.handleGetCondition)
+	mux.HandleFunc("PUT /api/conditions/{name}", s.handleUpdateCondition)
+	mux.HandleFunc("DELETE /api/conditions/{name}", s.handleDeleteCondition)
+	mux.HandleFunc("GET /api/evals/datasets", s.handleListEvalDatasets)
+	mux.HandleFunc("POST /api/evals/datasets", s.handleCreateEvalDataset)
+	mux.HandleFunc("GET /api/evals/datasets/{name}", s.handleGetEvalDataset)
+	mux.HandleFunc("PUT /api/evals/datasets/{name}", s.handleUpdateEvalDataset)
+	mux.HandleFunc("DELETE /api/evals/datasets/{name}", s.handleDeleteEvalDataset)
+	mux.HandleFunc("GET /api/templates", s.handleListTemplates)
+	mux.HandleFunc("POST /api/templates", s.handleCreateTemplate)
+	mux.HandleFunc("GET /api/templates/{id}", s.handleGetTemplate)
+	mux.HandleFunc("DELETE /api/templates/{id}", s.handleDeleteTemplate)
+	mux.HandleFunc("POST /api/templates/{id}/instantiate", s.handleInstantiateTemplate)
+	mux.HandleFunc("POST "+UploadsPath, s.handleCreateUpload)
+	mux.HandleFunc("GET "+UploadsPath+"/{upload_id}", s.handleGetUpload)
+	mux.HandleFunc("DELETE "+UploadsPath+"/{upload_id}", s.handleDeleteUpload)
+	mux.HandleFunc("GET /api/runs", s.handleListRuns)
+	mux.HandleFunc("GET /api/runs/leases", s.handleListRunLeases)
+	mux.HandleFunc("GET /api/runs/queue", s.handleRunQueue)
+	mux.HandleFunc("GET /api/runs/dedupe", s.handleWebhookDedupe)
+	mux.HandleFunc("GET /api/runs/webhook-rejections", s.handleWebhookRejections)
+	mux.HandleFunc("GET /api/runs/history", s.handleRunHistory)
+	mux.HandleFunc("GET /api/runs/compare-env", s.handleCompareRunEnvironments)
 	mux.HandleFunc("GET /api/runs/{run_id}/events", s.handleRunEvents)
+	mux.HandleFunc("GET /api/runs/{run_id}/output", s.handleRunOutput)
+	mux.HandleFunc("GET /api/runs/{run_id}/deliveries", s.handleListRunDeliveries)
+	mux.HandleFunc("GET /api/deliveries/{delivery_id}", s.handleGetDelivery)
+	mux.HandleFunc("POST /api/deliveries/{delivery_id}/retry", s.handleRetryDelivery)
+	mux.HandleFunc("GET /api/system/usage", s.handleSystemUsage)
+	mux.HandleFunc("GET /api/usage/features", s.handleFeatureUsage)
+	mux.HandleFunc("GET /api/events/schemas", s.handleListEventSchemas)
+	mux.HandleFunc("GET /api/events/schemas/{kind}", s.handleGetEventSchema)
+	mux.HandleFunc("GET /api/maintenance", s.handleGetMaintenance)
+	mux.HandleFunc("PUT "+AdminMaintenancePath, s.handleSetMaintenance)
+	mux.HandleFunc("GET "+AdminLoggingPath, s.handleGetLogging)
+	mux.HandleFunc("PUT "+AdminLoggingPath, s.handleSetLogging)
+	mux.HandleFunc("GET /api/audit", s.handleListAudit)
+	mux.HandleFunc("GET /api/admin/backup", s.handleAdminBackup)
+	mux.HandleFunc("POST "+AdminRestorePath, s.handleAdminRestore)
 }
 
 // --- Middleware ---
 
-func (s *Server) corsMiddleware(next http.Handler) http.Handler {
+func (s *Server) maxBodyMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", s.corsOrigin)
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if r.Method == http.MethodOptions {
-			w.WriteHeader(http.StatusNoContent)
+		if IsUploadRequest(r) || IsRestoreRequest(r) {
+			// These handlers apply their own, larger limits.
+			next.ServeHTTP(w, r)
 			return
 		}
-		next.ServeHTTP(w, r)
-	})
-}
-
-func (s *Server) maxBodyMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		r.Body = http.MaxBytesReader(w, r.Body, s.maxBody)
 		next.ServeHTTP(w, r)
 	})
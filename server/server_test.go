@@ -2,15 +2,20 @@ package server
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/petal-labs/petalflow/bus"
 	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
 	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/loader"
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 // testServer creates a Server with defaults suitable for testing.
@@ -29,6 +34,7 @@ func testServer(t *testing.T) *Server {
 		EventStore: newTestEventStore(t),
 		CORSOrigin: "*",
 		MaxBody:    1 << 20,
+		AllowAdmin: true,
 	})
 }
 
@@ -282,6 +288,58 @@ func TestGraphWorkflow_ValidationError(t *testing.T) {
 	}
 }
 
+func TestGraphWorkflow_RequirementsNotMet(t *testing.T) {
+	srv := testServer(t)
+	handler := srv.Handler()
+
+	payload := map[string]any{
+		"id":       "needs-newer",
+		"version":  "1.0",
+		"nodes":    []map[string]any{{"id": "start", "type": "func"}},
+		"edges":    []map[string]any{},
+		"requires": map[string]any{"engine": ">=99"},
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(mustJSON(t, payload)))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("save got %d, want %d; body: %s", w.Code, http.StatusUnprocessableEntity, w.Body.String())
+	}
+	var saveErr apiError
+	if err := json.Unmarshal(w.Body.Bytes(), &saveErr); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if want := "Workflow requires engine >=99, but this engine is version " + graph.EngineVersion; len(saveErr.Error.Details) != 1 || saveErr.Error.Details[0] != want {
+		t.Fatalf("save details = %q, want %q", saveErr.Error.Details, want)
+	}
+
+	// A workflow stored by a newer daemon is refused at run time.
+	gd := &graph.GraphDefinition{
+		ID:       "stored-newer",
+		Version:  "1.0",
+		Nodes:    []graph.NodeDef{{ID: "start", Type: "func"}},
+		Entry:    "start",
+		Requires: &graph.Requirements{NodeTypes: map[string]string{"func": ">=2"}},
+	}
+	now := time.Now().UTC()
+	if err := srv.store.Create(context.Background(), WorkflowRecord{ID: gd.ID, SchemaKind: loader.SchemaKindGraph, Source: mustJSON(t, gd), Compiled: gd, CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	r = httptest.NewRequest(http.MethodPost, "/api/workflows/stored-newer/run", strings.NewReader(`{}`))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	var runErr apiError
+	if err := json.Unmarshal(w.Body.Bytes(), &runErr); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if w.Code != http.StatusUnprocessableEntity || runErr.Error.Code != "REQUIREMENTS_NOT_MET" {
+		t.Fatalf("run got %d; body: %s", w.Code, w.Body.String())
+	}
+	if want := `Workflow requires node type "func" >=2, but this engine provides version 1.0.0`; len(runErr.Error.Details) != 1 || runErr.Error.Details[0] != want {
+		t.Fatalf("run details = %q, want %q", runErr.Error.Details, want)
+	}
+}
+
 func TestGraphWorkflow_InvalidJSON(t *testing.T) {
 	srv := testServer(t)
 
@@ -306,6 +364,82 @@ func TestRunWorkflow_NotFound(t *testing.T) {
 	}
 }
 
+func TestRunWorkflow_ResumeFrom(t *testing.T) {
+	srv := testServer(t)
+	srv.recordOutputs = true
+	handler := srv.Handler()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(validGraphJSON("resume-test")))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got %d; body: %s", w.Code, w.Body.String())
+	}
+
+	run := func(req RunRequest) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(req)
+		r := httptest.NewRequest(http.MethodPost, "/api/workflows/resume-test/run", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w = run(RunRequest{Input: map[string]any{"attempt": "first"}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("first run: got %d; body: %s", w.Code, w.Body.String())
+	}
+	var first RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &first); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	w = run(RunRequest{Input: map[string]any{"attempt": "second"}, Options: RunReqOptions{ResumeFrom: first.RunID}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("resumed run: got %d; body: %s", w.Code, w.Body.String())
+	}
+	var resumed RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resumed); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got := resumed.Output.Vars["attempt"]; got != "first" {
+		t.Errorf("attempt = %v, want the restored output of the first run", got)
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/api/runs/"+resumed.RunID+"/events", nil)
+	r.Header.Set("Accept", "application/json")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if !strings.Contains(w.Body.String(), `"node.restored"`) {
+		t.Errorf("expected node.restored event, got %s", w.Body.String())
+	}
+
+	if w := run(RunRequest{Options: RunReqOptions{ResumeFrom: "no-such-run"}}); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "RUN_NOT_FOUND") {
+		t.Errorf("unknown run: got %d %s", w.Code, w.Body.String())
+	}
+
+	r = httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(validGraphJSON("other")))
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	body, _ := json.Marshal(RunRequest{Options: RunReqOptions{ResumeFrom: first.RunID}})
+	r = httptest.NewRequest(http.MethodPost, "/api/workflows/other/run", bytes.NewReader(body))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "RUN_NOT_FOUND") {
+		t.Errorf("run of another workflow: got %d %s", w.Code, w.Body.String())
+	}
+
+	// Without recorded outputs the completed nodes can neither be restored
+	// nor safely re-executed.
+	srv.recordOutputs = false
+	w = run(RunRequest{Input: map[string]any{"attempt": "unrecorded"}})
+	var unrecorded RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &unrecorded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if w := run(RunRequest{Options: RunReqOptions{ResumeFrom: unrecorded.RunID}}); w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), "RESUME_OUTPUTS_MISSING") {
+		t.Errorf("resume without recorded outputs: got %d %s", w.Code, w.Body.String())
+	}
+}
+
 func TestRunWorkflow_WebhookTriggerSuccess(t *testing.T) {
 	srv := testServer(t)
 	handler := srv.Handler()
@@ -587,6 +721,57 @@ func TestRunEvents_NoStore(t *testing.T) {
 	}
 }
 
+func TestListRuns(t *testing.T) {
+	srv := testServer(t)
+	handler := srv.Handler()
+
+	createGraphWorkflow(t, handler, graphWorkflowPayload("list_runs", map[string]any{"id": "a", "type": "noop"}))
+	run := runWorkflow(t, handler, "list_runs", nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/api/runs", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d, want %d: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+
+	var runs []RunSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &runs); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(runs) != 1 || runs[0].RunID != run.RunID {
+		t.Fatalf("runs = %+v, want [%s]", runs, run.RunID)
+	}
+}
+
+func TestListProviders_OmitsCredentials(t *testing.T) {
+	srv := NewServer(ServerConfig{
+		Store: newTestWorkflowStore(t),
+		Providers: hydrate.ProviderMap{
+			"openai":    {APIKey: "sk-secret"},
+			"anthropic": {APIKey: "sk-ant", BaseURL: "https://proxy.internal"},
+		},
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/api/providers", nil)
+	w := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("got %d, want %d", w.Code, http.StatusOK)
+	}
+	if strings.Contains(w.Body.String(), "sk-") {
+		t.Fatalf("provider listing leaked credentials: %s", w.Body.String())
+	}
+
+	var providers []ProviderSummary
+	if err := json.Unmarshal(w.Body.Bytes(), &providers); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(providers) != 2 || providers[0].Name != "anthropic" || providers[0].BaseURL != "https://proxy.internal" {
+		t.Fatalf("providers = %+v", providers)
+	}
+}
+
 func TestIntegrationFlow(t *testing.T) {
 	srv := testServer(t)
 	handler := srv.Handler()
@@ -638,3 +823,35 @@ func TestIntegrationFlow(t *testing.T) {
 		t.Fatalf("get after delete: %d", w.Code)
 	}
 }
+
+func TestRunWorkflow_ChaosRequiresAllowChaos(t *testing.T) {
+	srv := testServer(t)
+	handler := srv.Handler()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(validGraphJSON("chaos-test")))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got %d; body: %s", w.Code, w.Body.String())
+	}
+
+	run := func(chaos *runtime.ChaosConfig) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(RunRequest{Options: RunReqOptions{Chaos: chaos}})
+		r := httptest.NewRequest(http.MethodPost, "/api/workflows/chaos-test/run", bytes.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := run(&runtime.ChaosConfig{Seed: 1, LatencyRate: 1, LatencyMaxMs: 1}); w.Code != http.StatusForbidden {
+		t.Fatalf("disabled: got %d; body: %s", w.Code, w.Body.String())
+	}
+
+	srv.allowChaos = true
+	if w := run(&runtime.ChaosConfig{LatencyRate: 2}); w.Code != http.StatusBadRequest {
+		t.Fatalf("invalid: got %d; body: %s", w.Code, w.Body.String())
+	}
+	if w := run(&runtime.ChaosConfig{Seed: 1, LatencyRate: 1, LatencyMaxMs: 1}); w.Code != http.StatusOK {
+		t.Fatalf("enabled: got %d; body: %s", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// StartupCheck is the outcome of one check the daemon ran while starting.
+type StartupCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// startupState is what /health/startup reports once startup completed.
+type startupState struct {
+	StartedAt time.Time      `json:"started_at"`
+	Checks    []StartupCheck `json:"checks,omitempty"`
+}
+
+// startupResponse is the /health/startup body.
+type startupResponse struct {
+	Status string `json:"status"`
+	*startupState
+}
+
+// MarkStarted records that startup completed, after the given checks.
+// Until it is called, /health/startup answers 503 so init containers and
+// startup probes wait for the stores to be migrated and workers started.
+func (s *Server) MarkStarted(checks []StartupCheck) {
+	s.startup.Store(&startupState{StartedAt: time.Now().UTC(), Checks: checks})
+}
+
+// handleStartupHealth reports whether startup completed.
+func (s *Server) handleStartupHealth(w http.ResponseWriter, _ *http.Request) {
+	state := s.startup.Load()
+	if state == nil {
+		writeJSON(w, http.StatusServiceUnavailable, startupResponse{Status: "starting"})
+		return
+	}
+	writeJSON(w, http.StatusOK, startupResponse{Status: "started", startupState: state})
+}
+
+// CheckDataDir checks that the SQLite database at dsn can be written: its
+// directory exists or can be created, accepts new files, and an existing
+// database file is writable. In-memory databases pass. The errors say
+// which path failed for which user and how to fix the volume.
+func CheckDataDir(dsn string) error {
+	path := sqliteFilePath(dsn)
+	if path == "" {
+		return nil
+	}
+	dir := filepath.Dir(path)
+	user := processUser()
+	fix := "set stores.sqlite_path (PETALFLOW_SQLITE_PATH) to a writable location"
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return fmt.Errorf("data directory %s does not exist and cannot be created by %s: mount a volume there or %s", dir, user, fix)
+		}
+		return fmt.Errorf("data directory %s: %w; %s", dir, err, fix)
+	}
+	probe, err := os.CreateTemp(dir, ".petalflow-write-check-*")
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) || isReadOnlyFS(err) {
+			return fmt.Errorf("data directory %s is not writable by %s: mount the volume read-write and give that user write access (for example chown it), or %s", dir, user, fix)
+		}
+		return fmt.Errorf("data directory %s: %w; %s", dir, err, fix)
+	}
+	_ = probe.Close()
+	_ = os.Remove(probe.Name())
+
+	db, err := os.OpenFile(path, os.O_RDWR, 0) // #nosec G304 -- configured database path
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil
+	case errors.Is(err, fs.ErrPermission) || isReadOnlyFS(err):
+		return fmt.Errorf("database %s is not writable by %s: give that user write access to the file, or %s", path, user, fix)
+	case err != nil:
+		return fmt.Errorf("database %s: %w", path, err)
+	}
+	return db.Close()
+}
+
+// sqliteFilePath returns the file a SQLite DSN opens, or "" for in-memory
+// databases.
+func sqliteFilePath(dsn string) string {
+	path, query, _ := strings.Cut(strings.TrimSpace(dsn), "?")
+	if rest, ok := strings.CutPrefix(path, "file:"); ok {
+		path = rest
+	}
+	if path == "" || path == ":memory:" || strings.Contains(query, "mode=memory") {
+		return ""
+	}
+	return path
+}
+
+func isReadOnlyFS(err error) bool {
+	return errors.Is(err, syscall.EROFS)
+}
+
+// processUser names the user the daemon runs as, for error messages.
+func processUser() string {
+	if uid := os.Getuid(); uid >= 0 {
+		return fmt.Sprintf("uid %d", uid)
+	}
+	return "the daemon's user"
+}
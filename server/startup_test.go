@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStartupHealth(t *testing.T) {
+	srv := testServer(t)
+	handler := srv.Handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/startup", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("before MarkStarted: got %d, want 503", w.Code)
+	}
+
+	srv.MarkStarted([]StartupCheck{{Name: "migrations", Status: "ok"}})
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health/startup", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("after MarkStarted: got %d; body: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Status string         `json:"status"`
+		Checks []StartupCheck `json:"checks"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if body.Status != "started" || len(body.Checks) != 1 || body.Checks[0].Name != "migrations" {
+		t.Errorf("body = %+v", body)
+	}
+}
+
+func TestCheckDataDir(t *testing.T) {
+	dir := t.TempDir()
+	if err := CheckDataDir(filepath.Join(dir, "nested", "petalflow.db")); err != nil {
+		t.Fatalf("missing directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "nested")); err != nil {
+		t.Fatalf("directory not created: %v", err)
+	}
+	if err := CheckDataDir("file::memory:?cache=shared"); err != nil {
+		t.Fatalf("in-memory: %v", err)
+	}
+
+	notDir := filepath.Join(dir, "file")
+	if err := os.WriteFile(notDir, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDataDir(filepath.Join(notDir, "petalflow.db")); err == nil || !strings.Contains(err.Error(), "PETALFLOW_SQLITE_PATH") {
+		t.Fatalf("directory is a file: got %v", err)
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("root can write read-only directories")
+	}
+	readOnly := filepath.Join(dir, "ro")
+	if err := os.Mkdir(readOnly, 0o500); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckDataDir(filepath.Join(readOnly, "petalflow.db")); err == nil || !strings.Contains(err.Error(), "is not writable by") {
+		t.Fatalf("read-only directory: got %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// DefaultStatsWindow is the window used by the workflow stats endpoint when
+// the request does not set one.
+const DefaultStatsWindow = 7 * 24 * time.Hour
+
+// workflowEventLister is implemented by event stores that can select the
+// events of a workflow's runs across the whole store.
+type workflowEventLister interface {
+	WorkflowEvents(ctx context.Context, workflowID string, since time.Time, kinds ...runtime.EventKind) ([]runtime.Event, error)
+}
+
+// WorkflowStats aggregates the runs of a workflow started within a window.
+type WorkflowStats struct {
+	WorkflowID string    `json:"workflow_id"`
+	Window     string    `json:"window"`
+	Since      time.Time `json:"since"`
+
+	Runs      int `json:"runs"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+	// Running counts runs without a run.finished event yet.
+	Running int `json:"running"`
+	// SuccessRate is Completed over finished runs, 0 when none finished.
+	SuccessRate float64 `json:"success_rate"`
+
+	DurationP50Ms int64 `json:"duration_p50_ms"`
+	DurationP95Ms int64 `json:"duration_p95_ms"`
+
+	// FailuresByNode counts node.failed events per node ID.
+	FailuresByNode map[string]int `json:"failures_by_node"`
+
+	AvgTokensPerRun  float64 `json:"avg_tokens_per_run"`
+	AvgCostUSDPerRun float64 `json:"avg_cost_usd_per_run"`
+	// TokensByRegion totals the tokens of LLM calls per provider region,
+	// for providers that route across regions. It is omitted when none
+	// did.
+	TokensByRegion map[string]float64 `json:"tokens_by_region,omitempty"`
+
+	// Triggers counts runs per trigger: manual, webhook or schedule.
+	Triggers map[string]int `json:"triggers"`
+
+	// SLA reports attainment over finished runs that tracked an SLA. It is
+	// omitted when none did.
+	SLA *SLAStats `json:"sla,omitempty"`
+
+	// Deadlines reports how often finished runs requested with a deadline
+	// completed by it. It is omitted when none had one.
+	Deadlines *DeadlineStats `json:"deadlines,omitempty"`
+}
+
+// DeadlineStats summarizes how often finished runs met their callers'
+// deadlines.
+type DeadlineStats struct {
+	Runs   int `json:"runs"`
+	Met    int `json:"met"`
+	Missed int `json:"missed"`
+	// Attainment is Met over Runs.
+	Attainment float64 `json:"attainment"`
+}
+
+// SLAStats summarizes how often finished runs met their workflow's SLA.
+type SLAStats struct {
+	Runs     int `json:"runs"`
+	Met      int `json:"met"`
+	Breached int `json:"breached"`
+	// Attainment is Met over Runs.
+	Attainment float64 `json:"attainment"`
+	// BreachesBySLA counts breaching runs per SLA name.
+	BreachesBySLA map[string]int `json:"breaches_by_sla"`
+}
+
+// statsEventKinds are the only events the stats aggregation reads.
+var statsEventKinds = []runtime.EventKind{
+	runtime.EventRunStarted,
+	runtime.EventRunFinished,
+	runtime.EventNodeFailed,
+	runtime.EventNodeOutputFinal,
+	runtime.EventLLMResponse,
+}
+
+// handleWorkflowStats returns health data for a workflow's recent runs,
+// computed from the event store.
+func (s *Server) handleWorkflowStats(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	lister, ok := s.eventStore.(workflowEventLister)
+	if !ok {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "event store does not support workflow stats")
+		return
+	}
+
+	window := DefaultStatsWindow
+	windowLabel := "7d"
+	if raw := r.URL.Query().Get("window"); raw != "" {
+		d, err := parseStatsWindow(raw)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_WINDOW", err.Error())
+			return
+		}
+		window, windowLabel = d, raw
+	}
+
+	if _, found, err := s.store.Get(r.Context(), id); err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	} else if !found {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+		return
+	}
+
+	since := time.Now().Add(-window)
+	events, err := lister.WorkflowEvents(r.Context(), id, since, statsEventKinds...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+
+	stats := aggregateWorkflowStats(events)
+	stats.WorkflowID = id
+	stats.Window = windowLabel
+	stats.Since = since.UTC()
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// parseStatsWindow accepts Go durations plus a whole-day "d" suffix.
+func parseStatsWindow(raw string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %q", raw)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return 0, fmt.Errorf("invalid window %q: %v", raw, err)
+		}
+		d = parsed
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("window %q must be positive", raw)
+	}
+	return d, nil
+}
+
+// runUsage accumulates token usage for one run. Provider-level llm.response
+// events are preferred; node.output.final usage is the fallback for clients
+// that are not instrumented.
+type runUsage struct {
+	llmTokens, llmCost      float64
+	nodeTokens, nodeCost    float64
+	llmRegions, nodeRegions map[string]float64
+	hasLLM                  bool
+}
+
+func (u runUsage) totals() (tokens, cost float64) {
+	if u.hasLLM {
+		return u.llmTokens, u.llmCost
+	}
+	return u.nodeTokens, u.nodeCost
+}
+
+// regions returns the run's tokens per region, from the same events as
+// totals.
+func (u runUsage) regions() map[string]float64 {
+	if u.hasLLM {
+		return u.llmRegions
+	}
+	return u.nodeRegions
+}
+
+// addRegionTokens adds the tokens of an event that names its region.
+func addRegionTokens(regions *map[string]float64, payload map[string]any) {
+	region, _ := payload["region"].(string)
+	if region == "" {
+		return
+	}
+	if *regions == nil {
+		*regions = map[string]float64{}
+	}
+	(*regions)[region] += payloadNumber(payload, "total_tokens")
+}
+
+// aggregateWorkflowStats computes stats from events ordered by run and
+// sequence, as returned by workflowEventLister.
+func aggregateWorkflowStats(events []runtime.Event) WorkflowStats {
+	stats := WorkflowStats{
+		FailuresByNode: map[string]int{},
+		Triggers:       map[string]int{},
+	}
+
+	var durations []time.Duration
+	usage := map[string]*runUsage{}
+	finished := map[string]bool{}
+	for _, e := range events {
+		switch e.Kind {
+		case runtime.EventRunStarted:
+			stats.Runs++
+			usage[e.RunID] = &runUsage{}
+			trigger, _ := e.Payload["trigger"].(string)
+			if trigger == "" {
+				trigger = "manual"
+			}
+			stats.Triggers[trigger]++
+		case runtime.EventRunFinished:
+			finished[e.RunID] = true
+			if status, _ := e.Payload["status"].(string); status == "completed" {
+				stats.Completed++
+			} else {
+				stats.Failed++
+			}
+			durations = append(durations, e.Elapsed)
+			if breaches, ok := payloadStrings(e.Payload, "sla_breaches"); ok {
+				if stats.SLA == nil {
+					stats.SLA = &SLAStats{BreachesBySLA: map[string]int{}}
+				}
+				stats.SLA.Runs++
+				if len(breaches) == 0 {
+					stats.SLA.Met++
+				} else {
+					stats.SLA.Breached++
+				}
+				for _, name := range breaches {
+					stats.SLA.BreachesBySLA[name]++
+				}
+			}
+			if met, ok := e.Payload["deadline_met"].(bool); ok {
+				if stats.Deadlines == nil {
+					stats.Deadlines = &DeadlineStats{}
+				}
+				stats.Deadlines.Runs++
+				if met {
+					stats.Deadlines.Met++
+				} else {
+					stats.Deadlines.Missed++
+				}
+			}
+		case runtime.EventNodeFailed:
+			if e.NodeID != "" {
+				stats.FailuresByNode[e.NodeID]++
+			}
+		case runtime.EventLLMResponse:
+			if u := usage[e.RunID]; u != nil {
+				u.hasLLM = true
+				u.llmTokens += payloadNumber(e.Payload, "total_tokens")
+				u.llmCost += payloadNumber(e.Payload, "cost_usd")
+				addRegionTokens(&u.llmRegions, e.Payload)
+			}
+		case runtime.EventNodeOutputFinal:
+			if u := usage[e.RunID]; u != nil {
+				u.nodeTokens += payloadNumber(e.Payload, "total_tokens")
+				u.nodeCost += payloadNumber(e.Payload, "cost_usd")
+				addRegionTokens(&u.nodeRegions, e.Payload)
+			}
+		}
+	}
+
+	stats.Running = stats.Runs - len(finished)
+	if n := stats.Completed + stats.Failed; n > 0 {
+		stats.SuccessRate = float64(stats.Completed) / float64(n)
+	}
+
+	if stats.SLA != nil {
+		stats.SLA.Attainment = float64(stats.SLA.Met) / float64(stats.SLA.Runs)
+	}
+	if stats.Deadlines != nil {
+		stats.Deadlines.Attainment = float64(stats.Deadlines.Met) / float64(stats.Deadlines.Runs)
+	}
+
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	stats.DurationP50Ms = percentile(durations, 50).Milliseconds()
+	stats.DurationP95Ms = percentile(durations, 95).Milliseconds()
+
+	if stats.Runs > 0 {
+		var tokens, cost float64
+		for _, u := range usage {
+			t, c := u.totals()
+			tokens += t
+			cost += c
+			for region, n := range u.regions() {
+				if stats.TokensByRegion == nil {
+					stats.TokensByRegion = map[string]float64{}
+				}
+				stats.TokensByRegion[region] += n
+			}
+		}
+		stats.AvgTokensPerRun = tokens / float64(stats.Runs)
+		stats.AvgCostUSDPerRun = cost / float64(stats.Runs)
+	}
+	return stats
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// payloadStrings reads a string list payload value emitted in-process
+// ([]string) or decoded from a persistent store ([]any). ok is false when
+// the key is absent.
+func payloadStrings(payload map[string]any, key string) ([]string, bool) {
+	switch v := payload[key].(type) {
+	case []string:
+		return v, true
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out, true
+	default:
+		return nil, false
+	}
+}
+
+// payloadNumber reads a numeric payload value emitted in-process (int or
+// float) or decoded from a persistent store (float64).
+func payloadNumber(payload map[string]any, key string) float64 {
+	switch v := payload[key].(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	default:
+		return 0
+	}
+}
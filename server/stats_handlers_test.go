@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+func TestAggregateWorkflowStats(t *testing.T) {
+	ev := func(runID string, kind runtime.EventKind, nodeID string, elapsed time.Duration, payload map[string]any) runtime.Event {
+		e := runtime.NewEvent(kind, runID)
+		e.NodeID = nodeID
+		e.Elapsed = elapsed
+		if payload != nil {
+			e.Payload = payload
+		}
+		return e
+	}
+	events := []runtime.Event{
+		ev("r1", runtime.EventRunStarted, "", 0, map[string]any{"trigger": "manual"}),
+		ev("r1", runtime.EventNodeOutputFinal, "llm", 0, map[string]any{"total_tokens": 100, "cost_usd": 0.5, "region": "us-east"}),
+		ev("r1", runtime.EventRunFinished, "", 100*time.Millisecond, map[string]any{"status": "completed"}),
+
+		// Provider events win over node usage within a run.
+		ev("r2", runtime.EventRunStarted, "", 0, map[string]any{"trigger": "webhook"}),
+		ev("r2", runtime.EventLLMResponse, "llm", 0, map[string]any{"total_tokens": float64(300), "cost_usd": 1.0, "region": "eu-west"}),
+		ev("r2", runtime.EventNodeOutputFinal, "llm", 0, map[string]any{"total_tokens": 300, "cost_usd": 1.0, "region": "eu-west"}),
+		ev("r2", runtime.EventNodeFailed, "ship", 0, nil),
+		ev("r2", runtime.EventRunFinished, "", 300*time.Millisecond, map[string]any{"status": "failed"}),
+
+		ev("r3", runtime.EventRunStarted, "", 0, map[string]any{"trigger": "schedule"}),
+		ev("r3", runtime.EventRunFinished, "", 200*time.Millisecond, map[string]any{"status": "completed"}),
+
+		ev("r4", runtime.EventRunStarted, "", 0, nil),
+	}
+
+	stats := aggregateWorkflowStats(events)
+	if stats.Runs != 4 || stats.Completed != 2 || stats.Failed != 1 || stats.Running != 1 {
+		t.Fatalf("counts = %+v", stats)
+	}
+	if got := stats.SuccessRate; got < 0.66 || got > 0.67 {
+		t.Errorf("success rate = %v, want 2/3", got)
+	}
+	if stats.DurationP50Ms != 200 || stats.DurationP95Ms != 300 {
+		t.Errorf("p50/p95 = %d/%d, want 200/300", stats.DurationP50Ms, stats.DurationP95Ms)
+	}
+	if stats.FailuresByNode["ship"] != 1 || len(stats.FailuresByNode) != 1 {
+		t.Errorf("failures = %v", stats.FailuresByNode)
+	}
+	if stats.AvgTokensPerRun != 100 || stats.AvgCostUSDPerRun != 0.375 {
+		t.Errorf("avg tokens/cost = %v/%v, want 100/0.375", stats.AvgTokensPerRun, stats.AvgCostUSDPerRun)
+	}
+	if len(stats.TokensByRegion) != 2 || stats.TokensByRegion["us-east"] != 100 || stats.TokensByRegion["eu-west"] != 300 {
+		t.Errorf("tokens by region = %v", stats.TokensByRegion)
+	}
+	want := map[string]int{"manual": 2, "webhook": 1, "schedule": 1}
+	for trigger, n := range want {
+		if stats.Triggers[trigger] != n {
+			t.Errorf("triggers = %v, want %v", stats.Triggers, want)
+			break
+		}
+	}
+	if stats.SLA != nil {
+		t.Errorf("SLA = %+v, want nil without SLA runs", stats.SLA)
+	}
+}
+
+func TestAggregateWorkflowStats_SLA(t *testing.T) {
+	finished := func(runID string, breaches any) runtime.Event {
+		e := runtime.NewEvent(runtime.EventRunFinished, runID)
+		e.Payload = map[string]any{"status": "completed", "sla_breaches": breaches}
+		return e
+	}
+	events := []runtime.Event{
+		runtime.NewEvent(runtime.EventRunStarted, "r1"),
+		finished("r1", []string{}),
+		runtime.NewEvent(runtime.EventRunStarted, "r2"),
+		// Decoded from the event store.
+		finished("r2", []any{"max_duration", "max_queue_wait"}),
+		runtime.NewEvent(runtime.EventRunStarted, "r3"),
+		finished("r3", []string{"max_duration"}),
+		runtime.NewEvent(runtime.EventRunStarted, "r4"),
+		finished("r4", []any{}),
+	}
+
+	sla := aggregateWorkflowStats(events).SLA
+	if sla == nil || sla.Runs != 4 || sla.Met != 2 || sla.Breached != 2 || sla.Attainment != 0.5 {
+		t.Fatalf("SLA = %+v", sla)
+	}
+	if sla.BreachesBySLA["max_duration"] != 2 || sla.BreachesBySLA["max_queue_wait"] != 1 {
+		t.Errorf("breaches by SLA = %v", sla.BreachesBySLA)
+	}
+}
+
+func TestWorkflowStats_Endpoint(t *testing.T) {
+	handler := testServer(t).Handler()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(validGraphJSON("stats-test")))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got %d; body: %s", w.Code, w.Body.String())
+	}
+	for i := 0; i < 2; i++ {
+		r = httptest.NewRequest(http.MethodPost, "/api/workflows/stats-test/run", bytes.NewReader([]byte(`{}`)))
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("run: got %d; body: %s", w.Code, w.Body.String())
+		}
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w = get("/api/workflows/stats-test/stats?window=1d")
+	if w.Code != http.StatusOK {
+		t.Fatalf("stats: got %d; body: %s", w.Code, w.Body.String())
+	}
+	var stats WorkflowStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if stats.WorkflowID != "stats-test" || stats.Window != "1d" || stats.Runs != 2 ||
+		stats.Completed != 2 || stats.SuccessRate != 1 || stats.Triggers["manual"] != 2 {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	if w := get("/api/workflows/stats-test/stats?window=soon"); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid window: got %d, want 400", w.Code)
+	}
+	if w := get("/api/workflows/missing/stats"); w.Code != http.StatusNotFound {
+		t.Errorf("missing workflow: got %d, want 404", w.Code)
+	}
+}
+
+func TestWorkflowStats_SLAAttainment(t *testing.T) {
+	handler := testServer(t).Handler()
+
+	var gd map[string]any
+	_ = json.Unmarshal(validGraphJSON("sla-test"), &gd)
+	gd["sla"] = map[string]any{"max_duration": "1ns", "max_queue_wait": "1h"}
+	body, _ := json.Marshal(gd)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(body)))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got %d; body: %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/workflows/sla-test/run", bytes.NewReader([]byte(`{}`))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("run: got %d; body: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/history?workflow_id=sla-test", nil))
+	var page RunHistoryPage
+	if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	if len(page.Runs) != 1 || len(page.Runs[0].SLABreaches) != 1 || page.Runs[0].SLABreaches[0] != "max_duration" {
+		t.Errorf("history = %+v", page.Runs)
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/sla-test/stats", nil))
+	var stats WorkflowStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	if stats.SLA == nil || stats.SLA.Runs != 1 || stats.SLA.Breached != 1 || stats.SLA.Attainment != 0 ||
+		stats.SLA.BreachesBySLA["max_duration"] != 1 {
+		t.Errorf("SLA stats = %+v", stats.SLA)
+	}
+}
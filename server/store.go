@@ -18,13 +18,26 @@ var (
 
 // WorkflowRecord represents a stored workflow.
 type WorkflowRecord struct {
-	ID         string                 `json:"id"`
-	SchemaKind loader.SchemaKind      `json:"kind"`
-	Name       string                 `json:"name,omitempty"`
-	Source     json.RawMessage        `json:"source"`
+	ID         string            `json:"id"`
+	SchemaKind loader.SchemaKind `json:"kind"`
+	Name       string            `json:"name,omitempty"`
+	// Source is the definition as JSON, whatever format it was submitted in.
+	Source json.RawMessage `json:"source"`
+	// SourceFormat is the format the definition was submitted in. Empty
+	// means JSON.
+	SourceFormat loader.SourceFormat `json:"source_format,omitempty"`
+	// SourceText holds the submitted definition verbatim when it was not
+	// JSON, so exports can reproduce it.
+	SourceText string                 `json:"-"`
 	Compiled   *graph.GraphDefinition `json:"compiled,omitempty"`
 	CreatedAt  time.Time              `json:"created_at"`
 	UpdatedAt  time.Time              `json:"updated_at"`
+	// PolicyWarnings lists the policy violations of warning severity
+	// found when the workflow was saved. Only save responses carry them.
+	PolicyWarnings []string `json:"policy_warnings,omitempty"`
+	// Lifecycle is the workflow's lifecycle state. Only list and get
+	// responses carry it.
+	Lifecycle *LifecycleStatus `json:"lifecycle,omitempty"`
 }
 
 // WorkflowStore provides CRUD operations for workflow records.
@@ -6,11 +6,17 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/petal-labs/petalflow/evals"
 	"github.com/petal-labs/petalflow/graph"
 	"github.com/petal-labs/petalflow/loader"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/nodes/conditional"
+	"github.com/petal-labs/petalflow/runtime"
+	"github.com/petal-labs/petalflow/templates"
 
 	_ "modernc.org/sqlite"
 )
@@ -23,6 +29,8 @@ CREATE TABLE IF NOT EXISTS workflows (
 	name TEXT,
 	source BLOB NOT NULL,
 	compiled BLOB,
+	source_format TEXT,
+	source_text BLOB,
 	created_at TEXT NOT NULL,
 	updated_at TEXT NOT NULL
 );
@@ -34,6 +42,11 @@ CREATE TABLE IF NOT EXISTS workflow_schedules (
 	enabled INTEGER NOT NULL DEFAULT 1,
 	input_json BLOB NOT NULL,
 	options_json BLOB NOT NULL,
+	timezone TEXT,
+	jitter TEXT,
+	missed_fire TEXT,
+	max_backfill INTEGER NOT NULL DEFAULT 0,
+	next_fire_at TEXT,
 	next_run_at TEXT NOT NULL,
 	last_run_at TEXT,
 	last_run_id TEXT,
@@ -48,28 +61,198 @@ CREATE INDEX IF NOT EXISTS idx_workflow_schedules_workflow
 ON workflow_schedules(workflow_id);
 
 CREATE INDEX IF NOT EXISTS idx_workflow_schedules_due
-ON workflow_schedules(enabled, next_run_at);`
+ON workflow_schedules(enabled, next_run_at);
+
+CREATE TABLE IF NOT EXISTS uploads (
+	id TEXT PRIMARY KEY,
+	workspace TEXT NOT NULL,
+	name TEXT,
+	content_type TEXT NOT NULL,
+	size INTEGER NOT NULL,
+	sha256 TEXT NOT NULL,
+	data BLOB NOT NULL,
+	created_at TEXT NOT NULL,
+	scan TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_uploads_workspace
+ON uploads(workspace);
+
+CREATE TABLE IF NOT EXISTS run_leases (
+	run_id TEXT PRIMARY KEY,
+	workflow_id TEXT NOT NULL,
+	owner TEXT NOT NULL,
+	status TEXT NOT NULL,
+	request_json BLOB,
+	started_at TEXT NOT NULL,
+	heartbeat_at TEXT NOT NULL,
+	expires_at TEXT NOT NULL,
+	interrupted_at TEXT,
+	requeued_run_id TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_run_leases_expiry
+ON run_leases(status, expires_at);
+
+CREATE TABLE IF NOT EXISTS conditions (
+	name TEXT PRIMARY KEY,
+	payload BLOB NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS workflow_templates (
+	id TEXT PRIMARY KEY,
+	payload BLOB NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS workflow_rollouts (
+	workflow_id TEXT PRIMARY KEY,
+	payload BLOB NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	FOREIGN KEY(workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS workflow_lifecycles (
+	workflow_id TEXT PRIMARY KEY,
+	payload BLOB NOT NULL,
+	updated_at TEXT NOT NULL,
+	FOREIGN KEY(workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	at TEXT NOT NULL,
+	actor TEXT NOT NULL,
+	kind TEXT NOT NULL,
+	workflow_id TEXT NOT NULL,
+	method TEXT NOT NULL,
+	payload BLOB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_at ON audit_log(at);
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+	SELECT RAISE(ABORT, 'audit log is append-only');
+END;
+
+CREATE TABLE IF NOT EXISTS workflow_presets (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	workflow_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	payload BLOB NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	UNIQUE(workflow_id, name),
+	FOREIGN KEY(workflow_id) REFERENCES workflows(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS eval_datasets (
+	name TEXT PRIMARY KEY,
+	payload BLOB NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS llm_output_history (
+	seq INTEGER PRIMARY KEY AUTOINCREMENT,
+	history_key TEXT NOT NULL,
+	sample BLOB NOT NULL,
+	created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_llm_output_history_key ON llm_output_history(history_key, seq);
+
+CREATE TABLE IF NOT EXISTS model_arm_stats (
+	stats_key TEXT NOT NULL,
+	arm TEXT NOT NULL,
+	pulls INTEGER NOT NULL DEFAULT 0,
+	rewards INTEGER NOT NULL DEFAULT 0,
+	reward_sum REAL NOT NULL DEFAULT 0,
+	updated_at TEXT NOT NULL,
+	PRIMARY KEY (stats_key, arm)
+);
+
+CREATE TABLE IF NOT EXISTS model_selections (
+	selection_id TEXT PRIMARY KEY,
+	stats_key TEXT NOT NULL,
+	payload BLOB NOT NULL,
+	created_at TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS workflow_state (
+	namespace TEXT NOT NULL,
+	state_key TEXT NOT NULL,
+	value TEXT NOT NULL,
+	expires_at INTEGER,
+	updated_at TEXT NOT NULL,
+	PRIMARY KEY (namespace, state_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_state_expires ON workflow_state(expires_at);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	workflow_id TEXT NOT NULL,
+	trigger_id TEXT NOT NULL,
+	event_id TEXT NOT NULL,
+	run_id TEXT NOT NULL,
+	received_at TEXT NOT NULL,
+	expires_at TEXT NOT NULL,
+	PRIMARY KEY (workflow_id, trigger_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_expires ON webhook_deliveries(expires_at);
+
+CREATE TABLE IF NOT EXISTS outbox_deliveries (
+	id TEXT PRIMARY KEY,
+	run_id TEXT NOT NULL,
+	node_id TEXT NOT NULL,
+	method TEXT NOT NULL,
+	url TEXT NOT NULL,
+	headers TEXT NOT NULL,
+	body BLOB NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	status_code INTEGER NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at INTEGER NOT NULL,
+	created_at TEXT NOT NULL,
+	updated_at TEXT NOT NULL,
+	delivered_at TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_deliveries_due ON outbox_deliveries(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_outbox_deliveries_run ON outbox_deliveries(run_id, created_at);`
 
 var workflowInsertQueries = [8]string{
-	"INSERT INTO workflows (id, schema_kind, name, source, compiled, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?)",
-	"INSERT INTO workflows (id, schema_kind, kind, name, source, compiled, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?)",
-	"INSERT INTO workflows (id, schema_kind, name, source, source_json, compiled, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?)",
-	"INSERT INTO workflows (id, schema_kind, kind, name, source, source_json, compiled, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
-	"INSERT INTO workflows (id, schema_kind, name, source, compiled, compiled_json, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?)",
-	"INSERT INTO workflows (id, schema_kind, kind, name, source, compiled, compiled_json, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
-	"INSERT INTO workflows (id, schema_kind, name, source, source_json, compiled, compiled_json, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
-	"INSERT INTO workflows (id, schema_kind, kind, name, source, source_json, compiled, compiled_json, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	"INSERT INTO workflows (id, schema_kind, name, source, compiled, source_format, source_text, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	"INSERT INTO workflows (id, schema_kind, kind, name, source, compiled, source_format, source_text, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	"INSERT INTO workflows (id, schema_kind, name, source, source_json, compiled, source_format, source_text, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	"INSERT INTO workflows (id, schema_kind, kind, name, source, source_json, compiled, source_format, source_text, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	"INSERT INTO workflows (id, schema_kind, name, source, compiled, compiled_json, source_format, source_text, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	"INSERT INTO workflows (id, schema_kind, kind, name, source, compiled, compiled_json, source_format, source_text, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	"INSERT INTO workflows (id, schema_kind, name, source, source_json, compiled, compiled_json, source_format, source_text, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+	"INSERT INTO workflows (id, schema_kind, kind, name, source, source_json, compiled, compiled_json, source_format, source_text, created_at, updated_at)\nVALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
 }
 
 var workflowUpdateQueries = [8]string{
-	"UPDATE workflows\nSET schema_kind = ?, name = ?, source = ?, compiled = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
-	"UPDATE workflows\nSET schema_kind = ?, kind = ?, name = ?, source = ?, compiled = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
-	"UPDATE workflows\nSET schema_kind = ?, name = ?, source = ?, source_json = ?, compiled = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
-	"UPDATE workflows\nSET schema_kind = ?, kind = ?, name = ?, source = ?, source_json = ?, compiled = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
-	"UPDATE workflows\nSET schema_kind = ?, name = ?, source = ?, compiled = ?, compiled_json = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
-	"UPDATE workflows\nSET schema_kind = ?, kind = ?, name = ?, source = ?, compiled = ?, compiled_json = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
-	"UPDATE workflows\nSET schema_kind = ?, name = ?, source = ?, source_json = ?, compiled = ?, compiled_json = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
-	"UPDATE workflows\nSET schema_kind = ?, kind = ?, name = ?, source = ?, source_json = ?, compiled = ?, compiled_json = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
+	"UPDATE workflows\nSET schema_kind = ?, name = ?, source = ?, compiled = ?, source_format = ?, source_text = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
+	"UPDATE workflows\nSET schema_kind = ?, kind = ?, name = ?, source = ?, compiled = ?, source_format = ?, source_text = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
+	"UPDATE workflows\nSET schema_kind = ?, name = ?, source = ?, source_json = ?, compiled = ?, source_format = ?, source_text = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
+	"UPDATE workflows\nSET schema_kind = ?, kind = ?, name = ?, source = ?, source_json = ?, compiled = ?, source_format = ?, source_text = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
+	"UPDATE workflows\nSET schema_kind = ?, name = ?, source = ?, compiled = ?, compiled_json = ?, source_format = ?, source_text = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
+	"UPDATE workflows\nSET schema_kind = ?, kind = ?, name = ?, source = ?, compiled = ?, compiled_json = ?, source_format = ?, source_text = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
+	"UPDATE workflows\nSET schema_kind = ?, name = ?, source = ?, source_json = ?, compiled = ?, compiled_json = ?, source_format = ?, source_text = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
+	"UPDATE workflows\nSET schema_kind = ?, kind = ?, name = ?, source = ?, source_json = ?, compiled = ?, compiled_json = ?, source_format = ?, source_text = ?, created_at = ?, updated_at = ?\nWHERE id = ?",
 }
 
 // SQLiteStoreConfig configures the SQLite workflow store.
@@ -91,7 +274,7 @@ func NewSQLiteStore(cfg SQLiteStoreConfig) (*SQLiteStore, error) {
 		return nil, errors.New("workflow store sqlite dsn is required")
 	}
 
-	db, err := sql.Open("sqlite", cfg.DSN)
+	db, err := sql.Open("sqlite", withSQLiteBusyTimeout(cfg.DSN))
 	if err != nil {
 		return nil, fmt.Errorf("workflow sqlite store open: %w", err)
 	}
@@ -113,6 +296,14 @@ func NewSQLiteStore(cfg SQLiteStoreConfig) (*SQLiteStore, error) {
 		_ = db.Close()
 		return nil, err
 	}
+	if err := migrateUploadsSQLiteSchema(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	if err := migrateSchedulesSQLiteSchema(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 	workflowColumns, err := sqliteTableColumns(db, "workflows")
 	if err != nil {
 		_ = db.Close()
@@ -129,7 +320,7 @@ func NewSQLiteStore(cfg SQLiteStoreConfig) (*SQLiteStore, error) {
 
 func (s *SQLiteStore) List(ctx context.Context) ([]WorkflowRecord, error) {
 	rows, err := s.db.QueryContext(ctx, `
-SELECT id, schema_kind, name, source, compiled, created_at, updated_at
+SELECT id, schema_kind, name, source, compiled, source_format, source_text, created_at, updated_at
 FROM workflows
 ORDER BY seq ASC`)
 	if err != nil {
@@ -155,7 +346,7 @@ ORDER BY seq ASC`)
 
 func (s *SQLiteStore) Get(ctx context.Context, id string) (WorkflowRecord, bool, error) {
 	row := s.db.QueryRowContext(ctx, `
-SELECT id, schema_kind, name, source, compiled, created_at, updated_at
+SELECT id, schema_kind, name, source, compiled, source_format, source_text, created_at, updated_at
 FROM workflows
 WHERE id = ?`, id)
 
@@ -197,6 +388,7 @@ func (s *SQLiteStore) Create(ctx context.Context, rec WorkflowRecord) error {
 	if s.workflowHasLegacyCompiledJSON {
 		args = append(args, legacyCompiled)
 	}
+	args = append(args, workflowSourceFormat(rec), workflowSourceText(rec))
 	args = append(args,
 		rec.CreatedAt.UTC().Format(time.RFC3339Nano),
 		rec.UpdatedAt.UTC().Format(time.RFC3339Nano),
@@ -237,6 +429,7 @@ func (s *SQLiteStore) Update(ctx context.Context, rec WorkflowRecord) error {
 	if s.workflowHasLegacyCompiledJSON {
 		args = append(args, legacyCompiled)
 	}
+	args = append(args, workflowSourceFormat(rec), workflowSourceText(rec))
 	args = append(args,
 		rec.CreatedAt.UTC().Format(time.RFC3339Nano),
 		rec.UpdatedAt.UTC().Format(time.RFC3339Nano),
@@ -277,7 +470,7 @@ func (s *SQLiteStore) Delete(ctx context.Context, id string) error {
 
 func (s *SQLiteStore) ListSchedules(ctx context.Context, workflowID string) ([]WorkflowSchedule, error) {
 	rows, err := s.db.QueryContext(ctx, `
-SELECT id, workflow_id, cron_expr, enabled, input_json, options_json, next_run_at, last_run_at, last_run_id, last_status, last_error, created_at, updated_at
+SELECT id, workflow_id, cron_expr, enabled, input_json, options_json, timezone, jitter, missed_fire, max_backfill, next_fire_at, next_run_at, last_run_at, last_run_id, last_status, last_error, created_at, updated_at
 FROM workflow_schedules
 WHERE workflow_id = ?
 ORDER BY created_at ASC`, workflowID)
@@ -302,7 +495,7 @@ ORDER BY created_at ASC`, workflowID)
 
 func (s *SQLiteStore) GetSchedule(ctx context.Context, workflowID, scheduleID string) (WorkflowSchedule, bool, error) {
 	row := s.db.QueryRowContext(ctx, `
-SELECT id, workflow_id, cron_expr, enabled, input_json, options_json, next_run_at, last_run_at, last_run_id, last_status, last_error, created_at, updated_at
+SELECT id, workflow_id, cron_expr, enabled, input_json, options_json, timezone, jitter, missed_fire, max_backfill, next_fire_at, next_run_at, last_run_at, last_run_id, last_status, last_error, created_at, updated_at
 FROM workflow_schedules
 WHERE workflow_id = ? AND id = ?`, workflowID, scheduleID)
 
@@ -341,15 +534,20 @@ func (s *SQLiteStore) CreateSchedule(ctx context.Context, schedule WorkflowSched
 
 	_, err = s.db.ExecContext(ctx, `
 INSERT INTO workflow_schedules
-	(id, workflow_id, cron_expr, enabled, input_json, options_json, next_run_at, last_run_at, last_run_id, last_status, last_error, created_at, updated_at)
+	(id, workflow_id, cron_expr, enabled, input_json, options_json, timezone, jitter, missed_fire, max_backfill, next_fire_at, next_run_at, last_run_at, last_run_id, last_status, last_error, created_at, updated_at)
 VALUES
-	(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		schedule.ID,
 		schedule.WorkflowID,
 		schedule.Cron,
 		enabled,
 		inputJSON,
 		optionsJSON,
+		nullIfEmpty(schedule.Timezone),
+		nullIfEmpty(schedule.Jitter),
+		nullIfEmpty(schedule.MissedFire),
+		schedule.MaxBackfill,
+		formatNullableTime(&schedule.NextFireAt),
 		schedule.NextRunAt.UTC().Format(time.RFC3339Nano),
 		formatNullableTime(schedule.LastRunAt),
 		nullIfEmpty(schedule.LastRunID),
@@ -393,6 +591,11 @@ SET
 	enabled = ?,
 	input_json = ?,
 	options_json = ?,
+	timezone = ?,
+	jitter = ?,
+	missed_fire = ?,
+	max_backfill = ?,
+	next_fire_at = ?,
 	next_run_at = ?,
 	last_run_at = ?,
 	last_run_id = ?,
@@ -404,6 +607,11 @@ WHERE workflow_id = ? AND id = ?`,
 		enabled,
 		inputJSON,
 		optionsJSON,
+		nullIfEmpty(schedule.Timezone),
+		nullIfEmpty(schedule.Jitter),
+		nullIfEmpty(schedule.MissedFire),
+		schedule.MaxBackfill,
+		formatNullableTime(&schedule.NextFireAt),
 		schedule.NextRunAt.UTC().Format(time.RFC3339Nano),
 		formatNullableTime(schedule.LastRunAt),
 		nullIfEmpty(schedule.LastRunID),
@@ -456,7 +664,7 @@ WHERE workflow_id = ?`, workflowID); err != nil {
 
 func (s *SQLiteStore) ListDueSchedules(ctx context.Context, now time.Time, limit int) ([]WorkflowSchedule, error) {
 	query := `
-SELECT id, workflow_id, cron_expr, enabled, input_json, options_json, next_run_at, last_run_at, last_run_id, last_status, last_error, created_at, updated_at
+SELECT id, workflow_id, cron_expr, enabled, input_json, options_json, timezone, jitter, missed_fire, max_backfill, next_fire_at, next_run_at, last_run_at, last_run_id, last_status, last_error, created_at, updated_at
 FROM workflow_schedules
 WHERE enabled = 1 AND next_run_at <= ?
 ORDER BY next_run_at ASC`
@@ -486,188 +694,1556 @@ ORDER BY next_run_at ASC`
 	return schedules, nil
 }
 
-// Close closes the underlying database connection.
-func (s *SQLiteStore) Close() error {
-	if s == nil || s.db == nil {
-		return nil
+func (s *SQLiteStore) CreateUpload(ctx context.Context, upload Upload, quota int64) error {
+	if upload.CreatedAt.IsZero() {
+		upload.CreatedAt = time.Now().UTC()
 	}
-	return s.db.Close()
-}
-
-func marshalCompiledGraph(compiled *graph.GraphDefinition) ([]byte, error) {
-	if compiled == nil {
-		return nil, nil
+	var scan sql.NullString
+	if upload.Scan != nil {
+		data, err := json.Marshal(upload.Scan)
+		if err != nil {
+			return fmt.Errorf("workflow sqlite store encode upload scan: %w", err)
+		}
+		scan = sql.NullString{String: string(data), Valid: true}
 	}
-	data, err := json.Marshal(compiled)
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("workflow sqlite store marshal compiled graph: %w", err)
+		return fmt.Errorf("workflow sqlite store create upload: %w", err)
 	}
-	return data, nil
-}
+	defer func() { _ = tx.Rollback() }()
 
-type workflowScanner interface {
-	Scan(dest ...any) error
-}
+	if quota > 0 {
+		var used int64
+		if err := tx.QueryRowContext(ctx, `
+SELECT COALESCE(SUM(size), 0)
+FROM uploads
+WHERE workspace = ?`, upload.Workspace).Scan(&used); err != nil {
+			return fmt.Errorf("workflow sqlite store upload usage: %w", err)
+		}
+		if used+upload.Size > quota {
+			return ErrUploadQuotaExceeded
+		}
+	}
 
-type scheduleScanner interface {
-	Scan(dest ...any) error
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO uploads (id, workspace, name, content_type, size, sha256, data, created_at, scan)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		upload.ID,
+		upload.Workspace,
+		nullIfEmpty(upload.Name),
+		upload.ContentType,
+		upload.Size,
+		upload.SHA256,
+		upload.Data,
+		upload.CreatedAt.UTC().Format(time.RFC3339Nano),
+		scan,
+	); err != nil {
+		return fmt.Errorf("workflow sqlite store create upload: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("workflow sqlite store create upload commit: %w", err)
+	}
+	return nil
 }
 
-func scanWorkflowRecord(scanner workflowScanner) (WorkflowRecord, error) {
+func (s *SQLiteStore) GetUpload(ctx context.Context, workspace, id string) (Upload, bool, error) {
 	var (
-		id        string
-		kind      string
+		upload    Upload
 		name      sql.NullString
-		sourceRaw []byte
-		compRaw   []byte
 		createdAt string
-		updatedAt string
+		scan      sql.NullString
 	)
-	if err := scanner.Scan(&id, &kind, &name, &sourceRaw, &compRaw, &createdAt, &updatedAt); err != nil {
-		return WorkflowRecord{}, err
+	err := s.db.QueryRowContext(ctx, `
+SELECT id, workspace, name, content_type, size, sha256, data, created_at, scan
+FROM uploads
+WHERE workspace = ? AND id = ?`, workspace, id).Scan(
+		&upload.ID,
+		&upload.Workspace,
+		&name,
+		&upload.ContentType,
+		&upload.Size,
+		&upload.SHA256,
+		&upload.Data,
+		&createdAt,
+		&scan,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Upload{}, false, nil
+		}
+		return Upload{}, false, fmt.Errorf("workflow sqlite store get upload: %w", err)
 	}
-
-	created, err := time.Parse(time.RFC3339Nano, createdAt)
+	upload.Name = name.String
+	upload.CreatedAt, err = time.Parse(time.RFC3339Nano, createdAt)
 	if err != nil {
-		return WorkflowRecord{}, fmt.Errorf("workflow sqlite store parse created_at: %w", err)
+		return Upload{}, false, fmt.Errorf("workflow sqlite store parse upload created_at: %w", err)
 	}
-	updated, err := time.Parse(time.RFC3339Nano, updatedAt)
+	if scan.Valid {
+		upload.Scan = &UploadScan{}
+		if err := json.Unmarshal([]byte(scan.String), upload.Scan); err != nil {
+			return Upload{}, false, fmt.Errorf("workflow sqlite store decode upload scan: %w", err)
+		}
+	}
+	return upload, true, nil
+}
+
+func (s *SQLiteStore) DeleteUpload(ctx context.Context, workspace, id string) error {
+	res, err := s.db.ExecContext(ctx, `
+DELETE FROM uploads
+WHERE workspace = ? AND id = ?`, workspace, id)
 	if err != nil {
-		return WorkflowRecord{}, fmt.Errorf("workflow sqlite store parse updated_at: %w", err)
+		return fmt.Errorf("workflow sqlite store delete upload: %w", err)
 	}
 
-	rec := WorkflowRecord{
-		ID:         id,
-		SchemaKind: loader.SchemaKind(kind),
-		Name:       name.String,
-		Source:     json.RawMessage(append([]byte(nil), sourceRaw...)),
-		CreatedAt:  created,
-		UpdatedAt:  updated,
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store delete upload affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrUploadNotFound
 	}
+	return nil
+}
 
-	if len(compRaw) > 0 {
-		var compiled graph.GraphDefinition
-		if err := json.Unmarshal(compRaw, &compiled); err != nil {
-			return WorkflowRecord{}, fmt.Errorf("workflow sqlite store unmarshal compiled graph: %w", err)
-		}
-		rec.Compiled = &compiled
+func (s *SQLiteStore) UploadUsage(ctx context.Context, workspace string) (int64, error) {
+	var used int64
+	if err := s.db.QueryRowContext(ctx, `
+SELECT COALESCE(SUM(size), 0)
+FROM uploads
+WHERE workspace = ?`, workspace).Scan(&used); err != nil {
+		return 0, fmt.Errorf("workflow sqlite store upload usage: %w", err)
 	}
+	return used, nil
+}
 
-	return rec, nil
+func (s *SQLiteStore) AcquireLease(ctx context.Context, lease runtime.RunLease) error {
+	if _, err := s.db.ExecContext(ctx, `
+INSERT INTO run_leases (run_id, workflow_id, owner, status, request_json, started_at, heartbeat_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		lease.RunID,
+		lease.WorkflowID,
+		lease.Owner,
+		RunLeaseStatusRunning,
+		[]byte(lease.Request),
+		lease.StartedAt.UTC().Format(time.RFC3339Nano),
+		lease.HeartbeatAt.UTC().Format(time.RFC3339Nano),
+		lease.ExpiresAt.UTC().Format(time.RFC3339Nano),
+	); err != nil {
+		return fmt.Errorf("workflow sqlite store acquire lease: %w", err)
+	}
+	return nil
 }
 
-func scanWorkflowSchedule(scanner scheduleScanner) (WorkflowSchedule, error) {
-	var (
-		id         string
-		workflowID string
-		cronExpr   string
-		enabledRaw int
-		inputRaw   []byte
-		optionsRaw []byte
-		nextRunAt  string
-		lastRunAt  sql.NullString
-		lastRunID  sql.NullString
-		lastStatus sql.NullString
-		lastError  sql.NullString
-		createdAt  string
-		updatedAt  string
-	)
-	if err := scanner.Scan(
-		&id,
-		&workflowID,
-		&cronExpr,
-		&enabledRaw,
-		&inputRaw,
-		&optionsRaw,
-		&nextRunAt,
-		&lastRunAt,
-		&lastRunID,
-		&lastStatus,
-		&lastError,
-		&createdAt,
-		&updatedAt,
+func (s *SQLiteStore) RenewLease(ctx context.Context, runID string, heartbeatAt, expiresAt time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `
+UPDATE run_leases
+SET heartbeat_at = ?, expires_at = ?
+WHERE run_id = ? AND status = ?`,
+		heartbeatAt.UTC().Format(time.RFC3339Nano),
+		expiresAt.UTC().Format(time.RFC3339Nano),
+		runID,
+		RunLeaseStatusRunning,
 	); err != nil {
-		return WorkflowSchedule{}, err
+		return fmt.Errorf("workflow sqlite store renew lease: %w", err)
 	}
+	return nil
+}
 
-	next, err := time.Parse(time.RFC3339Nano, nextRunAt)
-	if err != nil {
-		return WorkflowSchedule{}, fmt.Errorf("workflow sqlite store parse schedule next_run_at: %w", err)
+func (s *SQLiteStore) ReleaseLease(ctx context.Context, runID string) error {
+	if _, err := s.db.ExecContext(ctx, `
+DELETE FROM run_leases
+WHERE run_id = ?`, runID); err != nil {
+		return fmt.Errorf("workflow sqlite store release lease: %w", err)
 	}
-	created, err := time.Parse(time.RFC3339Nano, createdAt)
+	return nil
+}
+
+func (s *SQLiteStore) ListRunLeases(ctx context.Context) ([]RunLeaseRecord, error) {
+	return s.queryRunLeases(ctx, `
+SELECT run_id, workflow_id, owner, status, request_json, started_at, heartbeat_at, expires_at, interrupted_at, requeued_run_id
+FROM run_leases
+ORDER BY started_at ASC`)
+}
+
+func (s *SQLiteStore) ListExpiredLeases(ctx context.Context, now time.Time, limit int) ([]RunLeaseRecord, error) {
+	query := `
+SELECT run_id, workflow_id, owner, status, request_json, started_at, heartbeat_at, expires_at, interrupted_at, requeued_run_id
+FROM run_leases
+WHERE status = ? AND expires_at < ?
+ORDER BY expires_at ASC`
+	args := []any{RunLeaseStatusRunning, now.UTC().Format(time.RFC3339Nano)}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	return s.queryRunLeases(ctx, query, args...)
+}
+
+func (s *SQLiteStore) MarkLeaseInterrupted(ctx context.Context, runID string, now time.Time) (bool, error) {
+	at := now.UTC().Format(time.RFC3339Nano)
+	res, err := s.db.ExecContext(ctx, `
+UPDATE run_leases
+SET status = ?, interrupted_at = ?
+WHERE run_id = ? AND status = ? AND expires_at < ?`,
+		RunLeaseStatusInterrupted, at, runID, RunLeaseStatusRunning, at)
 	if err != nil {
-		return WorkflowSchedule{}, fmt.Errorf("workflow sqlite store parse schedule created_at: %w", err)
+		return false, fmt.Errorf("workflow sqlite store mark lease interrupted: %w", err)
 	}
-	updated, err := time.Parse(time.RFC3339Nano, updatedAt)
+	affected, err := res.RowsAffected()
 	if err != nil {
-		return WorkflowSchedule{}, fmt.Errorf("workflow sqlite store parse schedule updated_at: %w", err)
+		return false, fmt.Errorf("workflow sqlite store mark lease interrupted affected rows: %w", err)
 	}
+	return affected == 1, nil
+}
 
-	input, err := unmarshalScheduleInput(inputRaw)
-	if err != nil {
-		return WorkflowSchedule{}, err
+func (s *SQLiteStore) SetLeaseRequeuedRun(ctx context.Context, runID, requeuedRunID string) error {
+	if _, err := s.db.ExecContext(ctx, `
+UPDATE run_leases
+SET requeued_run_id = ?
+WHERE run_id = ?`, requeuedRunID, runID); err != nil {
+		return fmt.Errorf("workflow sqlite store set requeued run: %w", err)
 	}
-	options, err := unmarshalScheduleOptions(optionsRaw)
+	return nil
+}
+
+func (s *SQLiteStore) queryRunLeases(ctx context.Context, query string, args ...any) ([]RunLeaseRecord, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return WorkflowSchedule{}, err
+		return nil, fmt.Errorf("workflow sqlite store list leases: %w", err)
 	}
+	defer rows.Close()
 
-	var lastRunPtr *time.Time
-	if lastRunAt.Valid && strings.TrimSpace(lastRunAt.String) != "" {
-		parsed, err := time.Parse(time.RFC3339Nano, lastRunAt.String)
-		if err != nil {
-			return WorkflowSchedule{}, fmt.Errorf("workflow sqlite store parse schedule last_run_at: %w", err)
+	var leases []RunLeaseRecord
+	for rows.Next() {
+		var (
+			rec           RunLeaseRecord
+			request       []byte
+			startedAt     string
+			heartbeatAt   string
+			expiresAt     string
+			interruptedAt sql.NullString
+			requeuedRunID sql.NullString
+		)
+		if err := rows.Scan(&rec.RunID, &rec.WorkflowID, &rec.Owner, &rec.Status, &request,
+			&startedAt, &heartbeatAt, &expiresAt, &interruptedAt, &requeuedRunID); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store scan lease: %w", err)
 		}
-		lastRunPtr = &parsed
+		if len(request) > 0 {
+			rec.Request = json.RawMessage(append([]byte(nil), request...))
+		}
+		for _, ts := range []struct {
+			raw  string
+			dest *time.Time
+		}{{startedAt, &rec.StartedAt}, {heartbeatAt, &rec.HeartbeatAt}, {expiresAt, &rec.ExpiresAt}} {
+			parsed, err := time.Parse(time.RFC3339Nano, ts.raw)
+			if err != nil {
+				return nil, fmt.Errorf("workflow sqlite store parse lease time: %w", err)
+			}
+			*ts.dest = parsed
+		}
+		if interruptedAt.Valid {
+			parsed, err := time.Parse(time.RFC3339Nano, interruptedAt.String)
+			if err != nil {
+				return nil, fmt.Errorf("workflow sqlite store parse interrupted_at: %w", err)
+			}
+			rec.InterruptedAt = &parsed
+		}
+		rec.RequeuedRunID = requeuedRunID.String
+		leases = append(leases, rec)
 	}
-
-	return WorkflowSchedule{
-		ID:         id,
-		WorkflowID: workflowID,
-		Cron:       cronExpr,
-		Enabled:    enabledRaw == 1,
-		Input:      input,
-		Options:    options,
-		NextRunAt:  next,
-		LastRunAt:  lastRunPtr,
-		LastRunID:  lastRunID.String,
-		LastStatus: lastStatus.String,
-		LastError:  lastError.String,
-		CreatedAt:  created,
-		UpdatedAt:  updated,
-	}, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list leases rows: %w", err)
+	}
+	return leases, nil
 }
 
-func isWorkflowSQLiteUniqueViolation(err error) bool {
-	if err == nil {
-		return false
+func (s *SQLiteStore) ListConditions(ctx context.Context) ([]conditional.Definition, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT payload FROM conditions ORDER BY name ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list conditions: %w", err)
 	}
-	msg := err.Error()
-	return strings.Contains(msg, "UNIQUE constraint failed: workflows.id")
-}
+	defer rows.Close()
 
-func isWorkflowScheduleSQLiteUniqueViolation(err error) bool {
-	if err == nil {
-		return false
+	var defs []conditional.Definition
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store scan condition: %w", err)
+		}
+		var def conditional.Definition
+		if err := json.Unmarshal(payload, &def); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store decode condition: %w", err)
+		}
+		defs = append(defs, def)
 	}
-	msg := err.Error()
-	return strings.Contains(msg, "UNIQUE constraint failed: workflow_schedules.id")
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list conditions rows: %w", err)
+	}
+	return defs, nil
 }
 
-func marshalScheduleInput(input map[string]any) ([]byte, error) {
-	if input == nil {
-		return []byte(`{}`), nil
+func (s *SQLiteStore) GetCondition(ctx context.Context, name string) (conditional.Definition, bool, error) {
+	var payload []byte
+	err := s.db.QueryRowContext(ctx, `SELECT payload FROM conditions WHERE name = ?`, name).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return conditional.Definition{}, false, nil
 	}
-	data, err := json.Marshal(input)
 	if err != nil {
-		return nil, fmt.Errorf("workflow sqlite store marshal schedule input: %w", err)
+		return conditional.Definition{}, false, fmt.Errorf("workflow sqlite store get condition: %w", err)
 	}
-	return data, nil
+	var def conditional.Definition
+	if err := json.Unmarshal(payload, &def); err != nil {
+		return conditional.Definition{}, false, fmt.Errorf("workflow sqlite store decode condition: %w", err)
+	}
+	return def, true, nil
 }
 
-func unmarshalScheduleInput(raw []byte) (map[string]any, error) {
-	if len(raw) == 0 {
-		return map[string]any{}, nil
+func (s *SQLiteStore) CreateCondition(ctx context.Context, def conditional.Definition) error {
+	now := time.Now().UTC()
+	if def.CreatedAt.IsZero() {
+		def.CreatedAt = now
+	}
+	if def.UpdatedAt.IsZero() {
+		def.UpdatedAt = def.CreatedAt
+	}
+	payload, err := json.Marshal(def)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store encode condition: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO conditions (name, payload, created_at, updated_at)
+VALUES (?, ?, ?, ?)`,
+		def.Name,
+		payload,
+		def.CreatedAt.UTC().Format(time.RFC3339Nano),
+		def.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: conditions.name") {
+			return ErrConditionExists
+		}
+		return fmt.Errorf("workflow sqlite store create condition: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UpdateCondition(ctx context.Context, def conditional.Definition) error {
+	if def.UpdatedAt.IsZero() {
+		def.UpdatedAt = time.Now().UTC()
+	}
+	payload, err := json.Marshal(def)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store encode condition: %w", err)
+	}
+	res, err := s.db.ExecContext(ctx, `
+UPDATE conditions
+SET payload = ?, updated_at = ?
+WHERE name = ?`,
+		payload,
+		def.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		def.Name,
+	)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store update condition: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store update condition affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrConditionNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteCondition(ctx context.Context, name string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM conditions WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store delete condition: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store delete condition affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrConditionNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListTemplates(ctx context.Context) ([]templates.Template, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT payload FROM workflow_templates ORDER BY id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list templates: %w", err)
+	}
+	defer rows.Close()
+
+	var out []templates.Template
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store scan template: %w", err)
+		}
+		var t templates.Template
+		if err := json.Unmarshal(payload, &t); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store decode template: %w", err)
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list templates rows: %w", err)
+	}
+	return out, nil
+}
+
+func (s *SQLiteStore) GetTemplate(ctx context.Context, id string) (templates.Template, bool, error) {
+	var payload []byte
+	err := s.db.QueryRowContext(ctx, `SELECT payload FROM workflow_templates WHERE id = ?`, id).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return templates.Template{}, false, nil
+	}
+	if err != nil {
+		return templates.Template{}, false, fmt.Errorf("workflow sqlite store get template: %w", err)
+	}
+	var t templates.Template
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return templates.Template{}, false, fmt.Errorf("workflow sqlite store decode template: %w", err)
+	}
+	return t, true, nil
+}
+
+func (s *SQLiteStore) CreateTemplate(ctx context.Context, t templates.Template) error {
+	now := time.Now().UTC()
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = now
+	}
+	if t.UpdatedAt.IsZero() {
+		t.UpdatedAt = t.CreatedAt
+	}
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store encode template: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO workflow_templates (id, payload, created_at, updated_at)
+VALUES (?, ?, ?, ?)`,
+		t.ID,
+		payload,
+		t.CreatedAt.UTC().Format(time.RFC3339Nano),
+		t.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: workflow_templates.id") {
+			return ErrTemplateExists
+		}
+		return fmt.Errorf("workflow sqlite store create template: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteTemplate(ctx context.Context, id string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM workflow_templates WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store delete template: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store delete template affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrTemplateNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) ListPresets(ctx context.Context, workflowID string) ([]WorkflowPreset, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT payload FROM workflow_presets WHERE workflow_id = ? ORDER BY name ASC`, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list presets: %w", err)
+	}
+	defer rows.Close()
+
+	presets := []WorkflowPreset{}
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store scan preset: %w", err)
+		}
+		var preset WorkflowPreset
+		if err := json.Unmarshal(payload, &preset); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store decode preset: %w", err)
+		}
+		presets = append(presets, preset)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list presets rows: %w", err)
+	}
+	return presets, nil
+}
+
+func (s *SQLiteStore) GetPreset(ctx context.Context, workflowID, name string) (WorkflowPreset, bool, error) {
+	var payload []byte
+	err := s.db.QueryRowContext(ctx, `SELECT payload FROM workflow_presets WHERE workflow_id = ? AND name = ?`, workflowID, name).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return WorkflowPreset{}, false, nil
+	}
+	if err != nil {
+		return WorkflowPreset{}, false, fmt.Errorf("workflow sqlite store get preset: %w", err)
+	}
+	var preset WorkflowPreset
+	if err := json.Unmarshal(payload, &preset); err != nil {
+		return WorkflowPreset{}, false, fmt.Errorf("workflow sqlite store decode preset: %w", err)
+	}
+	return preset, true, nil
+}
+
+func (s *SQLiteStore) SavePreset(ctx context.Context, preset WorkflowPreset) error {
+	now := time.Now().UTC()
+	if preset.CreatedAt.IsZero() {
+		preset.CreatedAt = now
+	}
+	if preset.UpdatedAt.IsZero() {
+		preset.UpdatedAt = preset.CreatedAt
+	}
+	payload, err := json.Marshal(preset)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store encode preset: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO workflow_presets (workflow_id, name, payload, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (workflow_id, name) DO UPDATE SET
+	payload = excluded.payload,
+	created_at = excluded.created_at,
+	updated_at = excluded.updated_at`,
+		preset.WorkflowID,
+		preset.Name,
+		payload,
+		preset.CreatedAt.UTC().Format(time.RFC3339Nano),
+		preset.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return ErrWorkflowNotFound
+		}
+		return fmt.Errorf("workflow sqlite store save preset: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeletePreset(ctx context.Context, workflowID, name string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM workflow_presets WHERE workflow_id = ? AND name = ?`, workflowID, name)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store delete preset: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store delete preset affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrPresetNotFound
+	}
+	return nil
+}
+
+// storedRollout keeps the candidate's verbatim source text, which
+// WorkflowRecord leaves out of its JSON.
+type storedRollout struct {
+	WorkflowRollout
+	CandidateSourceText string `json:"candidate_source_text,omitempty"`
+}
+
+func (s *SQLiteStore) GetRollout(ctx context.Context, workflowID string) (WorkflowRollout, bool, error) {
+	var payload []byte
+	err := s.db.QueryRowContext(ctx, `SELECT payload FROM workflow_rollouts WHERE workflow_id = ?`, workflowID).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return WorkflowRollout{}, false, nil
+	}
+	if err != nil {
+		return WorkflowRollout{}, false, fmt.Errorf("workflow sqlite store get rollout: %w", err)
+	}
+	var stored storedRollout
+	if err := json.Unmarshal(payload, &stored); err != nil {
+		return WorkflowRollout{}, false, fmt.Errorf("workflow sqlite store decode rollout: %w", err)
+	}
+	stored.Candidate.SourceText = stored.CandidateSourceText
+	return stored.WorkflowRollout, true, nil
+}
+
+func (s *SQLiteStore) SaveRollout(ctx context.Context, rollout WorkflowRollout) error {
+	now := time.Now().UTC()
+	if rollout.CreatedAt.IsZero() {
+		rollout.CreatedAt = now
+	}
+	if rollout.UpdatedAt.IsZero() {
+		rollout.UpdatedAt = rollout.CreatedAt
+	}
+	payload, err := json.Marshal(storedRollout{WorkflowRollout: rollout, CandidateSourceText: rollout.Candidate.SourceText})
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store encode rollout: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO workflow_rollouts (workflow_id, payload, created_at, updated_at)
+VALUES (?, ?, ?, ?)
+ON CONFLICT (workflow_id) DO UPDATE SET
+	payload = excluded.payload,
+	created_at = excluded.created_at,
+	updated_at = excluded.updated_at`,
+		rollout.WorkflowID,
+		payload,
+		rollout.CreatedAt.UTC().Format(time.RFC3339Nano),
+		rollout.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return ErrWorkflowNotFound
+		}
+		return fmt.Errorf("workflow sqlite store save rollout: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetLifecycle(ctx context.Context, workflowID string) (WorkflowLifecycle, bool, error) {
+	var payload []byte
+	err := s.db.QueryRowContext(ctx, `SELECT payload FROM workflow_lifecycles WHERE workflow_id = ?`, workflowID).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return WorkflowLifecycle{}, false, nil
+	}
+	if err != nil {
+		return WorkflowLifecycle{}, false, fmt.Errorf("workflow sqlite store get lifecycle: %w", err)
+	}
+	var lifecycle WorkflowLifecycle
+	if err := json.Unmarshal(payload, &lifecycle); err != nil {
+		return WorkflowLifecycle{}, false, fmt.Errorf("workflow sqlite store decode lifecycle: %w", err)
+	}
+	return lifecycle, true, nil
+}
+
+func (s *SQLiteStore) ListLifecycles(ctx context.Context) ([]WorkflowLifecycle, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT payload FROM workflow_lifecycles ORDER BY workflow_id ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list lifecycles: %w", err)
+	}
+	defer rows.Close()
+
+	lifecycles := []WorkflowLifecycle{}
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store scan lifecycle: %w", err)
+		}
+		var lifecycle WorkflowLifecycle
+		if err := json.Unmarshal(payload, &lifecycle); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store decode lifecycle: %w", err)
+		}
+		lifecycles = append(lifecycles, lifecycle)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list lifecycles rows: %w", err)
+	}
+	return lifecycles, nil
+}
+
+func (s *SQLiteStore) SaveLifecycle(ctx context.Context, lifecycle WorkflowLifecycle) error {
+	if lifecycle.UpdatedAt.IsZero() {
+		lifecycle.UpdatedAt = time.Now().UTC()
+	}
+	payload, err := json.Marshal(lifecycle)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store encode lifecycle: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO workflow_lifecycles (workflow_id, payload, updated_at)
+VALUES (?, ?, ?)
+ON CONFLICT (workflow_id) DO UPDATE SET
+	payload = excluded.payload,
+	updated_at = excluded.updated_at`,
+		lifecycle.WorkflowID,
+		payload,
+		lifecycle.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "FOREIGN KEY constraint failed") {
+			return ErrWorkflowNotFound
+		}
+		return fmt.Errorf("workflow sqlite store save lifecycle: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) AppendAudit(ctx context.Context, entry AuditEntry) (int64, error) {
+	if entry.At.IsZero() {
+		entry.At = time.Now().UTC()
+	}
+	entry.ID = 0
+	payload, err := json.Marshal(entry)
+	if err != nil {
+		return 0, fmt.Errorf("workflow sqlite store encode audit entry: %w", err)
+	}
+	res, err := s.db.ExecContext(ctx, `
+INSERT INTO audit_log (at, actor, kind, workflow_id, method, payload)
+VALUES (?, ?, ?, ?, ?, ?)`,
+		entry.At.UTC().Format(time.RFC3339Nano),
+		entry.Actor,
+		entry.Resource.Kind,
+		entry.Resource.Workflow,
+		entry.Method,
+		payload,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("workflow sqlite store append audit entry: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("workflow sqlite store audit entry id: %w", err)
+	}
+	return id, nil
+}
+
+func (s *SQLiteStore) ListAudit(ctx context.Context, q AuditQuery) ([]AuditEntry, error) {
+	query := `SELECT id, payload FROM audit_log WHERE 1 = 1`
+	var args []any
+	if q.Actor != "" {
+		query += " AND actor = ?"
+		args = append(args, q.Actor)
+	}
+	if q.Kind != "" {
+		query += " AND kind = ?"
+		args = append(args, q.Kind)
+	}
+	if q.Workflow != "" {
+		query += " AND workflow_id = ?"
+		args = append(args, q.Workflow)
+	}
+	if q.Method != "" {
+		query += " AND method = ?"
+		args = append(args, q.Method)
+	}
+	if !q.Since.IsZero() {
+		query += " AND at >= ?"
+		args = append(args, q.Since.UTC().Format(time.RFC3339Nano))
+	}
+	if !q.Until.IsZero() {
+		query += " AND at < ?"
+		args = append(args, q.Until.UTC().Format(time.RFC3339Nano))
+	}
+	if q.Before > 0 {
+		query += " AND id < ?"
+		args = append(args, q.Before)
+	}
+	query += " ORDER BY id DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list audit: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var (
+			id      int64
+			payload []byte
+		)
+		if err := rows.Scan(&id, &payload); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store scan audit entry: %w", err)
+		}
+		var entry AuditEntry
+		if err := json.Unmarshal(payload, &entry); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store decode audit entry: %w", err)
+		}
+		entry.ID = id
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list audit rows: %w", err)
+	}
+	return entries, nil
+}
+
+func (s *SQLiteStore) ListEvalDatasets(ctx context.Context) ([]evals.Dataset, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT payload FROM eval_datasets ORDER BY name ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list eval datasets: %w", err)
+	}
+	defer rows.Close()
+
+	var datasets []evals.Dataset
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store scan eval dataset: %w", err)
+		}
+		var ds evals.Dataset
+		if err := json.Unmarshal(payload, &ds); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store decode eval dataset: %w", err)
+		}
+		datasets = append(datasets, ds)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list eval datasets rows: %w", err)
+	}
+	return datasets, nil
+}
+
+func (s *SQLiteStore) GetEvalDataset(ctx context.Context, name string) (evals.Dataset, bool, error) {
+	var payload []byte
+	err := s.db.QueryRowContext(ctx, `SELECT payload FROM eval_datasets WHERE name = ?`, name).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return evals.Dataset{}, false, nil
+	}
+	if err != nil {
+		return evals.Dataset{}, false, fmt.Errorf("workflow sqlite store get eval dataset: %w", err)
+	}
+	var ds evals.Dataset
+	if err := json.Unmarshal(payload, &ds); err != nil {
+		return evals.Dataset{}, false, fmt.Errorf("workflow sqlite store decode eval dataset: %w", err)
+	}
+	return ds, true, nil
+}
+
+func (s *SQLiteStore) CreateEvalDataset(ctx context.Context, ds evals.Dataset) error {
+	now := time.Now().UTC()
+	if ds.CreatedAt.IsZero() {
+		ds.CreatedAt = now
+	}
+	if ds.UpdatedAt.IsZero() {
+		ds.UpdatedAt = ds.CreatedAt
+	}
+	payload, err := json.Marshal(ds)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store encode eval dataset: %w", err)
+	}
+	_, err = s.db.ExecContext(ctx, `
+INSERT INTO eval_datasets (name, payload, created_at, updated_at)
+VALUES (?, ?, ?, ?)`,
+		ds.Name,
+		payload,
+		ds.CreatedAt.UTC().Format(time.RFC3339Nano),
+		ds.UpdatedAt.UTC().Format(time.RFC3339Nano),
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "UNIQUE constraint failed: eval_datasets.name") {
+			return ErrEvalDatasetExists
+		}
+		return fmt.Errorf("workflow sqlite store create eval dataset: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) UpdateEvalDataset(ctx context.Context, ds evals.Dataset) error {
+	if ds.UpdatedAt.IsZero() {
+		ds.UpdatedAt = time.Now().UTC()
+	}
+	payload, err := json.Marshal(ds)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store encode eval dataset: %w", err)
+	}
+	res, err := s.db.ExecContext(ctx, `
+UPDATE eval_datasets
+SET payload = ?, updated_at = ?
+WHERE name = ?`,
+		payload,
+		ds.UpdatedAt.UTC().Format(time.RFC3339Nano),
+		ds.Name,
+	)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store update eval dataset: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store update eval dataset affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrEvalDatasetNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteEvalDataset(ctx context.Context, name string) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM eval_datasets WHERE name = ?`, name)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store delete eval dataset: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store delete eval dataset affected rows: %w", err)
+	}
+	if affected == 0 {
+		return ErrEvalDatasetNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) RecentOutputs(ctx context.Context, key string, limit int) ([]nodes.OutputSample, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.QueryContext(ctx, `
+SELECT sample FROM (
+	SELECT seq, sample FROM llm_output_history
+	WHERE history_key = ?
+	ORDER BY seq DESC
+	LIMIT ?
+) ORDER BY seq ASC`, key, limit)
+	if err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list output history: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []nodes.OutputSample
+	for rows.Next() {
+		var payload []byte
+		if err := rows.Scan(&payload); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store scan output sample: %w", err)
+		}
+		var sample nodes.OutputSample
+		if err := json.Unmarshal(payload, &sample); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store decode output sample: %w", err)
+		}
+		samples = append(samples, sample)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list output history rows: %w", err)
+	}
+	return samples, nil
+}
+
+func (s *SQLiteStore) AppendOutput(ctx context.Context, key string, sample nodes.OutputSample, keep int) error {
+	payload, err := json.Marshal(sample)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store encode output sample: %w", err)
+	}
+	at := sample.At
+	if at.IsZero() {
+		at = time.Now()
+	}
+	if _, err := s.db.ExecContext(ctx, `INSERT INTO llm_output_history (history_key, sample, created_at) VALUES (?, ?, ?)`,
+		key, payload, at.UTC().Format(time.RFC3339Nano)); err != nil {
+		return fmt.Errorf("workflow sqlite store append output sample: %w", err)
+	}
+	if keep > 0 {
+		if _, err := s.db.ExecContext(ctx, `
+DELETE FROM llm_output_history
+WHERE history_key = ?
+  AND seq NOT IN (
+	SELECT seq FROM llm_output_history WHERE history_key = ? ORDER BY seq DESC LIMIT ?
+  )`, key, key, keep); err != nil {
+			return fmt.Errorf("workflow sqlite store prune output history: %w", err)
+		}
+	}
+	return nil
+}
+
+// ArmStats implements nodes.ArmStatsStore.
+func (s *SQLiteStore) ArmStats(ctx context.Context, key string) ([]nodes.ArmStats, error) {
+	rows, err := s.db.QueryContext(ctx, `
+SELECT arm, pulls, rewards, reward_sum, updated_at FROM model_arm_stats
+WHERE stats_key = ?
+ORDER BY arm`, key)
+	if err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list arm stats: %w", err)
+	}
+	defer rows.Close()
+
+	stats := []nodes.ArmStats{}
+	for rows.Next() {
+		var st nodes.ArmStats
+		var updatedAt string
+		if err := rows.Scan(&st.Arm, &st.Pulls, &st.Rewards, &st.RewardSum, &updatedAt); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store scan arm stats: %w", err)
+		}
+		st.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+		stats = append(stats, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("workflow sqlite store list arm stats rows: %w", err)
+	}
+	return stats, nil
+}
+
+// RecordSelection implements nodes.ArmStatsStore.
+func (s *SQLiteStore) RecordSelection(ctx context.Context, sel nodes.ModelSelection) error {
+	payload, err := json.Marshal(sel)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store encode model selection: %w", err)
+	}
+	at := sel.SelectedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	stamp := at.UTC().Format(time.RFC3339Nano)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store record model selection: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO model_selections (selection_id, stats_key, payload, created_at) VALUES (?, ?, ?, ?)`,
+		sel.ID, sel.Key, payload, stamp); err != nil {
+		return fmt.Errorf("workflow sqlite store record model selection: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO model_arm_stats (stats_key, arm, pulls, updated_at) VALUES (?, ?, 1, ?)
+ON CONFLICT (stats_key, arm) DO UPDATE SET pulls = pulls + 1, updated_at = excluded.updated_at`,
+		sel.Key, sel.Arm, stamp); err != nil {
+		return fmt.Errorf("workflow sqlite store count arm pull: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("workflow sqlite store record model selection: %w", err)
+	}
+	return nil
+}
+
+// RecordReward implements nodes.ArmStatsStore.
+func (s *SQLiteStore) RecordReward(ctx context.Context, selectionID string, reward float64) (nodes.ModelSelection, error) {
+	if err := nodes.ValidateReward(reward); err != nil {
+		return nodes.ModelSelection{}, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nodes.ModelSelection{}, fmt.Errorf("workflow sqlite store record reward: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var payload []byte
+	err = tx.QueryRowContext(ctx, `SELECT payload FROM model_selections WHERE selection_id = ?`, selectionID).Scan(&payload)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nodes.ModelSelection{}, nodes.ErrSelectionNotFound
+	}
+	if err != nil {
+		return nodes.ModelSelection{}, fmt.Errorf("workflow sqlite store load model selection: %w", err)
+	}
+	var sel nodes.ModelSelection
+	if err := json.Unmarshal(payload, &sel); err != nil {
+		return nodes.ModelSelection{}, fmt.Errorf("workflow sqlite store decode model selection: %w", err)
+	}
+	if sel.Reward != nil {
+		return sel, nodes.ErrSelectionRewarded
+	}
+
+	now := time.Now().UTC()
+	sel.Reward = &reward
+	sel.RewardedAt = &now
+	if payload, err = json.Marshal(sel); err != nil {
+		return nodes.ModelSelection{}, fmt.Errorf("workflow sqlite store encode model selection: %w", err)
+	}
+	stamp := now.Format(time.RFC3339Nano)
+	if _, err := tx.ExecContext(ctx, `UPDATE model_selections SET payload = ? WHERE selection_id = ?`, payload, selectionID); err != nil {
+		return nodes.ModelSelection{}, fmt.Errorf("workflow sqlite store record reward: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+INSERT INTO model_arm_stats (stats_key, arm, rewards, reward_sum, updated_at) VALUES (?, ?, 1, ?, ?)
+ON CONFLICT (stats_key, arm) DO UPDATE SET
+	rewards = rewards + 1,
+	reward_sum = reward_sum + excluded.reward_sum,
+	updated_at = excluded.updated_at`,
+		sel.Key, sel.Arm, reward, stamp); err != nil {
+		return nodes.ModelSelection{}, fmt.Errorf("workflow sqlite store credit arm reward: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nodes.ModelSelection{}, fmt.Errorf("workflow sqlite store record reward: %w", err)
+	}
+	return sel, nil
+}
+
+// stateExpiry returns the expires_at column of a state value stored at now
+// with ttl: unix milliseconds, or nil when it never expires.
+func stateExpiry(now time.Time, ttl time.Duration) any {
+	if ttl <= 0 {
+		return nil
+	}
+	return now.Add(ttl).UnixMilli()
+}
+
+// GetState implements nodes.StateStore.
+func (s *SQLiteStore) GetState(ctx context.Context, namespace, key string) (any, bool, error) {
+	var raw string
+	err := s.db.QueryRowContext(ctx, `
+SELECT value FROM workflow_state
+WHERE namespace = ? AND state_key = ? AND (expires_at IS NULL OR expires_at > ?)`,
+		namespace, key, time.Now().UnixMilli()).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("workflow sqlite store get state: %w", err)
+	}
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return nil, false, fmt.Errorf("workflow sqlite store decode state: %w", err)
+	}
+	return value, true, nil
+}
+
+// SetState implements nodes.StateStore. Expired values are pruned first.
+func (s *SQLiteStore) SetState(ctx context.Context, namespace, key string, value any, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("workflow sqlite store encode state: %w", err)
+	}
+	now := time.Now()
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM workflow_state WHERE expires_at <= ?`, now.UnixMilli()); err != nil {
+		return fmt.Errorf("workflow sqlite store prune state: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, `
+INSERT INTO workflow_state (namespace, state_key, value, expires_at, updated_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (namespace, state_key) DO UPDATE SET
+	value = excluded.value,
+	expires_at = excluded.expires_at,
+	updated_at = excluded.updated_at`,
+		namespace, key, string(data), stateExpiry(now, ttl), now.UTC().Format(time.RFC3339Nano)); err != nil {
+		return fmt.Errorf("workflow sqlite store set state: %w", err)
+	}
+	return nil
+}
+
+// IncrState implements nodes.StateStore with a single upsert, so
+// concurrent runs never lose an increment. An expired value restarts from
+// delta; a value that is not an integer is left alone.
+func (s *SQLiteStore) IncrState(ctx context.Context, namespace, key string, delta int64, ttl time.Duration) (int64, error) {
+	now := time.Now()
+	var raw string
+	err := s.db.QueryRowContext(ctx, `
+INSERT INTO workflow_state (namespace, state_key, value, expires_at, updated_at) VALUES (?1, ?2, ?3, ?4, ?5)
+ON CONFLICT (namespace, state_key) DO UPDATE SET
+	value = CASE WHEN workflow_state.expires_at <= ?6 THEN excluded.value
+		ELSE CAST(CAST(workflow_state.value AS INTEGER) + ?3 AS TEXT) END,
+	expires_at = CASE WHEN workflow_state.expires_at <= ?6 THEN excluded.expires_at
+		ELSE workflow_state.expires_at END,
+	updated_at = excluded.updated_at
+WHERE workflow_state.expires_at <= ?6 OR json_type(workflow_state.value) = 'integer'
+RETURNING value`,
+		namespace, key, delta, stateExpiry(now, ttl), now.UTC().Format(time.RFC3339Nano), now.UnixMilli()).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nodes.ErrStateNotCounter
+	}
+	if err != nil {
+		return 0, fmt.Errorf("workflow sqlite store increment state: %w", err)
+	}
+	count, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("workflow sqlite store decode counter: %w", err)
+	}
+	return count, nil
+}
+
+// ClaimWebhookDelivery implements WebhookDedupeStore. Expired deliveries
+// are pruned first.
+func (s *SQLiteStore) ClaimWebhookDelivery(ctx context.Context, d WebhookDelivery) (WebhookDelivery, bool, error) {
+	receivedAt := d.ReceivedAt.UTC().Format(time.RFC3339Nano)
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM webhook_deliveries WHERE expires_at <= ?`, receivedAt); err != nil {
+		return WebhookDelivery{}, false, fmt.Errorf("workflow sqlite store prune webhook deliveries: %w", err)
+	}
+	res, err := s.db.ExecContext(ctx, `
+INSERT OR IGNORE INTO webhook_deliveries (workflow_id, trigger_id, event_id, run_id, received_at, expires_at)
+VALUES (?, ?, ?, ?, ?, ?)`,
+		d.WorkflowID, d.TriggerID, d.EventID, d.RunID, receivedAt, d.ExpiresAt.UTC().Format(time.RFC3339Nano))
+	if err != nil {
+		return WebhookDelivery{}, false, fmt.Errorf("workflow sqlite store claim webhook delivery: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return WebhookDelivery{}, false, fmt.Errorf("workflow sqlite store claim webhook delivery: %w", err)
+	} else if n == 1 {
+		return d, true, nil
+	}
+
+	existing := WebhookDelivery{WorkflowID: d.WorkflowID, TriggerID: d.TriggerID, EventID: d.EventID}
+	var received, expires string
+	err = s.db.QueryRowContext(ctx, `
+SELECT run_id, received_at, expires_at FROM webhook_deliveries
+WHERE workflow_id = ? AND trigger_id = ? AND event_id = ?`, d.WorkflowID, d.TriggerID, d.EventID).
+		Scan(&existing.RunID, &received, &expires)
+	if err != nil {
+		return WebhookDelivery{}, false, fmt.Errorf("workflow sqlite store load webhook delivery: %w", err)
+	}
+	existing.ReceivedAt, _ = time.Parse(time.RFC3339Nano, received)
+	existing.ExpiresAt, _ = time.Parse(time.RFC3339Nano, expires)
+	return existing, false, nil
+}
+
+// ReleaseWebhookDelivery implements WebhookDedupeStore.
+func (s *SQLiteStore) ReleaseWebhookDelivery(ctx context.Context, workflowID, triggerID, eventID, runID string) error {
+	if _, err := s.db.ExecContext(ctx, `
+DELETE FROM webhook_deliveries
+WHERE workflow_id = ? AND trigger_id = ? AND event_id = ? AND run_id = ?`, workflowID, triggerID, eventID, runID); err != nil {
+		return fmt.Errorf("workflow sqlite store release webhook delivery: %w", err)
+	}
+	return nil
+}
+
+const outboxDeliveryColumns = `id, run_id, node_id, method, url, headers, body, status, attempts,
+	status_code, last_error, next_attempt_at, created_at, updated_at, delivered_at`
+
+// EnqueueDelivery implements nodes.Outbox.
+func (s *SQLiteStore) EnqueueDelivery(ctx context.Context, d nodes.OutboxDelivery) (nodes.OutboxDelivery, error) {
+	headers, err := json.Marshal(d.Headers)
+	if err != nil {
+		return nodes.OutboxDelivery{}, fmt.Errorf("workflow sqlite store encode delivery headers: %w", err)
+	}
+	now := time.Now().UTC()
+	if _, err := s.db.ExecContext(ctx, `
+INSERT OR IGNORE INTO outbox_deliveries (id, run_id, node_id, method, url, headers, body, status, next_attempt_at, created_at, updated_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.ID, d.RunID, d.NodeID, d.Method, d.URL, string(headers), []byte(d.Body), nodes.DeliveryPending,
+		now.UnixMilli(), now.Format(time.RFC3339Nano), now.Format(time.RFC3339Nano)); err != nil {
+		return nodes.OutboxDelivery{}, fmt.Errorf("workflow sqlite store enqueue delivery: %w", err)
+	}
+	stored, _, err := s.GetDelivery(ctx, d.ID)
+	return stored, err
+}
+
+// ClaimDueDeliveries implements OutboxStore with a single update, which
+// SQLite runs atomically.
+func (s *SQLiteStore) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]nodes.OutboxDelivery, error) {
+	return s.queryDeliveries(ctx, `
+UPDATE outbox_deliveries
+SET attempts = attempts + 1, next_attempt_at = ?, updated_at = ?
+WHERE id IN (
+	SELECT id FROM outbox_deliveries
+	WHERE status = ? AND next_attempt_at <= ?
+	ORDER BY next_attempt_at ASC, id ASC
+	LIMIT ?
+)
+RETURNING `+outboxDeliveryColumns,
+		leaseUntil.UnixMilli(), now.UTC().Format(time.RFC3339Nano), nodes.DeliveryPending, now.UnixMilli(), limit)
+}
+
+// FinishDeliveryAttempt implements OutboxStore.
+func (s *SQLiteStore) FinishDeliveryAttempt(ctx context.Context, d nodes.OutboxDelivery) (bool, error) {
+	var deliveredAt any
+	if d.DeliveredAt != nil {
+		deliveredAt = d.DeliveredAt.UTC().Format(time.RFC3339Nano)
+	}
+	res, err := s.db.ExecContext(ctx, `
+UPDATE outbox_deliveries
+SET status = ?, status_code = ?, last_error = ?, next_attempt_at = ?, delivered_at = ?, updated_at = ?
+WHERE id = ? AND attempts = ?`,
+		d.Status, d.StatusCode, d.LastError, d.NextAttemptAt.UnixMilli(), deliveredAt,
+		time.Now().UTC().Format(time.RFC3339Nano), d.ID, d.Attempts)
+	if err != nil {
+		return false, fmt.Errorf("workflow sqlite store finish delivery attempt: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("workflow sqlite store finish delivery attempt affected rows: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// ListDeliveries implements OutboxStore.
+func (s *SQLiteStore) ListDeliveries(ctx context.Context, runID string) ([]nodes.OutboxDelivery, error) {
+	return s.queryDeliveries(ctx, `SELECT `+outboxDeliveryColumns+`
+FROM outbox_deliveries WHERE run_id = ? ORDER BY created_at ASC, id ASC`, runID)
+}
+
+// GetDelivery implements OutboxStore.
+func (s *SQLiteStore) GetDelivery(ctx context.Context, id string) (nodes.OutboxDelivery, bool, error) {
+	deliveries, err := s.queryDeliveries(ctx, `SELECT `+outboxDeliveryColumns+` FROM outbox_deliveries WHERE id = ?`, id)
+	if err != nil || len(deliveries) == 0 {
+		return nodes.OutboxDelivery{}, false, err
+	}
+	return deliveries[0], true, nil
+}
+
+// RetryDelivery implements OutboxStore.
+func (s *SQLiteStore) RetryDelivery(ctx context.Context, id string, now time.Time) (nodes.OutboxDelivery, bool, error) {
+	retried, err := s.queryDeliveries(ctx, `
+UPDATE outbox_deliveries
+SET status = ?, attempts = 0, next_attempt_at = ?, updated_at = ?
+WHERE id = ? AND status = ?
+RETURNING `+outboxDeliveryColumns,
+		nodes.DeliveryPending, now.UnixMilli(), now.UTC().Format(time.RFC3339Nano), id, nodes.DeliveryFailed)
+	if err != nil {
+		return nodes.OutboxDelivery{}, false, err
+	}
+	if len(retried) == 1 {
+		return retried[0], true, nil
+	}
+	d, ok, err := s.GetDelivery(ctx, id)
+	if err != nil {
+		return nodes.OutboxDelivery{}, false, err
+	}
+	if !ok {
+		return nodes.OutboxDelivery{}, false, ErrDeliveryNotFound
+	}
+	return d, false, nil
+}
+
+func (s *SQLiteStore) queryDeliveries(ctx context.Context, query string, args ...any) ([]nodes.OutboxDelivery, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("workflow sqlite store query deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []nodes.OutboxDelivery{}
+	for rows.Next() {
+		var (
+			d                    nodes.OutboxDelivery
+			headers              string
+			body                 []byte
+			nextAttemptAt        int64
+			createdAt, updatedAt string
+			deliveredAt          sql.NullString
+		)
+		if err := rows.Scan(&d.ID, &d.RunID, &d.NodeID, &d.Method, &d.URL, &headers, &body, &d.Status, &d.Attempts,
+			&d.StatusCode, &d.LastError, &nextAttemptAt, &createdAt, &updatedAt, &deliveredAt); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store scan delivery: %w", err)
+		}
+		if err := json.Unmarshal([]byte(headers), &d.Headers); err != nil {
+			return nil, fmt.Errorf("workflow sqlite store decode delivery headers: %w", err)
+		}
+		d.Body = string(body)
+		d.NextAttemptAt = time.UnixMilli(nextAttemptAt).UTC()
+		d.CreatedAt, _ = time.Parse(time.RFC3339Nano, createdAt)
+		d.UpdatedAt, _ = time.Parse(time.RFC3339Nano, updatedAt)
+		if deliveredAt.Valid {
+			t, _ := time.Parse(time.RFC3339Nano, deliveredAt.String)
+			d.DeliveredAt = &t
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("workflow sqlite store query deliveries rows: %w", err)
+	}
+	return deliveries, nil
+}
+
+// Close closes the underlying database connection.
+func (s *SQLiteStore) Close() error {
+	if s == nil || s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
+
+func marshalCompiledGraph(compiled *graph.GraphDefinition) ([]byte, error) {
+	if compiled == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(compiled)
+	if err != nil {
+		return nil, fmt.Errorf("workflow sqlite store marshal compiled graph: %w", err)
+	}
+	return data, nil
+}
+
+type workflowScanner interface {
+	Scan(dest ...any) error
+}
+
+type scheduleScanner interface {
+	Scan(dest ...any) error
+}
+
+// workflowSourceFormat returns the stored format column, NULL for JSON.
+func workflowSourceFormat(rec WorkflowRecord) any {
+	if rec.SourceFormat == "" || rec.SourceFormat == loader.FormatJSON {
+		return nil
+	}
+	return string(rec.SourceFormat)
+}
+
+// workflowSourceText returns the stored verbatim source, NULL for JSON.
+func workflowSourceText(rec WorkflowRecord) any {
+	if workflowSourceFormat(rec) == nil {
+		return nil
+	}
+	return []byte(rec.SourceText)
+}
+
+func scanWorkflowRecord(scanner workflowScanner) (WorkflowRecord, error) {
+	var (
+		id        string
+		kind      string
+		name      sql.NullString
+		sourceRaw []byte
+		compRaw   []byte
+		format    sql.NullString
+		text      []byte
+		createdAt string
+		updatedAt string
+	)
+	if err := scanner.Scan(&id, &kind, &name, &sourceRaw, &compRaw, &format, &text, &createdAt, &updatedAt); err != nil {
+		return WorkflowRecord{}, err
+	}
+
+	created, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return WorkflowRecord{}, fmt.Errorf("workflow sqlite store parse created_at: %w", err)
+	}
+	updated, err := time.Parse(time.RFC3339Nano, updatedAt)
+	if err != nil {
+		return WorkflowRecord{}, fmt.Errorf("workflow sqlite store parse updated_at: %w", err)
+	}
+
+	rec := WorkflowRecord{
+		ID:         id,
+		SchemaKind: loader.SchemaKind(kind),
+		Name:       name.String,
+		Source:     json.RawMessage(append([]byte(nil), sourceRaw...)),
+		CreatedAt:  created,
+		UpdatedAt:  updated,
+	}
+	if format.String != "" && format.String != string(loader.FormatJSON) {
+		rec.SourceFormat = loader.SourceFormat(format.String)
+		rec.SourceText = string(text)
+	}
+
+	if len(compRaw) > 0 {
+		var compiled graph.GraphDefinition
+		if err := json.Unmarshal(compRaw, &compiled); err != nil {
+			return WorkflowRecord{}, fmt.Errorf("workflow sqlite store unmarshal compiled graph: %w", err)
+		}
+		rec.Compiled = &compiled
+	}
+
+	return rec, nil
+}
+
+func scanWorkflowSchedule(scanner scheduleScanner) (WorkflowSchedule, error) {
+	var (
+		id          string
+		workflowID  string
+		cronExpr    string
+		enabledRaw  int
+		inputRaw    []byte
+		optionsRaw  []byte
+		timezone    sql.NullString
+		jitter      sql.NullString
+		missedFire  sql.NullString
+		maxBackfill int
+		nextFireAt  sql.NullString
+		nextRunAt   string
+		lastRunAt   sql.NullString
+		lastRunID   sql.NullString
+		lastStatus  sql.NullString
+		lastError   sql.NullString
+		createdAt   string
+		updatedAt   string
+	)
+	if err := scanner.Scan(
+		&id,
+		&workflowID,
+		&cronExpr,
+		&enabledRaw,
+		&inputRaw,
+		&optionsRaw,
+		&timezone,
+		&jitter,
+		&missedFire,
+		&maxBackfill,
+		&nextFireAt,
+		&nextRunAt,
+		&lastRunAt,
+		&lastRunID,
+		&lastStatus,
+		&lastError,
+		&createdAt,
+		&updatedAt,
+	); err != nil {
+		return WorkflowSchedule{}, err
+	}
+
+	next, err := time.Parse(time.RFC3339Nano, nextRunAt)
+	if err != nil {
+		return WorkflowSchedule{}, fmt.Errorf("workflow sqlite store parse schedule next_run_at: %w", err)
+	}
+	created, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return WorkflowSchedule{}, fmt.Errorf("workflow sqlite store parse schedule created_at: %w", err)
+	}
+	updated, err := time.Parse(time.RFC3339Nano, updatedAt)
+	if err != nil {
+		return WorkflowSchedule{}, fmt.Errorf("workflow sqlite store parse schedule updated_at: %w", err)
+	}
+
+	input, err := unmarshalScheduleInput(inputRaw)
+	if err != nil {
+		return WorkflowSchedule{}, err
+	}
+	options, err := unmarshalScheduleOptions(optionsRaw)
+	if err != nil {
+		return WorkflowSchedule{}, err
+	}
+
+	var nextFire time.Time
+	if nextFireAt.Valid && strings.TrimSpace(nextFireAt.String) != "" {
+		nextFire, err = time.Parse(time.RFC3339Nano, nextFireAt.String)
+		if err != nil {
+			return WorkflowSchedule{}, fmt.Errorf("workflow sqlite store parse schedule next_fire_at: %w", err)
+		}
+	}
+
+	var lastRunPtr *time.Time
+	if lastRunAt.Valid && strings.TrimSpace(lastRunAt.String) != "" {
+		parsed, err := time.Parse(time.RFC3339Nano, lastRunAt.String)
+		if err != nil {
+			return WorkflowSchedule{}, fmt.Errorf("workflow sqlite store parse schedule last_run_at: %w", err)
+		}
+		lastRunPtr = &parsed
+	}
+
+	return WorkflowSchedule{
+		ID:          id,
+		WorkflowID:  workflowID,
+		Cron:        cronExpr,
+		Enabled:     enabledRaw == 1,
+		Input:       input,
+		Options:     options,
+		Timezone:    timezone.String,
+		Jitter:      jitter.String,
+		MissedFire:  missedFire.String,
+		MaxBackfill: maxBackfill,
+		NextFireAt:  nextFire,
+		NextRunAt:   next,
+		LastRunAt:   lastRunPtr,
+		LastRunID:   lastRunID.String,
+		LastStatus:  lastStatus.String,
+		LastError:   lastError.String,
+		CreatedAt:   created,
+		UpdatedAt:   updated,
+	}, nil
+}
+
+func isWorkflowSQLiteUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed: workflows.id")
+}
+
+func isWorkflowScheduleSQLiteUniqueViolation(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed: workflow_schedules.id")
+}
+
+func marshalScheduleInput(input map[string]any) ([]byte, error) {
+	if input == nil {
+		return []byte(`{}`), nil
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("workflow sqlite store marshal schedule input: %w", err)
+	}
+	return data, nil
+}
+
+func unmarshalScheduleInput(raw []byte) (map[string]any, error) {
+	if len(raw) == 0 {
+		return map[string]any{}, nil
 	}
 	var input map[string]any
 	if err := json.Unmarshal(raw, &input); err != nil {
@@ -744,6 +2320,45 @@ func normalizeLegacyWorkflowCompiled(compiled []byte) []byte {
 	return compiled
 }
 
+// migrateUploadsSQLiteSchema adds the scan column to uploads tables
+// created before uploads were scanned.
+func migrateUploadsSQLiteSchema(db *sql.DB) error {
+	columns, err := sqliteTableColumns(db, "uploads")
+	if err != nil {
+		return err
+	}
+	if !columns["scan"] {
+		if _, err := db.Exec(`ALTER TABLE uploads ADD COLUMN scan TEXT`); err != nil {
+			return fmt.Errorf("workflow sqlite store add uploads.scan: %w", err)
+		}
+	}
+	return nil
+}
+
+// migrateSchedulesSQLiteSchema adds the timezone, jitter and missed-fire
+// columns to workflow_schedules tables created before schedules had them.
+func migrateSchedulesSQLiteSchema(db *sql.DB) error {
+	columns, err := sqliteTableColumns(db, "workflow_schedules")
+	if err != nil {
+		return err
+	}
+	for _, col := range []struct{ name, def string }{
+		{"timezone", "TEXT"},
+		{"jitter", "TEXT"},
+		{"missed_fire", "TEXT"},
+		{"max_backfill", "INTEGER NOT NULL DEFAULT 0"},
+		{"next_fire_at", "TEXT"},
+	} {
+		if columns[col.name] {
+			continue
+		}
+		if _, err := db.Exec(`ALTER TABLE workflow_schedules ADD COLUMN ` + col.name + ` ` + col.def); err != nil {
+			return fmt.Errorf("workflow sqlite store add workflow_schedules.%s: %w", col.name, err)
+		}
+	}
+	return nil
+}
+
 func migrateLegacyWorkflowSQLiteSchema(db *sql.DB) error {
 	if db == nil {
 		return errors.New("workflow sqlite store db is nil")
@@ -785,6 +2400,16 @@ func migrateLegacyWorkflowSQLiteSchema(db *sql.DB) error {
 			return fmt.Errorf("workflow sqlite store add workflows.compiled: %w", err)
 		}
 	}
+	if !columns["source_format"] {
+		if _, err := db.Exec(`ALTER TABLE workflows ADD COLUMN source_format TEXT`); err != nil {
+			return fmt.Errorf("workflow sqlite store add workflows.source_format: %w", err)
+		}
+	}
+	if !columns["source_text"] {
+		if _, err := db.Exec(`ALTER TABLE workflows ADD COLUMN source_text BLOB`); err != nil {
+			return fmt.Errorf("workflow sqlite store add workflows.source_text: %w", err)
+		}
+	}
 	if !columns["created_at"] {
 		if _, err := db.Exec(`ALTER TABLE workflows ADD COLUMN created_at TEXT`); err != nil {
 			return fmt.Errorf("workflow sqlite store add workflows.created_at: %w", err)
@@ -857,6 +2482,20 @@ WHERE compiled IS NULL
 	return nil
 }
 
+// withSQLiteBusyTimeout makes every pooled connection wait up to five
+// seconds for a competing writer, instead of failing with SQLITE_BUSY.
+// DSNs that set their own busy_timeout are left alone.
+func withSQLiteBusyTimeout(dsn string) string {
+	if strings.Contains(dsn, "busy_timeout") {
+		return dsn
+	}
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	return dsn + sep + "_pragma=busy_timeout(5000)"
+}
+
 func sqliteTableColumns(db *sql.DB, table string) (map[string]bool, error) {
 	rows, err := db.Query(`PRAGMA table_info(` + table + `)`)
 	if err != nil {
@@ -888,3 +2527,10 @@ func sqliteTableColumns(db *sql.DB, table string) (map[string]bool, error) {
 
 var _ WorkflowStore = (*SQLiteStore)(nil)
 var _ WorkflowScheduleStore = (*SQLiteStore)(nil)
+var _ RolloutStore = (*SQLiteStore)(nil)
+var _ PresetStore = (*SQLiteStore)(nil)
+var _ LifecycleStore = (*SQLiteStore)(nil)
+var _ AuditStore = (*SQLiteStore)(nil)
+var _ nodes.ArmStatsStore = (*SQLiteStore)(nil)
+var _ nodes.StateStore = (*SQLiteStore)(nil)
+var _ OutboxStore = (*SQLiteStore)(nil)
@@ -4,11 +4,17 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
+	"net/http"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/petal-labs/petalflow/graph"
 	"github.com/petal-labs/petalflow/loader"
+	"github.com/petal-labs/petalflow/nodes"
 )
 
 var _ WorkflowStore = (*SQLiteStore)(nil)
@@ -627,3 +633,260 @@ func mustCreateWorkflowForSchedule(t *testing.T, store *SQLiteStore, workflowID
 		t.Fatalf("Create workflow %s: %v", workflowID, err)
 	}
 }
+
+func TestSQLiteStore_OutputHistory(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	ctx := context.Background()
+
+	for i := 1; i <= 5; i++ {
+		if err := store.AppendOutput(ctx, "wf/node", nodes.OutputSample{Length: i, Fields: []string{"title"}}, 3); err != nil {
+			t.Fatalf("AppendOutput: %v", err)
+		}
+	}
+	if err := store.AppendOutput(ctx, "wf/other", nodes.OutputSample{Length: 99}, 3); err != nil {
+		t.Fatalf("AppendOutput: %v", err)
+	}
+
+	samples, err := store.RecentOutputs(ctx, "wf/node", 10)
+	if err != nil {
+		t.Fatalf("RecentOutputs: %v", err)
+	}
+	if len(samples) != 3 {
+		t.Fatalf("samples = %d, want 3 after pruning", len(samples))
+	}
+	for i, want := range []int{3, 4, 5} {
+		if samples[i].Length != want {
+			t.Errorf("samples[%d].Length = %d, want %d", i, samples[i].Length, want)
+		}
+	}
+	if len(samples[0].Fields) != 1 || samples[0].Fields[0] != "title" {
+		t.Errorf("Fields = %v, want [title]", samples[0].Fields)
+	}
+
+	latest, err := store.RecentOutputs(ctx, "wf/node", 1)
+	if err != nil {
+		t.Fatalf("RecentOutputs: %v", err)
+	}
+	if len(latest) != 1 || latest[0].Length != 5 {
+		t.Fatalf("latest = %+v, want length 5", latest)
+	}
+}
+
+func TestSQLiteStore_State(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLiteStore(t)
+
+	if err := store.SetState(ctx, "wf", "profile", map[string]any{"tier": "gold"}, 0); err != nil {
+		t.Fatalf("SetState: %v", err)
+	}
+	value, ok, err := store.GetState(ctx, "wf", "profile")
+	if err != nil || !ok || value.(map[string]any)["tier"] != "gold" {
+		t.Fatalf("GetState = %v, %v, %v", value, ok, err)
+	}
+	if _, ok, _ := store.GetState(ctx, "other", "profile"); ok {
+		t.Fatal("namespaces should not share keys")
+	}
+	if _, err := store.IncrState(ctx, "wf", "profile", 1, 0); !errors.Is(err, nodes.ErrStateNotCounter) {
+		t.Fatalf("IncrState on an object: err = %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.IncrState(ctx, "wf", "sent", 1, time.Hour); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if count, err := store.IncrState(ctx, "wf", "sent", -5, time.Hour); err != nil || count != 15 {
+		t.Fatalf("IncrState = %d, %v, want 15", count, err)
+	}
+
+	if err := store.SetState(ctx, "wf", "window", float64(7), time.Millisecond); err != nil {
+		t.Fatalf("SetState: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, ok, _ := store.GetState(ctx, "wf", "window"); ok {
+		t.Fatal("expired value should not be found")
+	}
+	if count, err := store.IncrState(ctx, "wf", "window", 1, 0); err != nil || count != 1 {
+		t.Fatalf("IncrState after expiry = %d, %v, want 1", count, err)
+	}
+}
+
+func TestSQLiteStore_Presets(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteWorkflowStore(t)
+	mustCreateWorkflowForSchedule(t, store, "wf-presets")
+
+	for _, name := range []string{"regression case 17", "demo customer"} {
+		preset := WorkflowPreset{WorkflowID: "wf-presets", Name: name, InputPreset: graph.InputPreset{Input: map[string]any{"name": name}}}
+		if err := store.SavePreset(ctx, preset); err != nil {
+			t.Fatalf("SavePreset(%s): %v", name, err)
+		}
+	}
+	presets, err := store.ListPresets(ctx, "wf-presets")
+	if err != nil {
+		t.Fatalf("ListPresets: %v", err)
+	}
+	if len(presets) != 2 || presets[0].Name != "demo customer" || presets[1].Input["name"] != "regression case 17" {
+		t.Fatalf("presets = %+v, want both ordered by name", presets)
+	}
+
+	if err := store.DeletePreset(ctx, "wf-presets", "demo customer"); err != nil {
+		t.Fatalf("DeletePreset: %v", err)
+	}
+	if err := store.DeletePreset(ctx, "wf-presets", "demo customer"); !errors.Is(err, ErrPresetNotFound) {
+		t.Fatalf("DeletePreset twice = %v, want ErrPresetNotFound", err)
+	}
+	if err := store.SavePreset(ctx, WorkflowPreset{WorkflowID: "ghost", Name: "x"}); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Fatalf("SavePreset for unknown workflow = %v, want ErrWorkflowNotFound", err)
+	}
+
+	if err := store.Delete(ctx, "wf-presets"); err != nil {
+		t.Fatalf("Delete workflow: %v", err)
+	}
+	if _, found, err := store.GetPreset(ctx, "wf-presets", "regression case 17"); err != nil || found {
+		t.Fatalf("GetPreset after workflow delete = %v, %v, want not found", found, err)
+	}
+}
+
+func TestSQLiteStore_Lifecycles(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteWorkflowStore(t)
+	mustCreateWorkflowForSchedule(t, store, "wf-lifecycle")
+
+	lifecycle := WorkflowLifecycle{
+		WorkflowID:      "wf-lifecycle",
+		LifecycleStatus: LifecycleStatus{State: LifecycleDeprecated, Reason: "replaced"},
+		History:         []LifecycleTransition{{From: LifecycleActive, To: LifecycleDeprecated, Actor: "ops", At: time.Now().UTC()}},
+	}
+	if err := store.SaveLifecycle(ctx, lifecycle); err != nil {
+		t.Fatalf("SaveLifecycle: %v", err)
+	}
+	got, found, err := store.GetLifecycle(ctx, "wf-lifecycle")
+	if err != nil || !found || got.State != LifecycleDeprecated || len(got.History) != 1 || got.History[0].Actor != "ops" || got.UpdatedAt.IsZero() {
+		t.Fatalf("GetLifecycle = %+v, %v, %v", got, found, err)
+	}
+	if all, err := store.ListLifecycles(ctx); err != nil || len(all) != 1 {
+		t.Fatalf("ListLifecycles = %+v, %v", all, err)
+	}
+	if err := store.SaveLifecycle(ctx, WorkflowLifecycle{WorkflowID: "ghost"}); !errors.Is(err, ErrWorkflowNotFound) {
+		t.Fatalf("SaveLifecycle for unknown workflow = %v, want ErrWorkflowNotFound", err)
+	}
+
+	if err := store.Delete(ctx, "wf-lifecycle"); err != nil {
+		t.Fatalf("Delete workflow: %v", err)
+	}
+	if _, found, err := store.GetLifecycle(ctx, "wf-lifecycle"); err != nil || found {
+		t.Fatalf("GetLifecycle after workflow delete = %v, %v, want not found", found, err)
+	}
+}
+
+func TestSQLiteStore_AuditIsAppendOnly(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteWorkflowStore(t)
+	mustCreateWorkflowForSchedule(t, store, "wf-audit")
+
+	for _, method := range []string{http.MethodPut, http.MethodDelete} {
+		entry := AuditEntry{Actor: "ops", Action: ActionEdit, Method: method, Path: "/api/workflows/wf-audit", Resource: Resource{Kind: "workflows", ID: "wf-audit", Workflow: "wf-audit"}, Status: http.StatusOK}
+		if _, err := store.AppendAudit(ctx, entry); err != nil {
+			t.Fatalf("AppendAudit: %v", err)
+		}
+	}
+	// Entries outlive the workflows they concern and cannot be changed.
+	if err := store.Delete(ctx, "wf-audit"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.db.ExecContext(ctx, `UPDATE audit_log SET actor = 'someone'`); err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Errorf("update error = %v, want append-only", err)
+	}
+	if _, err := store.db.ExecContext(ctx, `DELETE FROM audit_log`); err == nil || !strings.Contains(err.Error(), "append-only") {
+		t.Errorf("delete error = %v, want append-only", err)
+	}
+
+	entries, err := store.ListAudit(ctx, AuditQuery{Workflow: "wf-audit", Method: http.MethodDelete})
+	if err != nil {
+		t.Fatalf("ListAudit: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Actor != "ops" || entries[0].ID != 2 {
+		t.Fatalf("entries = %+v", entries)
+	}
+}
+
+func TestSQLiteStore_Outbox(t *testing.T) {
+	ctx := context.Background()
+	store := newTestSQLiteStore(t)
+
+	d := nodes.OutboxDelivery{ID: "dlv_1", RunID: "run-1", NodeID: "notify", Method: "POST", URL: "https://example.com/hook",
+		Headers: map[string]string{"X-Token": "sealed"}, Body: `{"a":1}`}
+	stored, err := store.EnqueueDelivery(ctx, d)
+	if err != nil {
+		t.Fatalf("EnqueueDelivery: %v", err)
+	}
+	if stored.Status != nodes.DeliveryPending || stored.Headers["X-Token"] != "sealed" || stored.Body != `{"a":1}` || stored.CreatedAt.IsZero() {
+		t.Fatalf("stored delivery = %+v", stored)
+	}
+	d.Body = `{"a":2}`
+	if again, err := store.EnqueueDelivery(ctx, d); err != nil || again.Body != `{"a":1}` {
+		t.Fatalf("re-enqueue = %+v, %v; want the stored delivery", again, err)
+	}
+
+	now := time.Now().UTC()
+	claimed, err := store.ClaimDueDeliveries(ctx, now, now.Add(time.Minute), 10)
+	if err != nil || len(claimed) != 1 || claimed[0].Attempts != 1 {
+		t.Fatalf("ClaimDueDeliveries = %+v, %v", claimed, err)
+	}
+	if again, _ := store.ClaimDueDeliveries(ctx, now, now.Add(time.Minute), 10); len(again) != 0 {
+		t.Fatalf("claimed a leased delivery again: %+v", again)
+	}
+	// The lease lapses, as after a crash, and the delivery is claimed again.
+	reclaimed, err := store.ClaimDueDeliveries(ctx, now.Add(2*time.Minute), now.Add(3*time.Minute), 10)
+	if err != nil || len(reclaimed) != 1 || reclaimed[0].Attempts != 2 {
+		t.Fatalf("reclaim = %+v, %v", reclaimed, err)
+	}
+
+	stale := claimed[0]
+	stale.Status = nodes.DeliveryDelivered
+	if current, err := store.FinishDeliveryAttempt(ctx, stale); err != nil || current {
+		t.Fatalf("FinishDeliveryAttempt(stale) = %v, %v; want false", current, err)
+	}
+	failed := reclaimed[0]
+	failed.Status, failed.StatusCode, failed.LastError = nodes.DeliveryFailed, 400, "unexpected status code: 400"
+	if current, err := store.FinishDeliveryAttempt(ctx, failed); err != nil || !current {
+		t.Fatalf("FinishDeliveryAttempt = %v, %v; want true", current, err)
+	}
+
+	got, ok, err := store.GetDelivery(ctx, "dlv_1")
+	if err != nil || !ok || got.Status != nodes.DeliveryFailed || got.StatusCode != 400 || got.Attempts != 2 {
+		t.Fatalf("GetDelivery = %+v, %v, %v", got, ok, err)
+	}
+	if _, ok, _ := store.GetDelivery(ctx, "dlv_missing"); ok {
+		t.Fatal("GetDelivery found a missing delivery")
+	}
+
+	retried, ok, err := store.RetryDelivery(ctx, "dlv_1", now)
+	if err != nil || !ok || retried.Status != nodes.DeliveryPending || retried.Attempts != 0 {
+		t.Fatalf("RetryDelivery = %+v, %v, %v", retried, ok, err)
+	}
+	if _, ok, err := store.RetryDelivery(ctx, "dlv_1", now); err != nil || ok {
+		t.Fatalf("RetryDelivery(pending) = %v, %v; want false", ok, err)
+	}
+	if _, _, err := store.RetryDelivery(ctx, "dlv_missing", now); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Fatalf("RetryDelivery(missing) error = %v", err)
+	}
+
+	if _, err := store.EnqueueDelivery(ctx, nodes.OutboxDelivery{ID: "dlv_2", RunID: "run-1", NodeID: "alert", Method: "POST", URL: "https://example.com/alert"}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := store.ListDeliveries(ctx, "run-1")
+	if err != nil || len(list) != 2 || list[0].ID != "dlv_1" || list[1].ID != "dlv_2" {
+		t.Fatalf("ListDeliveries = %+v, %v", list, err)
+	}
+	if list, _ := store.ListDeliveries(ctx, "run-2"); len(list) != 0 {
+		t.Fatalf("ListDeliveries(run-2) = %+v", list)
+	}
+}
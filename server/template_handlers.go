@@ -0,0 +1,167 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/petal-labs/petalflow/loader"
+	"github.com/petal-labs/petalflow/templates"
+)
+
+// InstantiateTemplateRequest is the body of
+// POST /api/templates/{id}/instantiate.
+type InstantiateTemplateRequest struct {
+	// WorkflowID names the new workflow. Defaults to the template
+	// definition's id.
+	WorkflowID string            `json:"workflow_id,omitempty"`
+	Params     map[string]string `json:"params,omitempty"`
+}
+
+// lookupTemplate finds a built-in or registered template.
+func (s *Server) lookupTemplate(ctx context.Context, id string) (templates.Template, bool, error) {
+	if t, ok := templates.Builtin(id); ok {
+		return t, true, nil
+	}
+	if s.templates == nil {
+		return templates.Template{}, false, nil
+	}
+	return s.templates.GetTemplate(ctx, id)
+}
+
+// handleListTemplates returns the built-in templates and the registered
+// ones, sorted by ID.
+func (s *Server) handleListTemplates(w http.ResponseWriter, r *http.Request) {
+	list := templates.Builtins()
+	if s.templates != nil {
+		stored, err := s.templates.ListTemplates(r.Context())
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+			return
+		}
+		list = append(list, stored...)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	t, found, err := s.lookupTemplate(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("template %q not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, t)
+}
+
+// handleCreateTemplate registers a template. IDs of built-in templates
+// are taken.
+func (s *Server) handleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	if s.templates == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "template registration is not configured")
+		return
+	}
+	var t templates.Template
+	if err := decodeJSONBody(r, &t); err != nil {
+		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
+		return
+	}
+	if err := t.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_TEMPLATE", err.Error())
+		return
+	}
+	if _, ok := templates.Builtin(t.ID); ok {
+		writeError(w, http.StatusConflict, "CONFLICT", fmt.Sprintf("template %q is built in", t.ID))
+		return
+	}
+
+	now := time.Now().UTC()
+	t.Builtin = false
+	t.CreatedAt, t.UpdatedAt = now, now
+	if err := s.templates.CreateTemplate(r.Context(), t); err != nil {
+		if errors.Is(err, ErrTemplateExists) {
+			writeError(w, http.StatusConflict, "CONFLICT", fmt.Sprintf("template %q already exists", t.ID))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, t)
+}
+
+func (s *Server) handleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, ok := templates.Builtin(id); ok {
+		writeError(w, http.StatusConflict, "CONFLICT", fmt.Sprintf("template %q is built in and cannot be deleted", id))
+		return
+	}
+	if s.templates == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "template registration is not configured")
+		return
+	}
+	if err := s.templates.DeleteTemplate(r.Context(), id); err != nil {
+		if errors.Is(err, ErrTemplateNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("template %q not found", id))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleInstantiateTemplate fills in a template's parameters and stores the
+// result as a new workflow, as if it had been posted to the agent or graph
+// create route.
+func (s *Server) handleInstantiateTemplate(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req InstantiateTemplateRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
+		return
+	}
+
+	t, found, err := s.lookupTemplate(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("template %q not found", id))
+		return
+	}
+	kind, err := t.Kind()
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "INVALID_TEMPLATE", err.Error())
+		return
+	}
+	source, err := t.Instantiate(req.Params, req.WorkflowID)
+	if err != nil {
+		if errors.Is(err, templates.ErrInvalidParams) {
+			writeError(w, http.StatusBadRequest, "INVALID_PARAMS", err.Error())
+			return
+		}
+		writeError(w, http.StatusUnprocessableEntity, "INVALID_TEMPLATE", err.Error())
+		return
+	}
+
+	rec, err := newWorkflowRecord(kind, source)
+	if err != nil {
+		writeRunAPIError(w, err)
+		return
+	}
+	setWorkflowSource(&rec, source, source, loader.FormatJSON)
+	if err := s.createWorkflow(r.Context(), &rec); err != nil {
+		writeRunAPIError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, redactWorkflowRecord(rec))
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/templates"
+)
+
+func TestTemplateGallery(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	handler := NewServer(ServerConfig{
+		Store:         store,
+		TemplateStore: store,
+		Providers:     hydrate.ProviderMap{},
+		Bus:           bus.NewMemBus(bus.MemBusConfig{}),
+	}).Handler()
+
+	w := doConditionRequest(t, handler, http.MethodGet, "/api/templates", nil)
+	var list []templates.Template
+	_ = json.Unmarshal(w.Body.Bytes(), &list)
+	if w.Code != http.StatusOK || len(list) != len(templates.Builtins()) {
+		t.Fatalf("list: %d, %d templates", w.Code, len(list))
+	}
+
+	// Instantiating a built-in stores a concrete workflow.
+	w = doConditionRequest(t, handler, http.MethodPost, "/api/templates/rag-qa/instantiate", map[string]any{
+		"workflow_id": "support-qa",
+		"params":      map[string]string{"provider": "anthropic", "retriever_url": "https://search.internal/query"},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("instantiate: %d %s", w.Code, w.Body.String())
+	}
+	rec, found, err := store.Get(t.Context(), "support-qa")
+	if err != nil || !found {
+		t.Fatalf("stored workflow: found=%v err=%v", found, err)
+	}
+	for _, nd := range rec.Compiled.Nodes {
+		switch nd.ID {
+		case "retrieve":
+			if nd.Config["url"] != "https://search.internal/query" {
+				t.Errorf("retrieve url = %v", nd.Config["url"])
+			}
+		case "answer":
+			if nd.Config["provider"] != "anthropic" || nd.Config["model"] != "gpt-4o-mini" {
+				t.Errorf("answer config = %v", nd.Config)
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		path string
+		body map[string]any
+		want int
+	}{
+		{"missing required param", "/api/templates/rag-qa/instantiate", map[string]any{"workflow_id": "x"}, http.StatusBadRequest},
+		{"unknown param", "/api/templates/chat-assistant/instantiate", map[string]any{"params": map[string]string{"colour": "blue"}}, http.StatusBadRequest},
+		{"existing workflow", "/api/templates/rag-qa/instantiate", map[string]any{"workflow_id": "support-qa", "params": map[string]string{"retriever_url": "https://x"}}, http.StatusConflict},
+		{"unknown template", "/api/templates/nope/instantiate", map[string]any{}, http.StatusNotFound},
+	} {
+		if w := doConditionRequest(t, handler, http.MethodPost, tt.path, tt.body); w.Code != tt.want {
+			t.Errorf("%s: got %d, want %d (%s)", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+
+	// Registered templates join the gallery.
+	custom := map[string]any{
+		"id":     "echo",
+		"params": []map[string]any{{"name": "greeting", "default": "hi"}},
+		"definition": map[string]any{
+			"id":      "echo",
+			"version": "1.0",
+			"nodes":   []map[string]any{{"id": "say", "type": "transform", "config": map[string]any{"template": "${{ params.greeting }} {{.name}}", "output_key": "out"}}},
+			"edges":   []any{},
+			"entry":   "say",
+		},
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/templates", custom); w.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", w.Code, w.Body.String())
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/templates", custom); w.Code != http.StatusConflict {
+		t.Errorf("duplicate register: got %d, want 409", w.Code)
+	}
+	custom["id"] = "rag-qa"
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/templates", custom); w.Code != http.StatusConflict {
+		t.Errorf("register over a built-in: got %d, want 409", w.Code)
+	}
+	if w := doConditionRequest(t, handler, http.MethodGet, "/api/templates/echo", nil); w.Code != http.StatusOK {
+		t.Errorf("get: %d", w.Code)
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/templates/echo/instantiate", map[string]any{"params": map[string]string{"greeting": "hello"}}); w.Code != http.StatusCreated {
+		t.Errorf("instantiate registered: %d %s", w.Code, w.Body.String())
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodDelete, "/api/templates/rag-qa", nil); w.Code != http.StatusConflict {
+		t.Errorf("delete built-in: got %d, want 409", w.Code)
+	}
+	if w := doConditionRequest(t, handler, http.MethodDelete, "/api/templates/echo", nil); w.Code != http.StatusNoContent {
+		t.Errorf("delete: got %d, want 204", w.Code)
+	}
+	if w := doConditionRequest(t, handler, http.MethodGet, "/api/templates/echo", nil); w.Code != http.StatusNotFound {
+		t.Errorf("get deleted: got %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+	"errors"
+
+	"github.com/petal-labs/petalflow/templates"
+)
+
+var (
+	ErrTemplateExists   = errors.New("template already exists")
+	ErrTemplateNotFound = errors.New("template not found")
+)
+
+// TemplateStore persists the workflow templates registered on the daemon.
+// Built-in templates are not stored.
+type TemplateStore interface {
+	ListTemplates(ctx context.Context) ([]templates.Template, error)
+	GetTemplate(ctx context.Context, id string) (templates.Template, bool, error)
+	CreateTemplate(ctx context.Context, t templates.Template) error
+	DeleteTemplate(ctx context.Context, id string) error
+}
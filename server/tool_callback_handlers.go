@@ -0,0 +1,67 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/tool"
+)
+
+// ToolCallbackResponse is the response of POST /api/tool-callbacks/{token}.
+type ToolCallbackResponse struct {
+	Token    string `json:"token"`
+	Accepted bool   `json:"accepted"`
+}
+
+// IsToolCallbackRequest reports whether r posts a result to exactly
+// /api/tool-callbacks/{token}, with a token of the form the daemon issues
+// (see nodes.IsToolCallbackToken). The token is the callback's credential,
+// so bearer-token auth and authorization skip these requests: the external
+// systems that deliver them hold no daemon token.
+func IsToolCallbackRequest(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	token, ok := strings.CutPrefix(r.URL.Path, "/api/tool-callbacks/")
+	return ok && nodes.IsToolCallbackToken(token)
+}
+
+// handleToolCallback delivers the result of an asynchronous tool
+// invocation to the tool node waiting for it. The body takes the shapes of
+// a tool's invoke response; {"error": {...}} fails the node.
+func (s *Server) handleToolCallback(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		if isMaxBytesError(err) {
+			writeError(w, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE", "request body exceeds size limit")
+			return
+		}
+		writeError(w, http.StatusBadRequest, "READ_ERROR", err.Error())
+		return
+	}
+
+	outputs, invokeErr := tool.DecodeCallbackResult(body)
+	var toolErr *tool.ToolError
+	if invokeErr != nil && (!errors.As(invokeErr, &toolErr) || toolErr.Code == tool.ToolErrorCodeDecodeFailure) {
+		writeError(w, http.StatusBadRequest, "PARSE_ERROR", invokeErr.Error())
+		return
+	}
+
+	err = s.toolCallbacks.Deliver(token, outputs, invokeErr)
+	switch {
+	case errors.Is(err, nodes.ErrUnknownToolCallback):
+		writeError(w, http.StatusNotFound, "CALLBACK_NOT_FOUND", err.Error())
+	case errors.Is(err, nodes.ErrDuplicateToolCallback):
+		writeError(w, http.StatusConflict, "DUPLICATE_CALLBACK", err.Error())
+	case errors.Is(err, nodes.ErrExpiredToolCallback):
+		writeError(w, http.StatusGone, "CALLBACK_EXPIRED", err.Error())
+	case err != nil:
+		writeError(w, http.StatusBadRequest, "INVALID_TOKEN", err.Error())
+	default:
+		writeJSON(w, http.StatusAccepted, ToolCallbackResponse{Token: token, Accepted: true})
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/nodes"
+)
+
+func TestToolCallback_DeliversResultToWaitingNode(t *testing.T) {
+	s := NewServer(ServerConfig{Store: newTestSQLiteStore(t)})
+	handler := s.Handler()
+	post := func(token, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/tool-callbacks/"+token, bytes.NewReader([]byte(body)))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := post("job-1", `{"outputs": {"rows": 42}}`); w.Code != http.StatusNotFound {
+		t.Fatalf("tool-chosen token: %d %s", w.Code, w.Body.String())
+	}
+
+	// The tool hands the callback token it was issued to its job, which
+	// posts the result while the tool is still answering.
+	var bodies []string
+	var replies []*httptest.ResponseRecorder
+	tool := core.NewFuncTool("export", "", func(ctx context.Context, _ map[string]any) (map[string]any, error) {
+		token := core.ToolCallbackToken(ctx)
+		for _, body := range bodies {
+			replies = append(replies, post(token, body))
+		}
+		return map[string]any{core.ToolPendingKey: map[string]any{"token": "job-1"}}, nil
+	})
+	node := nodes.NewToolNode("export", tool, nodes.ToolNodeConfig{Callbacks: s.toolCallbacks})
+
+	bodies = []string{`[1, 2]`, `{"outputs": {"rows": 42}}`, `{"outputs": {"rows": 7}}`}
+	env, err := node.Run(context.Background(), core.NewEnvelope())
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if replies[0].Code != http.StatusBadRequest {
+		t.Errorf("invalid body: %d %s", replies[0].Code, replies[0].Body.String())
+	}
+	if replies[1].Code != http.StatusAccepted {
+		t.Errorf("callback: %d %s", replies[1].Code, replies[1].Body.String())
+	}
+	if replies[2].Code != http.StatusConflict || !strings.Contains(replies[2].Body.String(), "DUPLICATE_CALLBACK") {
+		t.Errorf("duplicate callback: %d %s", replies[2].Code, replies[2].Body.String())
+	}
+	if output, _ := env.GetVar("export_output"); output.(map[string]any)["rows"] != float64(42) {
+		t.Errorf("export_output = %v", output)
+	}
+
+	bodies, replies = []string{`{"error": {"code": "QUOTA", "message": "export quota exceeded"}}`}, nil
+	if _, err := node.Run(context.Background(), core.NewEnvelope()); err == nil || !strings.Contains(err.Error(), "export quota exceeded") {
+		t.Errorf("Run error = %v", err)
+	}
+	if replies[0].Code != http.StatusAccepted {
+		t.Errorf("error callback: %d %s", replies[0].Code, replies[0].Body.String())
+	}
+}
@@ -0,0 +1,309 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/scan"
+)
+
+// UploadsPath is the collection route for run input uploads.
+const UploadsPath = "/api/uploads"
+
+// DefaultUploadContentTypes are accepted when ServerConfig.UploadContentTypes
+// is empty.
+var DefaultUploadContentTypes = []string{
+	"text/*",
+	"application/json",
+	"application/pdf",
+	"image/png",
+	"image/jpeg",
+	"image/gif",
+	"image/webp",
+}
+
+// sniffedUploadTypes are media types with a reliable magic number. Uploads
+// declaring one of them must actually start with it.
+var sniffedUploadTypes = map[string]bool{
+	"application/pdf": true,
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+}
+
+// IsUploadRequest reports whether r creates an upload. Such requests carry
+// their own size limit and bypass the general max body middleware.
+func IsUploadRequest(r *http.Request) bool {
+	return r.Method == http.MethodPost && strings.TrimSuffix(r.URL.Path, "/") == UploadsPath
+}
+
+func requestWorkspace(r *http.Request) string {
+	if ws := strings.TrimSpace(r.Header.Get(WorkspaceHeader)); ws != "" {
+		return ws
+	}
+	return DefaultWorkspace
+}
+
+// handleCreateUpload stores the raw request body as an upload. The media
+// type comes from Content-Type and the name from the "name" query parameter
+// or a Content-Disposition filename.
+func (s *Server) handleCreateUpload(w http.ResponseWriter, r *http.Request) {
+	if s.uploadStore == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "uploads are not configured")
+		return
+	}
+	if r.ContentLength > s.maxUpload {
+		writeError(w, http.StatusRequestEntityTooLarge, "UPLOAD_TOO_LARGE", fmt.Sprintf("upload exceeds %d bytes", s.maxUpload))
+		return
+	}
+
+	contentType, err := s.uploadContentType(r.Header.Get("Content-Type"))
+	if err != nil {
+		writeError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", err.Error())
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxUpload))
+	if err != nil {
+		if isMaxBytesError(err) {
+			writeError(w, http.StatusRequestEntityTooLarge, "UPLOAD_TOO_LARGE", fmt.Sprintf("upload exceeds %d bytes", s.maxUpload))
+			return
+		}
+		writeError(w, http.StatusBadRequest, "READ_ERROR", err.Error())
+		return
+	}
+	if len(data) == 0 {
+		writeError(w, http.StatusBadRequest, "EMPTY_UPLOAD", "upload body is empty")
+		return
+	}
+	if err := checkUploadContent(contentType, data); err != nil {
+		writeError(w, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", err.Error())
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	upload := Upload{
+		ID:          uuid.NewString(),
+		Workspace:   requestWorkspace(r),
+		Name:        uploadName(r),
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		SHA256:      hex.EncodeToString(sum[:]),
+		Data:        data,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := s.scanUpload(r.Context(), &upload); err != nil {
+		s.logger.Error("upload scan failed", "error", err, "workspace", upload.Workspace)
+		writeError(w, http.StatusServiceUnavailable, "SCAN_UNAVAILABLE", "upload could not be scanned")
+		return
+	}
+	if err := s.uploadStore.CreateUpload(r.Context(), upload, s.uploadQuota); err != nil {
+		if errors.Is(err, ErrUploadQuotaExceeded) {
+			writeError(w, http.StatusRequestEntityTooLarge, "UPLOAD_QUOTA_EXCEEDED",
+				fmt.Sprintf("workspace %q would exceed its %d byte upload quota", upload.Workspace, s.uploadQuota))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, upload)
+}
+
+func (s *Server) handleGetUpload(w http.ResponseWriter, r *http.Request) {
+	if s.uploadStore == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "uploads are not configured")
+		return
+	}
+	id := r.PathValue("upload_id")
+	upload, ok, err := s.uploadStore.GetUpload(r.Context(), requestWorkspace(r), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("upload %q not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, upload)
+}
+
+func (s *Server) handleDeleteUpload(w http.ResponseWriter, r *http.Request) {
+	if s.uploadStore == nil {
+		writeError(w, http.StatusNotImplemented, "NOT_IMPLEMENTED", "uploads are not configured")
+		return
+	}
+	id := r.PathValue("upload_id")
+	if err := s.uploadStore.DeleteUpload(r.Context(), requestWorkspace(r), id); err != nil {
+		if errors.Is(err, ErrUploadNotFound) {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("upload %q not found", id))
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// scanUpload runs the upload scanner and records its verdict on upload.
+// Without a scanner it does nothing.
+func (s *Server) scanUpload(ctx context.Context, upload *Upload) error {
+	if s.uploadScanner == nil {
+		return nil
+	}
+	result, err := s.uploadScanner.Scan(ctx, scan.Artifact{
+		Name:        upload.Name,
+		ContentType: upload.ContentType,
+		Data:        upload.Data,
+	})
+	if err != nil {
+		return err
+	}
+	upload.Scan = &UploadScan{
+		Status:    UploadClean,
+		Scanner:   result.Scanner,
+		ScannedAt: time.Now().UTC(),
+	}
+	if !result.Clean {
+		upload.Scan.Status = UploadQuarantined
+		upload.Scan.Threat = result.Threat
+		s.logger.Warn("upload quarantined", "upload_id", upload.ID, "workspace", upload.Workspace, "threat", result.Threat, "scanner", result.Scanner)
+	}
+	return nil
+}
+
+// uploadContentType normalizes the Content-Type header and checks it
+// against the configured allow list.
+func (s *Server) uploadContentType(header string) (string, error) {
+	if strings.TrimSpace(header) == "" {
+		return "", errors.New("Content-Type header is required")
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return "", fmt.Errorf("invalid Content-Type: %w", err)
+	}
+	for _, allowed := range s.uploadContentTypes {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == mediaType {
+			return mediaType, nil
+		}
+		if family, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(mediaType, family+"/") {
+			return mediaType, nil
+		}
+	}
+	return "", fmt.Errorf("content type %q is not accepted", mediaType)
+}
+
+// checkUploadContent rejects bodies that do not match their declared type:
+// text must be valid UTF-8, JSON must parse and binary formats with a magic
+// number must start with it.
+func checkUploadContent(contentType string, data []byte) error {
+	switch {
+	case isTextContentType(contentType):
+		if !utf8.Valid(data) {
+			return fmt.Errorf("body is not valid UTF-8 text for %q", contentType)
+		}
+		if contentType == "application/json" || strings.HasSuffix(contentType, "+json") {
+			if !json.Valid(data) {
+				return fmt.Errorf("body is not valid JSON")
+			}
+		}
+	case sniffedUploadTypes[contentType]:
+		if detected, _, _ := mime.ParseMediaType(http.DetectContentType(data)); detected != contentType {
+			return fmt.Errorf("body looks like %q, not %q", detected, contentType)
+		}
+	}
+	return nil
+}
+
+func isTextContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/") ||
+		contentType == "application/json" ||
+		strings.HasSuffix(contentType, "+json")
+}
+
+func uploadName(r *http.Request) string {
+	if name := strings.TrimSpace(r.URL.Query().Get("name")); name != "" {
+		return name
+	}
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil {
+		return params["filename"]
+	}
+	return ""
+}
+
+// resolveRunUploads loads the uploads a run request references and converts
+// them to envelope artifacts. Uploads stored before scanning was enabled
+// are scanned first. Quarantined uploads fail the run or are left out,
+// depending on the quarantine action.
+func (s *Server) resolveRunUploads(ctx context.Context, workspace string, ids []string) ([]core.Artifact, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if s.uploadStore == nil {
+		return nil, &runAPIError{Status: http.StatusNotImplemented, Code: "NOT_IMPLEMENTED", Message: "uploads are not configured"}
+	}
+	if workspace == "" {
+		workspace = DefaultWorkspace
+	}
+
+	artifacts := make([]core.Artifact, 0, len(ids))
+	for _, id := range ids {
+		upload, ok, err := s.uploadStore.GetUpload(ctx, workspace, id)
+		if err != nil {
+			return nil, &runAPIError{Status: http.StatusInternalServerError, Code: "STORE_ERROR", Message: err.Error()}
+		}
+		if !ok {
+			return nil, &runAPIError{Status: http.StatusBadRequest, Code: "UPLOAD_NOT_FOUND", Message: fmt.Sprintf("upload %q not found in workspace %q", id, workspace)}
+		}
+		if upload.Scan == nil {
+			if err := s.scanUpload(ctx, &upload); err != nil {
+				s.logger.Error("upload scan failed", "error", err, "upload_id", id, "workspace", workspace)
+				return nil, &runAPIError{Status: http.StatusServiceUnavailable, Code: "SCAN_UNAVAILABLE", Message: fmt.Sprintf("upload %q could not be scanned", id)}
+			}
+		}
+		if upload.Scan != nil && upload.Scan.Status == UploadQuarantined {
+			if s.quarantineAction == QuarantineStrip {
+				s.logger.Warn("quarantined upload stripped from run", "upload_id", id, "workspace", workspace)
+				continue
+			}
+			return nil, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "UPLOAD_QUARANTINED", Message: fmt.Sprintf("upload %q is quarantined: %s", id, upload.Scan.Threat)}
+		}
+		artifacts = append(artifacts, uploadArtifact(upload))
+	}
+	return artifacts, nil
+}
+
+func uploadArtifact(upload Upload) core.Artifact {
+	art := core.Artifact{
+		ID:       upload.ID,
+		Type:     "file",
+		MimeType: upload.ContentType,
+		URI:      "upload://" + upload.ID,
+		Meta: map[string]any{
+			"upload_id": upload.ID,
+			"name":      upload.Name,
+			"size":      upload.Size,
+			"sha256":    upload.SHA256,
+		},
+	}
+	if isTextContentType(upload.ContentType) {
+		art.Text = string(upload.Data)
+	} else {
+		art.Bytes = upload.Data
+	}
+	return art
+}
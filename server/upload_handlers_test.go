@@ -0,0 +1,294 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/scan"
+)
+
+func uploadTestServer(t *testing.T, maxUpload, quota int64) http.Handler {
+	t.Helper()
+	store := newTestSQLiteStore(t)
+	return NewServer(ServerConfig{
+		Store:     store,
+		Providers: hydrate.ProviderMap{},
+		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+			return nil, nil
+		},
+		UploadStore:      store,
+		MaxBody:          512,
+		MaxUploadBytes:   maxUpload,
+		UploadQuotaBytes: quota,
+	}).Handler()
+}
+
+func postUpload(t *testing.T, handler http.Handler, workspace, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, UploadsPath+"?name=notes.txt", strings.NewReader(body))
+	r.Header.Set("Content-Type", contentType)
+	if workspace != "" {
+		r.Header.Set(WorkspaceHeader, workspace)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestUpload_CreateGetDelete(t *testing.T) {
+	handler := uploadTestServer(t, 1024, 0)
+
+	// Larger than MaxBody, but uploads have their own limit.
+	body := strings.Repeat("meeting notes ", 50)
+	w := postUpload(t, handler, "", "text/plain; charset=utf-8", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got %d; body: %s", w.Code, w.Body.String())
+	}
+	var created Upload
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if created.ID == "" || created.Workspace != DefaultWorkspace || created.Name != "notes.txt" ||
+		created.ContentType != "text/plain" || created.Size != int64(len(body)) || created.SHA256 == "" {
+		t.Fatalf("created = %+v", created)
+	}
+	if strings.Contains(w.Body.String(), "meeting notes") {
+		t.Error("upload data should not be echoed back")
+	}
+
+	r := httptest.NewRequest(http.MethodGet, UploadsPath+"/"+created.ID, nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: got %d; body: %s", w.Code, w.Body.String())
+	}
+
+	// Uploads are invisible to other workspaces.
+	r = httptest.NewRequest(http.MethodGet, UploadsPath+"/"+created.ID, nil)
+	r.Header.Set(WorkspaceHeader, "other")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("get from other workspace: got %d, want 404", w.Code)
+	}
+
+	r = httptest.NewRequest(http.MethodDelete, UploadsPath+"/"+created.ID, nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("delete: got %d; body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpload_Rejections(t *testing.T) {
+	handler := uploadTestServer(t, 32, 0)
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantCode    string
+	}{
+		{"too large", "text/plain", strings.Repeat("x", 33), http.StatusRequestEntityTooLarge, "UPLOAD_TOO_LARGE"},
+		{"missing type", "", "hello", http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"type not allowed", "application/zip", "PK", http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"invalid json", "application/json", "{nope", http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"mislabeled png", "image/png", "definitely text", http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"empty", "text/plain", "", http.StatusBadRequest, "EMPTY_UPLOAD"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postUpload(t, handler, "", tt.contentType, tt.body)
+			if w.Code != tt.wantStatus || !strings.Contains(w.Body.String(), tt.wantCode) {
+				t.Fatalf("got %d %s, want %d %s", w.Code, w.Body.String(), tt.wantStatus, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestUpload_WorkspaceQuota(t *testing.T) {
+	handler := uploadTestServer(t, 1024, 20)
+
+	if w := postUpload(t, handler, "team-a", "text/plain", strings.Repeat("a", 15)); w.Code != http.StatusCreated {
+		t.Fatalf("first upload: got %d; body: %s", w.Code, w.Body.String())
+	}
+	w := postUpload(t, handler, "team-a", "text/plain", strings.Repeat("a", 10))
+	if w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "UPLOAD_QUOTA_EXCEEDED") {
+		t.Fatalf("over quota: got %d %s", w.Code, w.Body.String())
+	}
+	// Quotas are per workspace.
+	if w := postUpload(t, handler, "team-b", "text/plain", strings.Repeat("b", 10)); w.Code != http.StatusCreated {
+		t.Fatalf("other workspace: got %d; body: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRunWorkflow_AttachesUploads(t *testing.T) {
+	handler := uploadTestServer(t, 1024, 0)
+
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(validGraphJSON("upload-run")))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create workflow: got %d; body: %s", w.Code, w.Body.String())
+	}
+
+	w = postUpload(t, handler, "team-a", "application/json", `{"rows":[1,2,3]}`)
+	var upload Upload
+	if err := json.Unmarshal(w.Body.Bytes(), &upload); err != nil {
+		t.Fatalf("unmarshal upload: %v", err)
+	}
+
+	run := func(workspace string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(RunRequest{Uploads: []string{upload.ID}})
+		r := httptest.NewRequest(http.MethodPost, "/api/workflows/upload-run/run", bytes.NewReader(body))
+		r.Header.Set(WorkspaceHeader, workspace)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w = run("team-a")
+	if w.Code != http.StatusOK {
+		t.Fatalf("run: got %d; body: %s", w.Code, w.Body.String())
+	}
+	var resp RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal run: %v", err)
+	}
+	if len(resp.Output.Artifacts) != 1 {
+		t.Fatalf("artifacts = %+v, want the upload", resp.Output.Artifacts)
+	}
+	art := resp.Output.Artifacts[0]
+	if art.ID != upload.ID || art.Type != "file" || art.MimeType != "application/json" || art.Text != `{"rows":[1,2,3]}` {
+		t.Fatalf("artifact = %+v", art)
+	}
+
+	if w := run("team-b"); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "UPLOAD_NOT_FOUND") {
+		t.Fatalf("cross-workspace run: got %d %s", w.Code, w.Body.String())
+	}
+}
+
+type eicarScanner struct{ err error }
+
+func (s eicarScanner) Scan(_ context.Context, a scan.Artifact) (scan.Result, error) {
+	if s.err != nil {
+		return scan.Result{}, s.err
+	}
+	if strings.Contains(string(a.Data), "EICAR") {
+		return scan.Result{Threat: "Eicar-Test-Signature", Scanner: "test"}, nil
+	}
+	return scan.Result{Clean: true, Scanner: "test"}, nil
+}
+
+func scanTestServer(t *testing.T, scanner scan.Scanner, action string) http.Handler {
+	t.Helper()
+	store := newTestSQLiteStore(t)
+	handler := NewServer(ServerConfig{
+		Store:     store,
+		Providers: hydrate.ProviderMap{},
+		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+			return nil, nil
+		},
+		UploadStore:      store,
+		UploadScanner:    scanner,
+		QuarantineAction: action,
+	}).Handler()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(validGraphJSON("scan-run")))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create workflow: got %d; body: %s", w.Code, w.Body.String())
+	}
+	return handler
+}
+
+func runWithUploads(t *testing.T, handler http.Handler, ids ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(RunRequest{Uploads: ids})
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/scan-run/run", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func createdUpload(t *testing.T, w *httptest.ResponseRecorder) Upload {
+	t.Helper()
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload: got %d; body: %s", w.Code, w.Body.String())
+	}
+	var upload Upload
+	if err := json.Unmarshal(w.Body.Bytes(), &upload); err != nil {
+		t.Fatalf("unmarshal upload: %v", err)
+	}
+	return upload
+}
+
+func TestUpload_ScanQuarantinesAndFailsRuns(t *testing.T) {
+	handler := scanTestServer(t, eicarScanner{}, "")
+
+	clean := createdUpload(t, postUpload(t, handler, "", "text/plain", "quarterly numbers"))
+	if clean.Scan == nil || clean.Scan.Status != UploadClean || clean.Scan.Scanner != "test" {
+		t.Fatalf("clean scan = %+v", clean.Scan)
+	}
+	infected := createdUpload(t, postUpload(t, handler, "", "text/plain", "X5O!P%@AP EICAR"))
+	if infected.Scan == nil || infected.Scan.Status != UploadQuarantined || infected.Scan.Threat != "Eicar-Test-Signature" {
+		t.Fatalf("infected scan = %+v", infected.Scan)
+	}
+
+	// The verdict is stored with the upload.
+	r := httptest.NewRequest(http.MethodGet, UploadsPath+"/"+infected.ID, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if !strings.Contains(w.Body.String(), `"status":"quarantined"`) {
+		t.Fatalf("get: %s", w.Body.String())
+	}
+
+	if w := runWithUploads(t, handler, clean.ID); w.Code != http.StatusOK {
+		t.Fatalf("clean run: got %d; body: %s", w.Code, w.Body.String())
+	}
+	w = runWithUploads(t, handler, clean.ID, infected.ID)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "UPLOAD_QUARANTINED") {
+		t.Fatalf("quarantined run: got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpload_ScanStripsQuarantined(t *testing.T) {
+	handler := scanTestServer(t, eicarScanner{}, QuarantineStrip)
+
+	clean := createdUpload(t, postUpload(t, handler, "", "text/plain", "quarterly numbers"))
+	infected := createdUpload(t, postUpload(t, handler, "", "text/plain", "X5O!P%@AP EICAR"))
+
+	w := runWithUploads(t, handler, clean.ID, infected.ID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("run: got %d; body: %s", w.Code, w.Body.String())
+	}
+	var resp RunResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal run: %v", err)
+	}
+	if len(resp.Output.Artifacts) != 1 || resp.Output.Artifacts[0].ID != clean.ID {
+		t.Fatalf("artifacts = %+v, want only the clean upload", resp.Output.Artifacts)
+	}
+}
+
+func TestUpload_ScanUnavailable(t *testing.T) {
+	handler := scanTestServer(t, eicarScanner{err: errors.New("clamd down")}, "")
+
+	w := postUpload(t, handler, "", "text/plain", "quarterly numbers")
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "SCAN_UNAVAILABLE") {
+		t.Fatalf("got %d %s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "clamd down") {
+		t.Error("scanner errors should not leak to clients")
+	}
+}
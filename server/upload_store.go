@@ -0,0 +1,68 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrUploadNotFound      = errors.New("upload not found")
+	ErrUploadQuotaExceeded = errors.New("workspace upload quota exceeded")
+)
+
+// WorkspaceHeader selects the workspace an upload or run belongs to.
+// Requests without it use DefaultWorkspace.
+const (
+	WorkspaceHeader  = "X-Workspace-ID"
+	DefaultWorkspace = "default"
+)
+
+// Upload is a file uploaded ahead of a run and referenced by ID from run
+// requests. Data is never serialized in API responses.
+type Upload struct {
+	ID          string    `json:"id"`
+	Workspace   string    `json:"workspace"`
+	Name        string    `json:"name,omitempty"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	CreatedAt   time.Time `json:"created_at"`
+	// Scan is the verdict of the upload scanner, nil when scanning was
+	// disabled at upload time.
+	Scan *UploadScan `json:"scan,omitempty"`
+	Data []byte      `json:"-"`
+}
+
+// Upload scan statuses.
+const (
+	UploadClean       = "clean"
+	UploadQuarantined = "quarantined"
+)
+
+// Quarantine actions for runs that reference a quarantined upload.
+const (
+	QuarantineFail  = "fail"
+	QuarantineStrip = "strip"
+)
+
+// UploadScan records how an upload was scanned.
+type UploadScan struct {
+	// Status is clean or quarantined.
+	Status string `json:"status"`
+	// Threat is what the scanner found in a quarantined upload.
+	Threat    string    `json:"threat,omitempty"`
+	Scanner   string    `json:"scanner,omitempty"`
+	ScannedAt time.Time `json:"scanned_at"`
+}
+
+// UploadStore persists uploads and enforces per-workspace storage quotas.
+type UploadStore interface {
+	// CreateUpload stores upload unless it would take the workspace's total
+	// stored bytes above quota, in which case it returns
+	// ErrUploadQuotaExceeded. A quota <= 0 disables the check.
+	CreateUpload(ctx context.Context, upload Upload, quota int64) error
+	GetUpload(ctx context.Context, workspace, id string) (Upload, bool, error)
+	DeleteUpload(ctx context.Context, workspace, id string) error
+	UploadUsage(ctx context.Context, workspace string) (int64, error)
+}
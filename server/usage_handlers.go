@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	goruntime "runtime"
+
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// SystemUsageResponse is the body of GET /api/system/usage.
+type SystemUsageResponse struct {
+	// Runs is the approximate memory held by the runs executing now.
+	Runs runtime.MemoryReport `json:"runs"`
+	// Limits are the per-run memory limits, in bytes. 0 means none.
+	Limits RunMemoryLimits `json:"limits"`
+	// Process is what the Go runtime reports for the whole daemon.
+	Process ProcessMemory `json:"process"`
+}
+
+// RunMemoryLimits are the soft and hard per-run memory limits.
+type RunMemoryLimits struct {
+	SoftBytes int64 `json:"soft_bytes"`
+	HardBytes int64 `json:"hard_bytes"`
+}
+
+// ProcessMemory summarizes the daemon's heap.
+type ProcessMemory struct {
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	HeapInuseBytes uint64 `json:"heap_inuse_bytes"`
+	SysBytes       uint64 `json:"sys_bytes"`
+	NumGC          uint32 `json:"num_gc"`
+	Goroutines     int    `json:"goroutines"`
+}
+
+// runMemoryConfig accounts every run with the server's accountant and
+// limits.
+func (s *Server) runMemoryConfig() *runtime.MemoryConfig {
+	return &runtime.MemoryConfig{
+		SoftLimitBytes: s.memorySoft,
+		HardLimitBytes: s.memoryHard,
+		Accountant:     s.memory,
+	}
+}
+
+// handleSystemUsage reports the memory held by active runs next to the
+// daemon's heap statistics.
+func (s *Server) handleSystemUsage(w http.ResponseWriter, _ *http.Request) {
+	var stats goruntime.MemStats
+	goruntime.ReadMemStats(&stats)
+	writeJSON(w, http.StatusOK, SystemUsageResponse{
+		Runs:   s.memory.Report(),
+		Limits: RunMemoryLimits{SoftBytes: s.memorySoft, HardBytes: s.memoryHard},
+		Process: ProcessMemory{
+			HeapAllocBytes: stats.HeapAlloc,
+			HeapInuseBytes: stats.HeapInuse,
+			SysBytes:       stats.Sys,
+			NumGC:          stats.NumGC,
+			Goroutines:     goruntime.NumGoroutine(),
+		},
+	})
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSystemUsage_Endpoint(t *testing.T) {
+	srv := testServer(t)
+	srv.memorySoft = 64 << 20
+	srv.memoryHard = 256 << 20
+	handler := srv.Handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/system/usage", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("usage: got %d; body: %s", w.Code, w.Body.String())
+	}
+	var resp SystemUsageResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Runs.ActiveRuns != 0 || resp.Runs.TotalBytes != 0 || resp.Runs.Runs == nil {
+		t.Errorf("runs = %+v, want none active", resp.Runs)
+	}
+	if resp.Limits.SoftBytes != 64<<20 || resp.Limits.HardBytes != 256<<20 {
+		t.Errorf("limits = %+v", resp.Limits)
+	}
+	if resp.Process.HeapAllocBytes == 0 || resp.Process.Goroutines == 0 {
+		t.Errorf("process = %+v", resp.Process)
+	}
+}
+
+func TestRunMemoryHardLimit(t *testing.T) {
+	srv := testServer(t)
+	srv.memoryHard = 1
+	handler := srv.Handler()
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(validGraphJSON("mem-test"))))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: got %d; body: %s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/workflows/mem-test/run", bytes.NewReader([]byte(`{}`))))
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("run: got %d, want 422; body: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Error.Code != "MEMORY_LIMIT_EXCEEDED" {
+		t.Errorf("code = %q, want MEMORY_LIMIT_EXCEEDED", resp.Error.Code)
+	}
+	if report := srv.memory.Report(); report.ActiveRuns != 0 {
+		t.Errorf("active runs after the run = %d, want 0", report.ActiveRuns)
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// WorkflowWait is a node of a running run that is blocked on something
+// outside the run.
+type WorkflowWait struct {
+	WaitID   string `json:"wait_id"`
+	RunID    string `json:"run_id"`
+	NodeID   string `json:"node_id"`
+	NodeKind string `json:"node_kind"`
+	// Reason is human, webhook or delay.
+	Reason       string    `json:"reason"`
+	WaitingSince time.Time `json:"waiting_since"`
+	WaitedMs     int64     `json:"waited_ms"`
+	// Deadline is when the wait gives up, if it ever does.
+	Deadline *time.Time `json:"deadline,omitempty"`
+	// Details are reason-specific, such as a human request's ID or a
+	// webhook's URL.
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// WorkflowWaits is the response of GET /api/workflows/{id}/waits.
+type WorkflowWaits struct {
+	WorkflowID string         `json:"workflow_id"`
+	Waits      []WorkflowWait `json:"waits"`
+}
+
+// waitTracker follows wait.started and wait.finished events to know which
+// runs of each workflow are blocked. It only sees runs of this process.
+type waitTracker struct {
+	mu    sync.Mutex
+	waits map[string]map[string]WorkflowWait // workflow ID -> wait ID
+}
+
+func newWaitTracker() *waitTracker {
+	return &waitTracker{waits: make(map[string]map[string]WorkflowWait)}
+}
+
+// handler returns the event handler for a run of workflowID.
+func (t *waitTracker) handler(workflowID string) runtime.EventHandler {
+	return func(e runtime.Event) {
+		switch e.Kind {
+		case runtime.EventWaitStarted:
+			t.start(workflowID, e)
+		case runtime.EventWaitFinished:
+			waitID, _ := e.Payload["wait_id"].(string)
+			t.mu.Lock()
+			delete(t.waits[workflowID], waitID)
+			t.mu.Unlock()
+		case runtime.EventRunFinished:
+			t.mu.Lock()
+			for id, wait := range t.waits[workflowID] {
+				if wait.RunID == e.RunID {
+					delete(t.waits[workflowID], id)
+				}
+			}
+			t.mu.Unlock()
+		}
+	}
+}
+
+func (t *waitTracker) start(workflowID string, e runtime.Event) {
+	wait := WorkflowWait{
+		RunID:        e.RunID,
+		NodeID:       e.NodeID,
+		NodeKind:     string(e.NodeKind),
+		WaitingSince: e.Time.UTC(),
+	}
+	for k, v := range e.Payload {
+		switch k {
+		case "wait_id":
+			wait.WaitID, _ = v.(string)
+		case "reason":
+			wait.Reason, _ = v.(string)
+		case "deadline":
+			if s, ok := v.(string); ok {
+				if deadline, err := time.Parse(time.RFC3339Nano, s); err == nil {
+					wait.Deadline = &deadline
+				}
+			}
+		default:
+			if wait.Details == nil {
+				wait.Details = make(map[string]any)
+			}
+			wait.Details[k] = v
+		}
+	}
+	if wait.WaitID == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.waits[workflowID] == nil {
+		t.waits[workflowID] = make(map[string]WorkflowWait)
+	}
+	t.waits[workflowID][wait.WaitID] = wait
+}
+
+// list returns workflowID's waits, longest waiting first.
+func (t *waitTracker) list(workflowID string, now time.Time) []WorkflowWait {
+	t.mu.Lock()
+	waits := make([]WorkflowWait, 0, len(t.waits[workflowID]))
+	for _, wait := range t.waits[workflowID] {
+		wait.WaitedMs = now.Sub(wait.WaitingSince).Milliseconds()
+		waits = append(waits, wait)
+	}
+	t.mu.Unlock()
+
+	sort.Slice(waits, func(i, j int) bool {
+		if !waits[i].WaitingSince.Equal(waits[j].WaitingSince) {
+			return waits[i].WaitingSince.Before(waits[j].WaitingSince)
+		}
+		return waits[i].WaitID < waits[j].WaitID
+	})
+	return waits
+}
+
+func (s *Server) handleWorkflowWaits(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	reason := r.URL.Query().Get("reason")
+	switch reason {
+	case "", runtime.WaitReasonHuman, runtime.WaitReasonWebhook, runtime.WaitReasonDelay, runtime.WaitReasonTool:
+	default:
+		writeError(w, http.StatusBadRequest, "INVALID_REASON", fmt.Sprintf("unknown wait reason %q (use human, webhook, delay or tool)", reason))
+		return
+	}
+
+	if _, found, err := s.store.Get(r.Context(), id); err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	} else if !found {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+		return
+	}
+
+	resp := WorkflowWaits{WorkflowID: id, Waits: []WorkflowWait{}}
+	for _, wait := range s.waits.list(id, time.Now().UTC()) {
+		if reason == "" || wait.Reason == reason {
+			resp.Waits = append(resp.Waits, wait)
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWorkflowWaits(t *testing.T) {
+	store := newTestSQLiteStore(t)
+	handler := NewServer(ServerConfig{Store: store}).Handler()
+
+	gd := map[string]any{
+		"id":      "approvals",
+		"version": "1.0",
+		"nodes": []map[string]any{{
+			"id":   "sign_off",
+			"type": "human",
+			"config": map[string]any{
+				"mode":       "approval",
+				"prompt":     "Ship it?",
+				"output_var": "decision",
+				"timeout":    "1m",
+			},
+		}},
+		"edges": []map[string]any{},
+		"entry": "sign_off",
+	}
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", gd); w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+
+	listWaits := func(query string) WorkflowWaits {
+		t.Helper()
+		w := doConditionRequest(t, handler, http.MethodGet, "/api/workflows/approvals/waits"+query, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("waits: %d %s", w.Code, w.Body.String())
+		}
+		var resp WorkflowWaits
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp
+	}
+	if got := listWaits(""); got.WorkflowID != "approvals" || len(got.Waits) != 0 {
+		t.Fatalf("idle waits = %+v", got)
+	}
+
+	// The auto approver answers after a delay, so the run blocks meanwhile.
+	done := make(chan int, 1)
+	go func() {
+		body := map[string]any{"options": map[string]any{"human": map[string]any{"mode": "auto_approve", "delay": "400ms"}}}
+		done <- doConditionRequest(t, handler, http.MethodPost, "/api/workflows/approvals/run", body).Code
+	}()
+
+	var waits WorkflowWaits
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if waits = listWaits(""); len(waits.Waits) > 0 {
+			break
+		}
+	}
+	if len(waits.Waits) != 1 {
+		t.Fatalf("waits = %+v, want one", waits)
+	}
+	wait := waits.Waits[0]
+	if wait.NodeID != "sign_off" || wait.NodeKind != "human" || wait.Reason != "human" || wait.RunID == "" {
+		t.Errorf("wait = %+v", wait)
+	}
+	if wait.Deadline == nil || wait.Deadline.Sub(wait.WaitingSince) > time.Minute || wait.Details["request_type"] != "approval" {
+		t.Errorf("wait deadline/details = %v %v", wait.Deadline, wait.Details)
+	}
+	if got := listWaits("?reason=webhook"); len(got.Waits) != 0 {
+		t.Errorf("reason filter = %+v", got)
+	}
+
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("run status = %d", code)
+	}
+	if got := listWaits(""); len(got.Waits) != 0 {
+		t.Errorf("waits after run = %+v", got)
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodGet, "/api/workflows/approvals/waits?reason=sleeping", nil); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid reason: %d", w.Code)
+	}
+	if w := doConditionRequest(t, handler, http.MethodGet, "/api/workflows/missing/waits", nil); w.Code != http.StatusNotFound {
+		t.Errorf("missing workflow: %d", w.Code)
+	}
+}
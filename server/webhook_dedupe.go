@@ -0,0 +1,198 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/nodes"
+)
+
+// WebhookDelivery is a webhook event ID claimed by the run it started.
+type WebhookDelivery struct {
+	WorkflowID string    `json:"workflow_id"`
+	TriggerID  string    `json:"trigger_id"`
+	EventID    string    `json:"event_id"`
+	RunID      string    `json:"run_id"`
+	ReceivedAt time.Time `json:"received_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// WebhookDedupeStore remembers webhook event IDs for their dedupe window.
+type WebhookDedupeStore interface {
+	// ClaimWebhookDelivery records d unless an unexpired delivery of the
+	// same workflow, trigger and event ID exists. It then returns that
+	// delivery and false.
+	ClaimWebhookDelivery(ctx context.Context, d WebhookDelivery) (WebhookDelivery, bool, error)
+	// ReleaseWebhookDelivery forgets the delivery claimed by runID, so a
+	// redelivery after a failed run starts a new run.
+	ReleaseWebhookDelivery(ctx context.Context, workflowID, triggerID, eventID, runID string) error
+}
+
+// MemoryWebhookDedupeStore is a WebhookDedupeStore that is lost on
+// restart.
+type MemoryWebhookDedupeStore struct {
+	mu         sync.Mutex
+	deliveries map[webhookDeliveryKey]WebhookDelivery
+}
+
+type webhookDeliveryKey struct {
+	workflowID, triggerID, eventID string
+}
+
+// NewMemoryWebhookDedupeStore creates an empty in-memory dedupe store.
+func NewMemoryWebhookDedupeStore() *MemoryWebhookDedupeStore {
+	return &MemoryWebhookDedupeStore{deliveries: make(map[webhookDeliveryKey]WebhookDelivery)}
+}
+
+// ClaimWebhookDelivery implements WebhookDedupeStore.
+func (m *MemoryWebhookDedupeStore) ClaimWebhookDelivery(_ context.Context, d WebhookDelivery) (WebhookDelivery, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for key, existing := range m.deliveries {
+		if !d.ReceivedAt.Before(existing.ExpiresAt) {
+			delete(m.deliveries, key)
+		}
+	}
+	key := webhookDeliveryKey{d.WorkflowID, d.TriggerID, d.EventID}
+	if existing, ok := m.deliveries[key]; ok {
+		return existing, false, nil
+	}
+	m.deliveries[key] = d
+	return d, true, nil
+}
+
+// ReleaseWebhookDelivery implements WebhookDedupeStore.
+func (m *MemoryWebhookDedupeStore) ReleaseWebhookDelivery(_ context.Context, workflowID, triggerID, eventID, runID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := webhookDeliveryKey{workflowID, triggerID, eventID}
+	if existing, ok := m.deliveries[key]; ok && existing.RunID == runID {
+		delete(m.deliveries, key)
+	}
+	return nil
+}
+
+// WebhookDuplicateResponse answers a redelivered webhook event.
+type WebhookDuplicateResponse struct {
+	ID        string `json:"id"`
+	RunID     string `json:"run_id"`
+	Status    string `json:"status"`
+	Duplicate bool   `json:"duplicate"`
+	EventID   string `json:"event_id"`
+	// FirstReceivedAt is when the delivery that started RunID arrived.
+	FirstReceivedAt time.Time `json:"first_received_at"`
+}
+
+// WebhookDedupeStats counts deliveries to triggers with dedupe enabled
+// since the daemon started.
+type WebhookDedupeStats struct {
+	Deliveries int64                       `json:"deliveries"`
+	Duplicates int64                       `json:"duplicates"`
+	Triggers   []WebhookTriggerDedupeStats `json:"triggers"`
+}
+
+// WebhookTriggerDedupeStats counts deliveries to one trigger.
+type WebhookTriggerDedupeStats struct {
+	WorkflowID string `json:"workflow_id"`
+	TriggerID  string `json:"trigger_id"`
+	Deliveries int64  `json:"deliveries"`
+	// Duplicates counts deliveries answered with an earlier run.
+	Duplicates int64 `json:"duplicates"`
+	// MissingEventID counts deliveries without an event ID, which run
+	// without dedupe.
+	MissingEventID  int64      `json:"missing_event_id"`
+	LastDuplicateAt *time.Time `json:"last_duplicate_at,omitempty"`
+}
+
+// webhookDedupeCounters tracks WebhookDedupeStats.
+type webhookDedupeCounters struct {
+	mu       sync.Mutex
+	triggers map[webhookDeliveryKey]*WebhookTriggerDedupeStats
+}
+
+func (c *webhookDedupeCounters) record(workflowID, triggerID string, update func(*WebhookTriggerDedupeStats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.triggers == nil {
+		c.triggers = make(map[webhookDeliveryKey]*WebhookTriggerDedupeStats)
+	}
+	key := webhookDeliveryKey{workflowID: workflowID, triggerID: triggerID}
+	stats, ok := c.triggers[key]
+	if !ok {
+		stats = &WebhookTriggerDedupeStats{WorkflowID: workflowID, TriggerID: triggerID}
+		c.triggers[key] = stats
+	}
+	stats.Deliveries++
+	update(stats)
+}
+
+func (c *webhookDedupeCounters) snapshot() WebhookDedupeStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := WebhookDedupeStats{Triggers: make([]WebhookTriggerDedupeStats, 0, len(c.triggers))}
+	for _, stats := range c.triggers {
+		out.Deliveries += stats.Deliveries
+		out.Duplicates += stats.Duplicates
+		out.Triggers = append(out.Triggers, *stats)
+	}
+	sort.Slice(out.Triggers, func(i, j int) bool {
+		a, b := out.Triggers[i], out.Triggers[j]
+		if a.WorkflowID != b.WorkflowID {
+			return a.WorkflowID < b.WorkflowID
+		}
+		return a.TriggerID < b.TriggerID
+	})
+	return out
+}
+
+// claimWebhookDelivery claims the request's event ID for runID. It returns
+// the earlier delivery when the request is a duplicate, and nil, nil when
+// the request has no event ID.
+func (s *Server) claimWebhookDelivery(
+	ctx context.Context,
+	workflowID, triggerID string,
+	cfg nodes.WebhookDedupeConfig,
+	request map[string]any,
+	runID string,
+) (claimed, duplicate *WebhookDelivery, err error) {
+	eventID, ok := cfg.EventIDFrom(request)
+	if !ok {
+		s.webhookDedupeStats.record(workflowID, triggerID, func(st *WebhookTriggerDedupeStats) { st.MissingEventID++ })
+		return nil, nil, nil
+	}
+	request["event_id"] = eventID
+
+	now := time.Now().UTC()
+	d := WebhookDelivery{
+		WorkflowID: workflowID,
+		TriggerID:  triggerID,
+		EventID:    eventID,
+		RunID:      runID,
+		ReceivedAt: now,
+		ExpiresAt:  now.Add(cfg.Window),
+	}
+	existing, ok, err := s.webhookDedupe.ClaimWebhookDelivery(ctx, d)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok {
+		s.webhookDedupeStats.record(workflowID, triggerID, func(*WebhookTriggerDedupeStats) {})
+		return &d, nil, nil
+	}
+	s.webhookDedupeStats.record(workflowID, triggerID, func(st *WebhookTriggerDedupeStats) {
+		st.Duplicates++
+		st.LastDuplicateAt = &now
+	})
+	s.logger.Info("duplicate webhook delivery suppressed",
+		"workflow_id", workflowID, "trigger_id", triggerID, "event_id", eventID, "run_id", existing.RunID)
+	return nil, &existing, nil
+}
+
+// handleWebhookDedupe returns the duplicate deliveries suppressed per
+// trigger.
+func (s *Server) handleWebhookDedupe(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.webhookDedupeStats.snapshot())
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dedupeWebhookGraphJSON(t *testing.T, id string) []byte {
+	t.Helper()
+	var gd map[string]any
+	if err := json.Unmarshal(validWebhookGraphJSON(id, []string{"POST"}, nil), &gd); err != nil {
+		t.Fatalf("unmarshal graph: %v", err)
+	}
+	trigger := gd["nodes"].([]any)[0].(map[string]any)
+	trigger["config"].(map[string]any)["dedupe"] = map[string]any{"event_id": "body.id", "window": "1h"}
+	b, _ := json.Marshal(gd)
+	return b
+}
+
+func TestWebhookTrigger_Dedupe(t *testing.T) {
+	srv := testServer(t)
+	handler := srv.Handler()
+
+	createW := httptest.NewRecorder()
+	handler.ServeHTTP(createW, httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(dedupeWebhookGraphJSON(t, "webhook-dedupe"))))
+	if createW.Code != http.StatusCreated {
+		t.Fatalf("create workflow status = %d body=%s", createW.Code, createW.Body.String())
+	}
+
+	deliver := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/workflows/webhook-dedupe/webhooks/incoming", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("webhook status = %d body=%s", w.Code, w.Body.String())
+		}
+		return w
+	}
+
+	var first RunResponse
+	_ = json.Unmarshal(deliver(`{"id":"evt_1","event":"order.created"}`).Body.Bytes(), &first)
+	if first.Status != "completed" || first.RunID == "" {
+		t.Fatalf("first delivery = %+v", first)
+	}
+	meta, _ := first.Output.Vars["webhook_meta"].(map[string]any)
+	if meta["event_id"] != "evt_1" {
+		t.Errorf("webhook_meta = %v, want event_id", meta)
+	}
+
+	var dup WebhookDuplicateResponse
+	_ = json.Unmarshal(deliver(`{"id":"evt_1","event":"order.created"}`).Body.Bytes(), &dup)
+	if !dup.Duplicate || dup.Status != "duplicate" || dup.RunID != first.RunID || dup.EventID != "evt_1" {
+		t.Fatalf("redelivery = %+v, want duplicate of %s", dup, first.RunID)
+	}
+
+	var other RunResponse
+	_ = json.Unmarshal(deliver(`{"id":"evt_2","event":"order.created"}`).Body.Bytes(), &other)
+	if other.RunID == "" || other.RunID == first.RunID {
+		t.Fatalf("new event run = %q, want a new run", other.RunID)
+	}
+	deliver(`{"event":"order.created"}`)
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/dedupe", nil))
+	var stats WebhookDedupeStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	if stats.Deliveries != 4 || stats.Duplicates != 1 || len(stats.Triggers) != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if tr := stats.Triggers[0]; tr.MissingEventID != 1 || tr.LastDuplicateAt == nil {
+		t.Errorf("trigger stats = %+v", tr)
+	}
+}
+
+func testWebhookDedupeStore(t *testing.T, store WebhookDedupeStore) {
+	t.Helper()
+	ctx := context.Background()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	delivery := func(runID string, at time.Time) WebhookDelivery {
+		return WebhookDelivery{WorkflowID: "wf", TriggerID: "hook", EventID: "evt_1", RunID: runID, ReceivedAt: at, ExpiresAt: at.Add(time.Hour)}
+	}
+
+	if _, ok, err := store.ClaimWebhookDelivery(ctx, delivery("run-1", now)); err != nil || !ok {
+		t.Fatalf("first claim = %v, %v", ok, err)
+	}
+	existing, ok, err := store.ClaimWebhookDelivery(ctx, delivery("run-2", now.Add(time.Minute)))
+	if err != nil || ok || existing.RunID != "run-1" || !existing.ReceivedAt.Equal(now) {
+		t.Fatalf("duplicate claim = %+v, %v, %v", existing, ok, err)
+	}
+
+	// Releasing another run's claim is a no-op.
+	if err := store.ReleaseWebhookDelivery(ctx, "wf", "hook", "evt_1", "run-2"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, ok, _ := store.ClaimWebhookDelivery(ctx, delivery("run-3", now.Add(time.Minute))); ok {
+		t.Fatal("claim of another run should not be released")
+	}
+	if err := store.ReleaseWebhookDelivery(ctx, "wf", "hook", "evt_1", "run-1"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, ok, _ := store.ClaimWebhookDelivery(ctx, delivery("run-4", now.Add(2*time.Minute))); !ok {
+		t.Fatal("released event ID should be claimable")
+	}
+
+	if _, ok, _ := store.ClaimWebhookDelivery(ctx, delivery("run-5", now.Add(3*time.Hour))); !ok {
+		t.Fatal("expired event ID should be claimable")
+	}
+}
+
+func TestMemoryWebhookDedupeStore(t *testing.T) {
+	testWebhookDedupeStore(t, NewMemoryWebhookDedupeStore())
+}
+
+func TestSQLiteStore_WebhookDeliveries(t *testing.T) {
+	testWebhookDedupeStore(t, newTestSQLiteStore(t))
+}
@@ -1,19 +1,35 @@
 package server
 
 import (
+	"context"
 	"crypto/subtle"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
+	"path"
 	"strings"
 	"time"
 
 	"github.com/petal-labs/petalflow/graph"
 	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/runtime"
 )
 
+// IsWebhookRequest reports whether r is a webhook delivery, addressed to
+// exactly /api/workflows/{id}/webhooks/{trigger_id}. Deliveries carry their
+// own trigger credentials, so bearer-token auth and authorization skip
+// them; every other route under a workflow, whatever its ID, does not.
+func IsWebhookRequest(r *http.Request) bool {
+	p := r.URL.Path
+	if path.Clean(p) != p {
+		return false
+	}
+	parts := strings.Split(strings.TrimPrefix(p, "/"), "/")
+	return len(parts) == 5 && parts[0] == "api" && parts[1] == "workflows" &&
+		parts[2] != "" && parts[3] == "webhooks" && parts[4] != ""
+}
+
 func (s *Server) handleWorkflowWebhook(w http.ResponseWriter, r *http.Request) {
 	workflowID := r.PathValue("id")
 	triggerID := r.PathValue("trigger_id")
@@ -31,14 +47,36 @@ func (s *Server) handleWorkflowWebhook(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "NOT_COMPILED", "workflow has no compiled graph")
 		return
 	}
+	deprecation, err := s.checkLifecycleRun(r.Context(), workflowID, false)
+	if err != nil {
+		writeRunAPIError(w, err)
+		return
+	}
 
-	triggerNode, ok := findNodeDef(rec.Compiled, triggerID)
+	// During a rollout deliveries are split like other runs, except that
+	// a candidate without this trigger leaves them on the stable revision.
+	revision, rollout, err := s.rolloutRevision(r.Context(), rec)
+	if err != nil {
+		writeRunAPIError(w, err)
+		return
+	}
+	triggerNode, ok := findNodeDef(revision, triggerID)
+	if (!ok || triggerNode.Type != "webhook_trigger") && rollout != nil && rollout.revision == RolloutCandidate {
+		revision = rec.Compiled
+		rollout = &rolloutRun{revision: RolloutStable, rolloutCreatedAt: rollout.rolloutCreatedAt}
+		triggerNode, ok = findNodeDef(revision, triggerID)
+	}
 	if !ok || triggerNode.Type != "webhook_trigger" {
 		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("webhook trigger %q not found", triggerID))
 		return
 	}
 
-	triggerCfg, err := nodes.ParseWebhookTriggerConfig(triggerNode.Config)
+	opened, err := s.openSecrets(&graph.GraphDefinition{Nodes: []graph.NodeDef{triggerNode}})
+	if err != nil {
+		writeRunAPIError(w, err)
+		return
+	}
+	triggerCfg, err := nodes.ParseWebhookTriggerConfig(opened.Nodes[0].Config)
 	if err != nil {
 		writeError(w, http.StatusUnprocessableEntity, "INVALID_WEBHOOK_TRIGGER", err.Error())
 		return
@@ -53,20 +91,52 @@ func (s *Server) handleWorkflowWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	requestBody, err := decodeWebhookBody(r)
+	requestBody, err := s.readWebhookBody(w, r, workflowID, triggerID, triggerCfg.Limits)
 	if err != nil {
-		if isMaxBytesError(err) {
-			writeError(w, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE", "request body exceeds size limit")
-			return
-		}
-		writeError(w, http.StatusBadRequest, "PARSE_ERROR", err.Error())
+		writeRunAPIError(w, err)
 		return
 	}
 
 	requestPayload := normalizeWebhookRequestPayload(workflowID, triggerID, r, requestBody)
 
-	compiled, err := cloneGraphDefinition(rec.Compiled)
+	var (
+		runID    string
+		delivery *WebhookDelivery
+	)
+	if triggerCfg.Dedupe != nil {
+		runID = runtime.NewRunID()
+		claimed, duplicate, err := s.claimWebhookDelivery(r.Context(), workflowID, triggerID, *triggerCfg.Dedupe, requestPayload, runID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+			return
+		}
+		if duplicate != nil {
+			writeJSON(w, http.StatusOK, WebhookDuplicateResponse{
+				ID:              workflowID,
+				RunID:           duplicate.RunID,
+				Status:          "duplicate",
+				Duplicate:       true,
+				EventID:         duplicate.EventID,
+				FirstReceivedAt: duplicate.ReceivedAt,
+			})
+			return
+		}
+		delivery = claimed
+	}
+	// A failed run gives up its event ID so the sender's retry runs again.
+	release := func() {
+		if delivery == nil {
+			return
+		}
+		if err := s.webhookDedupe.ReleaseWebhookDelivery(context.WithoutCancel(r.Context()),
+			workflowID, triggerID, delivery.EventID, delivery.RunID); err != nil {
+			s.logger.Warn("release webhook delivery", "workflow_id", workflowID, "event_id", delivery.EventID, "error", err)
+		}
+	}
+
+	compiled, err := cloneGraphDefinition(revision)
 	if err != nil {
+		release()
 		writeError(w, http.StatusInternalServerError, "RUNTIME_ERROR", fmt.Sprintf("clone compiled graph: %v", err))
 		return
 	}
@@ -83,9 +153,14 @@ func (s *Server) handleWorkflowWebhook(w http.ResponseWriter, r *http.Request) {
 
 	plan, err := s.planWorkflowRunWithDefinition(r.Context(), workflowID, compiled, runReq)
 	if err != nil {
+		release()
 		writeRunAPIError(w, err)
 		return
 	}
+	plan.class = RunClassWebhook
+	plan.runID = runID
+	plan.rollout = rollout
+	plan.deprecation = deprecation
 
 	resp, err := s.executeWorkflowRunSync(r.Context(), workflowID, plan, webhookRunMetadataDecorator(webhookRunMetadata{
 		WorkflowID: workflowID,
@@ -93,10 +168,12 @@ func (s *Server) handleWorkflowWebhook(w http.ResponseWriter, r *http.Request) {
 		Method:     strings.ToUpper(r.Method),
 	}))
 	if err != nil {
+		release()
 		writeRunAPIError(w, err)
 		return
 	}
 
+	setDeprecationHeaders(w, deprecation)
 	writeJSON(w, http.StatusOK, resp)
 }
 
@@ -180,27 +257,6 @@ func getEnv(key string) string {
 	return os.Getenv(key)
 }
 
-func decodeWebhookBody(r *http.Request) (any, error) {
-	bodyBytes, err := io.ReadAll(r.Body)
-	if err != nil {
-		return nil, err
-	}
-	if len(bodyBytes) == 0 {
-		return nil, nil
-	}
-
-	contentType := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Type")))
-	if strings.HasPrefix(contentType, "application/json") {
-		var payload any
-		if err := json.Unmarshal(bodyBytes, &payload); err != nil {
-			return nil, fmt.Errorf("invalid JSON body: %w", err)
-		}
-		return payload, nil
-	}
-
-	return string(bodyBytes), nil
-}
-
 func normalizeWebhookRequestPayload(workflowID string, triggerID string, r *http.Request, body any) map[string]any {
 	query := make(map[string]any, len(r.URL.Query()))
 	for key, values := range r.URL.Query() {
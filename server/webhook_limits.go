@@ -0,0 +1,158 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/nodes"
+)
+
+// Reasons webhook deliveries are rejected before they start a run.
+const (
+	WebhookRejectBodyTooLarge     = "body_too_large"
+	WebhookRejectUnsupportedType  = "unsupported_media_type"
+	WebhookRejectJSONTooDeep      = "json_too_deep"
+	WebhookRejectJSONArrayTooLong = "json_array_too_long"
+)
+
+// WebhookRejectionStats counts the webhook deliveries refused by request
+// limits since the daemon started.
+type WebhookRejectionStats struct {
+	Rejected int64                          `json:"rejected"`
+	Triggers []WebhookTriggerRejectionStats `json:"triggers"`
+}
+
+// WebhookTriggerRejectionStats counts the deliveries refused for one
+// trigger.
+type WebhookTriggerRejectionStats struct {
+	WorkflowID string `json:"workflow_id"`
+	TriggerID  string `json:"trigger_id"`
+	Rejected   int64  `json:"rejected"`
+	// Reasons counts rejections by reason, such as body_too_large.
+	Reasons        map[string]int64 `json:"reasons"`
+	LastRejectedAt *time.Time       `json:"last_rejected_at,omitempty"`
+}
+
+// webhookRejectionCounters tracks WebhookRejectionStats.
+type webhookRejectionCounters struct {
+	mu       sync.Mutex
+	triggers map[webhookDeliveryKey]*WebhookTriggerRejectionStats
+}
+
+func (c *webhookRejectionCounters) record(workflowID, triggerID, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.triggers == nil {
+		c.triggers = make(map[webhookDeliveryKey]*WebhookTriggerRejectionStats)
+	}
+	key := webhookDeliveryKey{workflowID: workflowID, triggerID: triggerID}
+	stats, ok := c.triggers[key]
+	if !ok {
+		stats = &WebhookTriggerRejectionStats{WorkflowID: workflowID, TriggerID: triggerID, Reasons: map[string]int64{}}
+		c.triggers[key] = stats
+	}
+	now := time.Now().UTC()
+	stats.Rejected++
+	stats.Reasons[reason]++
+	stats.LastRejectedAt = &now
+}
+
+func (c *webhookRejectionCounters) snapshot() WebhookRejectionStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := WebhookRejectionStats{Triggers: make([]WebhookTriggerRejectionStats, 0, len(c.triggers))}
+	for _, stats := range c.triggers {
+		out.Rejected += stats.Rejected
+		copied := *stats
+		copied.Reasons = make(map[string]int64, len(stats.Reasons))
+		for reason, n := range stats.Reasons {
+			copied.Reasons[reason] = n
+		}
+		out.Triggers = append(out.Triggers, copied)
+	}
+	sort.Slice(out.Triggers, func(i, j int) bool {
+		a, b := out.Triggers[i], out.Triggers[j]
+		if a.WorkflowID != b.WorkflowID {
+			return a.WorkflowID < b.WorkflowID
+		}
+		return a.TriggerID < b.TriggerID
+	})
+	return out
+}
+
+// readWebhookBody reads and decodes a delivery's body within the trigger's
+// request limits. JSON bodies are decoded; others are kept as text.
+// Deliveries over a limit are counted, logged and refused with 413, 415
+// or 422.
+func (s *Server) readWebhookBody(
+	w http.ResponseWriter,
+	r *http.Request,
+	workflowID, triggerID string,
+	limits nodes.WebhookRequestLimits,
+) (any, error) {
+	reject := func(reason string, status int, code, message string) error {
+		s.webhookRejections.record(workflowID, triggerID, reason)
+		s.logger.Warn("webhook delivery rejected",
+			"workflow_id", workflowID, "trigger_id", triggerID, "reason", reason, "remote_addr", r.RemoteAddr)
+		return &runAPIError{Status: status, Code: code, Message: message}
+	}
+
+	if limits.MaxBodyBytes > 0 {
+		if r.ContentLength > limits.MaxBodyBytes {
+			return nil, reject(WebhookRejectBodyTooLarge, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE",
+				fmt.Sprintf("request body exceeds %d bytes", limits.MaxBodyBytes))
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, limits.MaxBodyBytes)
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return nil, reject(WebhookRejectBodyTooLarge, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE",
+				fmt.Sprintf("request body exceeds %d bytes", maxErr.Limit))
+		}
+		return nil, &runAPIError{Status: http.StatusBadRequest, Code: "PARSE_ERROR", Message: err.Error()}
+	}
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	contentType := strings.TrimSpace(r.Header.Get("Content-Type"))
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !limits.AcceptsContentType(mediaType) {
+		if mediaType == "" {
+			mediaType = "none"
+		}
+		return nil, reject(WebhookRejectUnsupportedType, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE",
+			fmt.Sprintf("content type %q is not accepted by this trigger", mediaType))
+	}
+
+	if !strings.HasPrefix(strings.ToLower(contentType), "application/json") {
+		return string(body), nil
+	}
+	if err := limits.CheckJSON(body); err != nil {
+		if errors.Is(err, nodes.ErrWebhookJSONTooDeep) {
+			return nil, reject(WebhookRejectJSONTooDeep, http.StatusUnprocessableEntity, "JSON_TOO_DEEP", err.Error())
+		}
+		return nil, reject(WebhookRejectJSONArrayTooLong, http.StatusUnprocessableEntity, "JSON_ARRAY_TOO_LONG", err.Error())
+	}
+	var payload any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, &runAPIError{Status: http.StatusBadRequest, Code: "PARSE_ERROR", Message: fmt.Sprintf("invalid JSON body: %v", err)}
+	}
+	return payload, nil
+}
+
+// handleWebhookRejections returns the deliveries refused by request
+// limits per trigger.
+func (s *Server) handleWebhookRejections(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.webhookRejections.snapshot())
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWebhookTrigger_RequestLimits(t *testing.T) {
+	var gd map[string]any
+	if err := json.Unmarshal(validWebhookGraphJSON("webhook-limits", []string{"POST"}, nil), &gd); err != nil {
+		t.Fatalf("unmarshal graph: %v", err)
+	}
+	trigger := gd["nodes"].([]any)[0].(map[string]any)
+	trigger["config"].(map[string]any)["limits"] = map[string]any{
+		"max_body_bytes":   64,
+		"content_types":    []string{"application/json"},
+		"max_json_depth":   2,
+		"max_array_length": 3,
+	}
+	def, _ := json.Marshal(gd)
+
+	handler := testServer(t).Handler()
+	createW := httptest.NewRecorder()
+	handler.ServeHTTP(createW, httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(def)))
+	if createW.Code != http.StatusCreated {
+		t.Fatalf("create workflow status = %d body=%s", createW.Code, createW.Body.String())
+	}
+
+	deliver := func(contentType, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodPost, "/api/workflows/webhook-limits/webhooks/incoming", strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := deliver("application/json", `{"event":"order.created","items":[1,2]}`); w.Code != http.StatusOK {
+		t.Fatalf("delivery within limits = %d %s", w.Code, w.Body.String())
+	}
+	for _, tc := range []struct {
+		contentType, body string
+		status            int
+		code              string
+	}{
+		{"application/json", `{"event":"` + strings.Repeat("x", 64) + `"}`, http.StatusRequestEntityTooLarge, "BODY_TOO_LARGE"},
+		{"text/plain", "event=order.created", http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE"},
+		{"application/json", `{"a":{"b":{"c":1}}}`, http.StatusUnprocessableEntity, "JSON_TOO_DEEP"},
+		{"application/json", `{"items":[1,2,3,4]}`, http.StatusUnprocessableEntity, "JSON_ARRAY_TOO_LONG"},
+	} {
+		w := deliver(tc.contentType, tc.body)
+		var apiErr apiError
+		_ = json.Unmarshal(w.Body.Bytes(), &apiErr)
+		if w.Code != tc.status || apiErr.Error.Code != tc.code {
+			t.Errorf("%s %s: %d %s, want %d %s", tc.contentType, tc.body, w.Code, w.Body.String(), tc.status, tc.code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/runs/webhook-rejections", nil))
+	var stats WebhookRejectionStats
+	if err := json.Unmarshal(w.Body.Bytes(), &stats); err != nil {
+		t.Fatalf("decode stats: %v", err)
+	}
+	if stats.Rejected != 4 || len(stats.Triggers) != 1 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	tr := stats.Triggers[0]
+	if tr.TriggerID != "incoming" || tr.Reasons[WebhookRejectBodyTooLarge] != 1 || tr.Reasons[WebhookRejectJSONArrayTooLong] != 1 || tr.LastRejectedAt == nil {
+		t.Errorf("trigger stats = %+v", tr)
+	}
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/petal-labs/petalflow/graph"
+)
+
+// checkWorkflowPolicy applies the server's policy to a workflow being
+// saved. Violations of error severity reject the save; warnings are logged
+// and returned on the record.
+func (s *Server) checkWorkflowPolicy(rec *WorkflowRecord) error {
+	diags := s.policy.Check(rec.Compiled)
+	if graph.HasErrors(diags) {
+		return &runAPIError{
+			Status:  http.StatusUnprocessableEntity,
+			Code:    "POLICY_VIOLATION",
+			Message: "workflow violates the server policy",
+			Details: diagMessages(diags),
+		}
+	}
+	rec.PolicyWarnings = nil
+	for _, d := range graph.Warnings(diags) {
+		s.logger.Warn("workflow policy warning", "workflow_id", rec.ID, "code", d.Code, "message", d.Message)
+		rec.PolicyWarnings = append(rec.PolicyWarnings, d.Message)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/bus"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/policy"
+)
+
+func policyWorkflow(url string) map[string]any {
+	return map[string]any{
+		"id":      "notifier",
+		"version": "1.0",
+		"nodes": []map[string]any{{
+			"id":     "notify",
+			"type":   "webhook_call",
+			"config": map[string]any{"url": url},
+		}},
+		"entry": "notify",
+	}
+}
+
+func newPolicyTestServer(t *testing.T, p *policy.Policy) http.Handler {
+	t.Helper()
+	return NewServer(ServerConfig{
+		Store:     newTestSQLiteStore(t),
+		Providers: hydrate.ProviderMap{},
+		Bus:       bus.NewMemBus(bus.MemBusConfig{}),
+		Policy:    p,
+	}).Handler()
+}
+
+func TestWorkflowPolicy_BlocksViolations(t *testing.T) {
+	handler := newPolicyTestServer(t, &policy.Policy{AllowedHosts: []string{"*.corp.example.com"}})
+
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", policyWorkflow("https://hooks.evil.example/x"))
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "POLICY_VIOLATION") || !strings.Contains(w.Body.String(), "hooks.evil.example") {
+		t.Fatalf("create: %d %s, want a policy violation", w.Code, w.Body.String())
+	}
+
+	if w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", policyWorkflow("https://audit.corp.example.com/log")); w.Code != http.StatusCreated {
+		t.Fatalf("create allowed: %d %s", w.Code, w.Body.String())
+	}
+	w = doConditionRequest(t, handler, http.MethodPut, "/api/workflows/notifier", policyWorkflow("https://hooks.evil.example/x"))
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "POLICY_VIOLATION") {
+		t.Fatalf("update: %d %s, want a policy violation", w.Code, w.Body.String())
+	}
+}
+
+func TestWorkflowPolicy_FlagsWarnings(t *testing.T) {
+	handler := newPolicyTestServer(t, &policy.Policy{
+		AllowedHosts: []string{"*.corp.example.com"},
+		Severity:     map[string]string{policy.RuleEgress: policy.SeverityWarning},
+	})
+
+	w := doConditionRequest(t, handler, http.MethodPost, "/api/workflows/graph", policyWorkflow("https://hooks.evil.example/x"))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", w.Code, w.Body.String())
+	}
+	var rec WorkflowRecord
+	_ = json.Unmarshal(w.Body.Bytes(), &rec)
+	if len(rec.PolicyWarnings) != 1 || !strings.Contains(rec.PolicyWarnings[0], "allowed_hosts") {
+		t.Fatalf("policy_warnings = %q, want the egress warning", rec.PolicyWarnings)
+	}
+}
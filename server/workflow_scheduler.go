@@ -12,6 +12,7 @@ import (
 const (
 	defaultWorkflowSchedulePollInterval = 5 * time.Second
 	defaultWorkflowScheduleBatchLimit   = 100
+	defaultWorkflowScheduleClockSkew    = 30 * time.Second
 )
 
 // WorkflowSchedulerConfig configures the background workflow schedule runner.
@@ -20,8 +21,13 @@ type WorkflowSchedulerConfig struct {
 	Store        WorkflowScheduleStore
 	PollInterval time.Duration
 	BatchLimit   int
-	Now          func() time.Time
-	Logger       *slog.Logger
+	// ClockSkew is how late past its poll interval a run may start and
+	// still count as on time, allowing for clock drift between hosts and
+	// slow passes. Later runs were missed and follow their schedule's
+	// missed-fire policy. Defaults to 30s.
+	ClockSkew time.Duration
+	Now       func() time.Time
+	Logger    *slog.Logger
 }
 
 // WorkflowScheduler periodically executes due workflow schedules.
@@ -30,6 +36,7 @@ type WorkflowScheduler struct {
 	store        WorkflowScheduleStore
 	pollInterval time.Duration
 	batchLimit   int
+	clockSkew    time.Duration
 	now          func() time.Time
 	logger       *slog.Logger
 
@@ -53,6 +60,9 @@ func NewWorkflowScheduler(cfg WorkflowSchedulerConfig) (*WorkflowScheduler, erro
 	if cfg.BatchLimit <= 0 {
 		cfg.BatchLimit = defaultWorkflowScheduleBatchLimit
 	}
+	if cfg.ClockSkew <= 0 {
+		cfg.ClockSkew = defaultWorkflowScheduleClockSkew
+	}
 	if cfg.Now == nil {
 		cfg.Now = func() time.Time { return time.Now().UTC() }
 	}
@@ -65,6 +75,7 @@ func NewWorkflowScheduler(cfg WorkflowSchedulerConfig) (*WorkflowScheduler, erro
 		store:        cfg.Store,
 		pollInterval: cfg.PollInterval,
 		batchLimit:   cfg.BatchLimit,
+		clockSkew:    cfg.ClockSkew,
 		now:          cfg.Now,
 		logger:       cfg.Logger,
 		active:       map[string]struct{}{},
@@ -142,6 +153,10 @@ func (s *WorkflowScheduler) RunOnce(ctx context.Context) error {
 	if s == nil || s.store == nil || s.runner == nil {
 		return errors.New("workflow scheduler is not configured")
 	}
+	if s.runner.ReadOnly() {
+		// Due schedules stay due and run once read-only mode ends.
+		return nil
+	}
 
 	now := s.now().UTC()
 	dueSchedules, err := s.store.ListDueSchedules(ctx, now, s.batchLimit)
@@ -160,33 +175,88 @@ func (s *WorkflowScheduler) processDueSchedule(ctx context.Context, schedule Wor
 		return
 	}
 
+	cs, err := parseCronSchedule(schedule.Cron, schedule.Timezone)
+	if err != nil {
+		s.markScheduleFailure(ctx, schedule, now, err)
+		return
+	}
+
 	if s.isScheduleActive(schedule.ID) {
-		s.markSkippedOverlap(ctx, schedule, now)
+		s.markSkippedOverlap(ctx, schedule, cs, now)
 		return
 	}
 
-	nextRunAt, err := nextCronRunUTC(schedule.Cron, now)
-	if err != nil {
-		s.markScheduleFailure(ctx, schedule, now, fmt.Errorf("invalid cron expression: %w", err))
+	fires, missed := s.dueFires(schedule, cs, now)
+	if missed > 0 {
+		s.logger.Warn("schedule missed runs",
+			"schedule_id", schedule.ID,
+			"workflow_id", schedule.WorkflowID,
+			"missed", missed,
+			"missed_fire", schedule.missedFirePolicy(),
+			"running", len(fires),
+		)
+	}
+
+	schedule.scheduleNext(cs, now)
+	schedule.UpdatedAt = now
+	if len(fires) == 0 {
+		schedule.LastStatus = ScheduleRunStatusSkippedMissed
+		schedule.LastError = fmt.Sprintf("skipped %d missed run(s)", missed)
+		if err := s.store.UpdateSchedule(ctx, schedule); err != nil {
+			s.logger.Error("persist missed skip", "schedule_id", schedule.ID, "workflow_id", schedule.WorkflowID, "error", err)
+		}
 		return
 	}
 
-	schedule.NextRunAt = nextRunAt
 	schedule.LastStatus = ScheduleRunStatusRunning
 	schedule.LastError = ""
-	schedule.UpdatedAt = now
 	if err := s.store.UpdateSchedule(ctx, schedule); err != nil {
 		s.logger.Error("update schedule before run", "schedule_id", schedule.ID, "workflow_id", schedule.WorkflowID, "error", err)
 		return
 	}
 
 	s.markScheduleActive(schedule.ID)
-	go s.runSchedule(schedule, now)
+	go s.runSchedule(schedule, fires)
+}
+
+// dueFires returns the fire times to run now, and how many fire times
+// were missed. A schedule reached within the poll interval plus the clock
+// skew of its run time is on time; otherwise its missed-fire policy picks
+// the runs.
+func (s *WorkflowScheduler) dueFires(schedule WorkflowSchedule, cs cronSchedule, now time.Time) ([]time.Time, int) {
+	fireAt := schedule.NextFireAt
+	if fireAt.IsZero() {
+		// Schedules stored before fire times were recorded.
+		fireAt = schedule.NextRunAt
+	}
+	if now.Sub(schedule.NextRunAt) <= s.pollInterval+s.clockSkew {
+		return []time.Time{fireAt}, 0
+	}
+
+	switch schedule.missedFirePolicy() {
+	case MissedFireSkip:
+		_, total := cs.missedFires(fireAt, now, 0)
+		return nil, total
+	case MissedFireCatchUp:
+		return cs.missedFires(fireAt, now, schedule.maxBackfill())
+	default:
+		return cs.missedFires(fireAt, now, 1)
+	}
 }
 
-func (s *WorkflowScheduler) runSchedule(schedule WorkflowSchedule, scheduledAt time.Time) {
+// runSchedule runs the schedule once per fire time, one after another.
+func (s *WorkflowScheduler) runSchedule(schedule WorkflowSchedule, fires []time.Time) {
 	defer s.unmarkScheduleActive(schedule.ID)
+	for _, fireAt := range fires {
+		if !s.runScheduleOnce(schedule, fireAt) {
+			return
+		}
+	}
+}
 
+// runScheduleOnce runs the schedule for one fire time and records the
+// result. It reports false when the schedule is gone.
+func (s *WorkflowScheduler) runScheduleOnce(schedule WorkflowSchedule, scheduledAt time.Time) bool {
 	runReq := RunRequest{
 		Input:   cloneMapAny(schedule.Input),
 		Options: schedule.Options,
@@ -203,10 +273,10 @@ func (s *WorkflowScheduler) runSchedule(schedule WorkflowSchedule, scheduledAt t
 	latest, found, err := s.store.GetSchedule(context.Background(), schedule.WorkflowID, schedule.ID)
 	if err != nil {
 		s.logger.Error("load schedule after run", "schedule_id", schedule.ID, "workflow_id", schedule.WorkflowID, "error", err)
-		return
+		return false
 	}
 	if !found {
-		return
+		return false
 	}
 
 	latest.UpdatedAt = finish
@@ -223,16 +293,11 @@ func (s *WorkflowScheduler) runSchedule(schedule WorkflowSchedule, scheduledAt t
 	if err := s.store.UpdateSchedule(context.Background(), latest); err != nil {
 		s.logger.Error("persist schedule run result", "schedule_id", schedule.ID, "workflow_id", schedule.WorkflowID, "error", err)
 	}
+	return true
 }
 
-func (s *WorkflowScheduler) markSkippedOverlap(ctx context.Context, schedule WorkflowSchedule, now time.Time) {
-	nextRunAt, err := nextCronRunUTC(schedule.Cron, now)
-	if err != nil {
-		s.markScheduleFailure(ctx, schedule, now, fmt.Errorf("invalid cron expression: %w", err))
-		return
-	}
-
-	schedule.NextRunAt = nextRunAt
+func (s *WorkflowScheduler) markSkippedOverlap(ctx context.Context, schedule WorkflowSchedule, cs cronSchedule, now time.Time) {
+	schedule.scheduleNext(cs, now)
 	schedule.LastStatus = ScheduleRunStatusSkippedOverlap
 	schedule.LastError = "skipped because prior scheduled run is still active"
 	schedule.UpdatedAt = now
@@ -242,9 +307,8 @@ func (s *WorkflowScheduler) markSkippedOverlap(ctx context.Context, schedule Wor
 }
 
 func (s *WorkflowScheduler) markScheduleFailure(ctx context.Context, schedule WorkflowSchedule, now time.Time, runErr error) {
-	nextRunAt, nextErr := nextCronRunUTC(schedule.Cron, now)
-	if nextErr == nil {
-		schedule.NextRunAt = nextRunAt
+	if cs, err := parseCronSchedule(schedule.Cron, schedule.Timezone); err == nil {
+		schedule.scheduleNext(cs, now)
 	}
 	schedule.LastStatus = ScheduleRunStatusFailed
 	schedule.LastError = runErr.Error()
@@ -142,6 +142,148 @@ func TestWorkflowScheduler_SkipsOverlapWhenRunAlreadyActive(t *testing.T) {
 	}
 }
 
+func TestWorkflowScheduler_MissedFirePolicies(t *testing.T) {
+	now := time.Date(2026, 2, 16, 12, 0, 0, 0, time.UTC)
+
+	t.Run("skip", func(t *testing.T) {
+		store := newTestSQLiteStore(t)
+		srv := NewServer(ServerConfig{
+			Store:         store,
+			ScheduleStore: store,
+			Providers:     hydrate.ProviderMap{},
+			ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) { return nil, nil },
+		})
+		createWorkflowForScheduler(t, srv.Handler(), "scheduler-skip")
+		schedule := WorkflowSchedule{
+			ID:         "sched-skip",
+			WorkflowID: "scheduler-skip",
+			Cron:       "0 * * * *",
+			Enabled:    true,
+			MissedFire: MissedFireSkip,
+			NextRunAt:  now.Add(-3 * time.Hour),
+			NextFireAt: now.Add(-3 * time.Hour),
+			CreatedAt:  now.Add(-4 * time.Hour),
+			UpdatedAt:  now.Add(-4 * time.Hour),
+		}
+		if err := store.CreateSchedule(context.Background(), schedule); err != nil {
+			t.Fatalf("CreateSchedule: %v", err)
+		}
+		scheduler, err := NewWorkflowScheduler(WorkflowSchedulerConfig{
+			Runner: srv,
+			Store:  store,
+			Now:    func() time.Time { return now },
+		})
+		if err != nil {
+			t.Fatalf("NewWorkflowScheduler: %v", err)
+		}
+		if err := scheduler.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+
+		updated, _, err := store.GetSchedule(context.Background(), "scheduler-skip", "sched-skip")
+		if err != nil {
+			t.Fatalf("GetSchedule: %v", err)
+		}
+		if updated.LastStatus != ScheduleRunStatusSkippedMissed {
+			t.Fatalf("last_status=%q, want %q", updated.LastStatus, ScheduleRunStatusSkippedMissed)
+		}
+		if updated.LastError != "skipped 4 missed run(s)" {
+			t.Fatalf("last_error=%q", updated.LastError)
+		}
+		if want := now.Add(time.Hour); !updated.NextFireAt.Equal(want) {
+			t.Fatalf("next_fire_at=%s, want %s", updated.NextFireAt, want)
+		}
+	})
+
+	t.Run("catch_up", func(t *testing.T) {
+		store := newTestSQLiteStore(t)
+		eventStore := newTestEventStore(t)
+		srv := NewServer(ServerConfig{
+			Store:         store,
+			ScheduleStore: store,
+			Providers:     hydrate.ProviderMap{},
+			ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) { return nil, nil },
+			Bus:           bus.NewMemBus(bus.MemBusConfig{}),
+			EventStore:    eventStore,
+		})
+		createWorkflowForScheduler(t, srv.Handler(), "scheduler-catch-up")
+		schedule := WorkflowSchedule{
+			ID:          "sched-catch-up",
+			WorkflowID:  "scheduler-catch-up",
+			Cron:        "0 * * * *",
+			Enabled:     true,
+			MissedFire:  MissedFireCatchUp,
+			MaxBackfill: 2,
+			NextRunAt:   now.Add(-3 * time.Hour),
+			NextFireAt:  now.Add(-3 * time.Hour),
+			CreatedAt:   now.Add(-4 * time.Hour),
+			UpdatedAt:   now.Add(-4 * time.Hour),
+		}
+		if err := store.CreateSchedule(context.Background(), schedule); err != nil {
+			t.Fatalf("CreateSchedule: %v", err)
+		}
+		scheduler, err := NewWorkflowScheduler(WorkflowSchedulerConfig{
+			Runner: srv,
+			Store:  store,
+			Now:    func() time.Time { return now },
+		})
+		if err != nil {
+			t.Fatalf("NewWorkflowScheduler: %v", err)
+		}
+		if err := scheduler.RunOnce(context.Background()); err != nil {
+			t.Fatalf("RunOnce: %v", err)
+		}
+
+		deadline := time.Now().Add(2 * time.Second)
+		for scheduler.isScheduleActive("sched-catch-up") {
+			if time.Now().After(deadline) {
+				t.Fatal("timeout waiting for catch-up runs")
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+
+		ids, err := eventStore.(runIDLister).RunIDs(context.Background())
+		if err != nil {
+			t.Fatalf("RunIDs: %v", err)
+		}
+		scheduled := map[string]bool{}
+		for _, id := range ids {
+			events, err := eventStore.List(context.Background(), id, 0, 0)
+			if err != nil {
+				t.Fatalf("eventStore.List: %v", err)
+			}
+			for _, event := range events {
+				if event.Kind == "run.started" {
+					scheduled[event.Payload["scheduled_at"].(string)] = true
+				}
+			}
+		}
+		want := []string{"2026-02-16T11:00:00Z", "2026-02-16T12:00:00Z"}
+		if len(scheduled) != len(want) || !scheduled[want[0]] || !scheduled[want[1]] {
+			t.Fatalf("scheduled runs=%v, want %v", scheduled, want)
+		}
+	})
+}
+
+func TestWorkflowScheduler_DueFiresWithinClockSkew(t *testing.T) {
+	scheduler := &WorkflowScheduler{pollInterval: 5 * time.Second, clockSkew: 30 * time.Second}
+	cs, err := parseCronSchedule("0 * * * *", "")
+	if err != nil {
+		t.Fatalf("parseCronSchedule: %v", err)
+	}
+	fireAt := time.Date(2026, 2, 16, 12, 0, 0, 0, time.UTC)
+	schedule := WorkflowSchedule{MissedFire: MissedFireSkip, NextFireAt: fireAt, NextRunAt: fireAt.Add(10 * time.Second)}
+
+	fires, missed := scheduler.dueFires(schedule, cs, fireAt.Add(40*time.Second))
+	if len(fires) != 1 || !fires[0].Equal(fireAt) || missed != 0 {
+		t.Fatalf("on time: fires=%v missed=%d", fires, missed)
+	}
+	fires, missed = scheduler.dueFires(schedule, cs, fireAt.Add(50*time.Second))
+	if len(fires) != 0 || missed != 1 {
+		t.Fatalf("late: fires=%v missed=%d", fires, missed)
+	}
+}
+
 func createWorkflowForScheduler(t *testing.T, handler http.Handler, workflowID string) {
 	t.Helper()
 	req := httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(validGraphJSON(workflowID)))
@@ -0,0 +1,291 @@
+package server
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/loader"
+)
+
+// maxSearchMatchText caps the text of a reported match, in bytes.
+const maxSearchMatchText = 200
+
+// WorkflowSearchResponse is the response of GET /api/workflows/search.
+type WorkflowSearchResponse struct {
+	Results []WorkflowSearchResult `json:"results"`
+}
+
+// WorkflowSearchResult is a workflow matching a search, with the fields
+// that matched.
+type WorkflowSearchResult struct {
+	ID        string            `json:"id"`
+	Kind      loader.SchemaKind `json:"kind"`
+	Name      string            `json:"name,omitempty"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	Matches   []SearchMatch     `json:"matches"`
+}
+
+// SearchMatch is a field of a workflow definition that matched a search.
+type SearchMatch struct {
+	// NodeID and NodeType are empty for workflow-level fields.
+	NodeID   string `json:"node_id,omitempty"`
+	NodeType string `json:"node_type,omitempty"`
+	// Field is the field's path, such as "config.url" or "metadata.team".
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+// workflowQuery is a parsed search. Text matching is case-insensitive.
+type workflowQuery struct {
+	// terms must each appear in some field of the workflow.
+	terms []string
+	// nodeType requires a node of this type.
+	nodeType string
+	// configContains requires a config value of a node, of nodeType when
+	// set, containing it.
+	configContains string
+	// tagKey and tagValue require a metadata entry. An empty tagValue
+	// accepts any value, or tagKey listed in the "tags" metadata entry.
+	tagKey, tagValue string
+}
+
+func parseWorkflowQuery(r *http.Request) (workflowQuery, error) {
+	values := r.URL.Query()
+	q := workflowQuery{
+		terms:          strings.Fields(strings.ToLower(values.Get("q"))),
+		nodeType:       strings.TrimSpace(values.Get("node_type")),
+		configContains: strings.ToLower(strings.TrimSpace(values.Get("config_contains"))),
+	}
+	if tag := strings.TrimSpace(values.Get("tag")); tag != "" {
+		q.tagKey, q.tagValue, _ = strings.Cut(tag, "=")
+	}
+	if len(q.terms) == 0 && q.nodeType == "" && q.configContains == "" && q.tagKey == "" {
+		return q, fmt.Errorf("at least one of q, node_type, config_contains or tag is required")
+	}
+	return q, nil
+}
+
+// searchField is one searchable value of a workflow definition.
+type searchField struct {
+	nodeID, nodeType string
+	field            string
+	text             string
+	// lower is text in lower case, for matching.
+	lower string
+}
+
+func (f searchField) match() SearchMatch {
+	text := f.text
+	if len(text) > maxSearchMatchText {
+		text = text[:maxSearchMatchText] + "..."
+	}
+	return SearchMatch{NodeID: f.nodeID, NodeType: f.nodeType, Field: f.field, Text: text}
+}
+
+func (f searchField) isConfig() bool {
+	return strings.HasPrefix(f.field, "config.")
+}
+
+// indexedWorkflow is the search document of a stored workflow.
+type indexedWorkflow struct {
+	id        string
+	kind      loader.SchemaKind
+	name      string
+	updatedAt time.Time
+	metadata  map[string]string
+	fields    []searchField
+}
+
+// search returns the fields matching q, or false when the workflow does
+// not match.
+func (doc *indexedWorkflow) search(q workflowQuery) ([]SearchMatch, bool) {
+	if q.tagKey != "" && !doc.hasTag(q.tagKey, q.tagValue) {
+		return nil, false
+	}
+	var matched []searchField
+	if q.nodeType != "" || q.configContains != "" {
+		for _, f := range doc.fields {
+			if f.nodeID == "" || (q.nodeType != "" && f.nodeType != q.nodeType) {
+				continue
+			}
+			if q.configContains == "" {
+				if f.field == "type" {
+					matched = append(matched, f)
+				}
+				continue
+			}
+			if f.isConfig() && strings.Contains(f.lower, q.configContains) {
+				matched = append(matched, f)
+			}
+		}
+		if len(matched) == 0 {
+			return nil, false
+		}
+	}
+	for _, term := range q.terms {
+		found := false
+		for _, f := range doc.fields {
+			if strings.Contains(f.lower, term) {
+				found = true
+				matched = append(matched, f)
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+
+	matches := make([]SearchMatch, 0, len(matched))
+	seen := make(map[string]bool, len(matched))
+	for _, f := range matched {
+		key := f.nodeID + "\x00" + f.field
+		if !seen[key] {
+			seen[key] = true
+			matches = append(matches, f.match())
+		}
+	}
+	return matches, true
+}
+
+// hasTag reports whether the workflow's metadata has key, with value when
+// it is set. Without a value, key may also be one of the comma-separated
+// "tags" entries.
+func (doc *indexedWorkflow) hasTag(key, value string) bool {
+	current, ok := doc.metadata[key]
+	if value != "" {
+		return ok && current == value
+	}
+	if ok {
+		return true
+	}
+	for _, tag := range strings.Split(doc.metadata["tags"], ",") {
+		if strings.TrimSpace(tag) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// indexWorkflow builds the search document of rec from its compiled
+// definition. Secret values are left out.
+func indexWorkflow(rec WorkflowRecord) indexedWorkflow {
+	doc := indexedWorkflow{id: rec.ID, kind: rec.SchemaKind, name: rec.Name, updatedAt: rec.UpdatedAt}
+	add := func(nodeID, nodeType, field, text string) {
+		if text == "" {
+			return
+		}
+		doc.fields = append(doc.fields, searchField{
+			nodeID:   nodeID,
+			nodeType: nodeType,
+			field:    field,
+			text:     text,
+			lower:    strings.ToLower(text),
+		})
+	}
+	add("", "", "id", rec.ID)
+	if rec.Name != rec.ID {
+		add("", "", "name", rec.Name)
+	}
+	gd := rec.Compiled
+	if gd == nil {
+		return doc
+	}
+	doc.metadata = gd.Metadata
+	for _, key := range slices.Sorted(maps.Keys(gd.Metadata)) {
+		add("", "", "metadata."+key, gd.Metadata[key])
+	}
+	for _, nd := range gd.Nodes {
+		add(nd.ID, nd.Type, "id", nd.ID)
+		add(nd.ID, nd.Type, "type", nd.Type)
+		add(nd.ID, nd.Type, "description", nd.Description)
+		indexConfigValues(nd, "config", nd.Config, add)
+	}
+	return doc
+}
+
+// indexConfigValues adds the scalar values under v, by dotted path.
+func indexConfigValues(nd graph.NodeDef, path string, v any, add func(nodeID, nodeType, field, text string)) {
+	if _, ok := hydrate.SecretValue(v); ok {
+		return
+	}
+	switch v := v.(type) {
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			indexConfigValues(nd, path+"."+key, v[key], add)
+		}
+	case []any:
+		for i, item := range v {
+			indexConfigValues(nd, fmt.Sprintf("%s[%d]", path, i), item, add)
+		}
+	case nil:
+	default:
+		add(nd.ID, nd.Type, path, fmt.Sprint(v))
+	}
+}
+
+// workflowSearchIndex keeps the search documents of stored workflows,
+// rebuilding a document only when its workflow's UpdatedAt changes.
+type workflowSearchIndex struct {
+	mu   sync.Mutex
+	docs map[string]*indexedWorkflow
+}
+
+// refresh brings the index up to date with records and returns their
+// documents in ID order.
+func (idx *workflowSearchIndex) refresh(records []WorkflowRecord) []*indexedWorkflow {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	docs := make(map[string]*indexedWorkflow, len(records))
+	out := make([]*indexedWorkflow, 0, len(records))
+	for _, rec := range records {
+		doc, ok := idx.docs[rec.ID]
+		if !ok || !doc.updatedAt.Equal(rec.UpdatedAt) {
+			indexed := indexWorkflow(rec)
+			doc = &indexed
+		}
+		docs[rec.ID] = doc
+		out = append(out, doc)
+	}
+	idx.docs = docs
+	sort.Slice(out, func(i, j int) bool { return out[i].id < out[j].id })
+	return out
+}
+
+// handleSearchWorkflows finds stored workflows by node type, config
+// values, template text and metadata.
+func (s *Server) handleSearchWorkflows(w http.ResponseWriter, r *http.Request) {
+	q, err := parseWorkflowQuery(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_QUERY", err.Error())
+		return
+	}
+	records, err := s.store.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+
+	resp := WorkflowSearchResponse{Results: []WorkflowSearchResult{}}
+	for _, doc := range s.search.refresh(records) {
+		matches, ok := doc.search(q)
+		if !ok {
+			continue
+		}
+		resp.Results = append(resp.Results, WorkflowSearchResult{
+			ID:        doc.id,
+			Kind:      doc.kind,
+			Name:      doc.name,
+			UpdatedAt: doc.updatedAt,
+			Matches:   matches,
+		})
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
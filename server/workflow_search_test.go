@@ -0,0 +1,94 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSearchWorkflows(t *testing.T) {
+	srv := NewServer(ServerConfig{Store: newTestSQLiteStore(t)})
+	handler := srv.Handler()
+
+	refunds := graphWorkflowPayloadFromParts("refunds", []map[string]any{
+		{"id": "notify", "type": "webhook_call", "config": map[string]any{
+			"url":     "https://hooks.example.com/refunds",
+			"headers": map[string]any{"Authorization": map[string]any{"secret": "refunds-token"}},
+		}},
+	}, nil, "notify")
+	refunds["metadata"] = map[string]any{"team": "payments", "tags": "finance, billing"}
+	createGraphWorkflow(t, handler, refunds)
+	createGraphWorkflow(t, handler, graphWorkflowPayloadFromParts("support", []map[string]any{
+		{"id": "answer", "type": "llm_prompt", "config": map[string]any{"provider": "openai", "prompt_template": "Explain our refunds policy to {{.customer}}"}},
+		{"id": "log", "type": "webhook_call", "config": map[string]any{"url": "https://hooks.example.com/audit"}},
+	}, []map[string]any{{"source": "answer", "target": "log"}}, "answer"))
+
+	search := func(query string) WorkflowSearchResponse {
+		t.Helper()
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/search?"+query, nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("search %q: %d %s", query, w.Code, w.Body.String())
+		}
+		var resp WorkflowSearchResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		return resp
+	}
+	ids := func(resp WorkflowSearchResponse) []string {
+		out := []string{}
+		for _, r := range resp.Results {
+			out = append(out, r.ID)
+		}
+		return out
+	}
+
+	resp := search("node_type=webhook_call&config_contains=REFUNDS")
+	if len(resp.Results) != 1 || resp.Results[0].ID != "refunds" {
+		t.Fatalf("webhook search = %+v", resp.Results)
+	}
+	if m := resp.Results[0].Matches; len(m) != 1 || m[0].NodeID != "notify" || m[0].Field != "config.url" {
+		t.Errorf("matches = %+v", m)
+	}
+
+	if got := ids(search("q=refunds")); len(got) != 2 {
+		t.Errorf("q=refunds matched %v, want both workflows", got)
+	}
+	if got := ids(search("q=refunds+customer")); len(got) != 1 || got[0] != "support" {
+		t.Errorf("q=refunds customer matched %v", got)
+	}
+	if got := ids(search("node_type=webhook_call")); len(got) != 2 {
+		t.Errorf("node_type matched %v", got)
+	}
+	if got := ids(search("config_contains=refunds-token")); len(got) != 0 {
+		t.Errorf("secret value matched %v", got)
+	}
+	for query, want := range map[string]int{"tag=team=payments": 1, "tag=billing": 1, "tag=team=support": 0} {
+		if got := ids(search(query)); len(got) != want {
+			t.Errorf("%s matched %v, want %d", query, got, want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/search", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("empty search: %d, want 400", w.Code)
+	}
+}
+
+func TestCreateWorkflow_RejectsSearchID(t *testing.T) {
+	handler := NewServer(ServerConfig{Store: newTestSQLiteStore(t)}).Handler()
+
+	body, _ := json.Marshal(graphWorkflowPayloadFromParts("search", []map[string]any{
+		{"id": "log", "type": "webhook_call", "config": map[string]any{"url": "https://hooks.example.com/audit"}},
+	}, nil, "log"))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/workflows/graph", bytes.NewReader(body)))
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "RESERVED_ID") {
+		t.Fatalf("create workflow %q: %d %s, want 422 RESERVED_ID", "search", w.Code, w.Body.String())
+	}
+}
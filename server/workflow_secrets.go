@@ -0,0 +1,154 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/tool"
+)
+
+// sealWorkflowSecrets encrypts the {"secret": "..."} values of rec's
+// source and compiled graph. A value sent back as the redacted placeholder
+// keeps the ciphertext stored at the same path in previous. The verbatim
+// source text is dropped when it holds secrets, so exports are converted
+// from the sealed source instead.
+func (s *Server) sealWorkflowSecrets(rec *WorkflowRecord, previous *WorkflowRecord) error {
+	var prevSource, prevCompiled map[string]string
+	if previous != nil {
+		prevSource = collectJSONSecrets(previous.Source)
+		prevCompiled = collectJSONSecrets(compiledJSON(previous.Compiled))
+	}
+
+	source, sealed, err := s.sealJSONSecrets(rec.Source, prevSource)
+	if err != nil {
+		return err
+	}
+	if !sealed {
+		return nil
+	}
+	rec.Source = source
+	rec.SourceText = ""
+
+	compiled, sealed, err := s.sealJSONSecrets(compiledJSON(rec.Compiled), prevCompiled)
+	if err != nil {
+		return err
+	}
+	if sealed {
+		var gd graph.GraphDefinition
+		if err := json.Unmarshal(compiled, &gd); err != nil {
+			return &runAPIError{Status: http.StatusInternalServerError, Code: "SECRET_ERROR", Message: err.Error()}
+		}
+		rec.Compiled = &gd
+	}
+	return nil
+}
+
+func (s *Server) sealJSONSecrets(raw []byte, previous map[string]string) ([]byte, bool, error) {
+	return mapJSONSecrets(raw, func(path, value string) (any, error) {
+		switch {
+		case value == tool.MaskedSecretValue:
+			stored, ok := previous[path]
+			if !ok {
+				return nil, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "INVALID_SECRET",
+					Message: fmt.Sprintf("secret %s is the redacted placeholder but no value is stored for it", path)}
+			}
+			value = stored
+		case s.secrets == nil:
+			return nil, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "SECRETS_UNAVAILABLE",
+				Message: fmt.Sprintf("secret %s cannot be stored: the daemon has no secret key", path)}
+		case hydrate.IsEncryptedSecret(value):
+			if _, err := s.secrets.Decrypt(value); err != nil {
+				return nil, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "INVALID_SECRET",
+					Message: fmt.Sprintf("secret %s was not encrypted by this daemon", path)}
+			}
+		default:
+			encrypted, err := s.secrets.Encrypt(value)
+			if err != nil {
+				return nil, &runAPIError{Status: http.StatusInternalServerError, Code: "SECRET_ERROR", Message: err.Error()}
+			}
+			value = encrypted
+		}
+		return map[string]any{hydrate.SecretValueKey: value}, nil
+	})
+}
+
+// redactWorkflowRecord returns rec with every secret value replaced by the
+// redacted placeholder, for API responses.
+func redactWorkflowRecord(rec WorkflowRecord) WorkflowRecord {
+	redact := func(string, string) (any, error) {
+		return map[string]any{hydrate.SecretValueKey: tool.MaskedSecretValue}, nil
+	}
+	source, redacted, err := mapJSONSecrets(rec.Source, redact)
+	if err != nil || !redacted {
+		return rec
+	}
+	rec.Source = source
+	rec.SourceText = ""
+
+	if compiled, redacted, err := mapJSONSecrets(compiledJSON(rec.Compiled), redact); err == nil && redacted {
+		var gd graph.GraphDefinition
+		if json.Unmarshal(compiled, &gd) == nil {
+			rec.Compiled = &gd
+		}
+	}
+	return rec
+}
+
+// openSecrets returns gd with its secret values decrypted, for hydration.
+func (s *Server) openSecrets(gd *graph.GraphDefinition) (*graph.GraphDefinition, error) {
+	opened, err := hydrate.UnwrapSecrets(gd, s.secrets)
+	if err != nil {
+		return nil, &runAPIError{Status: http.StatusUnprocessableEntity, Code: "SECRET_ERROR", Message: err.Error()}
+	}
+	return opened, nil
+}
+
+// mapJSONSecrets applies hydrate.MapSecretValues to a JSON document and
+// reports whether it held secret values.
+func mapJSONSecrets(raw []byte, fn func(path, value string) (any, error)) ([]byte, bool, error) {
+	if len(raw) == 0 {
+		return raw, false, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return raw, false, nil
+	}
+	found := false
+	mapped, err := hydrate.MapSecretValues(doc, func(path, value string) (any, error) {
+		found = true
+		return fn(path, value)
+	})
+	if err != nil || !found {
+		return raw, false, err
+	}
+	out, err := json.Marshal(mapped)
+	if err != nil {
+		return raw, false, err
+	}
+	return out, true, nil
+}
+
+// collectJSONSecrets returns the secret values of a JSON document by path.
+func collectJSONSecrets(raw []byte) map[string]string {
+	values := map[string]string{}
+	_, _, _ = mapJSONSecrets(raw, func(path, value string) (any, error) {
+		values[path] = value
+		return nil, nil
+	})
+	return values
+}
+
+// compiledJSON returns gd as JSON, or nil when it cannot be encoded.
+func compiledJSON(gd *graph.GraphDefinition) []byte {
+	if gd == nil {
+		return nil
+	}
+	data, _ := json.Marshal(gd)
+	return data
+}
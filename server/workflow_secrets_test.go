@@ -0,0 +1,121 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/tool"
+)
+
+func TestWorkflowSecrets(t *testing.T) {
+	srv := testServer(t)
+	handler := srv.Handler()
+
+	do := func(method, path string, body []byte, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, bytes.NewReader(body))
+		for i := 0; i+1 < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+	webhook := func(token string) int {
+		return do(http.MethodPost, "/api/workflows/secret-hook/webhooks/incoming", []byte(`{"event":"ping"}`),
+			"Content-Type", "application/json", "X-PetalFlow-Webhook-Token", token).Code
+	}
+
+	auth := map[string]any{"type": "header_token", "token": map[string]any{"secret": "s3cr3t"}}
+	w := do(http.MethodPost, "/api/workflows/graph", validWebhookGraphJSON("secret-hook", []string{"POST"}, auth))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d body=%s", w.Code, w.Body.String())
+	}
+	if strings.Contains(w.Body.String(), "s3cr3t") || !strings.Contains(w.Body.String(), tool.MaskedSecretValue) {
+		t.Fatalf("create response not redacted: %s", w.Body.String())
+	}
+
+	rec, _, err := srv.store.Get(context.Background(), "secret-hook")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	stored, _ := json.Marshal(rec)
+	if strings.Contains(string(stored), "s3cr3t") || !strings.Contains(string(stored), "enc:v1:") {
+		t.Fatalf("stored record not encrypted: %s", stored)
+	}
+
+	if code := webhook("s3cr3t"); code != http.StatusOK {
+		t.Fatalf("webhook with token = %d, want 200", code)
+	}
+	if code := webhook(tool.MaskedSecretValue); code != http.StatusUnauthorized {
+		t.Fatalf("webhook with placeholder = %d, want 401", code)
+	}
+
+	for _, path := range []string{"/api/workflows/secret-hook", "/api/workflows", "/api/workflows/secret-hook/export?format=yaml"} {
+		if body := do(http.MethodGet, path, nil).Body.String(); strings.Contains(body, "s3cr3t") || strings.Contains(body, "enc:v1:") {
+			t.Errorf("GET %s leaks the secret: %s", path, body)
+		}
+	}
+
+	// Saving the redacted definition back keeps the stored secret.
+	var got WorkflowRecord
+	_ = json.Unmarshal(do(http.MethodGet, "/api/workflows/secret-hook", nil).Body.Bytes(), &got)
+	if w := do(http.MethodPut, "/api/workflows/secret-hook", got.Source); w.Code != http.StatusOK {
+		t.Fatalf("update status = %d body=%s", w.Code, w.Body.String())
+	}
+	if code := webhook("s3cr3t"); code != http.StatusOK {
+		t.Fatalf("webhook after update = %d, want 200", code)
+	}
+
+	// A placeholder where no secret was stored is rejected.
+	moved := map[string]any{"type": "header_token", "header": "X-Token", "token": "plain",
+		"extra": map[string]any{"secret": tool.MaskedSecretValue}}
+	if w := do(http.MethodPut, "/api/workflows/secret-hook", validWebhookGraphJSON("secret-hook", []string{"POST"}, moved)); w.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("placeholder without stored value = %d, want 422", w.Code)
+	}
+}
+
+func TestWorkflowSecrets_YAMLSourceNotKeptVerbatim(t *testing.T) {
+	handler := testServer(t).Handler()
+	body := `id: secret-yaml
+version: "1.0"
+nodes:
+  - id: incoming
+    type: webhook_trigger
+    config:
+      auth:
+        type: header_token
+        token:
+          secret: s3cr3t # keep me out of exports
+  - id: extract_event
+    type: transform
+    config:
+      transform: template
+      template: "{{.webhook_body.event}}"
+      output_var: event_name
+edges:
+  - source: incoming
+    target: extract_event
+entry: extract_event
+`
+	r := httptest.NewRequest(http.MethodPost, "/api/workflows/graph", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/yaml")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status = %d body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/workflows/secret-yaml/export", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/yaml" {
+		t.Fatalf("export = %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if out := w.Body.String(); strings.Contains(out, "s3cr3t") || !strings.Contains(out, tool.MaskedSecretValue) {
+		t.Fatalf("export = %s", out)
+	}
+}
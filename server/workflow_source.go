@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/petal-labs/petalflow/loader"
+)
+
+// requestSourceFormat returns the format of a workflow definition body.
+// The Content-Type header decides when it names JSON or YAML; otherwise the
+// body is sniffed.
+func requestSourceFormat(r *http.Request, body []byte) loader.SourceFormat {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch strings.ToLower(mediaType) {
+	case "application/json":
+		return loader.FormatJSON
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml":
+		return loader.FormatYAML
+	default:
+		return loader.SniffFormat(body)
+	}
+}
+
+// decodeWorkflowSource converts a workflow definition body to the canonical
+// JSON representation every schema is decoded and stored from.
+func decodeWorkflowSource(r *http.Request, body []byte) ([]byte, loader.SourceFormat, error) {
+	format := requestSourceFormat(r, body)
+	canonical, err := loader.CanonicalJSON(body, format)
+	if err != nil {
+		return nil, "", err
+	}
+	return canonical, format, nil
+}
+
+// decodeWorkflowSources splits a workflow definition body into the
+// workflows it defines, each as canonical JSON. verbatim reports whether
+// the body is a single definition that can be kept as submitted; it is
+// false when documents were split off or agents_ref resolved.
+func decodeWorkflowSources(r *http.Request, body []byte) (sources [][]byte, format loader.SourceFormat, verbatim bool, err error) {
+	canonical, format, err := decodeWorkflowSource(r, body)
+	if err != nil {
+		return nil, "", false, err
+	}
+	if sources, err = loader.SplitDocuments(body, format); err != nil {
+		return nil, "", false, err
+	}
+	verbatim = len(sources) == 1 && bytes.Equal(sources[0], canonical)
+	return sources, format, verbatim, nil
+}
+
+// setWorkflowSource stores a definition on rec, keeping the submitted text
+// when it was not JSON. A nil body converts on export instead.
+func setWorkflowSource(rec *WorkflowRecord, canonical, body []byte, format loader.SourceFormat) {
+	rec.Source = canonical
+	rec.SourceFormat = ""
+	rec.SourceText = ""
+	if format != loader.FormatJSON {
+		rec.SourceFormat = format
+		rec.SourceText = string(body)
+	}
+}
+
+// handleExportWorkflow returns a workflow's definition. The format defaults
+// to the one it was submitted in, whose text is returned verbatim; asking
+// for another format converts the stored definition. Saved input presets
+// are added to the definition's presets section.
+func (s *Server) handleExportWorkflow(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec, ok, err := s.store.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+		return
+	}
+	rec = redactWorkflowRecord(rec)
+	saved, err := s.savedPresets(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if len(saved) > 0 {
+		// The verbatim text cannot carry the saved presets.
+		if rec.Source, err = exportWithSavedPresets(rec.Source, saved); err != nil {
+			writeError(w, http.StatusInternalServerError, "EXPORT_ERROR", err.Error())
+			return
+		}
+		rec.SourceText = ""
+	}
+
+	stored := rec.SourceFormat
+	if stored == "" {
+		stored = loader.FormatJSON
+	}
+	format := stored
+	if name := r.URL.Query().Get("format"); name != "" {
+		if format, err = loader.ParseSourceFormat(name); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_FORMAT", err.Error())
+			return
+		}
+	}
+
+	var body []byte
+	switch {
+	case format == stored && rec.SourceText != "":
+		body = []byte(rec.SourceText)
+	case format == loader.FormatYAML:
+		if body, err = loader.ToYAML(rec.Source); err != nil {
+			writeError(w, http.StatusInternalServerError, "EXPORT_ERROR", err.Error())
+			return
+		}
+	default:
+		body = rec.Source
+	}
+
+	contentType := "application/json"
+	if format == loader.FormatYAML {
+		contentType = "application/yaml"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+"."+string(format)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// handleWorkflowDiagram returns the compiled workflow as a Mermaid
+// flowchart, labelled with each node's description and linked to its docs.
+func (s *Server) handleWorkflowDiagram(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	rec, ok, err := s.store.Get(r.Context(), id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "STORE_ERROR", err.Error())
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", fmt.Sprintf("workflow %q not found", id))
+		return
+	}
+	if rec.Compiled == nil {
+		writeError(w, http.StatusConflict, "NOT_COMPILED", fmt.Sprintf("workflow %q has no compiled graph", id))
+		return
+	}
+	w.Header().Set("Content-Type", "text/vnd.mermaid; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte(rec.Compiled.Mermaid()))
+}
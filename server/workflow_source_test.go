@@ -0,0 +1,257 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/loader"
+)
+
+const yamlGraphSource = `# Two no-op nodes.
+id: yaml_graph
+version: "1.0"
+nodes:
+  - id: a
+    type: noop
+  - id: b
+    type: noop
+edges:
+  - source: a
+    sourceHandle: output
+    target: b
+    targetHandle: input
+entry: a
+`
+
+func workflowSourceTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	return NewServer(ServerConfig{
+		Store:     newTestSQLiteStore(t),
+		Providers: hydrate.ProviderMap{},
+		ClientFactory: func(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+			return nil, nil
+		},
+	}).Handler()
+}
+
+func doSourceRequest(handler http.Handler, method, path, contentType, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		r.Header.Set("Content-Type", contentType)
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w
+}
+
+func TestCreateGraphWorkflow_YAML(t *testing.T) {
+	handler := workflowSourceTestServer(t)
+
+	w := doSourceRequest(handler, http.MethodPost, "/api/workflows/graph", "application/yaml", yamlGraphSource)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status %d: %s", w.Code, w.Body.String())
+	}
+	var rec WorkflowRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &rec); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.SourceFormat != loader.FormatYAML || rec.Compiled == nil || rec.Compiled.Entry != "a" {
+		t.Fatalf("record = %+v", rec)
+	}
+	if !json.Valid(rec.Source) || !bytes.Contains(rec.Source, []byte(`"id":"yaml_graph"`)) {
+		t.Fatalf("source should be canonical JSON, got %s", rec.Source)
+	}
+
+	w = doSourceRequest(handler, http.MethodGet, "/api/workflows/yaml_graph/export", "", "")
+	if w.Code != http.StatusOK || w.Body.String() != yamlGraphSource {
+		t.Fatalf("export status %d:\n%s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/yaml" {
+		t.Fatalf("export content type = %q", ct)
+	}
+
+	w = doSourceRequest(handler, http.MethodGet, "/api/workflows/yaml_graph/export?format=json", "", "")
+	if w.Code != http.StatusOK || !json.Valid(w.Body.Bytes()) {
+		t.Fatalf("json export status %d: %s", w.Code, w.Body.String())
+	}
+
+	if w := doSourceRequest(handler, http.MethodGet, "/api/workflows/yaml_graph/export?format=hcl", "", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("unsupported format status %d, want 400", w.Code)
+	}
+}
+
+func TestCreateGraphWorkflow_YAMLSameValidation(t *testing.T) {
+	handler := workflowSourceTestServer(t)
+
+	invalid := strings.Replace(yamlGraphSource, "entry: a", "entry: missing", 1)
+	w := doSourceRequest(handler, http.MethodPost, "/api/workflows/graph", "", invalid)
+	if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "VALIDATION_ERROR") {
+		t.Fatalf("invalid YAML graph status %d: %s", w.Code, w.Body.String())
+	}
+
+	w = doSourceRequest(handler, http.MethodPost, "/api/workflows/graph", "application/yaml", "nodes: [unclosed")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "PARSE_ERROR") {
+		t.Fatalf("malformed YAML status %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestUpdateWorkflow_SwitchesSourceFormat(t *testing.T) {
+	handler := workflowSourceTestServer(t)
+	if w := doSourceRequest(handler, http.MethodPost, "/api/workflows/graph", "", yamlGraphSource); w.Code != http.StatusCreated {
+		t.Fatalf("create status %d: %s", w.Code, w.Body.String())
+	}
+
+	jsonSource, err := loader.CanonicalJSON([]byte(yamlGraphSource), loader.FormatYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := doSourceRequest(handler, http.MethodPut, "/api/workflows/yaml_graph", "application/json", string(jsonSource))
+	if w.Code != http.StatusOK {
+		t.Fatalf("update status %d: %s", w.Code, w.Body.String())
+	}
+	var rec WorkflowRecord
+	if err := json.Unmarshal(w.Body.Bytes(), &rec); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if rec.SourceFormat != "" {
+		t.Fatalf("source_format after JSON update = %q", rec.SourceFormat)
+	}
+
+	w = doSourceRequest(handler, http.MethodGet, "/api/workflows/yaml_graph/export", "", "")
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("export content type = %q", ct)
+	}
+	w = doSourceRequest(handler, http.MethodGet, "/api/workflows/yaml_graph/export?format=yml", "", "")
+	if !strings.Contains(w.Body.String(), "id: yaml_graph\n") || !strings.Contains(w.Body.String(), "version: \"1.0\"\n") {
+		t.Fatalf("converted YAML export:\n%s", w.Body.String())
+	}
+}
+
+func TestWorkflowDiagram(t *testing.T) {
+	handler := workflowSourceTestServer(t)
+	source := `{
+  "id": "documented",
+  "version": "1.0",
+  "nodes": [
+    {"id": "a", "type": "noop", "description": "Starts the flow", "doc_url": "https://runbooks.example.com/a"},
+    {"id": "b", "type": "noop"}
+  ],
+  "edges": [{"source": "a", "sourceHandle": "output", "target": "b", "targetHandle": "input"}],
+  "entry": "a"
+}`
+	if w := doSourceRequest(handler, http.MethodPost, "/api/workflows/graph", "application/json", source); w.Code != http.StatusCreated {
+		t.Fatalf("create status %d: %s", w.Code, w.Body.String())
+	}
+
+	w := doSourceRequest(handler, http.MethodGet, "/api/workflows/documented/diagram", "", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("diagram status %d: %s", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/vnd.mermaid") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"flowchart TD", "Starts the flow", `click n0 href "https://runbooks.example.com/a"`, `n0 -->|"output"| n1`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("diagram missing %q:\n%s", want, body)
+		}
+	}
+
+	if w := doSourceRequest(handler, http.MethodGet, "/api/workflows/nope/diagram", "", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown workflow status = %d, want 404", w.Code)
+	}
+}
+
+func TestDiagMessages_NodeDocs(t *testing.T) {
+	msgs := diagMessages([]graph.Diagnostic{
+		{Severity: graph.SeverityError, Message: "Node \"a\" is broken", Node: "a", NodeDescription: "Starts the flow", DocURL: "https://example.com/a"},
+		{Severity: graph.SeverityError, Message: "plain"},
+		{Severity: graph.SeverityWarning, Message: "ignored"},
+	})
+	want := []string{`Node "a" is broken (Starts the flow; docs: https://example.com/a)`, "plain"}
+	if len(msgs) != len(want) || msgs[0] != want[0] || msgs[1] != want[1] {
+		t.Fatalf("diagMessages = %q, want %q", msgs, want)
+	}
+}
+
+const yamlAgentsSource = `kind: agents
+id: shared
+agents:
+  writer:
+    role: Writer
+    goal: Write
+    provider: anthropic
+    model: claude-sonnet-4-6
+---
+version: "1.0"
+kind: agent_workflow
+id: draft
+name: Draft
+agents_ref: shared
+tasks:
+  draft:
+    description: Draft the post
+    agent: writer
+    expected_output: Post
+execution:
+  strategy: sequential
+  task_order: [draft]
+---
+version: "1.0"
+kind: agent_workflow
+id: edit
+name: Edit
+agents_ref: [shared]
+tasks:
+  edit:
+    description: Edit the post
+    agent: writer
+    expected_output: Post
+execution:
+  strategy: sequential
+  task_order: [edit]
+`
+
+func TestCreateAgentWorkflow_MultiDocumentYAML(t *testing.T) {
+	handler := workflowSourceTestServer(t)
+
+	w := doSourceRequest(handler, http.MethodPost, "/api/workflows/agent", "application/yaml", yamlAgentsSource)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status %d: %s", w.Code, w.Body.String())
+	}
+	var created struct {
+		Workflows []WorkflowRecord `json:"workflows"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(created.Workflows) != 2 || created.Workflows[0].ID != "draft" || created.Workflows[1].ID != "edit" {
+		t.Fatalf("created = %+v", created.Workflows)
+	}
+
+	// Each workflow exports on its own, with the shared agents resolved.
+	w = doSourceRequest(handler, http.MethodGet, "/api/workflows/edit/export", "", "")
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "role: Writer") || strings.Contains(w.Body.String(), "agents_ref") {
+		t.Fatalf("export status %d:\n%s", w.Code, w.Body.String())
+	}
+
+	// A conflict on a later workflow creates none of them.
+	second := strings.Replace(yamlAgentsSource, "id: draft", "id: draft_2", 1)
+	if w := doSourceRequest(handler, http.MethodPost, "/api/workflows/agent", "application/yaml", second); w.Code != http.StatusConflict {
+		t.Fatalf("conflicting create status %d: %s", w.Code, w.Body.String())
+	}
+	if w := doSourceRequest(handler, http.MethodGet, "/api/workflows/draft_2", "", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("draft_2 status %d, want rolled back", w.Code)
+	}
+
+	if w := doSourceRequest(handler, http.MethodPut, "/api/workflows/draft", "application/yaml", yamlAgentsSource); w.Code != http.StatusBadRequest {
+		t.Fatalf("multi-document update status %d: %s", w.Code, w.Body.String())
+	}
+}
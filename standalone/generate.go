@@ -0,0 +1,148 @@
+package standalone
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/registry"
+)
+
+// Generated file names.
+const (
+	MainFile     = "main.go"
+	BindingsFile = "bindings.go"
+	WorkflowFile = "workflow.json"
+	GoModFile    = "go.mod"
+)
+
+// GenerateOptions configure Generate.
+type GenerateOptions struct {
+	// Module, when set, adds a go.mod declaring this module path.
+	Module string
+	// PetalflowVersion is the petalflow version go.mod requires. When it
+	// is not a release version, go.mod has no requirement and `go mod
+	// tidy` picks one.
+	PetalflowVersion string
+}
+
+// Generate returns the files of a main package that runs gd: main.go,
+// which embeds workflow.json and must not be edited, and bindings.go,
+// which wires providers and tools and is meant to be edited. Callers should
+// keep an existing bindings.go when regenerating.
+func Generate(gd *graph.GraphDefinition, opts GenerateOptions) (map[string][]byte, error) {
+	workflow, err := json.MarshalIndent(gd, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding workflow: %w", err)
+	}
+	data := generateData{
+		ID:    gd.ID,
+		Tools: WorkflowTools(gd),
+	}
+	if data.ID == "" {
+		data.ID = "workflow"
+	}
+
+	files := map[string][]byte{WorkflowFile: append(workflow, '\n')}
+	for name, tmpl := range map[string]*template.Template{MainFile: mainTemplate, BindingsFile: bindingsTemplate} {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("generating %s: %w", name, err)
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("formatting %s: %w", name, err)
+		}
+		files[name] = src
+	}
+
+	if opts.Module != "" {
+		var mod strings.Builder
+		fmt.Fprintf(&mod, "module %s\n\ngo 1.24\n", opts.Module)
+		if v := opts.PetalflowVersion; strings.HasPrefix(v, "v") && strings.Count(v, ".") >= 2 {
+			fmt.Fprintf(&mod, "\nrequire github.com/petal-labs/petalflow %s\n", v)
+		}
+		files[GoModFile] = []byte(mod.String())
+	}
+	return files, nil
+}
+
+// WorkflowTools lists the tools gd's nodes invoke, sorted: the tool_name of
+// tool nodes and the types of nodes that are not built in.
+func WorkflowTools(gd *graph.GraphDefinition) []string {
+	seen := make(map[string]bool)
+	for _, nd := range gd.Nodes {
+		name := ""
+		switch {
+		case nd.Type == "tool":
+			name, _ = nd.Config["tool_name"].(string)
+		case strings.Contains(nd.Type, ".") || !registry.Global().Has(nd.Type):
+			name = nd.Type
+		}
+		if name != "" {
+			seen[name] = true
+		}
+	}
+	tools := make([]string, 0, len(seen))
+	for name := range seen {
+		tools = append(tools, name)
+	}
+	sort.Strings(tools)
+	return tools
+}
+
+type generateData struct {
+	ID    string
+	Tools []string
+}
+
+var mainTemplate = template.Must(template.New("main").Parse(`// Code generated by petalflow compile --target go. DO NOT EDIT.
+
+// This command runs the {{printf "%q" .ID}} workflow without the petalflow daemon.
+// Run it with -help for its flags.
+package main
+
+import (
+	_ "embed"
+	"os"
+
+	"github.com/petal-labs/petalflow/standalone"
+)
+
+//go:embed workflow.json
+var workflow []byte
+
+func main() {
+	os.Exit(standalone.Main(workflow, bindings()))
+}
+`))
+
+var bindingsTemplate = template.Must(template.New("bindings").Parse(`package main
+
+import (
+	"github.com/petal-labs/petalflow/standalone"
+)
+
+// bindings returns the providers and tools the workflow runs with. Edit it
+// to register tools or to supply providers another way; petalflow compile
+// does not overwrite this file.
+//
+// By default providers come from PETALFLOW_PROVIDER_<NAME>_API_KEY
+// environment variables, ~/.petalflow/config.json and -provider-key flags.
+{{- if .Tools}}
+//
+// The workflow invokes these tools, which must be registered in the
+// ToolRegistry:
+{{- range .Tools}}
+//   - {{printf "%q" .}}
+{{- end}}
+{{- end}}
+func bindings() standalone.Bindings {
+	return standalone.EnvBindings{}
+}
+`))
@@ -0,0 +1,97 @@
+package standalone
+
+import (
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/graph"
+)
+
+func testDefinition() *graph.GraphDefinition {
+	return &graph.GraphDefinition{
+		ID:      "triage",
+		Version: "1.0",
+		Nodes: []graph.NodeDef{
+			{ID: "lookup", Type: "tool", Config: map[string]any{"tool_name": "crm_lookup"}},
+			{ID: "notify", Type: "slack.post_message"},
+			{ID: "shape", Type: "transform"},
+			{ID: "lookup_again", Type: "tool", Config: map[string]any{"tool_name": "crm_lookup"}},
+		},
+		Entry: "lookup",
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	files, err := Generate(testDefinition(), GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, ok := files[GoModFile]; ok {
+		t.Error("go.mod should only be generated when a module is given")
+	}
+	for _, name := range []string{MainFile, BindingsFile} {
+		if _, err := parser.ParseFile(token.NewFileSet(), name, files[name], parser.ParseComments); err != nil {
+			t.Errorf("%s does not parse: %v\n%s", name, err, files[name])
+		}
+	}
+
+	main := string(files[MainFile])
+	if !strings.Contains(main, "DO NOT EDIT") || !strings.Contains(main, "//go:embed workflow.json") {
+		t.Errorf("main.go =\n%s", main)
+	}
+	bindings := string(files[BindingsFile])
+	if !strings.Contains(bindings, "//   - \"crm_lookup\"\n//   - \"slack.post_message\"\n") {
+		t.Errorf("bindings.go should list the workflow's tools:\n%s", bindings)
+	}
+	if !strings.Contains(string(files[WorkflowFile]), `"id": "triage"`) {
+		t.Errorf("workflow.json = %s", files[WorkflowFile])
+	}
+}
+
+func TestGenerate_QuotesNamesInComments(t *testing.T) {
+	gd := testDefinition()
+	gd.ID = "triage\nfunc init() { panic(1) }"
+	gd.Nodes[0].Config["tool_name"] = "crm\nfunc init() { panic(2) }"
+	files, err := Generate(gd, GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for _, name := range []string{MainFile, BindingsFile} {
+		if strings.Contains(string(files[name]), "\nfunc init()") {
+			t.Errorf("%s lets a name escape its comment:\n%s", name, files[name])
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), name, files[name], parser.ParseComments); err != nil {
+			t.Errorf("%s does not parse: %v\n%s", name, err, files[name])
+		}
+	}
+}
+
+func TestGenerate_GoMod(t *testing.T) {
+	files, err := Generate(testDefinition(), GenerateOptions{Module: "example.com/triage", PetalflowVersion: "v0.4.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "module example.com/triage\n\ngo 1.24\n\nrequire github.com/petal-labs/petalflow v0.4.1\n"
+	if got := string(files[GoModFile]); got != want {
+		t.Errorf("go.mod =\n%s\nwant\n%s", got, want)
+	}
+
+	files, err = Generate(testDefinition(), GenerateOptions{Module: "example.com/triage", PetalflowVersion: "dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(files[GoModFile]); strings.Contains(got, "require") {
+		t.Errorf("dev builds should leave the requirement to go mod tidy:\n%s", got)
+	}
+}
+
+func TestWorkflowTools(t *testing.T) {
+	got := WorkflowTools(testDefinition())
+	want := []string{"crm_lookup", "slack.post_message"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("WorkflowTools = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,278 @@
+// Package standalone runs one embedded workflow as its own program, without
+// the daemon or a workflow store. `petalflow compile --target go` generates
+// a main package that embeds the compiled workflow and calls Main.
+package standalone
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/llmprovider"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/runtime"
+)
+
+// Exit codes, matching `petalflow run`.
+const (
+	ExitSuccess    = 0
+	ExitValidation = 1
+	ExitRuntime    = 2
+	ExitInputParse = 4
+	ExitProvider   = 5
+	ExitTimeout    = 10
+)
+
+// defaultTimeout bounds runs when neither -timeout nor the workflow's
+// run_defaults set a timeout.
+const defaultTimeout = 5 * time.Minute
+
+// Bindings connect a standalone workflow to its LLM providers and tools.
+type Bindings interface {
+	// Providers returns the configured providers. Keys given with
+	// -provider-key are layered over them.
+	Providers() (hydrate.ProviderMap, error)
+	// NewClient creates the LLM client for a provider.
+	NewClient(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error)
+	// Tools returns the tools the workflow's tool nodes invoke. It may
+	// return nil when the workflow has none.
+	Tools(ctx context.Context) (*core.ToolRegistry, error)
+}
+
+// EnvBindings resolves providers the way `petalflow run` does, from
+// PETALFLOW_PROVIDER_* environment variables and ~/.petalflow/config.json,
+// and creates clients with the built-in provider adapters.
+type EnvBindings struct {
+	// ToolRegistry holds the tools the workflow invokes. Nil means none.
+	ToolRegistry *core.ToolRegistry
+}
+
+// Providers implements Bindings.
+func (b EnvBindings) Providers() (hydrate.ProviderMap, error) {
+	return hydrate.ResolveProviders(nil)
+}
+
+// NewClient implements Bindings.
+func (b EnvBindings) NewClient(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+	return llmprovider.NewClient(name, cfg)
+}
+
+// Tools implements Bindings.
+func (b EnvBindings) Tools(context.Context) (*core.ToolRegistry, error) {
+	return b.ToolRegistry, nil
+}
+
+// Main runs the workflow with the process's arguments and standard streams
+// and returns the exit code.
+func Main(definition []byte, bindings Bindings) int {
+	return Run(context.Background(), definition, bindings, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+}
+
+// Run parses args, runs the workflow once and writes its output variables as
+// JSON to stdout. definition is a compiled graph workflow in JSON. An input
+// of "-" for -input-file reads stdin. Human nodes are rejected, since there
+// is no one to ask.
+func Run(ctx context.Context, definition []byte, bindings Bindings, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var gd graph.GraphDefinition
+	if err := json.Unmarshal(definition, &gd); err != nil {
+		fmt.Fprintf(stderr, "Error: embedded workflow: %v\n", err)
+		return ExitValidation
+	}
+
+	name := gd.ID
+	if name == "" {
+		name = "workflow"
+	}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	input := fs.String("input", "", "Input variables as an inline JSON object")
+	inputFile := fs.String("input-file", "", "Input variables from a JSON file (- for stdin)")
+	output := fs.String("output", "", "Write the output variables to this file instead of stdout")
+	timeout := fs.Duration("timeout", 0, "Execution timeout (default: the workflow's run_defaults, else 5m)")
+	maxNodeExecutions := fs.Int("max-node-executions", 0, "Fail the run after this many node executions (0 = unlimited)")
+	var providerKeys providerKeyFlag
+	fs.Var(&providerKeys, "provider-key", "Set a provider API key as name=key (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitSuccess
+		}
+		return ExitInputParse
+	}
+
+	env, err := readInput(*input, *inputFile, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitInputParse
+	}
+
+	providers, err := bindings.Providers()
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: resolving providers: %v\n", err)
+		return ExitProvider
+	}
+	if providers == nil {
+		providers = hydrate.ProviderMap{}
+	}
+	for name, key := range providerKeys {
+		cfg := providers[name]
+		cfg.APIKey = key
+		providers[name] = cfg
+	}
+
+	tools, err := bindings.Tools(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: loading tools: %v\n", err)
+		return ExitRuntime
+	}
+	if tools == nil {
+		tools = core.NewToolRegistry()
+	}
+
+	factory := hydrate.NewLiveNodeFactory(providers, bindings.NewClient,
+		hydrate.WithToolRegistry(tools),
+		hydrate.WithHumanHandler(nodes.NewAutoRejectHandler()),
+		hydrate.WithOutputHistory(nodes.NewMemoryOutputHistory(), gd.ID),
+		hydrate.WithArmStats(nodes.NewMemoryArmStats(), gd.ID),
+		hydrate.WithStateStore(nodes.NewMemoryStateStore(), gd.ID),
+	)
+	execGraph, err := hydrate.HydrateGraph(&gd, providers, factory)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: hydrating workflow: %v\n", err)
+		return ExitValidation
+	}
+
+	defaults := runtime.DefaultRunOptions()
+	settings, err := gd.ResolveRunSettings(graph.RunSettings{
+		MaxHops:         defaults.MaxHops,
+		Concurrency:     defaults.Concurrency,
+		ContinueOnError: defaults.ContinueOnError,
+		Timeout:         defaultTimeout,
+	}, graph.RunOverrides{Timeout: *timeout, MaxNodeExecutions: *maxNodeExecutions})
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitValidation
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, settings.Timeout)
+	defer cancel()
+	opts := runtime.DefaultRunOptions()
+	opts.MaxHops = settings.MaxHops
+	opts.Concurrency = settings.Concurrency
+	opts.ContinueOnError = settings.ContinueOnError
+	opts.MaxNodeExecutions = settings.MaxNodeExecutions
+	opts.NodeVisitLimits = settings.NodeVisitLimits
+	opts.VarLifetimes = gd.VarLifetimes()
+	opts.SLA = gd.SLATargets()
+
+	result, err := runtime.NewRuntime().Run(ctx, execGraph, env, opts)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			fmt.Fprintf(stderr, "Error: execution timed out after %s\n", settings.Timeout)
+			return ExitTimeout
+		}
+		fmt.Fprintf(stderr, "Error: execution failed: %v\n", err)
+		return ExitRuntime
+	}
+
+	if err := writeOutput(result, *output, stdout); err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitRuntime
+	}
+	return ExitSuccess
+}
+
+// readInput builds the input envelope from -input or -input-file.
+func readInput(input, inputFile string, stdin io.Reader) (*core.Envelope, error) {
+	if input != "" && inputFile != "" {
+		return nil, errors.New("cannot specify both -input and -input-file")
+	}
+	var data []byte
+	switch {
+	case input != "":
+		data = []byte(input)
+	case inputFile == "-":
+		var err error
+		if data, err = io.ReadAll(stdin); err != nil {
+			return nil, fmt.Errorf("reading stdin: %w", err)
+		}
+	case inputFile != "":
+		var err error
+		if data, err = os.ReadFile(inputFile); err != nil { // #nosec G304 -- path from user CLI flag
+			return nil, fmt.Errorf("reading input file: %w", err)
+		}
+	default:
+		return core.NewEnvelope(), nil
+	}
+
+	var vars map[string]any
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("parsing input JSON: %w", err)
+	}
+	env := core.NewEnvelope()
+	for k, v := range vars {
+		env.SetVar(k, v)
+	}
+	return env, nil
+}
+
+// outputEnvelope is the JSON form of a run's result.
+type outputEnvelope struct {
+	Vars map[string]any `json:"vars"`
+}
+
+func writeOutput(env *core.Envelope, path string, stdout io.Writer) error {
+	out := outputEnvelope{Vars: env.Vars}
+	if out.Vars == nil {
+		out.Vars = map[string]any{}
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling output: %w", err)
+	}
+	data = append(data, '\n')
+	if path != "" {
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return fmt.Errorf("writing output file: %w", err)
+		}
+		return nil
+	}
+	_, err = stdout.Write(data)
+	return err
+}
+
+// providerKeyFlag collects repeated -provider-key name=key values.
+type providerKeyFlag map[string]string
+
+func (f *providerKeyFlag) String() string {
+	names := make([]string, 0, len(*f))
+	for name := range *f {
+		names = append(names, name)
+	}
+	return strings.Join(names, ",")
+}
+
+func (f *providerKeyFlag) Set(value string) error {
+	parsed, err := hydrate.ParseProviderFlags([]string{value})
+	if err != nil {
+		return err
+	}
+	if *f == nil {
+		*f = make(providerKeyFlag)
+	}
+	for name, key := range parsed {
+		(*f)[name] = key
+	}
+	return nil
+}
+
+// Ensure interface compliance at compile time.
+var _ Bindings = EnvBindings{}
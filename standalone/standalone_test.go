@@ -0,0 +1,163 @@
+package standalone
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/hydrate"
+)
+
+const greetWorkflow = `{
+  "id": "greet",
+  "version": "1.0",
+  "nodes": [
+    {"id": "shape", "type": "transform", "config": {"transform": "template", "template": "Hello {{.name}}", "output_var": "greeting"}}
+  ],
+  "edges": [],
+  "entry": "shape"
+}`
+
+const llmWorkflow = `{
+  "id": "ask",
+  "version": "1.0",
+  "nodes": [
+    {"id": "answer", "type": "llm_prompt", "config": {"provider": "openai", "model": "gpt-4o", "prompt_template": "{{.question}}", "output_key": "answer"}}
+  ],
+  "edges": [],
+  "entry": "answer"
+}`
+
+type fakeClient struct{}
+
+func (fakeClient) Complete(_ context.Context, req core.LLMRequest) (core.LLMResponse, error) {
+	return core.LLMResponse{Text: "echo: " + req.InputText, Provider: "openai", Model: req.Model}, nil
+}
+
+// fakeBindings records the provider configs clients are created with.
+type fakeBindings struct {
+	providers hydrate.ProviderMap
+	clients   map[string]hydrate.ProviderConfig
+	toolsErr  error
+}
+
+func (b *fakeBindings) Providers() (hydrate.ProviderMap, error) { return b.providers, nil }
+
+func (b *fakeBindings) NewClient(name string, cfg hydrate.ProviderConfig) (core.LLMClient, error) {
+	if b.clients == nil {
+		b.clients = make(map[string]hydrate.ProviderConfig)
+	}
+	b.clients[name] = cfg
+	return fakeClient{}, nil
+}
+
+func (b *fakeBindings) Tools(context.Context) (*core.ToolRegistry, error) { return nil, b.toolsErr }
+
+func runStandalone(t *testing.T, definition string, bindings Bindings, stdin string, args ...string) (int, map[string]any, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(context.Background(), []byte(definition), bindings, args, strings.NewReader(stdin), &stdout, &stderr)
+	var out struct {
+		Vars map[string]any `json:"vars"`
+	}
+	if code == ExitSuccess && stdout.Len() > 0 {
+		if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+			t.Fatalf("stdout is not JSON: %v\n%s", err, stdout.String())
+		}
+	}
+	return code, out.Vars, stderr.String()
+}
+
+func TestRun_Transform(t *testing.T) {
+	code, vars, stderr := runStandalone(t, greetWorkflow, &fakeBindings{}, "", "-input", `{"name":"Ada"}`)
+	if code != ExitSuccess {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if vars["greeting"] != "Hello Ada" {
+		t.Errorf("greeting = %v, want Hello Ada", vars["greeting"])
+	}
+}
+
+func TestRun_InputFile(t *testing.T) {
+	code, vars, stderr := runStandalone(t, greetWorkflow, &fakeBindings{}, `{"name":"Grace"}`, "-input-file", "-")
+	if code != ExitSuccess || vars["greeting"] != "Hello Grace" {
+		t.Fatalf("stdin input: code = %d, vars = %v, stderr = %s", code, vars, stderr)
+	}
+
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in.json")
+	out := filepath.Join(dir, "out.json")
+	if err := os.WriteFile(in, []byte(`{"name":"Linus"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	code, _, stderr = runStandalone(t, greetWorkflow, &fakeBindings{}, "", "-input-file", in, "-output", out)
+	if code != ExitSuccess {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"greeting": "Hello Linus"`) {
+		t.Errorf("output file = %s", data)
+	}
+}
+
+func TestRun_InputErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{"invalid json", []string{"-input", "{bad"}},
+		{"both inputs", []string{"-input", "{}", "-input-file", "-"}},
+		{"missing file", []string{"-input-file", "/nonexistent/in.json"}},
+		{"unknown flag", []string{"-nope"}},
+		{"bad provider key", []string{"-provider-key", "openai"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _, _ := runStandalone(t, greetWorkflow, &fakeBindings{}, "", tt.args...); code != ExitInputParse {
+				t.Errorf("exit code = %d, want %d", code, ExitInputParse)
+			}
+		})
+	}
+}
+
+func TestRun_ProviderKey(t *testing.T) {
+	bindings := &fakeBindings{providers: hydrate.ProviderMap{
+		"openai": {APIKey: "from-env", BaseURL: "https://llm.example.com"},
+	}}
+	code, vars, stderr := runStandalone(t, llmWorkflow, bindings, "",
+		"-input", `{"question":"ping"}`, "-provider-key", "openai=sk-flag")
+	if code != ExitSuccess {
+		t.Fatalf("exit code = %d, stderr = %s", code, stderr)
+	}
+	if vars["answer"] != "echo: ping" {
+		t.Errorf("answer = %v", vars["answer"])
+	}
+	if got := bindings.clients["openai"]; got.APIKey != "sk-flag" || got.BaseURL != "https://llm.example.com" {
+		t.Errorf("client config = %+v, want flag key over configured base URL", got)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	if code, _, _ := runStandalone(t, "{", &fakeBindings{}, ""); code != ExitValidation {
+		t.Errorf("invalid definition: exit code = %d, want %d", code, ExitValidation)
+	}
+	if code, _, _ := runStandalone(t, llmWorkflow, &fakeBindings{}, ""); code != ExitValidation {
+		t.Errorf("missing provider: exit code = %d, want %d", code, ExitValidation)
+	}
+	code, _, stderr := runStandalone(t, greetWorkflow, &fakeBindings{toolsErr: errors.New("no registry")}, "")
+	if code != ExitRuntime || !strings.Contains(stderr, "no registry") {
+		t.Errorf("tools error: exit code = %d, stderr = %s", code, stderr)
+	}
+	if code, _, _ := runStandalone(t, greetWorkflow, &fakeBindings{}, "", "-help"); code != ExitSuccess {
+		t.Errorf("-help: exit code = %d, want %d", code, ExitSuccess)
+	}
+}
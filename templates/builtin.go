@@ -0,0 +1,45 @@
+package templates
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+)
+
+//go:embed builtin/*.json
+var builtinFS embed.FS
+
+// Builtins returns the templates shipped with PetalFlow, sorted by ID.
+func Builtins() []Template {
+	entries, err := builtinFS.ReadDir("builtin")
+	if err != nil {
+		panic(fmt.Sprintf("templates: reading builtins: %v", err))
+	}
+	out := make([]Template, 0, len(entries))
+	for _, e := range entries {
+		data, err := builtinFS.ReadFile(path.Join("builtin", e.Name()))
+		if err != nil {
+			panic(fmt.Sprintf("templates: reading %s: %v", e.Name(), err))
+		}
+		var t Template
+		if err := json.Unmarshal(data, &t); err != nil {
+			panic(fmt.Sprintf("templates: decoding %s: %v", e.Name(), err))
+		}
+		t.Builtin = true
+		out = append(out, t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Builtin returns the built-in template with the given ID.
+func Builtin(id string) (Template, bool) {
+	for _, t := range Builtins() {
+		if t.ID == id {
+			return t, true
+		}
+	}
+	return Template{}, false
+}
@@ -0,0 +1,175 @@
+// Package templates holds parameterized workflow templates. A template is
+// a workflow definition whose string values may contain ${{ params.name }}
+// placeholders; instantiating it with parameter values yields a concrete
+// definition ready to validate and store.
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/petal-labs/petalflow/loader"
+)
+
+var (
+	idPattern          = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+	paramNamePattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	placeholderPattern = regexp.MustCompile(`\$\{\{\s*params\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+)
+
+// ErrInvalidParams is wrapped by Instantiate errors caused by the
+// parameter values.
+var ErrInvalidParams = errors.New("invalid template parameters")
+
+// Template is a parameterized workflow definition.
+type Template struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Params      []Param `json:"params,omitempty"`
+	// Definition is an agent or graph workflow definition in JSON.
+	Definition json.RawMessage `json:"definition"`
+	// Builtin marks the templates shipped with PetalFlow.
+	Builtin   bool      `json:"builtin,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
+}
+
+// Param declares a template parameter.
+type Param struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Default     string `json:"default,omitempty"`
+}
+
+// Validate checks the template's ID, parameters and definition, and that
+// every placeholder names a declared parameter.
+func (t Template) Validate() error {
+	if !idPattern.MatchString(t.ID) {
+		return fmt.Errorf("template id %q must start with a lowercase letter or digit and contain only lowercase letters, digits, '-' and '_'", t.ID)
+	}
+	declared := make(map[string]bool, len(t.Params))
+	for _, p := range t.Params {
+		if !paramNamePattern.MatchString(p.Name) {
+			return fmt.Errorf("template %q: invalid parameter name %q", t.ID, p.Name)
+		}
+		if declared[p.Name] {
+			return fmt.Errorf("template %q: duplicate parameter %q", t.ID, p.Name)
+		}
+		declared[p.Name] = true
+		if p.Required && p.Default != "" {
+			return fmt.Errorf("template %q: parameter %q is required and cannot have a default", t.ID, p.Name)
+		}
+	}
+
+	var def map[string]any
+	if err := json.Unmarshal(t.Definition, &def); err != nil || def == nil {
+		return fmt.Errorf("template %q: definition must be a JSON object", t.ID)
+	}
+	if _, err := t.Kind(); err != nil {
+		return fmt.Errorf("template %q: %w", t.ID, err)
+	}
+	for _, name := range t.Placeholders() {
+		if !declared[name] {
+			return fmt.Errorf("template %q: placeholder %q has no parameter", t.ID, name)
+		}
+	}
+	return nil
+}
+
+// Kind reports whether the definition is an agent or a graph workflow.
+func (t Template) Kind() (loader.SchemaKind, error) {
+	return loader.DetectSchema(t.Definition, "template.json")
+}
+
+// Placeholders returns the parameter names the definition refers to,
+// sorted and without duplicates.
+func (t Template) Placeholders() []string {
+	seen := map[string]bool{}
+	for _, m := range placeholderPattern.FindAllSubmatch(t.Definition, -1) {
+		seen[string(m[1])] = true
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Instantiate substitutes values into the definition and returns the
+// concrete workflow definition as JSON. Parameters without a value take
+// their default; missing required parameters and unknown names are errors
+// wrapping ErrInvalidParams. A non-empty workflowID replaces the
+// definition's id.
+func (t Template) Instantiate(values map[string]string, workflowID string) ([]byte, error) {
+	declared := make(map[string]Param, len(t.Params))
+	for _, p := range t.Params {
+		declared[p.Name] = p
+	}
+	var unknown []string
+	for name := range values {
+		if _, ok := declared[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: template %q has no parameter %s", ErrInvalidParams, t.ID, strings.Join(unknown, ", "))
+	}
+
+	resolved := make(map[string]string, len(t.Params))
+	var missing []string
+	for _, p := range t.Params {
+		v, ok := values[p.Name]
+		switch {
+		case ok && (v != "" || !p.Required):
+			resolved[p.Name] = v
+		case p.Required:
+			missing = append(missing, p.Name)
+		default:
+			resolved[p.Name] = p.Default
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: template %q requires %s", ErrInvalidParams, t.ID, strings.Join(missing, ", "))
+	}
+
+	var def map[string]any
+	if err := json.Unmarshal(t.Definition, &def); err != nil {
+		return nil, fmt.Errorf("template %q: decoding definition: %w", t.ID, err)
+	}
+	out := substitute(def, resolved).(map[string]any)
+	if workflowID != "" {
+		out["id"] = workflowID
+	}
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// substitute replaces placeholders in every string of v.
+func substitute(v any, values map[string]string) any {
+	switch v := v.(type) {
+	case string:
+		return placeholderPattern.ReplaceAllStringFunc(v, func(m string) string {
+			return values[placeholderPattern.FindStringSubmatch(m)[1]]
+		})
+	case map[string]any:
+		for k, item := range v {
+			v[k] = substitute(item, values)
+		}
+		return v
+	case []any:
+		for i, item := range v {
+			v[i] = substitute(item, values)
+		}
+		return v
+	default:
+		return v
+	}
+}
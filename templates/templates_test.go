@@ -0,0 +1,115 @@
+package templates
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/registry"
+)
+
+func TestBuiltins(t *testing.T) {
+	builtins := Builtins()
+	if len(builtins) == 0 {
+		t.Fatal("no built-in templates")
+	}
+	for _, tmpl := range builtins {
+		t.Run(tmpl.ID, func(t *testing.T) {
+			if err := tmpl.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			values := map[string]string{}
+			for _, p := range tmpl.Params {
+				if p.Required {
+					values[p.Name] = "https://example.com/" + p.Name
+				}
+			}
+			data, err := tmpl.Instantiate(values, "")
+			if err != nil {
+				t.Fatalf("Instantiate: %v", err)
+			}
+			if strings.Contains(string(data), "${{") {
+				t.Errorf("placeholders left in %s", data)
+			}
+			var gd graph.GraphDefinition
+			if err := json.Unmarshal(data, &gd); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if diags := gd.ValidateWithRegistry(registry.Global()); graph.HasErrors(diags) {
+				t.Errorf("instantiated workflow is invalid: %v", diags)
+			}
+		})
+	}
+	if _, ok := Builtin("rag-qa"); !ok {
+		t.Error("rag-qa is missing")
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	tmpl := Template{
+		ID: "notify",
+		Params: []Param{
+			{Name: "model", Default: "gpt-4o-mini"},
+			{Name: "url", Required: true},
+		},
+		Definition: json.RawMessage(`{"id":"x","nodes":[{"id":"n","type":"noop","config":{` +
+			`"model":"${{ params.model }}","url":"${{params.url}}/hook?m=${{ params.model }}","count":3}}],"edges":[]}`),
+	}
+	if err := tmpl.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	data, err := tmpl.Instantiate(map[string]string{"url": "https://hooks.example.com"}, "my-notify")
+	if err != nil {
+		t.Fatalf("Instantiate: %v", err)
+	}
+	var got struct {
+		ID    string `json:"id"`
+		Nodes []struct {
+			Config map[string]any `json:"config"`
+		} `json:"nodes"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	cfg := got.Nodes[0].Config
+	if got.ID != "my-notify" || cfg["model"] != "gpt-4o-mini" || cfg["url"] != "https://hooks.example.com/hook?m=gpt-4o-mini" || cfg["count"] != float64(3) {
+		t.Errorf("instantiated = %s", data)
+	}
+
+	for _, values := range []map[string]string{
+		{},
+		{"url": ""},
+		{"url": "https://x", "colour": "blue"},
+	} {
+		if _, err := tmpl.Instantiate(values, ""); !errors.Is(err, ErrInvalidParams) {
+			t.Errorf("Instantiate(%v) error = %v, want ErrInvalidParams", values, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	graphDef := json.RawMessage(`{"nodes":[],"edges":[],"model":"${{ params.model }}"}`)
+	tests := []struct {
+		name string
+		tmpl Template
+		want string
+	}{
+		{"bad id", Template{ID: "RAG QA", Definition: graphDef}, "template id"},
+		{"undeclared placeholder", Template{ID: "t", Definition: graphDef}, `placeholder "model"`},
+		{"duplicate param", Template{ID: "t", Params: []Param{{Name: "model"}, {Name: "model"}}, Definition: graphDef}, "duplicate parameter"},
+		{"required default", Template{ID: "t", Params: []Param{{Name: "model", Required: true, Default: "m"}}, Definition: graphDef}, "cannot have a default"},
+		{"not a workflow", Template{ID: "t", Definition: json.RawMessage(`{"foo":1}`)}, "detect schema"},
+		{"not an object", Template{ID: "t", Definition: json.RawMessage(`[]`)}, "JSON object"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.tmpl.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
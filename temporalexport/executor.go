@@ -0,0 +1,107 @@
+// Package temporalexport runs PetalFlow workflows on Temporal clusters.
+// `petalflow compile --target temporal` generates a worker package whose
+// Temporal workflow runs each node as an activity, and the activities run
+// the nodes' PetalFlow implementations through an Executor.
+package temporalexport
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/petal-labs/petalflow/core"
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/hydrate"
+	"github.com/petal-labs/petalflow/nodes"
+	"github.com/petal-labs/petalflow/runtime"
+	"github.com/petal-labs/petalflow/standalone"
+)
+
+// NodeInput is the input of a node activity.
+type NodeInput struct {
+	// RunID identifies the workflow run in the node's trace.
+	RunID string `json:"run_id"`
+	// Vars are the run's variables before the node.
+	Vars map[string]any `json:"vars"`
+}
+
+// NodeResult is the result of a node activity.
+type NodeResult struct {
+	// Vars are the run's variables after the node, with the variable
+	// mappings of the edges taken applied.
+	Vars map[string]any `json:"vars"`
+	// Next lists the nodes to run next, in order. Routers and gates narrow
+	// it to the path they chose.
+	Next []string `json:"next"`
+}
+
+// Executor runs single nodes of a hydrated workflow. It is safe for
+// concurrent use by the activities of one worker.
+type Executor struct {
+	graph *graph.BasicGraph
+}
+
+// NewExecutor hydrates definition, a compiled graph workflow in JSON, with
+// the providers and tools of bindings. Human nodes are rejected, since
+// there is no one to ask.
+func NewExecutor(ctx context.Context, definition []byte, bindings standalone.Bindings) (*Executor, error) {
+	var gd graph.GraphDefinition
+	if err := json.Unmarshal(definition, &gd); err != nil {
+		return nil, fmt.Errorf("embedded workflow: %w", err)
+	}
+	providers, err := bindings.Providers()
+	if err != nil {
+		return nil, fmt.Errorf("resolving providers: %w", err)
+	}
+	tools, err := bindings.Tools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading tools: %w", err)
+	}
+	if tools == nil {
+		tools = core.NewToolRegistry()
+	}
+
+	factory := hydrate.NewLiveNodeFactory(providers, bindings.NewClient,
+		hydrate.WithToolRegistry(tools),
+		hydrate.WithHumanHandler(nodes.NewAutoRejectHandler()),
+		hydrate.WithOutputHistory(nodes.NewMemoryOutputHistory(), gd.ID),
+		hydrate.WithArmStats(nodes.NewMemoryArmStats(), gd.ID),
+		hydrate.WithStateStore(nodes.NewMemoryStateStore(), gd.ID),
+	)
+	g, err := hydrate.HydrateGraph(&gd, providers, factory)
+	if err != nil {
+		return nil, fmt.Errorf("hydrating workflow: %w", err)
+	}
+	return &Executor{graph: g}, nil
+}
+
+// RunNode runs one node on the run's variables and reports the nodes to
+// run next.
+func (e *Executor) RunNode(ctx context.Context, nodeID string, in NodeInput) (NodeResult, error) {
+	node, ok := e.graph.NodeByID(nodeID)
+	if !ok {
+		return NodeResult{}, fmt.Errorf("%w: %s", graph.ErrNodeNotFound, nodeID)
+	}
+	env := core.NewEnvelope()
+	env.Trace.RunID = in.RunID
+	for name, value := range in.Vars {
+		env.SetVar(name, value)
+	}
+
+	result, err := node.Run(ctx, env)
+	if err != nil {
+		return NodeResult{}, err
+	}
+	next := runtime.NextNodes(e.graph, node, result)
+	for _, to := range next {
+		if err := graph.ApplyEdgeVars(result, nodeID, to, e.graph.EdgeVars(nodeID, to)); err != nil {
+			return NodeResult{}, err
+		}
+	}
+
+	vars := result.Vars
+	if vars == nil {
+		vars = map[string]any{}
+	}
+	return NodeResult{Vars: vars, Next: next}, nil
+}
@@ -0,0 +1,73 @@
+package temporalexport
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/standalone"
+)
+
+func testDefinition() *graph.GraphDefinition {
+	return &graph.GraphDefinition{
+		ID:      "triage",
+		Version: "1.0",
+		Nodes: []graph.NodeDef{
+			{ID: "route", Type: "conditional", Config: map[string]any{
+				"default":    "small",
+				"conditions": []any{map[string]any{"name": "large", "expression": "input.size > 100"}},
+			}},
+			{ID: "large", Type: "transform", Config: map[string]any{"transform": "template", "template": "big {{.label}}", "output_var": "path"}},
+			{ID: "small", Type: "transform", Config: map[string]any{"transform": "template", "template": "small", "output_var": "path"}},
+		},
+		Edges: []graph.EdgeDef{
+			{Source: "route", SourceHandle: "large", Target: "large", TargetHandle: "input", Vars: []graph.VarMapping{{From: "size", To: "label", Transform: graph.VarTransformString}}},
+			{Source: "route", SourceHandle: "small", Target: "small", TargetHandle: "input"},
+		},
+		Entry: "route",
+	}
+}
+
+func TestExecutor_RunNode(t *testing.T) {
+	definition, err := json.Marshal(testDefinition())
+	if err != nil {
+		t.Fatal(err)
+	}
+	executor, err := NewExecutor(context.Background(), definition, standalone.EnvBindings{})
+	if err != nil {
+		t.Fatalf("NewExecutor: %v", err)
+	}
+
+	result, err := executor.RunNode(context.Background(), "route", NodeInput{RunID: "run-1", Vars: map[string]any{"size": 500}})
+	if err != nil {
+		t.Fatalf("RunNode: %v", err)
+	}
+	if len(result.Next) != 1 || result.Next[0] != "large" {
+		t.Fatalf("Next = %v", result.Next)
+	}
+	if result.Vars["label"] != "500" {
+		t.Errorf("edge vars were not applied: %v", result.Vars)
+	}
+
+	// Activity results cross Temporal's JSON data converter.
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var in NodeInput
+	if err := json.Unmarshal(data, &in); err != nil {
+		t.Fatal(err)
+	}
+	result, err = executor.RunNode(context.Background(), "large", in)
+	if err != nil {
+		t.Fatalf("RunNode: %v", err)
+	}
+	if result.Vars["path"] != "big 500" || len(result.Next) != 0 {
+		t.Errorf("result = %+v", result)
+	}
+
+	if _, err := executor.RunNode(context.Background(), "missing", NodeInput{}); err == nil {
+		t.Error("expected an error for an unknown node")
+	}
+}
@@ -0,0 +1,377 @@
+package temporalexport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"github.com/petal-labs/petalflow/graph"
+	"github.com/petal-labs/petalflow/runtime"
+	"github.com/petal-labs/petalflow/standalone"
+)
+
+// Generated file names.
+const (
+	MainFile       = "main.go"
+	WorkflowGoFile = "workflow.go"
+	ActivitiesFile = "activities.go"
+	BindingsFile   = "bindings.go"
+	WorkflowFile   = "workflow.json"
+	GoModFile      = "go.mod"
+)
+
+// defaultActivityTimeout bounds each node activity when the workflow's
+// run_defaults set no timeout.
+const defaultActivityTimeout = 5 * time.Minute
+
+// GenerateOptions configure Generate.
+type GenerateOptions struct {
+	// Module, when set, adds a go.mod declaring this module path.
+	Module string
+	// PetalflowVersion is the petalflow version go.mod requires. When it
+	// is not a release version, go.mod has no requirement and `go mod
+	// tidy` picks one.
+	PetalflowVersion string
+}
+
+// Generate returns the files of a main package that runs gd as a Temporal
+// worker: main.go, workflow.go and activities.go, which must not be
+// edited, workflow.json, which they embed, and bindings.go, which wires
+// providers, tools and activity options and is meant to be edited. Callers
+// should keep an existing bindings.go when regenerating.
+//
+// The Temporal workflow follows the graph like a sequential run, with one
+// activity per node. Each activity stub delegates to the node's PetalFlow
+// implementation through an Executor and reports the nodes to run next.
+func Generate(gd *graph.GraphDefinition, opts GenerateOptions) (map[string][]byte, error) {
+	workflow, err := json.MarshalIndent(gd, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding workflow: %w", err)
+	}
+	defaults := runtime.DefaultRunOptions()
+	settings, err := gd.ResolveRunSettings(graph.RunSettings{
+		MaxHops:     defaults.MaxHops,
+		Concurrency: defaults.Concurrency,
+		Timeout:     defaultActivityTimeout,
+	}, graph.RunOverrides{})
+	if err != nil {
+		return nil, err
+	}
+
+	data := generateData{
+		ID:           gd.ID,
+		WorkflowFunc: goIdentifier(gd.ID) + "Workflow",
+		Entry:        gd.EntryNode(),
+		MaxHops:      settings.MaxHops,
+		Timeout:      durationLiteral(settings.Timeout),
+		Tools:        standalone.WorkflowTools(gd),
+	}
+	if data.ID == "" {
+		data.ID = "workflow"
+	}
+	successors := make(map[string][]string)
+	for _, edge := range gd.Edges {
+		successors[edge.Source] = append(successors[edge.Source], edge.Target)
+	}
+	methods := make(map[string]bool, len(gd.Nodes))
+	for _, nd := range gd.Nodes {
+		method := "Run" + goIdentifier(nd.ID)
+		for i := 2; methods[method]; i++ {
+			method = fmt.Sprintf("Run%s%d", goIdentifier(nd.ID), i)
+		}
+		methods[method] = true
+		data.Nodes = append(data.Nodes, generateNode{
+			ID:         nd.ID,
+			Type:       nd.Type,
+			Method:     method,
+			Activity:   data.ID + "." + nd.ID,
+			Successors: successors[nd.ID],
+		})
+	}
+
+	files := map[string][]byte{WorkflowFile: append(workflow, '\n')}
+	for name, tmpl := range map[string]*template.Template{
+		MainFile:       mainTemplate,
+		WorkflowGoFile: workflowTemplate,
+		ActivitiesFile: activitiesTemplate,
+		BindingsFile:   bindingsTemplate,
+	} {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("generating %s: %w", name, err)
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("formatting %s: %w", name, err)
+		}
+		files[name] = src
+	}
+
+	if opts.Module != "" {
+		var mod strings.Builder
+		fmt.Fprintf(&mod, "module %s\n\ngo 1.24\n", opts.Module)
+		if v := opts.PetalflowVersion; strings.HasPrefix(v, "v") && strings.Count(v, ".") >= 2 {
+			fmt.Fprintf(&mod, "\nrequire github.com/petal-labs/petalflow %s\n", v)
+		}
+		files[GoModFile] = []byte(mod.String())
+	}
+	return files, nil
+}
+
+type generateData struct {
+	ID           string
+	WorkflowFunc string
+	Entry        string
+	MaxHops      int
+	Timeout      string
+	Nodes        []generateNode
+	Tools        []string
+}
+
+type generateNode struct {
+	ID         string
+	Type       string
+	Method     string
+	Activity   string
+	Successors []string
+}
+
+// goIdentifier turns an ID such as "fetch-weather_2" into the exported
+// identifier part "FetchWeather2".
+func goIdentifier(id string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range id {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// durationLiteral formats d as a Go expression of the time package.
+func durationLiteral(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%d * time.Hour", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%d * time.Minute", d/time.Minute)
+	case d%time.Second == 0:
+		return fmt.Sprintf("%d * time.Second", d/time.Second)
+	default:
+		return fmt.Sprintf("%d * time.Millisecond", d/time.Millisecond)
+	}
+}
+
+// funcs quote names taken from the definition, so that none can end the
+// comment it appears in.
+var funcs = template.FuncMap{"quoteJoin": func(s []string) string {
+	quoted := make([]string, len(s))
+	for i, v := range s {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}}
+
+var mainTemplate = template.Must(template.New("main").Parse(`// Code generated by petalflow compile --target temporal. DO NOT EDIT.
+
+// This command is a Temporal worker for the {{printf "%q" .ID}} workflow.
+// Run it with -help for its flags.
+package main
+
+import (
+	"context"
+	_ "embed"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/petal-labs/petalflow/temporalexport"
+)
+
+//go:embed workflow.json
+var definition []byte
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	address := flag.String("address", envOr("TEMPORAL_ADDRESS", client.DefaultHostPort), "Temporal frontend address")
+	namespace := flag.String("namespace", envOr("TEMPORAL_NAMESPACE", client.DefaultNamespace), "Temporal namespace")
+	taskQueue := flag.String("task-queue", {{printf "%q" .ID}}, "Task queue the worker polls")
+	flag.Parse()
+
+	executor, err := temporalexport.NewExecutor(context.Background(), definition, bindings())
+	if err != nil {
+		return err
+	}
+	c, err := client.Dial(client.Options{HostPort: *address, Namespace: *namespace})
+	if err != nil {
+		return fmt.Errorf("connecting to Temporal: %w", err)
+	}
+	defer c.Close()
+
+	w := worker.New(c, *taskQueue, worker.Options{})
+	w.RegisterWorkflowWithOptions({{.WorkflowFunc}}, workflow.RegisterOptions{Name: WorkflowName})
+	registerActivities(w, &Activities{Executor: executor})
+	return w.Run(worker.InterruptCh())
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+`))
+
+var workflowTemplate = template.Must(template.New("workflow").Parse(`// Code generated by petalflow compile --target temporal. DO NOT EDIT.
+
+package main
+
+import (
+	"fmt"
+
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/petal-labs/petalflow/temporalexport"
+)
+
+// WorkflowName is the name the workflow is registered and started under.
+const WorkflowName = {{printf "%q" .ID}}
+
+// entry is the node the workflow starts at.
+const entry = {{printf "%q" .Entry}}
+
+// maxHops bounds how often one node runs, like the run's max_hops.
+const maxHops = {{.MaxHops}}
+
+// activityNames maps node IDs to the activities that run them.
+var activityNames = map[string]string{
+{{- range .Nodes}}
+	{{printf "%q" .ID}}: {{printf "%q" .Activity}},
+{{- end}}
+}
+
+// {{.WorkflowFunc}} runs the workflow one node at a time, like a sequential
+// petalflow run. Its input and result are the run's variables. Each node
+// is an activity that reports the nodes to run next, so routers and gates
+// choose the path.
+func {{.WorkflowFunc}}(ctx workflow.Context, input map[string]any) (map[string]any, error) {
+	ctx = workflow.WithActivityOptions(ctx, activityOptions())
+	runID := workflow.GetInfo(ctx).WorkflowExecution.RunID
+
+	vars := input
+	queue := []string{entry}
+	visits := make(map[string]int)
+	for len(queue) > 0 {
+		nodeID := queue[0]
+		queue = queue[1:]
+		if visits[nodeID] >= maxHops {
+			continue
+		}
+		visits[nodeID]++
+
+		var result temporalexport.NodeResult
+		in := temporalexport.NodeInput{RunID: runID, Vars: vars}
+		if err := workflow.ExecuteActivity(ctx, activityNames[nodeID], in).Get(ctx, &result); err != nil {
+			return vars, fmt.Errorf("node %s: %w", nodeID, err)
+		}
+		vars = result.Vars
+		queue = append(queue, result.Next...)
+	}
+	return vars, nil
+}
+`))
+
+var activitiesTemplate = template.Must(template.New("activities").Funcs(funcs).Parse(`// Code generated by petalflow compile --target temporal. DO NOT EDIT.
+
+package main
+
+import (
+	"context"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/worker"
+
+	"github.com/petal-labs/petalflow/temporalexport"
+)
+
+// Activities run the workflow's nodes with their PetalFlow implementations.
+type Activities struct {
+	Executor *temporalexport.Executor
+}
+{{range .Nodes}}
+// {{.Method}} runs node {{printf "%q" .ID}} (type {{printf "%q" .Type}}).
+{{- if .Successors}} Its successors are {{quoteJoin .Successors}}.{{end}}
+func (a *Activities) {{.Method}}(ctx context.Context, in temporalexport.NodeInput) (temporalexport.NodeResult, error) {
+	return a.Executor.RunNode(ctx, {{printf "%q" .ID}}, in)
+}
+{{end}}
+// registerActivities registers the activity of every node with w.
+func registerActivities(w worker.Worker, a *Activities) {
+{{- range .Nodes}}
+	w.RegisterActivityWithOptions(a.{{.Method}}, activity.RegisterOptions{Name: {{printf "%q" .Activity}}})
+{{- end}}
+}
+`))
+
+var bindingsTemplate = template.Must(template.New("bindings").Parse(`package main
+
+import (
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/petal-labs/petalflow/standalone"
+)
+
+// bindings returns the providers and tools the node activities run with.
+// Edit it to register tools or to supply providers another way; petalflow
+// compile does not overwrite this file.
+//
+// By default providers come from PETALFLOW_PROVIDER_<NAME>_API_KEY
+// environment variables and ~/.petalflow/config.json.
+{{- if .Tools}}
+//
+// The workflow invokes these tools, which must be registered in the
+// ToolRegistry:
+{{- range .Tools}}
+//   - {{printf "%q" .}}
+{{- end}}
+{{- end}}
+func bindings() standalone.Bindings {
+	return standalone.EnvBindings{}
+}
+
+// activityOptions returns the options of every node activity. Nodes retry
+// their own provider and tool calls, so Temporal does not retry them
+// unless the policy here says so.
+func activityOptions() workflow.ActivityOptions {
+	return workflow.ActivityOptions{
+		StartToCloseTimeout: {{.Timeout}},
+		RetryPolicy:         &temporal.RetryPolicy{MaximumAttempts: 1},
+	}
+}
+`))
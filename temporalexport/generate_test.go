@@ -0,0 +1,81 @@
+package temporalexport
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/petal-labs/petalflow/graph"
+)
+
+func TestGenerate(t *testing.T) {
+	gd := testDefinition()
+	gd.Nodes = append(gd.Nodes,
+		graph.NodeDef{ID: "notify-team", Type: "tool", Config: map[string]any{"tool_name": "slack_post"}},
+		graph.NodeDef{ID: "notify_team", Type: "noop"},
+	)
+	gd.RunDefaults = &graph.RunDefaults{Timeout: "90s"}
+	files, err := Generate(gd, GenerateOptions{Module: "example.com/triage", PetalflowVersion: "v0.4.1"})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for _, name := range []string{MainFile, WorkflowGoFile, ActivitiesFile, BindingsFile} {
+		if _, err := parser.ParseFile(token.NewFileSet(), name, files[name], parser.ParseComments); err != nil {
+			t.Errorf("%s does not parse: %v\n%s", name, err, files[name])
+		}
+	}
+
+	workflow := string(files[WorkflowGoFile])
+	for _, want := range []string{
+		"DO NOT EDIT",
+		"func TriageWorkflow(ctx workflow.Context, input map[string]any) (map[string]any, error)",
+		`const entry = "route"`,
+		`"triage.large",`,
+	} {
+		if !strings.Contains(workflow, want) {
+			t.Errorf("workflow.go missing %q:\n%s", want, workflow)
+		}
+	}
+	activities := string(files[ActivitiesFile])
+	for _, want := range []string{
+		`// RunRoute runs node "route" (type "conditional"). Its successors are "large", "small".`,
+		`return a.Executor.RunNode(ctx, "notify-team", in)`,
+		"func (a *Activities) RunNotifyTeam2(",
+		`w.RegisterActivityWithOptions(a.RunSmall, activity.RegisterOptions{Name: "triage.small"})`,
+	} {
+		if !strings.Contains(activities, want) {
+			t.Errorf("activities.go missing %q:\n%s", want, activities)
+		}
+	}
+	bindings := string(files[BindingsFile])
+	if !strings.Contains(bindings, "StartToCloseTimeout: 90 * time.Second") || !strings.Contains(bindings, "//   - \"slack_post\"\n") {
+		t.Errorf("bindings.go =\n%s", bindings)
+	}
+	if !strings.Contains(string(files[MainFile]), "w.RegisterWorkflowWithOptions(TriageWorkflow, workflow.RegisterOptions{Name: WorkflowName})") {
+		t.Errorf("main.go =\n%s", files[MainFile])
+	}
+	if !strings.Contains(string(files[GoModFile]), "require github.com/petal-labs/petalflow v0.4.1") {
+		t.Errorf("go.mod = %s", files[GoModFile])
+	}
+}
+
+func TestGenerate_QuotesNamesInComments(t *testing.T) {
+	gd := testDefinition()
+	gd.Nodes = append(gd.Nodes,
+		graph.NodeDef{ID: "post\nfunc init() { panic(1) }", Type: "tool\nfunc init() { panic(2) }", Config: map[string]any{"tool_name": "crm\nfunc init() { panic(3) }"}},
+	)
+	gd.Edges = append(gd.Edges, graph.EdgeDef{Source: "small", Target: gd.Nodes[len(gd.Nodes)-1].ID})
+	files, err := Generate(gd, GenerateOptions{})
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	for _, name := range []string{MainFile, WorkflowGoFile, ActivitiesFile, BindingsFile} {
+		if strings.Contains(string(files[name]), "\nfunc init()") {
+			t.Errorf("%s lets a name escape its comment:\n%s", name, files[name])
+		}
+		if _, err := parser.ParseFile(token.NewFileSet(), name, files[name], parser.ParseComments); err != nil {
+			t.Errorf("%s does not parse: %v\n%s", name, err, files[name])
+		}
+	}
+}
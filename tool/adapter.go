@@ -20,6 +20,10 @@ type InvokeRequest struct {
 	Inputs    map[string]any `json:"inputs,omitempty"`
 	Config    map[string]any `json:"config,omitempty"`
 	RequestID string         `json:"request_id,omitempty"`
+	// CallbackToken is the secret an asynchronous action's job posts its
+	// result to, at POST /api/tool-callbacks/{token}. It is empty when
+	// results cannot be delivered by callback.
+	CallbackToken string `json:"callback_token,omitempty"`
 }
 
 // InvokeResponse is the transport-agnostic invocation result.
@@ -87,6 +87,21 @@ func decodeInvokeResponse(raw []byte, fallbackDuration int64) (InvokeResponse, e
 	return resp, nil
 }
 
+// DecodeCallbackResult decodes the body of an asynchronous tool's
+// completion callback. It accepts the shapes of an HTTP invoke response:
+// {"outputs": {...}}, {"error": {...}}, which is returned as a *ToolError,
+// or a bare outputs object.
+func DecodeCallbackResult(raw []byte) (map[string]any, error) {
+	resp, err := decodeInvokeResponse(raw, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Outputs == nil {
+		return map[string]any{}, nil
+	}
+	return resp.Outputs, nil
+}
+
 func decodeToolError(obj map[string]any) error {
 	code, _ := obj["code"].(string)
 	message, _ := obj["message"].(string)
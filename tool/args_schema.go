@@ -0,0 +1,66 @@
+package tool
+
+import "sort"
+
+// InputsJSONSchema converts an action's input field specs into a JSON Schema
+// object describing its arguments. Required fields without a default are
+// listed under "required"; fields typed "any" accept every value. It returns
+// nil when the action declares no inputs.
+func InputsJSONSchema(inputs map[string]FieldSpec) map[string]any {
+	if len(inputs) == 0 {
+		return nil
+	}
+	return objectJSONSchema(inputs)
+}
+
+func objectJSONSchema(properties map[string]FieldSpec) map[string]any {
+	props := make(map[string]any, len(properties))
+	var required []string
+	for name, spec := range properties {
+		props[name] = fieldJSONSchema(spec)
+		if spec.Required && spec.Default == nil {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		sort.Strings(required)
+		list := make([]any, len(required))
+		for i, name := range required {
+			list[i] = name
+		}
+		schema["required"] = list
+	}
+	return schema
+}
+
+func fieldJSONSchema(spec FieldSpec) map[string]any {
+	var schema map[string]any
+	switch spec.Type {
+	case TypeString, TypeBytes:
+		schema = map[string]any{"type": "string"}
+	case TypeInteger:
+		schema = map[string]any{"type": "integer"}
+	case TypeFloat:
+		schema = map[string]any{"type": "number"}
+	case TypeBoolean:
+		schema = map[string]any{"type": "boolean"}
+	case TypeArray:
+		schema = map[string]any{"type": "array"}
+		if spec.Items != nil {
+			schema["items"] = fieldJSONSchema(*spec.Items)
+		}
+	case TypeObject:
+		if len(spec.Properties) > 0 {
+			schema = objectJSONSchema(spec.Properties)
+		} else {
+			schema = map[string]any{"type": "object"}
+		}
+	default:
+		schema = map[string]any{}
+	}
+	if spec.Description != "" {
+		schema["description"] = spec.Description
+	}
+	return schema
+}
@@ -0,0 +1,40 @@
+package tool
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInputsJSONSchema(t *testing.T) {
+	if got := InputsJSONSchema(nil); got != nil {
+		t.Fatalf("no inputs: got %v, want nil", got)
+	}
+
+	schema := InputsJSONSchema(map[string]FieldSpec{
+		"bucket": {Type: TypeString, Required: true},
+		"limit":  {Type: TypeInteger, Required: true, Default: 10},
+		"ratio":  {Type: TypeFloat},
+		"tags":   {Type: TypeArray, Items: &FieldSpec{Type: TypeString}},
+		"filter": {Type: TypeObject, Properties: map[string]FieldSpec{
+			"prefix": {Type: TypeString, Required: true},
+		}},
+		"extra": {Type: TypeAny},
+	})
+
+	if !reflect.DeepEqual(schema["required"], []any{"bucket"}) {
+		t.Errorf("required = %v, want [bucket] (limit has a default)", schema["required"])
+	}
+	props := schema["properties"].(map[string]any)
+	if got := props["ratio"].(map[string]any)["type"]; got != "number" {
+		t.Errorf("float maps to %v, want number", got)
+	}
+	if got := props["tags"].(map[string]any)["items"]; !reflect.DeepEqual(got, map[string]any{"type": "string"}) {
+		t.Errorf("array items = %v", got)
+	}
+	if got := props["filter"].(map[string]any)["required"]; !reflect.DeepEqual(got, []any{"prefix"}) {
+		t.Errorf("nested required = %v", got)
+	}
+	if got := props["extra"]; !reflect.DeepEqual(got, map[string]any{}) {
+		t.Errorf("any maps to %v, want empty schema", got)
+	}
+}
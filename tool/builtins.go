@@ -9,6 +9,8 @@ import (
 	"slices"
 	"strings"
 	"text/template"
+
+	"github.com/petal-labs/petalflow/propagate"
 )
 
 var builtinNativeTools = map[string]NativeTool{
@@ -192,6 +194,7 @@ func (httpFetchTool) Invoke(ctx context.Context, action string, inputs map[strin
 	if auth, ok := config["authorization"].(string); ok && strings.TrimSpace(auth) != "" {
 		req.Header.Set("Authorization", auth)
 	}
+	propagate.SetHeaders(ctx, req.Header)
 
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
@@ -11,6 +11,9 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/petal-labs/petalflow/propagate"
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 // HTTPAdapter is the runtime adapter for HTTP-backed tools.
@@ -55,6 +58,9 @@ func (a *HTTPAdapter) Invoke(ctx context.Context, req InvokeRequest) (InvokeResp
 		"transport":   string(a.reg.Manifest.Transport.Type),
 		"tool_origin": string(a.reg.Origin),
 	}
+	if req.CallbackToken != "" {
+		payload["callback_token"] = req.CallbackToken
+	}
 
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -72,6 +78,10 @@ func (a *HTTPAdapter) Invoke(ctx context.Context, req InvokeRequest) (InvokeResp
 		action:    req.Action,
 		transport: a.reg.Manifest.Transport.Type,
 	}, func(attemptCtx context.Context, attempt int) (InvokeResponse, error) {
+		// The client enforces the manifest timeout; this only records when
+		// the run deadline cuts it short.
+		attemptCtx, cancel := runtime.WithCallTimeout(attemptCtx, timeoutFromRegistration(a.reg))
+		defer cancel()
 		start := time.Now()
 		httpReq, err := http.NewRequestWithContext(attemptCtx, http.MethodPost, endpoint, bytes.NewReader(body))
 		if err != nil {
@@ -84,6 +94,7 @@ func (a *HTTPAdapter) Invoke(ctx context.Context, req InvokeRequest) (InvokeResp
 		}
 		httpReq.Header.Set("Content-Type", "application/json")
 		httpReq.Header.Set("Accept", "application/json")
+		propagate.SetHeaders(attemptCtx, httpReq.Header)
 
 		resp, err := a.client.Do(httpReq)
 		if err != nil {
@@ -7,6 +7,10 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/petal-labs/petalflow/propagate"
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 func TestHTTPAdapterInvoke(t *testing.T) {
@@ -33,6 +37,9 @@ func TestHTTPAdapterInvoke(t *testing.T) {
 			if payload["action"] != "echo" {
 				t.Fatalf("payload action = %v, want echo", payload["action"])
 			}
+			if payload["callback_token"] != "cb-secret" {
+				t.Fatalf("payload callback_token = %v, want cb-secret", payload["callback_token"])
+			}
 
 			return &http.Response{
 				StatusCode: http.StatusOK,
@@ -48,6 +55,7 @@ func TestHTTPAdapterInvoke(t *testing.T) {
 		Inputs: map[string]any{
 			"value": "hello",
 		},
+		CallbackToken: "cb-secret",
 	})
 	if err != nil {
 		t.Fatalf("Invoke() error = %v", err)
@@ -60,6 +68,76 @@ func TestHTTPAdapterInvoke(t *testing.T) {
 	}
 }
 
+func TestHTTPAdapterInvokePropagatesRunHeaders(t *testing.T) {
+	reg := ToolRegistration{
+		Name:     "echo_http",
+		Origin:   OriginHTTP,
+		Manifest: NewManifest("echo_http"),
+	}
+	reg.Manifest.Transport = NewHTTPTransport(HTTPTransport{
+		Endpoint: "http://unit-test.local/echo",
+	})
+
+	var header http.Header
+	adapter := NewHTTPAdapter(reg)
+	adapter.client = &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			header = r.Header
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"outputs":{}}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	ctx := propagate.WithNode(context.Background(), "run-1", "echo")
+	ctx = propagate.WithConfig(ctx, propagate.Config{Enabled: true})
+	if _, err := adapter.Invoke(ctx, InvokeRequest{ToolName: "echo_http", Action: "echo"}); err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if header.Get(propagate.HeaderRunID) != "run-1" || header.Get(propagate.HeaderNodeID) != "echo" || header.Get(propagate.HeaderTraceParent) == "" {
+		t.Fatalf("request headers = %v", header)
+	}
+}
+
+func TestHTTPAdapterInvokeReportsClampedTimeout(t *testing.T) {
+	reg := ToolRegistration{
+		Name:     "echo_http",
+		Origin:   OriginHTTP,
+		Manifest: NewManifest("echo_http"),
+	}
+	reg.Manifest.Transport = NewHTTPTransport(HTTPTransport{
+		Endpoint:  "http://unit-test.local/echo",
+		TimeoutMS: 60000,
+	})
+
+	adapter := NewHTTPAdapter(reg)
+	adapter.client = &http.Client{
+		Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Body:       io.NopCloser(strings.NewReader(`{"outputs":{}}`)),
+				Header:     make(http.Header),
+			}, nil
+		}),
+	}
+
+	var events []runtime.Event
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	ctx = runtime.ContextWithEmitter(ctx, func(e runtime.Event) { events = append(events, e) })
+	if _, err := adapter.Invoke(ctx, InvokeRequest{ToolName: "echo_http", Action: "echo"}); err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != runtime.EventNodeDeadlineClamped {
+		t.Fatalf("events = %+v, want one %s", events, runtime.EventNodeDeadlineClamped)
+	}
+	if events[0].Payload["configured_timeout_ms"] != int64(60000) {
+		t.Errorf("configured_timeout_ms = %v, want 60000", events[0].Payload["configured_timeout_ms"])
+	}
+}
+
 func TestHTTPAdapterInvokeStatusError(t *testing.T) {
 	reg := ToolRegistration{
 		Name:     "echo_http",
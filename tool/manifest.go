@@ -63,6 +63,31 @@ type ActionSpec struct {
 	Outputs     map[string]FieldSpec `json:"outputs,omitempty"`
 	LLMCallable *bool                `json:"llm_callable,omitempty"`
 	Idempotent  bool                 `json:"idempotent,omitempty"`
+	Async       *AsyncSpec           `json:"async,omitempty"`
+	Cache       *CacheSpec           `json:"cache,omitempty"`
+}
+
+// CacheSpec marks an action's responses as cacheable: invoking it again
+// with the same arguments within TTLMS returns the same outputs, so tool
+// nodes answer repeat invocations from the shared tool cache. Only
+// idempotent, synchronous actions may be cached.
+type CacheSpec struct {
+	TTLMS int `json:"ttl_ms"`
+	// VaryOn lists the inputs that make up the cache key. By default every
+	// argument does; listing a subset lets arguments that do not change
+	// the outputs, such as a request ID, share entries.
+	VaryOn []string `json:"vary_on,omitempty"`
+}
+
+// AsyncSpec marks an action whose invocation may answer before its job is
+// done, with {"_pending": {"token": "..."}} in place of outputs. The
+// result arrives later through POST /api/tool-callbacks/{callback_token},
+// with the InvokeRequest.CallbackToken the invocation was sent, or, when
+// PollAction is set, by invoking that action with {"token": token}.
+type AsyncSpec struct {
+	PollAction     string `json:"poll_action,omitempty"`
+	PollIntervalMS int    `json:"poll_interval_ms,omitempty"`
+	TimeoutMS      int    `json:"timeout_ms,omitempty"`
 }
 
 // FieldSpec is the v1 field/type descriptor used for inputs, outputs, and config.
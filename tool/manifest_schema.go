@@ -323,6 +323,57 @@ func (v *manifestSchemaValidator) validateActions(actions map[string]any, path s
 			continue
 		}
 		v.validateAction(actionObj, actionPath)
+		if async, ok := actionObj["async"].(map[string]any); ok {
+			if poll, ok := async["poll_action"].(string); ok && poll != "" {
+				if _, exists := actions[poll]; !exists {
+					v.add(actionPath+".async.poll_action", "UNKNOWN_ACTION", fmt.Sprintf("action %q is not defined", poll))
+				}
+			}
+		}
+		if _, ok := actionObj["cache"]; ok {
+			v.validateCache(actionObj, actionPath)
+		}
+	}
+}
+
+// validateCache checks an action's cache declaration. Cached responses are
+// replayed without invoking the tool, so only idempotent actions that
+// answer synchronously may declare one.
+func (v *manifestSchemaValidator) validateCache(action map[string]any, path string) {
+	cache, ok := action["cache"].(map[string]any)
+	if !ok {
+		v.add(path+".cache", "TYPE", "must be an object")
+		return
+	}
+	if ttl, ok := asInteger(cache["ttl_ms"]); !ok || ttl <= 0 {
+		v.add(path+".cache.ttl_ms", "TYPE", "must be a positive integer")
+	}
+	if idempotent, _ := action["idempotent"].(bool); !idempotent {
+		v.add(path+".cache", "NOT_IDEMPOTENT", "only idempotent actions may be cached")
+	}
+	if _, async := action["async"]; async {
+		v.add(path+".cache", "ASYNC_NOT_CACHEABLE", "asynchronous actions may not be cached")
+	}
+
+	value, ok := cache["vary_on"]
+	if !ok {
+		return
+	}
+	names, ok := value.([]any)
+	if !ok {
+		v.add(path+".cache.vary_on", "TYPE", "must be an array of input names")
+		return
+	}
+	inputs, _ := action["inputs"].(map[string]any)
+	for i, raw := range names {
+		name, ok := raw.(string)
+		if !ok {
+			v.add(fmt.Sprintf("%s.cache.vary_on[%d]", path, i), "TYPE", "must be a string")
+			continue
+		}
+		if _, declared := inputs[name]; !declared {
+			v.add(fmt.Sprintf("%s.cache.vary_on[%d]", path, i), "UNKNOWN_INPUT", fmt.Sprintf("input %q is not declared", name))
+		}
 	}
 }
 
@@ -338,6 +389,15 @@ func (v *manifestSchemaValidator) validateAction(action map[string]any, path str
 		}
 	}
 
+	if value, ok := action["async"]; ok {
+		async, ok := value.(map[string]any)
+		if !ok {
+			v.add(path+".async", "TYPE", "must be an object")
+		} else {
+			v.validateAsync(async, path+".async")
+		}
+	}
+
 	if value, ok := action["inputs"]; ok {
 		inputs, ok := value.(map[string]any)
 		if !ok {
@@ -356,6 +416,17 @@ func (v *manifestSchemaValidator) validateAction(action map[string]any, path str
 	}
 }
 
+func (v *manifestSchemaValidator) validateAsync(async map[string]any, path string) {
+	v.optionalString(async, "poll_action", path+".poll_action")
+	for _, key := range []string{"poll_interval_ms", "timeout_ms"} {
+		if value, ok := async[key]; ok {
+			if _, ok := asNonNegativeInt(value); !ok {
+				v.add(path+"."+key, "TYPE", "must be a non-negative integer")
+			}
+		}
+	}
+}
+
 func (v *manifestSchemaValidator) validateFieldMap(fields map[string]any, path string) {
 	for key, rawSpec := range fields {
 		specPath := path + "." + key
@@ -97,6 +97,70 @@ func TestValidateManifestJSONFieldLevelErrors(t *testing.T) {
 	}
 }
 
+func TestValidateManifestJSONAsyncActions(t *testing.T) {
+	manifest := []byte(`{
+	  "manifest_version": "1.0",
+	  "tool": { "name": "exporter" },
+	  "transport": { "type": "http", "endpoint": "http://localhost:9801" },
+	  "actions": {
+		"export": { "async": { "poll_action": "status", "poll_interval_ms": 2000, "timeout_ms": 600000 } },
+		"status": { "inputs": { "token": { "type": "string", "required": true } } },
+		"purge": { "async": { "poll_action": "state", "timeout_ms": -1 } }
+	  }
+	}`)
+
+	fields := diagnosticFields(ValidateManifestJSON(manifest).Diagnostics)
+	want := []string{"actions.purge.async.poll_action", "actions.purge.async.timeout_ms"}
+	if !slices.Equal(slices.Sorted(slices.Values(fields)), want) {
+		t.Fatalf("error fields = %v, want %v", fields, want)
+	}
+
+	var parsed ToolManifest
+	if err := json.Unmarshal(manifest, &parsed); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if async := parsed.Actions["export"].Async; async == nil || async.PollAction != "status" || async.PollIntervalMS != 2000 {
+		t.Fatalf("export async = %+v", async)
+	}
+}
+
+func TestValidateManifestJSONCacheableActions(t *testing.T) {
+	manifest := []byte(`{
+	  "manifest_version": "1.0",
+	  "tool": { "name": "geo" },
+	  "transport": { "type": "http", "endpoint": "http://localhost:9801" },
+	  "actions": {
+		"geocode": {
+		  "idempotent": true,
+		  "inputs": { "address": { "type": "string", "required": true }, "trace": { "type": "string" } },
+		  "cache": { "ttl_ms": 86400000, "vary_on": ["address"] }
+		},
+		"reverse": { "cache": { "ttl_ms": 1000 } },
+		"lookup": { "idempotent": true, "cache": { "ttl_ms": 0, "vary_on": ["zip"] } },
+		"batch": { "idempotent": true, "async": { "poll_action": "geocode" }, "cache": { "ttl_ms": 1000 } }
+	  }
+	}`)
+
+	fields := diagnosticFields(ValidateManifestJSON(manifest).Diagnostics)
+	want := []string{
+		"actions.batch.cache",
+		"actions.lookup.cache.ttl_ms",
+		"actions.lookup.cache.vary_on[0]",
+		"actions.reverse.cache",
+	}
+	if !slices.Equal(slices.Sorted(slices.Values(fields)), want) {
+		t.Fatalf("error fields = %v, want %v", fields, want)
+	}
+
+	var parsed ToolManifest
+	if err := json.Unmarshal(manifest, &parsed); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if cache := parsed.Actions["geocode"].Cache; cache == nil || cache.TTLMS != 86400000 || !slices.Equal(cache.VaryOn, []string{"address"}) {
+		t.Fatalf("geocode cache = %+v", cache)
+	}
+}
+
 func TestValidateManifestJSONRequiredFieldErrors(t *testing.T) {
 	invalid := []byte(`{
 	  "manifest_version": "1.0",
@@ -10,6 +10,8 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+
+	"github.com/petal-labs/petalflow/propagate"
 )
 
 // SSETransportConfig configures an MCP endpoint transport.
@@ -66,6 +68,7 @@ func (t *SSETransport) Send(ctx context.Context, message Message) error {
 	for key, value := range t.cfg.Headers {
 		req.Header.Set(key, value)
 	}
+	propagate.SetHeaders(ctx, req.Header)
 
 	resp, err := t.cfg.Client.Do(req)
 	if err != nil {
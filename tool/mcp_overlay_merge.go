@@ -221,6 +221,15 @@ func cloneActionSpec(in ActionSpec) ActionSpec {
 		value := *in.LLMCallable
 		out.LLMCallable = &value
 	}
+	if in.Async != nil {
+		async := *in.Async
+		out.Async = &async
+	}
+	if in.Cache != nil {
+		cache := *in.Cache
+		cache.VaryOn = slices.Clone(in.Cache.VaryOn)
+		out.Cache = &cache
+	}
 	return out
 }
 
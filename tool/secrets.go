@@ -18,11 +18,14 @@ const (
 	encryptedValuePrefix = "enc:v1:"
 )
 
-type secretCodec struct {
+// SecretCodec encrypts values with AES-GCM under a key derived from
+// PETALFLOW_SECRET_KEY or, when unset, from the user, host and scope.
+type SecretCodec struct {
 	aead cipher.AEAD
 }
 
-func newSecretCodec(scope string) (*secretCodec, error) {
+// NewSecretCodec creates a codec for scope, such as the daemon database path.
+func NewSecretCodec(scope string) (*SecretCodec, error) {
 	key := deriveSecretKey(scope)
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -32,7 +35,7 @@ func newSecretCodec(scope string) (*secretCodec, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &secretCodec{aead: aead}, nil
+	return &SecretCodec{aead: aead}, nil
 }
 
 func deriveSecretKey(scope string) []byte {
@@ -55,7 +58,9 @@ func deriveSecretKey(scope string) []byte {
 	return sum[:]
 }
 
-func (c *secretCodec) Encrypt(value string) (string, error) {
+// Encrypt returns value encrypted with an "enc:v1:" prefix. Blank and
+// already encrypted values are returned as is.
+func (c *SecretCodec) Encrypt(value string) (string, error) {
 	if c == nil || c.aead == nil {
 		return "", fmt.Errorf("tool: secret codec is not initialized")
 	}
@@ -75,7 +80,8 @@ func (c *secretCodec) Encrypt(value string) (string, error) {
 	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(payload), nil
 }
 
-func (c *secretCodec) Decrypt(value string) (string, error) {
+// Decrypt reverses Encrypt. Values without the prefix are returned as is.
+func (c *SecretCodec) Decrypt(value string) (string, error) {
 	if c == nil || c.aead == nil {
 		return "", fmt.Errorf("tool: secret codec is not initialized")
 	}
@@ -7,6 +7,8 @@ import (
 	"slices"
 	"strings"
 	"time"
+
+	"github.com/petal-labs/petalflow/core"
 )
 
 var (
@@ -320,6 +322,8 @@ func (s *DaemonToolService) TestAction(ctx context.Context, name string, action
 		Action:   action,
 		Inputs:   cloneAnyMap(inputs),
 		Config:   configAsAnyMap(reg.Config),
+		// Set while a tool node runs the action; see core.ToolCallbackToken.
+		CallbackToken: core.ToolCallbackToken(ctx),
 	})
 	if err != nil {
 		return ToolTestResult{}, err
@@ -11,6 +11,8 @@ import (
 	"slices"
 	"strings"
 	"time"
+
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 // StdioAdapter is the runtime adapter for subprocess-backed tools.
@@ -124,11 +126,11 @@ func (a *StdioAdapter) invokeAttempt(parent context.Context, req InvokeRequest,
 	return decodeStdioInvokeResult(execCtx, stdoutBytes, stderrBytes, waitErr, start)
 }
 
+// withStdioInvokeTimeout bounds the command by the manifest timeout and any
+// earlier deadline on parent, whichever comes first. When the deadline on
+// parent wins, the run records a node.deadline_clamped event.
 func withStdioInvokeTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
-	if _, hasDeadline := parent.Deadline(); !hasDeadline && timeout > 0 {
-		return context.WithTimeout(parent, timeout)
-	}
-	return parent, func() {}
+	return runtime.WithCallTimeout(parent, timeout)
 }
 
 func (a *StdioAdapter) prepareCommand(
@@ -159,11 +161,12 @@ func (a *StdioAdapter) prepareCommand(
 
 func writeStdioInvokeRequest(cmd *exec.Cmd, stdin io.WriteCloser, req InvokeRequest) error {
 	payload := InvokeRequest{
-		ToolName:  req.ToolName,
-		Action:    req.Action,
-		Inputs:    req.Inputs,
-		Config:    req.Config,
-		RequestID: req.RequestID,
+		ToolName:      req.ToolName,
+		Action:        req.Action,
+		Inputs:        req.Inputs,
+		Config:        req.Config,
+		RequestID:     req.RequestID,
+		CallbackToken: req.CallbackToken,
 	}
 	if err := json.NewEncoder(stdin).Encode(payload); err != nil {
 		_ = stdin.Close()
@@ -12,6 +12,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"github.com/petal-labs/petalflow/runtime"
 )
 
 func TestStdioAdapterInvoke(t *testing.T) {
@@ -279,8 +281,27 @@ func TestWithStdioInvokeTimeout(t *testing.T) {
 	if !hasDeadline2 {
 		t.Fatal("expected deadline on returned context")
 	}
-	if !deadline2.Equal(parentDeadline) {
-		t.Fatalf("deadline changed: got %v, want %v", deadline2, parentDeadline)
+	if !deadline2.Before(parentDeadline) {
+		t.Fatalf("shorter manifest timeout should win: got %v, parent %v", deadline2, parentDeadline)
+	}
+
+	var events []runtime.Event
+	shortParent, shortCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer shortCancel()
+	shortParent = runtime.ContextWithEmitter(shortParent, func(e runtime.Event) { events = append(events, e) })
+	ctx3, cancel3 := withStdioInvokeTimeout(shortParent, time.Second)
+	defer cancel3()
+
+	shortDeadline, _ := shortParent.Deadline()
+	deadline3, _ := ctx3.Deadline()
+	if !deadline3.Equal(shortDeadline) {
+		t.Fatalf("earlier parent deadline should win: got %v, want %v", deadline3, shortDeadline)
+	}
+	if len(events) != 1 || events[0].Kind != runtime.EventNodeDeadlineClamped {
+		t.Fatalf("events = %+v, want one %s", events, runtime.EventNodeDeadlineClamped)
+	}
+	if events[0].Payload["configured_timeout_ms"] != int64(1000) {
+		t.Errorf("configured_timeout_ms = %v, want 1000", events[0].Payload["configured_timeout_ms"])
 	}
 }
 
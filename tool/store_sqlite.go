@@ -268,7 +268,7 @@ func (s *SQLiteStore) encryptSensitiveRegistration(reg *ToolRegistration) error
 		return nil
 	}
 
-	codec, err := newSecretCodec(s.scope)
+	codec, err := NewSecretCodec(s.scope)
 	if err != nil {
 		return fmt.Errorf("tool: initialize secret codec: %w", err)
 	}
@@ -294,7 +294,7 @@ func (s *SQLiteStore) decryptSensitiveRegistration(reg *ToolRegistration) error
 		return nil
 	}
 
-	codec, err := newSecretCodec(s.scope)
+	codec, err := NewSecretCodec(s.scope)
 	if err != nil {
 		return fmt.Errorf("tool: initialize secret codec: %w", err)
 	}